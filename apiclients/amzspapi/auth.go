@@ -0,0 +1,114 @@
+package amzspapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+)
+
+// tokenMargin is the time before expiry at which restricted data tokens are renewed
+const tokenMargin = time.Minute
+
+// restrictedTokens caches the restricted data tokens, which are valid for an hour, by path
+type restrictedTokens struct {
+	mu    sync.Mutex
+	items map[string]cachedToken
+}
+
+type cachedToken struct {
+	value     string
+	expiresAt time.Time
+}
+
+// accessToken returns a valid LWA access token, refreshing it if needed. Clients not created by NewClient don't cache the token
+func (c Client) accessToken(ctx context.Context) (token string, err error) {
+
+	token, err = c.token.Get(ctx, c.requestAccessToken)
+	if err != nil {
+		return "", fmt.Errorf("c.token.Get failed: %w", err)
+	}
+	return token, nil
+}
+
+// requestAccessToken exchanges the refresh token for an access token at the LWA token endpoint
+func (c Client) requestAccessToken(ctx context.Context) (apiclient.Token, error) {
+
+	if c.ClientId == "" || c.ClientSecret == "" || c.RefreshToken == "" {
+		return apiclient.Token{}, fmt.Errorf("%w: client id, client secret and refresh token are required", cerrors.ErrValidationFailed)
+	}
+
+	lwa := apiclient.OAuth2{
+		HttpClient:   c.HttpClient,
+		TokenUrl:     c.tokenUrl(),
+		ClientId:     c.ClientId,
+		ClientSecret: c.ClientSecret,
+		RefreshToken: c.RefreshToken,
+		Source:       apiShortname,
+	}
+	return lwa.RequestToken(ctx)
+}
+
+// ParseTokenJson parses the response of the LWA token endpoint
+func ParseTokenJson(content []byte) (token string, expiresIn time.Duration, err error) {
+
+	// content looks like this: {"access_token":"Atza|IwEBIA...","token_type":"bearer","expires_in":3600,"refresh_token":"Atzr|IwEBIA..."}
+
+	t, err := apiclient.ParseTokenJson(content)
+	return t.Value, t.ExpiresIn, err
+}
+
+// restrictedDataToken returns a restricted data token for GET requests to path with dataElements, e.g. buyerInfo, creating it if needed
+func (c Client) restrictedDataToken(ctx context.Context, path string, dataElements []string) (token string, err error) {
+
+	if c.restricted != nil {
+		c.restricted.mu.Lock()
+		cached, ok := c.restricted.items[path]
+		c.restricted.mu.Unlock()
+		if ok && time.Now().Add(tokenMargin).Before(cached.expiresAt) {
+			return cached.value, nil
+		}
+	}
+
+	lwaToken, err := c.accessToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("c.accessToken failed: %w", err)
+	}
+
+	reqBody := map[string]any{
+		"restrictedResources": []map[string]any{
+			{"method": http.MethodGet, "path": path, "dataElements": dataElements},
+		},
+	}
+	body, err := c.do(ctx, opCreateRestrictedDataToken, http.MethodPost, "/tokens/2021-03-01/restrictedDataToken", nil, reqBody, lwaToken)
+	if err != nil {
+		return "", fmt.Errorf("c.do failed: %w", err)
+	}
+
+	respS := struct {
+		RestrictedDataToken string `json:"restrictedDataToken"`
+		ExpiresIn           int    `json:"expiresIn"`
+	}{}
+	if err = json.Unmarshal(body, &respS); err != nil {
+		return "", fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+	if respS.RestrictedDataToken == "" {
+		return "", fmt.Errorf("%w: no restricted data token", cerrors.ErrValidationFailed)
+	}
+
+	if c.restricted != nil {
+		c.restricted.mu.Lock()
+		if c.restricted.items == nil {
+			c.restricted.items = make(map[string]cachedToken)
+		}
+		c.restricted.items[path] = cachedToken{value: respS.RestrictedDataToken, expiresAt: time.Now().Add(time.Duration(respS.ExpiresIn) * time.Second)}
+		c.restricted.mu.Unlock()
+	}
+
+	return respS.RestrictedDataToken, nil
+}
@@ -0,0 +1,305 @@
+package amzspapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+)
+
+// Docs: https://developer-docs.amazon.com/sp-api/docs
+// requests are authorized with a Login with Amazon (LWA) access token, refreshed from the refresh token of the seller's app authorization
+// personal data, such as buyer details, needs a restricted data token (RDT) and an approved role
+
+const (
+	apiShortname      string = "amzsp"
+	defaultTokenUrl   string = "https://api.amazon.com/auth/o2/token"
+	timeoutSecs       int    = 30
+	maxAttempts       int    = 3 // per request, if rate limited
+	defaultRetryAfter        = 2 * time.Second
+)
+
+// RegionUrls are the SP-API endpoints of the selling regions
+var RegionUrls = map[string]string{
+	"na": "https://sellingpartnerapi-na.amazon.com", // North America
+	"eu": "https://sellingpartnerapi-eu.amazon.com", // Europe, Middle East and India
+	"fe": "https://sellingpartnerapi-fe.amazon.com", // Far East
+}
+
+// RatePlan is the usage plan of an operation: a token bucket refilled at Rate requests per second, holding up to Burst requests
+type RatePlan struct {
+	Rate  float64
+	Burst int
+}
+
+// RatePlans are the default usage plans of the operations used. Amazon may grant higher ones, which are taken from the x-amzn-RateLimit-Limit header
+var RatePlans = map[string]RatePlan{
+	opGetOrders:                 {Rate: 0.0167, Burst: 20},
+	opGetOrderItems:             {Rate: 0.5, Burst: 30},
+	opCreateRestrictedDataToken: {Rate: 1, Burst: 10},
+}
+
+// operation names, as in the SP-API docs
+const (
+	opGetOrders                 string = "getOrders"
+	opGetOrderItems             string = "getOrderItems"
+	opCreateRestrictedDataToken string = "createRestrictedDataToken"
+)
+
+// copies of a Client share its access token and rate limits
+type Client struct {
+	HttpClient     *http.Client
+	BaseUrl        string // SP-API root, e.g. of a fixture server in tests. Defaults to the endpoint of Region
+	TokenUrl       string // LWA token endpoint. Defaults to Amazon
+	Region         string // key of RegionUrls
+	ClientId       string // LWA credentials of the app
+	ClientSecret   string
+	RefreshToken   string // of the seller's authorization of the app
+	RestrictedData bool   // request buyer details with a restricted data token. Needs the app to have an approved role for them
+	InfoLog        *slog.Logger
+	ErrorLog       *slog.Logger
+
+	token      *apiclient.TokenCache
+	restricted *restrictedTokens
+	buckets    *buckets
+}
+
+func NewClient(region, clientId, clientSecret, refreshToken string, infoLog, errorLog *slog.Logger) (client Client) {
+
+	return Client{
+		HttpClient: &http.Client{
+			Timeout: time.Duration(timeoutSecs) * time.Second,
+		},
+		TokenUrl:     defaultTokenUrl,
+		Region:       region,
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		RefreshToken: refreshToken,
+		InfoLog:      infoLog.With("api", apiShortname),
+		ErrorLog:     errorLog.With("api", apiShortname),
+		token:        &apiclient.TokenCache{},
+		restricted:   &restrictedTokens{},
+		buckets:      &buckets{items: make(map[string]*bucket)},
+	}
+}
+
+// baseUrl returns c.BaseUrl, or the endpoint of c.Region if not set
+func (c Client) baseUrl() string {
+	if c.BaseUrl != "" {
+		return strings.TrimSuffix(c.BaseUrl, "/")
+	}
+	return RegionUrls[c.Region]
+}
+
+// tokenUrl returns c.TokenUrl, or Amazon if not set
+func (c Client) tokenUrl() string {
+	if c.TokenUrl == "" {
+		return defaultTokenUrl
+	}
+	return c.TokenUrl
+}
+
+// apiErrors is the error response of the SP-API
+type apiErrors struct {
+	Errors []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (e apiErrors) String() string {
+	msgs := []string{}
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Code+": "+err.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// do sends a request of operation op to path with params and the JSON reqBody, if not nil, waiting for the rate limit, and returns the response body
+// token is sent as access token: the LWA access token, or a restricted data token. Rate limited requests (429) are retried
+func (c Client) do(ctx context.Context, op, method, path string, params url.Values, reqBody any, token string) (body []byte, err error) {
+
+	if c.baseUrl() == "" {
+		return nil, fmt.Errorf("%w: unknown region '%s'", cerrors.ErrValidationFailed, c.Region)
+	}
+
+	reqUrl := c.baseUrl() + path
+	if len(params) > 0 {
+		reqUrl += "?" + params.Encode()
+	}
+
+	var reqBytes []byte
+	if reqBody != nil {
+		if reqBytes, err = json.Marshal(reqBody); err != nil {
+			return nil, fmt.Errorf("json.Marshal failed: %w", err)
+		}
+	}
+
+	b := c.buckets.get(op)
+
+	for attempt := 1; ; attempt++ {
+
+		req, err := http.NewRequestWithContext(ctx, method, reqUrl, bytes.NewReader(reqBytes))
+		if err != nil {
+			return nil, fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+		}
+		req.Header.Set("x-amz-access-token", token)
+		req.Header.Set("Accept", "application/json")
+		if reqBody != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		if err := b.wait(ctx); err != nil {
+			return nil, fmt.Errorf("b.wait failed: %w", err)
+		}
+
+		resp, err := c.HttpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("c.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+		}
+
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("io.ReadAll failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+		}
+
+		// a granted plan may differ from the default
+		if rate, err := strconv.ParseFloat(resp.Header.Get("x-amzn-RateLimit-Limit"), 64); err == nil && rate > 0 {
+			b.setRate(rate)
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return body, nil
+		case resp.StatusCode == http.StatusNotFound:
+			return nil, fmt.Errorf("%w: %s", cerrors.ErrNotFound, path)
+		case resp.StatusCode == http.StatusTooManyRequests:
+			if attempt == maxAttempts {
+				return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+			}
+			retryAfter := b.refillTime()
+			c.InfoLog.Warn("rate limited, retrying", "operation", op, "retry_after", retryAfter.String())
+			b.pause(retryAfter)
+		case resp.StatusCode >= 400 && resp.StatusCode < 500:
+			// e.g. 400 invalid input, 403 unauthorized or expired token
+			errS := apiErrors{}
+			_ = json.Unmarshal(body, &errS)
+			return nil, fmt.Errorf("%w: status %d: %s", cerrors.ErrValidationFailed, resp.StatusCode, errS.String())
+		default:
+			return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+		}
+	}
+}
+
+// buckets holds a bucket per operation
+type buckets struct {
+	mu    sync.Mutex
+	items map[string]*bucket
+}
+
+// get returns the bucket of op, creating it from RatePlans if needed. A nil buckets returns a nil bucket, which does not wait
+func (bs *buckets) get(op string) *bucket {
+
+	if bs == nil {
+		return nil
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	b, ok := bs.items[op]
+	if !ok {
+		plan, ok := RatePlans[op]
+		if !ok {
+			plan = RatePlan{Rate: 1, Burst: 1}
+		}
+		b = &bucket{rate: plan.Rate, burst: float64(plan.Burst), tokens: float64(plan.Burst), last: time.Now()}
+		bs.items[op] = b
+	}
+	return b
+}
+
+// bucket is the token bucket of an operation's rate plan
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens added per second
+	burst  float64 // maximum tokens
+	tokens float64
+	last   time.Time // time tokens was last updated
+}
+
+// wait blocks until a token is available, and takes it, or returns ctx.Err() once ctx is done
+func (b *bucket) wait(ctx context.Context) error {
+
+	if b == nil {
+		return ctx.Err()
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	// taking a token may leave the bucket in debt, which later callers wait for in turn
+	b.tokens--
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	return apiclient.Sleep(ctx, delay)
+}
+
+// refillTime returns the time needed to add a token
+func (b *bucket) refillTime() time.Duration {
+
+	if b == nil {
+		return defaultRetryAfter
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Duration(float64(time.Second) / b.rate)
+}
+
+// pause empties the bucket, so that the next request waits for at least d
+func (b *bucket) pause(d time.Duration) {
+
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if debt := -d.Seconds() * b.rate; b.tokens > debt {
+		b.tokens = debt
+	}
+}
+
+// setRate sets the refill rate of b
+func (b *bucket) setRate(rate float64) {
+
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rate = rate
+}
@@ -0,0 +1,378 @@
+package amzspapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+	"github.com/loveyourstack/connectors/stores/amzorder/amzorderheader"
+	"github.com/loveyourstack/connectors/stores/amzorder/amzorderitem"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	ordersPath     string = "/orders/v0/orders"
+	ordersPageSize int    = 100             // maximum of getOrders
+	MinWindowLag          = 2 * time.Minute // LastUpdatedBefore must be at least this long before the request
+)
+
+// restrictedOrderElements are the personal data elements requested with a restricted data token
+var restrictedOrderElements = []string{"buyerInfo", "shippingAddress"}
+
+type Order struct {
+	AmazonOrderId      string
+	MarketplaceId      string
+	OrderStatus        string
+	FulfillmentChannel string
+	SalesChannel       string
+	Currency           string
+	OrderTotal         *float64 // nil while pending
+	ItemsShipped       int
+	ItemsUnshipped     int
+	ShipCity           string
+	ShipPostalCode     string
+	ShipCountry        string
+	BuyerEmail         string // only with a restricted data token
+	BuyerName          string // only with a restricted data token
+	PurchaseAt         time.Time
+	LastUpdateAt       time.Time
+}
+
+type OrderItem struct {
+	OrderItemId       string
+	Asin              string
+	SellerSku         string
+	Title             string
+	QuantityOrdered   int
+	QuantityShipped   int
+	Currency          string
+	ItemPrice         *float64
+	ItemTax           *float64
+	PromotionDiscount *float64
+}
+
+// money is an amount of the SP-API
+type money struct {
+	CurrencyCode string `json:"CurrencyCode"`
+	Amount       string `json:"Amount"`
+}
+
+// value returns the parsed amount of m, or nil if m is nil
+func (m *money) value() (*float64, error) {
+
+	if m == nil || m.Amount == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(m.Amount, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid amount '%s'", cerrors.ErrValidationFailed, m.Amount)
+	}
+	return &f, nil
+}
+
+// apiOrders is the response of getOrders
+type apiOrders struct {
+	Payload struct {
+		Orders []struct {
+			AmazonOrderId          string `json:"AmazonOrderId"`
+			MarketplaceId          string `json:"MarketplaceId"`
+			OrderStatus            string `json:"OrderStatus"`
+			FulfillmentChannel     string `json:"FulfillmentChannel"`
+			SalesChannel           string `json:"SalesChannel"`
+			OrderTotal             *money `json:"OrderTotal"`
+			NumberOfItemsShipped   int    `json:"NumberOfItemsShipped"`
+			NumberOfItemsUnshipped int    `json:"NumberOfItemsUnshipped"`
+			PurchaseDate           string `json:"PurchaseDate"`
+			LastUpdateDate         string `json:"LastUpdateDate"`
+			ShippingAddress        *struct {
+				City        string `json:"City"`
+				PostalCode  string `json:"PostalCode"`
+				CountryCode string `json:"CountryCode"`
+			} `json:"ShippingAddress"`
+			BuyerInfo *struct {
+				BuyerEmail string `json:"BuyerEmail"`
+				BuyerName  string `json:"BuyerName"`
+			} `json:"BuyerInfo"`
+		} `json:"Orders"`
+		NextToken string `json:"NextToken"`
+	} `json:"payload"`
+}
+
+// GetApiOrders returns the orders of marketplaceIds last updated in the window from updatedAfter to updatedBefore, which must be at least MinWindowLag ago
+// with c.RestrictedData, buyer details and full shipping addresses are requested with a restricted data token
+func (c Client) GetApiOrders(ctx context.Context, marketplaceIds []string, updatedAfter, updatedBefore time.Time) (orders []Order, err error) {
+
+	if len(marketplaceIds) == 0 {
+		return nil, fmt.Errorf("%w: at least 1 marketplace id is required", cerrors.ErrValidationFailed)
+	}
+	if updatedBefore.After(time.Now().Add(-MinWindowLag)) {
+		return nil, fmt.Errorf("%w: updatedBefore must be at least %s ago", cerrors.ErrValidationFailed, MinWindowLag)
+	}
+
+	token, err := c.ordersToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("c.ordersToken failed: %w", err)
+	}
+
+	err = apiclient.Cursor(ctx, apiclient.Paging{Log: c.InfoLog},
+		func(nextToken string) ([]byte, error) {
+			params := url.Values{}
+			params.Set("MarketplaceIds", strings.Join(marketplaceIds, ","))
+			if nextToken != "" {
+				// further pages only take the marketplaces and the token
+				params.Set("NextToken", nextToken)
+			} else {
+				params.Set("LastUpdatedAfter", updatedAfter.UTC().Format(time.RFC3339))
+				params.Set("LastUpdatedBefore", updatedBefore.UTC().Format(time.RFC3339))
+				params.Set("MaxResultsPerPage", strconv.Itoa(ordersPageSize))
+			}
+			return c.do(ctx, opGetOrders, http.MethodGet, ordersPath, params, nil, token)
+		},
+		func(body []byte) (string, error) {
+			page, nextToken, err := ParseOrdersJson(body)
+			if err != nil {
+				return "", fmt.Errorf("ParseOrdersJson failed: %w", err)
+			}
+			orders = append(orders, page...)
+			return nextToken, nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("apiclient.Cursor failed: %w", err)
+	}
+
+	return orders, nil
+}
+
+// ordersToken returns the token for getOrders: a restricted data token if c.RestrictedData, otherwise the LWA access token
+func (c Client) ordersToken(ctx context.Context) (token string, err error) {
+
+	if c.RestrictedData {
+		token, err = c.restrictedDataToken(ctx, ordersPath, restrictedOrderElements)
+		if err != nil {
+			return "", fmt.Errorf("c.restrictedDataToken failed: %w", err)
+		}
+		return token, nil
+	}
+
+	token, err = c.accessToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("c.accessToken failed: %w", err)
+	}
+	return token, nil
+}
+
+// ParseOrdersJson parses the response of getOrders, returning its orders and the token of the next page, if any
+func ParseOrdersJson(content []byte) (orders []Order, nextToken string, err error) {
+
+	/* content looks like this:
+	{"payload":{"Orders":[{"AmazonOrderId":"303-1234567-1234567","PurchaseDate":"2024-06-01T10:00:00Z","LastUpdateDate":"2024-06-02T08:30:00Z","OrderStatus":"Shipped",
+	  "FulfillmentChannel":"AFN","SalesChannel":"Amazon.de","NumberOfItemsShipped":2,"NumberOfItemsUnshipped":0,"OrderTotal":{"CurrencyCode":"EUR","Amount":"59.98"},
+	  "MarketplaceId":"A1PA6795UKMFR9","ShippingAddress":{"City":"BERLIN","PostalCode":"10115","CountryCode":"DE"}}],"NextToken":"2YgYW55IGNhcm5hbCBwbGVhcw=="}}
+	*/
+
+	respS := apiOrders{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, "", fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, apiOrd := range respS.Payload.Orders {
+
+		ord := Order{
+			AmazonOrderId:      apiOrd.AmazonOrderId,
+			MarketplaceId:      apiOrd.MarketplaceId,
+			OrderStatus:        apiOrd.OrderStatus,
+			FulfillmentChannel: apiOrd.FulfillmentChannel,
+			SalesChannel:       apiOrd.SalesChannel,
+			ItemsShipped:       apiOrd.NumberOfItemsShipped,
+			ItemsUnshipped:     apiOrd.NumberOfItemsUnshipped,
+		}
+
+		if ord.PurchaseAt, err = time.Parse(time.RFC3339, apiOrd.PurchaseDate); err != nil {
+			return nil, "", fmt.Errorf("%w: invalid PurchaseDate '%s' of %s", cerrors.ErrValidationFailed, apiOrd.PurchaseDate, apiOrd.AmazonOrderId)
+		}
+		if ord.LastUpdateAt, err = time.Parse(time.RFC3339, apiOrd.LastUpdateDate); err != nil {
+			return nil, "", fmt.Errorf("%w: invalid LastUpdateDate '%s' of %s", cerrors.ErrValidationFailed, apiOrd.LastUpdateDate, apiOrd.AmazonOrderId)
+		}
+		if apiOrd.OrderTotal != nil {
+			ord.Currency = apiOrd.OrderTotal.CurrencyCode
+			if ord.OrderTotal, err = apiOrd.OrderTotal.value(); err != nil {
+				return nil, "", fmt.Errorf("OrderTotal of %s: %w", apiOrd.AmazonOrderId, err)
+			}
+		}
+		if apiOrd.ShippingAddress != nil {
+			ord.ShipCity = apiOrd.ShippingAddress.City
+			ord.ShipPostalCode = apiOrd.ShippingAddress.PostalCode
+			ord.ShipCountry = apiOrd.ShippingAddress.CountryCode
+		}
+		if apiOrd.BuyerInfo != nil {
+			ord.BuyerEmail = apiOrd.BuyerInfo.BuyerEmail
+			ord.BuyerName = apiOrd.BuyerInfo.BuyerName
+		}
+
+		orders = append(orders, ord)
+	}
+
+	return orders, respS.Payload.NextToken, nil
+}
+
+// apiOrderItems is the response of getOrderItems
+type apiOrderItems struct {
+	Payload struct {
+		OrderItems []struct {
+			OrderItemId       string `json:"OrderItemId"`
+			ASIN              string `json:"ASIN"`
+			SellerSKU         string `json:"SellerSKU"`
+			Title             string `json:"Title"`
+			QuantityOrdered   int    `json:"QuantityOrdered"`
+			QuantityShipped   int    `json:"QuantityShipped"`
+			ItemPrice         *money `json:"ItemPrice"`
+			ItemTax           *money `json:"ItemTax"`
+			PromotionDiscount *money `json:"PromotionDiscount"`
+		} `json:"OrderItems"`
+		NextToken string `json:"NextToken"`
+	} `json:"payload"`
+}
+
+// GetApiOrderItems returns the items of order amazonOrderId
+func (c Client) GetApiOrderItems(ctx context.Context, amazonOrderId string) (items []OrderItem, err error) {
+
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("c.accessToken failed: %w", err)
+	}
+
+	path := ordersPath + "/" + url.PathEscape(amazonOrderId) + "/orderItems"
+	params := url.Values{}
+
+	err = apiclient.Cursor(ctx, apiclient.Paging{Log: c.InfoLog},
+		func(nextToken string) ([]byte, error) {
+			if nextToken != "" {
+				params.Set("NextToken", nextToken)
+			}
+			return c.do(ctx, opGetOrderItems, http.MethodGet, path, params, nil, token)
+		},
+		func(body []byte) (string, error) {
+			page, nextToken, err := ParseOrderItemsJson(body)
+			if err != nil {
+				return "", fmt.Errorf("ParseOrderItemsJson failed: %w", err)
+			}
+			items = append(items, page...)
+			return nextToken, nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("apiclient.Cursor failed: %w", err)
+	}
+
+	return items, nil
+}
+
+// ParseOrderItemsJson parses the response of getOrderItems, returning its items and the token of the next page, if any
+func ParseOrderItemsJson(content []byte) (items []OrderItem, nextToken string, err error) {
+
+	/* content looks like this:
+	{"payload":{"AmazonOrderId":"303-1234567-1234567","OrderItems":[{"ASIN":"B00551Q3CS","OrderItemId":"05015851154158","SellerSKU":"NABetaASINB00551Q3CS",
+	  "Title":"B00551Q3CS [Card Book]","QuantityOrdered":1,"QuantityShipped":1,"ItemPrice":{"CurrencyCode":"EUR","Amount":"29.99"},
+	  "ItemTax":{"CurrencyCode":"EUR","Amount":"4.79"},"PromotionDiscount":{"CurrencyCode":"EUR","Amount":"0.00"}}]}}
+	*/
+
+	respS := apiOrderItems{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, "", fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, apiItem := range respS.Payload.OrderItems {
+
+		item := OrderItem{
+			OrderItemId:     apiItem.OrderItemId,
+			Asin:            apiItem.ASIN,
+			SellerSku:       apiItem.SellerSKU,
+			Title:           apiItem.Title,
+			QuantityOrdered: apiItem.QuantityOrdered,
+			QuantityShipped: apiItem.QuantityShipped,
+		}
+		if apiItem.ItemPrice != nil {
+			item.Currency = apiItem.ItemPrice.CurrencyCode
+		}
+		if item.ItemPrice, err = apiItem.ItemPrice.value(); err != nil {
+			return nil, "", fmt.Errorf("ItemPrice of %s: %w", apiItem.OrderItemId, err)
+		}
+		if item.ItemTax, err = apiItem.ItemTax.value(); err != nil {
+			return nil, "", fmt.Errorf("ItemTax of %s: %w", apiItem.OrderItemId, err)
+		}
+		if item.PromotionDiscount, err = apiItem.PromotionDiscount.value(); err != nil {
+			return nil, "", fmt.Errorf("PromotionDiscount of %s: %w", apiItem.OrderItemId, err)
+		}
+
+		items = append(items, item)
+	}
+
+	return items, respS.Payload.NextToken, nil
+}
+
+// OrdersToMap converts orders to a map with AmazonOrderId as key
+func OrdersToMap(orders []Order) (itemsMap map[string]amzorderheader.Model, err error) {
+
+	itemsMap = make(map[string]amzorderheader.Model)
+	for _, ord := range orders {
+
+		if _, ok := itemsMap[ord.AmazonOrderId]; ok {
+			return nil, fmt.Errorf("%w: duplicate order %s", cerrors.ErrValidationFailed, ord.AmazonOrderId)
+		}
+
+		itemsMap[ord.AmazonOrderId] = amzorderheader.Model{Input: amzorderheader.Input{
+			AmazonOrderId:      ord.AmazonOrderId,
+			MarketplaceId:      ord.MarketplaceId,
+			OrderStatus:        ord.OrderStatus,
+			FulfillmentChannel: ord.FulfillmentChannel,
+			SalesChannel:       ord.SalesChannel,
+			Currency:           ord.Currency,
+			OrderTotal:         ord.OrderTotal,
+			ItemsShipped:       ord.ItemsShipped,
+			ItemsUnshipped:     ord.ItemsUnshipped,
+			ShipCity:           ord.ShipCity,
+			ShipPostalCode:     ord.ShipPostalCode,
+			ShipCountry:        ord.ShipCountry,
+			BuyerEmail:         ord.BuyerEmail,
+			BuyerName:          ord.BuyerName,
+			PurchaseAt:         lystype.Datetime(ord.PurchaseAt),
+			LastUpdateAt:       lystype.Datetime(ord.LastUpdateAt),
+		}}
+	}
+
+	return itemsMap, nil
+}
+
+// OrderItemsToMap converts the items of order orderFk to a map with OrderItemId as key
+func OrderItemsToMap(items []OrderItem, orderFk int64) (itemsMap map[string]amzorderitem.Model, err error) {
+
+	itemsMap = make(map[string]amzorderitem.Model)
+	for _, item := range items {
+
+		if _, ok := itemsMap[item.OrderItemId]; ok {
+			return nil, fmt.Errorf("%w: duplicate order item %s", cerrors.ErrValidationFailed, item.OrderItemId)
+		}
+
+		itemsMap[item.OrderItemId] = amzorderitem.Model{Input: amzorderitem.Input{
+			OrderFk:           orderFk,
+			OrderItemId:       item.OrderItemId,
+			Asin:              item.Asin,
+			SellerSku:         item.SellerSku,
+			Title:             item.Title,
+			QuantityOrdered:   item.QuantityOrdered,
+			QuantityShipped:   item.QuantityShipped,
+			Currency:          item.Currency,
+			ItemPrice:         item.ItemPrice,
+			ItemTax:           item.ItemTax,
+			PromotionDiscount: item.PromotionDiscount,
+		}}
+	}
+
+	return itemsMap, nil
+}
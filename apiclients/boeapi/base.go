@@ -0,0 +1,45 @@
+package boeapi
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Docs: https://www.bankofengland.co.uk/boeapps/database/Help.asp#CSV
+// the Bank of England's Interactive Statistical Database (IADB) publishes daily spot exchange rates against sterling, recorded at 4pm London time
+
+const (
+	apiShortname   string = "boe"
+	defaultBaseUrl string = "https://www.bankofengland.co.uk/boeapps/database"
+	timeoutSecs    int    = 30
+	userAgent      string = "Mozilla/5.0 (compatible; loveyourstack-connectors)" // the IADB rejects requests without a browser-like user agent
+)
+
+type Client struct {
+	HttpClient *http.Client
+	BaseUrl    string // API root, e.g. of a fixture server in tests. Defaults to the IADB
+	InfoLog    *slog.Logger
+	ErrorLog   *slog.Logger
+}
+
+func NewClient(infoLog, errorLog *slog.Logger) (client Client) {
+
+	return Client{
+		HttpClient: &http.Client{
+			Timeout: time.Duration(timeoutSecs) * time.Second,
+		},
+		BaseUrl:  defaultBaseUrl,
+		InfoLog:  infoLog.With("api", apiShortname),
+		ErrorLog: errorLog.With("api", apiShortname),
+	}
+}
+
+// baseUrl returns c.BaseUrl, or the IADB if not set
+func (c Client) baseUrl() string {
+	if c.BaseUrl == "" {
+		return defaultBaseUrl
+	}
+	return strings.TrimSuffix(c.BaseUrl, "/")
+}
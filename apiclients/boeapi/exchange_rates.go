@@ -0,0 +1,207 @@
+package boeapi
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/ratesource"
+)
+
+// SeriesCodes maps currency codes to the IADB series of their daily spot rate: currency units per pound sterling
+var SeriesCodes = map[string]string{
+	"AUD": "XUDLADS",
+	"CAD": "XUDLCDS",
+	"CHF": "XUDLSFS",
+	"DKK": "XUDLDKS",
+	"EUR": "XUDLERS",
+	"HKD": "XUDLHDS",
+	"JPY": "XUDLJYS",
+	"NOK": "XUDLNKS",
+	"NZD": "XUDLNDS",
+	"SEK": "XUDLSKS",
+	"SGD": "XUDLSGS",
+	"USD": "XUDLUSS",
+	"ZAR": "XUDLZRS",
+}
+
+const (
+	iadbDateFormat    string = "02/Jan/2006" // of the request params
+	iadbCsvDateFormat string = "02 Jan 2006" // of the response
+)
+
+// GetApiSpotRates returns the daily spot rates from GBP to currencies, or to all currencies of SeriesCodes if empty, in the date range
+func (c Client) GetApiSpotRates(ctx context.Context, currencies []string, startDate, endDate time.Time) (rates []ratesource.Rate, err error) {
+
+	if startDate.After(endDate) {
+		return nil, fmt.Errorf("%w: startDate must be before endDate", cerrors.ErrValidationFailed)
+	}
+	if len(currencies) == 0 {
+		for curr := range SeriesCodes {
+			currencies = append(currencies, curr)
+		}
+		sort.Strings(currencies)
+	}
+
+	codes := []string{}
+	for _, curr := range currencies {
+		if curr == "GBP" {
+			continue
+		}
+		code, ok := SeriesCodes[curr]
+		if !ok {
+			return nil, fmt.Errorf("%w: currency not published by the Bank of England: %s", cerrors.ErrValidationFailed, curr)
+		}
+		codes = append(codes, code)
+	}
+	if len(codes) == 0 {
+		return nil, nil
+	}
+
+	params := url.Values{}
+	params.Set("csv.x", "yes")
+	params.Set("Datefrom", startDate.Format(iadbDateFormat))
+	params.Set("Dateto", endDate.Format(iadbDateFormat))
+	params.Set("SeriesCodes", strings.Join(codes, ","))
+	params.Set("CSVF", "TN") // tabular, without titles
+	params.Set("UsingCodes", "Y")
+	params.Set("VPD", "Y")
+	params.Set("VFD", "N")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseUrl()+"/_iadb-fromshowcolumns.asp?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("c.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+	}
+
+	// errors, such as an invalid series code, are served as an HTML page with status 200
+	if strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		return nil, fmt.Errorf("%w: no CSV returned for these params", cerrors.ErrValidationFailed)
+	}
+
+	rates, err = ParseSpotRatesCsv(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ParseSpotRatesCsv failed: %w", err)
+	}
+
+	return rates, nil
+}
+
+// ParseSpotRatesCsv parses an IADB CSV of spot rate series into rates from GBP, ordered by day and currency
+// empty values, e.g. of UK bank holidays, are skipped
+func ParseSpotRatesCsv(r io.Reader) (rates []ratesource.Rate, err error) {
+
+	/* content looks like this:
+	DATE,XUDLUSS,XUDLERS
+	02 Jan 2024,1.2657,1.1516
+	03 Jan 2024,1.2652,1.1578
+	*/
+
+	currByCode := make(map[string]string, len(SeriesCodes))
+	for curr, code := range SeriesCodes {
+		currByCode[code] = curr
+	}
+
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: cr.Read (header) failed: %w", cerrors.ErrValidationFailed, err)
+	}
+	if len(header) < 2 || strings.TrimSpace(header[0]) != "DATE" {
+		return nil, fmt.Errorf("%w: unexpected header: %v", cerrors.ErrValidationFailed, header)
+	}
+
+	currs := make([]string, len(header))
+	for i, code := range header[1:] {
+		curr, ok := currByCode[strings.TrimSpace(code)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown series: %s", cerrors.ErrValidationFailed, code)
+		}
+		currs[i+1] = curr
+	}
+
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: cr.Read failed: %w", cerrors.ErrValidationFailed, err)
+		}
+
+		day, err := time.Parse(iadbCsvDateFormat, strings.TrimSpace(row[0]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid date '%s': %w", cerrors.ErrValidationFailed, row[0], err)
+		}
+
+		for i := 1; i < len(row); i++ {
+			val := strings.TrimSpace(row[i])
+			if val == "" {
+				continue
+			}
+			rate, err := strconv.ParseFloat(val, 64)
+			if err != nil || rate <= 0 {
+				return nil, fmt.Errorf("%w: %s %s: invalid rate '%s'", cerrors.ErrValidationFailed, row[0], currs[i], val)
+			}
+			rates = append(rates, ratesource.Rate{Day: day, Base: "GBP", Quote: currs[i], Rate: rate})
+		}
+	}
+
+	sort.SliceStable(rates, func(i, j int) bool {
+		if !rates[i].Day.Equal(rates[j].Day) {
+			return rates[i].Day.Before(rates[j].Day)
+		}
+		return rates[i].Quote < rates[j].Quote
+	})
+
+	return rates, nil
+}
+
+// SourceName returns the name of the Bank of England as a ratesource.Source
+func (c Client) SourceName() string {
+	return apiShortname
+}
+
+// GetRates returns the daily spot rates from base to quotes, as a ratesource.Source. The Bank of England quotes from GBP, so other bases are cross rates
+func (c Client) GetRates(ctx context.Context, base string, quotes []string, startDate, endDate time.Time) (rates []ratesource.Rate, err error) {
+
+	// the base is needed for cross rates. Quotes not published are skipped. All currencies are requested if quotes is empty
+	currencies := []string{}
+	if len(quotes) > 0 {
+		for _, curr := range append([]string{base}, quotes...) {
+			if _, ok := SeriesCodes[curr]; ok && !slices.Contains(currencies, curr) {
+				currencies = append(currencies, curr)
+			}
+		}
+		if len(currencies) == 0 {
+			return nil, nil
+		}
+	}
+
+	gbpRates, err := c.GetApiSpotRates(ctx, currencies, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("c.GetApiSpotRates failed: %w", err)
+	}
+
+	return ratesource.Rebase(gbpRates, base, quotes)
+}
@@ -0,0 +1,116 @@
+package coingeckoapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+)
+
+// Docs: https://docs.coingecko.com/v3.0.1/reference/introduction
+// the public API is rate limited per IP. A free demo API key raises the limit
+
+const (
+	apiShortname             string = "coingecko"
+	defaultBaseUrl           string = "https://api.coingecko.com/api/v3"
+	timeoutSecs              int    = 20
+	DefaultRequestsPerMinute int    = 10 // stays below the limit of the public API without a key
+	maxAttempts              int    = 3  // per request, if rate limited
+	defaultRetryAfter               = time.Minute
+)
+
+// copies of a Client share its rate limit
+type Client struct {
+	HttpClient *http.Client
+	BaseUrl    string // API root, e.g. of a fixture server in tests. Defaults to the public API
+	ApiKey     string // optional demo API key
+	InfoLog    *slog.Logger
+	ErrorLog   *slog.Logger
+
+	limiter *apiclient.Limiter
+}
+
+// NewClient returns a Client making at most requestsPerMinute requests. If requestsPerMinute < 1, DefaultRequestsPerMinute is used
+func NewClient(apiKey string, requestsPerMinute int, infoLog, errorLog *slog.Logger) (client Client) {
+
+	if requestsPerMinute < 1 {
+		requestsPerMinute = DefaultRequestsPerMinute
+	}
+
+	return Client{
+		HttpClient: &http.Client{
+			Timeout: time.Duration(timeoutSecs) * time.Second,
+		},
+		BaseUrl:  defaultBaseUrl,
+		ApiKey:   apiKey,
+		InfoLog:  infoLog.With("api", apiShortname),
+		ErrorLog: errorLog.With("api", apiShortname),
+		limiter:  apiclient.NewLimiter(time.Minute / time.Duration(requestsPerMinute)),
+	}
+}
+
+// baseUrl returns c.BaseUrl, or the public API if not set
+func (c Client) baseUrl() string {
+	if c.BaseUrl == "" {
+		return defaultBaseUrl
+	}
+	return strings.TrimSuffix(c.BaseUrl, "/")
+}
+
+// get requests path with params, waiting for the rate limit, and returns the response body
+// rate limited requests (429) are retried after the delay given by the API
+func (c Client) get(ctx context.Context, path string, params url.Values) (body []byte, err error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseUrl()+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	if c.ApiKey != "" {
+		if err = (apiclient.APIKeyHeader{Name: "x-cg-demo-api-key", Key: c.ApiKey}).Authorize(req); err != nil {
+			return nil, fmt.Errorf("Authorize failed: %w", err)
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("c.limiter.Wait failed: %w", err)
+		}
+
+		resp, err := c.HttpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("c.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+		}
+
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("io.ReadAll failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+		}
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			return body, nil
+		case http.StatusNotFound:
+			return nil, fmt.Errorf("%w: %s", cerrors.ErrNotFound, path)
+		case http.StatusBadRequest, http.StatusUnauthorized, http.StatusUnprocessableEntity:
+			return nil, fmt.Errorf("%w: status %d: %s", cerrors.ErrValidationFailed, resp.StatusCode, string(body))
+		case http.StatusTooManyRequests:
+			if attempt == maxAttempts {
+				return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+			}
+			retryAfter := apiclient.ParseRetryAfter(resp.Header.Get("Retry-After"), defaultRetryAfter)
+			c.InfoLog.Warn("rate limited, retrying", "path", path, "retry_after", retryAfter.String())
+			c.limiter.Pause(retryAfter)
+		default:
+			return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package coingeckoapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/coingecko/cgcoin"
+)
+
+// maxIdsPerRequest is the page size of the coins/markets endpoint
+const maxIdsPerRequest int = 250
+
+type Coin struct {
+	Id     string // e.g. bitcoin
+	Symbol string // upper case, e.g. BTC
+	Name   string
+}
+
+// GetApiCoins returns the coins with ids, requesting them in batches of up to 250
+// ids unknown to CoinGecko are not returned
+func (c Client) GetApiCoins(ctx context.Context, ids []string) (coins []Coin, err error) {
+
+	for start := 0; start < len(ids); start += maxIdsPerRequest {
+
+		batch := ids[start:min(start+maxIdsPerRequest, len(ids))]
+
+		params := url.Values{}
+		params.Add("vs_currency", "usd") // required, but prices are not used here
+		params.Add("ids", strings.Join(batch, ","))
+		params.Add("per_page", strconv.Itoa(maxIdsPerRequest))
+
+		body, err := c.get(ctx, "/coins/markets", params)
+		if err != nil {
+			return nil, fmt.Errorf("c.get failed: %w", err)
+		}
+
+		batchCoins, err := ParseMarketsJson(body)
+		if err != nil {
+			return nil, fmt.Errorf("ParseMarketsJson failed: %w", err)
+		}
+		coins = append(coins, batchCoins...)
+	}
+
+	return coins, nil
+}
+
+// ParseMarketsJson parses the response of the coins/markets endpoint
+func ParseMarketsJson(content []byte) (coins []Coin, err error) {
+
+	/* content looks like this (shortened):
+	[{"id":"bitcoin","symbol":"btc","name":"Bitcoin","current_price":62750,"market_cap":1237480000000,...}]
+	*/
+
+	respS := []struct {
+		Id     string `json:"id"`
+		Symbol string `json:"symbol"`
+		Name   string `json:"name"`
+	}{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, item := range respS {
+		if item.Id == "" || item.Symbol == "" {
+			return nil, fmt.Errorf("%w: coin without id or symbol", cerrors.ErrValidationFailed)
+		}
+		coins = append(coins, Coin{Id: item.Id, Symbol: strings.ToUpper(item.Symbol), Name: item.Name})
+	}
+
+	return coins, nil
+}
+
+// CoinsToMap converts API coins to store models with Code (the CoinGecko id) as key
+func CoinsToMap(apiItems []Coin) (itemsMap map[string]cgcoin.Model) {
+
+	itemsMap = make(map[string]cgcoin.Model)
+	for _, apiItem := range apiItems {
+		itemsMap[apiItem.Id] = cgcoin.Model{
+			Input: cgcoin.Input{
+				Code:   apiItem.Id,
+				Name:   apiItem.Name,
+				Symbol: apiItem.Symbol,
+			},
+		}
+	}
+
+	return itemsMap
+}
@@ -0,0 +1,190 @@
+package coingeckoapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/sink"
+	"github.com/loveyourstack/connectors/stores/coingecko/cgprice"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+type Price struct {
+	CoinId      string
+	VsCurrency  string // upper case, e.g. EUR
+	Date        string // YYYY-MM-DD (UTC)
+	Price       float64
+	MarketCap   float64
+	TotalVolume float64
+}
+
+// marketChartResponse is the response of the coins/{id}/market_chart endpoint. Each point is [unix ms, value]
+type marketChartResponse struct {
+	Prices       [][2]float64 `json:"prices"`
+	MarketCaps   [][2]float64 `json:"market_caps"`
+	TotalVolumes [][2]float64 `json:"total_volumes"`
+}
+
+// GetApiDailyPrices returns the daily prices of coin id in vsCurr for the last days days, including the current day
+func (c Client) GetApiDailyPrices(ctx context.Context, id, vsCurr string, days int) (prices []Price, err error) {
+
+	if days < 1 {
+		return nil, fmt.Errorf("%w: days must be at least 1", cerrors.ErrValidationFailed)
+	}
+
+	params := url.Values{}
+	params.Add("vs_currency", strings.ToLower(vsCurr))
+	params.Add("days", strconv.Itoa(days))
+	params.Add("interval", "daily")
+
+	body, err := c.get(ctx, "/coins/"+url.PathEscape(id)+"/market_chart", params)
+	if err != nil {
+		return nil, fmt.Errorf("c.get failed: %w", err)
+	}
+
+	prices, err = ParseMarketChartJson(id, vsCurr, body)
+	if err != nil {
+		return nil, fmt.Errorf("ParseMarketChartJson failed: %w", err)
+	}
+
+	return prices, nil
+}
+
+// ParseMarketChartJson parses a market_chart response of coin id in vsCurr into daily prices, ordered by day
+// if a day has several points, such as the current point besides the daily one, the last point of the day is used
+func ParseMarketChartJson(id, vsCurr string, content []byte) (prices []Price, err error) {
+
+	/* content looks like this:
+	{"prices":[[1717372800000,62750.1],[1717430122000,63012.4]],"market_caps":[[1717372800000,1237480000000],...],"total_volumes":[[1717372800000,18500000000],...]}
+	*/
+
+	respS := marketChartResponse{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	// market caps and volumes share the timestamps of the prices
+	marketCaps := pointsByMs(respS.MarketCaps)
+	volumes := pointsByMs(respS.TotalVolumes)
+
+	lastMsByDay := make(map[string]float64)
+	pricesByDay := make(map[string]Price)
+	for _, point := range respS.Prices {
+
+		ms := point[0]
+		day := time.UnixMilli(int64(ms)).UTC().Format(lystype.DateFormat)
+		if lastMs, ok := lastMsByDay[day]; ok && lastMs > ms {
+			continue
+		}
+		if point[1] <= 0 {
+			return nil, fmt.Errorf("%w: price must be positive on %s", cerrors.ErrValidationFailed, day)
+		}
+
+		lastMsByDay[day] = ms
+		pricesByDay[day] = Price{
+			CoinId:      id,
+			VsCurrency:  strings.ToUpper(vsCurr),
+			Date:        day,
+			Price:       point[1],
+			MarketCap:   marketCaps[ms],
+			TotalVolume: volumes[ms],
+		}
+	}
+
+	for _, price := range pricesByDay {
+		prices = append(prices, price)
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i].Date < prices[j].Date })
+
+	return prices, nil
+}
+
+// pointsByMs returns the values of points with their unix ms as key
+func pointsByMs(points [][2]float64) map[float64]float64 {
+
+	m := make(map[float64]float64, len(points))
+	for _, point := range points {
+		m[point[0]] = point[1]
+	}
+	return m
+}
+
+// PricesToMap converts API prices of a single coin and vs currency to store models with day as key, using coinFk as the coin db id
+func PricesToMap(apiItems []Price, coinFk int64) (itemsMap map[string]cgprice.Model, err error) {
+
+	itemsMap = make(map[string]cgprice.Model)
+	for _, apiItem := range apiItems {
+
+		day, err := time.Parse(lystype.DateFormat, apiItem.Date)
+		if err != nil {
+			return nil, fmt.Errorf("time.Parse failed for Date '%s': %w", apiItem.Date, err)
+		}
+
+		itemsMap[apiItem.Date] = cgprice.Model{
+			Input: cgprice.Input{
+				CoinFk:      coinFk,
+				Day:         lystype.Date(day),
+				MarketCap:   apiItem.MarketCap,
+				Price:       apiItem.Price,
+				TotalVolume: apiItem.TotalVolume,
+				VsCurrency:  apiItem.VsCurrency,
+			},
+		}
+	}
+
+	return itemsMap, nil
+}
+
+// GetApiIntradayPrices returns the intraday prices of coin id in vsCurr for the last days days as ticks with series "id/VSCURR"
+// granularity is set by the API: 5-minutely for 1 day, hourly for up to 90 days
+func (c Client) GetApiIntradayPrices(ctx context.Context, id, vsCurr string, days int) (ticks []sink.Tick, err error) {
+
+	if days < 1 || days > 90 {
+		return nil, fmt.Errorf("%w: days must be between 1 and 90", cerrors.ErrValidationFailed)
+	}
+
+	params := url.Values{}
+	params.Add("vs_currency", strings.ToLower(vsCurr))
+	params.Add("days", strconv.Itoa(days))
+
+	body, err := c.get(ctx, "/coins/"+url.PathEscape(id)+"/market_chart", params)
+	if err != nil {
+		return nil, fmt.Errorf("c.get failed: %w", err)
+	}
+
+	ticks, err = ParseMarketChartTicksJson(id, vsCurr, body)
+	if err != nil {
+		return nil, fmt.Errorf("ParseMarketChartTicksJson failed: %w", err)
+	}
+
+	return ticks, nil
+}
+
+// ParseMarketChartTicksJson parses a market_chart response of coin id in vsCurr into a tick per price point, with the total volume as volume
+func ParseMarketChartTicksJson(id, vsCurr string, content []byte) (ticks []sink.Tick, err error) {
+
+	respS := marketChartResponse{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	series := id + "/" + strings.ToUpper(vsCurr)
+	volumes := pointsByMs(respS.TotalVolumes)
+
+	for _, point := range respS.Prices {
+		at := time.UnixMilli(int64(point[0])).UTC()
+		if point[1] <= 0 {
+			return nil, fmt.Errorf("%w: price must be positive at %s", cerrors.ErrValidationFailed, at.Format(time.RFC3339))
+		}
+		ticks = append(ticks, sink.Tick{Series: series, At: at, Value: point[1], Volume: volumes[point[0]]})
+	}
+
+	return ticks, nil
+}
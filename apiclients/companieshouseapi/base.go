@@ -0,0 +1,180 @@
+package companieshouseapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+)
+
+// Docs: https://developer-specs.company-information.service.gov.uk
+// the REST and streaming APIs need separate keys, each sent as basic auth user name with an empty password
+
+const (
+	apiShortname      string = "companieshouse"
+	defaultBaseUrl    string = "https://api.company-information.service.gov.uk"
+	defaultStreamUrl  string = "https://stream.companieshouse.gov.uk"
+	timeoutSecs       int    = 20
+	requestsPerWindow int    = 600 // REST API rate limit
+	rateWindow               = 5 * time.Minute
+	maxAttempts       int    = 3 // per request, if rate limited
+	defaultRetryAfter        = time.Minute
+)
+
+// copies of a Client share its rate limit
+type Client struct {
+	HttpClient   *http.Client
+	StreamClient *http.Client // used for the streaming API, without a timeout: streams are bounded by their ctx
+	BaseUrl      string       // REST API root, e.g. of a fixture server in tests. Defaults to Companies House
+	StreamUrl    string       // streaming API root. Defaults to Companies House
+	ApiKey       string
+	StreamKey    string // optional: without it, changes are not streamed and profiles are requested instead
+	InfoLog      *slog.Logger
+	ErrorLog     *slog.Logger
+
+	limiter *apiclient.Limiter
+}
+
+func NewClient(apiKey, streamKey string, infoLog, errorLog *slog.Logger) (client Client) {
+
+	return Client{
+		HttpClient: &http.Client{
+			Timeout: time.Duration(timeoutSecs) * time.Second,
+		},
+		StreamClient: &http.Client{},
+		BaseUrl:      defaultBaseUrl,
+		StreamUrl:    defaultStreamUrl,
+		ApiKey:       apiKey,
+		StreamKey:    streamKey,
+		InfoLog:      infoLog.With("api", apiShortname),
+		ErrorLog:     errorLog.With("api", apiShortname),
+		limiter:      apiclient.NewLimiter(rateWindow / time.Duration(requestsPerWindow)),
+	}
+}
+
+// baseUrl returns c.BaseUrl, or Companies House if not set
+func (c Client) baseUrl() string {
+	if c.BaseUrl == "" {
+		return defaultBaseUrl
+	}
+	return strings.TrimSuffix(c.BaseUrl, "/")
+}
+
+// streamUrl returns c.StreamUrl, or Companies House if not set
+func (c Client) streamUrl() string {
+	if c.StreamUrl == "" {
+		return defaultStreamUrl
+	}
+	return strings.TrimSuffix(c.StreamUrl, "/")
+}
+
+// get requests path with params from the REST API, waiting for the rate limit, and returns the response body
+// rate limited requests (429) are retried after the delay given by the API
+func (c Client) get(ctx context.Context, path string, params url.Values) (body []byte, err error) {
+
+	if c.ApiKey == "" {
+		return nil, fmt.Errorf("%w: api key is required", cerrors.ErrValidationFailed)
+	}
+
+	reqUrl := c.baseUrl() + path
+	if len(params) > 0 {
+		reqUrl += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	if err = (apiclient.Basic{Username: c.ApiKey}).Authorize(req); err != nil {
+		return nil, fmt.Errorf("Authorize failed: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("c.limiter.Wait failed: %w", err)
+		}
+
+		resp, err := c.HttpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("c.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+		}
+
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("io.ReadAll failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+		}
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			return body, nil
+		case http.StatusNotFound:
+			return nil, fmt.Errorf("%w: %s", cerrors.ErrNotFound, path)
+		case http.StatusBadRequest, http.StatusUnauthorized:
+			return nil, fmt.Errorf("%w: status %d: %s", cerrors.ErrValidationFailed, resp.StatusCode, string(body))
+		case http.StatusTooManyRequests:
+			if attempt == maxAttempts {
+				return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+			}
+			retryAfter := apiclient.ParseRetryAfter(resp.Header.Get("Retry-After"), defaultRetryAfter)
+			c.InfoLog.Warn("rate limited, retrying", "path", path, "retry_after", retryAfter.String())
+			c.limiter.Pause(retryAfter)
+		default:
+			return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+		}
+	}
+}
+
+// openStream opens the streaming API stream path from timepoint, or from the current position if timepoint is 0
+// the stream stays open until ctx is done or the caller closes the body
+func (c Client) openStream(ctx context.Context, path string, timepoint int64) (body io.ReadCloser, err error) {
+
+	if c.StreamKey == "" {
+		return nil, fmt.Errorf("%w: stream key is required", cerrors.ErrValidationFailed)
+	}
+
+	reqUrl := c.streamUrl() + path
+	if timepoint > 0 {
+		reqUrl += "?timepoint=" + strconv.FormatInt(timepoint, 10)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	if err = (apiclient.Basic{Username: c.StreamKey}).Authorize(req); err != nil {
+		return nil, fmt.Errorf("Authorize failed: %w", err)
+	}
+
+	client := c.StreamClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("client.Do failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		// timepoint is older than the stream's history
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: timepoint %d is no longer available", ErrTimepointExpired, timepoint)
+	case http.StatusUnauthorized:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: status %d", cerrors.ErrValidationFailed, resp.StatusCode)
+	default:
+		resp.Body.Close()
+		return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+	}
+}
@@ -0,0 +1,173 @@
+package companieshouseapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/companieshouse/chcompany"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+type Company struct {
+	Number       string
+	Name         string
+	Status       string
+	Type         string
+	Jurisdiction string
+	CreatedOn    string // YYYY-MM-DD, or empty
+	CeasedOn     string // YYYY-MM-DD, or empty
+	Address      Address
+	SicCodes     []string
+	Etag         string
+}
+
+// Address is a registered office address
+type Address struct {
+	Line       string // first address line, prefixed by the premises if any
+	Locality   string
+	PostalCode string
+	Country    string
+}
+
+// companyProfile is the company profile resource, returned by the company endpoint and contained in stream events
+type companyProfile struct {
+	CompanyNumber           string `json:"company_number"`
+	CompanyName             string `json:"company_name"`
+	CompanyStatus           string `json:"company_status"`
+	Type                    string `json:"type"`
+	Jurisdiction            string `json:"jurisdiction"`
+	DateOfCreation          string `json:"date_of_creation"`
+	DateOfCessation         string `json:"date_of_cessation"`
+	RegisteredOfficeAddress struct {
+		Premises     string `json:"premises"`
+		AddressLine1 string `json:"address_line_1"`
+		Locality     string `json:"locality"`
+		PostalCode   string `json:"postal_code"`
+		Country      string `json:"country"`
+	} `json:"registered_office_address"`
+	SicCodes []string `json:"sic_codes"`
+	Etag     string   `json:"etag"`
+}
+
+// GetApiCompanies returns the profiles of the companies with numbers. Unknown numbers are returned in notFound
+// a request is made per company, so large lists take minutes at the rate limit
+func (c Client) GetApiCompanies(ctx context.Context, numbers []string) (companies []Company, notFound []string, err error) {
+
+	for _, number := range numbers {
+
+		body, err := c.get(ctx, "/company/"+number, nil)
+		if errors.Is(err, cerrors.ErrNotFound) {
+			notFound = append(notFound, number)
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("c.get failed for %s: %w", number, err)
+		}
+
+		company, err := ParseCompanyJson(body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ParseCompanyJson failed for %s: %w", number, err)
+		}
+		companies = append(companies, company)
+	}
+
+	return companies, notFound, nil
+}
+
+// ParseCompanyJson parses a company profile resource
+func ParseCompanyJson(content []byte) (company Company, err error) {
+
+	profile := companyProfile{}
+	if err = json.Unmarshal(content, &profile); err != nil {
+		return Company{}, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	return profile.toCompany()
+}
+
+func (p companyProfile) toCompany() (company Company, err error) {
+
+	if p.CompanyNumber == "" || p.CompanyName == "" {
+		return Company{}, fmt.Errorf("%w: profile without company number or name", cerrors.ErrValidationFailed)
+	}
+	for _, d := range []string{p.DateOfCreation, p.DateOfCessation} {
+		if _, err := parseOptionalDate(d); err != nil {
+			return Company{}, fmt.Errorf("%s: %w", p.CompanyNumber, err)
+		}
+	}
+
+	addr := p.RegisteredOfficeAddress
+	line := strings.TrimSpace(addr.Premises + " " + addr.AddressLine1)
+
+	sicCodes := p.SicCodes
+	if sicCodes == nil {
+		sicCodes = []string{}
+	}
+
+	return Company{
+		Number:       p.CompanyNumber,
+		Name:         p.CompanyName,
+		Status:       p.CompanyStatus,
+		Type:         p.Type,
+		Jurisdiction: p.Jurisdiction,
+		CreatedOn:    p.DateOfCreation,
+		CeasedOn:     p.DateOfCessation,
+		Address:      Address{Line: line, Locality: addr.Locality, PostalCode: addr.PostalCode, Country: addr.Country},
+		SicCodes:     sicCodes,
+		Etag:         p.Etag,
+	}, nil
+}
+
+// parseOptionalDate parses a YYYY-MM-DD date. An empty string returns nil
+func parseOptionalDate(s string) (*lystype.Date, error) {
+
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(lystype.DateFormat, s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid date '%s': %w", cerrors.ErrValidationFailed, s, err)
+	}
+	d := lystype.Date(t)
+	return &d, nil
+}
+
+// CompaniesToMap converts companies to a map with CompanyNumber as key
+func CompaniesToMap(companies []Company) (itemsMap map[string]chcompany.Model, err error) {
+
+	itemsMap = make(map[string]chcompany.Model)
+	for _, co := range companies {
+
+		createdOn, err := parseOptionalDate(co.CreatedOn)
+		if err != nil {
+			return nil, fmt.Errorf("parseOptionalDate failed for %s: %w", co.Number, err)
+		}
+		ceasedOn, err := parseOptionalDate(co.CeasedOn)
+		if err != nil {
+			return nil, fmt.Errorf("parseOptionalDate failed for %s: %w", co.Number, err)
+		}
+
+		itemsMap[co.Number] = chcompany.Model{Input: chcompany.Input{
+			CompanyNumber:     co.Number,
+			CompanyName:       co.Name,
+			CompanyStatus:     co.Status,
+			CompanyType:       co.Type,
+			Jurisdiction:      co.Jurisdiction,
+			CreatedOn:         createdOn,
+			CeasedOn:          ceasedOn,
+			AddressLine:       co.Address.Line,
+			AddressLocality:   co.Address.Locality,
+			AddressPostalCode: co.Address.PostalCode,
+			AddressCountry:    co.Address.Country,
+			SicCodes:          co.SicCodes,
+			Etag:              co.Etag,
+		}}
+	}
+
+	return itemsMap, nil
+}
@@ -0,0 +1,138 @@
+package companieshouseapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+	"github.com/loveyourstack/connectors/stores/companieshouse/chofficer"
+)
+
+const officersPageSize int = 100 // maximum of the officers endpoint
+
+type Officer struct {
+	OfficerId          string // from the officer's appointments link, e.g. /officers/{id}/appointments
+	Role               string
+	Name               string
+	AppointedOn        string // YYYY-MM-DD, or empty
+	ResignedOn         string // YYYY-MM-DD, or empty
+	Nationality        string
+	Occupation         string
+	CountryOfResidence string
+}
+
+// officersResponse is the response of the officers endpoint
+type officersResponse struct {
+	Items []struct {
+		Name               string `json:"name"`
+		OfficerRole        string `json:"officer_role"`
+		AppointedOn        string `json:"appointed_on"`
+		ResignedOn         string `json:"resigned_on"`
+		Nationality        string `json:"nationality"`
+		Occupation         string `json:"occupation"`
+		CountryOfResidence string `json:"country_of_residence"`
+		Links              struct {
+			Officer struct {
+				Appointments string `json:"appointments"`
+			} `json:"officer"`
+		} `json:"links"`
+	} `json:"items"`
+	TotalResults int `json:"total_results"`
+}
+
+// GetApiOfficers returns the current and resigned officers of company number, requested in pages of officersPageSize
+func (c Client) GetApiOfficers(ctx context.Context, number string) (officers []Officer, err error) {
+
+	err = apiclient.Offsets(ctx, apiclient.Paging{Log: c.InfoLog}, officersPageSize,
+		func(start int) ([]byte, error) {
+			params := url.Values{}
+			params.Add("items_per_page", strconv.Itoa(officersPageSize))
+			params.Add("start_index", strconv.Itoa(start))
+			return c.get(ctx, "/company/"+number+"/officers", params)
+		},
+		func(body []byte) (bool, error) {
+			page, total, err := ParseOfficersJson(body)
+			if err != nil {
+				return false, fmt.Errorf("ParseOfficersJson failed: %w", err)
+			}
+			officers = append(officers, page...)
+			return len(page) > 0 && len(officers) < total, nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("apiclient.Offsets failed: %w", err)
+	}
+
+	return officers, nil
+}
+
+// ParseOfficersJson parses a page of the officers endpoint, returning its officers and the total number of officers
+func ParseOfficersJson(content []byte) (officers []Officer, total int, err error) {
+
+	respS := officersResponse{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, 0, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, item := range respS.Items {
+
+		// e.g. /officers/abcDEF123/appointments
+		linkA := strings.Split(strings.Trim(item.Links.Officer.Appointments, "/"), "/")
+		if len(linkA) < 2 || linkA[0] != "officers" {
+			return nil, 0, fmt.Errorf("%w: unexpected appointments link '%s' of %s", cerrors.ErrValidationFailed, item.Links.Officer.Appointments, item.Name)
+		}
+		for _, d := range []string{item.AppointedOn, item.ResignedOn} {
+			if _, err := parseOptionalDate(d); err != nil {
+				return nil, 0, fmt.Errorf("%s: %w", item.Name, err)
+			}
+		}
+
+		officers = append(officers, Officer{
+			OfficerId:          linkA[1],
+			Role:               item.OfficerRole,
+			Name:               item.Name,
+			AppointedOn:        item.AppointedOn,
+			ResignedOn:         item.ResignedOn,
+			Nationality:        item.Nationality,
+			Occupation:         item.Occupation,
+			CountryOfResidence: item.CountryOfResidence,
+		})
+	}
+
+	return officers, respS.TotalResults, nil
+}
+
+// OfficersToMap converts the officers of companyFk to a map with officerId+role+appointedOn as key
+func OfficersToMap(officers []Officer, companyFk int64) (itemsMap map[string]chofficer.Model, err error) {
+
+	itemsMap = make(map[string]chofficer.Model)
+	for _, o := range officers {
+
+		appointedOn, err := parseOptionalDate(o.AppointedOn)
+		if err != nil {
+			return nil, fmt.Errorf("parseOptionalDate failed for %s: %w", o.Name, err)
+		}
+		resignedOn, err := parseOptionalDate(o.ResignedOn)
+		if err != nil {
+			return nil, fmt.Errorf("parseOptionalDate failed for %s: %w", o.Name, err)
+		}
+
+		itemsMap[chofficer.NaturalKey(o.OfficerId, o.Role, appointedOn)] = chofficer.Model{Input: chofficer.Input{
+			CompanyFk:          companyFk,
+			OfficerId:          o.OfficerId,
+			OfficerRole:        o.Role,
+			Name:               o.Name,
+			AppointedOn:        appointedOn,
+			ResignedOn:         resignedOn,
+			Nationality:        o.Nationality,
+			Occupation:         o.Occupation,
+			CountryOfResidence: o.CountryOfResidence,
+		}}
+	}
+
+	return itemsMap, nil
+}
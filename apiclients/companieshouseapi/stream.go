@@ -0,0 +1,120 @@
+package companieshouseapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// ErrTimepointExpired is returned if a stream is resumed from a timepoint older than its history. The data must then be requested in full
+var ErrTimepointExpired = errors.New("stream timepoint expired")
+
+// event types of stream events
+const (
+	EventChanged string = "changed"
+	EventDeleted string = "deleted"
+)
+
+// CompanyChange is an event of the companies stream
+type CompanyChange struct {
+	Timepoint int64
+	Type      string // EventChanged or EventDeleted
+	Number    string
+	Company   Company // empty if deleted
+}
+
+// streamEvent is a line of the companies stream
+type streamEvent struct {
+	ResourceId string          `json:"resource_id"`
+	Data       json.RawMessage `json:"data"`
+	Event      struct {
+		Timepoint   int64  `json:"timepoint"`
+		PublishedAt string `json:"published_at"`
+		Type        string `json:"type"`
+	} `json:"event"`
+}
+
+// ReadCompanyChanges reads the companies stream from timepoint (from the current position if 0) for duration, returning the changes of companies for which keep returns true
+// lastTimepoint is the timepoint of the last event read, from which the next read should resume. It is timepoint if no event was read
+func (c Client) ReadCompanyChanges(ctx context.Context, timepoint int64, duration time.Duration, keep func(number string) bool) (changes []CompanyChange, lastTimepoint int64, err error) {
+
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	body, err := c.openStream(ctx, "/companies", timepoint)
+	if err != nil {
+		return nil, timepoint, fmt.Errorf("c.openStream failed: %w", err)
+	}
+	defer body.Close()
+
+	lastTimepoint = timepoint
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+
+		// blank lines are heartbeats
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		change, err := ParseCompanyEvent(line)
+		if err != nil {
+			return nil, lastTimepoint, fmt.Errorf("ParseCompanyEvent failed after timepoint %d: %w", lastTimepoint, err)
+		}
+		lastTimepoint = change.Timepoint
+
+		if keep == nil || keep(change.Number) {
+			changes = append(changes, change)
+		}
+	}
+
+	// the stream only ends when the read duration has elapsed, or ctx is cancelled by the caller
+	if err = scanner.Err(); err != nil && ctx.Err() == nil {
+		return nil, lastTimepoint, fmt.Errorf("scanner.Err: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+
+	return changes, lastTimepoint, nil
+}
+
+// ParseCompanyEvent parses a line of the companies stream
+func ParseCompanyEvent(line []byte) (change CompanyChange, err error) {
+
+	/* a line looks like this:
+	{"resource_kind":"company-profile","resource_uri":"/company/00000006","resource_id":"00000006","data":{...company profile...},
+	 "event":{"timepoint":12345678,"published_at":"2024-06-03T10:15:00","type":"changed"}}
+	*/
+
+	ev := streamEvent{}
+	if err = json.Unmarshal(line, &ev); err != nil {
+		return CompanyChange{}, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+	if ev.Event.Timepoint < 1 || ev.ResourceId == "" {
+		return CompanyChange{}, fmt.Errorf("%w: event without timepoint or resource id", cerrors.ErrValidationFailed)
+	}
+
+	change = CompanyChange{Timepoint: ev.Event.Timepoint, Type: ev.Event.Type, Number: ev.ResourceId}
+
+	switch ev.Event.Type {
+	case EventDeleted:
+		return change, nil
+	case EventChanged:
+		profile := companyProfile{}
+		if err = json.Unmarshal(ev.Data, &profile); err != nil {
+			return CompanyChange{}, fmt.Errorf("%w: json.Unmarshal failed on data of %s: %w", cerrors.ErrValidationFailed, ev.ResourceId, err)
+		}
+		if change.Company, err = profile.toCompany(); err != nil {
+			return CompanyChange{}, fmt.Errorf("profile.toCompany failed: %w", err)
+		}
+		return change, nil
+	default:
+		return CompanyChange{}, fmt.Errorf("%w: unknown event type '%s' of %s", cerrors.ErrValidationFailed, ev.Event.Type, ev.ResourceId)
+	}
+}
@@ -0,0 +1,55 @@
+package dhlapi
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Docs: https://developer.dhl.com/api-reference/shipment-tracking
+// an API key is needed. The default plan allows 250 requests per day, at most 1 per second
+
+const (
+	apiShortname   string = "dhl"
+	defaultBaseUrl string = "https://api-eu.dhl.com/track"
+	timeoutSecs    int    = 20
+
+	// Carrier is the carrier name under which DHL shipments are stored
+	Carrier string = "dhl"
+)
+
+type Client struct {
+	HttpClient *http.Client
+	BaseUrl    string // API root, e.g. of a fixture server in tests. Defaults to DHL
+	ApiKey     string
+	Language   string // ISO 639-1 language of status texts, e.g. "de". Defaults to English
+	InfoLog    *slog.Logger
+	ErrorLog   *slog.Logger
+}
+
+func NewClient(apiKey string, infoLog, errorLog *slog.Logger) (client Client) {
+
+	return Client{
+		HttpClient: &http.Client{
+			Timeout: time.Duration(timeoutSecs) * time.Second,
+		},
+		BaseUrl:  defaultBaseUrl,
+		ApiKey:   apiKey,
+		InfoLog:  infoLog.With("api", apiShortname),
+		ErrorLog: errorLog.With("api", apiShortname),
+	}
+}
+
+// baseUrl returns c.BaseUrl, or DHL if not set
+func (c Client) baseUrl() string {
+	if c.BaseUrl == "" {
+		return defaultBaseUrl
+	}
+	return strings.TrimSuffix(c.BaseUrl, "/")
+}
+
+// Carrier returns the carrier name of DHL shipments
+func (c Client) Carrier() string {
+	return Carrier
+}
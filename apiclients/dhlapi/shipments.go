@@ -0,0 +1,271 @@
+package dhlapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+	"github.com/loveyourstack/connectors/stores/shipment/trackedshipment"
+	"github.com/loveyourstack/connectors/stores/shipment/trackingevent"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+type Shipment struct {
+	TrackingNumber string
+	Service        string // e.g. express, parcel-de
+	Origin         string
+	Destination    string
+	Status         Event // latest status
+	Events         []Event
+}
+
+type Event struct {
+	At          time.Time
+	StatusCode  string // pre-transit, transit, delivered, failure or unknown
+	Status      string
+	Description string
+	Location    string
+}
+
+// apiShipments is the response of the shipments endpoint
+type apiShipments struct {
+	Shipments []struct {
+		Id          string     `json:"id"`
+		Service     string     `json:"service"`
+		Origin      apiPlace   `json:"origin"`
+		Destination apiPlace   `json:"destination"`
+		Status      apiEvent   `json:"status"`
+		Events      []apiEvent `json:"events"`
+	} `json:"shipments"`
+}
+
+type apiPlace struct {
+	Address struct {
+		CountryCode     string `json:"countryCode"`
+		PostalCode      string `json:"postalCode"`
+		AddressLocality string `json:"addressLocality"`
+	} `json:"address"`
+}
+
+type apiEvent struct {
+	Timestamp   string   `json:"timestamp"`
+	Location    apiPlace `json:"location"`
+	StatusCode  string   `json:"statusCode"`
+	Status      string   `json:"status"`
+	Description string   `json:"description"`
+}
+
+// GetApiShipment returns the tracking data of trackingNumber
+// cerrors.ErrNotFound is returned if DHL does not know it (yet): shipments are often registered before the carrier has scanned them
+func (c Client) GetApiShipment(ctx context.Context, trackingNumber string) (shipment Shipment, err error) {
+
+	params := url.Values{}
+	params.Set("trackingNumber", trackingNumber)
+	if c.Language != "" {
+		params.Set("language", c.Language)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseUrl()+"/shipments?"+params.Encode(), nil)
+	if err != nil {
+		return Shipment{}, fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	if err = (apiclient.APIKeyHeader{Name: "DHL-API-Key", Key: c.ApiKey}).Authorize(req); err != nil {
+		return Shipment{}, fmt.Errorf("Authorize failed: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return Shipment{}, fmt.Errorf("c.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return Shipment{}, fmt.Errorf("%w: tracking number %s", cerrors.ErrNotFound, trackingNumber)
+	case http.StatusUnauthorized:
+		return Shipment{}, fmt.Errorf("%w: status %d: pls check the API key", cerrors.ErrValidationFailed, resp.StatusCode)
+	default:
+		// including 429 when the daily quota is used up
+		return Shipment{}, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Shipment{}, fmt.Errorf("io.ReadAll failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+
+	shipment, err = ParseShipmentsJson(body, trackingNumber)
+	if err != nil {
+		return Shipment{}, fmt.Errorf("ParseShipmentsJson failed: %w", err)
+	}
+
+	return shipment, nil
+}
+
+// ParseShipmentsJson parses the response of the shipments endpoint, returning the shipment with trackingNumber, or the first if none has it
+func ParseShipmentsJson(content []byte, trackingNumber string) (shipment Shipment, err error) {
+
+	/* content looks like this:
+	{"shipments":[{"id":"00340434161094042557","service":"parcel-de",
+	  "origin":{"address":{"countryCode":"DE","addressLocality":"Bonn"}},"destination":{"address":{"countryCode":"DE","postalCode":"53113","addressLocality":"Bonn"}},
+	  "status":{"timestamp":"2024-06-04T09:12:00+02:00","location":{"address":{"addressLocality":"Bonn"}},"statusCode":"delivered","status":"DELIVERED","description":"The shipment has been delivered."},
+	  "events":[{"timestamp":"2024-06-04T09:12:00+02:00","location":{...},"statusCode":"delivered","status":"DELIVERED","description":"..."}, ...]}]}
+	*/
+
+	respS := apiShipments{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return Shipment{}, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+	if len(respS.Shipments) == 0 {
+		return Shipment{}, fmt.Errorf("%w: tracking number %s", cerrors.ErrNotFound, trackingNumber)
+	}
+
+	apiShp := respS.Shipments[0]
+	for _, s := range respS.Shipments {
+		if s.Id == trackingNumber {
+			apiShp = s
+			break
+		}
+	}
+
+	shipment = Shipment{
+		TrackingNumber: apiShp.Id,
+		Service:        apiShp.Service,
+		Origin:         apiShp.Origin.String(),
+		Destination:    apiShp.Destination.String(),
+	}
+
+	if shipment.Status, err = apiShp.Status.toEvent(); err != nil {
+		return Shipment{}, fmt.Errorf("status of %s: %w", apiShp.Id, err)
+	}
+	for _, apiEv := range apiShp.Events {
+		ev, err := apiEv.toEvent()
+		if err != nil {
+			return Shipment{}, fmt.Errorf("event of %s: %w", apiShp.Id, err)
+		}
+		shipment.Events = append(shipment.Events, ev)
+	}
+
+	return shipment, nil
+}
+
+// String returns the place as "locality, postal code, country code", omitting empty parts
+func (p apiPlace) String() string {
+
+	parts := []string{}
+	for _, s := range []string{p.Address.AddressLocality, p.Address.PostalCode, p.Address.CountryCode} {
+		if s = strings.TrimSpace(s); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (e apiEvent) toEvent() (ev Event, err error) {
+
+	ev = Event{
+		StatusCode:  normalizeStatusCode(e.StatusCode),
+		Status:      e.Status,
+		Description: e.Description,
+		Location:    e.Location.String(),
+	}
+	if e.Timestamp != "" {
+		if ev.At, err = parseTimestamp(e.Timestamp); err != nil {
+			return Event{}, err
+		}
+	}
+
+	return ev, nil
+}
+
+// parseTimestamp parses a DHL timestamp, which has a zone offset for most services, and is UTC without one
+func parseTimestamp(s string) (time.Time, error) {
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: invalid timestamp '%s'", cerrors.ErrValidationFailed, s)
+}
+
+// normalizeStatusCode returns statusCode if it is one of the status codes of trackedshipment, or trackedshipment.StatusUnknown
+func normalizeStatusCode(statusCode string) string {
+
+	switch statusCode {
+	case trackedshipment.StatusPreTransit, trackedshipment.StatusTransit, trackedshipment.StatusDelivered, trackedshipment.StatusFailure:
+		return statusCode
+	default:
+		return trackedshipment.StatusUnknown
+	}
+}
+
+// TrackShipment returns the tracking data of trackingNumber as store inputs. The ShipmentFk of events is not set
+func (c Client) TrackShipment(ctx context.Context, trackingNumber string) (shipment trackedshipment.Input, events []trackingevent.Input, err error) {
+
+	apiShp, err := c.GetApiShipment(ctx, trackingNumber)
+	if err != nil {
+		return trackedshipment.Input{}, nil, fmt.Errorf("c.GetApiShipment failed: %w", err)
+	}
+
+	shipment, events = ShipmentToInputs(apiShp)
+	shipment.TrackingNumber = trackingNumber
+
+	return shipment, events, nil
+}
+
+// ShipmentToInputs converts shipment to store inputs. Events without a timestamp are skipped
+func ShipmentToInputs(shipment Shipment) (shpInput trackedshipment.Input, evInputs []trackingevent.Input) {
+
+	shpInput = trackedshipment.Input{
+		Carrier:        Carrier,
+		TrackingNumber: shipment.TrackingNumber,
+		Service:        shipment.Service,
+		Origin:         shipment.Origin,
+		Destination:    shipment.Destination,
+		StatusCode:     shipment.Status.StatusCode,
+		Status:         shipment.Status.Status,
+		StatusAt:       optionalDatetime(shipment.Status.At),
+	}
+	if shipment.Status.StatusCode == trackedshipment.StatusDelivered {
+		// delivered_at ends the polling, so it is set even if DHL gives no timestamp
+		deliveredAt := shipment.Status.At
+		if deliveredAt.IsZero() {
+			deliveredAt = time.Now()
+		}
+		shpInput.DeliveredAt = optionalDatetime(deliveredAt)
+	}
+
+	evInputs = []trackingevent.Input{}
+	for _, ev := range shipment.Events {
+		if ev.At.IsZero() {
+			continue
+		}
+		evInputs = append(evInputs, trackingevent.Input{
+			EventAt:     lystype.Datetime(ev.At),
+			StatusCode:  ev.StatusCode,
+			Status:      ev.Status,
+			Description: ev.Description,
+			Location:    ev.Location,
+		})
+	}
+
+	return shpInput, evInputs
+}
+
+func optionalDatetime(t time.Time) *lystype.Datetime {
+	if t.IsZero() {
+		return nil
+	}
+	dt := lystype.Datetime(t)
+	return &dt
+}
@@ -0,0 +1,61 @@
+package ebayapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+)
+
+var (
+	// DefaultAppScopes are requested for application tokens
+	DefaultAppScopes = []string{"https://api.ebay.com/oauth/api_scope"}
+
+	// DefaultUserScopes are requested for user tokens: read access to the seller's inventory and offers
+	DefaultUserScopes = []string{"https://api.ebay.com/oauth/api_scope/sell.inventory.readonly"}
+)
+
+// auth returns the strategy authorizing requests: the bearer token of the OAuth access token, requested when needed. Clients not created by NewClient don't cache the token
+func (c Client) auth() apiclient.Auth {
+	return apiclient.TokenAuth{Cache: c.token, Request: c.requestAccessToken}
+}
+
+// requestAccessToken requests an access token at the OAuth token endpoint, authenticating with the client credentials
+// a user token is requested with the refresh token grant if c.RefreshToken is set, otherwise an application token with the client credentials grant
+func (c Client) requestAccessToken(ctx context.Context) (apiclient.Token, error) {
+
+	if c.ClientId == "" || c.ClientSecret == "" {
+		return apiclient.Token{}, fmt.Errorf("%w: client id and client secret are required", cerrors.ErrValidationFailed)
+	}
+
+	scopes := c.Scopes
+	if len(scopes) == 0 {
+		scopes = DefaultAppScopes
+		if c.RefreshToken != "" {
+			scopes = DefaultUserScopes
+		}
+	}
+
+	oauth := apiclient.OAuth2{
+		HttpClient:   c.HttpClient,
+		TokenUrl:     c.baseUrl() + "/identity/v1/oauth2/token",
+		ClientId:     c.ClientId,
+		ClientSecret: c.ClientSecret,
+		RefreshToken: c.RefreshToken,
+		Scopes:       scopes,
+		BasicAuth:    true,
+		Source:       apiShortname,
+	}
+	return oauth.RequestToken(ctx)
+}
+
+// ParseTokenJson parses the response of the OAuth token endpoint
+func ParseTokenJson(content []byte) (token string, expiresIn time.Duration, err error) {
+
+	// content looks like this: {"access_token":"v^1.1#i^1#p^3#r^1...","expires_in":7200,"token_type":"User Access Token"}
+
+	t, err := apiclient.ParseTokenJson(content)
+	return t.Value, t.ExpiresIn, err
+}
@@ -0,0 +1,177 @@
+package ebayapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+)
+
+// Docs: https://developer.ebay.com/api-docs/sell/inventory/overview.html
+// requests are authorized with an OAuth access token, requested with the app's client credentials
+// the Inventory API only accepts user access tokens, which need the refresh token of the seller's consent to the app
+
+const (
+	apiShortname      string = "ebay"
+	defaultBaseUrl    string = "https://api.ebay.com"
+	sandboxBaseUrl    string = "https://api.sandbox.ebay.com"
+	timeoutSecs       int    = 30
+	pageSize          int    = 100                    // maximum of the inventory items endpoint is 200
+	requestInterval          = 100 * time.Millisecond // well within the daily call limit of the Inventory API
+	maxAttempts       int    = 3                      // per request, if rate limited
+	defaultRetryAfter        = 5 * time.Second
+)
+
+// copies of a Client share its access token and rate limit
+type Client struct {
+	HttpClient   *http.Client
+	BaseUrl      string // API root, e.g. of a fixture server in tests. Defaults to eBay production, or the sandbox if Sandbox is set
+	Sandbox      bool
+	ClientId     string // OAuth credentials of the app
+	ClientSecret string
+	RefreshToken string   // of the seller's consent to the app. Without it, an application token is requested with the client credentials grant
+	Scopes       []string // defaults to DefaultUserScopes, or DefaultAppScopes without RefreshToken
+	InfoLog      *slog.Logger
+	ErrorLog     *slog.Logger
+
+	token   *apiclient.TokenCache
+	limiter *apiclient.Limiter
+}
+
+func NewClient(clientId, clientSecret, refreshToken string, infoLog, errorLog *slog.Logger) (client Client) {
+
+	return Client{
+		HttpClient: &http.Client{
+			Timeout: time.Duration(timeoutSecs) * time.Second,
+		},
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		RefreshToken: refreshToken,
+		InfoLog:      infoLog.With("api", apiShortname),
+		ErrorLog:     errorLog.With("api", apiShortname),
+		token:        &apiclient.TokenCache{},
+		limiter:      apiclient.NewLimiter(requestInterval),
+	}
+}
+
+// baseUrl returns c.BaseUrl, or the eBay production or sandbox root if not set
+func (c Client) baseUrl() string {
+	if c.BaseUrl != "" {
+		return strings.TrimSuffix(c.BaseUrl, "/")
+	}
+	if c.Sandbox {
+		return sandboxBaseUrl
+	}
+	return defaultBaseUrl
+}
+
+// apiErrors is the error response of the Sell APIs
+type apiErrors struct {
+	Errors []struct {
+		ErrorId int    `json:"errorId"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (e apiErrors) String() string {
+	msgs := []string{}
+	for _, err := range e.Errors {
+		msgs = append(msgs, strconv.Itoa(err.ErrorId)+": "+err.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// page is the paging envelope of the list endpoints
+type page struct {
+	Next  string `json:"next"` // URL of the next page, absent on the last page
+	Total int    `json:"total"`
+}
+
+// getAll requests all pages of the list endpoint path with params, following the next URLs of the responses, and calls pageFunc with each page's body
+func (c Client) getAll(ctx context.Context, path string, params url.Values, pageFunc func(body []byte) error) error {
+
+	params.Set("limit", strconv.Itoa(pageSize))
+	params.Set("offset", "0")
+	firstUrl := c.baseUrl() + path + "?" + params.Encode()
+
+	return apiclient.Cursor(ctx, apiclient.Paging{Log: c.InfoLog},
+		func(nextUrl string) ([]byte, error) {
+			if nextUrl == "" {
+				return c.get(ctx, firstUrl)
+			}
+			return c.get(ctx, nextUrl)
+		},
+		func(body []byte) (string, error) {
+			if err := pageFunc(body); err != nil {
+				return "", fmt.Errorf("pageFunc failed: %w", err)
+			}
+
+			p := page{}
+			if err := json.Unmarshal(body, &p); err != nil {
+				return "", fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+			}
+			return p.Next, nil
+		})
+}
+
+// get requests reqUrl with a user or application access token, waiting for the rate limit, and returns the response body
+// rate limited requests (429) are retried after the delay given by the API
+func (c Client) get(ctx context.Context, reqUrl string) (body []byte, err error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	if err = c.auth().Authorize(req); err != nil {
+		return nil, fmt.Errorf("c.auth().Authorize failed: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	for attempt := 1; ; attempt++ {
+
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("c.limiter.Wait failed: %w", err)
+		}
+
+		resp, err := c.HttpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("c.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+		}
+
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("io.ReadAll failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return body, nil
+		case resp.StatusCode == http.StatusNotFound:
+			return nil, fmt.Errorf("%w: %s", cerrors.ErrNotFound, req.URL.Path)
+		case resp.StatusCode == http.StatusTooManyRequests:
+			if attempt == maxAttempts {
+				return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+			}
+			retryAfter := apiclient.ParseRetryAfter(resp.Header.Get("Retry-After"), defaultRetryAfter)
+			c.InfoLog.Warn("rate limited, retrying", "path", req.URL.Path, "retry_after", retryAfter.String())
+			c.limiter.Pause(retryAfter)
+		case resp.StatusCode >= 400 && resp.StatusCode < 500:
+			// e.g. 400 invalid input, 401 invalid token, 403 missing scope
+			errS := apiErrors{}
+			_ = json.Unmarshal(body, &errS)
+			return nil, fmt.Errorf("%w: status %d: %s", cerrors.ErrValidationFailed, resp.StatusCode, errS.String())
+		default:
+			return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+		}
+	}
+}
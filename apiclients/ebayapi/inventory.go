@@ -0,0 +1,297 @@
+package ebayapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/ebay/ebayitem"
+	"github.com/loveyourstack/connectors/stores/ebay/ebayoffer"
+)
+
+type InventoryItem struct {
+	Sku       string
+	Locale    string
+	Condition string
+	Title     string
+	Brand     string
+	Mpn       string
+	Ean       string // first of the product's EANs
+	ImageUrl  string // first of the product's images
+	Quantity  int    // available to ship
+	Offers    []Offer
+}
+
+type Offer struct {
+	OfferId           string
+	Sku               string
+	MarketplaceId     string
+	Format            string // FIXED_PRICE or AUCTION
+	Status            string // PUBLISHED or UNPUBLISHED
+	ListingId         string
+	ListingStatus     string
+	CategoryId        string
+	Currency          string
+	Price             *float64
+	AvailableQuantity int
+	SoldQuantity      int
+}
+
+// GetApiInventory returns all inventory items of the seller, with their offers
+// offers can only be listed per SKU, so a request is made per inventory item
+func (c Client) GetApiInventory(ctx context.Context) (items []InventoryItem, err error) {
+
+	items, err = c.GetApiInventoryItems(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("c.GetApiInventoryItems failed: %w", err)
+	}
+
+	for i := range items {
+		items[i].Offers, err = c.GetApiOffers(ctx, items[i].Sku)
+		if err != nil {
+			return nil, fmt.Errorf("c.GetApiOffers failed for SKU %s: %w", items[i].Sku, err)
+		}
+	}
+
+	return items, nil
+}
+
+// GetApiInventoryItems returns all inventory items of the seller, without offers
+func (c Client) GetApiInventoryItems(ctx context.Context) (items []InventoryItem, err error) {
+
+	err = c.getAll(ctx, "/sell/inventory/v1/inventory_item", url.Values{}, func(body []byte) error {
+		page, err := ParseInventoryItemsJson(body)
+		if err != nil {
+			return fmt.Errorf("ParseInventoryItemsJson failed: %w", err)
+		}
+		items = append(items, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("c.getAll failed: %w", err)
+	}
+
+	return items, nil
+}
+
+// ParseInventoryItemsJson parses a page of the inventory items endpoint
+func ParseInventoryItemsJson(content []byte) (items []InventoryItem, err error) {
+
+	/* content looks like this:
+	{"href":"https://api.ebay.com/sell/inventory/v1/inventory_item?limit=100&offset=0","limit":100,"next":"https://api.ebay.com/sell/inventory/v1/inventory_item?limit=100&offset=100","size":100,"total":154,
+	 "inventoryItems":[{"sku":"GP-Cam-01","locale":"en_US","condition":"NEW","availability":{"shipToLocationAvailability":{"quantity":50}},
+	   "product":{"title":"GoPro Hero4 Helmet Cam","brand":"GoPro","mpn":"CHDHX-401","ean":["0818279012285"],"imageUrls":["https://i.ebayimg.com/images/i/182196556219-0-1/s-l1000.jpg"],"aspects":{"Type":["Helmet/Action"]}}}]}
+	*/
+
+	respS := struct {
+		InventoryItems []struct {
+			Sku          string `json:"sku"`
+			Locale       string `json:"locale"`
+			Condition    string `json:"condition"`
+			Availability struct {
+				ShipToLocationAvailability struct {
+					Quantity int `json:"quantity"`
+				} `json:"shipToLocationAvailability"`
+			} `json:"availability"`
+			Product struct {
+				Title     string   `json:"title"`
+				Brand     string   `json:"brand"`
+				Mpn       string   `json:"mpn"`
+				Ean       []string `json:"ean"`
+				ImageUrls []string `json:"imageUrls"`
+			} `json:"product"`
+		} `json:"inventoryItems"`
+	}{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, apiItem := range respS.InventoryItems {
+
+		if apiItem.Sku == "" {
+			return nil, fmt.Errorf("%w: inventory item without SKU", cerrors.ErrValidationFailed)
+		}
+
+		items = append(items, InventoryItem{
+			Sku:       apiItem.Sku,
+			Locale:    apiItem.Locale,
+			Condition: apiItem.Condition,
+			Title:     apiItem.Product.Title,
+			Brand:     apiItem.Product.Brand,
+			Mpn:       apiItem.Product.Mpn,
+			Ean:       first(apiItem.Product.Ean),
+			ImageUrl:  first(apiItem.Product.ImageUrls),
+			Quantity:  apiItem.Availability.ShipToLocationAvailability.Quantity,
+		})
+	}
+
+	return items, nil
+}
+
+// GetApiOffers returns the offers of sku, on any marketplace. A SKU without offers returns none
+func (c Client) GetApiOffers(ctx context.Context, sku string) (offers []Offer, err error) {
+
+	params := url.Values{}
+	params.Set("sku", sku)
+
+	err = c.getAll(ctx, "/sell/inventory/v1/offer", params, func(body []byte) error {
+		page, err := ParseOffersJson(body)
+		if err != nil {
+			return fmt.Errorf("ParseOffersJson failed: %w", err)
+		}
+		offers = append(offers, page...)
+		return nil
+	})
+	if err != nil {
+		// eBay returns 404 (error 25713) for a SKU without offers
+		if errors.Is(err, cerrors.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("c.getAll failed: %w", err)
+	}
+
+	return offers, nil
+}
+
+// ParseOffersJson parses a page of the offers endpoint
+func ParseOffersJson(content []byte) (offers []Offer, err error) {
+
+	/* content looks like this:
+	{"href":"https://api.ebay.com/sell/inventory/v1/offer?sku=GP-Cam-01&limit=100&offset=0","limit":100,"size":1,"total":1,
+	 "offers":[{"offerId":"7092321010","sku":"GP-Cam-01","marketplaceId":"EBAY_US","format":"FIXED_PRICE","availableQuantity":45,"categoryId":"31388",
+	   "pricingSummary":{"price":{"value":"272.17","currency":"USD"}},"listing":{"listingId":"110360288520","listingStatus":"ACTIVE","soldQuantity":5},"status":"PUBLISHED"}]}
+	*/
+
+	respS := struct {
+		Offers []struct {
+			OfferId           string `json:"offerId"`
+			Sku               string `json:"sku"`
+			MarketplaceId     string `json:"marketplaceId"`
+			Format            string `json:"format"`
+			AvailableQuantity int    `json:"availableQuantity"`
+			CategoryId        string `json:"categoryId"`
+			PricingSummary    struct {
+				Price *struct {
+					Value    string `json:"value"`
+					Currency string `json:"currency"`
+				} `json:"price"`
+			} `json:"pricingSummary"`
+			Listing struct {
+				ListingId     string `json:"listingId"`
+				ListingStatus string `json:"listingStatus"`
+				SoldQuantity  int    `json:"soldQuantity"`
+			} `json:"listing"`
+			Status string `json:"status"`
+		} `json:"offers"`
+	}{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, apiOffer := range respS.Offers {
+
+		if apiOffer.OfferId == "" {
+			return nil, fmt.Errorf("%w: offer without offerId of SKU %s", cerrors.ErrValidationFailed, apiOffer.Sku)
+		}
+
+		offer := Offer{
+			OfferId:           apiOffer.OfferId,
+			Sku:               apiOffer.Sku,
+			MarketplaceId:     apiOffer.MarketplaceId,
+			Format:            apiOffer.Format,
+			Status:            apiOffer.Status,
+			ListingId:         apiOffer.Listing.ListingId,
+			ListingStatus:     apiOffer.Listing.ListingStatus,
+			CategoryId:        apiOffer.CategoryId,
+			AvailableQuantity: apiOffer.AvailableQuantity,
+			SoldQuantity:      apiOffer.Listing.SoldQuantity,
+		}
+
+		// unpublished offers may not be priced yet
+		if p := apiOffer.PricingSummary.Price; p != nil && p.Value != "" {
+			price, err := strconv.ParseFloat(p.Value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid price '%s' of offerId %s", cerrors.ErrValidationFailed, p.Value, apiOffer.OfferId)
+			}
+			offer.Price = &price
+			offer.Currency = p.Currency
+		}
+
+		offers = append(offers, offer)
+	}
+
+	return offers, nil
+}
+
+func first(s []string) string {
+	if len(s) == 0 {
+		return ""
+	}
+	return s[0]
+}
+
+// InventoryItemsToMap converts inventory items to a map with Sku as key
+func InventoryItemsToMap(items []InventoryItem) (itemsMap map[string]ebayitem.Model, err error) {
+
+	itemsMap = make(map[string]ebayitem.Model)
+	for _, item := range items {
+
+		if _, ok := itemsMap[item.Sku]; ok {
+			return nil, fmt.Errorf("%w: duplicate SKU %s", cerrors.ErrValidationFailed, item.Sku)
+		}
+
+		itemsMap[item.Sku] = ebayitem.Model{Input: ebayitem.Input{
+			Sku:       item.Sku,
+			Locale:    item.Locale,
+			Condition: item.Condition,
+			Title:     item.Title,
+			Brand:     item.Brand,
+			Mpn:       item.Mpn,
+			Ean:       item.Ean,
+			ImageUrl:  item.ImageUrl,
+			Quantity:  item.Quantity,
+		}}
+	}
+
+	return itemsMap, nil
+}
+
+// OffersToMap converts the offers of items to a map with OfferId as key, resolving inventory item ids with itemIdMap (k = Sku, v = db id)
+func OffersToMap(items []InventoryItem, itemIdMap map[string]int64) (itemsMap map[string]ebayoffer.Model, err error) {
+
+	itemsMap = make(map[string]ebayoffer.Model)
+	for _, item := range items {
+
+		itemFk, ok := itemIdMap[item.Sku]
+		if !ok {
+			return nil, fmt.Errorf("%w: SKU %s", cerrors.ErrNotFound, item.Sku)
+		}
+
+		for _, o := range item.Offers {
+			if _, ok := itemsMap[o.OfferId]; ok {
+				return nil, fmt.Errorf("%w: duplicate offerId %s", cerrors.ErrValidationFailed, o.OfferId)
+			}
+
+			itemsMap[o.OfferId] = ebayoffer.Model{Input: ebayoffer.Input{
+				InventoryItemFk:   itemFk,
+				OfferId:           o.OfferId,
+				MarketplaceId:     o.MarketplaceId,
+				Format:            o.Format,
+				Status:            o.Status,
+				ListingId:         o.ListingId,
+				ListingStatus:     o.ListingStatus,
+				CategoryId:        o.CategoryId,
+				Currency:          o.Currency,
+				Price:             o.Price,
+				AvailableQuantity: o.AvailableQuantity,
+				SoldQuantity:      o.SoldQuantity,
+			}}
+		}
+	}
+
+	return itemsMap, nil
+}
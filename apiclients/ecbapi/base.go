@@ -1,33 +1,88 @@
 package ecbapi
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
 )
 
 // Docs: https://data.ecb.europa.eu/help/api/data
 
 const (
-	baseUrl     string = "https://data-api.ecb.europa.eu"
-	timeoutSecs int    = 20
+	apiShortname   string = "ecb"
+	defaultBaseUrl string = "https://data-api.ecb.europa.eu"
+	timeoutSecs    int    = 20
 )
 
 type Client struct {
-	HttpClient *http.Client
-	InfoLog    *slog.Logger
-	ErrorLog   *slog.Logger
+	HttpClient   *http.Client
+	BaseUrl      string // ECB data API root, e.g. of a fixture server in tests. Defaults to the ECB
+	HistZipUrl   string // location of eurofxref-hist.zip. Defaults to the ECB
+	PressFeedUrl string // location of the press RSS feed. Defaults to the ECB
+	InfoLog      *slog.Logger
+	ErrorLog     *slog.Logger
 }
 
 func NewClient(infoLog, errorLog *slog.Logger) (client Client) {
 
-	apiShortname := "ecb"
-
 	return Client{
 		HttpClient: &http.Client{
 			Timeout: time.Duration(timeoutSecs) * time.Second,
 		},
-		InfoLog:  infoLog.With("api", apiShortname),
-		ErrorLog: errorLog.With("api", apiShortname),
+		BaseUrl:      defaultBaseUrl,
+		HistZipUrl:   defaultHistZipUrl,
+		PressFeedUrl: defaultPressFeedUrl,
+		InfoLog:      infoLog.With("api", apiShortname),
+		ErrorLog:     errorLog.With("api", apiShortname),
+	}
+}
+
+// baseUrl returns c.BaseUrl, or the ECB data API if not set
+func (c Client) baseUrl() string {
+	if c.BaseUrl == "" {
+		return defaultBaseUrl
+	}
+	return strings.TrimSuffix(c.BaseUrl, "/")
+}
+
+// histZipUrl returns c.HistZipUrl, or the ECB's file if not set
+func (c Client) histZipUrl() string {
+	if c.HistZipUrl == "" {
+		return defaultHistZipUrl
+	}
+	return c.HistZipUrl
+}
+
+// pressFeedUrl returns c.PressFeedUrl, or the ECB's feed if not set
+func (c Client) pressFeedUrl() string {
+	if c.PressFeedUrl == "" {
+		return defaultPressFeedUrl
+	}
+	return c.PressFeedUrl
+}
+
+// Ping checks that the ECB data API is reachable by requesting the (small) EXR dataflow definition
+func (c Client) Ping(ctx context.Context) error {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseUrl()+"/service/dataflow/ECB/EXR", nil)
+	if err != nil {
+		return fmt.Errorf("http.NewRequestWithContext failed: %w", err)
 	}
+
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("c.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	return nil
 }
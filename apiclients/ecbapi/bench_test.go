@@ -0,0 +1,79 @@
+package ecbapi_test
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/apiclients/ecbapi/ecbapitest"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// benchRows are the numbers of generated rates of the sync benchmarks
+var benchRows = []int{10_000, 1_000_000}
+
+func BenchmarkExchangeRatesToMap(b *testing.B) {
+
+	currMap := ecbapitest.GenCurrMap()
+	for _, rows := range benchRows {
+		apiRates := ecbapitest.GenExchangeRates(rows)
+		b.Run(fmt.Sprintf("rows=%d", rows), func(b *testing.B) {
+			b.ReportAllocs()
+			defer reportGCs(b)()
+			for range b.N {
+				if _, err := ecbapi.ExchangeRatesToMap(apiRates, currMap); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkExchangeRatesToStringKeyMap builds the map with the day+toCurrFk string keys replaced by ecbexchangerate.NaturalKey, for comparison
+func BenchmarkExchangeRatesToStringKeyMap(b *testing.B) {
+
+	currMap := ecbapitest.GenCurrMap()
+	for _, rows := range benchRows {
+		apiRates := ecbapitest.GenExchangeRates(rows)
+		b.Run(fmt.Sprintf("rows=%d", rows), func(b *testing.B) {
+			b.ReportAllocs()
+			defer reportGCs(b)()
+			for range b.N {
+				if _, err := exchangeRatesToStringKeyMap(apiRates, currMap); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// exchangeRatesToStringKeyMap is ecbapi.ExchangeRatesToMap as it was with day+toCurrFk string keys
+func exchangeRatesToStringKeyMap(apiItems []ecbapi.ExchangeRate, currMap map[string]int64) (itemsMap map[string]ecbexchangerate.Model, err error) {
+
+	items, err := ecbapi.ExchangeRatesToItems(apiItems, currMap)
+	if err != nil {
+		return nil, fmt.Errorf("ecbapi.ExchangeRatesToItems failed: %w", err)
+	}
+
+	itemsMap = make(map[string]ecbexchangerate.Model)
+	for _, input := range items {
+		itemsMap[input.Day.Format(lystype.DateFormat)+"+"+fmt.Sprintf("%v", input.ToCurrencyFk)] = ecbexchangerate.Model{Input: input}
+	}
+
+	return itemsMap, nil
+}
+
+// reportGCs returns a func reporting the garbage collections per op since the call, which shows the GC pressure of the map keying besides its allocations
+func reportGCs(b *testing.B) func() {
+
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	return func() {
+		var after runtime.MemStats
+		runtime.ReadMemStats(&after)
+		b.ReportMetric(float64(after.NumGC-before.NumGC)/float64(b.N), "gcs/op")
+	}
+}
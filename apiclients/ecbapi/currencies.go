@@ -4,7 +4,9 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"net/http"
 
+	"github.com/loveyourstack/connectors/cerrors"
 	"github.com/loveyourstack/connectors/stores/ecb/ecbcurrency"
 	"github.com/loveyourstack/lys/lyserr"
 )
@@ -17,30 +19,45 @@ type Currency struct {
 // GetApiCurrencies returns all available currencies
 func (c Client) GetApiCurrencies() (currencies []Currency, err error) {
 
-	dataStructureUrl := baseUrl + "/service/datastructure/ECB/ECB_EXR1/1.0?references=children"
+	dataStructureUrl := c.baseUrl() + "/service/datastructure/ECB/ECB_EXR1/1.0?references=children"
 
 	// get all data structures
 	resp, err := c.HttpClient.Get(dataStructureUrl)
 	if err != nil {
 		//return nil, fmt.Errorf("c.HttpClient.Get failed: %w", err)
 		return nil, lyserr.Ext{
-			Err:     fmt.Errorf("c.HttpClient.Get failed: %w", err),
+			Err:     fmt.Errorf("c.HttpClient.Get failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err}),
 			Message: err.Error(),
 		}
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+
 	// read xml body
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("io.ReadAll failed: %w", err)
 	}
 
+	currencies, err = ParseCurrenciesXml(respBody)
+	if err != nil {
+		return nil, fmt.Errorf("ParseCurrenciesXml failed: %w", err)
+	}
+
+	return currencies, nil
+}
+
+// ParseCurrenciesXml parses the SDMX data structure response of the EXR dataflow into the currencies of its currency code list
+func ParseCurrenciesXml(content []byte) (currencies []Currency, err error) {
+
 	// unmarshal body into struct
 	respS := dataStructureResponse{}
-	err = xml.Unmarshal(respBody, &respS)
+	err = xml.Unmarshal(content, &respS)
 	if err != nil {
-		return nil, fmt.Errorf("xml.Unmarshal failed: %w", err)
+		return nil, fmt.Errorf("%w: xml.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
 	}
 
 	// parse out currencies
@@ -57,7 +74,7 @@ func (c Client) GetApiCurrencies() (currencies []Currency, err error) {
 		}
 	}
 	if len(currencies) == 0 {
-		return nil, fmt.Errorf("currencies could not be parsed out of datastructure xml response")
+		return nil, fmt.Errorf("%w: currencies could not be parsed out of datastructure xml response", cerrors.ErrValidationFailed)
 	}
 
 	return currencies, nil
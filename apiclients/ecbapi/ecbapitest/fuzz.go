@@ -0,0 +1,37 @@
+package ecbapitest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// AddFuzzSeeds adds the content of each sample in dir matching pattern to the seed corpus of f, so that fuzz targets of a parser start from real responses
+//
+//	func FuzzParseExchangeRatesCsv(f *testing.F) {
+//		ecbapitest.AddFuzzSeeds(f, filepath.Join(ecbapitest.CorpusDir(), "rates"), "*.csv")
+//		f.Fuzz(func(t *testing.T, content []byte) {
+//			ecbapi.ParseExchangeRatesCsv(bytes.NewReader(content), "EUR", ecbapi.Daily) // must not panic
+//		})
+//	}
+func AddFuzzSeeds(f *testing.F, dir, pattern string) {
+
+	f.Helper()
+
+	paths, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		f.Fatalf("filepath.Glob failed: %s", err.Error())
+	}
+
+	for _, path := range paths {
+		if strings.HasSuffix(path, goldenExt) {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatalf("os.ReadFile failed: %s", err.Error())
+		}
+		f.Add(content)
+	}
+}
@@ -0,0 +1,49 @@
+package ecbapitest
+
+import (
+	"time"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/calendar"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// GenCurrencies is the number of currencies of the rates generated by GenExchangeRates
+const GenCurrencies int = 30
+
+// GenExchangeRates returns rows daily rates from EUR, GenCurrencies per business day going back from today, for benchmarks of the sync hot paths
+func GenExchangeRates(rows int) (apiRates []ecbapi.ExchangeRate) {
+
+	apiRates = make([]ecbapi.ExchangeRate, 0, rows)
+	day := time.Now()
+	for len(apiRates) < rows {
+		day = calendar.PreviousBusinessDay(day)
+		for i := 0; i < GenCurrencies && len(apiRates) < rows; i++ {
+			apiRates = append(apiRates, ecbapi.ExchangeRate{
+				FromCurr:  "EUR",
+				ToCurr:    GenCurrencyCode(i),
+				Freq:      ecbapi.Daily,
+				PeriodStr: day.Format(lystype.DateFormat),
+				Rate:      1 + float32(i)/10,
+			})
+		}
+	}
+
+	return apiRates
+}
+
+// GenCurrMap returns the currency code map of the currencies of GenExchangeRates (k = code, v = id), with EUR as id 0
+func GenCurrMap() map[string]int64 {
+
+	currMap := map[string]int64{"EUR": 0}
+	for i := range GenCurrencies {
+		currMap[GenCurrencyCode(i)] = int64(i + 1)
+	}
+
+	return currMap
+}
+
+// GenCurrencyCode returns the 3 letter code of generated currency i, e.g. AAX
+func GenCurrencyCode(i int) string {
+	return string([]byte{'A' + byte(i/26), 'A' + byte(i%26), 'X'})
+}
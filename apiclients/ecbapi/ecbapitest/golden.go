@@ -0,0 +1,144 @@
+package ecbapitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+)
+
+// UpdateGoldenEnv is the environment variable which, if set to 1, makes RunGolden write golden files instead of comparing them
+const UpdateGoldenEnv string = "ECBAPITEST_UPDATE_GOLDEN"
+
+// goldenExt is appended to the sample file name to get its golden file name
+const goldenExt string = ".golden"
+
+// ParseFunc parses the content of a sample file. name is the file name, which may be used to select parse options
+type ParseFunc func(name string, content []byte) (any, error)
+
+// goldenResult is the content of a golden file: the parse result encoded as JSON, or the parse error
+type goldenResult struct {
+	Result any    `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// CorpusDir returns the directory of the ECB response samples shipped with this package: subdirectories currencies, errors, hist and rates
+func CorpusDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "testdata")
+}
+
+// RunGolden runs parse on each file in dir matching pattern (e.g. "*.csv") as a subtest, and compares the JSON encoded result,
+// or the error, with the sample's golden file (sample name + ".golden"). A missing golden file fails the subtest
+// run with UpdateGoldenEnv=1 to (re)write the golden files from the current parser output
+func RunGolden(t *testing.T, dir, pattern string, parse ParseFunc) {
+
+	t.Helper()
+
+	paths, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		t.Fatalf("filepath.Glob failed: %s", err.Error())
+	}
+	if len(paths) == 0 {
+		t.Fatalf("no samples found in %s matching %s", dir, pattern)
+	}
+
+	update := os.Getenv(UpdateGoldenEnv) == "1"
+
+	for _, path := range paths {
+
+		if strings.HasSuffix(path, goldenExt) {
+			continue
+		}
+		name := filepath.Base(path)
+
+		t.Run(name, func(t *testing.T) {
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("os.ReadFile failed: %s", err.Error())
+			}
+
+			got, err := goldenContent(parse(name, content))
+			if err != nil {
+				t.Fatalf("goldenContent failed: %s", err.Error())
+			}
+
+			goldenPath := path + goldenExt
+			if update {
+				if err = os.WriteFile(goldenPath, got, 0644); err != nil {
+					t.Fatalf("os.WriteFile failed: %s", err.Error())
+				}
+				return
+			}
+
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("os.ReadFile failed for golden file (run with %s=1 to create it): %s", UpdateGoldenEnv, err.Error())
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("result differs from %s (run with %s=1 to update it)\ngot:\n%s\nwant:\n%s", goldenPath, UpdateGoldenEnv, got, want)
+			}
+		})
+	}
+}
+
+// goldenContent returns the golden file content of a parse result
+func goldenContent(result any, parseErr error) ([]byte, error) {
+
+	gr := goldenResult{Result: result}
+	if parseErr != nil {
+		gr = goldenResult{Error: parseErr.Error()}
+	}
+
+	content, err := json.MarshalIndent(gr, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("json.MarshalIndent failed: %w", err)
+	}
+
+	return append(content, '\n'), nil
+}
+
+// ParseRatesSample is a ParseFunc for the samples in CorpusDir()/rates. Samples named monthly_* are parsed as monthly rates, all others as daily. The base currency is EUR
+func ParseRatesSample(name string, content []byte) (any, error) {
+
+	freq := ecbapi.Daily
+	if strings.HasPrefix(name, "monthly_") {
+		freq = ecbapi.Monthly
+	}
+
+	return ecbapi.ParseExchangeRatesCsv(bytes.NewReader(content), "EUR", freq)
+}
+
+// ParseCurrenciesSample is a ParseFunc for the samples in CorpusDir()/currencies
+func ParseCurrenciesSample(name string, content []byte) (any, error) {
+	return ecbapi.ParseCurrenciesXml(content)
+}
+
+// ParseHistSample is a ParseFunc for the samples in CorpusDir()/hist
+func ParseHistSample(name string, content []byte) (any, error) {
+	return ecbapi.ParseHistCsv(bytes.NewReader(content))
+}
+
+// ParseErrorSample is a ParseFunc for the samples in CorpusDir()/errors, named after the status of their response, e.g. 400_semantic_error.xml
+// a body which is not an SDMX error message returns an error
+func ParseErrorSample(name string, content []byte) (any, error) {
+
+	statusCode, err := strconv.Atoi(strings.SplitN(name, "_", 2)[0])
+	if err != nil {
+		return nil, fmt.Errorf("sample name must start with the status code: %s", name)
+	}
+
+	e, ok := ecbapi.ParseSdmxError(statusCode, content)
+	if !ok {
+		return nil, fmt.Errorf("not an SDMX error message")
+	}
+	return e, nil
+}
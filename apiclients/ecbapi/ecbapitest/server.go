@@ -0,0 +1,492 @@
+package ecbapitest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/calendar"
+)
+
+// Endpoint identifies one of the ECB API resources served by Server
+type Endpoint string
+
+const (
+	EndpointDataflow   Endpoint = "dataflow"   // used by ecbapi.Client.Ping
+	EndpointCurrencies Endpoint = "currencies" // SDMX data structure containing the currency code list
+	EndpointRates      Endpoint = "rates"      // CSV exchange rates
+	EndpointHistZip    Endpoint = "hist-zip"   // eurofxref-hist.zip
+	EndpointPressFeed  Endpoint = "press-feed" // RSS feed of press releases
+)
+
+// HistZipPath is the path of eurofxref-hist.zip on the server
+const HistZipPath string = "/stats/eurofxref/eurofxref-hist.zip"
+
+// PressFeedPath is the path of the press RSS feed on the server
+const PressFeedPath string = "/rss/press.html"
+
+// DefaultCurrencies are served by NewServer
+var DefaultCurrencies = []ecbapi.Currency{
+	{Code: "CHF", Name: "Swiss franc"},
+	{Code: "EUR", Name: "Euro"},
+	{Code: "GBP", Name: "UK pound sterling"},
+	{Code: "JPY", Name: "Japanese yen"},
+	{Code: "USD", Name: "US dollar"},
+}
+
+// DefaultRates are the rates from EUR used by NewServer for every business day
+var DefaultRates = map[string]float32{
+	"CHF": 0.9385,
+	"GBP": 0.8337,
+	"JPY": 162.72,
+	"USD": 1.0899,
+}
+
+// DefaultPressReleases are served by NewServer and NewServerWith
+var DefaultPressReleases = []ecbapi.PressRelease{
+	{
+		Url:         "https://www.ecb.europa.eu/press/pr/date/2024/html/ecb.mp240606~2148ecdb3c.en.html",
+		Title:       "Monetary policy decisions",
+		PublishedAt: time.Date(2024, 6, 6, 12, 15, 0, 0, time.UTC),
+		Description: "The Governing Council today decided to lower the three key ECB interest rates by 25 basis points.",
+	},
+	{
+		Url:         "https://www.ecb.europa.eu/press/key/date/2024/html/ecb.sp240605~ad9a5b2d1e.en.html",
+		Title:       "Speech by Christine Lagarde",
+		PublishedAt: time.Date(2024, 6, 5, 9, 0, 0, 0, time.UTC),
+	},
+}
+
+// Response is a canned response returned instead of the fixture data
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+}
+
+// Server is an httptest.Server imitating the ECB data API and the eurofxref-hist.zip download
+// the data served and any error responses can be changed while the server is running
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	currencies []ecbapi.Currency
+	rates      []ecbapi.ExchangeRate
+	press      []ecbapi.PressRelease
+	queued     map[Endpoint][]Response // responses returned before the fixture data, in order
+	requests   map[Endpoint]int
+}
+
+// NewServer starts a server serving DefaultCurrencies, and DefaultRates for each business day of the last 30 days, both daily and monthly
+// the caller must call Close when finished
+func NewServer() *Server {
+
+	end := time.Now()
+	start := end.AddDate(0, 0, -30)
+
+	return NewServerWith(DefaultCurrencies, append(DailyRates(DefaultRates, start, end), MonthlyRates(DefaultRates, start, end)...))
+}
+
+// NewServerWith starts a server serving currencies and rates. The caller must call Close when finished
+func NewServerWith(currencies []ecbapi.Currency, rates []ecbapi.ExchangeRate) *Server {
+
+	s := &Server{
+		currencies: currencies,
+		rates:      rates,
+		press:      DefaultPressReleases,
+		queued:     make(map[Endpoint][]Response),
+		requests:   make(map[Endpoint]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /service/dataflow/ECB/EXR", s.handle(EndpointDataflow, s.serveDataflow))
+	mux.HandleFunc("GET /service/datastructure/ECB/ECB_EXR1/1.0", s.handle(EndpointCurrencies, s.serveCurrencies))
+	mux.HandleFunc("GET /service/data/EXR/{key}", s.handle(EndpointRates, s.serveRates))
+	mux.HandleFunc("GET "+HistZipPath, s.handle(EndpointHistZip, s.serveHistZip))
+	mux.HandleFunc("GET "+PressFeedPath, s.handle(EndpointPressFeed, s.servePressFeed))
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// EcbClient returns an ecbapi.Client using the server. Nil loggers are replaced by a logger discarding all output
+func (s *Server) EcbClient(infoLog, errorLog *slog.Logger) ecbapi.Client {
+
+	discardLog := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if infoLog == nil {
+		infoLog = discardLog
+	}
+	if errorLog == nil {
+		errorLog = discardLog
+	}
+
+	c := ecbapi.NewClient(infoLog, errorLog)
+	c.HttpClient = s.Client()
+	c.BaseUrl = s.URL
+	c.HistZipUrl = s.URL + HistZipPath
+	c.PressFeedUrl = s.URL + PressFeedPath
+
+	return c
+}
+
+// SetCurrencies replaces the currencies served
+func (s *Server) SetCurrencies(currencies []ecbapi.Currency) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.currencies = currencies
+}
+
+// SetRates replaces the exchange rates served, by the rates endpoint as well as in the hist zip
+func (s *Server) SetRates(rates []ecbapi.ExchangeRate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rates = rates
+}
+
+// SetPressReleases replaces the press releases served
+func (s *Server) SetPressReleases(pressReleases []ecbapi.PressRelease) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.press = pressReleases
+}
+
+// Enqueue makes the next len(resps) requests to endpoint return resps, in order, instead of the fixture data
+func (s *Server) Enqueue(endpoint Endpoint, resps ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queued[endpoint] = append(s.queued[endpoint], resps...)
+}
+
+// FailNext makes the next n requests to endpoint fail with statusCode
+func (s *Server) FailNext(endpoint Endpoint, n int, statusCode int) {
+	for range n {
+		s.Enqueue(endpoint, Response{StatusCode: statusCode, Body: http.StatusText(statusCode)})
+	}
+}
+
+// RateLimitNext makes the next n requests to endpoint fail with 429 Too Many Requests and a Retry-After header
+func (s *Server) RateLimitNext(endpoint Endpoint, n int, retryAfter time.Duration) {
+
+	header := http.Header{}
+	header.Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+
+	for range n {
+		s.Enqueue(endpoint, Response{StatusCode: http.StatusTooManyRequests, Header: header, Body: http.StatusText(http.StatusTooManyRequests)})
+	}
+}
+
+// Requests returns the number of requests received by endpoint, including those answered with a queued response
+func (s *Server) Requests(endpoint Endpoint) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[endpoint]
+}
+
+// handle counts requests and returns the next queued response of endpoint, if any, before calling serve
+func (s *Server) handle(endpoint Endpoint, serve http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		s.mu.Lock()
+		s.requests[endpoint]++
+		var resp *Response
+		if q := s.queued[endpoint]; len(q) > 0 {
+			resp = &q[0]
+			s.queued[endpoint] = q[1:]
+		}
+		s.mu.Unlock()
+
+		if resp == nil {
+			serve(w, r)
+			return
+		}
+
+		for k, vals := range resp.Header {
+			for _, v := range vals {
+				w.Header().Add(k, v)
+			}
+		}
+		w.WriteHeader(resp.StatusCode)
+		io.WriteString(w, resp.Body)
+	}
+}
+
+func (s *Server) serveDataflow(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/xml")
+	io.WriteString(w, xml.Header+`<mes:Structure xmlns:mes="http://www.sdmx.org/resources/sdmxml/schemas/v2_1/message"><mes:Structures/></mes:Structure>`)
+}
+
+func (s *Server) serveCurrencies(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	currencies := s.currencies
+	s.mu.Unlock()
+
+	body, err := CurrenciesXML(currencies)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write(body)
+}
+
+// serveRates serves rates matching a key such as "D..EUR.SP00.A", within startPeriod and endPeriod. As the API, it responds 404 if nothing matches
+func (s *Server) serveRates(w http.ResponseWriter, r *http.Request) {
+
+	// key: {freq}.{currency, empty for all}.{base}.SP00.A
+	keyA := strings.Split(r.PathValue("key"), ".")
+	if len(keyA) != 5 {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(SdmxErrorXML(ecbapi.SdmxSyntaxError, fmt.Sprintf("Syntax error - The series key %s must have 5 dimensions", r.PathValue("key"))))
+		return
+	}
+	freq, toCurr, baseCurr := ecbapi.Frequency(keyA[0]), keyA[1], keyA[2]
+	startPeriod, endPeriod := r.URL.Query().Get("startPeriod"), r.URL.Query().Get("endPeriod")
+
+	s.mu.Lock()
+	var matching []ecbapi.ExchangeRate
+	for _, rate := range s.rates {
+		if rate.Freq != freq || rate.FromCurr != baseCurr || (toCurr != "" && rate.ToCurr != toCurr) {
+			continue
+		}
+		// periods of the same frequency compare correctly as strings
+		if (startPeriod != "" && rate.PeriodStr < startPeriod) || (endPeriod != "" && rate.PeriodStr > endPeriod) {
+			continue
+		}
+		matching = append(matching, rate)
+	}
+	s.mu.Unlock()
+
+	if len(matching) == 0 {
+		http.Error(w, "No results found.", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Write(RatesCSV(matching))
+}
+
+func (s *Server) serveHistZip(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	rates := s.rates
+	s.mu.Unlock()
+
+	body, err := HistZip(rates)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Write(body)
+}
+
+func (s *Server) servePressFeed(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	press := s.press
+	s.mu.Unlock()
+
+	body, err := PressFeedXML(press)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/rss+xml")
+	w.Write(body)
+}
+
+// DailyRates returns a daily rate from EUR for each currency in rates and each TARGET business day from start to end
+func DailyRates(rates map[string]float32, start, end time.Time) (exRates []ecbapi.ExchangeRate) {
+
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if !calendar.IsBusinessDay(day) {
+			continue
+		}
+		exRates = append(exRates, ratesOf(rates, ecbapi.Daily, day.Format("2006-01-02"))...)
+	}
+
+	return exRates
+}
+
+// MonthlyRates returns a monthly rate from EUR for each currency in rates and each month from start to end
+func MonthlyRates(rates map[string]float32, start, end time.Time) (exRates []ecbapi.ExchangeRate) {
+
+	month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for ; !month.After(end); month = month.AddDate(0, 1, 0) {
+		exRates = append(exRates, ratesOf(rates, ecbapi.Monthly, month.Format("2006-01"))...)
+	}
+
+	return exRates
+}
+
+// ratesOf returns the rates of one period, sorted by currency
+func ratesOf(rates map[string]float32, freq ecbapi.Frequency, periodStr string) (exRates []ecbapi.ExchangeRate) {
+
+	for toCurr, rate := range rates {
+		exRates = append(exRates, ecbapi.ExchangeRate{FromCurr: "EUR", ToCurr: toCurr, Freq: freq, PeriodStr: periodStr, Rate: rate})
+	}
+	sort.Slice(exRates, func(i, j int) bool { return exRates[i].ToCurr < exRates[j].ToCurr })
+
+	return exRates
+}
+
+// RatesCSV returns rates in the csvdata format of the ECB data API
+func RatesCSV(rates []ecbapi.ExchangeRate) []byte {
+
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	cw.Write([]string{"KEY", "FREQ", "CURRENCY", "CURRENCY_DENOM", "EXR_TYPE", "EXR_SUFFIX", "TIME_PERIOD", "OBS_VALUE"})
+	for _, rate := range rates {
+		key := fmt.Sprintf("EXR.%s.%s.%s.SP00.A", rate.Freq, rate.ToCurr, rate.FromCurr)
+		cw.Write([]string{key, rate.Freq.String(), rate.ToCurr, rate.FromCurr, "SP00", "A", rate.PeriodStr, formatRate(rate.Rate)})
+	}
+	cw.Flush()
+
+	return buf.Bytes()
+}
+
+// HistZip returns the daily rates from EUR contained in rates as an eurofxref-hist.zip file, latest day first, with N/A for missing values
+func HistZip(rates []ecbapi.ExchangeRate) ([]byte, error) {
+
+	// collect days and currencies
+	byDay := make(map[string]map[string]float32)
+	currSet := make(map[string]bool)
+	for _, rate := range rates {
+		if rate.Freq != ecbapi.Daily || rate.FromCurr != "EUR" {
+			continue
+		}
+		if byDay[rate.PeriodStr] == nil {
+			byDay[rate.PeriodStr] = make(map[string]float32)
+		}
+		byDay[rate.PeriodStr][rate.ToCurr] = rate.Rate
+		currSet[rate.ToCurr] = true
+	}
+
+	days := make([]string, 0, len(byDay))
+	for day := range byDay {
+		days = append(days, day)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(days)))
+
+	currs := make([]string, 0, len(currSet))
+	for curr := range currSet {
+		currs = append(currs, curr)
+	}
+	sort.Strings(currs)
+
+	// write csv, with the trailing comma of the original
+	var csvBuf bytes.Buffer
+	csvBuf.WriteString("Date," + strings.Join(currs, ",") + ",\n")
+	for _, day := range days {
+		csvBuf.WriteString(day)
+		for _, curr := range currs {
+			val := "N/A"
+			if rate, ok := byDay[day][curr]; ok {
+				val = formatRate(rate)
+			}
+			csvBuf.WriteString("," + val)
+		}
+		csvBuf.WriteString(",\n")
+	}
+
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	f, err := zw.Create("eurofxref-hist.csv")
+	if err != nil {
+		return nil, fmt.Errorf("zw.Create failed: %w", err)
+	}
+	if _, err = f.Write(csvBuf.Bytes()); err != nil {
+		return nil, fmt.Errorf("f.Write failed: %w", err)
+	}
+	if err = zw.Close(); err != nil {
+		return nil, fmt.Errorf("zw.Close failed: %w", err)
+	}
+
+	return zipBuf.Bytes(), nil
+}
+
+// SdmxErrorXML returns an SDMX-ML error message with code, e.g. ecbapi.SdmxSemanticError, and text, as the API sends with 4xx and 5xx responses
+// e.g. Enqueue(EndpointRates, Response{StatusCode: 400, Body: string(SdmxErrorXML(ecbapi.SdmxSemanticError, "Semantic Error - ..."))})
+func SdmxErrorXML(code int, text string) []byte {
+
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(text))
+
+	return []byte(xml.Header + `<message:Error xmlns:message="http://www.sdmx.org/resources/sdmxml/schemas/v2_1/message" xmlns:common="http://www.sdmx.org/resources/sdmxml/schemas/v2_1/common">` +
+		fmt.Sprintf(`<message:ErrorMessage code="%d"><common:Text xml:lang="en">%s</common:Text></message:ErrorMessage></message:Error>`, code, buf.String()))
+}
+
+// CurrenciesXML returns currencies as the SDMX data structure response containing the currency code list
+func CurrenciesXML(currencies []ecbapi.Currency) ([]byte, error) {
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<mes:Structure xmlns:mes="http://www.sdmx.org/resources/sdmxml/schemas/v2_1/message" xmlns:str="http://www.sdmx.org/resources/sdmxml/schemas/v2_1/structure" xmlns:com="http://www.sdmx.org/resources/sdmxml/schemas/v2_1/common">`)
+	buf.WriteString(`<mes:Structures><str:Codelists><str:Codelist id="CL_CURRENCY" agencyID="ECB" version="1.0"><com:Name xml:lang="en">Currency code list</com:Name>`)
+
+	for _, curr := range currencies {
+		buf.WriteString(`<str:Code id="`)
+		if err := xml.EscapeText(&buf, []byte(curr.Code)); err != nil {
+			return nil, fmt.Errorf("xml.EscapeText failed: %w", err)
+		}
+		buf.WriteString(`"><com:Name xml:lang="en">`)
+		if err := xml.EscapeText(&buf, []byte(curr.Name)); err != nil {
+			return nil, fmt.Errorf("xml.EscapeText failed: %w", err)
+		}
+		buf.WriteString(`</com:Name></str:Code>`)
+	}
+
+	buf.WriteString(`</str:Codelist></str:Codelists></mes:Structures></mes:Structure>`)
+
+	return buf.Bytes(), nil
+}
+
+func formatRate(rate float32) string {
+	return strconv.FormatFloat(float64(rate), 'f', -1, 32)
+}
+
+// PressFeedXML returns pressReleases as an RSS 2.0 feed. Their Category is not written: it is derived from the URL when parsed
+func PressFeedXML(pressReleases []ecbapi.PressRelease) ([]byte, error) {
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<rss version="2.0"><channel><title>ECB press</title><link>https://www.ecb.europa.eu/</link>`)
+
+	for _, pr := range pressReleases {
+		buf.WriteString(`<item>`)
+		for _, el := range []struct{ name, text string }{
+			{"title", pr.Title},
+			{"link", pr.Url},
+			{"description", pr.Description},
+			{"pubDate", pr.PublishedAt.Format(time.RFC1123Z)},
+		} {
+			buf.WriteString(`<` + el.name + `>`)
+			if err := xml.EscapeText(&buf, []byte(el.text)); err != nil {
+				return nil, fmt.Errorf("xml.EscapeText failed: %w", err)
+			}
+			buf.WriteString(`</` + el.name + `>`)
+		}
+		buf.WriteString(`</item>`)
+	}
+
+	buf.WriteString(`</channel></rss>`)
+
+	return buf.Bytes(), nil
+}
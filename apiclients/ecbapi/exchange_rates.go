@@ -3,10 +3,16 @@ package ecbapi
 import (
 	"encoding/csv"
 	"fmt"
+	"io"
+	"math"
+	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/loveyourstack/connectors/cerrors"
 	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
 	"github.com/loveyourstack/lys/lystype"
 )
@@ -17,20 +23,33 @@ type ExchangeRate struct {
 	Freq      Frequency
 	PeriodStr string // daily: YYYY-MM-DD, monthly: YYYY-MM
 	Rate      float32
+	Source    string    `json:"-"` // SourceName of the client the rate was fetched from. Set by the sync, see csyncdb.ExchangeRateSource
+	FetchedAt time.Time `json:"-"` // when the rate was fetched. Set by the sync
 }
 
+// ecbBaseCurr is the only base currency of the rates published by the ECB
+const ecbBaseCurr string = "EUR"
+
+// ErrUnsupportedBase is returned by GetAPIExchangeRates for a base currency whose rates can't be derived from the ECB's EUR rates. It matches cerrors.ErrValidationFailed
+var ErrUnsupportedBase = fmt.Errorf("%w: unsupported base currency", cerrors.ErrValidationFailed)
+
 // GetAPIExchangeRates returns average daily or monthly exchange rates from baseCurr to all other available currencies
+// the ECB only publishes rates from EUR: those from another base are derived from them as cross rates, see crossExchangeRates
 func (c Client) GetAPIExchangeRates(baseCurr string, freq Frequency, startDate, endDate time.Time) (exRates []ExchangeRate, err error) {
 
+	if baseCurr != ecbBaseCurr {
+		return c.crossExchangeRates(baseCurr, freq, startDate, endDate)
+	}
+
 	// validate dates
 	if startDate.After(time.Now()) {
-		return nil, fmt.Errorf("startDate must be before now")
+		return nil, fmt.Errorf("%w: startDate must be before now", cerrors.ErrValidationFailed)
 	}
 	if startDate.After(endDate) {
-		return nil, fmt.Errorf("startDate must be before endDate")
+		return nil, fmt.Errorf("%w: startDate must be before endDate", cerrors.ErrValidationFailed)
 	}
 	if endDate.After(time.Now()) {
-		return nil, fmt.Errorf("endDate must be before now")
+		return nil, fmt.Errorf("%w: endDate must be before now", cerrors.ErrValidationFailed)
 	}
 
 	// set vars depending on freq
@@ -41,11 +60,11 @@ func (c Client) GetAPIExchangeRates(baseCurr string, freq Frequency, startDate,
 	case Monthly:
 		dateFormat = "2006-01"
 	default:
-		return nil, fmt.Errorf("invalid freq '%s'", freq)
+		return nil, fmt.Errorf("%w: invalid freq '%s'", cerrors.ErrValidationFailed, freq)
 	}
 
 	// build URL
-	exrBaseUrl := baseUrl + "/service/data/EXR"
+	exrBaseUrl := c.baseUrl() + "/service/data/EXR"
 	path := fmt.Sprintf("/%s..%s.SP00.A", freq, baseCurr)
 	params := url.Values{}
 	params.Add("detail", "dataonly")
@@ -57,18 +76,81 @@ func (c Client) GetAPIExchangeRates(baseCurr string, freq Frequency, startDate,
 	// get rates
 	resp, err := c.HttpClient.Get(exrUrl)
 	if err != nil {
-		return nil, fmt.Errorf("c.HttpClient.Get failed: %w", err)
+		return nil, fmt.Errorf("c.HttpClient.Get failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
 	}
 	defer resp.Body.Close()
 
-	// read csv content
-	csvContent, err := csv.NewReader(resp.Body).ReadAll()
+	// the API responds 404 if there is no data for the params, and 4xx with an SDMX error message if they are invalid, e.g. an unknown currency
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("no rates found for these params: %w", responseError(resp))
+	default:
+		return nil, responseError(resp)
+	}
+
+	exRates, err = ParseExchangeRatesCsv(resp.Body, baseCurr, freq)
+	if err != nil {
+		return nil, fmt.Errorf("ParseExchangeRatesCsv failed: %w", err)
+	}
+
+	return exRates, nil
+}
+
+// crossExchangeRates returns the rates from baseCurr, derived from the EUR rates of each period: EUR at 1 / the EUR rate of baseCurr, and the other currencies at their EUR rate / that of baseCurr
+// periods without a rate of baseCurr are skipped. Monthly cross rates are ratios of the monthly averages, so approximate the averages of the daily cross rates
+// returns ErrUnsupportedBase if baseCurr has no EUR rate in the date range, e.g. because the ECB does not quote it
+func (c Client) crossExchangeRates(baseCurr string, freq Frequency, startDate, endDate time.Time) (exRates []ExchangeRate, err error) {
+
+	eurRates, err := c.GetAPIExchangeRates(ecbBaseCurr, freq, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("c.GetAPIExchangeRates failed for %s: %w", ecbBaseCurr, err)
+	}
+
+	// EUR rate of baseCurr per period
+	baseRates := make(map[string]float64)
+	for _, eurRate := range eurRates {
+		if eurRate.ToCurr == baseCurr {
+			baseRates[eurRate.PeriodStr] = float64(eurRate.Rate)
+		}
+	}
+	if len(baseRates) == 0 {
+		return nil, fmt.Errorf("%w: the ECB only publishes rates from %s, and has no rate of %s in the date range to derive those from %s: pls use %s or a currency quoted by the ECB (see GetCurrenciesMap) as base",
+			ErrUnsupportedBase, ecbBaseCurr, baseCurr, baseCurr, ecbBaseCurr)
+	}
+
+	exRates = make([]ExchangeRate, 0, len(eurRates))
+	for _, eurRate := range eurRates {
+
+		baseRate, ok := baseRates[eurRate.PeriodStr]
+		if !ok {
+			continue
+		}
+
+		exRate := ExchangeRate{FromCurr: baseCurr, ToCurr: eurRate.ToCurr, Freq: eurRate.Freq, PeriodStr: eurRate.PeriodStr, Rate: float32(float64(eurRate.Rate) / baseRate)}
+		if eurRate.ToCurr == baseCurr {
+			exRate.ToCurr, exRate.Rate = ecbBaseCurr, float32(1/baseRate)
+		}
+		exRates = append(exRates, exRate)
+	}
+
+	return exRates, nil
+}
+
+// ParseExchangeRatesCsv parses an EXR response of the ECB data API in csvdata format into exchange rates from baseCurr
+// missing observations (empty or NaN) are skipped
+func ParseExchangeRatesCsv(r io.Reader, baseCurr string, freq Frequency) (exRates []ExchangeRate, err error) {
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1 // line lengths are checked below, with a clearer error
+
+	csvContent, err := cr.ReadAll()
 	if err != nil {
-		return nil, fmt.Errorf("csv.NewReader().ReadAll failed: %w", err)
+		return nil, fmt.Errorf("%w: cr.ReadAll failed: %w", cerrors.ErrValidationFailed, err)
 	}
 
 	if len(csvContent) < 2 {
-		return nil, fmt.Errorf("no rates found for these params")
+		return nil, fmt.Errorf("%w: no rates found for these params", cerrors.ErrNotFound)
 	}
 
 	/* csvContent looks like this:
@@ -77,31 +159,48 @@ func (c Client) GetAPIExchangeRates(baseCurr string, freq Frequency, startDate,
 	EXR.D.AUD.EUR.SP00.A,D,AUD,EUR,SP00,A,2024-09-03,1.6394
 	*/
 
-	// for each line
-	for i, lineA := range csvContent {
+	// get column positions from header rather than assuming them, since other detail levels add columns
+	header := csvContent[0]
+	currIdx, periodIdx, valIdx := slices.Index(header, "CURRENCY"), slices.Index(header, "TIME_PERIOD"), slices.Index(header, "OBS_VALUE")
+	if currIdx == -1 || periodIdx == -1 || valIdx == -1 {
+		return nil, fmt.Errorf("%w: unexpected header: %s", cerrors.ErrValidationFailed, strings.Join(header, ","))
+	}
+
+	// for each line after header
+	for i, lineA := range csvContent[1:] {
+
+		lineNum := i + 2
 
-		// skip header
-		if i == 0 {
+		if len(lineA) != len(header) {
+			return nil, fmt.Errorf("%w: line %d: expected %d fields, got %d", cerrors.ErrValidationFailed, lineNum, len(header), len(lineA))
+		}
+
+		// skip missing observations
+		val := lineA[valIdx]
+		if val == "" || val == "NaN" {
 			continue
 		}
 
 		// parse out the values
 		exRate := ExchangeRate{
 			FromCurr:  baseCurr,
-			ToCurr:    lineA[2],
+			ToCurr:    lineA[currIdx],
 			Freq:      freq,
-			PeriodStr: lineA[6],
+			PeriodStr: lineA[periodIdx],
 		}
 
-		rateFl64, err := strconv.ParseFloat(lineA[7], 32)
+		exRate.Rate, err = parseRate(val)
 		if err != nil {
-			return nil, fmt.Errorf("strconv.ParseFloat failed for rate '%s': %w", lineA[7], err)
+			return nil, fmt.Errorf("line %d: parseRate failed: %w", lineNum, err)
 		}
-		exRate.Rate = float32(rateFl64)
 
 		exRates = append(exRates, exRate)
 	}
 
+	if len(exRates) == 0 {
+		return nil, fmt.Errorf("%w: no rates found for these params", cerrors.ErrNotFound)
+	}
+
 	return exRates, nil
 }
 
@@ -112,6 +211,23 @@ func (c Client) GetExchangeRates(baseCurr string, freq Frequency, startDate, end
 		return nil, fmt.Errorf("c.GetAPIExchangeRates failed: %w", err)
 	}
 
+	return ExchangeRatesToItems(apiItems, currMap)
+}
+
+func (c Client) GetExchangeRatesMap(baseCurr string, freq Frequency, startDate, endDate time.Time, currMap map[string]int64) (itemsMap map[ecbexchangerate.NaturalKey]ecbexchangerate.Model, err error) {
+
+	apiItems, err := c.GetAPIExchangeRates(baseCurr, freq, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("c.GetAPIExchangeRates failed: %w", err)
+	}
+
+	return ExchangeRatesToMap(apiItems, currMap)
+}
+
+// ExchangeRatesToItems converts API exchange rates to store inputs, using currMap (k = currency code, v = db id) to resolve currency fks
+func ExchangeRatesToItems(apiItems []ExchangeRate, currMap map[string]int64) (items []ecbexchangerate.Input, err error) {
+
+	items = make([]ecbexchangerate.Input, 0, len(apiItems))
 	for _, apiItem := range apiItems {
 		_item, err := apiExchangeRateToItem(apiItem, currMap)
 		if err != nil {
@@ -123,65 +239,84 @@ func (c Client) GetExchangeRates(baseCurr string, freq Frequency, startDate, end
 	return items, nil
 }
 
-func (c Client) GetExchangeRatesMap(baseCurr string, freq Frequency, startDate, endDate time.Time, currMap map[string]int64) (itemsMap map[string]ecbexchangerate.Model, err error) {
-
-	items, err := c.GetExchangeRates(baseCurr, freq, startDate, endDate, currMap)
-	if err != nil {
-		return nil, fmt.Errorf("c.GetExchangeRates failed: %w", err)
-	}
+// ExchangeRatesToMap is like ExchangeRatesToItems, but returns a map with ecbexchangerate.NaturalKey as key
+func ExchangeRatesToMap(apiItems []ExchangeRate, currMap map[string]int64) (itemsMap map[ecbexchangerate.NaturalKey]ecbexchangerate.Model, err error) {
 
-	// convert to map with day+toCurrFk as key
-	itemsMap = make(map[string]ecbexchangerate.Model)
-	for _, input := range items {
-		item := ecbexchangerate.Model{
-			Input: input,
+	itemsMap = make(map[ecbexchangerate.NaturalKey]ecbexchangerate.Model, len(apiItems))
+	for _, apiItem := range apiItems {
+		input, err := apiExchangeRateToItem(apiItem, currMap)
+		if err != nil {
+			return nil, fmt.Errorf("apiExchangeRateToItem failed: %w", err)
 		}
-		itemsMap[input.Day.Format(lystype.DateFormat)+"+"+fmt.Sprintf("%v", input.ToCurrencyFk)] = item
+		itemsMap[ecbexchangerate.KeyOf(input)] = ecbexchangerate.Model{Input: input}
 	}
 
 	return itemsMap, nil
 }
 
-func apiExchangeRateToItem(apiItem ExchangeRate, currMap map[string]int64) (item ecbexchangerate.Input, err error) {
+// Day returns the day of the period of r: the 1st of the month if monthly
+func (r ExchangeRate) Day() (day time.Time, err error) {
 
-	// day: if monthly, use 1st of month
-	var day lystype.Date
-	switch apiItem.Freq {
+	switch r.Freq {
 	case Daily:
-		periodTime, err := time.Parse("2006-01-02", apiItem.PeriodStr)
-		if err != nil {
-			return ecbexchangerate.Input{}, fmt.Errorf("time.Parse (Daily) failed for PeriodStr '%s': %w", apiItem.PeriodStr, err)
-		}
-		day = lystype.Date(periodTime)
+		day, err = time.Parse("2006-01-02", r.PeriodStr)
 	case Monthly:
-		periodTime, err := time.Parse("2006-01", apiItem.PeriodStr)
-		if err != nil {
-			return ecbexchangerate.Input{}, fmt.Errorf("time.Parse (Daily) failed for PeriodStr '%s': %w", apiItem.PeriodStr, err)
-		}
-		day = lystype.Date(periodTime)
+		day, err = time.Parse("2006-01", r.PeriodStr)
 	default:
-		return ecbexchangerate.Input{}, fmt.Errorf("invalid frequency: %s", apiItem.Freq)
+		return time.Time{}, fmt.Errorf("%w: invalid frequency: %s", cerrors.ErrValidationFailed, r.Freq)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: time.Parse failed for PeriodStr '%s': %w", cerrors.ErrValidationFailed, r.PeriodStr, err)
+	}
+
+	return day, nil
+}
+
+func apiExchangeRateToItem(apiItem ExchangeRate, currMap map[string]int64) (item ecbexchangerate.Input, err error) {
+
+	day, err := apiItem.Day()
+	if err != nil {
+		return ecbexchangerate.Input{}, fmt.Errorf("apiItem.Day failed: %w", err)
 	}
 
 	// from curr
 	fromCurrFk, ok := currMap[apiItem.FromCurr]
 	if !ok {
-		return ecbexchangerate.Input{}, fmt.Errorf("from currency code not in map: %s", apiItem.FromCurr)
+		return ecbexchangerate.Input{}, fmt.Errorf("%w: from currency code not in map: %s", cerrors.ErrNotFound, apiItem.FromCurr)
 	}
 
 	// to curr
 	toCurrFk, ok := currMap[apiItem.ToCurr]
 	if !ok {
-		return ecbexchangerate.Input{}, fmt.Errorf("to currency code not in map: %s", apiItem.ToCurr)
+		return ecbexchangerate.Input{}, fmt.Errorf("%w: to currency code not in map: %s", cerrors.ErrNotFound, apiItem.ToCurr)
 	}
 
 	item = ecbexchangerate.Input{
-		Day:            day,
-		Frequency:      apiItem.Freq.String(),
+		Day:            lystype.Date(day),
+		Frequency:      apiItem.Freq,
 		FromCurrencyFk: fromCurrFk,
 		Rate:           apiItem.Rate,
+		Source:         apiItem.Source,
 		ToCurrencyFk:   toCurrFk,
 	}
+	if !apiItem.FetchedAt.IsZero() {
+		fetchedAt := lystype.Datetime(apiItem.FetchedAt)
+		item.FetchedAt = &fetchedAt
+	}
 
 	return item, nil
 }
+
+// parseRate parses a rate value. Rates must be positive and finite: NaN and Inf, which strconv.ParseFloat accepts, are rejected
+func parseRate(val string) (rate float32, err error) {
+
+	rateFl64, err := strconv.ParseFloat(val, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: strconv.ParseFloat failed for rate '%s': %w", cerrors.ErrValidationFailed, val, err)
+	}
+	if math.IsNaN(rateFl64) || math.IsInf(rateFl64, 0) || rateFl64 <= 0 {
+		return 0, fmt.Errorf("%w: rate must be positive and finite, got '%s'", cerrors.ErrValidationFailed, val)
+	}
+
+	return float32(rateFl64), nil
+}
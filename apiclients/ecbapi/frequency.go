@@ -1,12 +1,11 @@
 package ecbapi
 
-type Frequency string
+import "github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
 
-func (e Frequency) String() string {
-	return string(e)
-}
+// Frequency is the frequency of ECB rates, the type stored in ecbexchangerate.Input
+type Frequency = ecbexchangerate.Frequency
 
 const (
-	Daily   Frequency = "D"
-	Monthly Frequency = "M"
+	Daily   Frequency = ecbexchangerate.Daily
+	Monthly Frequency = ecbexchangerate.Monthly
 )
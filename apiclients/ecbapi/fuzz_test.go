@@ -0,0 +1,145 @@
+package ecbapi_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/apiclients/ecbapi/ecbapitest"
+)
+
+func FuzzParseExchangeRatesCsv(f *testing.F) {
+
+	ecbapitest.AddFuzzSeeds(f, filepath.Join(ecbapitest.CorpusDir(), "rates"), "*.csv")
+
+	f.Fuzz(func(t *testing.T, content []byte) {
+
+		exRates, err := ecbapi.ParseExchangeRatesCsv(bytes.NewReader(content), "EUR", ecbapi.Daily)
+		if err != nil || hasCR(exRates) {
+			return
+		}
+
+		// rates parsed must parse back the same from the CSV they are written to
+		rows := [][]string{{"CURRENCY", "TIME_PERIOD", "OBS_VALUE"}}
+		for _, r := range exRates {
+			rows = append(rows, []string{r.ToCurr, r.PeriodStr, formatRate(r.Rate)})
+		}
+
+		got, err := ecbapi.ParseExchangeRatesCsv(bytes.NewReader(writeCsv(t, rows)), "EUR", ecbapi.Daily)
+		if err != nil {
+			t.Fatalf("ParseExchangeRatesCsv failed on the rates written back: %s", err.Error())
+		}
+		if !reflect.DeepEqual(got, exRates) {
+			t.Errorf("rates written back parse differently\ngot:  %v\nwant: %v", got, exRates)
+		}
+	})
+}
+
+func FuzzParseHistCsv(f *testing.F) {
+
+	ecbapitest.AddFuzzSeeds(f, filepath.Join(ecbapitest.CorpusDir(), "hist"), "*.csv")
+
+	f.Fuzz(func(t *testing.T, content []byte) {
+
+		exRates, err := ecbapi.ParseHistCsv(bytes.NewReader(content))
+		if err != nil || hasCR(exRates) {
+			return
+		}
+
+		// rates parsed must parse back the same from the CSV they are written to: a line per rate, N/A for the other currencies
+		header := []string{"Date"}
+		for _, r := range exRates {
+			if !slices.Contains(header[1:], r.ToCurr) {
+				header = append(header, r.ToCurr)
+			}
+		}
+		rows := [][]string{header}
+		for _, r := range exRates {
+			row := []string{r.PeriodStr}
+			for _, toCurr := range header[1:] {
+				if toCurr == r.ToCurr {
+					row = append(row, formatRate(r.Rate))
+					continue
+				}
+				row = append(row, "N/A")
+			}
+			rows = append(rows, row)
+		}
+
+		got, err := ecbapi.ParseHistCsv(bytes.NewReader(writeCsv(t, rows)))
+		if err != nil {
+			t.Fatalf("ParseHistCsv failed on the rates written back: %s", err.Error())
+		}
+		if !reflect.DeepEqual(got, exRates) {
+			t.Errorf("rates written back parse differently\ngot:  %v\nwant: %v", got, exRates)
+		}
+	})
+}
+
+func FuzzParseCurrenciesXml(f *testing.F) {
+
+	ecbapitest.AddFuzzSeeds(f, filepath.Join(ecbapitest.CorpusDir(), "currencies"), "*.xml")
+
+	f.Fuzz(func(t *testing.T, content []byte) {
+
+		currencies, err := ecbapi.ParseCurrenciesXml(content)
+		if err != nil {
+			return
+		}
+
+		// currencies parsed must parse back the same from the code list they are written to
+		var b strings.Builder
+		b.WriteString(`<message:Structure xmlns:message="m" xmlns:structure="s" xmlns:common="c"><message:Structures><structure:Codelists>`)
+		b.WriteString(`<structure:Codelist id="CL_CURRENCY"><common:Name xml:lang="en">Currency code list</common:Name>`)
+		for _, c := range currencies {
+			b.WriteString(`<structure:Code id="` + escapeXml(t, c.Code) + `"><common:Name xml:lang="en">` + escapeXml(t, c.Name) + `</common:Name></structure:Code>`)
+		}
+		b.WriteString(`</structure:Codelist></structure:Codelists></message:Structures></message:Structure>`)
+
+		got, err := ecbapi.ParseCurrenciesXml([]byte(b.String()))
+		if err != nil {
+			t.Fatalf("ParseCurrenciesXml failed on the currencies written back: %s", err.Error())
+		}
+		if !reflect.DeepEqual(got, currencies) {
+			t.Errorf("currencies written back parse differently\ngot:  %v\nwant: %v", got, currencies)
+		}
+	})
+}
+
+// formatRate returns the shortest representation of rate which parses back to it
+func formatRate(rate float32) string {
+	return strconv.FormatFloat(float64(rate), 'g', -1, 32)
+}
+
+// hasCR returns true if a field of exRates contains a carriage return, which encoding/csv doesn't read back as written: \r\n in a quoted field is read as \n
+func hasCR(exRates []ecbapi.ExchangeRate) bool {
+	return slices.ContainsFunc(exRates, func(r ecbapi.ExchangeRate) bool {
+		return strings.Contains(r.ToCurr, "\r") || strings.Contains(r.PeriodStr, "\r")
+	})
+}
+
+func writeCsv(t *testing.T, rows [][]string) []byte {
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(rows); err != nil {
+		t.Fatalf("w.WriteAll failed: %s", err.Error())
+	}
+	return buf.Bytes()
+}
+
+func escapeXml(t *testing.T, s string) string {
+
+	var b strings.Builder
+	if err := xml.EscapeText(&b, []byte(s)); err != nil {
+		t.Fatalf("xml.EscapeText failed: %s", err.Error())
+	}
+	return b.String()
+}
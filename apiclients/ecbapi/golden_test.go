@@ -0,0 +1,28 @@
+package ecbapi_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi/ecbapitest"
+)
+
+func TestGolden(t *testing.T) {
+
+	tests := []struct {
+		dir     string
+		pattern string
+		parse   ecbapitest.ParseFunc
+	}{
+		{"rates", "*.csv", ecbapitest.ParseRatesSample},
+		{"currencies", "*.xml", ecbapitest.ParseCurrenciesSample},
+		{"hist", "*.csv", ecbapitest.ParseHistSample},
+		{"errors", "*", ecbapitest.ParseErrorSample},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			ecbapitest.RunGolden(t, filepath.Join(ecbapitest.CorpusDir(), tt.dir), tt.pattern, tt.parse)
+		})
+	}
+}
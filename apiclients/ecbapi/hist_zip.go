@@ -0,0 +1,161 @@
+package ecbapi
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+)
+
+// defaultHistZipUrl is the ECB's complete daily reference rate history since 1999, published as a single zipped CSV
+const defaultHistZipUrl string = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist.zip"
+
+// histCsvFileName is the name of the CSV file contained in the hist zip
+const histCsvFileName string = "eurofxref-hist.csv"
+
+// histBaseCurr is the base currency of all rates in the hist zip
+const histBaseCurr string = ecbBaseCurr
+
+// DownloadHistZip returns the content of the ECB's eurofxref-hist.zip file
+func (c Client) DownloadHistZip() (zipContent []byte, err error) {
+
+	resp, err := c.HttpClient.Get(c.histZipUrl())
+	if err != nil {
+		return nil, fmt.Errorf("c.HttpClient.Get failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+	}
+
+	zipContent, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadAll failed: %w", err)
+	}
+
+	return zipContent, nil
+}
+
+// ParseHistZip validates and parses the content of an eurofxref-hist.zip file into daily exchange rates from EUR
+func ParseHistZip(zipContent []byte) (exRates []ExchangeRate, err error) {
+
+	zr, err := zip.NewReader(bytes.NewReader(zipContent), int64(len(zipContent)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: zip.NewReader failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	var csvFile *zip.File
+	for _, f := range zr.File {
+		if f.Name == histCsvFileName {
+			csvFile = f
+			break
+		}
+	}
+	if csvFile == nil {
+		return nil, fmt.Errorf("%w: %s not found in zip", cerrors.ErrValidationFailed, histCsvFileName)
+	}
+
+	rc, err := csvFile.Open()
+	if err != nil {
+		return nil, fmt.Errorf("csvFile.Open failed: %w", err)
+	}
+	defer rc.Close()
+
+	return ParseHistCsv(rc)
+}
+
+// ParseHistCsv validates and parses the content of an eurofxref-hist.csv file into daily exchange rates from EUR
+func ParseHistCsv(r io.Reader) (exRates []ExchangeRate, err error) {
+
+	/* csv content looks like this (note trailing comma, and N/A for currencies not quoted on that day):
+	Date,USD,JPY,BGN,CYP,CZK,...,
+	2024-10-15,1.0899,162.72,1.9558,N/A,25.324,...,
+	*/
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	csvContent, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: cr.ReadAll failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	if len(csvContent) < 2 {
+		return nil, fmt.Errorf("%w: no rates found in file", cerrors.ErrNotFound)
+	}
+
+	header := csvContent[0]
+	if len(header) < 2 || header[0] != "Date" {
+		return nil, fmt.Errorf("%w: unexpected header: %s", cerrors.ErrValidationFailed, strings.Join(header, ","))
+	}
+
+	// for each line after header
+	for i, lineA := range csvContent[1:] {
+
+		lineNum := i + 2
+
+		if len(lineA) != len(header) {
+			return nil, fmt.Errorf("%w: line %d: expected %d fields, got %d", cerrors.ErrValidationFailed, lineNum, len(header), len(lineA))
+		}
+
+		if _, err := time.Parse("2006-01-02", lineA[0]); err != nil {
+			return nil, fmt.Errorf("%w: line %d: invalid date '%s': %w", cerrors.ErrValidationFailed, lineNum, lineA[0], err)
+		}
+
+		for j := 1; j < len(lineA); j++ {
+
+			// skip trailing empty column and missing values
+			toCurr := strings.TrimSpace(header[j])
+			val := strings.TrimSpace(lineA[j])
+			if toCurr == "" || val == "" || val == "N/A" {
+				continue
+			}
+
+			rate, err := parseRate(val)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: parseRate failed for %s: %w", lineNum, toCurr, err)
+			}
+
+			exRates = append(exRates, ExchangeRate{
+				FromCurr:  histBaseCurr,
+				ToCurr:    toCurr,
+				Freq:      Daily,
+				PeriodStr: lineA[0],
+				Rate:      rate,
+			})
+		}
+	}
+
+	if len(exRates) == 0 {
+		return nil, fmt.Errorf("%w: no rates found in file", cerrors.ErrNotFound)
+	}
+
+	return exRates, nil
+}
+
+// GetHistExchangeRates parses the content of an eurofxref-hist.zip file into exchange rate inputs
+func GetHistExchangeRates(zipContent []byte, currMap map[string]int64) (items []ecbexchangerate.Input, err error) {
+
+	apiItems, err := ParseHistZip(zipContent)
+	if err != nil {
+		return nil, fmt.Errorf("ParseHistZip failed: %w", err)
+	}
+
+	for _, apiItem := range apiItems {
+		_item, err := apiExchangeRateToItem(apiItem, currMap)
+		if err != nil {
+			return nil, fmt.Errorf("apiExchangeRateToItem failed: %w", err)
+		}
+		items = append(items, _item)
+	}
+
+	return items, nil
+}
@@ -0,0 +1,186 @@
+package ecbapi
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbpressrelease"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// defaultPressFeedUrl is the ECB's RSS feed of press releases, speeches, interviews and monetary policy announcements
+const defaultPressFeedUrl string = "https://www.ecb.europa.eu/rss/press.html"
+
+type PressRelease struct {
+	Url         string
+	Title       string
+	Category    string // one of the ecbpressrelease.Category consts
+	PublishedAt time.Time
+	Description string
+}
+
+// feed is an RSS 2.0 or Atom feed. Only the elements of the feed's format are filled
+type feed struct {
+	XMLName xml.Name
+	Items   []struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		PubDate     string `xml:"pubDate"`
+	} `xml:"channel>item"`
+	Entries []struct {
+		Title string `xml:"title"`
+		Link  struct {
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+		Summary   string `xml:"summary"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+	} `xml:"entry"`
+}
+
+// GetApiPressReleases returns the items of the ECB's press feed
+func (c Client) GetApiPressReleases() (pressReleases []PressRelease, err error) {
+
+	resp, err := c.HttpClient.Get(c.pressFeedUrl())
+	if err != nil {
+		return nil, fmt.Errorf("c.HttpClient.Get failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadAll failed: %w", err)
+	}
+
+	pressReleases, err = ParsePressFeed(respBody)
+	if err != nil {
+		return nil, fmt.Errorf("ParsePressFeed failed: %w", err)
+	}
+
+	return pressReleases, nil
+}
+
+// ParsePressFeed parses an RSS 2.0 or Atom feed of ECB press items, categorizing each by its URL
+// an empty feed is not an error
+func ParsePressFeed(content []byte) (pressReleases []PressRelease, err error) {
+
+	respS := feed{}
+	if err = xml.Unmarshal(content, &respS); err != nil {
+		return nil, fmt.Errorf("%w: xml.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	switch respS.XMLName.Local {
+	case "rss":
+		for _, item := range respS.Items {
+			pr, err := newPressRelease(item.Link, item.Title, item.Description, item.PubDate)
+			if err != nil {
+				return nil, fmt.Errorf("newPressRelease failed: %w", err)
+			}
+			pressReleases = append(pressReleases, pr)
+		}
+	case "feed":
+		for _, entry := range respS.Entries {
+			published := entry.Published
+			if published == "" {
+				published = entry.Updated
+			}
+			pr, err := newPressRelease(entry.Link.Href, entry.Title, entry.Summary, published)
+			if err != nil {
+				return nil, fmt.Errorf("newPressRelease failed: %w", err)
+			}
+			pressReleases = append(pressReleases, pr)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unexpected root element: %s", cerrors.ErrValidationFailed, respS.XMLName.Local)
+	}
+
+	return pressReleases, nil
+}
+
+// feedTimeLayouts are the timestamp layouts of RSS (RFC 822, with 2 or 4 digit years) and Atom (RFC 3339)
+var feedTimeLayouts = []string{time.RFC1123Z, time.RFC1123, "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST", time.RFC822Z, time.RFC3339}
+
+func newPressRelease(link, title, description, published string) (pr PressRelease, err error) {
+
+	pr = PressRelease{
+		Url:         strings.TrimSpace(link),
+		Title:       strings.TrimSpace(title),
+		Description: strings.TrimSpace(description),
+	}
+	if pr.Url == "" || pr.Title == "" {
+		return PressRelease{}, fmt.Errorf("%w: item without link or title: '%s'", cerrors.ErrValidationFailed, title)
+	}
+	pr.Category = PressCategory(pr.Url)
+
+	published = strings.TrimSpace(published)
+	for _, layout := range feedTimeLayouts {
+		if pr.PublishedAt, err = time.Parse(layout, published); err == nil {
+			return pr, nil
+		}
+	}
+	return PressRelease{}, fmt.Errorf("%w: invalid publication time '%s' of %s", cerrors.ErrValidationFailed, published, pr.Url)
+}
+
+// pressUrlRe matches the document code of an ECB press URL, e.g. "ecb.mp240606" in .../press/pr/date/2024/html/ecb.mp240606~2148ecdb3c.en.html
+var pressUrlRe = regexp.MustCompile(`/ecb\.([a-z]+)\d{6}`)
+
+// PressCategory returns the category of the ECB press item at url, one of the ecbpressrelease.Category consts
+func PressCategory(url string) string {
+
+	switch {
+	case strings.Contains(url, "/press/blog/"):
+		return ecbpressrelease.CategoryBlog
+	case strings.Contains(url, "/press/inter/"):
+		return ecbpressrelease.CategoryInterview
+	case strings.Contains(url, "/press/key/"):
+		return ecbpressrelease.CategorySpeech
+	}
+
+	m := pressUrlRe.FindStringSubmatch(url)
+	if m == nil {
+		return ecbpressrelease.CategoryOther
+	}
+	switch m[1] {
+	case "mp":
+		return ecbpressrelease.CategoryMonetaryPolicyDecision
+	case "is":
+		return ecbpressrelease.CategoryMonetaryPolicyStatement
+	case "pr":
+		return ecbpressrelease.CategoryPressRelease
+	case "sp":
+		return ecbpressrelease.CategorySpeech
+	case "in":
+		return ecbpressrelease.CategoryInterview
+	case "blog":
+		return ecbpressrelease.CategoryBlog
+	default:
+		return ecbpressrelease.CategoryOther
+	}
+}
+
+// PressReleasesToMap converts press releases to a map with Url as key
+func PressReleasesToMap(pressReleases []PressRelease) (itemsMap map[string]ecbpressrelease.Model) {
+
+	itemsMap = make(map[string]ecbpressrelease.Model)
+	for _, pr := range pressReleases {
+		itemsMap[pr.Url] = ecbpressrelease.Model{Input: ecbpressrelease.Input{
+			Url:         pr.Url,
+			Title:       pr.Title,
+			Category:    pr.Category,
+			PublishedAt: lystype.Datetime(pr.PublishedAt),
+			Description: pr.Description,
+		}}
+	}
+	return itemsMap
+}
@@ -0,0 +1,57 @@
+package ecbapi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/ratesource"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// SourceName returns the name of the ECB as a ratesource.Source
+func (c Client) SourceName() string {
+	return apiShortname
+}
+
+// GetRates returns the daily reference rates from base to quotes, as a ratesource.Source. The ECB quotes from EUR, so other bases are cross rates
+func (c Client) GetRates(ctx context.Context, base string, quotes []string, startDate, endDate time.Time) (rates []ratesource.Rate, err error) {
+
+	exRates, err := c.GetAPIExchangeRates(ecbBaseCurr, Daily, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("c.GetAPIExchangeRates failed: %w", err)
+	}
+
+	eurRates, err := ExchangeRatesToRates(exRates)
+	if err != nil {
+		return nil, fmt.Errorf("ExchangeRatesToRates failed: %w", err)
+	}
+
+	return ratesource.Rebase(eurRates, base, quotes)
+}
+
+// ExchangeRatesToRates converts daily exchange rates in the format of the ECB client to ratesource rates
+func ExchangeRatesToRates(exRates []ExchangeRate) (rates []ratesource.Rate, err error) {
+
+	for _, exRate := range exRates {
+
+		day, err := time.Parse(lystype.DateFormat, exRate.PeriodStr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid daily period '%s': %w", cerrors.ErrValidationFailed, exRate.PeriodStr, err)
+		}
+
+		// via the shortest decimal of the float32, so that e.g. 1.0856 does not become 1.08560001850128
+		rate, _ := strconv.ParseFloat(strconv.FormatFloat(float64(exRate.Rate), 'g', -1, 32), 64)
+
+		rates = append(rates, ratesource.Rate{
+			Day:   day,
+			Base:  exRate.FromCurr,
+			Quote: exRate.ToCurr,
+			Rate:  rate,
+		})
+	}
+
+	return rates, nil
+}
@@ -0,0 +1,207 @@
+package ecbapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// maxErrorBodySize is the number of bytes of an error response read for its message
+const maxErrorBodySize int64 = 64 << 10
+
+// maxErrorTextLen is the length an unstructured error body is truncated to in the message
+const maxErrorTextLen int = 300
+
+// the error codes of the SDMX web service guidelines, returned by the ECB in the error bodies of 4xx and 5xx responses
+const (
+	SdmxNoResults          int = 100
+	SdmxUnauthorized       int = 110
+	SdmxTooLarge           int = 130 // the response would be too large due to the request, e.g. a period which is too long
+	SdmxSyntaxError        int = 140 // e.g. a malformed series key
+	SdmxSemanticError      int = 150 // e.g. an invalid dimension value of the series key
+	SdmxInternalError      int = 500
+	SdmxNotImplemented     int = 501
+	SdmxServiceUnavailable int = 503
+	SdmxSizeLimitExceeded  int = 510 // the response exceeds a limit of the service
+)
+
+// sdmxCodeTitles are the titles of the SDMX error codes, used if an error body has no text
+var sdmxCodeTitles = map[int]string{
+	SdmxNoResults:          "no results found",
+	SdmxUnauthorized:       "unauthorized",
+	SdmxTooLarge:           "response too large due to client request",
+	SdmxSyntaxError:        "syntax error",
+	SdmxSemanticError:      "semantic error",
+	SdmxInternalError:      "internal server error",
+	SdmxNotImplemented:     "not implemented",
+	SdmxServiceUnavailable: "service unavailable",
+	SdmxSizeLimitExceeded:  "response size exceeds service limit",
+}
+
+// SdmxError is the error body of an ECB response with a 4xx or 5xx status, e.g. "Semantic Error - The dimension value XXX of CURRENCY is invalid"
+// it matches cerrors.ErrNotFound for no results, a cerrors.UpstreamError for server errors, so that they are retried, and cerrors.ErrValidationFailed otherwise,
+// since the request must be changed, e.g. its series key or period
+type SdmxError struct {
+	StatusCode int
+	Code       int    // SDMX error code, e.g. SdmxSemanticError. 0 if the body is not an SDMX error message
+	Message    string // text of the error body
+}
+
+func (e SdmxError) Error() string {
+	if e.Code == 0 {
+		return fmt.Sprintf("%s: status %d: %s", apiShortname, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s: status %d: SDMX error %d: %s", apiShortname, e.StatusCode, e.Code, e.Message)
+}
+
+func (e SdmxError) Unwrap() []error {
+	switch {
+	case e.Code == SdmxNoResults || (e.Code == 0 && e.StatusCode == http.StatusNotFound):
+		return []error{cerrors.ErrNotFound}
+	case e.Code >= SdmxInternalError || e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests:
+		return []error{cerrors.UpstreamError{Source: apiShortname, StatusCode: e.StatusCode}}
+	default:
+		return []error{cerrors.ErrValidationFailed}
+	}
+}
+
+// sdmxErrorXml is an SDMX-ML 2.1 error message
+type sdmxErrorXml struct {
+	XMLName       xml.Name `xml:"Error"`
+	ErrorMessages []struct {
+		Code  int      `xml:"code,attr"`
+		Texts []string `xml:"Text"`
+	} `xml:"ErrorMessage"`
+}
+
+// sdmxErrorJson is an SDMX-JSON error message, in the 1.0 form with title and detail or the earlier form with message
+type sdmxErrorJson struct {
+	Errors []struct {
+		Code    int             `json:"code"`
+		Title   string          `json:"title"`
+		Detail  string          `json:"detail"`
+		Message json.RawMessage `json:"message"` // string or object of texts by language
+	} `json:"errors"`
+}
+
+// ParseSdmxError returns the SdmxError of body, the body of a response with statusCode. ok is false if body is not an SDMX error message
+func ParseSdmxError(statusCode int, body []byte) (e SdmxError, ok bool) {
+
+	e.StatusCode = statusCode
+	body = bytes.TrimSpace(body)
+
+	/* XML body looks like this:
+	<?xml version="1.0" encoding="UTF-8"?><message:Error xmlns:message="http://www.sdmx.org/resources/sdmxml/schemas/v2_1/message"
+	  xmlns:common="http://www.sdmx.org/resources/sdmxml/schemas/v2_1/common"><message:ErrorMessage code="150">
+	  <common:Text xml:lang="en">Semantic Error - The dimension value XXX of CURRENCY is invalid</common:Text></message:ErrorMessage></message:Error>
+	*/
+	if bytes.HasPrefix(body, []byte("<")) {
+		respS := sdmxErrorXml{}
+		if err := xml.Unmarshal(body, &respS); err != nil || len(respS.ErrorMessages) == 0 {
+			return e, false
+		}
+		var texts []string
+		for _, m := range respS.ErrorMessages {
+			texts = append(texts, m.Texts...)
+		}
+		e.Code = respS.ErrorMessages[0].Code
+		e.Message = sdmxMessage(e.Code, texts)
+		return e, true
+	}
+
+	/* JSON body looks like this:
+	{"errors":[{"code":150,"title":"Semantic Error","detail":"The dimension value XXX of CURRENCY is invalid"}]}
+	*/
+	if bytes.HasPrefix(body, []byte("{")) {
+		respS := sdmxErrorJson{}
+		if err := json.Unmarshal(body, &respS); err != nil || len(respS.Errors) == 0 {
+			return e, false
+		}
+		var texts []string
+		for _, m := range respS.Errors {
+			var parts []string
+			for _, part := range []string{m.Title, m.Detail, jsonText(m.Message)} {
+				if part = strings.TrimSpace(part); part != "" {
+					parts = append(parts, part)
+				}
+			}
+			texts = append(texts, strings.Join(parts, " - "))
+		}
+		e.Code = respS.Errors[0].Code
+		e.Message = sdmxMessage(e.Code, texts)
+		return e, true
+	}
+
+	return e, false
+}
+
+// sdmxMessage joins the non-empty texts of an error message, or returns the title of code if there are none
+func sdmxMessage(code int, texts []string) string {
+
+	var nonEmpty []string
+	for _, t := range texts {
+		if t = strings.Trim(strings.TrimSpace(t), " -"); t != "" {
+			nonEmpty = append(nonEmpty, t)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return sdmxCodeTitles[code]
+	}
+	return strings.Join(nonEmpty, "; ")
+}
+
+// jsonText returns the text of the message of an SDMX-JSON error: the string, or the English text of an object of texts by language
+func jsonText(raw json.RawMessage) string {
+
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var byLang map[string]string
+	if err := json.Unmarshal(raw, &byLang); err == nil {
+		if en, ok := byLang["en"]; ok {
+			return en
+		}
+		for _, t := range byLang {
+			return t
+		}
+	}
+	return ""
+}
+
+// responseError returns the error of resp, a response without the expected status: its SdmxError, or an SdmxError with the text of a plain body,
+// e.g. "No results found.", or a cerrors.UpstreamError if the body is empty and the status is not 404
+func responseError(resp *http.Response) error {
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+
+	if e, ok := ParseSdmxError(resp.StatusCode, body); ok {
+		return e
+	}
+
+	text := strings.Join(strings.Fields(string(body)), " ")
+	if strings.HasPrefix(text, "<") {
+		// an HTML error page, e.g. of a proxy
+		text = ""
+	}
+	if r := []rune(text); len(r) > maxErrorTextLen {
+		text = string(r[:maxErrorTextLen]) + "..."
+	}
+	if text == "" && resp.StatusCode != http.StatusNotFound {
+		return cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+	}
+	if text == "" {
+		text = sdmxCodeTitles[SdmxNoResults]
+	}
+
+	return SdmxError{StatusCode: resp.StatusCode, Message: text}
+}
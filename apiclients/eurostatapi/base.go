@@ -0,0 +1,44 @@
+package eurostatapi
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Docs: https://wikis.ec.europa.eu/display/EUROSTATHELP/API+Statistics+-+data+query
+// datasets and their dimensions can be browsed at https://ec.europa.eu/eurostat/databrowser
+
+const (
+	apiShortname   string = "eurostat"
+	defaultBaseUrl string = "https://ec.europa.eu/eurostat/api/dissemination/statistics/1.0"
+	timeoutSecs    int    = 60 // large datasets are slow to extract
+)
+
+type Client struct {
+	HttpClient *http.Client
+	BaseUrl    string // API root, e.g. of a fixture server in tests. Defaults to Eurostat
+	InfoLog    *slog.Logger
+	ErrorLog   *slog.Logger
+}
+
+func NewClient(infoLog, errorLog *slog.Logger) (client Client) {
+
+	return Client{
+		HttpClient: &http.Client{
+			Timeout: time.Duration(timeoutSecs) * time.Second,
+		},
+		BaseUrl:  defaultBaseUrl,
+		InfoLog:  infoLog.With("api", apiShortname),
+		ErrorLog: errorLog.With("api", apiShortname),
+	}
+}
+
+// baseUrl returns c.BaseUrl, or Eurostat if not set
+func (c Client) baseUrl() string {
+	if c.BaseUrl == "" {
+		return defaultBaseUrl
+	}
+	return strings.TrimSuffix(c.BaseUrl, "/")
+}
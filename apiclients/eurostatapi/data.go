@@ -0,0 +1,258 @@
+package eurostatapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/eurostat/eurostatobservation"
+)
+
+// timeDimension is the id of the time dimension in Eurostat datasets
+const timeDimension string = "time"
+
+type Observation struct {
+	Dataset    string
+	Dimensions map[string]string // k = dimension code, v = category code, excluding time
+	Period     string
+	Value      float64
+	Status     string // observation flag, e.g. p (provisional). Empty if none
+}
+
+// jsonStat is a JSON-stat 2.0 dataset, as returned by the data endpoint
+type jsonStat struct {
+	Id        []string                     `json:"id"`   // dimension ids, in order
+	Size      []int                        `json:"size"` // number of categories per dimension
+	Dimension map[string]jsonStatDimension `json:"dimension"`
+	Value     json.RawMessage              `json:"value"`  // object of k = flat index, or array
+	Status    json.RawMessage              `json:"status"` // object of k = flat index, or array
+}
+
+type jsonStatDimension struct {
+	Category struct {
+		Index json.RawMessage `json:"index"` // object of k = category code, v = position, or array of codes
+	} `json:"category"`
+}
+
+// errorResponse is the body of a Eurostat error response
+type errorResponse struct {
+	Error []struct {
+		Label string `json:"label"`
+	} `json:"error"`
+}
+
+// GetApiData returns the observations selected by q
+func (c Client) GetApiData(q Query) (observations []Observation, err error) {
+
+	if err = q.Validate(); err != nil {
+		return nil, fmt.Errorf("q.Validate failed: %w", err)
+	}
+
+	dataUrl := c.baseUrl() + "/data/" + q.Dataset + "?" + q.params().Encode()
+
+	resp, err := c.HttpClient.Get(dataUrl)
+	if err != nil {
+		return nil, fmt.Errorf("c.HttpClient.Get failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadRequest, http.StatusNotFound:
+		// the reason is in the body, e.g. an unknown dataset or dimension
+		body, _ := io.ReadAll(resp.Body)
+		errResp := errorResponse{}
+		json.Unmarshal(body, &errResp)
+		label := ""
+		if len(errResp.Error) > 0 {
+			label = errResp.Error[0].Label
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: dataset %s: %s", cerrors.ErrNotFound, q.Dataset, label)
+		}
+		return nil, fmt.Errorf("%w: dataset %s: %s", cerrors.ErrValidationFailed, q.Dataset, label)
+	default:
+		return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+	}
+
+	observations, err = ParseJsonStat(q.Dataset, resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ParseJsonStat failed: %w", err)
+	}
+
+	return observations, nil
+}
+
+// ParseJsonStat parses a JSON-stat 2.0 response of dataset into observations, ordered as in the response. Missing values are skipped
+func ParseJsonStat(dataset string, r io.Reader) (observations []Observation, err error) {
+
+	/* content looks like this (shortened):
+	{"version":"2.0","class":"dataset","id":["freq","unit","coicop","geo","time"],"size":[1,1,1,2,2],
+	 "dimension":{"geo":{"category":{"index":{"DE":0,"FR":1}}},"time":{"category":{"index":{"2024-01":0,"2024-02":1}}},...},
+	 "value":{"0":3.1,"1":2.7,"2":3.4},"status":{"1":"p"}}
+	*/
+
+	js := jsonStat{}
+	if err = json.NewDecoder(r).Decode(&js); err != nil {
+		return nil, fmt.Errorf("%w: json.Decode failed: %w", cerrors.ErrValidationFailed, err)
+	}
+	if len(js.Id) == 0 || len(js.Id) != len(js.Size) {
+		return nil, fmt.Errorf("%w: id and size must have the same, non-zero length", cerrors.ErrValidationFailed)
+	}
+
+	// category codes of each dimension, by position
+	codes := make([][]string, len(js.Id))
+	for i, dimId := range js.Id {
+		dim, ok := js.Dimension[dimId]
+		if !ok {
+			return nil, fmt.Errorf("%w: dimension %s not found", cerrors.ErrValidationFailed, dimId)
+		}
+		codes[i], err = parseCategoryIndex(dim.Category.Index, js.Size[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: dimension %s: %w", cerrors.ErrValidationFailed, dimId, err)
+		}
+	}
+
+	values, err := parseFlatValues[float64](js.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: value: %w", cerrors.ErrValidationFailed, err)
+	}
+	statuses, err := parseFlatValues[string](js.Status)
+	if err != nil {
+		return nil, fmt.Errorf("%w: status: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	total := 1
+	for _, size := range js.Size {
+		total *= size
+	}
+
+	flatIndexes := make([]int, 0, len(values))
+	for flatIndex := range values {
+		if flatIndex < 0 || flatIndex >= total {
+			return nil, fmt.Errorf("%w: value index %v out of range", cerrors.ErrValidationFailed, flatIndex)
+		}
+		flatIndexes = append(flatIndexes, flatIndex)
+	}
+	sort.Ints(flatIndexes)
+
+	for _, flatIndex := range flatIndexes {
+
+		obs := Observation{Dataset: dataset, Dimensions: make(map[string]string), Value: values[flatIndex], Status: statuses[flatIndex]}
+
+		// row-major order: the last dimension varies fastest
+		rest := flatIndex
+		for i := len(js.Id) - 1; i >= 0; i-- {
+			code := codes[i][rest%js.Size[i]]
+			rest /= js.Size[i]
+
+			if js.Id[i] == timeDimension {
+				obs.Period = code
+				continue
+			}
+			obs.Dimensions[js.Id[i]] = code
+		}
+		if obs.Period == "" {
+			return nil, fmt.Errorf("%w: dimension %s not found", cerrors.ErrValidationFailed, timeDimension)
+		}
+
+		observations = append(observations, obs)
+	}
+
+	return observations, nil
+}
+
+// parseCategoryIndex returns the category codes of a dimension by position. index is either an object of code to position, or an array of codes
+func parseCategoryIndex(index json.RawMessage, size int) (codes []string, err error) {
+
+	if err = json.Unmarshal(index, &codes); err == nil {
+		if len(codes) != size {
+			return nil, fmt.Errorf("index has %v categories, size is %v", len(codes), size)
+		}
+		return codes, nil
+	}
+
+	positions := make(map[string]int)
+	if err = json.Unmarshal(index, &positions); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal failed for index: %w", err)
+	}
+
+	codes = make([]string, size)
+	for code, pos := range positions {
+		if pos < 0 || pos >= size {
+			return nil, fmt.Errorf("position %v of category %s out of range", pos, code)
+		}
+		codes[pos] = code
+	}
+	for pos, code := range codes {
+		if code == "" {
+			return nil, fmt.Errorf("no category at position %v", pos)
+		}
+	}
+
+	return codes, nil
+}
+
+// parseFlatValues returns the non-null entries of a JSON-stat value or status member by flat index. It is either an object of flat index to value, or an array
+func parseFlatValues[T any](content json.RawMessage) (values map[int]T, err error) {
+
+	values = make(map[int]T)
+	if len(content) == 0 {
+		return values, nil
+	}
+
+	arr := []*T{}
+	if err = json.Unmarshal(content, &arr); err == nil {
+		for i, v := range arr {
+			if v != nil {
+				values[i] = *v
+			}
+		}
+		return values, nil
+	}
+
+	obj := make(map[string]*T)
+	if err = json.Unmarshal(content, &obj); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal failed: %w", err)
+	}
+	for k, v := range obj {
+		i, err := strconv.Atoi(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index '%s': %w", k, err)
+		}
+		if v != nil {
+			values[i] = *v
+		}
+	}
+
+	return values, nil
+}
+
+// ObservationsToMap converts API observations of a single dataset to store models with dimensions+period as key
+func ObservationsToMap(apiItems []Observation) (itemsMap map[string]eurostatobservation.Model, err error) {
+
+	itemsMap = make(map[string]eurostatobservation.Model)
+	for _, apiItem := range apiItems {
+
+		key, err := eurostatobservation.NaturalKey(apiItem.Dimensions, apiItem.Period)
+		if err != nil {
+			return nil, fmt.Errorf("eurostatobservation.NaturalKey failed: %w", err)
+		}
+
+		itemsMap[key] = eurostatobservation.Model{
+			Input: eurostatobservation.Input{
+				DatasetCode: apiItem.Dataset,
+				Dimensions:  apiItem.Dimensions,
+				Period:      apiItem.Period,
+				Status:      apiItem.Status,
+				Value:       apiItem.Value,
+			},
+		}
+	}
+
+	return itemsMap, nil
+}
@@ -0,0 +1,88 @@
+package eurostatapi
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// Query selects observations of a Eurostat dataset
+type Query struct {
+	Dataset     string              `toml:"dataset"`     // dataset code, e.g. prc_hicp_manr
+	Filters     map[string][]string `toml:"filters"`     // k = dimension code, v = category codes, e.g. geo = ["DE", "FR"]. Unfiltered dimensions return all categories
+	SincePeriod string              `toml:"sincePeriod"` // first period, e.g. 2020, 2020-Q1 or 2020-01
+	LastPeriods int                 `toml:"lastPeriods"` // number of most recent periods, if SincePeriod is not set
+}
+
+// Validate returns an error if q is incomplete
+func (q Query) Validate() error {
+
+	if q.Dataset == "" {
+		return fmt.Errorf("%w: dataset is required", cerrors.ErrValidationFailed)
+	}
+	if q.SincePeriod == "" && q.LastPeriods < 1 {
+		return fmt.Errorf("%w: dataset %s: sincePeriod or lastPeriods is required", cerrors.ErrValidationFailed, q.Dataset)
+	}
+	if _, ok := q.Filters[timeDimension]; ok {
+		return fmt.Errorf("%w: dataset %s: filter on %s is not allowed: use sincePeriod or lastPeriods", cerrors.ErrValidationFailed, q.Dataset, timeDimension)
+	}
+	return nil
+}
+
+// params returns the URL params of q
+func (q Query) params() url.Values {
+
+	params := url.Values{}
+	params.Add("format", "JSON")
+	params.Add("lang", "EN")
+	for dim, cats := range q.Filters {
+		for _, cat := range cats {
+			params.Add(dim, cat)
+		}
+	}
+	if q.SincePeriod != "" {
+		params.Add("sinceTimePeriod", q.SincePeriod)
+	} else {
+		params.Add("lastTimePeriod", strconv.Itoa(q.LastPeriods))
+	}
+	return params
+}
+
+// String returns q in a stable format, e.g. for the sync journal: "dataset=prc_hicp_manr coicop=CP00 geo=DE,FR last=12"
+func (q Query) String() string {
+
+	parts := []string{"dataset=" + q.Dataset}
+
+	dims := make([]string, 0, len(q.Filters))
+	for dim := range q.Filters {
+		dims = append(dims, dim)
+	}
+	sort.Strings(dims)
+	for _, dim := range dims {
+		parts = append(parts, dim+"="+strings.Join(q.Filters[dim], ","))
+	}
+
+	if q.SincePeriod != "" {
+		parts = append(parts, "since="+q.SincePeriod)
+	} else {
+		parts = append(parts, "last="+strconv.Itoa(q.LastPeriods))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Matches returns true if dimensions are selected by the filters of q
+func (q Query) Matches(dimensions map[string]string) bool {
+
+	for dim, cats := range q.Filters {
+		if !slices.Contains(cats, dimensions[dim]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package exhostapi
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// Docs: https://exchangerate.host/documentation
+// a commercial provider of intraday rates. An access key is required, and each request counts towards the plan's monthly quota
+
+const (
+	apiShortname   string = "exhost"
+	defaultBaseUrl string = "https://api.exchangerate.host"
+	timeoutSecs    int    = 20
+)
+
+// ErrQuotaExceeded is returned if the monthly request quota of the plan is used up, either as reported by the API or as tracked locally
+var ErrQuotaExceeded = fmt.Errorf("quota exceeded: %w", cerrors.ErrUpstreamUnavailable)
+
+type Client struct {
+	HttpClient *http.Client
+	BaseUrl    string // API root, e.g. of a fixture server in tests. Defaults to exchangerate.host
+	AccessKey  string
+	InfoLog    *slog.Logger
+	ErrorLog   *slog.Logger
+}
+
+func NewClient(accessKey string, infoLog, errorLog *slog.Logger) (client Client) {
+
+	return Client{
+		HttpClient: &http.Client{
+			Timeout: time.Duration(timeoutSecs) * time.Second,
+		},
+		BaseUrl:   defaultBaseUrl,
+		AccessKey: accessKey,
+		InfoLog:   infoLog.With("api", apiShortname),
+		ErrorLog:  errorLog.With("api", apiShortname),
+	}
+}
+
+// baseUrl returns c.BaseUrl, or exchangerate.host if not set
+func (c Client) baseUrl() string {
+	if c.BaseUrl == "" {
+		return defaultBaseUrl
+	}
+	return strings.TrimSuffix(c.BaseUrl, "/")
+}
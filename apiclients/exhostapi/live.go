@@ -0,0 +1,155 @@
+package exhostapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+	"github.com/loveyourstack/connectors/stores/exhost/exhostrate"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+type Rate struct {
+	FromCurrency string
+	ToCurrency   string
+	Rate         float64
+	QuotedAt     time.Time
+}
+
+// liveResponse is the response of the live endpoint. The API responds with status 200 on errors, setting Success to false
+type liveResponse struct {
+	Success   bool               `json:"success"`
+	Timestamp int64              `json:"timestamp"` // unix seconds of the quotes
+	Source    string             `json:"source"`
+	Quotes    map[string]float64 `json:"quotes"` // k = source + currency, e.g. USDEUR
+	Error     struct {
+		Code int    `json:"code"`
+		Type string `json:"type"`
+		Info string `json:"info"`
+	} `json:"error"`
+}
+
+// API error codes, see the docs
+const (
+	errCodeInvalidKey      int = 101
+	errCodeInactiveAccount int = 102
+	errCodeQuotaReached    int = 104
+	errCodeNoAccess        int = 105 // the plan does not include the endpoint or param
+	errCodeInvalidSource   int = 201
+	errCodeInvalidCurrency int = 202
+)
+
+// GetApiLiveRates returns the latest rates from source to currencies, or to all available currencies if currencies is empty
+func (c Client) GetApiLiveRates(ctx context.Context, source string, currencies []string) (rates []Rate, err error) {
+
+	if c.AccessKey == "" {
+		return nil, fmt.Errorf("%w: access key is required", cerrors.ErrValidationFailed)
+	}
+
+	params := url.Values{}
+	params.Add("source", source)
+	if len(currencies) > 0 {
+		params.Add("currencies", strings.Join(currencies, ","))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseUrl()+"/live?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	auth := apiclient.APIKeyQuery{Param: "access_key", Key: c.AccessKey}
+	if err = auth.Authorize(req); err != nil {
+		return nil, fmt.Errorf("auth.Authorize failed: %w", err)
+	}
+
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("c.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: auth.Redact(err)})
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+	}
+
+	rates, err = ParseLiveJson(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ParseLiveJson failed: %w", err)
+	}
+
+	return rates, nil
+}
+
+// ParseLiveJson parses a live endpoint response into rates, ordered by to currency
+func ParseLiveJson(r io.Reader) (rates []Rate, err error) {
+
+	/* content looks like this:
+	{"success":true,"timestamp":1717430400,"source":"USD","quotes":{"USDEUR":0.919604,"USDGBP":0.781404}}
+	or on error:
+	{"success":false,"error":{"code":104,"type":"usage_limit_reached","info":"..."}}
+	*/
+
+	respS := liveResponse{}
+	if err = json.NewDecoder(r).Decode(&respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Decode failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	if !respS.Success {
+		switch respS.Error.Code {
+		case errCodeQuotaReached:
+			return nil, fmt.Errorf("%w: %s", ErrQuotaExceeded, respS.Error.Info)
+		case errCodeInvalidKey, errCodeInactiveAccount, errCodeNoAccess, errCodeInvalidSource, errCodeInvalidCurrency:
+			return nil, fmt.Errorf("%w: %s: %s", cerrors.ErrValidationFailed, respS.Error.Type, respS.Error.Info)
+		default:
+			return nil, cerrors.UpstreamError{Source: apiShortname, Err: fmt.Errorf("error %d: %s: %s", respS.Error.Code, respS.Error.Type, respS.Error.Info)}
+		}
+	}
+
+	if respS.Timestamp == 0 {
+		return nil, fmt.Errorf("%w: timestamp is missing", cerrors.ErrValidationFailed)
+	}
+	quotedAt := time.Unix(respS.Timestamp, 0).UTC()
+
+	for pair, rate := range respS.Quotes {
+
+		toCurr, ok := strings.CutPrefix(pair, respS.Source)
+		if !ok || len(toCurr) != 3 {
+			return nil, fmt.Errorf("%w: unexpected quote '%s' for source %s", cerrors.ErrValidationFailed, pair, respS.Source)
+		}
+		if rate <= 0 {
+			return nil, fmt.Errorf("%w: rate must be positive for %s", cerrors.ErrValidationFailed, pair)
+		}
+
+		rates = append(rates, Rate{FromCurrency: respS.Source, ToCurrency: toCurr, Rate: rate, QuotedAt: quotedAt})
+	}
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("%w: no quotes in response", cerrors.ErrNotFound)
+	}
+
+	sort.Slice(rates, func(i, j int) bool { return rates[i].ToCurrency < rates[j].ToCurrency })
+
+	return rates, nil
+}
+
+// RatesToInputs converts API rates to store inputs
+func RatesToInputs(apiItems []Rate) (inputs []exhostrate.Input) {
+
+	inputs = make([]exhostrate.Input, len(apiItems))
+	for i, apiItem := range apiItems {
+		inputs[i] = exhostrate.Input{
+			FromCurrency: apiItem.FromCurrency,
+			QuotedAt:     lystype.Datetime(apiItem.QuotedAt),
+			Rate:         apiItem.Rate,
+			ToCurrency:   apiItem.ToCurrency,
+		}
+	}
+
+	return inputs
+}
@@ -0,0 +1,44 @@
+package exhostapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/loveyourstack/connectors/ratesource"
+)
+
+// rateSourceCurrency is the currency live rates are requested from as a ratesource.Source, since other sources need a paid plan
+const rateSourceCurrency string = "USD"
+
+// SourceName returns the name of exchangerate.host as a ratesource.Source
+func (c Client) SourceName() string {
+	return apiShortname
+}
+
+// GetRates returns the live rates from base to quotes as the rates of the current UTC day, as a ratesource.Source
+// exchangerate.host is only queried for live rates, so no rates are returned unless the date range includes the current day. The request counts towards the plan's quota
+func (c Client) GetRates(ctx context.Context, base string, quotes []string, startDate, endDate time.Time) (rates []ratesource.Rate, err error) {
+
+	today := ratesource.Day(time.Now())
+	if ratesource.Day(endDate).Before(today) || ratesource.Day(startDate).After(today) {
+		return nil, nil
+	}
+
+	currencies := []string{}
+	if len(quotes) > 0 {
+		currencies = append(append(currencies, quotes...), base)
+	}
+
+	apiRates, err := c.GetApiLiveRates(ctx, rateSourceCurrency, currencies)
+	if err != nil {
+		return nil, fmt.Errorf("c.GetApiLiveRates failed: %w", err)
+	}
+
+	usdRates := make([]ratesource.Rate, len(apiRates))
+	for i, r := range apiRates {
+		usdRates[i] = ratesource.Rate{Day: today, Base: r.FromCurrency, Quote: r.ToCurrency, Rate: r.Rate}
+	}
+
+	return ratesource.Rebase(usdRates, base, quotes)
+}
@@ -0,0 +1,44 @@
+package frankfurterapi
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Docs: https://frankfurter.dev
+// Frankfurter republishes the ECB's euro foreign exchange reference rates, so it can stand in for the ECB data API when that is down
+
+const (
+	apiShortname   string = "frankfurter"
+	defaultBaseUrl string = "https://api.frankfurter.dev/v1"
+	timeoutSecs    int    = 20
+)
+
+type Client struct {
+	HttpClient *http.Client
+	BaseUrl    string // API root, e.g. of a self-hosted instance. Defaults to api.frankfurter.dev
+	InfoLog    *slog.Logger
+	ErrorLog   *slog.Logger
+}
+
+func NewClient(infoLog, errorLog *slog.Logger) (client Client) {
+
+	return Client{
+		HttpClient: &http.Client{
+			Timeout: time.Duration(timeoutSecs) * time.Second,
+		},
+		BaseUrl:  defaultBaseUrl,
+		InfoLog:  infoLog.With("api", apiShortname),
+		ErrorLog: errorLog.With("api", apiShortname),
+	}
+}
+
+// baseUrl returns c.BaseUrl, or api.frankfurter.dev if not set
+func (c Client) baseUrl() string {
+	if c.BaseUrl == "" {
+		return defaultBaseUrl
+	}
+	return strings.TrimSuffix(c.BaseUrl, "/")
+}
@@ -0,0 +1,124 @@
+package frankfurterapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// timeSeriesResponse is the response of the time series endpoint
+type timeSeriesResponse struct {
+	Base      string                        `json:"base"`
+	StartDate string                        `json:"start_date"`
+	EndDate   string                        `json:"end_date"`
+	Rates     map[string]map[string]float64 `json:"rates"` // k = day (YYYY-MM-DD), v = map of k = currency code, v = rate
+}
+
+// GetAPIExchangeRates returns the daily exchange rates from baseCurr to all other available currencies, in the format of the ECB client
+// Frankfurter only has daily rates, so freq must be ecbapi.Daily
+func (c Client) GetAPIExchangeRates(baseCurr string, freq ecbapi.Frequency, startDate, endDate time.Time) (exRates []ecbapi.ExchangeRate, err error) {
+
+	// validate params
+	if freq != ecbapi.Daily {
+		return nil, fmt.Errorf("%w: freq '%s' not supported, only daily rates are available", cerrors.ErrValidationFailed, freq)
+	}
+	if startDate.After(time.Now()) {
+		return nil, fmt.Errorf("%w: startDate must be before now", cerrors.ErrValidationFailed)
+	}
+	if startDate.After(endDate) {
+		return nil, fmt.Errorf("%w: startDate must be before endDate", cerrors.ErrValidationFailed)
+	}
+
+	// build URL, e.g. /2024-09-02..2024-09-06?base=EUR
+	path := fmt.Sprintf("/%s..%s", startDate.Format(lystype.DateFormat), endDate.Format(lystype.DateFormat))
+	params := url.Values{}
+	params.Add("base", baseCurr)
+	tsUrl := c.baseUrl() + path + "?" + params.Encode()
+
+	// get rates
+	resp, err := c.HttpClient.Get(tsUrl)
+	if err != nil {
+		return nil, fmt.Errorf("c.HttpClient.Get failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: no rates found for these params", cerrors.ErrNotFound)
+	case http.StatusUnprocessableEntity:
+		return nil, fmt.Errorf("%w: params rejected by the API", cerrors.ErrValidationFailed)
+	default:
+		return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+	}
+
+	exRates, err = ParseTimeSeriesJson(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ParseTimeSeriesJson failed: %w", err)
+	}
+
+	return exRates, nil
+}
+
+// ParseTimeSeriesJson parses a time series response into daily exchange rates from its base currency, ordered by day and currency
+func ParseTimeSeriesJson(r io.Reader) (exRates []ecbapi.ExchangeRate, err error) {
+
+	/* content looks like this:
+	{"amount":1.0,"base":"EUR","start_date":"2024-09-02","end_date":"2024-09-03","rates":{"2024-09-02":{"AUD":1.6322,"BGN":1.9558},"2024-09-03":{...}}}
+	*/
+
+	respS := timeSeriesResponse{}
+	if err = json.NewDecoder(r).Decode(&respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Decode failed: %w", cerrors.ErrValidationFailed, err)
+	}
+	if respS.Base == "" {
+		return nil, fmt.Errorf("%w: base missing from response", cerrors.ErrValidationFailed)
+	}
+
+	days := make([]string, 0, len(respS.Rates))
+	for day := range respS.Rates {
+		if _, err := time.Parse(lystype.DateFormat, day); err != nil {
+			return nil, fmt.Errorf("%w: invalid day '%s': %w", cerrors.ErrValidationFailed, day, err)
+		}
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	for _, day := range days {
+
+		currs := make([]string, 0, len(respS.Rates[day]))
+		for curr := range respS.Rates[day] {
+			currs = append(currs, curr)
+		}
+		sort.Strings(currs)
+
+		for _, curr := range currs {
+			rate := respS.Rates[day][curr]
+			if rate <= 0 {
+				return nil, fmt.Errorf("%w: %s %s: rate must be positive, got %v", cerrors.ErrValidationFailed, day, curr, rate)
+			}
+
+			exRates = append(exRates, ecbapi.ExchangeRate{
+				FromCurr:  respS.Base,
+				ToCurr:    curr,
+				Freq:      ecbapi.Daily,
+				PeriodStr: day,
+				Rate:      float32(rate),
+			})
+		}
+	}
+
+	if len(exRates) == 0 {
+		return nil, fmt.Errorf("%w: no rates found for these params", cerrors.ErrNotFound)
+	}
+
+	return exRates, nil
+}
@@ -0,0 +1,31 @@
+package frankfurterapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/ratesource"
+)
+
+// SourceName returns the name of Frankfurter as a ratesource.Source
+func (c Client) SourceName() string {
+	return apiShortname
+}
+
+// GetRates returns the daily rates from base to quotes, as a ratesource.Source
+func (c Client) GetRates(ctx context.Context, base string, quotes []string, startDate, endDate time.Time) (rates []ratesource.Rate, err error) {
+
+	exRates, err := c.GetAPIExchangeRates(base, ecbapi.Daily, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("c.GetAPIExchangeRates failed: %w", err)
+	}
+
+	baseRates, err := ecbapi.ExchangeRatesToRates(exRates)
+	if err != nil {
+		return nil, fmt.Errorf("ecbapi.ExchangeRatesToRates failed: %w", err)
+	}
+
+	return ratesource.Rebase(baseRates, base, quotes)
+}
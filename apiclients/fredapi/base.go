@@ -0,0 +1,105 @@
+package fredapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+)
+
+// Docs: https://fred.stlouisfed.org/docs/api/fred/
+// an API key is required: https://fred.stlouisfed.org/docs/api/api_key.html
+
+const (
+	apiShortname   string = "fred"
+	defaultBaseUrl string = "https://api.stlouisfed.org/fred"
+	timeoutSecs    int    = 20
+)
+
+type Client struct {
+	HttpClient *http.Client
+	BaseUrl    string // API root, e.g. of a fixture server in tests. Defaults to FRED
+	ApiKey     string
+	InfoLog    *slog.Logger
+	ErrorLog   *slog.Logger
+}
+
+func NewClient(apiKey string, infoLog, errorLog *slog.Logger) (client Client) {
+
+	return Client{
+		HttpClient: &http.Client{
+			Timeout: time.Duration(timeoutSecs) * time.Second,
+		},
+		BaseUrl:  defaultBaseUrl,
+		ApiKey:   apiKey,
+		InfoLog:  infoLog.With("api", apiShortname),
+		ErrorLog: errorLog.With("api", apiShortname),
+	}
+}
+
+// baseUrl returns c.BaseUrl, or FRED if not set
+func (c Client) baseUrl() string {
+	if c.BaseUrl == "" {
+		return defaultBaseUrl
+	}
+	return strings.TrimSuffix(c.BaseUrl, "/")
+}
+
+// errorResponse is the body of a FRED error response
+type errorResponse struct {
+	ErrorCode    int    `json:"error_code"`
+	ErrorMessage string `json:"error_message"`
+}
+
+// get requests path with params and the API key, and returns the response body
+func (c Client) get(ctx context.Context, path string, params url.Values) (body []byte, err error) {
+
+	if c.ApiKey == "" {
+		return nil, fmt.Errorf("%w: api key is required", cerrors.ErrValidationFailed)
+	}
+
+	params.Set("file_type", "json")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseUrl()+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	auth := apiclient.APIKeyQuery{Param: "api_key", Key: c.ApiKey}
+	if err = auth.Authorize(req); err != nil {
+		return nil, fmt.Errorf("auth.Authorize failed: %w", err)
+	}
+
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("c.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: auth.Redact(err)})
+	}
+	defer resp.Body.Close()
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadAll failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return body, nil
+	case resp.StatusCode == http.StatusBadRequest, resp.StatusCode == http.StatusNotFound:
+		// FRED responds 400 for unknown series and invalid params, with the reason in the body
+		errResp := errorResponse{}
+		json.Unmarshal(body, &errResp)
+		if strings.Contains(strings.ToLower(errResp.ErrorMessage), "does not exist") {
+			return nil, fmt.Errorf("%w: %s", cerrors.ErrNotFound, errResp.ErrorMessage)
+		}
+		return nil, fmt.Errorf("%w: %s", cerrors.ErrValidationFailed, errResp.ErrorMessage)
+	default:
+		return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+	}
+}
@@ -0,0 +1,106 @@
+package fredapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/fred/fredobservation"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+type Observation struct {
+	SeriesId string
+	Date     string // YYYY-MM-DD: the start of the period for non-daily series
+	Value    float64
+}
+
+// observationsResponse is the response of the series/observations endpoint
+type observationsResponse struct {
+	Observations []struct {
+		Date  string `json:"date"`
+		Value string `json:"value"` // "." if missing
+	} `json:"observations"`
+}
+
+// GetApiObservations returns the observations of series id between startDate and endDate (inclusive), in its native frequency
+func (c Client) GetApiObservations(ctx context.Context, id string, startDate, endDate time.Time) (observations []Observation, err error) {
+
+	if startDate.After(endDate) {
+		return nil, fmt.Errorf("%w: startDate must be before endDate", cerrors.ErrValidationFailed)
+	}
+
+	params := url.Values{}
+	params.Add("series_id", id)
+	params.Add("observation_start", startDate.Format(lystype.DateFormat))
+	params.Add("observation_end", endDate.Format(lystype.DateFormat))
+
+	body, err := c.get(ctx, "/series/observations", params)
+	if err != nil {
+		return nil, fmt.Errorf("c.get failed: %w", err)
+	}
+
+	observations, err = ParseObservationsJson(id, body)
+	if err != nil {
+		return nil, fmt.Errorf("ParseObservationsJson failed: %w", err)
+	}
+
+	return observations, nil
+}
+
+// ParseObservationsJson parses the response of the series/observations endpoint for series id. Missing observations (".") are skipped
+// unlike ECB rates, an empty result is not an error, since a series may have no observations in a short range
+func ParseObservationsJson(id string, content []byte) (observations []Observation, err error) {
+
+	respS := observationsResponse{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, obs := range respS.Observations {
+
+		if obs.Value == "." || obs.Value == "" {
+			continue
+		}
+
+		if _, err := time.Parse(lystype.DateFormat, obs.Date); err != nil {
+			return nil, fmt.Errorf("%w: invalid date '%s': %w", cerrors.ErrValidationFailed, obs.Date, err)
+		}
+
+		val, err := strconv.ParseFloat(obs.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: strconv.ParseFloat failed for %s value '%s': %w", cerrors.ErrValidationFailed, obs.Date, obs.Value, err)
+		}
+
+		observations = append(observations, Observation{SeriesId: id, Date: obs.Date, Value: val})
+	}
+
+	return observations, nil
+}
+
+// ObservationsToMap converts API observations of a single series to store models with day as key, using seriesFk as the series db id
+func ObservationsToMap(apiItems []Observation, seriesFk int64) (itemsMap map[string]fredobservation.Model, err error) {
+
+	itemsMap = make(map[string]fredobservation.Model)
+	for _, apiItem := range apiItems {
+
+		day, err := time.Parse(lystype.DateFormat, apiItem.Date)
+		if err != nil {
+			return nil, fmt.Errorf("time.Parse failed for Date '%s': %w", apiItem.Date, err)
+		}
+
+		itemsMap[apiItem.Date] = fredobservation.Model{
+			Input: fredobservation.Input{
+				Day:      lystype.Date(day),
+				SeriesFk: seriesFk,
+				Value:    apiItem.Value,
+			},
+		}
+	}
+
+	return itemsMap, nil
+}
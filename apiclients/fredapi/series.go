@@ -0,0 +1,80 @@
+package fredapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/fred/fredseries"
+)
+
+type Series struct {
+	Id                 string `json:"id"`
+	Title              string `json:"title"`
+	Frequency          string `json:"frequency_short"`
+	Units              string `json:"units"`
+	SeasonalAdjustment string `json:"seasonal_adjustment_short"`
+}
+
+// seriesResponse is the response of the series endpoint
+type seriesResponse struct {
+	Seriess []Series `json:"seriess"`
+}
+
+// GetApiSeries returns the metadata of series id, e.g. DEXUSEU
+func (c Client) GetApiSeries(ctx context.Context, id string) (series Series, err error) {
+
+	params := url.Values{}
+	params.Add("series_id", id)
+
+	body, err := c.get(ctx, "/series", params)
+	if err != nil {
+		return Series{}, fmt.Errorf("c.get failed: %w", err)
+	}
+
+	series, err = ParseSeriesJson(body)
+	if err != nil {
+		return Series{}, fmt.Errorf("ParseSeriesJson failed: %w", err)
+	}
+
+	return series, nil
+}
+
+// ParseSeriesJson parses the response of the series endpoint
+func ParseSeriesJson(content []byte) (series Series, err error) {
+
+	respS := seriesResponse{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return Series{}, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+	if len(respS.Seriess) != 1 {
+		return Series{}, fmt.Errorf("%w: expected 1 series, got %d", cerrors.ErrValidationFailed, len(respS.Seriess))
+	}
+
+	return respS.Seriess[0], nil
+}
+
+// GetSeriesMap returns the metadata of the series ids as store models, with Code as key
+func (c Client) GetSeriesMap(ctx context.Context, ids []string) (itemsMap map[string]fredseries.Model, err error) {
+
+	itemsMap = make(map[string]fredseries.Model)
+	for _, id := range ids {
+		apiItem, err := c.GetApiSeries(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("c.GetApiSeries failed for %s: %w", id, err)
+		}
+		itemsMap[apiItem.Id] = fredseries.Model{
+			Input: fredseries.Input{
+				Code:               apiItem.Id,
+				Frequency:          apiItem.Frequency,
+				SeasonalAdjustment: apiItem.SeasonalAdjustment,
+				Title:              apiItem.Title,
+				Units:              apiItem.Units,
+			},
+		}
+	}
+
+	return itemsMap, nil
+}
@@ -0,0 +1,97 @@
+package gleifapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// Docs: https://www.gleif.org/en/lei-data/gleif-api and https://www.gleif.org/en/lei-data/gleif-golden-copy/golden-copy-api
+// no API key is needed. The API is limited to 60 requests per minute per client
+
+const (
+	apiShortname             string = "gleif"
+	defaultBaseUrl           string = "https://api.gleif.org/api/v1"
+	defaultGoldenCopyBaseUrl string = "https://goldencopy.gleif.org/api/v2"
+	timeoutSecs              int    = 20
+	downloadTimeoutSecs      int    = 600 // delta files of the last month are hundreds of MB
+)
+
+type Client struct {
+	HttpClient        *http.Client
+	DownloadClient    *http.Client // used for delta files, with a longer timeout
+	BaseUrl           string       // LEI records API root, e.g. of a fixture server in tests. Defaults to GLEIF
+	GoldenCopyBaseUrl string       // golden copy API root. Defaults to GLEIF
+	InfoLog           *slog.Logger
+	ErrorLog          *slog.Logger
+}
+
+func NewClient(infoLog, errorLog *slog.Logger) (client Client) {
+
+	return Client{
+		HttpClient: &http.Client{
+			Timeout: time.Duration(timeoutSecs) * time.Second,
+		},
+		DownloadClient: &http.Client{
+			Timeout: time.Duration(downloadTimeoutSecs) * time.Second,
+		},
+		BaseUrl:           defaultBaseUrl,
+		GoldenCopyBaseUrl: defaultGoldenCopyBaseUrl,
+		InfoLog:           infoLog.With("api", apiShortname),
+		ErrorLog:          errorLog.With("api", apiShortname),
+	}
+}
+
+// baseUrl returns c.BaseUrl, or GLEIF if not set
+func (c Client) baseUrl() string {
+	if c.BaseUrl == "" {
+		return defaultBaseUrl
+	}
+	return strings.TrimSuffix(c.BaseUrl, "/")
+}
+
+// goldenCopyBaseUrl returns c.GoldenCopyBaseUrl, or GLEIF if not set
+func (c Client) goldenCopyBaseUrl() string {
+	if c.GoldenCopyBaseUrl == "" {
+		return defaultGoldenCopyBaseUrl
+	}
+	return strings.TrimSuffix(c.GoldenCopyBaseUrl, "/")
+}
+
+// get requests url and returns the response body
+func (c Client) get(ctx context.Context, url string) (body []byte, err error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.api+json")
+
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("c.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+	defer resp.Body.Close()
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadAll failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return body, nil
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", cerrors.ErrNotFound, url)
+	case http.StatusBadRequest:
+		return nil, fmt.Errorf("%w: status %d: %s", cerrors.ErrValidationFailed, resp.StatusCode, string(body))
+	default:
+		return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+	}
+}
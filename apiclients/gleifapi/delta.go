@@ -0,0 +1,310 @@
+package gleifapi
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// DeltaType is the period covered by a golden copy delta file
+type DeltaType string
+
+const (
+	IntraDay  DeltaType = "IntraDay"  // changes of the last 8 hours
+	LastDay   DeltaType = "LastDay"   // changes of the last 24 hours
+	LastWeek  DeltaType = "LastWeek"  // changes of the last 7 days
+	LastMonth DeltaType = "LastMonth" // changes of the last 31 days
+)
+
+// Coverage returns the period covered by a delta file of type dt
+func (dt DeltaType) Coverage() time.Duration {
+	switch dt {
+	case IntraDay:
+		return 8 * time.Hour
+	case LastDay:
+		return 24 * time.Hour
+	case LastWeek:
+		return 7 * 24 * time.Hour
+	case LastMonth:
+		return 31 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// ChooseDeltaType returns the smallest delta type which covers the changes published after lastApplied up to publishAt
+// ok is false if lastApplied is zero or too old for any delta file, in which case the records must be fetched in full
+func ChooseDeltaType(lastApplied, publishAt time.Time) (dt DeltaType, ok bool) {
+
+	if lastApplied.IsZero() {
+		return "", false
+	}
+
+	gap := publishAt.Sub(lastApplied)
+	for _, dt := range []DeltaType{IntraDay, LastDay, LastWeek, LastMonth} {
+		if gap <= dt.Coverage() {
+			return dt, true
+		}
+	}
+	return "", false
+}
+
+// Publish is a golden copy publication. GLEIF publishes 3 times a day
+type Publish struct {
+	PublishAt time.Time
+	DeltaUrls map[DeltaType]string // URL of the zipped CSV delta file of each type
+}
+
+// publishResponse is the response of the golden-copies/publishes/lei2/latest endpoint
+type publishResponse struct {
+	Data struct {
+		PublishDate string `json:"publish_date"`
+		DeltaFiles  map[string]struct {
+			Csv struct {
+				Url string `json:"url"`
+			} `json:"csv"`
+		} `json:"delta_files"`
+	} `json:"data"`
+}
+
+// GetLatestPublish returns the latest golden copy publication of LEI records
+func (c Client) GetLatestPublish(ctx context.Context) (publish Publish, err error) {
+
+	body, err := c.get(ctx, c.goldenCopyBaseUrl()+"/golden-copies/publishes/lei2/latest")
+	if err != nil {
+		return Publish{}, fmt.Errorf("c.get failed: %w", err)
+	}
+
+	publish, err = ParsePublishJson(body)
+	if err != nil {
+		return Publish{}, fmt.Errorf("ParsePublishJson failed: %w", err)
+	}
+
+	return publish, nil
+}
+
+// ParsePublishJson parses the response of the golden-copies/publishes/lei2/latest endpoint
+func ParsePublishJson(content []byte) (publish Publish, err error) {
+
+	respS := publishResponse{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return Publish{}, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	// publish_date is UTC, e.g. "2024-06-03 08:00:00"
+	publish.PublishAt, err = time.Parse(time.DateTime, respS.Data.PublishDate)
+	if err != nil {
+		return Publish{}, fmt.Errorf("%w: invalid publish_date '%s': %w", cerrors.ErrValidationFailed, respS.Data.PublishDate, err)
+	}
+
+	publish.DeltaUrls = make(map[DeltaType]string)
+	for _, dt := range []DeltaType{IntraDay, LastDay, LastWeek, LastMonth} {
+		if f, ok := respS.Data.DeltaFiles[string(dt)]; ok && f.Csv.Url != "" {
+			publish.DeltaUrls[dt] = f.Csv.Url
+		}
+	}
+	if len(publish.DeltaUrls) == 0 {
+		return Publish{}, fmt.Errorf("%w: no delta files found", cerrors.ErrValidationFailed)
+	}
+
+	return publish, nil
+}
+
+// GetDeltaRecords downloads the delta file of type dt of publish and returns its records for which keep returns true, or all records if keep is nil
+// the file is downloaded to a temp file and streamed, since monthly deltas are too large to hold in memory
+func (c Client) GetDeltaRecords(ctx context.Context, publish Publish, dt DeltaType, keep func(lei string) bool) (records []Record, err error) {
+
+	deltaUrl, ok := publish.DeltaUrls[dt]
+	if !ok {
+		return nil, fmt.Errorf("%w: no %s delta file in publish of %s", cerrors.ErrNotFound, dt, publish.PublishAt.Format(time.DateTime))
+	}
+
+	f, err := os.CreateTemp("", "gleif-delta-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("os.CreateTemp failed: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if err = c.download(deltaUrl, f); err != nil {
+		return nil, fmt.Errorf("c.download failed: %w", err)
+	}
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("f.Seek failed: %w", err)
+	}
+
+	records, err = ParseDeltaZip(f, size, keep)
+	if err != nil {
+		return nil, fmt.Errorf("ParseDeltaZip failed: %w", err)
+	}
+
+	return records, nil
+}
+
+// download writes the content of url to w
+func (c Client) download(url string, w io.Writer) error {
+
+	client := c.DownloadClient
+	if client == nil {
+		client = c.HttpClient
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("client.Get failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+	}
+
+	if _, err = io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("io.Copy failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+
+	return nil
+}
+
+// ParseDeltaZip parses the zipped CSV delta file in r, keeping the records for which keep returns true, or all if keep is nil
+func ParseDeltaZip(r io.ReaderAt, size int64, keep func(lei string) bool) (records []Record, err error) {
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: zip.NewReader failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	// the file name contains the publish date and delta type, e.g. 20240603-0800-gleif-goldencopy-lei2-last24hours.csv
+	var csvFile *zip.File
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, ".csv") {
+			csvFile = f
+			break
+		}
+	}
+	if csvFile == nil {
+		return nil, fmt.Errorf("%w: no csv file found in zip", cerrors.ErrValidationFailed)
+	}
+
+	rc, err := csvFile.Open()
+	if err != nil {
+		return nil, fmt.Errorf("csvFile.Open failed: %w", err)
+	}
+	defer rc.Close()
+
+	return ParseDeltaCsv(rc, keep)
+}
+
+// deltaColumns are the LEI-CDF CSV columns read from delta files
+var deltaColumns = []string{
+	"LEI",
+	"Entity.LegalName",
+	"Entity.LegalAddress.FirstAddressLine",
+	"Entity.LegalAddress.City",
+	"Entity.LegalAddress.PostalCode",
+	"Entity.LegalAddress.Country",
+	"Entity.HeadquartersAddress.FirstAddressLine",
+	"Entity.HeadquartersAddress.City",
+	"Entity.HeadquartersAddress.PostalCode",
+	"Entity.HeadquartersAddress.Country",
+	"Entity.LegalJurisdiction",
+	"Entity.EntityStatus",
+	"Registration.InitialRegistrationDate",
+	"Registration.LastUpdateDate",
+	"Registration.RegistrationStatus",
+	"Registration.NextRenewalDate",
+	"Registration.ManagingLOU",
+}
+
+// ParseDeltaCsv parses a LEI-CDF CSV delta file line by line, keeping the records for which keep returns true, or all if keep is nil
+// columns are located by header name, since the file has over 200 columns whose order may change between format versions
+func ParseDeltaCsv(r io.Reader, keep func(lei string) bool) (records []Record, err error) {
+
+	// skip a UTF-8 byte order mark, which would make the quoted first header field invalid
+	br := bufio.NewReader(r)
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+		br.Discard(3)
+	}
+
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: cr.Read failed on header: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	colIdx := make(map[string]int)
+	for i, col := range header {
+		colIdx[col] = i
+	}
+	idx := make([]int, len(deltaColumns))
+	for i, col := range deltaColumns {
+		j, ok := colIdx[col]
+		if !ok {
+			return nil, fmt.Errorf("%w: column %s not found in header", cerrors.ErrValidationFailed, col)
+		}
+		idx[i] = j
+	}
+
+	for lineNum := 2; ; lineNum++ {
+
+		lineA, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: cr.Read failed on line %d: %w", cerrors.ErrValidationFailed, lineNum, err)
+		}
+		if len(lineA) != len(header) {
+			return nil, fmt.Errorf("%w: line %d: expected %d fields, got %d", cerrors.ErrValidationFailed, lineNum, len(header), len(lineA))
+		}
+
+		field := func(i int) string { return strings.TrimSpace(lineA[idx[i]]) }
+
+		lei := field(0)
+		if keep != nil && !keep(lei) {
+			continue
+		}
+
+		rec := Record{
+			Lei:                lei,
+			LegalName:          field(1),
+			LegalAddress:       Address{Line: field(2), City: field(3), PostalCode: field(4), Country: field(5)},
+			HqAddress:          Address{Line: field(6), City: field(7), PostalCode: field(8), Country: field(9)},
+			Jurisdiction:       field(10),
+			EntityStatus:       field(11),
+			RegistrationStatus: field(14),
+			ManagingLou:        field(16),
+		}
+
+		if rec.InitialRegistrationAt, err = parseTime(field(12)); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		if rec.UpdatedAt, err = parseTime(field(13)); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		if rec.NextRenewalAt, err = parseTime(field(15)); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
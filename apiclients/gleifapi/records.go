@@ -0,0 +1,195 @@
+package gleifapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/gleif/gleifentity"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	pageSize int = 200 // maximum of the lei-records endpoint
+)
+
+// Address is a legal or headquarters address of an entity
+type Address struct {
+	Line       string // first address line
+	City       string
+	PostalCode string
+	Country    string // ISO 3166-1 alpha-2
+}
+
+// Record is a Legal Entity Identifier record, as returned by the API or contained in a golden copy delta file
+type Record struct {
+	Lei                   string
+	LegalName             string
+	LegalAddress          Address
+	HqAddress             Address
+	Jurisdiction          string
+	EntityStatus          string
+	RegistrationStatus    string
+	InitialRegistrationAt time.Time // zero if unknown
+	UpdatedAt             time.Time
+	NextRenewalAt         time.Time // zero if unknown
+	ManagingLou           string
+}
+
+// recordsResponse is the JSON:API response of the lei-records endpoint
+type recordsResponse struct {
+	Data []struct {
+		Attributes struct {
+			Lei    string `json:"lei"`
+			Entity struct {
+				LegalName struct {
+					Name string `json:"name"`
+				} `json:"legalName"`
+				LegalAddress        apiAddress `json:"legalAddress"`
+				HeadquartersAddress apiAddress `json:"headquartersAddress"`
+				Jurisdiction        string     `json:"jurisdiction"`
+				Status              string     `json:"status"`
+			} `json:"entity"`
+			Registration struct {
+				InitialRegistrationDate string `json:"initialRegistrationDate"`
+				LastUpdateDate          string `json:"lastUpdateDate"`
+				Status                  string `json:"status"`
+				NextRenewalDate         string `json:"nextRenewalDate"`
+				ManagingLou             string `json:"managingLou"`
+			} `json:"registration"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+type apiAddress struct {
+	AddressLines []string `json:"addressLines"`
+	City         string   `json:"city"`
+	PostalCode   string   `json:"postalCode"`
+	Country      string   `json:"country"`
+}
+
+func (a apiAddress) toAddress() Address {
+	addr := Address{City: a.City, PostalCode: a.PostalCode, Country: a.Country}
+	if len(a.AddressLines) > 0 {
+		addr.Line = a.AddressLines[0]
+	}
+	return addr
+}
+
+// GetApiRecords returns the records of leis, requested in pages of pageSize. Unknown LEIs are not returned
+func (c Client) GetApiRecords(ctx context.Context, leis []string) (records []Record, err error) {
+
+	for start := 0; start < len(leis); start += pageSize {
+
+		end := min(start+pageSize, len(leis))
+
+		params := url.Values{}
+		params.Add("filter[lei]", strings.Join(leis[start:end], ","))
+		params.Add("page[size]", fmt.Sprint(pageSize))
+
+		body, err := c.get(ctx, c.baseUrl()+"/lei-records?"+params.Encode())
+		if err != nil {
+			return nil, fmt.Errorf("c.get failed: %w", err)
+		}
+
+		page, err := ParseRecordsJson(body)
+		if err != nil {
+			return nil, fmt.Errorf("ParseRecordsJson failed: %w", err)
+		}
+		records = append(records, page...)
+	}
+
+	return records, nil
+}
+
+// ParseRecordsJson parses the response of the lei-records endpoint
+func ParseRecordsJson(content []byte) (records []Record, err error) {
+
+	respS := recordsResponse{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, d := range respS.Data {
+
+		a := d.Attributes
+		rec := Record{
+			Lei:                a.Lei,
+			LegalName:          a.Entity.LegalName.Name,
+			LegalAddress:       a.Entity.LegalAddress.toAddress(),
+			HqAddress:          a.Entity.HeadquartersAddress.toAddress(),
+			Jurisdiction:       a.Entity.Jurisdiction,
+			EntityStatus:       a.Entity.Status,
+			RegistrationStatus: a.Registration.Status,
+			ManagingLou:        a.Registration.ManagingLou,
+		}
+
+		if rec.InitialRegistrationAt, err = parseTime(a.Registration.InitialRegistrationDate); err != nil {
+			return nil, fmt.Errorf("%s: %w", a.Lei, err)
+		}
+		if rec.UpdatedAt, err = parseTime(a.Registration.LastUpdateDate); err != nil {
+			return nil, fmt.Errorf("%s: %w", a.Lei, err)
+		}
+		if rec.NextRenewalAt, err = parseTime(a.Registration.NextRenewalDate); err != nil {
+			return nil, fmt.Errorf("%s: %w", a.Lei, err)
+		}
+
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
+
+// parseTime parses an ISO 8601 GLEIF timestamp. An empty string returns zero
+func parseTime(s string) (t time.Time, err error) {
+
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err = time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid timestamp '%s': %w", cerrors.ErrValidationFailed, s, err)
+	}
+	return t, nil
+}
+
+// RecordsToMap converts records to a map with Lei as key
+func RecordsToMap(records []Record) (itemsMap map[string]gleifentity.Model) {
+
+	itemsMap = make(map[string]gleifentity.Model)
+	for _, rec := range records {
+		itemsMap[rec.Lei] = gleifentity.Model{Input: gleifentity.Input{
+			Lei:                    rec.Lei,
+			LegalName:              rec.LegalName,
+			LegalAddressLine:       rec.LegalAddress.Line,
+			LegalAddressCity:       rec.LegalAddress.City,
+			LegalAddressPostalCode: rec.LegalAddress.PostalCode,
+			LegalAddressCountry:    rec.LegalAddress.Country,
+			HqAddressLine:          rec.HqAddress.Line,
+			HqAddressCity:          rec.HqAddress.City,
+			HqAddressPostalCode:    rec.HqAddress.PostalCode,
+			HqAddressCountry:       rec.HqAddress.Country,
+			Jurisdiction:           rec.Jurisdiction,
+			EntityStatus:           rec.EntityStatus,
+			RegistrationStatus:     rec.RegistrationStatus,
+			InitialRegistrationAt:  optionalDatetime(rec.InitialRegistrationAt),
+			RecordUpdatedAt:        lystype.Datetime(rec.UpdatedAt),
+			NextRenewalAt:          optionalDatetime(rec.NextRenewalAt),
+			ManagingLou:            rec.ManagingLou,
+		}}
+	}
+	return itemsMap
+}
+
+// optionalDatetime returns nil if t is zero
+func optionalDatetime(t time.Time) *lystype.Datetime {
+	if t.IsZero() {
+		return nil
+	}
+	dt := lystype.Datetime(t)
+	return &dt
+}
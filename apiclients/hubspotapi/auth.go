@@ -0,0 +1,48 @@
+package hubspotapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+)
+
+// auth returns the strategy authorizing requests: the private app's access token if set, or else an OAuth access token, requested when needed
+// clients not created by NewClient don't cache the OAuth token
+func (c Client) auth() apiclient.Auth {
+
+	if c.AccessToken != "" {
+		return apiclient.Bearer(c.AccessToken)
+	}
+	return apiclient.TokenAuth{Cache: c.token, Request: c.requestAccessToken}
+}
+
+// requestAccessToken requests an access token at the OAuth token endpoint with the refresh token grant
+func (c Client) requestAccessToken(ctx context.Context) (apiclient.Token, error) {
+
+	if c.ClientId == "" || c.ClientSecret == "" || c.RefreshToken == "" {
+		return apiclient.Token{}, fmt.Errorf("%w: an access token, or client id, client secret and refresh token are required", cerrors.ErrValidationFailed)
+	}
+
+	// rejected with BAD_REFRESH_TOKEN if the app was uninstalled
+	oauth := apiclient.OAuth2{
+		HttpClient:   c.HttpClient,
+		TokenUrl:     c.baseUrl() + "/oauth/v1/token",
+		ClientId:     c.ClientId,
+		ClientSecret: c.ClientSecret,
+		RefreshToken: c.RefreshToken,
+		Source:       apiShortname,
+	}
+	return oauth.RequestToken(ctx)
+}
+
+// ParseTokenJson parses the response of the OAuth token endpoint
+func ParseTokenJson(content []byte) (token string, expiresIn time.Duration, err error) {
+
+	// content looks like this: {"token_type":"bearer","refresh_token":"6f18f21e-a743-4509-b7fd-1a5e632fffa1","access_token":"CN2zlYnmLBICAQIYgZXFLyCWp1Yoy_9GMhkAgddk-zDc-H_rOad1X2s6Qv3fmG1spSY0Og0ACgJBAAADAIADAAABQhkAgddk-03q2qdkwdXbYWCoB9g3LA97OJ9I","expires_in":1800}
+
+	t, err := apiclient.ParseTokenJson(content)
+	return t.Value, t.ExpiresIn, err
+}
@@ -0,0 +1,179 @@
+package hubspotapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+)
+
+// Docs: https://developers.hubspot.com/docs/api/crm/understanding-the-crm
+// requests are authorized with the access token of a private app, or of an OAuth app, which is requested with the refresh token of the account's install
+
+const (
+	apiShortname      string = "hubspot"
+	defaultBaseUrl    string = "https://api.hubapi.com"
+	timeoutSecs       int    = 30
+	pageSize          int    = 100                    // maximum of the CRM object list endpoints
+	requestInterval          = 110 * time.Millisecond // rate limit of 100 requests per 10 seconds
+	maxAttempts       int    = 3                      // per request, if rate limited
+	defaultRetryAfter        = 10 * time.Second       // HubSpot doesn't send Retry-After: wait for the next rate limit window
+)
+
+// copies of a Client share its access token and rate limit
+type Client struct {
+	HttpClient   *http.Client
+	BaseUrl      string // API root, e.g. of a fixture server in tests. Defaults to HubSpot
+	AccessToken  string // of a private app. If empty, an OAuth access token is requested with the credentials below
+	ClientId     string // OAuth credentials of the app
+	ClientSecret string
+	RefreshToken string // of the account's install of the OAuth app
+	InfoLog      *slog.Logger
+	ErrorLog     *slog.Logger
+
+	token   *apiclient.TokenCache
+	limiter *apiclient.Limiter
+}
+
+// NewClient returns a Client authorized with the access token of a private app
+func NewClient(privateAppToken string, infoLog, errorLog *slog.Logger) (client Client) {
+
+	return Client{
+		HttpClient: &http.Client{
+			Timeout: time.Duration(timeoutSecs) * time.Second,
+		},
+		AccessToken: privateAppToken,
+		InfoLog:     infoLog.With("api", apiShortname),
+		ErrorLog:    errorLog.With("api", apiShortname),
+		token:       &apiclient.TokenCache{},
+		limiter:     apiclient.NewLimiter(requestInterval),
+	}
+}
+
+// NewOAuthClient returns a Client authorized with OAuth access tokens of an app, requested with the refresh token of its install
+func NewOAuthClient(clientId, clientSecret, refreshToken string, infoLog, errorLog *slog.Logger) (client Client) {
+
+	client = NewClient("", infoLog, errorLog)
+	client.ClientId = clientId
+	client.ClientSecret = clientSecret
+	client.RefreshToken = refreshToken
+	return client
+}
+
+// baseUrl returns c.BaseUrl, or HubSpot if not set
+func (c Client) baseUrl() string {
+	if c.BaseUrl != "" {
+		return strings.TrimSuffix(c.BaseUrl, "/")
+	}
+	return defaultBaseUrl
+}
+
+// apiError is the error response of the CRM API
+type apiError struct {
+	Category string `json:"category"` // e.g. VALIDATION_ERROR, MISSING_SCOPES
+	Message  string `json:"message"`
+}
+
+// page is the paging envelope of the list endpoints
+type page struct {
+	Paging *struct {
+		Next struct {
+			After string `json:"after"`
+		} `json:"next"`
+	} `json:"paging"` // absent on the last page
+}
+
+// getAll requests all pages of the list endpoint path with params, following the after cursors of the responses, and calls pageFunc with each page's body
+func (c Client) getAll(ctx context.Context, path string, params url.Values, pageFunc func(body []byte) error) error {
+
+	params.Set("limit", strconv.Itoa(pageSize))
+
+	return apiclient.Cursor(ctx, apiclient.Paging{Log: c.InfoLog},
+		func(cursor string) ([]byte, error) {
+			if cursor != "" {
+				params.Set("after", cursor)
+			}
+			return c.get(ctx, path, params)
+		},
+		func(body []byte) (string, error) {
+			if err := pageFunc(body); err != nil {
+				return "", fmt.Errorf("pageFunc failed: %w", err)
+			}
+
+			p := page{}
+			if err := json.Unmarshal(body, &p); err != nil {
+				return "", fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+			}
+			if p.Paging == nil {
+				return "", nil
+			}
+			return p.Paging.Next.After, nil
+		})
+}
+
+// get requests path with params and an access token, waiting for the rate limit, and returns the response body
+// rate limited requests (429) are retried after the next rate limit window
+func (c Client) get(ctx context.Context, path string, params url.Values) (body []byte, err error) {
+
+	reqUrl := c.baseUrl() + path
+	if len(params) > 0 {
+		reqUrl += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	if err = c.auth().Authorize(req); err != nil {
+		return nil, fmt.Errorf("c.auth().Authorize failed: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	for attempt := 1; ; attempt++ {
+
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("c.limiter.Wait failed: %w", err)
+		}
+
+		resp, err := c.HttpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("c.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+		}
+
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("io.ReadAll failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return body, nil
+		case resp.StatusCode == http.StatusNotFound:
+			return nil, fmt.Errorf("%w: %s", cerrors.ErrNotFound, path)
+		case resp.StatusCode == http.StatusTooManyRequests:
+			if attempt == maxAttempts {
+				return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+			}
+			retryAfter := apiclient.ParseRetryAfter(resp.Header.Get("Retry-After"), defaultRetryAfter)
+			c.InfoLog.Warn("rate limited, retrying", "path", path, "retry_after", retryAfter.String())
+			c.limiter.Pause(retryAfter)
+		case resp.StatusCode >= 400 && resp.StatusCode < 500:
+			// e.g. 400 unknown property, 401 invalid token, 403 missing scope
+			errS := apiError{}
+			_ = json.Unmarshal(body, &errS)
+			return nil, fmt.Errorf("%w: status %d: %s: %s", cerrors.ErrValidationFailed, resp.StatusCode, errS.Category, errS.Message)
+		default:
+			return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package hubspotapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/hubspot/hubcompany"
+	"github.com/loveyourstack/connectors/stores/hubspot/hubcontact"
+	"github.com/loveyourstack/connectors/stores/hubspot/hubdeal"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// the properties stored in columns of each object type
+var (
+	companyColumns = []string{"city", "country", "domain", "industry", "name", "phone"}
+	contactColumns = []string{"associatedcompanyid", "email", "firstname", "jobtitle", "lastname", "lifecyclestage", "phone"}
+	dealColumns    = []string{"amount", "closedate", "deal_currency_code", "dealname", "dealstage", "pipeline"}
+)
+
+// GetApiCompanies returns all companies with the column properties and those of propMap
+func (c Client) GetApiCompanies(ctx context.Context, propMap PropertyMap) (objects []Object, err error) {
+	return c.GetApiObjects(ctx, ObjectCompanies, requestedProperties(companyColumns, propMap), false)
+}
+
+// GetApiContacts returns all contacts with the column properties and those of propMap
+func (c Client) GetApiContacts(ctx context.Context, propMap PropertyMap) (objects []Object, err error) {
+	return c.GetApiObjects(ctx, ObjectContacts, requestedProperties(contactColumns, propMap), false)
+}
+
+// GetApiDeals returns all deals with the column properties and those of propMap, and their associated companies
+func (c Client) GetApiDeals(ctx context.Context, propMap PropertyMap) (objects []Object, err error) {
+	return c.GetApiObjects(ctx, ObjectDeals, requestedProperties(dealColumns, propMap), true)
+}
+
+// CompaniesToMap converts companies to a map with HubspotId as key
+func CompaniesToMap(objects []Object, propMap PropertyMap) (itemsMap map[int64]hubcompany.Model, err error) {
+
+	itemsMap = make(map[int64]hubcompany.Model)
+	for _, o := range objects {
+
+		if _, ok := itemsMap[o.HubspotId]; ok {
+			return nil, fmt.Errorf("%w: duplicate company %d", cerrors.ErrValidationFailed, o.HubspotId)
+		}
+
+		itemsMap[o.HubspotId] = hubcompany.Model{Input: hubcompany.Input{
+			City:             o.Properties["city"],
+			Country:          o.Properties["country"],
+			CreatedAtHubspot: lystype.Datetime(o.CreatedAt),
+			Domain:           o.Properties["domain"],
+			HubspotId:        o.HubspotId,
+			Industry:         o.Properties["industry"],
+			Name:             o.Properties["name"],
+			Phone:            o.Properties["phone"],
+			Properties:       mappedProperties(o, propMap),
+			UpdatedAtHubspot: lystype.Datetime(o.UpdatedAt),
+		}}
+	}
+
+	return itemsMap, nil
+}
+
+// ContactsToMap converts contacts to a map with HubspotId as key
+func ContactsToMap(objects []Object, propMap PropertyMap) (itemsMap map[int64]hubcontact.Model, err error) {
+
+	itemsMap = make(map[int64]hubcontact.Model)
+	for _, o := range objects {
+
+		if _, ok := itemsMap[o.HubspotId]; ok {
+			return nil, fmt.Errorf("%w: duplicate contact %d", cerrors.ErrValidationFailed, o.HubspotId)
+		}
+
+		companyId, err := parseOptionalId(o, "associatedcompanyid")
+		if err != nil {
+			return nil, fmt.Errorf("parseOptionalId failed: %w", err)
+		}
+
+		itemsMap[o.HubspotId] = hubcontact.Model{Input: hubcontact.Input{
+			CompanyHubspotId: companyId,
+			CreatedAtHubspot: lystype.Datetime(o.CreatedAt),
+			Email:            o.Properties["email"],
+			FirstName:        o.Properties["firstname"],
+			HubspotId:        o.HubspotId,
+			JobTitle:         o.Properties["jobtitle"],
+			LastName:         o.Properties["lastname"],
+			LifecycleStage:   o.Properties["lifecyclestage"],
+			Phone:            o.Properties["phone"],
+			Properties:       mappedProperties(o, propMap),
+			UpdatedAtHubspot: lystype.Datetime(o.UpdatedAt),
+		}}
+	}
+
+	return itemsMap, nil
+}
+
+// DealsToMap converts deals to a map with HubspotId as key
+func DealsToMap(objects []Object, propMap PropertyMap) (itemsMap map[int64]hubdeal.Model, err error) {
+
+	itemsMap = make(map[int64]hubdeal.Model)
+	for _, o := range objects {
+
+		if _, ok := itemsMap[o.HubspotId]; ok {
+			return nil, fmt.Errorf("%w: duplicate deal %d", cerrors.ErrValidationFailed, o.HubspotId)
+		}
+
+		amount, err := parseOptionalAmount(o, "amount")
+		if err != nil {
+			return nil, fmt.Errorf("parseOptionalAmount failed: %w", err)
+		}
+		closeDate, err := parseOptionalDatetime(o, "closedate")
+		if err != nil {
+			return nil, fmt.Errorf("parseOptionalDatetime failed: %w", err)
+		}
+
+		item := hubdeal.Model{Input: hubdeal.Input{
+			Amount:           amount,
+			CloseDate:        closeDate,
+			CreatedAtHubspot: lystype.Datetime(o.CreatedAt),
+			Currency:         o.Properties["deal_currency_code"],
+			DealStage:        o.Properties["dealstage"],
+			HubspotId:        o.HubspotId,
+			Name:             o.Properties["dealname"],
+			Pipeline:         o.Properties["pipeline"],
+			Properties:       mappedProperties(o, propMap),
+			UpdatedAtHubspot: lystype.Datetime(o.UpdatedAt),
+		}}
+		if len(o.CompanyIds) > 0 {
+			item.CompanyHubspotId = &o.CompanyIds[0]
+		}
+
+		itemsMap[o.HubspotId] = item
+	}
+
+	return itemsMap, nil
+}
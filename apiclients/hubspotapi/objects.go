@@ -0,0 +1,202 @@
+package hubspotapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// ObjectType is a CRM object type, as used in the API paths
+type ObjectType string
+
+const (
+	ObjectCompanies ObjectType = "companies"
+	ObjectContacts  ObjectType = "contacts"
+	ObjectDeals     ObjectType = "deals"
+)
+
+// PropertyMap maps keys of a store's properties column to HubSpot property names, e.g. lead_status = "hs_lead_status"
+// the mapped properties are synced in addition to the ones stored in columns
+type PropertyMap map[string]string
+
+// Validate returns an error if a key or property name of m is empty
+func (m PropertyMap) Validate() error {
+
+	for key, prop := range m {
+		if key == "" || prop == "" {
+			return fmt.Errorf("%w: empty key or property name in mapping %q = %q", cerrors.ErrValidationFailed, key, prop)
+		}
+	}
+	return nil
+}
+
+// Object is a CRM object of any type
+type Object struct {
+	HubspotId  int64
+	Properties map[string]string // k = HubSpot property name. Properties without value are absent
+	CompanyIds []int64           // associated companies, if requested
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// GetApiObjects returns all objects of objectType which are not archived, with properties, and with their associated companies if withCompanies is set
+func (c Client) GetApiObjects(ctx context.Context, objectType ObjectType, properties []string, withCompanies bool) (objects []Object, err error) {
+
+	params := url.Values{}
+	params.Set("properties", strings.Join(properties, ","))
+	params.Set("archived", "false")
+	if withCompanies {
+		params.Set("associations", string(ObjectCompanies))
+	}
+
+	err = c.getAll(ctx, "/crm/v3/objects/"+string(objectType), params, func(body []byte) error {
+		page, err := ParseObjectsJson(body)
+		if err != nil {
+			return fmt.Errorf("ParseObjectsJson failed: %w", err)
+		}
+		objects = append(objects, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("c.getAll failed: %w", err)
+	}
+
+	return objects, nil
+}
+
+// ParseObjectsJson parses a page of a CRM object list endpoint
+func ParseObjectsJson(content []byte) (objects []Object, err error) {
+
+	/* content looks like this:
+	{"results":[{"id":"512","properties":{"dealname":"Acme renewal","amount":"1500.00","closedate":"2024-03-28T16:50:06.678Z","dealstage":"closedwon",
+	  "hs_object_id":"512","pipeline":"default"},"createdAt":"2024-01-30T03:30:17.883Z","updatedAt":"2024-03-28T16:51:12.021Z","archived":false,
+	  "associations":{"companies":{"results":[{"id":"6172","type":"deal_to_company"},{"id":"6172","type":"deal_to_company_unlabeled"}]}}}],
+	 "paging":{"next":{"after":"513","link":"https://api.hubapi.com/crm/v3/objects/deals?after=513"}}}
+	*/
+
+	respS := struct {
+		Results []struct {
+			Id           string             `json:"id"`
+			Properties   map[string]*string `json:"properties"`
+			CreatedAt    time.Time          `json:"createdAt"`
+			UpdatedAt    time.Time          `json:"updatedAt"`
+			Associations map[string]struct {
+				Results []struct {
+					Id string `json:"id"`
+				} `json:"results"`
+			} `json:"associations"`
+		} `json:"results"`
+	}{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, apiObject := range respS.Results {
+
+		hubspotId, err := strconv.ParseInt(apiObject.Id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid object id: %s", cerrors.ErrValidationFailed, apiObject.Id)
+		}
+
+		object := Object{
+			HubspotId:  hubspotId,
+			Properties: make(map[string]string),
+			CreatedAt:  apiObject.CreatedAt,
+			UpdatedAt:  apiObject.UpdatedAt,
+		}
+		for prop, val := range apiObject.Properties {
+			if val != nil && *val != "" {
+				object.Properties[prop] = *val
+			}
+		}
+
+		// a company is listed once per association type
+		for _, assoc := range apiObject.Associations[string(ObjectCompanies)].Results {
+			companyId, err := strconv.ParseInt(assoc.Id, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid company id of object %d: %s", cerrors.ErrValidationFailed, hubspotId, assoc.Id)
+			}
+			if !slices.Contains(object.CompanyIds, companyId) {
+				object.CompanyIds = append(object.CompanyIds, companyId)
+			}
+		}
+
+		objects = append(objects, object)
+	}
+
+	return objects, nil
+}
+
+// requestedProperties returns the sorted, distinct property names of columns and of propMap
+func requestedProperties(columns []string, propMap PropertyMap) []string {
+
+	props := slices.Clone(columns)
+	for _, prop := range propMap {
+		props = append(props, prop)
+	}
+	slices.Sort(props)
+	return slices.Compact(props)
+}
+
+// mappedProperties returns the properties of o mapped by propMap, with the keys of propMap
+func mappedProperties(o Object, propMap PropertyMap) map[string]string {
+
+	props := make(map[string]string)
+	for key, prop := range propMap {
+		if val, ok := o.Properties[prop]; ok {
+			props[key] = val
+		}
+	}
+	return props
+}
+
+// parseOptionalId parses the id in property prop of o, returning nil if it has no value
+func parseOptionalId(o Object, prop string) (id *int64, err error) {
+
+	val, ok := o.Properties[prop]
+	if !ok {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid %s of object %d: %s", cerrors.ErrValidationFailed, prop, o.HubspotId, val)
+	}
+	return &n, nil
+}
+
+// parseOptionalAmount parses the amount in property prop of o, returning nil if it has no value
+func parseOptionalAmount(o Object, prop string) (amount *float64, err error) {
+
+	val, ok := o.Properties[prop]
+	if !ok {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid %s of object %d: %s", cerrors.ErrValidationFailed, prop, o.HubspotId, val)
+	}
+	return &f, nil
+}
+
+// parseOptionalDatetime parses the timestamp in property prop of o, returning nil if it has no value
+func parseOptionalDatetime(o Object, prop string) (dt *lystype.Datetime, err error) {
+
+	val, ok := o.Properties[prop]
+	if !ok {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid %s of object %d: %s", cerrors.ErrValidationFailed, prop, o.HubspotId, val)
+	}
+	ldt := lystype.Datetime(t)
+	return &ldt, nil
+}
@@ -0,0 +1,116 @@
+package imapapi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// Attachment is a file attached to a message
+type Attachment struct {
+	Filename    string
+	ContentType string // e.g. text/csv
+	Content     []byte // decoded
+}
+
+// ParseAttachments returns the attachments of the raw message, in the order of its MIME parts. Parts without a file name, such as the message text, are skipped
+func ParseAttachments(raw []byte) (attachments []Attachment, err error) {
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%w: mail.ReadMessage failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	attachments = []Attachment{}
+	if err = walkParts(textproto.MIMEHeader(msg.Header), msg.Body, &attachments); err != nil {
+		return nil, fmt.Errorf("walkParts failed: %w", err)
+	}
+
+	return attachments, nil
+}
+
+// walkParts appends the attachments of the part with header and body to attachments, descending into multipart parts
+func walkParts(header textproto.MIMEHeader, body io.Reader, attachments *[]Attachment) error {
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		// RFC 2045 default
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			// NextRawPart, since NextPart only decodes quoted-printable. Decoding is done for both encodings in partContent
+			part, err := mr.NextRawPart()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("%w: mr.NextRawPart failed: %w", cerrors.ErrValidationFailed, err)
+			}
+			if err = walkParts(part.Header, part, attachments); err != nil {
+				return err
+			}
+		}
+	}
+
+	filename := ""
+	if _, dispParams, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		filename = dispParams["filename"]
+	}
+	if filename == "" {
+		filename = params["name"]
+	}
+	if filename == "" {
+		return nil
+	}
+
+	content, err := partContent(header.Get("Content-Transfer-Encoding"), body)
+	if err != nil {
+		return fmt.Errorf("partContent failed on %s: %w", filename, err)
+	}
+
+	*attachments = append(*attachments, Attachment{
+		Filename:    decodeHeader(filename),
+		ContentType: mediaType,
+		Content:     content,
+	})
+	return nil
+}
+
+// partContent returns body decoded according to its Content-Transfer-Encoding
+func partContent(encoding string, body io.Reader) ([]byte, error) {
+
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		// line breaks are ignored by the decoder
+		content, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, body))
+		if err != nil {
+			return nil, fmt.Errorf("%w: base64 decoding failed: %w", cerrors.ErrValidationFailed, err)
+		}
+		return content, nil
+	case "quoted-printable":
+		content, err := io.ReadAll(quotedprintable.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("%w: quoted-printable decoding failed: %w", cerrors.ErrValidationFailed, err)
+		}
+		return content, nil
+	default:
+		content, err := io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("io.ReadAll failed: %w", err)
+		}
+		return content, nil
+	}
+}
@@ -0,0 +1,199 @@
+package imapapi
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// Docs: https://www.rfc-editor.org/rfc/rfc3501 (IMAP4rev1)
+// only the commands needed to read a mailbox are implemented. The mailbox is opened read-only with EXAMINE, so messages keep their flags
+
+const (
+	apiShortname   string = "imap"
+	defaultPort    string = "993"
+	timeoutSecs    int    = 60 // per command
+	maxLiteralSize int    = 50 << 20
+)
+
+// Client opens a connection per call, so calls don't share IMAP session state
+type Client struct {
+	Address  string // host:port of the IMAPS (implicit TLS) server, e.g. imap.gmail.com:993. The port defaults to 993
+	Username string
+	Password string // e.g. an app password
+	Mailbox  string // e.g. INBOX
+	InfoLog  *slog.Logger
+	ErrorLog *slog.Logger
+}
+
+func NewClient(address, username, password, mailbox string, infoLog, errorLog *slog.Logger) (client Client) {
+
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, defaultPort)
+	}
+	if mailbox == "" {
+		mailbox = "INBOX"
+	}
+
+	return Client{
+		Address:  address,
+		Username: username,
+		Password: password,
+		Mailbox:  mailbox,
+		InfoLog:  infoLog.With("api", apiShortname),
+		ErrorLog: errorLog.With("api", apiShortname),
+	}
+}
+
+// response is an untagged server response: its text, with each literal replaced by literalPlaceholder, and the literals in order
+type response struct {
+	Text     string
+	Literals [][]byte
+}
+
+const literalPlaceholder string = "\x00"
+
+var literalRegex = regexp.MustCompile(`\{(\d+)\+?\}$`)
+
+// session is an authenticated connection with the mailbox selected
+type session struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	tagNum int
+}
+
+// open connects, logs in and examines the mailbox, returning its UIDVALIDITY
+func (c Client) open() (s *session, uidValidity int64, err error) {
+
+	host, _, _ := net.SplitHostPort(c.Address)
+	dialer := &net.Dialer{Timeout: time.Duration(timeoutSecs) * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", c.Address, &tls.Config{ServerName: host})
+	if err != nil {
+		return nil, 0, fmt.Errorf("tls.DialWithDialer failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+	s = &session{conn: conn, r: bufio.NewReader(conn)}
+
+	// greeting
+	conn.SetDeadline(time.Now().Add(time.Duration(timeoutSecs) * time.Second))
+	greeting, err := s.readResponse()
+	if err != nil {
+		s.close()
+		return nil, 0, fmt.Errorf("s.readResponse failed: %w", err)
+	}
+	if !strings.HasPrefix(greeting.Text, "* OK") {
+		s.close()
+		return nil, 0, cerrors.UpstreamError{Source: apiShortname, Err: fmt.Errorf("unexpected greeting: %s", greeting.Text)}
+	}
+
+	if _, err = s.command("LOGIN " + quote(c.Username) + " " + quote(c.Password)); err != nil {
+		s.close()
+		return nil, 0, fmt.Errorf("LOGIN failed: %w", err)
+	}
+
+	resps, err := s.command("EXAMINE " + quote(c.Mailbox))
+	if err != nil {
+		s.logout()
+		return nil, 0, fmt.Errorf("EXAMINE failed: %w", err)
+	}
+	for _, resp := range resps {
+		if m := uidValidityRegex.FindStringSubmatch(resp.Text); m != nil {
+			uidValidity, _ = strconv.ParseInt(m[1], 10, 64)
+		}
+	}
+
+	return s, uidValidity, nil
+}
+
+var uidValidityRegex = regexp.MustCompile(`\[UIDVALIDITY (\d+)\]`)
+
+// command sends cmd and returns the untagged responses until its tagged completion, or an error if it did not complete with OK
+func (s *session) command(cmd string) (resps []response, err error) {
+
+	s.tagNum++
+	tag := fmt.Sprintf("A%03d", s.tagNum)
+
+	s.conn.SetDeadline(time.Now().Add(time.Duration(timeoutSecs) * time.Second))
+	if _, err = io.WriteString(s.conn, tag+" "+cmd+"\r\n"); err != nil {
+		return nil, cerrors.UpstreamError{Source: apiShortname, Err: err}
+	}
+
+	for {
+		resp, err := s.readResponse()
+		if err != nil {
+			return nil, err
+		}
+
+		if !strings.HasPrefix(resp.Text, tag+" ") {
+			resps = append(resps, resp)
+			continue
+		}
+
+		status := strings.TrimPrefix(resp.Text, tag+" ")
+		switch {
+		case strings.HasPrefix(status, "OK"):
+			return resps, nil
+		case strings.HasPrefix(status, "NO"):
+			// e.g. invalid credentials or unknown mailbox
+			return nil, fmt.Errorf("%w: %s", cerrors.ErrValidationFailed, status)
+		default:
+			return nil, cerrors.UpstreamError{Source: apiShortname, Err: fmt.Errorf("command failed: %s", status)}
+		}
+	}
+}
+
+// readResponse reads a response line, including any literals it contains
+func (s *session) readResponse() (resp response, err error) {
+
+	text := strings.Builder{}
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			return response{}, cerrors.UpstreamError{Source: apiShortname, Err: err}
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		m := literalRegex.FindStringSubmatchIndex(line)
+		if m == nil {
+			text.WriteString(line)
+			resp.Text = text.String()
+			return resp, nil
+		}
+
+		size, _ := strconv.Atoi(line[m[2]:m[3]])
+		if size > maxLiteralSize {
+			return response{}, cerrors.UpstreamError{Source: apiShortname, Err: fmt.Errorf("literal of %d bytes exceeds %d", size, maxLiteralSize)}
+		}
+		literal := make([]byte, size)
+		if _, err = io.ReadFull(s.r, literal); err != nil {
+			return response{}, cerrors.UpstreamError{Source: apiShortname, Err: err}
+		}
+
+		text.WriteString(line[:m[0]] + literalPlaceholder)
+		resp.Literals = append(resp.Literals, literal)
+	}
+}
+
+// logout ends the session, ignoring errors: the data needed was already read
+func (s *session) logout() {
+	_, _ = s.command("LOGOUT")
+	s.close()
+}
+
+func (s *session) close() {
+	s.conn.Close()
+}
+
+// quote returns str as an IMAP quoted string
+func quote(str string) string {
+	str = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r", "", "\n", "").Replace(str)
+	return `"` + str + `"`
+}
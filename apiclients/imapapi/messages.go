@@ -0,0 +1,168 @@
+package imapapi
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+const (
+	fetchBatchSize  int   = 200
+	maxMessageBytes int64 = 25 << 20 // larger messages are skipped
+	searchDateFmt         = "02-Jan-2006"
+	headerFields          = "MESSAGE-ID FROM SUBJECT DATE"
+)
+
+// Message is a message of the mailbox
+type Message struct {
+	Uid         int64
+	UidValidity int64 // UIDs are only unique within a UIDVALIDITY of the mailbox
+	MessageId   string
+	From        string // sender address, lower case
+	Subject     string
+	Date        time.Time
+	Size        int64
+	Raw         []byte // the full RFC 5322 message. Only set for the messages wanted by GetMessages
+}
+
+var (
+	fetchUidRegex  = regexp.MustCompile(`\bUID (\d+)`)
+	fetchSizeRegex = regexp.MustCompile(`\bRFC822\.SIZE (\d+)`)
+)
+
+// GetMessages returns the messages received since the day of since, in UID order, with the full content of those for which want returns true
+// want is called with the message headers. Wanted messages larger than maxMessageBytes are logged and skipped
+func (c Client) GetMessages(since time.Time, want func(m Message) bool) (messages []Message, err error) {
+
+	s, uidValidity, err := c.open()
+	if err != nil {
+		return nil, fmt.Errorf("c.open failed: %w", err)
+	}
+	defer s.logout()
+
+	resps, err := s.command("UID SEARCH SINCE " + since.Format(searchDateFmt))
+	if err != nil {
+		return nil, fmt.Errorf("UID SEARCH failed: %w", err)
+	}
+	uids := []string{}
+	for _, resp := range resps {
+		if strings.HasPrefix(resp.Text, "* SEARCH") {
+			uids = append(uids, strings.Fields(strings.TrimPrefix(resp.Text, "* SEARCH"))...)
+		}
+	}
+
+	for start := 0; start < len(uids); start += fetchBatchSize {
+		end := min(start+fetchBatchSize, len(uids))
+
+		resps, err := s.command("UID FETCH " + strings.Join(uids[start:end], ",") + " (UID RFC822.SIZE BODY.PEEK[HEADER.FIELDS (" + headerFields + ")])")
+		if err != nil {
+			return nil, fmt.Errorf("UID FETCH (headers) failed: %w", err)
+		}
+
+		for _, resp := range resps {
+			if !strings.Contains(resp.Text, " FETCH (") {
+				continue
+			}
+
+			m, err := ParseFetchHeaders(resp.Text, resp.Literals)
+			if err != nil {
+				return nil, fmt.Errorf("ParseFetchHeaders failed: %w", err)
+			}
+			m.UidValidity = uidValidity
+
+			if want(m) {
+				if m.Size > maxMessageBytes {
+					c.ErrorLog.Warn("message skipped: too large", slog.String("message_id", m.MessageId), slog.Int64("size", m.Size))
+					continue
+				}
+				if m.Raw, err = s.fetchRaw(m.Uid); err != nil {
+					return nil, fmt.Errorf("s.fetchRaw failed on UID: %v: %w", m.Uid, err)
+				}
+			}
+			messages = append(messages, m)
+		}
+	}
+
+	return messages, nil
+}
+
+// fetchRaw returns the full content of the message with uid
+func (s *session) fetchRaw(uid int64) (raw []byte, err error) {
+
+	resps, err := s.command("UID FETCH " + strconv.FormatInt(uid, 10) + " (UID BODY.PEEK[])")
+	if err != nil {
+		return nil, fmt.Errorf("UID FETCH (body) failed: %w", err)
+	}
+	for _, resp := range resps {
+		if strings.Contains(resp.Text, " FETCH (") && len(resp.Literals) > 0 {
+			return resp.Literals[0], nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: message not returned", cerrors.ErrNotFound)
+}
+
+// ParseFetchHeaders parses a FETCH response of the UID, RFC822.SIZE and header fields of a message
+func ParseFetchHeaders(text string, literals [][]byte) (m Message, err error) {
+
+	/* text looks like this, with the header fields as a literal:
+	* 12 FETCH (UID 4711 RFC822.SIZE 48210 BODY[HEADER.FIELDS (MESSAGE-ID FROM SUBJECT DATE)] \x00)
+
+	the literal looks like this:
+	Message-ID: <20240501061244.4A2B@mail.acme.example>
+	From: Acme Reports <reports@acme.example>
+	Subject: =?UTF-8?Q?Preisliste_Mai?=
+	Date: Wed, 01 May 2024 08:12:44 +0200
+	*/
+
+	uidMatch := fetchUidRegex.FindStringSubmatch(text)
+	if uidMatch == nil {
+		return Message{}, fmt.Errorf("%w: no UID: %s", cerrors.ErrValidationFailed, text)
+	}
+	m.Uid, _ = strconv.ParseInt(uidMatch[1], 10, 64)
+
+	if sizeMatch := fetchSizeRegex.FindStringSubmatch(text); sizeMatch != nil {
+		m.Size, _ = strconv.ParseInt(sizeMatch[1], 10, 64)
+	}
+
+	if len(literals) == 0 {
+		return m, nil
+	}
+
+	header, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(append(literals[0], "\r\n"...)))).ReadMIMEHeader()
+	if err != nil && len(header) == 0 {
+		return Message{}, fmt.Errorf("%w: ReadMIMEHeader failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	m.MessageId = strings.Trim(strings.TrimSpace(header.Get("Message-Id")), "<>")
+	m.Subject = decodeHeader(header.Get("Subject"))
+	if addr, err := mail.ParseAddress(decodeHeader(header.Get("From"))); err == nil {
+		m.From = strings.ToLower(addr.Address)
+	}
+	if date, err := mail.ParseDate(header.Get("Date")); err == nil {
+		m.Date = date
+	}
+
+	return m, nil
+}
+
+// decodeHeader decodes the RFC 2047 encoded words of a header value, returning it as is if it can't be decoded
+func decodeHeader(s string) string {
+
+	dec := mime.WordDecoder{}
+	decoded, err := dec.DecodeHeader(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
@@ -0,0 +1,98 @@
+package imapapi
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/flatfile"
+)
+
+// Rule selects the attachments of a partner delivery by sender, subject and file name, and sets the feed they are ingested into
+type Rule struct {
+	Feed      string            `toml:"feed"`      // name of the feed, stored as the feed of files and records
+	From      string            `toml:"from"`      // sender address, or @domain for any sender of the domain. Optional
+	Subject   string            `toml:"subject"`   // text the subject contains, case-insensitive. Optional
+	Filename  string            `toml:"filename"`  // glob of attachment file names, case-insensitive, e.g. "prices_*.xlsx". Defaults to all
+	Format    string            `toml:"format"`    // csv, json or xlsx. Defaults to the file name extension
+	Delimiter string            `toml:"delimiter"` // of csv files. Defaults to ","
+	Sheet     string            `toml:"sheet"`     // of xlsx files. Defaults to the first sheet
+	Mapping   *flatfile.Mapping `toml:"mapping"`   // optional: the records are also upserted into the columns of a table
+}
+
+// WithDefaults returns r with defaults applied to unset fields
+func (r Rule) WithDefaults() Rule {
+
+	if r.Filename == "" {
+		r.Filename = "*"
+	}
+	r.From = strings.ToLower(r.From)
+	if r.Mapping != nil {
+		m := r.Mapping.WithDefaults()
+		r.Mapping = &m
+	}
+
+	return r
+}
+
+// Validate returns an error if r, with defaults applied, is incomplete or invalid
+func (r Rule) Validate() error {
+
+	if _, err := path.Match(r.Filename, ""); err != nil {
+		return fmt.Errorf("%w: feed %s: filename: %w", cerrors.ErrValidationFailed, r.Feed, err)
+	}
+
+	// validates the feed name, any explicit format and the mapping
+	f := flatfile.Feed{Name: r.Feed, Format: r.Format, Delimiter: r.Delimiter, Mapping: r.Mapping}.WithDefaults()
+	return f.Validate()
+}
+
+// MatchesMessage returns true if the sender and subject of m match r
+func (r Rule) MatchesMessage(m Message) bool {
+
+	switch {
+	case r.From == "":
+	case strings.HasPrefix(r.From, "@"):
+		if !strings.HasSuffix(m.From, r.From) {
+			return false
+		}
+	default:
+		if m.From != r.From {
+			return false
+		}
+	}
+
+	return r.Subject == "" || strings.Contains(strings.ToLower(m.Subject), strings.ToLower(r.Subject))
+}
+
+// MatchesFile returns true if filename matches r and its format is known
+func (r Rule) MatchesFile(filename string) bool {
+
+	ok, _ := path.Match(strings.ToLower(r.Filename), strings.ToLower(filename))
+	return ok && r.format(filename) != ""
+}
+
+// FileFeed returns the feed which parses the attachment filename
+func (r Rule) FileFeed(filename string) flatfile.Feed {
+	return flatfile.Feed{Name: r.Feed, Format: r.format(filename), Delimiter: r.Delimiter, Sheet: r.Sheet, Mapping: r.Mapping}.WithDefaults()
+}
+
+// format returns r.Format, or the format of the extension of filename, or "" if it is unknown
+func (r Rule) format(filename string) string {
+
+	if r.Format != "" {
+		return r.Format
+	}
+
+	switch ext := strings.ToLower(path.Ext(filename)); ext {
+	case ".csv", ".txt":
+		return flatfile.FormatCsv
+	case ".json", ".ndjson", ".jsonl":
+		return flatfile.FormatJson
+	case ".xlsx":
+		return flatfile.FormatXlsx
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,66 @@
+package imfapi
+
+// currencyCodes maps the currency names used in IMF exchange rate reports to ISO 4217 codes
+// names not listed are skipped when parsing, and logged by GetApiExchangeRates
+var currencyCodes = map[string]string{
+	"Algerian dinar":      "DZD",
+	"Australian dollar":   "AUD",
+	"Bahrain dinar":       "BHD",
+	"Bolivar Fuerte":      "VEF",
+	"Botswana pula":       "BWP",
+	"Brazilian real":      "BRL",
+	"Brunei dollar":       "BND",
+	"Canadian dollar":     "CAD",
+	"Chilean peso":        "CLP",
+	"Chinese yuan":        "CNY",
+	"Colombian peso":      "COP",
+	"Czech koruna":        "CZK",
+	"Danish krone":        "DKK",
+	"Euro":                "EUR",
+	"Hungarian forint":    "HUF",
+	"Icelandic krona":     "ISK",
+	"Indian rupee":        "INR",
+	"Indonesian rupiah":   "IDR",
+	"Iranian rial":        "IRR",
+	"Israeli New Shekel":  "ILS",
+	"Japanese yen":        "JPY",
+	"Kazakhstani tenge":   "KZT",
+	"Korean won":          "KRW",
+	"Kuwaiti dinar":       "KWD",
+	"Libyan dinar":        "LYD",
+	"Malaysian ringgit":   "MYR",
+	"Mauritian rupee":     "MUR",
+	"Mexican peso":        "MXN",
+	"Nepalese rupee":      "NPR",
+	"New Zealand dollar":  "NZD",
+	"Norwegian krone":     "NOK",
+	"Omani rial":          "OMR",
+	"Pakistani rupee":     "PKR",
+	"Peruvian sol":        "PEN",
+	"Philippine peso":     "PHP",
+	"Polish zloty":        "PLN",
+	"Qatari riyal":        "QAR",
+	"Russian ruble":       "RUB",
+	"Saudi Arabian riyal": "SAR",
+	"Singapore dollar":    "SGD",
+	"South African rand":  "ZAR",
+	"Sri Lankan rupee":    "LKR",
+	"Swedish krona":       "SEK",
+	"Swiss franc":         "CHF",
+	"Thai baht":           "THB",
+	"Trinidadian dollar":  "TTD",
+	"Tunisian dinar":      "TND",
+	"U.A.E. dirham":       "AED",
+	"U.K. pound":          "GBP",
+	"U.S. dollar":         "USD",
+	"Uruguayan peso":      "UYU",
+}
+
+// usdPerUnitCurrencies are quoted in U.S. dollars per currency unit in the representative rates report, unlike the others
+// their rates are inverted, so that all representative rates are in currency units per U.S. dollar
+var usdPerUnitCurrencies = map[string]bool{
+	"AUD": true,
+	"EUR": true,
+	"GBP": true,
+	"NZD": true,
+}
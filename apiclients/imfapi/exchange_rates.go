@@ -0,0 +1,210 @@
+package imfapi
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/imf/imfexchangerate"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+type ExchangeRate struct {
+	RateType     RateType
+	CurrencyCode string // ISO 4217
+	Date         string // YYYY-MM-DD
+	Rate         float64
+}
+
+// GetApiExchangeRates returns the daily rates of rateType between startDate and endDate (inclusive), fetching one monthly report per month
+// currencies whose name is not known to this package are skipped and logged
+func (c Client) GetApiExchangeRates(rateType RateType, startDate, endDate time.Time) (exRates []ExchangeRate, err error) {
+
+	// validate params
+	if startDate.After(time.Now()) {
+		return nil, fmt.Errorf("%w: startDate must be before now", cerrors.ErrValidationFailed)
+	}
+	if startDate.After(endDate) {
+		return nil, fmt.Errorf("%w: startDate must be before endDate", cerrors.ErrValidationFailed)
+	}
+
+	start := startDate.Format(lystype.DateFormat)
+	end := endDate.Format(lystype.DateFormat)
+	unknownNames := make(map[string]bool)
+
+	for month := time.Date(startDate.Year(), startDate.Month(), 1, 0, 0, 0, 0, time.UTC); !month.After(endDate); month = month.AddDate(0, 1, 0) {
+
+		monthRates, monthUnknown, err := c.getMonth(rateType, month)
+		if err != nil {
+			return nil, fmt.Errorf("c.getMonth failed for %s: %w", month.Format("2006-01"), err)
+		}
+
+		for _, exRate := range monthRates {
+			if exRate.Date >= start && exRate.Date <= end {
+				exRates = append(exRates, exRate)
+			}
+		}
+		for _, name := range monthUnknown {
+			unknownNames[name] = true
+		}
+	}
+
+	if len(unknownNames) > 0 {
+		names := make([]string, 0, len(unknownNames))
+		for name := range unknownNames {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		c.InfoLog.Warn("skipped currencies with unknown names", "rate_type", rateType.String(), "names", strings.Join(names, ", "))
+	}
+
+	return exRates, nil
+}
+
+// getMonth returns the rates of the monthly report of rateType containing month
+func (c Client) getMonth(rateType RateType, month time.Time) (exRates []ExchangeRate, unknownNames []string, err error) {
+
+	// build URL, e.g. /rms_mth.aspx?SelectDate=2024-01-31&reportType=SDRCV&tsvflag=Y
+	params := url.Values{}
+	params.Add("SelectDate", month.AddDate(0, 1, -1).Format(lystype.DateFormat))
+	params.Add("reportType", rateType.String())
+	params.Add("tsvflag", "Y")
+	reportUrl := c.baseUrl() + "/rms_mth.aspx?" + params.Encode()
+
+	resp, err := c.HttpClient.Get(reportUrl)
+	if err != nil {
+		return nil, nil, fmt.Errorf("c.HttpClient.Get failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+	}
+
+	exRates, unknownNames, err = ParseExchangeRatesTsv(rateType, resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ParseExchangeRatesTsv failed: %w", err)
+	}
+
+	return exRates, unknownNames, nil
+}
+
+// footnoteRe matches footnote markers after a currency name, e.g. "Euro(1)"
+var footnoteRe = regexp.MustCompile(`\s*(\(\d+\)|\*+)$`)
+
+// ParseExchangeRatesTsv parses a monthly report of rateType in TSV format, returning its rates and the currency names which could not be mapped to a code
+// representative rates quoted in U.S. dollars per currency unit are inverted. Missing values ("NA") are skipped
+func ParseExchangeRatesTsv(rateType RateType, r io.Reader) (exRates []ExchangeRate, unknownNames []string, err error) {
+
+	/* content looks like this, with a table per half month:
+	SDRs per Currency unit for January 2024
+
+	Currency	January 02, 2024	January 03, 2024	...
+	Chinese yuan	0.1050523	0.1047218	...
+	Euro	0.8216690	NA	...
+	*/
+
+	var days []string // days of the columns of the current table. nil outside of a table
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+
+		fields := strings.Split(strings.TrimRight(scanner.Text(), "\r"), "\t")
+
+		// a line without values ends the current table
+		if len(fields) < 2 {
+			days = nil
+			continue
+		}
+
+		name := strings.TrimSpace(fields[0])
+
+		// header: parse the day of each column
+		if name == "Currency" {
+			days = make([]string, len(fields)-1)
+			for i, field := range fields[1:] {
+				day, err := time.Parse("January 2, 2006", strings.TrimSpace(field))
+				if err != nil {
+					return nil, nil, fmt.Errorf("%w: invalid column header '%s': %w", cerrors.ErrValidationFailed, field, err)
+				}
+				days[i] = day.Format(lystype.DateFormat)
+			}
+			continue
+		}
+
+		if days == nil {
+			continue
+		}
+
+		name = footnoteRe.ReplaceAllString(name, "")
+		code, ok := currencyCodes[name]
+		if !ok {
+			unknownNames = append(unknownNames, name)
+			continue
+		}
+
+		for i, field := range fields[1:] {
+
+			if i >= len(days) {
+				break
+			}
+
+			field = strings.ReplaceAll(strings.TrimSpace(field), ",", "")
+			if field == "" || field == "NA" {
+				continue
+			}
+
+			rate, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: strconv.ParseFloat failed for %s on %s: %w", cerrors.ErrValidationFailed, name, days[i], err)
+			}
+			if rate <= 0 {
+				return nil, nil, fmt.Errorf("%w: rate must be positive for %s on %s", cerrors.ErrValidationFailed, name, days[i])
+			}
+
+			if rateType == Representative && usdPerUnitCurrencies[code] {
+				// rounded, so that the stored value is stable across syncs
+				rate = math.Round(1/rate*1e6) / 1e6
+			}
+
+			exRates = append(exRates, ExchangeRate{RateType: rateType, CurrencyCode: code, Date: days[i], Rate: rate})
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("scanner.Err: %w", err)
+	}
+
+	return exRates, unknownNames, nil
+}
+
+// ExchangeRatesToMap converts API rates of a single rate type to store models with imfexchangerate.NaturalKey as key
+func ExchangeRatesToMap(apiItems []ExchangeRate) (itemsMap map[imfexchangerate.NaturalKey]imfexchangerate.Model, err error) {
+
+	itemsMap = make(map[imfexchangerate.NaturalKey]imfexchangerate.Model, len(apiItems))
+	for _, apiItem := range apiItems {
+
+		day, err := time.Parse(lystype.DateFormat, apiItem.Date)
+		if err != nil {
+			return nil, fmt.Errorf("time.Parse failed for Date '%s': %w", apiItem.Date, err)
+		}
+
+		input := imfexchangerate.Input{
+			CurrencyCode: apiItem.CurrencyCode,
+			Day:          lystype.Date(day),
+			Rate:         apiItem.Rate,
+			RateType:     apiItem.RateType.String(),
+		}
+		itemsMap[imfexchangerate.KeyOf(input)] = imfexchangerate.Model{Input: input}
+	}
+
+	return itemsMap, nil
+}
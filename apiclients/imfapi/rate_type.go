@@ -0,0 +1,13 @@
+package imfapi
+
+// RateType is an IMF exchange rate report type
+type RateType string
+
+func (e RateType) String() string {
+	return string(e)
+}
+
+const (
+	SdrPerCurrency RateType = "SDRCV" // SDRs per currency unit
+	Representative RateType = "REP"   // representative rate, in currency units per U.S. dollar
+)
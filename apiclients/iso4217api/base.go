@@ -0,0 +1,43 @@
+package iso4217api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Docs: https://www.six-group.com/en/products-services/financial-information/data-standards.html
+// SIX Financial Information is the maintenance agency of ISO 4217 and publishes the current currency codes as "list one". No API key is needed
+
+const (
+	apiShortname   string = "iso4217"
+	defaultListUrl string = "https://www.six-group.com/dam/download/financial-information/data-center/iso-currrency/lists/list-one.xml"
+	timeoutSecs    int    = 20
+)
+
+type Client struct {
+	HttpClient *http.Client
+	ListUrl    string // URL of the list one XML, e.g. of a fixture server in tests. Defaults to that of SIX
+	InfoLog    *slog.Logger
+	ErrorLog   *slog.Logger
+}
+
+func NewClient(infoLog, errorLog *slog.Logger) (client Client) {
+
+	return Client{
+		HttpClient: &http.Client{
+			Timeout: time.Duration(timeoutSecs) * time.Second,
+		},
+		ListUrl:  defaultListUrl,
+		InfoLog:  infoLog.With("api", apiShortname),
+		ErrorLog: errorLog.With("api", apiShortname),
+	}
+}
+
+// listUrl returns c.ListUrl, or that of SIX if not set
+func (c Client) listUrl() string {
+	if c.ListUrl == "" {
+		return defaultListUrl
+	}
+	return c.ListUrl
+}
@@ -0,0 +1,142 @@
+package iso4217api
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/iso4217/isocurrency"
+)
+
+// Currency is an ISO 4217 currency, with the entities (countries and territories) using it
+type Currency struct {
+	Code        string
+	NumericCode string
+	Name        string
+	MinorUnits  *int // number of decimals of the minor unit, e.g. 2 for EUR and 0 for JPY. Nil if not applicable, e.g. for gold (XAU)
+	Fund        bool // a fund code, e.g. CLF, rather than a currency
+	Entities    []string
+}
+
+// listXml is the list one XML
+type listXml struct {
+	XMLName   xml.Name `xml:"ISO_4217"`
+	Published string   `xml:"Pblshd,attr"`
+	Entries   []struct {
+		Entity     string          `xml:"CtryNm"`
+		Name       currencyNameXml `xml:"CcyNm"`
+		Code       string          `xml:"Ccy"`
+		Number     string          `xml:"CcyNbr"`
+		MinorUnits string          `xml:"CcyMnrUnts"`
+	} `xml:"CcyTbl>CcyNtry"`
+}
+
+type currencyNameXml struct {
+	Text   string `xml:",chardata"`
+	IsFund bool   `xml:"IsFund,attr"`
+}
+
+// GetApiCurrencies returns the current ISO 4217 currencies
+func (c Client) GetApiCurrencies() (currencies []Currency, err error) {
+
+	resp, err := c.HttpClient.Get(c.listUrl())
+	if err != nil {
+		return nil, fmt.Errorf("c.HttpClient.Get failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadAll failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+
+	currencies, err = ParseCurrenciesXml(body)
+	if err != nil {
+		return nil, fmt.Errorf("ParseCurrenciesXml failed: %w", err)
+	}
+
+	return currencies, nil
+}
+
+// ParseCurrenciesXml parses the list one XML into one Currency per code, sorted by code
+func ParseCurrenciesXml(content []byte) (currencies []Currency, err error) {
+
+	/* content looks like this:
+	<ISO_4217 Pblshd="2024-06-25">
+	  <CcyTbl>
+	    <CcyNtry><CtryNm>AFGHANISTAN</CtryNm><CcyNm>Afghani</CcyNm><Ccy>AFN</Ccy><CcyNbr>971</CcyNbr><CcyMnrUnts>2</CcyMnrUnts></CcyNtry>
+	    <CcyNtry><CtryNm>ANTARCTICA</CtryNm><CcyNm>No universal currency</CcyNm></CcyNtry>
+	    <CcyNtry><CtryNm>CHILE</CtryNm><CcyNm IsFund="true">Unidad de Fomento</CcyNm><Ccy>CLF</Ccy><CcyNbr>990</CcyNbr><CcyMnrUnts>4</CcyMnrUnts></CcyNtry>
+	    <CcyNtry><CtryNm>ZZ08_Gold</CtryNm><CcyNm>Gold</CcyNm><Ccy>XAU</Ccy><CcyNbr>959</CcyNbr><CcyMnrUnts>N.A.</CcyMnrUnts></CcyNtry>
+	    ...
+	*/
+
+	respS := listXml{}
+	if err = xml.Unmarshal(content, &respS); err != nil {
+		return nil, fmt.Errorf("%w: xml.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	byCode := make(map[string]*Currency)
+	for _, e := range respS.Entries {
+
+		// entities without a currency of their own, e.g. Antarctica
+		code := strings.ToUpper(strings.TrimSpace(e.Code))
+		if code == "" {
+			continue
+		}
+		if len(code) != 3 {
+			return nil, fmt.Errorf("%w: invalid code '%s' of %s", cerrors.ErrValidationFailed, e.Code, e.Entity)
+		}
+
+		curr, ok := byCode[code]
+		if !ok {
+			curr = &Currency{Code: code, NumericCode: strings.TrimSpace(e.Number), Name: strings.TrimSpace(e.Name.Text), Fund: e.Name.IsFund, Entities: []string{}}
+			// N.A. for precious metals, special drawing rights and test codes
+			if units, err := strconv.Atoi(strings.TrimSpace(e.MinorUnits)); err == nil {
+				curr.MinorUnits = &units
+			}
+			byCode[code] = curr
+		}
+		if entity := strings.TrimSpace(e.Entity); entity != "" {
+			curr.Entities = append(curr.Entities, entity)
+		}
+	}
+
+	for _, curr := range byCode {
+		sort.Strings(curr.Entities)
+		currencies = append(currencies, *curr)
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].Code < currencies[j].Code })
+
+	if len(currencies) == 0 {
+		return nil, fmt.Errorf("%w: no currencies found", cerrors.ErrNotFound)
+	}
+
+	return currencies, nil
+}
+
+// CurrenciesToMap converts currencies to a map with Code as key
+func CurrenciesToMap(currencies []Currency) (itemsMap map[string]isocurrency.Model) {
+
+	itemsMap = make(map[string]isocurrency.Model)
+	for _, curr := range currencies {
+		itemsMap[curr.Code] = isocurrency.Model{Input: isocurrency.Input{
+			Code:        curr.Code,
+			NumericCode: curr.NumericCode,
+			Name:        curr.Name,
+			MinorUnits:  curr.MinorUnits,
+			Fund:        curr.Fund,
+			Entities:    curr.Entities,
+		}}
+	}
+	return itemsMap
+}
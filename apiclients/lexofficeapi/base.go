@@ -0,0 +1,150 @@
+package lexofficeapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+)
+
+// Docs: https://developers.lexoffice.io/docs/
+// an API key, created in the lexoffice settings of the account, is needed
+
+const (
+	apiShortname      string = "lexoffice"
+	defaultBaseUrl    string = "https://api.lexoffice.io"
+	timeoutSecs       int    = 30
+	pageSize          int    = 250                    // maximum of list endpoints
+	requestInterval          = 500 * time.Millisecond // rate limit of 2 requests per second
+	maxAttempts       int    = 3                      // per request, if rate limited
+	defaultRetryAfter        = time.Second
+)
+
+// copies of a Client share its rate limit
+type Client struct {
+	HttpClient *http.Client
+	BaseUrl    string // API root, e.g. of a fixture server in tests. Defaults to lexoffice
+	ApiKey     string
+	InfoLog    *slog.Logger
+	ErrorLog   *slog.Logger
+
+	limiter *apiclient.Limiter
+}
+
+func NewClient(apiKey string, infoLog, errorLog *slog.Logger) (client Client) {
+
+	return Client{
+		HttpClient: &http.Client{
+			Timeout: time.Duration(timeoutSecs) * time.Second,
+		},
+		ApiKey:   apiKey,
+		InfoLog:  infoLog.With("api", apiShortname),
+		ErrorLog: errorLog.With("api", apiShortname),
+		limiter:  apiclient.NewLimiter(requestInterval),
+	}
+}
+
+// baseUrl returns c.BaseUrl, or lexoffice if not set
+func (c Client) baseUrl() string {
+	if c.BaseUrl != "" {
+		return strings.TrimSuffix(c.BaseUrl, "/")
+	}
+	return defaultBaseUrl
+}
+
+// page is the paging envelope of list endpoints
+type page struct {
+	Content json.RawMessage `json:"content"`
+	Last    bool            `json:"last"`
+}
+
+// getPages requests all pages of the list endpoint path with params and calls contentFunc with the content of each page
+func (c Client) getPages(ctx context.Context, path string, params url.Values, contentFunc func(content []byte) error) error {
+
+	params.Set("size", strconv.Itoa(pageSize))
+
+	return apiclient.PageNumbers(ctx, apiclient.Paging{Log: c.InfoLog}, 0,
+		func(pageNum int) ([]byte, error) {
+			params.Set("page", strconv.Itoa(pageNum))
+			return c.get(ctx, path, params)
+		},
+		func(body []byte) (bool, error) {
+			p := page{}
+			if err := json.Unmarshal(body, &p); err != nil {
+				return false, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+			}
+			if err := contentFunc(p.Content); err != nil {
+				return false, fmt.Errorf("contentFunc failed: %w", err)
+			}
+			return !p.Last, nil
+		})
+}
+
+// get requests path with params, waiting for the rate limit, and returns the response body
+// rate limited requests (429) are retried after the delay given by the API
+func (c Client) get(ctx context.Context, path string, params url.Values) (body []byte, err error) {
+
+	if c.ApiKey == "" {
+		return nil, fmt.Errorf("%w: API key is required", cerrors.ErrValidationFailed)
+	}
+
+	reqUrl := c.baseUrl() + path
+	if len(params) > 0 {
+		reqUrl += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	if err = apiclient.Bearer(c.ApiKey).Authorize(req); err != nil {
+		return nil, fmt.Errorf("Authorize failed: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	for attempt := 1; ; attempt++ {
+
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("c.limiter.Wait failed: %w", err)
+		}
+
+		resp, err := c.HttpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("c.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+		}
+
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("io.ReadAll failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return body, nil
+		case resp.StatusCode == http.StatusNotFound:
+			return nil, fmt.Errorf("%w: %s", cerrors.ErrNotFound, path)
+		case resp.StatusCode == http.StatusTooManyRequests:
+			if attempt == maxAttempts {
+				return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+			}
+			retryAfter := apiclient.ParseRetryAfter(resp.Header.Get("Retry-After"), defaultRetryAfter)
+			c.InfoLog.Warn("rate limited, retrying", "path", path, "retry_after", retryAfter.String())
+			c.limiter.Pause(retryAfter)
+		case resp.StatusCode >= 400 && resp.StatusCode < 500:
+			// e.g. 400 invalid parameters, 401 invalid API key
+			return nil, fmt.Errorf("%w: status %d: %s", cerrors.ErrValidationFailed, resp.StatusCode, string(body))
+		default:
+			return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package lexofficeapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/lexoffice/lexcontact"
+)
+
+type Contact struct {
+	LexofficeId       string
+	Version           int
+	CustomerNumber    *int
+	VendorNumber      *int
+	Name              string
+	Email             string
+	Street            string
+	Zip               string
+	City              string
+	CountryCode       string
+	VatRegistrationId string
+	Archived          bool
+}
+
+// GetApiContacts returns all contacts, including archived ones
+func (c Client) GetApiContacts(ctx context.Context) (contacts []Contact, err error) {
+
+	err = c.getPages(ctx, "/v1/contacts", url.Values{}, func(content []byte) error {
+		page, err := ParseContactsJson(content)
+		if err != nil {
+			return fmt.Errorf("ParseContactsJson failed: %w", err)
+		}
+		contacts = append(contacts, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("c.getPages failed: %w", err)
+	}
+
+	return contacts, nil
+}
+
+type apiAddress struct {
+	Street      string `json:"street"`
+	Zip         string `json:"zip"`
+	City        string `json:"city"`
+	CountryCode string `json:"countryCode"`
+}
+
+// ParseContactsJson parses the content of a page of the contacts endpoint
+func ParseContactsJson(content []byte) (contacts []Contact, err error) {
+
+	/* content looks like this:
+	[{"id":"e9066f04-8cc7-4616-93f8-ac9ecc8479c8","organizationId":"aa93e8a8-2aa3-470b-b914-caad8a255dd8","version":2,
+	  "roles":{"customer":{"number":10307}},"company":{"name":"Testfirma","vatRegistrationId":"DE123456789"},
+	  "addresses":{"billing":[{"street":"Junkersstraße 9","zip":"82178","city":"Puchheim","countryCode":"DE"}]},
+	  "emailAddresses":{"business":["info@testfirma.de"]},"archived":false}]
+	*/
+
+	respS := []struct {
+		Id      string `json:"id"`
+		Version int    `json:"version"`
+		Roles   struct {
+			Customer *struct {
+				Number *int `json:"number"`
+			} `json:"customer"`
+			Vendor *struct {
+				Number *int `json:"number"`
+			} `json:"vendor"`
+		} `json:"roles"`
+		Company *struct {
+			Name              string `json:"name"`
+			VatRegistrationId string `json:"vatRegistrationId"`
+		} `json:"company"`
+		Person *struct {
+			FirstName string `json:"firstName"`
+			LastName  string `json:"lastName"`
+		} `json:"person"`
+		Addresses struct {
+			Billing  []apiAddress `json:"billing"`
+			Shipping []apiAddress `json:"shipping"`
+		} `json:"addresses"`
+		EmailAddresses struct {
+			Business []string `json:"business"`
+			Office   []string `json:"office"`
+			Private  []string `json:"private"`
+			Other    []string `json:"other"`
+		} `json:"emailAddresses"`
+		Archived bool `json:"archived"`
+	}{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, apiContact := range respS {
+
+		contact := Contact{
+			LexofficeId: apiContact.Id,
+			Version:     apiContact.Version,
+			Archived:    apiContact.Archived,
+		}
+
+		// a contact is either a company or a person
+		switch {
+		case apiContact.Company != nil:
+			contact.Name = apiContact.Company.Name
+			contact.VatRegistrationId = apiContact.Company.VatRegistrationId
+		case apiContact.Person != nil:
+			contact.Name = strings.TrimSpace(apiContact.Person.FirstName + " " + apiContact.Person.LastName)
+		}
+		if contact.LexofficeId == "" || contact.Name == "" {
+			return nil, fmt.Errorf("%w: contact without id or name: %s", cerrors.ErrValidationFailed, apiContact.Id)
+		}
+
+		if apiContact.Roles.Customer != nil {
+			contact.CustomerNumber = apiContact.Roles.Customer.Number
+		}
+		if apiContact.Roles.Vendor != nil {
+			contact.VendorNumber = apiContact.Roles.Vendor.Number
+		}
+
+		// the first billing address, or else shipping address
+		addresses := append(apiContact.Addresses.Billing, apiContact.Addresses.Shipping...)
+		if len(addresses) > 0 {
+			contact.Street = addresses[0].Street
+			contact.Zip = addresses[0].Zip
+			contact.City = addresses[0].City
+			contact.CountryCode = addresses[0].CountryCode
+		}
+
+		for _, emails := range [][]string{apiContact.EmailAddresses.Business, apiContact.EmailAddresses.Office, apiContact.EmailAddresses.Private, apiContact.EmailAddresses.Other} {
+			if len(emails) > 0 {
+				contact.Email = emails[0]
+				break
+			}
+		}
+
+		contacts = append(contacts, contact)
+	}
+
+	return contacts, nil
+}
+
+// ContactsToMap converts contacts to a map with LexofficeId as key
+func ContactsToMap(contacts []Contact) (itemsMap map[string]lexcontact.Model, err error) {
+
+	itemsMap = make(map[string]lexcontact.Model)
+	for _, contact := range contacts {
+
+		if _, ok := itemsMap[contact.LexofficeId]; ok {
+			return nil, fmt.Errorf("%w: duplicate contact %s", cerrors.ErrValidationFailed, contact.LexofficeId)
+		}
+
+		itemsMap[contact.LexofficeId] = lexcontact.Model{Input: lexcontact.Input{
+			Archived:          contact.Archived,
+			City:              contact.City,
+			CountryCode:       contact.CountryCode,
+			CustomerNumber:    contact.CustomerNumber,
+			Email:             contact.Email,
+			LexofficeId:       contact.LexofficeId,
+			Name:              contact.Name,
+			Street:            contact.Street,
+			VatRegistrationId: contact.VatRegistrationId,
+			VendorNumber:      contact.VendorNumber,
+			Version:           contact.Version,
+			Zip:               contact.Zip,
+		}}
+	}
+
+	return itemsMap, nil
+}
@@ -0,0 +1,253 @@
+package lexofficeapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/lexoffice/lexinvoiceline"
+	"github.com/loveyourstack/connectors/stores/lexoffice/lexvoucher"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// VoucherTypeInvoice is the voucher type of sales invoices, the only type whose lines are fetched
+const VoucherTypeInvoice string = "invoice"
+
+type Voucher struct {
+	LexofficeId   string
+	VoucherType   string // e.g. invoice, creditnote, purchaseinvoice
+	VoucherStatus string // e.g. draft, open, paid, voided
+	VoucherNumber string
+	VoucherDate   time.Time
+	DueDate       *time.Time
+	ContactId     string
+	ContactName   string
+	Currency      string
+	TotalAmount   float64
+	OpenAmount    *float64
+	Archived      bool
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type InvoiceLine struct {
+	Type               string // e.g. custom, material, service, text
+	Name               string
+	Quantity           *float64
+	UnitName           string
+	UnitPriceNet       *float64
+	UnitPriceGross     *float64
+	TaxRatePercentage  *float64
+	DiscountPercentage *float64
+	LineItemAmount     *float64
+}
+
+// GetApiVouchers returns the vouchers of all types and statuses updated since the day of updatedSince
+func (c Client) GetApiVouchers(ctx context.Context, updatedSince time.Time) (vouchers []Voucher, err error) {
+
+	params := url.Values{}
+	params.Set("voucherType", "any")
+	params.Set("voucherStatus", "any")
+	params.Set("updatedDateFrom", updatedSince.Format(lystype.DateFormat))
+	params.Set("sort", "updatedDate,ASC")
+
+	err = c.getPages(ctx, "/v1/voucherlist", params, func(content []byte) error {
+		page, err := ParseVouchersJson(content)
+		if err != nil {
+			return fmt.Errorf("ParseVouchersJson failed: %w", err)
+		}
+		vouchers = append(vouchers, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("c.getPages failed: %w", err)
+	}
+
+	return vouchers, nil
+}
+
+// ParseVouchersJson parses the content of a page of the voucherlist endpoint
+func ParseVouchersJson(content []byte) (vouchers []Voucher, err error) {
+
+	/* content looks like this:
+	[{"id":"a8a2b5d7-3d5f-4a6b-9e24-35a1b8a4f7c2","voucherType":"invoice","voucherStatus":"open","voucherNumber":"RE1019",
+	  "voucherDate":"2023-02-22T00:00:00.000+01:00","createdDate":"2023-02-22T10:15:00.000+01:00","updatedDate":"2023-02-22T10:17:00.000+01:00",
+	  "dueDate":"2023-03-08T00:00:00.000+01:00","contactId":"e9066f04-8cc7-4616-93f8-ac9ecc8479c8","contactName":"Testfirma",
+	  "totalAmount":119.00,"openAmount":119.00,"currency":"EUR","archived":false}]
+	*/
+
+	respS := []struct {
+		Id            string     `json:"id"`
+		VoucherType   string     `json:"voucherType"`
+		VoucherStatus string     `json:"voucherStatus"`
+		VoucherNumber string     `json:"voucherNumber"`
+		VoucherDate   time.Time  `json:"voucherDate"`
+		CreatedDate   time.Time  `json:"createdDate"`
+		UpdatedDate   time.Time  `json:"updatedDate"`
+		DueDate       *time.Time `json:"dueDate"`
+		ContactId     string     `json:"contactId"`
+		ContactName   string     `json:"contactName"`
+		TotalAmount   float64    `json:"totalAmount"`
+		OpenAmount    *float64   `json:"openAmount"`
+		Currency      string     `json:"currency"`
+		Archived      bool       `json:"archived"`
+	}{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, apiVoucher := range respS {
+
+		if apiVoucher.Id == "" || apiVoucher.VoucherType == "" || apiVoucher.UpdatedDate.IsZero() {
+			return nil, fmt.Errorf("%w: voucher without id, type or updatedDate: %s", cerrors.ErrValidationFailed, apiVoucher.Id)
+		}
+
+		vouchers = append(vouchers, Voucher{
+			LexofficeId:   apiVoucher.Id,
+			VoucherType:   apiVoucher.VoucherType,
+			VoucherStatus: apiVoucher.VoucherStatus,
+			VoucherNumber: apiVoucher.VoucherNumber,
+			VoucherDate:   apiVoucher.VoucherDate,
+			DueDate:       apiVoucher.DueDate,
+			ContactId:     apiVoucher.ContactId,
+			ContactName:   apiVoucher.ContactName,
+			Currency:      apiVoucher.Currency,
+			TotalAmount:   apiVoucher.TotalAmount,
+			OpenAmount:    apiVoucher.OpenAmount,
+			Archived:      apiVoucher.Archived,
+			CreatedAt:     apiVoucher.CreatedDate,
+			UpdatedAt:     apiVoucher.UpdatedDate,
+		})
+	}
+
+	return vouchers, nil
+}
+
+// GetApiInvoiceLines returns the line items of the invoice with lexofficeId
+func (c Client) GetApiInvoiceLines(ctx context.Context, lexofficeId string) (lines []InvoiceLine, err error) {
+
+	body, err := c.get(ctx, "/v1/invoices/"+url.PathEscape(lexofficeId), nil)
+	if err != nil {
+		return nil, fmt.Errorf("c.get failed: %w", err)
+	}
+
+	lines, err = ParseInvoiceJson(body)
+	if err != nil {
+		return nil, fmt.Errorf("ParseInvoiceJson failed: %w", err)
+	}
+
+	return lines, nil
+}
+
+// ParseInvoiceJson parses the line items of an invoice
+func ParseInvoiceJson(body []byte) (lines []InvoiceLine, err error) {
+
+	/* body looks like this:
+	{"id":"a8a2b5d7-3d5f-4a6b-9e24-35a1b8a4f7c2","voucherNumber":"RE1019", ...
+	 "lineItems":[{"type":"custom","name":"Energieriegel Testpaket","quantity":1,"unitName":"Stück",
+	   "unitPrice":{"currency":"EUR","netAmount":5,"grossAmount":5.95,"taxRatePercentage":19},"discountPercentage":0,"lineItemAmount":5.95},
+	  {"type":"text","name":"Strukturieren Sie Ihre Belege durch Text-Elemente."}], ...}
+	*/
+
+	respS := struct {
+		LineItems []struct {
+			Type      string   `json:"type"`
+			Name      string   `json:"name"`
+			Quantity  *float64 `json:"quantity"`
+			UnitName  string   `json:"unitName"`
+			UnitPrice *struct {
+				NetAmount         *float64 `json:"netAmount"`
+				GrossAmount       *float64 `json:"grossAmount"`
+				TaxRatePercentage *float64 `json:"taxRatePercentage"`
+			} `json:"unitPrice"`
+			DiscountPercentage *float64 `json:"discountPercentage"`
+			LineItemAmount     *float64 `json:"lineItemAmount"`
+		} `json:"lineItems"`
+	}{}
+	if err = json.Unmarshal(body, &respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, apiLine := range respS.LineItems {
+
+		line := InvoiceLine{
+			Type:               apiLine.Type,
+			Name:               apiLine.Name,
+			Quantity:           apiLine.Quantity,
+			UnitName:           apiLine.UnitName,
+			DiscountPercentage: apiLine.DiscountPercentage,
+			LineItemAmount:     apiLine.LineItemAmount,
+		}
+		// text lines have no price
+		if apiLine.UnitPrice != nil {
+			line.UnitPriceNet = apiLine.UnitPrice.NetAmount
+			line.UnitPriceGross = apiLine.UnitPrice.GrossAmount
+			line.TaxRatePercentage = apiLine.UnitPrice.TaxRatePercentage
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// VouchersToMap converts vouchers to a map with LexofficeId as key
+func VouchersToMap(vouchers []Voucher) (itemsMap map[string]lexvoucher.Model, err error) {
+
+	itemsMap = make(map[string]lexvoucher.Model)
+	for _, voucher := range vouchers {
+
+		if _, ok := itemsMap[voucher.LexofficeId]; ok {
+			return nil, fmt.Errorf("%w: duplicate voucher %s", cerrors.ErrValidationFailed, voucher.LexofficeId)
+		}
+
+		item := lexvoucher.Model{Input: lexvoucher.Input{
+			Archived:           voucher.Archived,
+			ContactId:          voucher.ContactId,
+			ContactName:        voucher.ContactName,
+			CreatedAtLexoffice: lystype.Datetime(voucher.CreatedAt),
+			Currency:           voucher.Currency,
+			LexofficeId:        voucher.LexofficeId,
+			OpenAmount:         voucher.OpenAmount,
+			TotalAmount:        voucher.TotalAmount,
+			UpdatedAtLexoffice: lystype.Datetime(voucher.UpdatedAt),
+			VoucherDate:        lystype.Datetime(voucher.VoucherDate),
+			VoucherNumber:      voucher.VoucherNumber,
+			VoucherStatus:      voucher.VoucherStatus,
+			VoucherType:        voucher.VoucherType,
+		}}
+		if voucher.DueDate != nil {
+			dueDate := lystype.Datetime(*voucher.DueDate)
+			item.DueDate = &dueDate
+		}
+
+		itemsMap[voucher.LexofficeId] = item
+	}
+
+	return itemsMap, nil
+}
+
+// InvoiceLinesToInputs converts the lines of an invoice to store inputs of the voucher with voucherFk, numbering them from 1
+func InvoiceLinesToInputs(lines []InvoiceLine, voucherFk int64) []lexinvoiceline.Input {
+
+	inputs := make([]lexinvoiceline.Input, 0, len(lines))
+	for i, line := range lines {
+		inputs = append(inputs, lexinvoiceline.Input{
+			DiscountPercentage: line.DiscountPercentage,
+			LineItemAmount:     line.LineItemAmount,
+			Name:               line.Name,
+			Position:           i + 1,
+			Quantity:           line.Quantity,
+			TaxRatePercentage:  line.TaxRatePercentage,
+			Type:               line.Type,
+			UnitName:           line.UnitName,
+			UnitPriceGross:     line.UnitPriceGross,
+			UnitPriceNet:       line.UnitPriceNet,
+			VoucherFk:          voucherFk,
+		})
+	}
+	return inputs
+}
@@ -0,0 +1,141 @@
+package nagerapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/holiday/publicholiday"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+type PublicHoliday struct {
+	CountryCode string
+	Date        string // YYYY-MM-DD
+	Name        string // English name
+	LocalName   string
+	Nationwide  bool
+	Counties    []string // ISO 3166-2 subdivision codes if not nationwide
+	Types       []string // e.g. Public, Bank
+}
+
+// apiPublicHoliday is an element of the response of the PublicHolidays endpoint
+type apiPublicHoliday struct {
+	Date        string   `json:"date"`
+	LocalName   string   `json:"localName"`
+	Name        string   `json:"name"`
+	CountryCode string   `json:"countryCode"`
+	Global      bool     `json:"global"`
+	Counties    []string `json:"counties"` // null if global
+	Types       []string `json:"types"`
+}
+
+// GetApiPublicHolidays returns the public holidays of countryCode (ISO 3166-1 alpha-2) in year
+func (c Client) GetApiPublicHolidays(year int, countryCode string) (holidays []PublicHoliday, err error) {
+
+	holidaysUrl := fmt.Sprintf("%s/PublicHolidays/%d/%s", c.baseUrl(), year, countryCode)
+
+	resp, err := c.HttpClient.Get(holidaysUrl)
+	if err != nil {
+		return nil, fmt.Errorf("c.HttpClient.Get failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		// known country without holiday data
+		return nil, nil
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: country %s", cerrors.ErrNotFound, countryCode)
+	case http.StatusBadRequest:
+		return nil, fmt.Errorf("%w: invalid year %d or country %s", cerrors.ErrValidationFailed, year, countryCode)
+	default:
+		return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadAll failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+
+	holidays, err = ParsePublicHolidaysJson(body)
+	if err != nil {
+		return nil, fmt.Errorf("ParsePublicHolidaysJson failed: %w", err)
+	}
+
+	return holidays, nil
+}
+
+// ParsePublicHolidaysJson parses the response of the PublicHolidays endpoint
+func ParsePublicHolidaysJson(content []byte) (holidays []PublicHoliday, err error) {
+
+	/* content looks like this:
+	[{"date":"2024-01-01","localName":"Neujahr","name":"New Year's Day","countryCode":"DE","fixed":true,"global":true,"counties":null,"launchYear":1967,"types":["Public"]},
+	 {"date":"2024-01-06","localName":"Heilige Drei Könige","name":"Epiphany","countryCode":"DE","fixed":true,"global":false,"counties":["DE-BW","DE-BY","DE-ST"],"launchYear":1967,"types":["Public"]}, ...]
+	*/
+
+	apiHolidays := []apiPublicHoliday{}
+	if err = json.Unmarshal(content, &apiHolidays); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, h := range apiHolidays {
+
+		if _, err := time.Parse(lystype.DateFormat, h.Date); err != nil {
+			return nil, fmt.Errorf("%w: invalid date '%s': %w", cerrors.ErrValidationFailed, h.Date, err)
+		}
+		if h.Name == "" {
+			return nil, fmt.Errorf("%w: holiday on %s has no name", cerrors.ErrValidationFailed, h.Date)
+		}
+
+		holidays = append(holidays, PublicHoliday{
+			CountryCode: strings.ToUpper(h.CountryCode),
+			Date:        h.Date,
+			Name:        strings.TrimSpace(h.Name),
+			LocalName:   strings.TrimSpace(h.LocalName),
+			Nationwide:  h.Global,
+			Counties:    nonNil(h.Counties),
+			Types:       nonNil(h.Types),
+		})
+	}
+
+	return holidays, nil
+}
+
+// nonNil returns an empty slice if s is nil, since the DB columns are not nullable
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// PublicHolidaysToMap converts holidays to a map with day+name as key
+func PublicHolidaysToMap(holidays []PublicHoliday) (itemsMap map[string]publicholiday.Model, err error) {
+
+	itemsMap = make(map[string]publicholiday.Model)
+	for _, h := range holidays {
+
+		day, err := time.Parse(lystype.DateFormat, h.Date)
+		if err != nil {
+			return nil, fmt.Errorf("time.Parse failed for date '%s': %w", h.Date, err)
+		}
+
+		itemsMap[publicholiday.NaturalKey(h.Date, h.Name)] = publicholiday.Model{Input: publicholiday.Input{
+			CountryCode: h.CountryCode,
+			Day:         lystype.Date(day),
+			Name:        h.Name,
+			LocalName:   h.LocalName,
+			Nationwide:  h.Nationwide,
+			Counties:    h.Counties,
+			Types:       h.Types,
+		}}
+	}
+
+	return itemsMap, nil
+}
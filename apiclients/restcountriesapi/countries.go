@@ -0,0 +1,141 @@
+package restcountriesapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/country/isocountry"
+)
+
+// fields requested from the all endpoint, which requires a field list
+const fields string = "cca2,cca3,ccn3,name,currencies,region,subregion"
+
+// euMembers are the ISO 3166-1 alpha-2 codes of the EU member states. REST Countries has no membership field
+var euMembers = map[string]bool{
+	"AT": true, "BE": true, "BG": true, "CY": true, "CZ": true, "DE": true, "DK": true, "EE": true, "ES": true,
+	"FI": true, "FR": true, "GR": true, "HR": true, "HU": true, "IE": true, "IT": true, "LT": true, "LU": true,
+	"LV": true, "MT": true, "NL": true, "PL": true, "PT": true, "RO": true, "SE": true, "SI": true, "SK": true,
+}
+
+type Country struct {
+	Iso2          string
+	Iso3          string
+	NumericCode   string
+	Name          string
+	OfficialName  string
+	Region        string
+	Subregion     string
+	CurrencyCodes []string // sorted
+	EuMember      bool
+}
+
+// apiCountry is an element of the response of the all endpoint
+type apiCountry struct {
+	Name struct {
+		Common   string `json:"common"`
+		Official string `json:"official"`
+	} `json:"name"`
+	Cca2       string                     `json:"cca2"`
+	Cca3       string                     `json:"cca3"`
+	Ccn3       string                     `json:"ccn3"`
+	Currencies map[string]json.RawMessage `json:"currencies"` // keyed by ISO 4217 code
+	Region     string                     `json:"region"`
+	Subregion  string                     `json:"subregion"`
+}
+
+// GetApiCountries returns all countries and territories
+func (c Client) GetApiCountries() (countries []Country, err error) {
+
+	resp, err := c.HttpClient.Get(c.baseUrl() + "/all?fields=" + fields)
+	if err != nil {
+		return nil, fmt.Errorf("c.HttpClient.Get failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadAll failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+
+	countries, err = ParseCountriesJson(body)
+	if err != nil {
+		return nil, fmt.Errorf("ParseCountriesJson failed: %w", err)
+	}
+
+	return countries, nil
+}
+
+// ParseCountriesJson parses the response of the all endpoint
+func ParseCountriesJson(content []byte) (countries []Country, err error) {
+
+	/* content looks like this:
+	[{"name":{"common":"Germany","official":"Federal Republic of Germany","nativeName":{...}},"cca2":"DE","cca3":"DEU","ccn3":"276",
+	  "currencies":{"EUR":{"name":"Euro","symbol":"€"}},"region":"Europe","subregion":"Western Europe"}, ...]
+	*/
+
+	apiCountries := []apiCountry{}
+	if err = json.Unmarshal(content, &apiCountries); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, ac := range apiCountries {
+
+		iso2 := strings.ToUpper(ac.Cca2)
+		if len(iso2) != 2 || len(ac.Cca3) != 3 {
+			return nil, fmt.Errorf("%w: invalid codes '%s' / '%s' of %s", cerrors.ErrValidationFailed, ac.Cca2, ac.Cca3, ac.Name.Common)
+		}
+
+		currCodes := []string{}
+		for code := range ac.Currencies {
+			currCodes = append(currCodes, strings.ToUpper(code))
+		}
+		sort.Strings(currCodes)
+
+		countries = append(countries, Country{
+			Iso2:          iso2,
+			Iso3:          strings.ToUpper(ac.Cca3),
+			NumericCode:   ac.Ccn3,
+			Name:          ac.Name.Common,
+			OfficialName:  ac.Name.Official,
+			Region:        ac.Region,
+			Subregion:     ac.Subregion,
+			CurrencyCodes: currCodes,
+			EuMember:      euMembers[iso2],
+		})
+	}
+
+	if len(countries) == 0 {
+		return nil, fmt.Errorf("%w: no countries found", cerrors.ErrNotFound)
+	}
+
+	return countries, nil
+}
+
+// CountriesToMap converts countries to a map with Iso2 as key
+func CountriesToMap(countries []Country) (itemsMap map[string]isocountry.Model) {
+
+	itemsMap = make(map[string]isocountry.Model)
+	for _, ctry := range countries {
+		itemsMap[ctry.Iso2] = isocountry.Model{Input: isocountry.Input{
+			Iso2:          ctry.Iso2,
+			Iso3:          ctry.Iso3,
+			NumericCode:   ctry.NumericCode,
+			Name:          ctry.Name,
+			OfficialName:  ctry.OfficialName,
+			Region:        ctry.Region,
+			Subregion:     ctry.Subregion,
+			CurrencyCodes: ctry.CurrencyCodes,
+			EuMember:      ctry.EuMember,
+		}}
+	}
+	return itemsMap
+}
@@ -0,0 +1,207 @@
+package s3api
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+)
+
+// Docs: https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjectsV2.html
+// works with any S3-compatible object storage, e.g. Google Cloud Storage with HMAC keys (endpoint https://storage.googleapis.com, region auto) or MinIO
+// buckets are addressed path-style, i.e. <endpoint>/<bucket>/<key>
+
+const (
+	apiShortname   string = "s3"
+	timeoutSecs    int    = 120 // objects are downloaded in full
+	pageSize       int    = 1000
+	defaultRegion  string = "us-east-1"
+	maxObjectBytes int64  = 100 << 20 // larger objects are rejected, since they are held in memory
+)
+
+type Client struct {
+	HttpClient      *http.Client
+	Endpoint        string // e.g. https://s3.eu-central-1.amazonaws.com
+	Region          string // signing region, e.g. eu-central-1. Defaults to us-east-1
+	Bucket          string
+	AccessKeyId     string
+	SecretAccessKey string
+	InfoLog         *slog.Logger
+	ErrorLog        *slog.Logger
+}
+
+func NewClient(endpoint, region, bucket, accessKeyId, secretAccessKey string, infoLog, errorLog *slog.Logger) (client Client) {
+
+	if region == "" {
+		region = defaultRegion
+	}
+
+	return Client{
+		HttpClient: &http.Client{
+			Timeout: time.Duration(timeoutSecs) * time.Second,
+		},
+		Endpoint:        strings.TrimSuffix(endpoint, "/"),
+		Region:          region,
+		Bucket:          bucket,
+		AccessKeyId:     accessKeyId,
+		SecretAccessKey: secretAccessKey,
+		InfoLog:         infoLog.With("api", apiShortname),
+		ErrorLog:        errorLog.With("api", apiShortname),
+	}
+}
+
+// apiError is the XML error response
+type apiError struct {
+	Code    string `xml:"Code"` // e.g. AccessDenied, NoSuchBucket, SignatureDoesNotMatch
+	Message string `xml:"Message"`
+}
+
+// get requests the bucket path of key with params, signed, and returns the response body
+func (c Client) get(ctx context.Context, key string, params url.Values) (body []byte, err error) {
+
+	if c.Endpoint == "" || c.Bucket == "" {
+		return nil, fmt.Errorf("%w: endpoint and bucket are required", cerrors.ErrValidationFailed)
+	}
+
+	// the path is encoded as signed, so that keys with special characters are requested as they are signed
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("url.Parse failed: %w", err)
+	}
+	u.Path = "/" + c.Bucket + "/" + key
+	u.RawPath = uriEncode(u.Path, false)
+	u.RawQuery = canonicalQuery(params)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	c.sign(req, time.Now())
+
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("c.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+	defer resp.Body.Close()
+
+	body, err = io.ReadAll(io.LimitReader(resp.Body, maxObjectBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadAll failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+	}
+	if int64(len(body)) > maxObjectBytes {
+		return nil, fmt.Errorf("%w: %s is larger than %d bytes", cerrors.ErrValidationFailed, key, maxObjectBytes)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return body, nil
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", cerrors.ErrNotFound, u.Path)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		// e.g. 403 AccessDenied or SignatureDoesNotMatch
+		errS := apiError{}
+		_ = xml.Unmarshal(body, &errS)
+		return nil, fmt.Errorf("%w: status %d: %s: %s", cerrors.ErrValidationFailed, resp.StatusCode, errS.Code, errS.Message)
+	default:
+		return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+	}
+}
+
+// Object describes an object in the bucket
+type Object struct {
+	Key          string
+	ETag         string // changes when the object is replaced
+	Size         int64
+	LastModified time.Time
+}
+
+// ListObjects returns the objects whose key starts with prefix, in key order. Keys ending with "/", which folders are often created as, are skipped
+func (c Client) ListObjects(ctx context.Context, prefix string) (objects []Object, err error) {
+
+	params := url.Values{}
+	params.Set("list-type", "2")
+	params.Set("prefix", prefix)
+	params.Set("max-keys", strconv.Itoa(pageSize))
+
+	err = apiclient.Cursor(ctx, apiclient.Paging{Log: c.InfoLog},
+		func(nextToken string) ([]byte, error) {
+			if nextToken != "" {
+				params.Set("continuation-token", nextToken)
+			}
+			return c.get(ctx, "", params)
+		},
+		func(body []byte) (string, error) {
+			page, nextToken, err := ParseListObjectsXml(body)
+			if err != nil {
+				return "", fmt.Errorf("ParseListObjectsXml failed: %w", err)
+			}
+			objects = append(objects, page...)
+			return nextToken, nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("apiclient.Cursor failed: %w", err)
+	}
+
+	return objects, nil
+}
+
+// ParseListObjectsXml parses a page of ListObjectsV2 and returns the continuation token of the next page, if any
+func ParseListObjectsXml(content []byte) (objects []Object, nextToken string, err error) {
+
+	/* content looks like this:
+	<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>partner-drop</Name><Prefix>acme/</Prefix><KeyCount>2</KeyCount>
+	  <MaxKeys>1000</MaxKeys><IsTruncated>true</IsTruncated><NextContinuationToken>1ueGcxLPRx1Tr/XYExHnhbYLgveDs2J/wm36Hy4vbOwM=</NextContinuationToken>
+	  <Contents><Key>acme/prices-2024-05-01.csv</Key><LastModified>2024-05-01T06:12:44.000Z</LastModified><ETag>"9b2cf535f27731c974343645a3985328"</ETag>
+	  <Size>18342</Size><StorageClass>STANDARD</StorageClass></Contents>...</ListBucketResult>
+	*/
+
+	respS := struct {
+		IsTruncated           bool   `xml:"IsTruncated"`
+		NextContinuationToken string `xml:"NextContinuationToken"`
+		Contents              []struct {
+			Key          string    `xml:"Key"`
+			LastModified time.Time `xml:"LastModified"`
+			ETag         string    `xml:"ETag"`
+			Size         int64     `xml:"Size"`
+		} `xml:"Contents"`
+	}{}
+	if err = xml.Unmarshal(content, &respS); err != nil {
+		return nil, "", fmt.Errorf("%w: xml.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, apiObject := range respS.Contents {
+		if strings.HasSuffix(apiObject.Key, "/") {
+			continue
+		}
+		objects = append(objects, Object{
+			Key:          apiObject.Key,
+			ETag:         strings.Trim(apiObject.ETag, `"`),
+			Size:         apiObject.Size,
+			LastModified: apiObject.LastModified,
+		})
+	}
+
+	if respS.IsTruncated {
+		nextToken = respS.NextContinuationToken
+	}
+	return objects, nextToken, nil
+}
+
+// GetObject returns the content of the object with key
+func (c Client) GetObject(ctx context.Context, key string) (content []byte, err error) {
+
+	content, err = c.get(ctx, key, nil)
+	if err != nil {
+		return nil, fmt.Errorf("c.get failed: %w", err)
+	}
+	return content, nil
+}
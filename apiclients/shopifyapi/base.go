@@ -0,0 +1,130 @@
+package shopifyapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+)
+
+// Docs: https://shopify.dev/docs/api/admin-rest
+// an Admin API access token of a custom app is needed, with the read_products and read_orders scopes
+
+const (
+	apiShortname      string = "shopify"
+	DefaultApiVersion string = "2024-07"
+	timeoutSecs       int    = 30
+	pageSize          int    = 250                    // maximum of list endpoints
+	requestInterval          = 500 * time.Millisecond // leak rate of the standard plan's request bucket
+	maxAttempts       int    = 3                      // per request, if rate limited
+	defaultRetryAfter        = 2 * time.Second
+)
+
+// copies of a Client share its rate limit
+type Client struct {
+	HttpClient  *http.Client
+	BaseUrl     string // Admin API root, e.g. of a fixture server in tests. Defaults to the shop's myshopify.com domain
+	Shop        string // shop name, e.g. "acme" for acme.myshopify.com
+	ApiVersion  string // defaults to DefaultApiVersion
+	AccessToken string
+	InfoLog     *slog.Logger
+	ErrorLog    *slog.Logger
+
+	limiter *apiclient.Limiter
+}
+
+func NewClient(shop, accessToken string, infoLog, errorLog *slog.Logger) (client Client) {
+
+	return Client{
+		HttpClient: &http.Client{
+			Timeout: time.Duration(timeoutSecs) * time.Second,
+		},
+		Shop:        shop,
+		ApiVersion:  DefaultApiVersion,
+		AccessToken: accessToken,
+		InfoLog:     infoLog.With("api", apiShortname),
+		ErrorLog:    errorLog.With("api", apiShortname),
+		limiter:     apiclient.NewLimiter(requestInterval),
+	}
+}
+
+// baseUrl returns c.BaseUrl, or the Admin API root of c.Shop if not set
+func (c Client) baseUrl() string {
+	if c.BaseUrl != "" {
+		return strings.TrimSuffix(c.BaseUrl, "/")
+	}
+	apiVersion := c.ApiVersion
+	if apiVersion == "" {
+		apiVersion = DefaultApiVersion
+	}
+	return fmt.Sprintf("https://%s.myshopify.com/admin/api/%s", c.Shop, apiVersion)
+}
+
+// getAll requests all pages of the list endpoint path with params, following the cursors of the Link headers, and calls pageFunc with each page's body
+func (c Client) getAll(ctx context.Context, path string, params url.Values, pageFunc func(body []byte) error) error {
+
+	params.Set("limit", strconv.Itoa(pageSize))
+
+	return apiclient.LinkHeader(ctx, apiclient.Paging{Log: c.InfoLog}, c.baseUrl()+path+"?"+params.Encode(),
+		func(reqUrl string) ([]byte, http.Header, error) { return c.get(ctx, reqUrl) }, pageFunc)
+}
+
+// get requests reqUrl, waiting for the rate limit, and returns the response body and header
+// rate limited requests (429) are retried after the delay given by the API
+func (c Client) get(ctx context.Context, reqUrl string) (body []byte, header http.Header, err error) {
+
+	if c.AccessToken == "" {
+		return nil, nil, fmt.Errorf("%w: access token is required", cerrors.ErrValidationFailed)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	req.Header.Set("X-Shopify-Access-Token", c.AccessToken)
+	req.Header.Set("Accept", "application/json")
+
+	for attempt := 1; ; attempt++ {
+
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, nil, fmt.Errorf("c.limiter.Wait failed: %w", err)
+		}
+
+		resp, err := c.HttpClient.Do(req)
+		if err != nil {
+			return nil, nil, fmt.Errorf("c.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+		}
+
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("io.ReadAll failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+		}
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			return body, resp.Header, nil
+		case http.StatusNotFound:
+			return nil, nil, fmt.Errorf("%w: %s", cerrors.ErrNotFound, req.URL.Path)
+		case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden:
+			return nil, nil, fmt.Errorf("%w: status %d: %s", cerrors.ErrValidationFailed, resp.StatusCode, string(body))
+		case http.StatusTooManyRequests:
+			if attempt == maxAttempts {
+				return nil, nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+			}
+			retryAfter := apiclient.ParseRetryAfter(resp.Header.Get("Retry-After"), defaultRetryAfter)
+			c.InfoLog.Warn("rate limited, retrying", "path", req.URL.Path, "retry_after", retryAfter.String())
+			c.limiter.Pause(retryAfter)
+		default:
+			return nil, nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+		}
+	}
+}
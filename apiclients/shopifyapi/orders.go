@@ -0,0 +1,175 @@
+package shopifyapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/shopify/shoporder"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+type Order struct {
+	ShopifyId         int64
+	Name              string // e.g. #1001
+	Email             string
+	Currency          string
+	FinancialStatus   string
+	FulfillmentStatus string // empty if unfulfilled
+	SubtotalPrice     float64
+	TotalTax          float64
+	TotalDiscounts    float64
+	TotalPrice        float64
+	LineItemCount     int
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	ProcessedAt       *time.Time
+	CancelledAt       *time.Time
+	ClosedAt          *time.Time
+}
+
+// apiOrders is a page of the orders endpoint
+type apiOrders struct {
+	Orders []struct {
+		Id                int64      `json:"id"`
+		Name              string     `json:"name"`
+		Email             *string    `json:"email"`
+		Currency          string     `json:"currency"`
+		FinancialStatus   *string    `json:"financial_status"`
+		FulfillmentStatus *string    `json:"fulfillment_status"`
+		SubtotalPrice     string     `json:"subtotal_price"`
+		TotalTax          string     `json:"total_tax"`
+		TotalDiscounts    string     `json:"total_discounts"`
+		TotalPrice        string     `json:"total_price"`
+		CreatedAt         time.Time  `json:"created_at"`
+		UpdatedAt         time.Time  `json:"updated_at"`
+		ProcessedAt       *time.Time `json:"processed_at"`
+		CancelledAt       *time.Time `json:"cancelled_at"`
+		ClosedAt          *time.Time `json:"closed_at"`
+		LineItems         []struct {
+			Id int64 `json:"id"`
+		} `json:"line_items"`
+	} `json:"orders"`
+}
+
+// GetApiOrders returns the orders of any status updated since updatedSince
+func (c Client) GetApiOrders(ctx context.Context, updatedSince time.Time) (orders []Order, err error) {
+
+	params := url.Values{}
+	params.Set("status", "any")
+	params.Set("updated_at_min", updatedSince.Format(time.RFC3339))
+	params.Set("fields", "id,name,email,currency,financial_status,fulfillment_status,subtotal_price,total_tax,total_discounts,total_price,"+
+		"created_at,updated_at,processed_at,cancelled_at,closed_at,line_items")
+
+	err = c.getAll(ctx, "/orders.json", params, func(body []byte) error {
+		page, err := ParseOrdersJson(body)
+		if err != nil {
+			return fmt.Errorf("ParseOrdersJson failed: %w", err)
+		}
+		orders = append(orders, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("c.getAll failed: %w", err)
+	}
+
+	return orders, nil
+}
+
+// ParseOrdersJson parses a page of the orders endpoint
+func ParseOrdersJson(content []byte) (orders []Order, err error) {
+
+	/* content looks like this:
+	{"orders":[{"id":450789469,"name":"#1001","email":"bob.norman@mail.example.com","currency":"USD","financial_status":"paid","fulfillment_status":null,
+	  "subtotal_price":"597.00","total_tax":"11.94","total_discounts":"10.00","total_price":"598.94",
+	  "created_at":"2024-06-01T13:30:00-04:00","updated_at":"2024-06-02T09:00:00-04:00","processed_at":"2024-06-01T13:30:00-04:00","cancelled_at":null,"closed_at":null,
+	  "line_items":[{"id":466157049, ...}, ...]}]}
+	*/
+
+	respS := apiOrders{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, apiOrd := range respS.Orders {
+
+		ord := Order{
+			ShopifyId:         apiOrd.Id,
+			Name:              apiOrd.Name,
+			Email:             deref(apiOrd.Email),
+			Currency:          strings.ToUpper(apiOrd.Currency),
+			FinancialStatus:   deref(apiOrd.FinancialStatus),
+			FulfillmentStatus: deref(apiOrd.FulfillmentStatus),
+			LineItemCount:     len(apiOrd.LineItems),
+			CreatedAt:         apiOrd.CreatedAt,
+			UpdatedAt:         apiOrd.UpdatedAt,
+			ProcessedAt:       apiOrd.ProcessedAt,
+			CancelledAt:       apiOrd.CancelledAt,
+			ClosedAt:          apiOrd.ClosedAt,
+		}
+
+		for _, amount := range []struct {
+			s   string
+			dst *float64
+		}{
+			{apiOrd.SubtotalPrice, &ord.SubtotalPrice},
+			{apiOrd.TotalTax, &ord.TotalTax},
+			{apiOrd.TotalDiscounts, &ord.TotalDiscounts},
+			{apiOrd.TotalPrice, &ord.TotalPrice},
+		} {
+			if *amount.dst, err = parsePrice(amount.s); err != nil {
+				return nil, fmt.Errorf("order %s: %w", apiOrd.Name, err)
+			}
+		}
+
+		orders = append(orders, ord)
+	}
+
+	return orders, nil
+}
+
+// OrdersToMap converts the orders of shop to a map with ShopifyId as key
+func OrdersToMap(orders []Order, shop string) (itemsMap map[int64]shoporder.Model, err error) {
+
+	itemsMap = make(map[int64]shoporder.Model)
+	for _, ord := range orders {
+
+		if _, ok := itemsMap[ord.ShopifyId]; ok {
+			return nil, fmt.Errorf("%w: duplicate order %d", cerrors.ErrValidationFailed, ord.ShopifyId)
+		}
+
+		itemsMap[ord.ShopifyId] = shoporder.Model{Input: shoporder.Input{
+			Shop:              shop,
+			ShopifyId:         ord.ShopifyId,
+			Name:              ord.Name,
+			Email:             ord.Email,
+			Currency:          ord.Currency,
+			FinancialStatus:   ord.FinancialStatus,
+			FulfillmentStatus: ord.FulfillmentStatus,
+			SubtotalPrice:     ord.SubtotalPrice,
+			TotalTax:          ord.TotalTax,
+			TotalDiscounts:    ord.TotalDiscounts,
+			TotalPrice:        ord.TotalPrice,
+			LineItemCount:     ord.LineItemCount,
+			CreatedAtShop:     lystype.Datetime(ord.CreatedAt),
+			UpdatedAtShop:     lystype.Datetime(ord.UpdatedAt),
+			ProcessedAt:       optionalDatetime(ord.ProcessedAt),
+			CancelledAt:       optionalDatetime(ord.CancelledAt),
+			ClosedAt:          optionalDatetime(ord.ClosedAt),
+		}}
+	}
+
+	return itemsMap, nil
+}
+
+func optionalDatetime(t *time.Time) *lystype.Datetime {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	dt := lystype.Datetime(*t)
+	return &dt
+}
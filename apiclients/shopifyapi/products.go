@@ -0,0 +1,239 @@
+package shopifyapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/shopify/shopproduct"
+	"github.com/loveyourstack/connectors/stores/shopify/shopvariant"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+type Product struct {
+	ShopifyId   int64
+	Title       string
+	Handle      string
+	Vendor      string
+	ProductType string
+	Status      string // active, archived or draft
+	Tags        []string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Variants    []Variant
+}
+
+type Variant struct {
+	ShopifyId         int64
+	ProductId         int64
+	Title             string
+	Sku               string
+	Barcode           string
+	Price             float64
+	CompareAtPrice    *float64
+	InventoryQuantity int
+	UpdatedAt         time.Time
+}
+
+// apiProducts is a page of the products endpoint
+type apiProducts struct {
+	Products []struct {
+		Id          int64     `json:"id"`
+		Title       string    `json:"title"`
+		Handle      string    `json:"handle"`
+		Vendor      string    `json:"vendor"`
+		ProductType string    `json:"product_type"`
+		Status      string    `json:"status"`
+		Tags        string    `json:"tags"` // comma separated
+		CreatedAt   time.Time `json:"created_at"`
+		UpdatedAt   time.Time `json:"updated_at"`
+		Variants    []struct {
+			Id                int64     `json:"id"`
+			ProductId         int64     `json:"product_id"`
+			Title             string    `json:"title"`
+			Sku               *string   `json:"sku"`
+			Barcode           *string   `json:"barcode"`
+			Price             string    `json:"price"`
+			CompareAtPrice    *string   `json:"compare_at_price"`
+			InventoryQuantity int       `json:"inventory_quantity"`
+			UpdatedAt         time.Time `json:"updated_at"`
+		} `json:"variants"`
+	} `json:"products"`
+}
+
+// GetApiProducts returns all products of the shop, with their variants
+func (c Client) GetApiProducts(ctx context.Context) (products []Product, err error) {
+
+	params := url.Values{}
+	params.Set("fields", "id,title,handle,vendor,product_type,status,tags,created_at,updated_at,variants")
+
+	err = c.getAll(ctx, "/products.json", params, func(body []byte) error {
+		page, err := ParseProductsJson(body)
+		if err != nil {
+			return fmt.Errorf("ParseProductsJson failed: %w", err)
+		}
+		products = append(products, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("c.getAll failed: %w", err)
+	}
+
+	return products, nil
+}
+
+// ParseProductsJson parses a page of the products endpoint
+func ParseProductsJson(content []byte) (products []Product, err error) {
+
+	/* content looks like this:
+	{"products":[{"id":632910392,"title":"IPod Nano - 8GB","handle":"ipod-nano","vendor":"Apple","product_type":"Cult Products","status":"active","tags":"Emotive, Flash Memory",
+	  "created_at":"2024-01-02T09:28:43-05:00","updated_at":"2024-06-03T10:15:00-04:00",
+	  "variants":[{"id":808950810,"product_id":632910392,"title":"Pink","price":"199.00","compare_at_price":null,"sku":"IPOD2008PINK","barcode":"1234_pink","inventory_quantity":10,"updated_at":"..."}]}]}
+	*/
+
+	respS := apiProducts{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, apiProd := range respS.Products {
+
+		prod := Product{
+			ShopifyId:   apiProd.Id,
+			Title:       apiProd.Title,
+			Handle:      apiProd.Handle,
+			Vendor:      apiProd.Vendor,
+			ProductType: apiProd.ProductType,
+			Status:      apiProd.Status,
+			Tags:        splitTags(apiProd.Tags),
+			CreatedAt:   apiProd.CreatedAt,
+			UpdatedAt:   apiProd.UpdatedAt,
+		}
+
+		for _, apiVar := range apiProd.Variants {
+
+			price, err := parsePrice(apiVar.Price)
+			if err != nil {
+				return nil, fmt.Errorf("variant %d: %w", apiVar.Id, err)
+			}
+			var compareAtPrice *float64
+			if apiVar.CompareAtPrice != nil && *apiVar.CompareAtPrice != "" {
+				p, err := parsePrice(*apiVar.CompareAtPrice)
+				if err != nil {
+					return nil, fmt.Errorf("variant %d: %w", apiVar.Id, err)
+				}
+				compareAtPrice = &p
+			}
+
+			prod.Variants = append(prod.Variants, Variant{
+				ShopifyId:         apiVar.Id,
+				ProductId:         apiProd.Id,
+				Title:             apiVar.Title,
+				Sku:               deref(apiVar.Sku),
+				Barcode:           deref(apiVar.Barcode),
+				Price:             price,
+				CompareAtPrice:    compareAtPrice,
+				InventoryQuantity: apiVar.InventoryQuantity,
+				UpdatedAt:         apiVar.UpdatedAt,
+			})
+		}
+
+		products = append(products, prod)
+	}
+
+	return products, nil
+}
+
+// splitTags splits Shopify's comma separated tags
+func splitTags(tags string) []string {
+
+	res := []string{}
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			res = append(res, tag)
+		}
+	}
+	return res
+}
+
+// parsePrice parses a Shopify money amount, which is sent as a string, e.g. "199.00"
+func parsePrice(s string) (float64, error) {
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid amount '%s'", cerrors.ErrValidationFailed, s)
+	}
+	return f, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// ProductsToMap converts the products of shop to a map with ShopifyId as key
+func ProductsToMap(products []Product, shop string) (itemsMap map[int64]shopproduct.Model, err error) {
+
+	itemsMap = make(map[int64]shopproduct.Model)
+	for _, prod := range products {
+
+		if _, ok := itemsMap[prod.ShopifyId]; ok {
+			return nil, fmt.Errorf("%w: duplicate product %d", cerrors.ErrValidationFailed, prod.ShopifyId)
+		}
+
+		itemsMap[prod.ShopifyId] = shopproduct.Model{Input: shopproduct.Input{
+			Shop:          shop,
+			ShopifyId:     prod.ShopifyId,
+			Title:         prod.Title,
+			Handle:        prod.Handle,
+			Vendor:        prod.Vendor,
+			ProductType:   prod.ProductType,
+			Status:        prod.Status,
+			Tags:          prod.Tags,
+			CreatedAtShop: lystype.Datetime(prod.CreatedAt),
+			UpdatedAtShop: lystype.Datetime(prod.UpdatedAt),
+		}}
+	}
+
+	return itemsMap, nil
+}
+
+// VariantsToMap converts the variants of products to a map with ShopifyId as key, resolving product ids with productIdMap (k = product ShopifyId, v = db id)
+func VariantsToMap(products []Product, productIdMap map[int64]int64) (itemsMap map[int64]shopvariant.Model, err error) {
+
+	itemsMap = make(map[int64]shopvariant.Model)
+	for _, prod := range products {
+
+		productFk, ok := productIdMap[prod.ShopifyId]
+		if !ok {
+			return nil, fmt.Errorf("%w: product %d", cerrors.ErrNotFound, prod.ShopifyId)
+		}
+
+		for _, v := range prod.Variants {
+			if _, ok := itemsMap[v.ShopifyId]; ok {
+				return nil, fmt.Errorf("%w: duplicate variant %d", cerrors.ErrValidationFailed, v.ShopifyId)
+			}
+
+			itemsMap[v.ShopifyId] = shopvariant.Model{Input: shopvariant.Input{
+				ProductFk:         productFk,
+				ShopifyId:         v.ShopifyId,
+				Title:             v.Title,
+				Sku:               v.Sku,
+				Barcode:           v.Barcode,
+				Price:             v.Price,
+				CompareAtPrice:    v.CompareAtPrice,
+				InventoryQuantity: v.InventoryQuantity,
+				UpdatedAtShop:     lystype.Datetime(v.UpdatedAt),
+			}}
+		}
+	}
+
+	return itemsMap, nil
+}
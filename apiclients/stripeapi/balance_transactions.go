@@ -0,0 +1,203 @@
+package stripeapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/stripe/stripefee"
+	"github.com/loveyourstack/connectors/stores/stripe/stripetxn"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// BalanceTransaction is a movement of the Stripe balance, e.g. a charge, refund or payout. Amounts are in major units
+type BalanceTransaction struct {
+	StripeId          string
+	Type              string
+	ReportingCategory string
+	Status            string // available or pending
+	Currency          string // upper case
+	Amount            float64
+	Fee               float64
+	Net               float64
+	ExchangeRate      *float64
+	Description       string
+	SourceId          string
+	CreatedAt         time.Time
+	AvailableOn       time.Time
+	Fees              []Fee
+}
+
+// Fee is part of the fee of a balance transaction
+type Fee struct {
+	Type        string // application_fee, payment_method_passthrough_fee, stripe_fee or tax
+	Description string
+	Application string
+	Currency    string
+	Amount      float64
+}
+
+// Settled returns true once the transaction is available: pending transactions may still change
+func (t BalanceTransaction) Settled() bool {
+	return t.Status != "pending"
+}
+
+// GetApiBalanceTransactions returns the balance transactions created since createdSince, newest first
+func (c Client) GetApiBalanceTransactions(ctx context.Context, createdSince time.Time) (txns []BalanceTransaction, err error) {
+
+	params := url.Values{}
+	params.Set("created[gte]", strconv.FormatInt(createdSince.Unix(), 10))
+
+	err = c.listAll(ctx, "/v1/balance_transactions", params, func(body []byte) error {
+		page, err := ParseBalanceTransactionsJson(body)
+		if err != nil {
+			return fmt.Errorf("ParseBalanceTransactionsJson failed: %w", err)
+		}
+		txns = append(txns, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("c.listAll failed: %w", err)
+	}
+
+	return txns, nil
+}
+
+// ParseBalanceTransactionsJson parses a page of the balance transactions endpoint
+func ParseBalanceTransactionsJson(content []byte) (txns []BalanceTransaction, err error) {
+
+	/* content looks like this:
+	{"object":"list","url":"/v1/balance_transactions","has_more":true,"data":[{"id":"txn_1MiN3gLkdIwHu7ixxapQrznl","object":"balance_transaction",
+	  "amount":-400,"available_on":1678043844,"created":1678043844,"currency":"usd","description":null,"exchange_rate":null,"fee":0,
+	  "fee_details":[{"amount":30,"application":null,"currency":"usd","description":"Stripe processing fees","type":"stripe_fee"}],
+	  "net":-400,"reporting_category":"transfer","source":"tr_1MiN3gLkdIwHu7ixNCZvFdgA","status":"available","type":"transfer"}]}
+	*/
+
+	respS := struct {
+		Data []struct {
+			Id           string   `json:"id"`
+			Amount       int64    `json:"amount"`
+			AvailableOn  int64    `json:"available_on"`
+			Created      int64    `json:"created"`
+			Currency     string   `json:"currency"`
+			Description  *string  `json:"description"`
+			ExchangeRate *float64 `json:"exchange_rate"`
+			Fee          int64    `json:"fee"`
+			FeeDetails   []struct {
+				Amount      int64   `json:"amount"`
+				Application *string `json:"application"`
+				Currency    string  `json:"currency"`
+				Description *string `json:"description"`
+				Type        string  `json:"type"`
+			} `json:"fee_details"`
+			Net               int64   `json:"net"`
+			ReportingCategory string  `json:"reporting_category"`
+			Source            *string `json:"source"` // an id, as source is not expanded
+			Status            string  `json:"status"`
+			Type              string  `json:"type"`
+		} `json:"data"`
+	}{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, apiTxn := range respS.Data {
+
+		if apiTxn.Id == "" || apiTxn.Currency == "" || apiTxn.Created == 0 {
+			return nil, fmt.Errorf("%w: balance transaction without id, currency or created: %s", cerrors.ErrValidationFailed, apiTxn.Id)
+		}
+
+		currency := strings.ToUpper(apiTxn.Currency)
+		txn := BalanceTransaction{
+			StripeId:          apiTxn.Id,
+			Type:              apiTxn.Type,
+			ReportingCategory: apiTxn.ReportingCategory,
+			Status:            apiTxn.Status,
+			Currency:          currency,
+			Amount:            toMajorUnits(apiTxn.Amount, currency),
+			Fee:               toMajorUnits(apiTxn.Fee, currency),
+			Net:               toMajorUnits(apiTxn.Net, currency),
+			ExchangeRate:      apiTxn.ExchangeRate,
+			Description:       deref(apiTxn.Description),
+			SourceId:          deref(apiTxn.Source),
+			CreatedAt:         time.Unix(apiTxn.Created, 0).UTC(),
+			AvailableOn:       time.Unix(apiTxn.AvailableOn, 0).UTC(),
+			Fees:              []Fee{},
+		}
+
+		for _, apiFee := range apiTxn.FeeDetails {
+			feeCurrency := strings.ToUpper(apiFee.Currency)
+			txn.Fees = append(txn.Fees, Fee{
+				Type:        apiFee.Type,
+				Description: deref(apiFee.Description),
+				Application: deref(apiFee.Application),
+				Currency:    feeCurrency,
+				Amount:      toMajorUnits(apiFee.Amount, feeCurrency),
+			})
+		}
+
+		txns = append(txns, txn)
+	}
+
+	return txns, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// BalanceTransactionsToMap converts balance transactions to a map with StripeId as key
+func BalanceTransactionsToMap(txns []BalanceTransaction) (itemsMap map[string]stripetxn.Model, err error) {
+
+	itemsMap = make(map[string]stripetxn.Model)
+	for _, txn := range txns {
+
+		if _, ok := itemsMap[txn.StripeId]; ok {
+			return nil, fmt.Errorf("%w: duplicate balance transaction %s", cerrors.ErrValidationFailed, txn.StripeId)
+		}
+
+		itemsMap[txn.StripeId] = stripetxn.Model{Input: stripetxn.Input{
+			StripeId:          txn.StripeId,
+			Type:              txn.Type,
+			ReportingCategory: txn.ReportingCategory,
+			Status:            txn.Status,
+			Currency:          txn.Currency,
+			Amount:            txn.Amount,
+			Fee:               txn.Fee,
+			Net:               txn.Net,
+			ExchangeRate:      txn.ExchangeRate,
+			Description:       txn.Description,
+			SourceId:          txn.SourceId,
+			CreatedAtStripe:   lystype.Datetime(txn.CreatedAt),
+			AvailableOn:       lystype.Datetime(txn.AvailableOn),
+		}}
+	}
+
+	return itemsMap, nil
+}
+
+// FeesToInputs converts the fees of txn to inputs of the balance transaction with db id balanceTransactionFk
+func FeesToInputs(txn BalanceTransaction, balanceTransactionFk int64) []stripefee.Input {
+
+	inputs := []stripefee.Input{}
+	for _, fee := range txn.Fees {
+		inputs = append(inputs, stripefee.Input{
+			BalanceTransactionFk: balanceTransactionFk,
+			Type:                 fee.Type,
+			Description:          fee.Description,
+			Application:          fee.Application,
+			Currency:             fee.Currency,
+			Amount:               fee.Amount,
+		})
+	}
+
+	return inputs
+}
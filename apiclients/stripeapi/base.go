@@ -0,0 +1,185 @@
+package stripeapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+)
+
+// Docs: https://docs.stripe.com/api
+// a secret key, or a restricted key with read access to balance transactions and payouts, is needed
+
+const (
+	apiShortname      string = "stripe"
+	defaultBaseUrl    string = "https://api.stripe.com"
+	DefaultApiVersion string = "2024-06-20"
+	timeoutSecs       int    = 30
+	pageSize          int    = 100                    // maximum of list endpoints
+	requestInterval          = 100 * time.Millisecond // well within the live mode read limit of 100 requests per second
+	maxAttempts       int    = 4                      // per request, if rate limited
+	retryBackoff             = time.Second            // doubled on each retry: Stripe sends no Retry-After header
+)
+
+// copies of a Client share its rate limit
+type Client struct {
+	HttpClient *http.Client
+	BaseUrl    string // API root, e.g. of a fixture server in tests. Defaults to Stripe
+	ApiKey     string
+	ApiVersion string // sent as Stripe-Version, so that responses don't change with the account's default version. Defaults to DefaultApiVersion
+	InfoLog    *slog.Logger
+	ErrorLog   *slog.Logger
+
+	limiter *apiclient.Limiter
+}
+
+func NewClient(apiKey string, infoLog, errorLog *slog.Logger) (client Client) {
+
+	return Client{
+		HttpClient: &http.Client{
+			Timeout: time.Duration(timeoutSecs) * time.Second,
+		},
+		ApiKey:     apiKey,
+		ApiVersion: DefaultApiVersion,
+		InfoLog:    infoLog.With("api", apiShortname),
+		ErrorLog:   errorLog.With("api", apiShortname),
+		limiter:    apiclient.NewLimiter(requestInterval),
+	}
+}
+
+// baseUrl returns c.BaseUrl, or Stripe if not set
+func (c Client) baseUrl() string {
+	if c.BaseUrl != "" {
+		return strings.TrimSuffix(c.BaseUrl, "/")
+	}
+	return defaultBaseUrl
+}
+
+// apiError is the error response of the API
+type apiError struct {
+	Error struct {
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// listPage is the envelope of list endpoints
+type listPage struct {
+	Data []struct {
+		Id string `json:"id"`
+	} `json:"data"`
+	HasMore bool `json:"has_more"`
+}
+
+// listAll requests all pages of the list endpoint path with params, paging with the starting_after cursor, and calls pageFunc with each page's body
+// objects are listed newest first
+func (c Client) listAll(ctx context.Context, path string, params url.Values, pageFunc func(body []byte) error) error {
+
+	params.Set("limit", strconv.Itoa(pageSize))
+
+	return apiclient.Cursor(ctx, apiclient.Paging{Log: c.InfoLog},
+		func(cursor string) ([]byte, error) {
+			if cursor != "" {
+				params.Set("starting_after", cursor)
+			}
+			return c.get(ctx, path, params)
+		},
+		func(body []byte) (string, error) {
+			if err := pageFunc(body); err != nil {
+				return "", fmt.Errorf("pageFunc failed: %w", err)
+			}
+
+			page := listPage{}
+			if err := json.Unmarshal(body, &page); err != nil {
+				return "", fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+			}
+			if !page.HasMore || len(page.Data) == 0 {
+				return "", nil
+			}
+			return page.Data[len(page.Data)-1].Id, nil
+		})
+}
+
+// get requests path with params, waiting for the rate limit, and returns the response body
+// rate limited requests (429) are retried with exponential backoff
+func (c Client) get(ctx context.Context, path string, params url.Values) (body []byte, err error) {
+
+	if c.ApiKey == "" {
+		return nil, fmt.Errorf("%w: API key is required", cerrors.ErrValidationFailed)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseUrl()+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	if err = apiclient.Bearer(c.ApiKey).Authorize(req); err != nil {
+		return nil, fmt.Errorf("Authorize failed: %w", err)
+	}
+	if c.ApiVersion != "" {
+		req.Header.Set("Stripe-Version", c.ApiVersion)
+	}
+
+	for attempt := 1; ; attempt++ {
+
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("c.limiter.Wait failed: %w", err)
+		}
+
+		resp, err := c.HttpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("c.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+		}
+
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("io.ReadAll failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return body, nil
+		case resp.StatusCode == http.StatusNotFound:
+			return nil, fmt.Errorf("%w: %s", cerrors.ErrNotFound, path)
+		case resp.StatusCode == http.StatusTooManyRequests:
+			if attempt == maxAttempts {
+				return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+			}
+			retryAfter := retryBackoff << (attempt - 1)
+			c.InfoLog.Warn("rate limited, retrying", "path", path, "retry_after", retryAfter.String())
+			c.limiter.Pause(retryAfter)
+		case resp.StatusCode >= 400 && resp.StatusCode < 500:
+			// e.g. 400 invalid parameters, 401 invalid API key, 403 restricted key without permission
+			errS := apiError{}
+			_ = json.Unmarshal(body, &errS)
+			return nil, fmt.Errorf("%w: status %d: %s: %s", cerrors.ErrValidationFailed, resp.StatusCode, errS.Error.Type, errS.Error.Message)
+		default:
+			return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+		}
+	}
+}
+
+// zeroDecimalCurrencies have no minor unit: their amounts are sent as is
+// https://docs.stripe.com/currencies#zero-decimal
+var zeroDecimalCurrencies = map[string]bool{
+	"BIF": true, "CLP": true, "DJF": true, "GNF": true, "JPY": true, "KMF": true, "KRW": true, "MGA": true,
+	"PYG": true, "RWF": true, "UGX": true, "VND": true, "VUV": true, "XAF": true, "XOF": true, "XPF": true,
+}
+
+// toMajorUnits converts an amount in the minor unit of currency, as sent by Stripe, e.g. 1234 for 12.34 EUR, to the major unit
+func toMajorUnits(amount int64, currency string) float64 {
+	if zeroDecimalCurrencies[currency] {
+		return float64(amount)
+	}
+	return float64(amount) / 100
+}
@@ -0,0 +1,159 @@
+package stripeapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/stripe/stripepayout"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// PayoutSettleDays is the number of days after arrival during which a paid payout may still fail, e.g. if the bank returns it
+const PayoutSettleDays int = 5
+
+// Payout is a transfer of the Stripe balance to a bank account or card. Amounts are in major units
+type Payout struct {
+	StripeId             string
+	Amount               float64
+	Currency             string // upper case
+	Status               string // paid, pending, in_transit, canceled or failed
+	Method               string // standard or instant
+	Type                 string // bank_account or card
+	Automatic            bool
+	Description          string
+	StatementDescriptor  string
+	DestinationId        string
+	BalanceTransactionId string
+	FailureCode          string
+	CreatedAt            time.Time
+	ArrivalDate          time.Time
+}
+
+// Settled returns true if the payout can no longer change: it was canceled, failed, or paid more than PayoutSettleDays ago
+func (p Payout) Settled(now time.Time) bool {
+	switch p.Status {
+	case "canceled", "failed":
+		return true
+	case "paid":
+		return now.Sub(p.ArrivalDate) > time.Duration(PayoutSettleDays)*24*time.Hour
+	default:
+		return false
+	}
+}
+
+// GetApiPayouts returns the payouts created since createdSince, newest first
+func (c Client) GetApiPayouts(ctx context.Context, createdSince time.Time) (payouts []Payout, err error) {
+
+	params := url.Values{}
+	params.Set("created[gte]", strconv.FormatInt(createdSince.Unix(), 10))
+
+	err = c.listAll(ctx, "/v1/payouts", params, func(body []byte) error {
+		page, err := ParsePayoutsJson(body)
+		if err != nil {
+			return fmt.Errorf("ParsePayoutsJson failed: %w", err)
+		}
+		payouts = append(payouts, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("c.listAll failed: %w", err)
+	}
+
+	return payouts, nil
+}
+
+// ParsePayoutsJson parses a page of the payouts endpoint
+func ParsePayoutsJson(content []byte) (payouts []Payout, err error) {
+
+	/* content looks like this:
+	{"object":"list","url":"/v1/payouts","has_more":false,"data":[{"id":"po_1OaFDbEcg9tTZuTgNYmX0PKB","object":"payout","amount":1100,"arrival_date":1680652800,
+	  "automatic":true,"balance_transaction":"txn_1OaFDcEcg9tTZuTgYMR25tSe","created":1680648691,"currency":"eur","description":"STRIPE PAYOUT",
+	  "destination":"ba_1MtIhL2eZvKYlo2CAElKwKu2","failure_code":null,"method":"standard","statement_descriptor":null,"status":"paid","type":"bank_account"}]}
+	*/
+
+	respS := struct {
+		Data []struct {
+			Id                  string  `json:"id"`
+			Amount              int64   `json:"amount"`
+			ArrivalDate         int64   `json:"arrival_date"`
+			Automatic           bool    `json:"automatic"`
+			BalanceTransaction  *string `json:"balance_transaction"`
+			Created             int64   `json:"created"`
+			Currency            string  `json:"currency"`
+			Description         *string `json:"description"`
+			Destination         *string `json:"destination"`
+			FailureCode         *string `json:"failure_code"`
+			Method              string  `json:"method"`
+			StatementDescriptor *string `json:"statement_descriptor"`
+			Status              string  `json:"status"`
+			Type                string  `json:"type"`
+		} `json:"data"`
+	}{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, apiPayout := range respS.Data {
+
+		if apiPayout.Id == "" || apiPayout.Currency == "" || apiPayout.Created == 0 {
+			return nil, fmt.Errorf("%w: payout without id, currency or created: %s", cerrors.ErrValidationFailed, apiPayout.Id)
+		}
+
+		currency := strings.ToUpper(apiPayout.Currency)
+		payouts = append(payouts, Payout{
+			StripeId:             apiPayout.Id,
+			Amount:               toMajorUnits(apiPayout.Amount, currency),
+			Currency:             currency,
+			Status:               apiPayout.Status,
+			Method:               apiPayout.Method,
+			Type:                 apiPayout.Type,
+			Automatic:            apiPayout.Automatic,
+			Description:          deref(apiPayout.Description),
+			StatementDescriptor:  deref(apiPayout.StatementDescriptor),
+			DestinationId:        deref(apiPayout.Destination),
+			BalanceTransactionId: deref(apiPayout.BalanceTransaction),
+			FailureCode:          deref(apiPayout.FailureCode),
+			CreatedAt:            time.Unix(apiPayout.Created, 0).UTC(),
+			ArrivalDate:          time.Unix(apiPayout.ArrivalDate, 0).UTC(),
+		})
+	}
+
+	return payouts, nil
+}
+
+// PayoutsToMap converts payouts to a map with StripeId as key
+func PayoutsToMap(payouts []Payout) (itemsMap map[string]stripepayout.Model, err error) {
+
+	itemsMap = make(map[string]stripepayout.Model)
+	for _, p := range payouts {
+
+		if _, ok := itemsMap[p.StripeId]; ok {
+			return nil, fmt.Errorf("%w: duplicate payout %s", cerrors.ErrValidationFailed, p.StripeId)
+		}
+
+		itemsMap[p.StripeId] = stripepayout.Model{Input: stripepayout.Input{
+			StripeId:             p.StripeId,
+			Amount:               p.Amount,
+			Currency:             p.Currency,
+			Status:               p.Status,
+			Method:               p.Method,
+			Type:                 p.Type,
+			Automatic:            p.Automatic,
+			Description:          p.Description,
+			StatementDescriptor:  p.StatementDescriptor,
+			DestinationId:        p.DestinationId,
+			BalanceTransactionId: p.BalanceTransactionId,
+			FailureCode:          p.FailureCode,
+			CreatedAtStripe:      lystype.Datetime(p.CreatedAt),
+			ArrivalDate:          lystype.Date(p.ArrivalDate),
+		}}
+	}
+
+	return itemsMap, nil
+}
@@ -0,0 +1,157 @@
+package wiseapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+)
+
+// Docs: https://docs.wise.com/api-docs/api-reference
+// a personal API token, created in the Wise account settings, is needed. Read only tokens suffice
+
+const (
+	apiShortname      string = "wise"
+	defaultBaseUrl    string = "https://api.transferwise.com"
+	sandboxBaseUrl    string = "https://api.sandbox.transferwise.tech"
+	timeoutSecs       int    = 30
+	pageSize          int    = 100
+	requestInterval          = 200 * time.Millisecond
+	maxAttempts       int    = 3 // per request, if rate limited
+	defaultRetryAfter        = 5 * time.Second
+)
+
+// copies of a Client share its rate limit
+type Client struct {
+	HttpClient *http.Client
+	BaseUrl    string // API root, e.g. of a fixture server in tests. Defaults to Wise production, or the sandbox if Sandbox is set
+	Sandbox    bool
+	ApiToken   string
+	InfoLog    *slog.Logger
+	ErrorLog   *slog.Logger
+
+	limiter *apiclient.Limiter
+}
+
+func NewClient(apiToken string, infoLog, errorLog *slog.Logger) (client Client) {
+
+	return Client{
+		HttpClient: &http.Client{
+			Timeout: time.Duration(timeoutSecs) * time.Second,
+		},
+		ApiToken: apiToken,
+		InfoLog:  infoLog.With("api", apiShortname),
+		ErrorLog: errorLog.With("api", apiShortname),
+		limiter:  apiclient.NewLimiter(requestInterval),
+	}
+}
+
+// baseUrl returns c.BaseUrl, or the Wise production or sandbox root if not set
+func (c Client) baseUrl() string {
+	if c.BaseUrl != "" {
+		return strings.TrimSuffix(c.BaseUrl, "/")
+	}
+	if c.Sandbox {
+		return sandboxBaseUrl
+	}
+	return defaultBaseUrl
+}
+
+// get requests path with params, waiting for the rate limit, and returns the response body
+// rate limited requests (429) are retried after the delay given by the API
+func (c Client) get(ctx context.Context, path string, params url.Values) (body []byte, err error) {
+
+	if c.ApiToken == "" {
+		return nil, fmt.Errorf("%w: API token is required", cerrors.ErrValidationFailed)
+	}
+
+	reqUrl := c.baseUrl() + path
+	if len(params) > 0 {
+		reqUrl += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	if err = apiclient.Bearer(c.ApiToken).Authorize(req); err != nil {
+		return nil, fmt.Errorf("Authorize failed: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	for attempt := 1; ; attempt++ {
+
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("c.limiter.Wait failed: %w", err)
+		}
+
+		resp, err := c.HttpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("c.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+		}
+
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("io.ReadAll failed: %w", cerrors.UpstreamError{Source: apiShortname, Err: err})
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return body, nil
+		case resp.StatusCode == http.StatusNotFound:
+			return nil, fmt.Errorf("%w: %s", cerrors.ErrNotFound, path)
+		case resp.StatusCode == http.StatusTooManyRequests:
+			if attempt == maxAttempts {
+				return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+			}
+			retryAfter := apiclient.ParseRetryAfter(resp.Header.Get("Retry-After"), defaultRetryAfter)
+			c.InfoLog.Warn("rate limited, retrying", "path", path, "retry_after", retryAfter.String())
+			c.limiter.Pause(retryAfter)
+		case resp.StatusCode >= 400 && resp.StatusCode < 500:
+			// e.g. 401 invalid token, 403 endpoint not allowed for personal tokens of EU and UK business profiles
+			return nil, fmt.Errorf("%w: status %d: %s", cerrors.ErrValidationFailed, resp.StatusCode, string(body))
+		default:
+			return nil, cerrors.UpstreamError{Source: apiShortname, StatusCode: resp.StatusCode}
+		}
+	}
+}
+
+// Profile is a personal or business profile of the Wise account
+type Profile struct {
+	Id   int64
+	Type string // PERSONAL or BUSINESS
+}
+
+// GetApiProfiles returns the profiles of the account of the API token
+func (c Client) GetApiProfiles(ctx context.Context) (profiles []Profile, err error) {
+
+	body, err := c.get(ctx, "/v2/profiles", nil)
+	if err != nil {
+		return nil, fmt.Errorf("c.get failed: %w", err)
+	}
+
+	// body looks like this: [{"id":30000000,"publicId":"...","userId":10000000,"type":"PERSONAL","fullName":"Oliver Wilson",...}]
+
+	respS := []struct {
+		Id   int64  `json:"id"`
+		Type string `json:"type"`
+	}{}
+	if err = json.Unmarshal(body, &respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, p := range respS {
+		profiles = append(profiles, Profile{Id: p.Id, Type: p.Type})
+	}
+
+	return profiles, nil
+}
@@ -0,0 +1,39 @@
+package wiseapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/loveyourstack/connectors/ratesource"
+)
+
+// SourceName returns the name of Wise as a ratesource.Source
+func (c Client) SourceName() string {
+	return apiShortname
+}
+
+// GetRates returns the daily mid-market rates from base to quotes, as a ratesource.Source. Each quote is a request
+func (c Client) GetRates(ctx context.Context, base string, quotes []string, startDate, endDate time.Time) (rates []ratesource.Rate, err error) {
+
+	seen := make(map[string]bool)
+	for _, quote := range quotes {
+
+		apiRates, err := c.GetApiRates(ctx, Pair{Source: base, Target: quote}, startDate, endDate)
+		if err != nil {
+			return nil, fmt.Errorf("c.GetApiRates failed on %s/%s: %w", base, quote, err)
+		}
+
+		// of several rates of a day, the first is kept, as in RatesToMap
+		for _, r := range apiRates {
+			key := r.Target + "+" + r.Day.String()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			rates = append(rates, ratesource.Rate{Day: r.Day, Base: r.Source, Quote: r.Target, Rate: r.Rate})
+		}
+	}
+
+	return rates, nil
+}
@@ -0,0 +1,168 @@
+package wiseapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/sink"
+	"github.com/loveyourstack/connectors/stores/wise/wiserate"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// Pair is a currency pair of Wise rates
+type Pair struct {
+	Source string
+	Target string
+}
+
+func (p Pair) String() string {
+	return p.Source + "/" + p.Target
+}
+
+// ParsePair parses a currency pair such as "EUR/USD"
+func ParsePair(s string) (pair Pair, err error) {
+
+	source, target, ok := strings.Cut(strings.ToUpper(strings.TrimSpace(s)), "/")
+	if !ok || len(source) != 3 || len(target) != 3 || source == target {
+		return Pair{}, fmt.Errorf("%w: invalid currency pair '%s': must be like EUR/USD", cerrors.ErrValidationFailed, s)
+	}
+	return Pair{Source: source, Target: target}, nil
+}
+
+// Rate is a daily Wise mid-market rate
+type Rate struct {
+	Source string
+	Target string
+	Day    time.Time
+	Rate   float64
+}
+
+// GetApiRates returns the daily mid-market rates of pair in the date range
+func (c Client) GetApiRates(ctx context.Context, pair Pair, startDate, endDate time.Time) (rates []Rate, err error) {
+
+	params := url.Values{}
+	params.Set("source", pair.Source)
+	params.Set("target", pair.Target)
+	params.Set("from", startDate.Format("2006-01-02")+"T00:00:00")
+	params.Set("to", endDate.Format("2006-01-02")+"T23:59:59")
+	params.Set("group", "day")
+
+	body, err := c.get(ctx, "/v1/rates", params)
+	if err != nil {
+		return nil, fmt.Errorf("c.get failed: %w", err)
+	}
+
+	return ParseRatesJson(body)
+}
+
+// ParseRatesJson parses the response of the rates endpoint
+func ParseRatesJson(content []byte) (rates []Rate, err error) {
+
+	// content looks like this: [{"rate":1.166,"source":"EUR","target":"USD","time":"2018-08-31T00:00:00+0000"},{"rate":1.1616,"source":"EUR","target":"USD","time":"2018-08-30T00:00:00+0000"}]
+
+	respS := []struct {
+		Rate   float64 `json:"rate"`
+		Source string  `json:"source"`
+		Target string  `json:"target"`
+		Time   string  `json:"time"`
+	}{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, apiRate := range respS {
+
+		t, err := time.Parse("2006-01-02T15:04:05-0700", apiRate.Time)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid time '%s': %w", cerrors.ErrValidationFailed, apiRate.Time, err)
+		}
+		if apiRate.Rate <= 0 {
+			return nil, fmt.Errorf("%w: invalid rate %v of %s/%s on %s", cerrors.ErrValidationFailed, apiRate.Rate, apiRate.Source, apiRate.Target, apiRate.Time)
+		}
+
+		t = t.UTC()
+		rates = append(rates, Rate{
+			Source: apiRate.Source,
+			Target: apiRate.Target,
+			Day:    time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC),
+			Rate:   apiRate.Rate,
+		})
+	}
+
+	return rates, nil
+}
+
+// RatesToMap converts rates to a map with wiserate.NaturalKey as key. Of several rates of a pair on a day, the first is kept
+func RatesToMap(rates []Rate) (itemsMap map[wiserate.NaturalKey]wiserate.Model) {
+
+	itemsMap = make(map[wiserate.NaturalKey]wiserate.Model, len(rates))
+	for _, r := range rates {
+
+		input := wiserate.Input{
+			SourceCurrency: r.Source,
+			TargetCurrency: r.Target,
+			Day:            lystype.Date(r.Day),
+			Rate:           r.Rate,
+		}
+		key := wiserate.KeyOf(input)
+		if _, ok := itemsMap[key]; ok {
+			continue
+		}
+
+		itemsMap[key] = wiserate.Model{Input: input}
+	}
+
+	return itemsMap
+}
+
+// GetApiIntradayRates returns the hourly mid-market rates of pair in the date range as ticks with series "SOURCE/TARGET"
+func (c Client) GetApiIntradayRates(ctx context.Context, pair Pair, startDate, endDate time.Time) (ticks []sink.Tick, err error) {
+
+	params := url.Values{}
+	params.Set("source", pair.Source)
+	params.Set("target", pair.Target)
+	params.Set("from", startDate.Format("2006-01-02")+"T00:00:00")
+	params.Set("to", endDate.Format("2006-01-02")+"T23:59:59")
+	params.Set("group", "hour")
+
+	body, err := c.get(ctx, "/v1/rates", params)
+	if err != nil {
+		return nil, fmt.Errorf("c.get failed: %w", err)
+	}
+
+	return ParseRateTicksJson(body)
+}
+
+// ParseRateTicksJson parses the response of the rates endpoint into ticks, keeping the time of each rate
+func ParseRateTicksJson(content []byte) (ticks []sink.Tick, err error) {
+
+	respS := []struct {
+		Rate   float64 `json:"rate"`
+		Source string  `json:"source"`
+		Target string  `json:"target"`
+		Time   string  `json:"time"`
+	}{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, apiRate := range respS {
+
+		t, err := time.Parse("2006-01-02T15:04:05-0700", apiRate.Time)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid time '%s': %w", cerrors.ErrValidationFailed, apiRate.Time, err)
+		}
+		if apiRate.Rate <= 0 {
+			return nil, fmt.Errorf("%w: invalid rate %v of %s/%s at %s", cerrors.ErrValidationFailed, apiRate.Rate, apiRate.Source, apiRate.Target, apiRate.Time)
+		}
+
+		ticks = append(ticks, sink.Tick{Series: apiRate.Source + "/" + apiRate.Target, At: t.UTC(), Value: apiRate.Rate})
+	}
+
+	return ticks, nil
+}
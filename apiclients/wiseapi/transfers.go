@@ -0,0 +1,143 @@
+package wiseapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+	"github.com/loveyourstack/connectors/stores/wise/wisetransfer"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// Transfer is a conversion and payment made with Wise
+type Transfer struct {
+	WiseId         int64
+	ProfileId      int64
+	Status         string
+	Reference      string
+	SourceCurrency string
+	SourceValue    float64 // converted amount, after fees
+	TargetCurrency string
+	TargetValue    float64
+	Rate           float64
+	CreatedAt      time.Time
+}
+
+// GetApiTransfers returns the transfers of profileId created in the time range
+func (c Client) GetApiTransfers(ctx context.Context, profileId int64, createdStart, createdEnd time.Time) (transfers []Transfer, err error) {
+
+	params := url.Values{}
+	params.Set("profile", strconv.FormatInt(profileId, 10))
+	params.Set("createdDateStart", createdStart.UTC().Format("2006-01-02T15:04:05.000Z"))
+	params.Set("createdDateEnd", createdEnd.UTC().Format("2006-01-02T15:04:05.000Z"))
+	params.Set("limit", strconv.Itoa(pageSize))
+
+	err = apiclient.Offsets(ctx, apiclient.Paging{Log: c.InfoLog}, pageSize,
+		func(offset int) ([]byte, error) {
+			params.Set("offset", strconv.Itoa(offset))
+			return c.get(ctx, "/v1/transfers", params)
+		},
+		func(body []byte) (bool, error) {
+			page, err := ParseTransfersJson(body, profileId)
+			if err != nil {
+				return false, fmt.Errorf("ParseTransfersJson failed: %w", err)
+			}
+			transfers = append(transfers, page...)
+			return len(page) == pageSize, nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("apiclient.Offsets failed: %w", err)
+	}
+
+	return transfers, nil
+}
+
+// ParseTransfersJson parses a page of the transfers endpoint of profileId
+func ParseTransfersJson(content []byte, profileId int64) (transfers []Transfer, err error) {
+
+	/* content looks like this:
+	[{"id":16521632,"user":4342275,"targetAccount":8692237,"sourceAccount":null,"quote":null,"quoteUuid":"9bd6c1ad-0c92-4bd5-94b4-4f09e4d7c0c7","status":"outgoing_payment_sent",
+	  "reference":"","rate":0.89,"created":"2018-12-16 13:41:39","business":null,"details":{"reference":"invoice 123"},"hasActiveIssues":false,
+	  "sourceCurrency":"EUR","sourceValue":1000,"targetCurrency":"GBP","targetValue":890,"customerTransactionId":"6D9188CF-FA59-44C3-87A2-4506CE9C1EA3"}]
+	*/
+
+	respS := []struct {
+		Id        int64   `json:"id"`
+		Status    string  `json:"status"`
+		Reference string  `json:"reference"`
+		Rate      float64 `json:"rate"`
+		Created   string  `json:"created"`
+		Details   struct {
+			Reference string `json:"reference"`
+		} `json:"details"`
+		SourceCurrency string  `json:"sourceCurrency"`
+		SourceValue    float64 `json:"sourceValue"`
+		TargetCurrency string  `json:"targetCurrency"`
+		TargetValue    float64 `json:"targetValue"`
+	}{}
+	if err = json.Unmarshal(content, &respS); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	for _, apiTr := range respS {
+
+		// created is in UTC, without zone
+		createdAt, err := time.Parse("2006-01-02 15:04:05", apiTr.Created)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid created '%s' of transfer %d: %w", cerrors.ErrValidationFailed, apiTr.Created, apiTr.Id, err)
+		}
+
+		// the top level reference is deprecated
+		reference := apiTr.Details.Reference
+		if reference == "" {
+			reference = apiTr.Reference
+		}
+
+		transfers = append(transfers, Transfer{
+			WiseId:         apiTr.Id,
+			ProfileId:      profileId,
+			Status:         apiTr.Status,
+			Reference:      reference,
+			SourceCurrency: apiTr.SourceCurrency,
+			SourceValue:    apiTr.SourceValue,
+			TargetCurrency: apiTr.TargetCurrency,
+			TargetValue:    apiTr.TargetValue,
+			Rate:           apiTr.Rate,
+			CreatedAt:      createdAt,
+		})
+	}
+
+	return transfers, nil
+}
+
+// TransfersToMap converts transfers to a map with WiseId as key
+func TransfersToMap(transfers []Transfer) (itemsMap map[int64]wisetransfer.Model, err error) {
+
+	itemsMap = make(map[int64]wisetransfer.Model)
+	for _, tr := range transfers {
+
+		if _, ok := itemsMap[tr.WiseId]; ok {
+			return nil, fmt.Errorf("%w: duplicate transfer %d", cerrors.ErrValidationFailed, tr.WiseId)
+		}
+
+		itemsMap[tr.WiseId] = wisetransfer.Model{Input: wisetransfer.Input{
+			WiseId:         tr.WiseId,
+			ProfileId:      tr.ProfileId,
+			Status:         tr.Status,
+			Reference:      tr.Reference,
+			SourceCurrency: tr.SourceCurrency,
+			SourceValue:    tr.SourceValue,
+			TargetCurrency: tr.TargetCurrency,
+			TargetValue:    tr.TargetValue,
+			Rate:           tr.Rate,
+			CreatedAtWise:  lystype.Datetime(tr.CreatedAt),
+		}}
+	}
+
+	return itemsMap, nil
+}
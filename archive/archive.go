@@ -0,0 +1,299 @@
+// Package archive stores the raw API responses of connectors before they are parsed, compressed and hashed, so that a run can be reprocessed
+// exactly after a parser fix, without calling the provider again
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/connectors/rawpayload"
+	"github.com/loveyourstack/connectors/stores/connectors/syncrun"
+)
+
+// DefaultRetentionDays is the number of days payloads are kept in the connectors.raw_payload table
+const DefaultRetentionDays int = 30
+
+// Config contains the settings of the [archive] table
+type Config struct {
+	Connectors    []string `toml:"connectors"`    // names of the connectors whose responses are archived, or ["*"] for all. Archival is disabled if empty
+	RetentionDays int      `toml:"retentionDays"` // days payloads are kept before pruning. Defaults to DefaultRetentionDays
+}
+
+// Enabled returns true if the responses of any connector are archived
+func (c Config) Enabled() bool {
+	return len(c.Connectors) > 0
+}
+
+// Validate returns an error if c is invalid
+func (c Config) Validate() error {
+
+	if c.RetentionDays < 0 {
+		return fmt.Errorf("retentionDays must not be negative")
+	}
+	return nil
+}
+
+// Archiver archives the responses of the configured connectors in Db, normally the first sync target
+// a nil *Archiver archives nothing
+type Archiver struct {
+	Config   Config
+	Db       *pgxpool.Pool
+	InfoLog  *slog.Logger
+	ErrorLog *slog.Logger
+}
+
+// New returns an Archiver storing payloads in db, or nil if conf is not enabled
+func New(conf Config, db *pgxpool.Pool, infoLog, errorLog *slog.Logger) (*Archiver, error) {
+
+	if !conf.Enabled() {
+		return nil, nil
+	}
+	if err := conf.Validate(); err != nil {
+		return nil, fmt.Errorf("conf.Validate failed: %w", err)
+	}
+	if conf.RetentionDays == 0 {
+		conf.RetentionDays = DefaultRetentionDays
+	}
+
+	return &Archiver{Config: conf, Db: db, InfoLog: infoLog, ErrorLog: errorLog}, nil
+}
+
+// Recorder returns the recorder of a sync of dataset by connector starting now, or nil if its responses are not archived
+func (a *Archiver) Recorder(connector, dataset string) *Recorder {
+
+	if a == nil || !slices.Contains(a.Config.Connectors, connector) && !slices.Contains(a.Config.Connectors, "*") {
+		return nil
+	}
+	return &Recorder{Archiver: a, Connector: connector, Dataset: dataset, StartedAt: time.Now()}
+}
+
+// Prune deletes the payloads older than the retention
+func (a *Archiver) Prune(ctx context.Context) (numPruned int64, err error) {
+
+	if a == nil {
+		return 0, nil
+	}
+
+	numPruned, err = rawpayload.Store{Db: a.Db}.DeleteBefore(ctx, time.Now().AddDate(0, 0, -a.Config.RetentionDays))
+	if err != nil {
+		return 0, fmt.Errorf("rawpayload.Store.DeleteBefore failed: %w", err)
+	}
+
+	if numPruned > 0 {
+		a.InfoLog.Info("pruned raw payloads", clog.KeyCount, numPruned)
+	}
+
+	return numPruned, nil
+}
+
+// Recorder archives the responses of the API client of a connector during a sync of Dataset
+// a nil *Recorder archives nothing, so that connectors can apply the recorder of registry.Deps whether or not archival is enabled
+type Recorder struct {
+	Archiver  *Archiver
+	Connector string
+	Dataset   string
+	StartedAt time.Time
+}
+
+// Client returns a copy of hc whose responses are archived by r, or hc itself if r is nil
+func (r *Recorder) Client(hc *http.Client) *http.Client {
+
+	if r == nil || hc == nil {
+		return hc
+	}
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	archived := *hc
+	archived.Transport = recordTransport{base: base, r: r}
+	return &archived
+}
+
+// Finish assigns the payloads of the sync to its run in the Db of the archiver. Call it after the sync, whether or not it succeeded
+// payloads of a sync which recorded no run, e.g. because it was cancelled, keep no run
+func (r *Recorder) Finish(ctx context.Context) error {
+
+	if r == nil {
+		return nil
+	}
+
+	runs, err := syncrun.Store{Db: r.Archiver.Db}.SelectStartedSince(ctx, r.StartedAt)
+	if err != nil {
+		return fmt.Errorf("syncrun.Store.SelectStartedSince failed: %w", err)
+	}
+
+	// the latest run of the dataset, since runs are ordered by start
+	var runId int64
+	for _, run := range runs {
+		if run.Dataset == r.Dataset {
+			runId = run.Id
+		}
+	}
+	if runId == 0 {
+		return nil
+	}
+
+	if _, err = (rawpayload.Store{Db: r.Archiver.Db}).AssignRun(ctx, r.Connector, r.Dataset, r.StartedAt, runId); err != nil {
+		return fmt.Errorf("rawpayload.Store.AssignRun failed: %w", err)
+	}
+
+	return nil
+}
+
+// recordTransport archives each response before handing it on. Archiving never fails a request: errors are logged, and the body is passed on unchanged
+type recordTransport struct {
+	base http.RoundTripper
+	r    *Recorder
+}
+
+func (t recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	// the body is read here, so is handed on from memory
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadAll failed: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	a := t.r.Archiver
+	input, err := NewInput(t.r.Connector, t.r.Dataset, req, resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	if err != nil {
+		a.ErrorLog.Error("archive.NewInput failed", "connector", t.r.Connector, clog.KeyError, err.Error())
+		return resp, nil
+	}
+	if _, err = (rawpayload.Store{Db: a.Db}).Insert(req.Context(), input); err != nil {
+		a.ErrorLog.Error("rawpayload.Store.Insert failed", "connector", t.r.Connector, clog.KeyError, err.Error())
+	}
+
+	return resp, nil
+}
+
+// NewInput returns the archived payload of a response to req, with the body compressed and hashed, and credentials redacted from the URL and body
+func NewInput(connector, dataset string, req *http.Request, statusCode int, contentType string, body []byte) (rawpayload.Input, error) {
+
+	body, err := RedactBody(contentType, body)
+	if err != nil {
+		return rawpayload.Input{}, fmt.Errorf("RedactBody failed: %w", err)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return rawpayload.Input{}, fmt.Errorf("zw.Write failed: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return rawpayload.Input{}, fmt.Errorf("zw.Close failed: %w", err)
+	}
+
+	return rawpayload.Input{
+		Connector:   connector,
+		Dataset:     dataset,
+		Method:      req.Method,
+		RequestUrl:  RedactUrl(req.URL),
+		StatusCode:  statusCode,
+		ContentType: contentType,
+		Body:        buf.Bytes(),
+		BodyBytes:   int64(len(body)),
+		BodyHash:    Hash(body),
+	}, nil
+}
+
+// Body returns the uncompressed body of p, checked against its hash
+func Body(p rawpayload.Model) ([]byte, error) {
+
+	zr, err := gzip.NewReader(bytes.NewReader(p.Body))
+	if err != nil {
+		return nil, fmt.Errorf("gzip.NewReader failed: %w", err)
+	}
+	defer zr.Close()
+
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadAll failed: %w", err)
+	}
+	if Hash(body) != p.BodyHash {
+		return nil, fmt.Errorf("hash mismatch of payload %d", p.Id)
+	}
+
+	return body, nil
+}
+
+// Hash returns the hex encoded SHA-256 of body
+func Hash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// RedactBody returns body with the string values of the top-level keys naming tokens replaced by REDACTED if it is a JSON object, e.g. the
+// access_token of an OAuth token response or the restrictedDataToken of Amazon. Other bodies are returned unchanged
+func RedactBody(contentType string, body []byte) ([]byte, error) {
+
+	if !strings.Contains(strings.ToLower(contentType), "json") {
+		return body, nil
+	}
+	obj := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(body, &obj); err != nil {
+		// not an object
+		return body, nil
+	}
+
+	redacted := false
+	for k, v := range obj {
+		if strings.Contains(strings.ToLower(k), "token") && len(v) > 0 && v[0] == '"' {
+			obj[k] = json.RawMessage(`"REDACTED"`)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return body, nil
+	}
+
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal failed: %w", err)
+	}
+	return body, nil
+}
+
+// credentialParams are the substrings of the names of query params whose values are redacted, e.g. api_key or access_token
+var credentialParams = []string{"key", "token", "secret", "password", "signature"}
+
+// RedactUrl returns u with the values of query params naming credentials replaced by REDACTED
+func RedactUrl(u *url.URL) string {
+
+	q := u.Query()
+	for name := range q {
+		lower := strings.ToLower(name)
+		for _, cred := range credentialParams {
+			if strings.Contains(lower, cred) {
+				q.Set(name, "REDACTED")
+				break
+			}
+		}
+	}
+
+	redacted := *u
+	redacted.RawQuery = q.Encode()
+	return redacted.String()
+}
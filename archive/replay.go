@@ -0,0 +1,143 @@
+package archive
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/connectors/rawpayload"
+)
+
+// ErrNotArchived is returned by the requests of a replay for which no archived payload is left
+var ErrNotArchived = fmt.Errorf("%w: no archived payload", cerrors.ErrNotFound)
+
+// Replay serves the archived payloads of a run in place of the provider, so that a connector's current parser can reprocess them. It is safe for concurrent use
+// a request is served the first unused payload with the same method and URL. Failing that, since a sync computes its window anew, e.g. from today,
+// it is served the unused payload with the same method, host and path whose query params match most, earliest first
+// a nil *Replay serves nothing
+type Replay struct {
+	mu       sync.Mutex
+	payloads []replayPayload
+}
+
+type replayPayload struct {
+	model rawpayload.Model
+	url   *url.URL
+	used  bool
+}
+
+// NewReplay returns the replay of payloads, in the order they were captured
+func NewReplay(payloads []rawpayload.Model) (*Replay, error) {
+
+	r := &Replay{}
+	for _, p := range payloads {
+		u, err := url.Parse(p.RequestUrl)
+		if err != nil {
+			return nil, fmt.Errorf("url.Parse failed for payload %d: %w", p.Id, err)
+		}
+		r.payloads = append(r.payloads, replayPayload{model: p, url: u})
+	}
+	return r, nil
+}
+
+// Counts returns the number of payloads served and left unused
+func (r *Replay) Counts() (served, unused int) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range r.payloads {
+		if p.used {
+			served++
+		} else {
+			unused++
+		}
+	}
+	return served, unused
+}
+
+// Client returns a copy of hc whose requests are served by r, or hc itself if r is nil
+func (r *Replay) Client(hc *http.Client) *http.Client {
+
+	if r == nil || hc == nil {
+		return hc
+	}
+	replayed := *hc
+	replayed.Transport = r
+	return &replayed
+}
+
+// RoundTrip serves req from the archived payloads. It never calls the provider: requests without a payload fail with ErrNotArchived
+func (r *Replay) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	p, err := r.take(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := Body(p)
+	if err != nil {
+		return nil, fmt.Errorf("Body failed: %w", err)
+	}
+
+	header := make(http.Header)
+	if p.ContentType != "" {
+		header.Set("Content-Type", p.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", p.StatusCode, http.StatusText(p.StatusCode)),
+		StatusCode:    p.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// take marks the payload matching req as used and returns it
+func (r *Replay) take(req *http.Request) (rawpayload.Model, error) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	redacted := RedactUrl(req.URL)
+	query := req.URL.Query()
+
+	best, bestScore := -1, -1
+	for i, p := range r.payloads {
+		if p.used || p.model.Method != req.Method {
+			continue
+		}
+		if p.model.RequestUrl == redacted {
+			best = i
+			break
+		}
+		if p.url.Host != req.URL.Host || p.url.Path != req.URL.Path {
+			continue
+		}
+		score := 0
+		for name, values := range p.url.Query() {
+			if len(values) > 0 && query.Get(name) == values[0] {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	if best < 0 {
+		return rawpayload.Model{}, fmt.Errorf("%w for %s %s", ErrNotArchived, req.Method, redacted)
+	}
+	r.payloads[best].used = true
+	return r.payloads[best].model, nil
+}
@@ -0,0 +1,119 @@
+// Package calendar provides the TARGET2 business day calendar, on whose business days the ECB publishes its reference rates
+package calendar
+
+import (
+	"time"
+)
+
+// Location is the time zone in which TARGET2 operates, and in which its days begin and end
+var Location = func() *time.Location {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		// no tz database available: CET without daylight saving is close enough for day boundaries
+		return time.FixedZone("CET", 60*60)
+	}
+	return loc
+}()
+
+// IsClosingDay returns true if TARGET2 is closed on the date of day, apart from weekends: New Year's Day, Good Friday, Easter Monday, 1 May, 25 and 26 December
+// only the year, month and day of day are used
+func IsClosingDay(day time.Time) bool {
+
+	switch month, dom := day.Month(), day.Day(); {
+	case month == time.January && dom == 1,
+		month == time.May && dom == 1,
+		month == time.December && (dom == 25 || dom == 26):
+		return true
+	}
+
+	easter := easterSunday(day.Year())
+	date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	return date.Equal(easter.AddDate(0, 0, -2)) || date.Equal(easter.AddDate(0, 0, 1))
+}
+
+// ClosingDays returns the TARGET2 closing days of year which fall on a weekday, in date order
+func ClosingDays(year int) []time.Time {
+
+	days := []time.Time{}
+	for day := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC); day.Year() == year; day = day.AddDate(0, 0, 1) {
+		if !isWeekend(day) && IsClosingDay(day) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// IsBusinessDay returns true if the date of day is neither a weekend day nor a TARGET2 closing day
+func IsBusinessDay(day time.Time) bool {
+	return !isWeekend(day) && !IsClosingDay(day)
+}
+
+// PreviousBusinessDay returns the latest business day before the date of day, at midnight UTC
+func PreviousBusinessDay(day time.Time) time.Time {
+
+	day = truncateDay(day).AddDate(0, 0, -1)
+	for !IsBusinessDay(day) {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+// NextBusinessDay returns the earliest business day after the date of day, at midnight UTC
+func NextBusinessDay(day time.Time) time.Time {
+
+	day = truncateDay(day).AddDate(0, 0, 1)
+	for !IsBusinessDay(day) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// SubBusinessDays returns the nth business day before the date of day, at midnight UTC. n <= 0 returns the date of day
+func SubBusinessDays(day time.Time, n int) time.Time {
+
+	day = truncateDay(day)
+	for range n {
+		day = PreviousBusinessDay(day)
+	}
+	return day
+}
+
+// BusinessDaysBetween returns the number of business days after the date of start up to and including the date of end, or 0 if end is not after start
+// it is the number of fixings by which data ending on start trails data ending on end
+func BusinessDaysBetween(start, end time.Time) int {
+
+	n := 0
+	end = truncateDay(end)
+	for day := truncateDay(start).AddDate(0, 0, 1); !day.After(end); day = day.AddDate(0, 0, 1) {
+		if IsBusinessDay(day) {
+			n++
+		}
+	}
+	return n
+}
+
+// easterSunday returns the date of Easter Sunday in year of the Gregorian calendar, using the anonymous Gregorian algorithm
+func easterSunday(year int) time.Time {
+
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	dom := (h+l-7*m+114)%31 + 1
+
+	return time.Date(year, time.Month(month), dom, 0, 0, 0, 0, time.UTC)
+}
+
+func isWeekend(day time.Time) bool {
+	return day.Weekday() == time.Saturday || day.Weekday() == time.Sunday
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
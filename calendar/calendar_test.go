@@ -0,0 +1,101 @@
+package calendar_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/loveyourstack/connectors/calendar"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+func TestClosingDays(t *testing.T) {
+
+	tests := []struct {
+		year int
+		want []string
+	}{
+		{2019, []string{"2019-01-01", "2019-04-19", "2019-04-22", "2019-05-01", "2019-12-25", "2019-12-26"}},
+		{2020, []string{"2020-01-01", "2020-04-10", "2020-04-13", "2020-05-01", "2020-12-25"}}, // 26 December is a Saturday
+		{2021, []string{"2021-01-01", "2021-04-02", "2021-04-05"}},                             // 1 May, 25 and 26 December are at the weekend
+		{2024, []string{"2024-01-01", "2024-03-29", "2024-04-01", "2024-05-01", "2024-12-25", "2024-12-26"}},
+		{2025, []string{"2025-01-01", "2025-04-18", "2025-04-21", "2025-05-01", "2025-12-25", "2025-12-26"}},
+		{2038, []string{"2038-01-01", "2038-04-23", "2038-04-26"}}, // the latest Easter, 25 April
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, day := range calendar.ClosingDays(tt.year) {
+			got = append(got, day.Format(lystype.DateFormat))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("closing days of %d: got %v, want %v", tt.year, got, tt.want)
+		}
+	}
+}
+
+func TestIsBusinessDay(t *testing.T) {
+
+	tests := []struct {
+		day  string
+		want bool
+	}{
+		{"2024-03-28", true},  // Maundy Thursday
+		{"2024-03-29", false}, // Good Friday
+		{"2024-03-30", false}, // Saturday
+		{"2024-03-31", false}, // Easter Sunday
+		{"2024-04-01", false}, // Easter Monday
+		{"2024-04-02", true},
+		{"2024-05-01", false},
+		{"2024-05-09", true}, // Ascension Day is not a closing day
+		{"2024-12-24", true},
+		{"2024-12-25", false},
+		{"2024-12-26", false},
+		{"2024-12-31", true},
+		{"2025-01-01", false},
+		{"2025-01-02", true},
+		{"2021-05-01", false}, // Saturday
+		{"2021-05-03", true},  // closing days at the weekend are not moved
+	}
+
+	for _, tt := range tests {
+		day, err := time.Parse(lystype.DateFormat, tt.day)
+		if err != nil {
+			t.Fatalf("time.Parse failed: %s", err.Error())
+		}
+		if got := calendar.IsBusinessDay(day); got != tt.want {
+			t.Errorf("IsBusinessDay of %s: got %t, want %t", tt.day, got, tt.want)
+		}
+	}
+
+	// only the date counts, not the time or zone
+	if calendar.IsBusinessDay(time.Date(2024, 12, 25, 23, 30, 0, 0, calendar.Location)) {
+		t.Errorf("IsBusinessDay of 25 December in Berlin: got true, want false")
+	}
+}
+
+func TestBusinessDayArithmetic(t *testing.T) {
+
+	goodFriday := time.Date(2024, 3, 29, 0, 0, 0, 0, time.UTC)
+	maundyThursday := time.Date(2024, 3, 28, 0, 0, 0, 0, time.UTC)
+	easterTuesday := time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC)
+
+	if got := calendar.PreviousBusinessDay(easterTuesday); !got.Equal(maundyThursday) {
+		t.Errorf("PreviousBusinessDay: got %s, want %s", got, maundyThursday)
+	}
+	if got := calendar.NextBusinessDay(goodFriday.Add(15 * time.Hour)); !got.Equal(easterTuesday) {
+		t.Errorf("NextBusinessDay: got %s, want %s", got, easterTuesday)
+	}
+	if got := calendar.SubBusinessDays(easterTuesday, 2); !got.Equal(time.Date(2024, 3, 27, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("SubBusinessDays: got %s, want 2024-03-27", got)
+	}
+	if got := calendar.SubBusinessDays(goodFriday, 0); !got.Equal(goodFriday) {
+		t.Errorf("SubBusinessDays of 0: got %s, want %s", got, goodFriday)
+	}
+	if got := calendar.BusinessDaysBetween(maundyThursday, easterTuesday); got != 1 {
+		t.Errorf("BusinessDaysBetween: got %d, want 1", got)
+	}
+	if got := calendar.BusinessDaysBetween(easterTuesday, maundyThursday); got != 0 {
+		t.Errorf("BusinessDaysBetween of a reversed range: got %d, want 0", got)
+	}
+}
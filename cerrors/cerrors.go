@@ -0,0 +1,131 @@
+package cerrors
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// the error categories returned by apiclients, stores and csyncdb. Errors are wrapped, so test them with errors.Is or errors.As
+var (
+	ErrNotFound                = errors.New("not found")                 // the requested data does not exist, at the source or in the store
+	ErrStale                   = errors.New("stale")                     // stored data trails the source by more than allowed
+	ErrUpstreamUnavailable     = errors.New("upstream unavailable")      // a source API could not be reached or responded with an unexpected status
+	ErrValidationFailed        = errors.New("validation failed")         // data or params are invalid, e.g. an unparsable API response or a check constraint violation
+	ErrConflictPolicyViolation = errors.New("conflict policy violation") // a write conflicts with existing data, e.g. a duplicate natural key
+)
+
+// ErrNoRows is returned by stores if the row to be selected, updated or deleted does not exist
+// it matches both ErrNotFound and pgx.ErrNoRows
+var ErrNoRows = fmt.Errorf("%w: %w", ErrNotFound, pgx.ErrNoRows)
+
+// ErrRowChanged is returned by the optimistically locked updates of stores, such as ecbexchangerate.Store.UpdateIfUnchanged, if the row was modified since it was read
+// it matches ErrConflictPolicyViolation
+var ErrRowChanged = fmt.Errorf("%w: row changed since it was read", ErrConflictPolicyViolation)
+
+// postgres error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pgUniqueViolation    string = "23505"
+	pgExclusionViolation string = "23P01"
+	pgIntegrityClass     string = "23" // other integrity constraint violations: not null, foreign key, check
+)
+
+// UpstreamError is returned if a source API could not be reached (StatusCode 0, Err set) or responded with an unexpected status
+// it matches ErrUpstreamUnavailable
+type UpstreamError struct {
+	Source     string // connector name, e.g. "ecb"
+	StatusCode int
+	Err        error
+}
+
+func (e UpstreamError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s: unexpected status code: %d", e.Source, e.StatusCode)
+	}
+	return fmt.Sprintf("%s: %v", e.Source, e.Err)
+}
+
+func (e UpstreamError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{ErrUpstreamUnavailable}
+	}
+	return []error{ErrUpstreamUnavailable, e.Err}
+}
+
+// Retryable returns true if the request may succeed if repeated: if no response was received, or the status was 429 or 5xx
+func (e UpstreamError) Retryable() bool {
+	return e.StatusCode == 0 || e.StatusCode == 429 || e.StatusCode >= 500
+}
+
+// StaleError is returned if the latest stored day of Dataset trails the expected day by more than MaxLag periods
+// it matches ErrStale
+type StaleError struct {
+	Dataset     string
+	LatestDay   time.Time
+	ExpectedDay time.Time
+	Lag         int
+	MaxLag      int
+}
+
+func (e StaleError) Error() string {
+	return fmt.Sprintf("%s is stale: latest day %s trails expected day %s by %d periods (max %d)",
+		e.Dataset, e.LatestDay.Format("2006-01-02"), e.ExpectedDay.Format("2006-01-02"), e.Lag, e.MaxLag)
+}
+
+func (e StaleError) Unwrap() error {
+	return ErrStale
+}
+
+// FromPg adds the matching category to an error returned by pgx or lyspg: ErrNotFound for pgx.ErrNoRows, ErrConflictPolicyViolation for
+// unique and exclusion violations, and ErrValidationFailed for other integrity constraint violations. Other errors are returned unchanged
+func FromPg(err error) error {
+
+	if err == nil || errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.Code == pgUniqueViolation || pgErr.Code == pgExclusionViolation:
+			return fmt.Errorf("%w: %w", ErrConflictPolicyViolation, err)
+		case len(pgErr.Code) == 5 && pgErr.Code[:2] == pgIntegrityClass:
+			return fmt.Errorf("%w: %w", ErrValidationFailed, err)
+		}
+	}
+
+	return err
+}
+
+// FromSQLite adds the matching category to an error returned by a database/sql SQLite driver, as FromPg does: ErrNotFound for sql.ErrNoRows,
+// ErrConflictPolicyViolation for unique violations, and ErrValidationFailed for other constraint violations. Drivers differ in their error types,
+// so the SQLite error message is matched. Other errors are returned unchanged
+func FromSQLite(err error) error {
+
+	if err == nil || errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "UNIQUE constraint failed"), strings.Contains(msg, "PRIMARY KEY constraint failed"):
+		return fmt.Errorf("%w: %w", ErrConflictPolicyViolation, err)
+	case strings.Contains(msg, "constraint failed"):
+		return fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+
+	return err
+}
@@ -0,0 +1,123 @@
+package changefeed
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// brokers
+const (
+	BrokerKafka string = "kafka"
+	BrokerNats  string = "nats"
+)
+
+const (
+	defaultTopicPrefix string        = "connectors."
+	defaultBatchSize   int           = 500
+	defaultTimeout     time.Duration = 30 * time.Second
+
+	// DefaultOutboxRetentionDays is the number of days rows are kept in the connectors.outbox table
+	DefaultOutboxRetentionDays int = 7
+)
+
+// DefaultTables are the tables whose changes are published if Config.Tables is not set
+var DefaultTables = []string{"ecb.currency", "ecb.exchange_rate"}
+
+// Config is the configuration of the change feed, the [changefeed] table
+type Config struct {
+	Broker      string   `toml:"broker"`      // kafka or nats. Changes are not published to a broker if empty
+	Addresses   []string `toml:"addresses"`   // host:port of the Kafka bootstrap brokers or NATS servers, tried in turn
+	Tls         bool     `toml:"tls"`         // connect with TLS. NATS servers requiring TLS are always connected with TLS
+	Username    string   `toml:"username"`    // Kafka: SASL/PLAIN username. NATS: user
+	Password    string   `toml:"password"`    // Kafka: SASL/PLAIN password. NATS: password
+	Token       string   `toml:"token"`       // NATS: auth token
+	JetStream   bool     `toml:"jetStream"`   // NATS: wait for the JetStream ack of each message, so that changes are only removed once stored by a stream
+	TopicPrefix string   `toml:"topicPrefix"` // prepended to the table name to give the Kafka topic or NATS subject. Defaults to "connectors."
+	Tables      []string `toml:"tables"`      // schema qualified tables whose changes are published. Defaults to DefaultTables
+	BatchSize   int      `toml:"batchSize"`   // changes per publish. Defaults to 500
+	Timeout     string   `toml:"timeout"`     // Go duration of each broker request. Defaults to 30s
+
+	Outbox              bool `toml:"outbox"`              // write changes to the connectors.outbox table, e.g. for Debezium's outbox event router. Can be combined with a broker
+	OutboxRetentionDays int  `toml:"outboxRetentionDays"` // days outbox rows are kept before pruning. Defaults to DefaultOutboxRetentionDays
+}
+
+// Enabled returns true if changes are published to a broker or written to the outbox
+func (c Config) Enabled() bool {
+	return c.Broker != "" || c.Outbox
+}
+
+// WithDefaults returns c with defaults applied to unset fields
+func (c Config) WithDefaults() Config {
+
+	if c.TopicPrefix == "" {
+		c.TopicPrefix = defaultTopicPrefix
+	}
+	if len(c.Tables) == 0 {
+		c.Tables = DefaultTables
+	}
+	if c.BatchSize == 0 {
+		c.BatchSize = defaultBatchSize
+	}
+	if c.OutboxRetentionDays == 0 {
+		c.OutboxRetentionDays = DefaultOutboxRetentionDays
+	}
+
+	return c
+}
+
+// Validate returns an error if c, with defaults applied, is incomplete or invalid
+func (c Config) Validate() error {
+
+	switch c.Broker {
+	case BrokerKafka:
+		if c.Token != "" {
+			return fmt.Errorf("%w: token is only used with nats", cerrors.ErrValidationFailed)
+		}
+		if c.JetStream {
+			return fmt.Errorf("%w: jetStream is only used with nats", cerrors.ErrValidationFailed)
+		}
+	case BrokerNats:
+		if c.Token != "" && c.Username != "" {
+			return fmt.Errorf("%w: set either token or username, not both", cerrors.ErrValidationFailed)
+		}
+	case "":
+		if !c.Outbox {
+			return fmt.Errorf("%w: broker is required unless outbox is set", cerrors.ErrValidationFailed)
+		}
+	default:
+		return fmt.Errorf("%w: broker must be %s or %s: %q", cerrors.ErrValidationFailed, BrokerKafka, BrokerNats, c.Broker)
+	}
+
+	if c.Broker != "" && len(c.Addresses) == 0 {
+		return fmt.Errorf("%w: addresses are required", cerrors.ErrValidationFailed)
+	}
+	for _, table := range c.Tables {
+		schema, tbl, ok := strings.Cut(table, ".")
+		if !ok || schema == "" || tbl == "" || strings.Contains(tbl, ".") {
+			return fmt.Errorf("%w: tables must be schema qualified, e.g. ecb.exchange_rate: %q", cerrors.ErrValidationFailed, table)
+		}
+	}
+	if c.BatchSize < 1 {
+		return fmt.Errorf("%w: batchSize must be positive", cerrors.ErrValidationFailed)
+	}
+	if c.OutboxRetentionDays < 1 {
+		return fmt.Errorf("%w: outboxRetentionDays must be positive", cerrors.ErrValidationFailed)
+	}
+	if _, err := c.timeout(); err != nil {
+		return fmt.Errorf("%w: timeout: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	return nil
+}
+
+// timeout returns the parsed Timeout, or defaultTimeout if not set
+func (c Config) timeout() (time.Duration, error) {
+
+	if c.Timeout == "" {
+		return defaultTimeout, nil
+	}
+	return time.ParseDuration(c.Timeout)
+}
@@ -0,0 +1,238 @@
+package changefeed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/connectors/change"
+	"github.com/loveyourstack/connectors/stores/connectors/outbox"
+)
+
+// EventSchema identifies the version of the Event payload. It changes if a field is removed or its meaning changes, not if one is added
+const EventSchema string = "connectors.change.v1"
+
+// Event is the JSON payload published per row change
+type Event struct {
+	Schema     string          `json:"schema"` // EventSchema
+	Id         string          `json:"id"`     // unique per change, "<target>:<change id>". Changes are published at least once, so consumers should dedupe by it
+	Target     string          `json:"target"` // name of the database the change occurred in
+	Table      string          `json:"table"`  // schema qualified, e.g. ecb.exchange_rate
+	Op         string          `json:"op"`     // insert, update or delete
+	Key        string          `json:"key"`    // id of the row, if the table has an id column. Also the Kafka message key
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data,omitempty"` // the row after the change, with column names as keys
+	Old        json.RawMessage `json:"old,omitempty"`  // the row before an update or delete
+
+	ContractVersion string `json:"contract_version,omitempty"` // version of the data contract of the dataset of Table, if it has one. See GET /contracts
+}
+
+// NewEvent returns the event of a change in target
+func NewEvent(target string, c change.Model) Event {
+
+	ev := Event{
+		Schema:     EventSchema,
+		Id:         target + ":" + strconv.FormatInt(c.Id, 10),
+		Target:     target,
+		Table:      c.TableName,
+		Op:         c.Op,
+		OccurredAt: c.OccurredAt,
+		Data:       c.Data,
+		Old:        c.OldData,
+	}
+
+	row := c.Data
+	if len(row) == 0 {
+		row = c.OldData
+	}
+	var keyRow struct {
+		Id json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(row, &keyRow) == nil && len(keyRow.Id) > 0 {
+		var key string
+		if json.Unmarshal(keyRow.Id, &key) != nil {
+			key = string(keyRow.Id)
+		}
+		ev.Key = key
+	}
+
+	return ev
+}
+
+// Message is an event encoded for a broker
+type Message struct {
+	Topic   string // Kafka topic or NATS subject
+	Key     string
+	Id      string // event id, e.g. for broker side deduplication
+	Value   []byte
+	Headers map[string]string
+}
+
+// Publisher sends messages to a broker
+type Publisher interface {
+	// Publish returns once the broker has accepted all of msgs. On error, any of them may have been published
+	Publish(ctx context.Context, msgs []Message) error
+	Close() error
+}
+
+// Feed publishes the changes captured in the connectors.change table of a database to a broker, removing them once published
+// if the outbox is configured, it also prunes the connectors.outbox table, whose rows are read by CDC tooling
+type Feed struct {
+	Config    Config
+	Publisher Publisher // nil if no broker is configured
+	InfoLog   *slog.Logger
+	ErrorLog  *slog.Logger
+
+	// ContractVersions are the data contract versions of the datasets, keyed by dataset name, which is also the table name. Added to the events of their tables
+	ContractVersions map[string]string
+
+	mu sync.Mutex // publishers hold a connection, so publishes are serialized
+}
+
+// New returns the feed of conf. conf must be valid. The broker, if any, is connected on the first publish
+func New(conf Config, infoLog, errorLog *slog.Logger) (*Feed, error) {
+
+	timeout, err := conf.timeout()
+	if err != nil {
+		return nil, fmt.Errorf("conf.timeout failed: %w", err)
+	}
+
+	var pub Publisher
+	switch conf.Broker {
+	case BrokerKafka:
+		pub = NewKafkaPublisher(conf, timeout, infoLog, errorLog)
+	case BrokerNats:
+		pub = NewNatsPublisher(conf, timeout, infoLog, errorLog)
+	case "":
+		// outbox only
+	default:
+		return nil, fmt.Errorf("unknown broker: %s", conf.Broker)
+	}
+
+	return &Feed{
+		Config:    conf,
+		Publisher: pub,
+		InfoLog:   infoLog,
+		ErrorLog:  errorLog,
+	}, nil
+}
+
+// Message returns the message of ev
+func (f *Feed) Message(ev Event) (Message, error) {
+
+	value, err := json.Marshal(ev)
+	if err != nil {
+		return Message{}, fmt.Errorf("json.Marshal failed: %w", err)
+	}
+
+	msg := Message{
+		Topic: f.Config.TopicPrefix + ev.Table,
+		Key:   ev.Key,
+		Id:    ev.Id,
+		Value: value,
+		Headers: map[string]string{
+			"content-type": "application/json",
+			"schema":       EventSchema,
+		},
+	}
+	if ev.ContractVersion != "" {
+		msg.Headers["contract-version"] = ev.ContractVersion
+	}
+
+	return msg, nil
+}
+
+// Publish publishes the pending changes of db, the database of target, in batches, deleting each batch once the broker accepted it
+// changes which fail to publish stay pending and are published again by the next call. Without broker, Publish does nothing
+func (f *Feed) Publish(ctx context.Context, target string, db *pgxpool.Pool) (numPublished int, err error) {
+
+	if f.Publisher == nil {
+		return 0, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	store := change.Store{Db: db}
+
+	for {
+		items, err := store.SelectPending(ctx, f.Config.BatchSize)
+		if err != nil {
+			return numPublished, fmt.Errorf("store.SelectPending failed: %w", err)
+		}
+		if len(items) == 0 {
+			break
+		}
+
+		msgs := make([]Message, len(items))
+		ids := make([]int64, len(items))
+		for i, item := range items {
+			ids[i] = item.Id
+			ev := NewEvent(target, item)
+			ev.ContractVersion = f.ContractVersions[ev.Table]
+			msgs[i], err = f.Message(ev)
+			if err != nil {
+				return numPublished, fmt.Errorf("f.Message failed on change id: %d: %w", item.Id, err)
+			}
+		}
+
+		err = f.Publisher.Publish(ctx, msgs)
+		if err != nil {
+			return numPublished, fmt.Errorf("f.Publisher.Publish failed: %w", err)
+		}
+
+		_, err = store.DeleteByIds(ctx, ids)
+		if err != nil {
+			return numPublished, fmt.Errorf("store.DeleteByIds failed: %w", err)
+		}
+		numPublished += len(items)
+
+		if len(items) < f.Config.BatchSize {
+			break
+		}
+	}
+
+	if numPublished > 0 {
+		f.InfoLog.Info("published changes", "target", target, "broker", f.Config.Broker, clog.KeyCount, numPublished)
+	}
+
+	return numPublished, nil
+}
+
+// PruneOutbox deletes the outbox rows of db, the database of target, older than the retention. Without outbox, PruneOutbox does nothing
+func (f *Feed) PruneOutbox(ctx context.Context, target string, db *pgxpool.Pool) (numPruned int64, err error) {
+
+	if !f.Config.Outbox {
+		return 0, nil
+	}
+
+	numPruned, err = outbox.Store{Db: db}.DeleteBefore(ctx, time.Now().AddDate(0, 0, -f.Config.OutboxRetentionDays))
+	if err != nil {
+		return 0, fmt.Errorf("outbox.Store.DeleteBefore failed: %w", err)
+	}
+
+	if numPruned > 0 {
+		f.InfoLog.Info("pruned outbox", "target", target, clog.KeyCount, numPruned)
+	}
+
+	return numPruned, nil
+}
+
+// Close closes the broker connection, if any
+func (f *Feed) Close() error {
+
+	if f.Publisher == nil {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.Publisher.Close()
+}
@@ -0,0 +1,668 @@
+package changefeed
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// Kafka API keys and the versions used: Produce v3 is the first with record batches, and both have been supported since Kafka 1.0
+const (
+	kafkaApiProduce          int16 = 0
+	kafkaApiMetadata         int16 = 3
+	kafkaApiSaslHandshake    int16 = 17
+	kafkaApiSaslAuthenticate int16 = 36
+
+	kafkaProduceVersion          int16 = 3
+	kafkaMetadataVersion         int16 = 4
+	kafkaSaslHandshakeVersion    int16 = 1
+	kafkaSaslAuthenticateVersion int16 = 0
+)
+
+const (
+	kafkaClientId string = "connectors"
+	kafkaAcksAll  int16  = -1
+)
+
+// Kafka error codes handled by refreshing the metadata, see https://kafka.apache.org/protocol#protocol_error_codes
+const (
+	kafkaErrUnknownTopicOrPartition int16 = 3
+	kafkaErrLeaderNotAvailable      int16 = 5
+	kafkaErrNotLeaderForPartition   int16 = 6
+)
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// KafkaError is an error code returned by a Kafka broker
+type KafkaError struct {
+	Code  int16
+	Topic string
+}
+
+func (e KafkaError) Error() string {
+	if e.Topic != "" {
+		return fmt.Sprintf("kafka error code %d on topic %s", e.Code, e.Topic)
+	}
+	return fmt.Sprintf("kafka error code %d", e.Code)
+}
+
+// retriable returns true if the request may succeed with refreshed metadata
+func (e KafkaError) retriable() bool {
+	return e.Code == kafkaErrUnknownTopicOrPartition || e.Code == kafkaErrLeaderNotAvailable || e.Code == kafkaErrNotLeaderForPartition
+}
+
+// KafkaPublisher produces messages to Kafka topics with acks from all in-sync replicas
+// messages with the same key are written to the same partition, chosen as by the Java client's default partitioner, so that the changes of a row stay in order
+type KafkaPublisher struct {
+	Config   Config
+	Timeout  time.Duration
+	InfoLog  *slog.Logger
+	ErrorLog *slog.Logger
+
+	brokers    map[int32]string     // k = node id, v = address
+	leaders    map[string][]int32   // k = topic, v = leader node id by partition
+	conns      map[int32]*kafkaConn // open connections by node id
+	bootstrap  *kafkaConn           // connection to a bootstrap address, used for metadata
+	correlator int32
+}
+
+func NewKafkaPublisher(conf Config, timeout time.Duration, infoLog, errorLog *slog.Logger) *KafkaPublisher {
+	return &KafkaPublisher{
+		Config:   conf,
+		Timeout:  timeout,
+		InfoLog:  infoLog,
+		ErrorLog: errorLog,
+		brokers:  make(map[int32]string),
+		leaders:  make(map[string][]int32),
+		conns:    make(map[int32]*kafkaConn),
+	}
+}
+
+// Publish produces msgs, refreshing the metadata and retrying once if a partition's leader moved
+func (p *KafkaPublisher) Publish(ctx context.Context, msgs []Message) error {
+
+	err := p.produce(ctx, msgs)
+	if err == nil {
+		return nil
+	}
+
+	var kErr KafkaError
+	if !errors.As(err, &kErr) || !kErr.retriable() {
+		return err
+	}
+
+	p.InfoLog.Debug("kafka metadata outdated, retrying", "error", err.Error())
+	p.leaders = make(map[string][]int32)
+	return p.produce(ctx, msgs)
+}
+
+// Close closes the broker connections
+func (p *KafkaPublisher) Close() error {
+
+	var errs []error
+	for id, c := range p.conns {
+		errs = append(errs, c.Close())
+		delete(p.conns, id)
+	}
+	if p.bootstrap != nil {
+		errs = append(errs, p.bootstrap.Close())
+		p.bootstrap = nil
+	}
+	return errors.Join(errs...)
+}
+
+func (p *KafkaPublisher) produce(ctx context.Context, msgs []Message) error {
+
+	// partition the messages: k = leader node id, then topic, then partition
+	batches := make(map[int32]map[string]map[int32][]Message)
+	for _, msg := range msgs {
+
+		leaders, err := p.topicLeaders(ctx, msg.Topic)
+		if err != nil {
+			return fmt.Errorf("p.topicLeaders failed: %w", err)
+		}
+
+		partition := KafkaPartition([]byte(msg.Key), len(leaders))
+		leader := leaders[partition]
+		if batches[leader] == nil {
+			batches[leader] = make(map[string]map[int32][]Message)
+		}
+		if batches[leader][msg.Topic] == nil {
+			batches[leader][msg.Topic] = make(map[int32][]Message)
+		}
+		batches[leader][msg.Topic][partition] = append(batches[leader][msg.Topic][partition], msg)
+	}
+
+	for leader, topics := range batches {
+
+		conn, err := p.brokerConn(ctx, leader)
+		if err != nil {
+			return fmt.Errorf("p.brokerConn failed for node %d: %w", leader, err)
+		}
+
+		body := p.produceRequest(topics)
+		resp, err := p.request(ctx, conn, kafkaApiProduce, kafkaProduceVersion, body)
+		if err != nil {
+			p.closeBroker(leader)
+			return fmt.Errorf("p.request (produce) failed for node %d: %w", leader, err)
+		}
+
+		if err = parseProduceResponse(resp); err != nil {
+			return fmt.Errorf("parseProduceResponse failed for node %d: %w", leader, err)
+		}
+	}
+
+	return nil
+}
+
+// produceRequest returns the body of a Produce v3 request of the messages by topic and partition
+func (p *KafkaPublisher) produceRequest(topics map[string]map[int32][]Message) []byte {
+
+	var w kafkaWriter
+	w.int16(-1) // no transactional id
+	w.int16(kafkaAcksAll)
+	w.int32(int32(p.Timeout.Milliseconds()))
+	w.int32(int32(len(topics)))
+	for topic, partitions := range topics {
+		w.string(topic)
+		w.int32(int32(len(partitions)))
+		for partition, partitionMsgs := range partitions {
+			w.int32(partition)
+			batch := KafkaRecordBatch(partitionMsgs, time.Now())
+			w.int32(int32(len(batch)))
+			w.raw(batch)
+		}
+	}
+
+	return w.buf
+}
+
+// parseProduceResponse returns the first error code of a Produce v3 response
+func parseProduceResponse(resp []byte) error {
+
+	r := kafkaReader{buf: resp}
+	numTopics := r.int32()
+	for range numTopics {
+		topic := r.string()
+		numPartitions := r.int32()
+		for range numPartitions {
+			r.int32() // partition
+			code := r.int16()
+			r.int64() // base offset
+			r.int64() // log append time
+			if code != 0 && r.err == nil {
+				return KafkaError{Code: code, Topic: topic}
+			}
+		}
+	}
+
+	return r.err
+}
+
+// KafkaRecordBatch returns the messages as a v2 record batch, uncompressed and without producer id
+func KafkaRecordBatch(msgs []Message, now time.Time) []byte {
+
+	timestamp := now.UnixMilli()
+
+	// records, each prefixed by its varint length
+	var records kafkaWriter
+	for i, msg := range msgs {
+		var rec kafkaWriter
+		rec.int8(0)          // attributes
+		rec.varint(0)        // timestamp delta
+		rec.varint(int64(i)) // offset delta
+		if msg.Key == "" {
+			rec.varint(-1)
+		} else {
+			rec.varint(int64(len(msg.Key)))
+			rec.raw([]byte(msg.Key))
+		}
+		rec.varint(int64(len(msg.Value)))
+		rec.raw(msg.Value)
+		rec.varint(int64(len(msg.Headers)))
+		for k, v := range msg.Headers {
+			rec.varint(int64(len(k)))
+			rec.raw([]byte(k))
+			rec.varint(int64(len(v)))
+			rec.raw([]byte(v))
+		}
+
+		records.varint(int64(len(rec.buf)))
+		records.raw(rec.buf)
+	}
+
+	// the part of the batch covered by the CRC
+	var crcPart kafkaWriter
+	crcPart.int16(0)                    // attributes: no compression, create time
+	crcPart.int32(int32(len(msgs) - 1)) // last offset delta
+	crcPart.int64(timestamp)            // first timestamp
+	crcPart.int64(timestamp)            // max timestamp
+	crcPart.int64(-1)                   // producer id
+	crcPart.int16(-1)                   // producer epoch
+	crcPart.int32(-1)                   // base sequence
+	crcPart.int32(int32(len(msgs)))
+	crcPart.raw(records.buf)
+
+	var batch kafkaWriter
+	batch.int64(0)                                   // base offset, assigned by the broker
+	batch.int32(int32(4 + 1 + 4 + len(crcPart.buf))) // batch length: from the partition leader epoch on
+	batch.int32(-1)                                  // partition leader epoch
+	batch.int8(2)                                    // magic
+	batch.int32(int32(crc32.Checksum(crcPart.buf, crc32c)))
+	batch.raw(crcPart.buf)
+
+	return batch.buf
+}
+
+// KafkaPartition returns the partition of key among numPartitions, as the Java client's default partitioner: murmur2 of the key
+// messages without key are written to partition 0
+func KafkaPartition(key []byte, numPartitions int) int32 {
+
+	if len(key) == 0 || numPartitions < 2 {
+		return 0
+	}
+	return int32((murmur2(key) & 0x7fffffff) % uint32(numPartitions))
+}
+
+// murmur2 is the hash of Kafka's Java client, see org.apache.kafka.common.utils.Utils.murmur2
+func murmur2(data []byte) uint32 {
+
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+
+	length := len(data)
+	h := seed ^ uint32(length)
+
+	for i := 0; i+4 <= length; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	tail := data[length&^3:]
+	switch len(tail) {
+	case 3:
+		h ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(tail[0])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+
+	return h
+}
+
+// topicLeaders returns the leader node id of each partition of topic, fetching the metadata if needed
+// topics are created by the broker on the first request if it allows auto creation. Their leaders are then elected shortly after
+func (p *KafkaPublisher) topicLeaders(ctx context.Context, topic string) ([]int32, error) {
+
+	if leaders, ok := p.leaders[topic]; ok {
+		return leaders, nil
+	}
+
+	var err error
+	for attempt := 1; attempt <= 3; attempt++ {
+		err = p.fetchMetadata(ctx, topic)
+		if err == nil {
+			return p.leaders[topic], nil
+		}
+
+		var kErr KafkaError
+		if !errors.As(err, &kErr) || kErr.Code != kafkaErrLeaderNotAvailable {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Duration(attempt) * 500 * time.Millisecond):
+		}
+	}
+
+	return nil, err
+}
+
+// fetchMetadata requests the metadata of topic from a bootstrap broker, and stores the broker addresses and partition leaders
+func (p *KafkaPublisher) fetchMetadata(ctx context.Context, topic string) error {
+
+	if p.bootstrap == nil {
+		var errs []error
+		for _, addr := range p.Config.Addresses {
+			conn, err := p.dial(ctx, addr)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			p.bootstrap = conn
+			break
+		}
+		if p.bootstrap == nil {
+			return fmt.Errorf("no bootstrap broker available: %w", errors.Join(errs...))
+		}
+	}
+
+	var w kafkaWriter
+	w.int32(1)
+	w.string(topic)
+	w.bool(true) // allow auto topic creation
+
+	resp, err := p.request(ctx, p.bootstrap, kafkaApiMetadata, kafkaMetadataVersion, w.buf)
+	if err != nil {
+		p.bootstrap.Close()
+		p.bootstrap = nil
+		return fmt.Errorf("p.request (metadata) failed: %w", err)
+	}
+
+	r := kafkaReader{buf: resp}
+	r.int32() // throttle time
+	numBrokers := r.int32()
+	for range numBrokers {
+		nodeId := r.int32()
+		host := r.string()
+		port := r.int32()
+		r.nullableString() // rack
+		p.brokers[nodeId] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	r.nullableString() // cluster id
+	r.int32()          // controller id
+
+	numTopics := r.int32()
+	for range numTopics {
+		code := r.int16()
+		name := r.string()
+		r.bool() // is internal
+		numPartitions := r.int32()
+		leaders := make([]int32, numPartitions)
+		var partitionErr int16
+		for range numPartitions {
+			pCode := r.int16()
+			index := r.int32()
+			leader := r.int32()
+			r.int32Array() // replicas
+			r.int32Array() // in-sync replicas
+			if pCode != 0 {
+				partitionErr = pCode
+			}
+			if index >= 0 && index < numPartitions {
+				leaders[index] = leader
+			}
+		}
+		if r.err != nil {
+			break
+		}
+		if code != 0 {
+			return KafkaError{Code: code, Topic: name}
+		}
+		if partitionErr != 0 || numPartitions == 0 {
+			return KafkaError{Code: kafkaErrLeaderNotAvailable, Topic: name}
+		}
+		p.leaders[name] = leaders
+	}
+	if r.err != nil {
+		return fmt.Errorf("%w: invalid metadata response: %w", cerrors.ErrValidationFailed, r.err)
+	}
+
+	if _, ok := p.leaders[topic]; !ok {
+		return KafkaError{Code: kafkaErrUnknownTopicOrPartition, Topic: topic}
+	}
+
+	return nil
+}
+
+// brokerConn returns the open connection to node id, connecting if needed
+func (p *KafkaPublisher) brokerConn(ctx context.Context, nodeId int32) (*kafkaConn, error) {
+
+	if conn, ok := p.conns[nodeId]; ok {
+		return conn, nil
+	}
+
+	addr, ok := p.brokers[nodeId]
+	if !ok {
+		return nil, KafkaError{Code: kafkaErrNotLeaderForPartition}
+	}
+	conn, err := p.dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	p.conns[nodeId] = conn
+
+	return conn, nil
+}
+
+func (p *KafkaPublisher) closeBroker(nodeId int32) {
+	if conn, ok := p.conns[nodeId]; ok {
+		conn.Close()
+		delete(p.conns, nodeId)
+	}
+}
+
+// kafkaConn is a connection to a broker
+type kafkaConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// dial connects to addr, with TLS if configured, and authenticates with SASL/PLAIN if a username is configured
+func (p *KafkaPublisher) dial(ctx context.Context, addr string) (*kafkaConn, error) {
+
+	dialer := &net.Dialer{Timeout: p.Timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dialer.DialContext failed: %w", cerrors.UpstreamError{Source: BrokerKafka, Err: err})
+	}
+
+	if p.Config.Tls {
+		host, _, _ := net.SplitHostPort(addr)
+		tlsConn := tls.Client(netConn, &tls.Config{ServerName: host})
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			netConn.Close()
+			return nil, fmt.Errorf("tlsConn.HandshakeContext failed: %w", cerrors.UpstreamError{Source: BrokerKafka, Err: err})
+		}
+		netConn = tlsConn
+	}
+
+	conn := &kafkaConn{Conn: netConn, r: bufio.NewReader(netConn)}
+
+	if p.Config.Username != "" {
+		if err = p.authenticate(ctx, conn); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("p.authenticate failed: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+// authenticate performs a SASL/PLAIN handshake on conn
+func (p *KafkaPublisher) authenticate(ctx context.Context, conn *kafkaConn) error {
+
+	var w kafkaWriter
+	w.string("PLAIN")
+	resp, err := p.request(ctx, conn, kafkaApiSaslHandshake, kafkaSaslHandshakeVersion, w.buf)
+	if err != nil {
+		return fmt.Errorf("p.request (handshake) failed: %w", err)
+	}
+	r := kafkaReader{buf: resp}
+	if code := r.int16(); code != 0 {
+		return fmt.Errorf("%w: PLAIN mechanism not enabled: %w", cerrors.ErrValidationFailed, KafkaError{Code: code})
+	}
+
+	w = kafkaWriter{}
+	token := "\x00" + p.Config.Username + "\x00" + p.Config.Password
+	w.int32(int32(len(token)))
+	w.raw([]byte(token))
+	resp, err = p.request(ctx, conn, kafkaApiSaslAuthenticate, kafkaSaslAuthenticateVersion, w.buf)
+	if err != nil {
+		return fmt.Errorf("p.request (authenticate) failed: %w", err)
+	}
+	r = kafkaReader{buf: resp}
+	if code := r.int16(); code != 0 {
+		msg := r.nullableString()
+		return fmt.Errorf("%w: authentication failed: %s: %w", cerrors.ErrValidationFailed, msg, KafkaError{Code: code})
+	}
+
+	return nil
+}
+
+// request sends a request with body to conn and returns the response body, without its header
+func (p *KafkaPublisher) request(ctx context.Context, conn *kafkaConn, apiKey, apiVersion int16, body []byte) ([]byte, error) {
+
+	p.correlator++
+	correlationId := p.correlator
+
+	deadline := time.Now().Add(p.Timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, fmt.Errorf("conn.SetDeadline failed: %w", err)
+	}
+
+	var w kafkaWriter
+	w.int32(0) // size, set below
+	w.int16(apiKey)
+	w.int16(apiVersion)
+	w.int32(correlationId)
+	w.string(kafkaClientId)
+	w.raw(body)
+	binary.BigEndian.PutUint32(w.buf, uint32(len(w.buf)-4))
+
+	if _, err := conn.Write(w.buf); err != nil {
+		return nil, fmt.Errorf("conn.Write failed: %w", cerrors.UpstreamError{Source: BrokerKafka, Err: err})
+	}
+
+	var sizeBuf [4]byte
+	if _, err := io.ReadFull(conn.r, sizeBuf[:]); err != nil {
+		return nil, fmt.Errorf("io.ReadFull (size) failed: %w", cerrors.UpstreamError{Source: BrokerKafka, Err: err})
+	}
+	resp := make([]byte, binary.BigEndian.Uint32(sizeBuf[:]))
+	if _, err := io.ReadFull(conn.r, resp); err != nil {
+		return nil, fmt.Errorf("io.ReadFull failed: %w", cerrors.UpstreamError{Source: BrokerKafka, Err: err})
+	}
+
+	if len(resp) < 4 || int32(binary.BigEndian.Uint32(resp)) != correlationId {
+		return nil, fmt.Errorf("%w: unexpected correlation id in response", cerrors.ErrValidationFailed)
+	}
+
+	return resp[4:], nil
+}
+
+// kafkaWriter encodes the primitive types of the Kafka protocol, big endian
+type kafkaWriter struct {
+	buf []byte
+}
+
+func (w *kafkaWriter) raw(b []byte)   { w.buf = append(w.buf, b...) }
+func (w *kafkaWriter) int8(v int8)    { w.buf = append(w.buf, byte(v)) }
+func (w *kafkaWriter) int16(v int16)  { w.buf = binary.BigEndian.AppendUint16(w.buf, uint16(v)) }
+func (w *kafkaWriter) int32(v int32)  { w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(v)) }
+func (w *kafkaWriter) int64(v int64)  { w.buf = binary.BigEndian.AppendUint64(w.buf, uint64(v)) }
+func (w *kafkaWriter) varint(v int64) { w.buf = binary.AppendVarint(w.buf, v) } // zigzag, as used in records
+
+func (w *kafkaWriter) bool(v bool) {
+	if v {
+		w.int8(1)
+	} else {
+		w.int8(0)
+	}
+}
+
+func (w *kafkaWriter) string(s string) {
+	w.int16(int16(len(s)))
+	w.raw([]byte(s))
+}
+
+// kafkaReader decodes the primitive types of the Kafka protocol. After the first error, reads return zero values and err is set
+type kafkaReader struct {
+	buf []byte
+	err error
+}
+
+func (r *kafkaReader) next(n int) []byte {
+
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.buf) < n {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *kafkaReader) int16() int16 {
+	if b := r.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (r *kafkaReader) int32() int32 {
+	if b := r.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (r *kafkaReader) int64() int64 {
+	if b := r.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (r *kafkaReader) bool() bool {
+	b := r.next(1)
+	return b != nil && b[0] != 0
+}
+
+func (r *kafkaReader) string() string {
+	n := r.int16()
+	return string(r.next(int(n)))
+}
+
+func (r *kafkaReader) nullableString() string {
+	n := r.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(r.next(int(n)))
+}
+
+func (r *kafkaReader) int32Array() []int32 {
+	n := r.int32()
+	if n < 0 || r.err != nil {
+		return nil
+	}
+	vals := make([]int32, 0, min(int(n), len(r.buf)/4))
+	for range n {
+		vals = append(vals, r.int32())
+	}
+	return vals
+}
@@ -0,0 +1,323 @@
+package changefeed
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the vectors of the Java client's org.apache.kafka.common.utils.UtilsTest.testMurmur2
+func TestMurmur2(t *testing.T) {
+
+	tests := []struct {
+		key  string
+		want int32
+	}{
+		{"21", -973932308},
+		{"foobar", -790332482},
+		{"a-little-bit-long-string", -985981536},
+		{"a-little-bit-longer-string", -1486304829},
+		{"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8", -58897971},
+		{"abc", 479470107},
+	}
+
+	for _, tt := range tests {
+		if got := int32(murmur2([]byte(tt.key))); got != tt.want {
+			t.Errorf("murmur2(%q): got %d, want %d", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestKafkaPartition(t *testing.T) {
+
+	tests := []struct {
+		key           string
+		numPartitions int
+		want          int32
+	}{
+		{"foobar", 10, 6},
+		{"foobar", 7, 0},
+		{"abc", 10, 7},
+		{"abc", 7, 4},
+		{"21", 7, 3},
+		{"", 7, 0},    // no key
+		{"abc", 1, 0}, // single partition
+		{"abc", 0, 0}, // no partitions known
+	}
+
+	for _, tt := range tests {
+		if got := KafkaPartition([]byte(tt.key), tt.numPartitions); got != tt.want {
+			t.Errorf("KafkaPartition(%q, %d): got %d, want %d", tt.key, tt.numPartitions, got, tt.want)
+		}
+	}
+}
+
+func TestKafkaRecordBatch(t *testing.T) {
+
+	msgs := []Message{
+		{Key: "k", Value: []byte("v")},
+		{Value: []byte("v2"), Headers: map[string]string{"h": "x"}},
+	}
+
+	want := fixture(t, `
+		0000000000000000 -- base offset
+		00000047         -- batch length: 71
+		ffffffff         -- partition leader epoch
+		02               -- magic
+		f999d49c         -- CRC-32C of the rest
+		0000             -- attributes
+		00000001         -- last offset delta
+		0000018bcfe56800 -- first timestamp: 1700000000000
+		0000018bcfe56800 -- max timestamp
+		ffffffffffffffff -- producer id
+		ffff             -- producer epoch
+		ffffffff         -- base sequence
+		00000002         -- records
+		10 00 00 00 02 6b 02 76 00                -- length 8, attributes, timestamp delta, offset delta 0, key "k", value "v", no headers
+		18 00 00 02 01 04 7632 02 02 68 02 78     -- length 12, attributes, timestamp delta, offset delta 1, null key, value "v2", header "h": "x"
+	`)
+
+	got := KafkaRecordBatch(msgs, time.UnixMilli(1700000000000))
+	if !bytes.Equal(got, want) {
+		t.Errorf("KafkaRecordBatch:\ngot  %x\nwant %x", got, want)
+	}
+}
+
+func TestParseProduceResponse(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		resp    string
+		wantErr error
+	}{
+		{"ok", `
+			00000001 0001 74                        -- topic "t"
+			00000002                                -- partitions
+			00000000 0000 000000000000002a ffffffffffffffff -- partition 0, no error, base offset 42, log append time
+			00000001 0000 0000000000000007 ffffffffffffffff`, nil},
+		{"not leader", `
+			00000001 0001 74
+			00000002
+			00000000 0000 000000000000002a ffffffffffffffff
+			00000001 0006 ffffffffffffffff ffffffffffffffff -- partition 1, not leader for partition`, KafkaError{Code: kafkaErrNotLeaderForPartition, Topic: "t"}},
+		{"truncated", `
+			00000001 0001 74
+			00000001
+			00000000 0000 0000`, io.ErrUnexpectedEOF},
+		{"empty", ``, io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseProduceResponse(fixture(t, tt.resp))
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("parseProduceResponse failed: %s", err.Error())
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("parseProduceResponse: got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFetchMetadata(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		resp        string
+		wantBrokers map[int32]string
+		wantLeaders []int32
+		wantErr     error
+	}{
+		{"ok", `
+			00000000                   -- throttle time
+			00000002                   -- brokers
+			00000001 0002 6231 00002384 ffff   -- node 1, host "b1", port 9092, no rack
+			00000002 0002 6232 00002385 0001 72 -- node 2, host "b2", port 9093, rack "r"
+			0001 63                    -- cluster id "c"
+			00000001                   -- controller id
+			00000001                   -- topics
+			0000 0001 74 00            -- no error, topic "t", not internal
+			00000002                   -- partitions
+			0000 00000001 00000002 00000001 00000002 00000001 00000002 -- partition 1, leader 2, replicas [2], in-sync replicas [2]
+			0000 00000000 00000001 00000001 00000001 00000001 00000001 -- partition 0, leader 1`,
+			map[int32]string{1: "b1:9092", 2: "b2:9093"}, []int32{1, 2}, nil},
+		{"leader not available", `
+			00000000
+			00000001
+			00000001 0002 6231 00002384 ffff
+			ffff
+			00000001
+			00000001
+			0000 0001 74 00
+			00000001
+			0005 00000000 ffffffff 00000000 00000000 -- partition 0, leader not available, no leader`,
+			nil, nil, KafkaError{Code: kafkaErrLeaderNotAvailable, Topic: "t"}},
+		{"unknown topic", `
+			00000000
+			00000000
+			ffff
+			00000001
+			00000001
+			0003 0001 74 00 00000000 -- unknown topic or partition`,
+			nil, nil, KafkaError{Code: kafkaErrUnknownTopicOrPartition, Topic: "t"}},
+		{"truncated", `
+			00000000
+			00000001
+			00000001 0002 62`,
+			nil, nil, io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			conn, requests := serveKafka(t, fixture(t, tt.resp))
+			p := NewKafkaPublisher(Config{}, time.Second, nil, nil)
+			p.bootstrap = conn
+
+			err := p.fetchMetadata(t.Context(), "t")
+
+			wantReq := fixture(t, `
+				0000001c           -- size: 28
+				0003 0004          -- Metadata v4
+				00000001           -- correlation id
+				000a 636f6e6e6563746f7273 -- client id "connectors"
+				00000001 0001 74   -- topic "t"
+				01                 -- allow auto topic creation`)
+			if req := <-requests; !bytes.Equal(req, wantReq) {
+				t.Errorf("request:\ngot  %x\nwant %x", req, wantReq)
+			}
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("p.fetchMetadata: got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("p.fetchMetadata failed: %s", err.Error())
+			}
+			for nodeId, addr := range tt.wantBrokers {
+				if p.brokers[nodeId] != addr {
+					t.Errorf("broker %d: got %q, want %q", nodeId, p.brokers[nodeId], addr)
+				}
+			}
+			if !slices.Equal(p.leaders["t"], tt.wantLeaders) {
+				t.Errorf("leaders: got %v, want %v", p.leaders["t"], tt.wantLeaders)
+			}
+		})
+	}
+}
+
+func TestKafkaWriterReader(t *testing.T) {
+
+	var w kafkaWriter
+	w.int8(-1)
+	w.int16(-2)
+	w.int32(-3)
+	w.int64(-4)
+	w.bool(true)
+	w.string("ab")
+	w.varint(-1)
+	w.varint(150)
+
+	want := fixture(t, `
+		ff                 -- int8 -1
+		fffe               -- int16 -2
+		fffffffd           -- int32 -3
+		fffffffffffffffc   -- int64 -4
+		01                 -- true
+		0002 6162          -- "ab"
+		01                 -- varint -1, zigzag
+		ac02               -- varint 150, zigzag`)
+	if !bytes.Equal(w.buf, want) {
+		t.Fatalf("kafkaWriter:\ngot  %x\nwant %x", w.buf, want)
+	}
+
+	r := kafkaReader{buf: want[1:]}
+	if v := r.int16(); v != -2 {
+		t.Errorf("int16: got %d, want -2", v)
+	}
+	if v := r.int32(); v != -3 {
+		t.Errorf("int32: got %d, want -3", v)
+	}
+	if v := r.int64(); v != -4 {
+		t.Errorf("int64: got %d, want -4", v)
+	}
+	if v := r.bool(); !v {
+		t.Errorf("bool: got false, want true")
+	}
+	if v := r.string(); v != "ab" {
+		t.Errorf("string: got %q, want \"ab\"", v)
+	}
+
+	// reading past the end sets err and returns zero values from then on
+	r = kafkaReader{buf: fixture(t, `00000002 00000001`)}
+	if r.int32Array(); !errors.Is(r.err, io.ErrUnexpectedEOF) {
+		t.Errorf("int32Array of a truncated array: got %v, want io.ErrUnexpectedEOF", r.err)
+	}
+	if v := r.nullableString(); v != "" {
+		t.Errorf("nullableString after error: got %q, want \"\"", v)
+	}
+}
+
+// fixture returns the bytes of s, hex with whitespace and "--" comments to the end of the line
+func fixture(tb testing.TB, s string) []byte {
+
+	tb.Helper()
+
+	var b strings.Builder
+	for _, line := range strings.Split(s, "\n") {
+		line, _, _ = strings.Cut(line, "--")
+		b.WriteString(strings.Join(strings.Fields(line), ""))
+	}
+	buf, err := hex.DecodeString(b.String())
+	if err != nil {
+		tb.Fatalf("hex.DecodeString failed: %s", err.Error())
+	}
+	return buf
+}
+
+// serveKafka returns a connection to a broker which answers a single request with resp, and a channel receiving the request
+func serveKafka(tb testing.TB, resp []byte) (*kafkaConn, <-chan []byte) {
+
+	tb.Helper()
+
+	client, server := net.Pipe()
+	tb.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+
+	requests := make(chan []byte, 1)
+	go func() {
+		defer close(requests)
+
+		var sizeBuf [4]byte
+		if _, err := io.ReadFull(server, sizeBuf[:]); err != nil {
+			return
+		}
+		req := make([]byte, binary.BigEndian.Uint32(sizeBuf[:]))
+		if _, err := io.ReadFull(server, req); err != nil {
+			return
+		}
+		requests <- append(sizeBuf[:], req...)
+
+		// the response header is the correlation id of the request
+		var w kafkaWriter
+		w.int32(int32(4 + len(resp)))
+		w.raw(req[4:8])
+		w.raw(resp)
+		server.Write(w.buf)
+	}()
+
+	return &kafkaConn{Conn: client, r: bufio.NewReader(client)}, requests
+}
@@ -0,0 +1,379 @@
+package changefeed
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// natsMsgIdHeader is the header used by JetStream to deduplicate messages
+const natsMsgIdHeader string = "Nats-Msg-Id"
+
+// natsInfo is the part of the server's INFO used by the publisher
+type natsInfo struct {
+	TlsRequired bool  `json:"tls_required"`
+	Headers     bool  `json:"headers"`
+	MaxPayload  int64 `json:"max_payload"`
+}
+
+// natsConnect is the CONNECT sent to the server
+type natsConnect struct {
+	Verbose      bool   `json:"verbose"`
+	Pedantic     bool   `json:"pedantic"`
+	TlsRequired  bool   `json:"tls_required"`
+	Name         string `json:"name"`
+	Lang         string `json:"lang"`
+	Version      string `json:"version"`
+	Protocol     int    `json:"protocol"`
+	Headers      bool   `json:"headers"`
+	NoResponders bool   `json:"no_responders"`
+	User         string `json:"user,omitempty"`
+	Pass         string `json:"pass,omitempty"`
+	AuthToken    string `json:"auth_token,omitempty"`
+}
+
+// natsJsAck is the reply of JetStream to a published message
+type natsJsAck struct {
+	Stream string `json:"stream"`
+	Seq    uint64 `json:"seq"`
+	Error  *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error"`
+}
+
+// NatsPublisher publishes messages to NATS subjects, with the event id in the Nats-Msg-Id header for JetStream's deduplication
+// without JetStream, a publish is complete once the server answered a PING sent after the messages, i.e. has processed them
+type NatsPublisher struct {
+	Config   Config
+	Timeout  time.Duration
+	InfoLog  *slog.Logger
+	ErrorLog *slog.Logger
+
+	conn  net.Conn
+	r     *bufio.Reader
+	info  natsInfo
+	inbox string // subject prefix of JetStream acks
+}
+
+func NewNatsPublisher(conf Config, timeout time.Duration, infoLog, errorLog *slog.Logger) *NatsPublisher {
+	return &NatsPublisher{
+		Config:   conf,
+		Timeout:  timeout,
+		InfoLog:  infoLog,
+		ErrorLog: errorLog,
+	}
+}
+
+// Publish publishes msgs, connecting first if needed. The connection is closed on error, so that the next publish reconnects
+func (p *NatsPublisher) Publish(ctx context.Context, msgs []Message) error {
+
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			return fmt.Errorf("p.connect failed: %w", err)
+		}
+	}
+
+	err := p.publish(ctx, msgs)
+	if err != nil {
+		p.Close()
+		return err
+	}
+
+	return nil
+}
+
+// Close closes the connection
+func (p *NatsPublisher) Close() error {
+
+	if p.conn == nil {
+		return nil
+	}
+	err := p.conn.Close()
+	p.conn = nil
+	return err
+}
+
+func (p *NatsPublisher) publish(ctx context.Context, msgs []Message) error {
+
+	if err := p.setDeadline(ctx); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for i, msg := range msgs {
+		reply := ""
+		if p.Config.JetStream {
+			reply = p.inbox + "." + strconv.Itoa(i)
+		}
+		if err := p.writeMsg(&buf, msg, reply); err != nil {
+			return fmt.Errorf("p.writeMsg failed: %w", err)
+		}
+	}
+	if !p.Config.JetStream {
+		buf.WriteString("PING\r\n")
+	}
+
+	if _, err := p.conn.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("p.conn.Write failed: %w", cerrors.UpstreamError{Source: BrokerNats, Err: err})
+	}
+
+	if !p.Config.JetStream {
+		return p.awaitPong()
+	}
+	return p.awaitAcks(len(msgs))
+}
+
+// writeMsg writes the HPUB of msg, or PUB if the server does not support headers
+func (p *NatsPublisher) writeMsg(buf *bytes.Buffer, msg Message, reply string) error {
+
+	if p.info.MaxPayload > 0 && int64(len(msg.Value)) > p.info.MaxPayload {
+		return fmt.Errorf("%w: message %s exceeds the server's max payload of %d bytes", cerrors.ErrValidationFailed, msg.Id, p.info.MaxPayload)
+	}
+
+	subject := msg.Topic
+	if reply != "" {
+		subject += " " + reply
+	}
+
+	if !p.info.Headers {
+		fmt.Fprintf(buf, "PUB %s %d\r\n", subject, len(msg.Value))
+		buf.Write(msg.Value)
+		buf.WriteString("\r\n")
+		return nil
+	}
+
+	var hdr bytes.Buffer
+	hdr.WriteString("NATS/1.0\r\n")
+	fmt.Fprintf(&hdr, "%s: %s\r\n", natsMsgIdHeader, msg.Id)
+	keys := make([]string, 0, len(msg.Headers))
+	for k := range msg.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&hdr, "%s: %s\r\n", k, msg.Headers[k])
+	}
+	hdr.WriteString("\r\n")
+
+	fmt.Fprintf(buf, "HPUB %s %d %d\r\n", subject, hdr.Len(), hdr.Len()+len(msg.Value))
+	buf.Write(hdr.Bytes())
+	buf.Write(msg.Value)
+	buf.WriteString("\r\n")
+
+	return nil
+}
+
+// connect connects to the first reachable address, upgrading to TLS if configured or required, and sends CONNECT
+func (p *NatsPublisher) connect(ctx context.Context) error {
+
+	var errs []error
+	for _, addr := range p.Config.Addresses {
+		err := p.connectAddr(ctx, addr)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", addr, err))
+		p.Close()
+	}
+
+	return fmt.Errorf("no server available: %w", errors.Join(errs...))
+}
+
+func (p *NatsPublisher) connectAddr(ctx context.Context, addr string) error {
+
+	dialer := &net.Dialer{Timeout: p.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("dialer.DialContext failed: %w", cerrors.UpstreamError{Source: BrokerNats, Err: err})
+	}
+	p.conn = conn
+	p.r = bufio.NewReader(conn)
+	if err = p.setDeadline(ctx); err != nil {
+		return err
+	}
+
+	// the server sends INFO first
+	line, err := p.readLine()
+	if err != nil {
+		return fmt.Errorf("p.readLine failed: %w", err)
+	}
+	infoJson, ok := strings.CutPrefix(line, "INFO ")
+	if !ok {
+		return fmt.Errorf("%w: expected INFO, got: %s", cerrors.ErrValidationFailed, line)
+	}
+	if err = json.Unmarshal([]byte(infoJson), &p.info); err != nil {
+		return fmt.Errorf("json.Unmarshal (INFO) failed: %w", err)
+	}
+
+	if p.Config.Tls || p.info.TlsRequired {
+		host, _, _ := net.SplitHostPort(addr)
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			return fmt.Errorf("tlsConn.HandshakeContext failed: %w", cerrors.UpstreamError{Source: BrokerNats, Err: err})
+		}
+		p.conn = tlsConn
+		p.r = bufio.NewReader(tlsConn)
+	}
+
+	connect, err := json.Marshal(natsConnect{
+		TlsRequired:  p.Config.Tls || p.info.TlsRequired,
+		Name:         "connectors",
+		Lang:         "go",
+		Version:      "1",
+		Protocol:     1,
+		Headers:      p.info.Headers,
+		NoResponders: p.info.Headers,
+		User:         p.Config.Username,
+		Pass:         p.Config.Password,
+		AuthToken:    p.Config.Token,
+	})
+	if err != nil {
+		return fmt.Errorf("json.Marshal (CONNECT) failed: %w", err)
+	}
+
+	cmd := "CONNECT " + string(connect) + "\r\n"
+	if p.Config.JetStream {
+		p.inbox = "_INBOX." + newInboxId()
+		cmd += "SUB " + p.inbox + ".* 1\r\n"
+	}
+	cmd += "PING\r\n"
+	if _, err = io.WriteString(p.conn, cmd); err != nil {
+		return fmt.Errorf("io.WriteString failed: %w", cerrors.UpstreamError{Source: BrokerNats, Err: err})
+	}
+
+	// an authentication failure is answered with -ERR instead of PONG
+	return p.awaitPong()
+}
+
+// awaitPong reads until the server's PONG, answering its PINGs
+func (p *NatsPublisher) awaitPong() error {
+
+	for {
+		line, err := p.readLine()
+		if err != nil {
+			return fmt.Errorf("p.readLine failed: %w", err)
+		}
+		done, err := p.handleControl(line)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// handleControl handles a control line of the server. done is true for PONG
+func (p *NatsPublisher) handleControl(line string) (done bool, err error) {
+
+	switch {
+	case line == "PONG":
+		return true, nil
+	case line == "PING":
+		if _, err = io.WriteString(p.conn, "PONG\r\n"); err != nil {
+			return false, fmt.Errorf("io.WriteString failed: %w", cerrors.UpstreamError{Source: BrokerNats, Err: err})
+		}
+	case strings.HasPrefix(line, "-ERR"):
+		return false, fmt.Errorf("%w: server error: %s", cerrors.ErrUpstreamUnavailable, strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+	case line == "+OK", strings.HasPrefix(line, "INFO "):
+	default:
+		return false, fmt.Errorf("%w: unexpected line: %s", cerrors.ErrValidationFailed, line)
+	}
+
+	return false, nil
+}
+
+// awaitAcks reads the JetStream acks of numMsgs messages
+func (p *NatsPublisher) awaitAcks(numMsgs int) error {
+
+	acked := 0
+	for acked < numMsgs {
+		line, err := p.readLine()
+		if err != nil {
+			return fmt.Errorf("p.readLine failed: %w", err)
+		}
+
+		// MSG <subject> <sid> <size> or HMSG <subject> <sid> <header size> <total size>
+		fields := strings.Fields(line)
+		if len(fields) == 0 || (fields[0] != "MSG" && fields[0] != "HMSG") {
+			if _, err = p.handleControl(line); err != nil {
+				return err
+			}
+			continue
+		}
+
+		hdrSize := 0
+		totalSize, err := strconv.Atoi(fields[len(fields)-1])
+		if err == nil && fields[0] == "HMSG" && len(fields) >= 5 {
+			hdrSize, err = strconv.Atoi(fields[len(fields)-2])
+		}
+		if err != nil {
+			return fmt.Errorf("%w: invalid message line: %s", cerrors.ErrValidationFailed, line)
+		}
+		payload := make([]byte, totalSize+2) // with trailing CRLF
+		if _, err = io.ReadFull(p.r, payload); err != nil {
+			return fmt.Errorf("io.ReadFull failed: %w", cerrors.UpstreamError{Source: BrokerNats, Err: err})
+		}
+
+		// a status header without payload, e.g. 503 if no stream captures the subject
+		if hdrSize > 0 && totalSize == hdrSize {
+			status := strings.SplitN(string(payload[:hdrSize]), "\r\n", 2)[0]
+			return fmt.Errorf("%w: no JetStream ack: %s", cerrors.ErrUpstreamUnavailable, strings.TrimSpace(strings.TrimPrefix(status, "NATS/1.0")))
+		}
+
+		ack := natsJsAck{}
+		if err = json.Unmarshal(payload[hdrSize:totalSize], &ack); err != nil {
+			return fmt.Errorf("json.Unmarshal (ack) failed: %w", err)
+		}
+		if ack.Error != nil {
+			return fmt.Errorf("%w: JetStream error %d: %s", cerrors.ErrUpstreamUnavailable, ack.Error.Code, ack.Error.Description)
+		}
+		acked++
+	}
+
+	return nil
+}
+
+func (p *NatsPublisher) readLine() (string, error) {
+
+	line, err := p.r.ReadString('\n')
+	if err != nil {
+		return "", cerrors.UpstreamError{Source: BrokerNats, Err: err}
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// setDeadline sets the deadline of the connection to the timeout, or ctx's deadline if earlier
+func (p *NatsPublisher) setDeadline(ctx context.Context) error {
+
+	deadline := time.Now().Add(p.Timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := p.conn.SetDeadline(deadline); err != nil {
+		return fmt.Errorf("p.conn.SetDeadline failed: %w", err)
+	}
+	return nil
+}
+
+func newInboxId() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,174 @@
+package clog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// attribute keys used consistently across apiclients, csyncdb, httpapi and the daemon
+const (
+	KeyCode       string = "code"        // natural key of a synced item, e.g. a currency code
+	KeyCount      string = "count"       // number of affected items
+	KeyDataset    string = "dataset"     // dataset name as used in the sync journal, e.g. "ecb.exchange_rate"
+	KeyDurationMs string = "duration_ms" // elapsed time in milliseconds
+	KeyError      string = "error"       // error message
+	KeyMethod     string = "method"      // HTTP method
+	KeyPath       string = "path"        // HTTP request path
+	KeyStatus     string = "status"      // HTTP status code
+	KeyUrl        string = "url"         // outbound request URL
+)
+
+// log formats
+const (
+	FormatText string = "text"
+	FormatJSON string = "json"
+)
+
+// Config contains the logging settings
+type Config struct {
+	Format string `toml:"format"` // "text" (default) or "json"
+	Level  string `toml:"level"`  // "debug", "info" (default), "warn" or "error"
+
+	// sampling of repetitive debug and info records. Within each SampleTick, the first SampleFirst records with the same message are logged, then only every SampleThereafter-th
+	// warnings and errors are never sampled. Sampling is disabled if SampleFirst is 0
+	SampleFirst      int    `toml:"sampleFirst"`
+	SampleThereafter int    `toml:"sampleThereafter"` // defaults to 100
+	SampleTick       string `toml:"sampleTick"`       // Go duration, defaults to 1s
+}
+
+// New returns the info and error loggers for conf. Info logs are written to infoW, error logs to errorW
+func New(conf Config, infoW, errorW io.Writer) (infoLog, errorLog *slog.Logger, err error) {
+
+	level, err := ParseLevel(conf.Level)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ParseLevel failed: %w", err)
+	}
+
+	infoHandler, err := newHandler(conf.Format, infoW, level)
+	if err != nil {
+		return nil, nil, fmt.Errorf("newHandler failed: %w", err)
+	}
+	errorHandler, err := newHandler(conf.Format, errorW, slog.LevelWarn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("newHandler failed: %w", err)
+	}
+
+	if conf.SampleFirst > 0 {
+		tick := time.Second
+		if conf.SampleTick != "" {
+			tick, err = time.ParseDuration(conf.SampleTick)
+			if err != nil {
+				return nil, nil, fmt.Errorf("time.ParseDuration failed for sampleTick: %w", err)
+			}
+		}
+		thereafter := conf.SampleThereafter
+		if thereafter < 1 {
+			thereafter = 100
+		}
+		infoHandler = NewSamplingHandler(infoHandler, conf.SampleFirst, thereafter, tick)
+	}
+
+	return slog.New(infoHandler), slog.New(errorHandler), nil
+}
+
+// ParseLevel converts a level name to slog.Level. An empty name returns slog.LevelInfo
+func ParseLevel(name string) (slog.Level, error) {
+
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("invalid level '%s'", name)
+	}
+}
+
+func newHandler(format string, w io.Writer, level slog.Level) (slog.Handler, error) {
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch format {
+	case "", FormatText:
+		return slog.NewTextHandler(w, opts), nil
+	case FormatJSON:
+		return slog.NewJSONHandler(w, opts), nil
+	default:
+		return nil, fmt.Errorf("invalid format '%s'", format)
+	}
+}
+
+// SamplingHandler is a slog.Handler which drops repetitive debug and info records: per message and tick, it passes the first records and then every thereafter-th
+type SamplingHandler struct {
+	next       slog.Handler
+	first      int
+	thereafter int
+	tick       time.Duration
+	state      *samplingState // shared by handlers derived via WithAttrs and WithGroup
+}
+
+type samplingState struct {
+	mu        sync.Mutex
+	tickStart time.Time
+	counts    map[string]int
+}
+
+func NewSamplingHandler(next slog.Handler, first, thereafter int, tick time.Duration) *SamplingHandler {
+	return &SamplingHandler{
+		next:       next,
+		first:      first,
+		thereafter: thereafter,
+		tick:       tick,
+		state:      &samplingState{counts: make(map[string]int)},
+	}
+}
+
+func (h *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *SamplingHandler) Handle(ctx context.Context, r slog.Record) error {
+
+	if r.Level >= slog.LevelWarn || h.sample(r.Message, r.Time) {
+		return h.next.Handle(ctx, r)
+	}
+	return nil
+}
+
+func (h *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.next = h.next.WithAttrs(attrs)
+	return &h2
+}
+
+func (h *SamplingHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.next = h.next.WithGroup(name)
+	return &h2
+}
+
+// sample returns true if the record with msg at time t should be logged
+func (h *SamplingHandler) sample(msg string, t time.Time) bool {
+
+	s := h.state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t.Sub(s.tickStart) >= h.tick {
+		s.tickStart = t
+		clear(s.counts)
+	}
+
+	s.counts[msg]++
+	n := s.counts[msg]
+	return n <= h.first || (n-h.first)%h.thereafter == 0
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// output formats selectable with --output
+const (
+	outputTable string = "table"
+	outputJSON  string = "json"
+)
+
+var outputFormats = []string{outputTable, outputJSON}
+
+// commandResult is implemented by the result of each command that prints one
+type commandResult interface {
+	// writeTable writes the human-readable form of the result. Columns are separated by tabs
+	writeTable(w io.Writer)
+}
+
+// printResult writes res to stdout in the --output format
+func printResult(res commandResult) {
+
+	if cliApp.Output == outputJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(res); err != nil {
+			cliApp.ErrorLog.Error("enc.Encode failed: " + err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	res.writeTable(tw)
+	tw.Flush()
+}
+
+func validateOutput(output string) error {
+	for _, f := range outputFormats {
+		if output == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid --output '%s': must be one of %s", output, strings.Join(outputFormats, ", "))
+}
+
+// fixedCompletion returns a flag completion func offering vals
+func fixedCompletion(vals ...string) func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return vals, cobra.ShellCompDirectiveNoFileComp
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/archive"
+	"github.com/loveyourstack/connectors/changefeed"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/config"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/drift"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/sink"
+	"github.com/loveyourstack/lys/lyspgdb"
+	"github.com/spf13/cobra"
+)
+
+var version = "0.0.1"
+var rootCmd = &cobra.Command{
+	Use:     "connectors",
+	Version: version,
+	Short:   "connectors - CLI tool for connectors",
+	Long:    `connectors is a CLI tool for syncing, importing and exporting public API data`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if needsApp(cmd) {
+			initApp()
+		}
+	},
+	// no Run function: a subcommand is always needed
+}
+
+// cliApplication contains the fields common to all commands
+type cliApplication struct {
+	Config   *config.Config
+	InfoLog  *slog.Logger
+	ErrorLog *slog.Logger
+	Db       *pgxpool.Pool
+	ReadDb   *pgxpool.Pool    // Db as the read-only role if [dbReader] is configured, otherwise Db. Used by commands that only read
+	Targets  []csyncdb.Target // sync targets: Db first, then any additional databases from config
+	Validate *validator.Validate
+	Output   string // --output format
+
+	Changefeed *changefeed.Feed  // nil unless [changefeed] is configured
+	Sinks      sink.Router       // nil unless [clickhouse] is configured
+	Drift      *drift.Detector   // nil unless [drift] is enabled
+	Archive    *archive.Archiver // nil unless [archive] is configured
+}
+
+var (
+	cliApp         *cliApplication
+	configFilePath string
+	logFormat      string
+	logLevel       string
+	output         string
+	opTimeout      time.Duration
+	analyzeAfter   int64
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configFilePath, "config", config.DefaultFilePath, "path to the TOML config file")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "", "log format: text or json (overrides config)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "log level: debug, info, warn or error (overrides config)")
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", outputTable, "result format: table or json")
+	rootCmd.PersistentFlags().DurationVar(&opTimeout, "op-timeout", 0, "bounds each database operation, e.g. 5m (default none)")
+	rootCmd.PersistentFlags().Int64Var(&analyzeAfter, "analyze-after", 0, "runs ANALYZE on a table after a bulk load inserts at least this many rows into it (default never)")
+
+	rootCmd.RegisterFlagCompletionFunc("output", fixedCompletion(outputFormats...))
+	rootCmd.RegisterFlagCompletionFunc("log-format", fixedCompletion(clog.FormatText, clog.FormatJSON))
+	rootCmd.RegisterFlagCompletionFunc("log-level", fixedCompletion("debug", "info", "warn", "error"))
+}
+
+// cmdContext returns the context of a command, which bounds each database operation by --op-timeout and analyzes bulk loaded tables as set by --analyze-after
+func cmdContext() context.Context {
+	ctx := cruntime.WithOpTimeout(context.Background(), opTimeout)
+	return cruntime.WithAnalyzeAfter(ctx, analyzeAfter)
+}
+
+// needsApp returns false for commands that must work without config or db, such as shell completion
+func needsApp(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		switch c.Name() {
+		case "completion", "help", "scaffold", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+			return false
+		}
+	}
+	return true
+}
+
+func initApp() {
+
+	if err := validateOutput(output); err != nil {
+		log.Fatalf("initialization: %s", err.Error())
+	}
+
+	// load config from file
+	conf := config.Config{}
+	err := conf.LoadFromFile(configFilePath)
+	if err != nil {
+		log.Fatalf("initialization: config file not found: %s", err.Error())
+	}
+
+	// apply the [connectors.<name>] settings to the registered connectors
+	if err = registry.ConfigureAll(conf.ConnectorDecoder); err != nil {
+		log.Fatalf("initialization: invalid connector config: %s", err.Error())
+	}
+
+	if conf.Changefeed.Enabled() {
+		conf.Changefeed = conf.Changefeed.WithDefaults()
+		if err = conf.Changefeed.Validate(); err != nil {
+			log.Fatalf("initialization: invalid changefeed config: %s", err.Error())
+		}
+	}
+
+	ctx := context.Background()
+
+	// create loggers, flags taking precedence over config
+	if logFormat != "" {
+		conf.Log.Format = logFormat
+	}
+	if logLevel != "" {
+		conf.Log.Level = logLevel
+	}
+	// with json output, stdout is reserved for the result
+	infoW := os.Stdout
+	if output == outputJSON {
+		infoW = os.Stderr
+	}
+	infoLog, errorLog, err := clog.New(conf.Log, infoW, os.Stderr)
+	if err != nil {
+		log.Fatalf("initialization: invalid log config: %s", err.Error())
+	}
+
+	cliApp = &cliApplication{
+		Config:   &conf,
+		InfoLog:  infoLog,
+		ErrorLog: errorLog,
+		Validate: validator.New(validator.WithRequiredStructEnabled()),
+		Output:   output,
+	}
+
+	if conf.Changefeed.Enabled() {
+		cliApp.Changefeed, err = changefeed.New(conf.Changefeed, infoLog, errorLog)
+		if err != nil {
+			log.Fatalf("initialization: changefeed.New failed: %s", err.Error())
+		}
+		cliApp.Changefeed.ContractVersions = registry.ContractVersions(registry.All())
+	}
+
+	cliApp.Sinks, err = sink.NewRouter(conf.ClickHouse, infoLog, errorLog)
+	if err != nil {
+		log.Fatalf("initialization: invalid clickhouse config: %s", err.Error())
+	}
+
+	// connect to db and assign conn to cliApp
+	cliApp.Db, err = lyspgdb.GetPool(ctx, conf.Db, conf.DbUser)
+	if err != nil {
+		log.Fatalf("initialization: failed to create db connection pool: %s", err.Error())
+	}
+	// not deferring cliApp.Db.Close() here: it is called before subcommand is reached. Defer close in subcommand instead
+
+	// the read-only role, so that the read paths cannot modify data. Pools connect lazily, so commands not using it don't connect
+	cliApp.ReadDb = cliApp.Db
+	if conf.HasReader() {
+		cliApp.ReadDb, err = lyspgdb.GetPool(ctx, conf.Db, conf.DbReader)
+		if err != nil {
+			log.Fatalf("initialization: failed to create reader db connection pool: %s", err.Error())
+		}
+	}
+
+	// additional sync targets. Pools connect lazily, so commands not using them don't connect
+	cliApp.Targets = []csyncdb.Target{{Name: config.PrimaryTargetName, Db: cliApp.Db}}
+	for _, t := range conf.Targets {
+		db, err := lyspgdb.GetPool(ctx, t.Db, t.DbUser)
+		if err != nil {
+			log.Fatalf("initialization: failed to create db connection pool for target %s: %s", t.Name, err.Error())
+		}
+		cliApp.Targets = append(cliApp.Targets, csyncdb.Target{Name: t.Name, Db: db})
+	}
+
+	// the fields of API responses are recorded in the primary database
+	cliApp.Drift, err = drift.NewDetector(conf.Drift, cliApp.Db, infoLog, errorLog)
+	if err != nil {
+		log.Fatalf("initialization: invalid drift config: %s", err.Error())
+	}
+
+	// raw API responses are archived in the primary database, where sync runs are journaled as well
+	cliApp.Archive, err = archive.New(conf.Archive, cliApp.Db, infoLog, errorLog)
+	if err != nil {
+		log.Fatalf("initialization: invalid archive config: %s", err.Error())
+	}
+}
+
+// closeTargets closes the pools of the additional sync targets. The primary pool (cliApp.Db) is closed separately
+func closeTargets() {
+	for _, t := range cliApp.Targets[1:] {
+		t.Db.Close()
+	}
+}
+
+// closeReadDb closes the pool of the read-only role, if distinct from the primary pool
+func closeReadDb() {
+	if cliApp.ReadDb != cliApp.Db {
+		cliApp.ReadDb.Close()
+	}
+}
+
+// publishChanges publishes the pending row changes of each target if the change feed is enabled. Failed changes stay pending for the next publish
+func publishChanges(ctx context.Context) {
+
+	if cliApp.Changefeed == nil {
+		return
+	}
+	for _, t := range cliApp.Targets {
+		if _, err := cliApp.Changefeed.Publish(ctx, t.Name, t.Db); err != nil {
+			cliApp.ErrorLog.Error("cliApp.Changefeed.Publish failed", "target", t.Name, clog.KeyError, err.Error())
+		}
+	}
+}
+
+// closeChangefeed closes the broker connection of the change feed, if enabled
+func closeChangefeed() {
+	if cliApp.Changefeed != nil {
+		cliApp.Changefeed.Close()
+	}
+}
+
+func Execute() {
+	if err := rootCmd.Execute(); err != nil {
+		log.Fatal(err.Error())
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/loveyourstack/connectors/integrity"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/spf13/cobra"
+)
+
+var checkRefsSamples int
+
+// exit code of check-refs if any relation has orphans
+const checkRefsExitOrphans int = 2
+
+type checkRefsResult struct {
+	Relations []integrity.Result `json:"relations"`
+}
+
+var checkRefsCmd = &cobra.Command{
+	Use:   "check-refs",
+	Short: "Checks the references between connector schemas which the database doesn't enforce, e.g. of Stripe currencies to ECB currencies, and of the [[relations]] in config. Exits with 2 if any rows reference missing values.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+		defer closeReadDb()
+
+		rels := append(registry.Relations(registry.All()), cliApp.Config.Relations...)
+
+		results, err := integrity.Check(cmdContext(), cliApp.ReadDb, rels, checkRefsSamples)
+		printResult(checkRefsResult{Relations: results})
+		if err != nil {
+			cliApp.ErrorLog.Error("integrity.Check failed: " + err.Error())
+			cliApp.Db.Close()
+			closeReadDb()
+			os.Exit(1)
+		}
+
+		for _, res := range results {
+			if !res.OK() {
+				cliApp.Db.Close()
+				closeReadDb()
+				os.Exit(checkRefsExitOrphans)
+			}
+		}
+	},
+}
+
+func (res checkRefsResult) writeTable(w io.Writer) {
+	fmt.Fprintln(w, "RELATION\tCHECKED\tORPHANS\tSAMPLES")
+	for _, r := range res.Relations {
+		if r.Skipped {
+			fmt.Fprintf(w, "%s\t-\t-\tskipped: table not found\n", r.Relation)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", r.Relation, r.Checked, r.Orphans, strings.Join(r.Samples, ", "))
+	}
+}
+
+func init() {
+	checkRefsCmd.Flags().IntVar(&checkRefsSamples, "samples", integrity.DefaultSampleSize, "number of distinct missing values shown per relation")
+	rootCmd.AddCommand(checkRefsCmd)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/connectors/daemon"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/webhook"
+	"github.com/spf13/cobra"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Runs continuously: syncs the datasets of all registered connectors periodically, serves the HTTP API and fires the configured webhooks and publishes row changes to the change feed.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+		defer closeReadDb()
+		defer closeTargets()
+		defer closeChangefeed()
+
+		var emitters webhook.Emitters
+		for _, whConf := range cliApp.Config.Webhooks {
+			emitters = append(emitters, webhook.NewEmitter(whConf, cliApp.InfoLog, cliApp.ErrorLog))
+		}
+
+		// the HTTP API reads as the read-only role if configured, while syncs write to the targets as the owner
+		if cliApp.Config.HasReader() {
+			warnWritableReader()
+		}
+
+		c := ecbapi.NewClient(cliApp.InfoLog, cliApp.ErrorLog)
+		d, err := daemon.New(cliApp.Config.Daemon, cliApp.ReadDb, cliApp.Targets, registry.All(), c, emitters, cliApp.InfoLog, cliApp.ErrorLog)
+		if err != nil {
+			cliApp.ErrorLog.Error("daemon.New failed: " + err.Error())
+			os.Exit(1)
+		}
+		d.Changefeed = cliApp.Changefeed
+		d.Sinks = cliApp.Sinks
+		d.Rules = cliApp.Config.Quality
+		d.Derived = cliApp.Config.Derive
+		d.Quotas = cliApp.Config.Quotas
+		d.Drift = cliApp.Drift
+		d.Archive = cliApp.Archive
+
+		// connectors are configured anew before each cycle, so that their rotated secrets are picked up once cached ones expire
+		d.Reconfigure = func() ([]registry.Connector, error) {
+			if err := registry.ConfigureAll(cliApp.Config.ConnectorDecoder); err != nil {
+				return nil, fmt.Errorf("registry.ConfigureAll failed: %w", err)
+			}
+			return registry.All(), nil
+		}
+
+		// run until SIGINT/SIGTERM, letting an in-flight sync commit before exiting
+		rt := cruntime.New(d.DrainTimeout(), cliApp.InfoLog, cliApp.ErrorLog)
+		d.Start(rt)
+		if err = rt.Wait(); err != nil {
+			cliApp.ErrorLog.Error("rt.Wait failed: " + err.Error())
+			os.Exit(1)
+		}
+	},
+}
+
+// warnWritableReader logs a warning if the read-only role may modify tables, which defeats its purpose if the HTTP API is exposed
+func warnWritableReader() {
+
+	tables, err := migrate.WritableTables(cmdContext(), cliApp.ReadDb)
+	if err != nil {
+		cliApp.ErrorLog.Error("migrate.WritableTables failed", clog.KeyError, err.Error())
+		return
+	}
+	if len(tables) > 0 {
+		cliApp.InfoLog.Warn("the dbReader role may modify tables", "role", cliApp.Config.DbReader.Name, "tables", tables)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/converter"
+	"github.com/loveyourstack/connectors/export"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/loveyourstack/lys/lystype"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat       string
+	exportPartitioning string
+	exportOutDir       string
+	exportBaseCurr     string
+	exportFreq         string
+	exportFrom         string
+	exportTo           string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exports ECB exchange rate history to CSV, JSON Lines, Parquet, Arrow or Excel files, optionally partitioned by date.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+		defer closeReadDb()
+
+		startDate, err := time.Parse(lystype.DateFormat, exportFrom)
+		if err != nil {
+			cliApp.ErrorLog.Error("invalid --from date: " + err.Error())
+			os.Exit(1)
+		}
+		endDate := time.Now()
+		if exportTo != "" {
+			endDate, err = time.Parse(lystype.DateFormat, exportTo)
+			if err != nil {
+				cliApp.ErrorLog.Error("invalid --to date: " + err.Error())
+				os.Exit(1)
+			}
+		}
+
+		freq, err := ecbexchangerate.ParseFrequency(exportFreq)
+		if err != nil {
+			cliApp.ErrorLog.Error("invalid --freq: " + err.Error())
+			os.Exit(1)
+		}
+
+		ctx := cmdContext()
+		items, err := converter.Converter{Db: cliApp.ReadDb}.RangeRates(ctx, exportBaseCurr, freq, startDate, endDate)
+		if err != nil {
+			cliApp.ErrorLog.Error("converter.Converter.RangeRates failed: " + err.Error())
+			os.Exit(1)
+		}
+
+		filePaths, err := export.ExchangeRatesToDir(items, export.Format(exportFormat), export.Partitioning(exportPartitioning), exportOutDir)
+		if err != nil {
+			cliApp.ErrorLog.Error("export.ExchangeRatesToDir failed: " + err.Error())
+			os.Exit(1)
+		}
+
+		cliApp.InfoLog.Info("exported exchange rates", "rows", len(items), "files", len(filePaths), "dir", exportOutDir)
+		printResult(exportResult{Rows: len(items), Files: filePaths})
+	},
+}
+
+type exportResult struct {
+	Rows  int      `json:"rows"`
+	Files []string `json:"files"`
+}
+
+func (res exportResult) writeTable(w io.Writer) {
+	fmt.Fprintln(w, "FILE")
+	for _, filePath := range res.Files {
+		fmt.Fprintln(w, filePath)
+	}
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFormat, "format", export.CSV.String(), "output format: csv, jsonl, parquet, arrow (Arrow IPC file, or Feather v2), arrows (Arrow IPC stream) or xlsx (Excel workbook with a sheet per currency pair)")
+	exportCmd.Flags().StringVar(&exportPartitioning, "partition", export.PartitionNone.String(), "split files by date: none, year, month or day")
+	exportCmd.Flags().StringVar(&exportOutDir, "out", ".", "output directory")
+	exportCmd.Flags().StringVar(&exportBaseCurr, "base", "EUR", "base currency code. Rates from another base than EUR are derived from the stored EUR rates")
+	exportCmd.Flags().StringVar(&exportFreq, "freq", ecbapi.Daily.String(), "frequency: D or M")
+	exportCmd.Flags().StringVar(&exportFrom, "from", "1999-01-01", "start date (YYYY-MM-DD)")
+	exportCmd.Flags().StringVar(&exportTo, "to", "", "end date (YYYY-MM-DD), defaults to today")
+	exportCmd.RegisterFlagCompletionFunc("format", fixedCompletion(export.CSV.String(), export.JSONL.String(), export.Parquet.String(), export.Arrow.String(), export.ArrowStream.String(), export.XLSX.String()))
+	exportCmd.RegisterFlagCompletionFunc("partition", fixedCompletion(export.PartitionNone.String(), export.PartitionYear.String(), export.PartitionMonth.String(), export.PartitionDay.String()))
+	exportCmd.RegisterFlagCompletionFunc("freq", fixedCompletion(ecbapi.Daily.String(), ecbapi.Monthly.String()))
+	exportCmd.MarkFlagDirname("out")
+	rootCmd.AddCommand(exportCmd)
+}
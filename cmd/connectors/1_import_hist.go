@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importHistFile string
+	importHistSwap bool
+)
+
+var importHistCmd = &cobra.Command{
+	Use:   "import-hist",
+	Short: "Imports the complete ECB daily exchange rate history from eurofxref-hist.zip (downloaded, or read from --file). Currencies must be synced first.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+		defer closeChangefeed()
+
+		c := ecbapi.NewClient(cliApp.InfoLog, cliApp.ErrorLog)
+
+		// get zip content from file or from ECB
+		var zipContent []byte
+		var err error
+		if importHistFile != "" {
+			zipContent, err = os.ReadFile(importHistFile)
+			if err != nil {
+				cliApp.ErrorLog.Error("os.ReadFile failed: " + err.Error())
+				os.Exit(1)
+			}
+		} else {
+			cliApp.InfoLog.Info("downloading eurofxref-hist.zip")
+			zipContent, err = c.DownloadHistZip()
+			if err != nil {
+				cliApp.ErrorLog.Error("c.DownloadHistZip failed: " + err.Error())
+				os.Exit(1)
+			}
+		}
+
+		ctx := cmdContext()
+
+		if importHistSwap {
+			parsed, inserted, updated, deleted, err := csyncdb.EcbExchangeRatesHistSwap(ctx, cliApp.Db, c, zipContent)
+			if err != nil {
+				cliApp.ErrorLog.Error("csyncdb.EcbExchangeRatesHistSwap failed: " + err.Error())
+				os.Exit(1)
+			}
+
+			cliApp.InfoLog.Info("import-hist completed", "parsed", parsed, "inserted", inserted, "updated", updated, "deleted", deleted)
+			printResult(importHistResult{Parsed: parsed, Inserted: inserted, Updated: updated, Deleted: deleted, Unchanged: parsed - inserted - updated})
+			return
+		}
+
+		parsed, inserted, updated, err := csyncdb.EcbExchangeRatesHist(ctx, cliApp.Db, c, zipContent)
+		if err != nil {
+			cliApp.ErrorLog.Error("csyncdb.EcbExchangeRatesHist failed: " + err.Error())
+			os.Exit(1)
+		}
+		publishChanges(ctx)
+
+		cliApp.InfoLog.Info("import-hist completed", "parsed", parsed, "inserted", inserted, "updated", updated, "unchanged", parsed-inserted-updated)
+		printResult(importHistResult{Parsed: parsed, Inserted: inserted, Updated: updated, Unchanged: parsed - inserted - updated})
+	},
+}
+
+type importHistResult struct {
+	Parsed    int64 `json:"parsed"`
+	Inserted  int64 `json:"inserted"`
+	Updated   int64 `json:"updated"`
+	Deleted   int64 `json:"deleted"` // only with --swap
+	Unchanged int64 `json:"unchanged"`
+}
+
+func (res importHistResult) writeTable(w io.Writer) {
+	fmt.Fprintln(w, "PARSED\tINSERTED\tUPDATED\tDELETED\tUNCHANGED")
+	fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%d\n", res.Parsed, res.Inserted, res.Updated, res.Deleted, res.Unchanged)
+}
+
+func init() {
+	importHistCmd.Flags().StringVar(&importHistFile, "file", "", "path to a local eurofxref-hist.zip (downloads from the ECB if not supplied)")
+	importHistCmd.MarkFlagFilename("file", "zip")
+	importHistCmd.Flags().BoolVar(&importHistSwap, "swap", false, "replace the stored daily rates with the history by loading it into a new table and swapping it with the live one, deleting rates missing from the history")
+	rootCmd.AddCommand(importHistCmd)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	initYears int
+	initFreq  string
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Bootstraps the database and any additional targets: runs migrations, syncs currencies and backfills exchange rates for the last --years years.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+		defer closeTargets()
+		defer closeChangefeed()
+
+		if initYears < 0 {
+			cliApp.ErrorLog.Error("--years must not be negative")
+			os.Exit(1)
+		}
+
+		freq, err := ecbexchangerate.ParseFrequency(initFreq)
+		if err != nil {
+			cliApp.ErrorLog.Error("invalid --freq: " + err.Error())
+			os.Exit(1)
+		}
+
+		ctx := cmdContext()
+		c := ecbapi.NewClient(cliApp.InfoLog, cliApp.ErrorLog)
+
+		// 1. migrations
+		cliApp.InfoLog.Info("[1/3] running migrations")
+		var res initResult
+		res.Migrations, err = migrateTargets(ctx)
+		if err != nil {
+			cliApp.ErrorLog.Error(err.Error())
+			os.Exit(1)
+		}
+
+		// 2. currencies
+		cliApp.InfoLog.Info("[2/3] syncing currencies")
+		syncRes, err := syncEcbCurrencies(ctx, c)
+		res.Syncs = append(res.Syncs, syncRes)
+		if err != nil {
+			printResult(res)
+			cliApp.ErrorLog.Error(err.Error())
+			os.Exit(1)
+		}
+
+		// 3. rates: one year per API call to keep responses manageable
+		cliApp.InfoLog.Info("[3/3] backfilling exchange rates", "years", initYears)
+		now := time.Now()
+		for i := initYears; i > 0; i-- {
+
+			startDate := now.AddDate(-i, 0, 0)
+			endDate := now.AddDate(-i+1, 0, -1)
+			if i == 1 {
+				endDate = now
+			}
+
+			cliApp.InfoLog.Info(fmt.Sprintf("backfilling year %d/%d", initYears-i+1, initYears),
+				"from", startDate.Format("2006-01-02"), "to", endDate.Format("2006-01-02"))
+
+			syncRes, err := syncEcbExchangeRates(ctx, c, freq, startDate, endDate)
+			res.Syncs = append(res.Syncs, syncRes)
+			if err != nil {
+				printResult(res)
+				cliApp.ErrorLog.Error(err.Error())
+				os.Exit(1)
+			}
+		}
+
+		publishChanges(ctx)
+
+		cliApp.InfoLog.Info("init completed")
+		printResult(res)
+	},
+}
+
+type initResult struct {
+	Migrations migrateResult `json:"migrations"`
+	Syncs      []syncResult  `json:"syncs"`
+}
+
+func (res initResult) writeTable(w io.Writer) {
+
+	res.Migrations.writeTable(w)
+	fmt.Fprintln(w)
+
+	writeSyncsTable(w, res.Syncs)
+}
+
+func init() {
+	initCmd.Flags().IntVar(&initYears, "years", 5, "number of years of exchange rates to backfill (0 to skip)")
+	initCmd.Flags().StringVar(&initFreq, "freq", ecbapi.Daily.String(), "frequency: D or M")
+	initCmd.RegisterFlagCompletionFunc("freq", fixedCompletion(ecbapi.Daily.String(), ecbapi.Monthly.String()))
+	rootCmd.AddCommand(initCmd)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the registered connectors and their datasets.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+
+		var res listResult
+		for _, conn := range registry.All() {
+			res.Connectors = append(res.Connectors, listConnector{Name: conn.Name(), Enabled: registry.IsEnabled(conn), Datasets: conn.Datasets()})
+		}
+
+		printResult(res)
+	},
+}
+
+type listConnector struct {
+	Name     string             `json:"name"`
+	Enabled  bool               `json:"enabled"`
+	Datasets []registry.Dataset `json:"datasets"`
+}
+
+type listResult struct {
+	Connectors []listConnector `json:"connectors"`
+}
+
+func (res listResult) writeTable(w io.Writer) {
+
+	fmt.Fprintln(w, "CONNECTOR\tENABLED\tDATASET\tDESCRIPTION")
+	for _, conn := range res.Connectors {
+		for _, ds := range conn.Datasets {
+			fmt.Fprintf(w, "%s\t%t\t%s\t%s\n", conn.Name, conn.Enabled, ds.Name, ds.Description)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/connectors/change"
+	"github.com/loveyourstack/connectors/stores/connectors/outbox"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Applies all pending embedded schema migrations of the registered connectors to the database and any additional targets, sets the tables whose changes are captured for the change feed and outbox, grants the read-only role read access, and creates the ClickHouse tick table if configured.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+		defer closeTargets()
+
+		ctx := cmdContext()
+		res, err := migrateTargets(ctx)
+		if err != nil {
+			cliApp.ErrorLog.Error(err.Error())
+			os.Exit(1)
+		}
+
+		printResult(res)
+	},
+}
+
+type migrateTargetResult struct {
+	Target    string `json:"target"`
+	Applied   int    `json:"applied"`
+	Contracts int    `json:"contracts"` // data contract versions published
+}
+
+type migrateResult struct {
+	Targets []migrateTargetResult `json:"targets"`
+}
+
+func (res migrateResult) writeTable(w io.Writer) {
+
+	fmt.Fprintln(w, "TARGET\tMIGRATIONS APPLIED\tCONTRACTS PUBLISHED")
+	for _, tRes := range res.Targets {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", tRes.Target, tRes.Applied, tRes.Contracts)
+	}
+}
+
+// migrateTargets applies pending migrations to all targets, stopping at the first failure
+// it then captures the changes of the change feed tables for the broker and the outbox, if configured. Capture is removed from tables no longer configured
+// the data contracts of the datasets which changed since the last migration are published to each target
+// the read-only role, if configured, is granted read access to the schemas of the primary database
+// finally, the tables of the configured sinks are created
+func migrateTargets(ctx context.Context) (res migrateResult, err error) {
+
+	var capturedTables, outboxTables []string
+	if cliApp.Changefeed != nil {
+		if cliApp.Changefeed.Config.Broker != "" {
+			capturedTables = cliApp.Changefeed.Config.Tables
+		}
+		if cliApp.Changefeed.Config.Outbox {
+			outboxTables = cliApp.Changefeed.Config.Tables
+		}
+	}
+
+	for _, t := range cliApp.Targets {
+		numApplied, err := registry.MigrateAll(ctx, t.Db, cliApp.InfoLog)
+		if err != nil {
+			return res, fmt.Errorf("registry.MigrateAll failed for target %s: %w", t.Name, err)
+		}
+		cliApp.InfoLog.Info("migrations completed", "target", t.Name, "applied", numApplied)
+
+		added, removed, err := change.Store{Db: t.Db}.SetCapturedTables(ctx, capturedTables)
+		if err != nil {
+			return res, fmt.Errorf("change.Store.SetCapturedTables failed for target %s: %w", t.Name, err)
+		}
+		if len(added) > 0 || len(removed) > 0 {
+			cliApp.InfoLog.Info("change capture updated", "target", t.Name, "added", added, "removed", removed)
+		}
+
+		added, removed, err = outbox.Store{Db: t.Db}.SetCapturedTables(ctx, outboxTables)
+		if err != nil {
+			return res, fmt.Errorf("outbox.Store.SetCapturedTables failed for target %s: %w", t.Name, err)
+		}
+		if len(added) > 0 || len(removed) > 0 {
+			cliApp.InfoLog.Info("outbox capture updated", "target", t.Name, "added", added, "removed", removed)
+		}
+
+		numPublished, err := registry.PublishContracts(ctx, t.Db, registry.All(), cliApp.InfoLog)
+		if err != nil {
+			return res, fmt.Errorf("registry.PublishContracts failed for target %s: %w", t.Name, err)
+		}
+		res.Targets = append(res.Targets, migrateTargetResult{Target: t.Name, Applied: numApplied, Contracts: numPublished})
+	}
+
+	if cliApp.Config.HasReader() {
+		schemas, err := migrate.GrantRead(ctx, cliApp.Db, cliApp.Config.DbReader.Name)
+		if err != nil {
+			return res, fmt.Errorf("migrate.GrantRead failed: %w", err)
+		}
+		cliApp.InfoLog.Info("read access granted", "role", cliApp.Config.DbReader.Name, "schemas", schemas)
+	}
+
+	for _, s := range cliApp.Sinks.Sinks() {
+		if err = s.Migrate(ctx); err != nil {
+			return res, fmt.Errorf("s.Migrate failed for sink %s: %w", s.Name(), err)
+		}
+		cliApp.InfoLog.Info("sink migrated", "sink", s.Name())
+	}
+
+	return res, nil
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+}
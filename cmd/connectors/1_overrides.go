@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/stores/ecb/ecbrateoverride"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+	"github.com/spf13/cobra"
+)
+
+var (
+	overridesId       int64
+	overridesDay      string
+	overridesCurrency string
+	overridesRate     float64
+	overridesReason   string
+	overridesBy       string
+)
+
+var overridesCmd = &cobra.Command{
+	Use:   "overrides",
+	Short: "Enters, approves, revokes and lists manual corrections of ECB daily rates, which converters configured with them use ahead of the synced rates.",
+	// no Run function: a subcommand is always needed
+}
+
+var overridesAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Enters a correction of the EUR rate of --currency on --day with --reason. It is pending until approved by someone else.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+
+		day, err := time.Parse(lystype.DateFormat, overridesDay)
+		if err != nil {
+			cliApp.ErrorLog.Error("invalid --day date: " + err.Error())
+			os.Exit(1)
+		}
+
+		input := ecbrateoverride.Input{
+			Day:        lystype.Date(day),
+			EnteredBy:  overridesBy,
+			Rate:       overridesRate,
+			Reason:     overridesReason,
+			ToCurrency: strings.ToUpper(overridesCurrency),
+		}
+		store := ecbrateoverride.Store{Db: cliApp.Db}
+		if err = store.Validate(cliApp.Validate, input); err != nil {
+			cliApp.ErrorLog.Error("invalid correction, --day, --currency, --rate, --reason and --by are required: " + err.Error())
+			os.Exit(1)
+		}
+
+		ctx := cmdContext()
+		id, err := store.Insert(ctx, input)
+		if err != nil {
+			cliApp.ErrorLog.Error("ecbrateoverride.Store.Insert failed: " + err.Error())
+			os.Exit(1)
+		}
+		printOverride(ctx, id)
+	},
+}
+
+var overridesApproveCmd = &cobra.Command{
+	Use:   "approve",
+	Short: "Approves the pending correction --id by --by, who must not have entered it. It is used from now on, superseding any approved correction of the same rate.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+
+		ctx := cmdContext()
+		if err := (ecbrateoverride.Store{Db: cliApp.Db}).Approve(ctx, overridesId, overridesBy); err != nil {
+			cliApp.ErrorLog.Error("ecbrateoverride.Store.Approve failed: " + err.Error())
+			os.Exit(1)
+		}
+		printOverride(ctx, overridesId)
+	},
+}
+
+var overridesRevokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "Revokes the correction --id by --by with --reason, so that it is no longer used.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+
+		ctx := cmdContext()
+		if err := (ecbrateoverride.Store{Db: cliApp.Db}).Revoke(ctx, overridesId, overridesBy, overridesReason); err != nil {
+			cliApp.ErrorLog.Error("ecbrateoverride.Store.Revoke failed: " + err.Error())
+			os.Exit(1)
+		}
+		printOverride(ctx, overridesId)
+	},
+}
+
+var overridesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the corrections, latest day first, optionally only those of --day or --currency.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+		defer closeReadDb()
+
+		params := lyspg.SelectParams{}
+		if overridesDay != "" {
+			if _, err := time.Parse(lystype.DateFormat, overridesDay); err != nil {
+				cliApp.ErrorLog.Error("invalid --day date: " + err.Error())
+				os.Exit(1)
+			}
+			params.Conditions = append(params.Conditions, lyspg.Condition{Field: "day", Operator: lyspg.OpEquals, Value: overridesDay})
+		}
+		if overridesCurrency != "" {
+			params.Conditions = append(params.Conditions, lyspg.Condition{Field: "to_currency", Operator: lyspg.OpEquals, Value: strings.ToUpper(overridesCurrency)})
+		}
+
+		items, _, err := ecbrateoverride.Store{Db: cliApp.ReadDb}.Select(cmdContext(), params)
+		if err != nil {
+			cliApp.ErrorLog.Error("ecbrateoverride.Store.Select failed: " + err.Error())
+			os.Exit(1)
+		}
+		printResult(newOverridesResult(items...))
+	},
+}
+
+var overridesAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Shows the audit trail of the correction --id: who entered, approved and revoked it, when, and as which database role.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+		defer closeReadDb()
+
+		entries, err := ecbrateoverride.Store{Db: cliApp.ReadDb}.SelectAudit(cmdContext(), overridesId)
+		if err != nil {
+			cliApp.ErrorLog.Error("ecbrateoverride.Store.SelectAudit failed: " + err.Error())
+			os.Exit(1)
+		}
+		if len(entries) == 0 {
+			cliApp.ErrorLog.Error(fmt.Sprintf("override %d not found", overridesId))
+			os.Exit(1)
+		}
+		printResult(overridesAuditResult{Entries: entries})
+	},
+}
+
+type overrideRow struct {
+	ecbrateoverride.Model
+	Status ecbrateoverride.Status `json:"status"`
+}
+
+type overridesResult struct {
+	Overrides []overrideRow `json:"overrides"`
+}
+
+func newOverridesResult(items ...ecbrateoverride.Model) overridesResult {
+
+	res := overridesResult{Overrides: make([]overrideRow, len(items))}
+	for i, item := range items {
+		res.Overrides[i] = overrideRow{Model: item, Status: item.Status()}
+	}
+	return res
+}
+
+func (res overridesResult) writeTable(w io.Writer) {
+	fmt.Fprintln(w, "ID\tDAY\tCURRENCY\tRATE\tSTATUS\tENTERED BY\tAPPROVED BY\tREASON")
+	for _, o := range res.Overrides {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%.4f\t%s\t%s\t%s\t%s\n", o.Id, time.Time(o.Day).Format(lystype.DateFormat), o.ToCurrency, o.Rate, o.Status, o.EnteredBy, o.ApprovedBy, o.Reason)
+	}
+}
+
+type overridesAuditResult struct {
+	Entries []ecbrateoverride.AuditEntry `json:"entries"`
+}
+
+func (res overridesAuditResult) writeTable(w io.Writer) {
+	fmt.Fprintln(w, "ID\tACTION\tACTOR\tDB USER\tCHANGED AT")
+	for _, e := range res.Entries {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", e.Id, e.Action, e.Actor, e.DbUser, time.Time(e.ChangedAt).Format(time.RFC3339))
+	}
+}
+
+// printOverride prints the override id after a change
+func printOverride(ctx context.Context, id int64) {
+
+	item, err := ecbrateoverride.Store{Db: cliApp.Db}.SelectById(ctx, nil, id)
+	if err != nil {
+		cliApp.ErrorLog.Error("ecbrateoverride.Store.SelectById failed: " + err.Error())
+		os.Exit(1)
+	}
+	printResult(newOverridesResult(item))
+}
+
+func init() {
+	overridesAddCmd.Flags().StringVar(&overridesDay, "day", "", "day of the fixing corrected, e.g. 2024-03-28")
+	overridesAddCmd.Flags().StringVar(&overridesCurrency, "currency", "", "currency whose rate from EUR is corrected, e.g. USD")
+	overridesAddCmd.Flags().Float64Var(&overridesRate, "rate", 0, "corrected rate from EUR, at most 8 decimals")
+	overridesAddCmd.Flags().StringVar(&overridesReason, "reason", "", "why the rate is corrected, e.g. the reference of the authority's notice")
+	overridesAddCmd.Flags().StringVar(&overridesBy, "by", "", "who enters the correction")
+
+	overridesApproveCmd.Flags().Int64Var(&overridesId, "id", 0, "id of the correction")
+	overridesApproveCmd.Flags().StringVar(&overridesBy, "by", "", "who approves the correction")
+
+	overridesRevokeCmd.Flags().Int64Var(&overridesId, "id", 0, "id of the correction")
+	overridesRevokeCmd.Flags().StringVar(&overridesBy, "by", "", "who revokes the correction")
+	overridesRevokeCmd.Flags().StringVar(&overridesReason, "reason", "", "why the correction is revoked")
+
+	overridesListCmd.Flags().StringVar(&overridesDay, "day", "", "only the corrections of this day, e.g. 2024-03-28")
+	overridesListCmd.Flags().StringVar(&overridesCurrency, "currency", "", "only the corrections of this currency")
+
+	overridesAuditCmd.Flags().Int64Var(&overridesId, "id", 0, "id of the correction")
+
+	overridesCmd.AddCommand(overridesAddCmd, overridesApproveCmd, overridesRevokeCmd, overridesListCmd, overridesAuditCmd)
+	rootCmd.AddCommand(overridesCmd)
+}
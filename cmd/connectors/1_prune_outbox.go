@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var pruneOutboxCmd = &cobra.Command{
+	Use:   "prune-outbox",
+	Short: "Deletes the rows of the connectors.outbox table older than the change feed's outboxRetentionDays from the database and any additional targets. The daemon does this every sync cycle.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+		defer closeTargets()
+		defer closeChangefeed()
+
+		if cliApp.Changefeed == nil || !cliApp.Changefeed.Config.Outbox {
+			cliApp.ErrorLog.Error("the outbox is not enabled: set outbox = true in [changefeed]")
+			os.Exit(1)
+		}
+
+		ctx := cmdContext()
+		res, err := pruneOutbox(ctx)
+		printResult(res)
+		if err != nil {
+			cliApp.ErrorLog.Error(err.Error())
+			os.Exit(1)
+		}
+	},
+}
+
+type pruneOutboxTargetResult struct {
+	Target string `json:"target"`
+	Pruned int64  `json:"pruned"`
+}
+
+type pruneOutboxResult struct {
+	Targets []pruneOutboxTargetResult `json:"targets"`
+}
+
+func (res pruneOutboxResult) writeTable(w io.Writer) {
+
+	fmt.Fprintln(w, "TARGET\tROWS PRUNED")
+	for _, tRes := range res.Targets {
+		fmt.Fprintf(w, "%s\t%d\n", tRes.Target, tRes.Pruned)
+	}
+}
+
+// pruneOutbox prunes the outbox of all targets, stopping at the first failure
+func pruneOutbox(ctx context.Context) (res pruneOutboxResult, err error) {
+
+	for _, t := range cliApp.Targets {
+		numPruned, err := cliApp.Changefeed.PruneOutbox(ctx, t.Name, t.Db)
+		if err != nil {
+			return res, fmt.Errorf("cliApp.Changefeed.PruneOutbox failed for target %s: %w", t.Name, err)
+		}
+		res.Targets = append(res.Targets, pruneOutboxTargetResult{Target: t.Name, Pruned: numPruned})
+	}
+
+	return res, nil
+}
+
+func init() {
+	rootCmd.AddCommand(pruneOutboxCmd)
+}
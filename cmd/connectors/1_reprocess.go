@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/loveyourstack/connectors/archive"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/connectors/rawpayload"
+	"github.com/loveyourstack/connectors/stores/connectors/syncrun"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reprocessRun  int64
+	reprocessDays int
+)
+
+var reprocessCmd = &cobra.Command{
+	Use:   "reprocess",
+	Short: "Re-parses the archived raw payloads of a sync run with the current parser and syncs the result into the database and any additional targets, without calling the provider.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+		defer closeTargets()
+		defer closeChangefeed()
+
+		if reprocessRun < 1 {
+			cliApp.ErrorLog.Error("--run is required")
+			os.Exit(1)
+		}
+		if reprocessDays < 1 {
+			cliApp.ErrorLog.Error("--days must be at least 1")
+			os.Exit(1)
+		}
+
+		ctx := cmdContext()
+		res, err := reprocessRunPayloads(ctx, reprocessRun, reprocessDays)
+		publishChanges(ctx)
+		printResult(res)
+		if err != nil {
+			cliApp.ErrorLog.Error(err.Error())
+			os.Exit(1)
+		}
+	},
+}
+
+type reprocessResult struct {
+	Run      int64      `json:"run"`
+	Payloads int        `json:"payloads"`
+	Served   int        `json:"served"` // payloads requested by the sync. Those not requested were not reprocessed
+	Sync     syncResult `json:"sync"`
+}
+
+func (res reprocessResult) writeTable(w io.Writer) {
+
+	fmt.Fprintln(w, "RUN\tPAYLOADS\tSERVED")
+	fmt.Fprintf(w, "%d\t%d\t%d\n", res.Run, res.Payloads, res.Served)
+	fmt.Fprintln(w)
+	res.Sync.writeTable(w)
+}
+
+// reprocessRunPayloads syncs the dataset of run runId of the primary database into all targets, serving the requests of the connector from the payloads archived for the run
+// days sets the window of time series datasets, as for sync all: requests whose window no longer matches the archived URL are served the payload of the same path whose params match most
+func reprocessRunPayloads(ctx context.Context, runId int64, days int) (res reprocessResult, err error) {
+
+	res.Run = runId
+
+	run, err := syncrun.Store{Db: cliApp.Db}.SelectById(ctx, nil, runId)
+	if err != nil {
+		if errors.Is(cerrors.FromPg(err), cerrors.ErrNotFound) {
+			return res, fmt.Errorf("run %d not found", runId)
+		}
+		return res, fmt.Errorf("syncrun.Store.SelectById failed: %w", err)
+	}
+
+	payloads, err := rawpayload.Store{Db: cliApp.Db}.SelectByRun(ctx, runId)
+	if err != nil {
+		return res, fmt.Errorf("rawpayload.Store.SelectByRun failed: %w", err)
+	}
+	if len(payloads) == 0 {
+		return res, fmt.Errorf("no archived payloads for run %d: archive the responses of its connector in [archive]", runId)
+	}
+	res.Payloads = len(payloads)
+
+	conn, ok := datasetConnector(run.Dataset)
+	if !ok {
+		return res, fmt.Errorf("no registered connector syncs dataset %s", run.Dataset)
+	}
+
+	replay, err := archive.NewReplay(payloads)
+	if err != nil {
+		return res, fmt.Errorf("archive.NewReplay failed: %w", err)
+	}
+
+	deps := registry.Deps{
+		Targets:  cliApp.Targets,
+		Datasets: []string{run.Dataset},
+		Days:     days,
+		Sinks:    cliApp.Sinks,
+		Rules:    cliApp.Config.Quality,
+		Derived:  cliApp.Config.Derive,
+		Replay:   replay,
+		InfoLog:  cliApp.InfoLog,
+		ErrorLog: cliApp.ErrorLog,
+	}
+	syncErr := conn.Sync(ctx, deps)
+	res.Sync = newSyncResult(run.Dataset, "", syncErr)
+
+	served, unused := replay.Counts()
+	res.Served = served
+	if unused > 0 {
+		cliApp.InfoLog.Warn("archived payloads were not requested by the sync", "run", runId, clog.KeyCount, unused)
+	}
+
+	if syncErr != nil {
+		return res, fmt.Errorf("conn.Sync failed: %w", syncErr)
+	}
+
+	return res, nil
+}
+
+// datasetConnector returns the registered connector syncing dataset
+func datasetConnector(dataset string) (registry.Connector, bool) {
+
+	for _, conn := range registry.All() {
+		if slices.ContainsFunc(conn.Datasets(), func(ds registry.Dataset) bool { return ds.Name == dataset }) {
+			return conn, true
+		}
+	}
+	return nil, false
+}
+
+func init() {
+	reprocessCmd.Flags().Int64Var(&reprocessRun, "run", 0, "id of the sync run in connectors.sync_run of the primary database")
+	reprocessCmd.Flags().IntVar(&reprocessDays, "days", 7, "number of days of time series data to sync, as for sync all")
+	rootCmd.AddCommand(reprocessCmd)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/loveyourstack/connectors/scaffold"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scaffoldDir   string
+	scaffoldForce bool
+)
+
+var scaffoldCmd = &cobra.Command{
+	Use:   "scaffold <provider> <entity>",
+	Short: "Generates the API client, store, migration, sync funcs, connector and tests of a new connector syncing entity (singular, snake case, e.g. sales_order) from provider, below the module root --dir. Needs no config or db.",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		module, err := scaffold.ModulePath(scaffoldDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "scaffold.ModulePath failed: "+err.Error())
+			os.Exit(1)
+		}
+
+		n, err := scaffold.NewNames(module, args[0], args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "scaffold.NewNames failed: "+err.Error())
+			os.Exit(1)
+		}
+
+		paths, err := scaffold.Generate(scaffoldDir, n, scaffoldForce)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "scaffold.Generate failed: "+err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println("generated:")
+		for _, p := range paths {
+			fmt.Println("  " + p)
+		}
+		fmt.Printf(`next steps:
+  1. fill in the TODOs: the API endpoint, auth and response of the %[1]s, and the columns of the %[2]s table
+  2. move %[3]s to the dataset names in csyncdb/journal.go
+  3. add a blank import of %[4]s/registry/%[5]s to cmd/connectors/main.go and examples/nightly-sync/main.go
+  4. document the connector in README.md and its [connectors.%[6]s] table in the sample config
+  5. run: go test ./apiclients/%[7]s/ ./registry/%[5]s/
+`, n.EntitiesLabel, n.Dataset, n.DatasetConst, n.Module, n.ConnectorPkg, n.Provider, n.ApiPkg)
+	},
+}
+
+func init() {
+	scaffoldCmd.Flags().StringVar(&scaffoldDir, "dir", ".", "root of the Go module to generate into, containing its go.mod")
+	scaffoldCmd.Flags().BoolVar(&scaffoldForce, "force", false, "overwrite existing files")
+
+	rootCmd.AddCommand(scaffoldCmd)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/registry/ecbconnector"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	simulateSchema    string
+	simulateReuse     bool
+	simulateDays      int
+	simulateFreq      string
+	simulateFallbacks []string
+)
+
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Runs the ECB exchange rate sync of the last --days days against a snapshot of the stored rates in --schema, leaving the live table untouched, and reports the rates it would insert, update and delete.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+
+		if simulateDays < 1 {
+			cliApp.ErrorLog.Error("--days must be at least 1")
+			os.Exit(1)
+		}
+
+		fallbacks, err := ecbconnector.NewFallbacks(simulateFallbacks, cliApp.InfoLog, cliApp.ErrorLog)
+		if err != nil {
+			cliApp.ErrorLog.Error(err.Error())
+			os.Exit(1)
+		}
+
+		freq, err := ecbexchangerate.ParseFrequency(simulateFreq)
+		if err != nil {
+			cliApp.ErrorLog.Error("invalid --freq: " + err.Error())
+			os.Exit(1)
+		}
+
+		ctx := cmdContext()
+		c := ecbapi.NewClient(cliApp.InfoLog, cliApp.ErrorLog)
+		endDate := time.Now()
+		startDate := endDate.AddDate(0, 0, -simulateDays)
+
+		sim, err := csyncdb.SimulateEcbExchangeRates(ctx, cliApp.Db, c, simulateSchema, !simulateReuse, "EUR", freq, startDate, endDate,
+			cliApp.Config.Quality.For(csyncdb.DatasetEcbExchangeRates), fallbacks...)
+		if err != nil {
+			cliApp.ErrorLog.Error("csyncdb.SimulateEcbExchangeRates failed: " + err.Error())
+			os.Exit(1)
+		}
+
+		cliApp.InfoLog.Info("simulate completed", "schema", sim.Schema, "fetched", sim.Fetched, "new", sim.New, "updated", sim.Updated, "deleted", sim.Deleted)
+		printResult(simulateResult{Schema: sim.Schema, Snapshot: sim.Snapshot, Fetched: sim.Fetched, New: sim.New, Updated: sim.Updated, Deleted: sim.Deleted})
+	},
+}
+
+type simulateResult struct {
+	Schema   string `json:"schema"`
+	Snapshot int64  `json:"snapshot"`
+	Fetched  int    `json:"fetched"`
+	New      int    `json:"new"`
+	Updated  int    `json:"updated"`
+	Deleted  int    `json:"deleted"`
+}
+
+func (res simulateResult) writeTable(w io.Writer) {
+	fmt.Fprintln(w, "SCHEMA\tSNAPSHOT\tFETCHED\tNEW\tUPDATED\tDELETED")
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\n", res.Schema, res.Snapshot, res.Fetched, res.New, res.Updated, res.Deleted)
+}
+
+func init() {
+	simulateCmd.Flags().StringVar(&simulateSchema, "schema", "ecb_shadow", "schema of the snapshot the sync is run against")
+	simulateCmd.Flags().BoolVar(&simulateReuse, "reuse", false, "run against the existing snapshot, including the changes of previous simulations, rather than taking a new one")
+	simulateCmd.Flags().IntVar(&simulateDays, "days", 7, "number of days to sync, counting back from today")
+	simulateCmd.Flags().StringVar(&simulateFreq, "freq", ecbapi.Daily.String(), "frequency: D or M")
+	simulateCmd.Flags().StringSliceVar(&simulateFallbacks, "fallback", nil, "source to fetch the rates from if the ECB is unavailable (repeatable): frankfurter")
+	simulateCmd.RegisterFlagCompletionFunc("freq", fixedCompletion(ecbapi.Daily.String(), ecbapi.Monthly.String()))
+	simulateCmd.RegisterFlagCompletionFunc("fallback", fixedCompletion(ecbconnector.FallbackFrankfurter))
+	rootCmd.AddCommand(simulateCmd)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/freshness"
+	"github.com/loveyourstack/connectors/quota"
+	"github.com/loveyourstack/connectors/stores/connectors/syncrun"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbcurrency"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/loveyourstack/lys/lystype"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statusMaxLagDaily   int
+	statusMaxLagMonthly int
+	statusFailOnStale   bool
+)
+
+// exit code of status --fail-on-stale if any dataset is stale. Empty datasets are not considered, since not every dataset needs to be synced
+const statusExitStale int = 2
+
+// datasetStatus is one line of the status command output
+type datasetStatus struct {
+	Dataset     string                `json:"dataset"`
+	Rows        int64                 `json:"rows"`
+	EarliestDay *lystype.Date         `json:"earliest_day"`          // nil if not applicable or no data
+	LatestDay   *lystype.Date         `json:"latest_day"`            // nil if not applicable or no data
+	TableBytes  int64                 `json:"table_bytes,omitempty"` // size of the table, shared by the lines of a dataset. 0 if not applicable
+	Freshness   *freshness.Assessment `json:"freshness,omitempty"`   // nil if not applicable
+	LastRun     *syncrun.Model        `json:"last_run"`              // nil if never synced
+}
+
+// quotaStatus is the request usage of a connector with a quota in its current window
+type quotaStatus struct {
+	Connector   string    `json:"connector"`
+	WindowStart time.Time `json:"window_start"`
+	Used        int64     `json:"used"`
+	Quota       int64     `json:"quota"`
+}
+
+type statusResult struct {
+	Datasets []datasetStatus `json:"datasets"`
+	Quotas   []quotaStatus   `json:"quotas,omitempty"`
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Prints, per dataset, the row count, earliest and latest observation date, table size, last sync run outcome and freshness, and the request usage of connectors with a quota.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+		defer closeReadDb()
+
+		ctx := cmdContext()
+		statuses, err := getDatasetStatuses(ctx, time.Now())
+		if err != nil {
+			cliApp.ErrorLog.Error("getDatasetStatuses failed: " + err.Error())
+			os.Exit(1)
+		}
+
+		quotas, err := getQuotaStatuses(ctx, time.Now())
+		if err != nil {
+			cliApp.ErrorLog.Error("getQuotaStatuses failed: " + err.Error())
+			os.Exit(1)
+		}
+
+		printResult(statusResult{Datasets: statuses, Quotas: quotas})
+
+		if statusFailOnStale {
+			for _, st := range statuses {
+				if st.Freshness != nil && st.Freshness.Status == freshness.Stale {
+					cliApp.Db.Close()
+					closeReadDb()
+					os.Exit(statusExitStale)
+				}
+			}
+		}
+	},
+}
+
+func getDatasetStatuses(ctx context.Context, now time.Time) (statuses []datasetStatus, err error) {
+
+	runStore := syncrun.Store{Db: cliApp.ReadDb}
+	currStore := ecbcurrency.Store{Db: cliApp.ReadDb}
+	xrStore := ecbexchangerate.Store{Db: cliApp.ReadDb}
+
+	// currencies
+	currStatus := datasetStatus{Dataset: csyncdb.DatasetEcbCurrencies}
+	currStatus.Rows, err = currStore.Count(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("currStore.Count failed: %w", err)
+	}
+	currStatus.LastRun, err = selectLatestRun(ctx, runStore, csyncdb.DatasetEcbCurrencies)
+	if err != nil {
+		return nil, fmt.Errorf("selectLatestRun failed: %w", err)
+	}
+	statuses = append(statuses, currStatus)
+
+	// exchange rates: one line per frequency, sharing the journal entry of the dataset
+	xrLastRun, err := selectLatestRun(ctx, runStore, csyncdb.DatasetEcbExchangeRates)
+	if err != nil {
+		return nil, fmt.Errorf("selectLatestRun failed: %w", err)
+	}
+
+	xrStats, err := xrStore.Stats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("xrStore.Stats failed: %w", err)
+	}
+
+	for _, freq := range []ecbexchangerate.Frequency{ecbexchangerate.Daily, ecbexchangerate.Monthly} {
+
+		xrStatus := datasetStatus{Dataset: csyncdb.DatasetEcbExchangeRates + " (" + freq.String() + ")", TableBytes: xrStats.TableBytes, LastRun: xrLastRun}
+		var earliestDay, latestDay time.Time
+		xrStatus.Rows, earliestDay, latestDay = xrStats.Frequency(freq)
+		if !latestDay.IsZero() {
+			earliest, latest := lystype.Date(earliestDay), lystype.Date(latestDay)
+			xrStatus.EarliestDay, xrStatus.LatestDay = &earliest, &latest
+		}
+
+		var a freshness.Assessment
+		if freq == ecbexchangerate.Daily {
+			a = freshness.AssessDaily(latestDay, now, statusMaxLagDaily)
+		} else {
+			a = freshness.AssessMonthly(latestDay, now, statusMaxLagMonthly)
+		}
+		xrStatus.Freshness = &a
+
+		statuses = append(statuses, xrStatus)
+	}
+
+	return statuses, nil
+}
+
+// getQuotaStatuses returns the usage of each connector with a quota, ordered by connector
+func getQuotaStatuses(ctx context.Context, now time.Time) (statuses []quotaStatus, err error) {
+
+	tracker := quota.Tracker{Db: cliApp.ReadDb, Limits: cliApp.Config.Quotas}
+	for _, connector := range slices.Sorted(maps.Keys(cliApp.Config.Quotas)) {
+		u, _, err := tracker.Usage(ctx, connector, now)
+		if err != nil {
+			return nil, fmt.Errorf("tracker.Usage failed: %w", err)
+		}
+		statuses = append(statuses, quotaStatus{Connector: connector, WindowStart: u.WindowStart, Used: u.Used, Quota: u.Limit.Requests})
+	}
+
+	return statuses, nil
+}
+
+// selectLatestRun returns the latest sync run of dataset, or nil if there is none
+func selectLatestRun(ctx context.Context, runStore syncrun.Store, dataset string) (*syncrun.Model, error) {
+
+	run, err := runStore.SelectLatest(ctx, dataset)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("runStore.SelectLatest failed: %w", err)
+	}
+
+	return &run, nil
+}
+
+func (res statusResult) writeTable(w io.Writer) {
+
+	fmt.Fprintln(w, "DATASET\tROWS\tEARLIEST\tLATEST\tSIZE\tFRESHNESS\tLAST SYNC\tOUTCOME")
+
+	for _, st := range res.Datasets {
+
+		earliest, latest := "-", "-"
+		if st.EarliestDay != nil {
+			earliest = time.Time(*st.EarliestDay).Format(lystype.DateFormat)
+		}
+		if st.LatestDay != nil {
+			latest = time.Time(*st.LatestDay).Format(lystype.DateFormat)
+		}
+
+		size := "-"
+		if st.TableBytes > 0 {
+			size = formatBytes(st.TableBytes)
+		}
+
+		fresh := "-"
+		if st.Freshness != nil {
+			fresh = string(st.Freshness.Status)
+			if st.Freshness.Status != freshness.Empty {
+				fresh += fmt.Sprintf(" (lag %d)", st.Freshness.Lag)
+			}
+		}
+
+		lastSync, outcome := "never", "-"
+		if st.LastRun != nil {
+			lastSync = time.Time(st.LastRun.StartedAt).Format(lystype.DatetimeFormat)
+			outcome = st.LastRun.Status
+			if st.LastRun.Error != "" {
+				outcome += ": " + st.LastRun.Error
+			}
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n", st.Dataset, st.Rows, earliest, latest, size, fresh, lastSync, outcome)
+	}
+
+	if len(res.Quotas) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "CONNECTOR\tWINDOW START\tREQUESTS\tQUOTA")
+	for _, q := range res.Quotas {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", q.Connector, q.WindowStart.Format(lystype.DatetimeFormat), q.Used, q.Quota)
+	}
+}
+
+// formatBytes returns n in the largest binary unit it reaches, e.g. "8.2 MiB"
+func formatBytes(n int64) string {
+
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	statusCmd.Flags().IntVar(&statusMaxLagDaily, "max-lag-daily", 1, "number of business days daily rates may trail the expected publication before being reported stale")
+	statusCmd.Flags().IntVar(&statusMaxLagMonthly, "max-lag-monthly", 0, "number of months monthly rates may trail the expected publication before being reported stale")
+	statusCmd.Flags().BoolVar(&statusFailOnStale, "fail-on-stale", false, fmt.Sprintf("exit with code %d if any dataset is stale", statusExitStale))
+	rootCmd.AddCommand(statusCmd)
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/quota"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/registry/ecbconnector"
+	"github.com/loveyourstack/connectors/stores/connectors/syncrun"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncDays      int
+	syncFreq      string
+	syncFallbacks []string
+	syncAuto      bool
+	syncRevDays   int
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Syncs datasets from their source APIs into the database and any additional targets, recording each run in the sync journal.",
+	// no Run function: a subcommand is always needed
+}
+
+var syncCurrenciesCmd = &cobra.Command{
+	Use:   "currencies",
+	Short: "Syncs ECB currencies.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+		defer closeTargets()
+		defer closeChangefeed()
+
+		ctx := cmdContext()
+		c := ecbapi.NewClient(cliApp.InfoLog, cliApp.ErrorLog)
+
+		res, err := syncEcbCurrencies(ctx, c)
+		publishChanges(ctx)
+		printResult(res)
+		if err != nil {
+			cliApp.ErrorLog.Error(err.Error())
+			os.Exit(1)
+		}
+	},
+}
+
+var syncRatesCmd = &cobra.Command{
+	Use:   "rates",
+	Short: "Syncs ECB exchange rates of the last --days days, or with --auto from the latest stored day less --revision-days business days to the latest ECB business day.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+		defer closeTargets()
+		defer closeChangefeed()
+
+		if syncDays < 1 {
+			cliApp.ErrorLog.Error("--days must be at least 1")
+			os.Exit(1)
+		}
+
+		fallbacks, err := ecbconnector.NewFallbacks(syncFallbacks, cliApp.InfoLog, cliApp.ErrorLog)
+		if err != nil {
+			cliApp.ErrorLog.Error(err.Error())
+			os.Exit(1)
+		}
+
+		freq, err := ecbexchangerate.ParseFrequency(syncFreq)
+		if err != nil {
+			cliApp.ErrorLog.Error("invalid --freq: " + err.Error())
+			os.Exit(1)
+		}
+
+		ctx := cmdContext()
+		c := ecbapi.NewClient(cliApp.InfoLog, cliApp.ErrorLog)
+		endDate := time.Now()
+		startDate := endDate.AddDate(0, 0, -syncDays)
+		if syncAuto {
+			startDate, endDate, err = csyncdb.EcbSyncWindow(ctx, cliApp.Targets, freq, syncRevDays, syncDays, endDate)
+			if err != nil {
+				cliApp.ErrorLog.Error("csyncdb.EcbSyncWindow failed: " + err.Error())
+				os.Exit(1)
+			}
+			cliApp.InfoLog.Info("sized exchange rates window", "start_date", startDate.Format(time.DateOnly), "end_date", endDate.Format(time.DateOnly))
+		}
+
+		res, err := syncEcbExchangeRates(ctx, c, freq, startDate, endDate, fallbacks...)
+		publishChanges(ctx)
+		printResult(res)
+		if err != nil {
+			cliApp.ErrorLog.Error(err.Error())
+			os.Exit(1)
+		}
+	},
+}
+
+var syncAllCmd = &cobra.Command{
+	Use:   "all [connector...]",
+	Short: "Syncs every dataset of the given registered connectors, or of all of them, using the last --days days for time series.",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+		defer closeTargets()
+		defer closeChangefeed()
+
+		if syncDays < 1 {
+			cliApp.ErrorLog.Error("--days must be at least 1")
+			os.Exit(1)
+		}
+
+		var conns []registry.Connector
+		for _, conn := range registry.All() {
+			if registry.IsEnabled(conn) {
+				conns = append(conns, conn)
+			}
+		}
+		if len(args) > 0 {
+			conns = nil
+			for _, name := range args {
+				conn, ok := registry.Get(name)
+				if !ok {
+					cliApp.ErrorLog.Error("unknown connector: " + name)
+					os.Exit(1)
+				}
+				conns = append(conns, conn)
+			}
+		}
+
+		ctx := cmdContext()
+		res, err := syncConnectors(ctx, conns, syncDays)
+		publishChanges(ctx)
+		printResult(res)
+		if err != nil {
+			cliApp.ErrorLog.Error(err.Error())
+			os.Exit(1)
+		}
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var names []string
+		for _, conn := range registry.All() {
+			names = append(names, conn.Name())
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+// syncTargetResult is the outcome of a sync into a single target
+type syncTargetResult struct {
+	Target string `json:"target"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type syncResult struct {
+	Dataset string             `json:"dataset"`
+	Params  string             `json:"params,omitempty"`
+	Targets []syncTargetResult `json:"targets"`
+}
+
+// newSyncResult returns the per-target outcome of a sync which returned err
+func newSyncResult(dataset, params string, err error) syncResult {
+
+	res := syncResult{Dataset: dataset, Params: params}
+	for _, t := range cliApp.Targets {
+		tRes := syncTargetResult{Target: t.Name, Status: syncrun.StatusSucceeded}
+		if targetErr, failed := csyncdb.FindTargetError(err, t.Name); failed {
+			tRes.Status = syncrun.StatusFailed
+			tRes.Error = targetErr.Err.Error()
+		}
+		res.Targets = append(res.Targets, tRes)
+	}
+
+	return res
+}
+
+func (res syncResult) writeTable(w io.Writer) {
+
+	fmt.Fprintln(w, "DATASET\tTARGET\tOUTCOME")
+	for _, tRes := range res.Targets {
+		outcome := tRes.Status
+		if tRes.Error != "" {
+			outcome += ": " + tRes.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", res.Dataset, tRes.Target, outcome)
+	}
+}
+
+type syncAllResult struct {
+	Syncs []syncResult `json:"syncs"`
+}
+
+func (res syncAllResult) writeTable(w io.Writer) {
+	writeSyncsTable(w, res.Syncs)
+}
+
+// writeSyncsTable writes one line per dataset sync and target
+func writeSyncsTable(w io.Writer, syncs []syncResult) {
+
+	fmt.Fprintln(w, "DATASET\tPARAMS\tTARGET\tOUTCOME")
+	for _, syncRes := range syncs {
+		for _, tRes := range syncRes.Targets {
+			outcome := tRes.Status
+			if tRes.Error != "" {
+				outcome += ": " + tRes.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", syncRes.Dataset, syncRes.Params, tRes.Target, outcome)
+		}
+	}
+}
+
+// syncConnectors syncs each dataset of conns in turn into all targets. A failed dataset does not stop the others
+// requests are counted towards the quotas of the connectors, but syncs are not deferred: a manual sync is always urgent
+func syncConnectors(ctx context.Context, conns []registry.Connector, days int) (res syncAllResult, err error) {
+
+	// cliApp.Targets starts with the primary database
+	tracker := quota.Tracker{Db: cliApp.Targets[0].Db, Limits: cliApp.Config.Quotas, InfoLog: cliApp.InfoLog}
+
+	var failed []string
+	for _, conn := range conns {
+		for _, ds := range conn.Datasets() {
+
+			deps := registry.Deps{
+				Targets:  cliApp.Targets,
+				Datasets: []string{ds.Name},
+				Days:     days,
+				Sinks:    cliApp.Sinks,
+				Rules:    cliApp.Config.Quality,
+				Derived:  cliApp.Config.Derive,
+				Quota:    tracker.Meter(conn.Name()),
+				Drift:    cliApp.Drift.Guard(conn.Name()),
+				Archive:  cliApp.Archive.Recorder(conn.Name(), ds.Name),
+				InfoLog:  cliApp.InfoLog,
+				ErrorLog: cliApp.ErrorLog,
+			}
+			syncErr := conn.Sync(ctx, deps)
+			if err := tracker.Record(ctx, conn.Name(), deps.Quota, time.Now()); err != nil {
+				cliApp.ErrorLog.Error("tracker.Record failed", "connector", conn.Name(), clog.KeyError, err.Error())
+			}
+			if err := deps.Archive.Finish(ctx); err != nil {
+				cliApp.ErrorLog.Error("deps.Archive.Finish failed", "connector", conn.Name(), clog.KeyError, err.Error())
+			}
+			res.Syncs = append(res.Syncs, newSyncResult(ds.Name, "", syncErr))
+			if syncErr != nil {
+				cliApp.ErrorLog.Error("conn.Sync failed", "connector", conn.Name(), clog.KeyDataset, ds.Name, clog.KeyError, syncErr.Error())
+				failed = append(failed, ds.Name)
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return res, fmt.Errorf("sync failed for datasets: %s", strings.Join(failed, ", "))
+	}
+
+	return res, nil
+}
+
+// syncEcbCurrencies fetches ECB currencies once and syncs them into all targets, recording each run in the target's sync journal
+func syncEcbCurrencies(ctx context.Context, c ecbapi.Client) (res syncResult, err error) {
+
+	err = csyncdb.EcbCurrenciesToTargets(ctx, cliApp.Targets, c)
+	res = newSyncResult(csyncdb.DatasetEcbCurrencies, "", err)
+	if err != nil {
+		return res, fmt.Errorf("csyncdb.EcbCurrenciesToTargets failed: %w", err)
+	}
+
+	return res, nil
+}
+
+// syncEcbExchangeRates fetches the ECB exchange rates from EUR once and syncs them into all targets, recording each run in the target's sync journal
+func syncEcbExchangeRates(ctx context.Context, c ecbapi.Client, freq ecbapi.Frequency, startDate, endDate time.Time, fallbacks ...csyncdb.ExchangeRateSource) (res syncResult, err error) {
+
+	err = csyncdb.EcbExchangeRatesToTargets(ctx, cliApp.Targets, c, "EUR", freq, startDate, endDate, cliApp.Config.Quality.For(csyncdb.DatasetEcbExchangeRates),
+		cliApp.Config.Derive.For(csyncdb.DatasetEcbExchangeRates), fallbacks...)
+	res = newSyncResult(csyncdb.DatasetEcbExchangeRates, csyncdb.EcbExchangeRatesParams("EUR", freq, startDate, endDate), err)
+	if err != nil {
+		return res, fmt.Errorf("csyncdb.EcbExchangeRatesToTargets failed: %w", err)
+	}
+
+	return res, nil
+}
+
+func init() {
+	syncRatesCmd.Flags().IntVar(&syncDays, "days", 7, "number of days to sync, counting back from today. With --auto, only used for targets without rates")
+	syncRatesCmd.Flags().BoolVar(&syncAuto, "auto", false, "size the window from the rates already stored, so that cron jobs need no date arithmetic")
+	syncRatesCmd.Flags().IntVar(&syncRevDays, "revision-days", csyncdb.DefaultRevisionDays, "with --auto: number of business days before the latest stored day to sync again, picking up revised rates")
+	syncRatesCmd.Flags().StringVar(&syncFreq, "freq", ecbapi.Daily.String(), "frequency: D or M")
+	syncRatesCmd.Flags().StringSliceVar(&syncFallbacks, "fallback", nil, "source to fetch the rates from if the ECB is unavailable (repeatable): frankfurter")
+	syncRatesCmd.RegisterFlagCompletionFunc("freq", fixedCompletion(ecbapi.Daily.String(), ecbapi.Monthly.String()))
+	syncRatesCmd.RegisterFlagCompletionFunc("fallback", fixedCompletion(ecbconnector.FallbackFrankfurter))
+
+	syncAllCmd.Flags().IntVar(&syncDays, "days", 7, "number of days of time series data to sync, counting back from today")
+
+	syncCmd.AddCommand(syncAllCmd, syncCurrenciesCmd, syncRatesCmd)
+	rootCmd.AddCommand(syncCmd)
+}
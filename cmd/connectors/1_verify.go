@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/loveyourstack/lys/lystype"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyFreq   string
+	verifyFrom   string
+	verifyTo     string
+	verifyRepair bool
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Compares the stored ECB exchange rates with the ECB over a date range of any length, e.g. the full history, in bounded memory. With --repair, applies the differences as a sync would.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		defer cliApp.Db.Close()
+		defer closeChangefeed()
+
+		startDate, err := time.Parse(lystype.DateFormat, verifyFrom)
+		if err != nil {
+			cliApp.ErrorLog.Error("invalid --from date: " + err.Error())
+			os.Exit(1)
+		}
+		endDate := time.Now()
+		if verifyTo != "" {
+			endDate, err = time.Parse(lystype.DateFormat, verifyTo)
+			if err != nil {
+				cliApp.ErrorLog.Error("invalid --to date: " + err.Error())
+				os.Exit(1)
+			}
+		}
+
+		freq, err := ecbexchangerate.ParseFrequency(verifyFreq)
+		if err != nil {
+			cliApp.ErrorLog.Error("invalid --freq: " + err.Error())
+			os.Exit(1)
+		}
+
+		ctx := cmdContext()
+		c := ecbapi.NewClient(cliApp.InfoLog, cliApp.ErrorLog)
+
+		v, err := csyncdb.VerifyEcbExchangeRates(ctx, cliApp.Db, c, "EUR", freq, startDate, endDate, verifyRepair)
+		if verifyRepair {
+			publishChanges(ctx)
+		}
+		if err != nil {
+			cliApp.ErrorLog.Error("csyncdb.VerifyEcbExchangeRates failed: " + err.Error())
+			os.Exit(1)
+		}
+
+		cliApp.InfoLog.Info("verify completed", "checked", v.Checked, "missing", v.Missing, "changed", v.Changed, "extra", v.Extra, "repaired", v.Repaired)
+		printResult(verifyResult{Checked: v.Checked, Missing: v.Missing, Changed: v.Changed, Extra: v.Extra, Repaired: v.Repaired})
+	},
+}
+
+type verifyResult struct {
+	Checked  int64 `json:"checked"`
+	Missing  int64 `json:"missing"`
+	Changed  int64 `json:"changed"`
+	Extra    int64 `json:"extra"`
+	Repaired bool  `json:"repaired"`
+}
+
+func (res verifyResult) writeTable(w io.Writer) {
+	fmt.Fprintln(w, "CHECKED\tMISSING\tCHANGED\tEXTRA\tREPAIRED")
+	fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%t\n", res.Checked, res.Missing, res.Changed, res.Extra, res.Repaired)
+}
+
+func init() {
+	verifyCmd.Flags().StringVar(&verifyFreq, "freq", ecbapi.Daily.String(), "frequency: D or M")
+	verifyCmd.Flags().StringVar(&verifyFrom, "from", "1999-01-04", "start date (YYYY-MM-DD)")
+	verifyCmd.Flags().StringVar(&verifyTo, "to", "", "end date (YYYY-MM-DD), defaults to today")
+	verifyCmd.Flags().BoolVar(&verifyRepair, "repair", false, "insert missing, update changed and delete extra rates")
+	verifyCmd.RegisterFlagCompletionFunc("freq", fixedCompletion(ecbapi.Daily.String(), ecbapi.Monthly.String()))
+	rootCmd.AddCommand(verifyCmd)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	// registered connectors, discovered by the CLI and daemon. Out-of-tree connectors are added with a blank import as well
+	_ "github.com/loveyourstack/connectors/registry/amzspconnector"
+	_ "github.com/loveyourstack/connectors/registry/coingeckoconnector"
+	_ "github.com/loveyourstack/connectors/registry/companieshouseconnector"
+	_ "github.com/loveyourstack/connectors/registry/dhlconnector"
+	_ "github.com/loveyourstack/connectors/registry/ebayconnector"
+	_ "github.com/loveyourstack/connectors/registry/ecbconnector"
+	_ "github.com/loveyourstack/connectors/registry/eurostatconnector"
+	_ "github.com/loveyourstack/connectors/registry/exhostconnector"
+	_ "github.com/loveyourstack/connectors/registry/filedropconnector"
+	_ "github.com/loveyourstack/connectors/registry/fredconnector"
+	_ "github.com/loveyourstack/connectors/registry/fxreconconnector"
+	_ "github.com/loveyourstack/connectors/registry/gleifconnector"
+	_ "github.com/loveyourstack/connectors/registry/hubspotconnector"
+	_ "github.com/loveyourstack/connectors/registry/imapconnector"
+	_ "github.com/loveyourstack/connectors/registry/imfconnector"
+	_ "github.com/loveyourstack/connectors/registry/inboundconnector"
+	_ "github.com/loveyourstack/connectors/registry/iso4217connector"
+	_ "github.com/loveyourstack/connectors/registry/lexofficeconnector"
+	_ "github.com/loveyourstack/connectors/registry/nagerconnector"
+	_ "github.com/loveyourstack/connectors/registry/outboundconnector"
+	_ "github.com/loveyourstack/connectors/registry/restcountriesconnector"
+	_ "github.com/loveyourstack/connectors/registry/shopifyconnector"
+	_ "github.com/loveyourstack/connectors/registry/stripeconnector"
+	_ "github.com/loveyourstack/connectors/registry/wiseconnector"
+)
+
+func main() {
+	Execute()
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/BurntSushi/toml"
+	"github.com/loveyourstack/connectors/archive"
+	"github.com/loveyourstack/connectors/changefeed"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/daemon"
+	"github.com/loveyourstack/connectors/derive"
+	"github.com/loveyourstack/connectors/drift"
+	"github.com/loveyourstack/connectors/integrity"
+	"github.com/loveyourstack/connectors/quality"
+	"github.com/loveyourstack/connectors/quota"
+	"github.com/loveyourstack/connectors/secrets"
+	"github.com/loveyourstack/connectors/sink"
+	"github.com/loveyourstack/connectors/webhook"
+	"github.com/loveyourstack/lys/lyspgdb"
+)
+
+// DefaultFilePath is the config file location used by the connectors CLI if none is supplied
+const DefaultFilePath string = "/usr/local/etc/connectors_config.toml"
+
+// Config contains all configuration settings
+type Config struct {
+	Db       lyspgdb.Database `toml:"database"`
+	DbUser   lyspgdb.User     `toml:"dbUser"`
+	DbReader lyspgdb.User     `toml:"dbReader"` // optional read-only role of Db, used by the HTTP API, export and status instead of DbUser
+	Targets  []Target         `toml:"targets"`  // additional databases that syncs write to, besides Db
+	Daemon   daemon.Config    `toml:"daemon"`
+	Log      clog.Config      `toml:"log"`
+	Webhooks []webhook.Config `toml:"webhooks"`
+
+	Changefeed changefeed.Config     `toml:"changefeed"` // publication of row changes to Kafka or NATS. Disabled if no broker is set
+	ClickHouse sink.ClickHouseConfig `toml:"clickhouse"` // sink of high-volume tick datasets. Disabled if no url is set
+	Quality    quality.Rules         `toml:"quality"`    // data-quality rules per dataset, e.g. [[quality."ecb.exchange_rate"]]
+	Derive     derive.Fields         `toml:"derive"`     // derived fields per dataset, e.g. [[derive."ecb.exchange_rate"]]
+	Quotas     quota.Limits          `toml:"quotas"`     // request quotas per connector, e.g. [quotas.fred]
+	Drift      drift.Config          `toml:"drift"`      // detection of fields added to or removed from the responses of the connectors' APIs
+	Archive    archive.Config        `toml:"archive"`    // archival of the raw API responses of connectors, for reprocessing. Disabled if no connectors are set
+	Secrets    secrets.Config        `toml:"secrets"`    // providers of the secret references in config values, e.g. apiKey = "env:FRED_API_KEY"
+	Relations  integrity.Relations   `toml:"relations"`  // references of your own tables checked by check-refs, in addition to those of the connectors, e.g. [[relations]]
+
+	Connectors map[string]toml.Primitive `toml:"connectors"` // per-connector settings, e.g. [connectors.fred], decoded by the connector
+
+	md       toml.MetaData     // needed to decode Connectors
+	resolver *secrets.Resolver // resolves the secret references of Connectors when decoded
+}
+
+// PrimaryTargetName is the sync target name of the main database (Config.Db)
+const PrimaryTargetName string = "primary"
+
+// Target is an additional database that synced data is fanned out to, e.g. staging besides prod, or one per tenant
+type Target struct {
+	Name   string           `toml:"name"`
+	Db     lyspgdb.Database `toml:"database"`
+	DbUser lyspgdb.User     `toml:"dbUser"`
+}
+
+func (c *Config) LoadFromFile(configFilePath string) (err error) {
+
+	// ensure supplied path exists
+	if _, err := os.Stat(configFilePath); os.IsNotExist(err) {
+		return fmt.Errorf("configFilePath does not exist: %s", configFilePath)
+	} else if err != nil {
+		return fmt.Errorf("os.Stat failed: %w", err)
+	}
+
+	// read conf from toml file
+	c.md, err = toml.DecodeFile(configFilePath, c)
+	if err != nil {
+		return fmt.Errorf("toml.DecodeFile failed: %w", err)
+	}
+
+	// replace secret references by their secrets. Those of Connectors are resolved when decoded, so that rotated secrets are picked up by decoding again
+	ctx := context.Background()
+	c.resolver, err = secrets.NewResolver(ctx, c.Secrets)
+	if err != nil {
+		return fmt.Errorf("secrets.NewResolver failed: %w", err)
+	}
+	if err = c.resolver.ResolveFields(ctx, c); err != nil {
+		return fmt.Errorf("c.resolver.ResolveFields failed: %w", err)
+	}
+
+	// target names identify journal entries and errors, so must be unique
+	names := map[string]bool{PrimaryTargetName: true}
+	for _, t := range c.Targets {
+		if t.Name == "" {
+			return fmt.Errorf("targets: name is required")
+		}
+		if names[t.Name] {
+			return fmt.Errorf("targets: duplicate name '%s'", t.Name)
+		}
+		names[t.Name] = true
+	}
+
+	if err = c.Quality.Validate(); err != nil {
+		return fmt.Errorf("quality: %w", err)
+	}
+	if err = c.Derive.Validate(); err != nil {
+		return fmt.Errorf("derive: %w", err)
+	}
+	if err = c.Quotas.Validate(); err != nil {
+		return fmt.Errorf("quotas: %w", err)
+	}
+	if err = c.Drift.Validate(); err != nil {
+		return fmt.Errorf("drift: %w", err)
+	}
+	if err = c.Archive.Validate(); err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+	if err = c.Relations.Validate(); err != nil {
+		return fmt.Errorf("relations: %w", err)
+	}
+
+	return nil
+}
+
+// HasReader returns true if a read-only role is configured for Db
+func (c *Config) HasReader() bool {
+	return c.DbReader.Name != ""
+}
+
+// ConnectorDecoder returns a func decoding the [connectors.<name>] table into v, or nil if there is no such table. Pass it to registry.ConfigureAll
+// secret references in the table are resolved, from the cache of the resolver unless its refresh interval has passed
+func (c *Config) ConnectorDecoder(name string) func(v any) error {
+
+	prim, ok := c.Connectors[name]
+	if !ok {
+		return nil
+	}
+
+	return func(v any) error {
+		if err := c.md.PrimitiveDecode(prim, v); err != nil {
+			return fmt.Errorf("c.md.PrimitiveDecode failed for connector %s: %w", name, err)
+		}
+		if c.resolver == nil {
+			return nil
+		}
+		if err := c.resolver.ResolveFields(context.Background(), v); err != nil {
+			return fmt.Errorf("c.resolver.ResolveFields failed for connector %s: %w", name, err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,112 @@
+package contract
+
+import (
+	"fmt"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// ChangeKind is the kind of a Change between two versions of a contract
+type ChangeKind string
+
+const (
+	FieldAdded     ChangeKind = "field_added"
+	FieldRemoved   ChangeKind = "field_removed"   // breaking
+	TypeChanged    ChangeKind = "type_changed"    // breaking
+	BecameNullable ChangeKind = "became_nullable" // breaking, since consumers may not expect null
+	BecameNotNull  ChangeKind = "became_not_null"
+)
+
+// Change is a difference between two versions of a contract
+type Change struct {
+	Kind     ChangeKind `json:"kind"`
+	Field    string     `json:"field"`
+	Old      string     `json:"old,omitempty"` // the type before a TypeChanged
+	New      string     `json:"new,omitempty"` // the type after a TypeChanged
+	Breaking bool       `json:"breaking"`
+}
+
+// typeName returns the type of f as compared: its JSON Schema type and format, or its Go type if it has none
+func typeName(f Field) string {
+	if f.Type == "" {
+		return f.GoType
+	}
+	if f.Format != "" {
+		return f.Type + "/" + f.Format
+	}
+	return f.Type
+}
+
+// Compare returns the changes from prev to cur, in the order of the fields of prev, then of the fields added
+func Compare(prev, cur Contract) (changes []Change) {
+
+	for _, oldField := range prev.Fields {
+		newField, ok := cur.Field(oldField.Name)
+		if !ok {
+			changes = append(changes, Change{Kind: FieldRemoved, Field: oldField.Name, Breaking: true})
+			continue
+		}
+		if oldType, newType := typeName(oldField), typeName(newField); oldType != newType {
+			changes = append(changes, Change{Kind: TypeChanged, Field: oldField.Name, Old: oldType, New: newType, Breaking: true})
+		}
+		switch {
+		case !oldField.Nullable && newField.Nullable:
+			changes = append(changes, Change{Kind: BecameNullable, Field: oldField.Name, Breaking: true})
+		case oldField.Nullable && !newField.Nullable:
+			changes = append(changes, Change{Kind: BecameNotNull, Field: oldField.Name})
+		}
+	}
+
+	for _, newField := range cur.Fields {
+		if _, ok := prev.Field(newField.Name); !ok {
+			changes = append(changes, Change{Kind: FieldAdded, Field: newField.Name})
+		}
+	}
+
+	return changes
+}
+
+// IsBreaking returns true if any of changes is breaking
+func IsBreaking(changes []Change) bool {
+	for _, c := range changes {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiredVersion returns the lowest version a contract may have after oldVersion given its changes: the next major version if one is breaking, the next minor version if a field was added,
+// and oldVersion otherwise
+func RequiredVersion(oldVersion Version, changes []Change) Version {
+
+	if IsBreaking(changes) {
+		return Version{Major: oldVersion.Major + 1}
+	}
+	for _, c := range changes {
+		if c.Kind == FieldAdded {
+			return Version{Major: oldVersion.Major, Minor: oldVersion.Minor + 1}
+		}
+	}
+	return oldVersion
+}
+
+// Check returns the changes from prev to cur, and an error matching cerrors.ErrValidationFailed if the version of cur is lower than they require, see RequiredVersion
+func Check(prev, cur Contract) (changes []Change, err error) {
+
+	oldVersion, err := ParseVersion(prev.Version)
+	if err != nil {
+		return nil, fmt.Errorf("ParseVersion failed for the old version: %w", err)
+	}
+	newVersion, err := ParseVersion(cur.Version)
+	if err != nil {
+		return nil, fmt.Errorf("ParseVersion failed for the new version: %w", err)
+	}
+
+	changes = Compare(prev, cur)
+	if required := RequiredVersion(oldVersion, changes); newVersion.Compare(required) < 0 {
+		return changes, fmt.Errorf("%w: %s: version %s after %s must be at least %s for its changes", cerrors.ErrValidationFailed, cur.Dataset, newVersion, oldVersion, required)
+	}
+
+	return changes, nil
+}
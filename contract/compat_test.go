@@ -0,0 +1,103 @@
+package contract_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/contract"
+)
+
+// rateContract returns the contract of the ECB exchange rates at version, with fields
+func rateContract(version string, fields ...contract.Field) contract.Contract {
+	return contract.Contract{Dataset: "ecb.exchange_rate", Version: version, Fields: fields}
+}
+
+func TestCheck(t *testing.T) {
+
+	id, day, rate := contract.FieldOf("id", "int64"), contract.FieldOf("day", "lystype.Date"), contract.FieldOf("rate", "float32")
+	prev := rateContract("1.2.0", id, day, rate)
+
+	tests := []struct {
+		name        string
+		cur         contract.Contract
+		wantChanges []contract.Change
+		wantErr     bool
+	}{
+		{"unchanged", rateContract("1.2.0", id, day, rate), nil, false},
+		{"reordered", rateContract("1.2.0", rate, id, day), nil, false},
+		{"field added", rateContract("1.3.0", id, day, rate, contract.FieldOf("note", "*string")),
+			[]contract.Change{{Kind: contract.FieldAdded, Field: "note"}}, false},
+		{"field added without a minor version", rateContract("1.2.1", id, day, rate, contract.FieldOf("note", "*string")),
+			[]contract.Change{{Kind: contract.FieldAdded, Field: "note"}}, true},
+		{"field removed", rateContract("2.0.0", id, day),
+			[]contract.Change{{Kind: contract.FieldRemoved, Field: "rate", Breaking: true}}, false},
+		{"field removed without a major version", rateContract("1.3.0", id, day),
+			[]contract.Change{{Kind: contract.FieldRemoved, Field: "rate", Breaking: true}}, true},
+		{"type changed", rateContract("2.0.0", id, contract.FieldOf("day", "lystype.Datetime"), rate),
+			[]contract.Change{{Kind: contract.TypeChanged, Field: "day", Old: "string/date", New: "string/date-time", Breaking: true}}, false},
+		{"type changed without a major version", rateContract("1.2.0", id, day, contract.FieldOf("rate", "string")),
+			[]contract.Change{{Kind: contract.TypeChanged, Field: "rate", Old: "number", New: "string", Breaking: true}}, true},
+		{"float width is not a type change", rateContract("1.2.0", id, day, contract.FieldOf("rate", "float64")), nil, false},
+		{"became nullable", rateContract("1.2.0", id, day, contract.FieldOf("rate", "*float32")),
+			[]contract.Change{{Kind: contract.BecameNullable, Field: "rate", Breaking: true}}, true},
+		{"patch version bumped", rateContract("1.2.5", id, day, rate), nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := contract.Check(prev, tt.cur)
+			if tt.wantErr && !errors.Is(err, cerrors.ErrValidationFailed) {
+				t.Errorf("contract.Check: got %v, want cerrors.ErrValidationFailed", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("contract.Check: got %v, want nil", err)
+			}
+			if !reflect.DeepEqual(changes, tt.wantChanges) {
+				t.Errorf("changes: got %+v, want %+v", changes, tt.wantChanges)
+			}
+		})
+	}
+
+	// a nullable field which becomes not null is not breaking
+	changes, err := contract.Check(rateContract("1.0.0", contract.FieldOf("note", "*string")), rateContract("1.0.0", contract.FieldOf("note", "string")))
+	if err != nil {
+		t.Fatalf("contract.Check failed: %s", err.Error())
+	}
+	if want := []contract.Change{{Kind: contract.BecameNotNull, Field: "note"}}; !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes: got %+v, want %+v", changes, want)
+	}
+
+	// fields without a JSON Schema type are compared by their Go type
+	changes = contract.Compare(rateContract("1.0.0", contract.FieldOf("src", "ecb.Source")), rateContract("1.0.0", contract.FieldOf("src", "ecb.Origin")))
+	if !contract.IsBreaking(changes) {
+		t.Errorf("changes of an unresolved Go type: got %+v, want a breaking type change", changes)
+	}
+
+	if _, err = contract.Check(prev, rateContract("v2", id)); !errors.Is(err, cerrors.ErrValidationFailed) {
+		t.Errorf("contract.Check of an invalid version: got %v, want cerrors.ErrValidationFailed", err)
+	}
+}
+
+func TestRequiredVersion(t *testing.T) {
+
+	v := contract.Version{Major: 1, Minor: 2, Patch: 3}
+
+	tests := []struct {
+		name    string
+		changes []contract.Change
+		want    contract.Version
+	}{
+		{"none", nil, v},
+		{"not breaking", []contract.Change{{Kind: contract.BecameNotNull, Field: "note"}}, v},
+		{"added", []contract.Change{{Kind: contract.FieldAdded, Field: "note"}}, contract.Version{Major: 1, Minor: 3}},
+		{"breaking", []contract.Change{{Kind: contract.FieldAdded, Field: "note"}, {Kind: contract.FieldRemoved, Field: "rate", Breaking: true}}, contract.Version{Major: 2}},
+	}
+
+	for _, tt := range tests {
+		if got := contract.RequiredVersion(v, tt.changes); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
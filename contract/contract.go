@@ -0,0 +1,164 @@
+// Package contract describes the shape of the rows of a dataset as a versioned data contract, so that consumers of the HTTP API or change feed can detect breaking changes
+// a contract has a semantic version: a breaking change, such as a removed field or a changed type, needs a new major version, an added field a new minor version
+package contract
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// DefaultVersion is the version of a contract which has not declared one
+const DefaultVersion string = "1.0.0"
+
+// Contract is the shape of the rows of a dataset at a version
+type Contract struct {
+	Dataset string  `json:"dataset"` // e.g. "ecb.exchange_rate"
+	Version string  `json:"version"` // semantic version, e.g. "1.2.0"
+	Fields  []Field `json:"fields"`
+}
+
+// Field is a field of a Contract, with its JSON Schema type
+type Field struct {
+	Name     string `json:"name"`
+	Type     string `json:"type,omitempty"`   // JSON Schema type: string, number, integer, boolean, array or object. Empty if any value may occur
+	Format   string `json:"format,omitempty"` // JSON Schema format of strings, e.g. date or date-time
+	Nullable bool   `json:"nullable,omitempty"`
+	GoType   string `json:"go_type"` // e.g. "lystype.Date". Compared when Type is empty
+}
+
+// FieldOf returns the Field of a value of the Go type goType, as named by reflect, e.g. "*int64" or "lystype.Datetime"
+// pointers, slices and maps are nullable. Named types other than those of lystype and time are not resolved, so have an empty Type
+func FieldOf(name, goType string) Field {
+
+	f := Field{Name: name, GoType: goType}
+
+	t := goType
+	if strings.HasPrefix(t, "*") {
+		f.Nullable = true
+		t = strings.TrimPrefix(t, "*")
+	}
+
+	switch {
+	case t == "string":
+		f.Type = "string"
+	case t == "bool":
+		f.Type = "boolean"
+	case t == "float32" || t == "float64":
+		f.Type = "number"
+	case strings.HasPrefix(t, "int") || strings.HasPrefix(t, "uint"):
+		f.Type = "integer"
+	case t == "lystype.Date":
+		f.Type, f.Format = "string", "date"
+	case t == "lystype.Datetime" || t == "time.Time":
+		f.Type, f.Format = "string", "date-time"
+	case t == "lystype.Time":
+		f.Type, f.Format = "string", "time"
+	case t == "json.RawMessage":
+		f.Nullable = true
+	case strings.HasPrefix(t, "[]"):
+		f.Type, f.Nullable = "array", true
+	case strings.HasPrefix(t, "map["):
+		f.Type, f.Nullable = "object", true
+	}
+
+	return f
+}
+
+// Validate returns an error if c has no dataset, an invalid version or duplicate fields
+func (c Contract) Validate() error {
+
+	if c.Dataset == "" {
+		return fmt.Errorf("%w: dataset is required", cerrors.ErrValidationFailed)
+	}
+	if _, err := ParseVersion(c.Version); err != nil {
+		return fmt.Errorf("ParseVersion failed for %s: %w", c.Dataset, err)
+	}
+	seen := make(map[string]bool, len(c.Fields))
+	for _, f := range c.Fields {
+		if seen[f.Name] {
+			return fmt.Errorf("%w: %s: duplicate field: %s", cerrors.ErrValidationFailed, c.Dataset, f.Name)
+		}
+		seen[f.Name] = true
+	}
+	return nil
+}
+
+// Field returns the field of c named name
+func (c Contract) Field(name string) (f Field, ok bool) {
+	i := slices.IndexFunc(c.Fields, func(f Field) bool { return f.Name == name })
+	if i < 0 {
+		return Field{}, false
+	}
+	return c.Fields[i], true
+}
+
+// JSONSchema returns the JSON Schema (draft 2020-12) of a row of c. Fields without a Type accept any value
+func (c Contract) JSONSchema() map[string]any {
+
+	props := make(map[string]any, len(c.Fields))
+	for _, f := range c.Fields {
+		prop := map[string]any{}
+		if f.Type != "" {
+			prop["type"] = f.Type
+			if f.Nullable {
+				prop["type"] = []string{f.Type, "null"}
+			}
+		}
+		if f.Format != "" {
+			prop["format"] = f.Format
+		}
+		props[f.Name] = prop
+	}
+
+	return map[string]any{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"$id":        "urn:connectors:contract:" + c.Dataset + ":" + c.Version,
+		"title":      c.Dataset,
+		"version":    c.Version,
+		"type":       "object",
+		"properties": props,
+	}
+}
+
+// Version is a semantic version
+type Version struct {
+	Major, Minor, Patch int
+}
+
+// ParseVersion parses a version such as "1.2.0". Pre-release and build suffixes are not supported
+func ParseVersion(s string) (v Version, err error) {
+
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("%w: invalid version: '%s': must be MAJOR.MINOR.PATCH", cerrors.ErrValidationFailed, s)
+	}
+	nums := make([]int, 3)
+	for i, part := range parts {
+		nums[i], err = strconv.Atoi(part)
+		if err != nil || nums[i] < 0 {
+			return Version{}, fmt.Errorf("%w: invalid version: '%s': must be MAJOR.MINOR.PATCH", cerrors.ErrValidationFailed, s)
+		}
+	}
+	return Version{Major: nums[0], Minor: nums[1], Patch: nums[2]}, nil
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1, 0 or +1 depending on whether v is lower than, equal to or higher than other
+func (v Version) Compare(other Version) int {
+	for _, d := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if d != 0 {
+			if d < 0 {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
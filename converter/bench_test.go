@@ -0,0 +1,156 @@
+package converter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/apiclients/ecbapi/ecbapitest"
+	"github.com/loveyourstack/connectors/converter"
+	"github.com/loveyourstack/connectors/pgtest"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbcurrency"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+)
+
+func BenchmarkConvert(b *testing.B) {
+
+	ctx := context.Background()
+	conv := converter.Converter{Store: memGenRates(b, 250*ecbapitest.GenCurrencies)}
+	from, to := ecbapitest.GenCurrencyCode(0), ecbapitest.GenCurrencyCode(1)
+
+	b.ReportAllocs()
+	for range b.N {
+		if _, err := conv.Convert(ctx, from, to, 100, time.Time{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCacheHit converts with a Cache whose lookups of the day are all hits
+func BenchmarkCacheHit(b *testing.B) {
+
+	ctx := context.Background()
+	conv := converter.Converter{Store: converter.NewCache(memGenRates(b, 250*ecbapitest.GenCurrencies))}
+	from, to := ecbapitest.GenCurrencyCode(0), ecbapitest.GenCurrencyCode(1)
+
+	if _, err := conv.Convert(ctx, from, to, 100, time.Time{}); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, err := conv.Convert(ctx, from, to, 100, time.Time{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCacheMiss converts with a Cache reset before each conversion, so that its lookups are all misses
+func BenchmarkCacheMiss(b *testing.B) {
+
+	ctx := context.Background()
+	cache := converter.NewCache(memGenRates(b, 250*ecbapitest.GenCurrencies))
+	conv := converter.Converter{Store: cache}
+	from, to := ecbapitest.GenCurrencyCode(0), ecbapitest.GenCurrencyCode(1)
+
+	b.ReportAllocs()
+	for range b.N {
+		cache.Reset()
+		if _, err := conv.Convert(ctx, from, to, 100, time.Time{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkConverterRates runs Converter.Rates with the pool's default exec mode, where pgx prepares each statement once per connection and reuses it,
+// and with QueryExecModeDescribeExec, which prepares it on every call. Skipped without Docker
+func BenchmarkConverterRates(b *testing.B) {
+
+	ctx := context.Background()
+	db := pgtest.New(b, pgtest.Config{})
+	loadGenRates(b, db, 250*ecbapitest.GenCurrencies)
+
+	describeConfig := db.Config()
+	describeConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeDescribeExec
+	describeDb, err := pgxpool.NewWithConfig(ctx, describeConfig)
+	if err != nil {
+		b.Fatalf("pgxpool.NewWithConfig failed: %s", err.Error())
+	}
+	b.Cleanup(describeDb.Close)
+
+	for _, bm := range []struct {
+		name string
+		db   *pgxpool.Pool
+	}{
+		{"StatementCache", db},
+		{"DescribeExec", describeDb},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			conv := converter.Converter{Db: bm.db}
+			b.ReportAllocs()
+			for range b.N {
+				if _, err := conv.Rates(ctx, "EUR", time.Time{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// loadGenRates stores the generated currencies and rows generated rates in db
+func loadGenRates(tb testing.TB, db *pgxpool.Pool, rows int) {
+
+	tb.Helper()
+
+	ctx := context.Background()
+	currStore := ecbcurrency.Store{Db: db}
+	for code := range ecbapitest.GenCurrMap() {
+		if _, err := currStore.Insert(ctx, ecbcurrency.Input{Code: code, Name: code}); err != nil {
+			tb.Fatalf("currStore.Insert failed: %s", err.Error())
+		}
+	}
+	currMap, err := currStore.SelectCodeIdMap(ctx)
+	if err != nil {
+		tb.Fatalf("currStore.SelectCodeIdMap failed: %s", err.Error())
+	}
+
+	inputs, err := ecbapi.ExchangeRatesToItems(ecbapitest.GenExchangeRates(rows), currMap)
+	if err != nil {
+		tb.Fatalf("ecbapi.ExchangeRatesToItems failed: %s", err.Error())
+	}
+	if _, _, err = (ecbexchangerate.Store{Db: db}).CopyUpsert(ctx, inputs); err != nil {
+		tb.Fatalf("ecbexchangerate.Store.CopyUpsert failed: %s", err.Error())
+	}
+}
+
+// memGenRates returns a MemStore of the generated currencies and rows generated rates
+func memGenRates(tb testing.TB, rows int) *ecbexchangerate.MemStore {
+
+	tb.Helper()
+
+	ctx := context.Background()
+	currStore := ecbcurrency.NewMemStore()
+	for code := range ecbapitest.GenCurrMap() {
+		if _, err := currStore.Insert(ctx, ecbcurrency.Input{Code: code, Name: code}); err != nil {
+			tb.Fatalf("currStore.Insert failed: %s", err.Error())
+		}
+	}
+	currMap, err := currStore.SelectCodeIdMap(ctx)
+	if err != nil {
+		tb.Fatalf("currStore.SelectCodeIdMap failed: %s", err.Error())
+	}
+
+	inputs, err := ecbapi.ExchangeRatesToItems(ecbapitest.GenExchangeRates(rows), currMap)
+	if err != nil {
+		tb.Fatalf("ecbapi.ExchangeRatesToItems failed: %s", err.Error())
+	}
+	store := ecbexchangerate.NewMemStore(currStore)
+	if _, err = store.BulkInsert(ctx, inputs); err != nil {
+		tb.Fatalf("store.BulkInsert failed: %s", err.Error())
+	}
+	return store
+}
@@ -0,0 +1,159 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+)
+
+// maximum number of fixing lookups kept by a Cache. If exceeded, they are all dropped, so that requests for many historic days cannot grow it without bound
+const maxCacheDays int = 1000
+
+// Cache is a RateStore which keeps the results of the lookups of Store in memory, so that conversions on the same days do not query the database each time
+// entries are only dropped by Reset, which must be called when the rates change, e.g. by Converter.Warm after each sync. It is safe for concurrent use if Store is
+type Cache struct {
+	Store RateStore
+
+	mu         sync.RWMutex
+	gen        int // incremented by Reset, so that a lookup which started before is not cached
+	latestDays map[ecbexchangerate.Frequency]latestDayEntry
+	days       map[dayKey]dayRates
+}
+
+type latestDayEntry struct {
+	day   time.Time
+	count int64
+}
+
+type dayKey struct {
+	baseCurr        string
+	freq            ecbexchangerate.Frequency
+	day             time.Time
+	maxFallbackDays int
+}
+
+type dayRates struct {
+	actualDay time.Time
+	items     []ecbexchangerate.Model
+}
+
+var (
+	_ RateStore      = (*Cache)(nil)
+	_ RangeRateStore = (*Cache)(nil)
+	_ AsOfRateStore  = (*Cache)(nil)
+)
+
+// NewCache returns an empty Cache of store
+func NewCache(store RateStore) *Cache {
+	return &Cache{Store: store}
+}
+
+// Reset drops all entries, so that the next lookups read Store
+func (c *Cache) Reset() {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.latestDays = nil
+	c.days = nil
+}
+
+// SelectDayOnOrBefore returns the cached result of Store.SelectDayOnOrBefore. Errors, including cerrors.ErrNoRows, are not cached
+// the returned items are shared with other callers and must not be modified
+func (c *Cache) SelectDayOnOrBefore(ctx context.Context, baseCurr string, freq ecbexchangerate.Frequency, day time.Time, maxFallbackDays int) (actualDay time.Time, items []ecbexchangerate.Model, err error) {
+
+	key := dayKey{baseCurr: baseCurr, freq: freq, day: truncateDay(day), maxFallbackDays: maxFallbackDays}
+
+	c.mu.RLock()
+	entry, ok := c.days[key]
+	gen := c.gen
+	c.mu.RUnlock()
+	if ok {
+		return entry.actualDay, entry.items, nil
+	}
+
+	actualDay, items, err = c.Store.SelectDayOnOrBefore(ctx, baseCurr, freq, day, maxFallbackDays)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("c.Store.SelectDayOnOrBefore failed: %w", err)
+	}
+
+	c.mu.Lock()
+	if c.gen == gen {
+		if c.days == nil || len(c.days) >= maxCacheDays {
+			c.days = make(map[dayKey]dayRates)
+		}
+		c.days[key] = dayRates{actualDay: actualDay, items: items}
+	}
+	c.mu.Unlock()
+
+	return actualDay, items, nil
+}
+
+// SelectLatestDay returns the cached result of Store.SelectLatestDay
+func (c *Cache) SelectLatestDay(ctx context.Context, freq ecbexchangerate.Frequency) (latestDay time.Time, count int64, err error) {
+
+	c.mu.RLock()
+	entry, ok := c.latestDays[freq]
+	gen := c.gen
+	c.mu.RUnlock()
+	if ok {
+		return entry.day, entry.count, nil
+	}
+
+	latestDay, count, err = c.Store.SelectLatestDay(ctx, freq)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("c.Store.SelectLatestDay failed: %w", err)
+	}
+
+	c.mu.Lock()
+	if c.gen == gen {
+		if c.latestDays == nil {
+			c.latestDays = make(map[ecbexchangerate.Frequency]latestDayEntry)
+		}
+		c.latestDays[freq] = latestDayEntry{day: latestDay, count: count}
+	}
+	c.mu.Unlock()
+
+	return latestDay, count, nil
+}
+
+// SelectInRange passes through to Store if it is a RangeRateStore. Ranges are not cached
+func (c *Cache) SelectInRange(ctx context.Context, baseCurr string, freq ecbexchangerate.Frequency, startDate, endDate time.Time) (items []ecbexchangerate.Model, err error) {
+
+	rangeStore, ok := c.Store.(RangeRateStore)
+	if !ok {
+		return nil, fmt.Errorf("%w: the rate store %T cannot read date ranges", cerrors.ErrValidationFailed, c.Store)
+	}
+	return rangeStore.SelectInRange(ctx, baseCurr, freq, startDate, endDate)
+}
+
+// SelectDayOnOrBeforeAsOf passes through to Store if it is an AsOfRateStore. Lookups as of a time are not cached
+func (c *Cache) SelectDayOnOrBeforeAsOf(ctx context.Context, baseCurr string, freq ecbexchangerate.Frequency, day time.Time, maxFallbackDays int, knownAt time.Time) (actualDay time.Time, items []ecbexchangerate.Model, err error) {
+
+	asOfStore, ok := c.Store.(AsOfRateStore)
+	if !ok {
+		return time.Time{}, nil, fmt.Errorf("%w: KnownAt is set, but the rate store %T does not keep the values of rates over time", cerrors.ErrValidationFailed, c.Store)
+	}
+	return asOfStore.SelectDayOnOrBeforeAsOf(ctx, baseCurr, freq, day, maxFallbackDays, knownAt)
+}
+
+// Warm resets the Cache of c, if its Store is one, and loads the latest fixing into it, so that the first conversions after a sync do not all query the database at once
+func (c Converter) Warm(ctx context.Context) error {
+
+	cache, ok := c.Store.(*Cache)
+	if !ok {
+		return nil
+	}
+	cache.Reset()
+
+	// an empty store has nothing to warm
+	if _, err := c.Rates(ctx, ecbBaseCurr, time.Time{}); err != nil && !errors.Is(err, ErrRateNotFound) {
+		return fmt.Errorf("c.Rates failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package converter_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/converter"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+)
+
+func TestCache(t *testing.T) {
+
+	ctx := context.Background()
+	day2 := day1.AddDate(0, 0, 1)
+	mem := memRates(t, map[time.Time]map[string]float32{day1: {"USD": 1.1061}, day2: {"USD": 1.1072}})
+	store := &countingStore{RateStore: mem}
+	cache := converter.NewCache(store)
+
+	lookup := func(day time.Time, wantRate float64, wantErr error) {
+		t.Helper()
+		_, items, err := cache.SelectDayOnOrBefore(ctx, "EUR", ecbexchangerate.Daily, day, 0)
+		if wantErr != nil {
+			if !errors.Is(err, wantErr) {
+				t.Errorf("cache.SelectDayOnOrBefore of %s: got %v, want %v", day, err, wantErr)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("cache.SelectDayOnOrBefore failed: %s", err.Error())
+		}
+		if len(items) != 1 || items[0].RateFloat64() != wantRate {
+			t.Errorf("cache.SelectDayOnOrBefore of %s: got %+v, want USD %v", day, items, wantRate)
+		}
+	}
+
+	lookup(day1, 1.1061, nil)
+	lookup(day1.Add(time.Hour), 1.1061, nil) // the same day
+	lookup(day2, 1.1072, nil)
+	if n := store.dayLookups.Load(); n != 2 {
+		t.Errorf("store lookups: got %d, want 2: a miss per day", n)
+	}
+
+	// errors are not cached
+	noRates := day1.AddDate(0, 0, -7)
+	lookup(noRates, 0, cerrors.ErrNotFound)
+	lookup(noRates, 0, cerrors.ErrNotFound)
+	if n := store.dayLookups.Load(); n != 4 {
+		t.Errorf("store lookups: got %d, want 4: errors are looked up again", n)
+	}
+
+	for range 2 {
+		latestDay, count, err := cache.SelectLatestDay(ctx, ecbexchangerate.Daily)
+		if err != nil {
+			t.Fatalf("cache.SelectLatestDay failed: %s", err.Error())
+		}
+		if !latestDay.Equal(day2) || count != 2 {
+			t.Errorf("cache.SelectLatestDay: got %s, %d, want %s, 2", latestDay, count, day2)
+		}
+	}
+	if n := store.latestLookups.Load(); n != 1 {
+		t.Errorf("store latest day lookups: got %d, want 1", n)
+	}
+
+	// a revised rate is only read after Reset
+	items, err := mem.SelectInRange(ctx, "EUR", ecbexchangerate.Daily, day1, day1)
+	if err != nil {
+		t.Fatalf("mem.SelectInRange failed: %s", err.Error())
+	}
+	revised := items[0].Input
+	revised.Rate = 1.1099
+	if err = mem.Update(ctx, revised, items[0].Id); err != nil {
+		t.Fatalf("mem.Update failed: %s", err.Error())
+	}
+	lookup(day1, 1.1061, nil)
+	cache.Reset()
+	lookup(day1, 1.1099, nil)
+	if _, _, err = cache.SelectLatestDay(ctx, ecbexchangerate.Daily); err != nil {
+		t.Fatalf("cache.SelectLatestDay failed: %s", err.Error())
+	}
+	if n := store.latestLookups.Load(); n != 2 {
+		t.Errorf("store latest day lookups after Reset: got %d, want 2", n)
+	}
+}
+
+// TestCacheResetDuringLookup checks that a lookup which started before Reset is not cached, since it may have read the rates before they changed
+func TestCacheResetDuringLookup(t *testing.T) {
+
+	ctx := context.Background()
+	store := &countingStore{RateStore: memRates(t, map[time.Time]map[string]float32{day1: {"USD": 1.1061}}), started: make(chan struct{}), release: make(chan struct{})}
+	cache := converter.NewCache(store)
+
+	done := make(chan error)
+	go func() {
+		_, _, err := cache.SelectDayOnOrBefore(ctx, "EUR", ecbexchangerate.Daily, day1, 0)
+		done <- err
+	}()
+
+	<-store.started
+	cache.Reset()
+	store.started = nil
+	close(store.release)
+	if err := <-done; err != nil {
+		t.Fatalf("cache.SelectDayOnOrBefore failed: %s", err.Error())
+	}
+
+	if _, _, err := cache.SelectDayOnOrBefore(ctx, "EUR", ecbexchangerate.Daily, day1, 0); err != nil {
+		t.Fatalf("cache.SelectDayOnOrBefore failed: %s", err.Error())
+	}
+	if n := store.dayLookups.Load(); n != 2 {
+		t.Errorf("store lookups: got %d, want 2: the lookup started before Reset is not cached", n)
+	}
+}
+
+// TestCacheConcurrent converts from several goroutines while the cache is warmed, for go test -race
+func TestCacheConcurrent(t *testing.T) {
+
+	ctx := context.Background()
+	days := make(map[time.Time]map[string]float32)
+	for i := range 10 {
+		days[day1.AddDate(0, 0, 7*i)] = map[string]float32{"USD": 1.1061, "GBP": 0.84183}
+	}
+	conv := converter.Converter{Store: converter.NewCache(memRates(t, days))}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				day := day1.AddDate(0, 0, 7*((g+i)%10))
+				c, err := conv.Convert(ctx, "GBP", "USD", 100, day)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if c.Rate != 1.1061/0.84183 {
+					errs <- errors.New("wrong rate")
+					return
+				}
+				if i%25 == 0 {
+					if err = conv.Warm(ctx); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("conversion failed: %s", err.Error())
+	}
+}
+
+// countingStore counts the lookups of RateStore. If started is set, SelectDayOnOrBefore signals it and waits for release before looking up
+type countingStore struct {
+	converter.RateStore
+	dayLookups    atomic.Int64
+	latestLookups atomic.Int64
+	started       chan struct{}
+	release       chan struct{}
+}
+
+func (s *countingStore) SelectDayOnOrBefore(ctx context.Context, baseCurr string, freq ecbexchangerate.Frequency, day time.Time, maxFallbackDays int) (actualDay time.Time, items []ecbexchangerate.Model, err error) {
+
+	s.dayLookups.Add(1)
+	if s.started != nil {
+		s.started <- struct{}{}
+		<-s.release
+	}
+	return s.RateStore.SelectDayOnOrBefore(ctx, baseCurr, freq, day, maxFallbackDays)
+}
+
+func (s *countingStore) SelectLatestDay(ctx context.Context, freq ecbexchangerate.Frequency) (latestDay time.Time, count int64, err error) {
+	s.latestLookups.Add(1)
+	return s.RateStore.SelectLatestDay(ctx, freq)
+}
@@ -0,0 +1,314 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/calendar"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbrateoverride"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// ECB publishes EUR-denominated rates only: other bases are derived from them
+const ecbBaseCurr string = "EUR"
+
+// default number of TARGET business days looked back if there is no fixing on the requested day, e.g. because the ECB has not published it yet
+// weekends and TARGET closing days have no fixing and are skipped without counting
+const DefaultMaxFallbackDays int = 3
+
+// ErrRateNotFound is returned if no rate is available for the requested currency and day. It matches cerrors.ErrNotFound
+var ErrRateNotFound = fmt.Errorf("rate %w", cerrors.ErrNotFound)
+
+// RateStore is the part of ecbexchangerate.Storer used by Converter
+type RateStore interface {
+	SelectDayOnOrBefore(ctx context.Context, baseCurr string, freq ecbexchangerate.Frequency, day time.Time, maxFallbackDays int) (actualDay time.Time, items []ecbexchangerate.Model, err error)
+	SelectLatestDay(ctx context.Context, freq ecbexchangerate.Frequency) (latestDay time.Time, count int64, err error)
+}
+
+// AsOfRateStore is implemented by the rate stores which keep the values of rates over time, such as ecbexchangerate.Store, and is needed for Converter.KnownAt
+type AsOfRateStore interface {
+	SelectDayOnOrBeforeAsOf(ctx context.Context, baseCurr string, freq ecbexchangerate.Frequency, day time.Time, maxFallbackDays int, knownAt time.Time) (actualDay time.Time, items []ecbexchangerate.Model, err error)
+}
+
+// OverrideStore is the part of ecbrateoverride.Store used by Converter
+type OverrideStore interface {
+	SelectApproved(ctx context.Context, startDate, endDate, knownAt time.Time) (items []ecbrateoverride.Model, err error)
+}
+
+// CryptoStore is the part of cgprice.Store used by Converter
+type CryptoStore interface {
+	SelectSymbolPrices(ctx context.Context, vsCurr string, day time.Time, maxFallbackDays int) (prices map[string]float64, err error)
+}
+
+// Converter converts amounts between currencies using the stored ECB daily reference rates
+// rates between two non-EUR currencies are cross rates via EUR. Converter is safe for concurrent use if its Store and Crypto are
+type Converter struct {
+	Db              *pgxpool.Pool
+	Store           RateStore   // if nil, ecbexchangerate.Store using Db. Set to an ecbexchangerate.MemStore or SQLiteStore to convert without Postgres
+	Crypto          CryptoStore // optional, e.g. cgprice.Store: Convert then accepts coin symbols such as BTC, valued by their EUR price
+	MaxFallbackDays int         // TARGET business days. If 0, DefaultMaxFallbackDays is used
+
+	// optional, e.g. ecbrateoverride.Store: the approved manual corrections of the EUR rates of a fixing are used instead of the synced rates, and the rates they add
+	// are used too. Corrections of days without a fixing are not used. With KnownAt, the corrections in use at KnownAt are. They are read on each lookup, so they are not cached by Cache
+	Overrides OverrideStore
+
+	// if set, rates are converted with the ECB rates as they were stored at KnownAt, to reproduce a valuation made then: rates stored or revised later are ignored
+	// a zero day then means the date of KnownAt. Store must implement AsOfRateStore. Crypto prices are not versioned, so Crypto is not used
+	KnownAt time.Time
+}
+
+// Fixing identifies the ECB fixing whose rates were applied, e.g. to state it on an invoice
+type Fixing struct {
+	Day       lystype.Date              `json:"day"`                 // day of the ECB fixing used
+	Frequency ecbexchangerate.Frequency `json:"frequency"`           // always Daily: monthly averages are not used for conversions
+	Fallback  bool                      `json:"fallback"`            // true if the requested day had no fixing, so that of an earlier day was used. Never true for the latest fixing
+	Corrected []string                  `json:"corrected,omitempty"` // currencies whose EUR rate used is a manual correction rather than the ECB's, see Converter.Overrides
+}
+
+// Rates is the set of rates from Base to each other currency on Day
+type Rates struct {
+	Base string `json:"base"`
+	Fixing
+	Rates map[string]float64 `json:"rates"`
+}
+
+// Conversion is the result of a Convert call
+type Conversion struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Amount float64 `json:"amount"`
+	Rate   float64 `json:"rate"`
+	Result float64 `json:"result"`
+	Fixing
+}
+
+// Rates returns the rates from base to all currencies on day, or on the most recent day with a fixing before it. If day is zero, the latest fixing is used
+func (c Converter) Rates(ctx context.Context, base string, day time.Time) (r Rates, err error) {
+
+	eurDay, eurRates, corrected, err := c.eurRates(ctx, day)
+	if err != nil {
+		return Rates{}, fmt.Errorf("c.eurRates failed: %w", err)
+	}
+
+	r = Rates{Base: base, Fixing: fixing(eurDay, day), Rates: make(map[string]float64, len(eurRates))}
+	r.Corrected = corrected
+
+	if base == ecbBaseCurr {
+		r.Rates = eurRates
+		return r, nil
+	}
+
+	baseRate, ok := eurRates[base]
+	if !ok {
+		return Rates{}, notFound(base, eurDay)
+	}
+	r.Rates[ecbBaseCurr] = 1 / baseRate
+	for curr, rate := range eurRates {
+		if curr != base {
+			r.Rates[curr] = rate / baseRate
+		}
+	}
+
+	return r, nil
+}
+
+// Convert converts amount from one currency to another using the fixing of day, or of the most recent day with a fixing before it. If day is zero, the latest fixing is used
+func (c Converter) Convert(ctx context.Context, from, to string, amount float64, day time.Time) (conv Conversion, err error) {
+
+	eurDay, eurRates, corrected, err := c.eurRates(ctx, day)
+	if err != nil {
+		return Conversion{}, fmt.Errorf("c.eurRates failed: %w", err)
+	}
+
+	// EUR has an implicit rate of 1
+	eurRates[ecbBaseCurr] = 1
+
+	if err = c.addCryptoRates(ctx, eurRates, eurDay, from, to); err != nil {
+		return Conversion{}, fmt.Errorf("c.addCryptoRates failed: %w", err)
+	}
+
+	fromRate, ok := eurRates[from]
+	if !ok {
+		return Conversion{}, notFound(from, eurDay)
+	}
+	toRate, ok := eurRates[to]
+	if !ok {
+		return Conversion{}, notFound(to, eurDay)
+	}
+
+	rate := toRate / fromRate
+	conv = Conversion{
+		From:   from,
+		To:     to,
+		Amount: amount,
+		Rate:   rate,
+		Result: amount * rate,
+		Fixing: fixing(eurDay, day),
+	}
+	// only the corrections the rate depends on
+	for _, curr := range corrected {
+		if curr == from || curr == to {
+			conv.Corrected = append(conv.Corrected, curr)
+		}
+	}
+	return conv, nil
+}
+
+// eurRates returns the EUR rates of the fixing used for day, keyed by currency code, with the approved corrections of c.Overrides applied
+// corrected are the currencies whose rates were corrected, in order
+func (c Converter) eurRates(ctx context.Context, day time.Time) (actualDay time.Time, rates map[string]float64, corrected []string, err error) {
+
+	var xrStore RateStore = ecbexchangerate.Store{Db: c.Db}
+	if c.Store != nil {
+		xrStore = c.Store
+	}
+
+	if !c.KnownAt.IsZero() {
+		actualDay, rates, err = c.eurRatesAsOf(ctx, xrStore, day)
+	} else {
+		actualDay, rates, err = c.eurRatesLatest(ctx, xrStore, day)
+	}
+	if err != nil {
+		return time.Time{}, nil, nil, err
+	}
+
+	if c.Overrides == nil {
+		return actualDay, rates, nil, nil
+	}
+	overrides, err := c.Overrides.SelectApproved(ctx, actualDay, actualDay, c.KnownAt)
+	if err != nil {
+		return time.Time{}, nil, nil, fmt.Errorf("c.Overrides.SelectApproved failed: %w", err)
+	}
+	for _, o := range overrides {
+		rates[o.ToCurrency] = o.Rate
+		corrected = append(corrected, o.ToCurrency)
+	}
+
+	return actualDay, rates, corrected, nil
+}
+
+// eurRatesLatest returns the EUR rates of the fixing used for day, as currently stored
+func (c Converter) eurRatesLatest(ctx context.Context, xrStore RateStore, day time.Time) (actualDay time.Time, rates map[string]float64, err error) {
+
+	if day.IsZero() {
+		day, _, err = xrStore.SelectLatestDay(ctx, ecbexchangerate.Daily)
+		if err != nil {
+			return time.Time{}, nil, fmt.Errorf("xrStore.SelectLatestDay failed: %w", err)
+		}
+		if day.IsZero() {
+			return time.Time{}, nil, fmt.Errorf("%w: no exchange rates available", ErrRateNotFound)
+		}
+	}
+
+	actualDay, items, err := xrStore.SelectDayOnOrBefore(ctx, ecbBaseCurr, ecbexchangerate.Daily, day, c.fallbackCalendarDays(day))
+	if err != nil {
+		if errors.Is(err, cerrors.ErrNotFound) {
+			return time.Time{}, nil, fmt.Errorf("%w: no exchange rates available on or up to %d business days before %s", ErrRateNotFound, c.maxFallbackDays(), day.Format("2006-01-02"))
+		}
+		return time.Time{}, nil, fmt.Errorf("xrStore.SelectDayOnOrBefore failed: %w", err)
+	}
+
+	return actualDay, rateMap(items), nil
+}
+
+// eurRatesAsOf returns the EUR rates of the fixing used for day, as stored at c.KnownAt
+func (c Converter) eurRatesAsOf(ctx context.Context, xrStore RateStore, day time.Time) (actualDay time.Time, rates map[string]float64, err error) {
+
+	asOfStore, ok := xrStore.(AsOfRateStore)
+	if !ok {
+		return time.Time{}, nil, fmt.Errorf("%w: KnownAt is set, but the rate store %T does not keep the values of rates over time", cerrors.ErrValidationFailed, xrStore)
+	}
+
+	if day.IsZero() {
+		knownAt := c.KnownAt.In(calendar.Location)
+		day = time.Date(knownAt.Year(), knownAt.Month(), knownAt.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	actualDay, items, err := asOfStore.SelectDayOnOrBeforeAsOf(ctx, ecbBaseCurr, ecbexchangerate.Daily, day, c.fallbackCalendarDays(day), c.KnownAt)
+	if err != nil {
+		if errors.Is(err, cerrors.ErrNotFound) {
+			return time.Time{}, nil, fmt.Errorf("%w: no exchange rates known at %s on or up to %d business days before %s", ErrRateNotFound, c.KnownAt.Format(time.RFC3339), c.maxFallbackDays(), day.Format("2006-01-02"))
+		}
+		return time.Time{}, nil, fmt.Errorf("asOfStore.SelectDayOnOrBeforeAsOf failed: %w", err)
+	}
+
+	return actualDay, rateMap(items), nil
+}
+
+// addCryptoRates adds the EUR rates of those of currs not in eurRates from c.Crypto, using the price of day or of the most recent day before it
+// ECB rates take precedence, so that a coin symbol cannot replace an ISO currency
+func (c Converter) addCryptoRates(ctx context.Context, eurRates map[string]float64, day time.Time, currs ...string) error {
+
+	if c.Crypto == nil || !c.KnownAt.IsZero() {
+		return nil
+	}
+
+	missing := false
+	for _, curr := range currs {
+		if _, ok := eurRates[curr]; !ok {
+			missing = true
+		}
+	}
+	if !missing {
+		return nil
+	}
+
+	// the same window as the fixing, although coins trade every day
+	prices, err := c.Crypto.SelectSymbolPrices(ctx, ecbBaseCurr, day, c.fallbackCalendarDays(day))
+	if err != nil {
+		return fmt.Errorf("c.Crypto.SelectSymbolPrices failed: %w", err)
+	}
+
+	for _, curr := range currs {
+		if _, ok := eurRates[curr]; ok {
+			continue
+		}
+		// a rate is in units per EUR, a price in EUR per unit
+		if price, ok := prices[curr]; ok && price > 0 {
+			eurRates[curr] = 1 / price
+		}
+	}
+
+	return nil
+}
+
+// maxFallbackDays returns the number of business days looked back for a fixing
+func (c Converter) maxFallbackDays() int {
+	if c.MaxFallbackDays == 0 {
+		return DefaultMaxFallbackDays
+	}
+	return c.MaxFallbackDays
+}
+
+// fallbackCalendarDays returns the number of calendar days before day which span maxFallbackDays business days, for the stores' lookback
+func (c Converter) fallbackCalendarDays(day time.Time) int {
+	return int(truncateDay(day).Sub(calendar.SubBusinessDays(day, c.maxFallbackDays())).Hours() / 24)
+}
+
+// fixing returns the Fixing of the rates of actualDay, used for the requested day
+func fixing(actualDay, day time.Time) Fixing {
+	return Fixing{
+		Day:       lystype.Date(actualDay),
+		Frequency: ecbexchangerate.Daily,
+		Fallback:  !day.IsZero() && actualDay.Before(truncateDay(day)),
+	}
+}
+
+// rateMap returns the rates of items keyed by their to_currency
+func rateMap(items []ecbexchangerate.Model) map[string]float64 {
+
+	rates := make(map[string]float64, len(items))
+	for _, item := range items {
+		rates[item.ToCurrency] = item.RateFloat64()
+	}
+	return rates
+}
+
+func notFound(curr string, day time.Time) error {
+	return fmt.Errorf("%w: no exchange rate for currency %s on %s", ErrRateNotFound, curr, day.Format("2006-01-02"))
+}
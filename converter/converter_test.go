@@ -0,0 +1,181 @@
+package converter_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/loveyourstack/connectors/converter"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbcurrency"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbrateoverride"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+var day1 = time.Date(2024, 9, 2, 0, 0, 0, 0, time.UTC) // Monday
+
+func TestConverterOverrides(t *testing.T) {
+
+	ctx := context.Background()
+	store := memRates(t, map[time.Time]map[string]float32{day1: {"USD": 1.1061, "GBP": 0.84183}})
+
+	approvedAt := lystype.Datetime(time.Now().Add(-time.Hour))
+	revokedAt := lystype.Datetime(time.Now().Add(-time.Minute))
+	usd := ecbrateoverride.Model{Id: 1, ApprovedBy: "bob", ApprovedAt: &approvedAt, Input: ecbrateoverride.Input{Day: lystype.Date(day1), Rate: 1.2, ToCurrency: "USD"}}
+	revokedUsd := usd
+	revokedUsd.RevokedBy, revokedUsd.RevokedAt = "carol", &revokedAt
+	pendingUsd := usd
+	pendingUsd.ApprovedBy, pendingUsd.ApprovedAt = "", nil
+	otherDayUsd := usd
+	otherDayUsd.Day = lystype.Date(day1.AddDate(0, 0, -3))
+	chf := ecbrateoverride.Model{Id: 2, ApprovedBy: "bob", ApprovedAt: &approvedAt, Input: ecbrateoverride.Input{Day: lystype.Date(day1), Rate: 0.9412, ToCurrency: "CHF"}}
+
+	tests := []struct {
+		name          string
+		overrides     converter.OverrideStore
+		wantUsd       float64
+		wantCorrected []string
+	}{
+		{"no override store", nil, 1.1061, nil},
+		{"approved override beats the published rate", overrideStub{usd}, 1.2, []string{"USD"}},
+		{"revoked override falls back", overrideStub{revokedUsd}, 1.1061, nil},
+		{"pending override is not used", overrideStub{pendingUsd}, 1.1061, nil},
+		{"override of another day is not used", overrideStub{otherDayUsd}, 1.1061, nil},
+		{"override adds a rate", overrideStub{chf}, 1.1061, []string{"CHF"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			conv := converter.Converter{Store: store, Overrides: tt.overrides}
+
+			rates, err := conv.Rates(ctx, "EUR", day1)
+			if err != nil {
+				t.Fatalf("conv.Rates failed: %s", err.Error())
+			}
+			if rates.Rates["USD"] != tt.wantUsd || rates.Rates["GBP"] != 0.84183 {
+				t.Errorf("rates: got USD %v, GBP %v, want USD %v, GBP 0.84183", rates.Rates["USD"], rates.Rates["GBP"], tt.wantUsd)
+			}
+			if !slices.Equal(rates.Corrected, tt.wantCorrected) {
+				t.Errorf("rates corrected: got %v, want %v", rates.Corrected, tt.wantCorrected)
+			}
+
+			conv1, err := conv.Convert(ctx, "EUR", "USD", 100, day1)
+			if err != nil {
+				t.Fatalf("conv.Convert failed: %s", err.Error())
+			}
+			if conv1.Rate != tt.wantUsd || conv1.Result != 100*tt.wantUsd {
+				t.Errorf("EUR to USD: got rate %v, result %v, want %v, %v", conv1.Rate, conv1.Result, tt.wantUsd, 100*tt.wantUsd)
+			}
+			// only the corrections the rate depends on
+			wantCorrected := slices.DeleteFunc(slices.Clone(tt.wantCorrected), func(curr string) bool { return curr != "USD" })
+			if !slices.Equal(conv1.Corrected, wantCorrected) {
+				t.Errorf("EUR to USD corrected: got %v, want %v", conv1.Corrected, wantCorrected)
+			}
+		})
+	}
+}
+
+func TestConverterRangeRates(t *testing.T) {
+
+	ctx := context.Background()
+	day2 := day1.AddDate(0, 0, 1)
+	conv := converter.Converter{Store: memRates(t, map[time.Time]map[string]float32{
+		day1: {"USD": 1.1061, "GBP": 0.84183},
+		day2: {"GBP": 0.84211}, // no rate of USD
+	})}
+
+	type rate struct {
+		day      time.Time
+		from, to string
+		rate     float32
+	}
+	tests := []struct {
+		base string
+		want []rate
+	}{
+		{"EUR", []rate{{day1, "EUR", "GBP", 0.84183}, {day1, "EUR", "USD", 1.1061}, {day2, "EUR", "GBP", 0.84211}}},
+		{"USD", []rate{{day1, "USD", "EUR", float32(1 / 1.1061)}, {day1, "USD", "GBP", float32(0.84183 / 1.1061)}}},
+		{"CHF", nil},
+	}
+
+	for _, tt := range tests {
+		items, err := conv.RangeRates(ctx, tt.base, ecbexchangerate.Daily, day1, day2)
+		if err != nil {
+			t.Fatalf("conv.RangeRates failed: %s", err.Error())
+		}
+		var got []rate
+		for _, item := range items {
+			got = append(got, rate{time.Time(item.Day), item.FromCurrency, item.ToCurrency, item.Rate})
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("rates from %s: got %v, want %v", tt.base, got, tt.want)
+		}
+	}
+}
+
+// overrideStub is an OverrideStore of the overrides it holds
+type overrideStub []ecbrateoverride.Model
+
+func (s overrideStub) SelectApproved(ctx context.Context, startDate, endDate, knownAt time.Time) (items []ecbrateoverride.Model, err error) {
+
+	if knownAt.IsZero() {
+		knownAt = time.Now()
+	}
+	for _, o := range s {
+		day := time.Time(o.Day)
+		if day.Before(startDate) || day.After(endDate) || o.ApprovedAt == nil || time.Time(*o.ApprovedAt).After(knownAt) {
+			continue
+		}
+		if o.RevokedAt != nil && !time.Time(*o.RevokedAt).After(knownAt) {
+			continue
+		}
+		items = append(items, o)
+	}
+	return items, nil
+}
+
+// memRates returns a MemStore of the daily rates from EUR of each day, keyed by currency
+func memRates(tb testing.TB, days map[time.Time]map[string]float32) *ecbexchangerate.MemStore {
+
+	tb.Helper()
+
+	ctx := context.Background()
+	currStore := ecbcurrency.NewMemStore()
+	codes := []string{"EUR"}
+	for _, rates := range days {
+		for code := range rates {
+			if !slices.Contains(codes, code) {
+				codes = append(codes, code)
+			}
+		}
+	}
+	for _, code := range codes {
+		if _, err := currStore.Insert(ctx, ecbcurrency.Input{Code: code, Name: code}); err != nil {
+			tb.Fatalf("currStore.Insert failed: %s", err.Error())
+		}
+	}
+	currMap, err := currStore.SelectCodeIdMap(ctx)
+	if err != nil {
+		tb.Fatalf("currStore.SelectCodeIdMap failed: %s", err.Error())
+	}
+
+	var inputs []ecbexchangerate.Input
+	for day, rates := range days {
+		for code, rate := range rates {
+			inputs = append(inputs, ecbexchangerate.Input{
+				Day:            lystype.Date(day),
+				Frequency:      ecbexchangerate.Daily,
+				FromCurrencyFk: currMap["EUR"],
+				Rate:           rate,
+				ToCurrencyFk:   currMap[code],
+			})
+		}
+	}
+	store := ecbexchangerate.NewMemStore(currStore)
+	if _, err = store.BulkInsert(ctx, inputs); err != nil {
+		tb.Fatalf("store.BulkInsert failed: %s", err.Error())
+	}
+	return store
+}
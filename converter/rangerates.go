@@ -0,0 +1,76 @@
+package converter
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+)
+
+// RangeRates returns the rates from base with frequency freq between startDate and endDate (inclusive), ordered by day and to_currency, e.g. to export them
+// only the ECB's rates from EUR are stored: those from another base are derived from them when read, EUR at 1 / the EUR rate of base and each other currency at its EUR rate / that of base,
+// and have no Id or provenance. Periods without a rate of base are skipped. Overrides, KnownAt and Crypto are not used
+func (c Converter) RangeRates(ctx context.Context, base string, freq ecbexchangerate.Frequency, startDate, endDate time.Time) (items []ecbexchangerate.Model, err error) {
+
+	var xrStore RateStore = ecbexchangerate.Store{Db: c.Db}
+	if c.Store != nil {
+		xrStore = c.Store
+	}
+	rangeStore, ok := xrStore.(RangeRateStore)
+	if !ok {
+		return nil, fmt.Errorf("%w: the rate store %T cannot read date ranges", cerrors.ErrValidationFailed, xrStore)
+	}
+
+	eurItems, err := rangeStore.SelectInRange(ctx, ecbBaseCurr, freq, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("rangeStore.SelectInRange failed: %w", err)
+	}
+	if base == ecbBaseCurr {
+		return eurItems, nil
+	}
+
+	// EUR rate of base per period
+	baseItems := make(map[time.Time]ecbexchangerate.Model)
+	for _, item := range eurItems {
+		if item.ToCurrency == base {
+			baseItems[time.Time(item.Day)] = item
+		}
+	}
+
+	items = make([]ecbexchangerate.Model, 0, len(eurItems))
+	for _, item := range eurItems {
+
+		baseItem, ok := baseItems[time.Time(item.Day)]
+		if !ok {
+			continue
+		}
+		baseRate := baseItem.RateFloat64()
+
+		crossItem := ecbexchangerate.Model{
+			FromCurrency: base,
+			ToCurrency:   item.ToCurrency,
+			Input: ecbexchangerate.Input{
+				Day:            item.Day,
+				Frequency:      item.Frequency,
+				FromCurrencyFk: baseItem.ToCurrencyFk,
+				Rate:           float32(item.RateFloat64() / baseRate),
+				ToCurrencyFk:   item.ToCurrencyFk,
+			},
+		}
+		if item.ToCurrency == base {
+			crossItem.ToCurrency, crossItem.ToCurrencyFk, crossItem.Rate = ecbBaseCurr, item.FromCurrencyFk, float32(1/baseRate)
+		}
+		items = append(items, crossItem)
+	}
+
+	// EUR takes the place of base in the order of each period
+	slices.SortStableFunc(items, func(a, b ecbexchangerate.Model) int {
+		return cmp.Or(time.Time(a.Day).Compare(time.Time(b.Day)), cmp.Compare(a.ToCurrency, b.ToCurrency))
+	})
+
+	return items, nil
+}
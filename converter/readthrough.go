@@ -0,0 +1,180 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/loveyourstack/connectors/calendar"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+)
+
+// DefaultFetchInterval is the minimum time between two fetches of a ReadThrough if FetchInterval is not set
+const DefaultFetchInterval = time.Second
+
+// number of calendar days fetched by ReadThrough if the store has no rates at all, to find the latest fixing
+const readThroughLatestDays int = 7
+
+// Fetcher fetches the daily EUR rates of a date range from the ECB and stores them, so that they can then be read from the store of a ReadThrough
+// a range without rates, e.g. of TARGET closing days only, should return an error matching cerrors.ErrNotFound. See csyncdb.EcbRateFetcher
+type Fetcher interface {
+	FetchRates(ctx context.Context, startDate, endDate time.Time) error
+}
+
+// FetcherFunc adapts a func to a Fetcher
+type FetcherFunc func(ctx context.Context, startDate, endDate time.Time) error
+
+func (f FetcherFunc) FetchRates(ctx context.Context, startDate, endDate time.Time) error {
+	return f(ctx, startDate, endDate)
+}
+
+// ReadThrough is a RateStore which, when the daily EUR fixing of a day is missing from Store, fetches the rates of the fallback window of the day with Fetcher,
+// which stores them, and reads them again, so that interactive tools can convert on any day before a full backfill has completed
+// fetches are serialized and spaced by FetchInterval, and each window is fetched at most once, so that lookups of days the ECB has no rates for don't flood its API
+// wrap it in a Cache rather than the other way around, since a Cache below it would not see the fetched rates. It is safe for concurrent use if Store and Fetcher are
+type ReadThrough struct {
+	Store         RateStore
+	Fetcher       Fetcher
+	FetchInterval time.Duration // minimum time between two fetches. Defaults to DefaultFetchInterval
+
+	mu        sync.Mutex // held during fetches
+	lastFetch time.Time
+	fetched   map[fetchWindow]bool // bounded by maxCacheDays
+}
+
+type fetchWindow struct {
+	startDate time.Time
+	endDate   time.Time
+}
+
+var (
+	_ RateStore      = (*ReadThrough)(nil)
+	_ RangeRateStore = (*ReadThrough)(nil)
+	_ AsOfRateStore  = (*ReadThrough)(nil)
+)
+
+// NewReadThrough returns a ReadThrough of store, fetching missing rates with fetcher
+func NewReadThrough(store RateStore, fetcher Fetcher) *ReadThrough {
+	return &ReadThrough{Store: store, Fetcher: fetcher}
+}
+
+// SelectDayOnOrBefore returns the result of Store.SelectDayOnOrBefore. If no fixing is found, the window of day is fetched first
+// only daily EUR rates are fetched: other lookups are passed through
+func (r *ReadThrough) SelectDayOnOrBefore(ctx context.Context, baseCurr string, freq ecbexchangerate.Frequency, day time.Time, maxFallbackDays int) (actualDay time.Time, items []ecbexchangerate.Model, err error) {
+
+	actualDay, items, err = r.Store.SelectDayOnOrBefore(ctx, baseCurr, freq, day, maxFallbackDays)
+	if err == nil {
+		return actualDay, items, nil
+	}
+	err = fmt.Errorf("r.Store.SelectDayOnOrBefore failed: %w", err)
+	if !errors.Is(err, cerrors.ErrNotFound) || baseCurr != ecbBaseCurr || freq != ecbexchangerate.Daily {
+		return time.Time{}, nil, err
+	}
+
+	// the ECB has no rates after today
+	endDate := truncateDay(day)
+	if today := truncateDay(time.Now().In(calendar.Location)); endDate.After(today) {
+		endDate = today
+	}
+
+	fetched, fetchErr := r.fetch(ctx, endDate.AddDate(0, 0, -maxFallbackDays), endDate)
+	if fetchErr != nil {
+		return time.Time{}, nil, fetchErr
+	}
+	if !fetched {
+		return time.Time{}, nil, err
+	}
+
+	actualDay, items, err = r.Store.SelectDayOnOrBefore(ctx, baseCurr, freq, day, maxFallbackDays)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("r.Store.SelectDayOnOrBefore failed after fetch: %w", err)
+	}
+	return actualDay, items, nil
+}
+
+// SelectLatestDay returns the result of Store.SelectLatestDay. If Store has no daily rates, those of the last days are fetched first
+func (r *ReadThrough) SelectLatestDay(ctx context.Context, freq ecbexchangerate.Frequency) (latestDay time.Time, count int64, err error) {
+
+	latestDay, count, err = r.Store.SelectLatestDay(ctx, freq)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("r.Store.SelectLatestDay failed: %w", err)
+	}
+	if !latestDay.IsZero() || freq != ecbexchangerate.Daily {
+		return latestDay, count, nil
+	}
+
+	today := truncateDay(time.Now().In(calendar.Location))
+	fetched, err := r.fetch(ctx, today.AddDate(0, 0, -readThroughLatestDays), today)
+	if err != nil || !fetched {
+		return time.Time{}, 0, err
+	}
+
+	latestDay, count, err = r.Store.SelectLatestDay(ctx, freq)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("r.Store.SelectLatestDay failed after fetch: %w", err)
+	}
+	return latestDay, count, nil
+}
+
+// SelectInRange passes through to Store if it is a RangeRateStore. Ranges are not fetched, since they may be long: backfill them with a sync
+func (r *ReadThrough) SelectInRange(ctx context.Context, baseCurr string, freq ecbexchangerate.Frequency, startDate, endDate time.Time) (items []ecbexchangerate.Model, err error) {
+
+	rangeStore, ok := r.Store.(RangeRateStore)
+	if !ok {
+		return nil, fmt.Errorf("%w: the rate store %T cannot read date ranges", cerrors.ErrValidationFailed, r.Store)
+	}
+	return rangeStore.SelectInRange(ctx, baseCurr, freq, startDate, endDate)
+}
+
+// SelectDayOnOrBeforeAsOf passes through to Store if it is an AsOfRateStore. Rates fetched now were not known at a past time, so nothing is fetched
+func (r *ReadThrough) SelectDayOnOrBeforeAsOf(ctx context.Context, baseCurr string, freq ecbexchangerate.Frequency, day time.Time, maxFallbackDays int, knownAt time.Time) (actualDay time.Time, items []ecbexchangerate.Model, err error) {
+
+	asOfStore, ok := r.Store.(AsOfRateStore)
+	if !ok {
+		return time.Time{}, nil, fmt.Errorf("%w: KnownAt is set, but the rate store %T does not keep the values of rates over time", cerrors.ErrValidationFailed, r.Store)
+	}
+	return asOfStore.SelectDayOnOrBeforeAsOf(ctx, baseCurr, freq, day, maxFallbackDays, knownAt)
+}
+
+// fetch fetches the rates of the date range with r.Fetcher, unless it was fetched before. fetched is false if the ECB has no rates in it
+func (r *ReadThrough) fetch(ctx context.Context, startDate, endDate time.Time) (fetched bool, err error) {
+
+	w := fetchWindow{startDate: startDate, endDate: endDate}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// also found if a concurrent lookup of the same day fetched it while this one waited for the lock
+	if found, ok := r.fetched[w]; ok {
+		return found, nil
+	}
+
+	interval := r.FetchInterval
+	if interval <= 0 {
+		interval = DefaultFetchInterval
+	}
+	if wait := time.Until(r.lastFetch.Add(interval)); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	err = r.Fetcher.FetchRates(ctx, startDate, endDate)
+	r.lastFetch = time.Now()
+	if err != nil && !errors.Is(err, cerrors.ErrNotFound) {
+		// not remembered, so that the window is fetched again once the ECB is available
+		return false, fmt.Errorf("r.Fetcher.FetchRates failed: %w", err)
+	}
+
+	if r.fetched == nil || len(r.fetched) >= maxCacheDays {
+		r.fetched = make(map[fetchWindow]bool)
+	}
+	r.fetched[w] = err == nil
+
+	return err == nil, nil
+}
@@ -0,0 +1,206 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/loveyourstack/connectors/calendar"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// Interpolation decides the rate of a day without a fixing, such as a weekend or TARGET closing day, in Converter.Series
+type Interpolation int
+
+const (
+	// CarryForward uses the rate of the previous fixing, as Convert does. It is the default
+	CarryForward Interpolation = iota
+
+	// CarryBackward uses the rate of the next fixing
+	CarryBackward
+
+	// Linear interpolates linearly by calendar day between the rates of the previous and the next fixing
+	Linear
+)
+
+// SeriesOptions contains the settings of a Series
+type SeriesOptions struct {
+	Interpolation    Interpolation // how days without a fixing are filled. CarryForward by default
+	BusinessDaysOnly bool          // if true, weekends and TARGET closing days are omitted rather than filled. Business days without a fixing are still filled
+}
+
+// RangeRateStore is implemented by the rate stores which can read the rates of a date range, such as ecbexchangerate.Store, MemStore and SQLiteStore, and is needed for Converter.Series
+type RangeRateStore interface {
+	SelectInRange(ctx context.Context, baseCurr string, freq ecbexchangerate.Frequency, startDate, endDate time.Time) (items []ecbexchangerate.Model, err error)
+}
+
+// DailyRate is the rate of a calendar day in a Series
+type DailyRate struct {
+	Day          lystype.Date   `json:"day"`
+	Rate         float64        `json:"rate"`
+	Interpolated bool           `json:"interpolated"`        // true if Day has no fixing and Rate was derived from the neighboring fixings
+	SourceDays   []lystype.Date `json:"source_days"`         // days of the fixings Rate is based on: Day itself if it has a fixing, the previous or next fixing if carried, both if Linear
+	Corrected    bool           `json:"corrected,omitempty"` // true if Rate is based on a manual correction of the rate of from or to on a source day, see Converter.Overrides
+}
+
+// Series returns the rate from one ECB currency to another on each calendar day from startDate to endDate (inclusive), filling the days without a fixing as set in opts
+// fixings are looked for up to MaxFallbackDays business days before or after a day: weekends and TARGET closing days in between do not count. A day which cannot be filled
+// returns an error matching ErrRateNotFound. Coin symbols and KnownAt are not supported
+func (c Converter) Series(ctx context.Context, from, to string, startDate, endDate time.Time, opts SeriesOptions) (series []DailyRate, err error) {
+
+	if !c.KnownAt.IsZero() {
+		return nil, fmt.Errorf("%w: Series does not support KnownAt", cerrors.ErrValidationFailed)
+	}
+
+	var xrStore RateStore = ecbexchangerate.Store{Db: c.Db}
+	if c.Store != nil {
+		xrStore = c.Store
+	}
+	rangeStore, ok := xrStore.(RangeRateStore)
+	if !ok {
+		return nil, fmt.Errorf("%w: the rate store %T cannot read date ranges", cerrors.ErrValidationFailed, xrStore)
+	}
+
+	startDate, endDate = truncateDay(startDate), truncateDay(endDate)
+	if endDate.Before(startDate) {
+		return nil, fmt.Errorf("%w: endDate %s is before startDate %s", cerrors.ErrValidationFailed, endDate.Format(lystype.DateFormat), startDate.Format(lystype.DateFormat))
+	}
+
+	// the fixings of the range and of the windows around it
+	windowStart, windowEnd := calendar.SubBusinessDays(startDate, c.maxFallbackDays()), c.addBusinessDays(endDate)
+	items, err := rangeStore.SelectInRange(ctx, ecbBaseCurr, ecbexchangerate.Daily, windowStart, windowEnd)
+	if err != nil {
+		return nil, fmt.Errorf("rangeStore.SelectInRange failed: %w", err)
+	}
+
+	// the rate from from to to of each fixing day which has both
+	eurRatesByDay := make(map[time.Time]map[string]float64)
+	for _, item := range items {
+		day := truncateDay(time.Time(item.Day))
+		if eurRatesByDay[day] == nil {
+			eurRatesByDay[day] = map[string]float64{ecbBaseCurr: 1}
+		}
+		eurRatesByDay[day][item.ToCurrency] = item.RateFloat64()
+	}
+
+	// approved corrections replace the rates of the fixing of their day, if from or to
+	correctedDays := make(map[time.Time]bool)
+	if c.Overrides != nil {
+		overrides, err := c.Overrides.SelectApproved(ctx, windowStart, windowEnd, time.Time{})
+		if err != nil {
+			return nil, fmt.Errorf("c.Overrides.SelectApproved failed: %w", err)
+		}
+		for _, o := range overrides {
+			day := truncateDay(time.Time(o.Day))
+			if eurRatesByDay[day] == nil || (o.ToCurrency != from && o.ToCurrency != to) {
+				continue
+			}
+			eurRatesByDay[day][o.ToCurrency] = o.Rate
+			correctedDays[day] = true
+		}
+	}
+
+	fixingDays := []time.Time{}
+	fixingRates := make(map[time.Time]float64)
+	for day := windowStart; !day.After(windowEnd); day = day.AddDate(0, 0, 1) {
+		fromRate, fromOk := eurRatesByDay[day][from]
+		toRate, toOk := eurRatesByDay[day][to]
+		if fromOk && toOk {
+			fixingDays = append(fixingDays, day)
+			fixingRates[day] = toRate / fromRate
+		}
+	}
+
+	// i is the index of the first fixing day after day
+	i := 0
+	for day := startDate; !day.After(endDate); day = day.AddDate(0, 0, 1) {
+
+		for i < len(fixingDays) && !fixingDays[i].After(day) {
+			i++
+		}
+
+		if opts.BusinessDaysOnly && !calendar.IsBusinessDay(day) {
+			continue
+		}
+
+		if rate, ok := fixingRates[day]; ok {
+			series = append(series, DailyRate{Day: lystype.Date(day), Rate: rate, SourceDays: []lystype.Date{lystype.Date(day)}, Corrected: correctedDays[day]})
+			continue
+		}
+
+		var prev, next time.Time
+		if i > 0 && !fixingDays[i-1].Before(calendar.SubBusinessDays(day, c.maxFallbackDays())) {
+			prev = fixingDays[i-1]
+		}
+		if i < len(fixingDays) && !fixingDays[i].After(c.addBusinessDays(day)) {
+			next = fixingDays[i]
+		}
+
+		rate, sourceDays, ok := interpolate(opts.Interpolation, day, prev, next, fixingRates)
+		if !ok {
+			return nil, fmt.Errorf("%w: no fixing of %s/%s within %d business days of %s to interpolate from", ErrRateNotFound, from, to, c.maxFallbackDays(), day.Format(lystype.DateFormat))
+		}
+		dr := DailyRate{Day: lystype.Date(day), Rate: rate, Interpolated: true, SourceDays: sourceDays}
+		for _, sd := range sourceDays {
+			dr.Corrected = dr.Corrected || correctedDays[time.Time(sd)]
+		}
+		series = append(series, dr)
+	}
+
+	return series, nil
+}
+
+// SeriesMap returns the Series keyed by day, e.g. to look up the rate and source fixings of the day of each of a set of transactions
+func (c Converter) SeriesMap(ctx context.Context, from, to string, startDate, endDate time.Time, opts SeriesOptions) (rateMap map[time.Time]DailyRate, err error) {
+
+	series, err := c.Series(ctx, from, to, startDate, endDate, opts)
+	if err != nil {
+		return nil, fmt.Errorf("c.Series failed: %w", err)
+	}
+
+	rateMap = make(map[time.Time]DailyRate, len(series))
+	for _, r := range series {
+		rateMap[time.Time(r.Day)] = r
+	}
+	return rateMap, nil
+}
+
+// interpolate returns the rate of day by mode from the fixings of prev and next, either of which may be zero if there is none, and the days of the fixings used
+// ok is false if mode needs a missing one
+func interpolate(mode Interpolation, day, prev, next time.Time, fixingRates map[time.Time]float64) (rate float64, sourceDays []lystype.Date, ok bool) {
+
+	switch mode {
+	case CarryBackward:
+		if next.IsZero() {
+			return 0, nil, false
+		}
+		return fixingRates[next], []lystype.Date{lystype.Date(next)}, true
+	case Linear:
+		if prev.IsZero() || next.IsZero() {
+			return 0, nil, false
+		}
+		frac := day.Sub(prev).Hours() / next.Sub(prev).Hours()
+		return fixingRates[prev] + (fixingRates[next]-fixingRates[prev])*frac, []lystype.Date{lystype.Date(prev), lystype.Date(next)}, true
+	default:
+		if prev.IsZero() {
+			return 0, nil, false
+		}
+		return fixingRates[prev], []lystype.Date{lystype.Date(prev)}, true
+	}
+}
+
+// addBusinessDays returns the MaxFallbackDays-th business day after the date of day
+func (c Converter) addBusinessDays(day time.Time) time.Time {
+
+	day = truncateDay(day)
+	for range c.maxFallbackDays() {
+		day = calendar.NextBusinessDay(day)
+	}
+	return day
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
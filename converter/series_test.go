@@ -0,0 +1,154 @@
+package converter_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/loveyourstack/connectors/converter"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+func TestConverterSeries(t *testing.T) {
+
+	// Good Friday, 29 March, and Easter Monday, 1 April 2024, are TARGET closing days: there are no fixings from 29 March to 1 April
+	store := memRates(t, map[time.Time]map[string]float32{
+		date(t, "2024-03-27"): {"USD": 1.0826, "GBP": 0.8576},
+		date(t, "2024-03-28"): {"USD": 1.0811, "GBP": 0.8551},
+		date(t, "2024-04-02"): {"USD": 1.0772, "GBP": 0.8557},
+		date(t, "2024-04-03"): {"USD": 1.08, "GBP": 0.8575},
+	})
+
+	type dailyRate struct {
+		day          string
+		rate         float64
+		interpolated bool
+		sourceDays   []string
+	}
+	tests := []struct {
+		name            string
+		from, to        string
+		start, end      string
+		opts            converter.SeriesOptions
+		maxFallbackDays int
+		want            []dailyRate
+	}{
+		{"carry forward over Easter", "EUR", "USD", "2024-03-28", "2024-04-02", converter.SeriesOptions{}, 0, []dailyRate{
+			{"2024-03-28", 1.0811, false, []string{"2024-03-28"}},
+			{"2024-03-29", 1.0811, true, []string{"2024-03-28"}},
+			{"2024-03-30", 1.0811, true, []string{"2024-03-28"}},
+			{"2024-03-31", 1.0811, true, []string{"2024-03-28"}},
+			{"2024-04-01", 1.0811, true, []string{"2024-03-28"}},
+			{"2024-04-02", 1.0772, false, []string{"2024-04-02"}},
+		}},
+		{"carry backward over Easter", "EUR", "USD", "2024-03-29", "2024-04-01", converter.SeriesOptions{Interpolation: converter.CarryBackward}, 0, []dailyRate{
+			{"2024-03-29", 1.0772, true, []string{"2024-04-02"}},
+			{"2024-03-30", 1.0772, true, []string{"2024-04-02"}},
+			{"2024-03-31", 1.0772, true, []string{"2024-04-02"}},
+			{"2024-04-01", 1.0772, true, []string{"2024-04-02"}},
+		}},
+		{"linear over Easter", "EUR", "USD", "2024-03-29", "2024-04-01", converter.SeriesOptions{Interpolation: converter.Linear}, 0, []dailyRate{
+			{"2024-03-29", 1.0811 - 0.0039*1/5, true, []string{"2024-03-28", "2024-04-02"}},
+			{"2024-03-30", 1.0811 - 0.0039*2/5, true, []string{"2024-03-28", "2024-04-02"}},
+			{"2024-03-31", 1.0811 - 0.0039*3/5, true, []string{"2024-03-28", "2024-04-02"}},
+			{"2024-04-01", 1.0811 - 0.0039*4/5, true, []string{"2024-03-28", "2024-04-02"}},
+		}},
+		{"business days only", "EUR", "USD", "2024-03-28", "2024-04-02", converter.SeriesOptions{BusinessDaysOnly: true}, 0, []dailyRate{
+			{"2024-03-28", 1.0811, false, []string{"2024-03-28"}},
+			{"2024-04-02", 1.0772, false, []string{"2024-04-02"}},
+		}},
+		{"cross rate", "GBP", "USD", "2024-03-27", "2024-03-27", converter.SeriesOptions{}, 0, []dailyRate{
+			{"2024-03-27", 1.0826 / 0.8576, false, []string{"2024-03-27"}},
+		}},
+		{"carry backward at the start", "EUR", "USD", "2024-03-26", "2024-03-26", converter.SeriesOptions{Interpolation: converter.CarryBackward}, 0, []dailyRate{
+			{"2024-03-26", 1.0826, true, []string{"2024-03-27"}},
+		}},
+		{"carry forward at the end", "EUR", "USD", "2024-04-04", "2024-04-08", converter.SeriesOptions{}, 0, []dailyRate{
+			{"2024-04-04", 1.08, true, []string{"2024-04-03"}},
+			{"2024-04-05", 1.08, true, []string{"2024-04-03"}},
+			{"2024-04-06", 1.08, true, []string{"2024-04-03"}},
+			{"2024-04-07", 1.08, true, []string{"2024-04-03"}},
+			{"2024-04-08", 1.08, true, []string{"2024-04-03"}},
+		}},
+		{"closing days don't count towards the window", "EUR", "USD", "2024-04-01", "2024-04-01", converter.SeriesOptions{}, 1, []dailyRate{
+			{"2024-04-01", 1.0811, true, []string{"2024-03-28"}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			conv := converter.Converter{Store: store, MaxFallbackDays: tt.maxFallbackDays}
+			series, err := conv.Series(context.Background(), tt.from, tt.to, date(t, tt.start), date(t, tt.end), tt.opts)
+			if err != nil {
+				t.Fatalf("conv.Series failed: %s", err.Error())
+			}
+			if len(series) != len(tt.want) {
+				t.Fatalf("series: got %d days, want %d", len(series), len(tt.want))
+			}
+
+			for i, want := range tt.want {
+				got := series[i]
+				var sourceDays []string
+				for _, sd := range got.SourceDays {
+					sourceDays = append(sourceDays, sd.Format(lystype.DateFormat))
+				}
+				if got.Day.Format(lystype.DateFormat) != want.day || math.Abs(got.Rate-want.rate) > 1e-9 || got.Interpolated != want.interpolated || !slices.Equal(sourceDays, want.sourceDays) {
+					t.Errorf("day %d: got %s %v interpolated %t from %v, want %s %v interpolated %t from %v", i, got.Day.Format(lystype.DateFormat), got.Rate, got.Interpolated, sourceDays,
+						want.day, want.rate, want.interpolated, want.sourceDays)
+				}
+			}
+		})
+	}
+}
+
+func TestConverterSeriesNotFound(t *testing.T) {
+
+	store := memRates(t, map[time.Time]map[string]float32{
+		date(t, "2024-03-27"): {"USD": 1.0826},
+		date(t, "2024-04-03"): {"USD": 1.08},
+	})
+
+	tests := []struct {
+		name            string
+		start, end      string
+		opts            converter.SeriesOptions
+		maxFallbackDays int
+	}{
+		{"carry forward before the first fixing", "2024-03-26", "2024-03-27", converter.SeriesOptions{}, 0},
+		{"carry backward after the last fixing", "2024-04-03", "2024-04-04", converter.SeriesOptions{Interpolation: converter.CarryBackward}, 0},
+		{"linear after the last fixing", "2024-04-03", "2024-04-04", converter.SeriesOptions{Interpolation: converter.Linear}, 0},
+		{"window exceeded", "2024-04-03", "2024-04-09", converter.SeriesOptions{}, 3}, // 3 April is the 4th business day before 9 April
+		{"window exceeded over Easter", "2024-03-27", "2024-04-02", converter.SeriesOptions{}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv := converter.Converter{Store: store, MaxFallbackDays: tt.maxFallbackDays}
+			if _, err := conv.Series(context.Background(), "EUR", "USD", date(t, tt.start), date(t, tt.end), tt.opts); !errors.Is(err, converter.ErrRateNotFound) {
+				t.Errorf("conv.Series: got %v, want converter.ErrRateNotFound", err)
+			}
+		})
+	}
+
+	// the window ends on the 3rd business day: a day further off can't be filled, one within can
+	conv := converter.Converter{Store: store, MaxFallbackDays: 3}
+	if _, err := conv.Series(context.Background(), "EUR", "USD", date(t, "2024-04-03"), date(t, "2024-04-08"), converter.SeriesOptions{}); err != nil {
+		t.Errorf("conv.Series within the window failed: %s", err.Error())
+	}
+}
+
+// date returns the day of s, YYYY-MM-DD
+func date(tb testing.TB, s string) time.Time {
+
+	tb.Helper()
+
+	day, err := time.Parse(lystype.DateFormat, s)
+	if err != nil {
+		tb.Fatalf("time.Parse failed: %s", err.Error())
+	}
+	return day
+}
@@ -0,0 +1,37 @@
+package cruntime
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// analyzeMinRowsKey is the context key of the row count set by WithAnalyzeAfter
+type analyzeMinRowsKey struct{}
+
+// WithAnalyzeAfter returns a copy of ctx in which a bulk load of a store, such as ecbexchangerate.Store.BulkInsert or CopyUpsert, that inserts at least minRows rows
+// is followed by ANALYZE of the table, so that the next lookups are planned with its new statistics rather than waiting for autovacuum. Large first-time backfills benefit most
+// minRows <= 0 returns ctx unchanged
+func WithAnalyzeAfter(ctx context.Context, minRows int64) context.Context {
+
+	if minRows <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, analyzeMinRowsKey{}, minRows)
+}
+
+// AnalyzeAfterLoad runs ANALYZE on schemaName.tableName if rows, the number of rows just loaded, reaches the count set with WithAnalyzeAfter. Otherwise it does nothing
+func AnalyzeAfterLoad(ctx context.Context, db *pgxpool.Pool, schemaName, tableName string, rows int64) error {
+
+	minRows, _ := ctx.Value(analyzeMinRowsKey{}).(int64)
+	if minRows <= 0 || rows < minRows {
+		return nil
+	}
+
+	if _, err := db.Exec(ctx, "ANALYZE "+pgx.Identifier{schemaName, tableName}.Sanitize()); err != nil {
+		return fmt.Errorf("db.Exec failed for ANALYZE %s.%s: %w", schemaName, tableName, err)
+	}
+	return nil
+}
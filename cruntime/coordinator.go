@@ -0,0 +1,183 @@
+package cruntime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/loveyourstack/connectors/clog"
+)
+
+// ErrShuttingDown is returned by RunWork if shutdown has already begun
+var ErrShuttingDown = errors.New("shutting down")
+
+const defaultDrainTimeout time.Duration = 30 * time.Second
+
+// Coordinator ties long-running components (scheduler, HTTP server, webhook emitters) and in-flight work (syncs) to a single lifecycle
+// On SIGINT/SIGTERM, or if a component fails, it:
+//  1. cancels Context, so that components stop starting new work
+//  2. runs the OnShutdown funcs (e.g. http.Server.Shutdown)
+//  3. waits for components and in-flight work to finish, bounded by DrainTimeout
+//  4. if the drain timeout expires, cancels the context of in-flight work so that open transactions are rolled back
+type Coordinator struct {
+	DrainTimeout time.Duration
+	InfoLog      *slog.Logger
+	ErrorLog     *slog.Logger
+
+	ctx        context.Context // cancelled when shutdown begins
+	cancel     context.CancelFunc
+	workCtx    context.Context // cancelled when the drain timeout expires
+	workCancel context.CancelFunc
+
+	mu            sync.Mutex
+	shuttingDown  bool
+	shutdownFuncs []namedFunc
+	wg            sync.WaitGroup
+	errs          chan error
+}
+
+type namedFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func New(drainTimeout time.Duration, infoLog, errorLog *slog.Logger) *Coordinator {
+
+	if drainTimeout <= 0 {
+		drainTimeout = defaultDrainTimeout
+	}
+
+	c := &Coordinator{
+		DrainTimeout: drainTimeout,
+		InfoLog:      infoLog,
+		ErrorLog:     errorLog,
+		errs:         make(chan error, 1),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.workCtx, c.workCancel = context.WithCancel(context.Background())
+
+	return c
+}
+
+// Context returns the context which is cancelled as soon as shutdown begins. Components use it to stop starting new work
+func (c *Coordinator) Context() context.Context {
+	return c.ctx
+}
+
+// Go runs a long-running component. If fn returns an error before shutdown, shutdown is triggered and Wait returns the error
+func (c *Coordinator) Go(name string, fn func(ctx context.Context) error) {
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		err := fn(c.ctx)
+		if err != nil && c.ctx.Err() == nil {
+			select {
+			case c.errs <- fmt.Errorf("%s failed: %w", name, err):
+			default:
+				// another component already failed
+				c.ErrorLog.Error("component failed", "component", name, clog.KeyError, err.Error())
+			}
+		}
+	}()
+}
+
+// RunWork runs fn as in-flight work: its context is not cancelled when shutdown begins, only when the drain timeout expires. Returns ErrShuttingDown without calling fn if shutdown has already begun
+func (c *Coordinator) RunWork(fn func(ctx context.Context) error) error {
+
+	c.mu.Lock()
+	if c.shuttingDown {
+		c.mu.Unlock()
+		return ErrShuttingDown
+	}
+	c.wg.Add(1)
+	c.mu.Unlock()
+	defer c.wg.Done()
+
+	return fn(c.workCtx)
+}
+
+// OnShutdown registers fn to be called when shutdown begins, in reverse order of registration. fn receives a context bounded by the drain timeout
+func (c *Coordinator) OnShutdown(name string, fn func(ctx context.Context) error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.shutdownFuncs = append(c.shutdownFuncs, namedFunc{name: name, fn: fn})
+}
+
+// Shutdown begins shutdown without a signal
+func (c *Coordinator) Shutdown() {
+
+	select {
+	case c.errs <- nil:
+	default:
+	}
+}
+
+// Wait blocks until SIGINT/SIGTERM is received, Shutdown is called or a component fails, then shuts down as described on Coordinator
+// Returns the component error, if any, or an error if in-flight work had to be cancelled
+func (c *Coordinator) Wait() (err error) {
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	select {
+	case sig := <-sigCh:
+		c.InfoLog.Info("shutdown signal received", "signal", sig.String())
+	case err = <-c.errs:
+		if err != nil {
+			c.ErrorLog.Error("shutting down after component failure", clog.KeyError, err.Error())
+		}
+	}
+
+	// stop accepting new work
+	c.mu.Lock()
+	c.shuttingDown = true
+	shutdownFuncs := c.shutdownFuncs
+	c.mu.Unlock()
+	c.cancel()
+
+	start := time.Now()
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), c.DrainTimeout)
+	defer drainCancel()
+
+	for i := len(shutdownFuncs) - 1; i >= 0; i-- {
+		if sdErr := shutdownFuncs[i].fn(drainCtx); sdErr != nil {
+			c.ErrorLog.Error("shutdown func failed", "component", shutdownFuncs[i].name, clog.KeyError, sdErr.Error())
+		}
+	}
+
+	// wait for components and in-flight work
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		c.workCancel()
+		c.InfoLog.Info("shutdown completed", clog.KeyDurationMs, time.Since(start).Milliseconds())
+		return err
+
+	case <-drainCtx.Done():
+		c.ErrorLog.Error("drain timeout exceeded, cancelling in-flight work", "drain_timeout", c.DrainTimeout.String())
+		c.workCancel()
+
+		// cancelled work should return promptly, but don't hang forever
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			c.ErrorLog.Error("in-flight work did not stop after cancellation")
+		}
+		return errors.Join(err, fmt.Errorf("drain timeout of %s exceeded", c.DrainTimeout))
+	}
+}
@@ -0,0 +1,33 @@
+package cruntime
+
+import (
+	"context"
+	"time"
+)
+
+// opTimeoutKey is the context key of the timeout set by WithOpTimeout
+type opTimeoutKey struct{}
+
+// WithOpTimeout returns a copy of ctx in which each database operation of a store, such as ecbexchangerate.Store.SelectInRange, is bounded by timeout
+// unless the store sets its own Timeout. It bounds a sync's stores without reaching each of them, so that one stuck query can't hold the sync past its schedule window
+// a timeout <= 0 returns ctx unchanged
+func WithOpTimeout(ctx context.Context, timeout time.Duration) context.Context {
+
+	if timeout <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, opTimeoutKey{}, timeout)
+}
+
+// OpContext returns the context of a database operation: ctx bounded by timeout, or if timeout is 0, by that set with WithOpTimeout
+// the deadline of ctx still applies if it is earlier. The returned cancel func must be called when the operation ends
+func OpContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+
+	if timeout <= 0 {
+		timeout, _ = ctx.Value(opTimeoutKey{}).(time.Duration)
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,195 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/amzspapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/amzorder/amzorderheader"
+	"github.com/loveyourstack/connectors/stores/amzorder/amzorderitem"
+	"github.com/loveyourstack/connectors/stores/amzorder/amzsyncwindow"
+)
+
+// AmazonOrdersToTargets fetches the orders of marketplaceIds updated since the last sync, with their items, once and syncs them into each target, recording a journal entry in each
+// the window starts at the earliest end of the last window stored in the targets, or days ago for a target without one, and ends amzspapi.MinWindowLag ago
+func AmazonOrdersToTargets(ctx context.Context, targets []Target, c amzspapi.Client, marketplaceIds []string, days int) error {
+
+	windowEnd := time.Now().Add(-amzspapi.MinWindowLag).Truncate(time.Second)
+	windowStart := amazonWindowStart(ctx, targets, c, marketplaceIds, windowEnd.AddDate(0, 0, -days))
+
+	orders, itemsMap, fetchErr := getAmazonOrders(ctx, c, marketplaceIds, windowStart, windowEnd)
+
+	params := AmazonOrdersParams(marketplaceIds, windowStart, windowEnd)
+	return toTargets(ctx, targets, DatasetAmazonOrders, params, func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyAmazonOrders(ctx, db, c, orders, itemsMap, marketplaceIds, windowEnd)
+	})
+}
+
+// AmazonOrdersParams returns the journal params of an AmazonOrders run
+func AmazonOrdersParams(marketplaceIds []string, windowStart, windowEnd time.Time) string {
+	return fmt.Sprintf("marketplaces=%s lastUpdatedAfter=%s lastUpdatedBefore=%s", strings.Join(marketplaceIds, ","),
+		windowStart.UTC().Format(time.RFC3339), windowEnd.UTC().Format(time.RFC3339))
+}
+
+// amazonWindowStart returns the earliest end of the last window of marketplaceIds in targets, counting defaultStart for those without one
+// targets whose window can't be read are skipped: their sync fails when applied
+func amazonWindowStart(ctx context.Context, targets []Target, c amzspapi.Client, marketplaceIds []string, defaultStart time.Time) time.Time {
+
+	start := time.Time{}
+	for _, t := range targets {
+		for _, marketplaceId := range marketplaceIds {
+
+			lastUpdatedBefore, err := amzsyncwindow.Store{Db: t.Db}.SelectLastUpdatedBefore(ctx, marketplaceId)
+			if err != nil {
+				c.ErrorLog.Error("amzsyncwindow.Store.SelectLastUpdatedBefore failed", slog.String(clog.KeyDataset, DatasetAmazonOrders), slog.String("target", t.Name), slog.String(clog.KeyError, err.Error()))
+				continue
+			}
+			if lastUpdatedBefore.IsZero() {
+				lastUpdatedBefore = defaultStart
+			}
+			if start.IsZero() || lastUpdatedBefore.Before(start) {
+				start = lastUpdatedBefore
+			}
+		}
+	}
+
+	if start.IsZero() {
+		return defaultStart
+	}
+	return start
+}
+
+// getAmazonOrders fetches the orders updated in the window, and the items of each, with AmazonOrderId as key
+func getAmazonOrders(ctx context.Context, c amzspapi.Client, marketplaceIds []string, windowStart, windowEnd time.Time) (orders []amzspapi.Order, itemsMap map[string][]amzspapi.OrderItem, err error) {
+
+	orders, err = c.GetApiOrders(ctx, marketplaceIds, windowStart, windowEnd)
+	if err != nil {
+		return nil, nil, fmt.Errorf("c.GetApiOrders failed: %w", err)
+	}
+
+	// getOrderItems has a rate of 1 request per 2 seconds, so this takes a while for busy sellers
+	itemsMap = make(map[string][]amzspapi.OrderItem)
+	for _, ord := range orders {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		items, err := c.GetApiOrderItems(ctx, ord.AmazonOrderId)
+		if err != nil {
+			return nil, nil, fmt.Errorf("c.GetApiOrderItems failed on %s: %w", ord.AmazonOrderId, err)
+		}
+		itemsMap[ord.AmazonOrderId] = items
+	}
+
+	return orders, itemsMap, nil
+}
+
+// ApplyAmazonOrders inserts or updates the orders of db with already fetched API orders, syncs their items with itemsMap (k = AmazonOrderId),
+// and stores windowEnd as the end of the last window of marketplaceIds. c is only used for logging
+// the API orders are the ones updated in a window, so orders missing from them are not deleted
+func ApplyAmazonOrders(ctx context.Context, db *pgxpool.Pool, c amzspapi.Client, orders []amzspapi.Order, itemsMap map[string][]amzspapi.OrderItem, marketplaceIds []string, windowEnd time.Time) error {
+
+	ordStore := amzorderheader.Store{Db: db}
+	itemStore := amzorderitem.Store{Db: db}
+	unlock, err := lockStore(ordStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	apiOrdMap, err := amzspapi.OrdersToMap(orders)
+	if err != nil {
+		return fmt.Errorf("amzspapi.OrdersToMap failed: %w", err)
+	}
+
+	orderIds := make([]string, 0, len(apiOrdMap))
+	for orderId := range apiOrdMap {
+		orderIds = append(orderIds, orderId)
+	}
+
+	dbOrdMap, err := ordStore.SelectMapByNaturalKey(ctx, orderIds)
+	if err != nil {
+		return fmt.Errorf("ordStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	ordInserted, ordUpdated, itemsInserted, itemsUpdated, itemsDeleted := 0, 0, 0, 0, 0
+	for orderId, apiOrd := range apiOrdMap {
+
+		// insert or update the order
+		var orderFk int64
+		dbOrd, ok := dbOrdMap[orderId]
+		if !ok {
+			if orderFk, err = ordStore.Insert(ctx, apiOrd.Input); err != nil {
+				return fmt.Errorf("ordStore.Insert failed on order: %v: %w", orderId, err)
+			}
+			ordInserted++
+		} else {
+			orderFk = dbOrd.Id
+			if !ordStore.Equal(apiOrd, dbOrd) {
+				if err = ordStore.Update(ctx, apiOrd.Input, dbOrd.Id); err != nil {
+					return fmt.Errorf("ordStore.Update failed on order: %v: %w", orderId, err)
+				}
+				ordUpdated++
+			}
+		}
+
+		// sync its items
+		apiItemsMap, err := amzspapi.OrderItemsToMap(itemsMap[orderId], orderFk)
+		if err != nil {
+			return fmt.Errorf("amzspapi.OrderItemsToMap failed on order: %v: %w", orderId, err)
+		}
+		dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx, orderFk)
+		if err != nil {
+			return fmt.Errorf("itemStore.SelectMapByNaturalKey failed on order: %v: %w", orderId, err)
+		}
+
+		newItems := []amzorderitem.Input{}
+		for key, apiItem := range apiItemsMap {
+			dbItem, ok := dbItemsMap[key]
+			if !ok {
+				newItems = append(newItems, apiItem.Input)
+				continue
+			}
+			if !itemStore.Equal(apiItem, dbItem) {
+				if err = itemStore.Update(ctx, apiItem.Input, dbItem.Id); err != nil {
+					return fmt.Errorf("itemStore.Update failed on ID: %v: %w", dbItem.Id, err)
+				}
+				itemsUpdated++
+			}
+		}
+		for key, dbItem := range dbItemsMap {
+			if _, ok := apiItemsMap[key]; !ok {
+				if err = itemStore.Delete(ctx, dbItem.Id); err != nil {
+					return fmt.Errorf("itemStore.Delete failed on ID: %v: %w", dbItem.Id, err)
+				}
+				itemsDeleted++
+			}
+		}
+		if len(newItems) > 0 {
+			if _, err = itemStore.BulkInsert(ctx, newItems); err != nil {
+				return fmt.Errorf("itemStore.BulkInsert failed on order: %v: %w", orderId, err)
+			}
+			itemsInserted += len(newItems)
+		}
+	}
+
+	// the next sync continues from the end of this window
+	windowStore := amzsyncwindow.Store{Db: db}
+	for _, marketplaceId := range marketplaceIds {
+		if err = windowStore.SetLastUpdatedBefore(ctx, marketplaceId, windowEnd); err != nil {
+			return fmt.Errorf("windowStore.SetLastUpdatedBefore failed: %w", err)
+		}
+	}
+
+	c.InfoLog.Info("synced orders", slog.String(clog.KeyDataset, DatasetAmazonOrders), slog.Int("inserted", ordInserted), slog.Int("updated", ordUpdated),
+		slog.Int("items_inserted", itemsInserted), slog.Int("items_updated", itemsUpdated), slog.Int("items_deleted", itemsDeleted))
+
+	return nil
+}
@@ -0,0 +1,15 @@
+package csyncdb
+
+// splitUpdates returns the inputs of updates, keyed by DB ID, and their IDs at the same index, as taken by the BulkUpdate funcs of the stores
+// BulkUpdate sends the updates in a single round trip, rather than one per row
+func splitUpdates[T any](updates map[int64]T) (inputs []T, ids []int64) {
+
+	inputs = make([]T, 0, len(updates))
+	ids = make([]int64, 0, len(updates))
+	for id, input := range updates {
+		inputs = append(inputs, input)
+		ids = append(ids, id)
+	}
+
+	return inputs, ids
+}
@@ -0,0 +1,52 @@
+package csyncdb
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/apiclients/ecbapi/ecbapitest"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+)
+
+func BenchmarkDiffEcbExchangeRates(b *testing.B) {
+
+	for _, rows := range []int{10_000, 1_000_000} {
+
+		apiItemsMap, err := ecbapi.ExchangeRatesToMap(ecbapitest.GenExchangeRates(rows), ecbapitest.GenCurrMap())
+		if err != nil {
+			b.Fatalf("ecbapi.ExchangeRatesToMap failed: %s", err.Error())
+		}
+		dbItemsMap := genDbItemsMap(apiItemsMap, 0.01)
+		equal := ecbexchangerate.Store{}.Equal
+
+		b.Run(fmt.Sprintf("rows=%d", rows), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				DiffEcbExchangeRates(apiItemsMap, dbItemsMap, equal)
+			}
+		})
+	}
+}
+
+// genDbItemsMap returns a copy of apiItemsMap as stored items, with the rate of the changed fraction altered
+func genDbItemsMap(apiItemsMap map[ecbexchangerate.NaturalKey]ecbexchangerate.Model, changed float64) map[ecbexchangerate.NaturalKey]ecbexchangerate.Model {
+
+	every := 0
+	if changed > 0 {
+		every = int(1 / changed)
+	}
+
+	dbItemsMap := make(map[ecbexchangerate.NaturalKey]ecbexchangerate.Model, len(apiItemsMap))
+	i := 0
+	for key, item := range apiItemsMap {
+		i++
+		item.Id = int64(i)
+		if every > 0 && i%every == 0 {
+			item.Rate += 0.01
+		}
+		dbItemsMap[key] = item
+	}
+
+	return dbItemsMap
+}
@@ -0,0 +1,196 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/coingeckoapi"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/sink"
+	"github.com/loveyourstack/connectors/stores/coingecko/cgcoin"
+	"github.com/loveyourstack/connectors/stores/coingecko/cgprice"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// CoingeckoCoinsToTargets fetches the coins with ids once and syncs them into each target, recording a journal entry in each
+// coins not in ids are kept, so that their prices are not deleted when a coin is removed from the sync config
+func CoingeckoCoinsToTargets(ctx context.Context, targets []Target, c coingeckoapi.Client, ids []string) error {
+
+	apiItems, fetchErr := c.GetApiCoins(ctx, ids)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiCoins failed: %w", fetchErr)
+	}
+
+	return toTargets(ctx, targets, DatasetCoingeckoCoins, "", func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyCoingeckoCoins(ctx, db, c, coingeckoapi.CoinsToMap(apiItems))
+	})
+}
+
+// ApplyCoingeckoCoins inserts or updates the coins of db with already fetched API coins (map with Code as key). c is only used for logging
+func ApplyCoingeckoCoins(ctx context.Context, db *pgxpool.Pool, c coingeckoapi.Client, apiItemsMap map[string]cgcoin.Model) error {
+
+	itemStore := cgcoin.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	codes := make([]string, 0, len(apiItemsMap))
+	for code := range apiItemsMap {
+		codes = append(codes, code)
+	}
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx, codes)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	for key, apiItem := range apiItemsMap {
+
+		dbItem, ok := dbItemsMap[key]
+		if !ok {
+			if _, err = itemStore.Insert(ctx, apiItem.Input); err != nil {
+				return fmt.Errorf("itemStore.Insert failed on code: %v: %w", key, err)
+			}
+			c.InfoLog.Info("inserted coin", slog.String(clog.KeyDataset, DatasetCoingeckoCoins), slog.String(clog.KeyCode, key))
+			continue
+		}
+
+		if !itemStore.Equal(apiItem, dbItem) {
+			if err = itemStore.Update(ctx, apiItem.Input, dbItem.Id); err != nil {
+				return fmt.Errorf("itemStore.Update failed on code: %v: %w", key, err)
+			}
+			c.InfoLog.Info("updated coin", slog.String(clog.KeyDataset, DatasetCoingeckoCoins), slog.String(clog.KeyCode, key))
+		}
+	}
+
+	return nil
+}
+
+// CoingeckoPricesToTargets fetches the daily prices of coin id in vsCurr for the last days days once and syncs them into each target, recording a journal entry in each
+func CoingeckoPricesToTargets(ctx context.Context, targets []Target, c coingeckoapi.Client, id, vsCurr string, days int) error {
+
+	apiItems, fetchErr := c.GetApiDailyPrices(ctx, id, vsCurr, days)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiDailyPrices failed: %w", fetchErr)
+	}
+
+	// prices are daily in UTC
+	endDate := time.Now().UTC()
+	startDate := endDate.AddDate(0, 0, -days)
+
+	params := CoingeckoPricesParams(id, vsCurr, startDate, endDate)
+	return toTargets(ctx, targets, DatasetCoingeckoPrices, params, func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyCoingeckoPrices(ctx, db, c, id, vsCurr, apiItems, startDate, endDate)
+	})
+}
+
+// CoingeckoPricesParams returns the journal params of a CoingeckoPrices run
+func CoingeckoPricesParams(id, vsCurr string, startDate, endDate time.Time) string {
+	return fmt.Sprintf("coin=%s vs=%s from=%s to=%s", id, vsCurr, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+}
+
+// ApplyCoingeckoPrices syncs the prices of coin id in vsCurr in db in the date range with already fetched API prices. API prices outside of the range are ignored. c is only used for logging
+// the coin must have been synced first
+func ApplyCoingeckoPrices(ctx context.Context, db *pgxpool.Pool, c coingeckoapi.Client, id, vsCurr string, apiItems []coingeckoapi.Price, startDate, endDate time.Time) error {
+
+	itemStore := cgprice.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	coinMap, err := cgcoin.Store{Db: db}.SelectCodeIdMap(ctx)
+	if err != nil {
+		return fmt.Errorf("cgcoin.Store.SelectCodeIdMap failed: %w", err)
+	}
+	coinFk, ok := coinMap[id]
+	if !ok {
+		return fmt.Errorf("%w: coin %s not found: pls sync coins first", cerrors.ErrNotFound, id)
+	}
+
+	start, end := startDate.Format(lystype.DateFormat), endDate.Format(lystype.DateFormat)
+	inRange := []coingeckoapi.Price{}
+	for _, apiItem := range apiItems {
+		if apiItem.Date >= start && apiItem.Date <= end {
+			inRange = append(inRange, apiItem)
+		}
+	}
+
+	apiItemsMap, err := coingeckoapi.PricesToMap(inRange, coinFk)
+	if err != nil {
+		return fmt.Errorf("coingeckoapi.PricesToMap failed: %w", err)
+	}
+
+	// stored in upper case, like the API items
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx, id, strings.ToUpper(vsCurr), startDate, endDate)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	newItems := []cgprice.Input{}
+	updatedItems := make(map[int64]cgprice.Input)
+	deletedIds := []int64{}
+
+	for key, apiItem := range apiItemsMap {
+		dbItem, ok := dbItemsMap[key]
+		if !ok {
+			newItems = append(newItems, apiItem.Input)
+			continue
+		}
+		if !itemStore.Equal(apiItem, dbItem) {
+			updatedItems[dbItem.Id] = apiItem.Input
+		}
+	}
+	for key, dbItem := range dbItemsMap {
+		if _, ok := apiItemsMap[key]; !ok {
+			deletedIds = append(deletedIds, dbItem.Id)
+		}
+	}
+
+	if err = itemStore.BulkDelete(ctx, deletedIds); err != nil {
+		return fmt.Errorf("itemStore.BulkDelete failed: %w", err)
+	}
+	if len(newItems) > 0 {
+		if _, err = itemStore.BulkInsert(ctx, newItems); err != nil {
+			return fmt.Errorf("itemStore.BulkInsert failed: %w", err)
+		}
+	}
+	inputs, ids := splitUpdates(updatedItems)
+	if err = itemStore.BulkUpdate(ctx, inputs, ids); err != nil {
+		return fmt.Errorf("itemStore.BulkUpdate failed: %w", err)
+	}
+
+	c.InfoLog.Info("synced prices", slog.String(clog.KeyDataset, DatasetCoingeckoPrices), slog.String(clog.KeyCode, id), slog.String("vs_currency", vsCurr),
+		slog.Int("inserted", len(newItems)), slog.Int("updated", len(updatedItems)), slog.Int("deleted", len(deletedIds)))
+
+	return nil
+}
+
+// CoingeckoPriceTicksToTargets fetches the intraday prices of coin id in vsCurr for the last days days once and writes them as ticks, see TicksToTargets
+func CoingeckoPriceTicksToTargets(ctx context.Context, targets []Target, sinks sink.Router, c coingeckoapi.Client, id, vsCurr string, days int) error {
+
+	ticks, fetchErr := c.GetApiIntradayPrices(ctx, id, vsCurr, days)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiIntradayPrices failed: %w", fetchErr)
+	}
+
+	endDate := time.Now().UTC()
+	startDate := endDate.AddDate(0, 0, -days)
+
+	params := CoingeckoPricesParams(id, vsCurr, startDate, endDate)
+	return TicksToTargets(ctx, targets, sinks, c.InfoLog, DatasetCoingeckoPriceTicks, params, ticks, fetchErr)
+}
@@ -0,0 +1,361 @@
+package csyncdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/companieshouseapi"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/companieshouse/chcompany"
+	"github.com/loveyourstack/connectors/stores/companieshouse/chofficer"
+	"github.com/loveyourstack/connectors/stores/companieshouse/chstream"
+)
+
+// chCompaniesStream is the name of the companies stream in companieshouse.stream_position
+const chCompaniesStream string = "companies"
+
+// CompaniesHouseCompaniesToTargets syncs the profiles of the companies with numbers into each target, recording a journal entry in each
+// with a stream key, changes are read from the companies stream for streamDuration, resuming from the position stored in the first target. Without one, or if the position is unknown or expired, all profiles are requested
+// companies missing from a target are requested in any case, at most once for all targets
+func CompaniesHouseCompaniesToTargets(ctx context.Context, targets []Target, c companieshouseapi.Client, numbers []string, streamDuration time.Duration) error {
+
+	if len(targets) == 0 {
+		return nil
+	}
+
+	src := newChSource(c, numbers)
+	fetchErr := src.fetchChanges(ctx, targets[0].Db, streamDuration)
+
+	params := CompaniesHouseCompaniesParams(numbers, src.timepoint)
+	return toTargets(ctx, targets, DatasetCompaniesHouseCompanies, params, func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return syncChCompanies(ctx, db, src)
+	})
+}
+
+// CompaniesHouseCompaniesParams returns the journal params of a CompaniesHouseCompanies run
+func CompaniesHouseCompaniesParams(numbers []string, timepoint int64) string {
+	return fmt.Sprintf("companies=%d timepoint=%d", len(numbers), timepoint)
+}
+
+// syncChCompanies adds the profiles of companies missing from db to the changes of src, and applies them
+func syncChCompanies(ctx context.Context, db *pgxpool.Pool, src *chSource) error {
+
+	dbItemsMap, err := chcompany.Store{Db: db}.SelectMapByNaturalKey(ctx, src.numbers)
+	if err != nil {
+		return fmt.Errorf("chcompany.Store.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	companies := make(map[string]companieshouseapi.Company)
+	for number, co := range src.changed {
+		companies[number] = co
+	}
+
+	missing := []string{}
+	for _, number := range src.numbers {
+		if _, ok := dbItemsMap[number]; !ok && !src.deleted[number] {
+			if _, ok := companies[number]; !ok {
+				missing = append(missing, number)
+			}
+		}
+	}
+	requested, err := src.companies(ctx, missing)
+	if err != nil {
+		return fmt.Errorf("src.companies failed: %w", err)
+	}
+	for _, co := range requested {
+		companies[co.Number] = co
+	}
+
+	deleted := []string{}
+	for number := range src.deleted {
+		deleted = append(deleted, number)
+	}
+
+	list := make([]companieshouseapi.Company, 0, len(companies))
+	for _, co := range companies {
+		list = append(list, co)
+	}
+	apiItemsMap, err := companieshouseapi.CompaniesToMap(list)
+	if err != nil {
+		return fmt.Errorf("companieshouseapi.CompaniesToMap failed: %w", err)
+	}
+
+	return ApplyCompaniesHouseCompanies(ctx, db, src.c, apiItemsMap, deleted, src.timepoint)
+}
+
+// ApplyCompaniesHouseCompanies inserts or updates the companies of db with already fetched profiles (map with CompanyNumber as key), and deletes the companies with deleted numbers. c is only used for logging
+// if timepoint > 0, it is stored as the position of the companies stream
+func ApplyCompaniesHouseCompanies(ctx context.Context, db *pgxpool.Pool, c companieshouseapi.Client, apiItemsMap map[string]chcompany.Model, deleted []string, timepoint int64) error {
+
+	itemStore := chcompany.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	numbers := append([]string{}, deleted...)
+	for number := range apiItemsMap {
+		numbers = append(numbers, number)
+	}
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx, numbers)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	inserted, updated, deletedCount := 0, 0, 0
+	for key, apiItem := range apiItemsMap {
+
+		dbItem, ok := dbItemsMap[key]
+		if !ok {
+			if _, err = itemStore.Insert(ctx, apiItem.Input); err != nil {
+				return fmt.Errorf("itemStore.Insert failed on company: %v: %w", key, err)
+			}
+			inserted++
+			continue
+		}
+
+		if !itemStore.Equal(apiItem, dbItem) {
+			if err = itemStore.Update(ctx, apiItem.Input, dbItem.Id); err != nil {
+				return fmt.Errorf("itemStore.Update failed on company: %v: %w", key, err)
+			}
+			updated++
+		}
+	}
+
+	for _, number := range deleted {
+		if dbItem, ok := dbItemsMap[number]; ok {
+			if err = itemStore.Delete(ctx, dbItem.Id); err != nil {
+				return fmt.Errorf("itemStore.Delete failed on company: %v: %w", number, err)
+			}
+			deletedCount++
+		}
+	}
+
+	if timepoint > 0 {
+		if err = (chstream.Store{Db: db}).SetTimepoint(ctx, chCompaniesStream, timepoint); err != nil {
+			return fmt.Errorf("chstream.Store.SetTimepoint failed: %w", err)
+		}
+	}
+
+	c.InfoLog.Info("synced companies", slog.String(clog.KeyDataset, DatasetCompaniesHouseCompanies), slog.Int64("timepoint", timepoint),
+		slog.Int("inserted", inserted), slog.Int("updated", updated), slog.Int("deleted", deletedCount))
+
+	return nil
+}
+
+// chSource holds the company changes read once for all targets, and caches requested profiles
+// it is only used by the sequential toTargets, so it needs no locking
+type chSource struct {
+	c       companieshouseapi.Client
+	numbers []string
+
+	changed   map[string]companieshouseapi.Company // by company number
+	deleted   map[string]bool
+	timepoint int64 // stream position after the changes. 0 if unknown
+
+	requested map[string]companieshouseapi.Company
+	fetched   map[string]bool // numbers requested, including unknown ones
+}
+
+func newChSource(c companieshouseapi.Client, numbers []string) *chSource {
+	return &chSource{
+		c:         c,
+		numbers:   numbers,
+		changed:   make(map[string]companieshouseapi.Company),
+		deleted:   make(map[string]bool),
+		requested: make(map[string]companieshouseapi.Company),
+		fetched:   make(map[string]bool),
+	}
+}
+
+// fetchChanges fills s.changed and s.deleted from the companies stream, resuming from the position stored in posDb
+// without a stream key, or if the position is unknown or expired, all profiles are requested instead, and the current stream position is read if possible
+func (s *chSource) fetchChanges(ctx context.Context, posDb *pgxpool.Pool, streamDuration time.Duration) error {
+
+	requestAll := func() error {
+		companies, err := s.companies(ctx, s.numbers)
+		if err != nil {
+			return fmt.Errorf("s.companies failed: %w", err)
+		}
+		for _, co := range companies {
+			s.changed[co.Number] = co
+		}
+		return nil
+	}
+
+	if s.c.StreamKey == "" {
+		return requestAll()
+	}
+
+	timepoint, err := chstream.Store{Db: posDb}.SelectTimepoint(ctx, chCompaniesStream)
+	if err != nil {
+		return fmt.Errorf("chstream.Store.SelectTimepoint failed: %w", err)
+	}
+
+	watched := make(map[string]bool)
+	for _, number := range s.numbers {
+		watched[number] = true
+	}
+
+	if timepoint > 0 {
+		changes, lastTimepoint, err := s.c.ReadCompanyChanges(ctx, timepoint, streamDuration, func(number string) bool { return watched[number] })
+		if err == nil {
+			// later changes of a company replace earlier ones
+			for _, change := range changes {
+				if change.Type == companieshouseapi.EventDeleted {
+					delete(s.changed, change.Number)
+					s.deleted[change.Number] = true
+					continue
+				}
+				delete(s.deleted, change.Number)
+				s.changed[change.Number] = change.Company
+			}
+			s.timepoint = lastTimepoint
+			return nil
+		}
+		if !errors.Is(err, companieshouseapi.ErrTimepointExpired) {
+			return fmt.Errorf("s.c.ReadCompanyChanges failed: %w", err)
+		}
+		s.c.InfoLog.Warn("stream position expired, requesting all companies", slog.String(clog.KeyDataset, DatasetCompaniesHouseCompanies), slog.Int64("timepoint", timepoint))
+	}
+
+	// read the stream first, so that changes made while the profiles are requested are not missed by the next sync
+	_, s.timepoint, err = s.c.ReadCompanyChanges(ctx, 0, streamDuration, func(string) bool { return false })
+	if err != nil {
+		return fmt.Errorf("s.c.ReadCompanyChanges failed: %w", err)
+	}
+
+	return requestAll()
+}
+
+// companies returns the profiles of numbers, only requesting those not requested before. Unknown numbers are logged
+func (s *chSource) companies(ctx context.Context, numbers []string) ([]companieshouseapi.Company, error) {
+
+	toFetch := []string{}
+	for _, number := range numbers {
+		if !s.fetched[number] {
+			toFetch = append(toFetch, number)
+		}
+	}
+
+	if len(toFetch) > 0 {
+		companies, notFound, err := s.c.GetApiCompanies(ctx, toFetch)
+		if err != nil {
+			return nil, fmt.Errorf("s.c.GetApiCompanies failed: %w", err)
+		}
+		for _, co := range companies {
+			s.requested[co.Number] = co
+		}
+		for _, number := range notFound {
+			s.c.InfoLog.Warn("company not found", slog.String(clog.KeyDataset, DatasetCompaniesHouseCompanies), slog.String(clog.KeyCode, number))
+		}
+		for _, number := range toFetch {
+			s.fetched[number] = true
+		}
+	}
+
+	companies := []companieshouseapi.Company{}
+	for _, number := range numbers {
+		if co, ok := s.requested[number]; ok {
+			companies = append(companies, co)
+		}
+	}
+
+	return companies, nil
+}
+
+// CompaniesHouseOfficersToTargets fetches the officers of company number once and syncs them into each target, recording a journal entry in each
+func CompaniesHouseOfficersToTargets(ctx context.Context, targets []Target, c companieshouseapi.Client, number string) error {
+
+	apiItems, fetchErr := c.GetApiOfficers(ctx, number)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiOfficers failed: %w", fetchErr)
+	}
+
+	params := "company=" + number
+	return toTargets(ctx, targets, DatasetCompaniesHouseOfficers, params, func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyCompaniesHouseOfficers(ctx, db, c, number, apiItems)
+	})
+}
+
+// ApplyCompaniesHouseOfficers syncs the officers of company number in db with already fetched API officers. c is only used for logging
+// the company must have been synced first
+func ApplyCompaniesHouseOfficers(ctx context.Context, db *pgxpool.Pool, c companieshouseapi.Client, number string, apiItems []companieshouseapi.Officer) error {
+
+	itemStore := chofficer.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	companyMap, err := chcompany.Store{Db: db}.SelectMapByNaturalKey(ctx, []string{number})
+	if err != nil {
+		return fmt.Errorf("chcompany.Store.SelectMapByNaturalKey failed: %w", err)
+	}
+	company, ok := companyMap[number]
+	if !ok {
+		return fmt.Errorf("%w: company %s not found: pls sync companies first", cerrors.ErrNotFound, number)
+	}
+
+	apiItemsMap, err := companieshouseapi.OfficersToMap(apiItems, company.Id)
+	if err != nil {
+		return fmt.Errorf("companieshouseapi.OfficersToMap failed: %w", err)
+	}
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx, company.Id)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	newItems := []chofficer.Input{}
+	updatedItems := make(map[int64]chofficer.Input)
+	deletedIds := []int64{}
+
+	for key, apiItem := range apiItemsMap {
+		dbItem, ok := dbItemsMap[key]
+		if !ok {
+			newItems = append(newItems, apiItem.Input)
+			continue
+		}
+		if !itemStore.Equal(apiItem, dbItem) {
+			updatedItems[dbItem.Id] = apiItem.Input
+		}
+	}
+	for key, dbItem := range dbItemsMap {
+		if _, ok := apiItemsMap[key]; !ok {
+			deletedIds = append(deletedIds, dbItem.Id)
+		}
+	}
+
+	if err = itemStore.BulkDelete(ctx, deletedIds); err != nil {
+		return fmt.Errorf("itemStore.BulkDelete failed: %w", err)
+	}
+	if len(newItems) > 0 {
+		if _, err = itemStore.BulkInsert(ctx, newItems); err != nil {
+			return fmt.Errorf("itemStore.BulkInsert failed: %w", err)
+		}
+	}
+	inputs, ids := splitUpdates(updatedItems)
+	if err = itemStore.BulkUpdate(ctx, inputs, ids); err != nil {
+		return fmt.Errorf("itemStore.BulkUpdate failed: %w", err)
+	}
+
+	c.InfoLog.Info("synced officers", slog.String(clog.KeyDataset, DatasetCompaniesHouseOfficers), slog.String(clog.KeyCode, number),
+		slog.Int("inserted", len(newItems)), slog.Int("updated", len(updatedItems)), slog.Int("deleted", len(deletedIds)))
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/restcountriesapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/country/isocountry"
+)
+
+// CountriesToTargets fetches all countries once and syncs them into each target, recording a journal entry in each
+func CountriesToTargets(ctx context.Context, targets []Target, c restcountriesapi.Client) error {
+
+	apiItems, fetchErr := c.GetApiCountries()
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiCountries failed: %w", fetchErr)
+	}
+
+	return toTargets(ctx, targets, DatasetCountries, "", func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyCountries(ctx, db, c, restcountriesapi.CountriesToMap(apiItems))
+	})
+}
+
+// ApplyCountries syncs the countries of db with already fetched API countries (map with Iso2 as key). c is only used for logging
+func ApplyCountries(ctx context.Context, db *pgxpool.Pool, c restcountriesapi.Client, apiItemsMap map[string]isocountry.Model) error {
+
+	itemStore := isocountry.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	for key, apiItem := range apiItemsMap {
+
+		dbItem, ok := dbItemsMap[key]
+		if !ok {
+			if _, err = itemStore.Insert(ctx, apiItem.Input); err != nil {
+				return fmt.Errorf("itemStore.Insert failed on code: %v: %w", key, err)
+			}
+			c.InfoLog.Info("inserted country", slog.String(clog.KeyDataset, DatasetCountries), slog.String(clog.KeyCode, key))
+			continue
+		}
+
+		if !itemStore.Equal(apiItem, dbItem) {
+			if err = itemStore.Update(ctx, apiItem.Input, dbItem.Id); err != nil {
+				return fmt.Errorf("itemStore.Update failed on code: %v: %w", key, err)
+			}
+			c.InfoLog.Info("updated country", slog.String(clog.KeyDataset, DatasetCountries), slog.String(clog.KeyCode, key))
+		}
+	}
+
+	for key, dbItem := range dbItemsMap {
+		if _, ok := apiItemsMap[key]; !ok {
+			if err = itemStore.Delete(ctx, dbItem.Id); err != nil {
+				return fmt.Errorf("itemStore.Delete failed on code: %v: %w", key, err)
+			}
+			c.InfoLog.Info("deleted country", slog.String(clog.KeyDataset, DatasetCountries), slog.String(clog.KeyCode, key))
+		}
+	}
+
+	return nil
+}
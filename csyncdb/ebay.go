@@ -0,0 +1,139 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/ebayapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/ebay/ebayitem"
+	"github.com/loveyourstack/connectors/stores/ebay/ebayoffer"
+)
+
+// EbayInventoryToTargets fetches the seller's inventory items and their offers once and syncs them into each target, recording a journal entry in each
+func EbayInventoryToTargets(ctx context.Context, targets []Target, c ebayapi.Client) error {
+
+	items, fetchErr := c.GetApiInventory(ctx)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiInventory failed: %w", fetchErr)
+	}
+
+	return toTargets(ctx, targets, DatasetEbayInventory, "", func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyEbayInventory(ctx, db, c, items)
+	})
+}
+
+// ApplyEbayInventory syncs the inventory items and offers in db with already fetched API items. c is only used for logging
+// items and offers no longer in the seller's inventory are deleted
+func ApplyEbayInventory(ctx context.Context, db *pgxpool.Pool, c ebayapi.Client, items []ebayapi.InventoryItem) error {
+
+	itemStore := ebayitem.Store{Db: db}
+	offerStore := ebayoffer.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	// inventory items
+	apiItemsMap, err := ebayapi.InventoryItemsToMap(items)
+	if err != nil {
+		return fmt.Errorf("ebayapi.InventoryItemsToMap failed: %w", err)
+	}
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	// map of k = Sku, v = db id, to resolve the inventory item of offers
+	itemIdMap := make(map[string]int64)
+	itemsInserted, itemsUpdated, itemsDeleted := 0, 0, 0
+
+	for sku, apiItem := range apiItemsMap {
+
+		dbItem, ok := dbItemsMap[sku]
+		if !ok {
+			newId, err := itemStore.Insert(ctx, apiItem.Input)
+			if err != nil {
+				return fmt.Errorf("itemStore.Insert failed on SKU: %s: %w", sku, err)
+			}
+			itemIdMap[sku] = newId
+			itemsInserted++
+			continue
+		}
+
+		itemIdMap[sku] = dbItem.Id
+		if !itemStore.Equal(apiItem, dbItem) {
+			if err = itemStore.Update(ctx, apiItem.Input, dbItem.Id); err != nil {
+				return fmt.Errorf("itemStore.Update failed on SKU: %s: %w", sku, err)
+			}
+			itemsUpdated++
+		}
+	}
+
+	// deleting an inventory item deletes its offers
+	for sku, dbItem := range dbItemsMap {
+		if _, ok := apiItemsMap[sku]; !ok {
+			if err = itemStore.Delete(ctx, dbItem.Id); err != nil {
+				return fmt.Errorf("itemStore.Delete failed on ID: %v: %w", dbItem.Id, err)
+			}
+			itemsDeleted++
+		}
+	}
+
+	// offers
+	apiOffersMap, err := ebayapi.OffersToMap(items, itemIdMap)
+	if err != nil {
+		return fmt.Errorf("ebayapi.OffersToMap failed: %w", err)
+	}
+
+	dbOffersMap, err := offerStore.SelectMapByNaturalKey(ctx)
+	if err != nil {
+		return fmt.Errorf("offerStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	newOffers := []ebayoffer.Input{}
+	updatedOffers := make(map[int64]ebayoffer.Input)
+	deletedOfferIds := []int64{}
+
+	for offerId, apiOffer := range apiOffersMap {
+		dbOffer, ok := dbOffersMap[offerId]
+		if !ok {
+			newOffers = append(newOffers, apiOffer.Input)
+			continue
+		}
+		if !offerStore.Equal(apiOffer, dbOffer) {
+			updatedOffers[dbOffer.Id] = apiOffer.Input
+		}
+	}
+	for offerId, dbOffer := range dbOffersMap {
+		if _, ok := apiOffersMap[offerId]; !ok {
+			deletedOfferIds = append(deletedOfferIds, dbOffer.Id)
+		}
+	}
+
+	if err = offerStore.BulkDelete(ctx, deletedOfferIds); err != nil {
+		return fmt.Errorf("offerStore.BulkDelete failed: %w", err)
+	}
+	if len(newOffers) > 0 {
+		if _, err = offerStore.BulkInsert(ctx, newOffers); err != nil {
+			return fmt.Errorf("offerStore.BulkInsert failed: %w", err)
+		}
+	}
+	inputs, ids := splitUpdates(updatedOffers)
+	if err = offerStore.BulkUpdate(ctx, inputs, ids); err != nil {
+		return fmt.Errorf("offerStore.BulkUpdate failed: %w", err)
+	}
+
+	c.InfoLog.Info("synced inventory", slog.String(clog.KeyDataset, DatasetEbayInventory),
+		slog.Int("inserted", itemsInserted), slog.Int("updated", itemsUpdated), slog.Int("deleted", itemsDeleted),
+		slog.Int("offers_inserted", len(newOffers)), slog.Int("offers_updated", len(updatedOffers)), slog.Int("offers_deleted", len(deletedOfferIds)))
+
+	return nil
+}
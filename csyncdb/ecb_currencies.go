@@ -7,6 +7,7 @@ import (
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/clog"
 	"github.com/loveyourstack/connectors/stores/ecb/ecbcurrency"
 )
 
@@ -18,8 +19,25 @@ func EcbCurrencies(ctx context.Context, db *pgxpool.Pool, c ecbapi.Client) error
 		return fmt.Errorf("c.GetCurrenciesMap failed: %w", err)
 	}
 
+	return ApplyEcbCurrencies(ctx, db, c, apiItemsMap)
+}
+
+// ApplyEcbCurrencies syncs db with already fetched API currencies (map with Code as key). c is only used for logging
+func ApplyEcbCurrencies(ctx context.Context, db *pgxpool.Pool, c ecbapi.Client, apiItemsMap map[string]ecbcurrency.Model) error {
+	return ApplyEcbCurrenciesToStore(ctx, ecbcurrency.Store{Db: db}, c, apiItemsMap)
+}
+
+// ApplyEcbCurrenciesToStore is ApplyEcbCurrencies for any store, such as an ecbcurrency.MemStore
+// concurrent calls for the same store are serialized
+func ApplyEcbCurrenciesToStore(ctx context.Context, itemStore ecbcurrency.Storer, c ecbapi.Client, apiItemsMap map[string]ecbcurrency.Model) error {
+
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
 	// select DB items map with Code as key
-	itemStore := ecbcurrency.Store{Db: db}
 	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx)
 	if err != nil {
 		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
@@ -34,9 +52,9 @@ func EcbCurrencies(ctx context.Context, db *pgxpool.Pool, c ecbapi.Client) error
 			// insert to DB if not found
 			_, err = itemStore.Insert(ctx, apiItem.Input)
 			if err != nil {
-				return fmt.Errorf("itemStore.Insert failed on offerId: %v: %w", key, err)
+				return fmt.Errorf("itemStore.Insert failed on code: %v: %w", key, err)
 			}
-			c.InfoLog.Info("inserted currency", slog.String("code", apiItem.Code))
+			c.InfoLog.Info("inserted currency", slog.String(clog.KeyDataset, DatasetEcbCurrencies), slog.String(clog.KeyCode, apiItem.Code))
 			continue
 		}
 
@@ -45,9 +63,9 @@ func EcbCurrencies(ctx context.Context, db *pgxpool.Pool, c ecbapi.Client) error
 
 			err = itemStore.Update(ctx, apiItem.Input, dbItem.Id)
 			if err != nil {
-				return fmt.Errorf("itemStore.Update failed on offerId: %v: %w", key, err)
+				return fmt.Errorf("itemStore.Update failed on code: %v: %w", key, err)
 			}
-			c.InfoLog.Info("updated currency", slog.String("code", apiItem.Code))
+			c.InfoLog.Info("updated currency", slog.String(clog.KeyDataset, DatasetEcbCurrencies), slog.String(clog.KeyCode, apiItem.Code))
 		}
 	}
 
@@ -60,9 +78,9 @@ func EcbCurrencies(ctx context.Context, db *pgxpool.Pool, c ecbapi.Client) error
 			// delete if not found
 			err = itemStore.Delete(ctx, dbItem.Id)
 			if err != nil {
-				return fmt.Errorf("itemStore.Delete failed on offerId: %v: %w", key, err)
+				return fmt.Errorf("itemStore.Delete failed on code: %v: %w", key, err)
 			}
-			c.InfoLog.Info("deleted currency", slog.String("code", dbItem.Code))
+			c.InfoLog.Info("deleted currency", slog.String(clog.KeyDataset, DatasetEcbCurrencies), slog.String(clog.KeyCode, dbItem.Code))
 		}
 	}
 
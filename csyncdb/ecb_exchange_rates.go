@@ -4,99 +4,305 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/derive"
+	"github.com/loveyourstack/connectors/quality"
+	"github.com/loveyourstack/connectors/stores/connectors/derivedvalue"
+	"github.com/loveyourstack/connectors/stores/connectors/syncrun"
 	"github.com/loveyourstack/connectors/stores/ecb/ecbcurrency"
 	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
 )
 
-func EcbExchangeRates(ctx context.Context, db *pgxpool.Pool, c ecbapi.Client, baseCurr string, freq ecbapi.Frequency, startDate, endDate time.Time) error {
+// EcbExchangeRates syncs the rates of db in the date range with the ECB. If the ECB is unavailable, the rates are fetched from fallbacks in turn, e.g. a frankfurterapi.Client
+// the fetched rates are checked against rules, see ApplyEcbExchangeRatesToStores, and the derived fields are computed from the stored rates, see ApplyEcbExchangeRates
+func EcbExchangeRates(ctx context.Context, db *pgxpool.Pool, c ecbapi.Client, baseCurr string, freq ecbapi.Frequency, startDate, endDate time.Time, rules []quality.Rule, derived []derive.Field,
+	fallbacks ...ExchangeRateSource) error {
+
+	// select API items in date range
+	apiItems, err := getEcbExchangeRates(c, fallbacks, baseCurr, freq, startDate, endDate)
+	if err != nil {
+		return fmt.Errorf("getEcbExchangeRates failed: %w", err)
+	}
+
+	return ApplyEcbExchangeRates(ctx, db, c, apiItems, baseCurr, freq, startDate, endDate, rules, derived)
+}
+
+// ApplyEcbExchangeRates syncs the rates of db in the date range with already fetched API rates. c is only used for logging
+// the values of the derived fields of the rates in the date range are then replaced in connectors.derived_value, see deriveEcbExchangeRates
+func ApplyEcbExchangeRates(ctx context.Context, db *pgxpool.Pool, c ecbapi.Client, apiItems []ecbapi.ExchangeRate, baseCurr string, freq ecbapi.Frequency, startDate, endDate time.Time, rules []quality.Rule,
+	derived []derive.Field) error {
+
+	itemStore := ecbexchangerate.Store{Db: db}
+	if err := ApplyEcbExchangeRatesToStores(ctx, ecbcurrency.Store{Db: db}, itemStore, c, apiItems, baseCurr, freq, startDate, endDate, rules); err != nil {
+		return err
+	}
+
+	if err := deriveEcbExchangeRates(ctx, db, itemStore, c, baseCurr, freq, startDate, endDate, derived); err != nil {
+		return fmt.Errorf("deriveEcbExchangeRates failed: %w", err)
+	}
+
+	return nil
+}
+
+// deriveEcbExchangeRates computes the derived fields of the stored rates in the date range and replaces their values in connectors.derived_value of db
+// the rows have the series "<from>/<to>" and the field rate. The stored rates shortly before startDate are read too, so that the first day has a change from the previous one
+func deriveEcbExchangeRates(ctx context.Context, db *pgxpool.Pool, itemStore ecbexchangerate.Store, c ecbapi.Client, baseCurr string, freq ecbapi.Frequency, startDate, endDate time.Time,
+	derived []derive.Field) error {
+
+	if len(derived) == 0 {
+		return nil
+	}
+
+	// covers weekends and holidays of daily rates, and the previous month of monthly ones
+	lookbackStart := startDate.AddDate(0, 0, -14)
+	if freq == ecbapi.Monthly {
+		lookbackStart = startDate.AddDate(0, -2, 0)
+	}
+
+	items, err := itemStore.SelectInRange(ctx, baseCurr, freq, lookbackStart, endDate)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectInRange failed: %w", err)
+	}
+
+	rows := make([]derive.Row, len(items))
+	for i, item := range items {
+		// the float32 rate as written, e.g. 1.0823 rather than 1.08230006694793
+		rate, _ := strconv.ParseFloat(strconv.FormatFloat(float64(item.Rate), 'g', -1, 32), 64)
+		rows[i] = derive.Row{Series: item.FromCurrency + "/" + item.ToCurrency, Day: time.Time(item.Day), Values: map[string]float64{"rate": rate}}
+	}
+
+	values, err := derive.Compute(derived, rows, startDate)
+	if err != nil {
+		return fmt.Errorf("derive.Compute failed: %w", err)
+	}
+
+	var runId *int64
+	if id, ok := syncrun.RunIdFromContext(ctx); ok {
+		runId = &id
+	}
+	inputs := make([]derivedvalue.Input, len(values))
+	for i, v := range values {
+		inputs[i] = derivedvalue.Input{Dataset: DatasetEcbExchangeRates, Series: v.Series, Day: v.Day, Field: v.Field, Value: v.Value, SourceRunId: runId}
+	}
+
+	fields := make([]string, len(derived))
+	for i, f := range derived {
+		fields[i] = f.Name
+	}
+	if err = (derivedvalue.Store{Db: db}).Replace(ctx, DatasetEcbExchangeRates, fields, startDate, endDate, inputs); err != nil {
+		return fmt.Errorf("derivedvalue.Store.Replace failed: %w", err)
+	}
+	c.InfoLog.Info("stored derived values", slog.String(clog.KeyDataset, DatasetEcbExchangeRates), slog.Int(clog.KeyCount, len(inputs)))
+
+	return nil
+}
+
+// ApplyEcbExchangeRatesToStores is ApplyEcbExchangeRates for any stores, such as an ecbcurrency.MemStore and ecbexchangerate.MemStore
+// concurrent calls for the same itemStore are serialized. Returns ErrDerivedBase if baseCurr is not EUR
+// apiItems are first checked against the data-quality rules of the dataset, see quality.Check: rates violating a Reject rule are neither stored nor deleted,
+// and a violated Abort rule fails the sync before anything is written
+func ApplyEcbExchangeRatesToStores(ctx context.Context, currStore ecbcurrency.Storer, itemStore ecbexchangerate.Storer, c ecbapi.Client, apiItems []ecbapi.ExchangeRate, baseCurr string, freq ecbapi.Frequency, startDate, endDate time.Time, rules []quality.Rule) error {
+	_, err := applyEcbExchangeRatesToStores(ctx, currStore, itemStore, c, apiItems, baseCurr, freq, startDate, endDate, rules)
+	return err
+}
+
+// applyEcbExchangeRatesToStores is ApplyEcbExchangeRatesToStores, returning the diff applied
+func applyEcbExchangeRatesToStores(ctx context.Context, currStore ecbcurrency.Storer, itemStore ecbexchangerate.Storer, c ecbapi.Client, apiItems []ecbapi.ExchangeRate, baseCurr string, freq ecbapi.Frequency,
+	startDate, endDate time.Time, rules []quality.Rule) (diff EcbExchangeRatesDiff, err error) {
+
+	if err = checkStoredBase(baseCurr); err != nil {
+		return diff, err
+	}
+
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return diff, fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
 
 	// select map of k = ECB currency code, v = db id
-	currStore := ecbcurrency.Store{Db: db}
 	currMap, err := currStore.SelectCodeIdMap(ctx)
 	if err != nil {
-		return fmt.Errorf("currStore.SelectCodeIdMap failed: %w", err)
+		return diff, fmt.Errorf("currStore.SelectCodeIdMap failed: %w", err)
 	}
 	if len(currMap) == 0 {
-		return fmt.Errorf("no currencies found: pls sync currencies first")
+		return diff, fmt.Errorf("%w: no currencies found: pls sync currencies first", cerrors.ErrNotFound)
 	}
 
-	// select API items map in date range with day+toCurrFk as key
-	apiItemsMap, err := c.GetExchangeRatesMap(baseCurr, freq, startDate, endDate, currMap)
+	apiItems, rejectedItems, err := checkEcbExchangeRates(c, rules, apiItems)
 	if err != nil {
-		return fmt.Errorf("c.GetExchangeRatesMap failed: %w", err)
+		return diff, fmt.Errorf("checkEcbExchangeRates failed: %w", err)
 	}
 
-	// select DB items map in date range with day+toCurrFk as key
-	itemStore := ecbexchangerate.Store{Db: db}
-	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx, baseCurr, freq.String(), startDate, endDate)
+	// convert API items to map with day+toCurrFk as key, resolving this db's currency ids
+	apiItemsMap, err := ecbapi.ExchangeRatesToMap(apiItems, currMap)
 	if err != nil {
-		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+		return diff, fmt.Errorf("ecbapi.ExchangeRatesToMap failed: %w", err)
 	}
 
-	newItems := []ecbexchangerate.Input{}
-	updatedItems := make(map[int64]ecbexchangerate.Input) // map key is the DB ID
-	deletedItems := []ecbexchangerate.Model{}
+	// the rates stored or updated are traced back to this run
+	if runId, ok := syncrun.RunIdFromContext(ctx); ok {
+		for key, apiItem := range apiItemsMap {
+			apiItem.SourceRunId = &runId
+			apiItemsMap[key] = apiItem
+		}
+	}
 
-	// for each API item
-	for key, apiItem := range apiItemsMap {
+	// select DB items map in date range with day+toCurrFk as key
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx, baseCurr, freq, startDate, endDate)
+	if err != nil {
+		return diff, fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
 
-		// try to find the equivalent DB item
-		dbItem, ok := dbItemsMap[key]
-		if !ok {
-			newItems = append(newItems, apiItem.Input)
-			continue
+	// the stored values of rejected rates are kept, rather than deleted as missing from the API
+	if len(rejectedItems) > 0 {
+		rejectedMap, err := ecbapi.ExchangeRatesToMap(rejectedItems, currMap)
+		if err != nil {
+			return diff, fmt.Errorf("ecbapi.ExchangeRatesToMap failed for rejected items: %w", err)
 		}
+		for key := range rejectedMap {
+			if _, ok := apiItemsMap[key]; !ok {
+				delete(dbItemsMap, key)
+			}
+		}
+	}
 
-		// found: compare values and only update if needed
-		if !itemStore.Equal(apiItem, dbItem) {
-			updatedItems[dbItem.Id] = apiItem.Input
+	diff = DiffEcbExchangeRates(apiItemsMap, dbItemsMap, itemStore.Equal)
+	if err = applyEcbExchangeRatesDiff(ctx, itemStore, c, diff); err != nil {
+		return diff, fmt.Errorf("applyEcbExchangeRatesDiff failed: %w", err)
+	}
+
+	return diff, nil
+}
+
+// checkEcbExchangeRates checks apiItems against rules, logging the violations, and returns the items kept and those rejected
+// the rows checked have the series "<from>/<to>" and the fields rate, day, from_currency and to_currency
+func checkEcbExchangeRates(c ecbapi.Client, rules []quality.Rule, apiItems []ecbapi.ExchangeRate) (kept, rejectedItems []ecbapi.ExchangeRate, err error) {
+
+	if len(rules) == 0 {
+		return apiItems, nil, nil
+	}
+
+	rows := make([]quality.Row, len(apiItems))
+	for i, apiItem := range apiItems {
+		day, err := apiItem.Day()
+		if err != nil {
+			return nil, nil, fmt.Errorf("apiItem.Day failed: %w", err)
+		}
+		rows[i] = quality.Row{
+			Series: apiItem.FromCurr + "/" + apiItem.ToCurr,
+			Day:    day,
+			Values: map[string]any{"rate": apiItem.Rate, "day": day, "from_currency": apiItem.FromCurr, "to_currency": apiItem.ToCurr},
 		}
 	}
 
-	// for each DB item
-	for key, dbItem := range dbItemsMap {
+	violations, rejected, err := quality.Check(rules, rows)
+	if err != nil {
+		return nil, nil, fmt.Errorf("quality.Check failed: %w", err)
+	}
+	for _, v := range violations {
+		c.ErrorLog.Warn("data quality rule violated", slog.String(clog.KeyDataset, DatasetEcbExchangeRates), slog.String("rule", v.Rule.String()), slog.String("action", string(v.Action)),
+			slog.String("series", v.Series), slog.String("day", v.Day.Format("2006-01-02")), slog.String("message", v.Message))
+	}
 
-		// try to find the equivalent API item
-		_, ok := apiItemsMap[key]
-		if !ok {
-			deletedItems = append(deletedItems, dbItem)
+	kept = make([]ecbapi.ExchangeRate, 0, len(apiItems))
+	for i, apiItem := range apiItems {
+		if rejected[i] {
+			rejectedItems = append(rejectedItems, apiItem)
+			continue
 		}
+		kept = append(kept, apiItem)
+	}
+	if len(rejectedItems) > 0 {
+		c.ErrorLog.Warn("rejected exchange rates", slog.String(clog.KeyDataset, DatasetEcbExchangeRates), slog.Int(clog.KeyCount, len(rejectedItems)))
 	}
 
-	// run deletes
+	return kept, rejectedItems, nil
+}
+
+// applyEcbExchangeRatesDiff runs the deletes, inserts and updates of diff in itemStore
+func applyEcbExchangeRatesDiff(ctx context.Context, itemStore ecbexchangerate.Storer, c ecbapi.Client, diff EcbExchangeRatesDiff) error {
+
+	newItems, updatedItems, deletedItems := diff.New, diff.Updated, diff.Deleted
+
+	// run deletes (batched)
 	if len(deletedItems) > 0 {
+		deletedIds := make([]int64, 0, len(deletedItems))
 		for _, dbItem := range deletedItems {
-			err = itemStore.Delete(ctx, dbItem.Id)
-			if err != nil {
-				return fmt.Errorf("itemStore.Delete failed on ID: %v: %w", dbItem.Id, err)
-			}
+			deletedIds = append(deletedIds, dbItem.Id)
+		}
+		if err := itemStore.BulkDelete(ctx, deletedIds); err != nil {
+			return fmt.Errorf("itemStore.BulkDelete failed: %w", err)
 		}
-		c.InfoLog.Info("deleted exchange rates", slog.Int("num", len(deletedItems)))
+		c.InfoLog.Info("deleted exchange rates", slog.String(clog.KeyDataset, DatasetEcbExchangeRates), slog.Int(clog.KeyCount, len(deletedItems)))
 	}
 
 	// run inserts (bulk)
 	if len(newItems) > 0 {
-		_, err := itemStore.BulkInsert(ctx, newItems)
-		if err != nil {
+		if _, err := itemStore.BulkInsert(ctx, newItems); err != nil {
 			return fmt.Errorf("itemStore.BulkInsert failed: %w", err)
 		}
-		c.InfoLog.Info("inserted exchange rates", slog.Int("num", len(newItems)))
+		c.InfoLog.Info("inserted exchange rates", slog.String(clog.KeyDataset, DatasetEcbExchangeRates), slog.Int(clog.KeyCount, len(newItems)))
 	}
 
-	// run updates
+	// run updates (batched)
 	if len(updatedItems) > 0 {
-		for dbId, apiInput := range updatedItems {
-			err = itemStore.Update(ctx, apiInput, dbId)
-			if err != nil {
-				return fmt.Errorf("itemStore.Update failed on ID: %v: %w", dbId, err)
-			}
+		inputs, ids := splitUpdates(updatedItems)
+		if err := itemStore.BulkUpdate(ctx, inputs, ids); err != nil {
+			return fmt.Errorf("itemStore.BulkUpdate failed: %w", err)
 		}
-		c.InfoLog.Info("updated exchange rates", slog.Int("num", len(updatedItems)))
+		c.InfoLog.Info("updated exchange rates", slog.String(clog.KeyDataset, DatasetEcbExchangeRates), slog.Int(clog.KeyCount, len(updatedItems)))
 	}
 
 	return nil
 }
+
+// EcbExchangeRatesDiff contains the changes needed to sync stored exchange rates with API exchange rates
+type EcbExchangeRatesDiff struct {
+	New     []ecbexchangerate.Input
+	Updated map[int64]ecbexchangerate.Input // map key is the DB ID
+	Deleted []ecbexchangerate.Model
+}
+
+// DiffEcbExchangeRates compares API and DB items, both keyed by ecbexchangerate.NaturalKey, using equal to detect changed values
+func DiffEcbExchangeRates(apiItemsMap, dbItemsMap map[ecbexchangerate.NaturalKey]ecbexchangerate.Model, equal func(a, b ecbexchangerate.Model) bool) (diff EcbExchangeRatesDiff) {
+
+	// on a backfill, the API items not yet stored are new
+	diff.New = make([]ecbexchangerate.Input, 0, max(len(apiItemsMap)-len(dbItemsMap), 0))
+	diff.Updated = make(map[int64]ecbexchangerate.Input)
+	diff.Deleted = []ecbexchangerate.Model{}
+
+	// for each API item
+	for key, apiItem := range apiItemsMap {
+
+		// try to find the equivalent DB item
+		dbItem, ok := dbItemsMap[key]
+		if !ok {
+			diff.New = append(diff.New, apiItem.Input)
+			continue
+		}
+
+		// found: compare values and only update if needed
+		if !equal(apiItem, dbItem) {
+			diff.Updated[dbItem.Id] = apiItem.Input
+		}
+	}
+
+	// for each DB item
+	for key, dbItem := range dbItemsMap {
+
+		// try to find the equivalent API item
+		_, ok := apiItemsMap[key]
+		if !ok {
+			diff.Deleted = append(diff.Deleted, dbItem)
+		}
+	}
+
+	return diff
+}
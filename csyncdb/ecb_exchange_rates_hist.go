@@ -0,0 +1,111 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/connectors/syncrun"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbcurrency"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// EcbExchangeRatesHist loads the full daily rate history contained in zipContent (the ECB's eurofxref-hist.zip) using COPY
+// existing rows are updated if their rate differs, nothing is deleted. Duplicate rates are skipped
+func EcbExchangeRatesHist(ctx context.Context, db *pgxpool.Pool, c ecbapi.Client, zipContent []byte) (parsed, inserted, updated int64, err error) {
+
+	items, parsed, _, err := histExchangeRates(ctx, db, c, zipContent)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("histExchangeRates failed: %w", err)
+	}
+
+	// load
+	itemStore := ecbexchangerate.Store{Db: db}
+	inserted, updated, err = itemStore.CopyUpsert(ctx, items)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("itemStore.CopyUpsert failed: %w", err)
+	}
+	c.InfoLog.Info("loaded hist exchange rates", slog.String(clog.KeyDataset, DatasetEcbExchangeRates), slog.Int64("inserted", inserted), slog.Int64("updated", updated))
+
+	return parsed, inserted, updated, nil
+}
+
+// EcbExchangeRatesHistSwap replaces the stored daily EUR rates with the history contained in zipContent, using ecbexchangerate.Store.SwapReload, so that readers see either
+// the former rates or the complete history, never a partial load. Unlike EcbExchangeRatesHist, stored daily EUR rates missing from the history are deleted. Monthly rates are kept
+// concurrent syncs of the same store are serialized with it
+func EcbExchangeRatesHistSwap(ctx context.Context, db *pgxpool.Pool, c ecbapi.Client, zipContent []byte) (parsed, inserted, updated, deleted int64, err error) {
+
+	items, parsed, baseCurrFk, err := histExchangeRates(ctx, db, c, zipContent)
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("histExchangeRates failed: %w", err)
+	}
+
+	itemStore := ecbexchangerate.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	inserted, updated, deleted, err = itemStore.SwapReload(ctx, ecbexchangerate.Daily, baseCurrFk, items)
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("itemStore.SwapReload failed: %w", err)
+	}
+	c.InfoLog.Info("swapped in hist exchange rates", slog.String(clog.KeyDataset, DatasetEcbExchangeRates), slog.Int64("inserted", inserted), slog.Int64("updated", updated),
+		slog.Int64("deleted", deleted))
+
+	return parsed, inserted, updated, deleted, nil
+}
+
+// histExchangeRates returns the rates of zipContent with their provenance, without duplicates, the number parsed, including duplicates, and the db id of their base currency
+func histExchangeRates(ctx context.Context, db *pgxpool.Pool, c ecbapi.Client, zipContent []byte) (items []ecbexchangerate.Input, parsed, baseCurrFk int64, err error) {
+
+	// select map of k = ECB currency code, v = db id
+	currStore := ecbcurrency.Store{Db: db}
+	currMap, err := currStore.SelectCodeIdMap(ctx)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("currStore.SelectCodeIdMap failed: %w", err)
+	}
+	if len(currMap) == 0 {
+		return nil, 0, 0, fmt.Errorf("%w: no currencies found: pls sync currencies first", cerrors.ErrNotFound)
+	}
+
+	// parse and validate zip content
+	items, err = ecbapi.GetHistExchangeRates(zipContent, currMap)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("ecbapi.GetHistExchangeRates failed: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, 0, 0, fmt.Errorf("%w: no rates in zip content", cerrors.ErrNotFound)
+	}
+	parsed, baseCurrFk = int64(len(items)), items[0].FromCurrencyFk
+	c.InfoLog.Info("parsed hist exchange rates", slog.String(clog.KeyDataset, DatasetEcbExchangeRates), slog.Int64(clog.KeyCount, parsed))
+
+	// the file was fetched by the caller: its load time is recorded as the fetch time
+	fetchedAt := lystype.Datetime(time.Now())
+	runId, hasRun := syncrun.RunIdFromContext(ctx)
+	for i := range items {
+		items[i].Source = c.SourceName()
+		items[i].FetchedAt = &fetchedAt
+		if hasRun {
+			items[i].SourceRunId = &runId
+		}
+	}
+
+	// a rate listed twice is loaded once, keeping the first
+	items, skipped, err := ecbexchangerate.Dedup(items, ecbexchangerate.DuplicatesSkip)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("ecbexchangerate.Dedup failed: %w", err)
+	}
+	if skipped > 0 {
+		c.InfoLog.Warn("skipped duplicate hist exchange rates", slog.String(clog.KeyDataset, DatasetEcbExchangeRates), slog.Int(clog.KeyCount, skipped))
+	}
+
+	return items, parsed, baseCurrFk, nil
+}
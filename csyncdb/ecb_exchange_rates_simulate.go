@@ -0,0 +1,60 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/quality"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbcurrency"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+)
+
+// EcbExchangeRatesSimulation is the outcome of SimulateEcbExchangeRates
+type EcbExchangeRatesSimulation struct {
+	Schema   string // of the snapshot
+	Snapshot int64  // rates copied into the snapshot, 0 if an existing one was reused
+	Fetched  int    // API rates
+	New      int    // rates the sync inserted into the snapshot
+	Updated  int    // rates the sync updated
+	Deleted  int    // rates the sync deleted
+}
+
+// SimulateEcbExchangeRates runs the sync of EcbExchangeRates against a snapshot of the live rates in schema, e.g. "ecb_shadow", rather than against ecb.exchange_rate,
+// so that a change to the diff logic or to Equal can be checked on production-scale data before it touches the live table: the outcome counts the rates it would insert, update and delete
+// with snapshot, the snapshot is first made, or replaced, from the live table by ecbexchangerate.CreateShadow. Otherwise that of a previous call is reused, including the changes
+// of its simulation. Nothing is journaled or published, and the live tables are only read
+func SimulateEcbExchangeRates(ctx context.Context, db *pgxpool.Pool, c ecbapi.Client, schema string, snapshot bool, baseCurr string, freq ecbapi.Frequency, startDate, endDate time.Time,
+	rules []quality.Rule, fallbacks ...ExchangeRateSource) (sim EcbExchangeRatesSimulation, err error) {
+
+	if err = ecbexchangerate.ValidateShadowSchema(schema); err != nil {
+		return sim, fmt.Errorf("ecbexchangerate.ValidateShadowSchema failed: %w", err)
+	}
+	sim.Schema = schema
+
+	apiItems, err := getEcbExchangeRates(c, fallbacks, baseCurr, freq, startDate, endDate)
+	if err != nil {
+		return sim, fmt.Errorf("getEcbExchangeRates failed: %w", err)
+	}
+	sim.Fetched = len(apiItems)
+
+	if snapshot {
+		sim.Snapshot, err = ecbexchangerate.CreateShadow(ctx, db, schema)
+		if err != nil {
+			return sim, fmt.Errorf("ecbexchangerate.CreateShadow failed: %w", err)
+		}
+		c.InfoLog.Info("created shadow snapshot", slog.String(clog.KeyDataset, DatasetEcbExchangeRates), slog.String("schema", schema), slog.Int64(clog.KeyCount, sim.Snapshot))
+	}
+
+	diff, err := applyEcbExchangeRatesToStores(ctx, ecbcurrency.Store{Db: db}, ecbexchangerate.ShadowStore{Db: db, Schema: schema}, c, apiItems, baseCurr, freq, startDate, endDate, rules)
+	if err != nil {
+		return sim, fmt.Errorf("applyEcbExchangeRatesToStores failed: %w", err)
+	}
+	sim.New, sim.Updated, sim.Deleted = len(diff.New), len(diff.Updated), len(diff.Deleted)
+
+	return sim, nil
+}
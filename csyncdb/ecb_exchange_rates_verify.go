@@ -0,0 +1,225 @@
+package csyncdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbcurrency"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	VerifyChunkDays int = 365    // days of API rates fetched and merged at a time
+	VerifyPageSize  int = 10_000 // stored rates read at a time
+)
+
+// EcbExchangeRatesVerification is the outcome of VerifyEcbExchangeRates
+type EcbExchangeRatesVerification struct {
+	Checked  int64 // API rates compared
+	Missing  int64 // API rates not stored
+	Changed  int64 // stored rates which differ from the API rate
+	Extra    int64 // stored rates not in the API
+	Repaired bool  // whether the differences were applied
+}
+
+// VerifyEcbExchangeRates compares the rates of db in the date range with the ECB, and with repair, syncs them. See VerifyEcbExchangeRatesInStores
+func VerifyEcbExchangeRates(ctx context.Context, db *pgxpool.Pool, c ecbapi.Client, baseCurr string, freq ecbapi.Frequency, startDate, endDate time.Time, repair bool) (v EcbExchangeRatesVerification, err error) {
+	return VerifyEcbExchangeRatesInStores(ctx, ecbcurrency.Store{Db: db}, ecbexchangerate.Store{Db: db}, c, baseCurr, freq, startDate, endDate, repair)
+}
+
+// VerifyEcbExchangeRatesInStores compares the rates of itemStore in the date range with the ECB by a sort-merge join, so that memory stays flat however long the range:
+// the API rates are fetched in chunks of VerifyChunkDays, each retried by apiclient.Chunks, and sorted, and the stored rates are read in keyset pages of VerifyPageSize, both in ecbexchangerate.NaturalKey order
+// with repair, the differences of each chunk are applied as a sync would: missing rates are inserted, changed rates updated and extra rates deleted
+// a chunk the ECB has no rates for counts as empty. Concurrent calls for the same itemStore are serialized with syncs. Returns ErrDerivedBase if baseCurr is not EUR, since only the EUR rates are stored
+func VerifyEcbExchangeRatesInStores(ctx context.Context, currStore ecbcurrency.Storer, itemStore ecbexchangerate.Storer, c ecbapi.Client, baseCurr string, freq ecbapi.Frequency,
+	startDate, endDate time.Time, repair bool) (v EcbExchangeRatesVerification, err error) {
+
+	if err = checkStoredBase(baseCurr); err != nil {
+		return v, err
+	}
+
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return v, fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	// select map of k = ECB currency code, v = db id
+	currMap, err := currStore.SelectCodeIdMap(ctx)
+	if err != nil {
+		return v, fmt.Errorf("currStore.SelectCodeIdMap failed: %w", err)
+	}
+	if len(currMap) == 0 {
+		return v, fmt.Errorf("%w: no currencies found: pls sync currencies first", cerrors.ErrNotFound)
+	}
+
+	cursor := &ecbRateCursor{store: itemStore, baseCurr: baseCurr, freq: freq, startDate: startDate, endDate: endDate}
+	v.Repaired = repair
+
+	err = apiclient.Chunks(ctx, apiclient.Paging{Log: c.InfoLog}, startDate, endDate, VerifyChunkDays,
+		func(chunkStart, chunkEnd time.Time) ([]ecbapi.ExchangeRate, error) {
+			apiItems, err := c.GetAPIExchangeRates(baseCurr, freq, chunkStart, chunkEnd)
+			if err != nil && !errors.Is(err, cerrors.ErrNotFound) {
+				return nil, fmt.Errorf("c.GetAPIExchangeRates failed: %w", err)
+			}
+			return apiItems, nil
+		},
+		func(chunkStart, chunkEnd time.Time, apiItems []ecbapi.ExchangeRate) error {
+			inputs, err := ecbapi.ExchangeRatesToItems(apiItems, currMap)
+			if err != nil {
+				return fmt.Errorf("ecbapi.ExchangeRatesToItems failed: %w", err)
+			}
+
+			// sort in the order of the cursor. Of several rates with the same key, the first is kept
+			slices.SortStableFunc(inputs, func(a, b ecbexchangerate.Input) int {
+				return ecbexchangerate.KeyOf(a).Compare(ecbexchangerate.KeyOf(b))
+			})
+			inputs = slices.CompactFunc(inputs, func(a, b ecbexchangerate.Input) bool {
+				return ecbexchangerate.KeyOf(a) == ecbexchangerate.KeyOf(b)
+			})
+
+			diff, err := mergeEcbExchangeRates(ctx, inputs, cursor, itemStore.Equal)
+			if err != nil {
+				return fmt.Errorf("mergeEcbExchangeRates failed: %w", err)
+			}
+			v.add(diff, int64(len(inputs)))
+
+			c.InfoLog.Info("verified exchange rates", slog.String(clog.KeyDataset, DatasetEcbExchangeRates), slog.String("start", chunkStart.Format(lystype.DateFormat)), slog.String("end", chunkEnd.Format(lystype.DateFormat)),
+				slog.Int("missing", len(diff.New)), slog.Int("changed", len(diff.Updated)), slog.Int("extra", len(diff.Deleted)))
+
+			if repair {
+				if err = applyEcbExchangeRatesDiff(ctx, itemStore, c, diff); err != nil {
+					return fmt.Errorf("applyEcbExchangeRatesDiff failed: %w", err)
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return v, fmt.Errorf("apiclient.Chunks failed: %w", err)
+	}
+
+	// the stored rates after the last API rate are not in the API: drain them a page at a time
+	for {
+		diff := EcbExchangeRatesDiff{}
+		for len(diff.Deleted) < VerifyPageSize {
+			dbItem, ok, err := cursor.peek(ctx)
+			if err != nil {
+				return v, fmt.Errorf("cursor.peek failed: %w", err)
+			}
+			if !ok {
+				break
+			}
+			diff.Deleted = append(diff.Deleted, dbItem)
+			cursor.next()
+		}
+		if len(diff.Deleted) == 0 {
+			break
+		}
+		v.add(diff, 0)
+
+		if repair {
+			if err = applyEcbExchangeRatesDiff(ctx, itemStore, c, diff); err != nil {
+				return v, fmt.Errorf("applyEcbExchangeRatesDiff failed: %w", err)
+			}
+		}
+	}
+
+	return v, nil
+}
+
+// add adds the counts of diff, the outcome of checked API rates
+func (v *EcbExchangeRatesVerification) add(diff EcbExchangeRatesDiff, checked int64) {
+	v.Checked += checked
+	v.Missing += int64(len(diff.New))
+	v.Changed += int64(len(diff.Updated))
+	v.Extra += int64(len(diff.Deleted))
+}
+
+// mergeEcbExchangeRates compares inputs, sorted by ecbexchangerate.NaturalKey, with the stored rates of cursor up to the last of them, using equal to detect changed values
+// stored rates sorting before an input are not in the API, so are returned as deleted. cursor is left at the first stored rate after the last input
+func mergeEcbExchangeRates(ctx context.Context, inputs []ecbexchangerate.Input, cursor *ecbRateCursor, equal func(a, b ecbexchangerate.Model) bool) (diff EcbExchangeRatesDiff, err error) {
+
+	diff.New = []ecbexchangerate.Input{}
+	diff.Updated = make(map[int64]ecbexchangerate.Input)
+	diff.Deleted = []ecbexchangerate.Model{}
+
+	for _, input := range inputs {
+
+		key := ecbexchangerate.KeyOf(input)
+		for {
+			dbItem, ok, err := cursor.peek(ctx)
+			if err != nil {
+				return EcbExchangeRatesDiff{}, fmt.Errorf("cursor.peek failed: %w", err)
+			}
+
+			// no stored rate left, or the next is after the input: the input is new
+			if !ok || ecbexchangerate.KeyOf(dbItem.Input).Compare(key) > 0 {
+				diff.New = append(diff.New, input)
+				break
+			}
+
+			cursor.next()
+
+			// found: compare values and only update if needed
+			if ecbexchangerate.KeyOf(dbItem.Input) == key {
+				if !equal(ecbexchangerate.Model{Input: input}, dbItem) {
+					diff.Updated[dbItem.Id] = input
+				}
+				break
+			}
+
+			// before the input: not in the API
+			diff.Deleted = append(diff.Deleted, dbItem)
+		}
+	}
+
+	return diff, nil
+}
+
+// ecbRateCursor reads the stored rates of a date range in ecbexchangerate.NaturalKey order, a keyset page at a time
+// rates inserted before its position, as by a repair, are not read
+type ecbRateCursor struct {
+	store              ecbexchangerate.Storer
+	baseCurr           string
+	freq               ecbexchangerate.Frequency
+	startDate, endDate time.Time
+
+	page  []ecbexchangerate.Model
+	after ecbexchangerate.NaturalKey // key of the last rate read
+	done  bool                       // whether the last page was read
+}
+
+// peek returns the rate at the position of cur, reading the next page if needed. ok is false once all rates were read
+func (cur *ecbRateCursor) peek(ctx context.Context) (item ecbexchangerate.Model, ok bool, err error) {
+
+	if len(cur.page) == 0 && !cur.done {
+		cur.page, err = cur.store.SelectPageAfter(ctx, cur.baseCurr, cur.freq, cur.startDate, cur.endDate, cur.after, VerifyPageSize)
+		if err != nil {
+			return ecbexchangerate.Model{}, false, fmt.Errorf("cur.store.SelectPageAfter failed: %w", err)
+		}
+		cur.done = len(cur.page) < VerifyPageSize
+		if len(cur.page) > 0 {
+			cur.after = ecbexchangerate.KeyOf(cur.page[len(cur.page)-1].Input)
+		}
+	}
+
+	if len(cur.page) == 0 {
+		return ecbexchangerate.Model{}, false, nil
+	}
+	return cur.page[0], true, nil
+}
+
+// next advances cur past the rate returned by peek
+func (cur *ecbRateCursor) next() {
+	cur.page = cur.page[1:]
+}
@@ -0,0 +1,74 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbpressrelease"
+)
+
+// EcbPressReleasesToTargets fetches the ECB press feed once and syncs its items into each target, recording a journal entry in each
+func EcbPressReleasesToTargets(ctx context.Context, targets []Target, c ecbapi.Client) error {
+
+	apiItems, fetchErr := c.GetApiPressReleases()
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiPressReleases failed: %w", fetchErr)
+	}
+
+	return toTargets(ctx, targets, DatasetEcbPressReleases, "", func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyEcbPressReleases(ctx, db, c, ecbapi.PressReleasesToMap(apiItems))
+	})
+}
+
+// ApplyEcbPressReleases inserts or updates the press releases of db with already fetched feed items (map with Url as key). c is only used for logging
+// press releases no longer in the feed are kept, since the feed only lists recent items
+func ApplyEcbPressReleases(ctx context.Context, db *pgxpool.Pool, c ecbapi.Client, apiItemsMap map[string]ecbpressrelease.Model) error {
+
+	itemStore := ecbpressrelease.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	urls := make([]string, 0, len(apiItemsMap))
+	for url := range apiItemsMap {
+		urls = append(urls, url)
+	}
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx, urls)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	inserted, updated := 0, 0
+	for key, apiItem := range apiItemsMap {
+
+		dbItem, ok := dbItemsMap[key]
+		if !ok {
+			if _, err = itemStore.Insert(ctx, apiItem.Input); err != nil {
+				return fmt.Errorf("itemStore.Insert failed on url: %v: %w", key, err)
+			}
+			inserted++
+			continue
+		}
+
+		if !itemStore.Equal(apiItem, dbItem) {
+			if err = itemStore.Update(ctx, apiItem.Input, dbItem.Id); err != nil {
+				return fmt.Errorf("itemStore.Update failed on url: %v: %w", key, err)
+			}
+			updated++
+		}
+	}
+
+	c.InfoLog.Info("synced press releases", slog.String(clog.KeyDataset, DatasetEcbPressReleases), slog.Int("inserted", inserted), slog.Int("updated", updated))
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/converter"
+	"github.com/loveyourstack/connectors/derive"
+	"github.com/loveyourstack/connectors/quality"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbcurrency"
+)
+
+// EcbRateFetcher returns the Fetcher of a converter.ReadThrough which syncs the daily EUR rates of a date range from c into targets with EcbExchangeRatesToTargets,
+// so with a journal entry in each, the rates checked against rules and the derived fields computed. The currencies are synced first into targets which have none, so that the rates of an empty database can be stored
+func EcbRateFetcher(targets []Target, c ecbapi.Client, rules []quality.Rule, derived []derive.Field) converter.FetcherFunc {
+
+	return func(ctx context.Context, startDate, endDate time.Time) error {
+
+		for _, t := range targets {
+			count, err := ecbcurrency.Store{Db: t.Db}.Count(ctx)
+			if err != nil {
+				return fmt.Errorf("ecbcurrency.Store.Count failed for target %s: %w", t.Name, err)
+			}
+			if count == 0 {
+				if err = EcbCurrenciesToTargets(ctx, targets, c); err != nil {
+					return fmt.Errorf("EcbCurrenciesToTargets failed: %w", err)
+				}
+				break
+			}
+		}
+
+		if err := EcbExchangeRatesToTargets(ctx, targets, c, "EUR", ecbapi.Daily, startDate, endDate, rules, derived); err != nil {
+			return fmt.Errorf("EcbExchangeRatesToTargets failed: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,72 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/calendar"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/freshness"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+)
+
+// DefaultRevisionDays is the number of TARGET business days before the latest stored day which EcbSyncWindow syncs again, since the ECB may revise recent rates
+const DefaultRevisionDays int = 3
+
+// EcbSyncWindow returns the date range of an exchange rate sync at now, sized from the rates of freq stored in targets, so that callers such as cron jobs need no date arithmetic:
+// from the latest stored day less revisionDays TARGET business days (months for monthly rates), to pick up revised rates, to the latest day the ECB is expected to have published
+// the window covers the target furthest behind. A target without rates starts the window initialDays days before now
+func EcbSyncWindow(ctx context.Context, targets []Target, freq ecbapi.Frequency, revisionDays, initialDays int, now time.Time) (startDate, endDate time.Time, err error) {
+
+	if len(targets) == 0 {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: no targets", cerrors.ErrValidationFailed)
+	}
+	if revisionDays < 0 || initialDays < 1 {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: revisionDays must not be negative and initialDays must be at least 1", cerrors.ErrValidationFailed)
+	}
+
+	switch freq {
+	case ecbapi.Daily:
+		endDate = freshness.ExpectedLatestDaily(now)
+	case ecbapi.Monthly:
+		endDate = freshness.ExpectedLatestMonthly(now)
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: invalid freq '%s'", cerrors.ErrValidationFailed, freq)
+	}
+
+	for i, t := range targets {
+
+		latestDay, _, err := ecbexchangerate.Store{Db: t.Db}.SelectLatestDay(ctx, freq)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("SelectLatestDay failed for target %s: %w", t.Name, err)
+		}
+
+		var targetStart time.Time
+		switch {
+		case latestDay.IsZero():
+			targetStart = truncateDay(now).AddDate(0, 0, -initialDays)
+		case freq == ecbapi.Monthly:
+			targetStart = latestDay.AddDate(0, -revisionDays, 0)
+		default:
+			targetStart = calendar.SubBusinessDays(latestDay, revisionDays)
+		}
+
+		if i == 0 || targetStart.Before(startDate) {
+			startDate = targetStart
+		}
+	}
+
+	// the latest stored day may be after the expected one, e.g. if rates were published early
+	if startDate.After(endDate) {
+		startDate = endDate
+	}
+
+	return startDate, endDate, nil
+}
+
+// truncateDay returns the date of t at midnight UTC
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,101 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/eurostatapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/eurostat/eurostatobservation"
+)
+
+// EurostatObservationsToTargets fetches the observations selected by q once and syncs them into each target, recording a journal entry in each
+func EurostatObservationsToTargets(ctx context.Context, targets []Target, c eurostatapi.Client, q eurostatapi.Query) error {
+
+	apiItems, fetchErr := c.GetApiData(q)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiData failed: %w", fetchErr)
+	}
+
+	return toTargets(ctx, targets, DatasetEurostatObservations, q.String(), func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyEurostatObservations(ctx, db, c, q, apiItems)
+	})
+}
+
+// ApplyEurostatObservations syncs the observations of db selected by q with already fetched API observations. c is only used for logging
+// only the periods returned by the API are compared, so that older observations are kept when q selects the last periods
+func ApplyEurostatObservations(ctx context.Context, db *pgxpool.Pool, c eurostatapi.Client, q eurostatapi.Query, apiItems []eurostatapi.Observation) error {
+
+	itemStore := eurostatobservation.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	apiItemsMap, err := eurostatapi.ObservationsToMap(apiItems)
+	if err != nil {
+		return fmt.Errorf("eurostatapi.ObservationsToMap failed: %w", err)
+	}
+
+	periodsMap := make(map[string]bool)
+	for _, apiItem := range apiItems {
+		periodsMap[apiItem.Period] = true
+	}
+	periods := make([]string, 0, len(periodsMap))
+	for period := range periodsMap {
+		periods = append(periods, period)
+	}
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx, q.Dataset, periods)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	newItems := []eurostatobservation.Input{}
+	updatedItems := make(map[int64]eurostatobservation.Input)
+	deletedIds := []int64{}
+
+	for key, apiItem := range apiItemsMap {
+		dbItem, ok := dbItemsMap[key]
+		if !ok {
+			newItems = append(newItems, apiItem.Input)
+			continue
+		}
+		if !itemStore.Equal(apiItem, dbItem) {
+			updatedItems[dbItem.Id] = apiItem.Input
+		}
+	}
+	for key, dbItem := range dbItemsMap {
+		// observations of the dataset outside of the filters of q belong to other queries
+		if !q.Matches(dbItem.Dimensions) {
+			continue
+		}
+		if _, ok := apiItemsMap[key]; !ok {
+			deletedIds = append(deletedIds, dbItem.Id)
+		}
+	}
+
+	if err = itemStore.BulkDelete(ctx, deletedIds); err != nil {
+		return fmt.Errorf("itemStore.BulkDelete failed: %w", err)
+	}
+	if len(newItems) > 0 {
+		if _, err = itemStore.BulkInsert(ctx, newItems); err != nil {
+			return fmt.Errorf("itemStore.BulkInsert failed: %w", err)
+		}
+	}
+	inputs, ids := splitUpdates(updatedItems)
+	if err = itemStore.BulkUpdate(ctx, inputs, ids); err != nil {
+		return fmt.Errorf("itemStore.BulkUpdate failed: %w", err)
+	}
+
+	c.InfoLog.Info("synced observations", slog.String(clog.KeyDataset, DatasetEurostatObservations), slog.String(clog.KeyCode, q.Dataset),
+		slog.Int("inserted", len(newItems)), slog.Int("updated", len(updatedItems)), slog.Int("deleted", len(deletedIds)))
+
+	return nil
+}
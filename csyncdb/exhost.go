@@ -0,0 +1,121 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/exhostapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/exhost/exhostrate"
+	"github.com/loveyourstack/connectors/stores/exhost/exhostusage"
+)
+
+// ExhostRatesToTargets fetches the live exchangerate.host rates from source to currencies (all if empty) once and inserts them into each target, recording a journal entry in each
+// API usage is tracked in the first target: if monthlyQuota > 0 and the requests of the current month have reached it, no request is made and ErrQuotaExceeded is returned per target
+func ExhostRatesToTargets(ctx context.Context, targets []Target, c exhostapi.Client, source string, currencies []string, monthlyQuota int64) error {
+
+	if len(targets) == 0 {
+		return nil
+	}
+
+	apiItems, fetchErr := fetchExhostRates(ctx, targets[0].Db, c, source, currencies, monthlyQuota)
+
+	params := ExhostRatesParams(source, currencies)
+	return toTargets(ctx, targets, DatasetExhostRates, params, func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyExhostRates(ctx, db, c, apiItems)
+	})
+}
+
+// ExhostRatesParams returns the journal params of an ExhostRates run
+func ExhostRatesParams(source string, currencies []string) string {
+	if len(currencies) == 0 {
+		return fmt.Sprintf("source=%s currencies=all", source)
+	}
+	return fmt.Sprintf("source=%s currencies=%s", source, strings.Join(currencies, ","))
+}
+
+// fetchExhostRates requests the live rates if the monthly quota allows it, counting the request in usageDb
+func fetchExhostRates(ctx context.Context, usageDb *pgxpool.Pool, c exhostapi.Client, source string, currencies []string, monthlyQuota int64) (apiItems []exhostapi.Rate, err error) {
+
+	usageStore := exhostusage.Store{Db: usageDb}
+	now := time.Now()
+
+	if monthlyQuota > 0 {
+		used, err := usageStore.SelectCount(ctx, now)
+		if err != nil {
+			return nil, fmt.Errorf("usageStore.SelectCount failed: %w", err)
+		}
+		if used >= monthlyQuota {
+			return nil, fmt.Errorf("%w: %d of %d requests used this month", exhostapi.ErrQuotaExceeded, used, monthlyQuota)
+		}
+	}
+
+	// counted before the request, since failed requests may count towards the quota as well
+	used, err := usageStore.Increment(ctx, now)
+	if err != nil {
+		return nil, fmt.Errorf("usageStore.Increment failed: %w", err)
+	}
+
+	apiItems, err = c.GetApiLiveRates(ctx, source, currencies)
+	if err != nil {
+		return nil, fmt.Errorf("c.GetApiLiveRates failed: %w", err)
+	}
+
+	if monthlyQuota > 0 && used*10 >= monthlyQuota*9 {
+		c.InfoLog.Warn("monthly quota nearly used", slog.String(clog.KeyDataset, DatasetExhostRates), slog.Int64("used", used), slog.Int64("quota", monthlyQuota))
+	}
+
+	return apiItems, nil
+}
+
+// ApplyExhostRates inserts already fetched API rates into db, skipping rates already stored for their quote timestamp. c is only used for logging
+// rates are never updated or deleted, so that db keeps the history of intraday quotes
+func ApplyExhostRates(ctx context.Context, db *pgxpool.Pool, c exhostapi.Client, apiItems []exhostapi.Rate) error {
+
+	itemStore := exhostrate.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	// k = from currency + quote timestamp, v = set of to currencies already stored
+	storedMap := make(map[string]map[string]bool)
+
+	newItems := []exhostrate.Input{}
+	for _, input := range exhostapi.RatesToInputs(apiItems) {
+
+		quotedAt := time.Time(input.QuotedAt)
+		key := input.FromCurrency + "_" + quotedAt.Format(time.RFC3339)
+
+		stored, ok := storedMap[key]
+		if !ok {
+			var err error
+			stored, err = itemStore.SelectCurrenciesAt(ctx, input.FromCurrency, quotedAt)
+			if err != nil {
+				return fmt.Errorf("itemStore.SelectCurrenciesAt failed: %w", err)
+			}
+			storedMap[key] = stored
+		}
+
+		if !stored[input.ToCurrency] {
+			newItems = append(newItems, input)
+		}
+	}
+
+	if len(newItems) > 0 {
+		if _, err := itemStore.BulkInsert(ctx, newItems); err != nil {
+			return fmt.Errorf("itemStore.BulkInsert failed: %w", err)
+		}
+		c.InfoLog.Info("inserted rates", slog.String(clog.KeyDataset, DatasetExhostRates), slog.Int(clog.KeyCount, len(newItems)))
+	}
+
+	return nil
+}
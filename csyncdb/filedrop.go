@@ -0,0 +1,258 @@
+package csyncdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/s3api"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/flatfile"
+	"github.com/loveyourstack/connectors/stores/filedrop/fdfile"
+	"github.com/loveyourstack/connectors/stores/filedrop/fdmapped"
+	"github.com/loveyourstack/connectors/stores/filedrop/fdmessage"
+	"github.com/loveyourstack/connectors/stores/filedrop/fdrecord"
+)
+
+// FiledropFile is a file read for a feed, from a bucket or an email attachment: its records, or why it could not be parsed
+type FiledropFile struct {
+	Feed           string
+	Key            string // unique within the feed, e.g. the object key
+	Etag           string // changes when the file is replaced
+	Size           int64
+	LastModifiedAt time.Time
+	Records        []json.RawMessage
+	Mapping        *flatfile.Mapping // of the feed, if any
+	Rows           [][]any           // the Records mapped by Mapping
+	ParseError     string
+	Message        *fdmessage.Input // provenance of email attachments. FileFk is assigned when applied
+}
+
+// FiledropRecordsToTargets lists the files of feeds in the bucket, reads those which are new or changed in any target's manifest once, and syncs them into each target, recording a journal entry in each
+func FiledropRecordsToTargets(ctx context.Context, targets []Target, c s3api.Client, feeds []flatfile.Feed) error {
+
+	manifests := filedropManifests(ctx, targets, c, feeds)
+
+	files, fetchErr := getFiledropFiles(ctx, c, feeds, manifests)
+
+	return toTargets(ctx, targets, DatasetFiledropRecords, FiledropRecordsParams(feeds), func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyFiledropRecords(ctx, db, files, DatasetFiledropRecords, c.InfoLog)
+	})
+}
+
+// FiledropRecordsParams returns the journal params of a FiledropRecords run
+func FiledropRecordsParams(feeds []flatfile.Feed) string {
+
+	names := make([]string, len(feeds))
+	for i, f := range feeds {
+		names[i] = f.Name
+	}
+	return "feeds=" + strings.Join(names, ",")
+}
+
+// filedropManifests returns the manifest of each feed in each target, keyed by feed name and then object key
+// targets whose manifest can't be read are skipped: their sync fails when applied
+func filedropManifests(ctx context.Context, targets []Target, c s3api.Client, feeds []flatfile.Feed) (manifests []map[string]map[string]fdfile.Model) {
+
+	for _, t := range targets {
+		manifest := make(map[string]map[string]fdfile.Model, len(feeds))
+		ok := true
+		for _, f := range feeds {
+			fileMap, err := fdfile.Store{Db: t.Db}.SelectMapByFeed(ctx, f.Name)
+			if err != nil {
+				c.ErrorLog.Error("fdfile.Store.SelectMapByFeed failed", slog.String(clog.KeyDataset, DatasetFiledropRecords), slog.String("target", t.Name), slog.String(clog.KeyError, err.Error()))
+				ok = false
+				break
+			}
+			manifest[f.Name] = fileMap
+		}
+		if ok {
+			manifests = append(manifests, manifest)
+		}
+	}
+
+	return manifests
+}
+
+// getFiledropFiles lists the files of feeds and reads those whose etag differs from the manifest of any of manifests
+// a file which can't be parsed is returned with its ParseError rather than failing the run, so that one bad delivery does not block the feed
+func getFiledropFiles(ctx context.Context, c s3api.Client, feeds []flatfile.Feed, manifests []map[string]map[string]fdfile.Model) (files []FiledropFile, err error) {
+
+	for _, f := range feeds {
+
+		objects, err := c.ListObjects(ctx, f.Prefix)
+		if err != nil {
+			return nil, fmt.Errorf("c.ListObjects failed on feed: %s: %w", f.Name, err)
+		}
+
+		for _, obj := range objects {
+			if !f.Includes(obj.Key) || !filedropNeeded(manifests, f.Name, obj) {
+				continue
+			}
+
+			content, err := c.GetObject(ctx, obj.Key)
+			if err != nil {
+				return nil, fmt.Errorf("c.GetObject failed on key: %s: %w", obj.Key, err)
+			}
+
+			file := FiledropFile{Feed: f.Name, Key: obj.Key, Etag: obj.ETag, Size: obj.Size, LastModifiedAt: obj.LastModified}
+			if err = file.parse(f, content); err != nil {
+				c.ErrorLog.Warn("file could not be parsed", slog.String(clog.KeyDataset, DatasetFiledropRecords), slog.String("key", obj.Key), slog.String(clog.KeyError, err.Error()))
+			}
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
+
+// parse sets the Records of file from content, a file of f, and maps them if f has a mapping. If that fails, it sets ParseError and returns the error
+func (file *FiledropFile) parse(f flatfile.Feed, content []byte) (err error) {
+
+	file.Records, err = f.Parse(content)
+	if err == nil && f.Mapping != nil {
+		file.Mapping = f.Mapping
+		file.Rows, err = f.Mapping.Rows(file.Records)
+		if err != nil {
+			err = fmt.Errorf("mapping: %w", err)
+		}
+	}
+	if err != nil {
+		file.ParseError = err.Error()
+	}
+	return err
+}
+
+// filedropNeeded returns true if obj of feed is missing from any of manifests, or has a different etag there
+func filedropNeeded(manifests []map[string]map[string]fdfile.Model, feed string, obj s3api.Object) bool {
+
+	for _, manifest := range manifests {
+		dbFile, ok := manifest[feed][obj.Key]
+		if !ok || dbFile.Etag != obj.ETag {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyFiledropRecords replaces the records of db with those of the already read files which are new or changed in its manifest, and upserts their mapped rows. dataset is only used for logging
+// files removed from their source keep their records. A file which could not be parsed or mapped is recorded in the manifest with its error, and its previous records are kept
+func ApplyFiledropRecords(ctx context.Context, db *pgxpool.Pool, files []FiledropFile, dataset string, infoLog *slog.Logger) error {
+
+	fileStore := fdfile.Store{Db: db}
+	recordStore := fdrecord.Store{Db: db}
+	messageStore := fdmessage.Store{Db: db}
+	unlock, err := lockStore(fileStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	manifests := make(map[string]map[string]fdfile.Model)
+	filesRead, recordsInserted, rowsMapped, failed := 0, 0, 0, 0
+
+	for _, file := range files {
+
+		manifest, ok := manifests[file.Feed]
+		if !ok {
+			var err error
+			manifest, err = fileStore.SelectMapByFeed(ctx, file.Feed)
+			if err != nil {
+				return fmt.Errorf("fileStore.SelectMapByFeed failed on feed: %s: %w", file.Feed, err)
+			}
+			manifests[file.Feed] = manifest
+		}
+
+		if dbFile, ok := manifest[file.Key]; ok && dbFile.Etag == file.Etag {
+			continue
+		}
+
+		input := fdfile.Input{
+			Etag:                 file.Etag,
+			Feed:                 file.Feed,
+			Key:                  file.Key,
+			LastModifiedAtSource: file.LastModifiedAt,
+			Size:                 file.Size,
+		}
+
+		if file.ParseError != "" {
+			input.Error = file.ParseError
+			fileId, err := fileStore.Upsert(ctx, input)
+			if err != nil {
+				return fmt.Errorf("fileStore.Upsert failed on key: %s: %w", file.Key, err)
+			}
+			if err = upsertFiledropMessage(ctx, messageStore, file, fileId); err != nil {
+				return fmt.Errorf("upsertFiledropMessage failed on key: %s: %w", file.Key, err)
+			}
+			failed++
+			continue
+		}
+
+		// the file is recorded without etag until its records are replaced, so that it is read again if this fails
+		pending := input
+		pending.Etag = ""
+		fileId, err := fileStore.Upsert(ctx, pending)
+		if err != nil {
+			return fmt.Errorf("fileStore.Upsert failed on key: %s: %w", file.Key, err)
+		}
+
+		if err = recordStore.DeleteByFile(ctx, fileId); err != nil {
+			return fmt.Errorf("recordStore.DeleteByFile failed on key: %s: %w", file.Key, err)
+		}
+		if len(file.Records) > 0 {
+			inputs := make([]fdrecord.Input, len(file.Records))
+			for i, rec := range file.Records {
+				inputs[i] = fdrecord.Input{Data: rec, FileFk: fileId, LineNo: i + 1}
+			}
+			if _, err = recordStore.BulkInsert(ctx, inputs); err != nil {
+				return fmt.Errorf("recordStore.BulkInsert failed on key: %s: %w", file.Key, err)
+			}
+		}
+
+		if file.Mapping != nil && len(file.Rows) > 0 {
+			if _, err = (fdmapped.Store{Db: db}).Upsert(ctx, *file.Mapping, file.Rows); err != nil {
+				return fmt.Errorf("fdmapped.Store.Upsert failed on key: %s: %w", file.Key, err)
+			}
+			rowsMapped += len(file.Rows)
+		}
+
+		if err = upsertFiledropMessage(ctx, messageStore, file, fileId); err != nil {
+			return fmt.Errorf("upsertFiledropMessage failed on key: %s: %w", file.Key, err)
+		}
+
+		input.RowCount = len(file.Records)
+		if _, err = fileStore.Upsert(ctx, input); err != nil {
+			return fmt.Errorf("fileStore.Upsert failed on key: %s: %w", file.Key, err)
+		}
+
+		filesRead++
+		recordsInserted += len(file.Records)
+	}
+
+	if failed > 0 {
+		infoLog.Warn("files could not be parsed: see filedrop.file.error", slog.String(clog.KeyDataset, dataset), slog.Int(clog.KeyCount, failed))
+	}
+	infoLog.Info("synced file drop records", slog.String(clog.KeyDataset, dataset),
+		slog.Int("files", filesRead), slog.Int("inserted", recordsInserted), slog.Int("mapped", rowsMapped), slog.Int("failed", failed))
+
+	return nil
+}
+
+// upsertFiledropMessage records the message provenance of file, if any, as that of the file with fileId
+func upsertFiledropMessage(ctx context.Context, messageStore fdmessage.Store, file FiledropFile, fileId int64) error {
+
+	if file.Message == nil {
+		return nil
+	}
+
+	input := *file.Message
+	input.FileFk = fileId
+	return messageStore.Upsert(ctx, input)
+}
@@ -0,0 +1,165 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/fredapi"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/fred/fredobservation"
+	"github.com/loveyourstack/connectors/stores/fred/fredseries"
+)
+
+// FredSeriesToTargets fetches the metadata of the FRED series ids once and syncs it into each target, recording a journal entry in each
+// series not in ids are kept, so that their observations are not deleted when a series is removed from the sync config
+func FredSeriesToTargets(ctx context.Context, targets []Target, c fredapi.Client, ids []string) error {
+
+	apiItemsMap, fetchErr := c.GetSeriesMap(ctx, ids)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetSeriesMap failed: %w", fetchErr)
+	}
+
+	return toTargets(ctx, targets, DatasetFredSeries, "", func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyFredSeries(ctx, db, c, apiItemsMap)
+	})
+}
+
+// ApplyFredSeries inserts or updates the series of db with already fetched API series (map with Code as key). c is only used for logging
+func ApplyFredSeries(ctx context.Context, db *pgxpool.Pool, c fredapi.Client, apiItemsMap map[string]fredseries.Model) error {
+
+	itemStore := fredseries.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	codes := make([]string, 0, len(apiItemsMap))
+	for code := range apiItemsMap {
+		codes = append(codes, code)
+	}
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx, codes)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	for key, apiItem := range apiItemsMap {
+
+		dbItem, ok := dbItemsMap[key]
+		if !ok {
+			if _, err = itemStore.Insert(ctx, apiItem.Input); err != nil {
+				return fmt.Errorf("itemStore.Insert failed on code: %v: %w", key, err)
+			}
+			c.InfoLog.Info("inserted series", slog.String(clog.KeyDataset, DatasetFredSeries), slog.String(clog.KeyCode, key))
+			continue
+		}
+
+		if !itemStore.Equal(apiItem, dbItem) {
+			if err = itemStore.Update(ctx, apiItem.Input, dbItem.Id); err != nil {
+				return fmt.Errorf("itemStore.Update failed on code: %v: %w", key, err)
+			}
+			c.InfoLog.Info("updated series", slog.String(clog.KeyDataset, DatasetFredSeries), slog.String(clog.KeyCode, key))
+		}
+	}
+
+	return nil
+}
+
+// FredObservationsToTargets fetches the observations of FRED series id once and syncs them into each target, recording a journal entry in each
+func FredObservationsToTargets(ctx context.Context, targets []Target, c fredapi.Client, id string, startDate, endDate time.Time) error {
+
+	apiItems, fetchErr := c.GetApiObservations(ctx, id, startDate, endDate)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiObservations failed: %w", fetchErr)
+	}
+
+	params := FredObservationsParams(id, startDate, endDate)
+	return toTargets(ctx, targets, DatasetFredObservations, params, func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyFredObservations(ctx, db, c, id, apiItems, startDate, endDate)
+	})
+}
+
+// FredObservationsParams returns the journal params of a FredObservations run
+func FredObservationsParams(id string, startDate, endDate time.Time) string {
+	return fmt.Sprintf("series=%s from=%s to=%s", id, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+}
+
+// ApplyFredObservations syncs the observations of series id in db in the date range with already fetched API observations. c is only used for logging
+// the series must have been synced first
+func ApplyFredObservations(ctx context.Context, db *pgxpool.Pool, c fredapi.Client, id string, apiItems []fredapi.Observation, startDate, endDate time.Time) error {
+
+	itemStore := fredobservation.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	seriesMap, err := fredseries.Store{Db: db}.SelectCodeIdMap(ctx)
+	if err != nil {
+		return fmt.Errorf("fredseries.Store.SelectCodeIdMap failed: %w", err)
+	}
+	seriesFk, ok := seriesMap[id]
+	if !ok {
+		return fmt.Errorf("%w: series %s not found: pls sync series first", cerrors.ErrNotFound, id)
+	}
+
+	apiItemsMap, err := fredapi.ObservationsToMap(apiItems, seriesFk)
+	if err != nil {
+		return fmt.Errorf("fredapi.ObservationsToMap failed: %w", err)
+	}
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx, id, startDate, endDate)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	newItems := []fredobservation.Input{}
+	updatedItems := make(map[int64]fredobservation.Input)
+	deletedIds := []int64{}
+
+	for key, apiItem := range apiItemsMap {
+		dbItem, ok := dbItemsMap[key]
+		if !ok {
+			newItems = append(newItems, apiItem.Input)
+			continue
+		}
+		if !itemStore.Equal(apiItem, dbItem) {
+			updatedItems[dbItem.Id] = apiItem.Input
+		}
+	}
+	for key, dbItem := range dbItemsMap {
+		if _, ok := apiItemsMap[key]; !ok {
+			deletedIds = append(deletedIds, dbItem.Id)
+		}
+	}
+
+	if err = itemStore.BulkDelete(ctx, deletedIds); err != nil {
+		return fmt.Errorf("itemStore.BulkDelete failed: %w", err)
+	}
+	if len(newItems) > 0 {
+		if _, err = itemStore.BulkInsert(ctx, newItems); err != nil {
+			return fmt.Errorf("itemStore.BulkInsert failed: %w", err)
+		}
+	}
+	inputs, ids := splitUpdates(updatedItems)
+	if err = itemStore.BulkUpdate(ctx, inputs, ids); err != nil {
+		return fmt.Errorf("itemStore.BulkUpdate failed: %w", err)
+	}
+
+	c.InfoLog.Info("synced observations", slog.String(clog.KeyDataset, DatasetFredObservations), slog.String(clog.KeyCode, id),
+		slog.Int("inserted", len(newItems)), slog.Int("updated", len(updatedItems)), slog.Int("deleted", len(deletedIds)))
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package csyncdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/ratesource"
+	"github.com/loveyourstack/connectors/stores/fxrecon/fxrdivergence"
+	"github.com/loveyourstack/connectors/stores/fxrecon/fxrrate"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// FxReconParams contains the settings of a reconciliation of exchange rates from several sources
+type FxReconParams struct {
+	Primary      string   // name of the source the others are compared with
+	Base         string   // currency the rates are quoted from
+	Quotes       []string // currencies the rates are quoted to
+	TolerancePct float64  // divergences up to this percentage of the primary rate are accepted
+	StartDate    time.Time
+	EndDate      time.Time
+}
+
+// String returns the journal params of a reconciliation
+func (p FxReconParams) String() string {
+	return fmt.Sprintf("primary=%s base=%s quotes=%s tolerancePct=%v startDate=%s", p.Primary, p.Base, strings.Join(p.Quotes, ","), p.TolerancePct, p.StartDate.Format(lystype.DateFormat))
+}
+
+// FxReconRatesToTargets fetches the rates of each of sources once and stores them side by side in each target, then records the divergences from the primary source, recording a journal entry in each
+// a failing source does not stop the others: the rates fetched are applied, and the returned error includes the source's error
+func FxReconRatesToTargets(ctx context.Context, targets []Target, sources []ratesource.Source, p FxReconParams, infoLog, errorLog *slog.Logger) error {
+
+	rates := []ratesource.Rate{}
+	sourceRates := make(map[string][]ratesource.Rate)
+	var fetchErrs []error
+
+	for _, src := range sources {
+		srcRates, err := src.GetRates(ctx, p.Base, p.Quotes, p.StartDate, p.EndDate)
+		if err != nil {
+			errorLog.Error("rate source failed", slog.String(clog.KeyDataset, DatasetFxReconRates), slog.String("source", src.SourceName()), slog.String(clog.KeyError, err.Error()))
+			fetchErrs = append(fetchErrs, fmt.Errorf("src.GetRates failed on source: %s: %w", src.SourceName(), err))
+			continue
+		}
+		sourceRates[src.SourceName()] = srcRates
+		rates = append(rates, srcRates...)
+	}
+	fetchErr := errors.Join(fetchErrs...)
+
+	return toTargets(ctx, targets, DatasetFxReconRates, p.String(), func(ctx context.Context, db *pgxpool.Pool) error {
+		if err := ApplyFxReconRates(ctx, db, sourceRates, p, infoLog); err != nil {
+			return errors.Join(err, fetchErr)
+		}
+		return fetchErr
+	})
+}
+
+// ApplyFxReconRates inserts or updates the already fetched rates of each source, keyed by source name, in db, then replaces the divergences in the date range with those of the stored rates
+// rates missing from sourceRates are not deleted, so that the divergences of a source which failed are computed with its rates stored earlier
+func ApplyFxReconRates(ctx context.Context, db *pgxpool.Pool, sourceRates map[string][]ratesource.Rate, p FxReconParams, infoLog *slog.Logger) error {
+
+	rateStore := fxrrate.Store{Db: db}
+	divStore := fxrdivergence.Store{Db: db}
+	unlock, err := lockStore(rateStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	dbItemsMap, err := rateStore.SelectMapByNaturalKey(ctx, p.StartDate, p.EndDate)
+	if err != nil {
+		return fmt.Errorf("rateStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	newItems := []fxrrate.Input{}
+	updated := 0
+	for source, rates := range sourceRates {
+		for _, r := range rates {
+
+			apiItem := fxrrate.Model{Input: fxrrate.Input{
+				BaseCurrency:  r.Base,
+				Day:           lystype.Date(r.Day),
+				QuoteCurrency: r.Quote,
+				Rate:          r.Rate,
+				Source:        source,
+			}}
+			key := fxrrate.NaturalKey(source, r.Base, r.Quote, r.Day)
+
+			dbItem, ok := dbItemsMap[key]
+			if !ok {
+				newItems = append(newItems, apiItem.Input)
+				dbItemsMap[key] = apiItem
+				continue
+			}
+			if !rateStore.Equal(apiItem, dbItem) {
+				if err = rateStore.Update(ctx, apiItem.Input, dbItem.Id); err != nil {
+					return fmt.Errorf("rateStore.Update failed on ID: %v: %w", dbItem.Id, err)
+				}
+				apiItem.Id = dbItem.Id
+				dbItemsMap[key] = apiItem
+				updated++
+			}
+		}
+	}
+	if len(newItems) > 0 {
+		if _, err = rateStore.BulkInsert(ctx, newItems); err != nil {
+			return fmt.Errorf("rateStore.BulkInsert failed: %w", err)
+		}
+	}
+
+	// compare the stored rates of the pair, as updated above
+	primary := []ratesource.Rate{}
+	others := make(map[string][]ratesource.Rate)
+	for _, item := range dbItemsMap {
+		if item.BaseCurrency != p.Base {
+			continue
+		}
+		r := ratesource.Rate{Day: time.Time(item.Day), Base: item.BaseCurrency, Quote: item.QuoteCurrency, Rate: item.Rate}
+		if item.Source == p.Primary {
+			primary = append(primary, r)
+		} else {
+			others[item.Source] = append(others[item.Source], r)
+		}
+	}
+	divergences := ratesource.Compare(primary, others, p.TolerancePct)
+
+	if _, err = divStore.DeleteInRange(ctx, p.StartDate, p.EndDate); err != nil {
+		return fmt.Errorf("divStore.DeleteInRange failed: %w", err)
+	}
+
+	maxDiffPct := 0.0
+	if len(divergences) > 0 {
+		divInputs := make([]fxrdivergence.Input, len(divergences))
+		for i, d := range divergences {
+			divInputs[i] = fxrdivergence.Input{
+				BaseCurrency:  d.Base,
+				Day:           lystype.Date(d.Day),
+				DiffPct:       d.DiffPct,
+				PrimaryRate:   d.PrimaryRate,
+				PrimarySource: p.Primary,
+				QuoteCurrency: d.Quote,
+				Rate:          d.Rate,
+				Source:        d.Source,
+			}
+			maxDiffPct = math.Max(maxDiffPct, math.Abs(d.DiffPct))
+		}
+		if _, err = divStore.BulkInsert(ctx, divInputs); err != nil {
+			return fmt.Errorf("divStore.BulkInsert failed: %w", err)
+		}
+
+		infoLog.Warn("exchange rates diverge from the primary source: see fxrecon.divergence", slog.String(clog.KeyDataset, DatasetFxReconRates),
+			slog.String("primary", p.Primary), slog.Int(clog.KeyCount, len(divergences)), slog.Float64("max_diff_pct", maxDiffPct))
+	}
+
+	infoLog.Info("synced reconciled exchange rates", slog.String(clog.KeyDataset, DatasetFxReconRates),
+		slog.Int("inserted", len(newItems)), slog.Int("updated", updated), slog.Int("divergences", len(divergences)))
+
+	return nil
+}
@@ -0,0 +1,233 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/gleifapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/gleif/gleifdelta"
+	"github.com/loveyourstack/connectors/stores/gleif/gleifentity"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// deltaTypeApi is recorded in gleif.delta_publish when the records were fetched from the API instead of a delta file
+const deltaTypeApi string = "Api"
+
+// GleifEntitiesToTargets syncs the entities with leis into each target, recording a journal entry in each
+// each target is updated from the smallest golden copy delta file covering the time since its last applied publication. Targets never synced, or not for over a month, are loaded from the API instead, as are LEIs not yet in a target
+// delta files and API records are fetched at most once, however many targets need them
+func GleifEntitiesToTargets(ctx context.Context, targets []Target, c gleifapi.Client, leis []string) error {
+
+	publish, fetchErr := c.GetLatestPublish(ctx)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetLatestPublish failed: %w", fetchErr)
+	}
+
+	src := newGleifSource(c, publish, leis)
+
+	params := GleifEntitiesParams(publish.PublishAt, leis)
+	return toTargets(ctx, targets, DatasetGleifEntities, params, func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return syncGleifEntities(ctx, db, src)
+	})
+}
+
+// GleifEntitiesParams returns the journal params of a GleifEntities run
+func GleifEntitiesParams(publishAt time.Time, leis []string) string {
+	return fmt.Sprintf("publish=%s leis=%d", publishAt.UTC().Format(time.RFC3339), len(leis))
+}
+
+// syncGleifEntities determines the records db needs from src and applies them
+func syncGleifEntities(ctx context.Context, db *pgxpool.Pool, src *gleifSource) error {
+
+	lastApplied, err := gleifdelta.Store{Db: db}.SelectLatestPublishAt(ctx)
+	if err != nil {
+		return fmt.Errorf("gleifdelta.Store.SelectLatestPublishAt failed: %w", err)
+	}
+
+	dbItemsMap, err := gleifentity.Store{Db: db}.SelectMapByNaturalKey(ctx, src.leis)
+	if err != nil {
+		return fmt.Errorf("gleifentity.Store.SelectMapByNaturalKey failed: %w", err)
+	}
+	missing := []string{}
+	for _, lei := range src.leis {
+		if _, ok := dbItemsMap[lei]; !ok {
+			missing = append(missing, lei)
+		}
+	}
+
+	records := []gleifapi.Record{}
+	deltaType := ""
+
+	switch dt, ok := gleifapi.ChooseDeltaType(lastApplied, src.publish.PublishAt); {
+	case !lastApplied.Before(src.publish.PublishAt):
+		// already up to date with the latest publication: only load newly watched LEIs
+		if records, err = src.apiRecords(ctx, missing); err != nil {
+			return fmt.Errorf("src.apiRecords failed: %w", err)
+		}
+	case !ok:
+		deltaType = deltaTypeApi
+		if records, err = src.apiRecords(ctx, src.leis); err != nil {
+			return fmt.Errorf("src.apiRecords failed: %w", err)
+		}
+	default:
+		deltaType = string(dt)
+		if records, err = src.deltaRecords(ctx, dt); err != nil {
+			return fmt.Errorf("src.deltaRecords failed: %w", err)
+		}
+		apiRecords, err := src.apiRecords(ctx, missing)
+		if err != nil {
+			return fmt.Errorf("src.apiRecords failed: %w", err)
+		}
+		records = append(records, apiRecords...)
+	}
+
+	return ApplyGleifEntities(ctx, db, src.c, gleifapi.RecordsToMap(records), src.publish.PublishAt, deltaType)
+}
+
+// ApplyGleifEntities inserts or updates the entities of db with already fetched records (map with Lei as key). c is only used for logging
+// if deltaType is not empty, publishAt is recorded as applied, so that the next sync continues from it
+// entities are never deleted: lapsed and retired LEIs keep their last record, with the registration status showing it
+func ApplyGleifEntities(ctx context.Context, db *pgxpool.Pool, c gleifapi.Client, apiItemsMap map[string]gleifentity.Model, publishAt time.Time, deltaType string) error {
+
+	itemStore := gleifentity.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	leis := make([]string, 0, len(apiItemsMap))
+	for lei := range apiItemsMap {
+		leis = append(leis, lei)
+	}
+
+	dbItemsMap := map[string]gleifentity.Model{}
+	if len(leis) > 0 {
+		var err error
+		dbItemsMap, err = itemStore.SelectMapByNaturalKey(ctx, leis)
+		if err != nil {
+			return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+		}
+	}
+
+	inserted, updated := 0, 0
+	for key, apiItem := range apiItemsMap {
+
+		dbItem, ok := dbItemsMap[key]
+		if !ok {
+			if _, err := itemStore.Insert(ctx, apiItem.Input); err != nil {
+				return fmt.Errorf("itemStore.Insert failed on LEI: %v: %w", key, err)
+			}
+			inserted++
+			continue
+		}
+
+		if !itemStore.Equal(apiItem, dbItem) {
+			if err := itemStore.Update(ctx, apiItem.Input, dbItem.Id); err != nil {
+				return fmt.Errorf("itemStore.Update failed on LEI: %v: %w", key, err)
+			}
+			updated++
+		}
+	}
+
+	if deltaType != "" {
+		_, err := gleifdelta.Store{Db: db}.Insert(ctx, gleifdelta.Input{PublishAt: lystype.Datetime(publishAt), DeltaType: deltaType, RecordCount: len(apiItemsMap)})
+		if err != nil {
+			return fmt.Errorf("gleifdelta.Store.Insert failed: %w", err)
+		}
+	}
+
+	c.InfoLog.Info("synced entities", slog.String(clog.KeyDataset, DatasetGleifEntities), slog.String("delta_type", deltaType),
+		slog.Int("inserted", inserted), slog.Int("updated", updated))
+
+	return nil
+}
+
+// gleifSource fetches delta files and API records on first use and caches them for the other targets
+// it is only used by the sequential toTargets, so it needs no locking
+type gleifSource struct {
+	c       gleifapi.Client
+	publish gleifapi.Publish
+	leis    []string
+	watched map[string]bool
+
+	deltas  map[gleifapi.DeltaType][]gleifapi.Record
+	records map[string]gleifapi.Record // API records by LEI
+	fetched map[string]bool            // LEIs requested from the API, including unknown ones
+}
+
+func newGleifSource(c gleifapi.Client, publish gleifapi.Publish, leis []string) *gleifSource {
+
+	watched := make(map[string]bool)
+	for _, lei := range leis {
+		watched[lei] = true
+	}
+
+	return &gleifSource{
+		c:       c,
+		publish: publish,
+		leis:    leis,
+		watched: watched,
+		deltas:  make(map[gleifapi.DeltaType][]gleifapi.Record),
+		records: make(map[string]gleifapi.Record),
+		fetched: make(map[string]bool),
+	}
+}
+
+// deltaRecords returns the records of the watched LEIs in the delta file of type dt
+func (s *gleifSource) deltaRecords(ctx context.Context, dt gleifapi.DeltaType) ([]gleifapi.Record, error) {
+
+	if records, ok := s.deltas[dt]; ok {
+		return records, nil
+	}
+
+	records, err := s.c.GetDeltaRecords(ctx, s.publish, dt, func(lei string) bool { return s.watched[lei] })
+	if err != nil {
+		return nil, fmt.Errorf("s.c.GetDeltaRecords failed: %w", err)
+	}
+	s.deltas[dt] = records
+
+	return records, nil
+}
+
+// apiRecords returns the API records of leis, only requesting those not requested before
+func (s *gleifSource) apiRecords(ctx context.Context, leis []string) ([]gleifapi.Record, error) {
+
+	toFetch := []string{}
+	for _, lei := range leis {
+		if !s.fetched[lei] {
+			toFetch = append(toFetch, lei)
+		}
+	}
+
+	if len(toFetch) > 0 {
+		records, err := s.c.GetApiRecords(ctx, toFetch)
+		if err != nil {
+			return nil, fmt.Errorf("s.c.GetApiRecords failed: %w", err)
+		}
+		for _, rec := range records {
+			s.records[rec.Lei] = rec
+		}
+		for _, lei := range toFetch {
+			s.fetched[lei] = true
+		}
+	}
+
+	records := []gleifapi.Record{}
+	for _, lei := range leis {
+		if rec, ok := s.records[lei]; ok {
+			records = append(records, rec)
+		} else {
+			s.c.InfoLog.Warn("LEI not found", slog.String(clog.KeyDataset, DatasetGleifEntities), slog.String(clog.KeyCode, lei))
+		}
+	}
+
+	return records, nil
+}
@@ -0,0 +1,93 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/nagerapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/holiday/publicholiday"
+)
+
+// PublicHolidaysToTargets fetches the public holidays of countryCode in year once and syncs them into each target, recording a journal entry in each
+func PublicHolidaysToTargets(ctx context.Context, targets []Target, c nagerapi.Client, year int, countryCode string) error {
+
+	apiItems, fetchErr := c.GetApiPublicHolidays(year, countryCode)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiPublicHolidays failed: %w", fetchErr)
+	}
+
+	params := PublicHolidaysParams(year, countryCode)
+	return toTargets(ctx, targets, DatasetPublicHolidays, params, func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyPublicHolidays(ctx, db, c, apiItems, year, countryCode)
+	})
+}
+
+// PublicHolidaysParams returns the journal params of a PublicHolidays run
+func PublicHolidaysParams(year int, countryCode string) string {
+	return fmt.Sprintf("country=%s year=%d", countryCode, year)
+}
+
+// ApplyPublicHolidays syncs the holidays of countryCode in year in db with already fetched API holidays. c is only used for logging
+func ApplyPublicHolidays(ctx context.Context, db *pgxpool.Pool, c nagerapi.Client, apiItems []nagerapi.PublicHoliday, year int, countryCode string) error {
+
+	itemStore := publicholiday.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	apiItemsMap, err := nagerapi.PublicHolidaysToMap(apiItems)
+	if err != nil {
+		return fmt.Errorf("nagerapi.PublicHolidaysToMap failed: %w", err)
+	}
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx, countryCode, year)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	newItems := []publicholiday.Input{}
+	updatedItems := make(map[int64]publicholiday.Input)
+	deletedIds := []int64{}
+
+	for key, apiItem := range apiItemsMap {
+		dbItem, ok := dbItemsMap[key]
+		if !ok {
+			newItems = append(newItems, apiItem.Input)
+			continue
+		}
+		if !itemStore.Equal(apiItem, dbItem) {
+			updatedItems[dbItem.Id] = apiItem.Input
+		}
+	}
+	for key, dbItem := range dbItemsMap {
+		if _, ok := apiItemsMap[key]; !ok {
+			deletedIds = append(deletedIds, dbItem.Id)
+		}
+	}
+
+	if err = itemStore.BulkDelete(ctx, deletedIds); err != nil {
+		return fmt.Errorf("itemStore.BulkDelete failed: %w", err)
+	}
+	if len(newItems) > 0 {
+		if _, err = itemStore.BulkInsert(ctx, newItems); err != nil {
+			return fmt.Errorf("itemStore.BulkInsert failed: %w", err)
+		}
+	}
+	inputs, ids := splitUpdates(updatedItems)
+	if err = itemStore.BulkUpdate(ctx, inputs, ids); err != nil {
+		return fmt.Errorf("itemStore.BulkUpdate failed: %w", err)
+	}
+
+	c.InfoLog.Info("synced public holidays", slog.String(clog.KeyDataset, DatasetPublicHolidays), slog.String(clog.KeyCode, countryCode), slog.Int("year", year),
+		slog.Int("inserted", len(newItems)), slog.Int("updated", len(updatedItems)), slog.Int("deleted", len(deletedIds)))
+
+	return nil
+}
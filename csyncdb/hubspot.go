@@ -0,0 +1,255 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/hubspotapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/hubspot/hubcompany"
+	"github.com/loveyourstack/connectors/stores/hubspot/hubcontact"
+	"github.com/loveyourstack/connectors/stores/hubspot/hubdeal"
+)
+
+// HubspotParams returns the journal params of a HubSpot run: the keys of the mapped properties
+func HubspotParams(propMap hubspotapi.PropertyMap) string {
+
+	keys := make([]string, 0, len(propMap))
+	for key := range propMap {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return "properties=" + strings.Join(keys, ",")
+}
+
+// HubspotCompaniesToTargets fetches all companies with the properties of propMap once and syncs them into each target, recording a journal entry in each
+func HubspotCompaniesToTargets(ctx context.Context, targets []Target, c hubspotapi.Client, propMap hubspotapi.PropertyMap) error {
+
+	objects, fetchErr := c.GetApiCompanies(ctx, propMap)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiCompanies failed: %w", fetchErr)
+	}
+
+	return toTargets(ctx, targets, DatasetHubspotCompanies, HubspotParams(propMap), func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyHubspotCompanies(ctx, db, c, objects, propMap)
+	})
+}
+
+// ApplyHubspotCompanies syncs the companies of db with already fetched API companies. c is only used for logging
+// the API companies are complete, so companies missing from them, e.g. because they were archived, are deleted
+func ApplyHubspotCompanies(ctx context.Context, db *pgxpool.Pool, c hubspotapi.Client, objects []hubspotapi.Object, propMap hubspotapi.PropertyMap) error {
+
+	itemStore := hubcompany.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	apiItemsMap, err := hubspotapi.CompaniesToMap(objects, propMap)
+	if err != nil {
+		return fmt.Errorf("hubspotapi.CompaniesToMap failed: %w", err)
+	}
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	newItems := []hubcompany.Input{}
+	updatedItems := make(map[int64]hubcompany.Input)
+	deletedIds := []int64{}
+
+	for key, apiItem := range apiItemsMap {
+		dbItem, ok := dbItemsMap[key]
+		if !ok {
+			newItems = append(newItems, apiItem.Input)
+			continue
+		}
+		if !itemStore.Equal(apiItem, dbItem) {
+			updatedItems[dbItem.Id] = apiItem.Input
+		}
+	}
+	for key, dbItem := range dbItemsMap {
+		if _, ok := apiItemsMap[key]; !ok {
+			deletedIds = append(deletedIds, dbItem.Id)
+		}
+	}
+
+	if err = itemStore.BulkDelete(ctx, deletedIds); err != nil {
+		return fmt.Errorf("itemStore.BulkDelete failed: %w", err)
+	}
+	if len(newItems) > 0 {
+		if _, err = itemStore.BulkInsert(ctx, newItems); err != nil {
+			return fmt.Errorf("itemStore.BulkInsert failed: %w", err)
+		}
+	}
+	inputs, ids := splitUpdates(updatedItems)
+	if err = itemStore.BulkUpdate(ctx, inputs, ids); err != nil {
+		return fmt.Errorf("itemStore.BulkUpdate failed: %w", err)
+	}
+
+	c.InfoLog.Info("synced companies", slog.String(clog.KeyDataset, DatasetHubspotCompanies),
+		slog.Int("inserted", len(newItems)), slog.Int("updated", len(updatedItems)), slog.Int("deleted", len(deletedIds)))
+
+	return nil
+}
+
+// HubspotContactsToTargets fetches all contacts with the properties of propMap once and syncs them into each target, recording a journal entry in each
+func HubspotContactsToTargets(ctx context.Context, targets []Target, c hubspotapi.Client, propMap hubspotapi.PropertyMap) error {
+
+	objects, fetchErr := c.GetApiContacts(ctx, propMap)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiContacts failed: %w", fetchErr)
+	}
+
+	return toTargets(ctx, targets, DatasetHubspotContacts, HubspotParams(propMap), func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyHubspotContacts(ctx, db, c, objects, propMap)
+	})
+}
+
+// ApplyHubspotContacts syncs the contacts of db with already fetched API contacts. c is only used for logging
+// the API contacts are complete, so contacts missing from them, e.g. because they were archived, are deleted
+func ApplyHubspotContacts(ctx context.Context, db *pgxpool.Pool, c hubspotapi.Client, objects []hubspotapi.Object, propMap hubspotapi.PropertyMap) error {
+
+	itemStore := hubcontact.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	apiItemsMap, err := hubspotapi.ContactsToMap(objects, propMap)
+	if err != nil {
+		return fmt.Errorf("hubspotapi.ContactsToMap failed: %w", err)
+	}
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	newItems := []hubcontact.Input{}
+	updatedItems := make(map[int64]hubcontact.Input)
+	deletedIds := []int64{}
+
+	for key, apiItem := range apiItemsMap {
+		dbItem, ok := dbItemsMap[key]
+		if !ok {
+			newItems = append(newItems, apiItem.Input)
+			continue
+		}
+		if !itemStore.Equal(apiItem, dbItem) {
+			updatedItems[dbItem.Id] = apiItem.Input
+		}
+	}
+	for key, dbItem := range dbItemsMap {
+		if _, ok := apiItemsMap[key]; !ok {
+			deletedIds = append(deletedIds, dbItem.Id)
+		}
+	}
+
+	if err = itemStore.BulkDelete(ctx, deletedIds); err != nil {
+		return fmt.Errorf("itemStore.BulkDelete failed: %w", err)
+	}
+	if len(newItems) > 0 {
+		if _, err = itemStore.BulkInsert(ctx, newItems); err != nil {
+			return fmt.Errorf("itemStore.BulkInsert failed: %w", err)
+		}
+	}
+	inputs, ids := splitUpdates(updatedItems)
+	if err = itemStore.BulkUpdate(ctx, inputs, ids); err != nil {
+		return fmt.Errorf("itemStore.BulkUpdate failed: %w", err)
+	}
+
+	c.InfoLog.Info("synced contacts", slog.String(clog.KeyDataset, DatasetHubspotContacts),
+		slog.Int("inserted", len(newItems)), slog.Int("updated", len(updatedItems)), slog.Int("deleted", len(deletedIds)))
+
+	return nil
+}
+
+// HubspotDealsToTargets fetches all deals with the properties of propMap once and syncs them into each target, recording a journal entry in each
+func HubspotDealsToTargets(ctx context.Context, targets []Target, c hubspotapi.Client, propMap hubspotapi.PropertyMap) error {
+
+	objects, fetchErr := c.GetApiDeals(ctx, propMap)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiDeals failed: %w", fetchErr)
+	}
+
+	return toTargets(ctx, targets, DatasetHubspotDeals, HubspotParams(propMap), func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyHubspotDeals(ctx, db, c, objects, propMap)
+	})
+}
+
+// ApplyHubspotDeals syncs the deals of db with already fetched API deals. c is only used for logging
+// the API deals are complete, so deals missing from them, e.g. because they were archived, are deleted
+func ApplyHubspotDeals(ctx context.Context, db *pgxpool.Pool, c hubspotapi.Client, objects []hubspotapi.Object, propMap hubspotapi.PropertyMap) error {
+
+	itemStore := hubdeal.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	apiItemsMap, err := hubspotapi.DealsToMap(objects, propMap)
+	if err != nil {
+		return fmt.Errorf("hubspotapi.DealsToMap failed: %w", err)
+	}
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	newItems := []hubdeal.Input{}
+	updatedItems := make(map[int64]hubdeal.Input)
+	deletedIds := []int64{}
+
+	for key, apiItem := range apiItemsMap {
+		dbItem, ok := dbItemsMap[key]
+		if !ok {
+			newItems = append(newItems, apiItem.Input)
+			continue
+		}
+		if !itemStore.Equal(apiItem, dbItem) {
+			updatedItems[dbItem.Id] = apiItem.Input
+		}
+	}
+	for key, dbItem := range dbItemsMap {
+		if _, ok := apiItemsMap[key]; !ok {
+			deletedIds = append(deletedIds, dbItem.Id)
+		}
+	}
+
+	if err = itemStore.BulkDelete(ctx, deletedIds); err != nil {
+		return fmt.Errorf("itemStore.BulkDelete failed: %w", err)
+	}
+	if len(newItems) > 0 {
+		if _, err = itemStore.BulkInsert(ctx, newItems); err != nil {
+			return fmt.Errorf("itemStore.BulkInsert failed: %w", err)
+		}
+	}
+	inputs, ids := splitUpdates(updatedItems)
+	if err = itemStore.BulkUpdate(ctx, inputs, ids); err != nil {
+		return fmt.Errorf("itemStore.BulkUpdate failed: %w", err)
+	}
+
+	c.InfoLog.Info("synced deals", slog.String(clog.KeyDataset, DatasetHubspotDeals),
+		slog.Int("inserted", len(newItems)), slog.Int("updated", len(updatedItems)), slog.Int("deleted", len(deletedIds)))
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package csyncdb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/imapapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/filedrop/fdmessage"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// ImapAttachmentsToTargets reads the attachments matching rules of the messages received in the last days days, which are not yet loaded in every target, once, and ingests them into the file drop records of each target, recording a journal entry in each
+func ImapAttachmentsToTargets(ctx context.Context, targets []Target, c imapapi.Client, rules []imapapi.Rule, days int) error {
+
+	since := time.Now().AddDate(0, 0, -days)
+	loaded := imapLoadedMessages(ctx, targets, c)
+
+	files, fetchErr := getImapAttachments(c, rules, since, loaded)
+
+	return toTargets(ctx, targets, DatasetImapAttachments, ImapAttachmentsParams(c.Mailbox, since), func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyFiledropRecords(ctx, db, files, DatasetImapAttachments, c.InfoLog)
+	})
+}
+
+// ImapAttachmentsParams returns the journal params of an ImapAttachments run
+func ImapAttachmentsParams(mailbox string, since time.Time) string {
+	return "mailbox=" + mailbox + "&since=" + since.Format(lystype.DateFormat)
+}
+
+// imapLoadedMessages returns the ids of the messages of the mailbox whose attachments are loaded in every target
+// targets whose messages can't be read are skipped: their sync fails when applied
+func imapLoadedMessages(ctx context.Context, targets []Target, c imapapi.Client) (loaded map[string]bool) {
+
+	for _, t := range targets {
+		ids, err := fdmessage.Store{Db: t.Db}.SelectMessageIds(ctx, c.Mailbox)
+		if err != nil {
+			c.ErrorLog.Error("fdmessage.Store.SelectMessageIds failed", slog.String(clog.KeyDataset, DatasetImapAttachments), slog.String("target", t.Name), slog.String(clog.KeyError, err.Error()))
+			continue
+		}
+		if loaded == nil {
+			loaded = ids
+			continue
+		}
+		for id := range loaded {
+			if !ids[id] {
+				delete(loaded, id)
+			}
+		}
+	}
+
+	return loaded
+}
+
+// getImapAttachments returns the attachments matching rules of the messages since, skipping the loaded messages, as files of the rules' feeds
+// messages which match a rule but have no matching attachment are read again by each run while they are within the window
+func getImapAttachments(c imapapi.Client, rules []imapapi.Rule, since time.Time, loaded map[string]bool) (files []FiledropFile, err error) {
+
+	want := func(m imapapi.Message) bool {
+		if loaded[imapMessageId(m)] {
+			return false
+		}
+		for _, r := range rules {
+			if r.MatchesMessage(m) {
+				return true
+			}
+		}
+		return false
+	}
+
+	messages, err := c.GetMessages(since, want)
+	if err != nil {
+		return nil, fmt.Errorf("c.GetMessages failed: %w", err)
+	}
+
+	for _, m := range messages {
+		if m.Raw == nil {
+			continue
+		}
+
+		attachments, err := imapapi.ParseAttachments(m.Raw)
+		if err != nil {
+			// retried while within the window, but does not block the other messages
+			c.ErrorLog.Warn("message could not be parsed", slog.String(clog.KeyDataset, DatasetImapAttachments), slog.String("message_id", m.MessageId), slog.String(clog.KeyError, err.Error()))
+			continue
+		}
+
+		files = append(files, imapMessageFiles(c.Mailbox, m, attachments, rules)...)
+	}
+
+	return files, nil
+}
+
+// imapMessageFiles returns the attachments of m matching rules as files, each parsed with the first matching rule
+func imapMessageFiles(mailbox string, m imapapi.Message, attachments []imapapi.Attachment, rules []imapapi.Rule) (files []FiledropFile) {
+
+	messageId := imapMessageId(m)
+	sentAt := &m.Date
+	lastModifiedAt := m.Date
+	if m.Date.IsZero() {
+		sentAt = nil
+		lastModifiedAt = time.Now()
+	}
+
+	seen := make(map[string]int)
+	for _, a := range attachments {
+		for _, r := range rules {
+			if !r.MatchesMessage(m) || !r.MatchesFile(a.Filename) {
+				continue
+			}
+
+			// attachments with the same name in a message get a suffix
+			key := messageId + "/" + a.Filename
+			seen[key]++
+			if seen[key] > 1 {
+				key += "#" + strconv.Itoa(seen[key])
+			}
+
+			sum := sha256.Sum256(a.Content)
+			file := FiledropFile{
+				Feed:           r.Feed,
+				Key:            key,
+				Etag:           hex.EncodeToString(sum[:]),
+				Size:           int64(len(a.Content)),
+				LastModifiedAt: lastModifiedAt,
+				Message: &fdmessage.Input{
+					Mailbox:     mailbox,
+					MessageId:   messageId,
+					Sender:      m.From,
+					SentAt:      sentAt,
+					Subject:     m.Subject,
+					Uid:         m.Uid,
+					UidValidity: m.UidValidity,
+				},
+			}
+
+			// a file which can't be parsed is kept with its ParseError
+			_ = file.parse(r.FileFeed(a.Filename), a.Content)
+
+			files = append(files, file)
+			break
+		}
+	}
+
+	return files
+}
+
+// imapMessageId returns the Message-ID of m, or an id made of its UID for messages without one
+func imapMessageId(m imapapi.Message) string {
+
+	if m.MessageId != "" {
+		return m.MessageId
+	}
+	return fmt.Sprintf("%d.%d@imap", m.UidValidity, m.Uid)
+}
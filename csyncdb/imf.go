@@ -0,0 +1,94 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/imfapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/imf/imfexchangerate"
+)
+
+// ImfExchangeRatesToTargets fetches the IMF rates of rateType in the date range once and syncs them into each target, recording a journal entry in each
+func ImfExchangeRatesToTargets(ctx context.Context, targets []Target, c imfapi.Client, rateType imfapi.RateType, startDate, endDate time.Time) error {
+
+	apiItems, fetchErr := c.GetApiExchangeRates(rateType, startDate, endDate)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiExchangeRates failed: %w", fetchErr)
+	}
+
+	params := ImfExchangeRatesParams(rateType, startDate, endDate)
+	return toTargets(ctx, targets, DatasetImfExchangeRates, params, func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyImfExchangeRates(ctx, db, c, apiItems, rateType, startDate, endDate)
+	})
+}
+
+// ImfExchangeRatesParams returns the journal params of an ImfExchangeRates run
+func ImfExchangeRatesParams(rateType imfapi.RateType, startDate, endDate time.Time) string {
+	return fmt.Sprintf("type=%s from=%s to=%s", rateType, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+}
+
+// ApplyImfExchangeRates syncs the rates of rateType in db in the date range with already fetched API rates. c is only used for logging
+func ApplyImfExchangeRates(ctx context.Context, db *pgxpool.Pool, c imfapi.Client, apiItems []imfapi.ExchangeRate, rateType imfapi.RateType, startDate, endDate time.Time) error {
+
+	itemStore := imfexchangerate.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	apiItemsMap, err := imfapi.ExchangeRatesToMap(apiItems)
+	if err != nil {
+		return fmt.Errorf("imfapi.ExchangeRatesToMap failed: %w", err)
+	}
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx, rateType.String(), startDate, endDate)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	newItems := []imfexchangerate.Input{}
+	updatedItems := make(map[int64]imfexchangerate.Input)
+	deletedIds := []int64{}
+
+	for key, apiItem := range apiItemsMap {
+		dbItem, ok := dbItemsMap[key]
+		if !ok {
+			newItems = append(newItems, apiItem.Input)
+			continue
+		}
+		if !itemStore.Equal(apiItem, dbItem) {
+			updatedItems[dbItem.Id] = apiItem.Input
+		}
+	}
+	for key, dbItem := range dbItemsMap {
+		if _, ok := apiItemsMap[key]; !ok {
+			deletedIds = append(deletedIds, dbItem.Id)
+		}
+	}
+
+	if err = itemStore.BulkDelete(ctx, deletedIds); err != nil {
+		return fmt.Errorf("itemStore.BulkDelete failed: %w", err)
+	}
+	if len(newItems) > 0 {
+		if _, err = itemStore.BulkInsert(ctx, newItems); err != nil {
+			return fmt.Errorf("itemStore.BulkInsert failed: %w", err)
+		}
+	}
+	inputs, ids := splitUpdates(updatedItems)
+	if err = itemStore.BulkUpdate(ctx, inputs, ids); err != nil {
+		return fmt.Errorf("itemStore.BulkUpdate failed: %w", err)
+	}
+
+	c.InfoLog.Info("synced exchange rates", slog.String(clog.KeyDataset, DatasetImfExchangeRates), slog.String("rate_type", rateType.String()),
+		slog.Int("inserted", len(newItems)), slog.Int("updated", len(updatedItems)), slog.Int("deleted", len(deletedIds)))
+
+	return nil
+}
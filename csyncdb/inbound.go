@@ -0,0 +1,144 @@
+package csyncdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/inbound/inbounddelivery"
+	"github.com/loveyourstack/connectors/stores/inbound/inboundevent"
+	"github.com/loveyourstack/connectors/webhookin"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// inboundBatchSize is the number of staged deliveries normalized per query
+const inboundBatchSize int = 500
+
+// StageInboundDelivery persists a verified webhook delivery in the staging table of each target, ignoring deliveries already staged
+func StageInboundDelivery(ctx context.Context, targets []Target, d webhookin.Delivery) error {
+
+	input := inbounddelivery.Input{
+		Provider:   d.Provider,
+		DeliveryId: d.Id,
+		Headers:    d.Headers,
+		Payload:    d.Payload,
+		ReceivedAt: d.ReceivedAt,
+	}
+
+	var errs []error
+	for _, t := range targets {
+		if _, err := (inbounddelivery.Store{Db: t.Db}).Stage(ctx, input); err != nil {
+			errs = append(errs, TargetError{Target: t.Name, Err: fmt.Errorf("inbounddelivery.Store.Stage failed: %w", err)})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// InboundEventsToTargets normalizes the staged webhook deliveries of each target into its events, recording a journal entry in each
+// processed deliveries are removed from staging after retention
+func InboundEventsToTargets(ctx context.Context, targets []Target, providers []webhookin.Provider, retention time.Duration, infoLog *slog.Logger) error {
+
+	return toTargets(ctx, targets, DatasetInboundEvents, "", func(ctx context.Context, db *pgxpool.Pool) error {
+		return ApplyInboundEvents(ctx, db, providers, retention, infoLog)
+	})
+}
+
+// ApplyInboundEvents normalizes the pending deliveries of db with their provider, inserts their events and marks them processed
+// a delivery which can't be normalized, e.g. because its provider is no longer configured, keeps its error and stays pending, so that it is retried by the next run
+func ApplyInboundEvents(ctx context.Context, db *pgxpool.Pool, providers []webhookin.Provider, retention time.Duration, infoLog *slog.Logger) error {
+
+	deliveryStore := inbounddelivery.Store{Db: db}
+	eventStore := inboundevent.Store{Db: db}
+	unlock, err := lockStore(deliveryStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	providerMap := make(map[string]webhookin.Provider, len(providers))
+	for _, p := range providers {
+		providerMap[p.Name] = p
+	}
+
+	inserted, duplicates, failed := 0, 0, 0
+
+	for afterId := int64(0); ; {
+
+		deliveries, err := deliveryStore.SelectPending(ctx, afterId, inboundBatchSize)
+		if err != nil {
+			return fmt.Errorf("deliveryStore.SelectPending failed: %w", err)
+		}
+		if len(deliveries) == 0 {
+			break
+		}
+
+		for _, d := range deliveries {
+			afterId = d.Id
+
+			input, err := inboundEventInput(providerMap, d)
+			if err != nil {
+				if err = deliveryStore.SetError(ctx, d.Id, err.Error()); err != nil {
+					return fmt.Errorf("deliveryStore.SetError failed on ID: %v: %w", d.Id, err)
+				}
+				failed++
+				continue
+			}
+
+			isNew, err := eventStore.InsertIfNew(ctx, input)
+			if err != nil {
+				return fmt.Errorf("eventStore.InsertIfNew failed on delivery ID: %v: %w", d.Id, err)
+			}
+			if isNew {
+				inserted++
+			} else {
+				duplicates++
+			}
+
+			if err = deliveryStore.SetProcessed(ctx, d.Id); err != nil {
+				return fmt.Errorf("deliveryStore.SetProcessed failed on ID: %v: %w", d.Id, err)
+			}
+		}
+	}
+
+	removed, err := deliveryStore.DeleteProcessedBefore(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return fmt.Errorf("deliveryStore.DeleteProcessedBefore failed: %w", err)
+	}
+
+	if failed > 0 {
+		infoLog.Warn("webhook deliveries could not be normalized: see inbound.delivery.error", slog.String(clog.KeyDataset, DatasetInboundEvents), slog.Int(clog.KeyCount, failed))
+	}
+	infoLog.Info("synced inbound events", slog.String(clog.KeyDataset, DatasetInboundEvents),
+		slog.Int("inserted", inserted), slog.Int("duplicates", duplicates), slog.Int("failed", failed), slog.Int64("deliveries_removed", removed))
+
+	return nil
+}
+
+// inboundEventInput normalizes delivery d with its provider in providerMap
+func inboundEventInput(providerMap map[string]webhookin.Provider, d inbounddelivery.Model) (input inboundevent.Input, err error) {
+
+	p, ok := providerMap[d.Provider]
+	if !ok {
+		return inboundevent.Input{}, fmt.Errorf("provider %s is not configured", d.Provider)
+	}
+
+	ev, err := p.Normalize(d.Headers, d.Payload, d.ReceivedAt)
+	if err != nil {
+		return inboundevent.Input{}, fmt.Errorf("p.Normalize failed: %w", err)
+	}
+
+	deliveryFk := d.Id
+	return inboundevent.Input{
+		DeliveryFk: &deliveryFk,
+		EventId:    ev.Id,
+		EventType:  ev.Type,
+		OccurredAt: lystype.Datetime(ev.OccurredAt),
+		Payload:    d.Payload,
+		Provider:   d.Provider,
+	}, nil
+}
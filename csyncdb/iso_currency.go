@@ -0,0 +1,75 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/iso4217api"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/iso4217/isocurrency"
+)
+
+// Iso4217CurrenciesToTargets fetches the ISO 4217 currencies once and syncs them into each target, recording a journal entry in each
+func Iso4217CurrenciesToTargets(ctx context.Context, targets []Target, c iso4217api.Client) error {
+
+	apiItems, fetchErr := c.GetApiCurrencies()
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiCurrencies failed: %w", fetchErr)
+	}
+
+	return toTargets(ctx, targets, DatasetIso4217Currencies, "", func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyIso4217Currencies(ctx, db, c, iso4217api.CurrenciesToMap(apiItems))
+	})
+}
+
+// ApplyIso4217Currencies syncs the ISO 4217 currencies of db with already fetched API currencies (map with Code as key). c is only used for logging
+func ApplyIso4217Currencies(ctx context.Context, db *pgxpool.Pool, c iso4217api.Client, apiItemsMap map[string]isocurrency.Model) error {
+
+	itemStore := isocurrency.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	for key, apiItem := range apiItemsMap {
+
+		dbItem, ok := dbItemsMap[key]
+		if !ok {
+			if _, err = itemStore.Insert(ctx, apiItem.Input); err != nil {
+				return fmt.Errorf("itemStore.Insert failed on code: %v: %w", key, err)
+			}
+			c.InfoLog.Info("inserted currency", slog.String(clog.KeyDataset, DatasetIso4217Currencies), slog.String(clog.KeyCode, key))
+			continue
+		}
+
+		if !itemStore.Equal(apiItem, dbItem) {
+			if err = itemStore.Update(ctx, apiItem.Input, dbItem.Id); err != nil {
+				return fmt.Errorf("itemStore.Update failed on code: %v: %w", key, err)
+			}
+			c.InfoLog.Info("updated currency", slog.String(clog.KeyDataset, DatasetIso4217Currencies), slog.String(clog.KeyCode, key))
+		}
+	}
+
+	// currencies withdrawn from the list, e.g. HRK after Croatia adopted the euro
+	for key, dbItem := range dbItemsMap {
+		if _, ok := apiItemsMap[key]; !ok {
+			if err = itemStore.Delete(ctx, dbItem.Id); err != nil {
+				return fmt.Errorf("itemStore.Delete failed on code: %v: %w", key, err)
+			}
+			c.InfoLog.Info("deleted currency", slog.String(clog.KeyDataset, DatasetIso4217Currencies), slog.String(clog.KeyCode, key))
+		}
+	}
+
+	return nil
+}
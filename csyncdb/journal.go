@@ -0,0 +1,82 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/stores/connectors/syncrun"
+)
+
+// dataset names used in the sync journal
+const (
+	DatasetEcbCurrencies             string = "ecb.currency"
+	DatasetEcbExchangeRates          string = "ecb.exchange_rate"
+	DatasetEcbPressReleases          string = "ecb.press_release"
+	DatasetFredSeries                string = "fred.series"
+	DatasetFredObservations          string = "fred.observation"
+	DatasetImfExchangeRates          string = "imf.exchange_rate"
+	DatasetEurostatObservations      string = "eurostat.observation"
+	DatasetExhostRates               string = "exhost.rate"
+	DatasetCoingeckoCoins            string = "coingecko.coin"
+	DatasetCoingeckoPrices           string = "coingecko.price"
+	DatasetCoingeckoPriceTicks       string = "coingecko.price_tick"
+	DatasetGleifEntities             string = "gleif.entity"
+	DatasetPublicHolidays            string = "holiday.public_holiday"
+	DatasetCountries                 string = "country.country"
+	DatasetIso4217Currencies         string = "iso4217.currency"
+	DatasetCompaniesHouseCompanies   string = "companieshouse.company"
+	DatasetCompaniesHouseOfficers    string = "companieshouse.officer"
+	DatasetShipments                 string = "shipment.shipment"
+	DatasetShopifyProducts           string = "shopify.product"
+	DatasetShopifyOrders             string = "shopify.sales_order"
+	DatasetAmazonOrders              string = "amzorder.sales_order"
+	DatasetEbayInventory             string = "ebay.inventory_item"
+	DatasetStripeBalanceTransactions string = "stripe.balance_transaction"
+	DatasetStripePayouts             string = "stripe.payout"
+	DatasetWiseRates                 string = "wise.rate"
+	DatasetWiseRateTicks             string = "wise.rate_tick"
+	DatasetWiseTransfers             string = "wise.transfer"
+	DatasetLexofficeContacts         string = "lexoffice.contact"
+	DatasetLexofficeVouchers         string = "lexoffice.voucher"
+	DatasetHubspotCompanies          string = "hubspot.company"
+	DatasetHubspotContacts           string = "hubspot.contact"
+	DatasetHubspotDeals              string = "hubspot.deal"
+	DatasetInboundEvents             string = "inbound.event"
+	DatasetFiledropRecords           string = "filedrop.record"
+	DatasetImapAttachments           string = "imap.attachment"
+	DatasetFxReconRates              string = "fxrecon.rate"
+	DatasetOutboundRates             string = "outbound.rate"
+)
+
+// Journaled runs syncFunc and records its start, end and outcome in the sync journal (connectors.sync_run)
+// the ctx passed to syncFunc carries the id of the run, see syncrun.RunIdFromContext, so that the rows it stores can be traced back to it
+// the error returned is the error of syncFunc, or of the journal if syncFunc succeeded
+func Journaled(ctx context.Context, db *pgxpool.Pool, dataset, params string, syncFunc func(ctx context.Context) error) error {
+
+	runStore := syncrun.Store{Db: db}
+	runId, err := runStore.Start(ctx, dataset, params)
+	if err != nil {
+		return fmt.Errorf("runStore.Start failed: %w", err)
+	}
+
+	syncErr := syncFunc(syncrun.ContextWithRunId(ctx, runId))
+
+	// record outcome even if ctx was cancelled during the sync
+	err = runStore.Finish(context.WithoutCancel(ctx), runId, syncErr)
+	if syncErr != nil {
+		return syncErr
+	}
+	if err != nil {
+		return fmt.Errorf("runStore.Finish failed: %w", err)
+	}
+
+	return nil
+}
+
+// EcbExchangeRatesParams returns the journal params of an EcbExchangeRates run
+func EcbExchangeRatesParams(baseCurr string, freq ecbapi.Frequency, startDate, endDate time.Time) string {
+	return fmt.Sprintf("base=%s freq=%s from=%s to=%s", baseCurr, freq, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+}
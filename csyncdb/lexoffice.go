@@ -0,0 +1,231 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/lexofficeapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/lexoffice/lexcontact"
+	"github.com/loveyourstack/connectors/stores/lexoffice/lexinvoiceline"
+	"github.com/loveyourstack/connectors/stores/lexoffice/lexvoucher"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// LexofficeContactsToTargets fetches all contacts once and syncs them into each target, recording a journal entry in each
+func LexofficeContactsToTargets(ctx context.Context, targets []Target, c lexofficeapi.Client) error {
+
+	contacts, fetchErr := c.GetApiContacts(ctx)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiContacts failed: %w", fetchErr)
+	}
+
+	return toTargets(ctx, targets, DatasetLexofficeContacts, "", func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyLexofficeContacts(ctx, db, c, contacts)
+	})
+}
+
+// ApplyLexofficeContacts syncs the contacts of db with already fetched API contacts. c is only used for logging
+// the API contacts are complete, so contacts missing from them are deleted
+func ApplyLexofficeContacts(ctx context.Context, db *pgxpool.Pool, c lexofficeapi.Client, contacts []lexofficeapi.Contact) error {
+
+	itemStore := lexcontact.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	apiItemsMap, err := lexofficeapi.ContactsToMap(contacts)
+	if err != nil {
+		return fmt.Errorf("lexofficeapi.ContactsToMap failed: %w", err)
+	}
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	newItems := []lexcontact.Input{}
+	updatedItems := make(map[int64]lexcontact.Input)
+	deletedIds := []int64{}
+
+	for key, apiItem := range apiItemsMap {
+		dbItem, ok := dbItemsMap[key]
+		if !ok {
+			newItems = append(newItems, apiItem.Input)
+			continue
+		}
+		if !itemStore.Equal(apiItem, dbItem) {
+			updatedItems[dbItem.Id] = apiItem.Input
+		}
+	}
+	for key, dbItem := range dbItemsMap {
+		if _, ok := apiItemsMap[key]; !ok {
+			deletedIds = append(deletedIds, dbItem.Id)
+		}
+	}
+
+	if err = itemStore.BulkDelete(ctx, deletedIds); err != nil {
+		return fmt.Errorf("itemStore.BulkDelete failed: %w", err)
+	}
+	if len(newItems) > 0 {
+		if _, err = itemStore.BulkInsert(ctx, newItems); err != nil {
+			return fmt.Errorf("itemStore.BulkInsert failed: %w", err)
+		}
+	}
+	inputs, ids := splitUpdates(updatedItems)
+	if err = itemStore.BulkUpdate(ctx, inputs, ids); err != nil {
+		return fmt.Errorf("itemStore.BulkUpdate failed: %w", err)
+	}
+
+	c.InfoLog.Info("synced contacts", slog.String(clog.KeyDataset, DatasetLexofficeContacts),
+		slog.Int("inserted", len(newItems)), slog.Int("updated", len(updatedItems)), slog.Int("deleted", len(deletedIds)))
+
+	return nil
+}
+
+// LexofficeVouchersToTargets fetches the vouchers updated since the latest update stored in the targets once, with the lines of their invoices, and syncs them into each target, recording a journal entry in each
+// the earliest of the targets' latest updates is used, or days ago for a target without vouchers
+func LexofficeVouchersToTargets(ctx context.Context, targets []Target, c lexofficeapi.Client, days int) error {
+
+	updatedSince := lexofficeUpdatedSince(ctx, targets, c, time.Now().AddDate(0, 0, -days))
+
+	vouchers, invoiceLines, fetchErr := getLexofficeVouchers(ctx, c, updatedSince)
+
+	return toTargets(ctx, targets, DatasetLexofficeVouchers, LexofficeVouchersParams(updatedSince), func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyLexofficeVouchers(ctx, db, c, vouchers, invoiceLines)
+	})
+}
+
+// LexofficeVouchersParams returns the journal params of a LexofficeVouchers run
+func LexofficeVouchersParams(updatedSince time.Time) string {
+	return "updatedSince=" + updatedSince.Format(lystype.DateFormat)
+}
+
+// lexofficeUpdatedSince returns the earliest of the latest voucher updates in targets, counting defaultSince for those without vouchers
+// targets whose vouchers can't be read are skipped: their sync fails when applied
+func lexofficeUpdatedSince(ctx context.Context, targets []Target, c lexofficeapi.Client, defaultSince time.Time) time.Time {
+
+	since := time.Time{}
+	for _, t := range targets {
+
+		maxUpdatedAt, err := lexvoucher.Store{Db: t.Db}.SelectMaxUpdatedAt(ctx)
+		if err != nil {
+			c.ErrorLog.Error("lexvoucher.Store.SelectMaxUpdatedAt failed", slog.String(clog.KeyDataset, DatasetLexofficeVouchers), slog.String("target", t.Name), slog.String(clog.KeyError, err.Error()))
+			continue
+		}
+		if maxUpdatedAt.IsZero() {
+			maxUpdatedAt = defaultSince
+		}
+		if since.IsZero() || maxUpdatedAt.Before(since) {
+			since = maxUpdatedAt
+		}
+	}
+
+	if since.IsZero() {
+		return defaultSince
+	}
+	return since
+}
+
+// getLexofficeVouchers fetches the vouchers updated since updatedSince, and the lines of those which are invoices, with the voucher's LexofficeId as key
+func getLexofficeVouchers(ctx context.Context, c lexofficeapi.Client, updatedSince time.Time) (vouchers []lexofficeapi.Voucher, invoiceLines map[string][]lexofficeapi.InvoiceLine, err error) {
+
+	vouchers, err = c.GetApiVouchers(ctx, updatedSince)
+	if err != nil {
+		return nil, nil, fmt.Errorf("c.GetApiVouchers failed: %w", err)
+	}
+
+	invoiceLines = make(map[string][]lexofficeapi.InvoiceLine)
+	for _, v := range vouchers {
+		if v.VoucherType != lexofficeapi.VoucherTypeInvoice {
+			continue
+		}
+		lines, err := c.GetApiInvoiceLines(ctx, v.LexofficeId)
+		if err != nil {
+			return nil, nil, fmt.Errorf("c.GetApiInvoiceLines failed on voucher: %s: %w", v.LexofficeId, err)
+		}
+		invoiceLines[v.LexofficeId] = lines
+	}
+
+	return vouchers, invoiceLines, nil
+}
+
+// ApplyLexofficeVouchers inserts or updates the vouchers of db with already fetched API vouchers, and replaces the lines of the inserted or updated invoices with invoiceLines. c is only used for logging
+// the API vouchers are the ones updated since the last sync, so vouchers missing from them are not deleted
+func ApplyLexofficeVouchers(ctx context.Context, db *pgxpool.Pool, c lexofficeapi.Client, vouchers []lexofficeapi.Voucher, invoiceLines map[string][]lexofficeapi.InvoiceLine) error {
+
+	voucherStore := lexvoucher.Store{Db: db}
+	lineStore := lexinvoiceline.Store{Db: db}
+	unlock, err := lockStore(voucherStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	apiItemsMap, err := lexofficeapi.VouchersToMap(vouchers)
+	if err != nil {
+		return fmt.Errorf("lexofficeapi.VouchersToMap failed: %w", err)
+	}
+
+	lexofficeIds := make([]string, 0, len(apiItemsMap))
+	for lexofficeId := range apiItemsMap {
+		lexofficeIds = append(lexofficeIds, lexofficeId)
+	}
+
+	dbItemsMap, err := voucherStore.SelectMapByNaturalKey(ctx, lexofficeIds)
+	if err != nil {
+		return fmt.Errorf("voucherStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	inserted, updated := 0, 0
+	replacedLineVoucherIds := []int64{}
+	newLines := []lexinvoiceline.Input{}
+
+	for lexofficeId, apiItem := range apiItemsMap {
+
+		dbItem, ok := dbItemsMap[lexofficeId]
+		if !ok {
+			newId, err := voucherStore.Insert(ctx, apiItem.Input)
+			if err != nil {
+				return fmt.Errorf("voucherStore.Insert failed on voucher: %s: %w", lexofficeId, err)
+			}
+			newLines = append(newLines, lexofficeapi.InvoiceLinesToInputs(invoiceLines[lexofficeId], newId)...)
+			inserted++
+			continue
+		}
+
+		if !voucherStore.Equal(apiItem, dbItem) {
+			if err = voucherStore.Update(ctx, apiItem.Input, dbItem.Id); err != nil {
+				return fmt.Errorf("voucherStore.Update failed on voucher: %s: %w", lexofficeId, err)
+			}
+			replacedLineVoucherIds = append(replacedLineVoucherIds, dbItem.Id)
+			newLines = append(newLines, lexofficeapi.InvoiceLinesToInputs(invoiceLines[lexofficeId], dbItem.Id)...)
+			updated++
+		}
+	}
+
+	if err = lineStore.DeleteByVouchers(ctx, replacedLineVoucherIds); err != nil {
+		return fmt.Errorf("lineStore.DeleteByVouchers failed: %w", err)
+	}
+	if len(newLines) > 0 {
+		if _, err = lineStore.BulkInsert(ctx, newLines); err != nil {
+			return fmt.Errorf("lineStore.BulkInsert failed: %w", err)
+		}
+	}
+
+	c.InfoLog.Info("synced vouchers", slog.String(clog.KeyDataset, DatasetLexofficeVouchers),
+		slog.Int("inserted", inserted), slog.Int("updated", updated), slog.Int("lines_inserted", len(newLines)))
+
+	return nil
+}
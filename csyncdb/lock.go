@@ -0,0 +1,56 @@
+package csyncdb
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// an apply is a read-diff-write sequence, so two concurrent applies to the same table would conflict, e.g. both inserting the same new rate
+// storeLocks serializes them per table and database: see storeLockKeyOf
+var storeLocks sync.Map // k = storeLockKey, v = *sync.Mutex
+
+// storeLockKey identifies the table an apply writes
+type storeLockKey struct {
+	db    any    // the Db of the store, e.g. its *pgxpool.Pool, or the store itself if it is a pointer, e.g. a *MemStore
+	table string // package path of the store: each store package writes one table
+}
+
+// storeLockKeyOf returns the lock key of store: Store values of the same package sharing a Db, e.g. with different Timeouts, get the same key
+func storeLockKeyOf(store any) (key storeLockKey, err error) {
+
+	if store == nil {
+		return key, fmt.Errorf("store is nil")
+	}
+
+	t, v := reflect.TypeOf(store), reflect.ValueOf(store)
+	if t.Kind() == reflect.Pointer {
+		return storeLockKey{db: store, table: t.Elem().PkgPath()}, nil
+	}
+
+	if t.Kind() == reflect.Struct {
+		if db := v.FieldByName("Db"); db.IsValid() && db.Type().Comparable() {
+			return storeLockKey{db: db.Interface(), table: t.PkgPath()}, nil
+		}
+	}
+
+	if !t.Comparable() {
+		return key, fmt.Errorf("store of type %s has neither a Db field nor is comparable, so cannot be locked", t)
+	}
+	return storeLockKey{db: store, table: t.PkgPath()}, nil
+}
+
+// lockStore locks the table of store for the duration of an apply and returns the unlock func
+func lockStore(store any) (unlock func(), err error) {
+
+	key, err := storeLockKeyOf(store)
+	if err != nil {
+		return nil, fmt.Errorf("storeLockKeyOf failed: %w", err)
+	}
+
+	v, _ := storeLocks.LoadOrStore(key, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+
+	return mu.Unlock, nil
+}
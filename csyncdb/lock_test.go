@@ -0,0 +1,152 @@
+package csyncdb
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbcurrency"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbpressrelease"
+)
+
+func TestStoreLockKeyOf(t *testing.T) {
+
+	pool, otherPool := &pgxpool.Pool{}, &pgxpool.Pool{}
+	memStore := ecbexchangerate.NewMemStore(ecbcurrency.NewMemStore())
+
+	tests := []struct {
+		name      string
+		a, b      any
+		sameTable bool
+	}{
+		{"same pool, other timeout", ecbexchangerate.Store{Db: pool}, ecbexchangerate.Store{Db: pool, Timeout: time.Second}, true},
+		{"other pool", ecbexchangerate.Store{Db: pool}, ecbexchangerate.Store{Db: otherPool}, false},
+		{"other table", ecbexchangerate.Store{Db: pool}, ecbpressrelease.Store{Db: pool}, false},
+		{"same MemStore", memStore, memStore, true},
+		{"other MemStore", memStore, ecbexchangerate.NewMemStore(ecbcurrency.NewMemStore()), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			keyA, err := storeLockKeyOf(tt.a)
+			if err != nil {
+				t.Fatalf("storeLockKeyOf failed: %s", err.Error())
+			}
+			keyB, err := storeLockKeyOf(tt.b)
+			if err != nil {
+				t.Fatalf("storeLockKeyOf failed: %s", err.Error())
+			}
+			if (keyA == keyB) != tt.sameTable {
+				t.Errorf("keys equal: %t, want %t", keyA == keyB, tt.sameTable)
+			}
+		})
+	}
+}
+
+func TestLockStoreNotLockable(t *testing.T) {
+
+	type mapStore struct{ items map[string]int }
+
+	for _, store := range []any{nil, mapStore{}} {
+		if _, err := lockStore(store); err == nil {
+			t.Errorf("lockStore(%T): expected error", store)
+		}
+	}
+}
+
+func TestLockStoreConcurrent(t *testing.T) {
+
+	pool := &pgxpool.Pool{}
+	applies := 0 // not synchronized otherwise: the race detector reports a missing lock
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			unlock, err := lockStore(ecbexchangerate.Store{Db: pool, Timeout: time.Duration(i) * time.Second})
+			if err != nil {
+				t.Errorf("lockStore failed: %s", err.Error())
+				return
+			}
+			defer unlock()
+			applies++
+		}()
+	}
+	wg.Wait()
+
+	if applies != 20 {
+		t.Errorf("applies: got %d, want 20", applies)
+	}
+}
+
+func TestApplyEcbExchangeRatesToStoresConcurrent(t *testing.T) {
+
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := ecbapi.NewClient(logger, logger)
+
+	currStore := ecbcurrency.NewMemStore()
+	if err := ApplyEcbCurrenciesToStore(ctx, currStore, c, map[string]ecbcurrency.Model{
+		"EUR": {Input: ecbcurrency.Input{Code: "EUR", Name: "Euro"}},
+		"USD": {Input: ecbcurrency.Input{Code: "USD", Name: "US dollar"}},
+		"JPY": {Input: ecbcurrency.Input{Code: "JPY", Name: "Japanese yen"}},
+	}); err != nil {
+		t.Fatalf("ApplyEcbCurrenciesToStore failed: %s", err.Error())
+	}
+	itemStore := &yieldingRateStore{MemStore: ecbexchangerate.NewMemStore(currStore)}
+
+	// 100 days of USD and JPY rates
+	startDate := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	endDate := startDate.AddDate(0, 0, 99)
+	apiItems := make([]ecbapi.ExchangeRate, 0, 200)
+	for day := startDate; !day.After(endDate); day = day.AddDate(0, 0, 1) {
+		apiItems = append(apiItems,
+			ecbapi.ExchangeRate{FromCurr: "EUR", ToCurr: "USD", Freq: ecbapi.Daily, PeriodStr: day.Format(time.DateOnly), Rate: 1.1},
+			ecbapi.ExchangeRate{FromCurr: "EUR", ToCurr: "JPY", Freq: ecbapi.Daily, PeriodStr: day.Format(time.DateOnly), Rate: 160})
+	}
+
+	// unserialized, concurrent applies would each find the rates missing and insert them, failing on the duplicates
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if err := ApplyEcbExchangeRatesToStores(ctx, currStore, itemStore, c, apiItems, "EUR", ecbapi.Daily, startDate, endDate, nil); err != nil {
+				t.Errorf("ApplyEcbExchangeRatesToStores failed: %s", err.Error())
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	items, err := itemStore.SelectInRange(ctx, "EUR", ecbexchangerate.Daily, startDate, endDate)
+	if err != nil {
+		t.Fatalf("itemStore.SelectInRange failed: %s", err.Error())
+	}
+	if len(items) != len(apiItems) {
+		t.Errorf("rates stored: got %d, want %d", len(items), len(apiItems))
+	}
+}
+
+// yieldingRateStore yields after reading the stored rates, so that concurrent applies interleave between their read and their write, even on a single CPU
+type yieldingRateStore struct {
+	*ecbexchangerate.MemStore
+}
+
+func (s *yieldingRateStore) SelectMapByNaturalKey(ctx context.Context, baseCurr string, freq ecbexchangerate.Frequency, startDate, endDate time.Time) (itemsMap map[ecbexchangerate.NaturalKey]ecbexchangerate.Model, err error) {
+	itemsMap, err = s.MemStore.SelectMapByNaturalKey(ctx, baseCurr, freq, startDate, endDate)
+	runtime.Gosched()
+	return itemsMap, err
+}
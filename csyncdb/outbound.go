@@ -0,0 +1,170 @@
+package csyncdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/ratepush"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/loveyourstack/connectors/stores/outbound/obstate"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// OutboundDestination is a destination with its pusher
+type OutboundDestination struct {
+	ratepush.Destination
+	Pusher ratepush.Pusher
+}
+
+// OutboundRatesToTargets pushes the daily ECB rates changed since the last push to each destination, reading them from the destination's target
+// a destination which was never pushed to receives the rates of the last days days. Targets without destinations are skipped
+// a failing destination does not stop the others: the returned error joins a TargetError per target with a failed destination
+func OutboundRatesToTargets(ctx context.Context, targets []Target, dests []OutboundDestination, days int, infoLog, errorLog *slog.Logger) error {
+
+	if len(targets) == 0 {
+		return nil
+	}
+
+	// group destinations by target
+	targetDests := make(map[string][]OutboundDestination)
+	for _, d := range dests {
+		targetName := d.Target
+		if targetName == "" {
+			targetName = targets[0].Name
+		}
+		targetDests[targetName] = append(targetDests[targetName], d)
+	}
+
+	var errs []error
+	for _, t := range targets {
+		tDests, ok := targetDests[t.Name]
+		if !ok {
+			continue
+		}
+		delete(targetDests, t.Name)
+
+		names := make([]string, len(tDests))
+		for i, d := range tDests {
+			names[i] = d.Name
+		}
+
+		err := toTargets(ctx, []Target{t}, DatasetOutboundRates, "destinations="+strings.Join(names, ","), func(ctx context.Context, db *pgxpool.Pool) error {
+			return ApplyOutboundRates(ctx, db, tDests, time.Now().AddDate(0, 0, -days), infoLog, errorLog)
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// destinations of targets which are not configured
+	for targetName, tDests := range targetDests {
+		for _, d := range tDests {
+			errs = append(errs, fmt.Errorf("%w: destination %s: unknown target: %s", cerrors.ErrValidationFailed, d.Name, targetName))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ApplyOutboundRates pushes the daily ECB rates of db changed since the cursor of each destination, and records the delivery state of each in db
+// rates are read from startDate for destinations which were never pushed to
+func ApplyOutboundRates(ctx context.Context, db *pgxpool.Pool, dests []OutboundDestination, startDate time.Time, infoLog, errorLog *slog.Logger) error {
+
+	stateStore := obstate.Store{Db: db}
+	unlock, err := lockStore(stateStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	var errs []error
+	for _, d := range dests {
+		err := applyOutboundDestination(ctx, db, stateStore, d, startDate, infoLog)
+		if err != nil {
+			errorLog.Error("outbound push failed", slog.String(clog.KeyDataset, DatasetOutboundRates), slog.String("destination", d.Name), slog.String(clog.KeyError, err.Error()))
+			errs = append(errs, fmt.Errorf("destination %s: %w", d.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// applyOutboundDestination pushes the rates changed since the cursor of d and records its delivery state
+func applyOutboundDestination(ctx context.Context, db *pgxpool.Pool, stateStore obstate.Store, d OutboundDestination, startDate time.Time, infoLog *slog.Logger) error {
+
+	attemptAt := time.Now()
+
+	since := time.Time{}
+	state, err := stateStore.SelectByDestination(ctx, d.Name)
+	if err != nil && !errors.Is(err, cerrors.ErrNotFound) {
+		return fmt.Errorf("stateStore.SelectByDestination failed: %w", err)
+	}
+	if state.CursorAt != nil {
+		// the cursor replaces the start date: rates of earlier days which changed are pushed too
+		since = *state.CursorAt
+		startDate = time.Time{}
+	}
+
+	items, err := ecbexchangerate.Store{Db: db}.SelectChangedSince(ctx, ecbapi.Daily, since, startDate)
+	if err != nil {
+		return fmt.Errorf("ecbexchangerate.Store.SelectChangedSince failed: %w", err)
+	}
+
+	rates, cursorAt := OutboundRates(items, d.Destination)
+
+	if len(rates) > 0 {
+		err = d.Pusher.Push(ctx, rates)
+		if err != nil {
+			if recErr := stateStore.RecordFailure(ctx, d.Name, err, attemptAt); recErr != nil {
+				return errors.Join(err, fmt.Errorf("stateStore.RecordFailure failed: %w", recErr))
+			}
+			return fmt.Errorf("d.Pusher.Push failed: %w", err)
+		}
+		infoLog.Info("pushed exchange rates", slog.String(clog.KeyDataset, DatasetOutboundRates), slog.String("destination", d.Name), slog.Int(clog.KeyCount, len(rates)))
+	}
+
+	err = stateStore.RecordSuccess(ctx, d.Name, cursorAt, len(rates), attemptAt)
+	if err != nil {
+		return fmt.Errorf("stateStore.RecordSuccess failed: %w", err)
+	}
+
+	return nil
+}
+
+// OutboundRates converts the changed rates to the rates pushed to d, which are those of its currencies
+// cursorAt is the latest change time of items, or nil if there are none. It also covers items of other currencies, so that they are not read again
+func OutboundRates(items []ecbexchangerate.Model, d ratepush.Destination) (rates []ratepush.Rate, cursorAt *time.Time) {
+
+	rates = []ratepush.Rate{}
+	for _, item := range items {
+
+		changedAt := time.Time(item.EntryAt)
+		if lastModifiedAt := time.Time(item.LastModifiedAt); lastModifiedAt.After(changedAt) {
+			changedAt = lastModifiedAt
+		}
+		if cursorAt == nil || changedAt.After(*cursorAt) {
+			cursorAt = &changedAt
+		}
+
+		if !d.Includes(item.ToCurrency) {
+			continue
+		}
+		rates = append(rates, ratepush.Rate{
+			Day:          item.Day.Format(lystype.DateFormat),
+			Frequency:    item.Frequency.String(),
+			FromCurrency: item.FromCurrency,
+			ToCurrency:   item.ToCurrency,
+			Rate:         item.RateFloat64(),
+		})
+	}
+
+	return rates, cursorAt
+}
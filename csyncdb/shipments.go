@@ -0,0 +1,230 @@
+package csyncdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/shipment/trackedshipment"
+	"github.com/loveyourstack/connectors/stores/shipment/trackingevent"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// ShipmentTracker fetches the tracking data of a carrier's shipments as store inputs. Implemented by dhlapi.Client
+type ShipmentTracker interface {
+	Carrier() string
+	TrackShipment(ctx context.Context, trackingNumber string) (shipment trackedshipment.Input, events []trackingevent.Input, err error)
+}
+
+// ShipmentsToTargets polls the open shipments of tracker's carrier in each target until they are delivered, recording a journal entry in each
+// trackingNumbers are registered in each target first. Shipments polled within pollInterval, or registered longer than maxAge ago, are skipped
+// at most maxRequests tracking requests are made in total: a shipment open in several targets is requested once
+func ShipmentsToTargets(ctx context.Context, targets []Target, tracker ShipmentTracker, trackingNumbers []string, pollInterval, maxAge time.Duration, maxRequests int, infoLog *slog.Logger) error {
+
+	src := &shipmentSource{tracker: tracker, maxRequests: maxRequests, cache: make(map[string]trackedShipment)}
+
+	params := ShipmentsParams(tracker.Carrier(), pollInterval, maxAge)
+	return toTargets(ctx, targets, DatasetShipments, params, func(ctx context.Context, db *pgxpool.Pool) error {
+		return pollShipments(ctx, db, src, trackingNumbers, pollInterval, maxAge, infoLog)
+	})
+}
+
+// ShipmentsParams returns the journal params of a Shipments run
+func ShipmentsParams(carrier string, pollInterval, maxAge time.Duration) string {
+	return fmt.Sprintf("carrier=%s pollInterval=%s maxAge=%s", carrier, pollInterval, maxAge)
+}
+
+// pollShipments registers trackingNumbers in db, then applies the tracking data of its open shipments
+func pollShipments(ctx context.Context, db *pgxpool.Pool, src *shipmentSource, trackingNumbers []string, pollInterval, maxAge time.Duration, infoLog *slog.Logger) error {
+
+	carrier := src.tracker.Carrier()
+	shpStore := trackedshipment.Store{Db: db}
+
+	if err := RegisterShipments(ctx, db, carrier, trackingNumbers); err != nil {
+		return fmt.Errorf("RegisterShipments failed: %w", err)
+	}
+
+	now := time.Now()
+	openItems, err := shpStore.SelectOpen(ctx, carrier, now.Add(-maxAge), now.Add(-pollInterval))
+	if err != nil {
+		return fmt.Errorf("shpStore.SelectOpen failed: %w", err)
+	}
+
+	polled, delivered, unknown := 0, 0, 0
+	for _, dbItem := range openItems {
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		tracked, ok, err := src.track(ctx, dbItem.TrackingNumber)
+		if err != nil {
+			return fmt.Errorf("src.track failed: %w", err)
+		}
+		if !ok {
+			infoLog.Warn("tracking request limit reached, remaining shipments are polled by the next sync", slog.String(clog.KeyDataset, DatasetShipments),
+				slog.Int("limit", src.maxRequests), slog.Int("skipped", len(openItems)-polled))
+			break
+		}
+		polled++
+
+		if tracked.notFound {
+			// not scanned by the carrier yet
+			if err = shpStore.SetPolled(ctx, dbItem.Id, now); err != nil {
+				return fmt.Errorf("shpStore.SetPolled failed on %s: %w", dbItem.TrackingNumber, err)
+			}
+			unknown++
+			continue
+		}
+
+		if err = ApplyShipment(ctx, db, dbItem, tracked.shipment, tracked.events, now, infoLog); err != nil {
+			return fmt.Errorf("ApplyShipment failed on %s: %w", dbItem.TrackingNumber, err)
+		}
+		if tracked.shipment.DeliveredAt != nil {
+			delivered++
+		}
+	}
+
+	infoLog.Info("polled shipments", slog.String(clog.KeyDataset, DatasetShipments), slog.String("carrier", carrier),
+		slog.Int("open", len(openItems)), slog.Int("polled", polled), slog.Int("delivered", delivered), slog.Int("unknown", unknown))
+
+	return nil
+}
+
+// RegisterShipments inserts the shipments of carrier with trackingNumbers which are not in db yet, so that they are polled
+func RegisterShipments(ctx context.Context, db *pgxpool.Pool, carrier string, trackingNumbers []string) error {
+
+	shpStore := trackedshipment.Store{Db: db}
+
+	dbItemsMap, err := shpStore.SelectMapByNaturalKey(ctx, carrier, trackingNumbers)
+	if err != nil {
+		return fmt.Errorf("shpStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	for _, number := range trackingNumbers {
+		if _, ok := dbItemsMap[number]; ok {
+			continue
+		}
+		input := trackedshipment.Input{Carrier: carrier, TrackingNumber: number, StatusCode: trackedshipment.StatusUnknown}
+		if _, err = shpStore.Insert(ctx, input); err != nil && !errors.Is(err, cerrors.ErrConflictPolicyViolation) {
+			return fmt.Errorf("shpStore.Insert failed on %s: %w", number, err)
+		}
+	}
+
+	return nil
+}
+
+// ApplyShipment updates dbItem with already fetched tracking data, and inserts its new events. polledAt is stored as its poll time
+// the reference of dbItem is kept. Events are only added: events no longer returned by the carrier are kept
+func ApplyShipment(ctx context.Context, db *pgxpool.Pool, dbItem trackedshipment.Model, shipment trackedshipment.Input, events []trackingevent.Input, polledAt time.Time, infoLog *slog.Logger) error {
+
+	shpStore := trackedshipment.Store{Db: db}
+	evStore := trackingevent.Store{Db: db}
+	unlock, err := lockStore(evStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	shipment.Carrier = dbItem.Carrier
+	shipment.TrackingNumber = dbItem.TrackingNumber
+	shipment.Reference = dbItem.Reference
+
+	if !shpStore.Equal(trackedshipment.Model{Input: shipment}, dbItem) {
+		shipment.LastPolledAt = optionalDatetime(polledAt)
+		if err := shpStore.Update(ctx, shipment, dbItem.Id); err != nil {
+			return fmt.Errorf("shpStore.Update failed: %w", err)
+		}
+		infoLog.Info("shipment status changed", slog.String(clog.KeyDataset, DatasetShipments), slog.String(clog.KeyCode, dbItem.TrackingNumber),
+			slog.String("from", dbItem.StatusCode), slog.String("to", shipment.StatusCode))
+	} else {
+		if err := shpStore.SetPolled(ctx, dbItem.Id, polledAt); err != nil {
+			return fmt.Errorf("shpStore.SetPolled failed: %w", err)
+		}
+	}
+
+	dbEventsMap, err := evStore.SelectMapByNaturalKey(ctx, dbItem.Id)
+	if err != nil {
+		return fmt.Errorf("evStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	newItems := []trackingevent.Input{}
+	seen := make(map[string]bool)
+	for _, ev := range events {
+		key := trackingevent.NaturalKey(time.Time(ev.EventAt), ev.StatusCode, ev.Description)
+		if _, ok := dbEventsMap[key]; ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		ev.ShipmentFk = dbItem.Id
+		newItems = append(newItems, ev)
+	}
+
+	if len(newItems) > 0 {
+		if _, err = evStore.BulkInsert(ctx, newItems); err != nil {
+			return fmt.Errorf("evStore.BulkInsert failed: %w", err)
+		}
+		infoLog.Info("inserted tracking events", slog.String(clog.KeyDataset, DatasetShipments), slog.String(clog.KeyCode, dbItem.TrackingNumber), slog.Int(clog.KeyCount, len(newItems)))
+	}
+
+	return nil
+}
+
+// trackedShipment is the tracking data of a shipment fetched by a shipmentSource
+type trackedShipment struct {
+	shipment trackedshipment.Input
+	events   []trackingevent.Input
+	notFound bool // the carrier does not know the tracking number yet
+}
+
+// shipmentSource caches the tracking data fetched for all targets, and limits the number of tracking requests
+// it is only used by the sequential toTargets, so it needs no locking
+type shipmentSource struct {
+	tracker     ShipmentTracker
+	maxRequests int
+	requests    int
+	cache       map[string]trackedShipment
+	err         error // error of a tracking request, returned for all later requests, e.g. when the carrier's quota is used up
+}
+
+// track returns the tracking data of trackingNumber. ok is false if it is not cached and the request limit is reached
+func (s *shipmentSource) track(ctx context.Context, trackingNumber string) (tracked trackedShipment, ok bool, err error) {
+
+	if tracked, ok := s.cache[trackingNumber]; ok {
+		return tracked, true, nil
+	}
+	if s.err != nil {
+		return trackedShipment{}, false, s.err
+	}
+	if s.requests >= s.maxRequests {
+		return trackedShipment{}, false, nil
+	}
+	s.requests++
+
+	shipment, events, err := s.tracker.TrackShipment(ctx, trackingNumber)
+	switch {
+	case err == nil:
+		tracked = trackedShipment{shipment: shipment, events: events}
+	case errors.Is(err, cerrors.ErrNotFound):
+		tracked = trackedShipment{notFound: true}
+	default:
+		s.err = fmt.Errorf("s.tracker.TrackShipment failed on %s: %w", trackingNumber, err)
+		return trackedShipment{}, false, s.err
+	}
+
+	s.cache[trackingNumber] = tracked
+	return tracked, true, nil
+}
+
+func optionalDatetime(t time.Time) *lystype.Datetime {
+	if t.IsZero() {
+		return nil
+	}
+	dt := lystype.Datetime(t)
+	return &dt
+}
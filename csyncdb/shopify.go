@@ -0,0 +1,219 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/shopifyapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/shopify/shoporder"
+	"github.com/loveyourstack/connectors/stores/shopify/shopproduct"
+	"github.com/loveyourstack/connectors/stores/shopify/shopvariant"
+)
+
+// ShopifyProductsToTargets fetches the products and variants of c.Shop once and syncs them into each target, recording a journal entry in each
+func ShopifyProductsToTargets(ctx context.Context, targets []Target, c shopifyapi.Client) error {
+
+	products, fetchErr := c.GetApiProducts(ctx)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiProducts failed: %w", fetchErr)
+	}
+
+	return toTargets(ctx, targets, DatasetShopifyProducts, "shop="+c.Shop, func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyShopifyProducts(ctx, db, c, products)
+	})
+}
+
+// ApplyShopifyProducts syncs the products and variants of c.Shop in db with already fetched API products. c is otherwise only used for logging
+// products and variants no longer in the shop are deleted
+func ApplyShopifyProducts(ctx context.Context, db *pgxpool.Pool, c shopifyapi.Client, products []shopifyapi.Product) error {
+
+	prodStore := shopproduct.Store{Db: db}
+	varStore := shopvariant.Store{Db: db}
+	unlock, err := lockStore(prodStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	// products
+	apiProdMap, err := shopifyapi.ProductsToMap(products, c.Shop)
+	if err != nil {
+		return fmt.Errorf("shopifyapi.ProductsToMap failed: %w", err)
+	}
+
+	dbProdMap, err := prodStore.SelectMapByNaturalKey(ctx, c.Shop)
+	if err != nil {
+		return fmt.Errorf("prodStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	// map of k = product ShopifyId, v = db id, to resolve the product of variants
+	productIdMap := make(map[int64]int64)
+	prodInserted, prodUpdated, prodDeleted := 0, 0, 0
+
+	for shopifyId, apiItem := range apiProdMap {
+
+		dbItem, ok := dbProdMap[shopifyId]
+		if !ok {
+			newId, err := prodStore.Insert(ctx, apiItem.Input)
+			if err != nil {
+				return fmt.Errorf("prodStore.Insert failed on product: %v: %w", shopifyId, err)
+			}
+			productIdMap[shopifyId] = newId
+			prodInserted++
+			continue
+		}
+
+		productIdMap[shopifyId] = dbItem.Id
+		if !prodStore.Equal(apiItem, dbItem) {
+			if err = prodStore.Update(ctx, apiItem.Input, dbItem.Id); err != nil {
+				return fmt.Errorf("prodStore.Update failed on product: %v: %w", shopifyId, err)
+			}
+			prodUpdated++
+		}
+	}
+
+	// deleting a product deletes its variants
+	for shopifyId, dbItem := range dbProdMap {
+		if _, ok := apiProdMap[shopifyId]; !ok {
+			if err = prodStore.Delete(ctx, dbItem.Id); err != nil {
+				return fmt.Errorf("prodStore.Delete failed on ID: %v: %w", dbItem.Id, err)
+			}
+			prodDeleted++
+		}
+	}
+
+	// variants
+	apiVarMap, err := shopifyapi.VariantsToMap(products, productIdMap)
+	if err != nil {
+		return fmt.Errorf("shopifyapi.VariantsToMap failed: %w", err)
+	}
+
+	dbVarMap, err := varStore.SelectMapByNaturalKey(ctx, c.Shop)
+	if err != nil {
+		return fmt.Errorf("varStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	newVars := []shopvariant.Input{}
+	updatedVars := make(map[int64]shopvariant.Input)
+	deletedVarIds := []int64{}
+
+	for shopifyId, apiItem := range apiVarMap {
+		dbItem, ok := dbVarMap[shopifyId]
+		if !ok {
+			newVars = append(newVars, apiItem.Input)
+			continue
+		}
+		if !varStore.Equal(apiItem, dbItem) || apiItem.ProductFk != dbItem.ProductFk {
+			updatedVars[dbItem.Id] = apiItem.Input
+		}
+	}
+	for shopifyId, dbItem := range dbVarMap {
+		if _, ok := apiVarMap[shopifyId]; !ok {
+			deletedVarIds = append(deletedVarIds, dbItem.Id)
+		}
+	}
+
+	if err = varStore.BulkDelete(ctx, deletedVarIds); err != nil {
+		return fmt.Errorf("varStore.BulkDelete failed: %w", err)
+	}
+	if len(newVars) > 0 {
+		if _, err = varStore.BulkInsert(ctx, newVars); err != nil {
+			return fmt.Errorf("varStore.BulkInsert failed: %w", err)
+		}
+	}
+	inputs, ids := splitUpdates(updatedVars)
+	if err = varStore.BulkUpdate(ctx, inputs, ids); err != nil {
+		return fmt.Errorf("varStore.BulkUpdate failed: %w", err)
+	}
+
+	c.InfoLog.Info("synced products", slog.String(clog.KeyDataset, DatasetShopifyProducts), slog.String("shop", c.Shop),
+		slog.Int("inserted", prodInserted), slog.Int("updated", prodUpdated), slog.Int("deleted", prodDeleted),
+		slog.Int("variants_inserted", len(newVars)), slog.Int("variants_updated", len(updatedVars)), slog.Int("variants_deleted", len(deletedVarIds)))
+
+	return nil
+}
+
+// ShopifyOrdersToTargets fetches the orders of c.Shop updated since updatedSince once and syncs them into each target, recording a journal entry in each
+func ShopifyOrdersToTargets(ctx context.Context, targets []Target, c shopifyapi.Client, updatedSince time.Time) error {
+
+	orders, fetchErr := c.GetApiOrders(ctx, updatedSince)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiOrders failed: %w", fetchErr)
+	}
+
+	params := ShopifyOrdersParams(c.Shop, updatedSince)
+	return toTargets(ctx, targets, DatasetShopifyOrders, params, func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyShopifyOrders(ctx, db, c, orders)
+	})
+}
+
+// ShopifyOrdersParams returns the journal params of a ShopifyOrders run
+func ShopifyOrdersParams(shop string, updatedSince time.Time) string {
+	return fmt.Sprintf("shop=%s updatedSince=%s", shop, updatedSince.Format(time.RFC3339))
+}
+
+// ApplyShopifyOrders inserts or updates the orders of c.Shop in db with already fetched API orders. c is otherwise only used for logging
+// the API orders are the ones updated in a time window, so orders missing from them are not deleted
+func ApplyShopifyOrders(ctx context.Context, db *pgxpool.Pool, c shopifyapi.Client, orders []shopifyapi.Order) error {
+
+	itemStore := shoporder.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	apiItemsMap, err := shopifyapi.OrdersToMap(orders, c.Shop)
+	if err != nil {
+		return fmt.Errorf("shopifyapi.OrdersToMap failed: %w", err)
+	}
+
+	shopifyIds := make([]int64, 0, len(apiItemsMap))
+	for shopifyId := range apiItemsMap {
+		shopifyIds = append(shopifyIds, shopifyId)
+	}
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx, c.Shop, shopifyIds)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	newItems := []shoporder.Input{}
+	updatedItems := make(map[int64]shoporder.Input)
+
+	for shopifyId, apiItem := range apiItemsMap {
+		dbItem, ok := dbItemsMap[shopifyId]
+		if !ok {
+			newItems = append(newItems, apiItem.Input)
+			continue
+		}
+		if !itemStore.Equal(apiItem, dbItem) {
+			updatedItems[dbItem.Id] = apiItem.Input
+		}
+	}
+
+	if len(newItems) > 0 {
+		if _, err = itemStore.BulkInsert(ctx, newItems); err != nil {
+			return fmt.Errorf("itemStore.BulkInsert failed: %w", err)
+		}
+	}
+	inputs, ids := splitUpdates(updatedItems)
+	if err = itemStore.BulkUpdate(ctx, inputs, ids); err != nil {
+		return fmt.Errorf("itemStore.BulkUpdate failed: %w", err)
+	}
+
+	c.InfoLog.Info("synced orders", slog.String(clog.KeyDataset, DatasetShopifyOrders), slog.String("shop", c.Shop),
+		slog.Int("inserted", len(newItems)), slog.Int("updated", len(updatedItems)))
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package csyncdb
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/clog"
+)
+
+// ErrDerivedBase is returned by the syncs of exchange rates from a base currency other than EUR, whose rates the ECB does not publish. It matches cerrors.ErrValidationFailed
+// rates from such a base are derived from the EUR rates, see ecbapi.Client.GetAPIExchangeRates: rather than stored, they are converted from the stored EUR rates when read, e.g. by converter.Converter
+var ErrDerivedBase = fmt.Errorf("%w: only the rates from EUR are stored", cerrors.ErrValidationFailed)
+
+// ExchangeRateSource fetches exchange rates in the format of the ECB client. Implemented by ecbapi.Client and frankfurterapi.Client
+// it is used for ECB fallbacks. Rates of any provider are fetched with a ratesource.Source
+type ExchangeRateSource interface {
+	SourceName() string // stored as the source of the rates, e.g. "ecb"
+	GetAPIExchangeRates(baseCurr string, freq ecbapi.Frequency, startDate, endDate time.Time) (exRates []ecbapi.ExchangeRate, err error)
+}
+
+// getEcbExchangeRates fetches the rates to store from c. If the ECB is unavailable (cerrors.ErrUpstreamUnavailable), each fallback is tried in turn
+// other ECB errors, such as no rates for the params, are returned without trying the fallbacks, since they would give the same result. Returns ErrDerivedBase if baseCurr is not EUR
+func getEcbExchangeRates(c ecbapi.Client, fallbacks []ExchangeRateSource, baseCurr string, freq ecbapi.Frequency, startDate, endDate time.Time) (apiItems []ecbapi.ExchangeRate, err error) {
+
+	if err = checkStoredBase(baseCurr); err != nil {
+		return nil, err
+	}
+
+	apiItems, err = c.GetAPIExchangeRates(baseCurr, freq, startDate, endDate)
+	if err == nil {
+		return withSource(apiItems, c.SourceName(), time.Now()), nil
+	}
+	err = fmt.Errorf("c.GetAPIExchangeRates failed: %w", err)
+	if !errors.Is(err, cerrors.ErrUpstreamUnavailable) {
+		return nil, err
+	}
+
+	for i, fallback := range fallbacks {
+
+		c.ErrorLog.Warn("ECB unavailable, trying fallback source", slog.String(clog.KeyDataset, DatasetEcbExchangeRates), slog.Int("fallback", i), slog.String(clog.KeyError, err.Error()))
+
+		var fallbackErr error
+		apiItems, fallbackErr = fallback.GetAPIExchangeRates(baseCurr, freq, startDate, endDate)
+		if fallbackErr == nil {
+			c.InfoLog.Info("fetched exchange rates from fallback source", slog.String(clog.KeyDataset, DatasetEcbExchangeRates), slog.Int("fallback", i), slog.Int(clog.KeyCount, len(apiItems)))
+			return withSource(apiItems, fallback.SourceName(), time.Now()), nil
+		}
+		err = errors.Join(err, fmt.Errorf("fallback %d: fallback.GetAPIExchangeRates failed: %w", i, fallbackErr))
+	}
+
+	return nil, err
+}
+
+// withSource sets the source and fetch time of apiItems, which the sync stores as their provenance
+func withSource(apiItems []ecbapi.ExchangeRate, source string, fetchedAt time.Time) []ecbapi.ExchangeRate {
+	for i := range apiItems {
+		apiItems[i].Source = source
+		apiItems[i].FetchedAt = fetchedAt
+	}
+	return apiItems
+}
+
+// checkStoredBase returns ErrDerivedBase if the rates from baseCurr are not stored
+func checkStoredBase(baseCurr string) error {
+	if baseCurr != "EUR" {
+		return fmt.Errorf("%w: the rates from %s are derived from those from EUR: pls sync EUR and convert the stored rates to %s, e.g. with converter.Converter", ErrDerivedBase, baseCurr, baseCurr)
+	}
+	return nil
+}
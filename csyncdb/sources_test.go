@@ -0,0 +1,40 @@
+package csyncdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbcurrency"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+)
+
+func TestDerivedBase(t *testing.T) {
+
+	ctx := context.Background()
+	day := time.Date(2024, 9, 2, 0, 0, 0, 0, time.UTC)
+	currStore := ecbcurrency.NewMemStore()
+	if _, err := currStore.Insert(ctx, ecbcurrency.Input{Code: "USD", Name: "US dollar"}); err != nil {
+		t.Fatalf("currStore.Insert failed: %s", err.Error())
+	}
+	itemStore := ecbexchangerate.NewMemStore(currStore)
+	apiItems := []ecbapi.ExchangeRate{{FromCurr: "USD", ToCurr: "EUR", Freq: ecbapi.Daily, PeriodStr: "2024-09-02", Rate: 0.9041}}
+
+	err := ApplyEcbExchangeRatesToStores(ctx, currStore, itemStore, ecbapi.Client{}, apiItems, "USD", ecbapi.Daily, day, day, nil)
+	if !errors.Is(err, ErrDerivedBase) || !errors.Is(err, cerrors.ErrValidationFailed) {
+		t.Errorf("ApplyEcbExchangeRatesToStores from USD: got %v, want ErrDerivedBase", err)
+	}
+	if items, _ := itemStore.SelectInRange(ctx, "USD", ecbexchangerate.Daily, day, day); len(items) != 0 {
+		t.Errorf("rates from USD stored: got %d, want 0", len(items))
+	}
+
+	if _, err = getEcbExchangeRates(ecbapi.Client{}, nil, "USD", ecbapi.Daily, day, day); !errors.Is(err, ErrDerivedBase) {
+		t.Errorf("getEcbExchangeRates from USD: got %v, want ErrDerivedBase", err)
+	}
+	if _, err = VerifyEcbExchangeRatesInStores(ctx, currStore, itemStore, ecbapi.Client{}, "USD", ecbapi.Daily, day, day, true); !errors.Is(err, ErrDerivedBase) {
+		t.Errorf("VerifyEcbExchangeRatesInStores from USD: got %v, want ErrDerivedBase", err)
+	}
+}
@@ -0,0 +1,259 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/stripeapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/stripe/stripecursor"
+	"github.com/loveyourstack/connectors/stores/stripe/stripefee"
+	"github.com/loveyourstack/connectors/stores/stripe/stripepayout"
+	"github.com/loveyourstack/connectors/stores/stripe/stripetxn"
+)
+
+// StripeBalanceTransactionsToTargets fetches the balance transactions created since the cursor, with their fees, once and syncs them into each target, recording a journal entry in each
+// the cursor is the earliest one stored in the targets, or days ago for a target without one
+func StripeBalanceTransactionsToTargets(ctx context.Context, targets []Target, c stripeapi.Client, days int) error {
+
+	createdSince := stripeCreatedSince(ctx, targets, c, stripecursor.ObjectBalanceTransaction, DatasetStripeBalanceTransactions, time.Now().AddDate(0, 0, -days))
+
+	txns, fetchErr := c.GetApiBalanceTransactions(ctx, createdSince)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiBalanceTransactions failed: %w", fetchErr)
+	}
+
+	return toTargets(ctx, targets, DatasetStripeBalanceTransactions, StripeParams(createdSince), func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyStripeBalanceTransactions(ctx, db, c, txns, createdSince)
+	})
+}
+
+// StripeParams returns the journal params of a Stripe run
+func StripeParams(createdSince time.Time) string {
+	return "createdSince=" + createdSince.UTC().Format(time.RFC3339)
+}
+
+// stripeCreatedSince returns the earliest cursor of object in targets, counting defaultSince for those without one
+// targets whose cursor can't be read are skipped: their sync fails when applied
+func stripeCreatedSince(ctx context.Context, targets []Target, c stripeapi.Client, object, dataset string, defaultSince time.Time) time.Time {
+
+	since := time.Time{}
+	for _, t := range targets {
+
+		createdSince, err := stripecursor.Store{Db: t.Db}.SelectCreatedSince(ctx, object)
+		if err != nil {
+			c.ErrorLog.Error("stripecursor.Store.SelectCreatedSince failed", slog.String(clog.KeyDataset, dataset), slog.String("target", t.Name), slog.String(clog.KeyError, err.Error()))
+			continue
+		}
+		if createdSince.IsZero() {
+			createdSince = defaultSince
+		}
+		if since.IsZero() || createdSince.Before(since) {
+			since = createdSince
+		}
+	}
+
+	if since.IsZero() {
+		return defaultSince
+	}
+	return since
+}
+
+// nextStripeCursor returns the cursor of the next sync: the creation time of the earliest object which may still change, so that it is fetched again,
+// or else of the latest object. created and settled describe the fetched objects
+func nextStripeCursor(createdSince time.Time, created []time.Time, settled []bool) time.Time {
+
+	earliestOpen, latest := time.Time{}, time.Time{}
+	for i, t := range created {
+		if !settled[i] && (earliestOpen.IsZero() || t.Before(earliestOpen)) {
+			earliestOpen = t
+		}
+		if t.After(latest) {
+			latest = t
+		}
+	}
+
+	switch {
+	case !earliestOpen.IsZero():
+		return earliestOpen
+	case !latest.IsZero():
+		return latest
+	default:
+		return createdSince
+	}
+}
+
+// ApplyStripeBalanceTransactions inserts or updates the balance transactions of db with already fetched API transactions created since createdSince,
+// replaces the fees of the inserted or updated ones, and stores the cursor of the next sync. c is only used for logging
+// the API transactions are the ones created since the cursor, so transactions missing from them are not deleted
+func ApplyStripeBalanceTransactions(ctx context.Context, db *pgxpool.Pool, c stripeapi.Client, txns []stripeapi.BalanceTransaction, createdSince time.Time) error {
+
+	txnStore := stripetxn.Store{Db: db}
+	feeStore := stripefee.Store{Db: db}
+	unlock, err := lockStore(txnStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	apiItemsMap, err := stripeapi.BalanceTransactionsToMap(txns)
+	if err != nil {
+		return fmt.Errorf("stripeapi.BalanceTransactionsToMap failed: %w", err)
+	}
+
+	stripeIds := make([]string, 0, len(apiItemsMap))
+	for stripeId := range apiItemsMap {
+		stripeIds = append(stripeIds, stripeId)
+	}
+
+	dbItemsMap, err := txnStore.SelectMapByNaturalKey(ctx, stripeIds)
+	if err != nil {
+		return fmt.Errorf("txnStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	inserted, updated := 0, 0
+	replacedFeeTxnIds := []int64{}
+	newFees := []stripefee.Input{}
+	created := make([]time.Time, 0, len(txns))
+	settled := make([]bool, 0, len(txns))
+
+	for _, txn := range txns {
+
+		created = append(created, txn.CreatedAt)
+		settled = append(settled, txn.Settled())
+
+		apiItem := apiItemsMap[txn.StripeId]
+		dbItem, ok := dbItemsMap[txn.StripeId]
+		if !ok {
+			newId, err := txnStore.Insert(ctx, apiItem.Input)
+			if err != nil {
+				return fmt.Errorf("txnStore.Insert failed on balance transaction: %s: %w", txn.StripeId, err)
+			}
+			newFees = append(newFees, stripeapi.FeesToInputs(txn, newId)...)
+			inserted++
+			continue
+		}
+
+		if !txnStore.Equal(apiItem, dbItem) {
+			if err = txnStore.Update(ctx, apiItem.Input, dbItem.Id); err != nil {
+				return fmt.Errorf("txnStore.Update failed on balance transaction: %s: %w", txn.StripeId, err)
+			}
+			replacedFeeTxnIds = append(replacedFeeTxnIds, dbItem.Id)
+			newFees = append(newFees, stripeapi.FeesToInputs(txn, dbItem.Id)...)
+			updated++
+		}
+	}
+
+	if err = feeStore.DeleteByBalanceTransactions(ctx, replacedFeeTxnIds); err != nil {
+		return fmt.Errorf("feeStore.DeleteByBalanceTransactions failed: %w", err)
+	}
+	if len(newFees) > 0 {
+		if _, err = feeStore.BulkInsert(ctx, newFees); err != nil {
+			return fmt.Errorf("feeStore.BulkInsert failed: %w", err)
+		}
+	}
+
+	next := nextStripeCursor(createdSince, created, settled)
+	if err = (stripecursor.Store{Db: db}).SetCreatedSince(ctx, stripecursor.ObjectBalanceTransaction, next); err != nil {
+		return fmt.Errorf("stripecursor.Store.SetCreatedSince failed: %w", err)
+	}
+
+	c.InfoLog.Info("synced balance transactions", slog.String(clog.KeyDataset, DatasetStripeBalanceTransactions),
+		slog.Int("inserted", inserted), slog.Int("updated", updated), slog.Int("fees_inserted", len(newFees)), slog.Time("next_created_since", next))
+
+	return nil
+}
+
+// StripePayoutsToTargets fetches the payouts created since the cursor once and syncs them into each target, recording a journal entry in each
+// the cursor is the earliest one stored in the targets, or days ago for a target without one
+func StripePayoutsToTargets(ctx context.Context, targets []Target, c stripeapi.Client, days int) error {
+
+	createdSince := stripeCreatedSince(ctx, targets, c, stripecursor.ObjectPayout, DatasetStripePayouts, time.Now().AddDate(0, 0, -days))
+
+	payouts, fetchErr := c.GetApiPayouts(ctx, createdSince)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetApiPayouts failed: %w", fetchErr)
+	}
+
+	return toTargets(ctx, targets, DatasetStripePayouts, StripeParams(createdSince), func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyStripePayouts(ctx, db, c, payouts, createdSince)
+	})
+}
+
+// ApplyStripePayouts inserts or updates the payouts of db with already fetched API payouts created since createdSince, and stores the cursor of the next sync. c is only used for logging
+// the API payouts are the ones created since the cursor, so payouts missing from them are not deleted
+func ApplyStripePayouts(ctx context.Context, db *pgxpool.Pool, c stripeapi.Client, payouts []stripeapi.Payout, createdSince time.Time) error {
+
+	itemStore := stripepayout.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	apiItemsMap, err := stripeapi.PayoutsToMap(payouts)
+	if err != nil {
+		return fmt.Errorf("stripeapi.PayoutsToMap failed: %w", err)
+	}
+
+	stripeIds := make([]string, 0, len(apiItemsMap))
+	for stripeId := range apiItemsMap {
+		stripeIds = append(stripeIds, stripeId)
+	}
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx, stripeIds)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	newItems := []stripepayout.Input{}
+	updatedItems := make(map[int64]stripepayout.Input)
+
+	for stripeId, apiItem := range apiItemsMap {
+		dbItem, ok := dbItemsMap[stripeId]
+		if !ok {
+			newItems = append(newItems, apiItem.Input)
+			continue
+		}
+		if !itemStore.Equal(apiItem, dbItem) {
+			updatedItems[dbItem.Id] = apiItem.Input
+		}
+	}
+
+	if len(newItems) > 0 {
+		if _, err = itemStore.BulkInsert(ctx, newItems); err != nil {
+			return fmt.Errorf("itemStore.BulkInsert failed: %w", err)
+		}
+	}
+	inputs, ids := splitUpdates(updatedItems)
+	if err = itemStore.BulkUpdate(ctx, inputs, ids); err != nil {
+		return fmt.Errorf("itemStore.BulkUpdate failed: %w", err)
+	}
+
+	now := time.Now()
+	created := make([]time.Time, 0, len(payouts))
+	settled := make([]bool, 0, len(payouts))
+	for _, p := range payouts {
+		created = append(created, p.CreatedAt)
+		settled = append(settled, p.Settled(now))
+	}
+
+	next := nextStripeCursor(createdSince, created, settled)
+	if err = (stripecursor.Store{Db: db}).SetCreatedSince(ctx, stripecursor.ObjectPayout, next); err != nil {
+		return fmt.Errorf("stripecursor.Store.SetCreatedSince failed: %w", err)
+	}
+
+	c.InfoLog.Info("synced payouts", slog.String(clog.KeyDataset, DatasetStripePayouts),
+		slog.Int("inserted", len(newItems)), slog.Int("updated", len(updatedItems)), slog.Time("next_created_since", next))
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package csyncdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/derive"
+	"github.com/loveyourstack/connectors/quality"
+)
+
+// Target is a named database that synced data is written to
+type Target struct {
+	Name string
+	Db   *pgxpool.Pool
+}
+
+// TargetError is the error of a sync into a single Target. The *ToTargets funcs sync the targets in turn and a failing target does not stop the others:
+// their returned error joins a TargetError per failed target, see FindTargetError
+type TargetError struct {
+	Target string
+	Err    error
+}
+
+func (e TargetError) Error() string {
+	return fmt.Sprintf("target %s: %s", e.Target, e.Err.Error())
+}
+
+func (e TargetError) Unwrap() error {
+	return e.Err
+}
+
+// EcbCurrenciesToTargets fetches the ECB currencies once and syncs them into each target, recording a journal entry in each
+func EcbCurrenciesToTargets(ctx context.Context, targets []Target, c ecbapi.Client) error {
+
+	apiItemsMap, fetchErr := c.GetCurrenciesMap()
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("c.GetCurrenciesMap failed: %w", fetchErr)
+	}
+
+	return toTargets(ctx, targets, DatasetEcbCurrencies, "", func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyEcbCurrencies(ctx, db, c, apiItemsMap)
+	})
+}
+
+// EcbExchangeRatesToTargets fetches the ECB exchange rates once and syncs them into each target, recording a journal entry in each
+// if the ECB is unavailable, the rates are fetched from fallbacks in turn, and they are checked against rules and the derived fields computed, as for EcbExchangeRates
+func EcbExchangeRatesToTargets(ctx context.Context, targets []Target, c ecbapi.Client, baseCurr string, freq ecbapi.Frequency, startDate, endDate time.Time, rules []quality.Rule, derived []derive.Field,
+	fallbacks ...ExchangeRateSource) error {
+
+	apiItems, fetchErr := getEcbExchangeRates(c, fallbacks, baseCurr, freq, startDate, endDate)
+	if fetchErr != nil {
+		fetchErr = fmt.Errorf("getEcbExchangeRates failed: %w", fetchErr)
+	}
+
+	params := EcbExchangeRatesParams(baseCurr, freq, startDate, endDate)
+	return toTargets(ctx, targets, DatasetEcbExchangeRates, params, func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyEcbExchangeRates(ctx, db, c, apiItems, baseCurr, freq, startDate, endDate, rules, derived)
+	})
+}
+
+// toTargets runs applyFunc, journaled, for each target in turn
+// a fetch error is returned by applyFunc rather than earlier so that the failed run is recorded in every target's journal
+func toTargets(ctx context.Context, targets []Target, dataset, params string, applyFunc func(ctx context.Context, db *pgxpool.Pool) error) error {
+
+	var errs []error
+	for _, t := range targets {
+		err := Journaled(ctx, t.Db, dataset, params, func(ctx context.Context) error {
+			return applyFunc(ctx, t.Db)
+		})
+		if err != nil {
+			errs = append(errs, TargetError{Target: t.Name, Err: err})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// FindTargetError returns the TargetError of targetName contained in err, as returned (possibly wrapped) by the *ToTargets funcs
+func FindTargetError(err error, targetName string) (TargetError, bool) {
+
+	switch e := err.(type) {
+	case nil:
+		return TargetError{}, false
+	case TargetError:
+		if e.Target == targetName {
+			return e, true
+		}
+		return TargetError{}, false
+	case interface{ Unwrap() []error }:
+		for _, joinedErr := range e.Unwrap() {
+			if targetErr, ok := FindTargetError(joinedErr, targetName); ok {
+				return targetErr, true
+			}
+		}
+		return TargetError{}, false
+	default:
+		return FindTargetError(errors.Unwrap(err), targetName)
+	}
+}
@@ -0,0 +1,54 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/sink"
+	"github.com/loveyourstack/connectors/stores/connectors/tick"
+)
+
+// TicksToTargets writes already fetched ticks of dataset to the sink routed by sinks, or, if dataset has no route, into the connectors.tick table of each target
+// either way, a journal entry is recorded in each target. A sink is written once, and its outcome recorded in every target's journal
+// a fetch error is recorded in the journals as for the other *ToTargets funcs. infoLog is only used for logging
+func TicksToTargets(ctx context.Context, targets []Target, sinks sink.Router, infoLog *slog.Logger, dataset, params string, ticks []sink.Tick, fetchErr error) error {
+
+	s, ok := sinks.Route(dataset)
+	if !ok {
+		return toTargets(ctx, targets, dataset, params, func(ctx context.Context, db *pgxpool.Pool) error {
+			if fetchErr != nil {
+				return fetchErr
+			}
+			return ApplyTicks(ctx, db, infoLog, dataset, ticks)
+		})
+	}
+
+	sinkErr := fetchErr
+	if sinkErr == nil {
+		if err := s.WriteTicks(ctx, dataset, ticks); err != nil {
+			sinkErr = fmt.Errorf("%s sink: WriteTicks failed: %w", s.Name(), err)
+		}
+	}
+
+	params += " sink=" + s.Name()
+	return toTargets(ctx, targets, dataset, params, func(ctx context.Context, db *pgxpool.Pool) error {
+		return sinkErr
+	})
+}
+
+// ApplyTicks inserts or updates ticks of dataset in the connectors.tick table of db
+func ApplyTicks(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger, dataset string, ticks []sink.Tick) error {
+
+	// a single upsert, so no need to lock the store
+	rowsAffected, err := tick.Store{Db: db}.Upsert(ctx, dataset, ticks)
+	if err != nil {
+		return fmt.Errorf("tick.Store.Upsert failed: %w", err)
+	}
+
+	infoLog.Info("synced ticks", slog.String(clog.KeyDataset, dataset), slog.Int("fetched", len(ticks)), slog.Int64("changed", rowsAffected))
+
+	return nil
+}
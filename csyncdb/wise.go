@@ -0,0 +1,224 @@
+package csyncdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/wiseapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/sink"
+	"github.com/loveyourstack/connectors/stores/wise/wiserate"
+	"github.com/loveyourstack/connectors/stores/wise/wisetransfer"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// WiseRatesToTargets fetches the daily mid-market rates of pairs in the date range once and syncs them into each target, recording a journal entry in each
+func WiseRatesToTargets(ctx context.Context, targets []Target, c wiseapi.Client, pairs []wiseapi.Pair, startDate, endDate time.Time) error {
+
+	var rates []wiseapi.Rate
+	var fetchErr error
+	for _, pair := range pairs {
+		pairRates, err := c.GetApiRates(ctx, pair, startDate, endDate)
+		if err != nil {
+			fetchErr = fmt.Errorf("c.GetApiRates failed for %s: %w", pair, err)
+			break
+		}
+		rates = append(rates, pairRates...)
+	}
+
+	params := WiseRatesParams(pairs, startDate, endDate)
+	return toTargets(ctx, targets, DatasetWiseRates, params, func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyWiseRates(ctx, db, c, rates, startDate, endDate)
+	})
+}
+
+// WiseRateTicksToTargets fetches the hourly mid-market rates of pairs in the date range once and writes them as ticks, see TicksToTargets
+func WiseRateTicksToTargets(ctx context.Context, targets []Target, sinks sink.Router, c wiseapi.Client, pairs []wiseapi.Pair, startDate, endDate time.Time) error {
+
+	var ticks []sink.Tick
+	var fetchErr error
+	for _, pair := range pairs {
+		pairTicks, err := c.GetApiIntradayRates(ctx, pair, startDate, endDate)
+		if err != nil {
+			fetchErr = fmt.Errorf("c.GetApiIntradayRates failed for %s: %w", pair, err)
+			break
+		}
+		ticks = append(ticks, pairTicks...)
+	}
+
+	params := WiseRatesParams(pairs, startDate, endDate)
+	return TicksToTargets(ctx, targets, sinks, c.InfoLog, DatasetWiseRateTicks, params, ticks, fetchErr)
+}
+
+// WiseRatesParams returns the journal params of a WiseRates run
+func WiseRatesParams(pairs []wiseapi.Pair, startDate, endDate time.Time) string {
+
+	pairStrs := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		pairStrs = append(pairStrs, pair.String())
+	}
+	return fmt.Sprintf("pairs=%s from=%s to=%s", strings.Join(pairStrs, ","), startDate.Format(lystype.DateFormat), endDate.Format(lystype.DateFormat))
+}
+
+// ApplyWiseRates inserts or updates the rates of db in the date range with already fetched API rates. c is only used for logging
+// rates of pairs which are no longer synced are kept
+func ApplyWiseRates(ctx context.Context, db *pgxpool.Pool, c wiseapi.Client, rates []wiseapi.Rate, startDate, endDate time.Time) error {
+
+	itemStore := wiserate.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	apiItemsMap := wiseapi.RatesToMap(rates)
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx, startDate, endDate)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	newItems := []wiserate.Input{}
+	updatedItems := make(map[int64]wiserate.Input)
+
+	for key, apiItem := range apiItemsMap {
+		dbItem, ok := dbItemsMap[key]
+		if !ok {
+			newItems = append(newItems, apiItem.Input)
+			continue
+		}
+		if !itemStore.Equal(apiItem, dbItem) {
+			updatedItems[dbItem.Id] = apiItem.Input
+		}
+	}
+
+	if len(newItems) > 0 {
+		if _, err = itemStore.BulkInsert(ctx, newItems); err != nil {
+			return fmt.Errorf("itemStore.BulkInsert failed: %w", err)
+		}
+	}
+	inputs, ids := splitUpdates(updatedItems)
+	if err = itemStore.BulkUpdate(ctx, inputs, ids); err != nil {
+		return fmt.Errorf("itemStore.BulkUpdate failed: %w", err)
+	}
+
+	c.InfoLog.Info("synced rates", slog.String(clog.KeyDataset, DatasetWiseRates), slog.Int("inserted", len(newItems)), slog.Int("updated", len(updatedItems)))
+
+	return nil
+}
+
+// WiseTransfersToTargets fetches the transfers of profileIds created since createdSince once and syncs them into each target, recording a journal entry in each
+// the transfers of all profiles of the account are fetched if profileIds is empty
+func WiseTransfersToTargets(ctx context.Context, targets []Target, c wiseapi.Client, profileIds []int64, createdSince time.Time) error {
+
+	transfers, fetchErr := getWiseTransfers(ctx, c, profileIds, createdSince, time.Now())
+
+	params := WiseTransfersParams(profileIds, createdSince)
+	return toTargets(ctx, targets, DatasetWiseTransfers, params, func(ctx context.Context, db *pgxpool.Pool) error {
+		if fetchErr != nil {
+			return fetchErr
+		}
+		return ApplyWiseTransfers(ctx, db, c, transfers)
+	})
+}
+
+// WiseTransfersParams returns the journal params of a WiseTransfers run
+func WiseTransfersParams(profileIds []int64, createdSince time.Time) string {
+
+	profiles := "all"
+	if len(profileIds) > 0 {
+		idStrs := make([]string, 0, len(profileIds))
+		for _, id := range profileIds {
+			idStrs = append(idStrs, strconv.FormatInt(id, 10))
+		}
+		profiles = strings.Join(idStrs, ",")
+	}
+	return fmt.Sprintf("profiles=%s createdSince=%s", profiles, createdSince.UTC().Format(time.RFC3339))
+}
+
+// getWiseTransfers fetches the transfers of profileIds, or of all profiles if empty, created in the time range
+func getWiseTransfers(ctx context.Context, c wiseapi.Client, profileIds []int64, createdStart, createdEnd time.Time) (transfers []wiseapi.Transfer, err error) {
+
+	if len(profileIds) == 0 {
+		profiles, err := c.GetApiProfiles(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("c.GetApiProfiles failed: %w", err)
+		}
+		for _, p := range profiles {
+			profileIds = append(profileIds, p.Id)
+		}
+	}
+
+	for _, profileId := range profileIds {
+		profileTransfers, err := c.GetApiTransfers(ctx, profileId, createdStart, createdEnd)
+		if err != nil {
+			return nil, fmt.Errorf("c.GetApiTransfers failed for profile %d: %w", profileId, err)
+		}
+		transfers = append(transfers, profileTransfers...)
+	}
+
+	return transfers, nil
+}
+
+// ApplyWiseTransfers inserts or updates the transfers of db with already fetched API transfers. c is only used for logging
+// the API transfers are the ones created in a time window, so transfers missing from them are not deleted
+func ApplyWiseTransfers(ctx context.Context, db *pgxpool.Pool, c wiseapi.Client, transfers []wiseapi.Transfer) error {
+
+	itemStore := wisetransfer.Store{Db: db}
+	unlock, err := lockStore(itemStore)
+	if err != nil {
+		return fmt.Errorf("lockStore failed: %w", err)
+	}
+	defer unlock()
+
+	apiItemsMap, err := wiseapi.TransfersToMap(transfers)
+	if err != nil {
+		return fmt.Errorf("wiseapi.TransfersToMap failed: %w", err)
+	}
+
+	wiseIds := make([]int64, 0, len(apiItemsMap))
+	for wiseId := range apiItemsMap {
+		wiseIds = append(wiseIds, wiseId)
+	}
+
+	dbItemsMap, err := itemStore.SelectMapByNaturalKey(ctx, wiseIds)
+	if err != nil {
+		return fmt.Errorf("itemStore.SelectMapByNaturalKey failed: %w", err)
+	}
+
+	newItems := []wisetransfer.Input{}
+	updatedItems := make(map[int64]wisetransfer.Input)
+
+	for wiseId, apiItem := range apiItemsMap {
+		dbItem, ok := dbItemsMap[wiseId]
+		if !ok {
+			newItems = append(newItems, apiItem.Input)
+			continue
+		}
+		if !itemStore.Equal(apiItem, dbItem) {
+			updatedItems[dbItem.Id] = apiItem.Input
+		}
+	}
+
+	if len(newItems) > 0 {
+		if _, err = itemStore.BulkInsert(ctx, newItems); err != nil {
+			return fmt.Errorf("itemStore.BulkInsert failed: %w", err)
+		}
+	}
+	inputs, ids := splitUpdates(updatedItems)
+	if err = itemStore.BulkUpdate(ctx, inputs, ids); err != nil {
+		return fmt.Errorf("itemStore.BulkUpdate failed: %w", err)
+	}
+
+	c.InfoLog.Info("synced transfers", slog.String(clog.KeyDataset, DatasetWiseTransfers), slog.Int("inserted", len(newItems)), slog.Int("updated", len(updatedItems)))
+
+	return nil
+}
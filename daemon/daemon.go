@@ -0,0 +1,586 @@
+package daemon
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/archive"
+	"github.com/loveyourstack/connectors/calendar"
+	"github.com/loveyourstack/connectors/changefeed"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/converter"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/derive"
+	"github.com/loveyourstack/connectors/drift"
+	"github.com/loveyourstack/connectors/freshness"
+	"github.com/loveyourstack/connectors/httpapi"
+	"github.com/loveyourstack/connectors/notify"
+	"github.com/loveyourstack/connectors/quality"
+	"github.com/loveyourstack/connectors/quota"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/registry/coingeckoconnector"
+	"github.com/loveyourstack/connectors/registry/ecbconnector"
+	"github.com/loveyourstack/connectors/sink"
+	"github.com/loveyourstack/connectors/stores/coingecko/cgprice"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbrateoverride"
+	"github.com/loveyourstack/connectors/stores/iso4217/isocurrency"
+	"github.com/loveyourstack/connectors/valuation"
+	"github.com/loveyourstack/connectors/webhook"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	defaultListenAddress string        = "localhost:8080"
+	defaultSyncInterval  time.Duration = time.Hour
+	defaultSyncDays      int           = 7
+	defaultBaseCurrency  string        = "EUR"
+	defaultMaxLagDaily   int           = 1
+)
+
+// Config contains the daemon settings
+type Config struct {
+	ListenAddress string   `toml:"listenAddress"` // HTTP listen address. Defaults to localhost:8080
+	SyncInterval  string   `toml:"syncInterval"`  // Go duration between syncs, e.g. "30m". Defaults to 1h
+	SyncDays      int      `toml:"syncDays"`      // number of days synced per run, counting back from today. Defaults to 7
+	BaseCurrency  string   `toml:"baseCurrency"`  // base currency of the rates of the period-end report, derived from the EUR rates if not EUR. Defaults to EUR
+	Fallbacks     []string `toml:"fallbacks"`     // sources tried in turn for ECB rates if the ECB is unavailable, e.g. ["frankfurter"]
+	MaxLagDaily   int      `toml:"maxLagDaily"`   // TARGET business days daily rates may trail before rates.stale is emitted. Defaults to 1
+	DrainTimeout  string   `toml:"drainTimeout"`  // Go duration that shutdown waits for an in-flight sync to commit before cancelling it. Defaults to 30s
+	OpTimeout     string   `toml:"opTimeout"`     // Go duration bounding each database operation of a sync, e.g. "5m". Defaults to none
+	AnalyzeAfter  int64    `toml:"analyzeAfter"`  // rows a bulk load must insert into a table for the table to be analyzed afterwards. Defaults to 0: never
+
+	Auth       httpapi.AuthConfig `toml:"auth"`
+	TLS        httpapi.TLSConfig  `toml:"tls"`
+	RateFormat httpapi.RateFormat `toml:"rateFormat"` // decimals of the numbers of the rate API. Written as computed by default
+
+	GraphQL bool `toml:"graphql"` // serve POST /graphql, a GraphQL endpoint over currencies, exchange rates and conversions
+
+	ReadThrough bool `toml:"readThrough"` // the rate routes fetch a daily fixing missing from Db from the ECB and sync it into Targets, e.g. while a backfill is running
+
+	RateOverrides bool `toml:"rateOverrides"` // the rate routes use the approved manual rate corrections in ecb.exchange_rate_override of Db ahead of the synced rates
+
+	Email notify.EmailConfig `toml:"email"` // email reports: the summary of recent syncs and the period-end rate sheet. Disabled if no host is set
+}
+
+// Daemon periodically syncs the datasets of the registered connectors, serves the HTTP API and emits webhook events about sync outcomes
+type Daemon struct {
+	Config     Config
+	Db         *pgxpool.Pool    // primary database, used by the HTTP API and reports. Needs only read access: syncs write to Targets
+	Targets    []csyncdb.Target // databases synced each cycle, normally including Db
+	Connectors []registry.Connector
+	EcbClient  ecbapi.Client
+	Emitters   webhook.Emitters
+	Changefeed *changefeed.Feed  // publishes the row changes of each sync and prunes the outbox if set
+	Sinks      sink.Router       // sinks of tick datasets, passed to the connectors
+	Rules      quality.Rules     // data-quality rules, passed to the connectors
+	Derived    derive.Fields     // derived fields, passed to the connectors
+	Quotas     quota.Limits      // request quotas per connector. Usage is tracked in the first target, and syncs are deferred as the limits are approached
+	Drift      *drift.Detector   // checks the API responses of the syncs for added or removed fields if set. Each change is emitted as schema.drift event
+	Archive    *archive.Archiver // archives the raw API responses of the syncs if set, and prunes them after each cycle
+	Notifier   notify.Notifier   // sends the configured reports after each cycle if set. Set by New if email is configured
+	InfoLog    *slog.Logger
+	ErrorLog   *slog.Logger
+
+	// Reconfigure returns the connectors configured anew, e.g. with rotated secrets. If set, it is called before each cycle, whose syncs then use its connectors
+	Reconfigure func() ([]registry.Connector, error)
+
+	syncInterval time.Duration
+	drainTimeout time.Duration
+	opTimeout    time.Duration
+	tlsConf      *tls.Config
+	stale        map[string]bool  // freshness outcome per target of the previous cycle, so that rates.stale is only emitted on transition
+	ecbSyncedAt  time.Time        // time of the last ECB rates sync that succeeded for all targets
+	rateCache    *converter.Cache // rates of the rate routes, reset and warmed after each ECB rates sync. Read through with Config.ReadThrough
+
+	summaryInterval time.Duration // time between sync summaries. 0: none
+	summarySentAt   time.Time     // end of the period of the last sync summary
+	periodEndMonth  time.Time     // month of the last period-end rate sheet sent, or that closed at startup
+}
+
+// New returns a Daemon syncing connectors, normally registry.All(). The ECB connector is given the configured base currency and fallbacks
+func New(conf Config, db *pgxpool.Pool, targets []csyncdb.Target, connectors []registry.Connector, c ecbapi.Client, emitters webhook.Emitters, infoLog, errorLog *slog.Logger) (*Daemon, error) {
+
+	if conf.ListenAddress == "" {
+		conf.ListenAddress = defaultListenAddress
+	}
+	if conf.SyncDays < 1 {
+		conf.SyncDays = defaultSyncDays
+	}
+	if conf.BaseCurrency == "" {
+		conf.BaseCurrency = defaultBaseCurrency
+	}
+	if conf.MaxLagDaily < 1 {
+		conf.MaxLagDaily = defaultMaxLagDaily
+	}
+
+	// validate fallback names now rather than on every sync
+	if _, err := ecbconnector.NewFallbacks(conf.Fallbacks, infoLog, errorLog); err != nil {
+		return nil, fmt.Errorf("ecbconnector.NewFallbacks failed: %w", err)
+	}
+
+	syncInterval := defaultSyncInterval
+	if conf.SyncInterval != "" {
+		var err error
+		syncInterval, err = time.ParseDuration(conf.SyncInterval)
+		if err != nil {
+			return nil, fmt.Errorf("time.ParseDuration failed for syncInterval: %w", err)
+		}
+		if syncInterval < time.Minute {
+			return nil, fmt.Errorf("syncInterval must be at least 1m")
+		}
+	}
+
+	var drainTimeout time.Duration
+	if conf.DrainTimeout != "" {
+		var err error
+		drainTimeout, err = time.ParseDuration(conf.DrainTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("time.ParseDuration failed for drainTimeout: %w", err)
+		}
+	}
+
+	var opTimeout time.Duration
+	if conf.OpTimeout != "" {
+		var err error
+		opTimeout, err = time.ParseDuration(conf.OpTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("time.ParseDuration failed for opTimeout: %w", err)
+		}
+	}
+
+	tlsConf, err := conf.TLS.Build()
+	if err != nil {
+		return nil, fmt.Errorf("conf.TLS.Build failed: %w", err)
+	}
+	if err = conf.RateFormat.Validate(); err != nil {
+		return nil, fmt.Errorf("conf.RateFormat.Validate failed: %w", err)
+	}
+
+	var notifier notify.Notifier
+	var summaryInterval time.Duration
+	if conf.Email.Enabled() {
+		email, err := notify.NewEmail(conf.Email)
+		if err != nil {
+			return nil, fmt.Errorf("notify.NewEmail failed: %w", err)
+		}
+		notifier = email
+		if conf.Email.SyncSummary != "" {
+			// already validated by NewEmail
+			summaryInterval, _ = time.ParseDuration(conf.Email.SyncSummary)
+		}
+	}
+
+	now := time.Now()
+	d := &Daemon{
+		Config:       conf,
+		Db:           db,
+		Targets:      targets,
+		Connectors:   withEcbSettings(conf, connectors),
+		EcbClient:    c,
+		Emitters:     emitters,
+		InfoLog:      infoLog,
+		ErrorLog:     errorLog,
+		syncInterval: syncInterval,
+		drainTimeout: drainTimeout,
+		opTimeout:    opTimeout,
+		tlsConf:      tlsConf,
+		stale:        make(map[string]bool),
+		rateCache:    converter.NewCache(ecbexchangerate.Store{Db: db}),
+		Notifier:     notifier,
+
+		summaryInterval: summaryInterval,
+		summarySentAt:   now,
+		periodEndMonth:  closedMonth(now),
+	}
+
+	if conf.ReadThrough {
+		d.rateCache = converter.NewCache(converter.NewReadThrough(ecbexchangerate.Store{Db: db}, converter.FetcherFunc(d.fetchRates)))
+	}
+
+	return d, nil
+}
+
+// fetchRates syncs the daily EUR rates of the date range into d.Targets, for the read-through of the rate routes
+// it reads d.Rules and d.Derived when called, since they are set after New
+func (d *Daemon) fetchRates(ctx context.Context, startDate, endDate time.Time) error {
+	d.InfoLog.Info("fetching missing rates", "start_date", startDate.Format(time.DateOnly), "end_date", endDate.Format(time.DateOnly))
+	return csyncdb.EcbRateFetcher(d.Targets, d.EcbClient, d.Rules.For(csyncdb.DatasetEcbExchangeRates), d.Derived.For(csyncdb.DatasetEcbExchangeRates))(ctx, startDate, endDate)
+}
+
+// withEcbSettings returns a copy of connectors, so that the caller's slice is not modified, with the ECB connector given the daily frequency and the configured fallbacks
+func withEcbSettings(conf Config, connectors []registry.Connector) []registry.Connector {
+
+	conns := make([]registry.Connector, len(connectors))
+	for i, conn := range connectors {
+		if ecbConn, ok := conn.(ecbconnector.Connector); ok {
+			ecbConn.Freq = ecbapi.Daily
+			ecbConn.Fallbacks = conf.Fallbacks
+			conn = ecbConn
+		}
+		conns[i] = conn
+	}
+	return conns
+}
+
+// DrainTimeout returns the configured drain timeout, or 0 to use the cruntime default
+func (d *Daemon) DrainTimeout() time.Duration {
+	return d.drainTimeout
+}
+
+// Handler returns the HTTP handler served by the daemon
+func (d *Daemon) Handler() http.Handler {
+
+	mux := http.NewServeMux()
+	httpapi.AddHealthRoutes(mux, []httpapi.Check{
+		httpapi.DbCheck(d.Db),
+		httpapi.SchemaCheck(d.Db, "ecb", []string{"currency", "exchange_rate"}),
+		httpapi.EcbCheck(d.EcbClient),
+	})
+	httpapi.AddRateRoutes(mux, d.converter(), d.Config.RateFormat, d.ErrorLog)
+	httpapi.AddDatasetRoutes(mux, d.Connectors)
+	httpapi.AddContractRoutes(mux, d.Connectors, d.Db, d.ErrorLog)
+	httpapi.AddExportRoutes(mux, d.Db, d.ErrorLog)
+	if d.Config.GraphQL {
+		httpapi.AddGraphQLRoutes(mux, d.Db, d.converter(), d.Config.RateFormat, d.ErrorLog)
+	}
+
+	// the routes of receiving connectors authenticate their own requests, e.g. by webhook signature, so are served without the API credentials
+	outer := http.NewServeMux()
+	outer.Handle("/", httpapi.RequireAuth(d.Config.Auth, mux))
+	for _, conn := range d.Connectors {
+		if r, ok := conn.(registry.Receiver); ok && registry.IsEnabled(conn) {
+			r.AddRoutes(outer, d.Targets, d.InfoLog, d.ErrorLog)
+		}
+	}
+
+	return httpapi.LogRequests(d.InfoLog, outer)
+}
+
+// converter returns the converter of the rate routes. Coin symbols are accepted if the CoinGecko connector is synced
+func (d *Daemon) converter() converter.Converter {
+
+	conv := converter.Converter{Db: d.Db}
+	if d.rateCache != nil {
+		conv.Store = d.rateCache
+	}
+	if d.Config.RateOverrides {
+		conv.Overrides = ecbrateoverride.Store{Db: d.Db}
+	}
+	for _, conn := range d.Connectors {
+		if conn.Name() == coingeckoconnector.Name && registry.IsEnabled(conn) {
+			conv.Crypto = cgprice.Store{Db: d.Db}
+		}
+	}
+	return conv
+}
+
+// Start registers the HTTP server, webhook emitters and sync scheduler with rt. Call rt.Wait to run until shutdown
+func (d *Daemon) Start(rt *cruntime.Coordinator) {
+
+	if d.Drift != nil {
+		d.Drift.OnDrift = d.emitSchemaDrift
+	}
+
+	rt.Go("webhooks", func(ctx context.Context) error {
+		d.Emitters.Run(ctx)
+		return nil
+	})
+
+	srv := &http.Server{
+		Addr:              d.Config.ListenAddress,
+		Handler:           d.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig:         d.tlsConf,
+	}
+	rt.Go("http", func(ctx context.Context) error {
+		d.InfoLog.Info("listening", "address", d.Config.ListenAddress, "tls", d.tlsConf != nil, "auth", d.Config.Auth.Enabled())
+		var err error
+		if d.tlsConf != nil {
+			// certificates are already loaded into srv.TLSConfig
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	})
+	rt.OnShutdown("http", srv.Shutdown)
+
+	rt.Go("scheduler", func(ctx context.Context) error {
+
+		// minor units synced by an earlier run are used before the first sync of this one
+		d.loadMinorUnits(ctx)
+
+		ticker := time.NewTicker(d.syncInterval)
+		defer ticker.Stop()
+
+		for {
+			// the sync cycle runs as in-flight work: a shutdown signal lets it finish, bounded by the drain timeout
+			err := rt.RunWork(func(workCtx context.Context) error {
+				d.syncCycle(ctx, workCtx)
+				return nil
+			})
+			if errors.Is(err, cruntime.ErrShuttingDown) {
+				return nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// syncCycle syncs each dataset of each connector in turn, then checks the freshness of ECB daily rates, emitting webhook events for each outcome, and sends the reports that are due
+// each dataset sync runs with workCtx so that a started sync is committed during shutdown. No further sync is started once stopCtx is cancelled
+// each database operation of a sync is bounded by the configured opTimeout, and large bulk loads are followed by ANALYZE as set by analyzeAfter
+func (d *Daemon) syncCycle(stopCtx, ctx context.Context) {
+
+	ctx = cruntime.WithOpTimeout(ctx, d.opTimeout)
+	ctx = cruntime.WithAnalyzeAfter(ctx, d.Config.AnalyzeAfter)
+
+	if d.Reconfigure != nil {
+		conns, err := d.Reconfigure()
+		if err != nil {
+			// keep syncing with the previous configuration
+			d.ErrorLog.Error("d.Reconfigure failed", clog.KeyError, err.Error())
+		} else {
+			d.Connectors = withEcbSettings(d.Config, conns)
+		}
+	}
+
+	for _, conn := range d.Connectors {
+
+		if !registry.IsEnabled(conn) {
+			continue
+		}
+
+		for _, ds := range conn.Datasets() {
+
+			if stopCtx.Err() != nil {
+				return
+			}
+
+			// on TARGET closing days the ECB publishes no fixing: once the last one is synced, there is nothing new to fetch
+			if ds.Name == csyncdb.DatasetEcbExchangeRates && d.ecbRatesCurrent(time.Now()) {
+				d.InfoLog.Debug("skipped sync on TARGET closing day", clog.KeyDataset, ds.Name)
+				continue
+			}
+
+			if d.quotaDefers(ctx, conn.Name(), ds.Name) {
+				continue
+			}
+
+			params := ""
+			if ds.Name == csyncdb.DatasetEcbExchangeRates {
+				endDate := time.Now()
+				startDate := endDate.AddDate(0, 0, -d.Config.SyncDays)
+				if ecbConn, ok := conn.(ecbconnector.Connector); ok {
+					// the same window as the connector's sync, unless it fails, in which case so does the sync
+					if s, e, err := ecbConn.RatesWindow(ctx, d.Targets, d.Config.SyncDays, endDate); err == nil {
+						startDate, endDate = s, e
+					}
+				}
+				params = csyncdb.EcbExchangeRatesParams("EUR", ecbapi.Daily, startDate, endDate)
+			}
+
+			deps := registry.Deps{
+				Targets:  d.Targets,
+				Datasets: []string{ds.Name},
+				Days:     d.Config.SyncDays,
+				Sinks:    d.Sinks,
+				Rules:    d.Rules,
+				Derived:  d.Derived,
+				Quota:    d.quotaTracker().Meter(conn.Name()),
+				Drift:    d.Drift.Guard(conn.Name()),
+				Archive:  d.Archive.Recorder(conn.Name(), ds.Name),
+				InfoLog:  d.InfoLog,
+				ErrorLog: d.ErrorLog,
+			}
+			err := conn.Sync(ctx, deps)
+			if err != nil {
+				d.ErrorLog.Error("conn.Sync failed", "connector", conn.Name(), clog.KeyDataset, ds.Name, clog.KeyError, err.Error())
+				d.emitSyncFailed(ds.Name, params, err)
+			}
+			if err := d.quotaTracker().Record(ctx, conn.Name(), deps.Quota, time.Now()); err != nil {
+				d.ErrorLog.Error("quota.Tracker.Record failed", "connector", conn.Name(), clog.KeyError, err.Error())
+			}
+			if err := deps.Archive.Finish(ctx); err != nil {
+				d.ErrorLog.Error("deps.Archive.Finish failed", "connector", conn.Name(), clog.KeyError, err.Error())
+			}
+			d.publishChanges(ctx)
+
+			if ds.Name == csyncdb.DatasetIso4217Currencies && err == nil {
+				d.loadMinorUnits(ctx)
+			}
+			if ds.Name != csyncdb.DatasetEcbExchangeRates {
+				continue
+			}
+			if err == nil {
+				d.ecbSyncedAt = time.Now()
+			}
+			d.afterEcbRatesSync(ctx, err)
+
+			// freshness per target, skipping targets whose sync failed
+			for _, t := range d.Targets {
+				if _, failed := csyncdb.FindTargetError(err, t.Name); failed {
+					continue
+				}
+				d.checkFreshness(ctx, t, params)
+			}
+		}
+	}
+
+	d.pruneOutbox(ctx)
+	if _, err := d.Archive.Prune(ctx); err != nil {
+		d.ErrorLog.Error("d.Archive.Prune failed", clog.KeyError, err.Error())
+	}
+	d.sendReports(ctx, time.Now())
+}
+
+// afterEcbRatesSync refreshes the latest rates view of each target whose sync succeeded, then resets the rate cache and loads the latest fixing into it
+// so that the first conversion requests after a sync do not all query the database at once
+func (d *Daemon) afterEcbRatesSync(ctx context.Context, syncErr error) {
+
+	for _, t := range d.Targets {
+		if _, failed := csyncdb.FindTargetError(syncErr, t.Name); failed {
+			continue
+		}
+		if err := (ecbexchangerate.Store{Db: t.Db}).RefreshLatest(ctx); err != nil {
+			d.ErrorLog.Error("ecbexchangerate.Store.RefreshLatest failed", "target", t.Name, clog.KeyError, err.Error())
+		}
+	}
+
+	if err := d.converter().Warm(ctx); err != nil {
+		d.ErrorLog.Error("converter.Warm failed", clog.KeyError, err.Error())
+	}
+}
+
+// loadMinorUnits loads the ISO 4217 minor units synced into Db, which valuation and the rate routes then round amounts to
+// the built-in minor units are kept if none are synced, or if the iso4217 connector was never migrated
+func (d *Daemon) loadMinorUnits(ctx context.Context) {
+
+	count, err := valuation.LoadMinorUnits(ctx, isocurrency.Store{Db: d.Db})
+	if err != nil {
+		d.ErrorLog.Error("valuation.LoadMinorUnits failed", clog.KeyDataset, csyncdb.DatasetIso4217Currencies, clog.KeyError, err.Error())
+		return
+	}
+	if count > 0 {
+		d.InfoLog.Info("loaded minor units", clog.KeyDataset, csyncdb.DatasetIso4217Currencies, "count", count)
+	}
+}
+
+// ecbRatesCurrent returns true if now is on a TARGET closing day and ECB rates were synced into all targets since the last fixing was published
+func (d *Daemon) ecbRatesCurrent(now time.Time) bool {
+	return !calendar.IsBusinessDay(now.In(calendar.Location)) && d.ecbSyncedAt.After(freshness.LastDailyPublication(now))
+}
+
+// quotaTracker returns the tracker of the request quotas, recording usage in the first target
+func (d *Daemon) quotaTracker() quota.Tracker {
+
+	t := quota.Tracker{Limits: d.Quotas, InfoLog: d.InfoLog}
+	if len(d.Targets) > 0 {
+		t.Db = d.Targets[0].Db
+	}
+	return t
+}
+
+// quotaDefers returns true if the sync of dataset is deferred to the next quota window of its connector, since the requests used approach the limit
+// if the usage can't be read, the sync is not deferred
+func (d *Daemon) quotaDefers(ctx context.Context, connector, dataset string) bool {
+
+	u, ok, err := d.quotaTracker().Usage(ctx, connector, time.Now())
+	if err != nil {
+		d.ErrorLog.Error("quota.Tracker.Usage failed", "connector", connector, clog.KeyError, err.Error())
+		return false
+	}
+	if !ok || !u.Defer(dataset) {
+		return false
+	}
+
+	d.InfoLog.Warn("deferred sync: request quota nearly used", "connector", connector, clog.KeyDataset, dataset, "used", u.Used, "quota", u.Limit.Requests)
+	return true
+}
+
+// publishChanges publishes the pending row changes of each target to the change feed, if set
+func (d *Daemon) publishChanges(ctx context.Context) {
+
+	if d.Changefeed == nil {
+		return
+	}
+	for _, t := range d.Targets {
+		if _, err := d.Changefeed.Publish(ctx, t.Name, t.Db); err != nil {
+			d.ErrorLog.Error("d.Changefeed.Publish failed", "target", t.Name, clog.KeyError, err.Error())
+		}
+	}
+}
+
+// pruneOutbox deletes the outbox rows of each target older than the change feed's retention, if set
+func (d *Daemon) pruneOutbox(ctx context.Context) {
+
+	if d.Changefeed == nil {
+		return
+	}
+	for _, t := range d.Targets {
+		if _, err := d.Changefeed.PruneOutbox(ctx, t.Name, t.Db); err != nil {
+			d.ErrorLog.Error("d.Changefeed.PruneOutbox failed", "target", t.Name, clog.KeyError, err.Error())
+		}
+	}
+}
+
+// checkFreshness emits rates.synced for target t, and rates.stale if its daily rates became stale
+func (d *Daemon) checkFreshness(ctx context.Context, t csyncdb.Target, params string) {
+
+	a, err := freshness.EcbExchangeRates(ctx, t.Db, ecbapi.Daily, time.Now(), d.Config.MaxLagDaily)
+	if err != nil {
+		d.ErrorLog.Error("freshness.EcbExchangeRates failed", "target", t.Name, clog.KeyDataset, csyncdb.DatasetEcbExchangeRates, clog.KeyError, err.Error())
+		return
+	}
+
+	latestDay := ""
+	if !a.LatestDay.IsZero() {
+		latestDay = a.LatestDay.Format(lystype.DateFormat)
+	}
+	d.Emitters.Emit(webhook.EventRatesSynced, webhook.RatesSyncedData{Target: t.Name, Dataset: csyncdb.DatasetEcbExchangeRates, Params: params, LatestDay: latestDay})
+
+	stale := a.Status != freshness.Fresh
+	if stale && !d.stale[t.Name] {
+		d.InfoLog.Warn("exchange rates are stale", "target", t.Name, clog.KeyDataset, csyncdb.DatasetEcbExchangeRates, "latest_day", latestDay, "lag", a.Lag)
+		d.Emitters.Emit(webhook.EventRatesStale, webhook.RatesStaleData{
+			Target:      t.Name,
+			Dataset:     csyncdb.DatasetEcbExchangeRates,
+			LatestDay:   latestDay,
+			ExpectedDay: a.ExpectedDay.Format(lystype.DateFormat),
+			Lag:         a.Lag,
+		})
+	}
+	d.stale[t.Name] = stale
+}
+
+// emitSyncFailed emits sync.failed for each failed target in err
+func (d *Daemon) emitSyncFailed(dataset, params string, err error) {
+
+	for _, t := range d.Targets {
+		if targetErr, ok := csyncdb.FindTargetError(err, t.Name); ok {
+			d.Emitters.Emit(webhook.EventSyncFailed, webhook.SyncFailedData{Target: t.Name, Dataset: dataset, Params: params, Error: targetErr.Err.Error()})
+		}
+	}
+}
+
+// emitSchemaDrift emits schema.drift for w
+func (d *Daemon) emitSchemaDrift(w drift.Warning) {
+	d.Emitters.Emit(webhook.EventSchemaDrift, webhook.SchemaDriftData{Connector: w.Connector, Endpoint: w.Endpoint, Added: w.Added, Removed: w.Removed})
+}
@@ -0,0 +1,168 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/calendar"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/converter"
+	"github.com/loveyourstack/connectors/export"
+	"github.com/loveyourstack/connectors/freshness"
+	"github.com/loveyourstack/connectors/notify"
+	"github.com/loveyourstack/connectors/stores/connectors/syncrun"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+)
+
+// reportRun is a sync run of a target, as listed in the sync summary
+type reportRun struct {
+	target string
+	syncrun.Model
+}
+
+// sendReports sends the reports of the notifier that are due at now, if set: the summary of the sync runs since the last one, and the rate sheet of the month
+// that closed most recently. A report that fails to send is retried after the next cycle
+func (d *Daemon) sendReports(ctx context.Context, now time.Time) {
+
+	if d.Notifier == nil {
+		return
+	}
+
+	if d.summaryInterval > 0 && !now.Before(d.summarySentAt.Add(d.summaryInterval)) {
+		if err := d.sendSyncSummary(ctx, now); err != nil {
+			d.ErrorLog.Error("d.sendSyncSummary failed", clog.KeyError, err.Error())
+		}
+	}
+
+	if d.Config.Email.PeriodEnd {
+		if err := d.sendPeriodEnd(ctx, now); err != nil {
+			d.ErrorLog.Error("d.sendPeriodEnd failed", clog.KeyError, err.Error())
+		}
+	}
+}
+
+// sendSyncSummary sends the sync runs of each target started since the last summary, as a CSV attachment, with the failed runs listed in the body
+func (d *Daemon) sendSyncSummary(ctx context.Context, now time.Time) error {
+
+	var runs []reportRun
+	for _, t := range d.Targets {
+		items, err := syncrun.Store{Db: t.Db}.SelectStartedSince(ctx, d.summarySentAt)
+		if err != nil {
+			return fmt.Errorf("syncrun.Store.SelectStartedSince failed for target %s: %w", t.Name, err)
+		}
+		for _, item := range items {
+			runs = append(runs, reportRun{target: t.Name, Model: item})
+		}
+	}
+
+	csvData, err := syncSummaryCsv(runs)
+	if err != nil {
+		return fmt.Errorf("syncSummaryCsv failed: %w", err)
+	}
+
+	var failed []string
+	for _, run := range runs {
+		if run.Status == syncrun.StatusFailed {
+			failed = append(failed, fmt.Sprintf("- %s on %s at %s: %s", run.Dataset, run.target, time.Time(run.StartedAt).UTC().Format(time.RFC3339), run.Error))
+		}
+	}
+
+	subject := fmt.Sprintf("Sync summary: %d runs succeeded", len(runs))
+	if len(failed) > 0 {
+		subject = fmt.Sprintf("Sync summary: %d of %d runs failed", len(failed), len(runs))
+	}
+	body := fmt.Sprintf("%d sync runs started between %s and %s.\n", len(runs), d.summarySentAt.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339))
+	if len(failed) > 0 {
+		body += "\nFailed runs:\n" + strings.Join(failed, "\n") + "\n"
+	}
+
+	err = d.Notifier.Notify(ctx, notify.Message{
+		Subject:     subject,
+		Body:        body,
+		Attachments: []notify.Attachment{{Filename: "sync_summary.csv", ContentType: "text/csv", Data: csvData}},
+	})
+	if err != nil {
+		return fmt.Errorf("d.Notifier.Notify failed: %w", err)
+	}
+
+	d.summarySentAt = now
+	d.InfoLog.Info("sent sync summary", clog.KeyCount, len(runs))
+	return nil
+}
+
+func syncSummaryCsv(runs []reportRun) ([]byte, error) {
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"target", "dataset", "params", "status", "started_at", "finished_at", "duration_s", "error"})
+
+	for _, run := range runs {
+		finishedAt, duration := "", ""
+		if run.FinishedAt != nil {
+			finishedAt = time.Time(*run.FinishedAt).UTC().Format(time.RFC3339)
+			duration = fmt.Sprintf("%.1f", time.Time(*run.FinishedAt).Sub(time.Time(run.StartedAt)).Seconds())
+		}
+		_ = w.Write([]string{run.target, run.Dataset, run.Params, run.Status, time.Time(run.StartedAt).UTC().Format(time.RFC3339), finishedAt, duration, run.Error})
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("w.Flush failed: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// closedMonth returns the first day of the month that closed most recently at now: the month before that of the latest published ECB fixing
+func closedMonth(now time.Time) time.Time {
+	latest := freshness.ExpectedLatestDaily(now)
+	return time.Date(latest.Year(), latest.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+}
+
+// sendPeriodEnd sends the sheet of the daily ECB rates of the month that closed most recently, once its last fixing is synced into all targets
+// each month is sent once by the daemon running when it closes: the month closed at startup is not sent
+func (d *Daemon) sendPeriodEnd(ctx context.Context, now time.Time) error {
+
+	month := closedMonth(now)
+	if !month.After(d.periodEndMonth) {
+		return nil
+	}
+
+	// the last fixing of the month is the latest one published before midnight of the next month's first day
+	nextMonth := month.AddDate(0, 1, 0)
+	lastPublication := freshness.LastDailyPublication(time.Date(nextMonth.Year(), nextMonth.Month(), 1, 0, 0, 0, 0, calendar.Location))
+	if !d.ecbSyncedAt.After(lastPublication) {
+		return nil
+	}
+
+	items, err := converter.Converter{Db: d.Db}.RangeRates(ctx, d.Config.BaseCurrency, ecbexchangerate.Daily, month, nextMonth.AddDate(0, 0, -1))
+	if err != nil {
+		return fmt.Errorf("converter.Converter.RangeRates failed: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err = export.WriteExchangeRates(&buf, items, export.XLSX); err != nil {
+		return fmt.Errorf("export.WriteExchangeRates failed: %w", err)
+	}
+
+	period := month.Format("2006-01")
+	err = d.Notifier.Notify(ctx, notify.Message{
+		Subject: fmt.Sprintf("Period-end rates %s", period),
+		Body:    fmt.Sprintf("ECB reference rates from %s for %s: the Summary sheet has the rate of each pair at month end, the other sheets the daily rates.\n", d.Config.BaseCurrency, period),
+		Attachments: []notify.Attachment{{
+			Filename:    fmt.Sprintf("exchange_rates_%s.%s", period, export.XLSX.Ext()),
+			ContentType: export.XLSX.ContentType(),
+			Data:        buf.Bytes(),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("d.Notifier.Notify failed: %w", err)
+	}
+
+	d.periodEndMonth = month
+	d.InfoLog.Info("sent period-end rates", "period", period, clog.KeyCount, len(items))
+	return nil
+}
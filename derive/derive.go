@@ -0,0 +1,209 @@
+// Package derive computes derived fields of synced rows, e.g. the inverse of a rate or its change from the previous day, with registered transforms
+// configured per dataset, so that the values are stored by the sync itself rather than by a separate post-processing job
+package derive
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// Row is a row of a dataset as seen by the transforms
+type Row struct {
+	Series string             // the series of the row, e.g. "EUR/USD", within which rows are compared with the previous day
+	Day    time.Time          // the day of the row
+	Values map[string]float64 // the numeric values of the fields the transforms may read, by name
+}
+
+// Transform returns the value of a field derived from the source field of row. prev is the previous row of its series, or nil for the first row
+// ok is false if the row has no value, e.g. for a change without a previous row, or an inverse of 0
+type Transform func(source string, row Row, prev *Row) (value float64, ok bool)
+
+// names of the built-in transforms
+const (
+	Inverse   string = "inverse"    // 1 / source, e.g. the rate of the inverse pair
+	Change    string = "change"     // source - source of the previous row
+	ChangePct string = "change_pct" // relative change from the previous row, in percent
+	ChangeBp  string = "change_bp"  // relative change from the previous row, in basis points
+)
+
+var (
+	transformsMu sync.RWMutex
+	transforms   = map[string]Transform{
+		Inverse:   inverse,
+		Change:    change,
+		ChangePct: relativeChange(100),
+		ChangeBp:  relativeChange(10000),
+	}
+)
+
+// Register makes t available to Field configs as name, e.g. in the init func of a main package. It panics if name is empty or already registered
+func Register(name string, t Transform) {
+
+	transformsMu.Lock()
+	defer transformsMu.Unlock()
+
+	if name == "" || t == nil {
+		panic("derive: Register of empty name or nil transform")
+	}
+	if _, ok := transforms[name]; ok {
+		panic("derive: Register called twice for transform " + name)
+	}
+	transforms[name] = t
+}
+
+// Lookup returns the transform registered as name
+func Lookup(name string) (Transform, bool) {
+
+	transformsMu.RLock()
+	defer transformsMu.RUnlock()
+
+	t, ok := transforms[name]
+	return t, ok
+}
+
+// Names returns the sorted names of the registered transforms
+func Names() []string {
+
+	transformsMu.RLock()
+	defer transformsMu.RUnlock()
+
+	names := make([]string, 0, len(transforms))
+	for name := range transforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Field is a field derived from a field of the rows of a dataset
+type Field struct {
+	Name      string `toml:"name" json:"name"`           // of the derived field, e.g. inverse_rate
+	Transform string `toml:"transform" json:"transform"` // name of a registered transform, e.g. inverse
+	Source    string `toml:"source" json:"source"`       // the field read by the transform, e.g. rate
+}
+
+// Fields are the derived fields of each dataset, keyed by dataset name, as in the [[derive."ecb.exchange_rate"]] config tables
+type Fields map[string][]Field
+
+// For returns the derived fields of dataset
+func (f Fields) For(dataset string) []Field {
+	return f[dataset]
+}
+
+// Validate returns an error naming the first invalid field
+func (f Fields) Validate() error {
+
+	for dataset, fields := range f {
+		names := make(map[string]bool, len(fields))
+		for i, field := range fields {
+			if err := field.Validate(); err != nil {
+				return fmt.Errorf("%s field %d: %w", dataset, i, err)
+			}
+			if names[field.Name] {
+				return fmt.Errorf("%s field %d: %w: duplicate name: %s", dataset, i, cerrors.ErrValidationFailed, field.Name)
+			}
+			names[field.Name] = true
+		}
+	}
+	return nil
+}
+
+// Validate returns an error if f is incomplete or its transform is not registered
+func (f Field) Validate() error {
+
+	if f.Name == "" || f.Source == "" {
+		return fmt.Errorf("%w: name and source are required", cerrors.ErrValidationFailed)
+	}
+	if _, ok := Lookup(f.Transform); !ok {
+		return fmt.Errorf("%w: unknown transform: '%s', expected one of %s", cerrors.ErrValidationFailed, f.Transform, strings.Join(Names(), ", "))
+	}
+	return nil
+}
+
+// Value is the value of a derived field of a row
+type Value struct {
+	Series string
+	Day    time.Time
+	Field  string
+	Value  float64
+}
+
+// Compute returns the values of fields for the rows on or after from. The rows before from are only the previous rows of the first ones, e.g. the stored rows before
+// the sync window, so that the first day of the window has a change too. rows are compared in day order within each series, whatever their order
+// a field whose source is missing from the Values of a row returns an error matching cerrors.ErrValidationFailed, since the field does not fit the dataset
+func Compute(fields []Field, rows []Row, from time.Time) (values []Value, err error) {
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	sorted := slices.Clone(rows)
+	slices.SortStableFunc(sorted, func(a, b Row) int {
+		return cmp.Or(cmp.Compare(a.Series, b.Series), a.Day.Compare(b.Day))
+	})
+
+	for _, f := range fields {
+		t, ok := Lookup(f.Transform)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown transform: '%s'", cerrors.ErrValidationFailed, f.Transform)
+		}
+
+		for i, row := range sorted {
+			if _, ok := row.Values[f.Source]; !ok {
+				return nil, fmt.Errorf("%w: field %s: unknown source: %s", cerrors.ErrValidationFailed, f.Name, f.Source)
+			}
+			if row.Day.Before(from) {
+				continue
+			}
+
+			var prev *Row
+			if i > 0 && sorted[i-1].Series == row.Series {
+				prev = &sorted[i-1]
+			}
+
+			v, ok := t(f.Source, row, prev)
+			if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			values = append(values, Value{Series: row.Series, Day: row.Day, Field: f.Name, Value: v})
+		}
+	}
+
+	return values, nil
+}
+
+func inverse(source string, row Row, prev *Row) (float64, bool) {
+
+	v := row.Values[source]
+	if v == 0 {
+		return 0, false
+	}
+	return 1 / v, true
+}
+
+func change(source string, row Row, prev *Row) (float64, bool) {
+
+	if prev == nil {
+		return 0, false
+	}
+	return row.Values[source] - prev.Values[source], true
+}
+
+// relativeChange returns the transform of the change relative to the previous value, multiplied by scale
+func relativeChange(scale float64) Transform {
+	return func(source string, row Row, prev *Row) (float64, bool) {
+
+		if prev == nil || prev.Values[source] == 0 {
+			return 0, false
+		}
+		return (row.Values[source]/prev.Values[source] - 1) * scale, true
+	}
+}
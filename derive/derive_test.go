@@ -0,0 +1,129 @@
+package derive_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/derive"
+)
+
+var day0 = time.Date(2024, 9, 2, 0, 0, 0, 0, time.UTC)
+
+func TestCompute(t *testing.T) {
+
+	d0, d1, d2, d3 := day0, day0.AddDate(0, 0, 1), day0.AddDate(0, 0, 2), day0.AddDate(0, 0, 3)
+
+	// out of order, and EUR/USD has a row of d0 before the window
+	rows := []derive.Row{
+		{Series: "EUR/USD", Day: d2, Values: map[string]float64{"rate": 1.0}},
+		{Series: "EUR/GBP", Day: d1, Values: map[string]float64{"rate": 0.8}},
+		{Series: "EUR/USD", Day: d0, Values: map[string]float64{"rate": 1.0}},
+		{Series: "EUR/USD", Day: d1, Values: map[string]float64{"rate": 1.25}},
+		{Series: "EUR/GBP", Day: d2, Values: map[string]float64{"rate": 0}},
+		{Series: "EUR/GBP", Day: d3, Values: map[string]float64{"rate": 0.5}},
+	}
+
+	tests := []struct {
+		transform string
+		want      []derive.Value
+	}{
+		{derive.Inverse, []derive.Value{ // none of 0
+			{"EUR/GBP", d1, "x", 1.25}, {"EUR/GBP", d3, "x", 2}, {"EUR/USD", d1, "x", 0.8}, {"EUR/USD", d2, "x", 1},
+		}},
+		{derive.Change, []derive.Value{ // none of the first day of EUR/GBP, which has no previous row
+			{"EUR/GBP", d2, "x", -0.8}, {"EUR/GBP", d3, "x", 0.5}, {"EUR/USD", d1, "x", 0.25}, {"EUR/USD", d2, "x", -0.25},
+		}},
+		{derive.ChangePct, []derive.Value{ // none from 0
+			{"EUR/GBP", d2, "x", -100}, {"EUR/USD", d1, "x", 25}, {"EUR/USD", d2, "x", -20},
+		}},
+		{derive.ChangeBp, []derive.Value{
+			{"EUR/GBP", d2, "x", -10000}, {"EUR/USD", d1, "x", 2500}, {"EUR/USD", d2, "x", -2000},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.transform, func(t *testing.T) {
+
+			values, err := derive.Compute([]derive.Field{{Name: "x", Transform: tt.transform, Source: "rate"}}, rows, d1)
+			if err != nil {
+				t.Fatalf("derive.Compute failed: %s", err.Error())
+			}
+			if len(values) != len(tt.want) {
+				t.Fatalf("values: got %+v, want %+v", values, tt.want)
+			}
+			for i, want := range tt.want {
+				got := values[i]
+				if got.Series != want.Series || !got.Day.Equal(want.Day) || got.Field != want.Field || math.Abs(got.Value-want.Value) > 1e-9 {
+					t.Errorf("value %d: got %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+
+	// fields are computed in order
+	values, err := derive.Compute([]derive.Field{{Name: "inverse_rate", Transform: derive.Inverse, Source: "rate"}, {Name: "rate_change", Transform: derive.Change, Source: "rate"}}, rows[:2], d0)
+	if err != nil {
+		t.Fatalf("derive.Compute failed: %s", err.Error())
+	}
+	if len(values) != 2 || values[0].Field != "inverse_rate" || values[1].Field != "inverse_rate" {
+		t.Errorf("values: got %+v, want the inverse_rate of both rows", values)
+	}
+
+	// a source the rows don't have doesn't fit the dataset
+	if _, err = derive.Compute([]derive.Field{{Name: "x", Transform: derive.Inverse, Source: "amount"}}, rows, d1); !errors.Is(err, cerrors.ErrValidationFailed) {
+		t.Errorf("derive.Compute of an unknown source: got %v, want cerrors.ErrValidationFailed", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+
+	name := "test_double"
+	if _, ok := derive.Lookup(name); !ok {
+		derive.Register(name, func(source string, row derive.Row, prev *derive.Row) (float64, bool) {
+			return 2 * row.Values[source], true
+		})
+	}
+
+	field := derive.Field{Name: "double_rate", Transform: name, Source: "rate"}
+	if err := (derive.Fields{"ecb.exchange_rate": {field}}).Validate(); err != nil {
+		t.Fatalf("Validate failed: %s", err.Error())
+	}
+	values, err := derive.Compute([]derive.Field{field}, []derive.Row{{Series: "EUR/USD", Day: day0, Values: map[string]float64{"rate": 1.1}}}, day0)
+	if err != nil {
+		t.Fatalf("derive.Compute failed: %s", err.Error())
+	}
+	if len(values) != 1 || values[0].Value != 2.2 {
+		t.Errorf("values: got %+v, want 2.2", values)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Register of a registered name: got no panic")
+			}
+		}()
+		derive.Register(derive.Inverse, func(source string, row derive.Row, prev *derive.Row) (float64, bool) { return 0, false })
+	}()
+}
+
+func TestFieldsValidate(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		fields []derive.Field
+	}{
+		{"unknown transform", []derive.Field{{Name: "x", Transform: "log", Source: "rate"}}},
+		{"no name", []derive.Field{{Transform: derive.Inverse, Source: "rate"}}},
+		{"no source", []derive.Field{{Name: "x", Transform: derive.Inverse}}},
+		{"duplicate name", []derive.Field{{Name: "x", Transform: derive.Inverse, Source: "rate"}, {Name: "x", Transform: derive.Change, Source: "rate"}}},
+	}
+
+	for _, tt := range tests {
+		if err := (derive.Fields{"ecb.exchange_rate": tt.fields}).Validate(); !errors.Is(err, cerrors.ErrValidationFailed) {
+			t.Errorf("%s: got %v, want cerrors.ErrValidationFailed", tt.name, err)
+		}
+	}
+}
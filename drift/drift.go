@@ -0,0 +1,251 @@
+// Package drift detects changes of the shape of upstream API responses: the fields seen in the JSON or CSV responses of each endpoint are recorded,
+// and a Warning is raised when fields are added or removed, so that silent upstream format changes are noticed before they corrupt synced data
+package drift
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/stores/connectors/responsefield"
+)
+
+const (
+	defaultRemovedAfter time.Duration = 7 * 24 * time.Hour
+
+	// storeInterval is the time after which the last seen time of unchanged fields is stored again, so that unchanged responses don't write on every request
+	storeInterval time.Duration = time.Hour
+)
+
+// Config contains the settings of the [drift] table
+type Config struct {
+	Enabled      bool   `toml:"enabled"`
+	RemovedAfter string `toml:"removedAfter"` // Go duration a JSON field must be missing from the responses of its endpoint for before it is reported removed. Defaults to 168h
+}
+
+// Validate returns an error if c is invalid
+func (c Config) Validate() error {
+
+	if c.RemovedAfter == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(c.RemovedAfter)
+	if err != nil {
+		return fmt.Errorf("time.ParseDuration failed for removedAfter: %w", err)
+	}
+	if d <= 0 {
+		return fmt.Errorf("removedAfter must be positive")
+	}
+	return nil
+}
+
+// Warning is raised when the fields of the responses of an endpoint change
+type Warning struct {
+	Connector string
+	Endpoint  string
+	Added     []string
+	Removed   []string
+}
+
+func (w Warning) Error() string {
+	return fmt.Sprintf("response schema of %s endpoint %s changed: added [%s], removed [%s]", w.Connector, w.Endpoint, strings.Join(w.Added, ", "), strings.Join(w.Removed, ", "))
+}
+
+// Detector records the fields of responses in Db, normally the first sync target, and raises a Warning when they change. It is safe for concurrent use
+// a nil *Detector checks nothing
+type Detector struct {
+	Db           *pgxpool.Pool
+	RemovedAfter time.Duration
+	InfoLog      *slog.Logger
+	ErrorLog     *slog.Logger
+	OnDrift      func(w Warning) // if set, called for each Warning after it is logged, e.g. to emit a webhook event
+
+	mu    sync.Mutex
+	known map[string]endpointFields // k = connector + " " + endpoint
+}
+
+// endpointFields are the fields of an endpoint not reported removed, with the time each was last seen
+type endpointFields struct {
+	lastSeen map[string]time.Time
+	storedAt time.Time // when the fields were last stored
+}
+
+// NewDetector returns a Detector recording fields in db, or nil if conf is not enabled
+func NewDetector(conf Config, db *pgxpool.Pool, infoLog, errorLog *slog.Logger) (*Detector, error) {
+
+	if !conf.Enabled {
+		return nil, nil
+	}
+	if err := conf.Validate(); err != nil {
+		return nil, fmt.Errorf("conf.Validate failed: %w", err)
+	}
+
+	removedAfter := defaultRemovedAfter
+	if conf.RemovedAfter != "" {
+		// already validated
+		removedAfter, _ = time.ParseDuration(conf.RemovedAfter)
+	}
+
+	return &Detector{
+		Db:           db,
+		RemovedAfter: removedAfter,
+		InfoLog:      infoLog,
+		ErrorLog:     errorLog,
+		known:        make(map[string]endpointFields),
+	}, nil
+}
+
+// Check compares fields, those of a response of endpoint, with the fields recorded before, and records them. complete is true if the response contains every field
+// of the endpoint, e.g. a CSV header, so that missing fields are reported removed at once rather than once they have been missing for RemovedAfter
+// the first response of an endpoint only records its fields. A returned Warning has already been logged and passed to OnDrift
+func (d *Detector) Check(ctx context.Context, connector, endpoint string, fields []string, complete bool, now time.Time) (*Warning, error) {
+
+	if d == nil {
+		return nil, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	store := responsefield.Store{Db: d.Db}
+	key := connector + " " + endpoint
+
+	known, ok := d.known[key]
+	if !ok {
+		stored, err := store.SelectCurrent(ctx, connector, endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("store.SelectCurrent failed: %w", err)
+		}
+		known = endpointFields{lastSeen: make(map[string]time.Time, len(stored))}
+		for f, m := range stored {
+			known.lastSeen[f] = m.LastSeenAt
+		}
+	}
+	baseline := len(known.lastSeen) == 0
+
+	seen := make(map[string]bool, len(fields))
+	var added, removed []string
+	for _, f := range fields {
+		seen[f] = true
+		if _, ok := known.lastSeen[f]; !ok {
+			added = append(added, f)
+		}
+	}
+	for f, lastSeen := range known.lastSeen {
+		if !seen[f] && (complete || now.Sub(lastSeen) >= d.RemovedAfter) {
+			removed = append(removed, f)
+		}
+	}
+	changed := len(added) > 0 || len(removed) > 0
+
+	if changed || now.Sub(known.storedAt) >= storeInterval {
+		if err := store.Seen(ctx, connector, endpoint, fields, now); err != nil {
+			return nil, fmt.Errorf("store.Seen failed: %w", err)
+		}
+		if err := store.MarkRemoved(ctx, connector, endpoint, removed, now); err != nil {
+			return nil, fmt.Errorf("store.MarkRemoved failed: %w", err)
+		}
+		known.storedAt = now
+	}
+
+	for _, f := range fields {
+		known.lastSeen[f] = now
+	}
+	for _, f := range removed {
+		delete(known.lastSeen, f)
+	}
+	d.known[key] = known
+
+	if !changed || baseline {
+		return nil, nil
+	}
+
+	w := Warning{Connector: connector, Endpoint: endpoint, Added: added, Removed: removed}
+	slices.Sort(w.Removed)
+	d.InfoLog.Warn("response schema changed", "connector", connector, "endpoint", endpoint, "added", w.Added, "removed", w.Removed)
+	if d.OnDrift != nil {
+		d.OnDrift(w)
+	}
+
+	return &w, nil
+}
+
+// Guard returns the guard of the responses of connector, or nil if d is nil
+func (d *Detector) Guard(connector string) *Guard {
+
+	if d == nil {
+		return nil
+	}
+	return &Guard{Detector: d, Connector: connector}
+}
+
+// Guard checks the responses of the API client of a connector
+// a nil *Guard checks nothing, so that connectors can apply the guard of registry.Deps whether or not drift detection is enabled
+type Guard struct {
+	Detector  *Detector
+	Connector string
+}
+
+// Client returns a copy of hc whose successful JSON and CSV responses are checked by g, or hc itself if g is nil
+func (g *Guard) Client(hc *http.Client) *http.Client {
+
+	if g == nil || hc == nil {
+		return hc
+	}
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	guarded := *hc
+	guarded.Transport = guardTransport{base: base, g: g}
+	return &guarded
+}
+
+// guardTransport checks the fields of responses. Checking never fails a request: errors are logged, and the body is passed on unchanged
+type guardTransport struct {
+	base http.RoundTripper
+	g    *Guard
+}
+
+func (t guardTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	format := Format(resp.Header.Get("Content-Type"))
+	if format == "" {
+		return resp, nil
+	}
+
+	// the body is read here, so is handed on from memory
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadAll failed: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	fields, err := Fields(format, body)
+	if err != nil {
+		// not parseable, e.g. an error page: left to the client
+		return resp, nil
+	}
+
+	d := t.g.Detector
+	endpoint := Endpoint(req.URL, format)
+	if _, err = d.Check(req.Context(), t.g.Connector, endpoint, fields, format == FormatCSV, time.Now()); err != nil {
+		d.ErrorLog.Error("d.Check failed", "connector", t.g.Connector, "endpoint", endpoint, clog.KeyError, err.Error())
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,152 @@
+package drift
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// response formats whose fields are checked
+const (
+	FormatJSON string = "json"
+	FormatCSV  string = "csv"
+)
+
+// versionSegment matches API version path segments, e.g. v1 or v3.1, which are kept although they contain digits
+var versionSegment = regexp.MustCompile(`^v\d+(\.\d+)?$`)
+
+// Format returns the format of a response with contentType, or "" if its fields are not checked, e.g. for zip archives
+func Format(contentType string) string {
+
+	contentType = strings.ToLower(contentType)
+	switch {
+	case strings.Contains(contentType, "json"):
+		return FormatJSON
+	case strings.Contains(contentType, "csv"):
+		return FormatCSV
+	default:
+		return ""
+	}
+}
+
+// Endpoint returns the host and path of u with variable path segments, such as ids, dates and series keys, replaced by {id}, followed by format
+// e.g. "sellingpartnerapi-eu.amazon.com/orders/v0/orders/{id}/orderItems json"
+func Endpoint(u *url.URL, format string) string {
+
+	segments := strings.Split(u.EscapedPath(), "/")
+	for i, seg := range segments {
+		if strings.ContainsFunc(seg, unicode.IsDigit) && !versionSegment.MatchString(seg) {
+			segments[i] = "{id}"
+		}
+	}
+	return u.Host + strings.Join(segments, "/") + " " + format
+}
+
+// Fields returns the sorted fields of body in format
+func Fields(format string, body []byte) ([]string, error) {
+
+	switch format {
+	case FormatJSON:
+		return JSONFields(body)
+	case FormatCSV:
+		return CSVColumns(body)
+	default:
+		return nil, fmt.Errorf("%w: unknown format '%s'", cerrors.ErrValidationFailed, format)
+	}
+}
+
+// JSONFields returns the sorted paths of the object keys of body, joined by ".", with "[]" for array elements, e.g. data[].amount
+// the elements of an array are merged. Objects keyed by data rather than field names, such as currency codes or dates, have their keys replaced by "*"
+func JSONFields(body []byte) ([]string, error) {
+
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("%w: dec.Decode failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	fieldSet := make(map[string]bool)
+	addJSONFields(v, "", fieldSet)
+
+	fields := make([]string, 0, len(fieldSet))
+	for f := range fieldSet {
+		fields = append(fields, f)
+	}
+	slices.Sort(fields)
+	return fields, nil
+}
+
+func addJSONFields(v any, path string, fieldSet map[string]bool) {
+
+	switch t := v.(type) {
+	case map[string]any:
+		dataKeyed := isDataKeyed(t)
+		for k, child := range t {
+			if dataKeyed {
+				k = "*"
+			}
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			fieldSet[p] = true
+			addJSONFields(child, p, fieldSet)
+		}
+	case []any:
+		for _, child := range t {
+			addJSONFields(child, path+"[]", fieldSet)
+		}
+	}
+}
+
+// isDataKeyed returns true if every key of obj looks like data rather than a field name: starting with a digit, e.g. a date, or in upper case, e.g. a currency code
+func isDataKeyed(obj map[string]any) bool {
+
+	if len(obj) == 0 {
+		return false
+	}
+	for k := range obj {
+		if k == "" {
+			return false
+		}
+		if unicode.IsDigit(rune(k[0])) {
+			continue
+		}
+		if len(k) < 2 || strings.ContainsFunc(k, func(r rune) bool { return !unicode.IsUpper(r) && !unicode.IsDigit(r) && r != '_' && r != '-' }) {
+			return false
+		}
+	}
+	return true
+}
+
+// CSVColumns returns the sorted columns of the header line of body
+func CSVColumns(body []byte) ([]string, error) {
+
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(body, []byte("\ufeff"))))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: r.Read failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	columns := make([]string, 0, len(header))
+	for _, col := range header {
+		col = strings.TrimSpace(col)
+		if col != "" && !slices.Contains(columns, col) {
+			columns = append(columns, col)
+		}
+	}
+	slices.Sort(columns)
+	return columns, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/config"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/loveyourstack/lys/lyspgdb"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// backfill syncs ECB exchange rates for a past date range into the configured database, in chunks of -chunk-days
+// each chunk is journaled like any other sync, so an interrupted backfill can be resumed with -from set to the last failed chunk
+
+func main() {
+
+	configFilePath := flag.String("config", config.DefaultFilePath, "path to the TOML config file")
+	fromStr := flag.String("from", "", "first day to backfill (YYYY-MM-DD, required)")
+	toStr := flag.String("to", "", "last day to backfill (YYYY-MM-DD). Defaults to today")
+	chunkDays := flag.Int("chunk-days", 90, "number of days requested from the ECB per sync")
+	freqStr := flag.String("freq", ecbapi.Daily.String(), "frequency: D (daily) or M (monthly)")
+	flag.Parse()
+
+	if *chunkDays < 1 {
+		log.Fatal("-chunk-days must be at least 1")
+	}
+	freq, err := ecbexchangerate.ParseFrequency(*freqStr)
+	if err != nil {
+		log.Fatalf("invalid -freq: %s", err.Error())
+	}
+
+	from, err := time.Parse(lystype.DateFormat, *fromStr)
+	if err != nil {
+		log.Fatalf("invalid -from: %s", err.Error())
+	}
+	to := time.Now()
+	if *toStr != "" {
+		to, err = time.Parse(lystype.DateFormat, *toStr)
+		if err != nil {
+			log.Fatalf("invalid -to: %s", err.Error())
+		}
+	}
+	if from.After(to) {
+		log.Fatal("-from must not be after -to")
+	}
+
+	conf := config.Config{}
+	if err := conf.LoadFromFile(*configFilePath); err != nil {
+		log.Fatalf("conf.LoadFromFile failed: %s", err.Error())
+	}
+
+	infoLog, errorLog, err := clog.New(conf.Log, os.Stdout, os.Stderr)
+	if err != nil {
+		log.Fatalf("clog.New failed: %s", err.Error())
+	}
+
+	ctx := context.Background()
+
+	db, err := lyspgdb.GetPool(ctx, conf.Db, conf.DbUser)
+	if err != nil {
+		log.Fatalf("lyspgdb.GetPool failed: %s", err.Error())
+	}
+	defer db.Close()
+
+	targets := []csyncdb.Target{{Name: config.PrimaryTargetName, Db: db}}
+	c := ecbapi.NewClient(infoLog, errorLog)
+
+	// rates reference currencies, so sync them first
+	if err := csyncdb.EcbCurrenciesToTargets(ctx, targets, c); err != nil {
+		errorLog.Error("csyncdb.EcbCurrenciesToTargets failed: " + err.Error())
+		os.Exit(1)
+	}
+
+	for start := from; !start.After(to); start = start.AddDate(0, 0, *chunkDays) {
+
+		end := start.AddDate(0, 0, *chunkDays-1)
+		if end.After(to) {
+			end = to
+		}
+
+		if err := csyncdb.EcbExchangeRatesToTargets(ctx, targets, c, "EUR", freq, start, end, nil, nil); err != nil {
+			errorLog.Error("csyncdb.EcbExchangeRatesToTargets failed", "from", start.Format(lystype.DateFormat), "to", end.Format(lystype.DateFormat), "error", err.Error())
+			os.Exit(1)
+		}
+		infoLog.Info("chunk synced", "from", start.Format(lystype.DateFormat), "to", end.Format(lystype.DateFormat))
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/config"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/drift"
+	"github.com/loveyourstack/connectors/quota"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/sink"
+	"github.com/loveyourstack/lys/lyspgdb"
+
+	// connectors register themselves when imported
+	_ "github.com/loveyourstack/connectors/registry/amzspconnector"
+	_ "github.com/loveyourstack/connectors/registry/coingeckoconnector"
+	_ "github.com/loveyourstack/connectors/registry/companieshouseconnector"
+	_ "github.com/loveyourstack/connectors/registry/dhlconnector"
+	_ "github.com/loveyourstack/connectors/registry/ebayconnector"
+	_ "github.com/loveyourstack/connectors/registry/ecbconnector"
+	_ "github.com/loveyourstack/connectors/registry/eurostatconnector"
+	_ "github.com/loveyourstack/connectors/registry/exhostconnector"
+	_ "github.com/loveyourstack/connectors/registry/filedropconnector"
+	_ "github.com/loveyourstack/connectors/registry/fredconnector"
+	_ "github.com/loveyourstack/connectors/registry/fxreconconnector"
+	_ "github.com/loveyourstack/connectors/registry/gleifconnector"
+	_ "github.com/loveyourstack/connectors/registry/hubspotconnector"
+	_ "github.com/loveyourstack/connectors/registry/imapconnector"
+	_ "github.com/loveyourstack/connectors/registry/imfconnector"
+	_ "github.com/loveyourstack/connectors/registry/inboundconnector"
+	_ "github.com/loveyourstack/connectors/registry/iso4217connector"
+	_ "github.com/loveyourstack/connectors/registry/lexofficeconnector"
+	_ "github.com/loveyourstack/connectors/registry/nagerconnector"
+	_ "github.com/loveyourstack/connectors/registry/outboundconnector"
+	_ "github.com/loveyourstack/connectors/registry/restcountriesconnector"
+	_ "github.com/loveyourstack/connectors/registry/shopifyconnector"
+	_ "github.com/loveyourstack/connectors/registry/stripeconnector"
+	_ "github.com/loveyourstack/connectors/registry/wiseconnector"
+)
+
+// nightly-sync syncs every dataset of the registered connectors once into the configured database and targets, and exits
+// it is meant to be run by cron or a systemd timer, as an alternative to the connectors daemon
+
+func main() {
+
+	configFilePath := flag.String("config", config.DefaultFilePath, "path to the TOML config file")
+	days := flag.Int("days", 7, "number of days synced, counting back from today")
+	flag.Parse()
+
+	conf := config.Config{}
+	if err := conf.LoadFromFile(*configFilePath); err != nil {
+		log.Fatalf("conf.LoadFromFile failed: %s", err.Error())
+	}
+	if err := registry.ConfigureAll(conf.ConnectorDecoder); err != nil {
+		log.Fatalf("registry.ConfigureAll failed: %s", err.Error())
+	}
+
+	infoLog, errorLog, err := clog.New(conf.Log, os.Stdout, os.Stderr)
+	if err != nil {
+		log.Fatalf("clog.New failed: %s", err.Error())
+	}
+
+	ctx := context.Background()
+
+	db, err := lyspgdb.GetPool(ctx, conf.Db, conf.DbUser)
+	if err != nil {
+		log.Fatalf("lyspgdb.GetPool failed: %s", err.Error())
+	}
+	defer db.Close()
+
+	// the primary database first, then any additional targets
+	targets := []csyncdb.Target{{Name: config.PrimaryTargetName, Db: db}}
+	for _, t := range conf.Targets {
+		tDb, err := lyspgdb.GetPool(ctx, t.Db, t.DbUser)
+		if err != nil {
+			log.Fatalf("lyspgdb.GetPool failed for target %s: %s", t.Name, err.Error())
+		}
+		defer tDb.Close()
+		targets = append(targets, csyncdb.Target{Name: t.Name, Db: tDb})
+	}
+
+	// tick datasets routed to ClickHouse, if configured, are written there instead of to the targets
+	sinks, err := sink.NewRouter(conf.ClickHouse, infoLog, errorLog)
+	if err != nil {
+		log.Fatalf("sink.NewRouter failed: %s", err.Error())
+	}
+
+	// requests to providers with a quota are counted in the primary database
+	tracker := quota.Tracker{Db: db, Limits: conf.Quotas, InfoLog: infoLog}
+
+	// fields added to or removed from API responses are logged if [drift] is enabled
+	detector, err := drift.NewDetector(conf.Drift, db, infoLog, errorLog)
+	if err != nil {
+		log.Fatalf("drift.NewDetector failed: %s", err.Error())
+	}
+
+	// a failed connector does not stop the others
+	failed := false
+	for _, conn := range registry.All() {
+
+		if !registry.IsEnabled(conn) {
+			continue
+		}
+
+		deps := registry.Deps{
+			Targets:  targets,
+			Days:     *days,
+			Sinks:    sinks,
+			Rules:    conf.Quality,
+			Derived:  conf.Derive,
+			Quota:    tracker.Meter(conn.Name()),
+			Drift:    detector.Guard(conn.Name()),
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+		}
+
+		err := conn.Sync(ctx, deps)
+		if err := tracker.Record(ctx, conn.Name(), deps.Quota, time.Now()); err != nil {
+			errorLog.Error("tracker.Record failed", "connector", conn.Name(), "error", err.Error())
+		}
+		if err != nil {
+			errorLog.Error("sync failed", "connector", conn.Name(), "error", err.Error())
+			failed = true
+			continue
+		}
+		infoLog.Info("sync completed", "connector", conn.Name())
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
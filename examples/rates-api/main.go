@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/config"
+	"github.com/loveyourstack/connectors/converter"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/httpapi"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/loveyourstack/lys/lyspgdb"
+)
+
+// rates-api serves the exchange rate and health endpoints of httpapi from the configured database, without syncing
+// the rates are expected to be kept up to date by another process, e.g. examples/nightly-sync. With -read-through, a fixing missing from the database is fetched from the ECB and stored
+
+func main() {
+
+	configFilePath := flag.String("config", config.DefaultFilePath, "path to the TOML config file")
+	addr := flag.String("addr", "localhost:8080", "HTTP listen address")
+	readThrough := flag.Bool("read-through", false, "fetch and store the rates of a day missing from the database")
+	flag.Parse()
+
+	conf := config.Config{}
+	if err := conf.LoadFromFile(*configFilePath); err != nil {
+		log.Fatalf("conf.LoadFromFile failed: %s", err.Error())
+	}
+
+	infoLog, errorLog, err := clog.New(conf.Log, os.Stdout, os.Stderr)
+	if err != nil {
+		log.Fatalf("clog.New failed: %s", err.Error())
+	}
+
+	db, err := lyspgdb.GetPool(context.Background(), conf.Db, conf.DbUser)
+	if err != nil {
+		log.Fatalf("lyspgdb.GetPool failed: %s", err.Error())
+	}
+	defer db.Close()
+
+	c := ecbapi.NewClient(infoLog, errorLog)
+
+	conv := converter.Converter{Db: db}
+	if *readThrough {
+		targets := []csyncdb.Target{{Name: config.PrimaryTargetName, Db: db}}
+		conv.Store = converter.NewReadThrough(ecbexchangerate.Store{Db: db}, csyncdb.EcbRateFetcher(targets, c, conf.Quality.For(csyncdb.DatasetEcbExchangeRates), conf.Derive.For(csyncdb.DatasetEcbExchangeRates)))
+	}
+
+	mux := http.NewServeMux()
+	httpapi.AddHealthRoutes(mux, []httpapi.Check{
+		httpapi.DbCheck(db),
+		httpapi.SchemaCheck(db, "ecb", []string{"currency", "exchange_rate"}),
+		httpapi.EcbCheck(c),
+	})
+	httpapi.AddRateRoutes(mux, conv, httpapi.RateFormat{}, errorLog)
+
+	// the auth settings of the daemon apply here too
+	handler := httpapi.LogRequests(infoLog, httpapi.RequireAuth(conf.Daemon.Auth, mux))
+
+	infoLog.Info("listening", "addr", *addr)
+	if err := http.ListenAndServe(*addr, handler); err != nil {
+		errorLog.Error("http.ListenAndServe failed: " + err.Error())
+		os.Exit(1)
+	}
+}
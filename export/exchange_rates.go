@@ -0,0 +1,208 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/loveyourstack/connectors/internal/arrow"
+	"github.com/loveyourstack/connectors/internal/parquet"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// exchangeRateFileName is the base name (without extension) of each exported exchange rate file
+const exchangeRateFileName string = "exchange_rates"
+
+// ExchangeRate is the flat row written by all exchange rate exporters
+type ExchangeRate struct {
+	Day          lystype.Date `json:"day"`
+	Frequency    string       `json:"frequency"`
+	FromCurrency string       `json:"from_currency"`
+	ToCurrency   string       `json:"to_currency"`
+	Rate         float32      `json:"rate"`
+}
+
+var exchangeRateColumns = []parquet.Column{
+	{Name: "day", Type: parquet.Date},
+	{Name: "frequency", Type: parquet.String},
+	{Name: "from_currency", Type: parquet.String},
+	{Name: "to_currency", Type: parquet.String},
+	{Name: "rate", Type: parquet.Float},
+}
+
+var exchangeRateArrowColumns = []arrow.Column{
+	{Name: "day", Type: arrow.Date},
+	{Name: "frequency", Type: arrow.String},
+	{Name: "from_currency", Type: arrow.String},
+	{Name: "to_currency", Type: arrow.String},
+	{Name: "rate", Type: arrow.Float},
+}
+
+func modelToExchangeRate(item ecbexchangerate.Model) ExchangeRate {
+	return ExchangeRate{
+		Day:          item.Day,
+		Frequency:    item.Frequency.String(),
+		FromCurrency: item.FromCurrency,
+		ToCurrency:   item.ToCurrency,
+		Rate:         item.Rate,
+	}
+}
+
+// ExchangeRatesToDir writes items into outDir in the supplied format, split into one file per partition
+// items should be ordered by day. Returns the paths of the files written
+func ExchangeRatesToDir(items []ecbexchangerate.Model, format Format, partitioning Partitioning, outDir string) (filePaths []string, err error) {
+
+	if err = format.Validate(); err != nil {
+		return nil, fmt.Errorf("format.Validate failed: %w", err)
+	}
+	if err = partitioning.Validate(); err != nil {
+		return nil, fmt.Errorf("partitioning.Validate failed: %w", err)
+	}
+
+	// group items by partition dir, keeping the partition order
+	partDirs := []string{}
+	partItems := make(map[string][]ecbexchangerate.Model)
+	for _, item := range items {
+		partDir := partitioning.Dir(time.Time(item.Day))
+		if _, ok := partItems[partDir]; !ok {
+			partDirs = append(partDirs, partDir)
+		}
+		partItems[partDir] = append(partItems[partDir], item)
+	}
+
+	// write one file per partition
+	for _, partDir := range partDirs {
+
+		dir := filepath.Join(outDir, partDir)
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("os.MkdirAll failed for dir: %s: %w", dir, err)
+		}
+
+		filePath := filepath.Join(dir, exchangeRateFileName+"."+format.Ext())
+		if err = exchangeRatesToFile(partItems[partDir], format, filePath); err != nil {
+			return nil, fmt.Errorf("exchangeRatesToFile failed for file: %s: %w", filePath, err)
+		}
+		filePaths = append(filePaths, filePath)
+	}
+
+	return filePaths, nil
+}
+
+func exchangeRatesToFile(items []ecbexchangerate.Model, format Format, filePath string) (err error) {
+
+	f, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("os.Create failed: %w", err)
+	}
+	defer f.Close()
+
+	if err = WriteExchangeRates(f, items, format); err != nil {
+		return fmt.Errorf("WriteExchangeRates failed: %w", err)
+	}
+
+	return f.Close()
+}
+
+// WriteExchangeRates writes items to w in the supplied format
+func WriteExchangeRates(w io.Writer, items []ecbexchangerate.Model, format Format) error {
+
+	switch format {
+	case CSV:
+		return writeExchangeRatesCsv(w, items)
+	case JSONL:
+		return writeExchangeRatesJsonl(w, items)
+	case Parquet:
+		return writeExchangeRatesParquet(w, items)
+	case Arrow, ArrowStream:
+		return writeExchangeRatesArrow(w, items, format == ArrowStream)
+	case XLSX:
+		return writeExchangeRatesXlsx(w, items)
+	default:
+		return fmt.Errorf("invalid format: %s", format)
+	}
+}
+
+func writeExchangeRatesCsv(w io.Writer, items []ecbexchangerate.Model) error {
+
+	cw := csv.NewWriter(w)
+
+	header := []string{}
+	for _, col := range exchangeRateColumns {
+		header = append(header, col.Name)
+	}
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("cw.Write (header) failed: %w", err)
+	}
+
+	for _, item := range items {
+		rec := []string{
+			item.Day.Format(lystype.DateFormat),
+			item.Frequency.String(),
+			item.FromCurrency,
+			item.ToCurrency,
+			strconv.FormatFloat(float64(item.Rate), 'f', -1, 32),
+		}
+		if err := cw.Write(rec); err != nil {
+			return fmt.Errorf("cw.Write failed: %w", err)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeExchangeRatesJsonl(w io.Writer, items []ecbexchangerate.Model) error {
+
+	enc := json.NewEncoder(w)
+	for _, item := range items {
+		if err := enc.Encode(modelToExchangeRate(item)); err != nil {
+			return fmt.Errorf("enc.Encode failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func writeExchangeRatesParquet(w io.Writer, items []ecbexchangerate.Model) error {
+
+	pw, err := parquet.NewWriter(w, exchangeRateColumns)
+	if err != nil {
+		return fmt.Errorf("parquet.NewWriter failed: %w", err)
+	}
+
+	for _, item := range items {
+		err = pw.WriteRow(time.Time(item.Day), item.Frequency, item.FromCurrency, item.ToCurrency, item.Rate)
+		if err != nil {
+			return fmt.Errorf("pw.WriteRow failed: %w", err)
+		}
+	}
+
+	return pw.Close()
+}
+
+func writeExchangeRatesArrow(w io.Writer, items []ecbexchangerate.Model, stream bool) error {
+
+	newWriter := arrow.NewWriter
+	if stream {
+		newWriter = arrow.NewStreamWriter
+	}
+	aw, err := newWriter(w, exchangeRateArrowColumns)
+	if err != nil {
+		return fmt.Errorf("arrow.NewWriter failed: %w", err)
+	}
+
+	for _, item := range items {
+		err = aw.WriteRow(time.Time(item.Day), item.Frequency.String(), item.FromCurrency, item.ToCurrency, item.Rate)
+		if err != nil {
+			return fmt.Errorf("aw.WriteRow failed: %w", err)
+		}
+	}
+
+	return aw.Close()
+}
@@ -0,0 +1,106 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/loveyourstack/connectors/internal/xlsx"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+)
+
+// summarySheetName is the name of the sheet of period-end rates, the first of the workbook
+const summarySheetName string = "Summary"
+
+var exchangeRatePairColumns = []xlsx.Column{
+	{Name: "Day", Type: xlsx.Date},
+	{Name: "Rate", Type: xlsx.Rate},
+	{Name: "Frequency", Type: xlsx.String},
+}
+
+// writeExchangeRatesXlsx writes items to w as a workbook with a sheet per currency pair, e.g. "EUR-USD", of its rates by day,
+// preceded by a summary sheet of the rate of each pair at each month end: that of the last day of the month with rates of any pair. Pairs are in alphabetical order
+// items should be ordered by day
+func writeExchangeRatesXlsx(w io.Writer, items []ecbexchangerate.Model) error {
+
+	pairItems := make(map[string][]ecbexchangerate.Model)
+	for _, item := range items {
+		pair := item.FromCurrency + "-" + item.ToCurrency
+		pairItems[pair] = append(pairItems[pair], item)
+	}
+	pairs := make([]string, 0, len(pairItems))
+	for pair := range pairItems {
+		pairs = append(pairs, pair)
+	}
+	slices.Sort(pairs)
+
+	wb := &xlsx.Workbook{}
+
+	if err := addPeriodEndSheet(wb, items, pairs); err != nil {
+		return fmt.Errorf("addPeriodEndSheet failed: %w", err)
+	}
+
+	for _, pair := range pairs {
+		sheet, err := wb.AddSheet(pair, exchangeRatePairColumns)
+		if err != nil {
+			return fmt.Errorf("wb.AddSheet failed for %s: %w", pair, err)
+		}
+		for _, item := range pairItems[pair] {
+			if err = sheet.WriteRow(time.Time(item.Day), item.RateFloat64(), item.Frequency.String()); err != nil {
+				return fmt.Errorf("sheet.WriteRow failed for %s: %w", pair, err)
+			}
+		}
+	}
+
+	if _, err := wb.WriteTo(w); err != nil {
+		return fmt.Errorf("wb.WriteTo failed: %w", err)
+	}
+
+	return nil
+}
+
+// addPeriodEndSheet adds the summary sheet: a row per month with its last day with rates and the last rate of each of pairs in the month. Pairs without a rate in the month are empty
+func addPeriodEndSheet(wb *xlsx.Workbook, items []ecbexchangerate.Model, pairs []string) error {
+
+	cols := []xlsx.Column{{Name: "Period end", Type: xlsx.Date}, {Name: "Last fixing", Type: xlsx.Date}}
+	pairIdx := make(map[string]int, len(pairs))
+	for _, pair := range pairs {
+		cols = append(cols, xlsx.Column{Name: pair, Type: xlsx.Rate})
+		pairIdx[pair] = len(cols) - 1
+	}
+
+	sheet, err := wb.AddSheet(summarySheetName, cols)
+	if err != nil {
+		return fmt.Errorf("wb.AddSheet failed: %w", err)
+	}
+
+	var row []any
+	var month time.Time
+	flush := func() error {
+		if row == nil {
+			return nil
+		}
+		return sheet.WriteRow(row...)
+	}
+
+	for _, item := range items {
+		day := time.Time(item.Day)
+		if itemMonth := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC); !itemMonth.Equal(month) {
+			if err = flush(); err != nil {
+				return fmt.Errorf("flush failed: %w", err)
+			}
+			month = itemMonth
+			row = make([]any, len(cols))
+			row[0] = month.AddDate(0, 1, -1)
+		}
+		// items are ordered by day, so the last rate of the month wins
+		row[1] = day
+		row[pairIdx[item.FromCurrency+"-"+item.ToCurrency]] = item.RateFloat64()
+	}
+	if err = flush(); err != nil {
+		return fmt.Errorf("flush failed: %w", err)
+	}
+
+	return nil
+}
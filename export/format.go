@@ -0,0 +1,97 @@
+package export
+
+import (
+	"fmt"
+	"time"
+)
+
+// Format is an export file format
+type Format string
+
+func (e Format) String() string {
+	return string(e)
+}
+
+const (
+	CSV     Format = "csv"
+	JSONL   Format = "jsonl"
+	Parquet Format = "parquet"
+
+	// Arrow is the Arrow IPC file format, also known as Feather v2
+	Arrow Format = "arrow"
+
+	// ArrowStream is the Arrow IPC streaming format, which can be read while being written, e.g. from an HTTP response
+	ArrowStream Format = "arrows"
+
+	// XLSX is an Excel workbook with a sheet per currency pair and a summary sheet of the period-end rates
+	XLSX Format = "xlsx"
+)
+
+// Ext returns the file extension (without dot) of the format
+func (e Format) Ext() string {
+	return string(e)
+}
+
+// ContentType returns the media type of the format
+func (e Format) ContentType() string {
+	switch e {
+	case CSV:
+		return "text/csv"
+	case JSONL:
+		return "application/jsonl"
+	case Arrow:
+		return "application/vnd.apache.arrow.file"
+	case ArrowStream:
+		return "application/vnd.apache.arrow.stream"
+	case XLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+func (e Format) Validate() error {
+	switch e {
+	case CSV, JSONL, Parquet, Arrow, ArrowStream, XLSX:
+		return nil
+	default:
+		return fmt.Errorf("invalid format '%s'", e)
+	}
+}
+
+// Partitioning determines how exported rows are split into files. Partitions are written as Hive-style directories, e.g. year=2024/month=09
+type Partitioning string
+
+func (e Partitioning) String() string {
+	return string(e)
+}
+
+const (
+	PartitionNone  Partitioning = "none"
+	PartitionYear  Partitioning = "year"
+	PartitionMonth Partitioning = "month"
+	PartitionDay   Partitioning = "day"
+)
+
+func (e Partitioning) Validate() error {
+	switch e {
+	case PartitionNone, PartitionYear, PartitionMonth, PartitionDay:
+		return nil
+	default:
+		return fmt.Errorf("invalid partitioning '%s'", e)
+	}
+}
+
+// Dir returns the relative partition directory for day
+func (e Partitioning) Dir(day time.Time) string {
+	switch e {
+	case PartitionYear:
+		return day.Format("year=2006")
+	case PartitionMonth:
+		return day.Format("year=2006/month=01")
+	case PartitionDay:
+		return day.Format("year=2006/month=01/day=02")
+	default:
+		return ""
+	}
+}
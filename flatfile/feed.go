@@ -0,0 +1,214 @@
+package flatfile
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// file formats
+const (
+	FormatCsv  string = "csv"  // header row, then a record per row. Values are stored as strings
+	FormatJson string = "json" // an array of objects, or newline-delimited objects (NDJSON)
+	FormatXlsx string = "xlsx" // Excel workbook: header row, then a record per row of a sheet. Values are stored as strings
+)
+
+var feedNameRegex = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// Feed configures the files of a partner delivery: where they are dropped in the bucket and how they are parsed
+type Feed struct {
+	Name      string   `toml:"name"`      // lower case, stored as the feed of files and records
+	Prefix    string   `toml:"prefix"`    // key prefix of the feed's files, e.g. "acme/prices/"
+	Suffix    string   `toml:"suffix"`    // optional: only keys with this suffix are read, e.g. ".csv"
+	Format    string   `toml:"format"`    // csv or json. Defaults to csv
+	Delimiter string   `toml:"delimiter"` // of csv files. Defaults to ","
+	Sheet     string   `toml:"sheet"`     // of xlsx files. Defaults to the first sheet
+	Mapping   *Mapping `toml:"mapping"`   // optional: the records are also upserted into the columns of a table
+}
+
+// WithDefaults returns f with defaults applied to unset fields
+func (f Feed) WithDefaults() Feed {
+
+	if f.Format == "" {
+		f.Format = FormatCsv
+	}
+	if f.Delimiter == "" {
+		f.Delimiter = ","
+	}
+	if f.Mapping != nil {
+		m := f.Mapping.WithDefaults()
+		f.Mapping = &m
+	}
+
+	return f
+}
+
+// Validate returns an error if f, with defaults applied, is incomplete or invalid
+func (f Feed) Validate() error {
+
+	if !feedNameRegex.MatchString(f.Name) {
+		return fmt.Errorf("%w: name must be lower case letters, digits, _ or -: %q", cerrors.ErrValidationFailed, f.Name)
+	}
+
+	switch f.Format {
+	case FormatCsv:
+		if utf8.RuneCountInString(f.Delimiter) != 1 {
+			return fmt.Errorf("%w: feed %s: delimiter must be a single character", cerrors.ErrValidationFailed, f.Name)
+		}
+	case FormatJson, FormatXlsx:
+	default:
+		return fmt.Errorf("%w: feed %s: unknown format: %s", cerrors.ErrValidationFailed, f.Name, f.Format)
+	}
+
+	if f.Mapping != nil {
+		if err := f.Mapping.Validate(); err != nil {
+			return fmt.Errorf("feed %s: mapping: %w", f.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Includes returns true if the file with key belongs to f
+func (f Feed) Includes(key string) bool {
+	return strings.HasPrefix(key, f.Prefix) && strings.HasSuffix(key, f.Suffix)
+}
+
+// Parse returns the records of a file of f, each as a JSON object
+func (f Feed) Parse(content []byte) (records []json.RawMessage, err error) {
+
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")) // UTF-8 BOM, as written e.g. by Excel
+
+	switch f.Format {
+	case FormatCsv:
+		return ParseCsv(content, []rune(f.Delimiter)[0])
+	case FormatJson:
+		return ParseJson(content)
+	case FormatXlsx:
+		return ParseXlsx(content, f.Sheet)
+	default:
+		return nil, fmt.Errorf("%w: unknown format: %s", cerrors.ErrValidationFailed, f.Format)
+	}
+}
+
+// ParseCsv returns a JSON object per row of content, keyed by the column names of the header row
+func ParseCsv(content []byte, delimiter rune) (records []json.RawMessage, err error) {
+
+	r := csv.NewReader(bytes.NewReader(content))
+	r.Comma = delimiter
+	r.ReuseRecord = true
+
+	header, err := r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: no header row", cerrors.ErrValidationFailed)
+		}
+		return nil, fmt.Errorf("%w: r.Read failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	cols, err := headerColumns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	records = []json.RawMessage{}
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// includes rows with a different number of fields than the header
+			return nil, fmt.Errorf("%w: r.Read failed: %w", cerrors.ErrValidationFailed, err)
+		}
+
+		rec, err := rowRecord(cols, row)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
+
+// headerColumns returns the trimmed column names of header, which must be unique and not empty
+func headerColumns(header []string) (cols []string, err error) {
+
+	cols = make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, col := range header {
+		col = strings.TrimSpace(col)
+		if col == "" {
+			return nil, fmt.Errorf("%w: header column %d is empty", cerrors.ErrValidationFailed, i+1)
+		}
+		if seen[col] {
+			return nil, fmt.Errorf("%w: duplicate header column: %s", cerrors.ErrValidationFailed, col)
+		}
+		seen[col] = true
+		cols[i] = col
+	}
+
+	return cols, nil
+}
+
+// rowRecord returns row as a JSON object keyed by cols. Missing trailing values are empty strings
+func rowRecord(cols, row []string) (json.RawMessage, error) {
+
+	rec := make(map[string]string, len(cols))
+	for i, col := range cols {
+		if i < len(row) {
+			rec[col] = row[i]
+		} else {
+			rec[col] = ""
+		}
+	}
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal failed: %w", err)
+	}
+	return b, nil
+}
+
+// ParseJson returns the objects of content, which is either a JSON array of objects or newline-delimited objects
+func ParseJson(content []byte) (records []json.RawMessage, err error) {
+
+	content = bytes.TrimSpace(content)
+	records = []json.RawMessage{}
+
+	if bytes.HasPrefix(content, []byte("[")) {
+		if err = json.Unmarshal(content, &records); err != nil {
+			return nil, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(content))
+		for {
+			var rec json.RawMessage
+			err = dec.Decode(&rec)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%w: record %d: dec.Decode failed: %w", cerrors.ErrValidationFailed, len(records)+1, err)
+			}
+			records = append(records, rec)
+		}
+	}
+
+	for i, rec := range records {
+		if !bytes.HasPrefix(bytes.TrimSpace(rec), []byte("{")) {
+			return nil, fmt.Errorf("%w: record %d is not an object", cerrors.ErrValidationFailed, i+1)
+		}
+	}
+
+	return records, nil
+}
@@ -0,0 +1,283 @@
+package flatfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// column types of a Mapping
+const (
+	TypeText        string = "text"
+	TypeInt         string = "int"     // bigint
+	TypeNumeric     string = "numeric" // exact: values are not converted to floats
+	TypeBool        string = "bool"    // true/false, 1/0, yes/no, y/n, case-insensitive
+	TypeDate        string = "date"
+	TypeTimestamptz string = "timestamptz"
+)
+
+// FormatExcel is the Format of date and timestamptz columns holding Excel serial numbers, as read from xlsx files
+const FormatExcel string = "excel"
+
+// excelEpoch is day 0 of Excel serial dates, accounting for Excel's 1900 leap year bug
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+var identRegex = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// Mapping maps the records of a feed onto the columns of a table, so that simple sources are loaded into typed columns without code
+// the table, created by the user, must have a unique constraint on the Key columns: rows are upserted by key. Rows removed from a file are kept
+type Mapping struct {
+	Table   string   `toml:"table"`   // schema qualified, e.g. acme.price
+	Key     []string `toml:"key"`     // names of the columns identifying a row, e.g. ["sku", "valid_from"]
+	Columns []Column `toml:"columns"` // in insert order
+}
+
+// Column maps a field of the records onto a column of the table
+type Column struct {
+	Name     string `toml:"name"`     // of the column, lower case
+	Source   string `toml:"source"`   // field of the record, e.g. a csv header column. Defaults to Name
+	Type     string `toml:"type"`     // text, int, numeric, bool, date or timestamptz. Defaults to text
+	Format   string `toml:"format"`   // Go time layout of date and timestamptz values, or "excel" for serial numbers. Defaults to 2006-01-02 and RFC 3339
+	Default  string `toml:"default"`  // used when the field is missing, null or empty. Coerced like a value of the field
+	Required bool   `toml:"required"` // a record without the field, and without Default, fails the file. Otherwise the column is set to NULL
+}
+
+// WithDefaults returns m with defaults applied to the unset fields of its columns
+func (m Mapping) WithDefaults() Mapping {
+
+	cols := make([]Column, len(m.Columns))
+	for i, c := range m.Columns {
+		if c.Source == "" {
+			c.Source = c.Name
+		}
+		if c.Type == "" {
+			c.Type = TypeText
+		}
+		if c.Format == "" {
+			switch c.Type {
+			case TypeDate:
+				c.Format = time.DateOnly
+			case TypeTimestamptz:
+				c.Format = time.RFC3339
+			}
+		}
+		cols[i] = c
+	}
+	m.Columns = cols
+
+	return m
+}
+
+// Validate returns an error if m, with defaults applied, is incomplete or invalid
+func (m Mapping) Validate() error {
+
+	schema, table, ok := strings.Cut(m.Table, ".")
+	if !ok || !identRegex.MatchString(schema) || !identRegex.MatchString(table) {
+		return fmt.Errorf("%w: table must be schema qualified, lower case, e.g. acme.price: %q", cerrors.ErrValidationFailed, m.Table)
+	}
+	if len(m.Columns) == 0 {
+		return fmt.Errorf("%w: table %s: no columns", cerrors.ErrValidationFailed, m.Table)
+	}
+
+	names := make([]string, 0, len(m.Columns))
+	for _, c := range m.Columns {
+		if !identRegex.MatchString(c.Name) {
+			return fmt.Errorf("%w: table %s: column name must be lower case letters, digits or _: %q", cerrors.ErrValidationFailed, m.Table, c.Name)
+		}
+		if slices.Contains(names, c.Name) {
+			return fmt.Errorf("%w: table %s: duplicate column: %s", cerrors.ErrValidationFailed, m.Table, c.Name)
+		}
+		names = append(names, c.Name)
+
+		switch c.Type {
+		case TypeText, TypeInt, TypeNumeric, TypeBool, TypeDate, TypeTimestamptz:
+		default:
+			return fmt.Errorf("%w: table %s: column %s: unknown type: %s", cerrors.ErrValidationFailed, m.Table, c.Name, c.Type)
+		}
+		if c.Default != "" {
+			if _, err := c.coerce(c.Default); err != nil {
+				return fmt.Errorf("%w: table %s: column %s: default: %w", cerrors.ErrValidationFailed, m.Table, c.Name, err)
+			}
+		}
+	}
+
+	if len(m.Key) == 0 {
+		return fmt.Errorf("%w: table %s: no key columns", cerrors.ErrValidationFailed, m.Table)
+	}
+	for _, k := range m.Key {
+		if !slices.Contains(names, k) {
+			return fmt.Errorf("%w: table %s: key column is not mapped: %s", cerrors.ErrValidationFailed, m.Table, k)
+		}
+	}
+
+	return nil
+}
+
+// ColumnNames returns the names of the columns of m, in insert order
+func (m Mapping) ColumnNames() []string {
+
+	names := make([]string, len(m.Columns))
+	for i, c := range m.Columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Rows returns the values of the columns of m for each of records. A later record with the key of an earlier one replaces it
+// a record which can't be mapped fails all of them, so that a file is loaded either completely or not at all
+func (m Mapping) Rows(records []json.RawMessage) (rows [][]any, err error) {
+
+	keyIdx := make([]int, len(m.Key))
+	for i, k := range m.Key {
+		keyIdx[i] = slices.IndexFunc(m.Columns, func(c Column) bool { return c.Name == k })
+	}
+
+	rows = make([][]any, 0, len(records))
+	rowByKey := make(map[string]int, len(records))
+	for i, rec := range records {
+
+		fields := make(map[string]any)
+		dec := json.NewDecoder(bytes.NewReader(rec))
+		dec.UseNumber()
+		if err = dec.Decode(&fields); err != nil {
+			return nil, fmt.Errorf("%w: record %d: dec.Decode failed: %w", cerrors.ErrValidationFailed, i+1, err)
+		}
+
+		row := make([]any, len(m.Columns))
+		for j, c := range m.Columns {
+			row[j], err = c.value(fields[c.Source])
+			if err != nil {
+				return nil, fmt.Errorf("%w: record %d: column %s: %w", cerrors.ErrValidationFailed, i+1, c.Name, err)
+			}
+		}
+
+		keyVals := make([]string, len(keyIdx))
+		for j, idx := range keyIdx {
+			if row[idx] == nil {
+				return nil, fmt.Errorf("%w: record %d: key column %s is null", cerrors.ErrValidationFailed, i+1, m.Columns[idx].Name)
+			}
+			keyVals[j] = keyString(row[idx])
+		}
+		key := strings.Join(keyVals, "\x00")
+
+		if idx, ok := rowByKey[key]; ok {
+			rows[idx] = row
+			continue
+		}
+		rowByKey[key] = len(rows)
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// keyString returns v, a coerced value, as a string which is equal for equal values
+func keyString(v any) string {
+
+	switch t := v.(type) {
+	case pgtype.Numeric:
+		f, _ := t.Float64Value()
+		return strconv.FormatFloat(f.Float64, 'g', -1, 64)
+	case pgtype.Date:
+		return t.Time.Format(time.DateOnly)
+	case time.Time:
+		return t.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// value returns the column value of the field v of a record, decoded with json.Number
+func (c Column) value(v any) (any, error) {
+
+	var s string
+	switch t := v.(type) {
+	case nil:
+	case string:
+		s = strings.TrimSpace(t)
+	case json.Number:
+		s = t.String()
+	case bool:
+		s = strconv.FormatBool(t)
+	default:
+		return nil, fmt.Errorf("field %s is not a scalar", c.Source)
+	}
+
+	if s == "" {
+		s = c.Default
+	}
+	if s == "" {
+		if c.Required {
+			return nil, fmt.Errorf("field %s is missing", c.Source)
+		}
+		return nil, nil
+	}
+
+	return c.coerce(s)
+}
+
+// coerce converts s to the type of c
+func (c Column) coerce(s string) (any, error) {
+
+	switch c.Type {
+	case TypeText:
+		return s, nil
+
+	case TypeInt:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("strconv.ParseInt failed: %w", err)
+		}
+		return i, nil
+
+	case TypeNumeric:
+		var n pgtype.Numeric
+		if err := n.Scan(s); err != nil {
+			return nil, fmt.Errorf("not a number: %q", s)
+		}
+		return n, nil
+
+	case TypeBool:
+		switch strings.ToLower(s) {
+		case "true", "1", "yes", "y":
+			return true, nil
+		case "false", "0", "no", "n":
+			return false, nil
+		default:
+			return nil, fmt.Errorf("not a bool: %q", s)
+		}
+
+	case TypeDate, TypeTimestamptz:
+		var t time.Time
+		if c.Format == FormatExcel {
+			serial, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("strconv.ParseFloat failed: %w", err)
+			}
+			days := math.Floor(serial)
+			t = excelEpoch.AddDate(0, 0, int(days)).Add(time.Duration(math.Round((serial-days)*24*float64(time.Hour/time.Second))) * time.Second)
+		} else {
+			var err error
+			t, err = time.Parse(c.Format, s)
+			if err != nil {
+				return nil, fmt.Errorf("time.Parse failed: %w", err)
+			}
+		}
+		if c.Type == TypeDate {
+			return pgtype.Date{Time: time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), Valid: true}, nil
+		}
+		return t, nil
+
+	default:
+		return nil, fmt.Errorf("unknown type: %s", c.Type)
+	}
+}
@@ -0,0 +1,249 @@
+package flatfile
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// maxXlsxPartBytes limits the uncompressed size of the workbook parts read, as a guard against zip bombs
+const maxXlsxPartBytes int64 = 200 << 20
+
+// ParseXlsx returns a JSON object per row of sheet in the workbook content, keyed by the column names of the first row. The first sheet is used if sheet is empty
+// values are the cell values as stored: numbers, including dates, which are stored as serial numbers, are not formatted. Empty rows are skipped
+func ParseXlsx(content []byte, sheet string) (records []json.RawMessage, err error) {
+
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: zip.NewReader failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	sheetPath, err := xlsxSheetPath(zr, sheet)
+	if err != nil {
+		return nil, fmt.Errorf("xlsxSheetPath failed: %w", err)
+	}
+
+	sharedStrings, err := xlsxSharedStrings(zr)
+	if err != nil {
+		return nil, fmt.Errorf("xlsxSharedStrings failed: %w", err)
+	}
+
+	rows, err := xlsxRows(zr, sheetPath, sharedStrings)
+	if err != nil {
+		return nil, fmt.Errorf("xlsxRows failed: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: no header row", cerrors.ErrValidationFailed)
+	}
+
+	cols, err := headerColumns(rows[0])
+	if err != nil {
+		return nil, err
+	}
+
+	records = []json.RawMessage{}
+	for _, row := range rows[1:] {
+		rec, err := rowRecord(cols, row)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
+
+// readXlsxPart decodes the XML part with name into v. If the part is missing, it returns an error wrapping fs.ErrNotExist
+func readXlsxPart(zr *zip.Reader, name string, v any) error {
+
+	f, err := zr.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err = xml.NewDecoder(io.LimitReader(f, maxXlsxPartBytes)).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s: Decode failed: %w", cerrors.ErrValidationFailed, name, err)
+	}
+	return nil
+}
+
+// xlsxSheetPath returns the path of the part of the sheet with name, or of the first sheet if name is empty
+func xlsxSheetPath(zr *zip.Reader, name string) (string, error) {
+
+	workbook := struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RId  string `xml:"id,attr"` // r:id
+		} `xml:"sheets>sheet"`
+	}{}
+	if err := readXlsxPart(zr, "xl/workbook.xml", &workbook); err != nil {
+		return "", fmt.Errorf("%w: not a workbook: %w", cerrors.ErrValidationFailed, err)
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", fmt.Errorf("%w: workbook has no sheets", cerrors.ErrValidationFailed)
+	}
+
+	rId := ""
+	for _, s := range workbook.Sheets {
+		if name == "" || s.Name == name {
+			rId = s.RId
+			break
+		}
+	}
+	if rId == "" {
+		return "", fmt.Errorf("%w: sheet not found: %s", cerrors.ErrValidationFailed, name)
+	}
+
+	rels := struct {
+		Relationships []struct {
+			Id     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}{}
+	if err := readXlsxPart(zr, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", fmt.Errorf("readXlsxPart failed: %w", err)
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.Id != rId {
+			continue
+		}
+		// targets are relative to xl/, or absolute within the package
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+
+	return "", fmt.Errorf("%w: relationship not found: %s", cerrors.ErrValidationFailed, rId)
+}
+
+// xlsxSharedStrings returns the shared strings of the workbook, which cells of type s refer to by index
+func xlsxSharedStrings(zr *zip.Reader) (sharedStrings []string, err error) {
+
+	sst := struct {
+		Items []struct {
+			T    string `xml:"t"`
+			Runs []struct {
+				T string `xml:"t"`
+			} `xml:"r"` // rich text
+		} `xml:"si"`
+	}{}
+	if err = readXlsxPart(zr, "xl/sharedStrings.xml", &sst); err != nil {
+		// workbooks without text have no shared strings
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("readXlsxPart failed: %w", err)
+	}
+
+	sharedStrings = make([]string, len(sst.Items))
+	for i, item := range sst.Items {
+		s := item.T
+		for _, run := range item.Runs {
+			s += run.T
+		}
+		sharedStrings[i] = s
+	}
+
+	return sharedStrings, nil
+}
+
+// xlsxRows returns the values of the non-empty rows of the sheet at sheetPath, positioned by their cell references
+func xlsxRows(zr *zip.Reader, sheetPath string, sharedStrings []string) (rows [][]string, err error) {
+
+	sheet := struct {
+		Rows []struct {
+			Cells []struct {
+				Ref       string `xml:"r,attr"` // e.g. B3
+				Type      string `xml:"t,attr"`
+				Value     string `xml:"v"`
+				InlineStr struct {
+					T    string `xml:"t"`
+					Runs []struct {
+						T string `xml:"t"`
+					} `xml:"r"`
+				} `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}{}
+	if err = readXlsxPart(zr, sheetPath, &sheet); err != nil {
+		return nil, fmt.Errorf("readXlsxPart failed: %w", err)
+	}
+
+	for _, sheetRow := range sheet.Rows {
+
+		row := []string{}
+		empty := true
+		for _, cell := range sheetRow.Cells {
+
+			val := cell.Value
+			switch cell.Type {
+			case "s":
+				idx := 0
+				if _, err := fmt.Sscan(cell.Value, &idx); err != nil || idx < 0 || idx >= len(sharedStrings) {
+					return nil, fmt.Errorf("%w: cell %s: invalid shared string index: %s", cerrors.ErrValidationFailed, cell.Ref, cell.Value)
+				}
+				val = sharedStrings[idx]
+			case "inlineStr":
+				val = cell.InlineStr.T
+				for _, run := range cell.InlineStr.Runs {
+					val += run.T
+				}
+			case "b":
+				val = map[string]string{"0": "false", "1": "true"}[cell.Value]
+			}
+
+			// cells without a reference follow the previous one
+			col := len(row)
+			if cell.Ref != "" {
+				col = xlsxColumnIndex(cell.Ref)
+				if col < 0 {
+					return nil, fmt.Errorf("%w: invalid cell reference: %s", cerrors.ErrValidationFailed, cell.Ref)
+				}
+			}
+			for len(row) < col {
+				row = append(row, "")
+			}
+			if col < len(row) {
+				row[col] = val
+			} else {
+				row = append(row, val)
+			}
+
+			if val != "" {
+				empty = false
+			}
+		}
+
+		if !empty {
+			rows = append(rows, row)
+		}
+	}
+
+	return rows, nil
+}
+
+// xlsxColumnIndex returns the 0-based column of the cell reference ref, e.g. 27 for AB3, or -1 if ref is invalid
+func xlsxColumnIndex(ref string) int {
+
+	col := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+	}
+	if i == 0 || i > 3 {
+		return -1
+	}
+	return col - 1
+}
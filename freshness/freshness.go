@@ -0,0 +1,166 @@
+package freshness
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/calendar"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+)
+
+// Status is the outcome of a freshness assessment
+type Status string
+
+const (
+	Fresh Status = "fresh"
+	Stale Status = "stale"
+	Empty Status = "empty" // no data at all
+)
+
+// ECB daily reference rates are published around 16:00 CET on TARGET business days
+const ecbPublicationHour int = 16
+
+// ECB monthly averages are published in the first days of the following month
+const ecbMonthlyPublicationDay int = 5
+
+// Assessment is the freshness of a single dataset
+type Assessment struct {
+	LatestDay   time.Time `json:"latest_day"`
+	ExpectedDay time.Time `json:"expected_day"`
+	Lag         int       `json:"lag"` // number of periods (business days or months) by which LatestDay trails ExpectedDay
+	MaxLag      int       `json:"max_lag"`
+	Status      Status    `json:"status"`
+}
+
+// ExpectedLatestDaily returns the most recent day for which ECB daily rates should be available at now: the latest TARGET business day whose rates were published
+func ExpectedLatestDaily(now time.Time) time.Time {
+
+	now = now.In(calendar.Location)
+	day := truncateDay(now)
+
+	// today's rates are only expected after publication
+	if now.Hour() < ecbPublicationHour || !calendar.IsBusinessDay(day) {
+		return calendar.PreviousBusinessDay(day)
+	}
+	return day
+}
+
+// NextDailyPublication returns the time after now at which the next ECB daily fixing is expected to be published, on the next TARGET business day if need be
+func NextDailyPublication(now time.Time) time.Time {
+
+	local := now.In(calendar.Location)
+	day := truncateDay(local)
+	if local.Hour() >= ecbPublicationHour || !calendar.IsBusinessDay(day) {
+		day = calendar.NextBusinessDay(day)
+	}
+
+	return time.Date(day.Year(), day.Month(), day.Day(), ecbPublicationHour, 0, 0, 0, calendar.Location)
+}
+
+// LastDailyPublication returns the time at or before now at which the latest ECB daily fixing was expected to be published, that of ExpectedLatestDaily
+func LastDailyPublication(now time.Time) time.Time {
+
+	day := ExpectedLatestDaily(now)
+	return time.Date(day.Year(), day.Month(), day.Day(), ecbPublicationHour, 0, 0, 0, calendar.Location)
+}
+
+// ExpectedLatestMonthly returns the first day of the most recent month for which ECB monthly averages should be available at now
+func ExpectedLatestMonthly(now time.Time) time.Time {
+
+	now = now.In(calendar.Location)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+
+	if now.Day() < ecbMonthlyPublicationDay {
+		month = month.AddDate(0, -1, 0)
+	}
+
+	return month
+}
+
+// AssessDaily assesses daily data whose most recent observation is latestDay. Data is stale if it trails by more than maxLag business days
+func AssessDaily(latestDay, now time.Time, maxLag int) Assessment {
+
+	a := Assessment{
+		LatestDay:   latestDay,
+		ExpectedDay: ExpectedLatestDaily(now),
+		MaxLag:      maxLag,
+	}
+	if latestDay.IsZero() {
+		a.Status = Empty
+		return a
+	}
+
+	a.Lag = calendar.BusinessDaysBetween(latestDay, a.ExpectedDay)
+
+	a.Status = statusFromLag(a.Lag, maxLag)
+	return a
+}
+
+// AssessMonthly assesses monthly data whose most recent observation is latestDay (1st of month). Data is stale if it trails by more than maxLag months
+func AssessMonthly(latestDay, now time.Time, maxLag int) Assessment {
+
+	a := Assessment{
+		LatestDay:   latestDay,
+		ExpectedDay: ExpectedLatestMonthly(now),
+		MaxLag:      maxLag,
+	}
+	if latestDay.IsZero() {
+		a.Status = Empty
+		return a
+	}
+
+	lag := (a.ExpectedDay.Year()-latestDay.Year())*12 + int(a.ExpectedDay.Month()) - int(latestDay.Month())
+	if lag > 0 {
+		a.Lag = lag
+	}
+
+	a.Status = statusFromLag(a.Lag, maxLag)
+	return a
+}
+
+// Err returns nil if a is fresh, a cerrors.StaleError if it is stale, and an error matching cerrors.ErrNotFound if there is no data
+func (a Assessment) Err(dataset string) error {
+
+	switch a.Status {
+	case Stale:
+		return cerrors.StaleError{Dataset: dataset, LatestDay: a.LatestDay, ExpectedDay: a.ExpectedDay, Lag: a.Lag, MaxLag: a.MaxLag}
+	case Empty:
+		return fmt.Errorf("%w: %s has no data", cerrors.ErrNotFound, dataset)
+	default:
+		return nil
+	}
+}
+
+// EcbExchangeRates assesses the freshness of the ECB exchange rates stored in db with frequency freq
+func EcbExchangeRates(ctx context.Context, db *pgxpool.Pool, freq ecbexchangerate.Frequency, now time.Time, maxLag int) (a Assessment, err error) {
+
+	xrStore := ecbexchangerate.Store{Db: db}
+	stats, err := xrStore.Stats(ctx)
+	if err != nil {
+		return Assessment{}, fmt.Errorf("xrStore.Stats failed: %w", err)
+	}
+	_, _, latestDay := stats.Frequency(freq)
+
+	switch freq {
+	case ecbexchangerate.Daily:
+		return AssessDaily(latestDay, now, maxLag), nil
+	case ecbexchangerate.Monthly:
+		return AssessMonthly(latestDay, now, maxLag), nil
+	default:
+		return Assessment{}, fmt.Errorf("%w: invalid freq '%s'", cerrors.ErrValidationFailed, freq)
+	}
+}
+
+func statusFromLag(lag, maxLag int) Status {
+	if lag > maxLag {
+		return Stale
+	}
+	return Fresh
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,90 @@
+package httpapi
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// AuthConfig configures the authentication middleware. If neither Tokens nor BasicUsers are set, authentication is disabled
+type AuthConfig struct {
+	Tokens      []string          `toml:"tokens"`      // accepted bearer tokens ("Authorization: Bearer <token>")
+	BasicUsers  map[string]string `toml:"basicUsers"`  // accepted Basic auth user names and passwords
+	PublicPaths []string          `toml:"publicPaths"` // paths served without authentication. Defaults to /healthz and /readyz so that probes keep working
+	Realm       string            `toml:"realm"`       // Basic auth realm. Defaults to "connectors"
+}
+
+var defaultPublicPaths = []string{"/healthz", "/readyz"}
+
+// Enabled returns true if any credentials are configured
+func (conf AuthConfig) Enabled() bool {
+	return len(conf.Tokens) > 0 || len(conf.BasicUsers) > 0
+}
+
+// RequireAuth returns middleware that rejects requests without a valid bearer token or Basic auth credentials with 401. If conf is not Enabled, next is returned unchanged
+func RequireAuth(conf AuthConfig, next http.Handler) http.Handler {
+
+	if !conf.Enabled() {
+		return next
+	}
+
+	publicPaths := conf.PublicPaths
+	if publicPaths == nil {
+		publicPaths = defaultPublicPaths
+	}
+	realm := conf.Realm
+	if realm == "" {
+		realm = "connectors"
+	}
+
+	// hash credentials once so that comparisons are constant-time regardless of length
+	tokenHashes := make([][32]byte, len(conf.Tokens))
+	for i, token := range conf.Tokens {
+		tokenHashes[i] = sha256.Sum256([]byte(token))
+	}
+	userHashes := make(map[string][32]byte, len(conf.BasicUsers))
+	for user, password := range conf.BasicUsers {
+		userHashes[user] = sha256.Sum256([]byte(password))
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if slices.Contains(publicPaths, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && token != "" {
+			if matchesAny(sha256.Sum256([]byte(token)), tokenHashes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		} else if user, password, ok := r.BasicAuth(); ok {
+			if expected, found := userHashes[user]; found {
+				hash := sha256.Sum256([]byte(password))
+				if subtle.ConstantTimeCompare(hash[:], expected[:]) == 1 {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+		}
+
+		if len(userHashes) > 0 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+		} else {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+realm+`"`)
+		}
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+func matchesAny(hash [32]byte, hashes [][32]byte) bool {
+
+	found := 0
+	for _, h := range hashes {
+		found |= subtle.ConstantTimeCompare(hash[:], h[:])
+	}
+	return found == 1
+}
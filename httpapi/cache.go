@@ -0,0 +1,85 @@
+package httpapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/freshness"
+	"github.com/loveyourstack/lys"
+)
+
+const (
+	minRateMaxAge        time.Duration = time.Minute
+	historicRateMaxAge   time.Duration = 24 * time.Hour
+	rateStaleIfErrorSecs int           = 24 * 60 * 60
+)
+
+// rateMaxAge returns how long a response based on the fixing of fixingDay may be cached at now
+// rates change at most once per business day: responses based on the latest fixing are cacheable until the next publication is due,
+// responses based on older fixings are cacheable for a day only, since ECB may still revise them
+func rateMaxAge(fixingDay, now time.Time) time.Duration {
+
+	if fixingDay.Before(freshness.ExpectedLatestDaily(now)) {
+		return historicRateMaxAge
+	}
+
+	return max(freshness.NextDailyPublication(now).Sub(now), minRateMaxAge)
+}
+
+// writeCachedResponse writes resp as JSON with Cache-Control and a strong ETag derived from the body
+// if the request's If-None-Match matches the ETag, 304 Not Modified is returned without a body
+func writeCachedResponse(resp lys.StdResponse, maxAge time.Duration, w http.ResponseWriter, r *http.Request) {
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		// should never happen
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	// authenticated responses must not be stored by shared caches
+	scope := "public"
+	if r.Header.Get("Authorization") != "" {
+		scope = "private"
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d, stale-if-error=%d", scope, int(maxAge.Seconds()), rateStaleIfErrorSecs))
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// etagMatches reports whether the If-None-Match header value matches etag, using weak comparison as required for GET
+func etagMatches(ifNoneMatch, etag string) bool {
+
+	if ifNoneMatch == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,68 @@
+package httpapi
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/contract"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/connectors/contractlog"
+	"github.com/loveyourstack/lys"
+)
+
+// DatasetContract is the data contract of a dataset and its JSON Schema, as returned by GET /contracts
+type DatasetContract struct {
+	contract.Contract
+	Schema map[string]any `json:"schema"`
+}
+
+// AddContractRoutes adds the data contracts of the datasets of connectors, and the changes between their versions published to db by the migrate command, to mux:
+//
+//	GET /contracts
+//	GET /contracts/changes?since=2024-01-16T09:00:00Z
+func AddContractRoutes(mux *http.ServeMux, connectors []registry.Connector, db *pgxpool.Pool, errorLog *slog.Logger) {
+	mux.HandleFunc("GET /contracts", Contracts(connectors))
+	mux.HandleFunc("GET /contracts/changes", ContractChanges(db, errorLog))
+}
+
+// Contracts returns the current data contract of each dataset of connectors which describes its fields, with its JSON Schema
+func Contracts(connectors []registry.Connector) http.HandlerFunc {
+
+	// contracts do not change while serving
+	contracts := registry.Contracts(connectors)
+	items := make([]DatasetContract, len(contracts))
+	for i, c := range contracts {
+		items[i] = DatasetContract{Contract: c, Schema: c.JSONSchema()}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		lys.JsonResponse(lys.StdResponse{Status: lys.ReqSucceeded, Data: items}, http.StatusOK, w)
+	}
+}
+
+// ContractChanges returns the contract versions published after since (RFC 3339, default all), oldest first, with their changes from the previous version and whether they are breaking
+// consumers poll it with the published_at of the last version they saw to detect breaking changes of the shape of the datasets
+func ContractChanges(db *pgxpool.Pool, errorLog *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		var since time.Time
+		if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+			var err error
+			since, err = time.Parse(time.RFC3339, sinceStr)
+			if err != nil {
+				lys.HandleUserError(http.StatusBadRequest, "invalid since, expected RFC 3339, e.g. 2024-01-16T09:00:00Z: "+sinceStr, w)
+				return
+			}
+		}
+
+		items, err := contractlog.Store{Db: db}.SelectSince(r.Context(), since)
+		if err != nil {
+			lys.HandleError(r.Context(), err, errorLog, w)
+			return
+		}
+
+		lys.JsonResponse(lys.StdResponse{Status: lys.ReqSucceeded, Data: items}, http.StatusOK, w)
+	}
+}
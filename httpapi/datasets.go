@@ -0,0 +1,36 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/lys"
+)
+
+// ConnectorDatasets is a connector and the descriptors of its datasets, as returned by GET /datasets
+type ConnectorDatasets struct {
+	Connector string             `json:"connector"`
+	Enabled   bool               `json:"enabled"`
+	Datasets  []registry.Dataset `json:"datasets"`
+}
+
+// AddDatasetRoutes adds the catalog of the datasets of connectors to mux, so that catalog tools can document what the installation syncs:
+//
+//	GET /datasets
+func AddDatasetRoutes(mux *http.ServeMux, connectors []registry.Connector) {
+	mux.HandleFunc("GET /datasets", Datasets(connectors))
+}
+
+// Datasets returns the descriptors of the datasets of each of connectors: source, update cadence, license, dimensions and fields
+func Datasets(connectors []registry.Connector) http.HandlerFunc {
+
+	// connectors and their datasets do not change while serving
+	catalog := make([]ConnectorDatasets, len(connectors))
+	for i, conn := range connectors {
+		catalog[i] = ConnectorDatasets{Connector: conn.Name(), Enabled: registry.IsEnabled(conn), Datasets: conn.Datasets()}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		lys.JsonResponse(lys.StdResponse{Status: lys.ReqSucceeded, Data: catalog}, http.StatusOK, w)
+	}
+}
@@ -0,0 +1,89 @@
+package httpapi
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/converter"
+	"github.com/loveyourstack/connectors/export"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/loveyourstack/lys"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// AddExportRoutes adds the export of the exchange rate history of db to mux:
+//
+//	GET /export/exchange_rates?format=arrows&base=EUR&freq=D&from=2020-01-01&to=2024-12-31
+func AddExportRoutes(mux *http.ServeMux, db *pgxpool.Pool, errorLog *slog.Logger) {
+	mux.HandleFunc("GET /export/exchange_rates", ExportExchangeRates(db, errorLog))
+}
+
+// ExportExchangeRates returns the rates from base (default EUR) with frequency freq (default D) between from (default 1999-01-01) and to (default today) as a file
+// in format: csv (the default), jsonl, parquet, arrow, arrows or xlsx. Rates from another base than EUR are derived from the stored EUR rates, see converter.Converter.RangeRates. The Arrow stream, arrows, can be read by pyarrow.ipc.open_stream without CSV parsing
+func ExportExchangeRates(db *pgxpool.Pool, errorLog *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		q := r.URL.Query()
+
+		format := export.CSV
+		if formatStr := q.Get("format"); formatStr != "" {
+			format = export.Format(formatStr)
+		}
+		if err := format.Validate(); err != nil {
+			lys.HandleUserError(http.StatusBadRequest, err.Error(), w)
+			return
+		}
+
+		baseCurr := "EUR"
+		if baseStr := q.Get("base"); baseStr != "" {
+			baseCurr = strings.ToUpper(baseStr)
+		}
+
+		freq := ecbexchangerate.Daily
+		if freqStr := q.Get("freq"); freqStr != "" {
+			var err error
+			freq, err = ecbexchangerate.ParseFrequency(freqStr)
+			if err != nil {
+				lys.HandleUserError(http.StatusBadRequest, "invalid freq, expected D or M: "+freqStr, w)
+				return
+			}
+		}
+
+		startDate := time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)
+		if fromStr := q.Get("from"); fromStr != "" {
+			var err error
+			startDate, err = time.Parse(lystype.DateFormat, fromStr)
+			if err != nil {
+				lys.HandleUserError(http.StatusBadRequest, "invalid from, expected YYYY-MM-DD: "+fromStr, w)
+				return
+			}
+		}
+		endDate := time.Now()
+		if toStr := q.Get("to"); toStr != "" {
+			var err error
+			endDate, err = time.Parse(lystype.DateFormat, toStr)
+			if err != nil {
+				lys.HandleUserError(http.StatusBadRequest, "invalid to, expected YYYY-MM-DD: "+toStr, w)
+				return
+			}
+		}
+
+		items, err := converter.Converter{Db: db}.RangeRates(r.Context(), baseCurr, freq, startDate, endDate)
+		if err != nil {
+			lys.HandleError(r.Context(), err, errorLog, w)
+			return
+		}
+
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", `attachment; filename="exchange_rates.`+format.Ext()+`"`)
+
+		// the status is sent with the first bytes, so a failure while writing can only be logged
+		if err = export.WriteExchangeRates(w, items, format); err != nil {
+			errorLog.Error("export.WriteExchangeRates failed", "format", format.String(), clog.KeyError, err.Error())
+		}
+	}
+}
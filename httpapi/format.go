@@ -0,0 +1,113 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/loveyourstack/connectors/converter"
+	"github.com/loveyourstack/connectors/valuation"
+)
+
+// RateFormat sets the decimals of the numbers written by the rate API. If it is zero, they are written as computed: the ECB rates from EUR as published,
+// and the cross rates and converted amounts with all their digits
+type RateFormat struct {
+	Decimals         int            `toml:"decimals"`         // decimals of the rates, if > 0, e.g. 4 as published by the ECB for most currencies
+	CurrencyDecimals map[string]int `toml:"currencyDecimals"` // decimals of the rates to particular currencies, overriding Decimals, e.g. { JPY = 2, HUF = 2 }
+	MinorUnitAmounts bool           `toml:"minorUnitAmounts"` // if true, converted amounts are written to the minor unit of their currency, e.g. cents, see valuation.MinorUnits
+}
+
+// IsZero returns true if f sets no decimals, so that numbers are written as computed
+func (f RateFormat) IsZero() bool {
+	return f.Decimals == 0 && len(f.CurrencyDecimals) == 0 && !f.MinorUnitAmounts
+}
+
+// Validate returns an error if a number of decimals is negative
+func (f RateFormat) Validate() error {
+
+	if f.Decimals < 0 {
+		return fmt.Errorf("decimals must not be negative")
+	}
+	for curr, decimals := range f.CurrencyDecimals {
+		if decimals < 0 {
+			return fmt.Errorf("currencyDecimals: decimals of %s must not be negative", curr)
+		}
+	}
+	return nil
+}
+
+// rate returns the JSON number of rate, a rate to currency curr
+func (f RateFormat) rate(rate float64, curr string) json.Number {
+
+	decimals, ok := f.CurrencyDecimals[curr]
+	if !ok {
+		decimals = f.Decimals
+	}
+	if decimals == 0 && !ok {
+		return floatNumber(rate)
+	}
+	return json.Number(strconv.FormatFloat(rate, 'f', decimals, 64))
+}
+
+// amount returns the JSON number of amount, an amount in currency curr
+func (f RateFormat) amount(amount float64, curr string) json.Number {
+
+	if !f.MinorUnitAmounts {
+		return floatNumber(amount)
+	}
+	return json.Number(strconv.FormatFloat(amount, 'f', valuation.MinorUnits(curr), 64))
+}
+
+// floatNumber returns the JSON number of v with all its digits, as encoding/json writes a float64
+func floatNumber(v float64) json.Number {
+	b, _ := json.Marshal(v)
+	return json.Number(b)
+}
+
+// formattedRates is converter.Rates with the rates formatted by a RateFormat
+type formattedRates struct {
+	Base string `json:"base"`
+	converter.Fixing
+	Rates map[string]json.Number `json:"rates"`
+}
+
+// formattedConversion is converter.Conversion with the rate and amounts formatted by a RateFormat
+type formattedConversion struct {
+	From   string      `json:"from"`
+	To     string      `json:"to"`
+	Amount json.Number `json:"amount"`
+	Rate   json.Number `json:"rate"`
+	Result json.Number `json:"result"`
+	converter.Fixing
+}
+
+// formatRates returns the response data of rates: rates itself if f is zero
+func (f RateFormat) formatRates(rates converter.Rates) any {
+
+	if f.IsZero() {
+		return rates
+	}
+
+	res := formattedRates{Base: rates.Base, Fixing: rates.Fixing, Rates: make(map[string]json.Number, len(rates.Rates))}
+	for curr, rate := range rates.Rates {
+		res.Rates[curr] = f.rate(rate, curr)
+	}
+	return res
+}
+
+// formatConversion returns the response data of conv: conv itself if f is zero
+func (f RateFormat) formatConversion(conv converter.Conversion) any {
+
+	if f.IsZero() {
+		return conv
+	}
+
+	return formattedConversion{
+		From:   conv.From,
+		To:     conv.To,
+		Amount: f.amount(conv.Amount, conv.From),
+		Rate:   f.rate(conv.Rate, conv.To),
+		Result: f.amount(conv.Result, conv.To),
+		Fixing: conv.Fixing,
+	}
+}
@@ -0,0 +1,271 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/converter"
+	"github.com/loveyourstack/connectors/internal/graphql"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbcurrency"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	// maxGraphQLBytes is the size limit of GraphQL request bodies
+	maxGraphQLBytes int64 = 1 << 20
+
+	// maxGraphQLRates is the most exchange rates returned by a query, and the default limit
+	maxGraphQLRates int = 10000
+)
+
+// AddGraphQLRoutes adds a GraphQL endpoint over currencies, exchange rates and conversions to mux, see GraphQLSchema:
+//
+//	POST /graphql
+//	GET /graphql?query=...&variables=...
+func AddGraphQLRoutes(mux *http.ServeMux, db *pgxpool.Pool, conv converter.Converter, format RateFormat, errorLog *slog.Logger) {
+	h := GraphQL(GraphQLSchema(db, conv, format, errorLog))
+	mux.HandleFunc("POST /graphql", h)
+	mux.HandleFunc("GET /graphql", h)
+}
+
+// GraphQL executes the GraphQL request of r against schema. Responses are GraphQL responses, not lys.StdResponses, so that GraphQL clients can read them
+// requests which fail before execution, e.g. with a syntax error, have status 400
+func GraphQL(schema graphql.Schema) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		var req graphql.Request
+		if r.Method == http.MethodGet {
+			q := r.URL.Query()
+			req.Query, req.OperationName = q.Get("query"), q.Get("operationName")
+			if varsStr := q.Get("variables"); varsStr != "" {
+				if err := json.Unmarshal([]byte(varsStr), &req.Variables); err != nil {
+					writeGraphQLResponse(graphql.Response{Errors: []graphql.Error{{Message: "invalid variables: " + err.Error()}}}, w)
+					return
+				}
+			}
+		} else {
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLBytes)).Decode(&req); err != nil {
+				writeGraphQLResponse(graphql.Response{Errors: []graphql.Error{{Message: "invalid request body: " + err.Error()}}}, w)
+				return
+			}
+		}
+		if strings.TrimSpace(req.Query) == "" {
+			writeGraphQLResponse(graphql.Response{Errors: []graphql.Error{{Message: "query is required"}}}, w)
+			return
+		}
+
+		writeGraphQLResponse(schema.Execute(r.Context(), req), w)
+	}
+}
+
+func writeGraphQLResponse(res graphql.Response, w http.ResponseWriter) {
+
+	status := http.StatusOK
+	if res.Data == nil {
+		status = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
+
+// GraphQLSchema returns the GraphQL schema of the currencies and exchange rates of db, and of the conversions of conv, formatted by format:
+//
+//	currencies(codes: [String]): [Currency]
+//	exchangeRates(from: String = "EUR", to: [String], freq: String = "D", start: String!, end: String, order: String = "ASC", limit: Int = 10000): [ExchangeRate]
+//	convert(from: String!, to: String!, amount: Float = 1, date: String, knownAt: String): Conversion
+//
+// the fields of Currency and ExchangeRate are the JSON names of the stores' Models. Dates are YYYY-MM-DD, knownAt RFC 3339
+func GraphQLSchema(db *pgxpool.Pool, conv converter.Converter, format RateFormat, errorLog *slog.Logger) graphql.Schema {
+
+	currStore := ecbcurrency.Store{Db: db}
+	xrStore := ecbexchangerate.Store{Db: db}
+
+	return graphql.Schema{
+		Query: map[string]graphql.RootField{
+			"currencies": {
+				Type: graphql.NewObject("Currency", currStore.GetMeta().JsonTags...),
+				Args: []string{"codes"},
+				Resolve: func(ctx context.Context, args graphql.Args) (any, error) {
+					codes, err := args.Strings("codes")
+					if err != nil {
+						return nil, err
+					}
+					var params lyspg.SelectParams
+					if len(codes) > 0 {
+						params.Conditions = []lyspg.Condition{{Field: "code", Operator: lyspg.OpIn, InValues: upperAll(codes)}}
+					}
+					items, _, err := currStore.Select(ctx, params)
+					if err != nil {
+						return nil, fmt.Errorf("currStore.Select failed: %w", err)
+					}
+					return items, nil
+				},
+			},
+			"exchangeRates": {
+				Type: graphql.NewObject("ExchangeRate", xrStore.GetMeta().JsonTags...),
+				Args: []string{"from", "to", "freq", "start", "end", "order", "limit"},
+				Resolve: func(ctx context.Context, args graphql.Args) (any, error) {
+					return resolveExchangeRates(ctx, xrStore, args)
+				},
+			},
+			"convert": {
+				Type: graphql.NewObject("Conversion", "from", "to", "amount", "rate", "result", "day", "frequency", "fallback"),
+				Args: []string{"from", "to", "amount", "date", "knownAt"},
+				Resolve: func(ctx context.Context, args graphql.Args) (any, error) {
+					return resolveConvert(ctx, conv, format, args)
+				},
+			},
+		},
+		ErrorMessage: graphQLErrorMessage(errorLog),
+	}
+}
+
+func resolveExchangeRates(ctx context.Context, xrStore ecbexchangerate.Store, args graphql.Args) (any, error) {
+
+	from, err := args.String("from", "EUR")
+	if err != nil {
+		return nil, err
+	}
+	to, err := args.Strings("to")
+	if err != nil {
+		return nil, err
+	}
+	freqStr, err := args.String("freq", ecbexchangerate.Daily.String())
+	if err != nil {
+		return nil, err
+	}
+	freq, err := ecbexchangerate.ParseFrequency(freqStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid freq: %s", cerrors.ErrValidationFailed, freqStr)
+	}
+	startDate, err := dateArg(args, "start")
+	if err != nil {
+		return nil, err
+	}
+	if startDate.IsZero() {
+		return nil, fmt.Errorf("%w: start is required", cerrors.ErrValidationFailed)
+	}
+	endDate, err := dateArg(args, "end")
+	if err != nil {
+		return nil, err
+	}
+	if endDate.IsZero() {
+		endDate = time.Now()
+	}
+	orderStr, err := args.String("order", "ASC")
+	if err != nil {
+		return nil, err
+	}
+	order := ecbexchangerate.OrderNaturalKey
+	switch strings.ToUpper(orderStr) {
+	case "ASC":
+	case "DESC":
+		order = ecbexchangerate.OrderNaturalKeyDesc
+	default:
+		return nil, fmt.Errorf("%w: invalid order, expected ASC or DESC: %s", cerrors.ErrValidationFailed, orderStr)
+	}
+	limit, err := args.Int("limit", maxGraphQLRates)
+	if err != nil {
+		return nil, err
+	}
+	if limit < 1 || limit > maxGraphQLRates {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", cerrors.ErrValidationFailed, maxGraphQLRates)
+	}
+
+	params := ecbexchangerate.Query{}.ForPair(strings.ToUpper(from), "").ToCurrencies(upperAll(to)...).WithFrequency(freq).InRange(startDate, endDate).OrderBy(order).Params()
+	params.Limit = limit
+
+	items, _, err := xrStore.Select(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("xrStore.Select failed: %w", err)
+	}
+	return items, nil
+}
+
+func resolveConvert(ctx context.Context, conv converter.Converter, format RateFormat, args graphql.Args) (any, error) {
+
+	from, err := args.String("from", "")
+	if err != nil {
+		return nil, err
+	}
+	to, err := args.String("to", "")
+	if err != nil {
+		return nil, err
+	}
+	if from == "" || to == "" {
+		return nil, fmt.Errorf("%w: from and to are required", cerrors.ErrValidationFailed)
+	}
+	amount, err := args.Float("amount", 1)
+	if err != nil {
+		return nil, err
+	}
+	day, err := dateArg(args, "date")
+	if err != nil {
+		return nil, err
+	}
+	knownAtStr, err := args.String("knownAt", "")
+	if err != nil {
+		return nil, err
+	}
+	if knownAtStr != "" {
+		conv.KnownAt, err = time.Parse(time.RFC3339, knownAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid knownAt, expected RFC 3339, e.g. 2024-01-16T09:00:00Z: %s", cerrors.ErrValidationFailed, knownAtStr)
+		}
+	}
+
+	conversion, err := conv.Convert(ctx, strings.ToUpper(from), strings.ToUpper(to), amount, day)
+	if err != nil {
+		return nil, fmt.Errorf("conv.Convert failed: %w", err)
+	}
+	return format.formatConversion(conversion), nil
+}
+
+// dateArg returns the date argument name, or the zero time if it is missing
+func dateArg(args graphql.Args, name string) (time.Time, error) {
+
+	s, err := args.String(name, "")
+	if err != nil || s == "" {
+		return time.Time{}, err
+	}
+	day, err := time.Parse(lystype.DateFormat, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid %s, expected YYYY-MM-DD: %s", cerrors.ErrValidationFailed, name, s)
+	}
+	return day, nil
+}
+
+func upperAll(ss []string) []string {
+
+	upper := make([]string, len(ss))
+	for i, s := range ss {
+		upper[i] = strings.ToUpper(s)
+	}
+	return upper
+}
+
+// graphQLErrorMessage returns the message of the GraphQL error of err: err itself if caused by the request, e.g. an unknown currency, and a generic message otherwise, logging err
+func graphQLErrorMessage(errorLog *slog.Logger) func(ctx context.Context, err error) string {
+	return func(ctx context.Context, err error) string {
+
+		if errors.Is(err, cerrors.ErrNotFound) || errors.Is(err, cerrors.ErrValidationFailed) {
+			return err.Error()
+		}
+		if !errors.Is(err, context.Canceled) {
+			errorLog.Error("graphql resolver failed", clog.KeyError, err.Error())
+		}
+		return "internal server error"
+	}
+}
@@ -0,0 +1,161 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+)
+
+// health status values
+const (
+	StatusOk     string = "ok"
+	StatusFailed string = "failed"
+)
+
+// default timeout applied to all checks of a single readiness request
+const defaultCheckTimeout time.Duration = 5 * time.Second
+
+// Check is a single named dependency check run by the readiness endpoint
+type Check struct {
+	Name     string
+	Optional bool // if true, a failure is reported but does not make the service unready
+	Func     func(ctx context.Context) error
+}
+
+// CheckResult is the outcome of a single Check
+type CheckResult struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Optional   bool   `json:"optional,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// HealthResponse is the JSON body returned by the health endpoints
+type HealthResponse struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks,omitempty"`
+}
+
+// AddHealthRoutes adds GET /healthz (liveness) and GET /readyz (readiness, running checks) to mux
+func AddHealthRoutes(mux *http.ServeMux, checks []Check) {
+	mux.HandleFunc("GET /healthz", Healthz())
+	mux.HandleFunc("GET /readyz", Readyz(checks, defaultCheckTimeout))
+}
+
+// Healthz is a liveness probe: it only confirms that the process is able to serve HTTP requests
+func Healthz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeHealthResponse(HealthResponse{Status: StatusOk}, http.StatusOK, w)
+	}
+}
+
+// Readyz is a readiness probe: it runs all checks concurrently and returns 503 if any non-optional check fails
+func Readyz(checks []Check, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		resp := RunChecks(ctx, checks)
+
+		httpStatus := http.StatusOK
+		if resp.Status != StatusOk {
+			httpStatus = http.StatusServiceUnavailable
+		}
+		writeHealthResponse(resp, httpStatus, w)
+	}
+}
+
+// RunChecks runs the supplied checks concurrently and returns the combined result
+func RunChecks(ctx context.Context, checks []Check) (resp HealthResponse) {
+
+	resp.Status = StatusOk
+	resp.Checks = make([]CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			start := time.Now()
+			res := CheckResult{Name: check.Name, Status: StatusOk, Optional: check.Optional}
+			if err := check.Func(ctx); err != nil {
+				res.Status = StatusFailed
+				res.Error = err.Error()
+			}
+			res.DurationMs = time.Since(start).Milliseconds()
+			resp.Checks[i] = res
+		}()
+	}
+	wg.Wait()
+
+	for _, res := range resp.Checks {
+		if res.Status != StatusOk && !res.Optional {
+			resp.Status = StatusFailed
+		}
+	}
+
+	return resp
+}
+
+// DbCheck returns a check that pings the database
+func DbCheck(db *pgxpool.Pool) Check {
+	return Check{
+		Name: "db",
+		Func: func(ctx context.Context) error {
+			return db.Ping(ctx)
+		},
+	}
+}
+
+// SchemaCheck returns a check that verifies that the supplied tables exist in schemaName
+func SchemaCheck(db *pgxpool.Pool, schemaName string, tableNames []string) Check {
+	return Check{
+		Name: "schema " + schemaName,
+		Func: func(ctx context.Context) error {
+			for _, tableName := range tableNames {
+				var exists bool
+				err := db.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL;", schemaName+"."+tableName).Scan(&exists)
+				if err != nil {
+					return fmt.Errorf("db.QueryRow failed: %w", err)
+				}
+				if !exists {
+					return fmt.Errorf("table %s.%s not found", schemaName, tableName)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// EcbCheck returns an optional check that verifies that the ECB data API is reachable
+func EcbCheck(c ecbapi.Client) Check {
+	return Check{
+		Name:     "ecb api",
+		Optional: true,
+		Func:     c.Ping,
+	}
+}
+
+func writeHealthResponse(resp HealthResponse, httpStatus int, w http.ResponseWriter) {
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		// should never happen
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(httpStatus)
+	w.Write(body)
+}
@@ -0,0 +1,42 @@
+package httpapi
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/loveyourstack/connectors/clog"
+)
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// LogRequests returns middleware that logs each request with method, path, status and duration. Health probes are logged at debug level
+func LogRequests(infoLog *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		start := time.Now()
+		sr := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sr, r)
+
+		level := slog.LevelInfo
+		if r.URL.Path == "/healthz" || r.URL.Path == "/readyz" {
+			level = slog.LevelDebug
+		}
+
+		infoLog.Log(r.Context(), level, "http request",
+			slog.String(clog.KeyMethod, r.Method),
+			slog.String(clog.KeyPath, r.URL.Path),
+			slog.Int(clog.KeyStatus, sr.status),
+			slog.Int64(clog.KeyDurationMs, time.Since(start).Milliseconds()),
+		)
+	})
+}
@@ -0,0 +1,119 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/converter"
+	"github.com/loveyourstack/lys"
+	"github.com/loveyourstack/lys/lyserr"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// AddRateRoutes adds the read-only rate API to mux:
+//
+//	GET /latest?base=EUR&symbols=USD,GBP
+//	GET /convert?from=USD&to=GBP&amount=100&date=2024-01-15&known_at=2024-01-16T09:00:00Z
+//
+// the numbers of the responses are written with the decimals set by format
+func AddRateRoutes(mux *http.ServeMux, conv converter.Converter, format RateFormat, errorLog *slog.Logger) {
+	mux.HandleFunc("GET /latest", Latest(conv, format, errorLog))
+	mux.HandleFunc("GET /convert", Convert(conv, format, errorLog))
+}
+
+// Latest returns the latest rates from base (default EUR), optionally limited to the comma-separated symbols
+func Latest(conv converter.Converter, format RateFormat, errorLog *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		base := strings.ToUpper(r.URL.Query().Get("base"))
+		if base == "" {
+			base = "EUR"
+		}
+
+		rates, err := conv.Rates(r.Context(), base, time.Time{})
+		if err != nil {
+			handleRateError(r.Context(), err, errorLog, w)
+			return
+		}
+
+		if symbolsStr := r.URL.Query().Get("symbols"); symbolsStr != "" {
+			filtered := make(map[string]float64)
+			for _, symbol := range strings.Split(strings.ToUpper(symbolsStr), ",") {
+				if rate, ok := rates.Rates[symbol]; ok {
+					filtered[symbol] = rate
+				}
+			}
+			rates.Rates = filtered
+		}
+
+		writeCachedResponse(lys.StdResponse{Status: lys.ReqSucceeded, Data: format.formatRates(rates)}, rateMaxAge(time.Time(rates.Day), time.Now()), w, r)
+	}
+}
+
+// Convert converts amount (default 1) between from and to, using the fixing of date (YYYY-MM-DD, default latest)
+// with known_at (RFC 3339), the rates are those stored at that time, see converter.Converter.KnownAt
+func Convert(conv converter.Converter, format RateFormat, errorLog *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		q := r.URL.Query()
+		from, to := strings.ToUpper(q.Get("from")), strings.ToUpper(q.Get("to"))
+		if from == "" || to == "" {
+			lys.HandleUserError(http.StatusBadRequest, "from and to are required", w)
+			return
+		}
+
+		amount := 1.0
+		if amountStr := q.Get("amount"); amountStr != "" {
+			var err error
+			amount, err = strconv.ParseFloat(amountStr, 64)
+			if err != nil {
+				lys.HandleUserError(http.StatusBadRequest, "invalid amount: "+amountStr, w)
+				return
+			}
+		}
+
+		var day time.Time
+		if dateStr := q.Get("date"); dateStr != "" {
+			var err error
+			day, err = time.Parse(lystype.DateFormat, dateStr)
+			if err != nil {
+				lys.HandleUserError(http.StatusBadRequest, "invalid date, expected YYYY-MM-DD: "+dateStr, w)
+				return
+			}
+		}
+
+		if knownAtStr := q.Get("known_at"); knownAtStr != "" {
+			var err error
+			conv.KnownAt, err = time.Parse(time.RFC3339, knownAtStr)
+			if err != nil {
+				lys.HandleUserError(http.StatusBadRequest, "invalid known_at, expected RFC 3339, e.g. 2024-01-16T09:00:00Z: "+knownAtStr, w)
+				return
+			}
+		}
+
+		conversion, err := conv.Convert(r.Context(), from, to, amount, day)
+		if err != nil {
+			handleRateError(r.Context(), err, errorLog, w)
+			return
+		}
+
+		writeCachedResponse(lys.StdResponse{Status: lys.ReqSucceeded, Data: format.formatConversion(conversion)}, rateMaxAge(time.Time(conversion.Day), time.Now()), w, r)
+	}
+}
+
+func handleRateError(ctx context.Context, err error, errorLog *slog.Logger, w http.ResponseWriter) {
+
+	switch {
+	case errors.Is(err, cerrors.ErrNotFound):
+		err = lyserr.User{Message: err.Error(), StatusCode: http.StatusNotFound}
+	case errors.Is(err, cerrors.ErrValidationFailed):
+		err = lyserr.User{Message: err.Error(), StatusCode: http.StatusBadRequest}
+	}
+	lys.HandleError(ctx, err, errorLog, w)
+}
@@ -0,0 +1,56 @@
+package httpapi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSConfig configures HTTPS. If CertFile and KeyFile are empty, the server uses plain HTTP
+type TLSConfig struct {
+	CertFile     string `toml:"certFile"`     // PEM certificate (chain)
+	KeyFile      string `toml:"keyFile"`      // PEM private key
+	ClientCAFile string `toml:"clientCaFile"` // optional PEM CA bundle: if set, clients must present a certificate signed by it (mTLS)
+}
+
+// Enabled returns true if a certificate is configured
+func (conf TLSConfig) Enabled() bool {
+	return conf.CertFile != "" || conf.KeyFile != ""
+}
+
+// Build returns the *tls.Config for conf, loading the certificate and client CA files. Returns nil if conf is not Enabled
+func (conf TLSConfig) Build() (*tls.Config, error) {
+
+	if !conf.Enabled() {
+		return nil, nil
+	}
+	if conf.CertFile == "" || conf.KeyFile == "" {
+		return nil, fmt.Errorf("certFile and keyFile must both be set")
+	}
+
+	cert, err := tls.LoadX509KeyPair(conf.CertFile, conf.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("tls.LoadX509KeyPair failed: %w", err)
+	}
+
+	tlsConf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if conf.ClientCAFile != "" {
+		caPem, err := os.ReadFile(conf.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("os.ReadFile failed: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPem) {
+			return nil, fmt.Errorf("no certificates found in clientCaFile %s", conf.ClientCAFile)
+		}
+		tlsConf.ClientCAs = pool
+		tlsConf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConf, nil
+}
@@ -0,0 +1,155 @@
+// Package integrity declares and checks FK-like relationships between the tables of connector schemas which the database does not enforce,
+// e.g. that the currency of each Stripe balance transaction is an ECB currency code, since each connector's schema is migrated and synced on its own
+package integrity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+)
+
+// DefaultSampleSize is the number of orphaned values returned per relation by Check if sampleSize is not set
+const DefaultSampleSize int = 5
+
+// Relation is a reference from a column of one table to a key column of another, usually in another connector's schema
+type Relation struct {
+	Table      string `toml:"table" json:"table"`                      // referencing table, schema qualified, e.g. stripe.balance_transaction
+	Column     string `toml:"column" json:"column"`                    // referencing column, e.g. currency
+	RefTable   string `toml:"refTable" json:"ref_table"`               // referenced table, schema qualified, e.g. ecb.currency
+	RefColumn  string `toml:"refColumn" json:"ref_column"`             // referenced column, which should be unique, e.g. code
+	AllowEmpty bool   `toml:"allowEmpty" json:"allow_empty,omitempty"` // an empty string references nothing, as NULL always does, e.g. the currency of an order without amounts. Text columns only
+}
+
+// String returns r as e.g. "stripe.balance_transaction.currency -> ecb.currency.code"
+func (r Relation) String() string {
+	return fmt.Sprintf("%s.%s -> %s.%s", r.Table, r.Column, r.RefTable, r.RefColumn)
+}
+
+// Validate returns an error if a field of r is missing or a table is not schema qualified
+func (r Relation) Validate() error {
+
+	if r.Column == "" || r.RefColumn == "" {
+		return fmt.Errorf("%w: column and refColumn are required", cerrors.ErrValidationFailed)
+	}
+	if _, err := tableIdentifier(r.Table); err != nil {
+		return fmt.Errorf("table: %w", err)
+	}
+	if _, err := tableIdentifier(r.RefTable); err != nil {
+		return fmt.Errorf("refTable: %w", err)
+	}
+	return nil
+}
+
+// Relations are relations declared in the [[relations]] config tables, in addition to those of the connectors
+type Relations []Relation
+
+// Validate returns an error naming the first invalid relation
+func (rs Relations) Validate() error {
+
+	for i, r := range rs {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("relation %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Result is the outcome of checking a Relation
+type Result struct {
+	Relation Relation `json:"relation"`
+	Skipped  bool     `json:"skipped"`           // a table does not exist, e.g. since its connector was never migrated
+	Checked  int64    `json:"checked"`           // referencing rows with a value
+	Orphans  int64    `json:"orphans"`           // referencing rows whose value is not in the referenced column
+	Samples  []string `json:"samples,omitempty"` // distinct orphaned values, as text, in order
+}
+
+// OK returns true if r has no orphans
+func (r Result) OK() bool {
+	return r.Orphans == 0
+}
+
+// Check counts the rows of each relation of rels in db whose value is not found in the referenced column, with up to sampleSize of their distinct values
+// relations whose tables don't exist are skipped. A relation whose check fails, e.g. since its columns have different types, doesn't stop the others:
+// its error is joined to the returned error
+func Check(ctx context.Context, db *pgxpool.Pool, rels []Relation, sampleSize int) (results []Result, err error) {
+
+	if sampleSize < 1 {
+		sampleSize = DefaultSampleSize
+	}
+
+	var errs []error
+	for _, rel := range rels {
+		res, err := check(ctx, db, rel, sampleSize)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("check failed for %s: %w", rel, err))
+			continue
+		}
+		results = append(results, res)
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// check checks a single relation
+func check(ctx context.Context, db *pgxpool.Pool, rel Relation, sampleSize int) (res Result, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, 0)
+	defer cancel()
+
+	res.Relation = rel
+
+	if err = rel.Validate(); err != nil {
+		return res, err
+	}
+	table, _ := tableIdentifier(rel.Table)
+	refTable, _ := tableIdentifier(rel.RefTable)
+
+	var tableExists, refTableExists bool
+	if err = db.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL, to_regclass($2) IS NOT NULL;", table.Sanitize(), refTable.Sanitize()).Scan(&tableExists, &refTableExists); err != nil {
+		return res, fmt.Errorf("db.QueryRow (to_regclass) failed: %w", err)
+	}
+	if !tableExists || !refTableExists {
+		res.Skipped = true
+		return res, nil
+	}
+
+	col := "a." + pgx.Identifier{rel.Column}.Sanitize()
+	hasValue := col + " IS NOT NULL"
+	if rel.AllowEmpty {
+		hasValue += " AND " + col + " <> ''"
+	}
+	orphaned := fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s r WHERE r.%s = %s)", refTable.Sanitize(), pgx.Identifier{rel.RefColumn}.Sanitize(), col)
+
+	stmt := fmt.Sprintf("SELECT count(*), count(*) FILTER (WHERE %s) FROM %s a WHERE %s;", orphaned, table.Sanitize(), hasValue)
+	if err = db.QueryRow(ctx, stmt).Scan(&res.Checked, &res.Orphans); err != nil {
+		return res, fmt.Errorf("db.QueryRow (count) failed: %w", err)
+	}
+	if res.Orphans == 0 {
+		return res, nil
+	}
+
+	stmt = fmt.Sprintf("SELECT DISTINCT %[1]s::text FROM %[2]s a WHERE %[3]s AND %[4]s ORDER BY 1 LIMIT %[5]d;", col, table.Sanitize(), hasValue, orphaned, sampleSize)
+	rows, _ := db.Query(ctx, stmt)
+	res.Samples, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return res, fmt.Errorf("pgx.CollectRows (samples) failed: %w", err)
+	}
+
+	return res, nil
+}
+
+// tableIdentifier splits a schema qualified table name
+func tableIdentifier(table string) (pgx.Identifier, error) {
+
+	schema, tbl, ok := strings.Cut(table, ".")
+	if !ok || schema == "" || tbl == "" || strings.Contains(tbl, ".") {
+		return nil, fmt.Errorf("%w: table must be schema qualified, e.g. ecb.currency: %q", cerrors.ErrValidationFailed, table)
+	}
+	return pgx.Identifier{schema, tbl}, nil
+}
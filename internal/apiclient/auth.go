@@ -0,0 +1,58 @@
+package apiclient
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Auth authorizes outbound API requests, e.g. by setting a header. Its strategies are shared by the API clients, so that none reimplements them
+type Auth interface {
+	Authorize(req *http.Request) error
+}
+
+// APIKeyHeader sends Key in header Name, preceded by Prefix, e.g. "DHL-API-Key: <key>" or "Authorization: Bearer <key>"
+type APIKeyHeader struct {
+	Name   string
+	Prefix string
+	Key    string
+}
+
+func (a APIKeyHeader) Authorize(req *http.Request) error {
+	req.Header.Set(a.Name, a.Prefix+a.Key)
+	return nil
+}
+
+// Bearer returns the APIKeyHeader sending token as bearer token in the Authorization header
+func Bearer(token string) APIKeyHeader {
+	return APIKeyHeader{Name: "Authorization", Prefix: "Bearer ", Key: token}
+}
+
+// APIKeyQuery sends Key as query param Param, e.g. "api_key=<key>"
+type APIKeyQuery struct {
+	Param string
+	Key   string
+}
+
+func (a APIKeyQuery) Authorize(req *http.Request) error {
+	q := req.URL.Query()
+	q.Set(a.Param, a.Key)
+	req.URL.RawQuery = q.Encode()
+	return nil
+}
+
+// Redact returns err with the key removed from its message. Errors of the HTTP client contain the request URL, and so the key
+func (a APIKeyQuery) Redact(err error) error {
+	return fmt.Errorf("%s", strings.ReplaceAll(err.Error(), a.Key, "REDACTED"))
+}
+
+// Basic sends HTTP Basic credentials, e.g. an API key as Username with an empty Password
+type Basic struct {
+	Username string
+	Password string
+}
+
+func (a Basic) Authorize(req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
@@ -0,0 +1,64 @@
+package apiclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HMACSigner signs requests with the hex encoded HMAC-SHA256 of a message built from the request, by default "<timestamp>.<body>"
+type HMACSigner struct {
+	Secret          string
+	Header          string                                                       // header of the signature, e.g. X-Connectors-Signature
+	Prefix          string                                                       // precedes the signature, e.g. "sha256="
+	TimestampHeader string                                                       // if set, the Unix timestamp of the signature is sent in it
+	Message         func(timestamp int64, req *http.Request, body []byte) []byte // the signed message. Defaults to TimestampedBody
+}
+
+// TimestampedBody returns "<timestamp>.<body>", the message signed by default
+func TimestampedBody(timestamp int64, req *http.Request, body []byte) []byte {
+	return append([]byte(strconv.FormatInt(timestamp, 10)+"."), body...)
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of message using secret
+func Sign(secret string, message []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(message)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Authorize signs req. Its body is read with req.GetBody, so is left unread: requests with a body must be created from a bytes.Reader, bytes.Buffer or strings.Reader
+func (s HMACSigner) Authorize(req *http.Request) error {
+
+	var body []byte
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return fmt.Errorf("req.GetBody failed: %w", err)
+		}
+		defer rc.Close()
+		if body, err = io.ReadAll(rc); err != nil {
+			return fmt.Errorf("io.ReadAll failed: %w", err)
+		}
+	} else if req.Body != nil && req.Body != http.NoBody {
+		return fmt.Errorf("request body cannot be read again for signing")
+	}
+
+	message := s.Message
+	if message == nil {
+		message = TimestampedBody
+	}
+
+	timestamp := time.Now().Unix()
+	if s.TimestampHeader != "" {
+		req.Header.Set(s.TimestampHeader, strconv.FormatInt(timestamp, 10))
+	}
+	req.Header.Set(s.Header, s.Prefix+Sign(s.Secret, message(timestamp, req, body)))
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package apiclient
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limiter spaces the requests of a client by interval. A nil Limiter does not wait
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time // earliest time of the next request
+}
+
+func NewLimiter(interval time.Duration) *Limiter {
+	return &Limiter{interval: interval}
+}
+
+// Wait blocks until the next request may be made, or returns ctx.Err() once ctx is done
+func (l *Limiter) Wait(ctx context.Context) error {
+
+	if l == nil {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	return Sleep(ctx, delay)
+}
+
+// Pause delays the next request by at least d, e.g. the Retry-After of a rate limited response
+func (l *Limiter) Pause(d time.Duration) {
+
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if resume := time.Now().Add(d); l.next.Before(resume) {
+		l.next = resume
+	}
+}
+
+// ParseRetryAfter returns the delay of a Retry-After header in seconds, which some APIs send as a decimal, e.g. 2.0, or fallback
+func ParseRetryAfter(header string, fallback time.Duration) time.Duration {
+
+	secs, err := strconv.ParseFloat(header, 64)
+	if err != nil || secs <= 0 {
+		return fallback
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// Sleep waits for d, or returns ctx.Err() once ctx is done
+func Sleep(ctx context.Context, d time.Duration) error {
+
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,69 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiterWait(t *testing.T) {
+
+	ctx := context.Background()
+	l := NewLimiter(50 * time.Millisecond)
+
+	start := time.Now()
+	for range 3 {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatalf("l.Wait failed: %s", err.Error())
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("3 requests: got %s, want at least 100ms", elapsed)
+	}
+
+	// a nil Limiter does not wait
+	var nilLimiter *Limiter
+	if err := nilLimiter.Wait(ctx); err != nil {
+		t.Errorf("nil Limiter: got %v, want nil", err)
+	}
+}
+
+func TestLimiterCanceledWhilePaused(t *testing.T) {
+
+	l := NewLimiter(time.Millisecond)
+	l.Pause(time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("l.Wait: got %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("returned after %s, want without waiting for the pause", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"5", 5 * time.Second},
+		{"2.0", 2 * time.Second},
+		{"0.5", 500 * time.Millisecond},
+		{"", time.Minute},
+		{"0", time.Minute},
+		{"-1", time.Minute},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := ParseRetryAfter(tt.header, time.Minute); got != tt.want {
+			t.Errorf("ParseRetryAfter('%s'): got %s, want %s", tt.header, got, tt.want)
+		}
+	}
+}
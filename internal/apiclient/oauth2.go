@@ -0,0 +1,93 @@
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// OAuth2 requests access tokens at an OAuth 2.0 token endpoint: with the refresh token grant if RefreshToken is set, e.g. of a seller's consent to the app,
+// and otherwise with the client credentials grant
+type OAuth2 struct {
+	HttpClient   *http.Client
+	TokenUrl     string
+	ClientId     string
+	ClientSecret string
+	RefreshToken string
+	Scopes       []string // sent as scope if set
+	BasicAuth    bool     // send the client credentials as HTTP Basic credentials rather than as form fields
+	Source       string   // API shortname of upstream errors, e.g. "ebay"
+}
+
+// RequestToken requests an access token. Rejected credentials (400 or 401), e.g. invalid_grant if the consent was revoked, fail with cerrors.ErrValidationFailed
+func (o OAuth2) RequestToken(ctx context.Context) (Token, error) {
+
+	form := url.Values{}
+	if o.RefreshToken != "" {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", o.RefreshToken)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+	if len(o.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.Scopes, " "))
+	}
+	if !o.BasicAuth {
+		form.Set("client_id", o.ClientId)
+		form.Set("client_secret", o.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.TokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Token{}, fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if o.BasicAuth {
+		req.SetBasicAuth(o.ClientId, o.ClientSecret)
+	}
+
+	resp, err := o.HttpClient.Do(req)
+	if err != nil {
+		return Token{}, fmt.Errorf("o.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: o.Source, Err: err})
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Token{}, fmt.Errorf("io.ReadAll failed: %w", cerrors.UpstreamError{Source: o.Source, Err: err})
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized:
+		return Token{}, fmt.Errorf("%w: OAuth status %d: %s", cerrors.ErrValidationFailed, resp.StatusCode, string(body))
+	default:
+		return Token{}, cerrors.UpstreamError{Source: o.Source, StatusCode: resp.StatusCode}
+	}
+
+	return ParseTokenJson(body)
+}
+
+// ParseTokenJson parses the response of an OAuth 2.0 token endpoint, e.g. {"access_token":"...","token_type":"bearer","expires_in":3600}
+func ParseTokenJson(content []byte) (Token, error) {
+
+	respS := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}{}
+	if err := json.Unmarshal(content, &respS); err != nil {
+		return Token{}, fmt.Errorf("%w: json.Unmarshal failed: %w", cerrors.ErrValidationFailed, err)
+	}
+	if respS.AccessToken == "" {
+		return Token{}, fmt.Errorf("%w: no access token", cerrors.ErrValidationFailed)
+	}
+
+	return Token{Value: respS.AccessToken, ExpiresIn: time.Duration(respS.ExpiresIn) * time.Second}, nil
+}
@@ -0,0 +1,207 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// defaults of Paging
+const (
+	DefaultMaxPages    int = 10000
+	DefaultMaxAttempts int = 3
+	DefaultBackoff         = 2 * time.Second
+)
+
+// Paging bounds the requests of the pagination helpers, so that each API client's "fetch all pages" loop is a single call with the same retries and limits
+// the zero value applies the defaults. The helpers take the ctx of the requests and stop with ctx.Err() once it is done, also while waiting to retry a page
+type Paging struct {
+	MaxPages    int           // the loop fails with cerrors.ErrValidationFailed after this many pages, e.g. if a cursor never ends. Defaults to DefaultMaxPages
+	MaxAttempts int           // per page, while its request fails with a retryable cerrors.UpstreamError. Defaults to DefaultMaxAttempts
+	Backoff     time.Duration // wait before the second attempt of a page, doubled before each further one. Defaults to DefaultBackoff
+	Log         *slog.Logger  // logs the retries if set
+}
+
+func (p Paging) maxPages() int {
+	if p.MaxPages < 1 {
+		return DefaultMaxPages
+	}
+	return p.MaxPages
+}
+
+func (p Paging) maxAttempts() int {
+	if p.MaxAttempts < 1 {
+		return DefaultMaxAttempts
+	}
+	return p.MaxAttempts
+}
+
+func (p Paging) backoff() time.Duration {
+	if p.Backoff <= 0 {
+		return DefaultBackoff
+	}
+	return p.Backoff
+}
+
+// Cursor requests the pages of a cursor paginated endpoint: get requests the page of cursor, which is "" for the first page, and handle processes it,
+// returning the cursor of the next page, or "" after the last one. E.g. Stripe's starting_after or HubSpot's after
+func Cursor[T any](ctx context.Context, p Paging, get func(cursor string) (T, error), handle func(page T) (next string, err error)) error {
+
+	cursor := ""
+	for pageNum := 1; ; pageNum++ {
+		if pageNum > p.maxPages() {
+			return fmt.Errorf("%w: more than %d pages", cerrors.ErrValidationFailed, p.maxPages())
+		}
+
+		page, err := retry(ctx, p, func() (T, error) { return get(cursor) })
+		if err != nil {
+			return fmt.Errorf("get failed on page %d: %w", pageNum, err)
+		}
+		if cursor, err = handle(page); err != nil {
+			return fmt.Errorf("handle failed on page %d: %w", pageNum, err)
+		}
+		if cursor == "" {
+			return nil
+		}
+	}
+}
+
+// PageNumbers requests the pages of an endpoint paginated by page number, starting with first, e.g. 0 or 1: get requests page number pageNum,
+// and handle processes it, returning false after the last page
+func PageNumbers[T any](ctx context.Context, p Paging, first int, get func(pageNum int) (T, error), handle func(page T) (more bool, err error)) error {
+
+	for pageNum := first; ; pageNum++ {
+		if pageNum-first >= p.maxPages() {
+			return fmt.Errorf("%w: more than %d pages", cerrors.ErrValidationFailed, p.maxPages())
+		}
+
+		page, err := retry(ctx, p, func() (T, error) { return get(pageNum) })
+		if err != nil {
+			return fmt.Errorf("get failed on page %d: %w", pageNum, err)
+		}
+		more, err := handle(page)
+		if err != nil {
+			return fmt.Errorf("handle failed on page %d: %w", pageNum, err)
+		}
+		if !more {
+			return nil
+		}
+	}
+}
+
+// Offsets requests the pages of an endpoint paginated by offset and limit, size being the limit: get requests the page starting at offset,
+// and handle processes it, returning false after the last page, e.g. if it holds fewer than size items
+func Offsets[T any](ctx context.Context, p Paging, size int, get func(offset int) (T, error), handle func(page T) (more bool, err error)) error {
+
+	if size < 1 {
+		return fmt.Errorf("%w: size must be at least 1", cerrors.ErrValidationFailed)
+	}
+	return PageNumbers(ctx, p, 0, func(pageNum int) (T, error) { return get(pageNum * size) }, handle)
+}
+
+// LinkHeader requests the pages of an endpoint paginated by the rel="next" URL of the Link header, starting with firstUrl: get requests reqUrl,
+// returning the page and the response header, and handle processes the page. E.g. Shopify's page_info cursors
+func LinkHeader[T any](ctx context.Context, p Paging, firstUrl string, get func(reqUrl string) (T, http.Header, error), handle func(page T) error) error {
+
+	type linkedPage struct {
+		page   T
+		header http.Header
+	}
+
+	return Cursor(ctx, p,
+		func(cursor string) (linkedPage, error) {
+			if cursor == "" {
+				cursor = firstUrl
+			}
+			page, header, err := get(cursor)
+			return linkedPage{page: page, header: header}, err
+		},
+		func(lp linkedPage) (string, error) {
+			if err := handle(lp.page); err != nil {
+				return "", err
+			}
+			return NextLink(lp.header.Get("Link")), nil
+		})
+}
+
+// nextLinkRx matches the next page URL of a Link header, e.g. <https://acme.myshopify.com/admin/api/2024-07/products.json?limit=250&page_info=abc>; rel="next"
+var nextLinkRx = regexp.MustCompile(`<([^>]+)>;\s*rel="?next"?`)
+
+// NextLink returns the next page URL of a Link header, or "" on the last page
+func NextLink(header string) string {
+
+	m := nextLinkRx.FindStringSubmatch(header)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// Chunks requests the data between startDate and endDate (inclusive) in consecutive chunks of at most days days, as needed by endpoints which limit
+// the period of a request, e.g. SDMX series: get requests the chunk from chunkStart to chunkEnd, and handle processes it
+func Chunks[T any](ctx context.Context, p Paging, startDate, endDate time.Time, days int, get func(chunkStart, chunkEnd time.Time) (T, error), handle func(chunkStart, chunkEnd time.Time, chunk T) error) error {
+
+	if days < 1 {
+		return fmt.Errorf("%w: days must be at least 1", cerrors.ErrValidationFailed)
+	}
+
+	pageNum := 0
+	for chunkStart := startDate; !chunkStart.After(endDate); chunkStart = chunkStart.AddDate(0, 0, days) {
+
+		pageNum++
+		if pageNum > p.maxPages() {
+			return fmt.Errorf("%w: more than %d chunks", cerrors.ErrValidationFailed, p.maxPages())
+		}
+
+		chunkEnd := chunkStart.AddDate(0, 0, days-1)
+		if chunkEnd.After(endDate) {
+			chunkEnd = endDate
+		}
+
+		chunk, err := retry(ctx, p, func() (T, error) { return get(chunkStart, chunkEnd) })
+		if err != nil {
+			return fmt.Errorf("get failed for %s to %s: %w", chunkStart.Format(time.DateOnly), chunkEnd.Format(time.DateOnly), err)
+		}
+		if err = handle(chunkStart, chunkEnd, chunk); err != nil {
+			return fmt.Errorf("handle failed for %s to %s: %w", chunkStart.Format(time.DateOnly), chunkEnd.Format(time.DateOnly), err)
+		}
+	}
+
+	return nil
+}
+
+// retry calls get until it succeeds, fails with an error which is not a retryable cerrors.UpstreamError, or p.MaxAttempts is reached
+// clients retrying rate limited requests themselves, with the delay given by the API, only see their final failure retried here
+// returns ctx.Err() without calling get if ctx is done, including while waiting to retry
+func retry[T any](ctx context.Context, p Paging, get func() (T, error)) (T, error) {
+
+	var zero T
+
+	wait := p.backoff()
+	for attempt := 1; ; attempt++ {
+
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+
+		page, err := get()
+		var upErr cerrors.UpstreamError
+		if err == nil || attempt >= p.maxAttempts() || !errors.As(err, &upErr) || !upErr.Retryable() {
+			return page, err
+		}
+
+		if p.Log != nil {
+			p.Log.Warn("request failed, retrying", "attempt", attempt, "retry_after", wait.String(), "error", err.Error())
+		}
+		if err := Sleep(ctx, wait); err != nil {
+			return zero, err
+		}
+		wait *= 2
+	}
+}
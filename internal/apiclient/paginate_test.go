@@ -0,0 +1,87 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+func TestCursorCanceledWhileWaiting(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	get := func(cursor string) (string, error) {
+		calls++
+		cancel()
+		return "", cerrors.UpstreamError{Source: "test", StatusCode: 503}
+	}
+	handle := func(page string) (string, error) { return "", nil }
+
+	start := time.Now()
+	err := Cursor(ctx, Paging{Backoff: time.Hour}, get, handle)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("returned after %s, expected without waiting for the backoff", elapsed)
+	}
+	if calls != 1 {
+		t.Errorf("calls: got %d, want 1", calls)
+	}
+}
+
+func TestChunksCanceled(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	get := func(chunkStart, chunkEnd time.Time) (int, error) {
+		t.Fatal("get called with ctx done")
+		return 0, nil
+	}
+	handle := func(chunkStart, chunkEnd time.Time, chunk int) error { return nil }
+
+	startDate := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := Chunks(ctx, Paging{}, startDate, startDate.AddDate(0, 0, 30), 7, get, handle); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got: %v", err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		errs      []error // returned by the successive attempts, nil once exhausted
+		wantCalls int
+		wantErr   bool
+	}{
+		{"success", nil, 1, false},
+		{"retryable then success", []error{cerrors.UpstreamError{StatusCode: 502}}, 2, false},
+		{"not retryable", []error{cerrors.UpstreamError{StatusCode: 404}}, 1, true},
+		{"other error", []error{errors.New("parse failed")}, 1, true},
+		{"max attempts", []error{cerrors.UpstreamError{StatusCode: 429}, cerrors.UpstreamError{StatusCode: 429}, cerrors.UpstreamError{StatusCode: 429}}, 3, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			calls := 0
+			_, err := retry(context.Background(), Paging{Backoff: time.Millisecond}, func() (int, error) {
+				calls++
+				if calls <= len(tt.errs) {
+					return 0, tt.errs[calls-1]
+				}
+				return calls, nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err: %v, wantErr %t", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls: got %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package apiclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// tokenMargin is the time before expiry at which cached tokens are renewed
+const tokenMargin = time.Minute
+
+// Token is an access token valid for ExpiresIn from when it was issued
+type Token struct {
+	Value     string
+	ExpiresIn time.Duration
+}
+
+// TokenCache caches an access token until shortly before it expires. It is safe for concurrent use, and shared by copies of the client holding a pointer to it
+// a nil *TokenCache does not cache: each Get requests a new token
+type TokenCache struct {
+	mu        sync.Mutex
+	value     string
+	expiresAt time.Time
+}
+
+// Get returns the cached token if it is valid for at least another minute, and otherwise the token returned by request, which it caches
+// request is passed on each call rather than held by the cache, so that it uses the client's current settings. It is called with ctx
+func (tc *TokenCache) Get(ctx context.Context, request func(ctx context.Context) (Token, error)) (string, error) {
+
+	if tc == nil {
+		token, err := request(ctx)
+		if err != nil {
+			return "", fmt.Errorf("request failed: %w", err)
+		}
+		return token.Value, nil
+	}
+
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	if tc.value != "" && time.Now().Add(tokenMargin).Before(tc.expiresAt) {
+		return tc.value, nil
+	}
+
+	token, err := request(ctx)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	tc.value = token.Value
+	tc.expiresAt = time.Now().Add(token.ExpiresIn)
+
+	return token.Value, nil
+}
+
+// Reset drops the cached token, e.g. after the API rejected it, so that the next Get requests a new one
+func (tc *TokenCache) Reset() {
+
+	if tc == nil {
+		return
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.value = ""
+}
+
+// TokenAuth sends the token of Cache, requested by Request with the context of the request when needed, as bearer token
+type TokenAuth struct {
+	Cache   *TokenCache
+	Request func(ctx context.Context) (Token, error)
+}
+
+func (a TokenAuth) Authorize(req *http.Request) error {
+
+	token, err := a.Cache.Get(req.Context(), a.Request)
+	if err != nil {
+		return fmt.Errorf("a.Cache.Get failed: %w", err)
+	}
+	return Bearer(token).Authorize(req)
+}
@@ -0,0 +1,162 @@
+package arrow
+
+import (
+	"encoding/binary"
+)
+
+// fbBuilder builds a FlatBuffer back to front, as the reference builders do: objects are prepended, so that the objects a table refers to are built before it
+// offsets are measured from the end of the buffer, which is fixed while building. It supports the tables, vectors and structs of the Arrow metadata only
+type fbBuilder struct {
+	data     []byte // the buffer built so far
+	minAlign int
+	fields   []uint32 // offsets of the fields of the table being built, by slot. 0 if absent
+	tableEnd uint32
+}
+
+func (b *fbBuilder) offset() uint32 {
+	return uint32(len(b.data))
+}
+
+// place prepends v
+func (b *fbBuilder) place(v []byte) {
+	b.data = append(append(make([]byte, 0, len(v)+len(b.data)), v...), b.data...)
+}
+
+// prep pads the buffer so that a value of size bytes prepended after additional bytes is aligned to size
+func (b *fbBuilder) prep(size, additional int) {
+
+	if size > b.minAlign {
+		b.minAlign = size
+	}
+	if n := -(len(b.data) + additional) & (size - 1); n > 0 {
+		b.place(make([]byte, n))
+	}
+}
+
+func (b *fbBuilder) prependUint8(v uint8) {
+	b.prep(1, 0)
+	b.place([]byte{v})
+}
+
+func (b *fbBuilder) prependUint16(v uint16) {
+	b.prep(2, 0)
+	b.place(binary.LittleEndian.AppendUint16(nil, v))
+}
+
+func (b *fbBuilder) prependUint32(v uint32) {
+	b.prep(4, 0)
+	b.place(binary.LittleEndian.AppendUint32(nil, v))
+}
+
+func (b *fbBuilder) prependUint64(v uint64) {
+	b.prep(8, 0)
+	b.place(binary.LittleEndian.AppendUint64(nil, v))
+}
+
+// prependOffset prepends a reference to the object at off, relative to the reference itself
+func (b *fbBuilder) prependOffset(off uint32) {
+	b.prep(4, 0)
+	b.place(binary.LittleEndian.AppendUint32(nil, b.offset()-off+4))
+}
+
+// createString returns the offset of the string s
+func (b *fbBuilder) createString(s string) uint32 {
+
+	b.prep(4, len(s)+1)
+	b.place(append([]byte(s), 0))
+	b.place(binary.LittleEndian.AppendUint32(nil, uint32(len(s))))
+	return b.offset()
+}
+
+// createOffsetVector returns the offset of the vector of references to offs
+func (b *fbBuilder) createOffsetVector(offs []uint32) uint32 {
+
+	b.prep(4, 4*len(offs))
+	for i := len(offs) - 1; i >= 0; i-- {
+		b.prependOffset(offs[i])
+	}
+	b.place(binary.LittleEndian.AppendUint32(nil, uint32(len(offs))))
+	return b.offset()
+}
+
+// createStructVector returns the offset of the vector of structs, each of size bytes and aligned to 8 bytes, prepended in turn by prependStruct from the last
+func (b *fbBuilder) createStructVector(n, size int, prependStruct func(i int)) uint32 {
+
+	b.prep(4, size*n)
+	b.prep(8, size*n)
+	for i := n - 1; i >= 0; i-- {
+		prependStruct(i)
+	}
+	b.place(binary.LittleEndian.AppendUint32(nil, uint32(n)))
+	return b.offset()
+}
+
+// startTable starts a table with numFields field slots. Its fields are added with the add funcs, after the objects they refer to are built
+func (b *fbBuilder) startTable(numFields int) {
+	b.fields = make([]uint32, numFields)
+	b.tableEnd = b.offset()
+}
+
+func (b *fbBuilder) addUint8(slot int, v uint8) {
+	b.prependUint8(v)
+	b.fields[slot] = b.offset()
+}
+
+func (b *fbBuilder) addInt16(slot int, v int16) {
+	b.prependUint16(uint16(v))
+	b.fields[slot] = b.offset()
+}
+
+func (b *fbBuilder) addInt32(slot int, v int32) {
+	b.prependUint32(uint32(v))
+	b.fields[slot] = b.offset()
+}
+
+func (b *fbBuilder) addInt64(slot int, v int64) {
+	b.prependUint64(uint64(v))
+	b.fields[slot] = b.offset()
+}
+
+func (b *fbBuilder) addBool(slot int, v bool) {
+	var u uint8
+	if v {
+		u = 1
+	}
+	b.addUint8(slot, u)
+}
+
+func (b *fbBuilder) addOffset(slot int, off uint32) {
+	b.prependOffset(off)
+	b.fields[slot] = b.offset()
+}
+
+// endTable writes the table started by startTable and its vtable, and returns the table's offset
+func (b *fbBuilder) endTable() uint32 {
+
+	// the table starts with the signed offset of its vtable, written once the vtable is
+	b.prependUint32(0)
+	tableOffset := b.offset()
+
+	for i := len(b.fields) - 1; i >= 0; i-- {
+		var fieldOffset uint16
+		if b.fields[i] != 0 {
+			fieldOffset = uint16(tableOffset - b.fields[i])
+		}
+		b.prependUint16(fieldOffset)
+	}
+	b.prependUint16(uint16(tableOffset - b.tableEnd))
+	b.prependUint16(uint16(2 * (len(b.fields) + 2)))
+
+	vtableOffset := b.offset()
+	binary.LittleEndian.PutUint32(b.data[len(b.data)-int(tableOffset):], uint32(int32(vtableOffset)-int32(tableOffset)))
+
+	b.fields = nil
+	return tableOffset
+}
+
+// finish prepends the reference to the root table and returns the buffer
+func (b *fbBuilder) finish(root uint32) []byte {
+	b.prep(b.minAlign, 4)
+	b.prependOffset(root)
+	return b.data
+}
@@ -0,0 +1,399 @@
+// Package arrow is a minimal, dependency-free Apache Arrow IPC writer.
+// It supports flat schemas of non-nullable columns and uncompressed record batches, in the file format (Feather v2) and the streaming format, which is all the exporters need.
+package arrow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Type is the type of a column, mapping to an Arrow logical type
+type Type int
+
+const (
+	String    Type = iota // Utf8
+	Date                  // Date32 (days since epoch)
+	Float                 // FloatingPoint SINGLE
+	Double                // FloatingPoint DOUBLE
+	Int64                 // Int 64, signed
+	Timestamp             // Timestamp MILLISECOND, UTC
+)
+
+// Column defines a single column of the schema
+type Column struct {
+	Name string
+	Type Type
+}
+
+// Schema.fbs and Message.fbs enum values
+const (
+	metadataV5 int16 = 4
+
+	headerSchema      uint8 = 1
+	headerRecordBatch uint8 = 3
+
+	typeInt           uint8 = 2
+	typeFloatingPoint uint8 = 3
+	typeUtf8          uint8 = 5
+	typeDate          uint8 = 8
+	typeTimestamp     uint8 = 10
+
+	precisionSingle int16 = 1
+	precisionDouble int16 = 2
+	dateUnitDay     int16 = 0
+	timeUnitMilli   int16 = 1
+)
+
+const (
+	fileMagic        string = "ARROW1"
+	continuation     uint32 = 0xFFFFFFFF
+	defaultBatchSize int    = 65536
+)
+
+// block locates a message in the file, for the footer
+type block struct {
+	offset         int64
+	metaDataLength int32
+	bodyLength     int64
+}
+
+// Writer writes rows as Arrow record batches. Rows are buffered in memory and written as one record batch every BatchSize rows
+type Writer struct {
+	BatchSize int
+
+	w       io.Writer
+	offset  int64
+	stream  bool // streaming format: no magic or footer
+	cols    []Column
+	values  []bytes.Buffer // values of the current batch, one per column. The UTF-8 bytes of strings
+	offsets [][]int32      // end offsets of the strings of the current batch in values, per column
+	numRows int
+	batches []block
+}
+
+// NewWriter writes the file header and schema to w and returns a Writer of an Arrow IPC file with the supplied columns, readable e.g. by pandas.read_feather
+func NewWriter(w io.Writer, cols []Column) (aw *Writer, err error) {
+	return newWriter(w, cols, false)
+}
+
+// NewStreamWriter writes the schema to w and returns a Writer of an Arrow IPC stream with the supplied columns, readable e.g. by pyarrow.ipc.open_stream
+// unlike a file, a stream can be read while being written, e.g. from an HTTP response
+func NewStreamWriter(w io.Writer, cols []Column) (aw *Writer, err error) {
+	return newWriter(w, cols, true)
+}
+
+func newWriter(w io.Writer, cols []Column, stream bool) (aw *Writer, err error) {
+
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("cols has len 0")
+	}
+	for _, col := range cols {
+		if col.Type < String || col.Type > Timestamp {
+			return nil, fmt.Errorf("column %s: unknown type %d", col.Name, col.Type)
+		}
+	}
+
+	aw = &Writer{
+		BatchSize: defaultBatchSize,
+		w:         w,
+		stream:    stream,
+		cols:      cols,
+		values:    make([]bytes.Buffer, len(cols)),
+		offsets:   make([][]int32, len(cols)),
+	}
+
+	if !stream {
+		// the magic is padded to 8 bytes
+		if err = aw.write([]byte(fileMagic + "\x00\x00")); err != nil {
+			return nil, fmt.Errorf("aw.write failed: %w", err)
+		}
+	}
+
+	b := &fbBuilder{}
+	if _, err = aw.writeMessage(aw.message(b, headerSchema, aw.schema(b), 0), nil); err != nil {
+		return nil, fmt.Errorf("aw.writeMessage (schema) failed: %w", err)
+	}
+
+	return aw, nil
+}
+
+// WriteRow buffers a single row. vals must match the column types: string, time.Time (Date, Timestamp), float32, float64 or int64
+func (aw *Writer) WriteRow(vals ...any) error {
+
+	if len(vals) != len(aw.cols) {
+		return fmt.Errorf("expected %d values, got %d", len(aw.cols), len(vals))
+	}
+
+	for i, col := range aw.cols {
+		buf := &aw.values[i]
+
+		switch col.Type {
+		case String:
+			v, ok := vals[i].(string)
+			if !ok {
+				return fmt.Errorf("column %s: expected string, got %T", col.Name, vals[i])
+			}
+			buf.WriteString(v)
+			aw.offsets[i] = append(aw.offsets[i], int32(buf.Len()))
+
+		case Date:
+			v, ok := vals[i].(time.Time)
+			if !ok {
+				return fmt.Errorf("column %s: expected time.Time, got %T", col.Name, vals[i])
+			}
+			days := time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+			binary.Write(buf, binary.LittleEndian, int32(days))
+
+		case Float:
+			v, ok := vals[i].(float32)
+			if !ok {
+				return fmt.Errorf("column %s: expected float32, got %T", col.Name, vals[i])
+			}
+			binary.Write(buf, binary.LittleEndian, math.Float32bits(v))
+
+		case Double:
+			v, ok := vals[i].(float64)
+			if !ok {
+				return fmt.Errorf("column %s: expected float64, got %T", col.Name, vals[i])
+			}
+			binary.Write(buf, binary.LittleEndian, math.Float64bits(v))
+
+		case Int64:
+			v, ok := vals[i].(int64)
+			if !ok {
+				return fmt.Errorf("column %s: expected int64, got %T", col.Name, vals[i])
+			}
+			binary.Write(buf, binary.LittleEndian, v)
+
+		case Timestamp:
+			v, ok := vals[i].(time.Time)
+			if !ok {
+				return fmt.Errorf("column %s: expected time.Time, got %T", col.Name, vals[i])
+			}
+			binary.Write(buf, binary.LittleEndian, v.UnixMilli())
+		}
+	}
+
+	aw.numRows++
+	if aw.numRows >= aw.BatchSize {
+		if err := aw.flushBatch(); err != nil {
+			return fmt.Errorf("aw.flushBatch failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Close flushes any buffered rows and writes the end of stream marker and, for files, the footer. It does not close the underlying writer
+func (aw *Writer) Close() error {
+
+	if aw.numRows > 0 {
+		if err := aw.flushBatch(); err != nil {
+			return fmt.Errorf("aw.flushBatch failed: %w", err)
+		}
+	}
+
+	eos := binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, continuation), 0)
+	if err := aw.write(eos); err != nil {
+		return fmt.Errorf("aw.write (end of stream) failed: %w", err)
+	}
+	if aw.stream {
+		return nil
+	}
+
+	footer := aw.footer()
+	if err := aw.write(footer); err != nil {
+		return fmt.Errorf("aw.write (footer) failed: %w", err)
+	}
+	if err := binary.Write(aw.w, binary.LittleEndian, uint32(len(footer))); err != nil {
+		return fmt.Errorf("binary.Write (footer length) failed: %w", err)
+	}
+	if err := aw.write([]byte(fileMagic)); err != nil {
+		return fmt.Errorf("aw.write (magic) failed: %w", err)
+	}
+
+	return nil
+}
+
+// flushBatch writes the buffered values as a record batch. Each column has a validity buffer of length 0, since no value is null,
+// then its offsets if it is a string column, then its values. Buffers are padded to 8 bytes
+func (aw *Writer) flushBatch() error {
+
+	type buffer struct{ offset, length int64 }
+
+	var body bytes.Buffer
+	var buffers []buffer
+	addBuffer := func(b []byte) {
+		buffers = append(buffers, buffer{offset: int64(body.Len()), length: int64(len(b))})
+		body.Write(b)
+		body.Write(make([]byte, padding(len(b))))
+	}
+
+	for i, col := range aw.cols {
+		addBuffer(nil)
+		if col.Type == String {
+			offsets := make([]byte, 0, 4*(aw.numRows+1))
+			offsets = binary.LittleEndian.AppendUint32(offsets, 0)
+			for _, off := range aw.offsets[i] {
+				offsets = binary.LittleEndian.AppendUint32(offsets, uint32(off))
+			}
+			addBuffer(offsets)
+		}
+		addBuffer(aw.values[i].Bytes())
+	}
+
+	b := &fbBuilder{}
+	buffersVec := b.createStructVector(len(buffers), 16, func(i int) {
+		b.prependUint64(uint64(buffers[i].length))
+		b.prependUint64(uint64(buffers[i].offset))
+	})
+	nodesVec := b.createStructVector(len(aw.cols), 16, func(i int) {
+		b.prependUint64(0) // null count
+		b.prependUint64(uint64(aw.numRows))
+	})
+	b.startTable(3)
+	b.addInt64(0, int64(aw.numRows))
+	b.addOffset(1, nodesVec)
+	b.addOffset(2, buffersVec)
+	batch := b.endTable()
+
+	blk, err := aw.writeMessage(aw.message(b, headerRecordBatch, batch, int64(body.Len())), body.Bytes())
+	if err != nil {
+		return fmt.Errorf("aw.writeMessage failed: %w", err)
+	}
+	aw.batches = append(aw.batches, blk)
+
+	for i := range aw.cols {
+		aw.values[i].Reset()
+		aw.offsets[i] = aw.offsets[i][:0]
+	}
+	aw.numRows = 0
+
+	return nil
+}
+
+// schema builds the Schema table of the columns and returns its offset
+func (aw *Writer) schema(b *fbBuilder) uint32 {
+
+	fieldOffs := make([]uint32, len(aw.cols))
+	for i, col := range aw.cols {
+		name := b.createString(col.Name)
+
+		var typeType uint8
+		var tz uint32
+		if col.Type == Timestamp {
+			tz = b.createString("UTC")
+		}
+		switch col.Type {
+		case String:
+			typeType = typeUtf8
+			b.startTable(0)
+		case Date:
+			typeType = typeDate
+			b.startTable(1)
+			b.addInt16(0, dateUnitDay)
+		case Float, Double:
+			typeType = typeFloatingPoint
+			b.startTable(1)
+			if col.Type == Float {
+				b.addInt16(0, precisionSingle)
+			} else {
+				b.addInt16(0, precisionDouble)
+			}
+		case Int64:
+			typeType = typeInt
+			b.startTable(2)
+			b.addInt32(0, 64)
+			b.addBool(1, true)
+		case Timestamp:
+			typeType = typeTimestamp
+			b.startTable(2)
+			b.addInt16(0, timeUnitMilli)
+			b.addOffset(1, tz)
+		}
+		typ := b.endTable()
+
+		// readers require the children, even if empty
+		children := b.createOffsetVector(nil)
+
+		b.startTable(7)
+		b.addOffset(0, name)
+		b.addBool(1, false) // nullable
+		b.addUint8(2, typeType)
+		b.addOffset(3, typ)
+		b.addOffset(5, children)
+		fieldOffs[i] = b.endTable()
+	}
+	fields := b.createOffsetVector(fieldOffs)
+
+	b.startTable(4)
+	b.addInt16(0, 0) // little endian
+	b.addOffset(1, fields)
+	return b.endTable()
+}
+
+// message finishes b with a Message table of the header at headerOffset, and returns the buffer
+func (aw *Writer) message(b *fbBuilder, headerType uint8, headerOffset uint32, bodyLength int64) []byte {
+
+	b.startTable(5)
+	b.addInt64(3, bodyLength)
+	b.addOffset(2, headerOffset)
+	b.addInt16(0, metadataV5)
+	b.addUint8(1, headerType)
+	return b.finish(b.endTable())
+}
+
+// footer returns the Footer of the file, with the schema and the blocks of the record batches
+func (aw *Writer) footer() []byte {
+
+	b := &fbBuilder{}
+	blockVec := func(blocks []block) uint32 {
+		return b.createStructVector(len(blocks), 24, func(i int) {
+			b.prependUint64(uint64(blocks[i].bodyLength))
+			b.prependUint32(0) // padding
+			b.prependUint32(uint32(blocks[i].metaDataLength))
+			b.prependUint64(uint64(blocks[i].offset))
+		})
+	}
+	batches := blockVec(aw.batches)
+	dictionaries := blockVec(nil)
+	schema := aw.schema(b)
+
+	b.startTable(5)
+	b.addOffset(1, schema)
+	b.addOffset(2, dictionaries)
+	b.addOffset(3, batches)
+	b.addInt16(0, metadataV5)
+	return b.finish(b.endTable())
+}
+
+// writeMessage writes an encapsulated message: the continuation marker, the length of meta padded to 8 bytes, meta, its padding and body
+func (aw *Writer) writeMessage(meta, body []byte) (blk block, err error) {
+
+	padded := len(meta) + padding(len(meta))
+	blk = block{offset: aw.offset, metaDataLength: int32(8 + padded), bodyLength: int64(len(body))}
+
+	prefix := binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, continuation), uint32(padded))
+	for _, b := range [][]byte{prefix, meta, make([]byte, padded-len(meta)), body} {
+		if err = aw.write(b); err != nil {
+			return blk, err
+		}
+	}
+
+	return blk, nil
+}
+
+func (aw *Writer) write(b []byte) error {
+	n, err := aw.w.Write(b)
+	aw.offset += int64(n)
+	return err
+}
+
+// padding returns the number of bytes padding n bytes to a multiple of 8
+func padding(n int) int {
+	return -n & 7
+}
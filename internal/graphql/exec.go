@@ -0,0 +1,306 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// Object is an object type of a schema
+type Object struct {
+	Name   string
+	Fields map[string]*Object // the object type of each field, nil for scalars and lists of scalars
+}
+
+// NewObject returns the object type name with the scalar fields fields, e.g. the JSON tags of a store's Model
+func NewObject(name string, fields ...string) *Object {
+
+	o := &Object{Name: name, Fields: make(map[string]*Object, len(fields))}
+	for _, f := range fields {
+		o.Fields[f] = nil
+	}
+	return o
+}
+
+// RootField is a field of the query type
+type RootField struct {
+	Type    *Object  // object type of the result, or of its elements if it is a list
+	Args    []string // names of the accepted arguments
+	Resolve func(ctx context.Context, args Args) (any, error)
+}
+
+// Schema is the query type of a GraphQL endpoint
+type Schema struct {
+	Query map[string]RootField
+
+	// ErrorMessage returns the message of an error returned by a resolver, e.g. to hide internal errors. The error itself if nil
+	ErrorMessage func(ctx context.Context, err error) string
+}
+
+// Request is a GraphQL request, as sent in the body of a POST
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Error is a GraphQL error
+type Error struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"` // response keys leading to the field whose resolver failed
+}
+
+// Response is a GraphQL response
+type Response struct {
+	Data   *Ordered `json:"data"` // nil if the request failed before execution
+	Errors []Error  `json:"errors,omitempty"`
+}
+
+// Ordered is a JSON object whose keys are written in the order of the query, as GraphQL requires
+type Ordered struct {
+	keys   []string
+	values []any
+}
+
+func (o *Ordered) set(key string, v any) {
+	o.keys = append(o.keys, key)
+	o.values = append(o.values, v)
+}
+
+// MarshalJSON implements json.Marshaler
+func (o *Ordered) MarshalJSON() ([]byte, error) {
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		kb, _ := json.Marshal(k)
+		buf.Write(kb)
+		buf.WriteByte(':')
+		vb, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(vb)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Execute parses and validates req against s, then resolves its root fields in turn
+// errors of the request itself are returned without data. A failing resolver sets its field to null and adds an error, the other fields are still resolved
+func (s Schema) Execute(ctx context.Context, req Request) Response {
+
+	fields, err := Parse(req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	if err = s.validate(fields); err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	res := Response{Data: &Ordered{}}
+	for _, f := range fields {
+		if f.Name == "__typename" {
+			res.Data.set(f.Key(), "Query")
+			continue
+		}
+
+		rf := s.Query[f.Name]
+		v, err := rf.Resolve(ctx, f.Args)
+		if err == nil {
+			v, err = project(v, rf.Type, f.Selections)
+		}
+		if err != nil {
+			msg := err.Error()
+			if s.ErrorMessage != nil {
+				msg = s.ErrorMessage(ctx, err)
+			}
+			res.Errors = append(res.Errors, Error{Message: msg, Path: []any{f.Key()}})
+			v = nil
+		}
+		res.Data.set(f.Key(), v)
+	}
+
+	return res
+}
+
+// validate returns an error if fields select unknown fields or arguments, or do not select the subfields of objects
+func (s Schema) validate(fields []Field) error {
+
+	for _, f := range fields {
+		if f.Name == "__typename" {
+			continue
+		}
+		rf, ok := s.Query[f.Name]
+		if !ok {
+			return fmt.Errorf("cannot query field '%s' on type 'Query'", f.Name)
+		}
+		for arg := range f.Args {
+			if !slices.Contains(rf.Args, arg) {
+				return fmt.Errorf("unknown argument '%s' on field 'Query.%s'", arg, f.Name)
+			}
+		}
+		if err := validateSelections(f, rf.Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateSelections(f Field, obj *Object) error {
+
+	if obj == nil {
+		if len(f.Selections) > 0 {
+			return fmt.Errorf("field '%s' is a scalar and cannot have a selection", f.Name)
+		}
+		return nil
+	}
+	if len(f.Selections) == 0 {
+		return fmt.Errorf("field '%s' of type '%s' must have a selection of subfields", f.Name, obj.Name)
+	}
+
+	for _, sub := range f.Selections {
+		if len(sub.Args) > 0 {
+			return fmt.Errorf("field '%s.%s' has no arguments", obj.Name, sub.Name)
+		}
+		if sub.Name == "__typename" {
+			continue
+		}
+		subObj, ok := obj.Fields[sub.Name]
+		if !ok {
+			return fmt.Errorf("cannot query field '%s' on type '%s'", sub.Name, obj.Name)
+		}
+		if err := validateSelections(sub, subObj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// project returns the selections of v, the result of a resolver, as of its JSON encoding
+func project(v any, obj *Object, selections []Field) (any, error) {
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal failed: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var decoded any
+	if err = dec.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("dec.Decode failed: %w", err)
+	}
+
+	return selectFields(decoded, obj, selections), nil
+}
+
+func selectFields(v any, obj *Object, selections []Field) any {
+
+	switch v := v.(type) {
+	case []any:
+		for i, el := range v {
+			v[i] = selectFields(el, obj, selections)
+		}
+		return v
+	case map[string]any:
+		if obj == nil {
+			return v
+		}
+		o := &Ordered{}
+		for _, sub := range selections {
+			if sub.Name == "__typename" {
+				o.set(sub.Key(), obj.Name)
+				continue
+			}
+			// fields omitted from the JSON, e.g. by omitempty, are null
+			o.set(sub.Key(), selectFields(v[sub.Name], obj.Fields[sub.Name], sub.Selections))
+		}
+		return o
+	default:
+		return v
+	}
+}
+
+// Args are the argument values of a field: strings, int64s, float64s, bools, nil, []any and map[string]any, and the JSON decoded values of variables
+// the errors of its getters match cerrors.ErrValidationFailed
+type Args map[string]any
+
+// String returns the string argument name, or def if it is missing or null
+func (a Args) String(name, def string) (string, error) {
+
+	v, ok := a[name]
+	if !ok || v == nil {
+		return def, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: argument '%s' must be a string", cerrors.ErrValidationFailed, name)
+	}
+	return s, nil
+}
+
+// Strings returns the list of strings argument name, or nil if it is missing or null. A single string is a list of one, as GraphQL coerces it
+func (a Args) Strings(name string) ([]string, error) {
+
+	v, ok := a[name]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	if s, ok := v.(string); ok {
+		return []string{s}, nil
+	}
+	list, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: argument '%s' must be a list of strings", cerrors.ErrValidationFailed, name)
+	}
+	ss := make([]string, len(list))
+	for i, el := range list {
+		if ss[i], ok = el.(string); !ok {
+			return nil, fmt.Errorf("%w: argument '%s' must be a list of strings", cerrors.ErrValidationFailed, name)
+		}
+	}
+	return ss, nil
+}
+
+// Int returns the integer argument name, or def if it is missing or null
+func (a Args) Int(name string, def int) (int, error) {
+
+	v, ok := a[name]
+	if !ok || v == nil {
+		return def, nil
+	}
+	switch n := v.(type) {
+	case int64:
+		return int(n), nil
+	case float64:
+		// variables are decoded from JSON as float64
+		if n == math.Trunc(n) && math.Abs(n) <= math.MaxInt32 {
+			return int(n), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: argument '%s' must be an integer", cerrors.ErrValidationFailed, name)
+}
+
+// Float returns the number argument name, or def if it is missing or null
+func (a Args) Float(name string, def float64) (float64, error) {
+
+	v, ok := a[name]
+	if !ok || v == nil {
+		return def, nil
+	}
+	switch n := v.(type) {
+	case int64:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	}
+	return 0, fmt.Errorf("%w: argument '%s' must be a number", cerrors.ErrValidationFailed, name)
+}
@@ -0,0 +1,125 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type testRate struct {
+	Day  string  `json:"day"`
+	To   string  `json:"to"`
+	Rate float64 `json:"rate,omitempty"`
+}
+
+var testSchema = Schema{
+	Query: map[string]RootField{
+		"hello": {
+			Resolve: func(ctx context.Context, args Args) (any, error) {
+				return "world", nil
+			},
+		},
+		"rates": {
+			Type: NewObject("ExchangeRate", "day", "to", "rate"),
+			Args: []string{"to", "limit"},
+			Resolve: func(ctx context.Context, args Args) (any, error) {
+				to, err := args.Strings("to")
+				if err != nil {
+					return nil, err
+				}
+				limit, err := args.Int("limit", 10)
+				if err != nil {
+					return nil, err
+				}
+				var rates []testRate
+				for _, r := range []testRate{{"2024-09-02", "USD", 1.1061}, {"2024-09-02", "JPY", 161.63}, {"2024-09-02", "XXX", 0}} {
+					if len(rates) < limit && (to == nil || slices.Contains(to, r.To)) {
+						rates = append(rates, r)
+					}
+				}
+				return rates, nil
+			},
+		},
+		"fail": {
+			Resolve: func(ctx context.Context, args Args) (any, error) {
+				return nil, errors.New("resolver failed")
+			},
+		},
+	},
+}
+
+func TestExecute(t *testing.T) {
+
+	tests := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{"scalar", Request{Query: `{ hello }`},
+			`{"data":{"hello":"world"}}`},
+		{"aliases in query order", Request{Query: `{ b: hello, __typename, a: hello }`},
+			`{"data":{"b":"world","__typename":"Query","a":"world"}}`},
+		{"selections in query order", Request{Query: `{ rates(to: "USD") { rate __typename day } }`},
+			`{"data":{"rates":[{"rate":1.1061,"__typename":"ExchangeRate","day":"2024-09-02"}]}}`},
+		{"omitted field is null", Request{Query: `{ rates(to: ["XXX"]) { to rate } }`},
+			`{"data":{"rates":[{"to":"XXX","rate":null}]}}`},
+		{"variables", Request{Query: `query($to: [String], $limit: Int) { rates(to: $to, limit: $limit) { to } }`, Variables: map[string]any{"to": []any{"USD", "JPY"}, "limit": 1.0}},
+			`{"data":{"rates":[{"to":"USD"}]}}`},
+		{"operation name", Request{Query: `query A { hello } query B { r: rates(limit: 2) { to } }`, OperationName: "B"},
+			`{"data":{"r":[{"to":"USD"},{"to":"JPY"}]}}`},
+		{"failing resolver", Request{Query: `{ fail hello }`},
+			`{"data":{"fail":null,"hello":"world"},"errors":[{"message":"resolver failed","path":["fail"]}]}`},
+		{"invalid argument", Request{Query: `{ hello r: rates(limit: "all") { to } }`},
+			`{"data":{"hello":"world","r":null},"errors":[{"message":"validation failed: argument 'limit' must be an integer","path":["r"]}]}`},
+		{"syntax error", Request{Query: `{ rates { to }`},
+			`{"data":null,"errors":[{"message":"unterminated selection set"}]}`},
+		{"fragment", Request{Query: `{ rates { ...RateFields } }`},
+			`{"data":null,"errors":[{"message":"fragments are not supported"}]}`},
+		{"too deep", Request{Query: `{ rates ` + strings.Repeat("{ to ", maxDepth) + strings.Repeat("}", maxDepth) + ` }`},
+			`{"data":null,"errors":[{"message":"query is nested deeper than 16 levels at position 83"}]}`},
+		{"missing variable", Request{Query: `query($limit: Int!) { rates(limit: $limit) { to } }`},
+			`{"data":null,"errors":[{"message":"variable $limit is required"}]}`},
+		{"unknown field", Request{Query: `{ hello nope }`},
+			`{"data":null,"errors":[{"message":"cannot query field 'nope' on type 'Query'"}]}`},
+		{"unknown argument", Request{Query: `{ hello(name: "x") }`},
+			`{"data":null,"errors":[{"message":"unknown argument 'name' on field 'Query.hello'"}]}`},
+		{"scalar with selection", Request{Query: `{ hello { length } }`},
+			`{"data":null,"errors":[{"message":"field 'hello' is a scalar and cannot have a selection"}]}`},
+		{"object without selection", Request{Query: `{ rates }`},
+			`{"data":null,"errors":[{"message":"field 'rates' of type 'ExchangeRate' must have a selection of subfields"}]}`},
+		{"unknown subfield", Request{Query: `{ rates { to nope } }`},
+			`{"data":null,"errors":[{"message":"cannot query field 'nope' on type 'ExchangeRate'"}]}`},
+		{"subfield with arguments", Request{Query: `{ rates { to(upper: true) } }`},
+			`{"data":null,"errors":[{"message":"field 'ExchangeRate.to' has no arguments"}]}`},
+		{"scalar subfield with selection", Request{Query: `{ rates { to { code } } }`},
+			`{"data":null,"errors":[{"message":"field 'to' is a scalar and cannot have a selection"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(testSchema.Execute(context.Background(), tt.req))
+			if err != nil {
+				t.Fatalf("json.Marshal failed: %s", err.Error())
+			}
+			if string(b) != tt.want {
+				t.Errorf("got  %s\nwant %s", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteErrorMessage(t *testing.T) {
+
+	s := testSchema
+	s.ErrorMessage = func(ctx context.Context, err error) string {
+		return "internal error"
+	}
+
+	res := s.Execute(context.Background(), Request{Query: `{ fail }`})
+	if len(res.Errors) != 1 || res.Errors[0].Message != "internal error" {
+		t.Errorf("errors: got %+v, want internal error", res.Errors)
+	}
+}
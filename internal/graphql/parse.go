@@ -0,0 +1,571 @@
+// Package graphql is a minimal, dependency-free GraphQL query executor.
+// It supports query operations with fields, aliases, arguments and variables, which is all the HTTP API needs. Mutations, subscriptions, fragments, directives and introspection are not supported.
+// selection sets and argument values may be nested at most maxDepth levels deep, so that a query cannot exhaust the stack.
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Field is a selected field of a query
+type Field struct {
+	Alias      string // empty if not aliased
+	Name       string
+	Args       Args    // argument values, with variables resolved
+	Selections []Field // subfields. Empty for scalars
+}
+
+// Key returns the key of the field in the response: its alias, or its name if it has none
+func (f Field) Key() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// variable is a reference to a variable in an argument value, replaced by the variable's value once the operation is chosen
+type variable string
+
+// maxDepth is the deepest nesting of selection sets, lists and input objects in a query. The schemas served need far fewer levels
+const maxDepth int = 16
+
+type varDef struct {
+	name     string
+	nonNull  bool
+	defValue any
+	hasDef   bool
+}
+
+type operation struct {
+	name       string
+	varDefs    []varDef
+	selections []Field
+}
+
+// Parse parses query and returns the selections of its operation named operationName, with the variables in their arguments replaced by those of variables
+// operationName may be empty if query has a single operation. Variables are as decoded from JSON
+func Parse(query, operationName string, variables map[string]any) (fields []Field, err error) {
+
+	toks, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{toks: toks}
+	var ops []operation
+	for p.peek().kind != tokEOF {
+		op, err := p.operation()
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("query has no operation")
+	}
+
+	var op operation
+	switch {
+	case operationName != "":
+		found := false
+		for _, o := range ops {
+			if o.name == operationName {
+				op, found = o, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown operation: %s", operationName)
+		}
+	case len(ops) > 1:
+		return nil, fmt.Errorf("operationName is required when the query has several operations")
+	default:
+		op = ops[0]
+	}
+
+	vars := make(map[string]any, len(op.varDefs))
+	for _, def := range op.varDefs {
+		v, ok := variables[def.name]
+		if !ok && def.hasDef {
+			v, ok = def.defValue, true
+		}
+		if def.nonNull && (!ok || v == nil) {
+			return nil, fmt.Errorf("variable $%s is required", def.name)
+		}
+		vars[def.name] = v
+	}
+
+	return resolveFields(op.selections, vars)
+}
+
+// resolveFields replaces the variables in the arguments of fields by their values
+func resolveFields(fields []Field, vars map[string]any) ([]Field, error) {
+
+	for i, f := range fields {
+		for name, v := range f.Args {
+			rv, err := resolveValue(v, vars)
+			if err != nil {
+				return nil, err
+			}
+			f.Args[name] = rv
+		}
+		subs, err := resolveFields(f.Selections, vars)
+		if err != nil {
+			return nil, err
+		}
+		fields[i].Selections = subs
+	}
+	return fields, nil
+}
+
+func resolveValue(v any, vars map[string]any) (any, error) {
+
+	switch v := v.(type) {
+	case variable:
+		val, ok := vars[string(v)]
+		if !ok {
+			return nil, fmt.Errorf("variable $%s is not defined by the operation", string(v))
+		}
+		return val, nil
+	case []any:
+		for i, el := range v {
+			rv, err := resolveValue(el, vars)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = rv
+		}
+		return v, nil
+	case map[string]any:
+		for k, el := range v {
+			rv, err := resolveValue(el, vars)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = rv
+		}
+		return v, nil
+	default:
+		return v, nil
+	}
+}
+
+type parser struct {
+	toks  []token
+	i     int
+	depth int // nesting of the selection set or value parsed
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+// punct returns true and consumes the next token if it is the punctuator s
+func (p *parser) punct(s string) bool {
+	if t := p.peek(); t.kind == tokPunct && t.val == s {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.punct(s) {
+		t := p.peek()
+		return fmt.Errorf("expected '%s' at position %d, found '%s'", s, t.pos, t.val)
+	}
+	return nil
+}
+
+// nest enters a selection set or value nested in the current one, starting at position pos. The caller decrements p.depth when leaving it
+func (p *parser) nest(pos int) error {
+	p.depth++
+	if p.depth > maxDepth {
+		return fmt.Errorf("query is nested deeper than %d levels at position %d", maxDepth, pos)
+	}
+	return nil
+}
+
+func (p *parser) name() (string, error) {
+	t := p.next()
+	if t.kind != tokName {
+		return "", fmt.Errorf("expected a name at position %d, found '%s'", t.pos, t.val)
+	}
+	return t.val, nil
+}
+
+// operation parses an operation definition: a query, or the shorthand of a query, a selection set
+func (p *parser) operation() (op operation, err error) {
+
+	t := p.peek()
+	if t.kind == tokPunct && t.val == "{" {
+		op.selections, err = p.selectionSet()
+		return op, err
+	}
+	if t.kind != tokName {
+		return op, fmt.Errorf("expected an operation at position %d, found '%s'", t.pos, t.val)
+	}
+	switch t.val {
+	case "query":
+		p.next()
+	case "mutation", "subscription", "fragment":
+		return op, fmt.Errorf("%s is not supported", t.val)
+	default:
+		return op, fmt.Errorf("expected an operation at position %d, found '%s'", t.pos, t.val)
+	}
+
+	if p.peek().kind == tokName {
+		op.name = p.next().val
+	}
+	if p.punct("(") {
+		for !p.punct(")") {
+			def, err := p.varDef()
+			if err != nil {
+				return op, err
+			}
+			op.varDefs = append(op.varDefs, def)
+		}
+	}
+	if p.peek().val == "@" {
+		return op, fmt.Errorf("directives are not supported")
+	}
+
+	op.selections, err = p.selectionSet()
+	return op, err
+}
+
+// varDef parses a variable definition, e.g. $from: String! = "EUR"
+func (p *parser) varDef() (def varDef, err error) {
+
+	if err = p.expect("$"); err != nil {
+		return def, err
+	}
+	if def.name, err = p.name(); err != nil {
+		return def, err
+	}
+	if err = p.expect(":"); err != nil {
+		return def, err
+	}
+	if def.nonNull, err = p.typeRef(); err != nil {
+		return def, err
+	}
+	if p.punct("=") {
+		if def.defValue, err = p.value(true); err != nil {
+			return def, err
+		}
+		def.hasDef = true
+	}
+	return def, nil
+}
+
+// typeRef parses a type reference, e.g. [String!]!, returning whether it is non-null. Variables are not checked against their types: the resolvers check the types of their arguments
+func (p *parser) typeRef() (nonNull bool, err error) {
+
+	if p.punct("[") {
+		if _, err = p.typeRef(); err != nil {
+			return false, err
+		}
+		if err = p.expect("]"); err != nil {
+			return false, err
+		}
+	} else if _, err = p.name(); err != nil {
+		return false, err
+	}
+	return p.punct("!"), nil
+}
+
+func (p *parser) selectionSet() (fields []Field, err error) {
+
+	if err = p.nest(p.peek().pos); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+
+	if err = p.expect("{"); err != nil {
+		return nil, err
+	}
+	for !p.punct("}") {
+		if p.peek().kind == tokEOF {
+			return nil, fmt.Errorf("unterminated selection set")
+		}
+		f, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty selection set")
+	}
+	return fields, nil
+}
+
+func (p *parser) field() (f Field, err error) {
+
+	if p.peek().val == "..." {
+		return f, fmt.Errorf("fragments are not supported")
+	}
+
+	if f.Name, err = p.name(); err != nil {
+		return f, err
+	}
+	if p.punct(":") {
+		f.Alias = f.Name
+		if f.Name, err = p.name(); err != nil {
+			return f, err
+		}
+	}
+
+	f.Args = Args{}
+	if p.punct("(") {
+		for !p.punct(")") {
+			argName, err := p.name()
+			if err != nil {
+				return f, err
+			}
+			if err = p.expect(":"); err != nil {
+				return f, err
+			}
+			if f.Args[argName], err = p.value(false); err != nil {
+				return f, err
+			}
+		}
+	}
+	if p.peek().val == "@" {
+		return f, fmt.Errorf("directives are not supported")
+	}
+
+	if t := p.peek(); t.kind == tokPunct && t.val == "{" {
+		if f.Selections, err = p.selectionSet(); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// value parses an input value. Enum values are returned as strings. If constant, as in a variable default, variables are not allowed
+func (p *parser) value(constant bool) (any, error) {
+
+	t := p.next()
+	switch t.kind {
+	case tokInt:
+		return strconv.ParseInt(t.val, 10, 64)
+	case tokFloat:
+		return strconv.ParseFloat(t.val, 64)
+	case tokString:
+		return t.val, nil
+	case tokName:
+		switch t.val {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return t.val, nil
+	case tokPunct:
+		switch t.val {
+		case "$":
+			if constant {
+				return nil, fmt.Errorf("variables are not allowed at position %d", t.pos)
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			return variable(name), nil
+		case "[":
+			if err := p.nest(t.pos); err != nil {
+				return nil, err
+			}
+			defer func() { p.depth-- }()
+			list := []any{}
+			for !p.punct("]") {
+				if p.peek().kind == tokEOF {
+					return nil, fmt.Errorf("unterminated list")
+				}
+				v, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+			return list, nil
+		case "{":
+			if err := p.nest(t.pos); err != nil {
+				return nil, err
+			}
+			defer func() { p.depth-- }()
+			obj := map[string]any{}
+			for !p.punct("}") {
+				name, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				if err = p.expect(":"); err != nil {
+					return nil, err
+				}
+				if obj[name], err = p.value(constant); err != nil {
+					return nil, err
+				}
+			}
+			return obj, nil
+		}
+	}
+	return nil, fmt.Errorf("expected a value at position %d, found '%s'", t.pos, t.val)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind tokenKind
+	val  string // unquoted for strings
+	pos  int    // byte offset in the query
+}
+
+// lex splits src into tokens, skipping whitespace, commas and comments
+func lex(src string) (toks []token, err error) {
+
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' && src[i] != '\r' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "..."):
+			toks = append(toks, token{kind: tokPunct, val: "...", pos: i})
+			i += 3
+		case strings.IndexByte("!$&():=@[]{}|", c) >= 0:
+			toks = append(toks, token{kind: tokPunct, val: string(c), pos: i})
+			i++
+		case c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] >= 'A' && src[i] <= 'Z' || src[i] >= 'a' && src[i] <= 'z' || src[i] >= '0' && src[i] <= '9') {
+				i++
+			}
+			toks = append(toks, token{kind: tokName, val: src[start:i], pos: start})
+		case c == '-' || c >= '0' && c <= '9':
+			start := i
+			kind := tokInt
+			if c == '-' {
+				i++
+			}
+			i = skipDigits(src, i)
+			if i < len(src) && src[i] == '.' {
+				kind = tokFloat
+				i = skipDigits(src, i+1)
+			}
+			if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+				kind = tokFloat
+				i++
+				if i < len(src) && (src[i] == '+' || src[i] == '-') {
+					i++
+				}
+				i = skipDigits(src, i)
+			}
+			toks = append(toks, token{kind: kind, val: src[start:i], pos: start})
+		case c == '"':
+			if strings.HasPrefix(src[i:], `"""`) {
+				return nil, fmt.Errorf("block strings are not supported")
+			}
+			s, end, err := lexString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, token{kind: tokString, val: s, pos: i})
+			i = end
+		default:
+			r, _ := utf8.DecodeRuneInString(src[i:])
+			if r == '\uFEFF' {
+				i += utf8.RuneLen(r)
+				continue
+			}
+			return nil, fmt.Errorf("unexpected character '%c' at position %d", r, i)
+		}
+	}
+
+	return append(toks, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+func skipDigits(src string, i int) int {
+	for i < len(src) && src[i] >= '0' && src[i] <= '9' {
+		i++
+	}
+	return i
+}
+
+// lexString returns the value of the string starting with the quote at src[start], and the offset after its closing quote
+func lexString(src string, start int) (s string, end int, err error) {
+
+	var sb strings.Builder
+	i := start + 1
+	for i < len(src) {
+		c := src[i]
+		switch c {
+		case '"':
+			return sb.String(), i + 1, nil
+		case '\n', '\r':
+			return "", 0, fmt.Errorf("unterminated string at position %d", start)
+		case '\\':
+			if i+1 >= len(src) {
+				return "", 0, fmt.Errorf("unterminated string at position %d", start)
+			}
+			switch esc := src[i+1]; esc {
+			case '"', '\\', '/':
+				sb.WriteByte(esc)
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'u':
+				if i+6 > len(src) {
+					return "", 0, fmt.Errorf("invalid unicode escape at position %d", i)
+				}
+				r, err := strconv.ParseUint(src[i+2:i+6], 16, 32)
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid unicode escape at position %d", i)
+				}
+				sb.WriteRune(rune(r))
+				i += 4
+			default:
+				return "", 0, fmt.Errorf("invalid escape '\\%c' at position %d", esc, i)
+			}
+			i += 2
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string at position %d", start)
+}
@@ -0,0 +1,117 @@
+package graphql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		variables     map[string]any
+		want          []Field
+	}{
+		{"shorthand", `{ a b }`, "", nil,
+			[]Field{{Name: "a", Args: Args{}}, {Name: "b", Args: Args{}}}},
+		{"alias and arguments", `{ r: rates(from: "EUR", to: ["USD", "JPY"], limit: 10, min: -1.5e2, desc: true, end: null, order: ASC, o: {k: 1}) { day rate } }`, "", nil,
+			[]Field{{Alias: "r", Name: "rates", Args: Args{
+				"from": "EUR", "to": []any{"USD", "JPY"}, "limit": int64(10), "min": -150.0, "desc": true, "end": nil, "order": "ASC", "o": map[string]any{"k": int64(1)},
+			}, Selections: []Field{{Name: "day", Args: Args{}}, {Name: "rate", Args: Args{}}}}}},
+		{"variables", `query Rates($to: [String!]!, $limit: Int = 5, $end: String) { rates(to: $to, limit: $limit, end: $end) { day } }`, "", map[string]any{"to": []any{"USD"}},
+			[]Field{{Name: "rates", Args: Args{"to": []any{"USD"}, "limit": int64(5), "end": nil}, Selections: []Field{{Name: "day", Args: Args{}}}}}},
+		{"variable overrides default", `query($limit: Int = 5) { a(limit: $limit) }`, "", map[string]any{"limit": 7.0},
+			[]Field{{Name: "a", Args: Args{"limit": 7.0}}}},
+		{"variables in list and object", `query($c: String) { a(l: [$c, "x"], o: {c: $c}) }`, "", map[string]any{"c": "y"},
+			[]Field{{Name: "a", Args: Args{"l": []any{"y", "x"}, "o": map[string]any{"c": "y"}}}}},
+		{"operation name", `query A { a } query B { b }`, "B", nil,
+			[]Field{{Name: "b", Args: Args{}}}},
+		{"comments, commas and BOM", "\uFEFF# rates\n{ a, # first\n b }", "", nil,
+			[]Field{{Name: "a", Args: Args{}}, {Name: "b", Args: Args{}}}},
+		{"string escapes", `{ a(s: "\"\\\/\b\f\n\r\t\u00e9") }`, "", nil,
+			[]Field{{Name: "a", Args: Args{"s": "\"\\/\b\f\n\r\té"}}}},
+		{"maximum depth", strings.Repeat("{ a ", maxDepth) + strings.Repeat("}", maxDepth), "", nil,
+			nested(maxDepth)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.query, tt.operationName, tt.variables)
+			if err != nil {
+				t.Fatalf("Parse failed: %s", err.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		variables     map[string]any
+		wantErr       string
+	}{
+		{"empty", ``, "", nil, "query has no operation"},
+		{"unterminated selection set", `{ a`, "", nil, "unterminated selection set"},
+		{"empty selection set", `{ a {} }`, "", nil, "empty selection set"},
+		{"missing value", `{ a(x: ) }`, "", nil, "expected a value at position 7, found ')'"},
+		{"missing colon", `{ a(x 1) }`, "", nil, "expected ':' at position 6, found '1'"},
+		{"unterminated list", `{ a(l: [1 2`, "", nil, "unterminated list"},
+		{"unterminated string", `{ a(s: "abc) }`, "", nil, "unterminated string at position 7"},
+		{"newline in string", "{ a(s: \"a\nb\") }", "", nil, "unterminated string at position 7"},
+		{"block string", `{ a(s: """abc""") }`, "", nil, "block strings are not supported"},
+		{"invalid escape", `{ a(s: "\q") }`, "", nil, `invalid escape '\q'`},
+		{"invalid unicode escape", `{ a(s: "\u00zz") }`, "", nil, "invalid unicode escape"},
+		{"unexpected character", `{ a % }`, "", nil, "unexpected character '%' at position 4"},
+		{"not an operation", `rates { a }`, "", nil, "expected an operation at position 0, found 'rates'"},
+		{"mutation", `mutation { a }`, "", nil, "mutation is not supported"},
+		{"subscription", `subscription { a }`, "", nil, "subscription is not supported"},
+		{"operation directive", `query Q @cached { a }`, "", nil, "directives are not supported"},
+		{"field directive", `{ a @skip(if: true) }`, "", nil, "directives are not supported"},
+		{"operationName required", `{ a } { b }`, "", nil, "operationName is required"},
+		{"unknown operation", `query A { a }`, "B", nil, "unknown operation: B"},
+		{"required variable missing", `query($x: Int!) { a(x: $x) }`, "", nil, "variable $x is required"},
+		{"required variable null", `query($x: Int!) { a(x: $x) }`, "", map[string]any{"x": nil}, "variable $x is required"},
+		{"undefined variable", `{ a(x: $y) }`, "", nil, "variable $y is not defined by the operation"},
+		{"variable in default", `query($x: Int = $y) { a }`, "", nil, "variables are not allowed"},
+		{"variable without type", `query($x) { a }`, "", nil, "expected ':'"},
+		{"unterminated list type", `query($x: [Int) { a }`, "", nil, "expected ']'"},
+		{"fragment definition", `fragment F on ExchangeRate { day } { rates { ...F } }`, "", nil, "fragment is not supported"},
+		{"fragment spread", `{ rates { ...F } }`, "", nil, "fragments are not supported"},
+		{"inline fragment", `{ rates { ... on ExchangeRate { day } } }`, "", nil, "fragments are not supported"},
+		{"selections too deep", strings.Repeat("{ a ", maxDepth+1) + strings.Repeat("}", maxDepth+1), "", nil, "query is nested deeper than 16 levels"},
+		{"list too deep", `{ a(l: ` + strings.Repeat("[", maxDepth) + strings.Repeat("]", maxDepth) + `) }`, "", nil, "query is nested deeper than 16 levels"},
+		{"object too deep", `{ a(o: ` + strings.Repeat("{k: ", maxDepth) + strings.Repeat("}", maxDepth) + `) }`, "", nil, "query is nested deeper than 16 levels"},
+		{"default too deep", `query($x: Int = ` + strings.Repeat("[", maxDepth+1) + `) { a }`, "", nil, "query is nested deeper than 16 levels"},
+		{"unterminated deep nesting", `{ a(l: ` + strings.Repeat("[", 100_000), "", nil, "query is nested deeper than 16 levels"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.query, tt.operationName, tt.variables)
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %q, want %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+// nested returns the fields of { a { a ... } } with depth selection sets
+func nested(depth int) []Field {
+	f := Field{Name: "a", Args: Args{}}
+	for range depth - 1 {
+		f = Field{Name: "a", Args: Args{}, Selections: []Field{f}}
+	}
+	return []Field{f}
+}
@@ -0,0 +1,135 @@
+// Package optlock updates rows of Postgres tables with optimistic locking on their last_modified_at column, for the UpdateIfUnchanged methods of stores
+package optlock
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// Update updates the row of id with the db-tagged fields of input, as lyspg.Update, if its last_modified_at still equals readAt
+// see UpdatePartial
+func Update[T any](ctx context.Context, db *pgxpool.Pool, schemaName, tableName, pkColName string, input T, id int64, readAt time.Time) error {
+
+	dbTags, assignmentsMap, err := fieldsOf(input)
+	if err != nil {
+		return fmt.Errorf("fieldsOf failed: %w", err)
+	}
+
+	return UpdatePartial(ctx, db, schemaName, tableName, pkColName, dbTags, assignmentsMap, id, readAt)
+}
+
+// UpdatePartial updates the columns of assignmentsMap in the row of id, as lyspg.UpdatePartial, if its last_modified_at still equals readAt, the value read
+// readAt must be the exact value read, at the microsecond precision of Postgres: a value rounded, e.g. to the second precision of lystype.Datetime in JSON, never matches
+// returns cerrors.ErrRowChanged if the row was modified since, and cerrors.ErrNoRows if it no longer exists
+func UpdatePartial(ctx context.Context, db *pgxpool.Pool, schemaName, tableName, pkColName string, allowedFields []string, assignmentsMap map[string]any, id int64, readAt time.Time) error {
+
+	stmt, args, err := updateStmt(schemaName, tableName, pkColName, allowedFields, assignmentsMap, id, readAt)
+	if err != nil {
+		return err
+	}
+
+	cmdTag, err := db.Exec(ctx, stmt, args...)
+	if err != nil {
+		return fmt.Errorf("db.Exec failed: %w", err)
+	}
+	if cmdTag.RowsAffected() > 0 {
+		return nil
+	}
+
+	// nothing updated: the row was either changed or deleted
+	var exists bool
+	err = db.QueryRow(ctx, fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s.%s WHERE %s = $1);", schemaName, tableName, pkColName), id).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("db.QueryRow failed: %w", err)
+	}
+	if exists {
+		return cerrors.ErrRowChanged
+	}
+	return cerrors.ErrNoRows
+}
+
+// fieldsOf returns the db tags of the fields of input, and their values keyed by db tag
+func fieldsOf[T any](input T) (dbTags []string, assignmentsMap map[string]any, err error) {
+
+	reflVal := reflect.ValueOf(input)
+	meta, err := lysmeta.AnalyzeStructs(reflVal)
+	if err != nil {
+		return nil, nil, fmt.Errorf("lysmeta.AnalyzeStructs failed: %w", err)
+	}
+
+	assignmentsMap = make(map[string]any, len(meta.DbTags))
+	for i := range reflVal.NumField() {
+		if dbTag := reflVal.Type().Field(i).Tag.Get("db"); dbTag != "" {
+			assignmentsMap[dbTag] = reflVal.Field(i).Interface()
+		}
+	}
+
+	return meta.DbTags, assignmentsMap, nil
+}
+
+// updateStmt returns the statement of UpdatePartial and its args
+func updateStmt(schemaName, tableName, pkColName string, allowedFields []string, assignmentsMap map[string]any, id int64, readAt time.Time) (stmt string, args []any, err error) {
+
+	// sorted, so that the statement of a set of columns is constant
+	cols := make([]string, 0, len(assignmentsMap))
+	for col := range assignmentsMap {
+		if !slices.Contains(allowedFields, col) {
+			return "", nil, fmt.Errorf("%w: invalid field: %s", cerrors.ErrValidationFailed, col)
+		}
+		cols = append(cols, col)
+	}
+	slices.Sort(cols)
+
+	assignments := make([]string, len(cols))
+	args = make([]any, 0, len(cols)+2)
+	for i, col := range cols {
+		assignments[i] = col + " = $" + strconv.Itoa(i+1)
+		args = append(args, dbValue(assignmentsMap[col]))
+	}
+	args = append(args, id, readAt)
+
+	stmt = fmt.Sprintf("UPDATE %s.%s SET %s WHERE %s = $%d AND last_modified_at = $%d;",
+		schemaName, tableName, strings.Join(assignments, ", "), pkColName, len(cols)+1, len(cols)+2)
+
+	return stmt, args, nil
+}
+
+// dbValue returns val in a form pgx can encode: the lystype date and time types are converted as lyspg does
+func dbValue(val any) any {
+
+	switch v := val.(type) {
+	case lystype.Date:
+		return v.Format(lystype.DateFormat)
+	case *lystype.Date:
+		if v == nil {
+			return nil
+		}
+		return v.Format(lystype.DateFormat)
+	case lystype.Time:
+		return v.Format(lystype.TimeFormatDb)
+	case *lystype.Time:
+		if v == nil {
+			return nil
+		}
+		return v.Format(lystype.TimeFormatDb)
+	case lystype.Datetime:
+		return time.Time(v)
+	case *lystype.Datetime:
+		if v == nil {
+			return nil
+		}
+		return time.Time(*v)
+	default:
+		return val
+	}
+}
@@ -0,0 +1,111 @@
+package optlock
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+type testInput struct {
+	Code           string           `db:"code"`
+	Day            lystype.Date     `db:"day"`
+	LastModifiedAt lystype.Datetime `db:"last_modified_at"`
+	Note           *string          `db:"note"`
+	internal       int
+}
+
+func TestFieldsOf(t *testing.T) {
+
+	day := time.Date(2024, 9, 2, 0, 0, 0, 0, time.UTC)
+	input := testInput{Code: "USD", Day: lystype.Date(day), LastModifiedAt: lystype.Datetime(day), internal: 1}
+
+	dbTags, assignmentsMap, err := fieldsOf(input)
+	if err != nil {
+		t.Fatalf("fieldsOf failed: %s", err.Error())
+	}
+
+	wantTags := []string{"code", "day", "last_modified_at", "note"}
+	if !slices.Equal(dbTags, wantTags) {
+		t.Errorf("dbTags: got %v, want %v", dbTags, wantTags)
+	}
+	wantMap := map[string]any{"code": "USD", "day": lystype.Date(day), "last_modified_at": lystype.Datetime(day), "note": (*string)(nil)}
+	if !reflect.DeepEqual(assignmentsMap, wantMap) {
+		t.Errorf("assignmentsMap: got %v, want %v", assignmentsMap, wantMap)
+	}
+}
+
+func TestUpdateStmt(t *testing.T) {
+
+	readAt := time.Date(2024, 9, 2, 16, 5, 1, 123456000, time.UTC)
+	allowedFields := []string{"code", "day", "last_modified_at", "note"}
+
+	tests := []struct {
+		name           string
+		assignmentsMap map[string]any
+		wantStmt       string
+		wantArgs       []any
+	}{
+		{"single column", map[string]any{"code": "USD"},
+			"UPDATE ecb.currency SET code = $1 WHERE id = $2 AND last_modified_at = $3;",
+			[]any{"USD", int64(7), readAt}},
+		{"columns sorted", map[string]any{"note": "x", "code": "USD", "day": lystype.Date(readAt)},
+			"UPDATE ecb.currency SET code = $1, day = $2, note = $3 WHERE id = $4 AND last_modified_at = $5;",
+			[]any{"USD", "2024-09-02", "x", int64(7), readAt}},
+		{"lystype values converted", map[string]any{"last_modified_at": lystype.Datetime(readAt), "day": (*lystype.Date)(nil)},
+			"UPDATE ecb.currency SET day = $1, last_modified_at = $2 WHERE id = $3 AND last_modified_at = $4;",
+			[]any{nil, readAt, int64(7), readAt}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, args, err := updateStmt("ecb", "currency", "id", allowedFields, tt.assignmentsMap, 7, readAt)
+			if err != nil {
+				t.Fatalf("updateStmt failed: %s", err.Error())
+			}
+			if stmt != tt.wantStmt {
+				t.Errorf("stmt:\ngot  %s\nwant %s", stmt, tt.wantStmt)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args: got %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+
+	// columns not allowed are rejected, so that a caller's map can't name arbitrary SQL
+	if _, _, err := updateStmt("ecb", "currency", "id", allowedFields, map[string]any{"code = 'x', id": 1}, 7, readAt); !errors.Is(err, cerrors.ErrValidationFailed) {
+		t.Errorf("updateStmt of an invalid field: got %v, want cerrors.ErrValidationFailed", err)
+	}
+}
+
+func TestDbValue(t *testing.T) {
+
+	at := time.Date(2024, 9, 2, 16, 5, 1, 0, time.UTC)
+	date, datetime, tm := lystype.Date(at), lystype.Datetime(at), lystype.Time(at)
+
+	tests := []struct {
+		name string
+		val  any
+		want any
+	}{
+		{"date", date, "2024-09-02"},
+		{"date pointer", &date, "2024-09-02"},
+		{"nil date pointer", (*lystype.Date)(nil), nil},
+		{"time", tm, at.Format(lystype.TimeFormatDb)},
+		{"nil time pointer", (*lystype.Time)(nil), nil},
+		{"datetime", datetime, at},
+		{"datetime pointer", &datetime, at},
+		{"nil datetime pointer", (*lystype.Datetime)(nil), nil},
+		{"other", 1.5, 1.5},
+	}
+
+	for _, tt := range tests {
+		if got := dbValue(tt.val); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
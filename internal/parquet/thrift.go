@@ -0,0 +1,108 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// compact protocol field types
+const (
+	ctI32    byte = 5
+	ctI64    byte = 6
+	ctBinary byte = 8
+	ctList   byte = 9
+	ctStruct byte = 12
+)
+
+// compactWriter is a minimal Thrift compact protocol encoder, sufficient for writing Parquet page headers and file metadata
+type compactWriter struct {
+	buf     bytes.Buffer
+	lastIds []int16 // stack of the last field id written per nested struct
+}
+
+func (cw *compactWriter) structBegin() {
+	cw.lastIds = append(cw.lastIds, 0)
+}
+
+func (cw *compactWriter) structEnd() {
+	cw.buf.WriteByte(0) // stop field
+	cw.lastIds = cw.lastIds[:len(cw.lastIds)-1]
+}
+
+func (cw *compactWriter) fieldHeader(id int16, fieldType byte) {
+
+	last := cw.lastIds[len(cw.lastIds)-1]
+	delta := id - last
+	if delta > 0 && delta <= 15 {
+		cw.buf.WriteByte(byte(delta)<<4 | fieldType)
+	} else {
+		cw.buf.WriteByte(fieldType)
+		cw.varint(uint64(zigzag64(int64(id))))
+	}
+	cw.lastIds[len(cw.lastIds)-1] = id
+}
+
+func (cw *compactWriter) fieldI32(id int16, v int32) {
+	cw.fieldHeader(id, ctI32)
+	cw.varint(uint64(zigzag64(int64(v))))
+}
+
+func (cw *compactWriter) fieldI64(id int16, v int64) {
+	cw.fieldHeader(id, ctI64)
+	cw.varint(zigzag64(v))
+}
+
+func (cw *compactWriter) fieldString(id int16, v string) {
+	cw.fieldHeader(id, ctBinary)
+	cw.binary([]byte(v))
+}
+
+func (cw *compactWriter) fieldStruct(id int16, writeFields func()) {
+	cw.fieldHeader(id, ctStruct)
+	cw.structBegin()
+	writeFields()
+	cw.structEnd()
+}
+
+func (cw *compactWriter) fieldList(id int16, elemType byte, size int, writeElems func()) {
+	cw.fieldHeader(id, ctList)
+	if size < 15 {
+		cw.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		cw.buf.WriteByte(0xF0 | elemType)
+		cw.varint(uint64(size))
+	}
+	writeElems()
+}
+
+// listI32Elem writes an i32 list element
+func (cw *compactWriter) listI32Elem(v int32) {
+	cw.varint(uint64(zigzag64(int64(v))))
+}
+
+// listStringElem writes a string list element
+func (cw *compactWriter) listStringElem(v string) {
+	cw.binary([]byte(v))
+}
+
+// listStructElem writes a struct list element
+func (cw *compactWriter) listStructElem(writeFields func()) {
+	cw.structBegin()
+	writeFields()
+	cw.structEnd()
+}
+
+func (cw *compactWriter) binary(b []byte) {
+	cw.varint(uint64(len(b)))
+	cw.buf.Write(b)
+}
+
+func (cw *compactWriter) varint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	cw.buf.Write(tmp[:n])
+}
+
+func zigzag64(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
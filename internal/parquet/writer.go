@@ -0,0 +1,360 @@
+// Package parquet is a minimal, dependency-free Parquet file writer.
+// It supports flat schemas of required columns only, PLAIN encoding and GZIP compression, which is all the exporters need.
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Type is the type of a column, mapping to a Parquet physical type and (where needed) converted type
+type Type int
+
+const (
+	String    Type = iota // BYTE_ARRAY / UTF8
+	Date                  // INT32 / DATE (days since epoch)
+	Float                 // FLOAT
+	Double                // DOUBLE
+	Int64                 // INT64
+	Timestamp             // INT64 / TIMESTAMP_MILLIS
+)
+
+// Column defines a single column of the file schema
+type Column struct {
+	Name string
+	Type Type
+}
+
+// parquet.thrift enum values
+const (
+	physInt32     int32 = 1
+	physInt64     int32 = 2
+	physFloat     int32 = 4
+	physDouble    int32 = 5
+	physByteArray int32 = 6
+
+	convUtf8            int32 = 0
+	convDate            int32 = 6
+	convTimestampMillis int32 = 9
+
+	encPlain int32 = 0
+	encRle   int32 = 3
+
+	codecGzip int32 = 2
+
+	pageTypeData int32 = 0
+
+	repetitionRequired int32 = 0
+)
+
+const (
+	magic               string = "PAR1"
+	defaultRowGroupSize int    = 100000
+)
+
+type columnChunkMeta struct {
+	dataPageOffset   int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+type rowGroupMeta struct {
+	numRows int64
+	columns []columnChunkMeta
+}
+
+// Writer writes rows to a Parquet file. Rows are buffered in memory and written as one row group every RowGroupSize rows
+type Writer struct {
+	RowGroupSize int
+
+	w         io.Writer
+	offset    int64
+	cols      []Column
+	buffers   []bytes.Buffer // PLAIN encoded values of the current row group, one per column
+	numRows   int            // in current row group
+	totalRows int64
+	rowGroups []rowGroupMeta
+}
+
+// NewWriter writes the file header to w and returns a Writer for the supplied columns
+func NewWriter(w io.Writer, cols []Column) (pw *Writer, err error) {
+
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("cols has len 0")
+	}
+
+	pw = &Writer{
+		RowGroupSize: defaultRowGroupSize,
+		w:            w,
+		cols:         cols,
+		buffers:      make([]bytes.Buffer, len(cols)),
+	}
+
+	if err = pw.write([]byte(magic)); err != nil {
+		return nil, fmt.Errorf("pw.write failed: %w", err)
+	}
+
+	return pw, nil
+}
+
+// WriteRow buffers a single row. vals must match the column types: string, time.Time (Date, Timestamp), float32, float64 or int64
+func (pw *Writer) WriteRow(vals ...any) error {
+
+	if len(vals) != len(pw.cols) {
+		return fmt.Errorf("expected %d values, got %d", len(pw.cols), len(vals))
+	}
+
+	for i, col := range pw.cols {
+		buf := &pw.buffers[i]
+
+		switch col.Type {
+		case String:
+			v, ok := vals[i].(string)
+			if !ok {
+				return fmt.Errorf("column %s: expected string, got %T", col.Name, vals[i])
+			}
+			binary.Write(buf, binary.LittleEndian, uint32(len(v)))
+			buf.WriteString(v)
+
+		case Date:
+			v, ok := vals[i].(time.Time)
+			if !ok {
+				return fmt.Errorf("column %s: expected time.Time, got %T", col.Name, vals[i])
+			}
+			days := time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+			binary.Write(buf, binary.LittleEndian, int32(days))
+
+		case Float:
+			v, ok := vals[i].(float32)
+			if !ok {
+				return fmt.Errorf("column %s: expected float32, got %T", col.Name, vals[i])
+			}
+			binary.Write(buf, binary.LittleEndian, math.Float32bits(v))
+
+		case Double:
+			v, ok := vals[i].(float64)
+			if !ok {
+				return fmt.Errorf("column %s: expected float64, got %T", col.Name, vals[i])
+			}
+			binary.Write(buf, binary.LittleEndian, math.Float64bits(v))
+
+		case Int64:
+			v, ok := vals[i].(int64)
+			if !ok {
+				return fmt.Errorf("column %s: expected int64, got %T", col.Name, vals[i])
+			}
+			binary.Write(buf, binary.LittleEndian, v)
+
+		case Timestamp:
+			v, ok := vals[i].(time.Time)
+			if !ok {
+				return fmt.Errorf("column %s: expected time.Time, got %T", col.Name, vals[i])
+			}
+			binary.Write(buf, binary.LittleEndian, v.UnixMilli())
+
+		default:
+			return fmt.Errorf("column %s: unknown type %d", col.Name, col.Type)
+		}
+	}
+
+	pw.numRows++
+	if pw.numRows >= pw.RowGroupSize {
+		if err := pw.flushRowGroup(); err != nil {
+			return fmt.Errorf("pw.flushRowGroup failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Close flushes any buffered rows and writes the file footer. It does not close the underlying writer
+func (pw *Writer) Close() error {
+
+	if pw.numRows > 0 {
+		if err := pw.flushRowGroup(); err != nil {
+			return fmt.Errorf("pw.flushRowGroup failed: %w", err)
+		}
+	}
+
+	footer := pw.fileMetaData()
+	if err := pw.write(footer); err != nil {
+		return fmt.Errorf("pw.write (footer) failed: %w", err)
+	}
+	if err := binary.Write(pw.w, binary.LittleEndian, uint32(len(footer))); err != nil {
+		return fmt.Errorf("binary.Write (footer length) failed: %w", err)
+	}
+	if err := pw.write([]byte(magic)); err != nil {
+		return fmt.Errorf("pw.write (magic) failed: %w", err)
+	}
+
+	return nil
+}
+
+// flushRowGroup writes the buffered values as a row group with one data page per column
+func (pw *Writer) flushRowGroup() error {
+
+	rg := rowGroupMeta{numRows: int64(pw.numRows)}
+
+	for i := range pw.cols {
+
+		pageData := pw.buffers[i].Bytes()
+
+		var compressed bytes.Buffer
+		gzw := gzip.NewWriter(&compressed)
+		if _, err := gzw.Write(pageData); err != nil {
+			return fmt.Errorf("gzw.Write failed: %w", err)
+		}
+		if err := gzw.Close(); err != nil {
+			return fmt.Errorf("gzw.Close failed: %w", err)
+		}
+
+		header := pageHeader(pw.numRows, len(pageData), compressed.Len())
+
+		chunk := columnChunkMeta{
+			dataPageOffset:   pw.offset,
+			uncompressedSize: int64(len(header) + len(pageData)),
+			compressedSize:   int64(len(header) + compressed.Len()),
+		}
+
+		if err := pw.write(header); err != nil {
+			return fmt.Errorf("pw.write (page header) failed: %w", err)
+		}
+		if err := pw.write(compressed.Bytes()); err != nil {
+			return fmt.Errorf("pw.write (page data) failed: %w", err)
+		}
+
+		rg.columns = append(rg.columns, chunk)
+		pw.buffers[i].Reset()
+	}
+
+	pw.rowGroups = append(pw.rowGroups, rg)
+	pw.totalRows += int64(pw.numRows)
+	pw.numRows = 0
+
+	return nil
+}
+
+func (pw *Writer) write(b []byte) error {
+	n, err := pw.w.Write(b)
+	pw.offset += int64(n)
+	return err
+}
+
+func pageHeader(numValues, uncompressedSize, compressedSize int) []byte {
+
+	cw := &compactWriter{}
+	cw.structBegin()
+	cw.fieldI32(1, pageTypeData)
+	cw.fieldI32(2, int32(uncompressedSize))
+	cw.fieldI32(3, int32(compressedSize))
+	cw.fieldStruct(5, func() {
+		cw.fieldI32(1, int32(numValues))
+		cw.fieldI32(2, encPlain)
+		cw.fieldI32(3, encRle)
+		cw.fieldI32(4, encRle)
+	})
+	cw.structEnd()
+
+	return cw.buf.Bytes()
+}
+
+func (pw *Writer) fileMetaData() []byte {
+
+	cw := &compactWriter{}
+	cw.structBegin()
+
+	// version
+	cw.fieldI32(1, 1)
+
+	// schema: root element followed by one element per column
+	cw.fieldList(2, ctStruct, len(pw.cols)+1, func() {
+		cw.listStructElem(func() {
+			cw.fieldString(4, "schema")
+			cw.fieldI32(5, int32(len(pw.cols)))
+		})
+		for _, col := range pw.cols {
+			physType, convType, hasConvType := col.Type.parquetTypes()
+			cw.listStructElem(func() {
+				cw.fieldI32(1, physType)
+				cw.fieldI32(3, repetitionRequired)
+				cw.fieldString(4, col.Name)
+				if hasConvType {
+					cw.fieldI32(6, convType)
+				}
+			})
+		}
+	})
+
+	// num_rows
+	cw.fieldI64(3, pw.totalRows)
+
+	// row_groups
+	cw.fieldList(4, ctStruct, len(pw.rowGroups), func() {
+		for _, rg := range pw.rowGroups {
+			var totalSize int64
+			for _, chunk := range rg.columns {
+				totalSize += chunk.uncompressedSize
+			}
+
+			cw.listStructElem(func() {
+				cw.fieldList(1, ctStruct, len(rg.columns), func() {
+					for i, chunk := range rg.columns {
+						physType, _, _ := pw.cols[i].Type.parquetTypes()
+						cw.listStructElem(func() {
+							cw.fieldI64(2, chunk.dataPageOffset)
+							cw.fieldStruct(3, func() {
+								cw.fieldI32(1, physType)
+								cw.fieldList(2, ctI32, 2, func() {
+									cw.listI32Elem(encPlain)
+									cw.listI32Elem(encRle)
+								})
+								cw.fieldList(3, ctBinary, 1, func() {
+									cw.listStringElem(pw.cols[i].Name)
+								})
+								cw.fieldI32(4, codecGzip)
+								cw.fieldI64(5, rg.numRows)
+								cw.fieldI64(6, chunk.uncompressedSize)
+								cw.fieldI64(7, chunk.compressedSize)
+								cw.fieldI64(9, chunk.dataPageOffset)
+							})
+						})
+					}
+				})
+				cw.fieldI64(2, totalSize)
+				cw.fieldI64(3, rg.numRows)
+			})
+		}
+	})
+
+	// created_by
+	cw.fieldString(6, "github.com/loveyourstack/connectors")
+
+	cw.structEnd()
+
+	return cw.buf.Bytes()
+}
+
+func (t Type) parquetTypes() (physType, convType int32, hasConvType bool) {
+
+	switch t {
+	case String:
+		return physByteArray, convUtf8, true
+	case Date:
+		return physInt32, convDate, true
+	case Float:
+		return physFloat, 0, false
+	case Double:
+		return physDouble, 0, false
+	case Int64:
+		return physInt64, 0, false
+	case Timestamp:
+		return physInt64, convTimestampMillis, true
+	default:
+		return 0, 0, false
+	}
+}
@@ -0,0 +1,302 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPageHeader(t *testing.T) {
+
+	// PageHeader{type: DATA_PAGE, uncompressed_page_size: 16, compressed_page_size: 30, data_page_header: {num_values: 2, encoding: PLAIN, definition_level_encoding: RLE, repetition_level_encoding: RLE}}
+	want := []byte{0x15, 0x00, 0x15, 0x20, 0x15, 0x3C, 0x2C, 0x15, 0x04, 0x15, 0x00, 0x15, 0x06, 0x15, 0x06, 0x00, 0x00}
+	if got := pageHeader(2, 16, 30); !bytes.Equal(got, want) {
+		t.Errorf("pageHeader:\ngot  % x\nwant % x", got, want)
+	}
+}
+
+func TestCompactWriter(t *testing.T) {
+
+	cw := &compactWriter{}
+	cw.structBegin()
+	cw.fieldI64(1, -1)
+	cw.fieldString(20, "ab") // delta of 19: long form
+	cw.fieldList(21, ctI32, 15, func() {
+		for range 15 {
+			cw.listI32Elem(1)
+		}
+	})
+	cw.structEnd()
+
+	want := []byte{0x16, 0x01, 0x08, 0x28, 0x02, 'a', 'b', 0x19, 0xF5, 0x0F}
+	want = append(want, slices.Repeat([]byte{0x02}, 15)...)
+	want = append(want, 0x00)
+	if got := cw.buf.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("compactWriter:\ngot  % x\nwant % x", got, want)
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+
+	cols := []Column{{"day", Date}, {"currency", String}, {"rate", Float}, {"inverse", Double}, {"id", Int64}, {"synced_at", Timestamp}}
+	day := time.Date(2024, 9, 2, 0, 0, 0, 0, time.UTC)
+	syncedAt := time.Date(2024, 9, 2, 16, 5, 1, 123000000, time.UTC)
+	rows := [][]any{
+		{day, "USD", float32(1.1061), 1 / 1.1061, int64(1), syncedAt},
+		{day, "GBP", float32(0.84183), 1 / 0.84183, int64(2), syncedAt},
+		{day.AddDate(0, 0, 1), "USD", float32(1.1072), 1 / 1.1072, int64(3), syncedAt.Add(24 * time.Hour)},
+	}
+
+	var buf bytes.Buffer
+	pw, err := NewWriter(&buf, cols)
+	if err != nil {
+		t.Fatalf("NewWriter failed: %s", err.Error())
+	}
+	pw.RowGroupSize = 2
+	for _, row := range rows {
+		if err = pw.WriteRow(row...); err != nil {
+			t.Fatalf("pw.WriteRow failed: %s", err.Error())
+		}
+	}
+	if err = pw.Close(); err != nil {
+		t.Fatalf("pw.Close failed: %s", err.Error())
+	}
+
+	file := buf.Bytes()
+	if string(file[:4]) != magic || string(file[len(file)-4:]) != magic {
+		t.Fatalf("file does not start and end with %s", magic)
+	}
+	footerLen := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	footer, err := readStruct(bytes.NewReader(file[len(file)-8-footerLen : len(file)-8]))
+	if err != nil {
+		t.Fatalf("readStruct of the footer failed: %s", err.Error())
+	}
+
+	if footer[1] != int64(1) || footer[3] != int64(3) || footer[6] != "github.com/loveyourstack/connectors" {
+		t.Errorf("footer: got version %v, num_rows %v, created_by %v, want 1, 3, github.com/loveyourstack/connectors", footer[1], footer[3], footer[6])
+	}
+
+	// schema: the root, then each column with its physical and converted type
+	schema := footer[2].([]any)
+	if len(schema) != len(cols)+1 || schema[0].(map[int16]any)[5] != int64(len(cols)) {
+		t.Fatalf("schema: got %v, want a root of %d columns", schema, len(cols))
+	}
+	for i, col := range cols {
+		elem := schema[i+1].(map[int16]any)
+		physType, convType, hasConvType := col.Type.parquetTypes()
+		if elem[4] != col.Name || elem[1] != int64(physType) || elem[3] != int64(repetitionRequired) {
+			t.Errorf("schema of %s: got %v", col.Name, elem)
+		}
+		if gotConv, ok := elem[6]; ok != hasConvType || (ok && gotConv != int64(convType)) {
+			t.Errorf("converted type of %s: got %v, want %d", col.Name, gotConv, convType)
+		}
+	}
+
+	// row groups of 2 and 1 rows, whose column chunks point to their page headers
+	rowGroups := footer[4].([]any)
+	if len(rowGroups) != 2 {
+		t.Fatalf("row groups: got %d, want 2", len(rowGroups))
+	}
+	got := make([][]any, len(rows))
+	rowOffset := 0
+	for _, rgAny := range rowGroups {
+		rg := rgAny.(map[int16]any)
+		numRows := int(rg[3].(int64))
+
+		var totalSize int64
+		for i, chunkAny := range rg[1].([]any) {
+			meta := chunkAny.(map[int16]any)[3].(map[int16]any)
+			totalSize += meta[6].(int64)
+			offset := meta[9].(int64)
+			if meta[4] != int64(codecGzip) || meta[5] != int64(numRows) || !slices.Equal(meta[3].([]any), []any{cols[i].Name}) {
+				t.Errorf("column chunk of %s: got %v", cols[i].Name, meta)
+			}
+
+			// the page header, then the page of compressed_page_size bytes
+			r := bytes.NewReader(file[offset:])
+			header, err := readStruct(r)
+			if err != nil {
+				t.Fatalf("readStruct of the page header failed: %s", err.Error())
+			}
+			headerLen := int64(len(file[offset:])) - int64(r.Len())
+			compressedSize, uncompressedSize := header[3].(int64), header[2].(int64)
+			if headerLen+compressedSize != meta[7].(int64) || headerLen+uncompressedSize != meta[6].(int64) {
+				t.Errorf("page sizes of %s: got header %d, compressed %d, uncompressed %d, chunk %v, %v", cols[i].Name, headerLen, compressedSize, uncompressedSize, meta[7], meta[6])
+			}
+			if dph := header[5].(map[int16]any); header[1] != int64(pageTypeData) || dph[1] != int64(numRows) || dph[2] != int64(encPlain) {
+				t.Errorf("page header of %s: got %v", cols[i].Name, header)
+			}
+
+			gzr, err := gzip.NewReader(bytes.NewReader(file[offset+headerLen : offset+headerLen+compressedSize]))
+			if err != nil {
+				t.Fatalf("gzip.NewReader failed: %s", err.Error())
+			}
+			page, err := io.ReadAll(gzr)
+			if err != nil {
+				t.Fatalf("io.ReadAll failed: %s", err.Error())
+			}
+			if int64(len(page)) != uncompressedSize {
+				t.Errorf("page of %s: got %d bytes, want %d", cols[i].Name, len(page), uncompressedSize)
+			}
+
+			pr := bytes.NewReader(page)
+			for j := range numRows {
+				got[rowOffset+j] = append(got[rowOffset+j], readPlain(t, pr, cols[i].Type))
+			}
+		}
+		if rg[2] != totalSize {
+			t.Errorf("row group total_byte_size: got %v, want %d", rg[2], totalSize)
+		}
+		rowOffset += numRows
+	}
+
+	for i, row := range rows {
+		for j, val := range row {
+			if tm, ok := val.(time.Time); ok {
+				val = tm.UTC()
+			}
+			if got[i][j] != val {
+				t.Errorf("row %d, %s: got %v, want %v", i, cols[j].Name, got[i][j], val)
+			}
+		}
+	}
+}
+
+func TestWriteRowErrors(t *testing.T) {
+
+	pw, err := NewWriter(io.Discard, []Column{{"rate", Float}})
+	if err != nil {
+		t.Fatalf("NewWriter failed: %s", err.Error())
+	}
+	if err = pw.WriteRow(1.1); err == nil {
+		t.Errorf("pw.WriteRow of a float64 in a Float column: got nil, want an error")
+	}
+	if err = pw.WriteRow(float32(1.1), float32(1.2)); err == nil {
+		t.Errorf("pw.WriteRow of 2 values: got nil, want an error")
+	}
+	if _, err = NewWriter(io.Discard, nil); err == nil {
+		t.Errorf("NewWriter of no columns: got nil, want an error")
+	}
+}
+
+// readPlain reads a PLAIN encoded value of type typ from r
+func readPlain(t *testing.T, r io.Reader, typ Type) any {
+
+	t.Helper()
+
+	var err error
+	var val any
+	switch typ {
+	case String:
+		var n uint32
+		if err = binary.Read(r, binary.LittleEndian, &n); err == nil {
+			b := make([]byte, n)
+			_, err = io.ReadFull(r, b)
+			val = string(b)
+		}
+	case Date:
+		var days int32
+		err = binary.Read(r, binary.LittleEndian, &days)
+		val = time.Unix(int64(days)*86400, 0).UTC()
+	case Float:
+		var bits uint32
+		err = binary.Read(r, binary.LittleEndian, &bits)
+		val = math.Float32frombits(bits)
+	case Double:
+		var bits uint64
+		err = binary.Read(r, binary.LittleEndian, &bits)
+		val = math.Float64frombits(bits)
+	case Int64:
+		var v int64
+		err = binary.Read(r, binary.LittleEndian, &v)
+		val = v
+	case Timestamp:
+		var ms int64
+		err = binary.Read(r, binary.LittleEndian, &ms)
+		val = time.UnixMilli(ms).UTC()
+	}
+	if err != nil {
+		t.Fatalf("binary.Read failed: %s", err.Error())
+	}
+	return val
+}
+
+// readStruct decodes a Thrift compact protocol struct of the field types written by compactWriter, by field id
+// integers are returned as int64, binaries as string, lists as []any and structs as map[int16]any
+func readStruct(r *bytes.Reader) (map[int16]any, error) {
+
+	fields := make(map[int16]any)
+	var lastId int16
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == 0 {
+			return fields, nil
+		}
+
+		fieldType := b & 0x0F
+		if delta := int16(b >> 4); delta != 0 {
+			lastId += delta
+		} else {
+			id, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, err
+			}
+			lastId = int16(unzigzag(id))
+		}
+
+		if fields[lastId], err = readValue(r, fieldType); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func readValue(r *bytes.Reader, fieldType byte) (any, error) {
+
+	switch fieldType {
+	case ctI32, ctI64:
+		v, err := binary.ReadUvarint(r)
+		return unzigzag(v), err
+	case ctBinary:
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		return string(b), err
+	case ctList:
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(b >> 4)
+		if size == 15 {
+			if size, err = binary.ReadUvarint(r); err != nil {
+				return nil, err
+			}
+		}
+		elems := make([]any, size)
+		for i := range elems {
+			if elems[i], err = readValue(r, b&0x0F); err != nil {
+				return nil, err
+			}
+		}
+		return elems, nil
+	case ctStruct:
+		return readStruct(r)
+	}
+	return nil, fmt.Errorf("unsupported field type: %d", fieldType)
+}
+
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
@@ -0,0 +1,332 @@
+// Package xlsx is a minimal, dependency-free Excel workbook (XLSX) writer.
+// It supports sheets of typed columns with a bold, frozen header row, column widths and number formats, and inline strings, which is all the exporters need.
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type is the type of a column, which determines its cell format
+type Type int
+
+const (
+	String  Type = iota // text
+	Date                // date, formatted yyyy-mm-dd
+	Rate                // number with 4 to 6 decimals, as ECB rates are published
+	Number              // number in the General format
+	Integer             // whole number
+)
+
+// Column defines a single column of a sheet
+type Column struct {
+	Name  string
+	Type  Type
+	Width float64 // in characters. A default width for the type if 0
+}
+
+// maxSheetNameLen is the longest sheet name Excel accepts
+const maxSheetNameLen int = 31
+
+// styles.xml cellXfs indexes
+const (
+	styleDefault int = iota
+	styleHeader
+	styleDate
+	styleRate
+	styleInteger
+)
+
+// excelEpoch is day 0 of Excel's 1900 date system, as corrected for its leap year bug
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// Workbook is an XLSX workbook built in memory. Sheets are written in the order they are added
+type Workbook struct {
+	sheets []*Sheet
+}
+
+// Sheet is a sheet of a Workbook
+type Sheet struct {
+	name    string
+	cols    []Column
+	rows    bytes.Buffer // the <row> elements written so far
+	numRows int          // including the header row
+}
+
+// AddSheet adds a sheet named name with cols, writing their names as the header row
+// name must be unique in the workbook, at most 31 characters long and must not contain any of []:*?/\
+func (wb *Workbook) AddSheet(name string, cols []Column) (*Sheet, error) {
+
+	if name == "" || len([]rune(name)) > maxSheetNameLen || strings.ContainsAny(name, `[]:*?/\`) {
+		return nil, fmt.Errorf("invalid sheet name: '%s'", name)
+	}
+	for _, s := range wb.sheets {
+		if strings.EqualFold(s.name, name) {
+			return nil, fmt.Errorf("duplicate sheet name: '%s'", name)
+		}
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("cols has len 0")
+	}
+
+	s := &Sheet{name: name, cols: cols}
+	s.rows.WriteString(`<row r="1">`)
+	for i, col := range cols {
+		s.writeString(i, col.Name, styleHeader)
+	}
+	s.rows.WriteString(`</row>`)
+	s.numRows = 1
+
+	wb.sheets = append(wb.sheets, s)
+	return s, nil
+}
+
+// WriteRow writes a single row. vals must match the column types: string (String), time.Time (Date), float32 or float64 (Rate, Number) and int or int64 (Integer)
+// a nil val leaves its cell empty
+func (s *Sheet) WriteRow(vals ...any) error {
+
+	if len(vals) != len(s.cols) {
+		return fmt.Errorf("expected %d values, got %d", len(s.cols), len(vals))
+	}
+
+	s.numRows++
+	fmt.Fprintf(&s.rows, `<row r="%d">`, s.numRows)
+	for i, col := range s.cols {
+		if vals[i] == nil {
+			continue
+		}
+
+		switch col.Type {
+		case String:
+			v, ok := vals[i].(string)
+			if !ok {
+				return fmt.Errorf("column %s: expected string, got %T", col.Name, vals[i])
+			}
+			s.writeString(i, v, styleDefault)
+
+		case Date:
+			v, ok := vals[i].(time.Time)
+			if !ok {
+				return fmt.Errorf("column %s: expected time.Time, got %T", col.Name, vals[i])
+			}
+			day := time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC)
+			s.writeNumber(i, strconv.FormatInt(int64(day.Sub(excelEpoch).Hours()/24), 10), styleDate)
+
+		case Rate, Number:
+			style := styleRate
+			if col.Type == Number {
+				style = styleDefault
+			}
+			switch v := vals[i].(type) {
+			case float32:
+				s.writeNumber(i, strconv.FormatFloat(float64(v), 'f', -1, 32), style)
+			case float64:
+				s.writeNumber(i, strconv.FormatFloat(v, 'f', -1, 64), style)
+			default:
+				return fmt.Errorf("column %s: expected float32 or float64, got %T", col.Name, vals[i])
+			}
+
+		case Integer:
+			switch v := vals[i].(type) {
+			case int:
+				s.writeNumber(i, strconv.Itoa(v), styleInteger)
+			case int64:
+				s.writeNumber(i, strconv.FormatInt(v, 10), styleInteger)
+			default:
+				return fmt.Errorf("column %s: expected int or int64, got %T", col.Name, vals[i])
+			}
+
+		default:
+			return fmt.Errorf("column %s: unknown type %d", col.Name, col.Type)
+		}
+	}
+	s.rows.WriteString(`</row>`)
+
+	return nil
+}
+
+func (s *Sheet) writeString(col int, v string, style int) {
+	fmt.Fprintf(&s.rows, `<c r="%s%d" t="inlineStr" s="%d"><is><t xml:space="preserve">`, colName(col), s.numRows, style)
+	xml.EscapeText(&s.rows, []byte(v))
+	s.rows.WriteString(`</t></is></c>`)
+}
+
+func (s *Sheet) writeNumber(col int, v string, style int) {
+	fmt.Fprintf(&s.rows, `<c r="%s%d" s="%d"><v>%s</v></c>`, colName(col), s.numRows, style, v)
+}
+
+// WriteTo writes the workbook to w as an XLSX file
+func (wb *Workbook) WriteTo(w io.Writer) (n int64, err error) {
+
+	if len(wb.sheets) == 0 {
+		return 0, fmt.Errorf("workbook has no sheets")
+	}
+
+	cw := &countingWriter{w: w}
+	zw := zip.NewWriter(cw)
+
+	parts := []struct {
+		name    string
+		content []byte
+	}{
+		{"[Content_Types].xml", wb.contentTypes()},
+		{"_rels/.rels", []byte(rootRels)},
+		{"xl/workbook.xml", wb.workbook()},
+		{"xl/_rels/workbook.xml.rels", wb.workbookRels()},
+		{"xl/styles.xml", []byte(styles)},
+	}
+	for i, s := range wb.sheets {
+		parts = append(parts, struct {
+			name    string
+			content []byte
+		}{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), s.xml()})
+	}
+
+	for _, part := range parts {
+		fw, err := zw.Create(part.name)
+		if err != nil {
+			return cw.n, fmt.Errorf("zw.Create failed for %s: %w", part.name, err)
+		}
+		if _, err = fw.Write(part.content); err != nil {
+			return cw.n, fmt.Errorf("fw.Write failed for %s: %w", part.name, err)
+		}
+	}
+	if err = zw.Close(); err != nil {
+		return cw.n, fmt.Errorf("zw.Close failed: %w", err)
+	}
+
+	return cw.n, nil
+}
+
+// xml returns the worksheet part of s, with its header row frozen
+func (s *Sheet) xml() []byte {
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	buf.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	buf.WriteString(`<cols>`)
+	for i, col := range s.cols {
+		width := col.Width
+		if width == 0 {
+			width = defaultWidth(col)
+		}
+		fmt.Fprintf(&buf, `<col min="%d" max="%d" width="%s" customWidth="1"/>`, i+1, i+1, strconv.FormatFloat(width, 'f', -1, 64))
+	}
+	buf.WriteString(`</cols><sheetData>`)
+	buf.Write(s.rows.Bytes())
+	buf.WriteString(`</sheetData>`)
+	fmt.Fprintf(&buf, `<autoFilter ref="A1:%s%d"/>`, colName(len(s.cols)-1), s.numRows)
+	buf.WriteString(`</worksheet>`)
+	return buf.Bytes()
+}
+
+func defaultWidth(col Column) float64 {
+
+	width := float64(len(col.Name) + 4) // room for the autofilter button
+	switch col.Type {
+	case Date:
+		width = max(width, 12)
+	case Rate, Number:
+		width = max(width, 14)
+	}
+	return width
+}
+
+func (wb *Workbook) contentTypes() []byte {
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	buf.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	buf.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	buf.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	buf.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range wb.sheets {
+		fmt.Fprintf(&buf, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	buf.WriteString(`</Types>`)
+	return buf.Bytes()
+}
+
+func (wb *Workbook) workbook() []byte {
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, s := range wb.sheets {
+		buf.WriteString(`<sheet name="`)
+		xml.EscapeText(&buf, []byte(s.name))
+		fmt.Fprintf(&buf, `" sheetId="%d" r:id="rId%d"/>`, i+1, i+1)
+	}
+	buf.WriteString(`</sheets><definedNames>`)
+	// the autofilters of the sheets need their hidden database names
+	for i, s := range wb.sheets {
+		fmt.Fprintf(&buf, `<definedName name="_xlnm._FilterDatabase" localSheetId="%d" hidden="1">'`, i)
+		xml.EscapeText(&buf, []byte(strings.ReplaceAll(s.name, "'", "''")))
+		fmt.Fprintf(&buf, `'!$A$1:$%s$%d</definedName>`, colName(len(s.cols)-1), s.numRows)
+	}
+	buf.WriteString(`</definedNames></workbook>`)
+	return buf.Bytes()
+}
+
+func (wb *Workbook) workbookRels() []byte {
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range wb.sheets {
+		fmt.Fprintf(&buf, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&buf, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(wb.sheets)+1)
+	buf.WriteString(`</Relationships>`)
+	return buf.Bytes()
+}
+
+const rootRels string = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// styles has the cellXfs of the style consts, in their order
+const styles string = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="2"><numFmt numFmtId="164" formatCode="yyyy-mm-dd"/><numFmt numFmtId="165" formatCode="0.0000##"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="5">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="1" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+// colName returns the letters of the column at index i, e.g. A for 0 and AA for 26
+func colName(i int) string {
+
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,55 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// GrantRead grants role read-only access to the schemas owned by the current user, e.g. the connector schemas when run by their owner:
+// usage of each schema and SELECT on its tables and views, including those created by later migrations
+func GrantRead(ctx context.Context, db *pgxpool.Pool, role string) (schemas []string, err error) {
+
+	rows, _ := db.Query(ctx, "SELECT nspname FROM pg_namespace WHERE nspowner = (SELECT oid FROM pg_roles WHERE rolname = current_user) ORDER BY nspname;")
+	schemas, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", err)
+	}
+
+	roleIdent := pgx.Identifier{role}.Sanitize()
+	for _, schema := range schemas {
+		schemaIdent := pgx.Identifier{schema}.Sanitize()
+		stmts := []string{
+			fmt.Sprintf("GRANT USAGE ON SCHEMA %s TO %s;", schemaIdent, roleIdent),
+			fmt.Sprintf("GRANT SELECT ON ALL TABLES IN SCHEMA %s TO %s;", schemaIdent, roleIdent),
+			fmt.Sprintf("ALTER DEFAULT PRIVILEGES IN SCHEMA %s GRANT SELECT ON TABLES TO %s;", schemaIdent, roleIdent),
+		}
+		for _, stmt := range stmts {
+			if _, err = db.Exec(ctx, stmt); err != nil {
+				return nil, fmt.Errorf("db.Exec failed for schema %s: %w", schema, err)
+			}
+		}
+	}
+
+	return schemas, nil
+}
+
+// WritableTables returns the tables, as schema.table, of the non-system schemas that the current user may insert into, update, delete from or truncate
+// it is empty for a read-only role
+func WritableTables(ctx context.Context, db *pgxpool.Pool) (tables []string, err error) {
+
+	stmt := `SELECT n.nspname || '.' || c.relname FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p') AND n.nspname <> 'information_schema' AND n.nspname NOT LIKE 'pg\_%'
+		AND has_schema_privilege(n.oid, 'USAGE') AND has_table_privilege(c.oid, 'INSERT, UPDATE, DELETE, TRUNCATE')
+		ORDER BY 1;`
+
+	rows, _ := db.Query(ctx, stmt)
+	tables, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", err)
+	}
+
+	return tables, nil
+}
@@ -0,0 +1,173 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// advisory lock key ("conn" in ASCII) held while migrations run, so that concurrent runs are serialized
+const lockKey int64 = 0x636f6e6e
+
+// Source is a named set of SQL migrations, e.g. the embedded migrations of a connector schema
+// each .sql file in Dir is a migration, and they are applied in file name order. The file name without extension is the version
+type Source struct {
+	Name string
+	FS   fs.FS
+	Dir  string
+}
+
+// Run applies all migrations from sources which have not yet been applied. Each migration runs in its own transaction
+// applied migrations are recorded in connectors.schema_migration
+func Run(ctx context.Context, db *pgxpool.Pool, sources []Source, infoLog *slog.Logger) (numApplied int, err error) {
+
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("db.Acquire failed: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1);", lockKey); err != nil {
+		return 0, fmt.Errorf("conn.Exec (lock) failed: %w", err)
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1);", lockKey)
+
+	// ensure tracking table exists
+	_, err = conn.Exec(ctx, `CREATE SCHEMA IF NOT EXISTS connectors;
+		CREATE TABLE IF NOT EXISTS connectors.schema_migration (
+			source text NOT NULL,
+			version text NOT NULL,
+			applied_at timestamp with time zone NOT NULL DEFAULT now(),
+			PRIMARY KEY (source, version)
+		);`)
+	if err != nil {
+		return 0, fmt.Errorf("conn.Exec (create tracking table) failed: %w", err)
+	}
+
+	for _, source := range sources {
+
+		// get versions already applied for this source
+		rows, _ := conn.Query(ctx, "SELECT version FROM connectors.schema_migration WHERE source = $1;", source.Name)
+		appliedVersions, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return numApplied, fmt.Errorf("pgx.CollectRows failed for source: %s: %w", source.Name, err)
+		}
+		appliedMap := make(map[string]bool)
+		for _, v := range appliedVersions {
+			appliedMap[v] = true
+		}
+
+		sqlFileNames, err := fileNames(source)
+		if err != nil {
+			return numApplied, fmt.Errorf("fileNames failed for source: %s: %w", source.Name, err)
+		}
+
+		for _, fileName := range sqlFileNames {
+
+			version := strings.TrimSuffix(fileName, ".sql")
+			if appliedMap[version] {
+				continue
+			}
+
+			if err = apply(ctx, conn.Conn(), source, fileName, version); err != nil {
+				return numApplied, fmt.Errorf("apply failed for source: %s, version: %s: %w", source.Name, version, err)
+			}
+			numApplied++
+			infoLog.Info("applied migration", slog.String("source", source.Name), slog.String("version", version))
+		}
+	}
+
+	return numApplied, nil
+}
+
+// Pending returns the versions from sources which have not yet been applied, keyed by source name
+func Pending(ctx context.Context, db *pgxpool.Pool, sources []Source) (pending map[string][]string, err error) {
+
+	var exists bool
+	if err = db.QueryRow(ctx, "SELECT to_regclass('connectors.schema_migration') IS NOT NULL;").Scan(&exists); err != nil {
+		return nil, fmt.Errorf("db.QueryRow failed: %w", err)
+	}
+
+	pending = make(map[string][]string)
+	for _, source := range sources {
+
+		appliedMap := make(map[string]bool)
+		if exists {
+			rows, _ := db.Query(ctx, "SELECT version FROM connectors.schema_migration WHERE source = $1;", source.Name)
+			appliedVersions, err := pgx.CollectRows(rows, pgx.RowTo[string])
+			if err != nil {
+				return nil, fmt.Errorf("pgx.CollectRows failed for source: %s: %w", source.Name, err)
+			}
+			for _, v := range appliedVersions {
+				appliedMap[v] = true
+			}
+		}
+
+		sqlFileNames, err := fileNames(source)
+		if err != nil {
+			return nil, fmt.Errorf("fileNames failed for source: %s: %w", source.Name, err)
+		}
+		for _, fileName := range sqlFileNames {
+			version := strings.TrimSuffix(fileName, ".sql")
+			if !appliedMap[version] {
+				pending[source.Name] = append(pending[source.Name], version)
+			}
+		}
+	}
+
+	return pending, nil
+}
+
+func apply(ctx context.Context, conn *pgx.Conn, source Source, fileName, version string) error {
+
+	sqlContent, err := fs.ReadFile(source.FS, path.Join(source.Dir, fileName))
+	if err != nil {
+		return fmt.Errorf("fs.ReadFile failed: %w", err)
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("conn.Begin failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, string(sqlContent)); err != nil {
+		return fmt.Errorf("tx.Exec failed: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, "INSERT INTO connectors.schema_migration (source, version) VALUES ($1, $2);", source.Name, version); err != nil {
+		return fmt.Errorf("tx.Exec (record version) failed: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("tx.Commit failed: %w", err)
+	}
+
+	return nil
+}
+
+// fileNames returns the names of the .sql files in the source dir, sorted
+func fileNames(source Source) (names []string, err error) {
+
+	dirEntries, err := fs.ReadDir(source.FS, source.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("fs.ReadDir failed: %w", err)
+	}
+
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), ".sql") {
+			continue
+		}
+		names = append(names, dirEntry.Name())
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
@@ -0,0 +1,262 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSmtpPort      int           = 587
+	implicitTlsPort      int           = 465
+	defaultSubjectPrefix string        = "[connectors] "
+	smtpTimeout          time.Duration = 30 * time.Second
+)
+
+// EmailConfig is the configuration of the email reports sent by the daemon
+type EmailConfig struct {
+	Host          string   `toml:"host"`     // SMTP server. Email reports are disabled if empty
+	Port          int      `toml:"port"`     // defaults to 587. Port 465 connects with implicit TLS, other ports upgrade with STARTTLS if the server offers it
+	Username      string   `toml:"username"` // SMTP AUTH PLAIN user, only sent over TLS or to localhost. No authentication if empty
+	Password      string   `toml:"password"`
+	From          string   `toml:"from"`          // sender address, e.g. "Connectors <connectors@example.com>"
+	To            []string `toml:"to"`            // recipient addresses
+	SubjectPrefix string   `toml:"subjectPrefix"` // prepended to each subject. Defaults to "[connectors] "
+
+	SyncSummary string `toml:"syncSummary"` // Go duration between summaries of the sync runs, e.g. "24h". No summaries if empty
+	PeriodEnd   bool   `toml:"periodEnd"`   // send the sheet of the ECB rates of each month once its last fixing is synced
+}
+
+// Enabled returns true if an SMTP server is configured
+func (c EmailConfig) Enabled() bool {
+	return c.Host != ""
+}
+
+// Validate returns an error if an enabled config is incomplete
+func (c EmailConfig) Validate() error {
+
+	if !c.Enabled() {
+		return nil
+	}
+	if _, err := mail.ParseAddress(c.From); err != nil {
+		return fmt.Errorf("invalid from address '%s': %w", c.From, err)
+	}
+	if len(c.To) == 0 {
+		return fmt.Errorf("to is required")
+	}
+	for _, to := range c.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return fmt.Errorf("invalid to address '%s': %w", to, err)
+		}
+	}
+	if c.SyncSummary != "" {
+		if _, err := time.ParseDuration(c.SyncSummary); err != nil {
+			return fmt.Errorf("time.ParseDuration failed for syncSummary: %w", err)
+		}
+	}
+	return nil
+}
+
+// Email is the Notifier sending each Message as an email through an SMTP server
+type Email struct {
+	Config EmailConfig
+}
+
+// NewEmail returns an Email notifier, or an error if conf is invalid
+func NewEmail(conf EmailConfig) (*Email, error) {
+
+	if err := conf.Validate(); err != nil {
+		return nil, fmt.Errorf("conf.Validate failed: %w", err)
+	}
+	if conf.Port == 0 {
+		conf.Port = defaultSmtpPort
+	}
+	if conf.SubjectPrefix == "" {
+		conf.SubjectPrefix = defaultSubjectPrefix
+	}
+
+	return &Email{Config: conf}, nil
+}
+
+// Notify sends msg to the configured recipients
+func (e *Email) Notify(ctx context.Context, msg Message) error {
+
+	from, err := mail.ParseAddress(e.Config.From)
+	if err != nil {
+		return fmt.Errorf("mail.ParseAddress failed for from: %w", err)
+	}
+	var rcpts []string
+	for _, to := range e.Config.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return fmt.Errorf("mail.ParseAddress failed for to: %w", err)
+		}
+		rcpts = append(rcpts, addr.Address)
+	}
+
+	data, err := e.compose(msg, time.Now())
+	if err != nil {
+		return fmt.Errorf("e.compose failed: %w", err)
+	}
+
+	c, err := e.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("e.dial failed: %w", err)
+	}
+	defer c.Close()
+
+	if e.Config.Username != "" {
+		if err = c.Auth(smtp.PlainAuth("", e.Config.Username, e.Config.Password, e.Config.Host)); err != nil {
+			return fmt.Errorf("c.Auth failed: %w", err)
+		}
+	}
+	if err = c.Mail(from.Address); err != nil {
+		return fmt.Errorf("c.Mail failed: %w", err)
+	}
+	for _, rcpt := range rcpts {
+		if err = c.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("c.Rcpt failed for %s: %w", rcpt, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("c.Data failed: %w", err)
+	}
+	if _, err = w.Write(data); err != nil {
+		return fmt.Errorf("w.Write failed: %w", err)
+	}
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("w.Close failed: %w", err)
+	}
+
+	return c.Quit()
+}
+
+// dial connects to the SMTP server, with implicit TLS on port 465 and STARTTLS otherwise if offered. The connection is bounded by ctx and smtpTimeout
+func (e *Email) dial(ctx context.Context) (*smtp.Client, error) {
+
+	addr := net.JoinHostPort(e.Config.Host, strconv.Itoa(e.Config.Port))
+	tlsConf := &tls.Config{ServerName: e.Config.Host, MinVersion: tls.VersionTLS12}
+
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+
+	var conn net.Conn
+	var err error
+	if e.Config.Port == implicitTlsPort {
+		conn, err = (&tls.Dialer{Config: tlsConf}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("DialContext failed: %w", err)
+	}
+	deadline, _ := ctx.Deadline()
+	if err = conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("conn.SetDeadline failed: %w", err)
+	}
+
+	c, err := smtp.NewClient(conn, e.Config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("smtp.NewClient failed: %w", err)
+	}
+	if e.Config.Port != implicitTlsPort {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err = c.StartTLS(tlsConf); err != nil {
+				c.Close()
+				return nil, fmt.Errorf("c.StartTLS failed: %w", err)
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// compose returns msg as a MIME message: a quoted-printable text part followed by a base64 part per attachment
+func (e *Email) compose(msg Message, now time.Time) ([]byte, error) {
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	hdr := []string{
+		"From: " + e.Config.From,
+		"To: " + strings.Join(e.Config.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", e.Config.SubjectPrefix+msg.Subject),
+		"Date: " + now.Format(time.RFC1123Z),
+		"Message-ID: " + messageId(e.Config.Host),
+		"MIME-Version: 1.0",
+		`Content-Type: multipart/mixed; boundary="` + mw.Boundary() + `"`,
+	}
+	buf.WriteString(strings.Join(hdr, "\r\n") + "\r\n\r\n")
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("mw.CreatePart failed for body: %w", err)
+	}
+	qw := quotedprintable.NewWriter(part)
+	if _, err = qw.Write([]byte(strings.ReplaceAll(msg.Body, "\n", "\r\n"))); err != nil {
+		return nil, fmt.Errorf("qw.Write failed: %w", err)
+	}
+	if err = qw.Close(); err != nil {
+		return nil, fmt.Errorf("qw.Close failed: %w", err)
+	}
+
+	for _, a := range msg.Attachments {
+		part, err = mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("mw.CreatePart failed for %s: %w", a.Filename, err)
+		}
+		if err = writeBase64Lines(part, a.Data); err != nil {
+			return nil, fmt.Errorf("writeBase64Lines failed for %s: %w", a.Filename, err)
+		}
+	}
+
+	if err = mw.Close(); err != nil {
+		return nil, fmt.Errorf("mw.Close failed: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64Lines writes data base64 encoded in lines of 76 characters, the limit of RFC 2045
+func writeBase64Lines(w io.Writer, data []byte) error {
+
+	enc := base64.StdEncoding.EncodeToString(data)
+	for len(enc) > 0 {
+		n := min(76, len(enc))
+		if _, err := w.Write([]byte(enc[:n] + "\r\n")); err != nil {
+			return err
+		}
+		enc = enc[n:]
+	}
+	return nil
+}
+
+func messageId(host string) string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + host + ">"
+}
@@ -0,0 +1,24 @@
+package notify
+
+import (
+	"context"
+)
+
+// Notifier sends reports produced by the daemon, e.g. the summary of recent syncs
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Message is a report: a plain text body with optional file attachments
+type Message struct {
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// Attachment is a file attached to a Message
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
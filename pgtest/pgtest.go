@@ -0,0 +1,130 @@
+package pgtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	// the ECB schema is always migrated. Other connectors are migrated if registered by the caller
+	_ "github.com/loveyourstack/connectors/registry/ecbconnector"
+)
+
+const (
+	DefaultImage string = "postgres:17-alpine"
+	dbName       string = "connectors_test"
+	dbUser       string = "connectors"
+	dbPassword   string = "connectors"
+	startTimeout        = time.Minute
+)
+
+// Config contains the settings of the test database
+type Config struct {
+	Image       string       // Postgres image. Defaults to DefaultImage
+	SkipMigrate bool         // if true, no migrations are applied
+	InfoLog     *slog.Logger // logs migration progress. Defaults to discarding output
+}
+
+// Start starts a Postgres container, applies the migrations of all registered connectors and returns a pool connected to it
+// cleanup closes the pool and terminates the container. It must be called even if the test fails
+func Start(ctx context.Context, conf Config) (db *pgxpool.Pool, cleanup func(), err error) {
+
+	if conf.Image == "" {
+		conf.Image = DefaultImage
+	}
+	if conf.InfoLog == nil {
+		conf.InfoLog = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	ctr, err := postgres.Run(ctx, conf.Image,
+		postgres.WithDatabase(dbName),
+		postgres.WithUsername(dbUser),
+		postgres.WithPassword(dbPassword),
+		testcontainers.WithWaitStrategy(
+			// postgres restarts once during initialization, so wait for the second ready message
+			wait.ForLog("database system is ready to accept connections").WithOccurrence(2).WithStartupTimeout(startTimeout),
+		),
+	)
+	if err != nil {
+		if ctr != nil {
+			testcontainers.TerminateContainer(ctr)
+		}
+		return nil, nil, fmt.Errorf("postgres.Run failed: %w", err)
+	}
+
+	terminate := func() {
+		testcontainers.TerminateContainer(ctr)
+	}
+
+	connStr, err := ctr.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		terminate()
+		return nil, nil, fmt.Errorf("ctr.ConnectionString failed: %w", err)
+	}
+
+	db, err = pgxpool.New(ctx, connStr)
+	if err != nil {
+		terminate()
+		return nil, nil, fmt.Errorf("pgxpool.New failed: %w", err)
+	}
+
+	cleanup = func() {
+		db.Close()
+		terminate()
+	}
+
+	if !conf.SkipMigrate {
+		if _, err = registry.MigrateAll(ctx, db, conf.InfoLog); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("registry.MigrateAll failed: %w", err)
+		}
+	}
+
+	return db, cleanup, nil
+}
+
+// New is Start for use in tests: it fails tb if the database cannot be started, and registers cleanup with tb.Cleanup
+// if Docker is not available, tb is skipped rather than failed, so that unit tests still run on machines without Docker
+func New(tb testing.TB, conf Config) *pgxpool.Pool {
+
+	tb.Helper()
+
+	if err := dockerHealth(); err != nil {
+		tb.Skipf("pgtest: Docker is not available: %s", err.Error())
+	}
+
+	db, cleanup, err := Start(context.Background(), conf)
+	if err != nil {
+		tb.Fatalf("pgtest.Start failed: %s", err.Error())
+	}
+	tb.Cleanup(cleanup)
+
+	return db
+}
+
+// dockerHealth returns an error if the Docker daemon cannot be reached
+func dockerHealth() (err error) {
+
+	// the provider panics rather than returning an error if no Docker host is configured
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	if err != nil {
+		return fmt.Errorf("testcontainers.ProviderDocker.GetProvider failed: %w", err)
+	}
+	defer provider.Close()
+
+	return provider.Health(context.Background())
+}
@@ -0,0 +1,284 @@
+// Package quality checks synced rows against configurable data-quality rules before they are stored, e.g. that rates are positive or do not jump from one day to the next
+package quality
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// Kind is the check made by a Rule
+type Kind string
+
+const (
+	NotNull   Kind = "not_null"   // Field must have a value: not nil, an empty string, a zero time or NaN
+	Range     Kind = "range"      // Field must be within the bounds of the rule which are set, e.g. Gt 0 for a rate
+	Monotonic Kind = "monotonic"  // the days of each series must be strictly increasing in the order the rows are received, so neither repeated nor out of order
+	MaxChange Kind = "max_change" // Field must not change by more than MaxChangePct percent from the previous row of its series
+)
+
+// Action is what a sync does with a row violating a Rule
+type Action string
+
+const (
+	Warn   Action = "warn"   // the violation is logged and the row stored. It is the default
+	Reject Action = "reject" // the violation is logged and the row not stored. A value already stored for its key is kept
+	Abort  Action = "abort"  // the run fails, storing nothing
+)
+
+// Rule is a data-quality check of the rows of a dataset
+type Rule struct {
+	Kind         Kind     `toml:"kind" json:"kind"`
+	Field        string   `toml:"field" json:"field,omitempty"` // the field checked. Not used by Monotonic
+	Gt           *float64 `toml:"gt" json:"gt,omitempty"`       // bounds of Range. At least one must be set
+	Gte          *float64 `toml:"gte" json:"gte,omitempty"`
+	Lt           *float64 `toml:"lt" json:"lt,omitempty"`
+	Lte          *float64 `toml:"lte" json:"lte,omitempty"`
+	MaxChangePct float64  `toml:"maxChangePct" json:"max_change_pct,omitempty"` // of MaxChange, relative to the previous value
+	Action       Action   `toml:"action" json:"action,omitempty"`               // Warn by default
+}
+
+// Rules are the rules of each dataset, keyed by dataset name, as in the [[quality."ecb.exchange_rate"]] config tables
+type Rules map[string][]Rule
+
+// For returns the rules of dataset
+func (r Rules) For(dataset string) []Rule {
+	return r[dataset]
+}
+
+// Validate returns an error naming the first invalid rule
+func (r Rules) Validate() error {
+
+	for dataset, rules := range r {
+		for i, rule := range rules {
+			if err := rule.Validate(); err != nil {
+				return fmt.Errorf("%s rule %d: %w", dataset, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate returns an error if r is incomplete or has an unknown kind or action
+func (r Rule) Validate() error {
+
+	switch r.Kind {
+	case NotNull, Range, MaxChange:
+		if r.Field == "" {
+			return fmt.Errorf("%w: field is required for kind %s", cerrors.ErrValidationFailed, r.Kind)
+		}
+	case Monotonic:
+	default:
+		return fmt.Errorf("%w: unknown kind: '%s'", cerrors.ErrValidationFailed, r.Kind)
+	}
+
+	if r.Kind == Range && r.Gt == nil && r.Gte == nil && r.Lt == nil && r.Lte == nil {
+		return fmt.Errorf("%w: range needs at least one of gt, gte, lt or lte", cerrors.ErrValidationFailed)
+	}
+	if r.Kind == MaxChange && r.MaxChangePct <= 0 {
+		return fmt.Errorf("%w: max_change needs a positive maxChangePct", cerrors.ErrValidationFailed)
+	}
+
+	switch r.Action {
+	case "", Warn, Reject, Abort:
+	default:
+		return fmt.Errorf("%w: unknown action: '%s'", cerrors.ErrValidationFailed, r.Action)
+	}
+
+	return nil
+}
+
+// action returns the Action of r, applying the default
+func (r Rule) action() Action {
+	if r.Action == "" {
+		return Warn
+	}
+	return r.Action
+}
+
+// String returns the kind and field of r, e.g. "range(rate)", to identify it in logs
+func (r Rule) String() string {
+	if r.Field == "" {
+		return string(r.Kind)
+	}
+	return fmt.Sprintf("%s(%s)", r.Kind, r.Field)
+}
+
+// Row is a row of a dataset as seen by the rules
+type Row struct {
+	Series string         // the series of the row, e.g. "EUR/USD", within which Monotonic and MaxChange compare consecutive rows
+	Day    time.Time      // the day of the row, checked by Monotonic
+	Values map[string]any // the values of the fields the rules may check, by name
+}
+
+// Violation is a row which failed a rule
+type Violation struct {
+	Rule    Rule
+	Action  Action    // the action taken, that of Rule or its default
+	Row     int       // index of the row in the rows checked
+	Series  string    // of the row
+	Day     time.Time // of the row
+	Message string
+}
+
+// seriesState is the previous kept row of a series, to which Monotonic and MaxChange compare the next
+type seriesState struct {
+	day   time.Time
+	value float64
+	ok    bool // if false, value is not a number
+}
+
+// Check evaluates rules on each of rows in order. Monotonic and MaxChange compare a row with the previous row of its series which was not rejected
+// it returns all violations, and rejected[i] is true if row i violated a Reject rule. If an Abort rule is violated, the error matches cerrors.ErrValidationFailed and names the violation
+// a rule of a field missing from the Values of a row returns an error matching cerrors.ErrValidationFailed, since the rule does not fit the dataset
+func Check(rules []Rule, rows []Row) (violations []Violation, rejected []bool, err error) {
+
+	rejected = make([]bool, len(rows))
+	if len(rules) == 0 {
+		return nil, rejected, nil
+	}
+
+	// the previous kept row of each series, per rule
+	prevs := make([]map[string]seriesState, len(rules))
+	for i := range prevs {
+		prevs[i] = make(map[string]seriesState)
+	}
+
+	for i, row := range rows {
+
+		for j, rule := range rules {
+
+			msg, err := check(rule, row, prevs[j])
+			if err != nil {
+				return nil, nil, fmt.Errorf("rule %s: %w", rule, err)
+			}
+			if msg == "" {
+				continue
+			}
+
+			v := Violation{Rule: rule, Action: rule.action(), Row: i, Series: row.Series, Day: row.Day, Message: msg}
+			if v.Action == Abort {
+				return nil, nil, fmt.Errorf("%w: data quality rule %s violated by %s on %s: %s", cerrors.ErrValidationFailed, rule, row.Series, row.Day.Format("2006-01-02"), msg)
+			}
+			if v.Action == Reject {
+				rejected[i] = true
+			}
+			violations = append(violations, v)
+		}
+
+		if rejected[i] {
+			continue
+		}
+		for j, rule := range rules {
+			if rule.Kind == Monotonic || rule.Kind == MaxChange {
+				value, ok := toFloat(row.Values[rule.Field])
+				prevs[j][row.Series] = seriesState{day: row.Day, value: value, ok: ok}
+			}
+		}
+	}
+
+	return violations, rejected, nil
+}
+
+// check returns the message of the violation of rule by row, or "" if there is none
+func check(rule Rule, row Row, prevs map[string]seriesState) (msg string, err error) {
+
+	if rule.Kind == Monotonic {
+		prev, ok := prevs[row.Series]
+		if ok && !row.Day.After(prev.day) {
+			return fmt.Sprintf("day %s does not follow %s", row.Day.Format("2006-01-02"), prev.day.Format("2006-01-02")), nil
+		}
+		return "", nil
+	}
+
+	val, ok := row.Values[rule.Field]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown field: %s", cerrors.ErrValidationFailed, rule.Field)
+	}
+
+	if isNull(val) {
+		if rule.Kind == NotNull {
+			return "no value", nil
+		}
+		// other rules only check values
+		return "", nil
+	}
+
+	switch rule.Kind {
+	case Range:
+		num, ok := toFloat(val)
+		if !ok {
+			return fmt.Sprintf("%v is not a number", val), nil
+		}
+		var failed []string
+		if rule.Gt != nil && !(num > *rule.Gt) {
+			failed = append(failed, fmt.Sprintf("> %g", *rule.Gt))
+		}
+		if rule.Gte != nil && !(num >= *rule.Gte) {
+			failed = append(failed, fmt.Sprintf(">= %g", *rule.Gte))
+		}
+		if rule.Lt != nil && !(num < *rule.Lt) {
+			failed = append(failed, fmt.Sprintf("< %g", *rule.Lt))
+		}
+		if rule.Lte != nil && !(num <= *rule.Lte) {
+			failed = append(failed, fmt.Sprintf("<= %g", *rule.Lte))
+		}
+		if len(failed) > 0 {
+			return fmt.Sprintf("%g is not %s", num, strings.Join(failed, " and ")), nil
+		}
+	case MaxChange:
+		num, ok := toFloat(val)
+		prev, hasPrev := prevs[row.Series]
+		if !ok || !hasPrev || !prev.ok || prev.value == 0 {
+			return "", nil
+		}
+		changePct := math.Abs(num-prev.value) / math.Abs(prev.value) * 100
+		if changePct > rule.MaxChangePct {
+			return fmt.Sprintf("%g changed by %.2f%% from %g on %s, more than %g%%", num, changePct, prev.value, prev.day.Format("2006-01-02"), rule.MaxChangePct), nil
+		}
+	}
+
+	return "", nil
+}
+
+// isNull returns true if val is nil, an empty string, a zero time or NaN
+func isNull(val any) bool {
+
+	switch v := val.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case time.Time:
+		return v.IsZero()
+	case float64:
+		return math.IsNaN(v)
+	case float32:
+		return math.IsNaN(float64(v))
+	}
+	return false
+}
+
+// toFloat returns val as a float64. ok is false if it is not a number
+func toFloat(val any) (num float64, ok bool) {
+
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case float32:
+		// by its shortest decimal representation, so that 1.1 is not compared or logged as 1.100000023841858
+		num, err := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'g', -1, 32), 64)
+		return num, err == nil
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,149 @@
+package quality_test
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/quality"
+)
+
+var day1 = time.Date(2024, 9, 2, 0, 0, 0, 0, time.UTC)
+
+func TestCheck(t *testing.T) {
+
+	zero, one := 0.0, 1.0
+
+	// rate rows of EUR/USD on consecutive days
+	rates := func(vals ...any) []quality.Row {
+		rows := make([]quality.Row, len(vals))
+		for i, val := range vals {
+			rows[i] = quality.Row{Series: "EUR/USD", Day: day1.AddDate(0, 0, i), Values: map[string]any{"rate": val}}
+		}
+		return rows
+	}
+
+	tests := []struct {
+		name    string
+		rule    quality.Rule
+		rows    []quality.Row
+		wantRow []int // the rows violating rule
+	}{
+		{"not null", quality.Rule{Kind: quality.NotNull, Field: "rate"}, rates(1.1, nil, "", math.NaN(), float32(math.NaN()), time.Time{}, 0.0), []int{1, 2, 3, 4, 5}},
+
+		{"positive rate", quality.Rule{Kind: quality.Range, Field: "rate", Gt: &zero}, rates(1.1, 1e-8, float32(0.84183), 1), nil},
+		{"non-positive rate", quality.Rule{Kind: quality.Range, Field: "rate", Gt: &zero}, rates(0.0, -1.1, float32(0), int64(-1)), []int{0, 1, 2, 3}},
+		{"gte boundary", quality.Rule{Kind: quality.Range, Field: "rate", Gte: &zero}, rates(0.0, -1e-8), []int{1}},
+		{"lt boundary", quality.Rule{Kind: quality.Range, Field: "rate", Lt: &one}, rates(0.99, 1.0), []int{1}},
+		{"lte boundary", quality.Rule{Kind: quality.Range, Field: "rate", Lte: &one}, rates(1.0, 1.01), []int{1}},
+		{"range of a non-number", quality.Rule{Kind: quality.Range, Field: "rate", Gt: &zero}, rates("1.1"), []int{0}},
+		{"range skips no value", quality.Rule{Kind: quality.Range, Field: "rate", Gt: &zero}, rates(nil), nil},
+
+		{"jump at the threshold", quality.Rule{Kind: quality.MaxChange, Field: "rate", MaxChangePct: 50}, rates(1.0, 1.5, 0.75), nil},
+		{"jump above the threshold", quality.Rule{Kind: quality.MaxChange, Field: "rate", MaxChangePct: 50}, rates(1.0, 1.5000001, 1.0), []int{1}},
+		{"fall above the threshold", quality.Rule{Kind: quality.MaxChange, Field: "rate", MaxChangePct: 10}, rates(1.0, 0.89), []int{1}},
+		{"no jump from zero", quality.Rule{Kind: quality.MaxChange, Field: "rate", MaxChangePct: 10}, rates(0.0, 1.1), nil},
+		{"no jump over no value", quality.Rule{Kind: quality.MaxChange, Field: "rate", MaxChangePct: 10}, rates(1.0, nil, 1.05), nil},
+
+		{"days increasing", quality.Rule{Kind: quality.Monotonic}, rates(1.1, 1.1, 1.1), nil},
+		{"stale day", quality.Rule{Kind: quality.Monotonic}, []quality.Row{
+			{Series: "EUR/USD", Day: day1}, {Series: "EUR/USD", Day: day1}, {Series: "EUR/USD", Day: day1.AddDate(0, 0, 1)},
+		}, []int{1}},
+		{"day out of order", quality.Rule{Kind: quality.Monotonic}, []quality.Row{
+			{Series: "EUR/USD", Day: day1.AddDate(0, 0, 1)}, {Series: "EUR/USD", Day: day1},
+		}, []int{1}},
+		{"days of other series", quality.Rule{Kind: quality.Monotonic}, []quality.Row{
+			{Series: "EUR/USD", Day: day1}, {Series: "EUR/GBP", Day: day1}, {Series: "EUR/GBP", Day: day1.AddDate(0, 0, 1)},
+		}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			violations, rejected, err := quality.Check([]quality.Rule{tt.rule}, tt.rows)
+			if err != nil {
+				t.Fatalf("quality.Check failed: %s", err.Error())
+			}
+			var gotRow []int
+			for _, v := range violations {
+				gotRow = append(gotRow, v.Row)
+				if v.Action != quality.Warn || v.Message == "" {
+					t.Errorf("violation of row %d: got action %s, message '%s', want warn with a message", v.Row, v.Action, v.Message)
+				}
+			}
+			if !slices.Equal(gotRow, tt.wantRow) {
+				t.Errorf("violating rows: got %v, want %v", gotRow, tt.wantRow)
+			}
+			if slices.Contains(rejected, true) {
+				t.Errorf("rejected: got %v, want none: warn is the default", rejected)
+			}
+		})
+	}
+}
+
+func TestCheckActions(t *testing.T) {
+
+	rows := []quality.Row{
+		{Series: "EUR/USD", Day: day1, Values: map[string]any{"rate": 1.0}},
+		{Series: "EUR/USD", Day: day1.AddDate(0, 0, 1), Values: map[string]any{"rate": 2.0}},
+		{Series: "EUR/USD", Day: day1.AddDate(0, 0, 2), Values: map[string]any{"rate": 1.04}},
+	}
+	jump := quality.Rule{Kind: quality.MaxChange, Field: "rate", MaxChangePct: 10, Action: quality.Reject}
+
+	// a rejected row is not the base of the next: 1.04 is compared with 1.0
+	violations, rejected, err := quality.Check([]quality.Rule{jump}, rows)
+	if err != nil {
+		t.Fatalf("quality.Check failed: %s", err.Error())
+	}
+	if len(violations) != 1 || violations[0].Row != 1 || violations[0].Action != quality.Reject {
+		t.Errorf("violations: got %+v, want row 1 rejected", violations)
+	}
+	if !slices.Equal(rejected, []bool{false, true, false}) {
+		t.Errorf("rejected: got %v, want [false true false]", rejected)
+	}
+
+	jump.Action = quality.Abort
+	if _, _, err = quality.Check([]quality.Rule{jump}, rows); !errors.Is(err, cerrors.ErrValidationFailed) {
+		t.Errorf("quality.Check of an abort rule: got %v, want cerrors.ErrValidationFailed", err)
+	}
+
+	// a rule of a field the rows don't have doesn't fit the dataset
+	unknown := quality.Rule{Kind: quality.NotNull, Field: "amount"}
+	if _, _, err = quality.Check([]quality.Rule{unknown}, rows); !errors.Is(err, cerrors.ErrValidationFailed) {
+		t.Errorf("quality.Check of an unknown field: got %v, want cerrors.ErrValidationFailed", err)
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+
+	zero := 0.0
+
+	tests := []struct {
+		name    string
+		rule    quality.Rule
+		wantErr bool
+	}{
+		{"not null", quality.Rule{Kind: quality.NotNull, Field: "rate"}, false},
+		{"not null without field", quality.Rule{Kind: quality.NotNull}, true},
+		{"range", quality.Rule{Kind: quality.Range, Field: "rate", Gt: &zero, Action: quality.Reject}, false},
+		{"range without bounds", quality.Rule{Kind: quality.Range, Field: "rate"}, true},
+		{"max change", quality.Rule{Kind: quality.MaxChange, Field: "rate", MaxChangePct: 10}, false},
+		{"max change of 0", quality.Rule{Kind: quality.MaxChange, Field: "rate"}, true},
+		{"monotonic", quality.Rule{Kind: quality.Monotonic, Action: quality.Abort}, false},
+		{"unknown kind", quality.Rule{Kind: "unique", Field: "rate"}, true},
+		{"unknown action", quality.Rule{Kind: quality.Monotonic, Action: "drop"}, true},
+	}
+
+	for _, tt := range tests {
+		err := tt.rule.Validate()
+		if tt.wantErr && !errors.Is(err, cerrors.ErrValidationFailed) {
+			t.Errorf("%s: got %v, want cerrors.ErrValidationFailed", tt.name, err)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("%s: got %v, want nil", tt.name, err)
+		}
+	}
+}
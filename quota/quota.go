@@ -0,0 +1,234 @@
+// Package quota accounts for the requests made to the APIs of connectors whose providers limit them, e.g. to a monthly cap per API key,
+// so that syncs warn when the limit is approached and the daemon defers non-urgent syncs before it is reached
+package quota
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/stores/connectors/quotausage"
+)
+
+// Window is the period a Limit counts requests over. Windows start in UTC
+type Window string
+
+const (
+	Hour  Window = "hour"
+	Day   Window = "day"
+	Month Window = "month" // the default
+)
+
+const (
+	defaultWarnAt  float64 = 0.8
+	defaultDeferAt float64 = 0.9
+)
+
+// Limit is the request quota of a connector's provider
+type Limit struct {
+	Requests int64    `toml:"requests"` // requests allowed per window
+	Window   Window   `toml:"window"`   // hour, day or month. Defaults to month
+	WarnAt   float64  `toml:"warnAt"`   // share of Requests used from which a warning is logged after each sync. Defaults to 0.8
+	DeferAt  float64  `toml:"deferAt"`  // share of Requests used from which the daemon defers syncs of datasets not in Urgent. Defaults to 0.9
+	Urgent   []string `toml:"urgent"`   // datasets synced by the daemon until Requests are used up
+}
+
+// Limits are the limits of each connector, keyed by connector name, as in the [quotas.<name>] config tables
+type Limits map[string]Limit
+
+// For returns the limit of connector, and false if it has none
+func (ls Limits) For(connector string) (Limit, bool) {
+	l, ok := ls[connector]
+	return l, ok
+}
+
+// Validate returns an error naming the first invalid limit
+func (ls Limits) Validate() error {
+
+	for connector, l := range ls {
+		if err := l.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", connector, err)
+		}
+	}
+	return nil
+}
+
+// Validate returns an error if l is invalid
+func (l Limit) Validate() error {
+
+	if l.Requests < 1 {
+		return fmt.Errorf("requests must be at least 1")
+	}
+	switch l.Window {
+	case "", Hour, Day, Month:
+	default:
+		return fmt.Errorf("invalid window '%s': must be hour, day or month", l.Window)
+	}
+	if l.WarnAt < 0 || l.WarnAt > 1 {
+		return fmt.Errorf("warnAt must be between 0 and 1")
+	}
+	if l.DeferAt < 0 || l.DeferAt > 1 {
+		return fmt.Errorf("deferAt must be between 0 and 1")
+	}
+	return nil
+}
+
+// WindowStart returns the start of the window containing t
+func (l Limit) WindowStart(t time.Time) time.Time {
+
+	t = t.UTC()
+	switch l.Window {
+	case Hour:
+		return t.Truncate(time.Hour)
+	case Day:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// warnThreshold returns the requests from which a warning is logged
+func (l Limit) warnThreshold() float64 {
+
+	if l.WarnAt == 0 {
+		return defaultWarnAt * float64(l.Requests)
+	}
+	return l.WarnAt * float64(l.Requests)
+}
+
+// deferThreshold returns the requests from which syncs of datasets not in Urgent are deferred
+func (l Limit) deferThreshold() float64 {
+
+	if l.DeferAt == 0 {
+		return defaultDeferAt * float64(l.Requests)
+	}
+	return l.DeferAt * float64(l.Requests)
+}
+
+// Meter counts the HTTP requests of an API client. It is safe for concurrent use
+// a nil *Meter counts nothing, so that connectors can apply the meter of registry.Deps whether or not their provider has a limit
+type Meter struct {
+	mu sync.Mutex
+	n  int64
+}
+
+// Client returns a copy of hc whose requests are counted by m, or hc itself if m is nil
+func (m *Meter) Client(hc *http.Client) *http.Client {
+
+	if m == nil || hc == nil {
+		return hc
+	}
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	counted := *hc
+	counted.Transport = meteredTransport{base: base, m: m}
+	return &counted
+}
+
+// take returns the requests counted since the last call
+func (m *Meter) take() int64 {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := m.n
+	m.n = 0
+	return n
+}
+
+// meteredTransport counts each request sent, whether or not it succeeds, since failed requests may count towards the quota as well
+type meteredTransport struct {
+	base http.RoundTripper
+	m    *Meter
+}
+
+func (t meteredTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	t.m.mu.Lock()
+	t.m.n++
+	t.m.mu.Unlock()
+	return t.base.RoundTrip(req)
+}
+
+// Usage is the requests made in the current window of a connector with a limit
+type Usage struct {
+	Connector   string
+	WindowStart time.Time
+	Used        int64
+	Limit       Limit
+}
+
+// Tracker persists the requests counted by meters in Db, normally the first sync target, so that usage survives restarts
+type Tracker struct {
+	Db      *pgxpool.Pool
+	Limits  Limits
+	InfoLog *slog.Logger
+}
+
+// Meter returns a new meter for connector, or nil if it has no limit
+func (t Tracker) Meter(connector string) *Meter {
+
+	if _, ok := t.Limits.For(connector); !ok {
+		return nil
+	}
+	return &Meter{}
+}
+
+// Record adds the requests counted by m since the last call to the usage of connector in the window containing now
+// a warning is logged if the usage reaches the warning threshold of the limit. Nothing is recorded if m is nil
+func (t Tracker) Record(ctx context.Context, connector string, m *Meter, now time.Time) error {
+
+	l, ok := t.Limits.For(connector)
+	if m == nil || !ok {
+		return nil
+	}
+	n := m.take()
+	if n == 0 {
+		return nil
+	}
+
+	windowStart := l.WindowStart(now)
+	used, err := quotausage.Store{Db: t.Db}.Add(ctx, connector, windowStart, n)
+	if err != nil {
+		return fmt.Errorf("quotausage.Store.Add failed: %w", err)
+	}
+
+	if float64(used) >= l.warnThreshold() {
+		t.InfoLog.Warn("request quota nearly used", "connector", connector, "used", used, "quota", l.Requests, "window_start", windowStart.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// Usage returns the usage of connector in the window containing now, and false if it has no limit
+func (t Tracker) Usage(ctx context.Context, connector string, now time.Time) (u Usage, ok bool, err error) {
+
+	l, ok := t.Limits.For(connector)
+	if !ok {
+		return Usage{}, false, nil
+	}
+
+	u = Usage{Connector: connector, WindowStart: l.WindowStart(now), Limit: l}
+	u.Used, err = quotausage.Store{Db: t.Db}.SelectRequests(ctx, connector, u.WindowStart)
+	if err != nil {
+		return Usage{}, false, fmt.Errorf("quotausage.Store.SelectRequests failed: %w", err)
+	}
+
+	return u, true, nil
+}
+
+// Defer returns true if the sync of dataset should wait for the next window: always once the limit is used up,
+// and from the defer threshold unless dataset is urgent
+func (u Usage) Defer(dataset string) bool {
+
+	if u.Used >= u.Limit.Requests {
+		return true
+	}
+	return float64(u.Used) >= u.Limit.deferThreshold() && !slices.Contains(u.Limit.Urgent, dataset)
+}
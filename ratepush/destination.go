@@ -0,0 +1,170 @@
+package ratepush
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// destination kinds
+const (
+	KindRest  string = "rest"  // rates are sent in batches, in a JSON body or one rendered by Template
+	KindOData string = "odata" // rates are created one by one as entities of a SAP-style OData v2 entity set
+)
+
+const (
+	defaultBatchSize int           = 500
+	defaultRateType  string        = "M"
+	defaultTimeout   time.Duration = 30 * time.Second
+)
+
+var destinationNameRegex = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// Destination configures a downstream system that newly synced rates are pushed to
+type Destination struct {
+	Name       string            `toml:"name"`       // lower case, stored as the destination of the delivery state
+	Kind       string            `toml:"kind"`       // rest or odata. Defaults to rest
+	Url        string            `toml:"url"`        // rest: the endpoint. odata: the entity set, e.g. .../API_EXCHANGE_RATE_SRV/A_ExchangeRate
+	Method     string            `toml:"method"`     // of rest requests. Defaults to POST
+	Headers    map[string]string `toml:"headers"`    // added to each request, e.g. an API key header
+	Token      string            `toml:"token"`      // sent as bearer token, if set
+	Username   string            `toml:"username"`   // for basic auth, if set
+	Password   string            `toml:"password"`   // for basic auth
+	Template   string            `toml:"template"`   // rest: Go text/template of the request body, executed with a Batch. Defaults to the Batch as JSON
+	BatchSize  int               `toml:"batchSize"`  // rest: rates per request. Defaults to 500
+	RateType   string            `toml:"rateType"`   // odata: the ExchangeRateType of created entities. Defaults to M
+	Currencies []string          `toml:"currencies"` // quote currencies of the rates to push. All if empty
+	Target     string            `toml:"target"`     // name of the sync target whose rates are pushed. Defaults to the first, i.e. [database]
+	Timeout    string            `toml:"timeout"`    // Go duration of each request. Defaults to 30s
+}
+
+// WithDefaults returns d with the defaults of its kind applied to unset fields
+func (d Destination) WithDefaults() Destination {
+
+	if d.Kind == "" {
+		d.Kind = KindRest
+	}
+	if d.Method == "" {
+		d.Method = http.MethodPost
+	}
+	if d.BatchSize == 0 {
+		d.BatchSize = defaultBatchSize
+	}
+	if d.RateType == "" {
+		d.RateType = defaultRateType
+	}
+	for i, curr := range d.Currencies {
+		d.Currencies[i] = strings.ToUpper(curr)
+	}
+
+	return d
+}
+
+// Validate returns an error if d, with defaults applied, is incomplete or invalid
+func (d Destination) Validate() error {
+
+	if !destinationNameRegex.MatchString(d.Name) {
+		return fmt.Errorf("%w: name must be lower case letters, digits, _ or -: %q", cerrors.ErrValidationFailed, d.Name)
+	}
+	if !strings.HasPrefix(d.Url, "http://") && !strings.HasPrefix(d.Url, "https://") {
+		return fmt.Errorf("%w: destination %s: url must start with http:// or https://", cerrors.ErrValidationFailed, d.Name)
+	}
+	if d.BatchSize < 1 {
+		return fmt.Errorf("%w: destination %s: batchSize must be positive", cerrors.ErrValidationFailed, d.Name)
+	}
+	if d.Token != "" && d.Username != "" {
+		return fmt.Errorf("%w: destination %s: set either token or username, not both", cerrors.ErrValidationFailed, d.Name)
+	}
+
+	switch d.Kind {
+	case KindRest:
+		if _, err := newBodyTemplate(d.Template); err != nil {
+			return fmt.Errorf("%w: destination %s: template: %w", cerrors.ErrValidationFailed, d.Name, err)
+		}
+	case KindOData:
+		if d.Template != "" {
+			return fmt.Errorf("%w: destination %s: template is only used by rest destinations", cerrors.ErrValidationFailed, d.Name)
+		}
+	default:
+		return fmt.Errorf("%w: destination %s: unknown kind: %s", cerrors.ErrValidationFailed, d.Name, d.Kind)
+	}
+
+	if _, err := d.timeout(); err != nil {
+		return fmt.Errorf("%w: destination %s: timeout: %w", cerrors.ErrValidationFailed, d.Name, err)
+	}
+
+	return nil
+}
+
+// Includes returns true if rates quoted in currency should be pushed to d
+func (d Destination) Includes(currency string) bool {
+	return len(d.Currencies) == 0 || slices.Contains(d.Currencies, currency)
+}
+
+// timeout returns the parsed Timeout, or defaultTimeout if not set
+func (d Destination) timeout() (time.Duration, error) {
+
+	if d.Timeout == "" {
+		return defaultTimeout, nil
+	}
+	return time.ParseDuration(d.Timeout)
+}
+
+// authorize adds the configured headers and credentials to req
+func (d Destination) authorize(req *http.Request) {
+
+	for k, v := range d.Headers {
+		req.Header.Set(k, v)
+	}
+	switch {
+	case d.Token != "":
+		req.Header.Set("Authorization", "Bearer "+d.Token)
+	case d.Username != "":
+		req.SetBasicAuth(d.Username, d.Password)
+	}
+}
+
+// Rate is an exchange rate pushed to destinations
+type Rate struct {
+	Day          string  `json:"day"` // yyyy-mm-dd
+	Frequency    string  `json:"frequency"`
+	FromCurrency string  `json:"from_currency"`
+	ToCurrency   string  `json:"to_currency"`
+	Rate         float64 `json:"rate"`
+}
+
+// Batch is the data of a rest request body
+type Batch struct {
+	Destination string `json:"destination"`
+	Rates       []Rate `json:"rates"`
+}
+
+// Pusher sends rates to a destination. Push returns once all rates are accepted, or on the first failure
+type Pusher interface {
+	Push(ctx context.Context, rates []Rate) error
+}
+
+// NewPusher returns the Pusher of d's kind. d must be valid
+func NewPusher(d Destination, infoLog, errorLog *slog.Logger) (Pusher, error) {
+
+	timeout, err := d.timeout()
+	if err != nil {
+		return nil, fmt.Errorf("d.timeout failed: %w", err)
+	}
+
+	switch d.Kind {
+	case KindRest:
+		return NewRestPusher(d, timeout, infoLog, errorLog)
+	case KindOData:
+		return NewODataPusher(d, timeout, infoLog, errorLog)
+	default:
+		return nil, fmt.Errorf("%w: unknown kind: %s", cerrors.ErrValidationFailed, d.Kind)
+	}
+}
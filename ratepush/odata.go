@@ -0,0 +1,213 @@
+package ratepush
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const csrfTokenHeader string = "X-CSRF-Token"
+
+// ODataPusher creates rates as entities of a SAP-style OData v2 entity set, such as A_ExchangeRate of SAP S/4HANA's exchange rate API
+// writes need a CSRF token, which is fetched with the session cookies before the first write and again if the service rejects it
+// a rate which already exists is reported with status 409 by such services: it is then updated at its entity key
+type ODataPusher struct {
+	Destination Destination
+	HttpClient  *http.Client
+	InfoLog     *slog.Logger
+	ErrorLog    *slog.Logger
+
+	csrfToken string
+}
+
+func NewODataPusher(d Destination, timeout time.Duration, infoLog, errorLog *slog.Logger) (*ODataPusher, error) {
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("cookiejar.New failed: %w", err)
+	}
+
+	return &ODataPusher{
+		Destination: d,
+		HttpClient:  &http.Client{Timeout: timeout, Jar: jar},
+		InfoLog:     infoLog,
+		ErrorLog:    errorLog,
+	}, nil
+}
+
+// ODataExchangeRate is the entity created per rate
+// in OData v2 JSON, decimals are strings and dates are "/Date(<Unix milliseconds>)/"
+type ODataExchangeRate struct {
+	ExchangeRateType            string `json:"ExchangeRateType"`
+	SourceCurrency              string `json:"SourceCurrency"`
+	TargetCurrency              string `json:"TargetCurrency"`
+	ExchangeRateEffectiveDate   string `json:"ExchangeRateEffectiveDate"`
+	ExchangeRate                string `json:"ExchangeRate"`
+	NumberOfSourceCurrencyUnits string `json:"NumberOfSourceCurrencyUnits"`
+	NumberOfTargetCurrencyUnits string `json:"NumberOfTargetCurrencyUnits"`
+}
+
+// Entity returns the entity of rate, quoted per unit of the source currency
+func (p *ODataPusher) Entity(rate Rate) (ODataExchangeRate, error) {
+
+	day, err := time.Parse(lystype.DateFormat, rate.Day)
+	if err != nil {
+		return ODataExchangeRate{}, fmt.Errorf("time.Parse failed: %w", err)
+	}
+
+	return ODataExchangeRate{
+		ExchangeRateType:            p.Destination.RateType,
+		SourceCurrency:              rate.FromCurrency,
+		TargetCurrency:              rate.ToCurrency,
+		ExchangeRateEffectiveDate:   fmt.Sprintf("/Date(%d)/", day.UnixMilli()),
+		ExchangeRate:                strconv.FormatFloat(rate.Rate, 'f', -1, 64),
+		NumberOfSourceCurrencyUnits: "1",
+		NumberOfTargetCurrencyUnits: "1",
+	}, nil
+}
+
+// EntityUrl returns the URL of the entity of rate, addressed by its key
+func (p *ODataPusher) EntityUrl(rate Rate) string {
+
+	key := fmt.Sprintf("ExchangeRateType='%s',SourceCurrency='%s',TargetCurrency='%s',ExchangeRateEffectiveDate=datetime'%sT00:00:00'",
+		p.Destination.RateType, rate.FromCurrency, rate.ToCurrency, rate.Day)
+
+	return strings.TrimSuffix(p.Destination.Url, "/") + "(" + key + ")"
+}
+
+// Push creates an entity per rate, in order
+func (p *ODataPusher) Push(ctx context.Context, rates []Rate) error {
+
+	for i, rate := range rates {
+		err := p.pushRate(ctx, rate)
+		if err != nil {
+			return fmt.Errorf("p.pushRate failed for %s/%s on %s: %w", rate.FromCurrency, rate.ToCurrency, rate.Day, err)
+		}
+		if (i+1)%100 == 0 {
+			p.InfoLog.Debug("pushed rates", "destination", p.Destination.Name, clog.KeyCount, i+1)
+		}
+	}
+
+	return nil
+}
+
+func (p *ODataPusher) pushRate(ctx context.Context, rate Rate) error {
+
+	entity, err := p.Entity(rate)
+	if err != nil {
+		return fmt.Errorf("p.Entity failed: %w", err)
+	}
+	body, err := json.Marshal(entity)
+	if err != nil {
+		return fmt.Errorf("json.Marshal failed: %w", err)
+	}
+
+	statusCode, err := p.write(ctx, http.MethodPost, p.Destination.Url, body)
+	if err != nil {
+		return fmt.Errorf("p.write (create) failed: %w", err)
+	}
+	if statusCode == http.StatusConflict {
+		statusCode, err = p.write(ctx, http.MethodPut, p.EntityUrl(rate), body)
+		if err != nil {
+			return fmt.Errorf("p.write (update) failed: %w", err)
+		}
+	}
+	if statusCode < 200 || statusCode > 299 {
+		return cerrors.UpstreamError{Source: p.Destination.Name, StatusCode: statusCode}
+	}
+
+	return nil
+}
+
+// write sends body with a CSRF token, fetching the token first if needed, and again once if the service requires a new one
+// it returns the status code of the response: only transport errors are returned as err
+func (p *ODataPusher) write(ctx context.Context, method, reqUrl string, body []byte) (statusCode int, err error) {
+
+	for attempt := 1; ; attempt++ {
+
+		if p.csrfToken == "" {
+			p.csrfToken, err = p.fetchCsrfToken(ctx)
+			if err != nil {
+				return 0, fmt.Errorf("p.fetchCsrfToken failed: %w", err)
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, reqUrl, bytes.NewReader(body))
+		if err != nil {
+			return 0, fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set(csrfTokenHeader, p.csrfToken)
+		p.Destination.authorize(req)
+
+		resp, err := p.HttpClient.Do(req)
+		if err != nil {
+			return 0, fmt.Errorf("p.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: p.Destination.Name, Err: err})
+		}
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+
+		// the token expired with the session
+		if resp.StatusCode == http.StatusForbidden && strings.EqualFold(resp.Header.Get(csrfTokenHeader), "required") && attempt == 1 {
+			p.csrfToken = ""
+			continue
+		}
+
+		if resp.StatusCode >= 300 && resp.StatusCode != http.StatusConflict {
+			p.ErrorLog.Error("odata write failed", "destination", p.Destination.Name, "method", method, "status", resp.StatusCode, "response", strings.TrimSpace(string(respBody)))
+		}
+		return resp.StatusCode, nil
+	}
+}
+
+// fetchCsrfToken requests a CSRF token with a GET of the entity set. The session cookies are kept by the client's jar
+func (p *ODataPusher) fetchCsrfToken(ctx context.Context) (string, error) {
+
+	u, err := url.Parse(p.Destination.Url)
+	if err != nil {
+		return "", fmt.Errorf("url.Parse failed: %w", err)
+	}
+	q := u.Query()
+	q.Set("$top", "0")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set(csrfTokenHeader, "Fetch")
+	p.Destination.authorize(req)
+
+	resp, err := p.HttpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("p.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: p.Destination.Name, Err: err})
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", cerrors.UpstreamError{Source: p.Destination.Name, StatusCode: resp.StatusCode}
+	}
+
+	token := resp.Header.Get(csrfTokenHeader)
+	if token == "" {
+		return "", fmt.Errorf("%w: no %s header in response", cerrors.ErrValidationFailed, csrfTokenHeader)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,119 @@
+package ratepush
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/clog"
+)
+
+// RestPusher sends rates in batches to a REST endpoint
+type RestPusher struct {
+	Destination Destination
+	HttpClient  *http.Client
+	InfoLog     *slog.Logger
+	ErrorLog    *slog.Logger
+
+	tmpl *template.Template // nil for the default JSON body
+}
+
+func NewRestPusher(d Destination, timeout time.Duration, infoLog, errorLog *slog.Logger) (*RestPusher, error) {
+
+	tmpl, err := newBodyTemplate(d.Template)
+	if err != nil {
+		return nil, fmt.Errorf("newBodyTemplate failed: %w", err)
+	}
+
+	return &RestPusher{
+		Destination: d,
+		HttpClient:  &http.Client{Timeout: timeout},
+		InfoLog:     infoLog,
+		ErrorLog:    errorLog,
+		tmpl:        tmpl,
+	}, nil
+}
+
+// newBodyTemplate parses text, with a json func which marshals its argument. Returns nil if text is empty
+func newBodyTemplate(text string) (*template.Template, error) {
+
+	if text == "" {
+		return nil, nil
+	}
+
+	funcs := template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}
+	return template.New("body").Funcs(funcs).Option("missingkey=error").Parse(text)
+}
+
+// Push sends rates in batches of Destination.BatchSize
+func (p *RestPusher) Push(ctx context.Context, rates []Rate) error {
+
+	for start := 0; start < len(rates); start += p.Destination.BatchSize {
+		end := min(start+p.Destination.BatchSize, len(rates))
+
+		body, err := p.Body(Batch{Destination: p.Destination.Name, Rates: rates[start:end]})
+		if err != nil {
+			return fmt.Errorf("p.Body failed: %w", err)
+		}
+
+		err = p.send(ctx, body)
+		if err != nil {
+			return fmt.Errorf("p.send failed for rates %d to %d: %w", start+1, end, err)
+		}
+		p.InfoLog.Debug("pushed rates", "destination", p.Destination.Name, clog.KeyCount, end-start)
+	}
+
+	return nil
+}
+
+// Body returns the request body of batch: the rendered Template, or else the batch as JSON
+func (p *RestPusher) Body(batch Batch) ([]byte, error) {
+
+	if p.tmpl == nil {
+		return json.Marshal(batch)
+	}
+
+	var buf bytes.Buffer
+	err := p.tmpl.Execute(&buf, batch)
+	if err != nil {
+		return nil, fmt.Errorf("p.tmpl.Execute failed: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (p *RestPusher) send(ctx context.Context, body []byte) error {
+
+	req, err := http.NewRequestWithContext(ctx, p.Destination.Method, p.Destination.Url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	p.Destination.authorize(req)
+
+	resp, err := p.HttpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("p.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: p.Destination.Name, Err: err})
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%w: %s", cerrors.UpstreamError{Source: p.Destination.Name, StatusCode: resp.StatusCode}, strings.TrimSpace(string(respBody)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	return nil
+}
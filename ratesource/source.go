@@ -0,0 +1,147 @@
+package ratesource
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// Rate is a daily exchange rate: units of Quote per unit of Base
+type Rate struct {
+	Day   time.Time // UTC midnight
+	Base  string
+	Quote string
+	Rate  float64
+}
+
+// Source is a provider of daily exchange rates, such as a central bank or a commercial provider
+// implemented by the ecbapi, frankfurterapi, boeapi, wiseapi and exhostapi clients
+type Source interface {
+	// SourceName is the unique name the rates of the source are stored under, e.g. "ecb"
+	SourceName() string
+
+	// GetRates returns the daily rates from base to each of quotes in the date range
+	// sources which quote from another currency derive them as cross rates, so a quote missing from the source's rates is missing from the result
+	GetRates(ctx context.Context, base string, quotes []string, startDate, endDate time.Time) (rates []Rate, err error)
+}
+
+// Day returns the UTC midnight of the calendar day of t
+func Day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Rebase converts rates, which must all have the same base, into rates from base to quotes on each day, via cross rates if base differs
+// days without a rate of base are skipped. Results are ordered by day and quote
+func Rebase(rates []Rate, base string, quotes []string) (rebased []Rate, err error) {
+
+	if len(rates) == 0 {
+		return nil, nil
+	}
+
+	fromBase := rates[0].Base
+	byDay := make(map[time.Time]map[string]float64)
+	for _, r := range rates {
+		if r.Base != fromBase {
+			return nil, fmt.Errorf("%w: rates have several bases: %s and %s", cerrors.ErrValidationFailed, fromBase, r.Base)
+		}
+		if r.Rate <= 0 {
+			return nil, fmt.Errorf("%w: invalid rate %v of %s/%s on %s", cerrors.ErrValidationFailed, r.Rate, r.Base, r.Quote, r.Day.Format(lystype.DateFormat))
+		}
+		if byDay[r.Day] == nil {
+			byDay[r.Day] = map[string]float64{fromBase: 1}
+		}
+		byDay[r.Day][r.Quote] = r.Rate
+	}
+
+	for day, dayRates := range byDay {
+
+		baseRate, ok := dayRates[base]
+		if !ok {
+			continue
+		}
+
+		for quote, rate := range dayRates {
+			if quote == base || (len(quotes) > 0 && !slices.Contains(quotes, quote)) {
+				continue
+			}
+			rebased = append(rebased, Rate{Day: day, Base: base, Quote: quote, Rate: rate / baseRate})
+		}
+	}
+
+	sort.Slice(rebased, func(i, j int) bool {
+		if !rebased[i].Day.Equal(rebased[j].Day) {
+			return rebased[i].Day.Before(rebased[j].Day)
+		}
+		return rebased[i].Quote < rebased[j].Quote
+	})
+
+	return rebased, nil
+}
+
+// Divergence is a rate of a source which differs from the rate of the primary source on the same day by more than the tolerance
+type Divergence struct {
+	Day         time.Time
+	Base        string
+	Quote       string
+	Source      string
+	Rate        float64
+	PrimaryRate float64
+	DiffPct     float64 // (Rate - PrimaryRate) / PrimaryRate * 100
+}
+
+// Compare returns the rates of others, keyed by source name, which diverge from the primary rates of the same day and pair by more than tolerancePct percent
+// days and pairs the primary source has no rate for are not compared. Results are ordered by day, quote and source
+func Compare(primary []Rate, others map[string][]Rate, tolerancePct float64) (divergences []Divergence) {
+
+	type pairDay struct {
+		base, quote string
+		day         time.Time
+	}
+
+	primaryMap := make(map[pairDay]float64, len(primary))
+	for _, r := range primary {
+		primaryMap[pairDay{r.Base, r.Quote, r.Day}] = r.Rate
+	}
+
+	for source, rates := range others {
+		for _, r := range rates {
+			primaryRate, ok := primaryMap[pairDay{r.Base, r.Quote, r.Day}]
+			if !ok || primaryRate == 0 {
+				continue
+			}
+
+			diffPct := (r.Rate - primaryRate) / primaryRate * 100
+			if math.Abs(diffPct) <= tolerancePct {
+				continue
+			}
+			divergences = append(divergences, Divergence{
+				Day:         r.Day,
+				Base:        r.Base,
+				Quote:       r.Quote,
+				Source:      source,
+				Rate:        r.Rate,
+				PrimaryRate: primaryRate,
+				DiffPct:     diffPct,
+			})
+		}
+	}
+
+	sort.Slice(divergences, func(i, j int) bool {
+		a, b := divergences[i], divergences[j]
+		if !a.Day.Equal(b.Day) {
+			return a.Day.Before(b.Day)
+		}
+		if a.Quote != b.Quote {
+			return a.Quote < b.Quote
+		}
+		return a.Source < b.Source
+	})
+
+	return divergences
+}
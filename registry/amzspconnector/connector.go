@@ -0,0 +1,118 @@
+package amzspconnector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/amzspapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/integrity"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/amzorder"
+	"github.com/loveyourstack/connectors/stores/amzorder/amzorderheader"
+)
+
+const Name string = "amzsp"
+
+const DefaultRegion string = "eu"
+
+func init() {
+	registry.Register(Connector{Config: Config{Region: DefaultRegion}})
+}
+
+// Config contains the settings of the [connectors.amzsp] table
+type Config struct {
+	Region         string   `toml:"region"`   // na, eu or fe. Defaults to DefaultRegion
+	ClientId       string   `toml:"clientId"` // LWA credentials of the app
+	ClientSecret   string   `toml:"clientSecret"`
+	RefreshToken   string   `toml:"refreshToken"` // of the seller's authorization of the app
+	MarketplaceIds []string `toml:"marketplaceIds"`
+	RestrictedData bool     `toml:"restrictedData"` // request buyer details with a restricted data token. Needs an approved role
+}
+
+// Connector syncs the orders and order items of an Amazon seller with the Selling Partner API
+// it is disabled until the credentials and marketplaces are configured
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetAmazonOrders,
+			Description: "Amazon seller orders and their items, synced incrementally by last update",
+			SourceURL:   "https://developer-docs.amazon.com/sp-api/docs/orders-api-v0-reference",
+			Cadence:     "continuous",
+			License:     "data of the configured seller account, under the SP-API terms",
+			Dimensions:  []string{"amazon_order_id"},
+			Fields:      registry.StoreFields(amzorderheader.Store{}),
+		},
+	}
+}
+
+// Configure applies the [connectors.amzsp] table
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := Config{Region: DefaultRegion}
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	conf.Region = strings.ToLower(conf.Region)
+	if _, ok := amzspapi.RegionUrls[conf.Region]; !ok {
+		return nil, fmt.Errorf("invalid region '%s': must be na, eu or fe", conf.Region)
+	}
+	for i, id := range conf.MarketplaceIds {
+		conf.MarketplaceIds[i] = strings.ToUpper(strings.TrimSpace(id))
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+// Enabled returns true if the credentials and marketplaces are configured
+func (c Connector) Enabled() bool {
+	return c.Config.ClientId != "" && c.Config.ClientSecret != "" && c.Config.RefreshToken != "" && len(c.Config.MarketplaceIds) > 0
+}
+
+// Sync syncs the orders updated since the last sync, or in the last deps.Days days on the first sync
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !deps.Includes(csyncdb.DatasetAmazonOrders) {
+		return nil
+	}
+	if !c.Enabled() {
+		return fmt.Errorf("no credentials or marketplaces configured: pls set clientId, clientSecret, refreshToken and marketplaceIds in [connectors.%s]", Name)
+	}
+	if deps.Days < 1 {
+		return fmt.Errorf("deps.Days must be at least 1")
+	}
+
+	client := amzspapi.NewClient(c.Config.Region, c.Config.ClientId, c.Config.ClientSecret, c.Config.RefreshToken, deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+	client.RestrictedData = c.Config.RestrictedData
+
+	if err := csyncdb.AmazonOrdersToTargets(ctx, deps.Targets, client, c.Config.MarketplaceIds, deps.Days); err != nil {
+		return fmt.Errorf("csyncdb.AmazonOrdersToTargets failed: %w", err)
+	}
+
+	return nil
+}
+
+// Relations returns the references of the currencies of the orders and items to the ECB currencies, so that they can be converted with the stored rates. Orders without amounts have none
+func (c Connector) Relations() []integrity.Relation {
+	return []integrity.Relation{
+		{Table: "amzorder.sales_order", Column: "currency", RefTable: "ecb.currency", RefColumn: "code", AllowEmpty: true},
+		{Table: "amzorder.order_item", Column: "currency", RefTable: "ecb.currency", RefColumn: "code", AllowEmpty: true},
+	}
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: amzorder.Migrations, Dir: "migrations"}}, infoLog)
+}
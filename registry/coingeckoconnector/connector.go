@@ -0,0 +1,171 @@
+package coingeckoconnector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/coingeckoapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/coingecko"
+	"github.com/loveyourstack/connectors/stores/coingecko/cgcoin"
+	"github.com/loveyourstack/connectors/stores/coingecko/cgprice"
+)
+
+const Name string = "coingecko"
+
+var (
+	// DefaultCoins are synced if the config does not list any
+	DefaultCoins = []string{"bitcoin", "ethereum", "tether", "usd-coin"}
+
+	// DefaultVsCurrencies are synced if the config does not list any. EUR prices are needed by the converter
+	DefaultVsCurrencies = []string{"EUR", "USD"}
+)
+
+func init() {
+	registry.Register(Connector{Config: Config{Coins: DefaultCoins, VsCurrencies: DefaultVsCurrencies}})
+}
+
+// Config contains the settings of the [connectors.coingecko] table
+type Config struct {
+	ApiKey            string   `toml:"apiKey"`            // optional demo API key
+	RequestsPerMinute int      `toml:"requestsPerMinute"` // defaults to coingeckoapi.DefaultRequestsPerMinute. Raise it with an API key
+	Coins             []string `toml:"coins"`             // CoinGecko coin ids, e.g. bitcoin. Defaults to DefaultCoins
+	VsCurrencies      []string `toml:"vsCurrencies"`      // currencies of the prices, e.g. EUR. Defaults to DefaultVsCurrencies
+	Intraday          bool     `toml:"intraday"`          // also sync intraday prices as ticks of coingecko.price_tick, e.g. to a ClickHouse sink
+	IntradayDays      int      `toml:"intradayDays"`      // days of intraday prices to sync, up to 90. Defaults to 1, which gives 5-minutely prices, else hourly
+}
+
+// DefaultIntradayDays is the number of days of intraday prices synced if not configured
+const DefaultIntradayDays int = 1
+
+// Connector syncs crypto coins and their daily prices from CoinGecko
+// a request is made per coin and vs currency, spaced to stay within the rate limit, so large configs take minutes to sync
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+
+	datasets := []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetCoingeckoCoins,
+			Description: "CoinGecko coins",
+			SourceURL:   "https://docs.coingecko.com/",
+			Cadence:     "on change, as coins are listed",
+			License:     "CoinGecko API terms: attribution required",
+			Dimensions:  []string{"code"},
+			Fields:      registry.StoreFields(cgcoin.Store{}),
+		},
+		{
+			Name:        csyncdb.DatasetCoingeckoPrices,
+			Description: "CoinGecko daily coin prices, market caps and volumes",
+			SourceURL:   "https://docs.coingecko.com/",
+			Cadence:     "daily",
+			License:     "CoinGecko API terms: attribution required",
+			Dimensions:  []string{"coin", "vs_currency", "day"},
+			Fields:      registry.StoreFields(cgprice.Store{}),
+		},
+	}
+	if c.Config.Intraday {
+		datasets = append(datasets, registry.Dataset{
+			Name:        csyncdb.DatasetCoingeckoPriceTicks,
+			Description: "CoinGecko intraday coin prices and volumes",
+			SourceURL:   "https://docs.coingecko.com/",
+			Cadence:     "every few minutes",
+			License:     "CoinGecko API terms: attribution required",
+			Dimensions:  []string{"series", "at"},
+		})
+	}
+
+	return datasets
+}
+
+// Configure applies the [connectors.coingecko] table
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := Config{}
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	if len(conf.Coins) == 0 {
+		conf.Coins = DefaultCoins
+	}
+	if len(conf.VsCurrencies) == 0 {
+		conf.VsCurrencies = DefaultVsCurrencies
+	}
+	for i, vsCurr := range conf.VsCurrencies {
+		conf.VsCurrencies[i] = strings.ToUpper(vsCurr)
+	}
+	if conf.RequestsPerMinute < 0 {
+		return nil, fmt.Errorf("requestsPerMinute must not be negative")
+	}
+	if conf.IntradayDays == 0 {
+		conf.IntradayDays = DefaultIntradayDays
+	}
+	if conf.IntradayDays < 1 || conf.IntradayDays > 90 {
+		return nil, fmt.Errorf("intradayDays must be between 1 and 90")
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+// Sync syncs the configured coins, then the prices of the last deps.Days days of each coin in each vs currency
+// if intraday is set, the intraday prices of the last IntradayDays days follow, written to the sink routed by deps.Sinks or else to the targets
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	client := coingeckoapi.NewClient(c.Config.ApiKey, c.Config.RequestsPerMinute, deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+
+	var errs []error
+
+	if deps.Includes(csyncdb.DatasetCoingeckoCoins) {
+		if err := csyncdb.CoingeckoCoinsToTargets(ctx, deps.Targets, client, c.Config.Coins); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.CoingeckoCoinsToTargets failed: %w", err))
+		}
+	}
+
+	if deps.Includes(csyncdb.DatasetCoingeckoPrices) {
+		if deps.Days < 1 {
+			return errors.Join(append(errs, fmt.Errorf("deps.Days must be at least 1"))...)
+		}
+		for _, id := range c.Config.Coins {
+			for _, vsCurr := range c.Config.VsCurrencies {
+				if ctx.Err() != nil {
+					return errors.Join(append(errs, ctx.Err())...)
+				}
+				if err := csyncdb.CoingeckoPricesToTargets(ctx, deps.Targets, client, id, vsCurr, deps.Days); err != nil {
+					errs = append(errs, fmt.Errorf("csyncdb.CoingeckoPricesToTargets failed for %s in %s: %w", id, vsCurr, err))
+				}
+			}
+		}
+	}
+
+	if c.Config.Intraday && deps.Includes(csyncdb.DatasetCoingeckoPriceTicks) {
+		for _, id := range c.Config.Coins {
+			for _, vsCurr := range c.Config.VsCurrencies {
+				if ctx.Err() != nil {
+					return errors.Join(append(errs, ctx.Err())...)
+				}
+				if err := csyncdb.CoingeckoPriceTicksToTargets(ctx, deps.Targets, deps.Sinks, client, id, vsCurr, c.Config.IntradayDays); err != nil {
+					errs = append(errs, fmt.Errorf("csyncdb.CoingeckoPriceTicksToTargets failed for %s in %s: %w", id, vsCurr, err))
+				}
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: coingecko.Migrations, Dir: "migrations"}}, infoLog)
+}
@@ -0,0 +1,133 @@
+package companieshouseconnector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/companieshouseapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/companieshouse"
+	"github.com/loveyourstack/connectors/stores/companieshouse/chcompany"
+	"github.com/loveyourstack/connectors/stores/companieshouse/chofficer"
+)
+
+const Name string = "companieshouse"
+
+const defaultStreamSeconds int = 30
+
+func init() {
+	registry.Register(Connector{Config: Config{StreamSeconds: defaultStreamSeconds}})
+}
+
+// Config contains the settings of the [connectors.companieshouse] table
+type Config struct {
+	ApiKey        string   `toml:"apiKey"`        // REST API key. Required
+	StreamKey     string   `toml:"streamKey"`     // streaming API key. Optional: without it, all watched companies are requested on each sync
+	Companies     []string `toml:"companies"`     // watchlist of the company numbers to sync
+	StreamSeconds int      `toml:"streamSeconds"` // time spent reading the companies stream on each sync
+}
+
+// Connector syncs the profiles and officers of a watchlist of UK companies from Companies House
+// it is disabled until the API key is configured
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetCompaniesHouseCompanies,
+			Description: "Companies House company profiles: names, status, addresses and SIC codes",
+			SourceURL:   "https://developer.company-information.service.gov.uk/",
+			Cadence:     "continuous, through the streaming API",
+			License:     "Open Government Licence v3.0",
+			Dimensions:  []string{"company_number"},
+			Fields:      registry.StoreFields(chcompany.Store{}),
+		},
+		{
+			Name:        csyncdb.DatasetCompaniesHouseOfficers,
+			Description: "Companies House officers: directors and secretaries of the watched companies",
+			SourceURL:   "https://developer.company-information.service.gov.uk/",
+			Cadence:     "continuous, through the streaming API",
+			License:     "Open Government Licence v3.0",
+			Dimensions:  []string{"company_number", "officer_id", "officer_role", "appointed_on"},
+			Fields:      registry.StoreFields(chofficer.Store{}),
+		},
+	}
+}
+
+// Configure applies the [connectors.companieshouse] table
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := Config{StreamSeconds: defaultStreamSeconds}
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	for i, number := range conf.Companies {
+		conf.Companies[i] = strings.ToUpper(strings.TrimSpace(number))
+		if len(conf.Companies[i]) != 8 {
+			return nil, fmt.Errorf("invalid company number '%s': must have 8 characters, e.g. 00445790", number)
+		}
+	}
+	if conf.StreamSeconds < 1 {
+		return nil, fmt.Errorf("invalid streamSeconds %d: must be > 0", conf.StreamSeconds)
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+// Enabled returns true if the API key is configured
+func (c Connector) Enabled() bool {
+	return c.Config.ApiKey != ""
+}
+
+// Sync syncs the watched companies, then their officers. deps.Days is not used
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if c.Config.ApiKey == "" {
+		return fmt.Errorf("no API key configured: pls set apiKey in [connectors.%s]", Name)
+	}
+	if len(c.Config.Companies) == 0 {
+		return fmt.Errorf("no companies configured: pls set companies in [connectors.%s]", Name)
+	}
+
+	client := companieshouseapi.NewClient(c.Config.ApiKey, c.Config.StreamKey, deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+
+	var errs []error
+
+	if deps.Includes(csyncdb.DatasetCompaniesHouseCompanies) {
+		streamDuration := time.Duration(c.Config.StreamSeconds) * time.Second
+		if err := csyncdb.CompaniesHouseCompaniesToTargets(ctx, deps.Targets, client, c.Config.Companies, streamDuration); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.CompaniesHouseCompaniesToTargets failed: %w", err))
+		}
+	}
+
+	if deps.Includes(csyncdb.DatasetCompaniesHouseOfficers) {
+		for _, number := range c.Config.Companies {
+			if ctx.Err() != nil {
+				return errors.Join(append(errs, ctx.Err())...)
+			}
+			if err := csyncdb.CompaniesHouseOfficersToTargets(ctx, deps.Targets, client, number); err != nil {
+				errs = append(errs, fmt.Errorf("csyncdb.CompaniesHouseOfficersToTargets failed for %s: %w", number, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: companieshouse.Migrations, Dir: "migrations"}}, infoLog)
+}
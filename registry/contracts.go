@@ -0,0 +1,94 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/contract"
+	"github.com/loveyourstack/connectors/stores/connectors/contractlog"
+)
+
+// Contract returns the data contract of the rows of d
+func (d Dataset) Contract() contract.Contract {
+
+	c := contract.Contract{Dataset: d.Name, Version: d.Version, Fields: make([]contract.Field, len(d.Fields))}
+	if c.Version == "" {
+		c.Version = contract.DefaultVersion
+	}
+	for i, f := range d.Fields {
+		c.Fields[i] = contract.FieldOf(f.Name, f.Type)
+	}
+	return c
+}
+
+// Contracts returns the data contracts of the datasets of connectors which describe their fields
+func Contracts(connectors []Connector) (contracts []contract.Contract) {
+
+	for _, conn := range connectors {
+		for _, ds := range conn.Datasets() {
+			if len(ds.Fields) == 0 {
+				continue
+			}
+			contracts = append(contracts, ds.Contract())
+		}
+	}
+	return contracts
+}
+
+// ContractVersions returns the contract version of the datasets of connectors, keyed by dataset name
+func ContractVersions(connectors []Connector) map[string]string {
+
+	versions := make(map[string]string)
+	for _, c := range Contracts(connectors) {
+		versions[c.Dataset] = c.Version
+	}
+	return versions
+}
+
+// PublishContracts records the contracts of connectors in connectors.contract_version whose version or fields differ from the latest recorded version of their dataset, with the changes from it
+// a contract whose version is lower than its changes require is recorded as breaking and logged, rather than failing the migration
+func PublishContracts(ctx context.Context, db *pgxpool.Pool, connectors []Connector, infoLog *slog.Logger) (numPublished int, err error) {
+
+	logStore := contractlog.Store{Db: db}
+
+	latest, err := logStore.SelectLatest(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("logStore.SelectLatest failed: %w", err)
+	}
+
+	for _, cur := range Contracts(connectors) {
+		if err = cur.Validate(); err != nil {
+			return numPublished, fmt.Errorf("cur.Validate failed: %w", err)
+		}
+
+		input := contractlog.Input{Dataset: cur.Dataset, Version: cur.Version, Fields: cur.Fields}
+
+		if prev, ok := latest[cur.Dataset]; ok {
+			if prev.Version == cur.Version && slices.Equal(prev.Fields, cur.Fields) {
+				continue
+			}
+
+			changes, err := contract.Check(prev.Contract(), cur)
+			if err != nil {
+				infoLog.Warn("contract version does not match its changes", "dataset", cur.Dataset, "error", err.Error())
+			}
+			input.Changes = changes
+
+			// a new major version is breaking by definition, even without breaking changes
+			prevVersion, _ := contract.ParseVersion(prev.Version)
+			curVersion, _ := contract.ParseVersion(cur.Version)
+			input.Breaking = contract.IsBreaking(changes) || curVersion.Major > prevVersion.Major
+		}
+
+		if _, err = logStore.Insert(ctx, input); err != nil {
+			return numPublished, fmt.Errorf("logStore.Insert failed for %s: %w", cur.Dataset, err)
+		}
+		numPublished++
+		infoLog.Info("contract published", "dataset", cur.Dataset, "version", cur.Version, "breaking", input.Breaking)
+	}
+
+	return numPublished, nil
+}
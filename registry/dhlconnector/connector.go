@@ -0,0 +1,129 @@
+package dhlconnector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/dhlapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/shipment"
+	"github.com/loveyourstack/connectors/stores/shipment/trackedshipment"
+)
+
+const Name string = "dhl"
+
+const (
+	DefaultPollInterval string = "4h"
+	DefaultMaxAgeDays   int    = 60
+	DefaultMaxRequests  int    = 10 // with hourly syncs, within the 250 requests per day of DHL's default plan
+)
+
+func init() {
+	registry.Register(Connector{Config: defaultConfig()})
+}
+
+// Config contains the settings of the [connectors.dhl] table
+type Config struct {
+	ApiKey          string   `toml:"apiKey"`
+	Language        string   `toml:"language"`        // ISO 639-1 language of status texts. Defaults to English
+	TrackingNumbers []string `toml:"trackingNumbers"` // shipments registered on each sync. Others are registered by inserting them into shipment.shipment
+	PollInterval    string   `toml:"pollInterval"`    // Go duration between polls of a shipment. Defaults to DefaultPollInterval
+	MaxAgeDays      int      `toml:"maxAgeDays"`      // undelivered shipments registered longer ago are no longer polled. Defaults to DefaultMaxAgeDays
+	MaxRequests     int      `toml:"maxRequests"`     // tracking requests per sync. Defaults to DefaultMaxRequests
+}
+
+func defaultConfig() Config {
+	return Config{PollInterval: DefaultPollInterval, MaxAgeDays: DefaultMaxAgeDays, MaxRequests: DefaultMaxRequests}
+}
+
+// Connector polls the open DHL shipments of each target until they are delivered, using the DHL Unified Tracking API
+// it is disabled until the API key is configured
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetShipments,
+			Description: "Shipment tracking: status and tracking events of open DHL shipments, polled until delivered",
+			SourceURL:   "https://developer.dhl.com/api-reference/shipment-tracking",
+			Cadence:     "continuous, polled until delivered",
+			License:     "DHL API terms of use",
+			Dimensions:  []string{"carrier", "tracking_number"},
+			Fields:      registry.StoreFields(trackedshipment.Store{}),
+		},
+	}
+}
+
+// Configure applies the [connectors.dhl] table
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := defaultConfig()
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	for i, number := range conf.TrackingNumbers {
+		conf.TrackingNumbers[i] = strings.TrimSpace(number)
+		if conf.TrackingNumbers[i] == "" {
+			return nil, fmt.Errorf("empty tracking number")
+		}
+	}
+	if _, err := time.ParseDuration(conf.PollInterval); err != nil {
+		return nil, fmt.Errorf("invalid pollInterval '%s': %w", conf.PollInterval, err)
+	}
+	if conf.MaxAgeDays < 1 {
+		return nil, fmt.Errorf("invalid maxAgeDays %d: must be > 0", conf.MaxAgeDays)
+	}
+	if conf.MaxRequests < 1 {
+		return nil, fmt.Errorf("invalid maxRequests %d: must be > 0", conf.MaxRequests)
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+// Enabled returns true if the API key is configured
+func (c Connector) Enabled() bool {
+	return c.Config.ApiKey != ""
+}
+
+// Sync polls the open shipments. deps.Days is not used
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !deps.Includes(csyncdb.DatasetShipments) {
+		return nil
+	}
+	if c.Config.ApiKey == "" {
+		return fmt.Errorf("no API key configured: pls set apiKey in [connectors.%s]", Name)
+	}
+
+	pollInterval, err := time.ParseDuration(c.Config.PollInterval)
+	if err != nil {
+		return fmt.Errorf("time.ParseDuration failed on pollInterval: %w", err)
+	}
+	maxAge := time.Duration(c.Config.MaxAgeDays) * 24 * time.Hour
+
+	client := dhlapi.NewClient(c.Config.ApiKey, deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+	client.Language = c.Config.Language
+
+	if err := csyncdb.ShipmentsToTargets(ctx, deps.Targets, client, c.Config.TrackingNumbers, pollInterval, maxAge, c.Config.MaxRequests, client.InfoLog); err != nil {
+		return fmt.Errorf("csyncdb.ShipmentsToTargets failed: %w", err)
+	}
+
+	return nil
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: shipment.Migrations, Dir: "migrations"}}, infoLog)
+}
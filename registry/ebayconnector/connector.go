@@ -0,0 +1,102 @@
+package ebayconnector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/ebayapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/integrity"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/ebay"
+	"github.com/loveyourstack/connectors/stores/ebay/ebayitem"
+)
+
+const Name string = "ebay"
+
+func init() {
+	registry.Register(Connector{})
+}
+
+// Config contains the settings of the [connectors.ebay] table
+type Config struct {
+	ClientId     string `toml:"clientId"` // OAuth credentials of the app
+	ClientSecret string `toml:"clientSecret"`
+	RefreshToken string `toml:"refreshToken"` // of the seller's consent to the app: the Inventory API needs a user token
+	Sandbox      bool   `toml:"sandbox"`
+}
+
+// Connector syncs the inventory items and offers of an eBay seller with the Sell Inventory API
+// it is disabled until the credentials are configured
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetEbayInventory,
+			Description: "eBay inventory items and their offers: quantities, prices and listing status",
+			SourceURL:   "https://developer.ebay.com/api-docs/sell/inventory/overview.html",
+			Cadence:     "continuous",
+			License:     "data of the configured seller account",
+			Dimensions:  []string{"sku"},
+			Fields:      registry.StoreFields(ebayitem.Store{}),
+		},
+	}
+}
+
+// Configure applies the [connectors.ebay] table
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := Config{}
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+// Enabled returns true if the credentials are configured
+func (c Connector) Enabled() bool {
+	return c.Config.ClientId != "" && c.Config.ClientSecret != "" && c.Config.RefreshToken != ""
+}
+
+// Sync syncs all inventory items and their offers
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !deps.Includes(csyncdb.DatasetEbayInventory) {
+		return nil
+	}
+	if !c.Enabled() {
+		return fmt.Errorf("no credentials configured: pls set clientId, clientSecret and refreshToken in [connectors.%s]", Name)
+	}
+
+	client := ebayapi.NewClient(c.Config.ClientId, c.Config.ClientSecret, c.Config.RefreshToken, deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+	client.Sandbox = c.Config.Sandbox
+
+	if err := csyncdb.EbayInventoryToTargets(ctx, deps.Targets, client); err != nil {
+		return fmt.Errorf("csyncdb.EbayInventoryToTargets failed: %w", err)
+	}
+
+	return nil
+}
+
+// Relations returns the reference of the offers' currency to the ECB currencies, so that they can be converted with the stored rates. Offers without a price have none
+func (c Connector) Relations() []integrity.Relation {
+	return []integrity.Relation{
+		{Table: "ebay.offer", Column: "currency", RefTable: "ecb.currency", RefColumn: "code", AllowEmpty: true},
+	}
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: ebay.Migrations, Dir: "migrations"}}, infoLog)
+}
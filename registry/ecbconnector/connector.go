@@ -0,0 +1,198 @@
+package ecbconnector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/apiclients/frankfurterapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/integrity"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/ratesource"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/ecb"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbcurrency"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbpressrelease"
+)
+
+const (
+	Name string = "ecb"
+
+	// only the ECB's rates from EUR are stored: rates from other bases are derived from them when read, see csyncdb.ErrDerivedBase
+	baseCurrency string = "EUR"
+)
+
+// names of the sources that can be used as exchange rate fallbacks
+const (
+	FallbackFrankfurter string = "frankfurter"
+)
+
+func init() {
+	registry.Register(Connector{Freq: ecbapi.Daily})
+}
+
+// Config contains the settings of the [connectors.ecb] table
+type Config struct {
+	AutoWindow   bool `toml:"autoWindow"`   // size the exchange rates window from the rates stored in the targets, see csyncdb.EcbSyncWindow, rather than syncing the last deps.Days days
+	RevisionDays int  `toml:"revisionDays"` // with AutoWindow: TARGET business days before the latest stored day which are synced again. Defaults to csyncdb.DefaultRevisionDays
+}
+
+// Connector syncs ECB currencies, exchange rates and press releases
+type Connector struct {
+	Freq      ecbapi.Frequency
+	Fallbacks []string // sources tried in turn for exchange rates if the ECB is unavailable, e.g. FallbackFrankfurter
+	Config    Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetEcbCurrencies,
+			Description: "ECB currencies",
+			SourceURL:   "https://data.ecb.europa.eu/data/datasets/EXR",
+			Cadence:     "on change, as currencies are added to the reference rates",
+			License:     "ECB statistics reuse policy: free of charge, with the source acknowledged",
+			Dimensions:  []string{"code"},
+			Fields:      registry.StoreFields(ecbcurrency.Store{}),
+		},
+		{
+			Name:        csyncdb.DatasetEcbExchangeRates,
+			Description: "ECB euro foreign exchange reference rates",
+			SourceURL:   "https://www.ecb.europa.eu/stats/policy_and_exchange_rates/euro_reference_exchange_rates/html/index.en.html",
+			Cadence:     "daily on TARGET business days, around 16:00 CET",
+			License:     "ECB statistics reuse policy: free of charge, with the source acknowledged",
+			Dimensions:  []string{"frequency", "day", "from_currency", "to_currency"},
+			Fields:      registry.StoreFields(ecbexchangerate.Store{}),
+		},
+		{
+			Name:        csyncdb.DatasetEcbPressReleases,
+			Description: "ECB press releases, speeches and monetary policy decisions",
+			SourceURL:   "https://www.ecb.europa.eu/home/html/rss.en.html",
+			Cadence:     "several times a week",
+			License:     "ECB copyright: reproduction permitted with the source acknowledged",
+			Dimensions:  []string{"url"},
+			Fields:      registry.StoreFields(ecbpressrelease.Store{}),
+		},
+	}
+}
+
+// Configure applies the [connectors.ecb] table
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := Config{}
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	if conf.RevisionDays < 0 {
+		return nil, fmt.Errorf("revisionDays must not be negative")
+	}
+
+	c.Config = conf
+	return c, nil
+}
+
+// RatesWindow returns the date range of the exchange rates synced at now: the last days days, or with Config.AutoWindow the window sized from the rates
+// stored in targets, in which case days only applies to targets without rates
+func (c Connector) RatesWindow(ctx context.Context, targets []csyncdb.Target, days int, now time.Time) (startDate, endDate time.Time, err error) {
+
+	if days < 1 {
+		return time.Time{}, time.Time{}, fmt.Errorf("days must be at least 1")
+	}
+	if !c.Config.AutoWindow {
+		return now.AddDate(0, 0, -days), now, nil
+	}
+
+	revisionDays := c.Config.RevisionDays
+	if revisionDays == 0 {
+		revisionDays = csyncdb.DefaultRevisionDays
+	}
+	startDate, endDate, err = csyncdb.EcbSyncWindow(ctx, targets, c.Freq, revisionDays, days, now)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("csyncdb.EcbSyncWindow failed: %w", err)
+	}
+	return startDate, endDate, nil
+}
+
+// Sync syncs currencies, then the exchange rates of RatesWindow, then the press releases currently in the press feed
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	client := ecbapi.NewClient(deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+
+	fallbacks, err := NewFallbacks(c.Fallbacks, deps.InfoLog, deps.ErrorLog)
+	if err != nil {
+		return fmt.Errorf("NewFallbacks failed: %w", err)
+	}
+
+	var errs []error
+
+	if deps.Includes(csyncdb.DatasetEcbCurrencies) {
+		if err := csyncdb.EcbCurrenciesToTargets(ctx, deps.Targets, client); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.EcbCurrenciesToTargets failed: %w", err))
+		}
+	}
+
+	if deps.Includes(csyncdb.DatasetEcbExchangeRates) {
+		startDate, endDate, err := c.RatesWindow(ctx, deps.Targets, deps.Days, time.Now())
+		if err != nil {
+			return errors.Join(append(errs, fmt.Errorf("c.RatesWindow failed: %w", err))...)
+		}
+		if c.Config.AutoWindow {
+			deps.InfoLog.Info("sized exchange rates window", "start_date", startDate.Format(time.DateOnly), "end_date", endDate.Format(time.DateOnly))
+		}
+		if err := csyncdb.EcbExchangeRatesToTargets(ctx, deps.Targets, client, baseCurrency, c.Freq, startDate, endDate, deps.Rules.For(csyncdb.DatasetEcbExchangeRates),
+			deps.Derived.For(csyncdb.DatasetEcbExchangeRates), fallbacks...); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.EcbExchangeRatesToTargets failed: %w", err))
+		}
+	}
+
+	if deps.Includes(csyncdb.DatasetEcbPressReleases) {
+		if err := csyncdb.EcbPressReleasesToTargets(ctx, deps.Targets, client); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.EcbPressReleasesToTargets failed: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// RateSource returns the ECB client as a rate source
+func (c Connector) RateSource(infoLog, errorLog *slog.Logger) ratesource.Source {
+	return ecbapi.NewClient(infoLog, errorLog)
+}
+
+// NewFallbacks returns the exchange rate sources named by names, in the same order
+func NewFallbacks(names []string, infoLog, errorLog *slog.Logger) (fallbacks []csyncdb.ExchangeRateSource, err error) {
+
+	for _, name := range names {
+		switch name {
+		case FallbackFrankfurter:
+			fallbacks = append(fallbacks, frankfurterapi.NewClient(infoLog, errorLog))
+		default:
+			return nil, fmt.Errorf("unknown fallback source: %s", name)
+		}
+	}
+
+	return fallbacks, nil
+}
+
+// Relations returns the references of the exchange rates to their currencies. They are also enforced by foreign keys, so the check only fails if those were dropped
+func (c Connector) Relations() []integrity.Relation {
+	return []integrity.Relation{
+		{Table: "ecb.exchange_rate", Column: "from_currency_fk", RefTable: "ecb.currency", RefColumn: "id"},
+		{Table: "ecb.exchange_rate", Column: "to_currency_fk", RefTable: "ecb.currency", RefColumn: "id"},
+	}
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: ecb.Migrations, Dir: "migrations"}}, infoLog)
+}
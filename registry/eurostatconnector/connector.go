@@ -0,0 +1,115 @@
+package eurostatconnector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/eurostatapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/eurostat"
+	"github.com/loveyourstack/connectors/stores/eurostat/eurostatobservation"
+)
+
+const Name string = "eurostat"
+
+// DefaultQueries are synced if the config does not list any: HICP inflation, GDP growth and population of the euro area and its largest economies
+var DefaultQueries = []eurostatapi.Query{
+	{
+		// HICP all-items, annual rate of change
+		Dataset:     "prc_hicp_manr",
+		Filters:     map[string][]string{"coicop": {"CP00"}, "geo": {"EA", "DE", "FR", "IT", "ES"}},
+		LastPeriods: 24,
+	},
+	{
+		// GDP, chain-linked volumes, % change on previous quarter, seasonally and calendar adjusted
+		Dataset:     "namq_10_gdp",
+		Filters:     map[string][]string{"na_item": {"B1GQ"}, "unit": {"CLV_PCH_PRE"}, "s_adj": {"SCA"}, "geo": {"EA20", "DE", "FR", "IT", "ES"}},
+		LastPeriods: 8,
+	},
+	{
+		// population on 1 January
+		Dataset:     "demo_pjan",
+		Filters:     map[string][]string{"age": {"TOTAL"}, "sex": {"T"}, "geo": {"EU27_2020", "DE", "FR", "IT", "ES"}},
+		LastPeriods: 5,
+	},
+}
+
+func init() {
+	registry.Register(Connector{Config: Config{Queries: DefaultQueries}})
+}
+
+// Config contains the settings of the [connectors.eurostat] table
+type Config struct {
+	Queries []eurostatapi.Query `toml:"queries"` // queries to sync. Defaults to DefaultQueries
+}
+
+// Connector syncs observations of Eurostat datasets
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetEurostatObservations,
+			Description: "Eurostat observations, e.g. HICP, GDP and population",
+			SourceURL:   "https://ec.europa.eu/eurostat/web/user-guides/data-browser/api-data-access/api-introduction",
+			Cadence:     "per dataset, mostly monthly or quarterly",
+			License:     "Eurostat copyright notice: CC BY 4.0",
+			Dimensions:  []string{"dataset_code", "dimensions", "period"},
+			Fields:      registry.StoreFields(eurostatobservation.Store{}),
+		},
+	}
+}
+
+// Configure applies the [connectors.eurostat] table. Queries listed there replace DefaultQueries
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := Config{}
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	if len(conf.Queries) == 0 {
+		conf.Queries = DefaultQueries
+	}
+	for _, q := range conf.Queries {
+		if err := q.Validate(); err != nil {
+			return nil, fmt.Errorf("queries: %w", err)
+		}
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+// Sync syncs the observations of each configured query. The periods synced are set per query, so deps.Days is not used
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !deps.Includes(csyncdb.DatasetEurostatObservations) {
+		return nil
+	}
+
+	client := eurostatapi.NewClient(deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+
+	var errs []error
+	for _, q := range c.Config.Queries {
+		if err := csyncdb.EurostatObservationsToTargets(ctx, deps.Targets, client, q); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.EurostatObservationsToTargets failed for %s: %w", q.Dataset, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: eurostat.Migrations, Dir: "migrations"}}, infoLog)
+}
@@ -0,0 +1,109 @@
+package exhostconnector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/exhostapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/ratesource"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/exhost"
+	"github.com/loveyourstack/connectors/stores/exhost/exhostrate"
+)
+
+const (
+	Name          string = "exhost"
+	defaultSource string = "USD"
+)
+
+func init() {
+	registry.Register(Connector{Config: Config{Source: defaultSource}})
+}
+
+// Config contains the settings of the [connectors.exhost] table
+type Config struct {
+	AccessKey    string   `toml:"accessKey"`
+	Source       string   `toml:"source"`       // currency the rates are quoted from. Defaults to USD. Other sources need a paid plan
+	Currencies   []string `toml:"currencies"`   // currencies to quote. All available if empty
+	MonthlyQuota int64    `toml:"monthlyQuota"` // requests allowed per month by the plan. Syncs fail with exhostapi.ErrQuotaExceeded once used up. Unlimited if 0
+}
+
+// Connector syncs intraday rates from exchangerate.host, a commercial provider, for rates more frequent than the ECB's daily reference rates
+// each sync inserts the latest quotes, so the sync interval of the daemon sets how often rates are stored
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetExhostRates,
+			Description: "exchangerate.host intraday rates",
+			SourceURL:   "https://exchangerate.host/documentation",
+			Cadence:     "hourly or more often, depending on the plan",
+			License:     "exchangerate.host terms of service",
+			Dimensions:  []string{"from_currency", "to_currency", "quoted_at"},
+			Fields:      registry.StoreFields(exhostrate.Store{}),
+		},
+	}
+}
+
+// Enabled returns true if an access key is configured
+func (c Connector) Enabled() bool {
+	return c.Config.AccessKey != ""
+}
+
+// Configure applies the [connectors.exhost] table
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := Config{}
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	if conf.Source == "" {
+		conf.Source = defaultSource
+	}
+	if conf.MonthlyQuota < 0 {
+		return nil, fmt.Errorf("monthlyQuota must not be negative")
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+// Sync inserts the latest rates. deps.Days is not used
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !deps.Includes(csyncdb.DatasetExhostRates) {
+		return nil
+	}
+	if c.Config.AccessKey == "" {
+		return fmt.Errorf("accessKey is required: set it in [connectors.%s]", Name)
+	}
+
+	client := exhostapi.NewClient(c.Config.AccessKey, deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+
+	err := csyncdb.ExhostRatesToTargets(ctx, deps.Targets, client, c.Config.Source, c.Config.Currencies, c.Config.MonthlyQuota)
+	if err != nil {
+		return fmt.Errorf("csyncdb.ExhostRatesToTargets failed: %w", err)
+	}
+
+	return nil
+}
+
+// RateSource returns the exchangerate.host client as a rate source of live rates
+func (c Connector) RateSource(infoLog, errorLog *slog.Logger) ratesource.Source {
+	return exhostapi.NewClient(c.Config.AccessKey, infoLog, errorLog)
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: exhost.Migrations, Dir: "migrations"}}, infoLog)
+}
@@ -0,0 +1,108 @@
+package filedropconnector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/s3api"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/flatfile"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/filedrop"
+	"github.com/loveyourstack/connectors/stores/filedrop/fdrecord"
+)
+
+const Name string = "filedrop"
+
+func init() {
+	registry.Register(Connector{})
+}
+
+// Config contains the settings of the [connectors.filedrop] table
+type Config struct {
+	Endpoint        string          `toml:"endpoint"` // e.g. https://s3.eu-central-1.amazonaws.com, or https://storage.googleapis.com for GCS
+	Region          string          `toml:"region"`   // signing region. Defaults to us-east-1. Use auto for GCS
+	Bucket          string          `toml:"bucket"`
+	AccessKeyId     string          `toml:"accessKeyId"` // of an access key with read access to the bucket, or a GCS HMAC key
+	SecretAccessKey string          `toml:"secretAccessKey"`
+	Feeds           []flatfile.Feed `toml:"feeds"`
+}
+
+// Connector ingests the CSV and JSON files which partners drop in an S3-compatible bucket, keeping a manifest of the files read
+// it is disabled until the bucket, its credentials and a feed are configured
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetFiledropRecords,
+			Description: "Records of the files of each feed, read again when a file changes",
+			Cadence:     "as files are dropped or changed",
+			License:     "data of the dropped files",
+			Dimensions:  []string{"feed", "key", "line_no"},
+			Fields:      registry.StoreFields(fdrecord.Store{}),
+		},
+	}
+}
+
+// Configure applies the [connectors.filedrop] table
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := Config{}
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i, f := range conf.Feeds {
+		f = f.WithDefaults()
+		if err := f.Validate(); err != nil {
+			return nil, fmt.Errorf("feeds: %w", err)
+		}
+		if names[f.Name] {
+			return nil, fmt.Errorf("feeds: duplicate name: %s", f.Name)
+		}
+		names[f.Name] = true
+		conf.Feeds[i] = f
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+// Enabled returns true if the bucket, its credentials and a feed are configured
+func (c Connector) Enabled() bool {
+	return c.Config.Endpoint != "" && c.Config.Bucket != "" && c.Config.AccessKeyId != "" && c.Config.SecretAccessKey != "" && len(c.Config.Feeds) > 0
+}
+
+// Sync reads the new and changed files of the feeds. deps.Days is not used
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !c.Enabled() {
+		return fmt.Errorf("bucket or feeds not configured: pls set endpoint, bucket, accessKeyId, secretAccessKey and [[connectors.%s.feeds]]", Name)
+	}
+	if !deps.Includes(csyncdb.DatasetFiledropRecords) {
+		return nil
+	}
+
+	client := s3api.NewClient(c.Config.Endpoint, c.Config.Region, c.Config.Bucket, c.Config.AccessKeyId, c.Config.SecretAccessKey, deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+
+	if err := csyncdb.FiledropRecordsToTargets(ctx, deps.Targets, client, c.Config.Feeds); err != nil {
+		return fmt.Errorf("csyncdb.FiledropRecordsToTargets failed: %w", err)
+	}
+
+	return nil
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: filedrop.Migrations, Dir: "migrations"}}, infoLog)
+}
@@ -0,0 +1,154 @@
+package fredconnector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/fredapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/fred"
+	"github.com/loveyourstack/connectors/stores/fred/fredobservation"
+	"github.com/loveyourstack/connectors/stores/fred/fredseries"
+)
+
+const Name string = "fred"
+
+// DefaultSeries are synced if the config does not list any: H.10 USD exchange rates and treasury yields
+var DefaultSeries = []SeriesConfig{
+	{Id: "DEXUSEU"},  // U.S. dollars to one euro
+	{Id: "DEXUSUK"},  // U.S. dollars to one pound sterling
+	{Id: "DEXJPUS"},  // Japanese yen to one U.S. dollar
+	{Id: "DEXCAUS"},  // Canadian dollars to one U.S. dollar
+	{Id: "DEXSZUS"},  // Swiss francs to one U.S. dollar
+	{Id: "DEXCHUS"},  // Chinese yuan renminbi to one U.S. dollar
+	{Id: "DTWEXBGS"}, // nominal broad U.S. dollar index
+	{Id: "DGS3MO"},   // 3-month treasury constant maturity
+	{Id: "DGS2"},     // 2-year treasury constant maturity
+	{Id: "DGS10"},    // 10-year treasury constant maturity
+	{Id: "DGS30"},    // 30-year treasury constant maturity
+}
+
+func init() {
+	registry.Register(Connector{Config: Config{Series: DefaultSeries}})
+}
+
+// Config contains the settings of the [connectors.fred] table
+type Config struct {
+	ApiKey string         `toml:"apiKey"`
+	Series []SeriesConfig `toml:"series"` // series to sync. Defaults to DefaultSeries
+}
+
+// SeriesConfig is the sync configuration of a single series
+type SeriesConfig struct {
+	Id   string `toml:"id"`   // FRED series id, e.g. DEXUSEU
+	Days int    `toml:"days"` // number of days synced, counting back from today. Defaults to the sync's days. Monthly or quarterly series need more than the default
+}
+
+// Connector syncs the metadata and observations of FRED series
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetFredSeries,
+			Description: "FRED series metadata",
+			SourceURL:   "https://fred.stlouisfed.org/docs/api/fred/",
+			Cadence:     "on change",
+			License:     "FRED API terms of use. Some series are copyrighted by their sources",
+			Dimensions:  []string{"code"},
+			Fields:      registry.StoreFields(fredseries.Store{}),
+		},
+		{
+			Name:        csyncdb.DatasetFredObservations,
+			Description: "FRED series observations, e.g. H.10 exchange rates and treasury yields",
+			SourceURL:   "https://fred.stlouisfed.org/docs/api/fred/",
+			Cadence:     "per series, e.g. daily for the H.10 exchange rates",
+			License:     "FRED API terms of use. Some series are copyrighted by their sources",
+			Dimensions:  []string{"series", "day"},
+			Fields:      registry.StoreFields(fredobservation.Store{}),
+		},
+	}
+}
+
+// Enabled returns true if an API key is configured
+func (c Connector) Enabled() bool {
+	return c.Config.ApiKey != ""
+}
+
+// Configure applies the [connectors.fred] table. Series listed there replace DefaultSeries
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := Config{}
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	if len(conf.Series) == 0 {
+		conf.Series = DefaultSeries
+	}
+	for _, s := range conf.Series {
+		if s.Id == "" {
+			return nil, fmt.Errorf("series: id is required")
+		}
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+// Sync syncs the metadata of the configured series, then the observations of each
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if c.Config.ApiKey == "" {
+		return fmt.Errorf("apiKey is required: set it in [connectors.%s]", Name)
+	}
+
+	client := fredapi.NewClient(c.Config.ApiKey, deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+
+	var errs []error
+
+	if deps.Includes(csyncdb.DatasetFredSeries) {
+		ids := make([]string, len(c.Config.Series))
+		for i, s := range c.Config.Series {
+			ids[i] = s.Id
+		}
+		if err := csyncdb.FredSeriesToTargets(ctx, deps.Targets, client, ids); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.FredSeriesToTargets failed: %w", err))
+		}
+	}
+
+	if deps.Includes(csyncdb.DatasetFredObservations) {
+		endDate := time.Now()
+		for _, s := range c.Config.Series {
+			days := s.Days
+			if days < 1 {
+				days = deps.Days
+			}
+			if days < 1 {
+				errs = append(errs, fmt.Errorf("series %s: days must be at least 1", s.Id))
+				continue
+			}
+			startDate := endDate.AddDate(0, 0, -days)
+			if err := csyncdb.FredObservationsToTargets(ctx, deps.Targets, client, s.Id, startDate, endDate); err != nil {
+				errs = append(errs, fmt.Errorf("csyncdb.FredObservationsToTargets failed for %s: %w", s.Id, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: fred.Migrations, Dir: "migrations"}}, infoLog)
+}
@@ -0,0 +1,168 @@
+package fxreconconnector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/boeapi"
+	"github.com/loveyourstack/connectors/apiclients/frankfurterapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/ratesource"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/fxrecon"
+	"github.com/loveyourstack/connectors/stores/fxrecon/fxrrate"
+)
+
+const Name string = "fxrecon"
+
+// names of the rate sources which are not connectors. Other sources are the connectors implementing registry.RateProvider, e.g. ecb, wise or exhost
+const (
+	SourceBoe         string = "boe"
+	SourceFrankfurter string = "frankfurter"
+)
+
+const (
+	defaultPrimary      string  = "ecb"
+	defaultBase         string  = "EUR"
+	defaultTolerancePct float64 = 0.5 // central banks fix their rates at different times of day
+)
+
+var defaultQuotes = []string{"CHF", "GBP", "JPY", "USD"}
+
+func init() {
+	registry.Register(Connector{Config: defaultConfig()})
+}
+
+// Config contains the settings of the [connectors.fxrecon] table
+type Config struct {
+	Primary      string   `toml:"primary"`      // the source whose rates the others are compared with. Defaults to ecb
+	Sources      []string `toml:"sources"`      // the sources compared with the primary one, e.g. ["frankfurter", "boe", "wise"]
+	Base         string   `toml:"base"`         // defaults to EUR
+	Quotes       []string `toml:"quotes"`       // defaults to CHF, GBP, JPY and USD
+	TolerancePct float64  `toml:"tolerancePct"` // divergences above this percentage of the primary rate are recorded. Defaults to 0.5
+}
+
+func defaultConfig() Config {
+	return Config{Primary: defaultPrimary, Base: defaultBase, Quotes: defaultQuotes, TolerancePct: defaultTolerancePct}
+}
+
+// Connector stores the daily rates of several providers side by side and records where they diverge from the primary provider, e.g. to validate the ECB feed
+// it is disabled until sources to compare are configured
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetFxReconRates,
+			Description: "Daily exchange rates of the primary and compared sources, and their divergences beyond the tolerance",
+			Cadence:     "daily, from the rates of the reconciled sources",
+			License:     "terms of the reconciled sources",
+			Dimensions:  []string{"source", "base_currency", "quote_currency", "day"},
+			Fields:      registry.StoreFields(fxrrate.Store{}),
+		},
+	}
+}
+
+// Configure applies the [connectors.fxrecon] table
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := defaultConfig()
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	if conf.TolerancePct < 0 {
+		return nil, fmt.Errorf("tolerancePct must not be negative")
+	}
+	if len(conf.Quotes) == 0 {
+		return nil, fmt.Errorf("quotes must not be empty")
+	}
+	if slices.Contains(conf.Sources, conf.Primary) {
+		return nil, fmt.Errorf("sources must not contain the primary source: %s", conf.Primary)
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+// Enabled returns true if sources to compare are configured
+func (c Connector) Enabled() bool {
+	return len(c.Config.Sources) > 0
+}
+
+// Sync stores the rates of the last deps.Days days of each source and records their divergences
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !c.Enabled() {
+		return fmt.Errorf("no sources configured: pls set sources in [connectors.%s]", Name)
+	}
+	if deps.Days < 1 {
+		return fmt.Errorf("deps.Days must be at least 1")
+	}
+	if !deps.Includes(csyncdb.DatasetFxReconRates) {
+		return nil
+	}
+
+	sources := []ratesource.Source{}
+	for _, name := range append([]string{c.Config.Primary}, c.Config.Sources...) {
+		src, err := NewSource(name, deps.InfoLog, deps.ErrorLog)
+		if err != nil {
+			return fmt.Errorf("NewSource failed: %w", err)
+		}
+		sources = append(sources, src)
+	}
+
+	endDate := time.Now()
+	p := csyncdb.FxReconParams{
+		Primary:      c.Config.Primary,
+		Base:         c.Config.Base,
+		Quotes:       c.Config.Quotes,
+		TolerancePct: c.Config.TolerancePct,
+		StartDate:    endDate.AddDate(0, 0, -deps.Days),
+		EndDate:      endDate,
+	}
+
+	if err := csyncdb.FxReconRatesToTargets(ctx, deps.Targets, sources, p, deps.InfoLog, deps.ErrorLog); err != nil {
+		return fmt.Errorf("csyncdb.FxReconRatesToTargets failed: %w", err)
+	}
+
+	return nil
+}
+
+// NewSource returns the rate source with name: SourceBoe, SourceFrankfurter, or an enabled connector implementing registry.RateProvider
+func NewSource(name string, infoLog, errorLog *slog.Logger) (ratesource.Source, error) {
+
+	switch name {
+	case SourceBoe:
+		return boeapi.NewClient(infoLog, errorLog), nil
+	case SourceFrankfurter:
+		return frankfurterapi.NewClient(infoLog, errorLog), nil
+	}
+
+	conn, ok := registry.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown rate source: %s", name)
+	}
+	provider, ok := conn.(registry.RateProvider)
+	if !ok {
+		return nil, fmt.Errorf("connector %s is not a rate source", name)
+	}
+	if !registry.IsEnabled(conn) {
+		return nil, fmt.Errorf("connector %s is disabled: pls configure it to use it as a rate source", name)
+	}
+
+	return provider.RateSource(infoLog, errorLog), nil
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: fxrecon.Migrations, Dir: "migrations"}}, infoLog)
+}
@@ -0,0 +1,97 @@
+package gleifconnector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/gleifapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/gleif"
+	"github.com/loveyourstack/connectors/stores/gleif/gleifentity"
+)
+
+const Name string = "gleif"
+
+func init() {
+	registry.Register(Connector{})
+}
+
+// Config contains the settings of the [connectors.gleif] table
+type Config struct {
+	Leis []string `toml:"leis"` // watchlist of the Legal Entity Identifiers to sync, e.g. of counterparties
+}
+
+// Connector syncs Legal Entity Identifier records of a watchlist from GLEIF
+// it is disabled until the watchlist is configured: the full registry of over 2 million LEIs is not synced
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetGleifEntities,
+			Description: "GLEIF LEI records: legal names, addresses and registration status",
+			SourceURL:   "https://www.gleif.org/en/lei-data/gleif-golden-copy",
+			Cadence:     "three times a day, as golden copy delta files",
+			License:     "CC0 1.0",
+			Dimensions:  []string{"lei"},
+			Fields:      registry.StoreFields(gleifentity.Store{}),
+		},
+	}
+}
+
+// Configure applies the [connectors.gleif] table
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := Config{}
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	for i, lei := range conf.Leis {
+		conf.Leis[i] = strings.ToUpper(strings.TrimSpace(lei))
+		if len(conf.Leis[i]) != 20 {
+			return nil, fmt.Errorf("invalid LEI '%s': must have 20 characters", lei)
+		}
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+// Enabled returns true if the watchlist is configured
+func (c Connector) Enabled() bool {
+	return len(c.Config.Leis) > 0
+}
+
+// Sync syncs the watched entities. deps.Days is not used: the delta file is chosen from the time since the last sync
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !deps.Includes(csyncdb.DatasetGleifEntities) {
+		return nil
+	}
+	if len(c.Config.Leis) == 0 {
+		return fmt.Errorf("no LEIs configured: pls set leis in [connectors.%s]", Name)
+	}
+
+	client := gleifapi.NewClient(deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+
+	if err := csyncdb.GleifEntitiesToTargets(ctx, deps.Targets, client, c.Config.Leis); err != nil {
+		return fmt.Errorf("csyncdb.GleifEntitiesToTargets failed: %w", err)
+	}
+
+	return nil
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: gleif.Migrations, Dir: "migrations"}}, infoLog)
+}
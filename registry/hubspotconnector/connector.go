@@ -0,0 +1,152 @@
+package hubspotconnector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/hubspotapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/integrity"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/hubspot"
+	"github.com/loveyourstack/connectors/stores/hubspot/hubcompany"
+	"github.com/loveyourstack/connectors/stores/hubspot/hubcontact"
+	"github.com/loveyourstack/connectors/stores/hubspot/hubdeal"
+)
+
+const Name string = "hubspot"
+
+func init() {
+	registry.Register(Connector{})
+}
+
+// Config contains the settings of the [connectors.hubspot] table
+// the connector is authorized with the access token of a private app, or else with the OAuth credentials of an app and the refresh token of its install
+type Config struct {
+	AccessToken  string     `toml:"accessToken"` // of a private app with read scopes for companies, contacts and deals
+	ClientId     string     `toml:"clientId"`
+	ClientSecret string     `toml:"clientSecret"`
+	RefreshToken string     `toml:"refreshToken"`
+	Properties   Properties `toml:"properties"`
+}
+
+// Properties contains the property mapping of each object type: the HubSpot properties synced into the properties column, in addition to the standard ones
+type Properties struct {
+	Companies hubspotapi.PropertyMap `toml:"companies"`
+	Contacts  hubspotapi.PropertyMap `toml:"contacts"`
+	Deals     hubspotapi.PropertyMap `toml:"deals"`
+}
+
+// Connector syncs the companies, contacts and deals of a HubSpot account
+// it is disabled until an access token or the OAuth credentials are configured
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetHubspotCompanies,
+			Description: "HubSpot CRM companies and their mapped properties",
+			SourceURL:   "https://developers.hubspot.com/docs/api/crm/companies",
+			Cadence:     "continuous",
+			License:     "data of the configured HubSpot account",
+			Dimensions:  []string{"hubspot_id"},
+			Fields:      registry.StoreFields(hubcompany.Store{}),
+		},
+		{
+			Name:        csyncdb.DatasetHubspotContacts,
+			Description: "HubSpot CRM contacts and their mapped properties",
+			SourceURL:   "https://developers.hubspot.com/docs/api/crm/contacts",
+			Cadence:     "continuous",
+			License:     "data of the configured HubSpot account",
+			Dimensions:  []string{"hubspot_id"},
+			Fields:      registry.StoreFields(hubcontact.Store{}),
+		},
+		{
+			Name:        csyncdb.DatasetHubspotDeals,
+			Description: "HubSpot CRM deals with their company and mapped properties",
+			SourceURL:   "https://developers.hubspot.com/docs/api/crm/deals",
+			Cadence:     "continuous",
+			License:     "data of the configured HubSpot account",
+			Dimensions:  []string{"hubspot_id"},
+			Fields:      registry.StoreFields(hubdeal.Store{}),
+		},
+	}
+}
+
+// Configure applies the [connectors.hubspot] table
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := Config{}
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	for objectType, propMap := range map[string]hubspotapi.PropertyMap{"companies": conf.Properties.Companies, "contacts": conf.Properties.Contacts, "deals": conf.Properties.Deals} {
+		if err := propMap.Validate(); err != nil {
+			return nil, fmt.Errorf("properties.%s: %w", objectType, err)
+		}
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+// Enabled returns true if an access token or the OAuth credentials are configured
+func (c Connector) Enabled() bool {
+	return c.Config.AccessToken != "" || (c.Config.ClientId != "" && c.Config.ClientSecret != "" && c.Config.RefreshToken != "")
+}
+
+// Sync syncs all companies, contacts and deals. deps.Days is not used
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !c.Enabled() {
+		return fmt.Errorf("no credentials configured: pls set accessToken, or clientId, clientSecret and refreshToken in [connectors.%s]", Name)
+	}
+
+	client := hubspotapi.NewClient(c.Config.AccessToken, deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+	if c.Config.AccessToken == "" {
+		client = hubspotapi.NewOAuthClient(c.Config.ClientId, c.Config.ClientSecret, c.Config.RefreshToken, deps.InfoLog, deps.ErrorLog)
+	}
+
+	var errs []error
+
+	if deps.Includes(csyncdb.DatasetHubspotCompanies) {
+		if err := csyncdb.HubspotCompaniesToTargets(ctx, deps.Targets, client, c.Config.Properties.Companies); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.HubspotCompaniesToTargets failed: %w", err))
+		}
+	}
+
+	if deps.Includes(csyncdb.DatasetHubspotContacts) {
+		if err := csyncdb.HubspotContactsToTargets(ctx, deps.Targets, client, c.Config.Properties.Contacts); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.HubspotContactsToTargets failed: %w", err))
+		}
+	}
+
+	if deps.Includes(csyncdb.DatasetHubspotDeals) {
+		if err := csyncdb.HubspotDealsToTargets(ctx, deps.Targets, client, c.Config.Properties.Deals); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.HubspotDealsToTargets failed: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Relations returns the reference of the deals' currency to the ECB currencies, so that they can be converted with the stored rates. Accounts with a single currency have none
+func (c Connector) Relations() []integrity.Relation {
+	return []integrity.Relation{
+		{Table: "hubspot.deal", Column: "currency", RefTable: "ecb.currency", RefColumn: "code", AllowEmpty: true},
+	}
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: hubspot.Migrations, Dir: "migrations"}}, infoLog)
+}
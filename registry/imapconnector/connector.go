@@ -0,0 +1,104 @@
+package imapconnector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/imapapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/filedrop"
+	"github.com/loveyourstack/connectors/stores/filedrop/fdrecord"
+)
+
+const Name string = "imap"
+
+func init() {
+	registry.Register(Connector{})
+}
+
+// Config contains the settings of the [connectors.imap] table
+type Config struct {
+	Address  string         `toml:"address"` // host:port of the IMAPS server, e.g. imap.gmail.com:993
+	Username string         `toml:"username"`
+	Password string         `toml:"password"` // e.g. an app password
+	Mailbox  string         `toml:"mailbox"`  // defaults to INBOX
+	Rules    []imapapi.Rule `toml:"rules"`
+}
+
+// Connector ingests the CSV, JSON and XLSX attachments of the messages in a mailbox which match its rules into the file drop records, recording the message each file was loaded from
+// it is disabled until the mailbox credentials and a rule are configured
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetImapAttachments,
+			Description: "Records of the email attachments matching the rules, stored with the file drop records",
+			Cadence:     "as matching emails arrive",
+			License:     "data of the senders",
+			Dimensions:  []string{"feed", "key", "line_no"},
+			Fields:      registry.StoreFields(fdrecord.Store{}),
+		},
+	}
+}
+
+// Configure applies the [connectors.imap] table
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := Config{}
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+
+	for i, r := range conf.Rules {
+		r = r.WithDefaults()
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("rules: %w", err)
+		}
+		conf.Rules[i] = r
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+// Enabled returns true if the mailbox credentials and a rule are configured
+func (c Connector) Enabled() bool {
+	return c.Config.Address != "" && c.Config.Username != "" && c.Config.Password != "" && len(c.Config.Rules) > 0
+}
+
+// Sync ingests the matching attachments of the messages received in the last deps.Days days which are not yet loaded
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !c.Enabled() {
+		return fmt.Errorf("mailbox or rules not configured: pls set address, username, password and [[connectors.%s.rules]]", Name)
+	}
+	if deps.Days < 1 {
+		return fmt.Errorf("deps.Days must be at least 1")
+	}
+	if !deps.Includes(csyncdb.DatasetImapAttachments) {
+		return nil
+	}
+
+	client := imapapi.NewClient(c.Config.Address, c.Config.Username, c.Config.Password, c.Config.Mailbox, deps.InfoLog, deps.ErrorLog)
+
+	if err := csyncdb.ImapAttachmentsToTargets(ctx, deps.Targets, client, c.Config.Rules, deps.Days); err != nil {
+		return fmt.Errorf("csyncdb.ImapAttachmentsToTargets failed: %w", err)
+	}
+
+	return nil
+}
+
+// Migrate applies the migrations of the filedrop schema, which the attachments are stored in. They are shared with the filedrop connector and applied once
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: "filedrop", FS: filedrop.Migrations, Dir: "migrations"}}, infoLog)
+}
@@ -0,0 +1,74 @@
+package imfconnector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/imfapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/imf"
+	"github.com/loveyourstack/connectors/stores/imf/imfexchangerate"
+)
+
+const Name string = "imf"
+
+func init() {
+	registry.Register(Connector{})
+}
+
+// Connector syncs the IMF's daily SDR and representative exchange rates
+type Connector struct{}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetImfExchangeRates,
+			Description: "IMF SDRs per currency unit and representative rates per U.S. dollar",
+			SourceURL:   "https://www.imf.org/external/np/fin/data/param_rms_mth.aspx",
+			Cadence:     "daily on IMF business days",
+			License:     "IMF copyright and usage terms",
+			Dimensions:  []string{"rate_type", "currency_code", "day"},
+			Fields:      registry.StoreFields(imfexchangerate.Store{}),
+		},
+	}
+}
+
+// Sync syncs the SDR rates, then the representative rates, of the last deps.Days days
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !deps.Includes(csyncdb.DatasetImfExchangeRates) {
+		return nil
+	}
+	if deps.Days < 1 {
+		return fmt.Errorf("deps.Days must be at least 1")
+	}
+
+	client := imfapi.NewClient(deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+
+	endDate := time.Now()
+	startDate := endDate.AddDate(0, 0, -deps.Days)
+
+	var errs []error
+	for _, rateType := range []imfapi.RateType{imfapi.SdrPerCurrency, imfapi.Representative} {
+		if err := csyncdb.ImfExchangeRatesToTargets(ctx, deps.Targets, client, rateType, startDate, endDate); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.ImfExchangeRatesToTargets failed for %s: %w", rateType, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: imf.Migrations, Dir: "migrations"}}, infoLog)
+}
@@ -0,0 +1,118 @@
+package inboundconnector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/inbound"
+	"github.com/loveyourstack/connectors/stores/inbound/inboundevent"
+	"github.com/loveyourstack/connectors/webhookin"
+)
+
+const Name string = "inbound"
+
+// DefaultRetentionDays is the number of days processed deliveries are kept in staging
+const DefaultRetentionDays int = 30
+
+func init() {
+	registry.Register(Connector{})
+}
+
+// Config contains the settings of the [connectors.inbound] table
+type Config struct {
+	Providers     []webhookin.Provider `toml:"providers"`
+	RetentionDays int                  `toml:"retentionDays"` // defaults to DefaultRetentionDays
+}
+
+// Connector receives signed webhook deliveries at the daemon's /hooks/<provider> routes, stages them and normalizes them into events on each sync
+// it is disabled until a provider is configured
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetInboundEvents,
+			Description: "Events of webhook deliveries received by the daemon, normalized from staging",
+			Cadence:     "as deliveries are received",
+			License:     "data of the sending providers",
+			Dimensions:  []string{"provider", "event_id"},
+			Fields:      registry.StoreFields(inboundevent.Store{}),
+		},
+	}
+}
+
+// Configure applies the [connectors.inbound] table
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := Config{}
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	if conf.RetentionDays < 1 {
+		conf.RetentionDays = DefaultRetentionDays
+	}
+
+	names := make(map[string]bool)
+	for i, p := range conf.Providers {
+		p = p.WithDefaults()
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("providers: %w", err)
+		}
+		if names[p.Name] {
+			return nil, fmt.Errorf("providers: duplicate name: %s", p.Name)
+		}
+		names[p.Name] = true
+		conf.Providers[i] = p
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+// Enabled returns true if a provider is configured
+func (c Connector) Enabled() bool {
+	return len(c.Config.Providers) > 0
+}
+
+// AddRoutes adds the webhook route of the providers to mux, staging deliveries in each target
+func (c Connector) AddRoutes(mux *http.ServeMux, targets []csyncdb.Target, infoLog, errorLog *slog.Logger) {
+
+	stage := func(ctx context.Context, d webhookin.Delivery) error {
+		return csyncdb.StageInboundDelivery(ctx, targets, d)
+	}
+	mux.Handle(webhookin.RoutePattern, webhookin.Handler(c.Config.Providers, stage, infoLog, errorLog))
+}
+
+// Sync normalizes the staged deliveries into events. deps.Days is not used
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !c.Enabled() {
+		return fmt.Errorf("no providers configured: pls add [[connectors.%s.providers]]", Name)
+	}
+	if !deps.Includes(csyncdb.DatasetInboundEvents) {
+		return nil
+	}
+
+	retention := time.Duration(c.Config.RetentionDays) * 24 * time.Hour
+	if err := csyncdb.InboundEventsToTargets(ctx, deps.Targets, c.Config.Providers, retention, deps.InfoLog); err != nil {
+		return fmt.Errorf("csyncdb.InboundEventsToTargets failed: %w", err)
+	}
+
+	return nil
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: inbound.Migrations, Dir: "migrations"}}, infoLog)
+}
@@ -0,0 +1,63 @@
+package iso4217connector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/iso4217api"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/iso4217"
+	"github.com/loveyourstack/connectors/stores/iso4217/isocurrency"
+)
+
+const Name string = "iso4217"
+
+func init() {
+	registry.Register(Connector{})
+}
+
+// Connector syncs the ISO 4217 currency codes and their minor units, as published by SIX, the maintenance agency of the standard
+type Connector struct{}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetIso4217Currencies,
+			Description: "ISO 4217 currencies: codes, minor units and the countries using them",
+			SourceURL:   "https://www.six-group.com/en/products-services/financial-information/data-standards.html",
+			Cadence:     "a few times a year, as amendments are published",
+			License:     "ISO 4217 code list, published free of charge by SIX as maintenance agency",
+			Dimensions:  []string{"code"},
+			Fields:      registry.StoreFields(isocurrency.Store{}),
+		},
+	}
+}
+
+// Sync syncs all currencies of the current list. deps.Days is not used
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !deps.Includes(csyncdb.DatasetIso4217Currencies) {
+		return nil
+	}
+
+	client := iso4217api.NewClient(deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+
+	if err := csyncdb.Iso4217CurrenciesToTargets(ctx, deps.Targets, client); err != nil {
+		return fmt.Errorf("csyncdb.Iso4217CurrenciesToTargets failed: %w", err)
+	}
+
+	return nil
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: iso4217.Migrations, Dir: "migrations"}}, infoLog)
+}
@@ -0,0 +1,119 @@
+package lexofficeconnector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/lexofficeapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/integrity"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/lexoffice"
+	"github.com/loveyourstack/connectors/stores/lexoffice/lexcontact"
+	"github.com/loveyourstack/connectors/stores/lexoffice/lexvoucher"
+)
+
+const Name string = "lexoffice"
+
+func init() {
+	registry.Register(Connector{})
+}
+
+// Config contains the settings of the [connectors.lexoffice] table
+type Config struct {
+	ApiKey string `toml:"apiKey"` // created in the lexoffice settings under "Public API"
+}
+
+// Connector syncs the contacts, vouchers and invoice lines of a lexoffice account
+// it is disabled until the API key is configured
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetLexofficeContacts,
+			Description: "lexoffice customers and vendors",
+			SourceURL:   "https://developers.lexoffice.io/docs/",
+			Cadence:     "continuous",
+			License:     "data of the configured lexoffice organization",
+			Dimensions:  []string{"lexoffice_id"},
+			Fields:      registry.StoreFields(lexcontact.Store{}),
+		},
+		{
+			Name:        csyncdb.DatasetLexofficeVouchers,
+			Description: "lexoffice vouchers of all types and the lines of invoices, synced incrementally by update date",
+			SourceURL:   "https://developers.lexoffice.io/docs/",
+			Cadence:     "continuous",
+			License:     "data of the configured lexoffice organization",
+			Dimensions:  []string{"lexoffice_id"},
+			Fields:      registry.StoreFields(lexvoucher.Store{}),
+		},
+	}
+}
+
+// Configure applies the [connectors.lexoffice] table
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := Config{}
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+// Enabled returns true if the API key is configured
+func (c Connector) Enabled() bool {
+	return c.Config.ApiKey != ""
+}
+
+// Sync syncs all contacts, and the vouchers updated since the last sync, or in the last deps.Days days on the first sync
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !c.Enabled() {
+		return fmt.Errorf("no API key configured: pls set apiKey in [connectors.%s]", Name)
+	}
+	if deps.Days < 1 {
+		return fmt.Errorf("deps.Days must be at least 1")
+	}
+
+	client := lexofficeapi.NewClient(c.Config.ApiKey, deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+
+	var errs []error
+
+	if deps.Includes(csyncdb.DatasetLexofficeContacts) {
+		if err := csyncdb.LexofficeContactsToTargets(ctx, deps.Targets, client); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.LexofficeContactsToTargets failed: %w", err))
+		}
+	}
+
+	if deps.Includes(csyncdb.DatasetLexofficeVouchers) {
+		if err := csyncdb.LexofficeVouchersToTargets(ctx, deps.Targets, client, deps.Days); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.LexofficeVouchersToTargets failed: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Relations returns the reference of the vouchers' currency to the ECB currencies, so that they can be converted with the stored rates
+func (c Connector) Relations() []integrity.Relation {
+	return []integrity.Relation{
+		{Table: "lexoffice.voucher", Column: "currency", RefTable: "ecb.currency", RefColumn: "code"},
+	}
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: lexoffice.Migrations, Dir: "migrations"}}, infoLog)
+}
@@ -0,0 +1,117 @@
+package nagerconnector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/nagerapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/holiday"
+	"github.com/loveyourstack/connectors/stores/holiday/publicholiday"
+)
+
+const Name string = "nager"
+
+var (
+	// DefaultCountries are synced if the config does not list any
+	DefaultCountries = []string{"DE", "FR", "GB", "US"}
+
+	// DefaultYearsAhead is used if the config does not set yearsAhead
+	DefaultYearsAhead = 1
+)
+
+func init() {
+	registry.Register(Connector{Config: Config{Countries: DefaultCountries, YearsAhead: DefaultYearsAhead}})
+}
+
+// Config contains the settings of the [connectors.nager] table
+type Config struct {
+	Countries  []string `toml:"countries"`  // ISO 3166-1 alpha-2 codes. Defaults to DefaultCountries
+	YearsAhead int      `toml:"yearsAhead"` // number of years after the current one to sync. Defaults to DefaultYearsAhead
+}
+
+// Connector syncs public holidays per country and year from Nager.Date
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetPublicHolidays,
+			Description: "Nager.Date public holidays per country",
+			SourceURL:   "https://date.nager.at/Api",
+			Cadence:     "yearly, with occasional corrections",
+			License:     "MIT",
+			Dimensions:  []string{"country_code", "day", "name"},
+			Fields:      registry.StoreFields(publicholiday.Store{}),
+		},
+	}
+}
+
+// Configure applies the [connectors.nager] table
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := Config{YearsAhead: -1}
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	if len(conf.Countries) == 0 {
+		conf.Countries = DefaultCountries
+	}
+	for i, country := range conf.Countries {
+		conf.Countries[i] = strings.ToUpper(country)
+		if len(conf.Countries[i]) != 2 {
+			return nil, fmt.Errorf("invalid country '%s': must be an ISO 3166-1 alpha-2 code", country)
+		}
+	}
+	if conf.YearsAhead < 0 {
+		conf.YearsAhead = DefaultYearsAhead
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+// Sync syncs the holidays of each configured country, from the year deps.Days ago until Config.YearsAhead years after the current one
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !deps.Includes(csyncdb.DatasetPublicHolidays) {
+		return nil
+	}
+
+	client := nagerapi.NewClient(deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+
+	now := time.Now()
+	startYear := now.AddDate(0, 0, -deps.Days).Year()
+	endYear := now.Year() + c.Config.YearsAhead
+
+	var errs []error
+	for _, country := range c.Config.Countries {
+		for year := startYear; year <= endYear; year++ {
+			if ctx.Err() != nil {
+				return errors.Join(append(errs, ctx.Err())...)
+			}
+			if err := csyncdb.PublicHolidaysToTargets(ctx, deps.Targets, client, year, country); err != nil {
+				errs = append(errs, fmt.Errorf("csyncdb.PublicHolidaysToTargets failed for %s in %d: %w", country, year, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: holiday.Migrations, Dir: "migrations"}}, infoLog)
+}
@@ -0,0 +1,106 @@
+package outboundconnector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/ratepush"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/outbound"
+)
+
+const Name string = "outbound"
+
+func init() {
+	registry.Register(Connector{})
+}
+
+// Config contains the settings of the [connectors.outbound] table
+type Config struct {
+	Destinations []ratepush.Destination `toml:"destinations"`
+}
+
+// Connector pushes the daily ECB rates which were inserted or updated since its last push to downstream systems, tracking the delivery state of each destination
+// connectors sync in name order, so the daemon and CLI push the rates after the ecb connector synced them. It is disabled until a destination is configured
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetOutboundRates,
+			Description: "Pushes of newly synced ECB daily rates to the configured destinations",
+			Cadence:     "after each ECB rates sync",
+			License:     "ECB statistics reuse policy: free of charge, with the source acknowledged",
+			Dimensions:  []string{"destination"},
+		},
+	}
+}
+
+// Configure applies the [connectors.outbound] table
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := Config{}
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i, d := range conf.Destinations {
+		d = d.WithDefaults()
+		if err := d.Validate(); err != nil {
+			return nil, fmt.Errorf("destinations: %w", err)
+		}
+		if names[d.Name] {
+			return nil, fmt.Errorf("destinations: duplicate name: %s", d.Name)
+		}
+		names[d.Name] = true
+		conf.Destinations[i] = d
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+// Enabled returns true if a destination is configured
+func (c Connector) Enabled() bool {
+	return len(c.Config.Destinations) > 0
+}
+
+// Sync pushes the rates changed since the last push to each destination. deps.Days sets how far back rates are pushed to a new destination
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !c.Enabled() {
+		return fmt.Errorf("no destinations configured: pls add [[connectors.%s.destinations]]", Name)
+	}
+	if !deps.Includes(csyncdb.DatasetOutboundRates) {
+		return nil
+	}
+
+	dests := make([]csyncdb.OutboundDestination, len(c.Config.Destinations))
+	for i, d := range c.Config.Destinations {
+		pusher, err := ratepush.NewPusher(d, deps.InfoLog, deps.ErrorLog)
+		if err != nil {
+			return fmt.Errorf("ratepush.NewPusher failed for destination %s: %w", d.Name, err)
+		}
+		dests[i] = csyncdb.OutboundDestination{Destination: d, Pusher: pusher}
+	}
+
+	if err := csyncdb.OutboundRatesToTargets(ctx, deps.Targets, dests, deps.Days, deps.InfoLog, deps.ErrorLog); err != nil {
+		return fmt.Errorf("csyncdb.OutboundRatesToTargets failed: %w", err)
+	}
+
+	return nil
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: outbound.Migrations, Dir: "migrations"}}, infoLog)
+}
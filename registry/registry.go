@@ -0,0 +1,268 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/archive"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/derive"
+	"github.com/loveyourstack/connectors/drift"
+	"github.com/loveyourstack/connectors/integrity"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/quality"
+	"github.com/loveyourstack/connectors/quota"
+	"github.com/loveyourstack/connectors/ratesource"
+	"github.com/loveyourstack/connectors/sink"
+	"github.com/loveyourstack/connectors/stores/connectors"
+	"github.com/loveyourstack/lys/lysmeta"
+)
+
+// Dataset describes a dataset synced by a Connector, e.g. for catalog tools documenting what an installation syncs
+type Dataset struct {
+	Name        string   `json:"name"` // journal name, e.g. "ecb.exchange_rate". Must be unique across connectors
+	Description string   `json:"description"`
+	SourceURL   string   `json:"source_url,omitempty"` // documentation of the source's data or API
+	Cadence     string   `json:"cadence,omitempty"`    // how often the source has new data, e.g. "daily on TARGET business days, around 16:00 CET"
+	License     string   `json:"license,omitempty"`    // terms of use of the data
+	Dimensions  []string `json:"dimensions,omitempty"` // fields identifying a row, e.g. day, from_currency and to_currency
+	Fields      []Field  `json:"fields,omitempty"`     // fields of the stored rows, see StoreFields
+	Version     string   `json:"version,omitempty"`    // semantic version of the contract of Fields, see Contract. contract.DefaultVersion if empty
+}
+
+// Field is a field of the rows of a dataset
+type Field struct {
+	Name string `json:"name"` // JSON name, as returned by the stores' Select funcs
+	Type string `json:"type"` // Go type, e.g. "float32" or "lystype.Date"
+}
+
+// StoreFields returns the fields of the rows of store, from its GetMeta, in the order of its JSON tags
+func StoreFields(store interface{ GetMeta() lysmeta.Result }) []Field {
+
+	meta := store.GetMeta()
+	fields := make([]Field, len(meta.JsonTags))
+	for i, name := range meta.JsonTags {
+		fields[i] = Field{Name: name, Type: meta.JsonTagTypeMap[name]}
+	}
+	return fields
+}
+
+// Deps contains the dependencies passed to Connector.Sync
+type Deps struct {
+	Targets  []csyncdb.Target  // databases to sync into, each with its own journal entry
+	Datasets []string          // names of the datasets to sync. All datasets if empty
+	Days     int               // number of days of time series data to sync, counting back from today
+	Sinks    sink.Router       // sinks of tick datasets. Ticks of datasets without a route are written to the targets
+	Rules    quality.Rules     // data-quality rules checked by the syncs of the datasets which support them, e.g. ecb.exchange_rate
+	Derived  derive.Fields     // derived fields computed by the syncs of the datasets which support them, e.g. ecb.exchange_rate
+	Quota    *quota.Meter      // counts the requests of the connector's API client if its provider has a limit. Nil otherwise
+	Drift    *drift.Guard      // checks the responses of the connector's API client for added or removed fields if enabled. Nil otherwise
+	Archive  *archive.Recorder // archives the raw responses of the connector's API client if enabled for the connector. Nil otherwise
+	Replay   *archive.Replay   // if set, serves the requests of the connector's API client from the archived payloads of a run, in place of the provider
+	InfoLog  *slog.Logger
+	ErrorLog *slog.Logger
+}
+
+// Includes returns true if dataset should be synced according to d.Datasets
+func (d Deps) Includes(dataset string) bool {
+
+	if len(d.Datasets) == 0 {
+		return true
+	}
+	for _, ds := range d.Datasets {
+		if ds == dataset {
+			return true
+		}
+	}
+	return false
+}
+
+// HttpClient returns hc with its requests counted by d.Quota, and its responses archived by d.Archive and checked by d.Drift, as far as set
+// with d.Replay set, the requests are served by it instead, and neither counted, archived nor checked
+// connectors apply it to the HTTP client of their API client: client.HttpClient = deps.HttpClient(client.HttpClient)
+func (d Deps) HttpClient(hc *http.Client) *http.Client {
+
+	if d.Replay != nil {
+		return d.Replay.Client(hc)
+	}
+	return d.Drift.Client(d.Quota.Client(d.Archive.Client(hc)))
+}
+
+// Connector is a source of public API data which can be synced into the database
+// connectors register themselves in an init func, so that importing their package is enough for the CLI and daemon to discover them
+type Connector interface {
+	// Name is the unique connector name, e.g. "ecb"
+	Name() string
+
+	// Datasets returns the datasets synced by the connector, in the order they must be synced
+	Datasets() []Dataset
+
+	// Sync fetches the selected datasets and syncs them into deps.Targets, recording a journal entry per dataset in each target
+	// a failing target should not stop the others: the returned error should join a csyncdb.TargetError per failed target
+	Sync(ctx context.Context, deps Deps) error
+
+	// Migrate applies the pending migrations of the connector's schema to db
+	Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error)
+}
+
+// Configurable is implemented by connectors which need settings, such as an API key, from the [connectors.<name>] table of the config file
+type Configurable interface {
+	Connector
+
+	// Configure returns a copy of the connector with the settings decoded by decode applied. decode decodes the connector's table into v
+	Configure(decode func(v any) error) (Connector, error)
+}
+
+// Enabler is implemented by connectors which can be disabled, e.g. while a required API key is not configured
+// disabled connectors are still migrated, but are skipped by the daemon and by syncs of all connectors
+type Enabler interface {
+	Enabled() bool
+}
+
+// Receiver is implemented by connectors which receive data pushed to the daemon, e.g. webhooks, rather than only fetching it
+// their routes are served without the daemon's API credentials, so the handlers must authenticate requests themselves, e.g. by signature
+type Receiver interface {
+	// AddRoutes adds the connector's routes to mux. Received data is persisted to targets
+	AddRoutes(mux *http.ServeMux, targets []csyncdb.Target, infoLog, errorLog *slog.Logger)
+}
+
+// RateProvider is implemented by connectors whose API provides daily exchange rates, so that they can be reconciled with other sources
+type RateProvider interface {
+	// RateSource returns the connector's API client as a rate source, using its configured credentials
+	RateSource(infoLog, errorLog *slog.Logger) ratesource.Source
+}
+
+// Relater is implemented by connectors whose tables reference tables which the database doesn't tie them to, usually of other connectors' schemas,
+// e.g. by currency code
+type Relater interface {
+	// Relations returns the references of the connector's tables, checked by integrity.Check
+	Relations() []integrity.Relation
+}
+
+// IsEnabled returns false if c implements Enabler and is disabled
+func IsEnabled(c Connector) bool {
+	e, ok := c.(Enabler)
+	return !ok || e.Enabled()
+}
+
+// Relations returns the relations of the connectors which implement Relater, in the order of connectors
+func Relations(connectors []Connector) (rels []integrity.Relation) {
+
+	for _, c := range connectors {
+		if r, ok := c.(Relater); ok {
+			rels = append(rels, r.Relations()...)
+		}
+	}
+	return rels
+}
+
+var (
+	mu         sync.RWMutex
+	registered = make(map[string]Connector)
+)
+
+// Register makes c available under c.Name(). It panics if the name is empty or already registered
+func Register(c Connector) {
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	name := c.Name()
+	if name == "" {
+		panic("registry: Register called with empty connector name")
+	}
+	if _, exists := registered[name]; exists {
+		panic("registry: Register called twice for connector " + name)
+	}
+	registered[name] = c
+}
+
+// Get returns the connector registered under name
+func Get(name string) (c Connector, ok bool) {
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	c, ok = registered[name]
+	return c, ok
+}
+
+// All returns all registered connectors, sorted by name
+func All() []Connector {
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	conns := make([]Connector, 0, len(registered))
+	for _, c := range registered {
+		conns = append(conns, c)
+	}
+	sort.Slice(conns, func(i, j int) bool { return conns[i].Name() < conns[j].Name() })
+
+	return conns
+}
+
+// ConfigureAll replaces each registered Configurable connector with the result of its Configure
+// decoder returns the decode func of a connector's config table, or nil if there is none, in which case the connector keeps its defaults
+func ConfigureAll(decoder func(name string) func(v any) error) error {
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for name, c := range registered {
+		cc, ok := c.(Configurable)
+		if !ok {
+			continue
+		}
+
+		decode := decoder(name)
+		if decode == nil {
+			decode = func(v any) error { return nil }
+		}
+
+		configured, err := cc.Configure(decode)
+		if err != nil {
+			return fmt.Errorf("Configure failed for connector %s: %w", name, err)
+		}
+		registered[name] = configured
+	}
+
+	return nil
+}
+
+// FindDataset returns the connector which syncs dataset
+func FindDataset(dataset string) (c Connector, ok bool) {
+
+	for _, c := range All() {
+		for _, ds := range c.Datasets() {
+			if ds.Name == dataset {
+				return c, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// MigrateAll applies the framework migrations, then the migrations of each registered connector, to db
+func MigrateAll(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+
+	numApplied, err = migrate.Run(ctx, db, []migrate.Source{{Name: "connectors", FS: connectors.Migrations, Dir: "migrations"}}, infoLog)
+	if err != nil {
+		return numApplied, fmt.Errorf("migrate.Run failed for framework schema: %w", err)
+	}
+
+	for _, c := range All() {
+		n, err := c.Migrate(ctx, db, infoLog)
+		numApplied += n
+		if err != nil {
+			return numApplied, fmt.Errorf("c.Migrate failed for connector %s: %w", c.Name(), err)
+		}
+	}
+
+	return numApplied, nil
+}
@@ -0,0 +1,63 @@
+package restcountriesconnector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/restcountriesapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/country"
+	"github.com/loveyourstack/connectors/stores/country/isocountry"
+)
+
+const Name string = "restcountries"
+
+func init() {
+	registry.Register(Connector{})
+}
+
+// Connector syncs ISO 3166 country reference data from REST Countries
+type Connector struct{}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetCountries,
+			Description: "Countries: ISO codes, currencies used and EU membership",
+			SourceURL:   "https://restcountries.com",
+			Cadence:     "rarely",
+			License:     "MPL 2.0",
+			Dimensions:  []string{"iso2"},
+			Fields:      registry.StoreFields(isocountry.Store{}),
+		},
+	}
+}
+
+// Sync syncs all countries. deps.Days is not used
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !deps.Includes(csyncdb.DatasetCountries) {
+		return nil
+	}
+
+	client := restcountriesapi.NewClient(deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+
+	if err := csyncdb.CountriesToTargets(ctx, deps.Targets, client); err != nil {
+		return fmt.Errorf("csyncdb.CountriesToTargets failed: %w", err)
+	}
+
+	return nil
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: country.Migrations, Dir: "migrations"}}, infoLog)
+}
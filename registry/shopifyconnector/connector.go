@@ -0,0 +1,131 @@
+package shopifyconnector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/shopifyapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/integrity"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/shopify"
+	"github.com/loveyourstack/connectors/stores/shopify/shoporder"
+	"github.com/loveyourstack/connectors/stores/shopify/shopproduct"
+)
+
+const Name string = "shopify"
+
+func init() {
+	registry.Register(Connector{})
+}
+
+// Config contains the settings of the [connectors.shopify] table
+type Config struct {
+	Shop        string `toml:"shop"`        // shop name, e.g. "acme" for acme.myshopify.com
+	AccessToken string `toml:"accessToken"` // Admin API access token of a custom app
+	ApiVersion  string `toml:"apiVersion"`  // defaults to shopifyapi.DefaultApiVersion
+}
+
+// Connector syncs the products, variants and orders of a Shopify shop
+// it is disabled until the shop and access token are configured
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetShopifyProducts,
+			Description: "Shopify products and their variants: prices, SKUs and inventory",
+			SourceURL:   "https://shopify.dev/docs/api/admin-rest",
+			Cadence:     "continuous",
+			License:     "data of the configured shops, under the Shopify API terms",
+			Dimensions:  []string{"shop", "shopify_id"},
+			Fields:      registry.StoreFields(shopproduct.Store{}),
+		},
+		{
+			Name:        csyncdb.DatasetShopifyOrders,
+			Description: "Shopify orders: status and totals",
+			SourceURL:   "https://shopify.dev/docs/api/admin-rest",
+			Cadence:     "continuous",
+			License:     "data of the configured shops, under the Shopify API terms",
+			Dimensions:  []string{"shop", "shopify_id"},
+			Fields:      registry.StoreFields(shoporder.Store{}),
+		},
+	}
+}
+
+// Configure applies the [connectors.shopify] table
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := Config{}
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	conf.Shop = strings.TrimSuffix(strings.TrimSpace(conf.Shop), ".myshopify.com")
+	if strings.ContainsAny(conf.Shop, "/.: ") {
+		return nil, fmt.Errorf("invalid shop '%s': must be the shop name, e.g. acme for acme.myshopify.com", conf.Shop)
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+// Enabled returns true if the shop and access token are configured
+func (c Connector) Enabled() bool {
+	return c.Config.Shop != "" && c.Config.AccessToken != ""
+}
+
+// Sync syncs all products, then the orders updated in the last deps.Days days
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !c.Enabled() {
+		return fmt.Errorf("no shop or access token configured: pls set shop and accessToken in [connectors.%s]", Name)
+	}
+
+	client := shopifyapi.NewClient(c.Config.Shop, c.Config.AccessToken, deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+	if c.Config.ApiVersion != "" {
+		client.ApiVersion = c.Config.ApiVersion
+	}
+
+	var errs []error
+
+	if deps.Includes(csyncdb.DatasetShopifyProducts) {
+		if err := csyncdb.ShopifyProductsToTargets(ctx, deps.Targets, client); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.ShopifyProductsToTargets failed: %w", err))
+		}
+	}
+
+	if deps.Includes(csyncdb.DatasetShopifyOrders) {
+		if deps.Days < 1 {
+			return errors.Join(append(errs, fmt.Errorf("deps.Days must be at least 1"))...)
+		}
+		updatedSince := time.Now().AddDate(0, 0, -deps.Days)
+		if err := csyncdb.ShopifyOrdersToTargets(ctx, deps.Targets, client, updatedSince); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.ShopifyOrdersToTargets failed: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Relations returns the reference of the orders' currency to the ECB currencies, so that they can be converted with the stored rates
+func (c Connector) Relations() []integrity.Relation {
+	return []integrity.Relation{
+		{Table: "shopify.sales_order", Column: "currency", RefTable: "ecb.currency", RefColumn: "code"},
+	}
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: shopify.Migrations, Dir: "migrations"}}, infoLog)
+}
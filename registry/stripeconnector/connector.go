@@ -0,0 +1,125 @@
+package stripeconnector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/stripeapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/integrity"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/stripe"
+	"github.com/loveyourstack/connectors/stores/stripe/stripepayout"
+	"github.com/loveyourstack/connectors/stores/stripe/stripetxn"
+)
+
+const Name string = "stripe"
+
+func init() {
+	registry.Register(Connector{})
+}
+
+// Config contains the settings of the [connectors.stripe] table
+type Config struct {
+	ApiKey     string `toml:"apiKey"`     // secret key, or restricted key with read access to balance transactions and payouts
+	ApiVersion string `toml:"apiVersion"` // defaults to stripeapi.DefaultApiVersion
+}
+
+// Connector syncs the balance transactions, fees and payouts of a Stripe account
+// it is disabled until the API key is configured
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+	return []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetStripeBalanceTransactions,
+			Description: "Stripe balance transactions and their fees, synced incrementally by creation time",
+			SourceURL:   "https://docs.stripe.com/api/balance_transactions",
+			Cadence:     "continuous",
+			License:     "data of the configured Stripe account",
+			Dimensions:  []string{"stripe_id"},
+			Fields:      registry.StoreFields(stripetxn.Store{}),
+		},
+		{
+			Name:        csyncdb.DatasetStripePayouts,
+			Description: "Stripe payouts to bank accounts and cards, synced incrementally by creation time",
+			SourceURL:   "https://docs.stripe.com/api/payouts",
+			Cadence:     "daily, per payout schedule",
+			License:     "data of the configured Stripe account",
+			Dimensions:  []string{"stripe_id"},
+			Fields:      registry.StoreFields(stripepayout.Store{}),
+		},
+	}
+}
+
+// Configure applies the [connectors.stripe] table
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := Config{}
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+// Enabled returns true if the API key is configured
+func (c Connector) Enabled() bool {
+	return c.Config.ApiKey != ""
+}
+
+// Sync syncs the balance transactions and payouts created since the last sync, or in the last deps.Days days on the first sync
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !c.Enabled() {
+		return fmt.Errorf("no API key configured: pls set apiKey in [connectors.%s]", Name)
+	}
+	if deps.Days < 1 {
+		return fmt.Errorf("deps.Days must be at least 1")
+	}
+
+	client := stripeapi.NewClient(c.Config.ApiKey, deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+	if c.Config.ApiVersion != "" {
+		client.ApiVersion = c.Config.ApiVersion
+	}
+
+	var errs []error
+
+	if deps.Includes(csyncdb.DatasetStripeBalanceTransactions) {
+		if err := csyncdb.StripeBalanceTransactionsToTargets(ctx, deps.Targets, client, deps.Days); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.StripeBalanceTransactionsToTargets failed: %w", err))
+		}
+	}
+
+	if deps.Includes(csyncdb.DatasetStripePayouts) {
+		if err := csyncdb.StripePayoutsToTargets(ctx, deps.Targets, client, deps.Days); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.StripePayoutsToTargets failed: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Relations returns the references of the currencies of the Stripe amounts to the ECB currencies, so that they can be converted with the stored rates
+func (c Connector) Relations() []integrity.Relation {
+	return []integrity.Relation{
+		{Table: "stripe.balance_transaction", Column: "currency", RefTable: "ecb.currency", RefColumn: "code"},
+		{Table: "stripe.fee", Column: "currency", RefTable: "ecb.currency", RefColumn: "code"},
+		{Table: "stripe.payout", Column: "currency", RefTable: "ecb.currency", RefColumn: "code"},
+	}
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: stripe.Migrations, Dir: "migrations"}}, infoLog)
+}
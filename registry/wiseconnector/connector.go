@@ -0,0 +1,185 @@
+package wiseconnector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/wiseapi"
+	"github.com/loveyourstack/connectors/csyncdb"
+	"github.com/loveyourstack/connectors/integrity"
+	"github.com/loveyourstack/connectors/migrate"
+	"github.com/loveyourstack/connectors/ratesource"
+	"github.com/loveyourstack/connectors/registry"
+	"github.com/loveyourstack/connectors/stores/wise"
+	"github.com/loveyourstack/connectors/stores/wise/wiserate"
+	"github.com/loveyourstack/connectors/stores/wise/wisetransfer"
+)
+
+const Name string = "wise"
+
+// DefaultPairs are the currency pairs of which mid-market rates are synced if none are configured
+var DefaultPairs = []string{"EUR/USD", "EUR/GBP"}
+
+func init() {
+	registry.Register(Connector{Config: Config{Pairs: DefaultPairs}})
+}
+
+// Config contains the settings of the [connectors.wise] table
+type Config struct {
+	ApiToken   string   `toml:"apiToken"`   // personal API token
+	ProfileIds []int64  `toml:"profileIds"` // profiles whose transfers are synced. All profiles of the account if empty
+	Pairs      []string `toml:"pairs"`      // currency pairs of the mid-market rates, e.g. "EUR/USD". Defaults to DefaultPairs
+	Sandbox    bool     `toml:"sandbox"`
+	Intraday   bool     `toml:"intraday"` // also sync hourly rates of the pairs as ticks of wise.rate_tick, e.g. to a ClickHouse sink
+}
+
+// Connector syncs the transfers of a Wise account and Wise's daily mid-market rates
+// it is disabled until the API token is configured
+type Connector struct {
+	Config Config
+}
+
+func (c Connector) Name() string {
+	return Name
+}
+
+func (c Connector) Datasets() []registry.Dataset {
+
+	datasets := []registry.Dataset{
+		{
+			Name:        csyncdb.DatasetWiseRates,
+			Description: "Wise daily mid-market rates of the configured currency pairs",
+			SourceURL:   "https://docs.wise.com/api-docs/api-reference/rate",
+			Cadence:     "daily",
+			License:     "Wise API terms of use",
+			Dimensions:  []string{"source_currency", "target_currency", "day"},
+			Fields:      registry.StoreFields(wiserate.Store{}),
+		},
+		{
+			Name:        csyncdb.DatasetWiseTransfers,
+			Description: "Wise transfers: converted amounts and applied rates",
+			SourceURL:   "https://docs.wise.com/api-docs/api-reference/transfer",
+			Cadence:     "continuous",
+			License:     "data of the configured Wise profile",
+			Dimensions:  []string{"wise_id"},
+			Fields:      registry.StoreFields(wisetransfer.Store{}),
+		},
+	}
+	if c.Config.Intraday {
+		datasets = append(datasets, registry.Dataset{
+			Name:        csyncdb.DatasetWiseRateTicks,
+			Description: "Wise hourly mid-market rates of the configured currency pairs",
+			SourceURL:   "https://docs.wise.com/api-docs/api-reference/rate",
+			Cadence:     "hourly",
+			License:     "Wise API terms of use",
+			Dimensions:  []string{"series", "at"},
+		})
+	}
+
+	return datasets
+}
+
+// Configure applies the [connectors.wise] table
+func (c Connector) Configure(decode func(v any) error) (registry.Connector, error) {
+
+	conf := Config{Pairs: DefaultPairs}
+	if err := decode(&conf); err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	if _, err := parsePairs(conf.Pairs); err != nil {
+		return nil, fmt.Errorf("parsePairs failed: %w", err)
+	}
+
+	return Connector{Config: conf}, nil
+}
+
+func parsePairs(pairStrs []string) (pairs []wiseapi.Pair, err error) {
+
+	for _, s := range pairStrs {
+		pair, err := wiseapi.ParsePair(s)
+		if err != nil {
+			return nil, fmt.Errorf("wiseapi.ParsePair failed: %w", err)
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
+
+// Enabled returns true if the API token is configured
+func (c Connector) Enabled() bool {
+	return c.Config.ApiToken != ""
+}
+
+// Sync syncs the mid-market rates of the last deps.Days days, and the transfers created in them
+// if intraday is set, the hourly rates of the same days follow, written to the sink routed by deps.Sinks or else to the targets
+func (c Connector) Sync(ctx context.Context, deps registry.Deps) error {
+
+	if !c.Enabled() {
+		return fmt.Errorf("no API token configured: pls set apiToken in [connectors.%s]", Name)
+	}
+	if deps.Days < 1 {
+		return fmt.Errorf("deps.Days must be at least 1")
+	}
+
+	client := wiseapi.NewClient(c.Config.ApiToken, deps.InfoLog, deps.ErrorLog)
+	client.HttpClient = deps.HttpClient(client.HttpClient)
+	client.Sandbox = c.Config.Sandbox
+
+	endDate := time.Now()
+	startDate := endDate.AddDate(0, 0, -deps.Days)
+
+	var errs []error
+
+	if deps.Includes(csyncdb.DatasetWiseRates) {
+		pairs, err := parsePairs(c.Config.Pairs)
+		if err != nil {
+			return fmt.Errorf("parsePairs failed: %w", err)
+		}
+		if err := csyncdb.WiseRatesToTargets(ctx, deps.Targets, client, pairs, startDate, endDate); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.WiseRatesToTargets failed: %w", err))
+		}
+	}
+
+	if deps.Includes(csyncdb.DatasetWiseTransfers) {
+		if err := csyncdb.WiseTransfersToTargets(ctx, deps.Targets, client, c.Config.ProfileIds, startDate); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.WiseTransfersToTargets failed: %w", err))
+		}
+	}
+
+	if c.Config.Intraday && deps.Includes(csyncdb.DatasetWiseRateTicks) {
+		pairs, err := parsePairs(c.Config.Pairs)
+		if err != nil {
+			return fmt.Errorf("parsePairs failed: %w", err)
+		}
+		if err := csyncdb.WiseRateTicksToTargets(ctx, deps.Targets, deps.Sinks, client, pairs, startDate, endDate); err != nil {
+			errs = append(errs, fmt.Errorf("csyncdb.WiseRateTicksToTargets failed: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// RateSource returns the Wise client as a rate source of mid-market rates
+func (c Connector) RateSource(infoLog, errorLog *slog.Logger) ratesource.Source {
+	client := wiseapi.NewClient(c.Config.ApiToken, infoLog, errorLog)
+	client.Sandbox = c.Config.Sandbox
+	return client
+}
+
+// Relations returns the references of the currencies of the rates and transfers to the ECB currencies, so that they can be compared with the reference rates
+func (c Connector) Relations() []integrity.Relation {
+	return []integrity.Relation{
+		{Table: "wise.rate", Column: "source_currency", RefTable: "ecb.currency", RefColumn: "code"},
+		{Table: "wise.rate", Column: "target_currency", RefTable: "ecb.currency", RefColumn: "code"},
+		{Table: "wise.transfer", Column: "source_currency", RefTable: "ecb.currency", RefColumn: "code"},
+		{Table: "wise.transfer", Column: "target_currency", RefTable: "ecb.currency", RefColumn: "code"},
+	}
+}
+
+func (c Connector) Migrate(ctx context.Context, db *pgxpool.Pool, infoLog *slog.Logger) (numApplied int, err error) {
+	return migrate.Run(ctx, db, []migrate.Source{{Name: Name, FS: wise.Migrations, Dir: "migrations"}}, infoLog)
+}
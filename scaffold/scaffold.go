@@ -0,0 +1,199 @@
+// Package scaffold generates the files of a new connector, following the conventions of the existing ones: the API client, the store with its migrations,
+// the sync funcs, the registered connector and their tests. The generated code compiles as is, with TODOs where the provider's API must be filled in
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var (
+	providerRegex = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+	entityRegex   = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+)
+
+// Names are the identifiers of a scaffolded connector, derived from its provider and entity
+type Names struct {
+	Module         string // path of the Go module, e.g. github.com/loveyourstack/connectors
+	Provider       string // e.g. acme
+	ProviderTitle  string // e.g. Acme
+	Entity         string // singular, snake case, e.g. sales_order
+	EntityTitle    string // e.g. SalesOrder
+	EntityLabel    string // e.g. sales order
+	EntitiesTitle  string // plural, e.g. SalesOrders
+	EntitiesFile   string // plural, snake case, e.g. sales_orders
+	EntitiesLabel  string // plural, e.g. sales orders
+	ApiPkg         string // e.g. acmeapi
+	StorePkg       string // e.g. acmesalesorder
+	ConnectorPkg   string // e.g. acmeconnector
+	Dataset        string // schema and table, e.g. acme.sales_order
+	DatasetConst   string // e.g. DatasetAcmeSalesOrders
+	TableShortname string // e.g. acso
+}
+
+// NewNames returns the names of the connector of provider, e.g. acme, syncing entity, e.g. sales_order, in module
+func NewNames(module, provider, entity string) (Names, error) {
+
+	if !providerRegex.MatchString(provider) {
+		return Names{}, fmt.Errorf("%w: provider must be lower case letters and digits, starting with a letter: %q", cerrors.ErrValidationFailed, provider)
+	}
+	if !entityRegex.MatchString(entity) {
+		return Names{}, fmt.Errorf("%w: entity must be singular snake case, e.g. sales_order: %q", cerrors.ErrValidationFailed, entity)
+	}
+
+	words := strings.Split(entity, "_")
+	pluralWords := append(words[:len(words)-1:len(words)-1], plural(words[len(words)-1]))
+
+	shortname := provider[:min(2, len(provider))]
+	for _, w := range words {
+		shortname += w[:1]
+	}
+
+	n := Names{
+		Module:         module,
+		Provider:       provider,
+		ProviderTitle:  title(provider),
+		Entity:         entity,
+		EntityTitle:    titleWords(words),
+		EntityLabel:    strings.Join(words, " "),
+		EntitiesTitle:  titleWords(pluralWords),
+		EntitiesFile:   strings.Join(pluralWords, "_"),
+		EntitiesLabel:  strings.Join(pluralWords, " "),
+		ApiPkg:         provider + "api",
+		StorePkg:       provider + strings.Join(words, ""),
+		ConnectorPkg:   provider + "connector",
+		Dataset:        provider + "." + entity,
+		TableShortname: shortname,
+	}
+	n.DatasetConst = "Dataset" + n.ProviderTitle + n.EntitiesTitle
+
+	return n, nil
+}
+
+// File is a file generated for a connector
+type File struct {
+	Path     string // relative to the module root
+	template string
+}
+
+// Files returns the files generated for n, in the order they are written
+func Files(n Names) []File {
+	return []File{
+		{Path: filepath.Join("apiclients", n.ApiPkg, "base.go"), template: "apiclient_base.go.tmpl"},
+		{Path: filepath.Join("apiclients", n.ApiPkg, n.EntitiesFile+".go"), template: "apiclient_entity.go.tmpl"},
+		{Path: filepath.Join("apiclients", n.ApiPkg, n.EntitiesFile+"_test.go"), template: "apiclient_entity_test.go.tmpl"},
+		{Path: filepath.Join("stores", n.Provider, "migrations.go"), template: "store_migrations.go.tmpl"},
+		{Path: filepath.Join("stores", n.Provider, "migrations", "001_init.sql"), template: "store_migration_init.sql.tmpl"},
+		{Path: filepath.Join("stores", n.Provider, n.StorePkg, "store.go"), template: "store.go.tmpl"},
+		{Path: filepath.Join("csyncdb", n.Provider+".go"), template: "csyncdb.go.tmpl"},
+		{Path: filepath.Join("registry", n.ConnectorPkg, "connector.go"), template: "connector.go.tmpl"},
+		{Path: filepath.Join("registry", n.ConnectorPkg, "connector_test.go"), template: "connector_test.go.tmpl"},
+	}
+}
+
+// Generate writes the files of the connector of n below root, the module root, and returns their paths
+// no file is written if any of them exists, unless overwrite is true
+func Generate(root string, n Names, overwrite bool) (paths []string, err error) {
+
+	tmpl, err := template.ParseFS(templateFS, "templates/*.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("template.ParseFS failed: %w", err)
+	}
+
+	files := Files(n)
+	contents := make([][]byte, len(files))
+	for i, f := range files {
+
+		if !overwrite {
+			if _, err := os.Stat(filepath.Join(root, f.Path)); err == nil {
+				return nil, fmt.Errorf("%w: %s exists: pls choose another provider or entity, or overwrite", cerrors.ErrConflictPolicyViolation, f.Path)
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("os.Stat failed: %w", err)
+			}
+		}
+
+		var buf bytes.Buffer
+		if err = tmpl.ExecuteTemplate(&buf, f.template, n); err != nil {
+			return nil, fmt.Errorf("tmpl.ExecuteTemplate failed for %s: %w", f.Path, err)
+		}
+		contents[i] = buf.Bytes()
+
+		if filepath.Ext(f.Path) == ".go" {
+			if contents[i], err = format.Source(contents[i]); err != nil {
+				return nil, fmt.Errorf("format.Source failed for %s: %w", f.Path, err)
+			}
+		}
+	}
+
+	for i, f := range files {
+		path := filepath.Join(root, f.Path)
+		if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return paths, fmt.Errorf("os.MkdirAll failed: %w", err)
+		}
+		if err = os.WriteFile(path, contents[i], 0o644); err != nil {
+			return paths, fmt.Errorf("os.WriteFile failed: %w", err)
+		}
+		paths = append(paths, f.Path)
+	}
+
+	return paths, nil
+}
+
+// ModulePath returns the module path declared in the go.mod file of root
+func ModulePath(root string) (string, error) {
+
+	content, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", fmt.Errorf("os.ReadFile failed: %w", err)
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if path, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+			return strings.Trim(strings.TrimSpace(path), `"`), nil
+		}
+	}
+	return "", fmt.Errorf("%w: no module path in %s", cerrors.ErrValidationFailed, filepath.Join(root, "go.mod"))
+}
+
+// plural returns the English plural of the noun word
+func plural(word string) string {
+
+	switch {
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !strings.ContainsRune("aeiou", rune(word[len(word)-2])):
+		return word[:len(word)-1] + "ies"
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"), strings.HasSuffix(word, "z"), strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	default:
+		return word + "s"
+	}
+}
+
+// title returns word with its first letter in upper case
+func title(word string) string {
+	r := []rune(word)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// titleWords returns words joined in camel case, each starting with an upper case letter
+func titleWords(words []string) string {
+	var b strings.Builder
+	for _, w := range words {
+		b.WriteString(title(w))
+	}
+	return b.String()
+}
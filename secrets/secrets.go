@@ -0,0 +1,229 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// reference schemes of the built-in providers
+const (
+	SchemeEnv   string = "env"
+	SchemeFile  string = "file"
+	SchemeVault string = "vault"
+)
+
+const defaultRefreshInterval time.Duration = 5 * time.Minute
+
+// Config contains the settings of the [secrets] table
+type Config struct {
+	RefreshInterval string      `toml:"refreshInterval"` // Go duration for which resolved secrets are cached, so that rotated secrets are picked up after it. Defaults to 5m
+	Vault           VaultConfig `toml:"vault"`           // the vault provider is available if an address is set
+}
+
+// Provider returns the secret of a reference, the part of a config value after its scheme, e.g. the variable name of "env:FRED_API_KEY"
+type Provider interface {
+	Get(ctx context.Context, ref string) (string, error)
+}
+
+// Env is the provider of "env:NAME" references: the value of environment variable NAME
+type Env struct{}
+
+func (Env) Get(ctx context.Context, ref string) (string, error) {
+
+	v, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", fmt.Errorf("%w: environment variable %s is not set", cerrors.ErrNotFound, ref)
+	}
+	return v, nil
+}
+
+// File is the provider of "file:PATH" references: the content of the file at PATH without trailing line breaks, e.g. a Docker or Kubernetes secret
+type File struct{}
+
+func (File) Get(ctx context.Context, ref string) (string, error) {
+
+	b, err := os.ReadFile(ref)
+	if err != nil {
+		return "", fmt.Errorf("os.ReadFile failed: %w", err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+type cached struct {
+	value     string
+	fetchedAt time.Time
+}
+
+// Resolver resolves config values referring to secrets, e.g. "env:FRED_API_KEY", "file:/run/secrets/stripe_key" or "vault:connectors/amzsp#clientSecret", using the
+// provider of their scheme. Other values, including those of unknown schemes, are plain values and returned as is
+// resolved secrets are cached for RefreshInterval: resolving again after it returns the rotated secret. Resolver is safe for concurrent use
+type Resolver struct {
+	Providers       map[string]Provider // by scheme
+	RefreshInterval time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cached
+}
+
+// NewResolver returns a Resolver of the env and file providers, and of the vault provider if conf sets its address
+// the vault token may itself be an env or file reference
+func NewResolver(ctx context.Context, conf Config) (*Resolver, error) {
+
+	r := &Resolver{
+		Providers:       map[string]Provider{SchemeEnv: Env{}, SchemeFile: File{}},
+		RefreshInterval: defaultRefreshInterval,
+	}
+	if conf.RefreshInterval != "" {
+		var err error
+		r.RefreshInterval, err = time.ParseDuration(conf.RefreshInterval)
+		if err != nil {
+			return nil, fmt.Errorf("time.ParseDuration failed for refreshInterval: %w", err)
+		}
+	}
+
+	if conf.Vault.Enabled() {
+		token, err := r.Resolve(ctx, conf.Vault.Token)
+		if err != nil {
+			return nil, fmt.Errorf("r.Resolve failed for vault token: %w", err)
+		}
+		conf.Vault.Token = token
+		r.Providers[SchemeVault] = NewVault(conf.Vault)
+	}
+
+	return r, nil
+}
+
+// IsReference returns true if value refers to a secret of one of r's providers
+func (r *Resolver) IsReference(value string) bool {
+	scheme, _, ok := strings.Cut(value, ":")
+	_, known := r.Providers[scheme]
+	return ok && known
+}
+
+// Resolve returns the secret value refers to, or value itself if it is a plain value
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+
+	scheme, ref, ok := strings.Cut(value, ":")
+	p, known := r.Providers[scheme]
+	if !ok || !known {
+		return value, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if c, ok := r.cache[value]; ok && time.Since(c.fetchedAt) < r.RefreshInterval {
+		return c.value, nil
+	}
+
+	// the reference, not the secret, identifies the failure
+	secret, err := p.Get(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("%s provider failed for '%s': %w", scheme, value, err)
+	}
+
+	if r.cache == nil {
+		r.cache = make(map[string]cached)
+	}
+	r.cache[value] = cached{value: secret, fetchedAt: time.Now()}
+	return secret, nil
+}
+
+// ResolveFields replaces each secret reference in the exported string fields of the struct v points to, recursing into structs, pointers, slices and maps
+func (r *Resolver) ResolveFields(ctx context.Context, v any) error {
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("v must be a non-nil pointer")
+	}
+	return r.resolveValue(ctx, rv.Elem(), "")
+}
+
+func (r *Resolver) resolveValue(ctx context.Context, v reflect.Value, path string) error {
+
+	switch v.Kind() {
+
+	case reflect.String:
+		if !v.CanSet() || !r.IsReference(v.String()) {
+			return nil
+		}
+		secret, err := r.Resolve(ctx, v.String())
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		v.SetString(secret)
+
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			return r.resolveValue(ctx, v.Elem(), path)
+		}
+
+	case reflect.Struct:
+		t := v.Type()
+		for i := range v.NumField() {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			if err := r.resolveValue(ctx, v.Field(i), joinPath(path, fieldName(t.Field(i)))); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			if err := r.resolveValue(ctx, v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		// map elements are not addressable: string elements are resolved into a copy and stored back
+		if v.Type().Elem().Kind() != reflect.String {
+			iter := v.MapRange()
+			for iter.Next() {
+				elem := reflect.New(iter.Value().Type()).Elem()
+				elem.Set(iter.Value())
+				if err := r.resolveValue(ctx, elem, joinPath(path, fmt.Sprint(iter.Key()))); err != nil {
+					return err
+				}
+				v.SetMapIndex(iter.Key(), elem)
+			}
+			return nil
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if !r.IsReference(iter.Value().String()) {
+				continue
+			}
+			secret, err := r.Resolve(ctx, iter.Value().String())
+			if err != nil {
+				return fmt.Errorf("%s: %w", joinPath(path, fmt.Sprint(iter.Key())), err)
+			}
+			v.SetMapIndex(iter.Key(), reflect.ValueOf(secret).Convert(v.Type().Elem()))
+		}
+	}
+
+	return nil
+}
+
+// fieldName returns the toml name of f, as used in error messages
+func fieldName(f reflect.StructField) string {
+	if name, _, _ := strings.Cut(f.Tag.Get("toml"), ","); name != "" && name != "-" {
+		return name
+	}
+	return f.Name
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,165 @@
+package secrets_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/secrets"
+)
+
+func TestResolve(t *testing.T) {
+
+	ctx := context.Background()
+	t.Setenv("CONNECTORS_TEST_KEY", "env-secret")
+	t.Setenv("CONNECTORS_TEST_EMPTY", "")
+	t.Setenv("VAULT_ADDR", "")
+	keyFile := filepath.Join(t.TempDir(), "stripe_key")
+	if err := os.WriteFile(keyFile, []byte("file-secret\r\n"), 0600); err != nil {
+		t.Fatalf("os.WriteFile failed: %s", err.Error())
+	}
+
+	r, err := secrets.NewResolver(ctx, secrets.Config{})
+	if err != nil {
+		t.Fatalf("secrets.NewResolver failed: %s", err.Error())
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"env", "env:CONNECTORS_TEST_KEY", "env-secret"},
+		{"empty env", "env:CONNECTORS_TEST_EMPTY", ""},
+		{"file without trailing line break", "file:" + keyFile, "file-secret"},
+		{"plain value", "abc123", "abc123"},
+		{"unknown scheme", "https://api.example.com", "https://api.example.com"},
+		{"vault without an address", "vault:connectors/stripe#apiKey", "vault:connectors/stripe#apiKey"},
+	}
+
+	for _, tt := range tests {
+		got, err := r.Resolve(ctx, tt.value)
+		if err != nil {
+			t.Errorf("%s: r.Resolve failed: %s", tt.name, err.Error())
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got '%s', want '%s'", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err = r.Resolve(ctx, "env:CONNECTORS_TEST_MISSING"); !errors.Is(err, cerrors.ErrNotFound) {
+		t.Errorf("r.Resolve of an unset variable: got %v, want cerrors.ErrNotFound", err)
+	}
+	if _, err = r.Resolve(ctx, "file:"+filepath.Join(t.TempDir(), "missing")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("r.Resolve of a missing file: got %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestResolveRefresh(t *testing.T) {
+
+	ctx := context.Background()
+	t.Setenv("CONNECTORS_TEST_KEY", "v1")
+
+	r, err := secrets.NewResolver(ctx, secrets.Config{RefreshInterval: "1h"})
+	if err != nil {
+		t.Fatalf("secrets.NewResolver failed: %s", err.Error())
+	}
+
+	resolve := func(want string) {
+		t.Helper()
+		got, err := r.Resolve(ctx, "env:CONNECTORS_TEST_KEY")
+		if err != nil {
+			t.Fatalf("r.Resolve failed: %s", err.Error())
+		}
+		if got != want {
+			t.Errorf("r.Resolve: got '%s', want '%s'", got, want)
+		}
+	}
+
+	resolve("v1")
+	t.Setenv("CONNECTORS_TEST_KEY", "v2")
+	resolve("v1") // cached
+	r.RefreshInterval = time.Nanosecond
+	resolve("v2") // rotated
+
+	if _, err = secrets.NewResolver(ctx, secrets.Config{RefreshInterval: "5 minutes"}); err == nil {
+		t.Errorf("secrets.NewResolver of an invalid refreshInterval: got nil, want an error")
+	}
+}
+
+func TestResolveFields(t *testing.T) {
+
+	ctx := context.Background()
+	t.Setenv("CONNECTORS_TEST_KEY", "env-secret")
+
+	type apiConfig struct {
+		BaseUrl string            `toml:"baseUrl"`
+		ApiKey  string            `toml:"apiKey"`
+		Headers map[string]string `toml:"headers"`
+		apiKey  string
+	}
+	type config struct {
+		Apis    []apiConfig           `toml:"apis"`
+		Primary *apiConfig            `toml:"primary"`
+		Named   map[string]apiConfig  `toml:"named"`
+		Tokens  map[string]string     `toml:"tokens"`
+		Missing *apiConfig            `toml:"missing"`
+		Any     any                   `toml:"any"`
+		Nested  map[string]*apiConfig `toml:"nested"`
+	}
+
+	conf := config{
+		Apis:    []apiConfig{{BaseUrl: "https://api.example.com", ApiKey: "env:CONNECTORS_TEST_KEY", apiKey: "env:CONNECTORS_TEST_KEY"}},
+		Primary: &apiConfig{ApiKey: "env:CONNECTORS_TEST_KEY", Headers: map[string]string{"Authorization": "env:CONNECTORS_TEST_KEY"}},
+		Named:   map[string]apiConfig{"stripe": {ApiKey: "env:CONNECTORS_TEST_KEY"}},
+		Tokens:  map[string]string{"fred": "env:CONNECTORS_TEST_KEY", "plain": "abc"},
+		Nested:  map[string]*apiConfig{"wise": {ApiKey: "env:CONNECTORS_TEST_KEY"}},
+	}
+
+	r, err := secrets.NewResolver(ctx, secrets.Config{})
+	if err != nil {
+		t.Fatalf("secrets.NewResolver failed: %s", err.Error())
+	}
+	if err = r.ResolveFields(ctx, &conf); err != nil {
+		t.Fatalf("r.ResolveFields failed: %s", err.Error())
+	}
+
+	for name, got := range map[string]string{
+		"apis[0].apiKey":                conf.Apis[0].ApiKey,
+		"primary.apiKey":                conf.Primary.ApiKey,
+		"primary.headers.Authorization": conf.Primary.Headers["Authorization"],
+		"named.stripe.apiKey":           conf.Named["stripe"].ApiKey,
+		"tokens.fred":                   conf.Tokens["fred"],
+		"nested.wise.apiKey":            conf.Nested["wise"].ApiKey,
+	} {
+		if got != "env-secret" {
+			t.Errorf("%s: got '%s', want 'env-secret'", name, got)
+		}
+	}
+	if conf.Apis[0].BaseUrl != "https://api.example.com" || conf.Tokens["plain"] != "abc" {
+		t.Errorf("plain values: got '%s', '%s', want them unchanged", conf.Apis[0].BaseUrl, conf.Tokens["plain"])
+	}
+	if conf.Apis[0].apiKey != "env:CONNECTORS_TEST_KEY" {
+		t.Errorf("unexported field: got '%s', want it unchanged", conf.Apis[0].apiKey)
+	}
+
+	// the error names the field, not the secret
+	conf.Apis[0].ApiKey = "env:CONNECTORS_TEST_MISSING"
+	err = r.ResolveFields(ctx, &conf)
+	if !errors.Is(err, cerrors.ErrNotFound) {
+		t.Fatalf("r.ResolveFields of an unset variable: got %v, want cerrors.ErrNotFound", err)
+	}
+	if want := "apis[0].apiKey: env provider failed for 'env:CONNECTORS_TEST_MISSING': not found: environment variable CONNECTORS_TEST_MISSING is not set"; err.Error() != want {
+		t.Errorf("r.ResolveFields error:\ngot  %s\nwant %s", err.Error(), want)
+	}
+
+	if err = r.ResolveFields(ctx, conf); err == nil {
+		t.Errorf("r.ResolveFields of a struct value: got nil, want an error")
+	}
+}
@@ -0,0 +1,113 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+const defaultVaultMount string = "secret"
+
+// VaultConfig contains the settings of the [secrets.vault] table
+type VaultConfig struct {
+	Address   string `toml:"address"`   // e.g. https://vault.example.com:8200. Defaults to $VAULT_ADDR. The vault provider is unavailable without it
+	Token     string `toml:"token"`     // may be an env or file reference, e.g. "file:/var/run/secrets/vault-token". Defaults to $VAULT_TOKEN
+	Namespace string `toml:"namespace"` // Vault Enterprise namespace, if any
+	Mount     string `toml:"mount"`     // mount path of the KV version 2 secrets engine. Defaults to secret
+}
+
+// Enabled returns true if an address is configured or set in $VAULT_ADDR
+func (c VaultConfig) Enabled() bool {
+	return c.Address != "" || os.Getenv("VAULT_ADDR") != ""
+}
+
+// Vault is the provider of "vault:PATH#KEY" references: the field KEY of the latest version of the secret at PATH of a KV version 2 secrets engine
+type Vault struct {
+	Config     VaultConfig
+	HttpClient *http.Client
+}
+
+// NewVault returns a Vault provider, applying the defaults of conf
+func NewVault(conf VaultConfig) *Vault {
+
+	if conf.Address == "" {
+		conf.Address = os.Getenv("VAULT_ADDR")
+	}
+	conf.Address = strings.TrimSuffix(conf.Address, "/")
+	if conf.Token == "" {
+		conf.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if conf.Mount == "" {
+		conf.Mount = defaultVaultMount
+	}
+	conf.Mount = strings.Trim(conf.Mount, "/")
+
+	return &Vault{
+		Config:     conf,
+		HttpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type vaultKvResponse struct {
+	Data struct {
+		Data map[string]any `json:"data"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+func (v *Vault) Get(ctx context.Context, ref string) (string, error) {
+
+	path, key, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || key == "" {
+		return "", fmt.Errorf("%w: expected PATH#KEY", cerrors.ErrValidationFailed)
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/data/%s", v.Config.Address, v.Config.Mount, strings.Trim(path, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", v.Config.Token)
+	if v.Config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Config.Namespace)
+	}
+
+	resp, err := v.HttpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: v.HttpClient.Do failed: %w", cerrors.ErrUpstreamUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("io.ReadAll failed: %w", err)
+	}
+
+	var kvResp vaultKvResponse
+	_ = json.Unmarshal(body, &kvResp)
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", fmt.Errorf("%w: no secret at %s", cerrors.ErrNotFound, path)
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("%w: unexpected status code: %d: %s", cerrors.ErrUpstreamUnavailable, resp.StatusCode, strings.Join(kvResp.Errors, "; "))
+	}
+
+	val, ok := kvResp.Data.Data[key]
+	if !ok {
+		return "", fmt.Errorf("%w: no key %s in secret %s", cerrors.ErrNotFound, key, path)
+	}
+	s, ok := val.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: key %s of secret %s is not a string", cerrors.ErrValidationFailed, key, path)
+	}
+
+	return s, nil
+}
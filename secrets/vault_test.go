@@ -0,0 +1,124 @@
+package secrets_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/secrets"
+)
+
+// newVaultServer returns a test server of a KV version 2 engine mounted at kv, holding the secret connectors/stripe and accepting token s.3cr3t
+func newVaultServer(t *testing.T) *httptest.Server {
+
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("X-Vault-Token") != "s.3cr3t" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		if r.Header.Get("X-Vault-Namespace") != "finance" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":["namespace required"]}`))
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/kv/data/connectors/stripe":
+			_, _ = w.Write([]byte(`{"data":{"data":{"apiKey":"sk_test_123","retries":3},"metadata":{"version":2}}}`))
+		case "/v1/kv/data/connectors/unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"errors":["Vault is sealed"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestVaultGet(t *testing.T) {
+
+	ctx := context.Background()
+	srv := newVaultServer(t)
+	vault := secrets.NewVault(secrets.VaultConfig{Address: srv.URL + "/", Token: "s.3cr3t", Namespace: "finance", Mount: "/kv/"})
+
+	got, err := vault.Get(ctx, "/connectors/stripe/#apiKey")
+	if err != nil {
+		t.Fatalf("vault.Get failed: %s", err.Error())
+	}
+	if got != "sk_test_123" {
+		t.Errorf("vault.Get: got '%s', want 'sk_test_123'", got)
+	}
+
+	tests := []struct {
+		name    string
+		ref     string
+		wantErr error
+	}{
+		{"no key", "connectors/stripe", cerrors.ErrValidationFailed},
+		{"empty key", "connectors/stripe#", cerrors.ErrValidationFailed},
+		{"no path", "#apiKey", cerrors.ErrValidationFailed},
+		{"missing secret", "connectors/fred#apiKey", cerrors.ErrNotFound},
+		{"missing key", "connectors/stripe#clientSecret", cerrors.ErrNotFound},
+		{"key not a string", "connectors/stripe#retries", cerrors.ErrValidationFailed},
+		{"unavailable", "connectors/unavailable#apiKey", cerrors.ErrUpstreamUnavailable},
+	}
+
+	for _, tt := range tests {
+		if _, err := vault.Get(ctx, tt.ref); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// the token is sent
+	denied := secrets.NewVault(secrets.VaultConfig{Address: srv.URL, Token: "s.wrong", Namespace: "finance", Mount: "kv"})
+	if _, err = denied.Get(ctx, "connectors/stripe#apiKey"); !errors.Is(err, cerrors.ErrUpstreamUnavailable) {
+		t.Errorf("vault.Get with a wrong token: got %v, want cerrors.ErrUpstreamUnavailable", err)
+	}
+
+	// no server at the address
+	srvClosed := httptest.NewServer(http.NotFoundHandler())
+	srvClosed.Close()
+	closed := secrets.NewVault(secrets.VaultConfig{Address: srvClosed.URL, Token: "s.3cr3t"})
+	if _, err = closed.Get(ctx, "connectors/stripe#apiKey"); !errors.Is(err, cerrors.ErrUpstreamUnavailable) {
+		t.Errorf("vault.Get of a closed server: got %v, want cerrors.ErrUpstreamUnavailable", err)
+	}
+}
+
+func TestNewResolverVault(t *testing.T) {
+
+	ctx := context.Background()
+	srv := newVaultServer(t)
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", "")
+	t.Setenv("CONNECTORS_TEST_VAULT_TOKEN", "s.3cr3t")
+
+	// the address defaults to $VAULT_ADDR, and the token may be a reference
+	r, err := secrets.NewResolver(ctx, secrets.Config{Vault: secrets.VaultConfig{Token: "env:CONNECTORS_TEST_VAULT_TOKEN", Namespace: "finance", Mount: "kv"}})
+	if err != nil {
+		t.Fatalf("secrets.NewResolver failed: %s", err.Error())
+	}
+	if !r.IsReference("vault:connectors/stripe#apiKey") {
+		t.Errorf("r.IsReference of a vault reference: got false, want true")
+	}
+	got, err := r.Resolve(ctx, "vault:connectors/stripe#apiKey")
+	if err != nil {
+		t.Fatalf("r.Resolve failed: %s", err.Error())
+	}
+	if got != "sk_test_123" {
+		t.Errorf("r.Resolve: got '%s', want 'sk_test_123'", got)
+	}
+
+	if _, err = secrets.NewResolver(ctx, secrets.Config{Vault: secrets.VaultConfig{Token: "env:CONNECTORS_TEST_MISSING"}}); !errors.Is(err, cerrors.ErrNotFound) {
+		t.Errorf("secrets.NewResolver of a missing vault token: got %v, want cerrors.ErrNotFound", err)
+	}
+}
@@ -0,0 +1,228 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/clog"
+)
+
+// ClickHouseName is the name of the ClickHouse sink
+const ClickHouseName string = "clickhouse"
+
+const (
+	defaultClickHouseDatabase  string        = "connectors"
+	defaultClickHouseTable     string        = "tick"
+	defaultClickHouseBatchSize int           = 50000
+	defaultClickHouseTimeout   time.Duration = 60 * time.Second
+	clickHouseTimeFormat       string        = "2006-01-02 15:04:05.000"
+)
+
+var clickHouseIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ClickHouseConfig is the configuration of the ClickHouse sink, the [clickhouse] table
+type ClickHouseConfig struct {
+	Url       string   `toml:"url"`       // HTTP interface, e.g. http://localhost:8123. The sink is disabled if empty
+	Database  string   `toml:"database"`  // defaults to "connectors"
+	Table     string   `toml:"table"`     // defaults to "tick"
+	Username  string   `toml:"username"`  // defaults to the server's default user
+	Password  string   `toml:"password"`  //
+	Datasets  []string `toml:"datasets"`  // tick datasets written to ClickHouse instead of Postgres, e.g. ["coingecko.price_tick", "wise.rate_tick"]
+	BatchSize int      `toml:"batchSize"` // ticks per insert. Defaults to 50000
+	Timeout   string   `toml:"timeout"`   // Go duration of each request. Defaults to 60s
+}
+
+// Enabled returns true if a URL is configured
+func (c ClickHouseConfig) Enabled() bool {
+	return c.Url != ""
+}
+
+// WithDefaults returns c with defaults applied to unset fields
+func (c ClickHouseConfig) WithDefaults() ClickHouseConfig {
+
+	if c.Database == "" {
+		c.Database = defaultClickHouseDatabase
+	}
+	if c.Table == "" {
+		c.Table = defaultClickHouseTable
+	}
+	if c.BatchSize == 0 {
+		c.BatchSize = defaultClickHouseBatchSize
+	}
+
+	return c
+}
+
+// Validate returns an error if c, with defaults applied, is incomplete or invalid
+func (c ClickHouseConfig) Validate() error {
+
+	u, err := url.Parse(c.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an http(s) URL: %q", cerrors.ErrValidationFailed, c.Url)
+	}
+	if !clickHouseIdentifier.MatchString(c.Database) || !clickHouseIdentifier.MatchString(c.Table) {
+		return fmt.Errorf("%w: database and table must be plain identifiers", cerrors.ErrValidationFailed)
+	}
+	if len(c.Datasets) == 0 {
+		return fmt.Errorf("%w: datasets are required", cerrors.ErrValidationFailed)
+	}
+	if c.BatchSize < 1 {
+		return fmt.Errorf("%w: batchSize must be positive", cerrors.ErrValidationFailed)
+	}
+	if _, err := c.timeout(); err != nil {
+		return fmt.Errorf("%w: timeout: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	return nil
+}
+
+// timeout returns the parsed Timeout, or defaultClickHouseTimeout if not set
+func (c ClickHouseConfig) timeout() (time.Duration, error) {
+
+	if c.Timeout == "" {
+		return defaultClickHouseTimeout, nil
+	}
+	return time.ParseDuration(c.Timeout)
+}
+
+// ClickHouse writes ticks to a ReplacingMergeTree table over the ClickHouse HTTP interface
+// a tick written again for the same dataset, series and time replaces the earlier one when parts are merged, so query with FINAL for exact results
+type ClickHouse struct {
+	Config     ClickHouseConfig
+	HttpClient *http.Client
+	InfoLog    *slog.Logger
+	ErrorLog   *slog.Logger
+}
+
+// NewClickHouse returns the sink of conf. conf must be valid
+func NewClickHouse(conf ClickHouseConfig, infoLog, errorLog *slog.Logger) (*ClickHouse, error) {
+
+	timeout, err := conf.timeout()
+	if err != nil {
+		return nil, fmt.Errorf("conf.timeout failed: %w", err)
+	}
+
+	return &ClickHouse{
+		Config:     conf,
+		HttpClient: &http.Client{Timeout: timeout},
+		InfoLog:    infoLog.With("sink", ClickHouseName),
+		ErrorLog:   errorLog.With("sink", ClickHouseName),
+	}, nil
+}
+
+func (s *ClickHouse) Name() string {
+	return ClickHouseName
+}
+
+// Migrate creates the database and the tick table if they don't exist
+func (s *ClickHouse) Migrate(ctx context.Context) error {
+
+	if err := s.exec(ctx, "CREATE DATABASE IF NOT EXISTS "+s.Config.Database, nil); err != nil {
+		return fmt.Errorf("s.exec (create database) failed: %w", err)
+	}
+
+	stmt := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.%s
+(
+  dataset LowCardinality(String),
+  series LowCardinality(String),
+  at DateTime64(3, 'UTC'),
+  value Float64,
+  volume Float64,
+  inserted_at DateTime64(3, 'UTC') DEFAULT now64(3)
+)
+ENGINE = ReplacingMergeTree(inserted_at)
+PARTITION BY toYYYYMM(at)
+ORDER BY (dataset, series, at)`, s.Config.Database, s.Config.Table)
+
+	if err := s.exec(ctx, stmt, nil); err != nil {
+		return fmt.Errorf("s.exec (create table) failed: %w", err)
+	}
+
+	return nil
+}
+
+// WriteTicks inserts ticks in batches of Config.BatchSize
+func (s *ClickHouse) WriteTicks(ctx context.Context, dataset string, ticks []Tick) error {
+
+	stmt := fmt.Sprintf("INSERT INTO %s.%s (dataset, series, at, value, volume) FORMAT JSONEachRow", s.Config.Database, s.Config.Table)
+
+	for start := 0; start < len(ticks); start += s.Config.BatchSize {
+		end := min(start+s.Config.BatchSize, len(ticks))
+
+		body, err := ClickHouseRows(dataset, ticks[start:end])
+		if err != nil {
+			return fmt.Errorf("ClickHouseRows failed: %w", err)
+		}
+
+		if err = s.exec(ctx, stmt, body); err != nil {
+			return fmt.Errorf("s.exec failed for ticks %d to %d: %w", start+1, end, err)
+		}
+	}
+
+	s.InfoLog.Info("wrote ticks", clog.KeyDataset, dataset, clog.KeyCount, len(ticks))
+
+	return nil
+}
+
+// ClickHouseRows returns ticks of dataset as JSONEachRow lines
+func ClickHouseRows(dataset string, ticks []Tick) ([]byte, error) {
+
+	type row struct {
+		Dataset string  `json:"dataset"`
+		Series  string  `json:"series"`
+		At      string  `json:"at"`
+		Value   float64 `json:"value"`
+		Volume  float64 `json:"volume"`
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, t := range ticks {
+		err := enc.Encode(row{Dataset: dataset, Series: t.Series, At: t.At.UTC().Format(clickHouseTimeFormat), Value: t.Value, Volume: t.Volume})
+		if err != nil {
+			return nil, fmt.Errorf("enc.Encode failed: %w", err)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// exec runs query, with body as its data if not nil
+func (s *ClickHouse) exec(ctx context.Context, query string, body []byte) error {
+
+	params := url.Values{}
+	params.Set("query", query)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(s.Config.Url, "/")+"/?"+params.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	if s.Config.Username != "" {
+		req.Header.Set("X-ClickHouse-User", s.Config.Username)
+		req.Header.Set("X-ClickHouse-Key", s.Config.Password)
+	}
+
+	resp, err := s.HttpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("s.HttpClient.Do failed: %w", cerrors.UpstreamError{Source: ClickHouseName, Err: err})
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%w: %s", cerrors.UpstreamError{Source: ClickHouseName, StatusCode: resp.StatusCode}, strings.TrimSpace(string(respBody)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	return nil
+}
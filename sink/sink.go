@@ -0,0 +1,74 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Tick is an observation of an intraday series, e.g. a rate of a currency pair or a coin price at a point in time
+type Tick struct {
+	Series string    `json:"series"` // e.g. "EUR/USD" or "bitcoin/EUR"
+	At     time.Time `json:"at"`
+	Value  float64   `json:"value"`  // rate or price
+	Volume float64   `json:"volume"` // traded volume, if provided by the source
+}
+
+// Sink stores the ticks of high-volume datasets outside of the Postgres targets
+type Sink interface {
+	// Name identifies the sink in logs and journal params, e.g. "clickhouse"
+	Name() string
+
+	// WriteTicks stores ticks of dataset. Ticks already stored, by dataset, series and time, are replaced
+	WriteTicks(ctx context.Context, dataset string, ticks []Tick) error
+
+	// Migrate creates the tables of the sink if needed
+	Migrate(ctx context.Context) error
+}
+
+// Router maps tick datasets to the sink they are written to. Datasets without a route, and all datasets of a nil Router, are written to the Postgres targets
+type Router map[string]Sink
+
+// Route returns the sink of dataset, if any
+func (r Router) Route(dataset string) (s Sink, ok bool) {
+	s, ok = r[dataset]
+	return s, ok
+}
+
+// Sinks returns the distinct sinks of r
+func (r Router) Sinks() (sinks []Sink) {
+
+	seen := make(map[Sink]bool)
+	for _, s := range r {
+		if !seen[s] {
+			seen[s] = true
+			sinks = append(sinks, s)
+		}
+	}
+	return sinks
+}
+
+// NewRouter returns the router of the configured sinks, routing each of their datasets to them. It is nil if no sink is configured
+func NewRouter(clickHouse ClickHouseConfig, infoLog, errorLog *slog.Logger) (Router, error) {
+
+	if !clickHouse.Enabled() {
+		return nil, nil
+	}
+
+	clickHouse = clickHouse.WithDefaults()
+	if err := clickHouse.Validate(); err != nil {
+		return nil, fmt.Errorf("clickHouse.Validate failed: %w", err)
+	}
+	ch, err := NewClickHouse(clickHouse, infoLog, errorLog)
+	if err != nil {
+		return nil, fmt.Errorf("NewClickHouse failed: %w", err)
+	}
+
+	r := make(Router)
+	for _, dataset := range clickHouse.Datasets {
+		r[dataset] = ch
+	}
+
+	return r, nil
+}
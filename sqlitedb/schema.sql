@@ -0,0 +1,32 @@
+-- SQLite counterpart of the ecb schema, for use without Postgres. Postgres schemas become table name prefixes
+-- dates are stored as text in lystype.DateFormat and timestamps as text in sqlitedb.TimeFormat, so that they sort and compare as in Postgres
+
+CREATE TABLE IF NOT EXISTS ecb_currency
+(
+  id INTEGER PRIMARY KEY,
+  code TEXT NOT NULL UNIQUE,
+  name TEXT NOT NULL,
+  entry_at TEXT NOT NULL,
+  last_modified_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS ecb_exchange_rate
+(
+  id INTEGER PRIMARY KEY,
+  frequency TEXT NOT NULL CHECK (frequency IN ('D', 'M')),
+  day TEXT NOT NULL,
+  from_currency_fk INTEGER NOT NULL REFERENCES ecb_currency (id),
+  to_currency_fk INTEGER NOT NULL REFERENCES ecb_currency (id),
+  rate REAL NOT NULL,
+  entry_at TEXT NOT NULL,
+  last_modified_at TEXT NOT NULL,
+  UNIQUE (frequency, day, from_currency_fk, to_currency_fk)
+);
+
+CREATE INDEX IF NOT EXISTS ecb_exchange_rate_frequency_day_idx ON ecb_exchange_rate (frequency, day);
+
+CREATE VIEW IF NOT EXISTS v_ecb_exchange_rate AS
+SELECT er.id, er.frequency, er.day, er.from_currency_fk, fc.code AS from_currency, er.to_currency_fk, tc.code AS to_currency, er.rate, er.entry_at, er.last_modified_at
+FROM ecb_exchange_rate er
+JOIN ecb_currency fc ON fc.id = er.from_currency_fk
+JOIN ecb_currency tc ON tc.id = er.to_currency_fk;
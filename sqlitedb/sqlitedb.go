@@ -0,0 +1,60 @@
+// Package sqlitedb opens SQLite databases for the SQLite stores, such as ecbcurrency.SQLiteStore, for tools that need the ECB data without Postgres
+// it uses database/sql and does not import a driver: register one in main, e.g. modernc.org/sqlite (driver name "sqlite") or github.com/mattn/go-sqlite3 ("sqlite3")
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"time"
+)
+
+// TimeFormat is the text format of timestamps. Fixed width UTC, so that timestamps sort and compare as text
+const TimeFormat string = "2006-01-02T15:04:05.000000Z"
+
+//go:embed schema.sql
+var schema string
+
+// Open opens the SQLite database dataSourceName with the registered driver driverName, e.g. a file path or ":memory:", and creates the schema if needed
+// the returned db uses a single connection: SQLite serializes writes anyway, and each connection to ":memory:" would open a separate database
+func Open(ctx context.Context, driverName, dataSourceName string) (*sql.DB, error) {
+
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, fmt.Errorf("sql.Open failed: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err = db.ExecContext(ctx, "PRAGMA foreign_keys = ON;"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("db.ExecContext (foreign_keys) failed: %w", err)
+	}
+
+	if err = CreateSchema(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("CreateSchema failed: %w", err)
+	}
+
+	return db, nil
+}
+
+// CreateSchema creates the tables of the SQLite stores in db if they don't exist
+func CreateSchema(ctx context.Context, db *sql.DB) error {
+
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("db.ExecContext failed: %w", err)
+	}
+
+	return nil
+}
+
+// FormatTime returns t as stored
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(TimeFormat)
+}
+
+// ParseTime parses a stored timestamp
+func ParseTime(s string) (time.Time, error) {
+	return time.Parse(TimeFormat, s)
+}
@@ -0,0 +1,142 @@
+package amzorderheader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "Amazon orders"
+	schemaName     string = "amzorder"
+	tableName      string = "sales_order"
+	viewName       string = "sales_order"
+	pkColName      string = "id"
+	defaultOrderBy string = "purchase_at DESC"
+)
+
+type Input struct {
+	AmazonOrderId      string           `db:"amazon_order_id" json:"amazon_order_id,omitempty" validate:"required"`
+	BuyerEmail         string           `db:"buyer_email" json:"buyer_email"`
+	BuyerName          string           `db:"buyer_name" json:"buyer_name"`
+	Currency           string           `db:"currency" json:"currency"`
+	FulfillmentChannel string           `db:"fulfillment_channel" json:"fulfillment_channel"`
+	ItemsShipped       int              `db:"items_shipped" json:"items_shipped"`
+	ItemsUnshipped     int              `db:"items_unshipped" json:"items_unshipped"`
+	LastModifiedAt     lystype.Datetime `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	LastUpdateAt       lystype.Datetime `db:"last_update_at" json:"last_update_at,omitempty" validate:"required"`
+	MarketplaceId      string           `db:"marketplace_id" json:"marketplace_id,omitempty" validate:"required"`
+	OrderStatus        string           `db:"order_status" json:"order_status,omitempty" validate:"required"`
+	OrderTotal         *float64         `db:"order_total" json:"order_total,omitempty"`
+	PurchaseAt         lystype.Datetime `db:"purchase_at" json:"purchase_at,omitempty" validate:"required"`
+	SalesChannel       string           `db:"sales_channel" json:"sales_channel"`
+	ShipCity           string           `db:"ship_city" json:"ship_city"`
+	ShipCountry        string           `db:"ship_country" json:"ship_country"`
+	ShipPostalCode     string           `db:"ship_postal_code" json:"ship_postal_code"`
+}
+
+type Model struct {
+	Id      int64            `db:"id" json:"id"`
+	EntryAt lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	Input
+}
+
+var (
+	meta, inputMeta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+	inputMeta, _ = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem())
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id))
+}
+
+// Equal compares the Amazon update timestamps, and the buyer details, which are only returned with a restricted data token
+func (s Store) Equal(a, b Model) bool {
+	return time.Time(a.LastUpdateAt).Equal(time.Time(b.LastUpdateAt)) && a.BuyerEmail == b.BuyerEmail && a.BuyerName == b.BuyerName
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
+
+// SelectMapByNaturalKey returns the orders with amazonOrderIds, with AmazonOrderId as key
+func (s Store) SelectMapByNaturalKey(ctx context.Context, amazonOrderIds []string) (itemsMap map[string]Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	itemsMap = make(map[string]Model)
+	if len(amazonOrderIds) == 0 {
+		return itemsMap, nil
+	}
+
+	items, _, err := s.Select(ctx, lyspg.SelectParams{Conditions: []lyspg.Condition{{Field: "amazon_order_id", Operator: lyspg.OpIn, InValues: amazonOrderIds}}})
+	if err != nil {
+		return nil, fmt.Errorf("s.Select failed: %w", err)
+	}
+
+	for _, item := range items {
+		itemsMap[item.AmazonOrderId] = item
+	}
+
+	return itemsMap, nil
+}
+
+func (s Store) Update(ctx context.Context, input Input, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	input.LastModifiedAt = lystype.Datetime(time.Now())
+	return cerrors.FromPg(lyspg.Update[Input](ctx, s.Db, schemaName, tableName, pkColName, input, id))
+}
+
+func (s Store) Validate(validate *validator.Validate, input Input) error {
+	return lysmeta.Validate[Input](validate, input)
+}
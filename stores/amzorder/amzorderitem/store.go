@@ -0,0 +1,154 @@
+package amzorderitem
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "Amazon order items"
+	schemaName     string = "amzorder"
+	tableName      string = "order_item"
+	viewName       string = "v_order_item"
+	pkColName      string = "id"
+	defaultOrderBy string = "purchase_at DESC, order_item_id"
+)
+
+type Input struct {
+	Asin              string           `db:"asin" json:"asin,omitempty" validate:"required"`
+	Currency          string           `db:"currency" json:"currency"`
+	ItemPrice         *float64         `db:"item_price" json:"item_price,omitempty"`
+	ItemTax           *float64         `db:"item_tax" json:"item_tax,omitempty"`
+	LastModifiedAt    lystype.Datetime `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	OrderFk           int64            `db:"order_fk" json:"order_fk,omitempty" validate:"required"`
+	OrderItemId       string           `db:"order_item_id" json:"order_item_id,omitempty" validate:"required"`
+	PromotionDiscount *float64         `db:"promotion_discount" json:"promotion_discount,omitempty"`
+	QuantityOrdered   int              `db:"quantity_ordered" json:"quantity_ordered"`
+	QuantityShipped   int              `db:"quantity_shipped" json:"quantity_shipped"`
+	SellerSku         string           `db:"seller_sku" json:"seller_sku"`
+	Title             string           `db:"title" json:"title"`
+}
+
+type Model struct {
+	Id            int64            `db:"id" json:"id"`
+	AmazonOrderId string           `db:"amazon_order_id" json:"amazon_order_id,omitempty"`
+	EntryAt       lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	MarketplaceId string           `db:"marketplace_id" json:"marketplace_id,omitempty"`
+	PurchaseAt    lystype.Datetime `db:"purchase_at" json:"purchase_at,omitempty"`
+	Input
+}
+
+var (
+	meta, inputMeta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+	inputMeta, _ = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem())
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) BulkInsert(ctx context.Context, inputs []Input) (rowsAffected int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	rowsAffected, err = lyspg.BulkInsert[Input](ctx, s.Db, schemaName, tableName, inputs)
+	if err != nil {
+		return 0, cerrors.FromPg(err)
+	}
+	return rowsAffected, cruntime.AnalyzeAfterLoad(ctx, s.Db, schemaName, tableName, rowsAffected)
+}
+
+func (s Store) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id))
+}
+
+func (s Store) Equal(a, b Model) bool {
+	return a.Asin == b.Asin && a.SellerSku == b.SellerSku && a.Title == b.Title && a.QuantityOrdered == b.QuantityOrdered && a.QuantityShipped == b.QuantityShipped &&
+		a.Currency == b.Currency && equalAmount(a.ItemPrice, b.ItemPrice) && equalAmount(a.ItemTax, b.ItemTax) && equalAmount(a.PromotionDiscount, b.PromotionDiscount)
+}
+
+// equalAmount returns true if a and b are both nil or equal
+func equalAmount(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
+
+// SelectMapByNaturalKey returns the items of order orderFk, with OrderItemId as key
+func (s Store) SelectMapByNaturalKey(ctx context.Context, orderFk int64) (itemsMap map[string]Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	items, _, err := s.Select(ctx, lyspg.SelectParams{Conditions: []lyspg.Condition{{Field: "order_fk", Operator: lyspg.OpEquals, Value: fmt.Sprint(orderFk)}}})
+	if err != nil {
+		return nil, fmt.Errorf("s.Select failed: %w", err)
+	}
+
+	itemsMap = make(map[string]Model)
+	for _, item := range items {
+		itemsMap[item.OrderItemId] = item
+	}
+
+	return itemsMap, nil
+}
+
+func (s Store) Update(ctx context.Context, input Input, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	input.LastModifiedAt = lystype.Datetime(time.Now())
+	return cerrors.FromPg(lyspg.Update[Input](ctx, s.Db, schemaName, tableName, pkColName, input, id))
+}
+
+func (s Store) Validate(validate *validator.Validate, input Input) error {
+	return lysmeta.Validate[Input](validate, input)
+}
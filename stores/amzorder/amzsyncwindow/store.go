@@ -0,0 +1,63 @@
+package amzsyncwindow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+)
+
+const (
+	name       string = "Amazon order sync windows"
+	schemaName string = "amzorder"
+	tableName  string = "sync_window"
+)
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) GetName() string {
+	return name
+}
+
+// SelectLastUpdatedBefore returns the end of the last synced window of marketplaceId, or the zero time if none
+func (s Store) SelectLastUpdatedBefore(ctx context.Context, marketplaceId string) (lastUpdatedBefore time.Time, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf("SELECT last_updated_before FROM %s.%s WHERE marketplace_id = $1;", schemaName, tableName)
+
+	err = s.Db.QueryRow(ctx, stmt, marketplaceId).Scan(&lastUpdatedBefore)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("s.Db.QueryRow failed: %w", cerrors.FromPg(err))
+	}
+
+	return lastUpdatedBefore, nil
+}
+
+// SetLastUpdatedBefore stores lastUpdatedBefore as the end of the last synced window of marketplaceId
+func (s Store) SetLastUpdatedBefore(ctx context.Context, marketplaceId string, lastUpdatedBefore time.Time) error {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`INSERT INTO %s.%s (marketplace_id, last_updated_before) VALUES ($1, $2)
+		ON CONFLICT (marketplace_id) DO UPDATE SET last_updated_before = EXCLUDED.last_updated_before, last_modified_at = now();`, schemaName, tableName)
+
+	if _, err := s.Db.Exec(ctx, stmt, marketplaceId, lastUpdatedBefore); err != nil {
+		return fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return nil
+}
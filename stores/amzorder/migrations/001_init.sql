@@ -0,0 +1,100 @@
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA amzorder TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA amzorder GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA amzorder GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS amzorder;
+
+
+CREATE TABLE amzorder.sales_order
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  amazon_order_id text NOT NULL UNIQUE, -- e.g. 303-1234567-1234567
+  marketplace_id text NOT NULL, -- e.g. A1PA6795UKMFR9 for amazon.de
+  order_status text NOT NULL, -- e.g. Pending, Unshipped, Shipped, Canceled
+  fulfillment_channel text NOT NULL DEFAULT '', -- AFN (by Amazon) or MFN (by the seller)
+  sales_channel text NOT NULL DEFAULT '', -- e.g. Amazon.de
+  currency text NOT NULL DEFAULT '',
+  order_total numeric, -- null while pending
+  items_shipped int NOT NULL DEFAULT 0,
+  items_unshipped int NOT NULL DEFAULT 0,
+  ship_city text NOT NULL DEFAULT '',
+  ship_postal_code text NOT NULL DEFAULT '',
+  ship_country text NOT NULL DEFAULT '',
+  buyer_email text NOT NULL DEFAULT '', -- only with a restricted data token
+  buyer_name text NOT NULL DEFAULT '', -- only with a restricted data token
+  purchase_at timestamp with time zone NOT NULL,
+  last_update_at timestamp with time zone NOT NULL,
+  entry_at tracking_at,
+  last_modified_at tracking_at
+);
+COMMENT ON TABLE amzorder.sales_order IS 'shortname: amzo';
+
+CREATE INDEX ON amzorder.sales_order (marketplace_id, purchase_at);
+
+
+CREATE TABLE amzorder.order_item
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  order_fk bigint NOT NULL REFERENCES amzorder.sales_order(id) ON DELETE CASCADE,
+  order_item_id text NOT NULL,
+  asin text NOT NULL,
+  seller_sku text NOT NULL DEFAULT '',
+  title text NOT NULL DEFAULT '',
+  quantity_ordered int NOT NULL,
+  quantity_shipped int NOT NULL DEFAULT 0,
+  currency text NOT NULL DEFAULT '',
+  item_price numeric,
+  item_tax numeric,
+  promotion_discount numeric,
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  UNIQUE (order_fk, order_item_id)
+);
+COMMENT ON TABLE amzorder.order_item IS 'shortname: amzoi';
+
+CREATE INDEX ON amzorder.order_item (seller_sku);
+
+
+-- end of the last synced LastUpdatedAfter window per marketplace, from which the next sync continues
+CREATE TABLE amzorder.sync_window
+(	
+  marketplace_id text PRIMARY KEY,
+  last_updated_before timestamp with time zone NOT NULL,
+  entry_at tracking_at,
+  last_modified_at tracking_at
+);
+COMMENT ON TABLE amzorder.sync_window IS 'shortname: amzsw';
+
+
+CREATE VIEW amzorder.v_order_item AS
+  SELECT
+    amzo.amazon_order_id,
+    amzoi.asin,
+    amzoi.currency,
+    amzoi.entry_at,
+    amzoi.id,
+    amzoi.item_price,
+    amzoi.item_tax,
+    amzoi.last_modified_at,
+    amzo.marketplace_id,
+    amzoi.order_fk,
+    amzoi.order_item_id,
+    amzoi.promotion_discount,
+    amzo.purchase_at,
+    amzoi.quantity_ordered,
+    amzoi.quantity_shipped,
+    amzoi.seller_sku,
+    amzoi.title
+  FROM amzorder.order_item amzoi
+  JOIN amzorder.sales_order amzo ON amzoi.order_fk = amzo.id;
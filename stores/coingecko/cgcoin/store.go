@@ -0,0 +1,153 @@
+package cgcoin
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "CoinGecko coins"
+	schemaName     string = "coingecko"
+	tableName      string = "coin"
+	viewName       string = "coin"
+	pkColName      string = "id"
+	defaultOrderBy string = "code"
+)
+
+type Input struct {
+	Code           string           `db:"code" json:"code,omitempty" validate:"required"`
+	LastModifiedAt lystype.Datetime `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	Name           string           `db:"name" json:"name,omitempty" validate:"required"`
+	Symbol         string           `db:"symbol" json:"symbol,omitempty" validate:"required"`
+}
+
+type Model struct {
+	Id      int64            `db:"id" json:"id"`
+	EntryAt lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	Input
+}
+
+var (
+	meta, inputMeta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+	inputMeta, _ = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem())
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) Count(ctx context.Context) (count int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.GetRowCount(ctx, s.Db, schemaName, tableName)
+}
+
+func (s Store) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id))
+}
+
+func (s Store) Equal(a, b Model) bool {
+	return a.Name == b.Name && a.Symbol == b.Symbol
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
+
+// SelectMapByNaturalKey returns the coins with codes, or all coins if codes is empty, with Code as key
+func (s Store) SelectMapByNaturalKey(ctx context.Context, codes []string) (itemsMap map[string]Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	params := lyspg.SelectParams{}
+	if len(codes) > 0 {
+		params.Conditions = []lyspg.Condition{{Field: "code", Operator: lyspg.OpIn, InValues: codes}}
+	}
+
+	items, _, err := s.Select(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("s.Select failed: %w", err)
+	}
+
+	itemsMap = make(map[string]Model)
+	for _, item := range items {
+		itemsMap[item.Code] = item
+	}
+
+	return itemsMap, nil
+}
+
+func (s Store) SelectCodeIdMap(ctx context.Context) (codeIdMap map[string]int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	items, _, err := s.Select(ctx, lyspg.SelectParams{})
+	if err != nil {
+		return nil, fmt.Errorf("s.Select failed: %w", err)
+	}
+
+	codeIdMap = make(map[string]int64)
+	for _, item := range items {
+		codeIdMap[item.Code] = item.Id
+	}
+
+	return codeIdMap, nil
+}
+
+func (s Store) Update(ctx context.Context, input Input, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	input.LastModifiedAt = lystype.Datetime(time.Now())
+	return cerrors.FromPg(lyspg.Update[Input](ctx, s.Db, schemaName, tableName, pkColName, input, id))
+}
+
+func (s Store) Validate(validate *validator.Validate, input Input) error {
+	return lysmeta.Validate[Input](validate, input)
+}
@@ -0,0 +1,217 @@
+package cgprice
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "CoinGecko prices"
+	schemaName     string = "coingecko"
+	tableName      string = "price"
+	viewName       string = "v_price"
+	pkColName      string = "id"
+	defaultOrderBy string = "id"
+)
+
+// selectSymbolPricesStmt is constant so that pgx's default statement cache prepares it once per connection for the converter's lookups
+const selectSymbolPricesStmt string = `SELECT DISTINCT ON (symbol) symbol, price FROM ` + schemaName + `.` + viewName + `
+	WHERE vs_currency = $1 AND day <= $2 AND day >= $2::date - $3::int
+	ORDER BY symbol, day DESC;`
+
+type Input struct {
+	CoinFk         int64            `db:"coin_fk" json:"coin_fk,omitempty" validate:"required"`
+	Day            lystype.Date     `db:"day" json:"day,omitempty" validate:"required"`
+	LastModifiedAt lystype.Datetime `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	MarketCap      float64          `db:"market_cap" json:"market_cap"`
+	Price          float64          `db:"price" json:"price,omitempty" validate:"required"`
+	TotalVolume    float64          `db:"total_volume" json:"total_volume"`
+	VsCurrency     string           `db:"vs_currency" json:"vs_currency,omitempty" validate:"required"`
+}
+
+type Model struct {
+	Id      int64            `db:"id" json:"id"`
+	Coin    string           `db:"coin" json:"coin"`
+	EntryAt lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	Symbol  string           `db:"symbol" json:"symbol"`
+	Input
+}
+
+var (
+	meta, inputMeta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+	inputMeta, _ = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem())
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+// BulkDelete deletes the items of ids in a single round trip
+func (s Store) BulkDelete(ctx context.Context, ids []int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	if len(ids) == 0 {
+		return nil
+	}
+	return cerrors.FromPg(lyspg.BulkDelete(ctx, s.Db, schemaName, tableName, pkColName, ids))
+}
+
+func (s Store) BulkInsert(ctx context.Context, inputs []Input) (rowsAffected int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	rowsAffected, err = lyspg.BulkInsert[Input](ctx, s.Db, schemaName, tableName, inputs)
+	if err != nil {
+		return 0, cerrors.FromPg(err)
+	}
+	return rowsAffected, cruntime.AnalyzeAfterLoad(ctx, s.Db, schemaName, tableName, rowsAffected)
+}
+
+// BulkUpdate updates the items of ids with the inputs at the same index in a single round trip. The inputs are not modified
+func (s Store) BulkUpdate(ctx context.Context, inputs []Input, ids []int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	if len(inputs) == 0 {
+		return nil
+	}
+	now := lystype.Datetime(time.Now())
+	inputs = slices.Clone(inputs)
+	for i := range inputs {
+		inputs[i].LastModifiedAt = now
+	}
+	return cerrors.FromPg(lyspg.BulkUpdate[Input](ctx, s.Db, schemaName, tableName, pkColName, inputs, ids))
+}
+
+func (s Store) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id))
+}
+
+func (s Store) Equal(a, b Model) bool {
+	return a.Price == b.Price && a.MarketCap == b.MarketCap && a.TotalVolume == b.TotalVolume
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
+
+// SelectInRange returns the prices of coin in vsCurr between startDate and endDate (inclusive), ordered by day
+func (s Store) SelectInRange(ctx context.Context, coin, vsCurr string, startDate, endDate time.Time) (items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	items, _, err = s.Select(ctx, lyspg.SelectParams{
+		Conditions: []lyspg.Condition{
+			{Field: "coin", Operator: lyspg.OpEquals, Value: coin},
+			{Field: "vs_currency", Operator: lyspg.OpEquals, Value: vsCurr},
+			{Field: "day", Operator: lyspg.OpGreaterThanEquals, Value: startDate.Format(lystype.DateFormat)},
+			{Field: "day", Operator: lyspg.OpLessThanEquals, Value: endDate.Format(lystype.DateFormat)},
+		},
+		Sorts: []string{"day"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s.Select failed: %w", err)
+	}
+
+	return items, nil
+}
+
+// SelectMapByNaturalKey is like SelectInRange, but returns a map with day as key
+func (s Store) SelectMapByNaturalKey(ctx context.Context, coin, vsCurr string, startDate, endDate time.Time) (itemsMap map[string]Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	items, err := s.SelectInRange(ctx, coin, vsCurr, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("s.SelectInRange failed: %w", err)
+	}
+
+	itemsMap = make(map[string]Model)
+	for _, dbItem := range items {
+		itemsMap[dbItem.Day.Format(lystype.DateFormat)] = dbItem
+	}
+
+	return itemsMap, nil
+}
+
+// SelectSymbolPrices returns the price in vsCurr of each coin on day, or on the most recent day up to maxFallbackDays before it, with symbol as key
+func (s Store) SelectSymbolPrices(ctx context.Context, vsCurr string, day time.Time, maxFallbackDays int) (prices map[string]float64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	rows, _ := s.Db.Query(ctx, selectSymbolPricesStmt, vsCurr, day.Format(lystype.DateFormat), maxFallbackDays)
+	type symbolPrice struct {
+		Symbol string  `db:"symbol"`
+		Price  float64 `db:"price"`
+	}
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByName[symbolPrice])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	prices = make(map[string]float64, len(items))
+	for _, item := range items {
+		prices[item.Symbol] = item.Price
+	}
+
+	return prices, nil
+}
+
+func (s Store) Update(ctx context.Context, input Input, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	input.LastModifiedAt = lystype.Datetime(time.Now())
+	return cerrors.FromPg(lyspg.Update[Input](ctx, s.Db, schemaName, tableName, pkColName, input, id))
+}
+
+func (s Store) Validate(validate *validator.Validate, input Input) error {
+	return lysmeta.Validate[Input](validate, input)
+}
@@ -0,0 +1,63 @@
+
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA coingecko TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA coingecko GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA coingecko GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS coingecko;
+
+
+CREATE TABLE coingecko.coin
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  code text NOT NULL UNIQUE, -- natural key: CoinGecko coin id, e.g. bitcoin
+  symbol text NOT NULL, -- upper case, e.g. BTC. Used as currency code by the converter
+  name text NOT NULL
+);
+COMMENT ON TABLE coingecko.coin IS 'shortname: cgc';
+
+
+-- daily prices: the last quote of each UTC day, so the current day is updated until it ends
+CREATE TABLE coingecko.price
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  coin_fk bigint NOT NULL REFERENCES coingecko.coin(id) ON DELETE CASCADE,
+  vs_currency text NOT NULL, -- upper case, e.g. EUR
+  day date NOT NULL,
+  price numeric NOT NULL,
+  market_cap numeric NOT NULL,
+  total_volume numeric NOT NULL,
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  UNIQUE (coin_fk, vs_currency, day)
+);
+COMMENT ON TABLE coingecko.price IS 'shortname: cgp';
+
+
+CREATE VIEW coingecko.v_price AS
+  SELECT
+    cgc.code AS coin,
+    cgp.coin_fk,
+    cgp.day,
+    cgp.entry_at,
+    cgp.id,
+    cgp.last_modified_at,
+    cgp.market_cap,
+    cgp.price,
+    cgc.symbol,
+    cgp.total_volume,
+    cgp.vs_currency
+  FROM coingecko.price cgp
+  JOIN coingecko.coin cgc ON cgp.coin_fk = cgc.id;
@@ -0,0 +1,139 @@
+package chcompany
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "Companies House companies"
+	schemaName     string = "companieshouse"
+	tableName      string = "company"
+	viewName       string = "company"
+	pkColName      string = "id"
+	defaultOrderBy string = "company_number"
+)
+
+type Input struct {
+	AddressCountry    string           `db:"address_country" json:"address_country"`
+	AddressLine       string           `db:"address_line" json:"address_line"`
+	AddressLocality   string           `db:"address_locality" json:"address_locality"`
+	AddressPostalCode string           `db:"address_postal_code" json:"address_postal_code"`
+	CeasedOn          *lystype.Date    `db:"ceased_on" json:"ceased_on,omitempty"`
+	CompanyName       string           `db:"company_name" json:"company_name,omitempty" validate:"required"`
+	CompanyNumber     string           `db:"company_number" json:"company_number,omitempty" validate:"required,len=8"`
+	CompanyStatus     string           `db:"company_status" json:"company_status,omitempty" validate:"required"`
+	CompanyType       string           `db:"company_type" json:"company_type,omitempty" validate:"required"`
+	CreatedOn         *lystype.Date    `db:"created_on" json:"created_on,omitempty"`
+	Etag              string           `db:"etag" json:"etag"`
+	Jurisdiction      string           `db:"jurisdiction" json:"jurisdiction"`
+	LastModifiedAt    lystype.Datetime `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	SicCodes          []string         `db:"sic_codes" json:"sic_codes"`
+}
+
+type Model struct {
+	Id      int64            `db:"id" json:"id"`
+	EntryAt lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	Input
+}
+
+var (
+	meta, inputMeta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+	inputMeta, _ = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem())
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id))
+}
+
+// Equal compares etags: Companies House changes the etag whenever a profile changes
+func (s Store) Equal(a, b Model) bool {
+	return a.Etag == b.Etag
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
+
+// SelectMapByNaturalKey returns the companies with numbers, with CompanyNumber as key
+func (s Store) SelectMapByNaturalKey(ctx context.Context, numbers []string) (itemsMap map[string]Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	itemsMap = make(map[string]Model)
+	if len(numbers) == 0 {
+		return itemsMap, nil
+	}
+
+	items, _, err := s.Select(ctx, lyspg.SelectParams{Conditions: []lyspg.Condition{{Field: "company_number", Operator: lyspg.OpIn, InValues: numbers}}})
+	if err != nil {
+		return nil, fmt.Errorf("s.Select failed: %w", err)
+	}
+
+	for _, item := range items {
+		itemsMap[item.CompanyNumber] = item
+	}
+
+	return itemsMap, nil
+}
+
+func (s Store) Update(ctx context.Context, input Input, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	input.LastModifiedAt = lystype.Datetime(time.Now())
+	return cerrors.FromPg(lyspg.Update[Input](ctx, s.Db, schemaName, tableName, pkColName, input, id))
+}
+
+func (s Store) Validate(validate *validator.Validate, input Input) error {
+	return lysmeta.Validate[Input](validate, input)
+}
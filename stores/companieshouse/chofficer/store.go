@@ -0,0 +1,186 @@
+package chofficer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "Companies House officers"
+	schemaName     string = "companieshouse"
+	tableName      string = "officer"
+	viewName       string = "v_officer"
+	pkColName      string = "id"
+	defaultOrderBy string = "id"
+)
+
+type Input struct {
+	AppointedOn        *lystype.Date    `db:"appointed_on" json:"appointed_on,omitempty"`
+	CompanyFk          int64            `db:"company_fk" json:"company_fk,omitempty" validate:"required"`
+	CountryOfResidence string           `db:"country_of_residence" json:"country_of_residence"`
+	LastModifiedAt     lystype.Datetime `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	Name               string           `db:"name" json:"name,omitempty" validate:"required"`
+	Nationality        string           `db:"nationality" json:"nationality"`
+	Occupation         string           `db:"occupation" json:"occupation"`
+	OfficerId          string           `db:"officer_id" json:"officer_id,omitempty" validate:"required"`
+	OfficerRole        string           `db:"officer_role" json:"officer_role,omitempty" validate:"required"`
+	ResignedOn         *lystype.Date    `db:"resigned_on" json:"resigned_on,omitempty"`
+}
+
+type Model struct {
+	Id            int64            `db:"id" json:"id"`
+	CompanyName   string           `db:"company_name" json:"company_name,omitempty"`
+	CompanyNumber string           `db:"company_number" json:"company_number,omitempty"`
+	EntryAt       lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	Input
+}
+
+var (
+	meta, inputMeta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+	inputMeta, _ = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem())
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+// BulkDelete deletes the items of ids in a single round trip
+func (s Store) BulkDelete(ctx context.Context, ids []int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	if len(ids) == 0 {
+		return nil
+	}
+	return cerrors.FromPg(lyspg.BulkDelete(ctx, s.Db, schemaName, tableName, pkColName, ids))
+}
+
+func (s Store) BulkInsert(ctx context.Context, inputs []Input) (rowsAffected int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	rowsAffected, err = lyspg.BulkInsert[Input](ctx, s.Db, schemaName, tableName, inputs)
+	if err != nil {
+		return 0, cerrors.FromPg(err)
+	}
+	return rowsAffected, cruntime.AnalyzeAfterLoad(ctx, s.Db, schemaName, tableName, rowsAffected)
+}
+
+// BulkUpdate updates the items of ids with the inputs at the same index in a single round trip. The inputs are not modified
+func (s Store) BulkUpdate(ctx context.Context, inputs []Input, ids []int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	if len(inputs) == 0 {
+		return nil
+	}
+	now := lystype.Datetime(time.Now())
+	inputs = slices.Clone(inputs)
+	for i := range inputs {
+		inputs[i].LastModifiedAt = now
+	}
+	return cerrors.FromPg(lyspg.BulkUpdate[Input](ctx, s.Db, schemaName, tableName, pkColName, inputs, ids))
+}
+
+func (s Store) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id))
+}
+
+func (s Store) Equal(a, b Model) bool {
+	return a.Name == b.Name && a.Nationality == b.Nationality && a.Occupation == b.Occupation && a.CountryOfResidence == b.CountryOfResidence &&
+		equalDate(a.ResignedOn, b.ResignedOn)
+}
+
+// equalDate returns true if a and b are both nil or the same day
+func equalDate(a, b *lystype.Date) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return time.Time(*a).Equal(time.Time(*b))
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
+
+// SelectMapByNaturalKey returns the officers of companyFk, with officerId+role+appointedOn as key
+func (s Store) SelectMapByNaturalKey(ctx context.Context, companyFk int64) (itemsMap map[string]Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	items, _, err := s.Select(ctx, lyspg.SelectParams{Conditions: []lyspg.Condition{{Field: "company_fk", Operator: lyspg.OpEquals, Value: fmt.Sprint(companyFk)}}})
+	if err != nil {
+		return nil, fmt.Errorf("s.Select failed: %w", err)
+	}
+
+	itemsMap = make(map[string]Model)
+	for _, item := range items {
+		itemsMap[NaturalKey(item.OfficerId, item.OfficerRole, item.AppointedOn)] = item
+	}
+
+	return itemsMap, nil
+}
+
+// NaturalKey returns the map key used by SelectMapByNaturalKey. company_fk is not part of it, since maps hold a single company
+func NaturalKey(officerId, role string, appointedOn *lystype.Date) string {
+	day := ""
+	if appointedOn != nil {
+		day = appointedOn.Format(lystype.DateFormat)
+	}
+	return officerId + "_" + role + "_" + day
+}
+
+func (s Store) Update(ctx context.Context, input Input, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	input.LastModifiedAt = lystype.Datetime(time.Now())
+	return cerrors.FromPg(lyspg.Update[Input](ctx, s.Db, schemaName, tableName, pkColName, input, id))
+}
+
+func (s Store) Validate(validate *validator.Validate, input Input) error {
+	return lysmeta.Validate[Input](validate, input)
+}
@@ -0,0 +1,63 @@
+package chstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+)
+
+const (
+	name       string = "Companies House stream positions"
+	schemaName string = "companieshouse"
+	tableName  string = "stream_position"
+)
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) GetName() string {
+	return name
+}
+
+// SelectTimepoint returns the stored timepoint of stream, or 0 if none
+func (s Store) SelectTimepoint(ctx context.Context, stream string) (timepoint int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf("SELECT timepoint FROM %s.%s WHERE stream = $1;", schemaName, tableName)
+
+	err = s.Db.QueryRow(ctx, stmt, stream).Scan(&timepoint)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.QueryRow failed: %w", cerrors.FromPg(err))
+	}
+
+	return timepoint, nil
+}
+
+// SetTimepoint stores timepoint as the position of stream
+func (s Store) SetTimepoint(ctx context.Context, stream string, timepoint int64) error {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`INSERT INTO %s.%s (stream, timepoint) VALUES ($1, $2)
+		ON CONFLICT (stream) DO UPDATE SET timepoint = EXCLUDED.timepoint, last_modified_at = now();`, schemaName, tableName)
+
+	if _, err := s.Db.Exec(ctx, stmt, stream, timepoint); err != nil {
+		return fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return nil
+}
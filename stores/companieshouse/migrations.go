@@ -0,0 +1,8 @@
+package companieshouse
+
+import "embed"
+
+// Migrations is an embedded filesystem containing the SQL migrations of the companieshouse schema, applied in file name order
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
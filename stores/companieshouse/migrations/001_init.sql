@@ -0,0 +1,92 @@
+
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA companieshouse TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA companieshouse GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA companieshouse GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS companieshouse;
+
+
+-- profiles of the watched companies
+CREATE TABLE companieshouse.company
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  company_number text NOT NULL UNIQUE, -- natural key, e.g. 00000006
+  company_name text NOT NULL,
+  company_status text NOT NULL, -- e.g. active, dissolved, liquidation
+  company_type text NOT NULL, -- e.g. ltd, plc, llp
+  jurisdiction text NOT NULL, -- e.g. england-wales, scotland
+  created_on date,
+  ceased_on date,
+  address_line text NOT NULL, -- registered office address
+  address_locality text NOT NULL,
+  address_postal_code text NOT NULL,
+  address_country text NOT NULL,
+  sic_codes text[] NOT NULL DEFAULT '{}',
+  etag text NOT NULL -- changes whenever the profile changes
+);
+COMMENT ON TABLE companieshouse.company IS 'shortname: chc';
+
+
+CREATE TABLE companieshouse.officer
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  company_fk bigint NOT NULL REFERENCES companieshouse.company(id) ON DELETE CASCADE,
+  officer_id text NOT NULL, -- from the officer's appointments link
+  officer_role text NOT NULL, -- e.g. director, secretary
+  appointed_on date,
+  resigned_on date,
+  name text NOT NULL,
+  nationality text NOT NULL,
+  occupation text NOT NULL,
+  country_of_residence text NOT NULL
+);
+COMMENT ON TABLE companieshouse.officer IS 'shortname: cho';
+
+-- appointed_on is missing for some old appointments
+CREATE UNIQUE INDEX officer_natural_key_idx ON companieshouse.officer (company_fk, officer_id, officer_role, COALESCE(appointed_on, '0001-01-01'));
+
+
+CREATE VIEW companieshouse.v_officer AS
+  SELECT
+    cho.appointed_on,
+    cho.company_fk,
+    chc.company_name,
+    chc.company_number,
+    cho.country_of_residence,
+    cho.entry_at,
+    cho.id,
+    cho.last_modified_at,
+    cho.name,
+    cho.nationality,
+    cho.occupation,
+    cho.officer_id,
+    cho.officer_role,
+    cho.resigned_on
+  FROM companieshouse.officer cho
+  JOIN companieshouse.company chc ON cho.company_fk = chc.id;
+
+
+-- position in each streaming API stream, from which the next sync resumes
+CREATE TABLE companieshouse.stream_position
+(	
+  stream text PRIMARY KEY, -- e.g. companies
+  timepoint bigint NOT NULL,
+  entry_at tracking_at,
+  last_modified_at tracking_at
+);
+COMMENT ON TABLE companieshouse.stream_position IS 'shortname: chsp';
@@ -0,0 +1,190 @@
+package change
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+)
+
+const (
+	name        string = "Changes"
+	schemaName  string = "connectors"
+	tableName   string = "change"
+	triggerName string = "connectors_change"
+)
+
+// op values
+const (
+	OpInsert string = "insert"
+	OpUpdate string = "update"
+	OpDelete string = "delete"
+)
+
+type Model struct {
+	Id         int64           `db:"id" json:"id"`
+	Data       json.RawMessage `db:"data" json:"data"`
+	OccurredAt time.Time       `db:"occurred_at" json:"occurred_at"`
+	OldData    json.RawMessage `db:"old_data" json:"old_data"`
+	Op         string          `db:"op" json:"op"`
+	TableName  string          `db:"table_name" json:"table_name"`
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) GetName() string {
+	return name
+}
+
+// CountPending returns the number of changes not yet published
+func (s Store) CountPending(ctx context.Context) (count int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`SELECT count(*) FROM %s.%s;`, schemaName, tableName)
+
+	err = s.Db.QueryRow(ctx, stmt).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.QueryRow failed: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteByIds deletes the changes with ids, once they are published
+// ids are listed rather than deleting up to the highest, since a change with a lower id may be committed later
+func (s Store) DeleteByIds(ctx context.Context, ids []int64) (rowsAffected int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`DELETE FROM %s.%s WHERE id = ANY($1);`, schemaName, tableName)
+
+	tag, err := s.Db.Exec(ctx, stmt, ids)
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// SelectPending returns up to limit of the oldest changes, in the order they occurred
+func (s Store) SelectPending(ctx context.Context, limit int) (items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`SELECT id, data, occurred_at, old_data, op, table_name FROM %s.%s ORDER BY id LIMIT $1;`, schemaName, tableName)
+
+	rows, _ := s.Db.Query(ctx, stmt, limit)
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	return items, nil
+}
+
+// SelectCapturedTables returns the schema qualified names of the tables whose changes are captured, sorted
+func (s Store) SelectCapturedTables(ctx context.Context) (tables []string, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return SelectTriggerTables(ctx, s.Db, triggerName)
+}
+
+// SetCapturedTables attaches the capture trigger to each of tables, given as schema.table, and removes it from any other table
+func (s Store) SetCapturedTables(ctx context.Context, tables []string) (added, removed []string, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return SetTriggerTables(ctx, s.Db, triggerName, schemaName+".capture_change", tables)
+}
+
+// SelectTriggerTables returns the schema qualified names of the tables having a trigger named trigger, sorted
+func SelectTriggerTables(ctx context.Context, db *pgxpool.Pool, trigger string) (tables []string, err error) {
+
+	stmt := `SELECT n.nspname || '.' || c.relname FROM pg_trigger t
+		JOIN pg_class c ON c.oid = t.tgrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE t.tgname = $1 ORDER BY 1;`
+
+	rows, _ := db.Query(ctx, stmt, trigger)
+	tables, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	return tables, nil
+}
+
+// SetTriggerTables attaches a row level trigger named trigger, executing function after each insert, update and delete, to each of tables,
+// and removes it from any other table. Tables are given as schema.table
+func SetTriggerTables(ctx context.Context, db *pgxpool.Pool, trigger, function string, tables []string) (added, removed []string, err error) {
+
+	current, err := SelectTriggerTables(ctx, db, trigger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("SelectTriggerTables failed: %w", err)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("db.Begin failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, table := range tables {
+		if slices.Contains(current, table) {
+			continue
+		}
+		ident, err := tableIdentifier(table)
+		if err != nil {
+			return nil, nil, fmt.Errorf("tableIdentifier failed: %w", err)
+		}
+		stmt := fmt.Sprintf(`CREATE TRIGGER %s AFTER INSERT OR UPDATE OR DELETE ON %s FOR EACH ROW EXECUTE FUNCTION %s();`, trigger, ident.Sanitize(), function)
+		if _, err = tx.Exec(ctx, stmt); err != nil {
+			return nil, nil, fmt.Errorf("tx.Exec (create trigger) failed on table: %s: %w", table, cerrors.FromPg(err))
+		}
+		added = append(added, table)
+	}
+
+	for _, table := range current {
+		if slices.Contains(tables, table) {
+			continue
+		}
+		ident, err := tableIdentifier(table)
+		if err != nil {
+			return nil, nil, fmt.Errorf("tableIdentifier failed: %w", err)
+		}
+		stmt := fmt.Sprintf(`DROP TRIGGER IF EXISTS %s ON %s;`, trigger, ident.Sanitize())
+		if _, err = tx.Exec(ctx, stmt); err != nil {
+			return nil, nil, fmt.Errorf("tx.Exec (drop trigger) failed on table: %s: %w", table, cerrors.FromPg(err))
+		}
+		removed = append(removed, table)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("tx.Commit failed: %w", err)
+	}
+
+	return added, removed, nil
+}
+
+// tableIdentifier splits a schema qualified table name
+func tableIdentifier(table string) (pgx.Identifier, error) {
+
+	schema, tbl, ok := strings.Cut(table, ".")
+	if !ok || schema == "" || tbl == "" || strings.Contains(tbl, ".") {
+		return nil, fmt.Errorf("%w: table must be schema qualified, e.g. ecb.exchange_rate: %q", cerrors.ErrValidationFailed, table)
+	}
+	return pgx.Identifier{schema, tbl}, nil
+}
@@ -0,0 +1,107 @@
+package contractlog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/contract"
+	"github.com/loveyourstack/connectors/cruntime"
+)
+
+const (
+	name       string = "Contract versions"
+	schemaName string = "connectors"
+	tableName  string = "contract_version"
+	columns    string = "id, dataset, version, fields, changes, breaking, published_at"
+)
+
+type Input struct {
+	Dataset  string            `db:"dataset" json:"dataset"`
+	Version  string            `db:"version" json:"version"`
+	Fields   []contract.Field  `db:"fields" json:"fields"`
+	Changes  []contract.Change `db:"changes" json:"changes"` // from the previous version of Dataset
+	Breaking bool              `db:"breaking" json:"breaking"`
+}
+
+type Model struct {
+	Id          int64     `db:"id" json:"id"`
+	PublishedAt time.Time `db:"published_at" json:"published_at"`
+	Input
+}
+
+// Contract returns the contract of m
+func (m Model) Contract() contract.Contract {
+	return contract.Contract{Dataset: m.Dataset, Version: m.Version, Fields: m.Fields}
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) GetName() string {
+	return name
+}
+
+// Insert records a published contract version
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	if input.Changes == nil {
+		input.Changes = []contract.Change{}
+	}
+
+	stmt := fmt.Sprintf(`INSERT INTO %s.%s (dataset, version, fields, changes, breaking) VALUES ($1, $2, $3, $4, $5) RETURNING id;`, schemaName, tableName)
+
+	err = s.Db.QueryRow(ctx, stmt, input.Dataset, input.Version, input.Fields, input.Changes, input.Breaking).Scan(&newId)
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.QueryRow failed: %w", cerrors.FromPg(err))
+	}
+
+	return newId, nil
+}
+
+// SelectLatest returns the latest published version of each dataset, keyed by dataset
+func (s Store) SelectLatest(ctx context.Context) (itemsMap map[string]Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`SELECT DISTINCT ON (dataset) %s FROM %s.%s ORDER BY dataset, id DESC;`, columns, schemaName, tableName)
+
+	rows, _ := s.Db.Query(ctx, stmt)
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	itemsMap = make(map[string]Model, len(items))
+	for _, item := range items {
+		itemsMap[item.Dataset] = item
+	}
+
+	return itemsMap, nil
+}
+
+// SelectSince returns the versions published after since, oldest first. A zero since returns all of them
+func (s Store) SelectSince(ctx context.Context, since time.Time) (items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`SELECT %s FROM %s.%s WHERE published_at > $1 ORDER BY id;`, columns, schemaName, tableName)
+
+	rows, _ := s.Db.Query(ctx, stmt, since)
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	return items, nil
+}
@@ -0,0 +1,72 @@
+package derivedvalue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name       string = "Derived values"
+	schemaName string = "connectors"
+	tableName  string = "derived_value"
+)
+
+type Input struct {
+	Dataset     string    `db:"dataset" json:"dataset"`
+	Series      string    `db:"series" json:"series"`
+	Day         time.Time `db:"day" json:"day"`
+	Field       string    `db:"field" json:"field"`
+	Value       float64   `db:"value" json:"value"`
+	SourceRunId *int64    `db:"source_run_id" json:"source_run_id,omitempty"` // id of the connectors.sync_run which stored the value
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) GetName() string {
+	return name
+}
+
+// Replace replaces the values of fields of dataset between startDate and endDate (inclusive) with inputs, in a transaction
+// values of rows no longer synced, e.g. deleted rates, are so removed as well
+func (s Store) Replace(ctx context.Context, dataset string, fields []string, startDate, endDate time.Time, inputs []Input) error {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	tx, err := s.Db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("s.Db.Begin failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	stmt := fmt.Sprintf(`DELETE FROM %s.%s WHERE dataset = $1 AND field = ANY($2) AND day BETWEEN $3 AND $4;`, schemaName, tableName)
+	if _, err = tx.Exec(ctx, stmt, dataset, fields, startDate.Format(lystype.DateFormat), endDate.Format(lystype.DateFormat)); err != nil {
+		return fmt.Errorf("tx.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	if len(inputs) > 0 {
+		rows := make([][]any, len(inputs))
+		for i, input := range inputs {
+			rows[i] = []any{input.Dataset, input.Series, input.Day, input.Field, input.Value, input.SourceRunId}
+		}
+		if _, err = tx.CopyFrom(ctx, pgx.Identifier{schemaName, tableName}, []string{"dataset", "series", "day", "field", "value", "source_run_id"}, pgx.CopyFromRows(rows)); err != nil {
+			return fmt.Errorf("tx.CopyFrom failed: %w", cerrors.FromPg(err))
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("tx.Commit failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,8 @@
+package connectors
+
+import "embed"
+
+// Migrations is an embedded filesystem containing the SQL migrations of the connectors (framework) schema, applied in file name order
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
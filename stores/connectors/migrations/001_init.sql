@@ -0,0 +1,31 @@
+
+-- framework tables shared by all connectors. connectors.schema_migration is created by the migration runner itself
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS connectors;
+
+CREATE TYPE connectors.sync_status AS ENUM ('running', 'succeeded', 'failed');
+
+
+CREATE TABLE connectors.sync_run
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  dataset text NOT NULL, -- e.g. ecb.exchange_rate
+  params text NOT NULL DEFAULT '',
+  status connectors.sync_status NOT NULL,
+  started_at timestamp with time zone NOT NULL,
+  finished_at timestamp with time zone,
+  error text NOT NULL DEFAULT '',
+  entry_at tracking_at,
+  last_modified_at tracking_at
+);
+COMMENT ON TABLE connectors.sync_run IS 'shortname: sr';
+
+CREATE INDEX sync_run_dataset_started_at_idx ON connectors.sync_run (dataset, started_at DESC);
@@ -0,0 +1,31 @@
+-- outbox of the row changes of the tables captured for the change feed. Rows are deleted once published
+CREATE TABLE IF NOT EXISTS connectors.change
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  table_name text NOT NULL, -- schema qualified, e.g. ecb.exchange_rate
+  op text NOT NULL CHECK (op IN ('insert', 'update', 'delete')),
+  data jsonb, -- the row after the change. NULL for deletes
+  old_data jsonb, -- the row before the change. NULL for inserts
+  occurred_at timestamp with time zone NOT NULL DEFAULT now()
+);
+COMMENT ON TABLE connectors.change IS 'shortname: chg';
+
+
+-- trigger function of the captured tables, attached as the connectors_change trigger
+CREATE OR REPLACE FUNCTION connectors.capture_change() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    INSERT INTO connectors.change (table_name, op, data) VALUES (TG_TABLE_SCHEMA || '.' || TG_TABLE_NAME, 'insert', to_jsonb(NEW));
+  ELSIF TG_OP = 'UPDATE' THEN
+    -- updates which only touch last_modified_at are not changes
+    IF to_jsonb(NEW) - 'last_modified_at' = to_jsonb(OLD) - 'last_modified_at' THEN
+      RETURN NULL;
+    END IF;
+    INSERT INTO connectors.change (table_name, op, data, old_data) VALUES (TG_TABLE_SCHEMA || '.' || TG_TABLE_NAME, 'update', to_jsonb(NEW), to_jsonb(OLD));
+  ELSE
+    INSERT INTO connectors.change (table_name, op, old_data) VALUES (TG_TABLE_SCHEMA || '.' || TG_TABLE_NAME, 'delete', to_jsonb(OLD));
+  END IF;
+  RETURN NULL;
+END
+$$;
@@ -0,0 +1,47 @@
+-- outbox of the row changes of the captured tables in the layout of Debezium's outbox event router, for consumption by CDC tooling
+-- rows are written in the transaction of the change and pruned after the retention, since CDC reads them from the WAL
+CREATE TABLE IF NOT EXISTS connectors.outbox
+(	
+  id uuid NOT NULL DEFAULT gen_random_uuid() PRIMARY KEY,
+  aggregatetype text NOT NULL, -- schema qualified table, e.g. ecb.exchange_rate. The event router routes by it
+  aggregateid text NOT NULL, -- id of the row, or empty if the table has no id column. The event router uses it as message key
+  type text NOT NULL CHECK (type IN ('insert', 'update', 'delete')),
+  payload jsonb NOT NULL, -- connectors.change.v1 event
+  occurred_at timestamp with time zone NOT NULL DEFAULT now()
+);
+COMMENT ON TABLE connectors.outbox IS 'shortname: obx';
+
+CREATE INDEX IF NOT EXISTS outbox_occurred_at_idx ON connectors.outbox (occurred_at);
+
+
+-- trigger function of the captured tables, attached as the connectors_outbox trigger
+CREATE OR REPLACE FUNCTION connectors.write_outbox() RETURNS trigger
+LANGUAGE plpgsql AS $$
+DECLARE
+  tbl text := TG_TABLE_SCHEMA || '.' || TG_TABLE_NAME;
+  op text := lower(TG_OP);
+  new_row jsonb;
+  old_row jsonb;
+  key text;
+BEGIN
+  IF TG_OP <> 'DELETE' THEN
+    new_row := to_jsonb(NEW);
+  END IF;
+  IF TG_OP <> 'INSERT' THEN
+    old_row := to_jsonb(OLD);
+  END IF;
+
+  -- updates which only touch last_modified_at are not changes
+  IF TG_OP = 'UPDATE' AND new_row - 'last_modified_at' = old_row - 'last_modified_at' THEN
+    RETURN NULL;
+  END IF;
+
+  key := coalesce(coalesce(new_row, old_row) ->> 'id', '');
+
+  INSERT INTO connectors.outbox (aggregatetype, aggregateid, type, payload)
+  VALUES (tbl, key, op, jsonb_build_object('schema', 'connectors.change.v1', 'table', tbl, 'op', op, 'key', key, 'occurred_at', now())
+    || CASE WHEN new_row IS NULL THEN '{}'::jsonb ELSE jsonb_build_object('data', new_row) END
+    || CASE WHEN old_row IS NULL THEN '{}'::jsonb ELSE jsonb_build_object('old', old_row) END);
+  RETURN NULL;
+END
+$$;
@@ -0,0 +1,14 @@
+-- ticks of intraday datasets, e.g. coingecko.price_tick, which are not routed to a sink such as ClickHouse
+CREATE TABLE IF NOT EXISTS connectors.tick
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  dataset text NOT NULL,
+  series text NOT NULL, -- e.g. EUR/USD or bitcoin/EUR
+  at timestamp with time zone NOT NULL,
+  value double precision NOT NULL,
+  volume double precision NOT NULL DEFAULT 0,
+  entry_at timestamp with time zone NOT NULL DEFAULT now(),
+  last_modified_at timestamp with time zone NOT NULL DEFAULT now(),
+  CONSTRAINT tick_dataset_series_at_key UNIQUE (dataset, series, at)
+);
+COMMENT ON TABLE connectors.tick IS 'shortname: tck';
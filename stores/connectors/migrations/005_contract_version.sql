@@ -0,0 +1,15 @@
+-- the data contracts published by each installation, one row per dataset version, so that consumers can follow the changes of the shape of the datasets
+CREATE TABLE IF NOT EXISTS connectors.contract_version
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  dataset text NOT NULL,
+  version text NOT NULL, -- semantic version, e.g. 1.2.0
+  fields jsonb NOT NULL, -- the contract.Field list
+  changes jsonb NOT NULL DEFAULT '[]', -- the contract.Change list from the previous version. Empty for the first
+  breaking boolean NOT NULL DEFAULT false, -- true if a change is breaking or the major version increased
+  published_at timestamp with time zone NOT NULL DEFAULT now()
+);
+COMMENT ON TABLE connectors.contract_version IS 'shortname: cv';
+
+CREATE INDEX IF NOT EXISTS contract_version_dataset_idx ON connectors.contract_version (dataset, id);
+CREATE INDEX IF NOT EXISTS contract_version_published_at_idx ON connectors.contract_version (published_at);
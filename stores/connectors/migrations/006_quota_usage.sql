@@ -0,0 +1,11 @@
+-- requests made to the API of each connector with a quota, per quota window, so that usage survives restarts of the daemon
+CREATE TABLE IF NOT EXISTS connectors.quota_usage
+(
+  connector text NOT NULL,
+  window_start timestamp with time zone NOT NULL, -- start of the hour, day or month counted, in UTC
+  requests bigint NOT NULL DEFAULT 0,
+  entry_at timestamp with time zone NOT NULL DEFAULT now(),
+  last_modified_at timestamp with time zone NOT NULL DEFAULT now(),
+  CONSTRAINT quota_usage_pkey PRIMARY KEY (connector, window_start)
+);
+COMMENT ON TABLE connectors.quota_usage IS 'shortname: qus';
@@ -0,0 +1,12 @@
+-- the fields seen in the responses of each API endpoint of the connectors, so that fields added or removed upstream are noticed
+CREATE TABLE IF NOT EXISTS connectors.response_field
+(
+  connector text NOT NULL,
+  endpoint text NOT NULL, -- host and path of the request, with variable path segments replaced by {id}, and the format, e.g. "api.stripe.com/v1/balance_transactions json"
+  field text NOT NULL, -- path of a JSON field, e.g. data[].amount, or a CSV column
+  first_seen_at timestamp with time zone NOT NULL DEFAULT now(),
+  last_seen_at timestamp with time zone NOT NULL DEFAULT now(),
+  removed_at timestamp with time zone, -- set once the field is reported removed. Cleared if it is seen again
+  CONSTRAINT response_field_pkey PRIMARY KEY (connector, endpoint, field)
+);
+COMMENT ON TABLE connectors.response_field IS 'shortname: rf';
@@ -0,0 +1,20 @@
+-- the raw responses of the APIs of the connectors with archival enabled, written before they are parsed, so that runs can be reprocessed after a parser fix without calling the provider again
+CREATE TABLE IF NOT EXISTS connectors.raw_payload
+(
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  connector text NOT NULL,
+  dataset text NOT NULL DEFAULT '',
+  run_id bigint, -- the connectors.sync_run of the dataset in this database, assigned once the sync has finished
+  method text NOT NULL,
+  request_url text NOT NULL, -- with the values of credential query params replaced by REDACTED
+  status_code integer NOT NULL,
+  content_type text NOT NULL DEFAULT '',
+  body bytea NOT NULL, -- gzip compressed
+  body_bytes bigint NOT NULL, -- uncompressed size
+  body_hash text NOT NULL, -- hex encoded SHA-256 of the uncompressed body
+  captured_at timestamp with time zone NOT NULL DEFAULT now()
+);
+COMMENT ON TABLE connectors.raw_payload IS 'shortname: rp';
+
+CREATE INDEX IF NOT EXISTS raw_payload_run_id_idx ON connectors.raw_payload (run_id, id);
+CREATE INDEX IF NOT EXISTS raw_payload_captured_at_idx ON connectors.raw_payload (captured_at);
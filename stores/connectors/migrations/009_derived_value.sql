@@ -0,0 +1,15 @@
+-- the values of the derived fields of synced rows, e.g. the inverse of a rate, computed by the sync of their dataset from the fields configured in [derive]
+CREATE TABLE IF NOT EXISTS connectors.derived_value
+(
+  dataset text NOT NULL, -- e.g. ecb.exchange_rate
+  series text NOT NULL, -- of the row the value is derived from, e.g. EUR/USD
+  day date NOT NULL,
+  field text NOT NULL, -- name of the derived field, e.g. inverse_rate
+  value double precision NOT NULL,
+  source_run_id bigint, -- id of the connectors.sync_run which stored the value
+  entry_at tracking_at,
+  CONSTRAINT derived_value_pkey PRIMARY KEY (dataset, series, day, field)
+);
+COMMENT ON TABLE connectors.derived_value IS 'shortname: dv';
+
+CREATE INDEX IF NOT EXISTS derived_value_field_idx ON connectors.derived_value (dataset, field, day);
@@ -0,0 +1,103 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/connectors/stores/connectors/change"
+)
+
+const (
+	name        string = "Outbox"
+	schemaName  string = "connectors"
+	tableName   string = "outbox"
+	triggerName string = "connectors_outbox"
+)
+
+// Model is a row change in the layout of Debezium's outbox event router: AggregateType routes it, AggregateId is the message key
+type Model struct {
+	Id            string          `db:"id" json:"id"` // uuid
+	AggregateId   string          `db:"aggregateid" json:"aggregateid"`
+	AggregateType string          `db:"aggregatetype" json:"aggregatetype"`
+	OccurredAt    time.Time       `db:"occurred_at" json:"occurred_at"`
+	Payload       json.RawMessage `db:"payload" json:"payload"`
+	Type          string          `db:"type" json:"type"`
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) GetName() string {
+	return name
+}
+
+// Count returns the number of rows in the outbox
+func (s Store) Count(ctx context.Context) (count int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`SELECT count(*) FROM %s.%s;`, schemaName, tableName)
+
+	err = s.Db.QueryRow(ctx, stmt).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.QueryRow failed: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteBefore prunes the rows which occurred before t
+func (s Store) DeleteBefore(ctx context.Context, t time.Time) (rowsAffected int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`DELETE FROM %s.%s WHERE occurred_at < $1;`, schemaName, tableName)
+
+	tag, err := s.Db.Exec(ctx, stmt, t)
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// SelectLatest returns up to limit of the latest rows, newest first
+func (s Store) SelectLatest(ctx context.Context, limit int) (items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`SELECT id::text AS id, aggregateid, aggregatetype, occurred_at, payload, type FROM %s.%s ORDER BY occurred_at DESC LIMIT $1;`, schemaName, tableName)
+
+	rows, _ := s.Db.Query(ctx, stmt, limit)
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	return items, nil
+}
+
+// SelectCapturedTables returns the schema qualified names of the tables whose changes are written to the outbox, sorted
+func (s Store) SelectCapturedTables(ctx context.Context) (tables []string, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return change.SelectTriggerTables(ctx, s.Db, triggerName)
+}
+
+// SetCapturedTables attaches the outbox trigger to each of tables, given as schema.table, and removes it from any other table
+func (s Store) SetCapturedTables(ctx context.Context, tables []string) (added, removed []string, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return change.SetTriggerTables(ctx, s.Db, triggerName, schemaName+".write_outbox", tables)
+}
@@ -0,0 +1,90 @@
+package quotausage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+)
+
+const (
+	name       string = "Quota usage"
+	schemaName string = "connectors"
+	tableName  string = "quota_usage"
+)
+
+type Model struct {
+	Connector      string    `db:"connector" json:"connector"`
+	WindowStart    time.Time `db:"window_start" json:"window_start"`
+	Requests       int64     `db:"requests" json:"requests"`
+	EntryAt        time.Time `db:"entry_at" json:"entry_at"`
+	LastModifiedAt time.Time `db:"last_modified_at" json:"last_modified_at"`
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) GetName() string {
+	return name
+}
+
+// Add adds n to the requests of connector in the window starting at windowStart, and returns the requests of the window
+func (s Store) Add(ctx context.Context, connector string, windowStart time.Time, n int64) (requests int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`INSERT INTO %s.%s AS t (connector, window_start, requests) VALUES ($1, $2, $3)
+		ON CONFLICT (connector, window_start) DO UPDATE SET requests = t.requests + EXCLUDED.requests, last_modified_at = now()
+		RETURNING requests;`, schemaName, tableName)
+
+	if err = s.Db.QueryRow(ctx, stmt, connector, windowStart, n).Scan(&requests); err != nil {
+		return 0, fmt.Errorf("s.Db.QueryRow failed: %w", cerrors.FromPg(err))
+	}
+
+	return requests, nil
+}
+
+// SelectRequests returns the requests of connector in the window starting at windowStart, or 0 if none were made
+func (s Store) SelectRequests(ctx context.Context, connector string, windowStart time.Time) (requests int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`SELECT requests FROM %s.%s WHERE connector = $1 AND window_start = $2;`, schemaName, tableName)
+
+	err = s.Db.QueryRow(ctx, stmt, connector, windowStart).Scan(&requests)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.QueryRow failed: %w", cerrors.FromPg(err))
+	}
+
+	return requests, nil
+}
+
+// SelectLatest returns the latest window of each connector, ordered by connector
+func (s Store) SelectLatest(ctx context.Context) (items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`SELECT DISTINCT ON (connector) connector, window_start, requests, entry_at, last_modified_at FROM %s.%s
+		ORDER BY connector, window_start DESC;`, schemaName, tableName)
+
+	rows, _ := s.Db.Query(ctx, stmt)
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	return items, nil
+}
@@ -0,0 +1,114 @@
+package rawpayload
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+)
+
+const (
+	name       string = "Raw payloads"
+	schemaName string = "connectors"
+	tableName  string = "raw_payload"
+	columns    string = "id, connector, dataset, run_id, method, request_url, status_code, content_type, body, body_bytes, body_hash, captured_at"
+)
+
+type Input struct {
+	Connector   string `db:"connector" json:"connector"`
+	Dataset     string `db:"dataset" json:"dataset"`
+	Method      string `db:"method" json:"method"`
+	RequestUrl  string `db:"request_url" json:"request_url"`
+	StatusCode  int    `db:"status_code" json:"status_code"`
+	ContentType string `db:"content_type" json:"content_type"`
+	Body        []byte `db:"body" json:"-"` // gzip compressed
+	BodyBytes   int64  `db:"body_bytes" json:"body_bytes"`
+	BodyHash    string `db:"body_hash" json:"body_hash"`
+}
+
+type Model struct {
+	Id         int64     `db:"id" json:"id"`
+	RunId      *int64    `db:"run_id" json:"run_id"`
+	CapturedAt time.Time `db:"captured_at" json:"captured_at"`
+	Input
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) GetName() string {
+	return name
+}
+
+// Insert archives a payload
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`INSERT INTO %s.%s (connector, dataset, method, request_url, status_code, content_type, body, body_bytes, body_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id;`, schemaName, tableName)
+
+	err = s.Db.QueryRow(ctx, stmt, input.Connector, input.Dataset, input.Method, input.RequestUrl, input.StatusCode, input.ContentType, input.Body,
+		input.BodyBytes, input.BodyHash).Scan(&newId)
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.QueryRow failed: %w", cerrors.FromPg(err))
+	}
+
+	return newId, nil
+}
+
+// AssignRun sets the run of the payloads of connector and dataset captured since since which have none
+func (s Store) AssignRun(ctx context.Context, connector, dataset string, since time.Time, runId int64) (rowsAffected int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`UPDATE %s.%s SET run_id = $4 WHERE connector = $1 AND dataset = $2 AND captured_at >= $3 AND run_id IS NULL;`, schemaName, tableName)
+
+	tag, err := s.Db.Exec(ctx, stmt, connector, dataset, since, runId)
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// SelectByRun returns the payloads of runId in the order they were captured
+func (s Store) SelectByRun(ctx context.Context, runId int64) (items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`SELECT %s FROM %s.%s WHERE run_id = $1 ORDER BY id;`, columns, schemaName, tableName)
+
+	rows, _ := s.Db.Query(ctx, stmt, runId)
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	return items, nil
+}
+
+// DeleteBefore deletes the payloads captured before before
+func (s Store) DeleteBefore(ctx context.Context, before time.Time) (rowsAffected int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`DELETE FROM %s.%s WHERE captured_at < $1;`, schemaName, tableName)
+
+	tag, err := s.Db.Exec(ctx, stmt, before)
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,98 @@
+package responsefield
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+)
+
+const (
+	name       string = "Response fields"
+	schemaName string = "connectors"
+	tableName  string = "response_field"
+	columns    string = "connector, endpoint, field, first_seen_at, last_seen_at, removed_at"
+)
+
+type Model struct {
+	Connector   string     `db:"connector" json:"connector"`
+	Endpoint    string     `db:"endpoint" json:"endpoint"`
+	Field       string     `db:"field" json:"field"`
+	FirstSeenAt time.Time  `db:"first_seen_at" json:"first_seen_at"`
+	LastSeenAt  time.Time  `db:"last_seen_at" json:"last_seen_at"`
+	RemovedAt   *time.Time `db:"removed_at" json:"removed_at"`
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) GetName() string {
+	return name
+}
+
+// SelectCurrent returns the fields of endpoint which are not reported removed, keyed by field
+func (s Store) SelectCurrent(ctx context.Context, connector, endpoint string) (itemsMap map[string]Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`SELECT %s FROM %s.%s WHERE connector = $1 AND endpoint = $2 AND removed_at IS NULL;`, columns, schemaName, tableName)
+
+	rows, _ := s.Db.Query(ctx, stmt, connector, endpoint)
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	itemsMap = make(map[string]Model, len(items))
+	for _, item := range items {
+		itemsMap[item.Field] = item
+	}
+	return itemsMap, nil
+}
+
+// Seen inserts the fields of endpoint not stored yet, and sets the last seen time of the others to at, clearing their removal
+func (s Store) Seen(ctx context.Context, connector, endpoint string, fields []string, at time.Time) error {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	stmt := fmt.Sprintf(`INSERT INTO %s.%s (connector, endpoint, field, first_seen_at, last_seen_at)
+		SELECT $1, $2, field, $4, $4 FROM unnest($3::text[]) AS u (field)
+		ON CONFLICT (connector, endpoint, field) DO UPDATE SET last_seen_at = EXCLUDED.last_seen_at, removed_at = NULL;`, schemaName, tableName)
+
+	if _, err := s.Db.Exec(ctx, stmt, connector, endpoint, fields, at); err != nil {
+		return fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return nil
+}
+
+// MarkRemoved records the fields of endpoint as removed at at
+func (s Store) MarkRemoved(ctx context.Context, connector, endpoint string, fields []string, at time.Time) error {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	stmt := fmt.Sprintf(`UPDATE %s.%s SET removed_at = $4 WHERE connector = $1 AND endpoint = $2 AND field = ANY($3);`, schemaName, tableName)
+
+	if _, err := s.Db.Exec(ctx, stmt, connector, endpoint, fields, at); err != nil {
+		return fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return nil
+}
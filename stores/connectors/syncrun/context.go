@@ -0,0 +1,16 @@
+package syncrun
+
+import "context"
+
+type runIdKey struct{}
+
+// ContextWithRunId returns a copy of ctx carrying the id of the run being synced, so that stores can record which run wrote a row
+func ContextWithRunId(ctx context.Context, id int64) context.Context {
+	return context.WithValue(ctx, runIdKey{}, id)
+}
+
+// RunIdFromContext returns the id of the run carried by ctx. ok is false outside a journaled sync
+func RunIdFromContext(ctx context.Context) (id int64, ok bool) {
+	id, ok = ctx.Value(runIdKey{}).(int64)
+	return id, ok
+}
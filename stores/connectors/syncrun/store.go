@@ -0,0 +1,172 @@
+package syncrun
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "Sync runs"
+	schemaName     string = "connectors"
+	tableName      string = "sync_run"
+	viewName       string = "sync_run"
+	pkColName      string = "id"
+	defaultOrderBy string = "started_at DESC"
+)
+
+// sync_status enum values
+const (
+	StatusRunning   string = "running"
+	StatusSucceeded string = "succeeded"
+	StatusFailed    string = "failed"
+)
+
+type Input struct {
+	Dataset        string            `db:"dataset" json:"dataset,omitempty" validate:"required"`
+	Error          string            `db:"error" json:"error,omitempty"`
+	FinishedAt     *lystype.Datetime `db:"finished_at" json:"finished_at,omitempty"`
+	LastModifiedAt lystype.Datetime  `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	Params         string            `db:"params" json:"params,omitempty"`
+	StartedAt      lystype.Datetime  `db:"started_at" json:"started_at,omitempty" validate:"required"`
+	Status         string            `db:"status" json:"status,omitempty" validate:"required"`
+}
+
+type Model struct {
+	Id      int64            `db:"id" json:"id"`
+	EntryAt lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	Input
+}
+
+var (
+	meta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+// Finish marks the run with the supplied id as succeeded, or as failed if syncErr is not nil
+func (s Store) Finish(ctx context.Context, id int64, syncErr error) error {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	status := StatusSucceeded
+	errMsg := ""
+	if syncErr != nil {
+		status = StatusFailed
+		errMsg = syncErr.Error()
+	}
+
+	stmt := fmt.Sprintf("UPDATE %s.%s SET status = $1, error = $2, finished_at = now(), last_modified_at = now() WHERE %s = $3;", schemaName, tableName, pkColName)
+	cmdTag, err := s.Db.Exec(ctx, stmt, status, errMsg, id)
+	if err != nil {
+		return fmt.Errorf("s.Db.Exec failed: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return cerrors.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+}
+
+// SelectLatest returns the most recently started run of dataset. Returns cerrors.ErrNoRows if the dataset has never been synced
+func (s Store) SelectLatest(ctx context.Context, dataset string) (item Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	items, _, err := s.Select(ctx, lyspg.SelectParams{
+		Conditions: []lyspg.Condition{
+			{Field: "dataset", Operator: lyspg.OpEquals, Value: dataset},
+		},
+		Limit: 1,
+	})
+	if err != nil {
+		return Model{}, fmt.Errorf("s.Select failed: %w", err)
+	}
+	if len(items) == 0 {
+		return Model{}, cerrors.ErrNoRows
+	}
+
+	return items[0], nil
+}
+
+// SelectStartedSince returns the runs started at or after since, ordered by start
+func (s Store) SelectStartedSince(ctx context.Context, since time.Time) (items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf("SELECT %s FROM %s.%s WHERE started_at >= $1 ORDER BY started_at, %s;", strings.Join(meta.DbTags, ", "), schemaName, viewName, pkColName)
+
+	rows, _ := s.Db.Query(ctx, stmt, since)
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	return items, nil
+}
+
+// Start inserts a new run of dataset with status running
+func (s Store) Start(ctx context.Context, dataset, params string) (newId int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	now := lystype.Datetime(time.Now())
+	return s.Insert(ctx, Input{
+		Dataset:        dataset,
+		LastModifiedAt: now,
+		Params:         params,
+		StartedAt:      now,
+		Status:         StatusRunning,
+	})
+}
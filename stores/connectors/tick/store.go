@@ -0,0 +1,90 @@
+package tick
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/connectors/sink"
+)
+
+const (
+	name       string = "Ticks"
+	schemaName string = "connectors"
+	tableName  string = "tick"
+)
+
+type Model struct {
+	Id             int64     `db:"id" json:"id"`
+	At             time.Time `db:"at" json:"at"`
+	Dataset        string    `db:"dataset" json:"dataset"`
+	EntryAt        time.Time `db:"entry_at" json:"entry_at"`
+	LastModifiedAt time.Time `db:"last_modified_at" json:"last_modified_at"`
+	Series         string    `db:"series" json:"series"`
+	Value          float64   `db:"value" json:"value"`
+	Volume         float64   `db:"volume" json:"volume"`
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) GetName() string {
+	return name
+}
+
+// SelectInRange returns the ticks of series of dataset between start and end (inclusive), ordered by time
+func (s Store) SelectInRange(ctx context.Context, dataset, series string, start, end time.Time) (items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`SELECT id, at, dataset, entry_at, last_modified_at, series, value, volume FROM %s.%s
+		WHERE dataset = $1 AND series = $2 AND at BETWEEN $3 AND $4 ORDER BY at;`, schemaName, tableName)
+
+	rows, _ := s.Db.Query(ctx, stmt, dataset, series, start, end)
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	return items, nil
+}
+
+// Upsert inserts ticks of dataset, or updates the value and volume of ticks already stored with the same series and time
+// rowsAffected counts the inserted and changed ticks
+func (s Store) Upsert(ctx context.Context, dataset string, ticks []sink.Tick) (rowsAffected int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	if len(ticks) == 0 {
+		return 0, nil
+	}
+
+	series := make([]string, len(ticks))
+	ats := make([]time.Time, len(ticks))
+	values := make([]float64, len(ticks))
+	volumes := make([]float64, len(ticks))
+	for i, t := range ticks {
+		series[i], ats[i], values[i], volumes[i] = t.Series, t.At, t.Value, t.Volume
+	}
+
+	// DISTINCT ON: a statement may not update the same row twice
+	stmt := fmt.Sprintf(`INSERT INTO %s.%s AS t (dataset, series, at, value, volume)
+		SELECT DISTINCT ON (series, at) $1, series, at, value, volume FROM unnest($2::text[], $3::timestamptz[], $4::float8[], $5::float8[]) AS u (series, at, value, volume)
+		ON CONFLICT (dataset, series, at) DO UPDATE SET value = EXCLUDED.value, volume = EXCLUDED.volume, last_modified_at = now()
+		WHERE (t.value, t.volume) IS DISTINCT FROM (EXCLUDED.value, EXCLUDED.volume);`, schemaName, tableName)
+
+	tag, err := s.Db.Exec(ctx, stmt, dataset, series, ats, values, volumes)
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return tag.RowsAffected(), nil
+}
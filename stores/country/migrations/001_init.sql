@@ -0,0 +1,46 @@
+
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA country TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA country GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA country GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS country;
+
+
+CREATE TABLE country.country
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  iso2 text NOT NULL UNIQUE, -- natural key: ISO 3166-1 alpha-2, e.g. DE
+  iso3 text NOT NULL, -- ISO 3166-1 alpha-3, e.g. DEU
+  numeric_code text NOT NULL, -- ISO 3166-1 numeric, e.g. 276. Empty for some territories, e.g. Kosovo
+  name text NOT NULL, -- common English name
+  official_name text NOT NULL,
+  region text NOT NULL, -- e.g. Europe
+  subregion text NOT NULL,
+  currency_codes text[] NOT NULL DEFAULT '{}', -- ISO 4217 codes of the currencies used
+  eu_member bool NOT NULL
+);
+COMMENT ON TABLE country.country IS 'shortname: ctry';
+
+
+-- a row per country and currency used, to join currency data against
+CREATE VIEW country.v_country_currency AS
+  SELECT
+    ctry.id AS country_fk,
+    ctry.iso2,
+    ctry.name,
+    unnest(ctry.currency_codes) AS currency_code,
+    ctry.eu_member
+  FROM country.country ctry;
@@ -0,0 +1,182 @@
+package ebayoffer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "eBay offers"
+	schemaName     string = "ebay"
+	tableName      string = "offer"
+	viewName       string = "v_offer"
+	pkColName      string = "id"
+	defaultOrderBy string = "sku, marketplace_id"
+)
+
+type Input struct {
+	AvailableQuantity int              `db:"available_quantity" json:"available_quantity"`
+	CategoryId        string           `db:"category_id" json:"category_id"`
+	Currency          string           `db:"currency" json:"currency"`
+	Format            string           `db:"format" json:"format,omitempty" validate:"required"`
+	InventoryItemFk   int64            `db:"inventory_item_fk" json:"inventory_item_fk,omitempty" validate:"required"`
+	LastModifiedAt    lystype.Datetime `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	ListingId         string           `db:"listing_id" json:"listing_id"`
+	ListingStatus     string           `db:"listing_status" json:"listing_status"`
+	MarketplaceId     string           `db:"marketplace_id" json:"marketplace_id,omitempty" validate:"required"`
+	OfferId           string           `db:"offer_id" json:"offer_id,omitempty" validate:"required"`
+	Price             *float64         `db:"price" json:"price,omitempty"`
+	SoldQuantity      int              `db:"sold_quantity" json:"sold_quantity"`
+	Status            string           `db:"status" json:"status,omitempty" validate:"required"`
+}
+
+type Model struct {
+	Id      int64            `db:"id" json:"id"`
+	EntryAt lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	Sku     string           `db:"sku" json:"sku,omitempty"`
+	Title   string           `db:"title" json:"title,omitempty"`
+	Input
+}
+
+var (
+	meta, inputMeta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+	inputMeta, _ = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem())
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+// BulkDelete deletes the items of ids in a single round trip
+func (s Store) BulkDelete(ctx context.Context, ids []int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	if len(ids) == 0 {
+		return nil
+	}
+	return cerrors.FromPg(lyspg.BulkDelete(ctx, s.Db, schemaName, tableName, pkColName, ids))
+}
+
+func (s Store) BulkInsert(ctx context.Context, inputs []Input) (rowsAffected int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	rowsAffected, err = lyspg.BulkInsert[Input](ctx, s.Db, schemaName, tableName, inputs)
+	if err != nil {
+		return 0, cerrors.FromPg(err)
+	}
+	return rowsAffected, cruntime.AnalyzeAfterLoad(ctx, s.Db, schemaName, tableName, rowsAffected)
+}
+
+// BulkUpdate updates the items of ids with the inputs at the same index in a single round trip. The inputs are not modified
+func (s Store) BulkUpdate(ctx context.Context, inputs []Input, ids []int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	if len(inputs) == 0 {
+		return nil
+	}
+	now := lystype.Datetime(time.Now())
+	inputs = slices.Clone(inputs)
+	for i := range inputs {
+		inputs[i].LastModifiedAt = now
+	}
+	return cerrors.FromPg(lyspg.BulkUpdate[Input](ctx, s.Db, schemaName, tableName, pkColName, inputs, ids))
+}
+
+func (s Store) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id))
+}
+
+// Equal compares all values: offers have no update timestamp
+func (s Store) Equal(a, b Model) bool {
+	return a.InventoryItemFk == b.InventoryItemFk && a.MarketplaceId == b.MarketplaceId && a.Format == b.Format && a.Status == b.Status &&
+		a.ListingId == b.ListingId && a.ListingStatus == b.ListingStatus && a.CategoryId == b.CategoryId && a.Currency == b.Currency &&
+		equalPrice(a.Price, b.Price) && a.AvailableQuantity == b.AvailableQuantity && a.SoldQuantity == b.SoldQuantity
+}
+
+// equalPrice returns true if a and b are both nil or equal
+func equalPrice(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
+
+// SelectMapByNaturalKey returns all offers, with OfferId as key
+func (s Store) SelectMapByNaturalKey(ctx context.Context) (itemsMap map[string]Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	items, _, err := s.Select(ctx, lyspg.SelectParams{})
+	if err != nil {
+		return nil, fmt.Errorf("s.Select failed: %w", err)
+	}
+
+	itemsMap = make(map[string]Model)
+	for _, item := range items {
+		itemsMap[item.OfferId] = item
+	}
+
+	return itemsMap, nil
+}
+
+func (s Store) Update(ctx context.Context, input Input, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	input.LastModifiedAt = lystype.Datetime(time.Now())
+	return cerrors.FromPg(lyspg.Update[Input](ctx, s.Db, schemaName, tableName, pkColName, input, id))
+}
+
+func (s Store) Validate(validate *validator.Validate, input Input) error {
+	return lysmeta.Validate[Input](validate, input)
+}
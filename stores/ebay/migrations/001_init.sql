@@ -0,0 +1,80 @@
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA ebay TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA ebay GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA ebay GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS ebay;
+
+
+CREATE TABLE ebay.inventory_item
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  sku text NOT NULL UNIQUE, -- seller-defined
+  locale text NOT NULL DEFAULT '', -- e.g. en_US
+  title text NOT NULL DEFAULT '',
+  condition text NOT NULL DEFAULT '', -- e.g. NEW, USED_EXCELLENT
+  brand text NOT NULL DEFAULT '',
+  mpn text NOT NULL DEFAULT '', -- manufacturer part number
+  ean text NOT NULL DEFAULT '',
+  image_url text NOT NULL DEFAULT '', -- first image
+  quantity int NOT NULL DEFAULT 0, -- available to ship
+  entry_at tracking_at,
+  last_modified_at tracking_at
+);
+COMMENT ON TABLE ebay.inventory_item IS 'shortname: ebii';
+
+
+CREATE TABLE ebay.offer
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  inventory_item_fk bigint NOT NULL REFERENCES ebay.inventory_item(id) ON DELETE CASCADE,
+  offer_id text NOT NULL UNIQUE,
+  marketplace_id text NOT NULL, -- e.g. EBAY_US
+  format text NOT NULL, -- FIXED_PRICE or AUCTION
+  status text NOT NULL, -- PUBLISHED or UNPUBLISHED
+  listing_id text NOT NULL DEFAULT '', -- of published offers
+  listing_status text NOT NULL DEFAULT '', -- e.g. ACTIVE, ENDED
+  category_id text NOT NULL DEFAULT '',
+  currency text NOT NULL DEFAULT '',
+  price numeric,
+  available_quantity int NOT NULL DEFAULT 0,
+  sold_quantity int NOT NULL DEFAULT 0,
+  entry_at tracking_at,
+  last_modified_at tracking_at
+);
+COMMENT ON TABLE ebay.offer IS 'shortname: ebof';
+
+CREATE INDEX ON ebay.offer (inventory_item_fk);
+
+
+CREATE VIEW ebay.v_offer AS
+  SELECT
+    ebof.available_quantity,
+    ebof.category_id,
+    ebof.currency,
+    ebof.entry_at,
+    ebof.format,
+    ebof.id,
+    ebof.inventory_item_fk,
+    ebof.last_modified_at,
+    ebof.listing_id,
+    ebof.listing_status,
+    ebof.marketplace_id,
+    ebof.offer_id,
+    ebof.price,
+    ebii.sku,
+    ebof.sold_quantity,
+    ebof.status,
+    ebii.title
+  FROM ebay.offer ebof
+  JOIN ebay.inventory_item ebii ON ebof.inventory_item_fk = ebii.id;
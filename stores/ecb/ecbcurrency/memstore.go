@@ -0,0 +1,155 @@
+package ecbcurrency
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// Storer is implemented by Store, MemStore and SQLiteStore
+type Storer interface {
+	Count(ctx context.Context) (count int64, err error)
+	Delete(ctx context.Context, id int64) error
+	Equal(a, b Model) bool
+	Insert(ctx context.Context, input Input) (newId int64, err error)
+	SelectCodeIdMap(ctx context.Context) (codeIdMap map[string]int64, err error)
+	SelectMapByNaturalKey(ctx context.Context) (itemsMap map[string]Model, err error)
+	Update(ctx context.Context, input Input, id int64) error
+}
+
+var (
+	_ Storer = Store{}
+	_ Storer = (*MemStore)(nil)
+	_ Storer = SQLiteStore{}
+)
+
+// MemStore is a map-based Storer for unit tests and for use without Postgres. It is safe for concurrent use
+type MemStore struct {
+	mu     sync.RWMutex
+	items  map[int64]Model
+	nextId int64
+}
+
+func NewMemStore() *MemStore {
+	return &MemStore{items: make(map[int64]Model), nextId: 1}
+}
+
+func (s *MemStore) Count(ctx context.Context) (count int64, err error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return int64(len(s.items)), nil
+}
+
+// Delete returns cerrors.ErrNoRows if id is not found, as Store does
+func (s *MemStore) Delete(ctx context.Context, id int64) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.items[id]; !ok {
+		return cerrors.ErrNoRows
+	}
+	delete(s.items, id)
+
+	return nil
+}
+
+func (s *MemStore) Equal(a, b Model) bool {
+	return a.Name == b.Name
+}
+
+// Insert returns an error if the code already exists, as the unique constraint of the table does
+func (s *MemStore) Insert(ctx context.Context, input Input) (newId int64, err error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range s.items {
+		if item.Code == input.Code {
+			return 0, fmt.Errorf("%w: code already exists: %s", cerrors.ErrConflictPolicyViolation, input.Code)
+		}
+	}
+
+	newId = s.nextId
+	s.nextId++
+	s.items[newId] = Model{Id: newId, EntryAt: lystype.Datetime(time.Now()), Input: input}
+
+	return newId, nil
+}
+
+// SelectAll returns all currencies, ordered by name
+func (s *MemStore) SelectAll(ctx context.Context) (items []Model, err error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, item := range s.items {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+
+	return items, nil
+}
+
+func (s *MemStore) SelectById(ctx context.Context, id int64) (item Model, err error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, ok := s.items[id]
+	if !ok {
+		return Model{}, cerrors.ErrNoRows
+	}
+
+	return item, nil
+}
+
+func (s *MemStore) SelectCodeIdMap(ctx context.Context) (codeIdMap map[string]int64, err error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	codeIdMap = make(map[string]int64, len(s.items))
+	for _, item := range s.items {
+		codeIdMap[item.Code] = item.Id
+	}
+
+	return codeIdMap, nil
+}
+
+func (s *MemStore) SelectMapByNaturalKey(ctx context.Context) (itemsMap map[string]Model, err error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	itemsMap = make(map[string]Model, len(s.items))
+	for _, item := range s.items {
+		itemsMap[item.Code] = item
+	}
+
+	return itemsMap, nil
+}
+
+// Update returns cerrors.ErrNoRows if id is not found, as Store does
+func (s *MemStore) Update(ctx context.Context, input Input, id int64) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[id]
+	if !ok {
+		return cerrors.ErrNoRows
+	}
+	input.LastModifiedAt = lystype.Datetime(time.Now())
+	item.Input = input
+	s.items[id] = item
+
+	return nil
+}
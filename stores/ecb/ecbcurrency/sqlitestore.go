@@ -0,0 +1,181 @@
+package ecbcurrency
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/sqlitedb"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const sqliteTableName string = "ecb_currency"
+
+// SQLiteStore is a Storer using a SQLite database opened with sqlitedb.Open, for tools and tests without Postgres. It is safe for concurrent use
+type SQLiteStore struct {
+	Db *sql.DB
+}
+
+func (s SQLiteStore) Count(ctx context.Context) (count int64, err error) {
+
+	err = s.Db.QueryRowContext(ctx, "SELECT count(*) FROM "+sqliteTableName+";").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.QueryRowContext failed: %w", err)
+	}
+
+	return count, nil
+}
+
+// Delete returns cerrors.ErrNoRows if id is not found, as Store does
+func (s SQLiteStore) Delete(ctx context.Context, id int64) error {
+
+	res, err := s.Db.ExecContext(ctx, "DELETE FROM "+sqliteTableName+" WHERE id = ?;", id)
+	if err != nil {
+		return fmt.Errorf("s.Db.ExecContext failed: %w", cerrors.FromSQLite(err))
+	}
+
+	return requireRowAffected(res)
+}
+
+func (s SQLiteStore) Equal(a, b Model) bool {
+	return a.Name == b.Name
+}
+
+// Insert returns an error matching cerrors.ErrConflictPolicyViolation if the code already exists
+func (s SQLiteStore) Insert(ctx context.Context, input Input) (newId int64, err error) {
+
+	stmt := "INSERT INTO " + sqliteTableName + " (code, name, entry_at, last_modified_at) VALUES (?, ?, ?, ?);"
+	res, err := s.Db.ExecContext(ctx, stmt, input.Code, input.Name, sqlitedb.FormatTime(time.Now()), sqlitedb.FormatTime(time.Time(input.LastModifiedAt)))
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.ExecContext failed: %w", cerrors.FromSQLite(err))
+	}
+
+	newId, err = res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("res.LastInsertId failed: %w", err)
+	}
+
+	return newId, nil
+}
+
+// SelectAll returns all currencies, ordered by name
+func (s SQLiteStore) SelectAll(ctx context.Context) (items []Model, err error) {
+	return s.selectWhere(ctx, "", "ORDER BY name")
+}
+
+// SelectById returns cerrors.ErrNoRows if id is not found
+func (s SQLiteStore) SelectById(ctx context.Context, id int64) (item Model, err error) {
+
+	items, err := s.selectWhere(ctx, "WHERE id = ?", "", id)
+	if err != nil {
+		return Model{}, fmt.Errorf("s.selectWhere failed: %w", err)
+	}
+	if len(items) == 0 {
+		return Model{}, cerrors.ErrNoRows
+	}
+
+	return items[0], nil
+}
+
+func (s SQLiteStore) SelectCodeIdMap(ctx context.Context) (codeIdMap map[string]int64, err error) {
+
+	items, err := s.SelectAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("s.SelectAll failed: %w", err)
+	}
+
+	codeIdMap = make(map[string]int64, len(items))
+	for _, item := range items {
+		codeIdMap[item.Code] = item.Id
+	}
+
+	return codeIdMap, nil
+}
+
+func (s SQLiteStore) SelectMapByNaturalKey(ctx context.Context) (itemsMap map[string]Model, err error) {
+
+	items, err := s.SelectAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("s.SelectAll failed: %w", err)
+	}
+
+	// convert to map with Code as key, without the view columns, as Store does
+	itemsMap = make(map[string]Model, len(items))
+	for _, item := range items {
+		itemsMap[item.Code] = Model{Id: item.Id, Input: item.Input}
+	}
+
+	return itemsMap, nil
+}
+
+// Update returns cerrors.ErrNoRows if id is not found, as Store does
+func (s SQLiteStore) Update(ctx context.Context, input Input, id int64) error {
+
+	stmt := "UPDATE " + sqliteTableName + " SET code = ?, name = ?, last_modified_at = ? WHERE id = ?;"
+	res, err := s.Db.ExecContext(ctx, stmt, input.Code, input.Name, sqlitedb.FormatTime(time.Now()), id)
+	if err != nil {
+		return fmt.Errorf("s.Db.ExecContext failed: %w", cerrors.FromSQLite(err))
+	}
+
+	return requireRowAffected(res)
+}
+
+// selectWhere returns the currencies matching where, e.g. "WHERE id = ?", with args
+func (s SQLiteStore) selectWhere(ctx context.Context, where, orderBy string, args ...any) (items []Model, err error) {
+
+	stmt := fmt.Sprintf("SELECT id, code, name, entry_at, last_modified_at FROM %s %s %s;", sqliteTableName, where, orderBy)
+
+	rows, err := s.Db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("s.Db.QueryContext failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item Model
+		var entryAt, lastModifiedAt string
+		if err = rows.Scan(&item.Id, &item.Code, &item.Name, &entryAt, &lastModifiedAt); err != nil {
+			return nil, fmt.Errorf("rows.Scan failed: %w", err)
+		}
+		if item.EntryAt, item.LastModifiedAt, err = parseTracking(entryAt, lastModifiedAt); err != nil {
+			return nil, fmt.Errorf("parseTracking failed on id: %d: %w", item.Id, err)
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return items, nil
+}
+
+// parseTracking parses the stored entry_at and last_modified_at
+func parseTracking(entryAt, lastModifiedAt string) (lystype.Datetime, lystype.Datetime, error) {
+
+	entry, err := sqlitedb.ParseTime(entryAt)
+	if err != nil {
+		return lystype.Datetime{}, lystype.Datetime{}, fmt.Errorf("sqlitedb.ParseTime failed on entry_at: %w", err)
+	}
+	lastModified, err := sqlitedb.ParseTime(lastModifiedAt)
+	if err != nil {
+		return lystype.Datetime{}, lystype.Datetime{}, fmt.Errorf("sqlitedb.ParseTime failed on last_modified_at: %w", err)
+	}
+
+	return lystype.Datetime(entry), lystype.Datetime(lastModified), nil
+}
+
+// requireRowAffected returns cerrors.ErrNoRows if res affected no row
+func requireRowAffected(res sql.Result) error {
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected failed: %w", err)
+	}
+	if n == 0 {
+		return cerrors.ErrNoRows
+	}
+
+	return nil
+}
@@ -9,6 +9,9 @@ import (
 
 	"github.com/go-playground/validator/v10"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/connectors/internal/optlock"
 	"github.com/loveyourstack/lys/lysmeta"
 	"github.com/loveyourstack/lys/lyspg"
 	"github.com/loveyourstack/lys/lystype"
@@ -49,11 +52,20 @@ func init() {
 }
 
 type Store struct {
-	Db *pgxpool.Pool
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) Count(ctx context.Context) (count int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.GetRowCount(ctx, s.Db, schemaName, tableName)
 }
 
 func (s Store) Delete(ctx context.Context, id int64) error {
-	return lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id)
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id))
 }
 
 func (s Store) Equal(a, b Model) bool {
@@ -68,15 +80,23 @@ func (s Store) GetName() string {
 }
 
 func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
-	return lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
 }
 
 func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
 	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
 }
 
 func (s Store) SelectMapByNaturalKey(ctx context.Context) (itemsMap map[string]Model, err error) {
 
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
 	items, _, err := s.Select(ctx, lyspg.SelectParams{})
 	if err != nil {
 		return nil, fmt.Errorf("s.Select failed: %w", err)
@@ -97,6 +117,9 @@ func (s Store) SelectMapByNaturalKey(ctx context.Context) (itemsMap map[string]M
 
 func (s Store) SelectCodeIdMap(ctx context.Context) (codeIdMap map[string]int64, err error) {
 
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
 	items, _, err := s.Select(ctx, lyspg.SelectParams{})
 	if err != nil {
 		return nil, fmt.Errorf("s.Select failed: %w", err)
@@ -111,19 +134,43 @@ func (s Store) SelectCodeIdMap(ctx context.Context) (codeIdMap map[string]int64,
 }
 
 func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
-	return lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
 }
 
 func (s Store) Update(ctx context.Context, input Input, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
 	input.LastModifiedAt = lystype.Datetime(time.Now())
-	return lyspg.Update[Input](ctx, s.Db, schemaName, tableName, pkColName, input, id)
+	return cerrors.FromPg(lyspg.Update[Input](ctx, s.Db, schemaName, tableName, pkColName, input, id))
 }
 
 func (s Store) UpdatePartial(ctx context.Context, assignmentsMap map[string]any, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
 	assignmentsMap["last_modified_at"] = lystype.Datetime(time.Now())
 	return lyspg.UpdatePartial(ctx, s.Db, schemaName, tableName, pkColName, inputMeta.DbTags, assignmentsMap, id)
 }
 
+// UpdateIfUnchanged is Update with optimistic locking: readAt is the LastModifiedAt of the row when it was read. If the row was modified since, e.g. by a sync or another correction,
+// it is not overwritten and cerrors.ErrRowChanged is returned
+func (s Store) UpdateIfUnchanged(ctx context.Context, input Input, id int64, readAt time.Time) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	input.LastModifiedAt = lystype.Datetime(time.Now())
+	return cerrors.FromPg(optlock.Update(ctx, s.Db, schemaName, tableName, pkColName, input, id, readAt))
+}
+
+// UpdatePartialIfUnchanged is UpdatePartial with optimistic locking, as UpdateIfUnchanged
+func (s Store) UpdatePartialIfUnchanged(ctx context.Context, assignmentsMap map[string]any, id int64, readAt time.Time) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	assignmentsMap["last_modified_at"] = lystype.Datetime(time.Now())
+	return cerrors.FromPg(optlock.UpdatePartial(ctx, s.Db, schemaName, tableName, pkColName, inputMeta.DbTags, assignmentsMap, id, readAt))
+}
+
 func (s Store) Validate(validate *validator.Validate, input Input) error {
 	return lysmeta.Validate[Input](validate, input)
 }
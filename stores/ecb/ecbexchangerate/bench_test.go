@@ -0,0 +1,135 @@
+package ecbexchangerate_test
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/apiclients/ecbapi"
+	"github.com/loveyourstack/connectors/apiclients/ecbapi/ecbapitest"
+	"github.com/loveyourstack/connectors/pgtest"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+)
+
+// benchDbRows is the number of rates loaded per iteration of the database benchmarks
+const benchDbRows int = 100_000
+
+func BenchmarkEqual(b *testing.B) {
+
+	s := ecbexchangerate.Store{}
+	x := ecbexchangerate.Model{Input: ecbexchangerate.Input{Rate: 1.0899}}
+	y := ecbexchangerate.Model{Input: ecbexchangerate.Input{Rate: 1.08991}}
+
+	b.ReportAllocs()
+	for range b.N {
+		s.Equal(x, y)
+	}
+}
+
+func BenchmarkMemStoreBulkInsert(b *testing.B) {
+
+	for _, rows := range []int{10_000, 1_000_000} {
+
+		inputs, err := ecbapi.ExchangeRatesToItems(ecbapitest.GenExchangeRates(rows), ecbapitest.GenCurrMap())
+		if err != nil {
+			b.Fatalf("ecbapi.ExchangeRatesToItems failed: %s", err.Error())
+		}
+
+		b.Run(fmt.Sprintf("rows=%d", rows), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				if _, err := ecbexchangerate.NewMemStore(nil).BulkInsert(context.Background(), inputs); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkStoreLoad loads benchDbRows rates into an empty table per iteration, with the row-wise BulkInsert and with CopyUpsert. Skipped without Docker
+func BenchmarkStoreLoad(b *testing.B) {
+
+	ctx := context.Background()
+	db := pgtest.New(b, pgtest.Config{})
+	s := ecbexchangerate.Store{Db: db}
+	inputs := genDbInputs(b, db, benchDbRows)
+
+	load := func(name string, loadFunc func() error) {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				b.StopTimer()
+				if _, err := db.Exec(ctx, "TRUNCATE ecb.exchange_rate, ecb.exchange_rate_version;"); err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+				if err := loadFunc(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+
+	load("BulkInsert", func() error {
+		_, err := s.BulkInsert(ctx, inputs)
+		return err
+	})
+	load("CopyUpsert", func() error {
+		_, _, err := s.CopyUpsert(ctx, inputs)
+		return err
+	})
+}
+
+// BenchmarkStoreSelect reads benchDbRows stored rates: as the diff of a sync does, keyed by NaturalKey, and as a slice. Skipped without Docker
+func BenchmarkStoreSelect(b *testing.B) {
+
+	ctx := context.Background()
+	db := pgtest.New(b, pgtest.Config{})
+	s := ecbexchangerate.Store{Db: db}
+	if _, _, err := s.CopyUpsert(ctx, genDbInputs(b, db, benchDbRows)); err != nil {
+		b.Fatalf("s.CopyUpsert failed: %s", err.Error())
+	}
+
+	// the generated rates go back from today
+	startDate, endDate := time.Now().AddDate(-100, 0, 0), time.Now()
+
+	b.Run("SelectMapByNaturalKey", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			itemsMap, err := s.SelectMapByNaturalKey(ctx, "EUR", ecbexchangerate.Daily, startDate, endDate)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(itemsMap) != benchDbRows {
+				b.Fatalf("got %d rates, want %d", len(itemsMap), benchDbRows)
+			}
+		}
+	})
+
+	b.Run("SelectInRange", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			if _, err := s.SelectInRange(ctx, "EUR", ecbexchangerate.Daily, startDate, endDate); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// genDbInputs inserts the generated currencies into db and returns rows generated rates referencing them
+func genDbInputs(tb testing.TB, db *pgxpool.Pool, rows int) []ecbexchangerate.Input {
+
+	tb.Helper()
+
+	currMap := insertCurrencies(tb, db, slices.Collect(maps.Keys(ecbapitest.GenCurrMap()))...)
+
+	inputs, err := ecbapi.ExchangeRatesToItems(ecbapitest.GenExchangeRates(rows), currMap)
+	if err != nil {
+		tb.Fatalf("ecbapi.ExchangeRatesToItems failed: %s", err.Error())
+	}
+	return inputs
+}
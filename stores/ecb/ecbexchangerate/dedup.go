@@ -0,0 +1,53 @@
+package ecbexchangerate
+
+import (
+	"fmt"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// DuplicatePolicy decides what the bulk loads of a store, BulkInsert and CopyUpsert, do with inputs sharing the key of the table's unique constraint,
+// such as the same observation returned twice by the API across the boundary of two requested ranges
+type DuplicatePolicy int
+
+const (
+	// DuplicatesFail returns an error matching cerrors.ErrConflictPolicyViolation which names the duplicate key, before anything is written
+	DuplicatesFail DuplicatePolicy = iota
+
+	// DuplicatesSkip loads the first input of each key and skips the others
+	DuplicatesSkip
+)
+
+// Dedup returns inputs without duplicate keys according to policy, and the number of inputs skipped
+// inputs is returned as is if it has no duplicates. Otherwise a new slice is returned: inputs is not modified
+func Dedup(inputs []Input, policy DuplicatePolicy) (deduped []Input, skipped int, err error) {
+
+	seen := make(map[uniqueKey]bool, len(inputs))
+	for i, input := range inputs {
+
+		key := uniqueKeyOf(input)
+		if !seen[key] {
+			seen[key] = true
+			if deduped != nil {
+				deduped = append(deduped, input)
+			}
+			continue
+		}
+
+		if policy != DuplicatesSkip {
+			return nil, 0, fmt.Errorf("%w: duplicate key in inputs: %s", cerrors.ErrConflictPolicyViolation, key)
+		}
+
+		// first duplicate: copy the inputs before it
+		if deduped == nil {
+			deduped = make([]Input, i, len(inputs)-1)
+			copy(deduped, inputs[:i])
+		}
+		skipped++
+	}
+
+	if deduped == nil {
+		return inputs, 0, nil
+	}
+	return deduped, skipped, nil
+}
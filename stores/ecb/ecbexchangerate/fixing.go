@@ -0,0 +1,107 @@
+package ecbexchangerate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const fixingTableName string = "exchange_rate_fixing"
+
+// Fixing is a daily rate as it was when the snapshot of its day was taken by SnapshotDay, with the provenance of the rate then
+type Fixing struct {
+	Id             int64             `db:"id" json:"id"`
+	SnapshotAt     lystype.Datetime  `db:"snapshot_at" json:"snapshot_at"` // when the snapshot of the day was taken
+	Day            lystype.Date      `db:"day" json:"day"`
+	FromCurrency   string            `db:"from_currency" json:"from_currency"`
+	ToCurrency     string            `db:"to_currency" json:"to_currency"`
+	Rate           float32           `db:"rate" json:"rate"`
+	ExchangeRateId int64             `db:"exchange_rate_id" json:"exchange_rate_id"`     // id of the rate in ecb.exchange_rate, which may since have been revised or deleted
+	RateModifiedAt lystype.Datetime  `db:"rate_modified_at" json:"rate_modified_at"`     // LastModifiedAt of the rate when the snapshot was taken
+	Source         string            `db:"source" json:"source,omitempty"`               // as Input.Source
+	SourceRunId    *int64            `db:"source_run_id" json:"source_run_id,omitempty"` // as Input.SourceRunId
+	FetchedAt      *lystype.Datetime `db:"fetched_at" json:"fetched_at,omitempty"`       // as Input.FetchedAt
+}
+
+// RateFloat64 returns the Rate of f as published, as Model.RateFloat64
+func (f Fixing) RateFloat64() float64 {
+	return rateFloat64(f.Rate)
+}
+
+var (
+	// statement of SnapshotDay: copies the daily rates of a day of all currency pairs, unless the day already has a snapshot
+	insertFixingStmt = fmt.Sprintf(`INSERT INTO %[1]s.%[2]s (day, from_currency, to_currency, rate, exchange_rate_id, rate_modified_at, source, source_run_id, fetched_at)
+		SELECT v.day, v.from_currency, v.to_currency, v.rate, v.id, v.last_modified_at, v.source, v.source_run_id, v.fetched_at
+		FROM %[1]s.%[3]s v
+		WHERE v.frequency = 'D' AND v.day = $1 AND NOT EXISTS (SELECT 1 FROM %[1]s.%[2]s f WHERE f.day = $1);`, schemaName, fixingTableName, viewName)
+
+	// statement of SelectFixing
+	selectFixingStmt = fmt.Sprintf(`SELECT id, snapshot_at, day, from_currency, to_currency, rate, exchange_rate_id, rate_modified_at, source, source_run_id, fetched_at
+		FROM %s.%s WHERE day = $1 ORDER BY from_currency, to_currency;`, schemaName, fixingTableName)
+)
+
+// SnapshotDay returns the snapshot of the daily rates of day, taking it first if the day has none: all currency pairs stored for the day are copied with their provenance
+// into ecb.exchange_rate_fixing, so that end-of-day jobs, e.g. valuations, consume a set of rates which stays the same even if the ECB revises a rate and a sync updates it
+// a snapshot is taken once and never changed: calling SnapshotDay again returns it as first taken, and the table rejects updates and deletes
+// concurrent calls for the same day take a single snapshot. Returns cerrors.ErrNoRows if there are no daily rates of day, e.g. on a TARGET closing day, and then nothing is stored
+func (s Store) SnapshotDay(ctx context.Context, day time.Time) (items []Fixing, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	dayStr := day.Format(lystype.DateFormat)
+
+	tx, err := s.Db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("s.Db.Begin failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// serializes the snapshots of a day, so that a concurrent call doesn't add pairs to a snapshot taken meanwhile
+	if _, err = tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1), ($2::date - date '2000-01-01'));", schemaName+"."+fixingTableName, dayStr); err != nil {
+		return nil, fmt.Errorf("tx.Exec (lock) failed: %w", cerrors.FromPg(err))
+	}
+
+	if _, err = tx.Exec(ctx, insertFixingStmt, dayStr); err != nil {
+		return nil, fmt.Errorf("tx.Exec (insert) failed: %w", cerrors.FromPg(err))
+	}
+
+	rows, _ := tx.Query(ctx, selectFixingStmt, dayStr)
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[Fixing])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+	if len(items) == 0 {
+		return nil, cerrors.ErrNoRows
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("tx.Commit failed: %w", err)
+	}
+
+	return items, nil
+}
+
+// SelectFixing returns the snapshot of the daily rates of day taken by SnapshotDay, ordered by from and to currency, without taking one
+// returns cerrors.ErrNoRows if the day has no snapshot
+func (s Store) SelectFixing(ctx context.Context, day time.Time) (items []Fixing, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	rows, _ := s.Db.Query(ctx, selectFixingStmt, day.Format(lystype.DateFormat))
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[Fixing])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+	if len(items) == 0 {
+		return nil, cerrors.ErrNoRows
+	}
+
+	return items, nil
+}
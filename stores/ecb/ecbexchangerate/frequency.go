@@ -0,0 +1,34 @@
+package ecbexchangerate
+
+import (
+	"fmt"
+
+	"github.com/loveyourstack/connectors/cerrors"
+)
+
+// Frequency is the frequency of a rate, stored as the ecb.frequency enum in Postgres and checked by a constraint in SQLite
+// ecbapi.Frequency is an alias of it
+type Frequency string
+
+const (
+	Daily   Frequency = "D"
+	Monthly Frequency = "M"
+)
+
+func (f Frequency) String() string {
+	return string(f)
+}
+
+// Valid returns true if f is Daily or Monthly. Values are case sensitive: "d" is not valid
+func (f Frequency) Valid() bool {
+	return f == Daily || f == Monthly
+}
+
+// ParseFrequency returns the Frequency of s, or an error matching cerrors.ErrValidationFailed if s is not one
+func ParseFrequency(s string) (Frequency, error) {
+
+	if f := Frequency(s); f.Valid() {
+		return f, nil
+	}
+	return "", fmt.Errorf("%w: invalid frequency '%s': must be %s or %s", cerrors.ErrValidationFailed, s, Daily, Monthly)
+}
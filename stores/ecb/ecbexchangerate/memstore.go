@@ -0,0 +1,352 @@
+package ecbexchangerate
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// Storer is implemented by Store, MemStore and SQLiteStore
+type Storer interface {
+	BulkDelete(ctx context.Context, ids []int64) error
+	BulkInsert(ctx context.Context, inputs []Input) (rowsAffected int64, err error)
+	BulkUpdate(ctx context.Context, inputs []Input, ids []int64) error
+	Delete(ctx context.Context, id int64) error
+	Equal(a, b Model) bool
+	Insert(ctx context.Context, input Input) (newId int64, err error)
+	SelectDayOnOrBefore(ctx context.Context, baseCurr string, freq Frequency, day time.Time, maxFallbackDays int) (actualDay time.Time, items []Model, err error)
+	SelectInRange(ctx context.Context, baseCurr string, freq Frequency, startDate, endDate time.Time) (items []Model, err error)
+	SelectLatestDay(ctx context.Context, freq Frequency) (latestDay time.Time, count int64, err error)
+	SelectMapByNaturalKey(ctx context.Context, baseCurr string, freq Frequency, startDate, endDate time.Time) (itemsMap map[NaturalKey]Model, err error)
+	SelectPageAfter(ctx context.Context, baseCurr string, freq Frequency, startDate, endDate time.Time, after NaturalKey, limit int) (items []Model, err error)
+	Update(ctx context.Context, input Input, id int64) error
+}
+
+var (
+	_ Storer = Store{}
+	_ Storer = (*MemStore)(nil)
+	_ Storer = SQLiteStore{}
+)
+
+// CurrencyMapper resolves currency codes to ids, e.g. ecbcurrency.Store or ecbcurrency.MemStore
+type CurrencyMapper interface {
+	SelectCodeIdMap(ctx context.Context) (codeIdMap map[string]int64, err error)
+}
+
+// MemStore is a map-based Storer for unit tests and for use without Postgres, e.g. to back a converter.Converter. It is safe for concurrent use
+// as the exchange rate table, it stores currency fks: currency codes are resolved with Currencies, as the v_exchange_rate view does
+type MemStore struct {
+	Currencies CurrencyMapper
+	Duplicates DuplicatePolicy // what BulkInsert does with inputs of the same key. Defaults to DuplicatesFail
+
+	mu     sync.RWMutex
+	items  map[int64]Model
+	keys   map[uniqueKey]int64 // natural key index: k = uniqueKeyOf, v = id
+	nextId int64
+}
+
+func NewMemStore(currencies CurrencyMapper) *MemStore {
+	return &MemStore{Currencies: currencies, items: make(map[int64]Model), keys: make(map[uniqueKey]int64), nextId: 1}
+}
+
+// BulkInsert inserts all of inputs or none of them, as the single statement of Store. Inputs of the same key are handled by s.Duplicates
+func (s *MemStore) BulkInsert(ctx context.Context, inputs []Input) (rowsAffected int64, err error) {
+
+	inputs, _, err = Dedup(inputs, s.Duplicates)
+	if err != nil {
+		return 0, fmt.Errorf("Dedup failed: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// all or nothing, as the single statement of Store
+	for _, input := range inputs {
+		if err := checkFrequency(input); err != nil {
+			return 0, err
+		}
+		key := uniqueKeyOf(input)
+		if _, exists := s.keys[key]; exists {
+			return 0, fmt.Errorf("%w: natural key already exists: %s", cerrors.ErrConflictPolicyViolation, key)
+		}
+	}
+
+	for _, input := range inputs {
+		s.insert(input)
+	}
+
+	return int64(len(inputs)), nil
+}
+
+// Delete returns cerrors.ErrNoRows if id is not found, as Store does
+// BulkDelete deletes the items of ids, stopping at the first not found
+func (s *MemStore) BulkDelete(ctx context.Context, ids []int64) error {
+
+	for _, id := range ids {
+		if err := s.Delete(ctx, id); err != nil {
+			return fmt.Errorf("s.Delete failed on ID: %v: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// BulkUpdate updates the items of ids with the inputs at the same index, stopping at the first failure
+func (s *MemStore) BulkUpdate(ctx context.Context, inputs []Input, ids []int64) error {
+
+	if len(inputs) != len(ids) {
+		return fmt.Errorf("%w: len(inputs) is %v but len(ids) is %v", cerrors.ErrValidationFailed, len(inputs), len(ids))
+	}
+	for i, input := range inputs {
+		if err := s.Update(ctx, input, ids[i]); err != nil {
+			return fmt.Errorf("s.Update failed on ID: %v: %w", ids[i], err)
+		}
+	}
+
+	return nil
+}
+
+func (s *MemStore) Delete(ctx context.Context, id int64) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[id]
+	if !ok {
+		return cerrors.ErrNoRows
+	}
+	delete(s.items, id)
+	delete(s.keys, uniqueKeyOf(item.Input))
+
+	return nil
+}
+
+func (s *MemStore) Equal(a, b Model) bool {
+	return Store{}.Equal(a, b)
+}
+
+// Insert returns an error if the natural key (frequency, day, from and to currency) already exists, as the unique constraint of the table does
+func (s *MemStore) Insert(ctx context.Context, input Input) (newId int64, err error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := checkFrequency(input); err != nil {
+		return 0, err
+	}
+	key := uniqueKeyOf(input)
+	if _, exists := s.keys[key]; exists {
+		return 0, fmt.Errorf("%w: natural key already exists: %s", cerrors.ErrConflictPolicyViolation, key)
+	}
+
+	return s.insert(input), nil
+}
+
+// SelectDayOnOrBefore returns the rates from baseCurr with frequency freq of the most recent day on or before day, looking back at most maxFallbackDays calendar days
+// actualDay is the day of the returned rates. Returns cerrors.ErrNoRows if there are none in that window
+func (s *MemStore) SelectDayOnOrBefore(ctx context.Context, baseCurr string, freq Frequency, day time.Time, maxFallbackDays int) (actualDay time.Time, items []Model, err error) {
+
+	items, err = s.SelectInRange(ctx, baseCurr, freq, day.AddDate(0, 0, -maxFallbackDays), day)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("s.SelectInRange failed: %w", err)
+	}
+	if len(items) == 0 {
+		return time.Time{}, nil, cerrors.ErrNoRows
+	}
+
+	// items are ordered by day: keep those of the last one
+	actualDay = time.Time(items[len(items)-1].Day)
+	first := sort.Search(len(items), func(i int) bool { return !time.Time(items[i].Day).Before(actualDay) })
+
+	return actualDay, items[first:], nil
+}
+
+// SelectInRange returns the rates from baseCurr with frequency freq between startDate and endDate (inclusive), ordered by day and to_currency
+func (s *MemStore) SelectInRange(ctx context.Context, baseCurr string, freq Frequency, startDate, endDate time.Time) (items []Model, err error) {
+
+	idCodeMap, err := s.selectIdCodeMap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("s.selectIdCodeMap failed: %w", err)
+	}
+
+	start, end := startDate.Format(lystype.DateFormat), endDate.Format(lystype.DateFormat)
+
+	s.mu.RLock()
+	for _, item := range s.items {
+		item.FromCurrency, item.ToCurrency = idCodeMap[item.FromCurrencyFk], idCodeMap[item.ToCurrencyFk]
+		day := item.Day.Format(lystype.DateFormat)
+		if item.FromCurrency == baseCurr && item.Frequency == freq && day >= start && day <= end {
+			items = append(items, item)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		dayI, dayJ := time.Time(items[i].Day), time.Time(items[j].Day)
+		if !dayI.Equal(dayJ) {
+			return dayI.Before(dayJ)
+		}
+		return items[i].ToCurrency < items[j].ToCurrency
+	})
+
+	return items, nil
+}
+
+// SelectLatestDay returns the most recent day and the total row count of the rates with frequency freq. latestDay is zero if there are none
+func (s *MemStore) SelectLatestDay(ctx context.Context, freq Frequency) (latestDay time.Time, count int64, err error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, item := range s.items {
+		if item.Frequency != freq {
+			continue
+		}
+		count++
+		if day := time.Time(item.Day); day.After(latestDay) {
+			latestDay = day
+		}
+	}
+
+	return latestDay, count, nil
+}
+
+func (s *MemStore) SelectMapByNaturalKey(ctx context.Context, baseCurr string, freq Frequency, startDate, endDate time.Time) (itemsMap map[NaturalKey]Model, err error) {
+
+	items, err := s.SelectInRange(ctx, baseCurr, freq, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("s.SelectInRange failed: %w", err)
+	}
+
+	// convert to map, without the view columns, as Store does
+	itemsMap = make(map[NaturalKey]Model, len(items))
+	for _, item := range items {
+		itemsMap[KeyOf(item.Input)] = Model{Id: item.Id, Input: item.Input}
+	}
+
+	return itemsMap, nil
+}
+
+// SelectPageAfter returns up to limit rates from baseCurr with frequency freq in the date range whose NaturalKey sorts after after, in NaturalKey order
+// unlike Store, it filters the whole range for each page
+func (s *MemStore) SelectPageAfter(ctx context.Context, baseCurr string, freq Frequency, startDate, endDate time.Time, after NaturalKey, limit int) (items []Model, err error) {
+
+	rangeItems, err := s.SelectInRange(ctx, baseCurr, freq, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("s.SelectInRange failed: %w", err)
+	}
+
+	for _, item := range rangeItems {
+		if KeyOf(item.Input).Compare(after) > 0 {
+			items = append(items, item)
+		}
+	}
+	slices.SortFunc(items, func(a, b Model) int { return KeyOf(a.Input).Compare(KeyOf(b.Input)) })
+
+	return items[:min(limit, len(items))], nil
+}
+
+// Update returns cerrors.ErrNoRows if id is not found, as Store does
+func (s *MemStore) Update(ctx context.Context, input Input, id int64) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := checkFrequency(input); err != nil {
+		return err
+	}
+	item, ok := s.items[id]
+	if !ok {
+		return cerrors.ErrNoRows
+	}
+	oldKey, newKey := uniqueKeyOf(item.Input), uniqueKeyOf(input)
+	if existingId, exists := s.keys[newKey]; exists && existingId != id {
+		return fmt.Errorf("%w: natural key already exists: %s", cerrors.ErrConflictPolicyViolation, newKey)
+	}
+
+	input.LastModifiedAt = lystype.Datetime(time.Now())
+	input.Rate = roundRate(input.Rate)
+	item.Input = input
+	s.items[id] = item
+
+	delete(s.keys, oldKey)
+	s.keys[newKey] = id
+
+	return nil
+}
+
+// insert adds input without checks. s.mu must be held
+func (s *MemStore) insert(input Input) (newId int64) {
+
+	input.Rate = roundRate(input.Rate)
+
+	newId = s.nextId
+	s.nextId++
+	s.items[newId] = Model{Id: newId, EntryAt: lystype.Datetime(time.Now()), Input: input}
+	s.keys[uniqueKeyOf(input)] = newId
+
+	return newId
+}
+
+// selectIdCodeMap returns the currency codes keyed by id
+func (s *MemStore) selectIdCodeMap(ctx context.Context) (idCodeMap map[int64]string, err error) {
+
+	codeIdMap, err := s.Currencies.SelectCodeIdMap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("s.Currencies.SelectCodeIdMap failed: %w", err)
+	}
+
+	idCodeMap = make(map[int64]string, len(codeIdMap))
+	for code, id := range codeIdMap {
+		idCodeMap[id] = code
+	}
+
+	return idCodeMap, nil
+}
+
+// uniqueKey is the key of the unique constraint of the table: frequency, day, from and to currency
+type uniqueKey struct {
+	NaturalKey
+	Frequency      Frequency
+	FromCurrencyFk int64
+}
+
+func uniqueKeyOf(input Input) uniqueKey {
+	return uniqueKey{NaturalKey: KeyOf(input), Frequency: input.Frequency, FromCurrencyFk: input.FromCurrencyFk}
+}
+
+func (k uniqueKey) String() string {
+	return fmt.Sprintf("%s+%s+%d+%d", k.Frequency, k.day(), k.FromCurrencyFk, k.ToCurrencyFk)
+}
+
+// roundRate rounds to the 8 decimals stored by the numeric(18,8) rate column
+func roundRate(rate float32) float32 {
+	return float32(math.Round(float64(rate)*1e8) / 1e8)
+}
+
+// rateFloat64 returns rate with the fewest decimals, at most the 8 stored, which convert back to rate: the figure parsed into the float32, as published
+func rateFloat64(rate float32) float64 {
+	pow := 1.0
+	for range 8 {
+		if r := math.Round(float64(rate)*pow) / pow; float32(r) == rate {
+			return r
+		}
+		pow *= 10
+	}
+	return math.Round(float64(rate)*pow) / pow
+}
+
+// checkFrequency rejects the inputs which the frequency enum of Store and the check constraint of SQLiteStore would reject
+func checkFrequency(input Input) error {
+	if !input.Frequency.Valid() {
+		return fmt.Errorf("%w: invalid frequency '%s'", cerrors.ErrValidationFailed, input.Frequency)
+	}
+	return nil
+}
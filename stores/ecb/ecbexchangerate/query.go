@@ -0,0 +1,103 @@
+package ecbexchangerate
+
+import (
+	"slices"
+	"time"
+
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// Query builds the conditions of the common rate queries for Store.Select, e.g.
+//
+//	params := ecbexchangerate.Query{}.ForPair("EUR", "USD").InRange(start, end).DailyOnly().Params()
+//
+// each method returns a new Query, so a partial query can be reused as the base of others. The zero Query matches all rates
+type Query struct {
+	conds []lyspg.Condition
+	order Order
+}
+
+// Order is an order of the rates returned by Store.Select, applied with Query.OrderBy or through the Sorts of lyspg.SelectParams
+type Order int
+
+const (
+	// OrderNaturalKey orders by day, from_currency, to_currency and frequency: the key of the table's unique constraint, so pages are stable. It is the default
+	OrderNaturalKey Order = iota
+
+	// OrderNaturalKeyDesc is OrderNaturalKey with the latest day first
+	OrderNaturalKeyDesc
+
+	// OrderInsertion orders by id, the order in which the rates were inserted
+	OrderInsertion
+)
+
+// Sorts returns the lyspg.SelectParams Sorts of o
+func (o Order) Sorts() []string {
+
+	switch o {
+	case OrderNaturalKeyDesc:
+		return []string{"day DESC", "from_currency", "to_currency", "frequency"}
+	case OrderInsertion:
+		return []string{"id"}
+	default:
+		return []string{"day", "from_currency", "to_currency", "frequency"}
+	}
+}
+
+// ForPair restricts q to the rates from fromCurr to toCurr. An empty toCurr matches the rates from fromCurr to all currencies
+func (q Query) ForPair(fromCurr, toCurr string) Query {
+
+	q = q.with(lyspg.Condition{Field: "from_currency", Operator: lyspg.OpEquals, Value: fromCurr})
+	if toCurr != "" {
+		q = q.with(lyspg.Condition{Field: "to_currency", Operator: lyspg.OpEquals, Value: toCurr})
+	}
+	return q
+}
+
+// ToCurrencies restricts q to the rates to any of toCurrs. No toCurrs matches the rates to all currencies
+func (q Query) ToCurrencies(toCurrs ...string) Query {
+
+	if len(toCurrs) == 0 {
+		return q
+	}
+	return q.with(lyspg.Condition{Field: "to_currency", Operator: lyspg.OpIn, InValues: toCurrs})
+}
+
+// InRange restricts q to the rates between startDate and endDate (inclusive). Only their dates are used
+func (q Query) InRange(startDate, endDate time.Time) Query {
+	return q.with(
+		lyspg.Condition{Field: "day", Operator: lyspg.OpGreaterThanEquals, Value: startDate.Format(lystype.DateFormat)},
+		lyspg.Condition{Field: "day", Operator: lyspg.OpLessThanEquals, Value: endDate.Format(lystype.DateFormat)},
+	)
+}
+
+// WithFrequency restricts q to the rates with frequency freq
+func (q Query) WithFrequency(freq Frequency) Query {
+	return q.with(lyspg.Condition{Field: "frequency", Operator: lyspg.OpEquals, Value: freq.String()})
+}
+
+// DailyOnly restricts q to daily rates, excluding the monthly averages
+func (q Query) DailyOnly() Query {
+	return q.WithFrequency(Daily)
+}
+
+// OrderBy sets the order of the rates returned by q, OrderNaturalKey by default
+func (q Query) OrderBy(order Order) Query {
+	q.order = order
+	return q
+}
+
+// Params returns the lyspg.SelectParams of q. Fields, Limit and the like can be set on the result
+func (q Query) Params() lyspg.SelectParams {
+	return lyspg.SelectParams{
+		Conditions: slices.Clone(q.conds),
+		Sorts:      q.order.Sorts(),
+	}
+}
+
+// with returns a copy of q with conds appended, never sharing the backing array of q's conditions
+func (q Query) with(conds ...lyspg.Condition) Query {
+	q.conds = append(slices.Clip(q.conds), conds...)
+	return q
+}
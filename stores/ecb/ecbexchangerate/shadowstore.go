@@ -0,0 +1,260 @@
+package ecbexchangerate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// shadowComment marks the schemas created by CreateShadow, so that it never drops a schema it did not create
+const shadowComment string = "connectors: shadow of ecb.exchange_rate"
+
+// shadowSchemaRegex matches the schema names accepted for a shadow: unquoted lower case identifiers, e.g. ecb_shadow
+var shadowSchemaRegex = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// ShadowStore is a Storer of the snapshot of ecb.exchange_rate in Schema made by CreateShadow, e.g. to simulate a sync with new diff logic or Equal semantics
+// on production-scale data without touching the live table. Currency ids are those of ecb.currency, which the snapshot shares. It is safe for concurrent use
+// writes to the snapshot are not logged to ecb.exchange_rate_version
+type ShadowStore struct {
+	Db         *pgxpool.Pool
+	Schema     string          // of the snapshot, e.g. "ecb_shadow"
+	Timeout    time.Duration   // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+	Duplicates DuplicatePolicy // what BulkInsert does with inputs of the same key. Defaults to DuplicatesFail
+}
+
+var _ Storer = ShadowStore{}
+
+// ValidateShadowSchema returns an error matching cerrors.ErrValidationFailed if schema is not usable for a snapshot. It is checked before schema is used in a statement
+func ValidateShadowSchema(schema string) error {
+
+	if !shadowSchemaRegex.MatchString(schema) {
+		return fmt.Errorf("%w: invalid shadow schema: '%s': must be a lower case identifier", cerrors.ErrValidationFailed, schema)
+	}
+	if schema == schemaName {
+		return fmt.Errorf("%w: the shadow schema must not be the live schema %s", cerrors.ErrValidationFailed, schemaName)
+	}
+	return nil
+}
+
+// CreateShadow snapshots ecb.exchange_rate into schema, replacing the snapshot of a previous call, and returns the number of rates copied
+// schema gets a copy of the table, with the same ids, constraints and indexes, and a v_exchange_rate view, which ShadowStore reads. An existing schema which CreateShadow
+// did not create is not replaced: an error matching cerrors.ErrValidationFailed is returned
+func CreateShadow(ctx context.Context, db *pgxpool.Pool, schema string) (rows int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, 0)
+	defer cancel()
+
+	if err = ValidateShadowSchema(schema); err != nil {
+		return 0, fmt.Errorf("ValidateShadowSchema failed: %w", err)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("db.Begin failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var comment *string
+	err = tx.QueryRow(ctx, "SELECT obj_description(oid, 'pg_namespace') FROM pg_namespace WHERE nspname = $1;", schema).Scan(&comment)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		return 0, fmt.Errorf("tx.QueryRow (schema comment) failed: %w", cerrors.FromPg(err))
+	case comment == nil || *comment != shadowComment:
+		return 0, fmt.Errorf("%w: schema %s exists and is not a shadow made by CreateShadow", cerrors.ErrValidationFailed, schema)
+	}
+
+	stmts := []string{
+		fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE;", schema),
+		fmt.Sprintf("CREATE SCHEMA %s;", schema),
+		fmt.Sprintf("COMMENT ON SCHEMA %s IS '%s';", schema, shadowComment),
+		fmt.Sprintf("CREATE TABLE %[1]s.%[2]s (LIKE %[3]s.%[2]s INCLUDING ALL);", schema, tableName, schemaName),
+	}
+	for _, stmt := range stmts {
+		if _, err = tx.Exec(ctx, stmt); err != nil {
+			return 0, fmt.Errorf("tx.Exec failed: %s: %w", stmt, cerrors.FromPg(err))
+		}
+	}
+
+	tag, err := tx.Exec(ctx, fmt.Sprintf("INSERT INTO %[1]s.%[2]s SELECT * FROM %[3]s.%[2]s;", schema, tableName, schemaName))
+	if err != nil {
+		return 0, fmt.Errorf("tx.Exec (copy) failed: %w", cerrors.FromPg(err))
+	}
+	rows = tag.RowsAffected()
+
+	// the copied ids were given explicitly: new rates must be numbered after them
+	stmts = []string{
+		fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%[1]s.%[2]s', 'id'), coalesce((SELECT max(id) FROM %[1]s.%[2]s), 0) + 1, false);", schema, tableName),
+		viewStmt(schema),
+	}
+	for _, stmt := range stmts {
+		if _, err = tx.Exec(ctx, stmt); err != nil {
+			return 0, fmt.Errorf("tx.Exec failed: %s: %w", stmt, cerrors.FromPg(err))
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("tx.Commit failed: %w", err)
+	}
+
+	if _, err = db.Exec(ctx, fmt.Sprintf("ANALYZE %s.%s;", schema, tableName)); err != nil {
+		return rows, fmt.Errorf("db.Exec (analyze) failed: %w", cerrors.FromPg(err))
+	}
+
+	return rows, nil
+}
+
+// BulkDelete deletes the items of ids in a single round trip
+func (s ShadowStore) BulkDelete(ctx context.Context, ids []int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	if len(ids) == 0 {
+		return nil
+	}
+	return cerrors.FromPg(lyspg.BulkDelete(ctx, s.Db, s.Schema, tableName, pkColName, ids))
+}
+
+// BulkInsert inserts inputs in a single statement. Inputs of the same key are handled by s.Duplicates before anything is written
+func (s ShadowStore) BulkInsert(ctx context.Context, inputs []Input) (rowsAffected int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	inputs, _, err = Dedup(inputs, s.Duplicates)
+	if err != nil {
+		return 0, fmt.Errorf("Dedup failed: %w", err)
+	}
+	rowsAffected, err = lyspg.BulkInsert[Input](ctx, s.Db, s.Schema, tableName, inputs)
+	return rowsAffected, cerrors.FromPg(err)
+}
+
+// BulkUpdate updates the items of ids with the inputs at the same index in a single round trip. The inputs are not modified
+func (s ShadowStore) BulkUpdate(ctx context.Context, inputs []Input, ids []int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	if len(inputs) == 0 {
+		return nil
+	}
+	now := lystype.Datetime(time.Now())
+	inputs = slices.Clone(inputs)
+	for i := range inputs {
+		inputs[i].LastModifiedAt = now
+	}
+	return cerrors.FromPg(lyspg.BulkUpdate[Input](ctx, s.Db, s.Schema, tableName, pkColName, inputs, ids))
+}
+
+func (s ShadowStore) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, s.Schema, tableName, pkColName, id))
+}
+
+// Equal is that of Store, so that a simulation compares rates as a sync of the live table would
+func (s ShadowStore) Equal(a, b Model) bool {
+	return Store{}.Equal(a, b)
+}
+
+func (s ShadowStore) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, s.Schema, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+// SelectDayOnOrBefore is that of Store, reading the snapshot
+func (s ShadowStore) SelectDayOnOrBefore(ctx context.Context, baseCurr string, freq Frequency, day time.Time, maxFallbackDays int) (actualDay time.Time, items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	rows, _ := s.Db.Query(ctx, dayOnOrBeforeStmt(s.Schema), baseCurr, freq, day.Format(lystype.DateFormat), day.AddDate(0, 0, -maxFallbackDays).Format(lystype.DateFormat))
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+	if len(items) == 0 {
+		return time.Time{}, nil, cerrors.ErrNoRows
+	}
+
+	return time.Time(items[0].Day), items, nil
+}
+
+// SelectInRange is that of Store, reading the snapshot
+func (s ShadowStore) SelectInRange(ctx context.Context, baseCurr string, freq Frequency, startDate, endDate time.Time) (items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	items, _, err = lyspg.Select[Model](ctx, s.Db, s.Schema, tableName, viewName, defaultOrderBy, meta.DbTags,
+		Query{}.ForPair(baseCurr, "").WithFrequency(freq).InRange(startDate, endDate).Params())
+	if err != nil {
+		return nil, fmt.Errorf("lyspg.Select failed: %w", err)
+	}
+
+	return items, nil
+}
+
+// SelectLatestDay is that of Store, reading the snapshot
+func (s ShadowStore) SelectLatestDay(ctx context.Context, freq Frequency) (latestDay time.Time, count int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	var day *time.Time
+	err = s.Db.QueryRow(ctx, latestDayStmt(s.Schema), freq).Scan(&day, &count)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("s.Db.QueryRow failed: %w", err)
+	}
+	if day != nil {
+		latestDay = *day
+	}
+
+	return latestDay, count, nil
+}
+
+// SelectMapByNaturalKey is like SelectInRange, but returns a map with NaturalKey as key
+func (s ShadowStore) SelectMapByNaturalKey(ctx context.Context, baseCurr string, freq Frequency, startDate, endDate time.Time) (itemsMap map[NaturalKey]Model, err error) {
+
+	items, err := s.SelectInRange(ctx, baseCurr, freq, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("s.SelectInRange failed: %w", err)
+	}
+
+	// convert to map, without the view columns
+	itemsMap = make(map[NaturalKey]Model, len(items))
+	for _, dbItem := range items {
+		itemsMap[KeyOf(dbItem.Input)] = Model{Id: dbItem.Id, Input: dbItem.Input}
+	}
+
+	return itemsMap, nil
+}
+
+// SelectPageAfter is that of Store, reading the snapshot
+func (s ShadowStore) SelectPageAfter(ctx context.Context, baseCurr string, freq Frequency, startDate, endDate time.Time, after NaturalKey, limit int) (items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	rows, _ := s.Db.Query(ctx, pageAfterStmt(s.Schema), baseCurr, freq, startDate.Format(lystype.DateFormat), endDate.Format(lystype.DateFormat), after.day(), after.ToCurrencyFk, limit)
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	return items, nil
+}
+
+func (s ShadowStore) Update(ctx context.Context, input Input, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	input.LastModifiedAt = lystype.Datetime(time.Now())
+	return cerrors.FromPg(lyspg.Update[Input](ctx, s.Db, s.Schema, tableName, pkColName, input, id))
+}
@@ -0,0 +1,336 @@
+package ecbexchangerate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/sqlitedb"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	sqliteTableName string = "ecb_exchange_rate"
+	sqliteViewName  string = "v_ecb_exchange_rate"
+)
+
+// SQLiteStore is a Storer using a SQLite database opened with sqlitedb.Open, e.g. to back a converter.Converter without Postgres. It is safe for concurrent use
+// currency codes are resolved by the v_ecb_exchange_rate view from the ecb_currency table of the same database, see ecbcurrency.SQLiteStore
+// the provenance of the rates (Source, SourceRunId and FetchedAt) is not stored
+type SQLiteStore struct {
+	Db         *sql.DB
+	Duplicates DuplicatePolicy // what BulkInsert does with inputs of the same key. Defaults to DuplicatesFail
+}
+
+// BulkDelete deletes the items of ids in a transaction. If an id is not found, none are deleted
+func (s SQLiteStore) BulkDelete(ctx context.Context, ids []int64) error {
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("s.Db.BeginTx failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range ids {
+		if err = sqliteExecOne(ctx, tx, "DELETE FROM "+sqliteTableName+" WHERE id = ?;", id); err != nil {
+			return fmt.Errorf("sqliteExecOne failed on ID: %v: %w", id, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("tx.Commit failed: %w", err)
+	}
+
+	return nil
+}
+
+// BulkInsert inserts all of inputs or none of them, as the single statement of Store. Inputs of the same key are handled by s.Duplicates
+func (s SQLiteStore) BulkInsert(ctx context.Context, inputs []Input) (rowsAffected int64, err error) {
+
+	inputs, _, err = Dedup(inputs, s.Duplicates)
+	if err != nil {
+		return 0, fmt.Errorf("Dedup failed: %w", err)
+	}
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.BeginTx failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, sqliteInsertStmt)
+	if err != nil {
+		return 0, fmt.Errorf("tx.PrepareContext failed: %w", err)
+	}
+	defer stmt.Close()
+
+	entryAt := sqlitedb.FormatTime(time.Now())
+	for _, input := range inputs {
+		if _, err = stmt.ExecContext(ctx, sqliteInsertArgs(input, entryAt)...); err != nil {
+			return 0, fmt.Errorf("stmt.ExecContext failed: %w", cerrors.FromSQLite(err))
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("tx.Commit failed: %w", err)
+	}
+
+	return int64(len(inputs)), nil
+}
+
+// BulkUpdate updates the items of ids with the inputs at the same index in a transaction. If an id is not found, none are updated
+func (s SQLiteStore) BulkUpdate(ctx context.Context, inputs []Input, ids []int64) error {
+
+	if len(inputs) != len(ids) {
+		return fmt.Errorf("%w: len(inputs) is %v but len(ids) is %v", cerrors.ErrValidationFailed, len(inputs), len(ids))
+	}
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("s.Db.BeginTx failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	lastModifiedAt := sqlitedb.FormatTime(time.Now())
+	for i, input := range inputs {
+		if err = sqliteExecOne(ctx, tx, sqliteUpdateStmt, sqliteUpdateArgs(input, lastModifiedAt, ids[i])...); err != nil {
+			return fmt.Errorf("sqliteExecOne failed on ID: %v: %w", ids[i], err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("tx.Commit failed: %w", err)
+	}
+
+	return nil
+}
+
+// Delete returns cerrors.ErrNoRows if id is not found, as Store does
+func (s SQLiteStore) Delete(ctx context.Context, id int64) error {
+
+	res, err := s.Db.ExecContext(ctx, "DELETE FROM "+sqliteTableName+" WHERE id = ?;", id)
+	if err != nil {
+		return fmt.Errorf("s.Db.ExecContext failed: %w", cerrors.FromSQLite(err))
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected failed: %w", err)
+	}
+	if n == 0 {
+		return cerrors.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s SQLiteStore) Equal(a, b Model) bool {
+	return Store{}.Equal(a, b)
+}
+
+// Insert returns an error matching cerrors.ErrConflictPolicyViolation if the natural key (frequency, day, from and to currency) already exists
+func (s SQLiteStore) Insert(ctx context.Context, input Input) (newId int64, err error) {
+
+	res, err := s.Db.ExecContext(ctx, sqliteInsertStmt, sqliteInsertArgs(input, sqlitedb.FormatTime(time.Now()))...)
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.ExecContext failed: %w", cerrors.FromSQLite(err))
+	}
+
+	newId, err = res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("res.LastInsertId failed: %w", err)
+	}
+
+	return newId, nil
+}
+
+// SelectDayOnOrBefore returns the rates from baseCurr with frequency freq of the most recent day on or before day, looking back at most maxFallbackDays calendar days
+// actualDay is the day of the returned rates. Returns cerrors.ErrNoRows if there are none in that window
+func (s SQLiteStore) SelectDayOnOrBefore(ctx context.Context, baseCurr string, freq Frequency, day time.Time, maxFallbackDays int) (actualDay time.Time, items []Model, err error) {
+
+	stmt := "SELECT max(day) FROM " + sqliteViewName + " WHERE from_currency = ? AND frequency = ? AND day <= ? AND day >= ?;"
+
+	var foundDay sql.NullString
+	err = s.Db.QueryRowContext(ctx, stmt, baseCurr, freq, day.Format(lystype.DateFormat), day.AddDate(0, 0, -maxFallbackDays).Format(lystype.DateFormat)).Scan(&foundDay)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("s.Db.QueryRowContext failed: %w", err)
+	}
+	if !foundDay.Valid {
+		return time.Time{}, nil, cerrors.ErrNoRows
+	}
+
+	actualDay, err = time.Parse(lystype.DateFormat, foundDay.String)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("time.Parse failed: %w", err)
+	}
+
+	items, err = s.SelectInRange(ctx, baseCurr, freq, actualDay, actualDay)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("s.SelectInRange failed: %w", err)
+	}
+
+	return actualDay, items, nil
+}
+
+// SelectInRange returns the rates from baseCurr with frequency freq between startDate and endDate (inclusive), ordered by day and to_currency
+func (s SQLiteStore) SelectInRange(ctx context.Context, baseCurr string, freq Frequency, startDate, endDate time.Time) (items []Model, err error) {
+
+	stmt := `SELECT ` + sqliteModelCols + ` FROM ` + sqliteViewName + ` WHERE from_currency = ? AND frequency = ? AND day >= ? AND day <= ? ORDER BY day, to_currency;`
+
+	items, err = s.selectModels(ctx, stmt, baseCurr, freq, startDate.Format(lystype.DateFormat), endDate.Format(lystype.DateFormat))
+	if err != nil {
+		return nil, fmt.Errorf("s.selectModels failed: %w", err)
+	}
+
+	return items, nil
+}
+
+// SelectPageAfter returns up to limit rates from baseCurr with frequency freq in the date range whose NaturalKey sorts after after, in NaturalKey order
+// to read a range in keyset pages, pass the zero NaturalKey for the first page and the key of the last rate of a page for the next
+func (s SQLiteStore) SelectPageAfter(ctx context.Context, baseCurr string, freq Frequency, startDate, endDate time.Time, after NaturalKey, limit int) (items []Model, err error) {
+
+	stmt := `SELECT ` + sqliteModelCols + ` FROM ` + sqliteViewName + ` WHERE from_currency = ? AND frequency = ? AND day >= ? AND day <= ?
+		AND (day, to_currency_fk) > (?, ?) ORDER BY day, to_currency_fk LIMIT ?;`
+
+	items, err = s.selectModels(ctx, stmt, baseCurr, freq, startDate.Format(lystype.DateFormat), endDate.Format(lystype.DateFormat), after.day(), after.ToCurrencyFk, limit)
+	if err != nil {
+		return nil, fmt.Errorf("s.selectModels failed: %w", err)
+	}
+
+	return items, nil
+}
+
+// sqliteModelCols are the columns of the view scanned by selectModels
+const sqliteModelCols string = "id, frequency, day, from_currency_fk, from_currency, to_currency_fk, to_currency, rate, entry_at, last_modified_at"
+
+// selectModels returns the rates selected by stmt, which must select sqliteModelCols
+func (s SQLiteStore) selectModels(ctx context.Context, stmt string, args ...any) (items []Model, err error) {
+
+	rows, err := s.Db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("s.Db.QueryContext failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item Model
+		var day, entryAt, lastModifiedAt string
+		var rate float64
+		err = rows.Scan(&item.Id, &item.Frequency, &day, &item.FromCurrencyFk, &item.FromCurrency, &item.ToCurrencyFk, &item.ToCurrency, &rate, &entryAt, &lastModifiedAt)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan failed: %w", err)
+		}
+		if err = sqliteParseModel(&item, day, rate, entryAt, lastModifiedAt); err != nil {
+			return nil, fmt.Errorf("sqliteParseModel failed on id: %d: %w", item.Id, err)
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return items, nil
+}
+
+// SelectLatestDay returns the most recent day and the total row count of the rates with frequency freq. latestDay is zero if there are none
+func (s SQLiteStore) SelectLatestDay(ctx context.Context, freq Frequency) (latestDay time.Time, count int64, err error) {
+
+	stmt := "SELECT max(day), count(*) FROM " + sqliteTableName + " WHERE frequency = ?;"
+
+	var day sql.NullString
+	err = s.Db.QueryRowContext(ctx, stmt, freq).Scan(&day, &count)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("s.Db.QueryRowContext failed: %w", err)
+	}
+	if day.Valid {
+		latestDay, err = time.Parse(lystype.DateFormat, day.String)
+		if err != nil {
+			return time.Time{}, 0, fmt.Errorf("time.Parse failed: %w", err)
+		}
+	}
+
+	return latestDay, count, nil
+}
+
+func (s SQLiteStore) SelectMapByNaturalKey(ctx context.Context, baseCurr string, freq Frequency, startDate, endDate time.Time) (itemsMap map[NaturalKey]Model, err error) {
+
+	items, err := s.SelectInRange(ctx, baseCurr, freq, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("s.SelectInRange failed: %w", err)
+	}
+
+	// convert to map, without the view columns, as Store does
+	itemsMap = make(map[NaturalKey]Model, len(items))
+	for _, item := range items {
+		itemsMap[KeyOf(item.Input)] = Model{Id: item.Id, Input: item.Input}
+	}
+
+	return itemsMap, nil
+}
+
+// Update returns cerrors.ErrNoRows if id is not found, as Store does
+func (s SQLiteStore) Update(ctx context.Context, input Input, id int64) error {
+	return sqliteExecOne(ctx, s.Db, sqliteUpdateStmt, sqliteUpdateArgs(input, sqlitedb.FormatTime(time.Now()), id)...)
+}
+
+const sqliteUpdateStmt string = "UPDATE " + sqliteTableName + " SET frequency = ?, day = ?, from_currency_fk = ?, to_currency_fk = ?, rate = ?, last_modified_at = ? WHERE id = ?;"
+
+// sqliteUpdateArgs returns the args of sqliteUpdateStmt
+func sqliteUpdateArgs(input Input, lastModifiedAt string, id int64) []any {
+	return []any{input.Frequency, input.Day.Format(lystype.DateFormat), input.FromCurrencyFk, input.ToCurrencyFk, float64(roundRate(input.Rate)), lastModifiedAt, id}
+}
+
+// sqliteExecOne runs stmt, which must affect a single row, on db or a transaction. It returns cerrors.ErrNoRows if no row was affected
+func sqliteExecOne(ctx context.Context, db interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}, stmt string, args ...any) error {
+
+	res, err := db.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return fmt.Errorf("db.ExecContext failed: %w", cerrors.FromSQLite(err))
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected failed: %w", err)
+	}
+	if n == 0 {
+		return cerrors.ErrNoRows
+	}
+
+	return nil
+}
+
+const sqliteInsertStmt string = "INSERT INTO " + sqliteTableName + " (frequency, day, from_currency_fk, to_currency_fk, rate, entry_at, last_modified_at) VALUES (?, ?, ?, ?, ?, ?, ?);"
+
+// sqliteInsertArgs returns the args of sqliteInsertStmt. The rate is rounded as by the numeric column of Store
+func sqliteInsertArgs(input Input, entryAt string) []any {
+	return []any{input.Frequency, input.Day.Format(lystype.DateFormat), input.FromCurrencyFk, input.ToCurrencyFk, float64(roundRate(input.Rate)),
+		entryAt, sqlitedb.FormatTime(time.Time(input.LastModifiedAt))}
+}
+
+// sqliteParseModel sets the fields of item stored as text or float64
+func sqliteParseModel(item *Model, day string, rate float64, entryAt, lastModifiedAt string) error {
+
+	d, err := time.Parse(lystype.DateFormat, day)
+	if err != nil {
+		return fmt.Errorf("time.Parse failed on day: %w", err)
+	}
+	entry, err := sqlitedb.ParseTime(entryAt)
+	if err != nil {
+		return fmt.Errorf("sqlitedb.ParseTime failed on entry_at: %w", err)
+	}
+	lastModified, err := sqlitedb.ParseTime(lastModifiedAt)
+	if err != nil {
+		return fmt.Errorf("sqlitedb.ParseTime failed on last_modified_at: %w", err)
+	}
+
+	item.Day = lystype.Date(d)
+	item.Rate = float32(rate)
+	item.EntryAt = lystype.Datetime(entry)
+	item.LastModifiedAt = lystype.Datetime(lastModified)
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package ecbexchangerate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+// Stats describes the stored rates, see Store.Stats
+type Stats struct {
+	Rows       int64         `json:"rows"`
+	TableBytes int64         `json:"table_bytes"` // size on disk of the table, including its indexes
+	Series     []SeriesStats `json:"series"`      // per frequency and currency pair, ordered by them
+}
+
+// SeriesStats describes the stored rates of a frequency and currency pair
+type SeriesStats struct {
+	Frequency    Frequency    `db:"frequency" json:"frequency"`
+	FromCurrency string       `db:"from_currency" json:"from_currency"`
+	ToCurrency   string       `db:"to_currency" json:"to_currency"`
+	Rows         int64        `db:"rows" json:"rows"`
+	MinDay       lystype.Date `db:"min_day" json:"min_day"`
+	MaxDay       lystype.Date `db:"max_day" json:"max_day"`
+}
+
+// Frequency returns the row count and the earliest and latest day of the rates with frequency freq. The days are zero if there are none
+func (st Stats) Frequency(freq Frequency) (rows int64, minDay, maxDay time.Time) {
+
+	for _, series := range st.Series {
+		if series.Frequency != freq {
+			continue
+		}
+		rows += series.Rows
+		if minDay.IsZero() || time.Time(series.MinDay).Before(minDay) {
+			minDay = time.Time(series.MinDay)
+		}
+		if time.Time(series.MaxDay).After(maxDay) {
+			maxDay = time.Time(series.MaxDay)
+		}
+	}
+	return rows, minDay, maxDay
+}
+
+// Stats returns the row count, earliest and latest day of each frequency and currency pair, and the size of the table, e.g. for status reports and freshness checks
+// it reads all rates, so is meant for occasional use rather than per request
+func (s Store) Stats(ctx context.Context) (st Stats, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	rows, _ := s.Db.Query(ctx, selectSeriesStatsStmt)
+	st.Series, err = pgx.CollectRows(rows, pgx.RowToStructByName[SeriesStats])
+	if err != nil {
+		return Stats{}, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+	for _, series := range st.Series {
+		st.Rows += series.Rows
+	}
+
+	err = s.Db.QueryRow(ctx, "SELECT pg_total_relation_size($1::regclass);", schemaName+"."+tableName).Scan(&st.TableBytes)
+	if err != nil {
+		return Stats{}, fmt.Errorf("s.Db.QueryRow failed: %w", cerrors.FromPg(err))
+	}
+
+	return st, nil
+}
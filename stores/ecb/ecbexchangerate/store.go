@@ -1,35 +1,47 @@
 package ecbexchangerate
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"log"
 	"reflect"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/connectors/internal/optlock"
 	"github.com/loveyourstack/lys/lysmeta"
 	"github.com/loveyourstack/lys/lyspg"
 	"github.com/loveyourstack/lys/lystype"
 )
 
 const (
-	name           string = "Exchange rates"
-	schemaName     string = "ecb"
-	tableName      string = "exchange_rate"
-	viewName       string = "v_exchange_rate"
-	pkColName      string = "id"
-	defaultOrderBy string = "id"
+	name             string = "Exchange rates"
+	schemaName       string = "ecb"
+	tableName        string = "exchange_rate"
+	viewName         string = "v_exchange_rate"
+	versionTableName string = "exchange_rate_version"
+	latestViewName   string = "mv_latest_exchange_rate"
+	pkColName        string = "id"
+	defaultOrderBy   string = "day, from_currency, to_currency, frequency" // OrderNaturalKey
 )
 
 type Input struct {
-	Day            lystype.Date     `db:"day" json:"day,omitempty" validate:"required"`
-	Frequency      string           `db:"frequency" json:"frequency,omitempty" validate:"required"`
-	FromCurrencyFk int64            `db:"from_currency_fk" json:"from_currency_fk,omitempty" validate:"required"`
-	LastModifiedAt lystype.Datetime `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
-	Rate           float32          `db:"rate" json:"rate,omitempty" validate:"required"`
-	ToCurrencyFk   int64            `db:"to_currency_fk" json:"to_currency_fk,omitempty" validate:"required"`
+	Day            lystype.Date      `db:"day" json:"day,omitempty" validate:"required"`
+	FetchedAt      *lystype.Datetime `db:"fetched_at" json:"fetched_at,omitempty"` // when the rate was fetched from Source
+	Frequency      Frequency         `db:"frequency" json:"frequency,omitempty" validate:"required,oneof=D M"`
+	FromCurrencyFk int64             `db:"from_currency_fk" json:"from_currency_fk,omitempty" validate:"required"`
+	LastModifiedAt lystype.Datetime  `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	Rate           float32           `db:"rate" json:"rate,omitempty" validate:"required"`
+	Source         string            `db:"source" json:"source,omitempty"`               // name of the client the rate was fetched from, e.g. "ecb". Empty if not stored by a sync
+	SourceRunId    *int64            `db:"source_run_id" json:"source_run_id,omitempty"` // id of the connectors.sync_run which stored the value
+	ToCurrencyFk   int64             `db:"to_currency_fk" json:"to_currency_fk,omitempty" validate:"required"`
 }
 
 type Model struct {
@@ -42,6 +54,22 @@ type Model struct {
 
 var (
 	meta, inputMeta lysmeta.Result
+
+	// statements of the converter's lookups, built once so that their text is constant. pgx's default query exec mode, QueryExecModeCacheStatement,
+	// then prepares each once per connection and reuses it, so that a lookup is a single round trip without parsing or planning
+	selectDayOnOrBeforeStmt, selectLatestDayStmt string
+
+	// statement of SelectDayOnOrBeforeAsOf, which reads the value log instead of the table. Its columns are those of the view
+	selectDayOnOrBeforeAsOfStmt string
+
+	// statement of SelectPageAfter, also constant
+	selectPageAfterStmt string
+
+	// statement of SelectLatest
+	selectLatestStmt string
+
+	// statement of Stats. The rates are grouped by currency fk before joining the currency codes, so that the join is per series rather than per rate
+	selectSeriesStatsStmt string
 )
 
 func init() {
@@ -51,22 +79,195 @@ func init() {
 		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
 	}
 	inputMeta, _ = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem())
+
+	selectDayOnOrBeforeStmt = dayOnOrBeforeStmt(schemaName)
+	selectDayOnOrBeforeAsOfStmt = fmt.Sprintf(`WITH known AS (
+			SELECT DISTINCT ON (v.to_currency_fk, v.day) v.exchange_rate_id, v.frequency, v.from_currency_fk, v.to_currency_fk, v.day, v.rate, v.source, v.source_run_id, v.fetched_at, v.known_at
+			FROM %[1]s.%[2]s v
+			WHERE v.from_currency_fk = (SELECT id FROM %[1]s.currency WHERE code = $1) AND v.frequency = $2 AND v.day <= $3 AND v.day >= $4 AND v.known_at <= $5
+			ORDER BY v.to_currency_fk, v.day, v.known_at DESC, v.id DESC
+		), live AS (
+			SELECT * FROM known WHERE rate IS NOT NULL
+		)
+		SELECT live.exchange_rate_id AS id, live.day, live.frequency, live.from_currency_fk, from_curr.code AS from_currency, live.to_currency_fk, to_curr.code AS to_currency,
+			live.rate, live.source, live.source_run_id, live.fetched_at, (SELECT min(f.known_at) FROM %[1]s.%[2]s f WHERE f.exchange_rate_id = live.exchange_rate_id) AS entry_at, live.known_at AS last_modified_at
+		FROM live
+		JOIN %[1]s.currency from_curr ON live.from_currency_fk = from_curr.id
+		JOIN %[1]s.currency to_curr ON live.to_currency_fk = to_curr.id
+		WHERE live.day = (SELECT max(day) FROM live)
+		ORDER BY to_curr.code;`, schemaName, versionTableName)
+	selectLatestDayStmt = latestDayStmt(schemaName)
+	selectLatestStmt = fmt.Sprintf("SELECT %s FROM %s.%s WHERE from_currency = $1 AND frequency = $2 ORDER BY to_currency;", strings.Join(meta.DbTags, ", "), schemaName, latestViewName)
+	selectSeriesStatsStmt = fmt.Sprintf(`SELECT s.frequency, from_curr.code AS from_currency, to_curr.code AS to_currency, s.rows, s.min_day, s.max_day
+		FROM (
+			SELECT frequency, from_currency_fk, to_currency_fk, count(*) AS rows, min(day) AS min_day, max(day) AS max_day
+			FROM %[1]s.%[2]s GROUP BY frequency, from_currency_fk, to_currency_fk
+		) s
+		JOIN %[1]s.currency from_curr ON s.from_currency_fk = from_curr.id
+		JOIN %[1]s.currency to_curr ON s.to_currency_fk = to_curr.id
+		ORDER BY s.frequency, from_curr.code, to_curr.code;`, schemaName, tableName)
+	selectPageAfterStmt = pageAfterStmt(schemaName)
+}
+
+// dayOnOrBeforeStmt returns the statement of SelectDayOnOrBefore reading the view of schema
+func dayOnOrBeforeStmt(schema string) string {
+	return fmt.Sprintf(`SELECT %[1]s FROM %[2]s.%[3]s
+		WHERE from_currency = $1 AND frequency = $2 AND day = (SELECT max(day) FROM %[2]s.%[3]s WHERE from_currency = $1 AND frequency = $2 AND day <= $3 AND day >= $4)
+		ORDER BY to_currency;`, strings.Join(meta.DbTags, ", "), schema, viewName)
+}
+
+// latestDayStmt returns the statement of SelectLatestDay reading the table of schema
+func latestDayStmt(schema string) string {
+	return fmt.Sprintf("SELECT max(day), count(*) FROM %s.%s WHERE frequency = $1;", schema, tableName)
+}
+
+// pageAfterStmt returns the statement of SelectPageAfter reading the view of schema
+func pageAfterStmt(schema string) string {
+	return fmt.Sprintf(`SELECT %s FROM %s.%s
+		WHERE from_currency = $1 AND frequency = $2 AND day >= $3 AND day <= $4 AND (day, to_currency_fk) > ($5::date, $6)
+		ORDER BY day, to_currency_fk LIMIT $7;`, strings.Join(meta.DbTags, ", "), schema, viewName)
 }
 
 type Store struct {
-	Db *pgxpool.Pool
+	Db         *pgxpool.Pool
+	Timeout    time.Duration   // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+	Duplicates DuplicatePolicy // what BulkInsert and CopyUpsert do with inputs of the same key. Defaults to DuplicatesFail
+}
+
+// BulkDelete deletes the items of ids in a single round trip
+func (s Store) BulkDelete(ctx context.Context, ids []int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	if len(ids) == 0 {
+		return nil
+	}
+	return cerrors.FromPg(lyspg.BulkDelete(ctx, s.Db, schemaName, tableName, pkColName, ids))
 }
 
+// BulkInsert inserts inputs in a single statement. Inputs of the same key are handled by s.Duplicates before anything is written
 func (s Store) BulkInsert(ctx context.Context, inputs []Input) (rowsAffected int64, err error) {
-	return lyspg.BulkInsert[Input](ctx, s.Db, schemaName, tableName, inputs)
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	inputs, _, err = Dedup(inputs, s.Duplicates)
+	if err != nil {
+		return 0, fmt.Errorf("Dedup failed: %w", err)
+	}
+	rowsAffected, err = lyspg.BulkInsert[Input](ctx, s.Db, schemaName, tableName, inputs)
+	if err != nil {
+		return 0, cerrors.FromPg(err)
+	}
+	return rowsAffected, cruntime.AnalyzeAfterLoad(ctx, s.Db, schemaName, tableName, rowsAffected)
+}
+
+// BulkUpdate updates the items of ids with the inputs at the same index in a single round trip. The inputs are not modified
+func (s Store) BulkUpdate(ctx context.Context, inputs []Input, ids []int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	if len(inputs) == 0 {
+		return nil
+	}
+	now := lystype.Datetime(time.Now())
+	inputs = slices.Clone(inputs)
+	for i := range inputs {
+		inputs[i].LastModifiedAt = now
+	}
+	return cerrors.FromPg(lyspg.BulkUpdate[Input](ctx, s.Db, schemaName, tableName, pkColName, inputs, ids))
+}
+
+// CopyUpsert loads inputs into a temp table using the postgres COPY protocol, then inserts them into the exchange rate table
+// existing rows (by natural key) are updated if the rate has changed. Much faster than Insert/Update for large loads such as a first-time history import
+// inputs of the same key are handled by s.Duplicates. The loaded rows are committed even if a following ANALYZE, see cruntime.WithAnalyzeAfter, fails
+func (s Store) CopyUpsert(ctx context.Context, inputs []Input) (inserted, updated int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	if len(inputs) == 0 {
+		return 0, 0, fmt.Errorf("%w: inputs has len 0", cerrors.ErrValidationFailed)
+	}
+
+	// a key loaded twice would fail the upsert: ON CONFLICT DO UPDATE can't affect a row twice
+	inputs, _, err = Dedup(inputs, s.Duplicates)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Dedup failed: %w", err)
+	}
+
+	tx, err := s.Db.Begin(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("s.Db.Begin failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `CREATE TEMP TABLE tmp_exchange_rate (
+		day date, frequency ecb.frequency, from_currency_fk bigint, to_currency_fk bigint, rate numeric(18,8), source text, source_run_id bigint, fetched_at timestamp with time zone
+	) ON COMMIT DROP;`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("tx.Exec (create temp table) failed: %w", err)
+	}
+
+	copyCols := []string{"day", "frequency", "from_currency_fk", "to_currency_fk", "rate", "source", "source_run_id", "fetched_at"}
+	recs := make([][]any, len(inputs))
+	for i, input := range inputs {
+		var fetchedAt *time.Time
+		if input.FetchedAt != nil {
+			t := time.Time(*input.FetchedAt)
+			fetchedAt = &t
+		}
+		recs[i] = []any{time.Time(input.Day), input.Frequency, input.FromCurrencyFk, input.ToCurrencyFk, input.Rate, input.Source, input.SourceRunId, fetchedAt}
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"tmp_exchange_rate"}, copyCols, pgx.CopyFromRows(recs))
+	if err != nil {
+		return 0, 0, fmt.Errorf("tx.CopyFrom failed: %w", err)
+	}
+
+	// xmax = 0 identifies newly inserted rows
+	// the provenance of a row is only replaced with its rate, so that it stays that of the load which produced the value
+	stmt := fmt.Sprintf(`INSERT INTO %s.%s (day, frequency, from_currency_fk, to_currency_fk, rate, source, source_run_id, fetched_at)
+		SELECT day, frequency, from_currency_fk, to_currency_fk, rate, source, source_run_id, fetched_at FROM tmp_exchange_rate
+		ON CONFLICT (frequency, day, from_currency_fk, to_currency_fk) DO UPDATE SET rate = EXCLUDED.rate, source = EXCLUDED.source, source_run_id = EXCLUDED.source_run_id,
+			fetched_at = EXCLUDED.fetched_at, last_modified_at = now()
+		WHERE %s.rate IS DISTINCT FROM EXCLUDED.rate
+		RETURNING (xmax = 0) AS inserted;`, schemaName, tableName, tableName)
+
+	rows, _ := tx.Query(ctx, stmt)
+	insertedFlags, err := pgx.CollectRows(rows, pgx.RowTo[bool])
+	if err != nil {
+		return 0, 0, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+	for _, isInserted := range insertedFlags {
+		if isInserted {
+			inserted++
+		} else {
+			updated++
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, 0, fmt.Errorf("tx.Commit failed: %w", err)
+	}
+
+	if err = cruntime.AnalyzeAfterLoad(ctx, s.Db, schemaName, tableName, inserted); err != nil {
+		return inserted, updated, fmt.Errorf("cruntime.AnalyzeAfterLoad failed: %w", err)
+	}
+
+	return inserted, updated, nil
 }
 
 func (s Store) Delete(ctx context.Context, id int64) error {
-	return lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id)
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id))
 }
 
+// RateFloat64 returns the Rate of m as a float64 as published, e.g. 1.6322 rather than 1.6322000026702881, the float64 of the float32, and 0.84183 for a rate published with 5 decimals
+func (m Model) RateFloat64() float64 {
+	return rateFloat64(m.Rate)
+}
+
+// Equal compares the rates at the 8 decimals stored, so that the revision of a 5th decimal is a change. It is called for each row of a diff, so it rounds rather than formats them
 func (s Store) Equal(a, b Model) bool {
-	return fmt.Sprintf("%.4f", a.Rate) == fmt.Sprintf("%.4f", b.Rate)
+	return roundRate(a.Rate) == roundRate(b.Rate)
 }
 
 func (s Store) GetMeta() lysmeta.Result {
@@ -77,52 +278,237 @@ func (s Store) GetName() string {
 }
 
 func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
-	return lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
 }
 
+// Select returns the rates matching params, see Query. Without Sorts, they are ordered by OrderNaturalKey, so that paging with Limit and Offset is stable
 func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
 	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
 }
 
-func (s Store) SelectMapByNaturalKey(ctx context.Context, baseCurr, freq string, startDate, endDate time.Time) (itemsMap map[string]Model, err error) {
+// SelectInRange returns the rates from baseCurr with frequency freq between startDate and endDate (inclusive), ordered by day and to_currency
+func (s Store) SelectInRange(ctx context.Context, baseCurr string, freq Frequency, startDate, endDate time.Time) (items []Model, err error) {
 
-	items, _, err := s.Select(ctx, lyspg.SelectParams{
-		Conditions: []lyspg.Condition{
-			{Field: "from_currency", Operator: lyspg.OpEquals, Value: baseCurr},
-			{Field: "frequency", Operator: lyspg.OpEquals, Value: freq},
-			{Field: "day", Operator: lyspg.OpGreaterThanEquals, Value: startDate.Format(lystype.DateFormat)},
-			{Field: "day", Operator: lyspg.OpLessThanEquals, Value: endDate.Format(lystype.DateFormat)},
-		},
-	})
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	items, _, err = s.Select(ctx, Query{}.ForPair(baseCurr, "").WithFrequency(freq).InRange(startDate, endDate).Params())
 	if err != nil {
 		return nil, fmt.Errorf("s.Select failed: %w", err)
 	}
 
-	// convert to map with day+toCurrFk as key
-	itemsMap = make(map[string]Model)
+	return items, nil
+}
+
+// SelectChangedSince returns the rates with frequency freq which were inserted or updated after since, on or after startDate, in the order they changed
+// a zero since returns all rates on or after startDate
+func (s Store) SelectChangedSince(ctx context.Context, freq Frequency, since, startDate time.Time) (items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`SELECT %s FROM %s.%s WHERE frequency = $1 AND greatest(entry_at, last_modified_at) > $2 AND day >= $3
+		ORDER BY greatest(entry_at, last_modified_at), id;`, strings.Join(meta.DbTags, ", "), schemaName, viewName)
+
+	rows, _ := s.Db.Query(ctx, stmt, freq, since, startDate.Format(lystype.DateFormat))
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	return items, nil
+}
+
+// SelectDayOnOrBefore returns the rates from baseCurr with frequency freq of the most recent day on or before day, looking back at most maxFallbackDays calendar days
+// actualDay is the day of the returned rates. Returns cerrors.ErrNoRows if there are none in that window
+func (s Store) SelectDayOnOrBefore(ctx context.Context, baseCurr string, freq Frequency, day time.Time, maxFallbackDays int) (actualDay time.Time, items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	rows, _ := s.Db.Query(ctx, selectDayOnOrBeforeStmt, baseCurr, freq, day.Format(lystype.DateFormat), day.AddDate(0, 0, -maxFallbackDays).Format(lystype.DateFormat))
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+	if len(items) == 0 {
+		return time.Time{}, nil, cerrors.ErrNoRows
+	}
+
+	return time.Time(items[0].Day), items, nil
+}
+
+// SelectDayOnOrBeforeAsOf is SelectDayOnOrBefore with the rates as they were stored at knownAt, e.g. to reproduce a valuation computed then: rates stored later are
+// ignored, and rates revised or deleted since have their value at knownAt. The returned Models have the ids of the rates and the time their value was stored as LastModifiedAt
+// it reads ecb.exchange_rate_version, which a trigger fills from migration 003 on. Rates stored before have their value at that migration since their EntryAt
+func (s Store) SelectDayOnOrBeforeAsOf(ctx context.Context, baseCurr string, freq Frequency, day time.Time, maxFallbackDays int, knownAt time.Time) (actualDay time.Time, items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	rows, _ := s.Db.Query(ctx, selectDayOnOrBeforeAsOfStmt, baseCurr, freq, day.Format(lystype.DateFormat), day.AddDate(0, 0, -maxFallbackDays).Format(lystype.DateFormat), knownAt)
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+	if len(items) == 0 {
+		return time.Time{}, nil, cerrors.ErrNoRows
+	}
+
+	return time.Time(items[0].Day), items, nil
+}
+
+// SelectLatest returns the rates from baseCurr with frequency freq of the latest day, read from the ecb.mv_latest_exchange_rate materialized view
+// they are those of the last RefreshLatest. Returns cerrors.ErrNoRows if there are none
+func (s Store) SelectLatest(ctx context.Context, baseCurr string, freq Frequency) (latestDay time.Time, items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	rows, _ := s.Db.Query(ctx, selectLatestStmt, baseCurr, freq)
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+	if len(items) == 0 {
+		return time.Time{}, nil, cerrors.ErrNoRows
+	}
+
+	return time.Time(items[0].Day), items, nil
+}
+
+// RefreshLatest refreshes the ecb.mv_latest_exchange_rate materialized view read by SelectLatest, e.g. after a sync. Readers are not blocked meanwhile
+func (s Store) RefreshLatest(ctx context.Context) error {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	if _, err := s.Db.Exec(ctx, fmt.Sprintf("REFRESH MATERIALIZED VIEW CONCURRENTLY %s.%s;", schemaName, latestViewName)); err != nil {
+		return fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+	return nil
+}
+
+// SelectPageAfter returns up to limit rates from baseCurr with frequency freq in the date range whose NaturalKey sorts after after, in NaturalKey order
+// to read a range in keyset pages, pass the zero NaturalKey for the first page and the key of the last rate of a page for the next
+func (s Store) SelectPageAfter(ctx context.Context, baseCurr string, freq Frequency, startDate, endDate time.Time, after NaturalKey, limit int) (items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	rows, _ := s.Db.Query(ctx, selectPageAfterStmt, baseCurr, freq, startDate.Format(lystype.DateFormat), endDate.Format(lystype.DateFormat), after.day(), after.ToCurrencyFk, limit)
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	return items, nil
+}
+
+// SelectLatestDay returns the most recent day and the total row count of the rates with frequency freq. latestDay is zero if there are none
+func (s Store) SelectLatestDay(ctx context.Context, freq Frequency) (latestDay time.Time, count int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	var day *time.Time
+	err = s.Db.QueryRow(ctx, selectLatestDayStmt, freq).Scan(&day, &count)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("s.Db.QueryRow failed: %w", cerrors.FromPg(err))
+	}
+	if day != nil {
+		latestDay = *day
+	}
+
+	return latestDay, count, nil
+}
+
+// NaturalKey is the key of the maps returned by SelectMapByNaturalKey. Being a comparable struct, it is built without allocating, unlike a formatted string
+// frequency and from currency are not part of it, since maps hold a single base currency and frequency
+type NaturalKey struct {
+	Day          int64 // Unix time of the day
+	ToCurrencyFk int64
+}
+
+// KeyOf returns the NaturalKey of input
+func KeyOf(input Input) NaturalKey {
+	return NaturalKey{Day: time.Time(input.Day).Unix(), ToCurrencyFk: input.ToCurrencyFk}
+}
+
+// Compare returns -1, 0 or +1 depending on whether k sorts before, equal to or after other: by day, then by to currency fk, as SelectPageAfter
+func (k NaturalKey) Compare(other NaturalKey) int {
+	if c := cmp.Compare(k.Day, other.Day); c != 0 {
+		return c
+	}
+	return cmp.Compare(k.ToCurrencyFk, other.ToCurrencyFk)
+}
+
+// day returns the day of k in lystype.DateFormat
+func (k NaturalKey) day() string {
+	return time.Unix(k.Day, 0).UTC().Format(lystype.DateFormat)
+}
+
+// SelectMapByNaturalKey is like SelectInRange, but returns a map with NaturalKey as key
+func (s Store) SelectMapByNaturalKey(ctx context.Context, baseCurr string, freq Frequency, startDate, endDate time.Time) (itemsMap map[NaturalKey]Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	items, err := s.SelectInRange(ctx, baseCurr, freq, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("s.SelectInRange failed: %w", err)
+	}
+
+	// convert to map, without the view columns
+	itemsMap = make(map[NaturalKey]Model, len(items))
 	for _, dbItem := range items {
-		item := Model{
-			Id:    dbItem.Id,
-			Input: dbItem.Input,
-		}
-		itemsMap[dbItem.Day.Format(lystype.DateFormat)+"+"+fmt.Sprintf("%v", dbItem.ToCurrencyFk)] = item
+		itemsMap[KeyOf(dbItem.Input)] = Model{Id: dbItem.Id, Input: dbItem.Input}
 	}
 
 	return itemsMap, nil
 }
 
 func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
-	return lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
 }
 
 func (s Store) Update(ctx context.Context, input Input, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
 	input.LastModifiedAt = lystype.Datetime(time.Now())
-	return lyspg.Update[Input](ctx, s.Db, schemaName, tableName, pkColName, input, id)
+	return cerrors.FromPg(lyspg.Update[Input](ctx, s.Db, schemaName, tableName, pkColName, input, id))
 }
 
 func (s Store) UpdatePartial(ctx context.Context, assignmentsMap map[string]any, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	assignmentsMap["last_modified_at"] = lystype.Datetime(time.Now())
+	return cerrors.FromPg(lyspg.UpdatePartial(ctx, s.Db, schemaName, tableName, pkColName, inputMeta.DbTags, assignmentsMap, id))
+}
+
+// UpdateIfUnchanged is Update with optimistic locking: readAt is the LastModifiedAt of the row when it was read. If the row was modified since, e.g. by a sync or another correction,
+// it is not overwritten and cerrors.ErrRowChanged is returned
+func (s Store) UpdateIfUnchanged(ctx context.Context, input Input, id int64, readAt time.Time) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	input.LastModifiedAt = lystype.Datetime(time.Now())
+	return cerrors.FromPg(optlock.Update(ctx, s.Db, schemaName, tableName, pkColName, input, id, readAt))
+}
+
+// UpdatePartialIfUnchanged is UpdatePartial with optimistic locking, as UpdateIfUnchanged
+func (s Store) UpdatePartialIfUnchanged(ctx context.Context, assignmentsMap map[string]any, id int64, readAt time.Time) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
 	assignmentsMap["last_modified_at"] = lystype.Datetime(time.Now())
-	return lyspg.UpdatePartial(ctx, s.Db, schemaName, tableName, pkColName, inputMeta.DbTags, assignmentsMap, id)
+	return cerrors.FromPg(optlock.UpdatePartial(ctx, s.Db, schemaName, tableName, pkColName, inputMeta.DbTags, assignmentsMap, id, readAt))
 }
 
 func (s Store) Validate(validate *validator.Validate, input Input) error {
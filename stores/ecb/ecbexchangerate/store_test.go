@@ -0,0 +1,272 @@
+package ecbexchangerate_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/pgtest"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbcurrency"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbexchangerate"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+var (
+	day1 = time.Date(2024, 9, 2, 0, 0, 0, 0, time.UTC) // Monday
+	day2 = time.Date(2024, 9, 3, 0, 0, 0, 0, time.UTC)
+)
+
+func TestStoreBulkInsertSelect(t *testing.T) {
+
+	ctx := context.Background()
+	db := pgtest.New(t, pgtest.Config{})
+	s := ecbexchangerate.Store{Db: db}
+	currMap := insertCurrencies(t, db, "EUR", "USD", "JPY")
+
+	inputs := []ecbexchangerate.Input{
+		newInput(currMap, day1, "USD", 1.1061),
+		newInput(currMap, day1, "JPY", 161.63),
+		newInput(currMap, day2, "USD", 1.1047),
+		newInput(currMap, day2, "JPY", 160.48),
+	}
+	rowsAffected, err := s.BulkInsert(ctx, inputs)
+	if err != nil {
+		t.Fatalf("s.BulkInsert failed: %s", err.Error())
+	}
+	if rowsAffected != int64(len(inputs)) {
+		t.Fatalf("rowsAffected: got %d, want %d", rowsAffected, len(inputs))
+	}
+
+	itemsMap, err := s.SelectMapByNaturalKey(ctx, "EUR", ecbexchangerate.Daily, day1, day2)
+	if err != nil {
+		t.Fatalf("s.SelectMapByNaturalKey failed: %s", err.Error())
+	}
+	if len(itemsMap) != len(inputs) {
+		t.Fatalf("itemsMap: got %d rates, want %d", len(itemsMap), len(inputs))
+	}
+	if item := itemsMap[ecbexchangerate.KeyOf(inputs[2])]; item.RateFloat64() != 1.1047 {
+		t.Errorf("rate of %+v: got %v, want 1.1047", ecbexchangerate.KeyOf(inputs[2]), item.RateFloat64())
+	}
+
+	// the weekend after day2 falls back to day2
+	actualDay, items, err := s.SelectDayOnOrBefore(ctx, "EUR", ecbexchangerate.Daily, day2.AddDate(0, 0, 3), 5)
+	if err != nil {
+		t.Fatalf("s.SelectDayOnOrBefore failed: %s", err.Error())
+	}
+	if !actualDay.Equal(day2) {
+		t.Errorf("actualDay: got %s, want %s", actualDay, day2)
+	}
+	if len(items) != 2 || items[0].ToCurrency != "JPY" || items[0].RateFloat64() != 160.48 {
+		t.Errorf("items: got %+v, want JPY 160.48 and USD", items)
+	}
+
+	_, _, err = s.SelectDayOnOrBefore(ctx, "EUR", ecbexchangerate.Daily, day1.AddDate(0, 0, -1), 5)
+	if !errors.Is(err, cerrors.ErrNoRows) {
+		t.Errorf("s.SelectDayOnOrBefore before the first day: got %v, want cerrors.ErrNoRows", err)
+	}
+
+	// the natural key is unique
+	_, err = s.BulkInsert(ctx, inputs[:1])
+	if !errors.Is(err, cerrors.ErrConflictPolicyViolation) {
+		t.Errorf("s.BulkInsert of a stored rate: got %v, want cerrors.ErrConflictPolicyViolation", err)
+	}
+}
+
+func TestStoreUpdateIfUnchanged(t *testing.T) {
+
+	ctx := context.Background()
+	db := pgtest.New(t, pgtest.Config{})
+	s := ecbexchangerate.Store{Db: db}
+	currMap := insertCurrencies(t, db, "EUR", "USD")
+
+	input := newInput(currMap, day1, "USD", 1.1061)
+	id, err := s.Insert(ctx, input)
+	if err != nil {
+		t.Fatalf("s.Insert failed: %s", err.Error())
+	}
+	item, err := s.SelectById(ctx, nil, id)
+	if err != nil {
+		t.Fatalf("s.SelectById failed: %s", err.Error())
+	}
+	readAt := time.Time(item.LastModifiedAt)
+
+	input.Rate = 1.1062
+	if err = s.UpdateIfUnchanged(ctx, input, id, readAt); err != nil {
+		t.Fatalf("s.UpdateIfUnchanged failed: %s", err.Error())
+	}
+
+	// the update changed last_modified_at, so readAt is stale
+	input.Rate = 1.1063
+	if err = s.UpdateIfUnchanged(ctx, input, id, readAt); !errors.Is(err, cerrors.ErrRowChanged) {
+		t.Errorf("s.UpdateIfUnchanged with a stale readAt: got %v, want cerrors.ErrRowChanged", err)
+	}
+	item, err = s.SelectById(ctx, nil, id)
+	if err != nil {
+		t.Fatalf("s.SelectById failed: %s", err.Error())
+	}
+	if item.RateFloat64() != 1.1062 {
+		t.Errorf("rate: got %v, want 1.1062", item.RateFloat64())
+	}
+
+	err = s.UpdatePartialIfUnchanged(ctx, map[string]any{"rate": 1.1064}, id, time.Time(item.LastModifiedAt))
+	if err != nil {
+		t.Fatalf("s.UpdatePartialIfUnchanged failed: %s", err.Error())
+	}
+
+	err = s.UpdatePartialIfUnchanged(ctx, map[string]any{"rate": 1.1064}, id+1, time.Time(item.LastModifiedAt))
+	if !errors.Is(err, cerrors.ErrNoRows) {
+		t.Errorf("s.UpdatePartialIfUnchanged of an unknown id: got %v, want cerrors.ErrNoRows", err)
+	}
+}
+
+func TestStoreSnapshotDay(t *testing.T) {
+
+	ctx := context.Background()
+	db := pgtest.New(t, pgtest.Config{})
+	s := ecbexchangerate.Store{Db: db}
+	currMap := insertCurrencies(t, db, "EUR", "USD", "JPY", "GBP")
+
+	usd := newInput(currMap, day1, "USD", 1.1061)
+	if _, err := s.BulkInsert(ctx, []ecbexchangerate.Input{usd, newInput(currMap, day1, "JPY", 161.63), newInput(currMap, day1, "GBP", 0.84183)}); err != nil {
+		t.Fatalf("s.BulkInsert failed: %s", err.Error())
+	}
+
+	fixings, err := s.SnapshotDay(ctx, day1)
+	if err != nil {
+		t.Fatalf("s.SnapshotDay failed: %s", err.Error())
+	}
+	if len(fixings) != 3 {
+		t.Fatalf("fixings: got %d, want 3", len(fixings))
+	}
+	if fixings[0].ToCurrency != "GBP" || fixings[0].RateFloat64() != 0.84183 {
+		t.Errorf("GBP fixing: got %+v, want the 5 decimals published, 0.84183", fixings[0])
+	}
+
+	// a revision of the rate after the snapshot doesn't change it
+	itemsMap, err := s.SelectMapByNaturalKey(ctx, "EUR", ecbexchangerate.Daily, day1, day1)
+	if err != nil {
+		t.Fatalf("s.SelectMapByNaturalKey failed: %s", err.Error())
+	}
+	usd.Rate = 1.1099
+	if err = s.Update(ctx, usd, itemsMap[ecbexchangerate.KeyOf(usd)].Id); err != nil {
+		t.Fatalf("s.Update failed: %s", err.Error())
+	}
+
+	for _, snapshotFunc := range []func(context.Context, time.Time) ([]ecbexchangerate.Fixing, error){s.SnapshotDay, s.SelectFixing} {
+		again, err := snapshotFunc(ctx, day1)
+		if err != nil {
+			t.Fatalf("snapshotFunc failed: %s", err.Error())
+		}
+		if len(again) != 3 || again[2].ToCurrency != "USD" || again[2].RateFloat64() != 1.1061 {
+			t.Errorf("fixings after the revision: got %+v, want USD 1.1061", again)
+		}
+	}
+
+	if _, err = s.SnapshotDay(ctx, day2); !errors.Is(err, cerrors.ErrNoRows) {
+		t.Errorf("s.SnapshotDay of a day without rates: got %v, want cerrors.ErrNoRows", err)
+	}
+	if _, err = s.SelectFixing(ctx, day2); !errors.Is(err, cerrors.ErrNoRows) {
+		t.Errorf("s.SelectFixing of a day without snapshot: got %v, want cerrors.ErrNoRows", err)
+	}
+
+	// the snapshot is immutable
+	for _, stmt := range []string{"UPDATE ecb.exchange_rate_fixing SET rate = 1;", "DELETE FROM ecb.exchange_rate_fixing;"} {
+		if _, err = db.Exec(ctx, stmt); err == nil {
+			t.Errorf("%s: expected error", stmt)
+		}
+	}
+}
+
+func TestModelRateFloat64(t *testing.T) {
+
+	tests := []struct {
+		rate float32
+		want float64
+	}{
+		{1.6322, 1.6322},
+		{0.84183, 0.84183}, // GBP, published with 5 decimals
+		{17346.52, 17346.52},
+		{161.63, 161.63},
+		{1, 1},
+		{1.0 / 3, 0.33333334},
+	}
+
+	for _, tt := range tests {
+		if got := (ecbexchangerate.Model{Input: ecbexchangerate.Input{Rate: tt.rate}}).RateFloat64(); got != tt.want {
+			t.Errorf("RateFloat64 of %v: got %v, want %v", tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestFixingRateFloat64(t *testing.T) {
+
+	tests := []struct {
+		rate float32
+		want float64
+	}{
+		{1.1061, 1.1061},
+		{0.84183, 0.84183},
+		{161.63, 161.63},
+	}
+
+	for _, tt := range tests {
+		if got := (ecbexchangerate.Fixing{Rate: tt.rate}).RateFloat64(); got != tt.want {
+			t.Errorf("RateFloat64 of %v: got %v, want %v", tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestStoreEqual(t *testing.T) {
+
+	tests := []struct {
+		a, b float32
+		want bool
+	}{
+		{0.84183, 0.84183, true},
+		{0.84183, 0.84184, false}, // a revision of the 5th decimal
+		{1.1061, 1.10610001, true},
+		{1.1061, 1.1062, false},
+	}
+
+	s := ecbexchangerate.Store{}
+	for _, tt := range tests {
+		a := ecbexchangerate.Model{Input: ecbexchangerate.Input{Rate: tt.a}}
+		b := ecbexchangerate.Model{Input: ecbexchangerate.Input{Rate: tt.b}}
+		if got := s.Equal(a, b); got != tt.want {
+			t.Errorf("Equal of %v and %v: got %t, want %t", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// insertCurrencies inserts codes into db and returns their ids by code
+func insertCurrencies(tb testing.TB, db *pgxpool.Pool, codes ...string) map[string]int64 {
+
+	tb.Helper()
+
+	ctx := context.Background()
+	currStore := ecbcurrency.Store{Db: db}
+	for _, code := range codes {
+		if _, err := currStore.Insert(ctx, ecbcurrency.Input{Code: code, Name: code}); err != nil {
+			tb.Fatalf("currStore.Insert failed: %s", err.Error())
+		}
+	}
+	currMap, err := currStore.SelectCodeIdMap(ctx)
+	if err != nil {
+		tb.Fatalf("currStore.SelectCodeIdMap failed: %s", err.Error())
+	}
+	return currMap
+}
+
+// newInput returns the daily rate from EUR to toCurr of day
+func newInput(currMap map[string]int64, day time.Time, toCurr string, rate float32) ecbexchangerate.Input {
+	return ecbexchangerate.Input{
+		Day:            lystype.Date(day),
+		Frequency:      ecbexchangerate.Daily,
+		FromCurrencyFk: currMap["EUR"],
+		Rate:           rate,
+		ToCurrencyFk:   currMap[toCurr],
+	}
+}
@@ -0,0 +1,272 @@
+package ecbexchangerate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+)
+
+// loadTableName is the table SwapReload loads into before swapping it with the live table
+const loadTableName string = tableName + "_load"
+
+// viewStmt returns the statement creating or replacing the v_exchange_rate view of the table in schema. Its columns are those of migration 005, in the same order
+func viewStmt(schema string) string {
+	return fmt.Sprintf(`CREATE OR REPLACE VIEW %[1]s.%[2]s AS
+		SELECT xr.day, xr.frequency, xr.from_currency_fk, from_curr.code AS from_currency, xr.entry_at, xr.last_modified_at, xr.id, xr.rate, xr.to_currency_fk, to_curr.code AS to_currency,
+			xr.source, xr.source_run_id, xr.fetched_at
+		FROM %[1]s.%[3]s xr
+		JOIN %[4]s.currency from_curr ON xr.from_currency_fk = from_curr.id
+		JOIN %[4]s.currency to_curr ON xr.to_currency_fk = to_curr.id;`, schema, viewName, tableName, schemaName)
+}
+
+// SwapReload replaces the rates of frequency freq from the currency of fromCurrencyFk with inputs, e.g. the full history, without readers ever seeing a partly loaded table:
+// inputs are loaded into a new table, which is then swapped with the live one by renaming both in a single transaction. Rates of other frequencies or base currencies are kept
+// rates whose key is still loaded keep their id, entry time and, if their rate is unchanged, their provenance and LastModifiedAt. Those not in inputs are deleted
+// the value log gets a version of each inserted, updated and deleted rate, as a sync would write, and the triggers, comment and privileges of the table are carried over.
+// The v_exchange_rate view is repointed and mv_latest_exchange_rate recreated with its privileges. Objects outside the ecb migrations depending on the table fail the swap
+// the change feed does not capture the changes of a swap: its consumers must resync. The swap must be run by a role which may drop the table, e.g. its owner, which then owns the new one
+// rates stored by other writers between the load and the swap in the replaced range are lost. Inputs of the same key are handled by s.Duplicates
+func (s Store) SwapReload(ctx context.Context, freq Frequency, fromCurrencyFk int64, inputs []Input) (inserted, updated, deleted int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	if len(inputs) == 0 {
+		return 0, 0, 0, fmt.Errorf("%w: inputs has len 0", cerrors.ErrValidationFailed)
+	}
+	for i, input := range inputs {
+		if input.Frequency != freq || input.FromCurrencyFk != fromCurrencyFk {
+			return 0, 0, 0, fmt.Errorf("%w: input %d is not a rate of frequency %s from currency id %d", cerrors.ErrValidationFailed, i, freq, fromCurrencyFk)
+		}
+	}
+
+	inputs, _, err = Dedup(inputs, s.Duplicates)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("Dedup failed: %w", err)
+	}
+
+	if err = s.loadForSwap(ctx, inputs); err != nil {
+		return 0, 0, 0, fmt.Errorf("s.loadForSwap failed: %w", err)
+	}
+
+	inserted, updated, deleted, err = s.swap(ctx, freq, fromCurrencyFk)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("s.swap failed: %w", err)
+	}
+
+	if _, err = s.Db.Exec(ctx, fmt.Sprintf("ANALYZE %s.%s;", schemaName, tableName)); err != nil {
+		return inserted, updated, deleted, fmt.Errorf("s.Db.Exec (analyze) failed: %w", cerrors.FromPg(err))
+	}
+
+	return inserted, updated, deleted, nil
+}
+
+// loadForSwap creates the load table, replacing that of a failed SwapReload, and loads inputs into it using the postgres COPY protocol. The live table is only read
+// inputs take the id and entry time of the stored rate of their key, and, if the rate is unchanged, its provenance and LastModifiedAt. New rates are numbered by the sequence of the live table
+func (s Store) loadForSwap(ctx context.Context, inputs []Input) error {
+
+	tx, err := s.Db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("s.Db.Begin failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// foreign keys are not copied by LIKE. They are named as those of the live table, which only need to be unique per table
+	stmts := []string{
+		fmt.Sprintf("DROP TABLE IF EXISTS %s.%s;", schemaName, loadTableName),
+		fmt.Sprintf("CREATE TABLE %[1]s.%[2]s (LIKE %[1]s.%[3]s INCLUDING ALL);", schemaName, loadTableName, tableName),
+		fmt.Sprintf(`ALTER TABLE %[1]s.%[2]s
+			ADD CONSTRAINT %[3]s_from_currency_fk_fkey FOREIGN KEY (from_currency_fk) REFERENCES %[1]s.currency(id),
+			ADD CONSTRAINT %[3]s_to_currency_fk_fkey FOREIGN KEY (to_currency_fk) REFERENCES %[1]s.currency(id);`, schemaName, loadTableName, tableName),
+		`CREATE TEMP TABLE tmp_exchange_rate (
+			day date, frequency ecb.frequency, from_currency_fk bigint, to_currency_fk bigint, rate numeric(18,8), source text, source_run_id bigint, fetched_at timestamp with time zone
+		) ON COMMIT DROP;`,
+	}
+	for _, stmt := range stmts {
+		if _, err = tx.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("tx.Exec failed: %s: %w", stmt, cerrors.FromPg(err))
+		}
+	}
+
+	copyCols := []string{"day", "frequency", "from_currency_fk", "to_currency_fk", "rate", "source", "source_run_id", "fetched_at"}
+	recs := make([][]any, len(inputs))
+	for i, input := range inputs {
+		var fetchedAt *time.Time
+		if input.FetchedAt != nil {
+			t := time.Time(*input.FetchedAt)
+			fetchedAt = &t
+		}
+		recs[i] = []any{time.Time(input.Day), input.Frequency, input.FromCurrencyFk, input.ToCurrencyFk, input.Rate, input.Source, input.SourceRunId, fetchedAt}
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"tmp_exchange_rate"}, copyCols, pgx.CopyFromRows(recs))
+	if err != nil {
+		return fmt.Errorf("tx.CopyFrom failed: %w", err)
+	}
+
+	stmt := fmt.Sprintf(`INSERT INTO %[1]s.%[2]s (id, frequency, from_currency_fk, to_currency_fk, rate, day, entry_at, last_modified_at, source, source_run_id, fetched_at)
+		SELECT coalesce(xr.id, nextval(pg_get_serial_sequence('%[1]s.%[3]s', 'id'))), t.frequency, t.from_currency_fk, t.to_currency_fk, t.rate, t.day, coalesce(xr.entry_at, now()),
+			CASE WHEN xr.rate = t.rate THEN xr.last_modified_at ELSE now() END,
+			CASE WHEN xr.rate = t.rate THEN xr.source ELSE t.source END,
+			CASE WHEN xr.rate = t.rate THEN xr.source_run_id ELSE t.source_run_id END,
+			CASE WHEN xr.rate = t.rate THEN xr.fetched_at ELSE t.fetched_at END
+		FROM tmp_exchange_rate t
+		LEFT JOIN %[1]s.%[3]s xr ON (xr.frequency, xr.day, xr.from_currency_fk, xr.to_currency_fk) = (t.frequency, t.day, t.from_currency_fk, t.to_currency_fk);`,
+		schemaName, loadTableName, tableName)
+	if _, err = tx.Exec(ctx, stmt); err != nil {
+		return fmt.Errorf("tx.Exec (load) failed: %w", cerrors.FromPg(err))
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("tx.Commit failed: %w", err)
+	}
+
+	return nil
+}
+
+// swap copies the rates outside the reloaded range into the load table, logs the changed values and swaps the tables, in a transaction which holds an exclusive lock on the
+// live table from the start, so that no rate is written meanwhile. Readers of the table wait for the commit, and then see the new one
+func (s Store) swap(ctx context.Context, freq Frequency, fromCurrencyFk int64) (inserted, updated, deleted int64, err error) {
+
+	live, load := schemaName+"."+tableName, schemaName+"."+loadTableName
+
+	tx, err := s.Db.Begin(ctx)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("s.Db.Begin failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, fmt.Sprintf("LOCK TABLE %s IN ACCESS EXCLUSIVE MODE;", live)); err != nil {
+		return 0, 0, 0, fmt.Errorf("tx.Exec (lock) failed: %w", cerrors.FromPg(err))
+	}
+
+	// the rates outside the reloaded range, as they are now
+	stmt := fmt.Sprintf("INSERT INTO %s SELECT * FROM %s WHERE NOT (frequency = $1 AND from_currency_fk = $2);", load, live)
+	if _, err = tx.Exec(ctx, stmt, freq, fromCurrencyFk); err != nil {
+		return 0, 0, 0, fmt.Errorf("tx.Exec (copy kept rates) failed: %w", cerrors.FromPg(err))
+	}
+
+	// the value log, as ecb.record_exchange_rate_version would write it. Rates keep their id, so versions are matched by it
+	versionCols := "exchange_rate_id, frequency, from_currency_fk, to_currency_fk, day, rate, source, source_run_id, fetched_at"
+	versionStmts := []struct {
+		count *int64
+		stmt  string
+	}{
+		{&deleted, fmt.Sprintf(`INSERT INTO %[1]s.%[2]s (%[3]s) SELECT xr.id, xr.frequency, xr.from_currency_fk, xr.to_currency_fk, xr.day, NULL, '', NULL, NULL
+			FROM %[4]s xr WHERE NOT EXISTS (SELECT 1 FROM %[5]s n WHERE n.id = xr.id);`, schemaName, versionTableName, versionCols, live, load)},
+		{&inserted, fmt.Sprintf(`INSERT INTO %[1]s.%[2]s (%[3]s) SELECT n.id, n.frequency, n.from_currency_fk, n.to_currency_fk, n.day, n.rate, n.source, n.source_run_id, n.fetched_at
+			FROM %[5]s n WHERE NOT EXISTS (SELECT 1 FROM %[4]s xr WHERE xr.id = n.id);`, schemaName, versionTableName, versionCols, live, load)},
+		{&updated, fmt.Sprintf(`INSERT INTO %[1]s.%[2]s (%[3]s) SELECT n.id, n.frequency, n.from_currency_fk, n.to_currency_fk, n.day, n.rate, n.source, n.source_run_id, n.fetched_at
+			FROM %[5]s n JOIN %[4]s xr ON xr.id = n.id WHERE xr.rate IS DISTINCT FROM n.rate;`, schemaName, versionTableName, versionCols, live, load)},
+	}
+	for _, vs := range versionStmts {
+		tag, err := tx.Exec(ctx, vs.stmt)
+		if err != nil {
+			return 0, 0, 0, fmt.Errorf("tx.Exec (version log) failed: %w", cerrors.FromPg(err))
+		}
+		*vs.count = tag.RowsAffected()
+	}
+
+	// what the new table must get from the live one, read before the rename so that the statements name the live table
+	var recreateStmts []string
+	rows, _ := tx.Query(ctx, "SELECT pg_get_triggerdef(oid) || ';' FROM pg_trigger WHERE tgrelid = $1::regclass AND NOT tgisinternal ORDER BY tgname;", live)
+	triggerStmts, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("pgx.CollectRows (triggers) failed: %w", cerrors.FromPg(err))
+	}
+	recreateStmts = append(recreateStmts, triggerStmts...)
+
+	var comment *string
+	if err = tx.QueryRow(ctx, "SELECT obj_description($1::regclass, 'pg_class');", live).Scan(&comment); err != nil {
+		return 0, 0, 0, fmt.Errorf("tx.QueryRow (comment) failed: %w", cerrors.FromPg(err))
+	}
+	if comment != nil {
+		recreateStmts = append(recreateStmts, fmt.Sprintf("COMMENT ON TABLE %s IS '%s';", live, strings.ReplaceAll(*comment, "'", "''")))
+	}
+
+	var liveSeq string
+	if err = tx.QueryRow(ctx, "SELECT pg_get_serial_sequence($1, 'id');", live).Scan(&liveSeq); err != nil {
+		return 0, 0, 0, fmt.Errorf("tx.QueryRow (sequence) failed: %w", cerrors.FromPg(err))
+	}
+
+	// granted once the new objects have the names of the live ones
+	var grantStmts []string
+	latestView := schemaName + "." + latestViewName
+	for _, rel := range []string{live, liveSeq, latestView} {
+		relGrantStmts, err := selectGrantStmts(ctx, tx, rel)
+		if err != nil {
+			return 0, 0, 0, fmt.Errorf("selectGrantStmts failed for %s: %w", rel, err)
+		}
+		grantStmts = append(grantStmts, relGrantStmts...)
+	}
+
+	// the view is repointed by replacing it. The materialized view also reads the table itself, so is recreated as in migration 005
+	stmts := []string{
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s_old;", live, tableName),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", load, tableName),
+		viewStmt(schemaName),
+		fmt.Sprintf("DROP MATERIALIZED VIEW %s;", latestView),
+		fmt.Sprintf(`CREATE MATERIALIZED VIEW %[1]s AS
+			SELECT v.* FROM %[2]s.%[3]s v
+			WHERE v.day = (SELECT max(xr.day) FROM %[4]s xr WHERE xr.frequency = v.frequency);`, latestView, schemaName, viewName, live),
+		fmt.Sprintf("CREATE UNIQUE INDEX %s_key_idx ON %s (frequency, from_currency_fk, to_currency_fk);", latestViewName, latestView),
+		fmt.Sprintf("DROP TABLE %s_old;", live),
+		fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), coalesce((SELECT max(id) FROM %[1]s), 0) + 1, false);", live),
+	}
+	for _, stmt := range stmts {
+		if _, err = tx.Exec(ctx, stmt); err != nil {
+			return 0, 0, 0, fmt.Errorf("tx.Exec failed: %s: %w", stmt, cerrors.FromPg(err))
+		}
+	}
+
+	// the indexes and sequence of the new table are named after the load table, e.g. exchange_rate_load_pkey
+	var newSeq string
+	if err = tx.QueryRow(ctx, "SELECT pg_get_serial_sequence($1, 'id');", live).Scan(&newSeq); err != nil {
+		return 0, 0, 0, fmt.Errorf("tx.QueryRow (new sequence) failed: %w", cerrors.FromPg(err))
+	}
+	if newSeq != liveSeq {
+		recreateStmts = append(recreateStmts, fmt.Sprintf("ALTER SEQUENCE %s RENAME TO %s;", newSeq, liveSeq[strings.LastIndex(liveSeq, ".")+1:]))
+	}
+
+	rows, _ = tx.Query(ctx, "SELECT indexname FROM pg_indexes WHERE schemaname = $1 AND tablename = $2 AND indexname LIKE $3::text || '%';", schemaName, tableName, loadTableName)
+	indexNames, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("pgx.CollectRows (indexes) failed: %w", cerrors.FromPg(err))
+	}
+	for _, indexName := range indexNames {
+		newName := tableName + strings.TrimPrefix(indexName, loadTableName)
+		recreateStmts = append(recreateStmts, fmt.Sprintf("ALTER INDEX %s.%s RENAME TO %s;", schemaName, indexName, newName))
+	}
+
+	for _, stmt := range append(recreateStmts, grantStmts...) {
+		if _, err = tx.Exec(ctx, stmt); err != nil {
+			return 0, 0, 0, fmt.Errorf("tx.Exec failed: %s: %w", stmt, cerrors.FromPg(err))
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, 0, 0, fmt.Errorf("tx.Commit failed: %w", err)
+	}
+
+	return inserted, updated, deleted, nil
+}
+
+// selectGrantStmts returns the GRANT statements of the privileges on rel, a table, view or sequence, of the roles other than its owner
+func selectGrantStmts(ctx context.Context, tx pgx.Tx, rel string) (stmts []string, err error) {
+
+	rows, _ := tx.Query(ctx, `SELECT format('GRANT %s ON %s%s TO %s;', a.privilege_type, CASE WHEN c.relkind = 'S' THEN 'SEQUENCE ' ELSE '' END, c.oid::regclass, CASE WHEN a.grantee = 0 THEN 'PUBLIC' ELSE a.grantee::regrole::text END)
+		FROM pg_class c, aclexplode(c.relacl) a
+		WHERE c.oid = $1::regclass AND a.grantee <> c.relowner
+		ORDER BY a.grantee, a.privilege_type;`, rel)
+	stmts, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	return stmts, nil
+}
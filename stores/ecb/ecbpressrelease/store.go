@@ -0,0 +1,163 @@
+package ecbpressrelease
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "ECB press releases"
+	schemaName     string = "ecb"
+	tableName      string = "press_release"
+	viewName       string = "press_release"
+	pkColName      string = "id"
+	defaultOrderBy string = "published_at DESC"
+)
+
+// categories of press releases, derived from their URL
+const (
+	CategoryMonetaryPolicyDecision  string = "monetary_policy_decision"
+	CategoryMonetaryPolicyStatement string = "monetary_policy_statement"
+	CategoryPressRelease            string = "press_release"
+	CategorySpeech                  string = "speech"
+	CategoryInterview               string = "interview"
+	CategoryBlog                    string = "blog"
+	CategoryOther                   string = "other"
+)
+
+type Input struct {
+	Category       string           `db:"category" json:"category,omitempty" validate:"required"`
+	Description    string           `db:"description" json:"description"`
+	LastModifiedAt lystype.Datetime `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	PublishedAt    lystype.Datetime `db:"published_at" json:"published_at,omitempty" validate:"required"`
+	Title          string           `db:"title" json:"title,omitempty" validate:"required"`
+	Url            string           `db:"url" json:"url,omitempty" validate:"required,url"`
+}
+
+type Model struct {
+	Id      int64            `db:"id" json:"id"`
+	EntryAt lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	Input
+}
+
+var (
+	meta, inputMeta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+	inputMeta, _ = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem())
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id))
+}
+
+func (s Store) Equal(a, b Model) bool {
+	return a.Title == b.Title && a.Category == b.Category && a.Description == b.Description && time.Time(a.PublishedAt).Equal(time.Time(b.PublishedAt))
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
+
+// SelectInRange returns the press releases of category published between start and end (inclusive), oldest first. All categories if category is empty
+func (s Store) SelectInRange(ctx context.Context, category string, start, end time.Time) (items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	conds := []lyspg.Condition{
+		{Field: "published_at", Operator: lyspg.OpGreaterThanEquals, Value: start.Format(time.RFC3339)},
+		{Field: "published_at", Operator: lyspg.OpLessThanEquals, Value: end.Format(time.RFC3339)},
+	}
+	if category != "" {
+		conds = append(conds, lyspg.Condition{Field: "category", Operator: lyspg.OpEquals, Value: category})
+	}
+
+	items, _, err = s.Select(ctx, lyspg.SelectParams{Conditions: conds, Sorts: []string{"published_at"}})
+	if err != nil {
+		return nil, fmt.Errorf("s.Select failed: %w", err)
+	}
+
+	return items, nil
+}
+
+// SelectMapByNaturalKey returns the press releases with urls, with Url as key
+func (s Store) SelectMapByNaturalKey(ctx context.Context, urls []string) (itemsMap map[string]Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	itemsMap = make(map[string]Model)
+	if len(urls) == 0 {
+		return itemsMap, nil
+	}
+
+	items, _, err := s.Select(ctx, lyspg.SelectParams{Conditions: []lyspg.Condition{{Field: "url", Operator: lyspg.OpIn, InValues: urls}}})
+	if err != nil {
+		return nil, fmt.Errorf("s.Select failed: %w", err)
+	}
+
+	for _, item := range items {
+		itemsMap[item.Url] = item
+	}
+
+	return itemsMap, nil
+}
+
+func (s Store) Update(ctx context.Context, input Input, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	input.LastModifiedAt = lystype.Datetime(time.Now())
+	return cerrors.FromPg(lyspg.Update[Input](ctx, s.Db, schemaName, tableName, pkColName, input, id))
+}
+
+func (s Store) Validate(validate *validator.Validate, input Input) error {
+	return lysmeta.Validate[Input](validate, input)
+}
@@ -0,0 +1,276 @@
+package ecbrateoverride
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "Exchange rate overrides"
+	schemaName     string = "ecb"
+	tableName      string = "exchange_rate_override"
+	viewName       string = "exchange_rate_override"
+	auditTableName string = "exchange_rate_override_audit"
+	pkColName      string = "id"
+	defaultOrderBy string = "day DESC, to_currency, id"
+)
+
+// Status is the state of an override in the correction workflow
+type Status string
+
+const (
+	StatusPending  Status = "pending"  // entered, not yet approved, so not used yet
+	StatusApproved Status = "approved" // used ahead of the synced rate
+	StatusRevoked  Status = "revoked"  // no longer used, or never used if revoked while pending
+)
+
+// Input is an override as entered. It cannot be changed afterwards: enter and approve another one, which supersedes it
+type Input struct {
+	Day        lystype.Date `db:"day" json:"day" validate:"required"`
+	EnteredBy  string       `db:"entered_by" json:"entered_by" validate:"required"`
+	Rate       float64      `db:"rate" json:"rate" validate:"required,gt=0"` // from EUR, with at most the 8 decimals stored
+	Reason     string       `db:"reason" json:"reason" validate:"required"`
+	ToCurrency string       `db:"to_currency" json:"to_currency" validate:"required,len=3,uppercase"`
+}
+
+type Model struct {
+	Id             int64             `db:"id" json:"id"`
+	EntryAt        lystype.Datetime  `db:"entry_at" json:"entry_at"`
+	LastModifiedAt lystype.Datetime  `db:"last_modified_at" json:"last_modified_at"`
+	ApprovedBy     string            `db:"approved_by" json:"approved_by,omitempty"`
+	ApprovedAt     *lystype.Datetime `db:"approved_at" json:"approved_at,omitempty"` // from when the override is used
+	RevokedBy      string            `db:"revoked_by" json:"revoked_by,omitempty"`
+	RevokedAt      *lystype.Datetime `db:"revoked_at" json:"revoked_at,omitempty"` // from when the override is no longer used
+	RevokeReason   string            `db:"revoke_reason" json:"revoke_reason,omitempty"`
+	Input
+}
+
+// Status returns the state of m
+func (m Model) Status() Status {
+	switch {
+	case m.RevokedAt != nil:
+		return StatusRevoked
+	case m.ApprovedAt != nil:
+		return StatusApproved
+	default:
+		return StatusPending
+	}
+}
+
+// AuditEntry is a change of an override, written by trigger to ecb.exchange_rate_override_audit
+type AuditEntry struct {
+	Id         int64            `db:"id" json:"id"`
+	OverrideId int64            `db:"override_id" json:"override_id"`
+	Action     string           `db:"action" json:"action"` // entered, approved or revoked
+	Actor      string           `db:"actor" json:"actor"`
+	DbUser     string           `db:"db_user" json:"db_user"` // database role which made the change
+	ChangedAt  lystype.Datetime `db:"changed_at" json:"changed_at"`
+	Override   json.RawMessage  `db:"override" json:"override"` // the row of the override after the change
+}
+
+var (
+	meta lysmeta.Result
+
+	// statement of SelectApproved. An override superseded by another is revoked at the time the other is approved, so that a single one is in use at any time
+	selectApprovedStmt string
+
+	// statement of SelectAudit
+	selectAuditStmt string
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+
+	selectApprovedStmt = fmt.Sprintf(`SELECT %s FROM %s.%s
+		WHERE day >= $1 AND day <= $2 AND approved_at <= coalesce($3, now()) AND (revoked_at IS NULL OR revoked_at > coalesce($3, now()))
+		ORDER BY day, to_currency;`, strings.Join(meta.DbTags, ", "), schemaName, tableName)
+	selectAuditStmt = fmt.Sprintf(`SELECT id, override_id, action, actor, db_user, changed_at, override FROM %s.%s WHERE override_id = $1 ORDER BY id;`, schemaName, auditTableName)
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+// Approve approves the pending override id by approvedBy, who must not be who entered it, so that converters use it from now on
+// an approved override of the same day and currency is revoked, as superseded by id. Returns cerrors.ErrNoRows if id does not exist
+func (s Store) Approve(ctx context.Context, id int64, approvedBy string) error {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	if approvedBy == "" {
+		return fmt.Errorf("%w: approvedBy is required", cerrors.ErrValidationFailed)
+	}
+
+	tx, err := s.Db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("s.Db.Begin failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	item, err := selectForUpdate(ctx, tx, id)
+	if err != nil {
+		return fmt.Errorf("selectForUpdate failed: %w", err)
+	}
+	if status := item.Status(); status != StatusPending {
+		return fmt.Errorf("%w: override %d is %s", cerrors.ErrValidationFailed, id, status)
+	}
+	if approvedBy == item.EnteredBy {
+		return fmt.Errorf("%w: override %d must be approved by someone other than %s, who entered it", cerrors.ErrValidationFailed, id, item.EnteredBy)
+	}
+
+	_, err = tx.Exec(ctx, fmt.Sprintf(`UPDATE %s.%s SET revoked_by = $1, revoked_at = now(), revoke_reason = $2, last_modified_at = now()
+		WHERE day = $3 AND to_currency = $4 AND approved_at IS NOT NULL AND revoked_at IS NULL;`, schemaName, tableName),
+		approvedBy, fmt.Sprintf("superseded by override %d", id), time.Time(item.Day).Format(lystype.DateFormat), item.ToCurrency)
+	if err != nil {
+		return fmt.Errorf("tx.Exec (supersede) failed: %w", cerrors.FromPg(err))
+	}
+
+	_, err = tx.Exec(ctx, fmt.Sprintf("UPDATE %s.%s SET approved_by = $1, approved_at = now(), last_modified_at = now() WHERE id = $2;", schemaName, tableName), approvedBy, id)
+	if err != nil {
+		return fmt.Errorf("tx.Exec (approve) failed: %w", cerrors.FromPg(err))
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("tx.Commit failed: %w", err)
+	}
+	return nil
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+// Insert enters input as a pending override. It is not used until approved
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+// Revoke revokes the override id by revokedBy for reason, so that converters no longer use it, or never do if it is pending. Returns cerrors.ErrNoRows if id does not exist
+func (s Store) Revoke(ctx context.Context, id int64, revokedBy, reason string) error {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	if revokedBy == "" || reason == "" {
+		return fmt.Errorf("%w: revokedBy and reason are required", cerrors.ErrValidationFailed)
+	}
+
+	tx, err := s.Db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("s.Db.Begin failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	item, err := selectForUpdate(ctx, tx, id)
+	if err != nil {
+		return fmt.Errorf("selectForUpdate failed: %w", err)
+	}
+	if item.Status() == StatusRevoked {
+		return fmt.Errorf("%w: override %d is already revoked", cerrors.ErrValidationFailed, id)
+	}
+
+	_, err = tx.Exec(ctx, fmt.Sprintf("UPDATE %s.%s SET revoked_by = $1, revoked_at = now(), revoke_reason = $2, last_modified_at = now() WHERE id = $3;", schemaName, tableName), revokedBy, reason, id)
+	if err != nil {
+		return fmt.Errorf("tx.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("tx.Commit failed: %w", err)
+	}
+	return nil
+}
+
+// Select returns the overrides matching params, by default latest day first
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+// SelectApproved returns the overrides of the days from startDate to endDate (inclusive) which were in use at knownAt, ordered by day and currency
+// a zero knownAt returns those in use now
+func (s Store) SelectApproved(ctx context.Context, startDate, endDate, knownAt time.Time) (items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	var at *time.Time
+	if !knownAt.IsZero() {
+		at = &knownAt
+	}
+
+	rows, _ := s.Db.Query(ctx, selectApprovedStmt, startDate.Format(lystype.DateFormat), endDate.Format(lystype.DateFormat), at)
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	return items, nil
+}
+
+// SelectAudit returns the audit trail of the override id, oldest change first. Empty if id does not exist
+func (s Store) SelectAudit(ctx context.Context, id int64) (items []AuditEntry, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	rows, _ := s.Db.Query(ctx, selectAuditStmt, id)
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[AuditEntry])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	return items, nil
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
+
+func (s Store) Validate(validate *validator.Validate, input Input) error {
+	return lysmeta.Validate[Input](validate, input)
+}
+
+// selectForUpdate returns the override id, locked until the end of tx
+func selectForUpdate(ctx context.Context, tx pgx.Tx, id int64) (item Model, err error) {
+
+	rows, _ := tx.Query(ctx, fmt.Sprintf("SELECT %s FROM %s.%s WHERE id = $1 FOR UPDATE;", strings.Join(meta.DbTags, ", "), schemaName, tableName), id)
+	item, err = pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Model{}, cerrors.ErrNoRows
+		}
+		return Model{}, fmt.Errorf("pgx.CollectExactlyOneRow failed: %w", cerrors.FromPg(err))
+	}
+	return item, nil
+}
@@ -0,0 +1,226 @@
+package ecbrateoverride_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/pgtest"
+	"github.com/loveyourstack/connectors/stores/ecb/ecbrateoverride"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+var day = time.Date(2024, 9, 2, 0, 0, 0, 0, time.UTC)
+
+func TestStoreWorkflow(t *testing.T) {
+
+	ctx := context.Background()
+	db := pgtest.New(t, pgtest.Config{})
+	s := ecbrateoverride.Store{Db: db}
+
+	id1 := insertOverride(t, s, "alice", 1.1071)
+	assertStatus(t, s, id1, ecbrateoverride.StatusPending)
+
+	approved, err := s.SelectApproved(ctx, day, day, time.Time{})
+	if err != nil {
+		t.Fatalf("s.SelectApproved failed: %s", err.Error())
+	}
+	if len(approved) != 0 {
+		t.Errorf("approved before approval: got %d, want 0", len(approved))
+	}
+
+	// four eyes: who entered an override cannot approve it
+	if err = s.Approve(ctx, id1, "alice"); !errors.Is(err, cerrors.ErrValidationFailed) {
+		t.Errorf("s.Approve by who entered it: got %v, want cerrors.ErrValidationFailed", err)
+	}
+	if err = s.Approve(ctx, id1, "bob"); err != nil {
+		t.Fatalf("s.Approve failed: %s", err.Error())
+	}
+	assertStatus(t, s, id1, ecbrateoverride.StatusApproved)
+	if err = s.Approve(ctx, id1, "carol"); !errors.Is(err, cerrors.ErrValidationFailed) {
+		t.Errorf("s.Approve of an approved override: got %v, want cerrors.ErrValidationFailed", err)
+	}
+	assertApproved(t, s, time.Time{}, id1)
+
+	// approving a second override of the same rate supersedes the first
+	betweenApprovals, err := dbNow(ctx, s)
+	if err != nil {
+		t.Fatalf("dbNow failed: %s", err.Error())
+	}
+	id2 := insertOverride(t, s, "alice", 1.1072)
+	if err = s.Approve(ctx, id2, "carol"); err != nil {
+		t.Fatalf("s.Approve failed: %s", err.Error())
+	}
+	assertStatus(t, s, id1, ecbrateoverride.StatusRevoked)
+	assertStatus(t, s, id2, ecbrateoverride.StatusApproved)
+	assertApproved(t, s, time.Time{}, id2)
+	assertApproved(t, s, betweenApprovals, id1)
+
+	if err = s.Revoke(ctx, id2, "bob", ""); !errors.Is(err, cerrors.ErrValidationFailed) {
+		t.Errorf("s.Revoke without reason: got %v, want cerrors.ErrValidationFailed", err)
+	}
+	if err = s.Revoke(ctx, id2, "bob", "wrong notice"); err != nil {
+		t.Fatalf("s.Revoke failed: %s", err.Error())
+	}
+	if err = s.Revoke(ctx, id2, "bob", "wrong notice"); !errors.Is(err, cerrors.ErrValidationFailed) {
+		t.Errorf("s.Revoke of a revoked override: got %v, want cerrors.ErrValidationFailed", err)
+	}
+	assertApproved(t, s, time.Time{})
+
+	if err = s.Approve(ctx, id2+1, "bob"); !errors.Is(err, cerrors.ErrNoRows) {
+		t.Errorf("s.Approve of an unknown id: got %v, want cerrors.ErrNoRows", err)
+	}
+
+	tests := []struct {
+		id      int64
+		actions []string
+		actors  []string
+	}{
+		{id1, []string{"entered", "approved", "revoked"}, []string{"alice", "bob", "carol"}},
+		{id2, []string{"entered", "approved", "revoked"}, []string{"alice", "carol", "bob"}},
+		{id2 + 1, nil, nil},
+	}
+	for _, tt := range tests {
+		entries, err := s.SelectAudit(ctx, tt.id)
+		if err != nil {
+			t.Fatalf("s.SelectAudit failed: %s", err.Error())
+		}
+		var actions, actors []string
+		for _, e := range entries {
+			actions = append(actions, e.Action)
+			actors = append(actors, e.Actor)
+		}
+		if !slices.Equal(actions, tt.actions) || !slices.Equal(actors, tt.actors) {
+			t.Errorf("audit of %d: got %v by %v, want %v by %v", tt.id, actions, actors, tt.actions, tt.actors)
+		}
+	}
+}
+
+func TestStoreImmutable(t *testing.T) {
+
+	ctx := context.Background()
+	db := pgtest.New(t, pgtest.Config{})
+	s := ecbrateoverride.Store{Db: db}
+
+	id := insertOverride(t, s, "alice", 1.1071)
+
+	for _, stmt := range []string{
+		"UPDATE ecb.exchange_rate_override SET rate = 1.2 WHERE id = $1;",
+		"DELETE FROM ecb.exchange_rate_override WHERE id = $1;",
+		"UPDATE ecb.exchange_rate_override_audit SET actor = 'mallory' WHERE override_id = $1;",
+		"DELETE FROM ecb.exchange_rate_override_audit WHERE override_id = $1;",
+	} {
+		if _, err := db.Exec(ctx, stmt, id); err == nil {
+			t.Errorf("%s: expected error", stmt)
+		}
+	}
+}
+
+func TestModelStatus(t *testing.T) {
+
+	at := lystype.Datetime(day)
+
+	tests := []struct {
+		name  string
+		model ecbrateoverride.Model
+		want  ecbrateoverride.Status
+	}{
+		{"entered", ecbrateoverride.Model{}, ecbrateoverride.StatusPending},
+		{"approved", ecbrateoverride.Model{ApprovedBy: "bob", ApprovedAt: &at}, ecbrateoverride.StatusApproved},
+		{"approved and revoked", ecbrateoverride.Model{ApprovedBy: "bob", ApprovedAt: &at, RevokedBy: "carol", RevokedAt: &at}, ecbrateoverride.StatusRevoked},
+		{"revoked while pending", ecbrateoverride.Model{RevokedBy: "carol", RevokedAt: &at}, ecbrateoverride.StatusRevoked},
+	}
+
+	for _, tt := range tests {
+		if got := tt.model.Status(); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStoreValidate(t *testing.T) {
+
+	valid := ecbrateoverride.Input{Day: lystype.Date(day), EnteredBy: "alice", Rate: 0.84183, Reason: "notice", ToCurrency: "GBP"}
+
+	tests := []struct {
+		name    string
+		modify  func(*ecbrateoverride.Input)
+		wantErr bool
+	}{
+		{"valid", func(in *ecbrateoverride.Input) {}, false},
+		{"no day", func(in *ecbrateoverride.Input) { in.Day = lystype.Date{} }, true},
+		{"no enteredBy", func(in *ecbrateoverride.Input) { in.EnteredBy = "" }, true},
+		{"no reason", func(in *ecbrateoverride.Input) { in.Reason = "" }, true},
+		{"zero rate", func(in *ecbrateoverride.Input) { in.Rate = 0 }, true},
+		{"negative rate", func(in *ecbrateoverride.Input) { in.Rate = -1.1 }, true},
+		{"lower case currency", func(in *ecbrateoverride.Input) { in.ToCurrency = "gbp" }, true},
+		{"currency not 3 letters", func(in *ecbrateoverride.Input) { in.ToCurrency = "GB" }, true},
+	}
+
+	s := ecbrateoverride.Store{}
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	for _, tt := range tests {
+		input := valid
+		tt.modify(&input)
+		err := s.Validate(validate, input)
+		if tt.wantErr && err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("%s: s.Validate failed: %s", tt.name, err.Error())
+		}
+	}
+}
+
+// insertOverride enters the USD rate of day by enteredBy
+func insertOverride(tb testing.TB, s ecbrateoverride.Store, enteredBy string, rate float64) int64 {
+
+	tb.Helper()
+
+	id, err := s.Insert(context.Background(), ecbrateoverride.Input{Day: lystype.Date(day), EnteredBy: enteredBy, Rate: rate, Reason: "notice", ToCurrency: "USD"})
+	if err != nil {
+		tb.Fatalf("s.Insert failed: %s", err.Error())
+	}
+	return id
+}
+
+func assertStatus(tb testing.TB, s ecbrateoverride.Store, id int64, want ecbrateoverride.Status) {
+
+	tb.Helper()
+
+	item, err := s.SelectById(context.Background(), nil, id)
+	if err != nil {
+		tb.Fatalf("s.SelectById failed: %s", err.Error())
+	}
+	if item.Status() != want {
+		tb.Errorf("status of %d: got %s, want %s", id, item.Status(), want)
+	}
+}
+
+// assertApproved asserts that the overrides of day in use at knownAt are ids
+func assertApproved(tb testing.TB, s ecbrateoverride.Store, knownAt time.Time, ids ...int64) {
+
+	tb.Helper()
+
+	items, err := s.SelectApproved(context.Background(), day, day, knownAt)
+	if err != nil {
+		tb.Fatalf("s.SelectApproved failed: %s", err.Error())
+	}
+	var got []int64
+	for _, item := range items {
+		got = append(got, item.Id)
+	}
+	if !slices.Equal(got, ids) {
+		tb.Errorf("approved at %s: got %v, want %v", knownAt, got, ids)
+	}
+}
+
+// dbNow returns the time of the database, which approvals are stamped with
+func dbNow(ctx context.Context, s ecbrateoverride.Store) (now time.Time, err error) {
+	err = s.Db.QueryRow(ctx, "SELECT clock_timestamp();").Scan(&now)
+	return now, err
+}
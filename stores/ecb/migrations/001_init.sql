@@ -1,22 +1,33 @@
 
 /*
-if needed:
-CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
-*/
-
-CREATE SCHEMA ecb AUTHORIZATION <owner_user>;
-
-/*
-as needed:
+as needed, after running migrations as the owner user:
 GRANT USAGE ON SCHEMA ecb TO <cli_user>;
 ALTER DEFAULT PRIVILEGES IN SCHEMA ecb GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
 ALTER DEFAULT PRIVILEGES IN SCHEMA ecb GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
 */
 
-CREATE TYPE ecb.frequency AS ENUM ('D', 'M');
+-- statements are idempotent so that databases created from the former schema.sql can adopt migrations
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS ecb;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type t JOIN pg_namespace n ON n.oid = t.typnamespace WHERE n.nspname = 'ecb' AND t.typname = 'frequency') THEN
+    CREATE TYPE ecb.frequency AS ENUM ('D', 'M');
+  END IF;
+END
+$$;
 
 
-CREATE TABLE ecb.currency
+CREATE TABLE IF NOT EXISTS ecb.currency
 (	
   id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
   entry_at tracking_at,
@@ -27,7 +38,7 @@ CREATE TABLE ecb.currency
 COMMENT ON TABLE ecb.currency IS 'shortname: curr';
 
 
-CREATE TABLE ecb.exchange_rate
+CREATE TABLE IF NOT EXISTS ecb.exchange_rate
 (	
   id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
   frequency ecb.frequency NOT NULL,
@@ -0,0 +1,17 @@
+
+-- press releases, speeches and monetary policy announcements from the ECB's RSS feed
+-- the feed only lists recent items, so items are never deleted
+CREATE TABLE IF NOT EXISTS ecb.press_release
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  url text NOT NULL UNIQUE, -- natural key
+  title text NOT NULL,
+  category text NOT NULL, -- e.g. monetary_policy_decision, press_release, speech
+  published_at timestamp with time zone NOT NULL,
+  description text NOT NULL
+);
+COMMENT ON TABLE ecb.press_release IS 'shortname: pr';
+
+CREATE INDEX IF NOT EXISTS press_release_category_published_at_idx ON ecb.press_release (category, published_at);
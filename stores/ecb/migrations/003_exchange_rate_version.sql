@@ -0,0 +1,52 @@
+-- append-only log of the values of ecb.exchange_rate, so that the rates known at a past time can be looked up, e.g. to reproduce a valuation for an audit
+-- a row is written by trigger whenever a rate is inserted, its value or key changes, or it is deleted. rate is NULL for a deletion
+CREATE TABLE IF NOT EXISTS ecb.exchange_rate_version
+(
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  exchange_rate_id bigint NOT NULL, -- not a foreign key, so that the versions of deleted rates are kept
+  frequency ecb.frequency NOT NULL,
+  from_currency_fk bigint NOT NULL REFERENCES ecb.currency(id),
+  to_currency_fk bigint NOT NULL REFERENCES ecb.currency(id),
+  day date NOT NULL,
+  rate numeric(12,4),
+  known_at timestamp with time zone NOT NULL DEFAULT now() -- when the value was stored
+);
+COMMENT ON TABLE ecb.exchange_rate_version IS 'shortname: xrv';
+
+CREATE INDEX IF NOT EXISTS exchange_rate_version_lookup_idx ON ecb.exchange_rate_version (from_currency_fk, frequency, day, known_at);
+
+
+CREATE OR REPLACE FUNCTION ecb.record_exchange_rate_version() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+  -- the old key no longer has a rate if the row was deleted or moved to another key
+  IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND (NEW.frequency, NEW.day, NEW.from_currency_fk, NEW.to_currency_fk) IS DISTINCT FROM (OLD.frequency, OLD.day, OLD.from_currency_fk, OLD.to_currency_fk)) THEN
+    INSERT INTO ecb.exchange_rate_version (exchange_rate_id, frequency, from_currency_fk, to_currency_fk, day, rate)
+    VALUES (OLD.id, OLD.frequency, OLD.from_currency_fk, OLD.to_currency_fk, OLD.day, NULL);
+  END IF;
+
+  IF TG_OP = 'DELETE' THEN
+    RETURN NULL;
+  END IF;
+
+  -- updates which rewrite the same value, e.g. by a re-sync, are not new versions
+  IF TG_OP = 'UPDATE' AND (NEW.frequency, NEW.day, NEW.from_currency_fk, NEW.to_currency_fk, NEW.rate) IS NOT DISTINCT FROM (OLD.frequency, OLD.day, OLD.from_currency_fk, OLD.to_currency_fk, OLD.rate) THEN
+    RETURN NULL;
+  END IF;
+
+  INSERT INTO ecb.exchange_rate_version (exchange_rate_id, frequency, from_currency_fk, to_currency_fk, day, rate)
+  VALUES (NEW.id, NEW.frequency, NEW.from_currency_fk, NEW.to_currency_fk, NEW.day, NEW.rate);
+  RETURN NULL;
+END
+$$;
+
+DROP TRIGGER IF EXISTS exchange_rate_version ON ecb.exchange_rate;
+CREATE TRIGGER exchange_rate_version AFTER INSERT OR UPDATE OR DELETE ON ecb.exchange_rate
+  FOR EACH ROW EXECUTE FUNCTION ecb.record_exchange_rate_version();
+
+
+-- existing rates: their earlier values are unknown, so the current value is taken as known since the rate was stored
+INSERT INTO ecb.exchange_rate_version (exchange_rate_id, frequency, from_currency_fk, to_currency_fk, day, rate, known_at)
+SELECT xr.id, xr.frequency, xr.from_currency_fk, xr.to_currency_fk, xr.day, xr.rate, xr.entry_at
+FROM ecb.exchange_rate xr
+WHERE NOT EXISTS (SELECT 1 FROM ecb.exchange_rate_version v WHERE v.exchange_rate_id = xr.id);
@@ -0,0 +1,8 @@
+-- the rates of the latest day of each frequency, for cheap reads of the current rates. Refreshed after each rates sync by the daemon, or with ecbexchangerate.Store.RefreshLatest
+CREATE MATERIALIZED VIEW IF NOT EXISTS ecb.mv_latest_exchange_rate AS
+  SELECT v.*
+  FROM ecb.v_exchange_rate v
+  WHERE v.day = (SELECT max(xr.day) FROM ecb.exchange_rate xr WHERE xr.frequency = v.frequency);
+
+-- unique, so that the view can be refreshed concurrently, without blocking its readers
+CREATE UNIQUE INDEX IF NOT EXISTS mv_latest_exchange_rate_key_idx ON ecb.mv_latest_exchange_rate (frequency, from_currency_fk, to_currency_fk);
@@ -0,0 +1,68 @@
+-- provenance of each rate, so that a stored value can be traced back to the fetch and sync run that produced it
+-- rates stored before this migration, or by inserts outside a sync, have an empty source and NULL run and fetch time
+ALTER TABLE ecb.exchange_rate
+  ADD COLUMN IF NOT EXISTS source text NOT NULL DEFAULT '', -- name of the client the rate was fetched from, e.g. ecb, or frankfurter if the ECB was unavailable
+  ADD COLUMN IF NOT EXISTS source_run_id bigint, -- id of the connectors.sync_run which stored the value. Not a foreign key, since the connectors schema is migrated separately
+  ADD COLUMN IF NOT EXISTS fetched_at timestamp with time zone; -- when the rate was fetched from source
+
+
+-- new columns can only be appended to the view
+CREATE OR REPLACE VIEW ecb.v_exchange_rate AS
+  SELECT
+    xr.day,
+    xr.frequency,
+    xr.from_currency_fk,
+    from_curr.code AS from_currency,
+    xr.entry_at,
+    xr.last_modified_at,
+    xr.id,
+    xr.rate,
+    xr.to_currency_fk,
+    to_curr.code AS to_currency,
+    xr.source,
+    xr.source_run_id,
+    xr.fetched_at
+  FROM ecb.exchange_rate xr
+  JOIN ecb.currency from_curr ON xr.from_currency_fk = from_curr.id
+  JOIN ecb.currency to_curr ON xr.to_currency_fk = to_curr.id;
+
+
+-- the columns of a materialized view are fixed when it is created: recreate it with the new ones
+DROP MATERIALIZED VIEW IF EXISTS ecb.mv_latest_exchange_rate;
+CREATE MATERIALIZED VIEW ecb.mv_latest_exchange_rate AS
+  SELECT v.*
+  FROM ecb.v_exchange_rate v
+  WHERE v.day = (SELECT max(xr.day) FROM ecb.exchange_rate xr WHERE xr.frequency = v.frequency);
+
+CREATE UNIQUE INDEX mv_latest_exchange_rate_key_idx ON ecb.mv_latest_exchange_rate (frequency, from_currency_fk, to_currency_fk);
+
+
+-- the value log keeps the provenance of each value, so that rates looked up as of a past time are traceable too
+ALTER TABLE ecb.exchange_rate_version
+  ADD COLUMN IF NOT EXISTS source text NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS source_run_id bigint,
+  ADD COLUMN IF NOT EXISTS fetched_at timestamp with time zone;
+
+CREATE OR REPLACE FUNCTION ecb.record_exchange_rate_version() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+  -- the old key no longer has a rate if the row was deleted or moved to another key
+  IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND (NEW.frequency, NEW.day, NEW.from_currency_fk, NEW.to_currency_fk) IS DISTINCT FROM (OLD.frequency, OLD.day, OLD.from_currency_fk, OLD.to_currency_fk)) THEN
+    INSERT INTO ecb.exchange_rate_version (exchange_rate_id, frequency, from_currency_fk, to_currency_fk, day, rate)
+    VALUES (OLD.id, OLD.frequency, OLD.from_currency_fk, OLD.to_currency_fk, OLD.day, NULL);
+  END IF;
+
+  IF TG_OP = 'DELETE' THEN
+    RETURN NULL;
+  END IF;
+
+  -- updates which rewrite the same value, e.g. by a re-sync, are not new versions, even if their provenance differs
+  IF TG_OP = 'UPDATE' AND (NEW.frequency, NEW.day, NEW.from_currency_fk, NEW.to_currency_fk, NEW.rate) IS NOT DISTINCT FROM (OLD.frequency, OLD.day, OLD.from_currency_fk, OLD.to_currency_fk, OLD.rate) THEN
+    RETURN NULL;
+  END IF;
+
+  INSERT INTO ecb.exchange_rate_version (exchange_rate_id, frequency, from_currency_fk, to_currency_fk, day, rate, source, source_run_id, fetched_at)
+  VALUES (NEW.id, NEW.frequency, NEW.from_currency_fk, NEW.to_currency_fk, NEW.day, NEW.rate, NEW.source, NEW.source_run_id, NEW.fetched_at);
+  RETURN NULL;
+END
+$$;
@@ -0,0 +1,31 @@
+-- immutable snapshots of the daily fixings, taken by ecbexchangerate.Store.SnapshotDay, so that end-of-day jobs read a stable set of rates even if the ECB revises them later
+-- rows are copied with the provenance of their rate, and hold currency codes rather than foreign keys, so that a snapshot doesn't depend on any other row
+CREATE TABLE IF NOT EXISTS ecb.exchange_rate_fixing
+(
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  snapshot_at tracking_at, -- when the snapshot of the day was taken
+  day date NOT NULL,
+  from_currency text NOT NULL,
+  to_currency text NOT NULL,
+  rate numeric(12,4) NOT NULL,
+  exchange_rate_id bigint NOT NULL, -- not a foreign key, since the rate may be revised or deleted after the snapshot
+  rate_modified_at timestamp with time zone NOT NULL, -- last_modified_at of the rate when the snapshot was taken
+  source text NOT NULL DEFAULT '',
+  source_run_id bigint,
+  fetched_at timestamp with time zone,
+  UNIQUE (day, from_currency, to_currency)
+);
+COMMENT ON TABLE ecb.exchange_rate_fixing IS 'shortname: xrf';
+
+
+CREATE OR REPLACE FUNCTION ecb.reject_exchange_rate_fixing_change() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+  RAISE EXCEPTION 'ecb.exchange_rate_fixing is immutable: % is not allowed', TG_OP USING ERRCODE = 'restrict_violation';
+END
+$$;
+
+-- to purge old snapshots, the table owner can disable the trigger: ALTER TABLE ecb.exchange_rate_fixing DISABLE TRIGGER exchange_rate_fixing_immutable
+DROP TRIGGER IF EXISTS exchange_rate_fixing_immutable ON ecb.exchange_rate_fixing;
+CREATE TRIGGER exchange_rate_fixing_immutable BEFORE UPDATE OR DELETE ON ecb.exchange_rate_fixing
+  FOR EACH ROW EXECUTE FUNCTION ecb.reject_exchange_rate_fixing_change();
@@ -0,0 +1,93 @@
+-- manual corrections of the daily rates from EUR, e.g. rates corrected centrally by a tax authority, which converter.Converter uses ahead of the synced rates once approved
+-- an override is entered with a reason, approved by someone other than who entered it, and may be revoked. Overrides are never deleted, so that their history stays complete
+CREATE TABLE IF NOT EXISTS ecb.exchange_rate_override
+(
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  day date NOT NULL, -- day of the fixing corrected, usually in the past
+  to_currency text NOT NULL, -- the rate is from EUR, as those of ecb.exchange_rate
+  rate numeric(12,4) NOT NULL CHECK (rate > 0),
+  reason text NOT NULL CHECK (reason <> ''),
+  entered_by text NOT NULL CHECK (entered_by <> ''),
+  approved_by text NOT NULL DEFAULT '',
+  approved_at timestamp with time zone, -- from when the override is used
+  revoked_by text NOT NULL DEFAULT '',
+  revoked_at timestamp with time zone, -- from when the override is no longer used, e.g. since superseded by another
+  revoke_reason text NOT NULL DEFAULT '',
+  CHECK (approved_by <> entered_by),
+  CHECK ((approved_by = '') = (approved_at IS NULL)),
+  CHECK ((revoked_by = '') = (revoked_at IS NULL))
+);
+COMMENT ON TABLE ecb.exchange_rate_override IS 'shortname: xro';
+
+-- a single override of a rate is in use at a time
+CREATE UNIQUE INDEX IF NOT EXISTS exchange_rate_override_active_idx ON ecb.exchange_rate_override (day, to_currency) WHERE approved_at IS NOT NULL AND revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS exchange_rate_override_day_idx ON ecb.exchange_rate_override (day);
+
+
+-- audit trail: a row per change of an override, written by trigger, with the override as changed
+CREATE TABLE IF NOT EXISTS ecb.exchange_rate_override_audit
+(
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  override_id bigint NOT NULL REFERENCES ecb.exchange_rate_override(id),
+  action text NOT NULL, -- entered, approved or revoked
+  actor text NOT NULL, -- entered_by, approved_by or revoked_by of the override
+  db_user text NOT NULL DEFAULT current_user,
+  changed_at timestamp with time zone NOT NULL DEFAULT now(),
+  override jsonb NOT NULL
+);
+COMMENT ON TABLE ecb.exchange_rate_override_audit IS 'shortname: xroa';
+
+CREATE INDEX IF NOT EXISTS exchange_rate_override_audit_override_idx ON ecb.exchange_rate_override_audit (override_id, id);
+
+
+-- only the approval and revocation of an override may be set, each once, and neither overrides nor their audit trail may be deleted
+CREATE OR REPLACE FUNCTION ecb.check_exchange_rate_override_change() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+  IF TG_OP = 'DELETE' OR TG_TABLE_NAME = 'exchange_rate_override_audit' THEN
+    RAISE EXCEPTION 'ecb.%: % is not allowed', TG_TABLE_NAME, TG_OP USING ERRCODE = 'restrict_violation';
+  END IF;
+
+  IF (NEW.entry_at, NEW.day, NEW.to_currency, NEW.rate, NEW.reason, NEW.entered_by) IS DISTINCT FROM (OLD.entry_at, OLD.day, OLD.to_currency, OLD.rate, OLD.reason, OLD.entered_by) THEN
+    RAISE EXCEPTION 'ecb.exchange_rate_override: only the approval and revocation of an override may be changed' USING ERRCODE = 'restrict_violation';
+  END IF;
+  IF (OLD.approved_at IS NOT NULL OR OLD.revoked_at IS NOT NULL) AND (NEW.approved_by, NEW.approved_at) IS DISTINCT FROM (OLD.approved_by, OLD.approved_at) THEN
+    RAISE EXCEPTION 'ecb.exchange_rate_override: override % is already approved or revoked', OLD.id USING ERRCODE = 'restrict_violation';
+  END IF;
+  IF OLD.revoked_at IS NOT NULL AND (NEW.revoked_by, NEW.revoked_at, NEW.revoke_reason) IS DISTINCT FROM (OLD.revoked_by, OLD.revoked_at, OLD.revoke_reason) THEN
+    RAISE EXCEPTION 'ecb.exchange_rate_override: override % is already revoked', OLD.id USING ERRCODE = 'restrict_violation';
+  END IF;
+  RETURN NEW;
+END
+$$;
+
+DROP TRIGGER IF EXISTS exchange_rate_override_check ON ecb.exchange_rate_override;
+CREATE TRIGGER exchange_rate_override_check BEFORE UPDATE OR DELETE ON ecb.exchange_rate_override
+  FOR EACH ROW EXECUTE FUNCTION ecb.check_exchange_rate_override_change();
+
+DROP TRIGGER IF EXISTS exchange_rate_override_audit_check ON ecb.exchange_rate_override_audit;
+CREATE TRIGGER exchange_rate_override_audit_check BEFORE UPDATE OR DELETE ON ecb.exchange_rate_override_audit
+  FOR EACH ROW EXECUTE FUNCTION ecb.check_exchange_rate_override_change();
+
+
+CREATE OR REPLACE FUNCTION ecb.record_exchange_rate_override_audit() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    INSERT INTO ecb.exchange_rate_override_audit (override_id, action, actor, override) VALUES (NEW.id, 'entered', NEW.entered_by, to_jsonb(NEW));
+  END IF;
+  IF NEW.approved_at IS NOT NULL AND (TG_OP = 'INSERT' OR OLD.approved_at IS NULL) THEN
+    INSERT INTO ecb.exchange_rate_override_audit (override_id, action, actor, override) VALUES (NEW.id, 'approved', NEW.approved_by, to_jsonb(NEW));
+  END IF;
+  IF NEW.revoked_at IS NOT NULL AND (TG_OP = 'INSERT' OR OLD.revoked_at IS NULL) THEN
+    INSERT INTO ecb.exchange_rate_override_audit (override_id, action, actor, override) VALUES (NEW.id, 'revoked', NEW.revoked_by, to_jsonb(NEW));
+  END IF;
+  RETURN NULL;
+END
+$$;
+
+DROP TRIGGER IF EXISTS exchange_rate_override_audit ON ecb.exchange_rate_override;
+CREATE TRIGGER exchange_rate_override_audit AFTER INSERT OR UPDATE ON ecb.exchange_rate_override
+  FOR EACH ROW EXECUTE FUNCTION ecb.record_exchange_rate_override_audit();
@@ -0,0 +1,35 @@
+-- the ECB publishes some rates with 5 decimals, e.g. GBP at 0.84183, which numeric(12,4) rounded: widen the rates and their value log to 8 decimals
+-- rates already stored keep their rounded value until the next sync or reload of their range writes them as published
+
+-- the type of a column read by a view cannot change: drop the views and recreate them as in migration 005
+DROP MATERIALIZED VIEW IF EXISTS ecb.mv_latest_exchange_rate;
+DROP VIEW IF EXISTS ecb.v_exchange_rate;
+
+ALTER TABLE ecb.exchange_rate ALTER COLUMN rate TYPE numeric(18,8);
+ALTER TABLE ecb.exchange_rate_version ALTER COLUMN rate TYPE numeric(18,8);
+
+CREATE VIEW ecb.v_exchange_rate AS
+  SELECT
+    xr.day,
+    xr.frequency,
+    xr.from_currency_fk,
+    from_curr.code AS from_currency,
+    xr.entry_at,
+    xr.last_modified_at,
+    xr.id,
+    xr.rate,
+    xr.to_currency_fk,
+    to_curr.code AS to_currency,
+    xr.source,
+    xr.source_run_id,
+    xr.fetched_at
+  FROM ecb.exchange_rate xr
+  JOIN ecb.currency from_curr ON xr.from_currency_fk = from_curr.id
+  JOIN ecb.currency to_curr ON xr.to_currency_fk = to_curr.id;
+
+CREATE MATERIALIZED VIEW ecb.mv_latest_exchange_rate AS
+  SELECT v.*
+  FROM ecb.v_exchange_rate v
+  WHERE v.day = (SELECT max(xr.day) FROM ecb.exchange_rate xr WHERE xr.frequency = v.frequency);
+
+CREATE UNIQUE INDEX mv_latest_exchange_rate_key_idx ON ecb.mv_latest_exchange_rate (frequency, from_currency_fk, to_currency_fk);
@@ -0,0 +1,3 @@
+-- the fixings copy the rates, which migration 008 widened to 8 decimals: widen them alike, so that a snapshot holds the rates as published
+-- fixings already taken keep their 4 decimals, since snapshots are never changed
+ALTER TABLE ecb.exchange_rate_fixing ALTER COLUMN rate TYPE numeric(18,8);
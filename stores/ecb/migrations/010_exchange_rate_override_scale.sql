@@ -0,0 +1,2 @@
+-- overrides correct the rates, which migration 008 widened to 8 decimals: widen them alike, so that a rate published with 5 decimals, e.g. GBP at 0.84183, can be corrected as such
+ALTER TABLE ecb.exchange_rate_override ALTER COLUMN rate TYPE numeric(18,8);
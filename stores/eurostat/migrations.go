@@ -0,0 +1,8 @@
+package eurostat
+
+import "embed"
+
+// Migrations is an embedded filesystem containing the SQL migrations of the eurostat schema, applied in file name order
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
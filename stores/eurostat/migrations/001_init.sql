@@ -0,0 +1,36 @@
+
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA eurostat TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA eurostat GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA eurostat GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS eurostat;
+
+
+-- observations of any Eurostat dataset. The non-time dimensions of each observation are stored as a JSON object of dimension code to category code
+-- e.g. dataset_code prc_hicp_manr, dimensions {"freq": "M", "unit": "RCH_A", "coicop": "CP00", "geo": "DE"}, period 2024-01
+CREATE TABLE eurostat.observation
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  dataset_code text NOT NULL,
+  dimensions jsonb NOT NULL,
+  period text NOT NULL, -- Eurostat time period, e.g. 2024, 2024-Q1, 2024-01
+  value numeric NOT NULL,
+  status text NOT NULL DEFAULT '', -- Eurostat observation flag, e.g. p (provisional) or e (estimated)
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  UNIQUE (dataset_code, dimensions, period)
+);
+COMMENT ON TABLE eurostat.observation IS 'shortname: esobs';
+
+CREATE INDEX observation_dimensions_idx ON eurostat.observation USING gin (dimensions);
@@ -0,0 +1,128 @@
+package exhostrate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "exchangerate.host rates"
+	schemaName     string = "exhost"
+	tableName      string = "rate"
+	viewName       string = "rate"
+	pkColName      string = "id"
+	defaultOrderBy string = "quoted_at DESC"
+)
+
+// rates are only inserted: a rate is never changed once quoted
+type Input struct {
+	FromCurrency string           `db:"from_currency" json:"from_currency,omitempty" validate:"required,len=3"`
+	QuotedAt     lystype.Datetime `db:"quoted_at" json:"quoted_at,omitempty" validate:"required"`
+	Rate         float64          `db:"rate" json:"rate,omitempty" validate:"required"`
+	ToCurrency   string           `db:"to_currency" json:"to_currency,omitempty" validate:"required,len=3"`
+}
+
+type Model struct {
+	Id      int64            `db:"id" json:"id"`
+	EntryAt lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	Input
+}
+
+var (
+	meta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) BulkInsert(ctx context.Context, inputs []Input) (rowsAffected int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	rowsAffected, err = lyspg.BulkInsert[Input](ctx, s.Db, schemaName, tableName, inputs)
+	if err != nil {
+		return 0, cerrors.FromPg(err)
+	}
+	return rowsAffected, cruntime.AnalyzeAfterLoad(ctx, s.Db, schemaName, tableName, rowsAffected)
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+// SelectCurrenciesAt returns a set of the to currencies quoted from fromCurr at quotedAt
+func (s Store) SelectCurrenciesAt(ctx context.Context, fromCurr string, quotedAt time.Time) (currSet map[string]bool, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	items, _, err := s.Select(ctx, lyspg.SelectParams{
+		Fields: []string{"to_currency"},
+		Conditions: []lyspg.Condition{
+			{Field: "from_currency", Operator: lyspg.OpEquals, Value: fromCurr},
+			{Field: "quoted_at", Operator: lyspg.OpEquals, Value: quotedAt.Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s.Select failed: %w", err)
+	}
+
+	currSet = make(map[string]bool)
+	for _, item := range items {
+		currSet[item.ToCurrency] = true
+	}
+
+	return currSet, nil
+}
+
+// SelectLatest returns the most recent rate from fromCurr to toCurr
+func (s Store) SelectLatest(ctx context.Context, fromCurr, toCurr string) (item Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	items, _, err := s.Select(ctx, lyspg.SelectParams{
+		Conditions: []lyspg.Condition{
+			{Field: "from_currency", Operator: lyspg.OpEquals, Value: fromCurr},
+			{Field: "to_currency", Operator: lyspg.OpEquals, Value: toCurr},
+		},
+		Sorts: []string{"quoted_at DESC"},
+		Limit: 1,
+	})
+	if err != nil {
+		return Model{}, fmt.Errorf("s.Select failed: %w", err)
+	}
+	if len(items) == 0 {
+		return Model{}, cerrors.ErrNoRows
+	}
+
+	return items[0], nil
+}
@@ -0,0 +1,107 @@
+package exhostusage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "exchangerate.host API usage"
+	schemaName     string = "exhost"
+	tableName      string = "api_usage"
+	viewName       string = "api_usage"
+	pkColName      string = "month"
+	defaultOrderBy string = "month DESC"
+)
+
+type Model struct {
+	EntryAt       lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	LastRequestAt lystype.Datetime `db:"last_request_at" json:"last_request_at,omitempty"`
+	Month         lystype.Date     `db:"month" json:"month"`
+	RequestCount  int64            `db:"request_count" json:"request_count"`
+}
+
+var (
+	meta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+// Increment adds a request to the count of the month of t, and returns the new count
+func (s Store) Increment(ctx context.Context, t time.Time) (count int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`INSERT INTO %s.%s (month, request_count) VALUES ($1, 1)
+		ON CONFLICT (month) DO UPDATE SET request_count = %s.request_count + 1, last_request_at = now()
+		RETURNING request_count;`, schemaName, tableName, tableName)
+
+	err = s.Db.QueryRow(ctx, stmt, MonthOf(t)).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.QueryRow failed: %w", cerrors.FromPg(err))
+	}
+
+	return count, nil
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+// SelectCount returns the number of requests made in the month of t, or 0 if none
+func (s Store) SelectCount(ctx context.Context, t time.Time) (count int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf("SELECT request_count FROM %s.%s WHERE month = $1;", schemaName, tableName)
+
+	err = s.Db.QueryRow(ctx, stmt, MonthOf(t)).Scan(&count)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.QueryRow failed: %w", err)
+	}
+
+	return count, nil
+}
+
+// MonthOf returns the first day of the UTC month of t, the key of usage rows
+func MonthOf(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,44 @@
+
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA exhost TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA exhost GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA exhost GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS exhost;
+
+
+-- intraday rates from exchangerate.host: a row per quote timestamp, rather than per day
+CREATE TABLE exhost.rate
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  from_currency text NOT NULL, -- ISO 4217
+  to_currency text NOT NULL, -- ISO 4217
+  rate numeric NOT NULL,
+  quoted_at timestamp with time zone NOT NULL,
+  entry_at tracking_at,
+  UNIQUE (from_currency, to_currency, quoted_at)
+);
+COMMENT ON TABLE exhost.rate IS 'shortname: exhr';
+
+CREATE INDEX rate_quoted_at_idx ON exhost.rate (quoted_at DESC);
+
+
+-- API requests per calendar month (UTC), so that the plan's quota is not exceeded
+CREATE TABLE exhost.api_usage
+(	
+  month date PRIMARY KEY, -- first day of the month
+  request_count bigint NOT NULL DEFAULT 0,
+  last_request_at timestamp with time zone NOT NULL DEFAULT now(),
+  entry_at tracking_at
+);
+COMMENT ON TABLE exhost.api_usage IS 'shortname: exhu';
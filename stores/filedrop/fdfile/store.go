@@ -0,0 +1,83 @@
+package fdfile
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+)
+
+const (
+	name       string = "File drop files"
+	schemaName string = "filedrop"
+	tableName  string = "file"
+)
+
+type Input struct {
+	Error                string    `db:"error"`
+	Etag                 string    `db:"etag"`
+	Feed                 string    `db:"feed"`
+	Key                  string    `db:"key"`
+	LastModifiedAtSource time.Time `db:"last_modified_at_source"`
+	RowCount             int       `db:"row_count"`
+	Size                 int64     `db:"size"`
+}
+
+type Model struct {
+	Id int64 `db:"id"`
+	Input
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) GetName() string {
+	return name
+}
+
+// SelectMapByFeed returns the manifest of the files of feed. Map key is the object key
+func (s Store) SelectMapByFeed(ctx context.Context, feed string) (itemMap map[string]Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`SELECT id, error, etag, feed, key, last_modified_at_source, row_count, size FROM %s.%s WHERE feed = $1;`, schemaName, tableName)
+
+	rows, _ := s.Db.Query(ctx, stmt, feed)
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	itemMap = make(map[string]Model, len(items))
+	for _, item := range items {
+		itemMap[item.Key] = item
+	}
+
+	return itemMap, nil
+}
+
+// Upsert inserts input, or updates the file with the same feed and key, and returns its id
+func (s Store) Upsert(ctx context.Context, input Input) (id int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`INSERT INTO %s.%s (feed, key, etag, size, last_modified_at_source, row_count, error) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (feed, key) DO UPDATE SET etag = EXCLUDED.etag, size = EXCLUDED.size, last_modified_at_source = EXCLUDED.last_modified_at_source,
+		row_count = EXCLUDED.row_count, error = EXCLUDED.error, last_modified_at = now()
+		RETURNING id;`, schemaName, tableName)
+
+	err = s.Db.QueryRow(ctx, stmt, input.Feed, input.Key, input.Etag, input.Size, input.LastModifiedAtSource, input.RowCount, input.Error).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.QueryRow failed: %w", cerrors.FromPg(err))
+	}
+
+	return id, nil
+}
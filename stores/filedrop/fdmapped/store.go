@@ -0,0 +1,97 @@
+package fdmapped
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/connectors/flatfile"
+)
+
+const name string = "File drop mapped rows"
+
+// maxParams is the number of bind params of a statement allowed by Postgres
+const maxParams int = 65535
+
+// Store writes the mapped rows of file drop records into the tables of their feed mappings, which are created by the user rather than by migrations
+// it is safe for concurrent use: it holds no state besides the pool and timeout
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) GetName() string {
+	return name
+}
+
+// Upsert inserts rows, the values of the columns of m, into the table of m, updating the non-key columns of the rows whose key exists. rows must have unique keys
+// the table must have a unique constraint on the key columns of m
+func (s Store) Upsert(ctx context.Context, m flatfile.Mapping, rows [][]any) (rowsAffected int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	schemaName, tableName, _ := strings.Cut(m.Table, ".")
+	cols := m.ColumnNames()
+
+	quotedCols := make([]string, len(cols))
+	var updates []string
+	for i, col := range cols {
+		quotedCols[i] = pgx.Identifier{col}.Sanitize()
+		if !slices.Contains(m.Key, col) {
+			updates = append(updates, fmt.Sprintf("%[1]s = EXCLUDED.%[1]s", quotedCols[i]))
+		}
+	}
+	quotedKey := make([]string, len(m.Key))
+	for i, k := range m.Key {
+		quotedKey[i] = pgx.Identifier{k}.Sanitize()
+	}
+
+	onConflict := "DO NOTHING"
+	if len(updates) > 0 {
+		onConflict = "DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+
+	tx, err := s.Db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.Begin failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	chunkSize := maxParams / len(cols)
+	for start := 0; start < len(rows); start += chunkSize {
+		chunk := rows[start:min(start+chunkSize, len(rows))]
+
+		values := make([]string, len(chunk))
+		params := make([]any, 0, len(chunk)*len(cols))
+		for i, row := range chunk {
+			placeholders := make([]string, len(row))
+			for j, v := range row {
+				params = append(params, v)
+				placeholders[j] = fmt.Sprintf("$%d", len(params))
+			}
+			values[i] = "(" + strings.Join(placeholders, ", ") + ")"
+		}
+
+		stmt := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s ON CONFLICT (%s) %s;", pgx.Identifier{schemaName, tableName}.Sanitize(),
+			strings.Join(quotedCols, ", "), strings.Join(values, ", "), strings.Join(quotedKey, ", "), onConflict)
+
+		tag, err := tx.Exec(ctx, stmt, params...)
+		if err != nil {
+			return 0, fmt.Errorf("tx.Exec failed on table: %s: %w", m.Table, cerrors.FromPg(err))
+		}
+		rowsAffected += tag.RowsAffected()
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("tx.Commit failed: %w", err)
+	}
+
+	return rowsAffected, cruntime.AnalyzeAfterLoad(ctx, s.Db, schemaName, tableName, rowsAffected)
+}
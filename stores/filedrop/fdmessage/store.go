@@ -0,0 +1,78 @@
+package fdmessage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+)
+
+const (
+	name       string = "File drop messages"
+	schemaName string = "filedrop"
+	tableName  string = "message"
+)
+
+type Input struct {
+	FileFk      int64      `db:"file_fk"`
+	Mailbox     string     `db:"mailbox"`
+	MessageId   string     `db:"message_id"`
+	Sender      string     `db:"sender"`
+	SentAt      *time.Time `db:"sent_at"`
+	Subject     string     `db:"subject"`
+	Uid         int64      `db:"uid"`
+	UidValidity int64      `db:"uid_validity"`
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) GetName() string {
+	return name
+}
+
+// SelectMessageIds returns the ids of the messages of mailbox whose attachments were loaded
+func (s Store) SelectMessageIds(ctx context.Context, mailbox string) (ids map[string]bool, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf("SELECT DISTINCT message_id FROM %s.%s WHERE mailbox = $1;", schemaName, tableName)
+
+	rows, _ := s.Db.Query(ctx, stmt, mailbox)
+	items, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	ids = make(map[string]bool, len(items))
+	for _, id := range items {
+		ids[id] = true
+	}
+
+	return ids, nil
+}
+
+// Upsert records input as the provenance of its file, replacing any earlier one
+func (s Store) Upsert(ctx context.Context, input Input) error {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`INSERT INTO %s.%s (file_fk, message_id, mailbox, uid, uid_validity, sender, subject, sent_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (file_fk) DO UPDATE SET message_id = EXCLUDED.message_id, mailbox = EXCLUDED.mailbox, uid = EXCLUDED.uid, uid_validity = EXCLUDED.uid_validity,
+		sender = EXCLUDED.sender, subject = EXCLUDED.subject, sent_at = EXCLUDED.sent_at, last_modified_at = now();`, schemaName, tableName)
+
+	_, err := s.Db.Exec(ctx, stmt, input.FileFk, input.MessageId, input.Mailbox, input.Uid, input.UidValidity, input.Sender, input.Subject, input.SentAt)
+	if err != nil {
+		return fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package fdrecord
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "File drop records"
+	schemaName     string = "filedrop"
+	tableName      string = "record"
+	viewName       string = "v_record"
+	pkColName      string = "id"
+	defaultOrderBy string = "last_modified_at_source DESC, key, line_no"
+)
+
+type Input struct {
+	Data           json.RawMessage  `db:"data" json:"data,omitempty" validate:"required"`
+	FileFk         int64            `db:"file_fk" json:"file_fk,omitempty" validate:"required"`
+	LastModifiedAt lystype.Datetime `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	LineNo         int              `db:"line_no" json:"line_no,omitempty" validate:"required"`
+}
+
+type Model struct {
+	Id                   int64            `db:"id" json:"id"`
+	EntryAt              lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	Feed                 string           `db:"feed" json:"feed,omitempty"`
+	Key                  string           `db:"key" json:"key,omitempty"`
+	LastModifiedAtSource lystype.Datetime `db:"last_modified_at_source" json:"last_modified_at_source,omitempty"`
+	MessageId            *string          `db:"message_id" json:"message_id,omitempty"` // of records loaded from email attachments
+	Sender               *string          `db:"sender" json:"sender,omitempty"`
+	Input
+}
+
+var (
+	meta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) BulkInsert(ctx context.Context, inputs []Input) (rowsAffected int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	rowsAffected, err = lyspg.BulkInsert[Input](ctx, s.Db, schemaName, tableName, inputs)
+	if err != nil {
+		return 0, cerrors.FromPg(err)
+	}
+	return rowsAffected, cruntime.AnalyzeAfterLoad(ctx, s.Db, schemaName, tableName, rowsAffected)
+}
+
+// DeleteByFile deletes the records of the file with fileFk, which are replaced as a whole when the file changes
+func (s Store) DeleteByFile(ctx context.Context, fileFk int64) error {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf("DELETE FROM %s.%s WHERE file_fk = $1;", schemaName, tableName)
+	if _, err := s.Db.Exec(ctx, stmt, fileFk); err != nil {
+		return fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return nil
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
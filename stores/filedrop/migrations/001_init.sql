@@ -0,0 +1,62 @@
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA filedrop TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA filedrop GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA filedrop GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS filedrop;
+
+
+-- manifest of the files read from the bucket. A file is read again when its etag changes
+CREATE TABLE filedrop.file
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  feed text NOT NULL, -- name of the feed in the connector config
+  key text NOT NULL, -- object key in the bucket
+  etag text NOT NULL,
+  size bigint NOT NULL,
+  last_modified_at_source timestamp with time zone NOT NULL,
+  row_count int NOT NULL DEFAULT 0,
+  error text NOT NULL DEFAULT '', -- why the file could not be parsed. Its records are then left as they were
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  UNIQUE (feed, key)
+);
+COMMENT ON TABLE filedrop.file IS 'shortname: fdfi';
+
+
+CREATE TABLE filedrop.record
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  file_fk bigint NOT NULL REFERENCES filedrop.file(id) ON DELETE CASCADE,
+  line_no int NOT NULL, -- 1-based position of the record in the file, after any header row
+  data jsonb NOT NULL, -- the record as an object. Values of csv files are strings
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  UNIQUE (file_fk, line_no)
+);
+COMMENT ON TABLE filedrop.record IS 'shortname: fdre';
+
+
+CREATE VIEW filedrop.v_record AS
+SELECT
+  r.data,
+  r.entry_at,
+  f.feed,
+  r.file_fk,
+  r.id,
+  f.key,
+  r.last_modified_at,
+  f.last_modified_at_source,
+  r.line_no
+FROM filedrop.record r
+JOIN filedrop.file f ON r.file_fk = f.id;
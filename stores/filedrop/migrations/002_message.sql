@@ -0,0 +1,40 @@
+
+-- provenance of files received as email attachments: the message each file, and so each of its records, was loaded from
+CREATE TABLE IF NOT EXISTS filedrop.message
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  file_fk bigint NOT NULL UNIQUE REFERENCES filedrop.file(id) ON DELETE CASCADE,
+  message_id text NOT NULL, -- Message-ID header, without angle brackets
+  mailbox text NOT NULL,
+  uid bigint NOT NULL, -- IMAP UID, unique within uid_validity of the mailbox
+  uid_validity bigint NOT NULL,
+  sender text NOT NULL DEFAULT '', -- From address
+  subject text NOT NULL DEFAULT '',
+  sent_at timestamp with time zone, -- Date header
+  entry_at tracking_at,
+  last_modified_at tracking_at
+);
+COMMENT ON TABLE filedrop.message IS 'shortname: fdme';
+
+CREATE INDEX IF NOT EXISTS message_message_id_idx ON filedrop.message (message_id);
+
+
+-- adds the message provenance of records loaded from email attachments
+DROP VIEW IF EXISTS filedrop.v_record;
+
+CREATE VIEW filedrop.v_record AS
+SELECT
+  r.data,
+  r.entry_at,
+  f.feed,
+  r.file_fk,
+  r.id,
+  f.key,
+  r.last_modified_at,
+  f.last_modified_at_source,
+  r.line_no,
+  m.message_id,
+  m.sender
+FROM filedrop.record r
+JOIN filedrop.file f ON r.file_fk = f.id
+LEFT JOIN filedrop.message m ON m.file_fk = f.id;
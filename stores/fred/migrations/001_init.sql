@@ -0,0 +1,57 @@
+
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA fred TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA fred GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA fred GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS fred;
+
+
+CREATE TABLE fred.series
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  code text NOT NULL UNIQUE, -- natural key: FRED series id, e.g. DEXUSEU
+  title text NOT NULL,
+  frequency text NOT NULL, -- FRED short frequency, e.g. D, W, M, Q, A
+  units text NOT NULL,
+  seasonal_adjustment text NOT NULL
+);
+COMMENT ON TABLE fred.series IS 'shortname: fser';
+
+
+CREATE TABLE fred.observation
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  series_fk bigint NOT NULL REFERENCES fred.series(id) ON DELETE CASCADE,
+  day date NOT NULL,
+  value numeric NOT NULL,
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  UNIQUE (series_fk, day)
+);
+COMMENT ON TABLE fred.observation IS 'shortname: fobs';
+
+
+CREATE VIEW fred.v_observation AS
+  SELECT
+    obs.day,
+    obs.entry_at,
+    obs.id,
+    obs.last_modified_at,
+    obs.series_fk,
+    ser.code AS series,
+    obs.value
+  FROM fred.observation obs
+  JOIN fred.series ser ON obs.series_fk = ser.id;
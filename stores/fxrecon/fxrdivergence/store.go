@@ -0,0 +1,106 @@
+package fxrdivergence
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "Exchange rate divergences"
+	schemaName     string = "fxrecon"
+	tableName      string = "divergence"
+	viewName       string = "divergence"
+	pkColName      string = "id"
+	defaultOrderBy string = "day DESC, base_currency, quote_currency, source"
+)
+
+type Input struct {
+	BaseCurrency   string           `db:"base_currency" json:"base_currency,omitempty" validate:"required"`
+	Day            lystype.Date     `db:"day" json:"day,omitempty" validate:"required"`
+	DiffPct        float64          `db:"diff_pct" json:"diff_pct"`
+	LastModifiedAt lystype.Datetime `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	PrimaryRate    float64          `db:"primary_rate" json:"primary_rate,omitempty" validate:"required"`
+	PrimarySource  string           `db:"primary_source" json:"primary_source,omitempty" validate:"required"`
+	QuoteCurrency  string           `db:"quote_currency" json:"quote_currency,omitempty" validate:"required"`
+	Rate           float64          `db:"rate" json:"rate,omitempty" validate:"required"`
+	Source         string           `db:"source" json:"source,omitempty" validate:"required"`
+}
+
+type Model struct {
+	Id      int64            `db:"id" json:"id"`
+	EntryAt lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	Input
+}
+
+var (
+	meta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) BulkInsert(ctx context.Context, inputs []Input) (rowsAffected int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	rowsAffected, err = lyspg.BulkInsert[Input](ctx, s.Db, schemaName, tableName, inputs)
+	if err != nil {
+		return 0, cerrors.FromPg(err)
+	}
+	return rowsAffected, cruntime.AnalyzeAfterLoad(ctx, s.Db, schemaName, tableName, rowsAffected)
+}
+
+// DeleteInRange deletes the divergences in the date range, which are recomputed as a whole
+func (s Store) DeleteInRange(ctx context.Context, startDate, endDate time.Time) (rowsAffected int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf("DELETE FROM %s.%s WHERE day BETWEEN $1 AND $2;", schemaName, tableName)
+
+	tag, err := s.Db.Exec(ctx, stmt, startDate.Format(lystype.DateFormat), endDate.Format(lystype.DateFormat))
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
@@ -0,0 +1,53 @@
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA fxrecon TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA fxrecon GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA fxrecon GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS fxrecon;
+
+
+-- daily rates of each provider side by side
+CREATE TABLE fxrecon.rate
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  source text NOT NULL, -- e.g. ecb, frankfurter, boe, wise
+  base_currency text NOT NULL,
+  quote_currency text NOT NULL,
+  day date NOT NULL,
+  rate numeric NOT NULL, -- quote currency units per base currency unit
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  UNIQUE (source, base_currency, quote_currency, day)
+);
+COMMENT ON TABLE fxrecon.rate IS 'shortname: fxra';
+
+CREATE INDEX ON fxrecon.rate (day);
+
+
+-- rates which differ from the primary source's rate of the day by more than the tolerance. Recomputed for the days of each sync
+CREATE TABLE fxrecon.divergence
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  day date NOT NULL,
+  base_currency text NOT NULL,
+  quote_currency text NOT NULL,
+  source text NOT NULL,
+  rate numeric NOT NULL,
+  primary_source text NOT NULL,
+  primary_rate numeric NOT NULL,
+  diff_pct numeric NOT NULL, -- (rate - primary_rate) / primary_rate * 100
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  UNIQUE (day, base_currency, quote_currency, source)
+);
+COMMENT ON TABLE fxrecon.divergence IS 'shortname: fxdi';
@@ -0,0 +1,91 @@
+package gleifdelta
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "GLEIF applied delta publications"
+	schemaName     string = "gleif"
+	tableName      string = "delta_publish"
+	viewName       string = "delta_publish"
+	pkColName      string = "id"
+	defaultOrderBy string = "publish_at DESC"
+)
+
+type Input struct {
+	DeltaType   string           `db:"delta_type" json:"delta_type,omitempty" validate:"required"`
+	PublishAt   lystype.Datetime `db:"publish_at" json:"publish_at,omitempty" validate:"required"`
+	RecordCount int              `db:"record_count" json:"record_count"`
+}
+
+type Model struct {
+	Id      int64            `db:"id" json:"id"`
+	EntryAt lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	Input
+}
+
+var (
+	meta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+// SelectLatestPublishAt returns the latest applied publication time, or zero if none was applied
+func (s Store) SelectLatestPublishAt(ctx context.Context) (latest time.Time, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	items, _, err := s.Select(ctx, lyspg.SelectParams{Limit: 1})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("s.Select failed: %w", err)
+	}
+	if len(items) == 0 {
+		return time.Time{}, nil
+	}
+
+	return time.Time(items[0].PublishAt), nil
+}
@@ -0,0 +1,144 @@
+package gleifentity
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "GLEIF entities"
+	schemaName     string = "gleif"
+	tableName      string = "entity"
+	viewName       string = "entity"
+	pkColName      string = "id"
+	defaultOrderBy string = "lei"
+)
+
+type Input struct {
+	EntityStatus           string            `db:"entity_status" json:"entity_status,omitempty" validate:"required"`
+	HqAddressCity          string            `db:"hq_address_city" json:"hq_address_city"`
+	HqAddressCountry       string            `db:"hq_address_country" json:"hq_address_country"`
+	HqAddressLine          string            `db:"hq_address_line" json:"hq_address_line"`
+	HqAddressPostalCode    string            `db:"hq_address_postal_code" json:"hq_address_postal_code"`
+	InitialRegistrationAt  *lystype.Datetime `db:"initial_registration_at" json:"initial_registration_at,omitempty"`
+	Jurisdiction           string            `db:"jurisdiction" json:"jurisdiction"`
+	LastModifiedAt         lystype.Datetime  `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	LegalAddressCity       string            `db:"legal_address_city" json:"legal_address_city"`
+	LegalAddressCountry    string            `db:"legal_address_country" json:"legal_address_country"`
+	LegalAddressLine       string            `db:"legal_address_line" json:"legal_address_line"`
+	LegalAddressPostalCode string            `db:"legal_address_postal_code" json:"legal_address_postal_code"`
+	LegalName              string            `db:"legal_name" json:"legal_name,omitempty" validate:"required"`
+	Lei                    string            `db:"lei" json:"lei,omitempty" validate:"required,len=20"`
+	ManagingLou            string            `db:"managing_lou" json:"managing_lou"`
+	NextRenewalAt          *lystype.Datetime `db:"next_renewal_at" json:"next_renewal_at,omitempty"`
+	RecordUpdatedAt        lystype.Datetime  `db:"record_updated_at" json:"record_updated_at,omitempty" validate:"required"`
+	RegistrationStatus     string            `db:"registration_status" json:"registration_status,omitempty" validate:"required"`
+}
+
+type Model struct {
+	Id      int64            `db:"id" json:"id"`
+	EntryAt lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	Input
+}
+
+var (
+	meta, inputMeta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+	inputMeta, _ = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem())
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id))
+}
+
+// Equal compares the GLEIF update timestamps: GLEIF sets a new one whenever a record changes
+func (s Store) Equal(a, b Model) bool {
+	return time.Time(a.RecordUpdatedAt).Equal(time.Time(b.RecordUpdatedAt))
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
+
+// SelectMapByNaturalKey returns the entities with leis, or all entities if leis is empty, with Lei as key
+func (s Store) SelectMapByNaturalKey(ctx context.Context, leis []string) (itemsMap map[string]Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	params := lyspg.SelectParams{}
+	if len(leis) > 0 {
+		params.Conditions = []lyspg.Condition{{Field: "lei", Operator: lyspg.OpIn, InValues: leis}}
+	}
+
+	items, _, err := s.Select(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("s.Select failed: %w", err)
+	}
+
+	itemsMap = make(map[string]Model)
+	for _, item := range items {
+		itemsMap[item.Lei] = item
+	}
+
+	return itemsMap, nil
+}
+
+func (s Store) Update(ctx context.Context, input Input, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	input.LastModifiedAt = lystype.Datetime(time.Now())
+	return cerrors.FromPg(lyspg.Update[Input](ctx, s.Db, schemaName, tableName, pkColName, input, id))
+}
+
+func (s Store) Validate(validate *validator.Validate, input Input) error {
+	return lysmeta.Validate[Input](validate, input)
+}
@@ -0,0 +1,57 @@
+
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA gleif TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA gleif GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA gleif GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS gleif;
+
+
+-- Legal Entity Identifier records of the watched LEIs
+CREATE TABLE gleif.entity
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  lei text NOT NULL UNIQUE, -- natural key
+  legal_name text NOT NULL,
+  legal_address_line text NOT NULL,
+  legal_address_city text NOT NULL,
+  legal_address_postal_code text NOT NULL,
+  legal_address_country text NOT NULL, -- ISO 3166-1 alpha-2
+  hq_address_line text NOT NULL,
+  hq_address_city text NOT NULL,
+  hq_address_postal_code text NOT NULL,
+  hq_address_country text NOT NULL,
+  jurisdiction text NOT NULL,
+  entity_status text NOT NULL, -- e.g. ACTIVE, INACTIVE
+  registration_status text NOT NULL, -- e.g. ISSUED, LAPSED, RETIRED
+  initial_registration_at timestamp with time zone,
+  record_updated_at timestamp with time zone NOT NULL, -- GLEIF's last update of the record
+  next_renewal_at timestamp with time zone,
+  managing_lou text NOT NULL -- LEI of the issuing organization
+);
+COMMENT ON TABLE gleif.entity IS 'shortname: glei';
+
+
+-- golden copy publications whose delta files have been applied, so that the next sync picks the smallest delta file covering the gap
+CREATE TABLE gleif.delta_publish
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  entry_at tracking_at,
+  publish_at timestamp with time zone NOT NULL,
+  delta_type text NOT NULL, -- IntraDay, LastDay, LastWeek or LastMonth, or Api if the records were fetched from the API
+  record_count int NOT NULL,
+  UNIQUE (publish_at, delta_type)
+);
+COMMENT ON TABLE gleif.delta_publish IS 'shortname: gldp';
@@ -0,0 +1,8 @@
+package holiday
+
+import "embed"
+
+// Migrations is an embedded filesystem containing the SQL migrations of the holiday schema, applied in file name order
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
@@ -0,0 +1,36 @@
+
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA holiday TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA holiday GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA holiday GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS holiday;
+
+
+CREATE TABLE holiday.public_holiday
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  country_code text NOT NULL, -- ISO 3166-1 alpha-2
+  day date NOT NULL,
+  name text NOT NULL, -- English name
+  local_name text NOT NULL,
+  nationwide bool NOT NULL, -- false if only observed in the subdivisions listed in counties
+  counties text[] NOT NULL DEFAULT '{}', -- ISO 3166-2 codes, e.g. DE-BY
+  types text[] NOT NULL DEFAULT '{}', -- e.g. Public, Bank, School
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  UNIQUE (country_code, day, name)
+);
+COMMENT ON TABLE holiday.public_holiday IS 'shortname: hph';
+
+CREATE INDEX ON holiday.public_holiday (country_code, day);
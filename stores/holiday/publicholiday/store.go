@@ -0,0 +1,224 @@
+package publicholiday
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "Public holidays"
+	schemaName     string = "holiday"
+	tableName      string = "public_holiday"
+	viewName       string = "public_holiday"
+	pkColName      string = "id"
+	defaultOrderBy string = "id"
+)
+
+// TypePublic is the holiday type of official public holidays. Others are e.g. Bank, School and Observance
+const TypePublic string = "Public"
+
+type Input struct {
+	Counties       []string         `db:"counties" json:"counties"`
+	CountryCode    string           `db:"country_code" json:"country_code,omitempty" validate:"required,len=2"`
+	Day            lystype.Date     `db:"day" json:"day,omitempty" validate:"required"`
+	LastModifiedAt lystype.Datetime `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	LocalName      string           `db:"local_name" json:"local_name"`
+	Name           string           `db:"name" json:"name,omitempty" validate:"required"`
+	Nationwide     bool             `db:"nationwide" json:"nationwide"`
+	Types          []string         `db:"types" json:"types"`
+}
+
+type Model struct {
+	Id      int64            `db:"id" json:"id"`
+	EntryAt lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	Input
+}
+
+var (
+	meta, inputMeta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+	inputMeta, _ = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem())
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+// BulkDelete deletes the items of ids in a single round trip
+func (s Store) BulkDelete(ctx context.Context, ids []int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	if len(ids) == 0 {
+		return nil
+	}
+	return cerrors.FromPg(lyspg.BulkDelete(ctx, s.Db, schemaName, tableName, pkColName, ids))
+}
+
+func (s Store) BulkInsert(ctx context.Context, inputs []Input) (rowsAffected int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	rowsAffected, err = lyspg.BulkInsert[Input](ctx, s.Db, schemaName, tableName, inputs)
+	if err != nil {
+		return 0, cerrors.FromPg(err)
+	}
+	return rowsAffected, cruntime.AnalyzeAfterLoad(ctx, s.Db, schemaName, tableName, rowsAffected)
+}
+
+// BulkUpdate updates the items of ids with the inputs at the same index in a single round trip. The inputs are not modified
+func (s Store) BulkUpdate(ctx context.Context, inputs []Input, ids []int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	if len(inputs) == 0 {
+		return nil
+	}
+	now := lystype.Datetime(time.Now())
+	inputs = slices.Clone(inputs)
+	for i := range inputs {
+		inputs[i].LastModifiedAt = now
+	}
+	return cerrors.FromPg(lyspg.BulkUpdate[Input](ctx, s.Db, schemaName, tableName, pkColName, inputs, ids))
+}
+
+func (s Store) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id))
+}
+
+func (s Store) Equal(a, b Model) bool {
+	return a.LocalName == b.LocalName && a.Nationwide == b.Nationwide && slices.Equal(a.Counties, b.Counties) && slices.Equal(a.Types, b.Types)
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
+
+// SelectInRange returns the holidays of countryCode between startDate and endDate (inclusive), ordered by day
+func (s Store) SelectInRange(ctx context.Context, countryCode string, startDate, endDate time.Time) (items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	items, _, err = s.Select(ctx, lyspg.SelectParams{
+		Conditions: []lyspg.Condition{
+			{Field: "country_code", Operator: lyspg.OpEquals, Value: countryCode},
+			{Field: "day", Operator: lyspg.OpGreaterThanEquals, Value: startDate.Format(lystype.DateFormat)},
+			{Field: "day", Operator: lyspg.OpLessThanEquals, Value: endDate.Format(lystype.DateFormat)},
+		},
+		Sorts: []string{"day", "name"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s.Select failed: %w", err)
+	}
+
+	return items, nil
+}
+
+// SelectMapByNaturalKey returns the holidays of countryCode in year, with day+name as key
+func (s Store) SelectMapByNaturalKey(ctx context.Context, countryCode string, year int) (itemsMap map[string]Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	startDate := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	items, err := s.SelectInRange(ctx, countryCode, startDate, startDate.AddDate(1, 0, -1))
+	if err != nil {
+		return nil, fmt.Errorf("s.SelectInRange failed: %w", err)
+	}
+
+	itemsMap = make(map[string]Model)
+	for _, dbItem := range items {
+		itemsMap[NaturalKey(dbItem.Day.Format(lystype.DateFormat), dbItem.Name)] = dbItem
+	}
+
+	return itemsMap, nil
+}
+
+// NaturalKey returns the map key used by SelectMapByNaturalKey. country_code is not part of it, since maps hold a single country
+func NaturalKey(day, name string) string {
+	return day + "_" + name
+}
+
+// SelectNonBusinessDays returns the days (YYYY-MM-DD) between startDate and endDate on which nationwide public holidays of countryCode fall, for use with IsBusinessDay
+func (s Store) SelectNonBusinessDays(ctx context.Context, countryCode string, startDate, endDate time.Time) (days map[string]bool, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	items, err := s.SelectInRange(ctx, countryCode, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("s.SelectInRange failed: %w", err)
+	}
+
+	days = make(map[string]bool)
+	for _, item := range items {
+		if item.Nationwide && slices.Contains(item.Types, TypePublic) {
+			days[item.Day.Format(lystype.DateFormat)] = true
+		}
+	}
+
+	return days, nil
+}
+
+// IsBusinessDay returns true if day is neither a weekend day nor in holidays, as returned by SelectNonBusinessDays
+func IsBusinessDay(day time.Time, holidays map[string]bool) bool {
+
+	if wd := day.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		return false
+	}
+	return !holidays[day.Format(lystype.DateFormat)]
+}
+
+func (s Store) Update(ctx context.Context, input Input, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	input.LastModifiedAt = lystype.Datetime(time.Now())
+	return cerrors.FromPg(lyspg.Update[Input](ctx, s.Db, schemaName, tableName, pkColName, input, id))
+}
+
+func (s Store) Validate(validate *validator.Validate, input Input) error {
+	return lysmeta.Validate[Input](validate, input)
+}
@@ -0,0 +1,124 @@
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA hubspot TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA hubspot GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA hubspot GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS hubspot;
+
+
+-- the properties column of each table holds the HubSpot properties mapped in the connector config, as a JSON object of key to property value
+-- e.g. {"lead_status": "IN_PROGRESS"} for the mapping lead_status = "hs_lead_status"
+
+CREATE TABLE hubspot.company
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  hubspot_id bigint NOT NULL UNIQUE,
+  name text NOT NULL DEFAULT '',
+  domain text NOT NULL DEFAULT '',
+  industry text NOT NULL DEFAULT '', -- e.g. COMPUTER_SOFTWARE
+  city text NOT NULL DEFAULT '',
+  country text NOT NULL DEFAULT '', -- free text
+  phone text NOT NULL DEFAULT '',
+  properties jsonb NOT NULL DEFAULT '{}',
+  created_at_hubspot timestamp with time zone NOT NULL,
+  updated_at_hubspot timestamp with time zone NOT NULL,
+  entry_at tracking_at,
+  last_modified_at tracking_at
+);
+COMMENT ON TABLE hubspot.company IS 'shortname: hsco';
+
+
+CREATE TABLE hubspot.contact
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  hubspot_id bigint NOT NULL UNIQUE,
+  email text NOT NULL DEFAULT '',
+  first_name text NOT NULL DEFAULT '',
+  last_name text NOT NULL DEFAULT '',
+  phone text NOT NULL DEFAULT '',
+  job_title text NOT NULL DEFAULT '',
+  lifecycle_stage text NOT NULL DEFAULT '', -- e.g. lead, customer
+  company_hubspot_id bigint, -- primary company. Not a foreign key: the company may not be synced yet
+  properties jsonb NOT NULL DEFAULT '{}',
+  created_at_hubspot timestamp with time zone NOT NULL,
+  updated_at_hubspot timestamp with time zone NOT NULL,
+  entry_at tracking_at,
+  last_modified_at tracking_at
+);
+COMMENT ON TABLE hubspot.contact IS 'shortname: hsct';
+
+CREATE INDEX ON hubspot.contact (company_hubspot_id);
+
+
+CREATE TABLE hubspot.deal
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  hubspot_id bigint NOT NULL UNIQUE,
+  name text NOT NULL DEFAULT '',
+  pipeline text NOT NULL DEFAULT '', -- pipeline id, e.g. default
+  deal_stage text NOT NULL DEFAULT '', -- stage id, e.g. closedwon
+  amount numeric,
+  currency text NOT NULL DEFAULT '', -- empty if the account has a single currency
+  close_date timestamp with time zone,
+  company_hubspot_id bigint, -- first associated company
+  properties jsonb NOT NULL DEFAULT '{}',
+  created_at_hubspot timestamp with time zone NOT NULL,
+  updated_at_hubspot timestamp with time zone NOT NULL,
+  entry_at tracking_at,
+  last_modified_at tracking_at
+);
+COMMENT ON TABLE hubspot.deal IS 'shortname: hsde';
+
+CREATE INDEX ON hubspot.deal (company_hubspot_id);
+
+
+CREATE VIEW hubspot.v_contact AS
+  SELECT
+    hsct.company_hubspot_id,
+    hsco.name AS company_name,
+    hsct.created_at_hubspot,
+    hsct.email,
+    hsct.entry_at,
+    hsct.first_name,
+    hsct.hubspot_id,
+    hsct.id,
+    hsct.job_title,
+    hsct.last_modified_at,
+    hsct.last_name,
+    hsct.lifecycle_stage,
+    hsct.phone,
+    hsct.properties,
+    hsct.updated_at_hubspot
+  FROM hubspot.contact hsct
+  LEFT JOIN hubspot.company hsco ON hsct.company_hubspot_id = hsco.hubspot_id;
+
+
+CREATE VIEW hubspot.v_deal AS
+  SELECT
+    hsde.amount,
+    hsde.close_date,
+    hsde.company_hubspot_id,
+    hsco.name AS company_name,
+    hsde.created_at_hubspot,
+    hsde.currency,
+    hsde.deal_stage,
+    hsde.entry_at,
+    hsde.hubspot_id,
+    hsde.id,
+    hsde.last_modified_at,
+    hsde.name,
+    hsde.pipeline,
+    hsde.properties,
+    hsde.updated_at_hubspot
+  FROM hubspot.deal hsde
+  LEFT JOIN hubspot.company hsco ON hsde.company_hubspot_id = hsco.hubspot_id;
@@ -0,0 +1,36 @@
+
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA imf TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA imf GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA imf GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS imf;
+
+-- IMF exchange rate report types:
+-- SDRCV: SDRs per currency unit
+-- REP: representative rate, in currency units per U.S. dollar
+CREATE TYPE imf.rate_type AS ENUM ('SDRCV', 'REP');
+
+
+CREATE TABLE imf.exchange_rate
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  rate_type imf.rate_type NOT NULL,
+  currency_code text NOT NULL, -- ISO 4217
+  day date NOT NULL,
+  rate numeric NOT NULL,
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  UNIQUE (rate_type, currency_code, day)
+);
+COMMENT ON TABLE imf.exchange_rate IS 'shortname: imfxr';
@@ -0,0 +1,124 @@
+package inbounddelivery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+)
+
+const (
+	name       string = "Inbound webhook deliveries"
+	schemaName string = "inbound"
+	tableName  string = "delivery"
+)
+
+type Input struct {
+	Provider   string            `db:"provider"`
+	DeliveryId string            `db:"delivery_id"`
+	Headers    map[string]string `db:"headers"` // k = canonical header name
+	Payload    json.RawMessage   `db:"payload"`
+	ReceivedAt time.Time         `db:"received_at"`
+}
+
+type Model struct {
+	Id          int64      `db:"id"`
+	ProcessedAt *time.Time `db:"processed_at"`
+	Error       string     `db:"error"`
+	Input
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) GetName() string {
+	return name
+}
+
+// Stage inserts input unless a delivery with the same provider and delivery id was already staged, e.g. because the provider retried it
+func (s Store) Stage(ctx context.Context, input Input) (staged bool, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`INSERT INTO %s.%s (provider, delivery_id, headers, payload, received_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (provider, delivery_id) DO NOTHING;`, schemaName, tableName)
+
+	tag, err := s.Db.Exec(ctx, stmt, input.Provider, input.DeliveryId, input.Headers, input.Payload, input.ReceivedAt)
+	if err != nil {
+		return false, fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// SelectPending returns up to limit deliveries which are not yet processed, with an id greater than afterId, in id order
+func (s Store) SelectPending(ctx context.Context, afterId int64, limit int) (items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`SELECT id, provider, delivery_id, headers, payload, received_at, processed_at, error FROM %s.%s
+		WHERE processed_at IS NULL AND id > $1 ORDER BY id LIMIT $2;`, schemaName, tableName)
+
+	rows, _ := s.Db.Query(ctx, stmt, afterId, limit)
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	return items, nil
+}
+
+// SetProcessed marks the delivery with id as processed, clearing any previous error
+func (s Store) SetProcessed(ctx context.Context, id int64) error {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf("UPDATE %s.%s SET processed_at = now(), error = '', last_modified_at = now() WHERE id = $1;", schemaName, tableName)
+
+	if _, err := s.Db.Exec(ctx, stmt, id); err != nil {
+		return fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return nil
+}
+
+// SetError records the error of a failed normalization of the delivery with id, which stays pending
+func (s Store) SetError(ctx context.Context, id int64, msg string) error {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf("UPDATE %s.%s SET error = $2, last_modified_at = now() WHERE id = $1;", schemaName, tableName)
+
+	if _, err := s.Db.Exec(ctx, stmt, id, msg); err != nil {
+		return fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return nil
+}
+
+// DeleteProcessedBefore deletes the deliveries processed before t. Their events are kept
+func (s Store) DeleteProcessedBefore(ctx context.Context, t time.Time) (rowsAffected int64, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf("DELETE FROM %s.%s WHERE processed_at < $1;", schemaName, tableName)
+
+	tag, err := s.Db.Exec(ctx, stmt, t)
+	if err != nil {
+		return 0, fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,96 @@
+package inboundevent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "Inbound webhook events"
+	schemaName     string = "inbound"
+	tableName      string = "event"
+	viewName       string = "event"
+	pkColName      string = "id"
+	defaultOrderBy string = "occurred_at DESC"
+)
+
+type Input struct {
+	DeliveryFk     *int64           `db:"delivery_fk" json:"delivery_fk,omitempty"`
+	EventId        string           `db:"event_id" json:"event_id,omitempty" validate:"required"`
+	EventType      string           `db:"event_type" json:"event_type"`
+	LastModifiedAt lystype.Datetime `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	OccurredAt     lystype.Datetime `db:"occurred_at" json:"occurred_at,omitempty" validate:"required"`
+	Payload        json.RawMessage  `db:"payload" json:"payload,omitempty" validate:"required"`
+	Provider       string           `db:"provider" json:"provider,omitempty" validate:"required"`
+}
+
+type Model struct {
+	Id      int64            `db:"id" json:"id"`
+	EntryAt lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	Input
+}
+
+var (
+	meta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+// InsertIfNew inserts input unless the provider's event with the same id was already inserted, e.g. from an earlier delivery of it
+func (s Store) InsertIfNew(ctx context.Context, input Input) (inserted bool, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`INSERT INTO %s.%s (provider, event_id, event_type, occurred_at, payload, delivery_fk) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (provider, event_id) DO NOTHING;`, schemaName, tableName)
+
+	tag, err := s.Db.Exec(ctx, stmt, input.Provider, input.EventId, input.EventType, time.Time(input.OccurredAt), input.Payload, input.DeliveryFk)
+	if err != nil {
+		return false, fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
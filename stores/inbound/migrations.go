@@ -0,0 +1,8 @@
+package inbound
+
+import "embed"
+
+// Migrations is an embedded filesystem containing the SQL migrations of the inbound schema, applied in file name order
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
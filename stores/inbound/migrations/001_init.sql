@@ -0,0 +1,54 @@
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA inbound TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA inbound GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA inbound GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS inbound;
+
+
+-- staging table of verified webhook deliveries, as received. Syncs normalize them into inbound.event
+CREATE TABLE inbound.delivery
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  provider text NOT NULL, -- name of the provider in the connector config
+  delivery_id text NOT NULL, -- from the provider's id header or field, or else the SHA-256 of the payload
+  headers jsonb NOT NULL DEFAULT '{}', -- request headers, without credentials and signatures
+  payload jsonb NOT NULL,
+  received_at timestamp with time zone NOT NULL,
+  processed_at timestamp with time zone, -- null until normalized
+  error text NOT NULL DEFAULT '', -- of the last failed normalization
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  UNIQUE (provider, delivery_id)
+);
+COMMENT ON TABLE inbound.delivery IS 'shortname: ibde';
+
+CREATE INDEX ON inbound.delivery (id) WHERE processed_at IS NULL;
+
+
+CREATE TABLE inbound.event
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  provider text NOT NULL,
+  event_id text NOT NULL,
+  event_type text NOT NULL DEFAULT '', -- e.g. orders/create, charge.succeeded
+  occurred_at timestamp with time zone NOT NULL, -- from the provider's timestamp field, or else the time received
+  payload jsonb NOT NULL,
+  delivery_fk bigint REFERENCES inbound.delivery(id) ON DELETE SET NULL, -- null once the processed delivery is removed from staging
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  UNIQUE (provider, event_id)
+);
+COMMENT ON TABLE inbound.event IS 'shortname: ibev';
+
+CREATE INDEX ON inbound.event (provider, event_type, occurred_at);
@@ -0,0 +1,8 @@
+package iso4217
+
+import "embed"
+
+// Migrations is an embedded filesystem containing the SQL migrations of the iso4217 schema, applied in file name order
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
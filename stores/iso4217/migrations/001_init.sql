@@ -0,0 +1,31 @@
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA iso4217 TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA iso4217 GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA iso4217 GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS iso4217;
+
+
+CREATE TABLE iso4217.currency
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  code text NOT NULL UNIQUE, -- natural key: alphabetic code, e.g. EUR
+  numeric_code text NOT NULL, -- e.g. 978
+  name text NOT NULL, -- e.g. Euro
+  minor_units int, -- decimals of the minor unit, e.g. 2 for EUR and 0 for JPY. NULL if not applicable, e.g. for gold (XAU)
+  fund bool NOT NULL, -- a fund code, e.g. CLF, rather than a currency
+  entities text[] NOT NULL DEFAULT '{}' -- countries and territories using the currency, as named by ISO, e.g. GERMANY
+);
+COMMENT ON TABLE iso4217.currency IS 'shortname: isoc';
@@ -0,0 +1,134 @@
+package lexinvoiceline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "lexoffice invoice lines"
+	schemaName     string = "lexoffice"
+	tableName      string = "invoice_line"
+	viewName       string = "v_invoice_line"
+	pkColName      string = "id"
+	defaultOrderBy string = "voucher_date DESC, voucher_number, position"
+)
+
+type Input struct {
+	DiscountPercentage *float64         `db:"discount_percentage" json:"discount_percentage,omitempty"`
+	LastModifiedAt     lystype.Datetime `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	LineItemAmount     *float64         `db:"line_item_amount" json:"line_item_amount,omitempty"`
+	Name               string           `db:"name" json:"name"`
+	Position           int              `db:"position" json:"position,omitempty" validate:"required"`
+	Quantity           *float64         `db:"quantity" json:"quantity,omitempty"`
+	TaxRatePercentage  *float64         `db:"tax_rate_percentage" json:"tax_rate_percentage,omitempty"`
+	Type               string           `db:"type" json:"type,omitempty" validate:"required"`
+	UnitName           string           `db:"unit_name" json:"unit_name"`
+	UnitPriceGross     *float64         `db:"unit_price_gross" json:"unit_price_gross,omitempty"`
+	UnitPriceNet       *float64         `db:"unit_price_net" json:"unit_price_net,omitempty"`
+	VoucherFk          int64            `db:"voucher_fk" json:"voucher_fk,omitempty" validate:"required"`
+}
+
+type Model struct {
+	Id            int64            `db:"id" json:"id"`
+	ContactName   string           `db:"contact_name" json:"contact_name,omitempty"`
+	Currency      string           `db:"currency" json:"currency,omitempty"`
+	EntryAt       lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	VoucherDate   lystype.Datetime `db:"voucher_date" json:"voucher_date,omitempty"`
+	VoucherNumber string           `db:"voucher_number" json:"voucher_number,omitempty"`
+	Input
+}
+
+var (
+	meta, inputMeta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+	inputMeta, _ = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem())
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) BulkInsert(ctx context.Context, inputs []Input) (rowsAffected int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	rowsAffected, err = lyspg.BulkInsert[Input](ctx, s.Db, schemaName, tableName, inputs)
+	if err != nil {
+		return 0, cerrors.FromPg(err)
+	}
+	return rowsAffected, cruntime.AnalyzeAfterLoad(ctx, s.Db, schemaName, tableName, rowsAffected)
+}
+
+func (s Store) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id))
+}
+
+// DeleteByVouchers deletes the lines of the invoices with voucherFks, which are replaced as a whole when they change
+func (s Store) DeleteByVouchers(ctx context.Context, voucherFks []int64) error {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	if len(voucherFks) == 0 {
+		return nil
+	}
+
+	stmt := fmt.Sprintf("DELETE FROM %s.%s WHERE voucher_fk = ANY($1);", schemaName, tableName)
+	if _, err := s.Db.Exec(ctx, stmt, voucherFks); err != nil {
+		return fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return nil
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
+
+func (s Store) Validate(validate *validator.Validate, input Input) error {
+	return lysmeta.Validate[Input](validate, input)
+}
@@ -0,0 +1,177 @@
+package lexvoucher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "lexoffice vouchers"
+	schemaName     string = "lexoffice"
+	tableName      string = "voucher"
+	viewName       string = "voucher"
+	pkColName      string = "id"
+	defaultOrderBy string = "voucher_date DESC"
+)
+
+type Input struct {
+	Archived           bool              `db:"archived" json:"archived"`
+	ContactId          string            `db:"contact_id" json:"contact_id"`
+	ContactName        string            `db:"contact_name" json:"contact_name"`
+	CreatedAtLexoffice lystype.Datetime  `db:"created_at_lexoffice" json:"created_at_lexoffice,omitempty" validate:"required"`
+	Currency           string            `db:"currency" json:"currency,omitempty" validate:"required"`
+	DueDate            *lystype.Datetime `db:"due_date" json:"due_date,omitempty"`
+	LastModifiedAt     lystype.Datetime  `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	LexofficeId        string            `db:"lexoffice_id" json:"lexoffice_id,omitempty" validate:"required"`
+	OpenAmount         *float64          `db:"open_amount" json:"open_amount,omitempty"`
+	TotalAmount        float64           `db:"total_amount" json:"total_amount"`
+	UpdatedAtLexoffice lystype.Datetime  `db:"updated_at_lexoffice" json:"updated_at_lexoffice,omitempty" validate:"required"`
+	VoucherDate        lystype.Datetime  `db:"voucher_date" json:"voucher_date,omitempty" validate:"required"`
+	VoucherNumber      string            `db:"voucher_number" json:"voucher_number"`
+	VoucherStatus      string            `db:"voucher_status" json:"voucher_status,omitempty" validate:"required"`
+	VoucherType        string            `db:"voucher_type" json:"voucher_type,omitempty" validate:"required"`
+}
+
+type Model struct {
+	Id      int64            `db:"id" json:"id"`
+	EntryAt lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	Input
+}
+
+var (
+	meta, inputMeta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+	inputMeta, _ = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem())
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) BulkInsert(ctx context.Context, inputs []Input) (rowsAffected int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	rowsAffected, err = lyspg.BulkInsert[Input](ctx, s.Db, schemaName, tableName, inputs)
+	if err != nil {
+		return 0, cerrors.FromPg(err)
+	}
+	return rowsAffected, cruntime.AnalyzeAfterLoad(ctx, s.Db, schemaName, tableName, rowsAffected)
+}
+
+func (s Store) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id))
+}
+
+// Equal compares the lexoffice update timestamps, and the status and open amount, which change with payments
+func (s Store) Equal(a, b Model) bool {
+	return time.Time(a.UpdatedAtLexoffice).Equal(time.Time(b.UpdatedAtLexoffice)) && a.VoucherStatus == b.VoucherStatus && equalAmount(a.OpenAmount, b.OpenAmount)
+}
+
+// equalAmount returns true if a and b are both nil or equal
+func equalAmount(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
+
+// SelectMapByNaturalKey returns the vouchers with lexofficeIds, with LexofficeId as key
+func (s Store) SelectMapByNaturalKey(ctx context.Context, lexofficeIds []string) (itemsMap map[string]Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	itemsMap = make(map[string]Model)
+	if len(lexofficeIds) == 0 {
+		return itemsMap, nil
+	}
+
+	items, _, err := s.Select(ctx, lyspg.SelectParams{Conditions: []lyspg.Condition{{Field: "lexoffice_id", Operator: lyspg.OpIn, InValues: lexofficeIds}}})
+	if err != nil {
+		return nil, fmt.Errorf("s.Select failed: %w", err)
+	}
+
+	for _, item := range items {
+		itemsMap[item.LexofficeId] = item
+	}
+
+	return itemsMap, nil
+}
+
+// SelectMaxUpdatedAt returns the latest lexoffice update timestamp of the vouchers, or the zero time if there are none
+func (s Store) SelectMaxUpdatedAt(ctx context.Context) (maxUpdatedAt time.Time, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf("SELECT max(updated_at_lexoffice) FROM %s.%s;", schemaName, tableName)
+
+	var res *time.Time
+	if err = s.Db.QueryRow(ctx, stmt).Scan(&res); err != nil {
+		return time.Time{}, fmt.Errorf("s.Db.QueryRow failed: %w", cerrors.FromPg(err))
+	}
+	if res == nil {
+		return time.Time{}, nil
+	}
+
+	return *res, nil
+}
+
+func (s Store) Update(ctx context.Context, input Input, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	input.LastModifiedAt = lystype.Datetime(time.Now())
+	return cerrors.FromPg(lyspg.Update[Input](ctx, s.Db, schemaName, tableName, pkColName, input, id))
+}
+
+func (s Store) Validate(validate *validator.Validate, input Input) error {
+	return lysmeta.Validate[Input](validate, input)
+}
@@ -0,0 +1,8 @@
+package lexoffice
+
+import "embed"
+
+// Migrations is an embedded filesystem containing the SQL migrations of the lexoffice schema, applied in file name order
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
@@ -0,0 +1,108 @@
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA lexoffice TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA lexoffice GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA lexoffice GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS lexoffice;
+
+
+CREATE TABLE lexoffice.contact
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  lexoffice_id text NOT NULL UNIQUE, -- uuid
+  version int NOT NULL, -- incremented by lexoffice on each change
+  customer_number int,
+  vendor_number int,
+  name text NOT NULL, -- company name, or first and last name of a person
+  email text NOT NULL DEFAULT '',
+  street text NOT NULL DEFAULT '', -- of the first billing address
+  zip text NOT NULL DEFAULT '',
+  city text NOT NULL DEFAULT '',
+  country_code text NOT NULL DEFAULT '',
+  vat_registration_id text NOT NULL DEFAULT '',
+  archived boolean NOT NULL DEFAULT false,
+  entry_at tracking_at,
+  last_modified_at tracking_at
+);
+COMMENT ON TABLE lexoffice.contact IS 'shortname: lxco';
+
+
+CREATE TABLE lexoffice.voucher
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  lexoffice_id text NOT NULL UNIQUE, -- uuid
+  voucher_type text NOT NULL, -- e.g. invoice, creditnote, salesinvoice, purchaseinvoice
+  voucher_status text NOT NULL, -- e.g. draft, open, paid, voided
+  voucher_number text NOT NULL DEFAULT '',
+  voucher_date timestamp with time zone NOT NULL,
+  due_date timestamp with time zone,
+  contact_id text NOT NULL DEFAULT '', -- lexoffice_id of the contact, if any
+  contact_name text NOT NULL DEFAULT '',
+  currency text NOT NULL,
+  total_amount numeric NOT NULL, -- gross
+  open_amount numeric,
+  archived boolean NOT NULL DEFAULT false,
+  created_at_lexoffice timestamp with time zone NOT NULL,
+  updated_at_lexoffice timestamp with time zone NOT NULL,
+  entry_at tracking_at,
+  last_modified_at tracking_at
+);
+COMMENT ON TABLE lexoffice.voucher IS 'shortname: lxvo';
+
+CREATE INDEX ON lexoffice.voucher (updated_at_lexoffice);
+CREATE INDEX ON lexoffice.voucher (contact_id);
+
+
+CREATE TABLE lexoffice.invoice_line
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  voucher_fk bigint NOT NULL REFERENCES lexoffice.voucher(id) ON DELETE CASCADE,
+  position int NOT NULL, -- 1-based
+  type text NOT NULL, -- custom, material, service or text
+  name text NOT NULL DEFAULT '',
+  quantity numeric,
+  unit_name text NOT NULL DEFAULT '',
+  unit_price_net numeric,
+  unit_price_gross numeric,
+  tax_rate_percentage numeric,
+  discount_percentage numeric,
+  line_item_amount numeric, -- net or gross, as the invoice's tax type
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  UNIQUE (voucher_fk, position)
+);
+COMMENT ON TABLE lexoffice.invoice_line IS 'shortname: lxil';
+
+
+CREATE VIEW lexoffice.v_invoice_line AS
+  SELECT
+    lxvo.contact_name,
+    lxvo.currency,
+    lxil.discount_percentage,
+    lxil.entry_at,
+    lxil.id,
+    lxil.last_modified_at,
+    lxil.line_item_amount,
+    lxil.name,
+    lxil.position,
+    lxil.quantity,
+    lxil.tax_rate_percentage,
+    lxil.type,
+    lxil.unit_name,
+    lxil.unit_price_gross,
+    lxil.unit_price_net,
+    lxvo.voucher_date,
+    lxil.voucher_fk,
+    lxvo.voucher_number
+  FROM lexoffice.invoice_line lxil
+  JOIN lexoffice.voucher lxvo ON lxil.voucher_fk = lxvo.id;
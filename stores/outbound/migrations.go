@@ -0,0 +1,8 @@
+package outbound
+
+import "embed"
+
+// Migrations is an embedded filesystem containing the SQL migrations of the outbound schema, applied in file name order
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
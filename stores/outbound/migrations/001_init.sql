@@ -0,0 +1,33 @@
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA outbound TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA outbound GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA outbound GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS outbound;
+
+
+-- delivery state of each destination that rates are pushed to. Rates changed after cursor_at are pushed by the next sync
+CREATE TABLE outbound.delivery_state
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  destination text NOT NULL UNIQUE, -- name of the destination in the connector config
+  cursor_at timestamp with time zone, -- change time of the last rate pushed. NULL until the first successful push
+  last_attempt_at timestamp with time zone NOT NULL,
+  last_success_at timestamp with time zone,
+  last_pushed_count int NOT NULL DEFAULT 0, -- rates pushed by the last successful push
+  pushed_count bigint NOT NULL DEFAULT 0, -- rates pushed in total
+  error text NOT NULL DEFAULT '', -- why the last push failed. Empty if it succeeded
+  entry_at tracking_at,
+  last_modified_at tracking_at
+);
+COMMENT ON TABLE outbound.delivery_state IS 'shortname: obds';
@@ -0,0 +1,110 @@
+package obstate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+)
+
+const (
+	name       string = "Outbound delivery state"
+	schemaName string = "outbound"
+	tableName  string = "delivery_state"
+	columns    string = "id, destination, cursor_at, last_attempt_at, last_success_at, last_pushed_count, pushed_count, error"
+)
+
+type Model struct {
+	Id              int64      `db:"id" json:"id"`
+	CursorAt        *time.Time `db:"cursor_at" json:"cursor_at"`
+	Destination     string     `db:"destination" json:"destination"`
+	Error           string     `db:"error" json:"error"`
+	LastAttemptAt   time.Time  `db:"last_attempt_at" json:"last_attempt_at"`
+	LastPushedCount int        `db:"last_pushed_count" json:"last_pushed_count"`
+	LastSuccessAt   *time.Time `db:"last_success_at" json:"last_success_at"`
+	PushedCount     int64      `db:"pushed_count" json:"pushed_count"`
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) GetName() string {
+	return name
+}
+
+// SelectAll returns the state of each destination that rates were pushed to, ordered by destination
+func (s Store) SelectAll(ctx context.Context) (items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`SELECT %s FROM %s.%s ORDER BY destination;`, columns, schemaName, tableName)
+
+	rows, _ := s.Db.Query(ctx, stmt)
+	items, err = pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	return items, nil
+}
+
+// SelectByDestination returns the state of destination. The error matches cerrors.ErrNotFound if nothing was pushed to it yet
+func (s Store) SelectByDestination(ctx context.Context, destination string) (item Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`SELECT %s FROM %s.%s WHERE destination = $1;`, columns, schemaName, tableName)
+
+	rows, _ := s.Db.Query(ctx, stmt, destination)
+	item, err = pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return Model{}, fmt.Errorf("pgx.CollectExactlyOneRow failed: %w", cerrors.FromPg(err))
+	}
+
+	return item, nil
+}
+
+// RecordSuccess advances the cursor of destination to cursorAt after count rates were pushed at attemptAt, and clears its error
+// cursorAt is kept if nil, i.e. if there were no rates to push
+func (s Store) RecordSuccess(ctx context.Context, destination string, cursorAt *time.Time, count int, attemptAt time.Time) error {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`INSERT INTO %s.%s (destination, cursor_at, last_attempt_at, last_success_at, last_pushed_count, pushed_count) VALUES ($1, $2, $3, $3, $4, $4)
+		ON CONFLICT (destination) DO UPDATE SET cursor_at = coalesce(EXCLUDED.cursor_at, %s.cursor_at), last_attempt_at = EXCLUDED.last_attempt_at,
+		last_success_at = EXCLUDED.last_success_at, last_pushed_count = EXCLUDED.last_pushed_count, pushed_count = %s.pushed_count + EXCLUDED.pushed_count,
+		error = '', last_modified_at = now();`, schemaName, tableName, tableName, tableName)
+
+	_, err := s.Db.Exec(ctx, stmt, destination, cursorAt, attemptAt, count)
+	if err != nil {
+		return fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return nil
+}
+
+// RecordFailure records the error of the push to destination at attemptAt, keeping its cursor so that the rates are pushed again
+func (s Store) RecordFailure(ctx context.Context, destination string, pushErr error, attemptAt time.Time) error {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`INSERT INTO %s.%s (destination, last_attempt_at, error) VALUES ($1, $2, $3)
+		ON CONFLICT (destination) DO UPDATE SET last_attempt_at = EXCLUDED.last_attempt_at, error = EXCLUDED.error, last_modified_at = now();`, schemaName, tableName)
+
+	_, err := s.Db.Exec(ctx, stmt, destination, attemptAt, pushErr.Error())
+	if err != nil {
+		return fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return nil
+}
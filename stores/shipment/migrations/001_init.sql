@@ -0,0 +1,73 @@
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA shipment TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA shipment GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA shipment GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS shipment;
+
+
+-- shipments are registered by inserting a row with carrier and tracking_number, e.g. by an order system, or from the connector config
+CREATE TABLE shipment.shipment
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  carrier text NOT NULL, -- e.g. dhl
+  tracking_number text NOT NULL,
+  reference text NOT NULL DEFAULT '', -- optional reference of the registering system, e.g. an order number
+  service text NOT NULL DEFAULT '', -- carrier service, e.g. express or parcel-de
+  origin text NOT NULL DEFAULT '',
+  destination text NOT NULL DEFAULT '',
+  status_code text NOT NULL DEFAULT 'unknown', -- pre-transit, transit, delivered, failure or unknown
+  status text NOT NULL DEFAULT '', -- carrier status text
+  status_at timestamp with time zone,
+  delivered_at timestamp with time zone,
+  last_polled_at timestamp with time zone,
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  UNIQUE (carrier, tracking_number)
+);
+COMMENT ON TABLE shipment.shipment IS 'shortname: shp';
+
+-- open shipments, polled until delivered
+CREATE INDEX ON shipment.shipment (carrier, last_polled_at) WHERE delivered_at IS NULL;
+
+
+CREATE TABLE shipment.tracking_event
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  shipment_fk bigint NOT NULL REFERENCES shipment.shipment(id) ON DELETE CASCADE,
+  event_at timestamp with time zone NOT NULL,
+  status_code text NOT NULL,
+  status text NOT NULL DEFAULT '',
+  description text NOT NULL DEFAULT '',
+  location text NOT NULL DEFAULT '',
+  entry_at tracking_at,
+  UNIQUE (shipment_fk, event_at, status_code, description)
+);
+COMMENT ON TABLE shipment.tracking_event IS 'shortname: shpev';
+
+
+CREATE VIEW shipment.v_tracking_event AS
+  SELECT
+    shp.carrier,
+    shpev.description,
+    shpev.entry_at,
+    shpev.event_at,
+    shpev.id,
+    shpev.location,
+    shp.reference,
+    shpev.shipment_fk,
+    shpev.status,
+    shpev.status_code,
+    shp.tracking_number
+  FROM shipment.tracking_event shpev
+  JOIN shipment.shipment shp ON shpev.shipment_fk = shp.id;
@@ -0,0 +1,206 @@
+package trackedshipment
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "Tracked shipments"
+	schemaName     string = "shipment"
+	tableName      string = "shipment"
+	viewName       string = "shipment"
+	pkColName      string = "id"
+	defaultOrderBy string = "id"
+)
+
+// status codes, common to all carriers
+const (
+	StatusPreTransit string = "pre-transit"
+	StatusTransit    string = "transit"
+	StatusDelivered  string = "delivered"
+	StatusFailure    string = "failure"
+	StatusUnknown    string = "unknown"
+)
+
+type Input struct {
+	Carrier        string            `db:"carrier" json:"carrier,omitempty" validate:"required"`
+	DeliveredAt    *lystype.Datetime `db:"delivered_at" json:"delivered_at,omitempty"`
+	Destination    string            `db:"destination" json:"destination"`
+	LastModifiedAt lystype.Datetime  `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	LastPolledAt   *lystype.Datetime `db:"last_polled_at" json:"last_polled_at,omitempty"`
+	Origin         string            `db:"origin" json:"origin"`
+	Reference      string            `db:"reference" json:"reference"`
+	Service        string            `db:"service" json:"service"`
+	Status         string            `db:"status" json:"status"`
+	StatusAt       *lystype.Datetime `db:"status_at" json:"status_at,omitempty"`
+	StatusCode     string            `db:"status_code" json:"status_code,omitempty" validate:"required,oneof=pre-transit transit delivered failure unknown"`
+	TrackingNumber string            `db:"tracking_number" json:"tracking_number,omitempty" validate:"required"`
+}
+
+type Model struct {
+	Id      int64            `db:"id" json:"id"`
+	EntryAt lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	Input
+}
+
+var (
+	meta, inputMeta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+	inputMeta, _ = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem())
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id))
+}
+
+// Equal compares the tracking data of a and b, ignoring the reference and poll time, which are not set by carriers
+func (s Store) Equal(a, b Model) bool {
+	return a.Destination == b.Destination && a.Origin == b.Origin && a.Service == b.Service && a.Status == b.Status && a.StatusCode == b.StatusCode &&
+		equalDatetime(a.StatusAt, b.StatusAt) && equalDatetime(a.DeliveredAt, b.DeliveredAt)
+}
+
+// equalDatetime returns true if a and b are both nil or the same instant
+func equalDatetime(a, b *lystype.Datetime) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return time.Time(*a).Equal(time.Time(*b))
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
+
+// SelectMapByNaturalKey returns the shipments of carrier with trackingNumbers, with TrackingNumber as key
+func (s Store) SelectMapByNaturalKey(ctx context.Context, carrier string, trackingNumbers []string) (itemsMap map[string]Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	itemsMap = make(map[string]Model)
+	if len(trackingNumbers) == 0 {
+		return itemsMap, nil
+	}
+
+	items, _, err := s.Select(ctx, lyspg.SelectParams{
+		Conditions: []lyspg.Condition{
+			{Field: "carrier", Operator: lyspg.OpEquals, Value: carrier},
+			{Field: "tracking_number", Operator: lyspg.OpIn, InValues: trackingNumbers},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s.Select failed: %w", err)
+	}
+
+	for _, item := range items {
+		itemsMap[item.TrackingNumber] = item
+	}
+
+	return itemsMap, nil
+}
+
+// SelectOpen returns the undelivered shipments of carrier registered since registeredSince which were not polled since polledSince, oldest poll first
+func (s Store) SelectOpen(ctx context.Context, carrier string, registeredSince, polledSince time.Time) (items []Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	candidates, _, err := s.Select(ctx, lyspg.SelectParams{
+		Conditions: []lyspg.Condition{
+			{Field: "carrier", Operator: lyspg.OpEquals, Value: carrier},
+			{Field: "delivered_at", Operator: lyspg.OpNull},
+			{Field: "entry_at", Operator: lyspg.OpGreaterThanEquals, Value: registeredSince.Format(time.RFC3339)},
+		},
+		Sorts: []string{"last_polled_at NULLS FIRST", "id"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s.Select failed: %w", err)
+	}
+
+	items = []Model{}
+	for _, item := range candidates {
+		if item.LastPolledAt == nil || time.Time(*item.LastPolledAt).Before(polledSince) {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+// SetPolled sets the poll time of shipment id, without changing last_modified_at
+func (s Store) SetPolled(ctx context.Context, id int64, polledAt time.Time) error {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf("UPDATE %s.%s SET last_polled_at = $1 WHERE %s = $2;", schemaName, tableName, pkColName)
+	tag, err := s.Db.Exec(ctx, stmt, polledAt, id)
+	if err != nil {
+		return cerrors.FromPg(fmt.Errorf("s.Db.Exec failed: %w", err))
+	}
+	if tag.RowsAffected() == 0 {
+		return cerrors.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s Store) Update(ctx context.Context, input Input, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	input.LastModifiedAt = lystype.Datetime(time.Now())
+	return cerrors.FromPg(lyspg.Update[Input](ctx, s.Db, schemaName, tableName, pkColName, input, id))
+}
+
+func (s Store) Validate(validate *validator.Validate, input Input) error {
+	return lysmeta.Validate[Input](validate, input)
+}
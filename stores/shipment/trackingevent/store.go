@@ -0,0 +1,134 @@
+package trackingevent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "Shipment tracking events"
+	schemaName     string = "shipment"
+	tableName      string = "tracking_event"
+	viewName       string = "v_tracking_event"
+	pkColName      string = "id"
+	defaultOrderBy string = "event_at"
+)
+
+// Input has no last_modified_at: tracking events are only added, never updated
+type Input struct {
+	Description string           `db:"description" json:"description"`
+	EventAt     lystype.Datetime `db:"event_at" json:"event_at,omitempty" validate:"required"`
+	Location    string           `db:"location" json:"location"`
+	ShipmentFk  int64            `db:"shipment_fk" json:"shipment_fk,omitempty" validate:"required"`
+	Status      string           `db:"status" json:"status"`
+	StatusCode  string           `db:"status_code" json:"status_code,omitempty" validate:"required"`
+}
+
+type Model struct {
+	Id             int64            `db:"id" json:"id"`
+	Carrier        string           `db:"carrier" json:"carrier,omitempty"`
+	EntryAt        lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	Reference      string           `db:"reference" json:"reference"`
+	TrackingNumber string           `db:"tracking_number" json:"tracking_number,omitempty"`
+	Input
+}
+
+var (
+	meta, inputMeta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+	inputMeta, _ = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem())
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) BulkInsert(ctx context.Context, inputs []Input) (rowsAffected int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	rowsAffected, err = lyspg.BulkInsert[Input](ctx, s.Db, schemaName, tableName, inputs)
+	if err != nil {
+		return 0, cerrors.FromPg(err)
+	}
+	return rowsAffected, cruntime.AnalyzeAfterLoad(ctx, s.Db, schemaName, tableName, rowsAffected)
+}
+
+func (s Store) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id))
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+// NaturalKey returns the key of an event in maps returned by SelectMapByNaturalKey
+func NaturalKey(eventAt time.Time, statusCode, description string) string {
+	return eventAt.UTC().Format(time.RFC3339) + "|" + statusCode + "|" + description
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
+
+// SelectMapByNaturalKey returns the events of shipmentFk, with NaturalKey as key
+func (s Store) SelectMapByNaturalKey(ctx context.Context, shipmentFk int64) (itemsMap map[string]Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	items, _, err := s.Select(ctx, lyspg.SelectParams{Conditions: []lyspg.Condition{{Field: "shipment_fk", Operator: lyspg.OpEquals, Value: fmt.Sprint(shipmentFk)}}})
+	if err != nil {
+		return nil, fmt.Errorf("s.Select failed: %w", err)
+	}
+
+	itemsMap = make(map[string]Model)
+	for _, item := range items {
+		itemsMap[NaturalKey(time.Time(item.EventAt), item.StatusCode, item.Description)] = item
+	}
+
+	return itemsMap, nil
+}
+
+func (s Store) Validate(validate *validator.Validate, input Input) error {
+	return lysmeta.Validate[Input](validate, input)
+}
@@ -0,0 +1,107 @@
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA shopify TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA shopify GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA shopify GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS shopify;
+
+
+CREATE TABLE shopify.product
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  shop text NOT NULL, -- shop name, e.g. acme for acme.myshopify.com
+  shopify_id bigint NOT NULL,
+  title text NOT NULL,
+  handle text NOT NULL,
+  vendor text NOT NULL DEFAULT '',
+  product_type text NOT NULL DEFAULT '',
+  status text NOT NULL, -- active, archived or draft
+  tags text[] NOT NULL DEFAULT '{}',
+  created_at_shop timestamp with time zone NOT NULL,
+  updated_at_shop timestamp with time zone NOT NULL,
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  UNIQUE (shop, shopify_id)
+);
+COMMENT ON TABLE shopify.product IS 'shortname: shpr';
+
+
+CREATE TABLE shopify.variant
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  product_fk bigint NOT NULL REFERENCES shopify.product(id) ON DELETE CASCADE,
+  shopify_id bigint NOT NULL,
+  title text NOT NULL,
+  sku text NOT NULL DEFAULT '',
+  barcode text NOT NULL DEFAULT '',
+  price numeric NOT NULL,
+  compare_at_price numeric,
+  inventory_quantity int NOT NULL DEFAULT 0,
+  updated_at_shop timestamp with time zone NOT NULL,
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  UNIQUE (product_fk, shopify_id)
+);
+COMMENT ON TABLE shopify.variant IS 'shortname: shva';
+
+CREATE INDEX ON shopify.variant (sku);
+
+
+CREATE TABLE shopify.sales_order
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  shop text NOT NULL,
+  shopify_id bigint NOT NULL,
+  name text NOT NULL, -- order number shown to customers, e.g. #1001
+  email text NOT NULL DEFAULT '',
+  currency text NOT NULL,
+  financial_status text NOT NULL DEFAULT '', -- e.g. paid, refunded
+  fulfillment_status text NOT NULL DEFAULT '', -- e.g. fulfilled, partial. Empty if unfulfilled
+  subtotal_price numeric NOT NULL,
+  total_tax numeric NOT NULL,
+  total_discounts numeric NOT NULL,
+  total_price numeric NOT NULL,
+  line_item_count int NOT NULL,
+  created_at_shop timestamp with time zone NOT NULL,
+  updated_at_shop timestamp with time zone NOT NULL,
+  processed_at timestamp with time zone,
+  cancelled_at timestamp with time zone,
+  closed_at timestamp with time zone,
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  UNIQUE (shop, shopify_id)
+);
+COMMENT ON TABLE shopify.sales_order IS 'shortname: shso';
+
+CREATE INDEX ON shopify.sales_order (shop, updated_at_shop);
+
+
+CREATE VIEW shopify.v_variant AS
+  SELECT
+    shva.barcode,
+    shva.compare_at_price,
+    shva.entry_at,
+    shva.id,
+    shva.inventory_quantity,
+    shva.last_modified_at,
+    shva.price,
+    shva.product_fk,
+    shpr.shopify_id AS product_shopify_id,
+    shpr.title AS product_title,
+    shpr.shop,
+    shva.shopify_id,
+    shva.sku,
+    shva.title,
+    shva.updated_at_shop
+  FROM shopify.variant shva
+  JOIN shopify.product shpr ON shva.product_fk = shpr.id;
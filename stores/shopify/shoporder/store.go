@@ -0,0 +1,175 @@
+package shoporder
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "Shopify orders"
+	schemaName     string = "shopify"
+	tableName      string = "sales_order"
+	viewName       string = "sales_order"
+	pkColName      string = "id"
+	defaultOrderBy string = "created_at_shop DESC"
+)
+
+type Input struct {
+	CancelledAt       *lystype.Datetime `db:"cancelled_at" json:"cancelled_at,omitempty"`
+	ClosedAt          *lystype.Datetime `db:"closed_at" json:"closed_at,omitempty"`
+	CreatedAtShop     lystype.Datetime  `db:"created_at_shop" json:"created_at_shop,omitempty" validate:"required"`
+	Currency          string            `db:"currency" json:"currency,omitempty" validate:"required,len=3"`
+	Email             string            `db:"email" json:"email"`
+	FinancialStatus   string            `db:"financial_status" json:"financial_status"`
+	FulfillmentStatus string            `db:"fulfillment_status" json:"fulfillment_status"`
+	LastModifiedAt    lystype.Datetime  `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	LineItemCount     int               `db:"line_item_count" json:"line_item_count"`
+	Name              string            `db:"name" json:"name,omitempty" validate:"required"`
+	ProcessedAt       *lystype.Datetime `db:"processed_at" json:"processed_at,omitempty"`
+	Shop              string            `db:"shop" json:"shop,omitempty" validate:"required"`
+	ShopifyId         int64             `db:"shopify_id" json:"shopify_id,omitempty" validate:"required"`
+	SubtotalPrice     float64           `db:"subtotal_price" json:"subtotal_price"`
+	TotalDiscounts    float64           `db:"total_discounts" json:"total_discounts"`
+	TotalPrice        float64           `db:"total_price" json:"total_price"`
+	TotalTax          float64           `db:"total_tax" json:"total_tax"`
+	UpdatedAtShop     lystype.Datetime  `db:"updated_at_shop" json:"updated_at_shop,omitempty" validate:"required"`
+}
+
+type Model struct {
+	Id      int64            `db:"id" json:"id"`
+	EntryAt lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	Input
+}
+
+var (
+	meta, inputMeta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+	inputMeta, _ = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem())
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) BulkInsert(ctx context.Context, inputs []Input) (rowsAffected int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	rowsAffected, err = lyspg.BulkInsert[Input](ctx, s.Db, schemaName, tableName, inputs)
+	if err != nil {
+		return 0, cerrors.FromPg(err)
+	}
+	return rowsAffected, cruntime.AnalyzeAfterLoad(ctx, s.Db, schemaName, tableName, rowsAffected)
+}
+
+// BulkUpdate updates the items of ids with the inputs at the same index in a single round trip. The inputs are not modified
+func (s Store) BulkUpdate(ctx context.Context, inputs []Input, ids []int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	if len(inputs) == 0 {
+		return nil
+	}
+	now := lystype.Datetime(time.Now())
+	inputs = slices.Clone(inputs)
+	for i := range inputs {
+		inputs[i].LastModifiedAt = now
+	}
+	return cerrors.FromPg(lyspg.BulkUpdate[Input](ctx, s.Db, schemaName, tableName, pkColName, inputs, ids))
+}
+
+func (s Store) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id))
+}
+
+// Equal compares the Shopify update timestamps: Shopify sets a new one whenever an order changes
+func (s Store) Equal(a, b Model) bool {
+	return time.Time(a.UpdatedAtShop).Equal(time.Time(b.UpdatedAtShop))
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
+
+// SelectMapByNaturalKey returns the orders of shop with shopifyIds, with ShopifyId as key
+func (s Store) SelectMapByNaturalKey(ctx context.Context, shop string, shopifyIds []int64) (itemsMap map[int64]Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	itemsMap = make(map[int64]Model)
+	if len(shopifyIds) == 0 {
+		return itemsMap, nil
+	}
+
+	// lyspg.OpIn sends text values, which can't be compared with the bigint shopify_id
+	stmt := fmt.Sprintf("SELECT %s FROM %s.%s WHERE shop = $1 AND shopify_id = ANY($2);", strings.Join(meta.DbTags, ", "), schemaName, viewName)
+
+	rows, _ := s.Db.Query(ctx, stmt, shop, shopifyIds)
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	for _, item := range items {
+		itemsMap[item.ShopifyId] = item
+	}
+
+	return itemsMap, nil
+}
+
+func (s Store) Update(ctx context.Context, input Input, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	input.LastModifiedAt = lystype.Datetime(time.Now())
+	return cerrors.FromPg(lyspg.Update[Input](ctx, s.Db, schemaName, tableName, pkColName, input, id))
+}
+
+func (s Store) Validate(validate *validator.Validate, input Input) error {
+	return lysmeta.Validate[Input](validate, input)
+}
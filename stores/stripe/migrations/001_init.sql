@@ -0,0 +1,113 @@
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA stripe TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA stripe GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA stripe GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS stripe;
+
+-- amounts are in major currency units, e.g. 12.34 EUR, and currencies are ISO 4217 upper case codes, as in the exchange rate schemas
+
+
+CREATE TABLE stripe.balance_transaction
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  stripe_id text NOT NULL UNIQUE, -- e.g. txn_1MiN3gLkdIwHu7ixxapQrznl
+  type text NOT NULL, -- e.g. charge, refund, payout, stripe_fee
+  reporting_category text NOT NULL DEFAULT '', -- e.g. charge, refund, payout
+  status text NOT NULL, -- available or pending
+  currency text NOT NULL,
+  amount numeric NOT NULL, -- gross
+  fee numeric NOT NULL,
+  net numeric NOT NULL,
+  exchange_rate numeric, -- if converted from the currency of the source
+  description text NOT NULL DEFAULT '',
+  source_id text NOT NULL DEFAULT '', -- e.g. the charge, refund or payout id
+  created_at_stripe timestamp with time zone NOT NULL,
+  available_on timestamp with time zone NOT NULL,
+  entry_at tracking_at,
+  last_modified_at tracking_at
+);
+COMMENT ON TABLE stripe.balance_transaction IS 'shortname: stbt';
+
+CREATE INDEX ON stripe.balance_transaction (created_at_stripe);
+CREATE INDEX ON stripe.balance_transaction (source_id);
+
+
+CREATE TABLE stripe.fee
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  balance_transaction_fk bigint NOT NULL REFERENCES stripe.balance_transaction(id) ON DELETE CASCADE,
+  type text NOT NULL, -- application_fee, payment_method_passthrough_fee, stripe_fee or tax
+  description text NOT NULL DEFAULT '',
+  application text NOT NULL DEFAULT '', -- Connect application of application fees
+  currency text NOT NULL,
+  amount numeric NOT NULL,
+  entry_at tracking_at,
+  last_modified_at tracking_at
+);
+COMMENT ON TABLE stripe.fee IS 'shortname: stfe';
+
+CREATE INDEX ON stripe.fee (balance_transaction_fk);
+
+
+CREATE TABLE stripe.payout
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  stripe_id text NOT NULL UNIQUE, -- e.g. po_1OaFDbEcg9tTZuTgNYmX0PKB
+  amount numeric NOT NULL,
+  currency text NOT NULL,
+  status text NOT NULL, -- paid, pending, in_transit, canceled or failed
+  method text NOT NULL, -- standard or instant
+  type text NOT NULL, -- bank_account or card
+  automatic boolean NOT NULL,
+  description text NOT NULL DEFAULT '',
+  statement_descriptor text NOT NULL DEFAULT '', -- shown on the bank statement
+  destination_id text NOT NULL DEFAULT '', -- bank account or card id
+  balance_transaction_id text NOT NULL DEFAULT '',
+  failure_code text NOT NULL DEFAULT '',
+  created_at_stripe timestamp with time zone NOT NULL,
+  arrival_date date NOT NULL, -- expected, or actual once paid
+  entry_at tracking_at,
+  last_modified_at tracking_at
+);
+COMMENT ON TABLE stripe.payout IS 'shortname: stpo';
+
+CREATE INDEX ON stripe.payout (arrival_date);
+
+
+CREATE TABLE stripe.sync_cursor
+(	
+  object text PRIMARY KEY, -- balance_transaction or payout
+  created_since timestamp with time zone NOT NULL, -- the next sync lists the objects created since
+  entry_at tracking_at,
+  last_modified_at tracking_at
+);
+COMMENT ON TABLE stripe.sync_cursor IS 'shortname: stsc';
+
+
+CREATE VIEW stripe.v_fee AS
+  SELECT
+    stfe.amount,
+    stfe.application,
+    stfe.balance_transaction_fk,
+    stbt.stripe_id AS balance_transaction_stripe_id,
+    stbt.created_at_stripe,
+    stfe.currency,
+    stfe.description,
+    stfe.entry_at,
+    stfe.id,
+    stfe.last_modified_at,
+    stbt.source_id,
+    stfe.type
+  FROM stripe.fee stfe
+  JOIN stripe.balance_transaction stbt ON stfe.balance_transaction_fk = stbt.id;
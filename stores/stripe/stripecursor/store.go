@@ -0,0 +1,69 @@
+package stripecursor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+)
+
+const (
+	name       string = "Stripe sync cursors"
+	schemaName string = "stripe"
+	tableName  string = "sync_cursor"
+)
+
+// objects with a cursor
+const (
+	ObjectBalanceTransaction string = "balance_transaction"
+	ObjectPayout             string = "payout"
+)
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) GetName() string {
+	return name
+}
+
+// SelectCreatedSince returns the creation time from which the next sync of object lists its objects, or the zero time if none
+func (s Store) SelectCreatedSince(ctx context.Context, object string) (createdSince time.Time, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf("SELECT created_since FROM %s.%s WHERE object = $1;", schemaName, tableName)
+
+	err = s.Db.QueryRow(ctx, stmt, object).Scan(&createdSince)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("s.Db.QueryRow failed: %w", cerrors.FromPg(err))
+	}
+
+	return createdSince, nil
+}
+
+// SetCreatedSince stores createdSince as the creation time from which the next sync of object lists its objects
+func (s Store) SetCreatedSince(ctx context.Context, object string, createdSince time.Time) error {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	stmt := fmt.Sprintf(`INSERT INTO %s.%s (object, created_since) VALUES ($1, $2)
+		ON CONFLICT (object) DO UPDATE SET created_since = EXCLUDED.created_since, last_modified_at = now();`, schemaName, tableName)
+
+	if _, err := s.Db.Exec(ctx, stmt, object, createdSince); err != nil {
+		return fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return nil
+}
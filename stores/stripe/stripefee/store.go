@@ -0,0 +1,128 @@
+package stripefee
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "Stripe fees"
+	schemaName     string = "stripe"
+	tableName      string = "fee"
+	viewName       string = "v_fee"
+	pkColName      string = "id"
+	defaultOrderBy string = "created_at_stripe DESC, type"
+)
+
+type Input struct {
+	Amount               float64          `db:"amount" json:"amount"`
+	Application          string           `db:"application" json:"application"`
+	BalanceTransactionFk int64            `db:"balance_transaction_fk" json:"balance_transaction_fk,omitempty" validate:"required"`
+	Currency             string           `db:"currency" json:"currency,omitempty" validate:"required"`
+	Description          string           `db:"description" json:"description"`
+	LastModifiedAt       lystype.Datetime `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	Type                 string           `db:"type" json:"type,omitempty" validate:"required"`
+}
+
+type Model struct {
+	Id                         int64            `db:"id" json:"id"`
+	BalanceTransactionStripeId string           `db:"balance_transaction_stripe_id" json:"balance_transaction_stripe_id,omitempty"`
+	CreatedAtStripe            lystype.Datetime `db:"created_at_stripe" json:"created_at_stripe,omitempty"`
+	EntryAt                    lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	SourceId                   string           `db:"source_id" json:"source_id,omitempty"`
+	Input
+}
+
+var (
+	meta, inputMeta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+	inputMeta, _ = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem())
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) BulkInsert(ctx context.Context, inputs []Input) (rowsAffected int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	rowsAffected, err = lyspg.BulkInsert[Input](ctx, s.Db, schemaName, tableName, inputs)
+	if err != nil {
+		return 0, cerrors.FromPg(err)
+	}
+	return rowsAffected, cruntime.AnalyzeAfterLoad(ctx, s.Db, schemaName, tableName, rowsAffected)
+}
+
+func (s Store) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id))
+}
+
+// DeleteByBalanceTransactions deletes the fees of the balance transactions with balanceTransactionFks, which are replaced as a whole when they change
+func (s Store) DeleteByBalanceTransactions(ctx context.Context, balanceTransactionFks []int64) error {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	if len(balanceTransactionFks) == 0 {
+		return nil
+	}
+
+	stmt := fmt.Sprintf("DELETE FROM %s.%s WHERE balance_transaction_fk = ANY($1);", schemaName, tableName)
+	if _, err := s.Db.Exec(ctx, stmt, balanceTransactionFks); err != nil {
+		return fmt.Errorf("s.Db.Exec failed: %w", cerrors.FromPg(err))
+	}
+
+	return nil
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
+
+func (s Store) Validate(validate *validator.Validate, input Input) error {
+	return lysmeta.Validate[Input](validate, input)
+}
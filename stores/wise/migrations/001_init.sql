@@ -0,0 +1,74 @@
+/*
+as needed, after running migrations as the owner user:
+GRANT USAGE ON SCHEMA wise TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA wise GRANT SELECT, UPDATE, INSERT, DELETE ON TABLES TO <cli_user>;
+ALTER DEFAULT PRIVILEGES IN SCHEMA wise GRANT USAGE, SELECT ON SEQUENCES TO <cli_user>;
+*/
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'tracking_at') THEN
+    CREATE DOMAIN tracking_at AS timestamp with time zone NOT NULL DEFAULT now();
+  END IF;
+END
+$$;
+
+CREATE SCHEMA IF NOT EXISTS wise;
+
+
+CREATE TABLE wise.rate
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  source_currency text NOT NULL,
+  target_currency text NOT NULL,
+  day date NOT NULL,
+  rate numeric NOT NULL, -- Wise mid-market rate: target currency units per source currency unit
+  entry_at tracking_at,
+  last_modified_at tracking_at,
+  UNIQUE (source_currency, target_currency, day)
+);
+COMMENT ON TABLE wise.rate IS 'shortname: wsra';
+
+
+CREATE TABLE wise.transfer
+(	
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  wise_id bigint NOT NULL UNIQUE,
+  profile_id bigint NOT NULL,
+  status text NOT NULL, -- e.g. processing, outgoing_payment_sent, cancelled
+  reference text NOT NULL DEFAULT '',
+  source_currency text NOT NULL,
+  source_value numeric NOT NULL, -- converted amount, after fees
+  target_currency text NOT NULL,
+  target_value numeric NOT NULL,
+  rate numeric NOT NULL, -- applied conversion rate
+  created_at_wise timestamp with time zone NOT NULL,
+  entry_at tracking_at,
+  last_modified_at tracking_at
+);
+COMMENT ON TABLE wise.transfer IS 'shortname: wstr';
+
+CREATE INDEX ON wise.transfer (created_at_wise);
+
+
+-- transfers with the mid-market rate of their day, if synced, and the spread of the applied rate below it
+CREATE VIEW wise.v_transfer AS
+  SELECT
+    wstr.created_at_wise,
+    wstr.entry_at,
+    wstr.id,
+    wstr.last_modified_at,
+    wsra.rate AS mid_market_rate,
+    wstr.profile_id,
+    wstr.rate,
+    wstr.reference,
+    wstr.source_currency,
+    wstr.source_value,
+    CASE WHEN wsra.rate > 0 THEN round((wsra.rate - wstr.rate) / wsra.rate * 100, 4) END AS spread_pct,
+    wstr.status,
+    wstr.target_currency,
+    wstr.target_value,
+    wstr.wise_id
+  FROM wise.transfer wstr
+  LEFT JOIN wise.rate wsra ON wsra.source_currency = wstr.source_currency AND wsra.target_currency = wstr.target_currency
+    AND wsra.day = (wstr.created_at_wise AT TIME ZONE 'UTC')::date;
@@ -0,0 +1,170 @@
+package wisetransfer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/cruntime"
+	"github.com/loveyourstack/lys/lysmeta"
+	"github.com/loveyourstack/lys/lyspg"
+	"github.com/loveyourstack/lys/lystype"
+)
+
+const (
+	name           string = "Wise transfers"
+	schemaName     string = "wise"
+	tableName      string = "transfer"
+	viewName       string = "v_transfer"
+	pkColName      string = "id"
+	defaultOrderBy string = "created_at_wise DESC"
+)
+
+type Input struct {
+	CreatedAtWise  lystype.Datetime `db:"created_at_wise" json:"created_at_wise,omitempty" validate:"required"`
+	LastModifiedAt lystype.Datetime `db:"last_modified_at" json:"last_modified_at,omitempty"` // assigned in Update funcs
+	ProfileId      int64            `db:"profile_id" json:"profile_id,omitempty" validate:"required"`
+	Rate           float64          `db:"rate" json:"rate"`
+	Reference      string           `db:"reference" json:"reference"`
+	SourceCurrency string           `db:"source_currency" json:"source_currency,omitempty" validate:"required"`
+	SourceValue    float64          `db:"source_value" json:"source_value"`
+	Status         string           `db:"status" json:"status,omitempty" validate:"required"`
+	TargetCurrency string           `db:"target_currency" json:"target_currency,omitempty" validate:"required"`
+	TargetValue    float64          `db:"target_value" json:"target_value"`
+	WiseId         int64            `db:"wise_id" json:"wise_id,omitempty" validate:"required"`
+}
+
+type Model struct {
+	Id            int64            `db:"id" json:"id"`
+	EntryAt       lystype.Datetime `db:"entry_at" json:"entry_at,omitempty"`
+	MidMarketRate *float64         `db:"mid_market_rate" json:"mid_market_rate,omitempty"`
+	SpreadPct     *float64         `db:"spread_pct" json:"spread_pct,omitempty"`
+	Input
+}
+
+var (
+	meta, inputMeta lysmeta.Result
+)
+
+func init() {
+	var err error
+	meta, err = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem(), reflect.ValueOf(&Model{}).Elem())
+	if err != nil {
+		log.Fatalf("lysmeta.AnalyzeStructs failed for %s.%s: %s", schemaName, tableName, err.Error())
+	}
+	inputMeta, _ = lysmeta.AnalyzeStructs(reflect.ValueOf(&Input{}).Elem())
+}
+
+type Store struct {
+	Db      *pgxpool.Pool
+	Timeout time.Duration // if > 0, bounds each operation, overriding cruntime.WithOpTimeout
+}
+
+func (s Store) BulkInsert(ctx context.Context, inputs []Input) (rowsAffected int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	rowsAffected, err = lyspg.BulkInsert[Input](ctx, s.Db, schemaName, tableName, inputs)
+	if err != nil {
+		return 0, cerrors.FromPg(err)
+	}
+	return rowsAffected, cruntime.AnalyzeAfterLoad(ctx, s.Db, schemaName, tableName, rowsAffected)
+}
+
+// BulkUpdate updates the items of ids with the inputs at the same index in a single round trip. The inputs are not modified
+func (s Store) BulkUpdate(ctx context.Context, inputs []Input, ids []int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	if len(inputs) == 0 {
+		return nil
+	}
+	now := lystype.Datetime(time.Now())
+	inputs = slices.Clone(inputs)
+	for i := range inputs {
+		inputs[i].LastModifiedAt = now
+	}
+	return cerrors.FromPg(lyspg.BulkUpdate[Input](ctx, s.Db, schemaName, tableName, pkColName, inputs, ids))
+}
+
+func (s Store) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return cerrors.FromPg(lyspg.DeleteUnique(ctx, s.Db, schemaName, tableName, pkColName, id))
+}
+
+// Equal compares the values which change after a transfer is created: its status, and its amounts and rate until it is funded
+func (s Store) Equal(a, b Model) bool {
+	return a.Status == b.Status && a.Rate == b.Rate && a.SourceValue == b.SourceValue && a.TargetValue == b.TargetValue && a.Reference == b.Reference
+}
+
+func (s Store) GetMeta() lysmeta.Result {
+	return meta
+}
+func (s Store) GetName() string {
+	return name
+}
+
+func (s Store) Insert(ctx context.Context, input Input) (newId int64, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	newId, err = lyspg.Insert[Input, int64](ctx, s.Db, schemaName, tableName, pkColName, input)
+	return newId, cerrors.FromPg(err)
+}
+
+func (s Store) Select(ctx context.Context, params lyspg.SelectParams) (items []Model, unpagedCount lyspg.TotalCount, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	return lyspg.Select[Model](ctx, s.Db, schemaName, tableName, viewName, defaultOrderBy, meta.DbTags, params)
+}
+
+func (s Store) SelectById(ctx context.Context, fields []string, id int64) (item Model, err error) {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	item, err = lyspg.SelectUnique[Model](ctx, s.Db, schemaName, viewName, pkColName, fields, meta.DbTags, id)
+	return item, cerrors.FromPg(err)
+}
+
+// SelectMapByNaturalKey returns the transfers with wiseIds, with WiseId as key
+func (s Store) SelectMapByNaturalKey(ctx context.Context, wiseIds []int64) (itemsMap map[int64]Model, err error) {
+
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+
+	itemsMap = make(map[int64]Model)
+	if len(wiseIds) == 0 {
+		return itemsMap, nil
+	}
+
+	// lyspg.OpIn sends text values, which can't be compared with the bigint wise_id
+	stmt := fmt.Sprintf("SELECT %s FROM %s.%s WHERE wise_id = ANY($1);", strings.Join(meta.DbTags, ", "), schemaName, viewName)
+
+	rows, _ := s.Db.Query(ctx, stmt, wiseIds)
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByName[Model])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows failed: %w", cerrors.FromPg(err))
+	}
+
+	for _, item := range items {
+		itemsMap[item.WiseId] = item
+	}
+
+	return itemsMap, nil
+}
+
+func (s Store) Update(ctx context.Context, input Input, id int64) error {
+	ctx, cancel := cruntime.OpContext(ctx, s.Timeout)
+	defer cancel()
+	input.LastModifiedAt = lystype.Datetime(time.Now())
+	return cerrors.FromPg(lyspg.Update[Input](ctx, s.Db, schemaName, tableName, pkColName, input, id))
+}
+
+func (s Store) Validate(validate *validator.Validate, input Input) error {
+	return lysmeta.Validate[Input](validate, input)
+}
@@ -0,0 +1,95 @@
+package valuation
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"sync/atomic"
+)
+
+// RoundingPolicy decides how position values are rounded to the minor unit of the target currency
+type RoundingPolicy int
+
+const (
+	// RoundHalfEven rounds halves to the even minor unit, as accounting systems do, so that rounding errors do not accumulate. It is the default
+	RoundHalfEven RoundingPolicy = iota
+
+	// RoundHalfUp rounds halves away from zero
+	RoundHalfUp
+
+	// RoundNone does not round
+	RoundNone
+)
+
+// minorUnits are the ISO 4217 minor units of the currencies which do not have 2 decimals. They are used until a table is loaded with SetMinorUnits or LoadMinorUnits
+var minorUnits = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// loadedMinorUnits is the table set by SetMinorUnits, nil if none was set
+var loadedMinorUnits atomic.Pointer[map[string]int]
+
+// MinorUnitsSource reads the minor units of all currencies, e.g. isocurrency.Store with the ISO 4217 list synced by the iso4217 connector
+type MinorUnitsSource interface {
+	SelectMinorUnits(ctx context.Context) (units map[string]int, err error)
+}
+
+// MinorUnits returns the number of decimals of the minor unit of currency, e.g. 2 for EUR (cents), 0 for JPY and 3 for KWD
+// the loaded table is used if set. Currencies not in it, or without minor units such as gold (XAU), fall back to the built-in table, then to 2
+func MinorUnits(currency string) int {
+	if loaded := loadedMinorUnits.Load(); loaded != nil {
+		if units, ok := (*loaded)[currency]; ok {
+			return units
+		}
+	}
+	if units, ok := minorUnits[currency]; ok {
+		return units
+	}
+	return 2
+}
+
+// SetMinorUnits replaces the loaded table of minor units by currency code, e.g. after a sync of the ISO 4217 list. An empty table reverts to the built-in one
+// it is safe to call while amounts are rounded concurrently. units is copied, so the caller may modify it afterwards
+func SetMinorUnits(units map[string]int) {
+	if len(units) == 0 {
+		loadedMinorUnits.Store(nil)
+		return
+	}
+	clone := maps.Clone(units)
+	loadedMinorUnits.Store(&clone)
+}
+
+// LoadMinorUnits loads the minor units of src with SetMinorUnits and returns their number
+// if src has none, e.g. since the list was not synced yet, the table in use is kept
+func LoadMinorUnits(ctx context.Context, src MinorUnitsSource) (count int, err error) {
+
+	units, err := src.SelectMinorUnits(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("src.SelectMinorUnits failed: %w", err)
+	}
+	if len(units) == 0 {
+		return 0, nil
+	}
+
+	SetMinorUnits(units)
+	return len(units), nil
+}
+
+// Round rounds amount to the minor unit of currency according to p
+func (p RoundingPolicy) Round(amount float64, currency string) float64 {
+
+	if p == RoundNone {
+		return amount
+	}
+
+	// amounts such as 2.675 are stored as slightly less, 2.67499..., and would not be treated as halves: noise below a millionth of the minor unit is removed first
+	scale := math.Pow10(MinorUnits(currency))
+	scaled := math.Round(amount*scale*1e6) / 1e6
+
+	if p == RoundHalfUp {
+		return math.Round(scaled) / scale
+	}
+	return math.RoundToEven(scaled) / scale
+}
@@ -0,0 +1,21 @@
+package valuation
+
+import "testing"
+
+func TestSetMinorUnitsCopies(t *testing.T) {
+
+	t.Cleanup(func() { SetMinorUnits(nil) })
+
+	units := map[string]int{"XTS": 3}
+	SetMinorUnits(units)
+	units["XTS"] = 0
+
+	if got := MinorUnits("XTS"); got != 3 {
+		t.Errorf("MinorUnits after the caller modified the table: got %d, want 3", got)
+	}
+
+	SetMinorUnits(nil)
+	if got := MinorUnits("XTS"); got != 2 {
+		t.Errorf("MinorUnits after reverting to the built-in table: got %d, want 2", got)
+	}
+}
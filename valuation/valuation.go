@@ -0,0 +1,97 @@
+// Package valuation values portfolios of positions in several currencies in a single target currency, using the stored ECB rates
+package valuation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/converter"
+)
+
+// Position is an amount held in a currency
+type Position struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
+// PositionValue is a Position valued in the target currency
+type PositionValue struct {
+	Position
+	Rate  float64 `json:"rate"`  // units of the target currency per unit of Currency
+	Value float64 `json:"value"` // Amount * Rate, rounded by the policy of the Valuer
+}
+
+// Valuation is the result of a Value call
+type Valuation struct {
+	Currency string `json:"currency"` // target currency
+	converter.Fixing
+	Total     float64         `json:"total"` // sum of the position values, so that the detail adds up to it
+	Positions []PositionValue `json:"positions"`
+}
+
+// Valuer values portfolios with the rates of Converter. It is safe for concurrent use if Converter is
+type Valuer struct {
+	Converter converter.Converter
+	Rounding  RoundingPolicy // RoundHalfEven by default
+}
+
+// Value returns the value of positions in currency on day, or on the most recent day with a fixing before it. If day is zero, the latest fixing is used
+// the rates of all positions are read in one lookup. Only currencies without an ECB rate, such as coin symbols valued by Converter.Crypto, are converted one by one
+// returns an error matching converter.ErrRateNotFound which names the currencies without a rate
+func (v Valuer) Value(ctx context.Context, positions []Position, currency string, day time.Time) (val Valuation, err error) {
+
+	for i, pos := range positions {
+		if pos.Currency == "" {
+			return Valuation{}, fmt.Errorf("%w: position %d has no currency", cerrors.ErrValidationFailed, i)
+		}
+	}
+
+	// units of each currency per unit of the target currency
+	rates, err := v.Converter.Rates(ctx, currency, day)
+	if err != nil {
+		return Valuation{}, fmt.Errorf("v.Converter.Rates failed: %w", err)
+	}
+	rates.Rates[currency] = 1
+
+	// the rate of each currency into the target currency
+	toRates := make(map[string]float64, len(rates.Rates))
+	var missing []string
+	for _, pos := range positions {
+		if _, ok := toRates[pos.Currency]; ok {
+			continue
+		}
+
+		if rate, ok := rates.Rates[pos.Currency]; ok {
+			toRates[pos.Currency] = 1 / rate
+			continue
+		}
+
+		conv, err := v.Converter.Convert(ctx, pos.Currency, currency, 1, time.Time(rates.Day))
+		if err != nil {
+			if errors.Is(err, converter.ErrRateNotFound) {
+				missing = append(missing, pos.Currency)
+				toRates[pos.Currency] = 0
+				continue
+			}
+			return Valuation{}, fmt.Errorf("v.Converter.Convert failed for %s: %w", pos.Currency, err)
+		}
+		toRates[pos.Currency] = conv.Rate
+	}
+	if len(missing) > 0 {
+		return Valuation{}, fmt.Errorf("%w: no exchange rate to %s on %s for currencies %v", converter.ErrRateNotFound, currency, time.Time(rates.Day).Format("2006-01-02"), missing)
+	}
+
+	val = Valuation{Currency: currency, Fixing: rates.Fixing, Positions: make([]PositionValue, len(positions))}
+	for i, pos := range positions {
+		rate := toRates[pos.Currency]
+		value := v.Rounding.Round(pos.Amount*rate, currency)
+		val.Positions[i] = PositionValue{Position: pos, Rate: rate, Value: value}
+		val.Total += value
+	}
+	val.Total = v.Rounding.Round(val.Total, currency)
+
+	return val, nil
+}
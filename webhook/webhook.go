@@ -0,0 +1,271 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/connectors/internal/apiclient"
+)
+
+// event types
+const (
+	EventRatesSynced string = "rates.synced"
+	EventRatesStale  string = "rates.stale"
+	EventSyncFailed  string = "sync.failed"
+	EventSchemaDrift string = "schema.drift"
+)
+
+// request headers sent with each delivery
+const (
+	HeaderEvent     string = "X-Connectors-Event"
+	HeaderDelivery  string = "X-Connectors-Delivery"
+	HeaderTimestamp string = "X-Connectors-Timestamp"
+	HeaderSignature string = "X-Connectors-Signature"
+)
+
+const (
+	defaultMaxAttempts int           = 5
+	defaultQueueSize   int           = 1000
+	initialBackoff     time.Duration = 2 * time.Second
+	maxBackoff         time.Duration = 5 * time.Minute
+)
+
+// Config is the configuration of a single webhook endpoint
+type Config struct {
+	Url         string   `toml:"url"`
+	Secret      string   `toml:"secret"`      // HMAC-SHA256 signing key. If empty, deliveries are not signed
+	Events      []string `toml:"events"`      // event types to send. If empty, all events are sent
+	MaxAttempts int      `toml:"maxAttempts"` // number of delivery attempts per event, including the first. Defaults to 5
+}
+
+// Event is the JSON payload posted to the webhook endpoint
+type Event struct {
+	Id         string    `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+// RatesSyncedData is the Data of a rates.synced event
+type RatesSyncedData struct {
+	Target    string `json:"target,omitempty"` // sync target name if several databases are configured
+	Dataset   string `json:"dataset"`
+	Params    string `json:"params,omitempty"`
+	LatestDay string `json:"latest_day,omitempty"`
+}
+
+// RatesStaleData is the Data of a rates.stale event
+type RatesStaleData struct {
+	Target      string `json:"target,omitempty"` // sync target name if several databases are configured
+	Dataset     string `json:"dataset"`
+	LatestDay   string `json:"latest_day,omitempty"`
+	ExpectedDay string `json:"expected_day"`
+	Lag         int    `json:"lag"`
+}
+
+// SyncFailedData is the Data of a sync.failed event
+type SyncFailedData struct {
+	Target  string `json:"target,omitempty"` // sync target name if several databases are configured
+	Dataset string `json:"dataset"`
+	Params  string `json:"params,omitempty"`
+	Error   string `json:"error"`
+}
+
+// SchemaDriftData is the Data of a schema.drift event
+type SchemaDriftData struct {
+	Connector string   `json:"connector"`
+	Endpoint  string   `json:"endpoint"` // host and path of the requests, with variable segments replaced by {id}, and the response format
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+}
+
+// delivery is a queued event with its delivery state
+type delivery struct {
+	event   Event
+	body    []byte
+	attempt int
+}
+
+// Emitter posts events to a single webhook endpoint. Events are queued in memory and delivered by Run; failed deliveries are re-queued with exponential backoff
+type Emitter struct {
+	Config     Config
+	HttpClient *http.Client
+	InfoLog    *slog.Logger
+	ErrorLog   *slog.Logger
+
+	queue chan delivery
+}
+
+func NewEmitter(conf Config, infoLog, errorLog *slog.Logger) *Emitter {
+
+	if conf.MaxAttempts < 1 {
+		conf.MaxAttempts = defaultMaxAttempts
+	}
+
+	return &Emitter{
+		Config:     conf,
+		HttpClient: &http.Client{Timeout: 10 * time.Second},
+		InfoLog:    infoLog,
+		ErrorLog:   errorLog,
+		queue:      make(chan delivery, defaultQueueSize),
+	}
+}
+
+// Emit queues an event of eventType with the supplied data. It does not block: if the queue is full, the event is dropped and logged
+func (e *Emitter) Emit(eventType string, data any) {
+
+	if len(e.Config.Events) > 0 && !slices.Contains(e.Config.Events, eventType) {
+		return
+	}
+
+	ev := Event{
+		Id:         newId(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+
+	body, err := json.Marshal(ev)
+	if err != nil {
+		e.ErrorLog.Error("webhook: json.Marshal failed", "event", eventType, clog.KeyError, err.Error())
+		return
+	}
+
+	e.enqueue(delivery{event: ev, body: body})
+}
+
+// Run delivers queued events until ctx is cancelled
+func (e *Emitter) Run(ctx context.Context) {
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-e.queue:
+			e.deliver(ctx, d)
+		}
+	}
+}
+
+func (e *Emitter) deliver(ctx context.Context, d delivery) {
+
+	d.attempt++
+	err := e.post(ctx, d)
+	if err == nil {
+		e.InfoLog.Debug("webhook delivered", clog.KeyUrl, e.Config.Url, "event", d.event.Type, "id", d.event.Id, "attempt", d.attempt)
+		return
+	}
+
+	if d.attempt >= e.Config.MaxAttempts {
+		e.ErrorLog.Error("webhook delivery failed, giving up", clog.KeyUrl, e.Config.Url, "event", d.event.Type, "id", d.event.Id, "attempts", d.attempt, clog.KeyError, err.Error())
+		return
+	}
+
+	backoff := min(initialBackoff<<(d.attempt-1), maxBackoff)
+	e.ErrorLog.Warn("webhook delivery failed, retrying", clog.KeyUrl, e.Config.Url, "event", d.event.Type, "id", d.event.Id, "attempt", d.attempt, "retry_in", backoff.String(), clog.KeyError, err.Error())
+
+	// re-queue after backoff without blocking delivery of other events
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+			e.enqueue(d)
+		}
+	}()
+}
+
+func (e *Emitter) enqueue(d delivery) {
+	select {
+	case e.queue <- d:
+	default:
+		e.ErrorLog.Error("webhook queue full, event dropped", clog.KeyUrl, e.Config.Url, "event", d.event.Type, "id", d.event.Id)
+	}
+}
+
+func (e *Emitter) post(ctx context.Context, d delivery) error {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.Config.Url, bytes.NewReader(d.body))
+	if err != nil {
+		return fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, d.event.Type)
+	req.Header.Set(HeaderDelivery, d.event.Id)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	if e.Config.Secret != "" {
+		req.Header.Set(HeaderSignature, "sha256="+Sign(e.Config.Secret, timestamp, d.body))
+	}
+
+	resp, err := e.HttpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("e.HttpClient.Do failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of "<timestamp>.<body>" using secret. Receivers verify a delivery by recomputing it from the timestamp and signature headers
+func Sign(secret string, timestamp int64, body []byte) string {
+	return apiclient.Sign(secret, apiclient.TimestampedBody(timestamp, nil, body))
+}
+
+// Verify reports whether signature (with or without "sha256=" prefix) is valid for timestamp and body
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+
+	if len(signature) > 7 && signature[:7] == "sha256=" {
+		signature = signature[7:]
+	}
+	expected := Sign(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+func newId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Emitters fans events out to multiple webhook endpoints
+type Emitters []*Emitter
+
+// Emit queues the event on each emitter
+func (es Emitters) Emit(eventType string, data any) {
+	for _, e := range es {
+		e.Emit(eventType, data)
+	}
+}
+
+// Run runs all emitters until ctx is cancelled
+func (es Emitters) Run(ctx context.Context) {
+
+	var wg sync.WaitGroup
+	for _, e := range es {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.Run(ctx)
+		}()
+	}
+	wg.Wait()
+}
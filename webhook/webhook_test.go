@@ -0,0 +1,107 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/loveyourstack/connectors/webhook"
+)
+
+const (
+	testSecret    string = "whsec_test"
+	testTimestamp int64  = 1700000000
+	testBody      string = `{"id":"evt_1","type":"rates.synced"}`
+	testSignature string = "a58aa4f5f511f7a40562bd4e9779d17e1c29b9ca243466aabd8175df377ae329" // HMAC-SHA256 of "1700000000." + testBody
+)
+
+func TestSign(t *testing.T) {
+
+	if got := webhook.Sign(testSecret, testTimestamp, []byte(testBody)); got != testSignature {
+		t.Errorf("webhook.Sign: got %s, want %s", got, testSignature)
+	}
+}
+
+func TestVerify(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      string
+		signature string
+		want      bool
+	}{
+		{"valid", testSecret, testTimestamp, testBody, testSignature, true},
+		{"valid with prefix", testSecret, testTimestamp, testBody, "sha256=" + testSignature, true},
+		{"tampered body", testSecret, testTimestamp, `{"id":"evt_1","type":"sync.failed"}`, testSignature, false},
+		{"body with trailing line break", testSecret, testTimestamp, testBody + "\n", testSignature, false},
+		{"wrong secret", "whsec_other", testTimestamp, testBody, testSignature, false},
+		{"restamped delivery", testSecret, testTimestamp + 3600, testBody, testSignature, false}, // an old delivery can't be replayed with a new timestamp
+		{"truncated signature", testSecret, testTimestamp, testBody, testSignature[:32], false},
+		{"upper case signature", testSecret, testTimestamp, testBody, "A58AA4F5F511F7A40562BD4E9779D17E1C29B9CA243466AABD8175DF377AE329", false},
+		{"other prefix", testSecret, testTimestamp, testBody, "sha1=" + testSignature, false},
+		{"no signature", testSecret, testTimestamp, testBody, "", false},
+	}
+
+	for _, tt := range tests {
+		if got := webhook.Verify(tt.secret, tt.timestamp, []byte(tt.body), tt.signature); got != tt.want {
+			t.Errorf("%s: got %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEmitterSignsDeliveries(t *testing.T) {
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	deliveries := make(chan received, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- received{header: r.Header.Clone(), body: body}
+	}))
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	e := webhook.NewEmitter(webhook.Config{Url: srv.URL, Secret: testSecret}, logger, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go e.Run(ctx)
+
+	e.Emit(webhook.EventRatesSynced, webhook.RatesSyncedData{Dataset: "ecb.exchange_rate", LatestDay: "2024-09-02"})
+
+	var d received
+	select {
+	case d = <-deliveries:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no delivery within 5s")
+	}
+
+	timestamp, err := strconv.ParseInt(d.header.Get(webhook.HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("strconv.ParseInt failed: %s", err.Error())
+	}
+	if age := time.Since(time.Unix(timestamp, 0)); age < -time.Second || age > 5*time.Second {
+		t.Errorf("timestamp: got %d, want now", timestamp)
+	}
+	if !webhook.Verify(testSecret, timestamp, d.body, d.header.Get(webhook.HeaderSignature)) {
+		t.Errorf("signature %s does not verify", d.header.Get(webhook.HeaderSignature))
+	}
+
+	var ev webhook.Event
+	if err = json.Unmarshal(d.body, &ev); err != nil {
+		t.Fatalf("json.Unmarshal failed: %s", err.Error())
+	}
+	if ev.Type != webhook.EventRatesSynced || d.header.Get(webhook.HeaderEvent) != ev.Type || d.header.Get(webhook.HeaderDelivery) != ev.Id {
+		t.Errorf("event: got type %s, headers %s, %s, want rates.synced and id %s", ev.Type, d.header.Get(webhook.HeaderEvent), d.header.Get(webhook.HeaderDelivery), ev.Id)
+	}
+}
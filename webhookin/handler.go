@@ -0,0 +1,116 @@
+package webhookin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/loveyourstack/connectors/clog"
+	"github.com/loveyourstack/lys"
+)
+
+// RoutePattern is the pattern of the route served by Handler
+const RoutePattern string = "POST /hooks/{provider}"
+
+// maxBodyBytes is the size limit of payloads
+const maxBodyBytes int64 = 1 << 20
+
+// headers which are not stored with deliveries, in addition to the provider's signature header
+var secretHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization"}
+
+// Delivery is a verified webhook delivery, as staged
+type Delivery struct {
+	Provider   string
+	Id         string            // from the provider's id header or field, or else the PayloadHash
+	Headers    map[string]string // k = canonical header name
+	Payload    []byte
+	ReceivedAt time.Time
+}
+
+// Handler returns the handler of RoutePattern. It verifies the signature of a delivery to /hooks/<name> with the provider of that name and persists it with stage
+// deliveries are acknowledged with 202 once staged, and repeated deliveries are expected to be ignored by stage. If stage fails, 500 makes the provider retry
+func Handler(providers []Provider, stage func(ctx context.Context, d Delivery) error, infoLog, errorLog *slog.Logger) http.HandlerFunc {
+
+	providerMap := make(map[string]Provider, len(providers))
+	for _, p := range providers {
+		providerMap[p.Name] = p
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		receivedAt := time.Now()
+
+		p, ok := providerMap[r.PathValue("provider")]
+		if !ok {
+			lys.HandleUserError(http.StatusNotFound, "unknown provider", w)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err != nil {
+			if maxErr := (&http.MaxBytesError{}); errors.As(err, &maxErr) {
+				lys.HandleUserError(http.StatusRequestEntityTooLarge, "payload too large", w)
+				return
+			}
+			lys.HandleUserError(http.StatusBadRequest, "failed to read payload", w)
+			return
+		}
+
+		if err = p.Verify(r.Header, body, receivedAt); err != nil {
+			infoLog.Warn("webhook delivery rejected", "provider", p.Name, clog.KeyError, err.Error())
+			lys.HandleUserError(http.StatusUnauthorized, "invalid signature", w)
+			return
+		}
+		if !json.Valid(body) {
+			lys.HandleUserError(http.StatusBadRequest, "payload is not JSON", w)
+			return
+		}
+
+		headers := make(map[string]string, len(r.Header))
+		for name := range r.Header {
+			headers[name] = r.Header.Get(name)
+		}
+		for _, name := range secretHeaders {
+			delete(headers, name)
+		}
+		delete(headers, http.CanonicalHeaderKey(p.SignatureHeader))
+
+		d := Delivery{
+			Provider:   p.Name,
+			Id:         p.deliveryId(headers, body),
+			Headers:    headers,
+			Payload:    body,
+			ReceivedAt: receivedAt,
+		}
+		if err = stage(r.Context(), d); err != nil {
+			errorLog.Error("webhook delivery not staged", "provider", p.Name, "id", d.Id, clog.KeyError, err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		infoLog.Debug("webhook delivery staged", "provider", p.Name, "id", d.Id)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// deliveryId returns the event id of the delivery, or its PayloadHash if it has none
+func (p Provider) deliveryId(headers map[string]string, payload []byte) string {
+
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return PayloadHash(payload)
+	}
+
+	id, err := p.value(headers, doc, p.IdHeader, p.IdField)
+	if err != nil || id == "" {
+		return PayloadHash(payload)
+	}
+	return id
+}
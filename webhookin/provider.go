@@ -0,0 +1,319 @@
+package webhookin
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/webhook"
+)
+
+// signature schemes
+const (
+	SchemeHmacSha256 string = "hmac-sha256" // HMAC-SHA256 of the body in SignatureHeader, e.g. Shopify or GitHub
+	SchemeStripe     string = "stripe"      // HMAC-SHA256 of "<t>.<body>" in the Stripe-Signature header
+	SchemeConnectors string = "connectors"  // outbound webhooks of another connectors daemon, see webhook.Sign
+)
+
+const (
+	stripeSignatureHeader string        = "Stripe-Signature"
+	defaultTolerance      time.Duration = 5 * time.Minute
+)
+
+var providerNameRegex = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// Provider configures the deliveries accepted from a webhook provider at /hooks/<name>: how they are signed, and where their id, type and time are found
+// fields are dot-separated paths into the JSON payload, e.g. data.object.id
+type Provider struct {
+	Name            string `toml:"name"`   // lower case path segment, stored as the provider of deliveries and events
+	Secret          string `toml:"secret"` // signing secret shared with the provider
+	Scheme          string `toml:"scheme"` // signature scheme. Defaults to hmac-sha256
+	SignatureHeader string `toml:"signatureHeader"`
+	SignaturePrefix string `toml:"signaturePrefix"` // stripped from the signature, e.g. "sha256=" for GitHub
+	Encoding        string `toml:"encoding"`        // of hmac-sha256 signatures: hex or base64. Defaults to hex
+	Tolerance       string `toml:"tolerance"`       // Go duration that signed timestamps of the stripe and connectors schemes may trail. Defaults to 5m
+	IdHeader        string `toml:"idHeader"`        // header of the event id, e.g. X-Shopify-Webhook-Id
+	IdField         string `toml:"idField"`         // payload field of the event id, if there is no IdHeader. Without either, the SHA-256 of the payload is used
+	TypeHeader      string `toml:"typeHeader"`      // header of the event type, e.g. X-Shopify-Topic
+	TypeField       string `toml:"typeField"`       // payload field of the event type, if there is no TypeHeader
+	OccurredAtField string `toml:"occurredAtField"` // payload field of the event time, as RFC 3339 or Unix seconds. Without it, the time received is used
+}
+
+// WithDefaults returns p with the defaults of its scheme applied to unset fields
+func (p Provider) WithDefaults() Provider {
+
+	if p.Scheme == "" {
+		p.Scheme = SchemeHmacSha256
+	}
+	if p.Encoding == "" {
+		p.Encoding = "hex"
+	}
+
+	setDefault := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	switch p.Scheme {
+	case SchemeStripe:
+		setDefault(&p.SignatureHeader, stripeSignatureHeader)
+		setDefault(&p.IdField, "id")
+		setDefault(&p.TypeField, "type")
+		setDefault(&p.OccurredAtField, "created")
+	case SchemeConnectors:
+		setDefault(&p.SignatureHeader, webhook.HeaderSignature)
+		setDefault(&p.IdHeader, webhook.HeaderDelivery)
+		setDefault(&p.TypeHeader, webhook.HeaderEvent)
+		setDefault(&p.OccurredAtField, "occurred_at")
+	}
+
+	return p
+}
+
+// Validate returns an error if p, with defaults applied, is incomplete or invalid
+func (p Provider) Validate() error {
+
+	if !providerNameRegex.MatchString(p.Name) {
+		return fmt.Errorf("%w: name must be lower case letters, digits, _ or -: %q", cerrors.ErrValidationFailed, p.Name)
+	}
+	if p.Secret == "" {
+		return fmt.Errorf("%w: provider %s: secret is required", cerrors.ErrValidationFailed, p.Name)
+	}
+
+	switch p.Scheme {
+	case SchemeHmacSha256:
+		if p.SignatureHeader == "" {
+			return fmt.Errorf("%w: provider %s: signatureHeader is required", cerrors.ErrValidationFailed, p.Name)
+		}
+		if p.Encoding != "hex" && p.Encoding != "base64" {
+			return fmt.Errorf("%w: provider %s: encoding must be hex or base64", cerrors.ErrValidationFailed, p.Name)
+		}
+	case SchemeStripe, SchemeConnectors:
+	default:
+		return fmt.Errorf("%w: provider %s: unknown scheme: %s", cerrors.ErrValidationFailed, p.Name, p.Scheme)
+	}
+
+	if _, err := p.tolerance(); err != nil {
+		return fmt.Errorf("%w: provider %s: tolerance: %w", cerrors.ErrValidationFailed, p.Name, err)
+	}
+
+	return nil
+}
+
+// tolerance returns the parsed Tolerance, or defaultTolerance if not set
+func (p Provider) tolerance() (time.Duration, error) {
+
+	if p.Tolerance == "" {
+		return defaultTolerance, nil
+	}
+	return time.ParseDuration(p.Tolerance)
+}
+
+// Verify returns an error if the signature of body in header is invalid, or if a signed timestamp trails now by more than the tolerance
+func (p Provider) Verify(header http.Header, body []byte, now time.Time) error {
+
+	signature := strings.TrimPrefix(header.Get(p.SignatureHeader), p.SignaturePrefix)
+	if signature == "" {
+		return fmt.Errorf("%w: missing %s header", cerrors.ErrValidationFailed, p.SignatureHeader)
+	}
+
+	switch p.Scheme {
+
+	case SchemeHmacSha256:
+		mac := hmac.New(sha256.New, []byte(p.Secret))
+		mac.Write(body)
+		expected := hex.EncodeToString(mac.Sum(nil))
+		if p.Encoding == "base64" {
+			expected = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		}
+		if !hmac.Equal([]byte(expected), []byte(signature)) {
+			return fmt.Errorf("%w: invalid signature", cerrors.ErrValidationFailed)
+		}
+		return nil
+
+	case SchemeStripe:
+		// e.g. t=1492774577,v1=5257a869e7ecebeda32affa62cdca3fa51cad7e77a0e56ff536d0ce8e108d8bd. There may be several v1 signatures while a secret is rolled
+		var timestamp int64
+		var signatures []string
+		for _, part := range strings.Split(signature, ",") {
+			k, v, _ := strings.Cut(part, "=")
+			switch k {
+			case "t":
+				timestamp, _ = strconv.ParseInt(v, 10, 64)
+			case "v1":
+				signatures = append(signatures, v)
+			}
+		}
+		if err := p.checkTimestamp(timestamp, now); err != nil {
+			return err
+		}
+		for _, sig := range signatures {
+			if webhook.Verify(p.Secret, timestamp, body, sig) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: invalid signature", cerrors.ErrValidationFailed)
+
+	case SchemeConnectors:
+		timestamp, _ := strconv.ParseInt(header.Get(webhook.HeaderTimestamp), 10, 64)
+		if err := p.checkTimestamp(timestamp, now); err != nil {
+			return err
+		}
+		if !webhook.Verify(p.Secret, timestamp, body, signature) {
+			return fmt.Errorf("%w: invalid signature", cerrors.ErrValidationFailed)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("%w: unknown scheme: %s", cerrors.ErrValidationFailed, p.Scheme)
+	}
+}
+
+// checkTimestamp returns an error if the signed Unix timestamp is missing or outside the tolerance of now, so that captured deliveries can't be replayed
+func (p Provider) checkTimestamp(timestamp int64, now time.Time) error {
+
+	if timestamp <= 0 {
+		return fmt.Errorf("%w: missing signature timestamp", cerrors.ErrValidationFailed)
+	}
+	tolerance, err := p.tolerance()
+	if err != nil {
+		return fmt.Errorf("p.tolerance failed: %w", err)
+	}
+	if age := now.Sub(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: signature timestamp outside tolerance: %s", cerrors.ErrValidationFailed, age.Round(time.Second))
+	}
+	return nil
+}
+
+// Event contains the normalized values of a delivery
+type Event struct {
+	Id         string
+	Type       string
+	OccurredAt time.Time
+}
+
+// Normalize returns the id, type and time of a delivery with headers and payload, received at receivedAt
+func (p Provider) Normalize(headers map[string]string, payload []byte, receivedAt time.Time) (ev Event, err error) {
+
+	// numbers are decoded as json.Number, so that large ids keep their digits
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err = dec.Decode(&doc); err != nil {
+		return Event{}, fmt.Errorf("%w: dec.Decode failed: %w", cerrors.ErrValidationFailed, err)
+	}
+
+	ev.Id, err = p.value(headers, doc, p.IdHeader, p.IdField)
+	if err != nil {
+		return Event{}, fmt.Errorf("id: %w", err)
+	}
+	if ev.Id == "" {
+		ev.Id = PayloadHash(payload)
+	}
+
+	ev.Type, err = p.value(headers, doc, p.TypeHeader, p.TypeField)
+	if err != nil {
+		return Event{}, fmt.Errorf("type: %w", err)
+	}
+
+	ev.OccurredAt = receivedAt
+	if p.OccurredAtField != "" {
+		raw, ok := lookupField(doc, p.OccurredAtField)
+		if !ok {
+			return Event{}, fmt.Errorf("%w: field %s not found", cerrors.ErrValidationFailed, p.OccurredAtField)
+		}
+		ev.OccurredAt, err = parseTime(raw)
+		if err != nil {
+			return Event{}, fmt.Errorf("field %s: %w", p.OccurredAtField, err)
+		}
+	}
+
+	return ev, nil
+}
+
+// value returns the value of headerName in headers if set, or else of field in doc. It is empty if neither is configured
+func (p Provider) value(headers map[string]string, doc any, headerName, field string) (string, error) {
+
+	if headerName != "" {
+		val := headers[http.CanonicalHeaderKey(headerName)]
+		if val == "" {
+			return "", fmt.Errorf("%w: header %s not found", cerrors.ErrValidationFailed, headerName)
+		}
+		return val, nil
+	}
+	if field == "" {
+		return "", nil
+	}
+
+	raw, ok := lookupField(doc, field)
+	if !ok {
+		return "", fmt.Errorf("%w: field %s not found", cerrors.ErrValidationFailed, field)
+	}
+	switch v := raw.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	default:
+		return "", fmt.Errorf("%w: field %s is not a string or number", cerrors.ErrValidationFailed, field)
+	}
+}
+
+// lookupField returns the value at the dot-separated path in doc
+func lookupField(doc any, path string) (val any, ok bool) {
+
+	val = doc
+	for _, key := range strings.Split(path, ".") {
+		obj, isObj := val.(map[string]any)
+		if !isObj {
+			return nil, false
+		}
+		if val, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+	return val, val != nil
+}
+
+// parseTime parses an RFC 3339 string or Unix seconds
+func parseTime(raw any) (time.Time, error) {
+
+	switch v := raw.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: time.Parse failed: %w", cerrors.ErrValidationFailed, err)
+		}
+		return t, nil
+	case json.Number:
+		secs, err := v.Int64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: not Unix seconds: %s", cerrors.ErrValidationFailed, v)
+		}
+		return time.Unix(secs, 0), nil
+	default:
+		return time.Time{}, fmt.Errorf("%w: not a time", cerrors.ErrValidationFailed)
+	}
+}
+
+// PayloadHash returns the hex encoded SHA-256 of the compacted payload, the id of deliveries without one
+func PayloadHash(payload []byte) string {
+
+	buf := bytes.Buffer{}
+	if err := json.Compact(&buf, payload); err != nil {
+		buf.Reset()
+		buf.Write(payload)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,153 @@
+package webhookin_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loveyourstack/connectors/cerrors"
+	"github.com/loveyourstack/connectors/webhook"
+	"github.com/loveyourstack/connectors/webhookin"
+)
+
+const (
+	testSecret string = "whsec_test"
+	testBody   string = `{"id":"evt_1","type":"rates.synced"}`
+
+	// known answers of testSecret and testBody
+	testHexSignature       string = "13b54d1d65e31a6dc33c19eb97a6b0415ba94e210092ec9b59c7aae06d3ad000"
+	testBase64Signature    string = "E7VNHWXjGm3DPBnrl6awQVupTiEAkuybWceq4G060AA="
+	testTimestamp          int64  = 1700000000
+	testTimestampSignature string = "a58aa4f5f511f7a40562bd4e9779d17e1c29b9ca243466aabd8175df377ae329" // of "1700000000." + testBody
+)
+
+func TestProviderVerify(t *testing.T) {
+
+	signedAt := time.Unix(testTimestamp, 0)
+
+	github := webhookin.Provider{Name: "github", Secret: testSecret, SignatureHeader: "X-Hub-Signature-256", SignaturePrefix: "sha256="}.WithDefaults()
+	shopify := webhookin.Provider{Name: "shopify", Secret: testSecret, SignatureHeader: "X-Shopify-Hmac-Sha256", Encoding: "base64"}.WithDefaults()
+	stripe := webhookin.Provider{Name: "stripe", Secret: testSecret, Scheme: webhookin.SchemeStripe}.WithDefaults()
+	connectors := webhookin.Provider{Name: "connectors", Secret: testSecret, Scheme: webhookin.SchemeConnectors}.WithDefaults()
+	connectorsHour := connectors
+	connectorsHour.Tolerance = "1h"
+
+	stripeHeader := func(sig string) http.Header {
+		return http.Header{"Stripe-Signature": {sig}}
+	}
+	connectorsHeader := func(timestamp, sig string) http.Header {
+		h := http.Header{}
+		h.Set(webhook.HeaderTimestamp, timestamp)
+		h.Set(webhook.HeaderSignature, sig)
+		return h
+	}
+
+	tests := []struct {
+		name     string
+		provider webhookin.Provider
+		header   http.Header
+		body     string
+		now      time.Time
+		wantErr  bool
+	}{
+		{"hex", github, http.Header{"X-Hub-Signature-256": {"sha256=" + testHexSignature}}, testBody, signedAt, false},
+		{"hex of a tampered body", github, http.Header{"X-Hub-Signature-256": {"sha256=" + testHexSignature}}, `{"id":"evt_2","type":"rates.synced"}`, signedAt, true},
+		{"hex without signature", github, http.Header{}, testBody, signedAt, true},
+		{"hex in base64", github, http.Header{"X-Hub-Signature-256": {"sha256=" + testBase64Signature}}, testBody, signedAt, true},
+		{"base64", shopify, http.Header{"X-Shopify-Hmac-Sha256": {testBase64Signature}}, testBody, signedAt, false},
+		{"base64 of a tampered body", shopify, http.Header{"X-Shopify-Hmac-Sha256": {testBase64Signature}}, testBody + " ", signedAt, true},
+
+		{"stripe", stripe, stripeHeader("t=1700000000,v1=" + testTimestampSignature), testBody, signedAt.Add(time.Minute), false},
+		{"stripe while a secret is rolled", stripe, stripeHeader("t=1700000000,v1=" + testHexSignature + ",v1=" + testTimestampSignature), testBody, signedAt, false},
+		{"stripe of a tampered body", stripe, stripeHeader("t=1700000000,v1=" + testTimestampSignature), strings.Replace(testBody, "evt_1", "evt_2", 1), signedAt, true},
+		{"stripe restamped", stripe, stripeHeader("t=1700000060,v1=" + testTimestampSignature), testBody, signedAt, true},
+		{"stripe at the tolerance", stripe, stripeHeader("t=1700000000,v1=" + testTimestampSignature), testBody, signedAt.Add(5 * time.Minute), false},
+		{"stripe expired", stripe, stripeHeader("t=1700000000,v1=" + testTimestampSignature), testBody, signedAt.Add(5*time.Minute + time.Second), true},
+		{"stripe from the future", stripe, stripeHeader("t=1700000000,v1=" + testTimestampSignature), testBody, signedAt.Add(-6 * time.Minute), true},
+		{"stripe without timestamp", stripe, stripeHeader("v1=" + testTimestampSignature), testBody, signedAt, true},
+
+		{"connectors", connectors, connectorsHeader("1700000000", "sha256="+testTimestampSignature), testBody, signedAt, false},
+		{"connectors of a tampered body", connectors, connectorsHeader("1700000000", "sha256="+testTimestampSignature), `{"id":"evt_1"}`, signedAt, true},
+		{"connectors expired", connectors, connectorsHeader("1700000000", "sha256="+testTimestampSignature), testBody, signedAt.Add(10 * time.Minute), true},
+		{"connectors within a longer tolerance", connectorsHour, connectorsHeader("1700000000", "sha256="+testTimestampSignature), testBody, signedAt.Add(10 * time.Minute), false},
+		{"connectors without timestamp", connectors, connectorsHeader("", "sha256="+testTimestampSignature), testBody, signedAt, true},
+		{"connectors with a wrong secret", webhookin.Provider{Name: "c", Secret: "whsec_other", Scheme: webhookin.SchemeConnectors}.WithDefaults(),
+			connectorsHeader("1700000000", "sha256="+testTimestampSignature), testBody, signedAt, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.provider.Verify(tt.header, []byte(tt.body), tt.now)
+			if tt.wantErr && !errors.Is(err, cerrors.ErrValidationFailed) {
+				t.Errorf("p.Verify: got %v, want cerrors.ErrValidationFailed", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("p.Verify: got %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	connectors := webhookin.Provider{Name: "connectors", Secret: testSecret, Scheme: webhookin.SchemeConnectors}.WithDefaults()
+
+	var staged []webhookin.Delivery
+	mux := http.NewServeMux()
+	mux.Handle(webhookin.RoutePattern, webhookin.Handler([]webhookin.Provider{connectors}, func(ctx context.Context, d webhookin.Delivery) error {
+		staged = append(staged, d)
+		return nil
+	}, logger, logger))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	post := func(path string, timestamp int64, sig string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(testBody))
+		if err != nil {
+			t.Fatalf("http.NewRequest failed: %s", err.Error())
+		}
+		req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(webhook.HeaderSignature, sig)
+		req.Header.Set(webhook.HeaderDelivery, "d-1")
+		req.Header.Set(webhook.HeaderEvent, webhook.EventRatesSynced)
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatalf("srv.Client().Do failed: %s", err.Error())
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	now := time.Now().Unix()
+	if code := post("/hooks/connectors", now, "sha256="+webhook.Sign(testSecret, now, []byte(testBody))); code != http.StatusAccepted {
+		t.Errorf("valid delivery: got status %d, want %d", code, http.StatusAccepted)
+	}
+	if code := post("/hooks/connectors", testTimestamp, "sha256="+testTimestampSignature); code != http.StatusUnauthorized {
+		t.Errorf("expired delivery: got status %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := post("/hooks/connectors", now, "sha256="+testTimestampSignature); code != http.StatusUnauthorized {
+		t.Errorf("restamped delivery: got status %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := post("/hooks/stripe", now, "sha256="+webhook.Sign(testSecret, now, []byte(testBody))); code != http.StatusNotFound {
+		t.Errorf("unknown provider: got status %d, want %d", code, http.StatusNotFound)
+	}
+
+	if len(staged) != 1 {
+		t.Fatalf("staged: got %d deliveries, want 1", len(staged))
+	}
+	if d := staged[0]; d.Provider != "connectors" || d.Id != "d-1" || string(d.Payload) != testBody {
+		t.Errorf("staged: got %s, %s, %s, want connectors, d-1, %s", d.Provider, d.Id, d.Payload, testBody)
+	}
+	if _, ok := staged[0].Headers[webhook.HeaderSignature]; ok {
+		t.Errorf("staged headers: got the signature, want it removed")
+	}
+}